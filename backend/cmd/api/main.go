@@ -6,14 +6,62 @@ import (
 	"log"
 	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/sfumato00/content-analyzer/internal/cache"
 	"github.com/sfumato00/content-analyzer/internal/config"
 	"github.com/sfumato00/content-analyzer/internal/database"
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/handlers"
+	"github.com/sfumato00/content-analyzer/internal/logging"
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/outbound"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
 	"github.com/sfumato00/content-analyzer/internal/server"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/broker"
+	"github.com/sfumato00/content-analyzer/internal/services/contenthash"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/services/feeds"
+	"github.com/sfumato00/content-analyzer/internal/services/hooks"
+	"github.com/sfumato00/content-analyzer/internal/services/imports"
+	"github.com/sfumato00/content-analyzer/internal/services/limits"
+	"github.com/sfumato00/content-analyzer/internal/services/quarantine"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/services/retention"
+	"github.com/sfumato00/content-analyzer/internal/services/threads"
+	"github.com/sfumato00/content-analyzer/internal/services/tiering"
+	"github.com/sfumato00/content-analyzer/internal/services/topics"
+	"github.com/sfumato00/content-analyzer/internal/services/transcription"
+	"github.com/sfumato00/content-analyzer/internal/services/uploads"
+	"github.com/sfumato00/content-analyzer/internal/services/usage"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/version"
 )
 
+// migrationLockTimeout bounds how long a replica waits for another to
+// finish migrating
+const migrationLockTimeout = 5 * time.Minute
+
 func main() {
+	// `api --version` prints the build and exits
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "-version") {
+		fmt.Println(version.Get())
+		return
+	}
+
+	// `api config` prints the resolved configuration and exits
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(printConfig())
+	}
+
 	// Load configuration from environment variables
 	cfg, err := config.Load()
 	if err != nil {
@@ -23,38 +71,152 @@ func main() {
 	// Configure structured logging
 	setupLogging(cfg)
 
-	// Run migrations in development mode
-	if cfg.IsDevelopment() {
-		slog.Info("Running database migrations (development mode)")
-		if err := database.RunMigrations(cfg.DatabaseURL, "./migrations"); err != nil {
-			slog.Warn("Failed to run migrations", "error", err)
+	// Reload safe-to-change settings on SIGHUP or when a config file changes
+	watcher := config.NewWatcher(cfg)
+	watcher.OnReload(func(cfg *config.Config) {
+		if level, err := logging.ParseLevel(cfg.LogLevel); err == nil {
+			logging.Level.Set(level)
 		}
+	})
+
+	if err := models.SetPasswordParams(cfg.PasswordParams()); err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+
+	encryptor, err := cfg.Encryptor()
+	if err != nil {
+		log.Fatalf("Failed to configure encryption at rest: %v", err)
+	}
+
+	objects, err := cfg.Storage()
+	if err != nil {
+		log.Fatalf("Failed to configure object storage: %v", err)
+	}
+
+	// Hook targets, feeds, quick analysis pages and SSO providers are
+	// reached through one client that refuses internal addresses
+	outboundClient, err := cfg.Outbound()
+	if err != nil {
+		log.Fatalf("Failed to configure outbound requests: %v", err)
+	}
+
+	// Panics and server errors are reported to ERROR_REPORTING_DSN
+	reporter, err := cfg.ErrorReporter()
+	if err != nil {
+		log.Fatalf("Failed to configure error reporting: %v", err)
 	}
 
-	// Initialize database connection
 	ctx := context.Background()
-	db, err := database.New(ctx, cfg.DatabaseURL)
+
+	// Initialize Redis cache. In docker-compose the API can start before
+	// Redis and Postgres accept connections, so both are retried for
+	// STARTUP_WAIT, or with STARTUP_LAZY_CONNECT not waited for at all.
+	// Redis is optional: if it doesn't come up the API starts degraded.
+	var redisCache *cache.Cache
+	if !cfg.StartupLazyConnect {
+		err = resilience.Wait(ctx, "redis", cfg.StartupWait, func(ctx context.Context) error {
+			var err error
+			redisCache, err = cache.New(cfg.RedisURL)
+			return err
+		})
+		if err != nil {
+			slog.Warn("Starting without Redis", "error", err)
+		}
+	}
+	if redisCache == nil {
+		redisCache, err = cache.NewLazy(cfg.RedisURL)
+		if err != nil {
+			log.Fatalf("Failed to configure Redis: %v", err)
+		}
+	}
+	defer redisCache.Close()
+	redisCache.WithMetrics(metrics.Default)
+
+	// While Redis is down, caching, rate limits and the job queue degrade
+	if cfg.RedisCheckInterval > 0 {
+		go redisCache.Monitor(ctx, cfg.RedisCheckInterval)
+	}
+
+	// Initialize database connection
+	var db *database.Database
+	dbOpts := database.Options{
+		Tracer: database.NewQueryTracer(metrics.Default, cfg.SlowQueryThreshold),
+		Lazy:   cfg.StartupLazyConnect,
+	}
+	if cfg.StartupLazyConnect {
+		db, err = database.New(ctx, cfg.DatabaseURL, dbOpts)
+	} else {
+		err = resilience.Wait(ctx, "postgres", cfg.StartupWait, func(ctx context.Context) error {
+			var err error
+			db, err = database.New(ctx, cfg.DatabaseURL, dbOpts)
+			return err
+		})
+	}
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	slog.Info("Database connection established")
+	if cfg.StartupLazyConnect {
+		slog.Info("Connecting to the database and Redis on first use")
+	} else {
+		slog.Info("Database connection established")
+	}
+
+	// Run migrations in development mode, one replica at a time
+	if cfg.IsDevelopment() {
+		slog.Info("Running database migrations (development mode)")
+		migrateCtx, cancel := context.WithTimeout(ctx, migrationLockTimeout)
+		err := redisCache.Locker().WithLock(migrateCtx, "migrations", time.Minute, func(ctx context.Context) error {
+			return database.RunMigrations(cfg.DatabaseURL, "./migrations")
+		})
+		cancel()
+		if err != nil {
+			slog.Warn("Failed to run migrations", "error", err)
+		}
+	}
+
+	// Reads that tolerate lag go to the replica while it is healthy
+	if cfg.DatabaseReplicaURL != "" {
+		if err := db.ConnectReplica(ctx, cfg.DatabaseReplicaURL); err != nil {
+			log.Fatalf("Failed to configure database replica: %v", err)
+		}
+		go db.MonitorReplica(ctx, cfg.DatabaseReplicaCheckInterval)
+	}
 
-	// Initialize Redis cache
-	redisCache, err := cache.New(cfg.RedisURL)
+	// Initialize email notifications
+	renderer, err := notifications.NewRenderer()
 	if err != nil {
-		log.Fatalf("Failed to connect to Redis: %v", err)
+		log.Fatalf("Failed to load email templates: %v", err)
 	}
-	defer redisCache.Close()
+	notifier := notifications.NewNotifier(queue.New(redisCache.Client()), renderer, cfg.AppBaseURL)
+
+	// Start background job processing
+	jobsCtx, stopJobs := context.WithCancel(ctx)
+	// The worker and the server share one view of the model provider's
+	// rate limit, exported as metrics and on the admin jobs endpoint
+	budget := ai.NewBudget(limits.ProviderGemini).WithMetrics(metrics.Default)
+	// They also share the spend budgets; operators are emailed once per
+	// period between all replicas when one is used up
+	spendGuard := spend.New(models.NewSpendStore(db.Pool), cfg.SpendLimits(), cfg.CostBudgetMode).
+		WithNotifier(spend.NewEmailNotifier(notifier, cfg.AdminEmails, cfg.CostBudgetMode)).
+		WithLocker(redisCache.Locker())
+	jobsDone := startJobs(jobsCtx, watcher, db, redisCache, notifier, encryptor, objects, outboundClient, budget, spendGuard, reporter)
 
 	// Print startup banner
 	printBanner(cfg)
 
 	// Create and start HTTP server
-	srv := server.New(cfg, db, redisCache)
+	srv := server.New(watcher, db, redisCache, notifier, encryptor, objects, outboundClient, budget, spendGuard, reporter)
 
+	watchCtx, stopWatching := context.WithCancel(ctx)
+	defer stopWatching()
+	go watcher.Run(watchCtx)
+
+	build := version.Get()
 	slog.Info("Application starting",
+		"version", build.Version,
+		"commit", build.ShortCommit(),
 		"environment", cfg.Environment,
 		"port", cfg.Port,
 	)
@@ -65,26 +227,237 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Let in-flight jobs finish before closing connections
+	stopJobs()
+	<-jobsDone
+
+	// Send the error reports still waiting
+	flushCtx, cancelFlush := context.WithTimeout(ctx, 5*time.Second)
+	if err := reporter.Close(flushCtx); err != nil {
+		slog.Warn("Failed to send pending error reports", "error", err)
+	}
+	cancelFlush()
+
 	slog.Info("Application stopped")
 }
 
+// printConfig writes the resolved configuration to stdout with secrets
+// redacted, followed by any validation problems on stderr. It returns the
+// process exit code.
+func printConfig() int {
+	cfg := config.Read()
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to print configuration: %v\n", err)
+		return 1
+	}
+
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "\nConfiguration is invalid: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// startJobs wires job handlers and runs the worker and scheduler in the
+// background. The returned channel is closed once both have stopped.
+func startJobs(ctx context.Context, watcher *config.Watcher, db *database.Database, redisCache *cache.Cache, notifier *notifications.Notifier, encryptor *encryption.Encryptor, objects storage.Storage, outboundClient *outbound.Client, budget *ai.Budget, spendGuard *spend.Guard, reporter errreport.Reporter) <-chan struct{} {
+	cfg := watcher.Current()
+	aiClient := ai.NewClient(ai.Options{
+		APIKey:         cfg.GeminiAPIKey,
+		Model:          cfg.GeminiModel,
+		EmbeddingModel: cfg.GeminiEmbeddingModel,
+		HTTPClient:     cfg.GeminiHTTPClient(),
+		Budget:         budget,
+
+		SkipResponseSchema: !cfg.GeminiResponseSchema,
+		ReplayMode:         cfg.AIReplayMode,
+		ReplayDir:          cfg.AIReplayDir,
+	})
+	watcher.OnReload(func(cfg *config.Config) {
+		aiClient.SetModel(cfg.GeminiModel)
+	})
+
+	jobQueue := queue.New(redisCache.Client())
+
+	// Jobs calling the model wait while its rate limit runs low
+	worker := queue.NewWorker(jobQueue, cfg.WorkerConcurrency).
+		WithHighPriorityBurst(cfg.HighPriorityBurst).
+		WithThrottle(budget, analyzer.JobType, analyzer.ModulesJobType, threads.JobType, topics.JobType).
+		WithReporter(reporter)
+	submissionStore := models.NewSubmissionStore(db.Pool).WithEncryption(encryptor).WithStorage(objects).WithListener(events.NewPublisher(jobQueue))
+	pricing := ai.Pricing{InputPerMillion: cfg.GeminiInputPrice, OutputPerMillion: cfg.GeminiOutputPrice}
+
+	// Analyses that fail for good, or recordings never transcribed, are
+	// taken off monthly quotas
+	quotaTracker := quota.NewTracker(redisCache, cfg.QuotaLimits()).
+		WithNotifier(quota.NewEmailNotifier(notifier))
+	if cfg.QuotaWebhookURL != "" {
+		quotaTracker.WithNotifier(quota.NewWebhookNotifier(jobQueue))
+	}
+	contentAnalyzer := analyzer.NewAnalyzer(submissionStore, aiClient).
+		WithCancelWatcher(jobQueue).
+		WithPricing(pricing).
+		WithVerification(cfg.AnalysisVerification).
+		WithClaims(cfg.ClaimExtraction).
+		WithFactCheck(cfg.FactCheck()).
+		WithProofreading(cfg.Proofreading).
+		WithBias(cfg.BiasAnalysis).
+		WithAIDetection(cfg.AIDetection).
+		WithPerplexity(cfg.Perplexity()).
+		WithModeration(cfg.Moderation).
+		WithPolicies(models.NewModerationStore(db.Pool)).
+		WithGlossaries(models.NewGlossaryStore(db.Pool)).
+		WithTaxonomies(models.NewTaxonomyStore(db.Pool)).
+		WithProfiles(models.NewProfileStore(db.Pool)).
+		WithLimits(limits.New(cfg.AnalyzerLimits())).
+		WithSpendGuard(spendGuard).
+		WithQuota(quotaTracker).
+		WithRepairs(cfg.AIRepairAttempts).
+		WithArtifacts(cfg.AnalysisArtifacts).
+		WithStageConfig(cfg.AnalyzerStages()).
+		WithTimeout(cfg.AnalysisTimeout).
+		WithOrgs(models.NewOrganizationStore(db.Pool)).
+		WithMetrics(metrics.Default)
+	worker.Register(analyzer.JobType, contentAnalyzer.Handle)
+	worker.Register(analyzer.ModulesJobType, contentAnalyzer.HandleModules)
+	worker.Register(queue.UnholdJobType, jobQueue.UnholdHandler())
+	if cfg.QueueVisibilityTimeout > 0 {
+		worker.Register(queue.RequeueStaleJobType, jobQueue.RequeueStaleHandler(cfg.QueueVisibilityTimeout))
+	}
+	threadStore := models.NewThreadStore(db.Pool).WithEncryption(encryptor)
+	worker.Register(threads.JobType, threads.NewResponder(threadStore, submissionStore, aiClient).WithPricing(pricing).Handle)
+	if provider := cfg.Transcription(); provider != nil {
+		transcriptionStore := models.NewTranscriptionStore(db.Pool).WithEncryption(encryptor).WithStorage(objects)
+		worker.Register(transcription.JobType, transcription.NewTranscriber(transcriptionStore, submissionStore, jobQueue, provider).WithQuota(quotaTracker).Handle)
+	}
+
+	// Completed analyses are posted to the REST hooks Zapier and Make
+	// subscribe, and owners are emailed when one quarantines a submission.
+	// Every status change drops the owner's cached stats.
+	hookStore := models.NewHookStore(db.Pool).WithEncryption(encryptor)
+	dispatcher := events.NewDispatcher()
+	dispatcher.Subscribe(hooks.Subscriber(hookStore, jobQueue))
+	dispatcher.Subscribe(quarantine.Subscriber(submissionStore, models.NewUserStore(db.Pool), notifier))
+	dispatcher.Subscribe(handlers.StatsSubscriber(handlers.NewStatsCache(redisCache)))
+	worker.Register(events.StatusChangedJobType, dispatcher.Handle)
+	worker.Register(hooks.JobType, hooks.NewDeliverer(hookStore).WithHTTPClient(outboundClient.Via(cfg.WebhookProxy()).HTTPClient(10*time.Second)).Handle)
+
+	// Lifecycle events are published to the configured broker from their
+	// own jobs, so an outage only delays them
+	if publisher := cfg.EventPublisher(); publisher != nil {
+		defer publisher.Close()
+		dispatcher.Subscribe(broker.Subscriber(jobQueue))
+		worker.Register(broker.JobType, broker.NewRelay(submissionStore, publisher, cfg.EventSource).Handle)
+	}
+
+	clusterer := topics.NewClusterer(models.NewTopicStore(db.Pool).WithEncryption(encryptor).WithStorage(objects), aiClient)
+	worker.Register(topics.JobType, clusterer.Handle)
+	worker.Register(usage.JobType, usage.NewRollupJob(models.NewUsageStore(db.Pool)).Handle)
+	worker.Register(retention.JobType, retention.NewPurgeJob(models.NewRetentionStore(db.Pool)).Handle)
+	worker.Register(retention.TrashJobType, retention.NewTrashJob(submissionStore, cfg.TrashRetention).Handle)
+	// Old submissions' content is moved to object storage when tiering is
+	// on, which needs storage to be configured
+	if cfg.ContentTierMonths > 0 {
+		worker.Register(tiering.JobType, tiering.NewJob(submissionStore, cfg.ContentTierMonths).Handle)
+	}
+	// Content hashes not keyed the way new content is, after encryption
+	// was turned on or its master key rotated, are recomputed once per
+	// start; replicas starting together find nothing left to do
+	worker.Register(contenthash.JobType, contenthash.NewJob(submissionStore).Handle)
+	if _, err := jobQueue.Enqueue(ctx, contenthash.JobType, nil); err != nil {
+		slog.Warn("Failed to enqueue content rehash", "error", err)
+	}
+	// Direct uploads that were never completed are purged with their
+	// objects
+	if objects != nil {
+		worker.Register(uploads.JobType, uploads.NewPurgeJob(models.NewUploadStore(db.Pool).WithStorage(objects)).Handle)
+	}
+	worker.Register(feeds.JobType, feeds.NewPoller(models.NewFeedStore(db.Pool), submissionStore, jobQueue).WithHTTPClient(outboundClient.Via(cfg.FetchProxy()).HTTPClient(30*time.Second)).Handle)
+
+	// Imported rows queued for analysis count against monthly quotas like
+	// submissions made through the API
+	importer := imports.NewImporter(models.NewImportStore(db.Pool).WithEncryption(encryptor).WithStorage(objects), submissionStore, jobQueue).
+		WithQuota(models.NewUserStore(db.Pool), quotaTracker)
+	worker.Register(imports.JobType, importer.Handle)
+
+	mailer, err := notifications.NewMailer(notifications.MailerConfig{
+		Driver:             cfg.MailDriver,
+		From:               cfg.MailFrom,
+		SMTPHost:           cfg.SMTPHost,
+		SMTPPort:           cfg.SMTPPort,
+		SMTPUsername:       cfg.SMTPUsername,
+		SMTPPassword:       cfg.SMTPPassword,
+		SendGridAPIKey:     cfg.SendGridAPIKey,
+		AWSRegion:          cfg.AWSRegion,
+		AWSAccessKeyID:     cfg.AWSAccessKeyID,
+		AWSSecretAccessKey: cfg.AWSSecretAccessKey,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure mailer: %v", err)
+	}
+	digestJob := notifications.NewDigestJob(models.NewAnalyticsStore(db.Pool).WithReplica(db), notifier)
+	worker.Register(notifications.EmailJobType, notifications.NewDeliveryHandler(mailer))
+	worker.Register(notifications.WeeklyDigestJobType, digestJob.Handle)
+	if cfg.QuotaWebhookURL != "" {
+		webhook := quota.NewWebhook(cfg.QuotaWebhookURL, cfg.QuotaWebhookSecret)
+		if client := cfg.QuotaWebhookHTTPClient(); client != nil {
+			webhook.WithHTTPClient(client)
+		}
+		worker.Register(quota.WebhookJobType, webhook.Handle)
+	}
+
+	scheduler := queue.NewScheduler(jobQueue).WithLocker(redisCache.Locker())
+	scheduler.Every(cfg.TopicClusteringInterval, topics.JobType, nil)
+	scheduler.Every(cfg.WeeklyDigestInterval, notifications.WeeklyDigestJobType, nil)
+	scheduler.Every(cfg.UsageRollupInterval, usage.JobType, nil)
+	scheduler.Every(cfg.RetentionPurgeInterval, retention.JobType, nil)
+	scheduler.Every(cfg.TrashPurgeInterval, retention.TrashJobType, nil)
+	if cfg.ContentTierMonths > 0 {
+		scheduler.Every(cfg.ContentTierInterval, tiering.JobType, nil)
+	}
+	scheduler.Every(cfg.FeedPollInterval, feeds.JobType, nil)
+	// Analyses held over a spend budget are tried again; those still over
+	// it are held again
+	scheduler.Every(10*time.Minute, queue.UnholdJobType, nil)
+	// Jobs of a worker that crashed are put back once they're in flight
+	// longer than the visibility timeout
+	if cfg.QueueVisibilityTimeout > 0 {
+		scheduler.Every(time.Minute, queue.RequeueStaleJobType, nil)
+	}
+	if objects != nil {
+		scheduler.Every(uploads.Interval, uploads.JobType, nil)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			worker.Run(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			scheduler.Run(ctx)
+		}()
+		wg.Wait()
+	}()
+
+	return done
+}
+
 // setupLogging configures the structured logger
 func setupLogging(cfg *config.Config) {
-	var handler slog.Handler
-
-	if cfg.IsProduction() {
-		// JSON logging for production
-		handler = slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelInfo,
-		})
-	} else {
-		// Text logging for development
-		handler = slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-			Level: slog.LevelDebug,
-		})
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		level = slog.LevelInfo
 	}
+	logging.Level.Set(level)
 
-	logger := slog.New(handler)
+	logger := slog.New(logging.NewHandler(os.Stdout, cfg.LogFormat))
 	slog.SetDefault(logger)
 }
 
@@ -101,9 +474,14 @@ func printBanner(cfg *config.Config) {
 
 	fmt.Println("  AI-Powered Content Analysis Platform")
 	fmt.Println("  =====================================")
+	fmt.Printf("  Version:     %s (%s)\n", version.Get().Version, version.Get().ShortCommit())
 	fmt.Printf("  Environment: %s\n", cfg.Environment)
 	fmt.Printf("  Port:        %s\n", cfg.Port)
-	fmt.Printf("  URL:         http://localhost:%s\n", cfg.Port)
+	scheme := "http"
+	if cfg.TLSEnabled() {
+		scheme = "https"
+	}
+	fmt.Printf("  URL:         %s://localhost:%s\n", scheme, cfg.Port)
 	fmt.Println("  =====================================")
 	fmt.Println()
 }
@@ -0,0 +1,148 @@
+package abuse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCaptcha_Verify(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   string
+		status  int
+		reply   verifyResponse
+		wantErr error
+		wantAny bool
+	}{
+		{name: "accepted", token: "ok", status: http.StatusOK, reply: verifyResponse{Success: true}},
+		{name: "rejected", token: "bad", status: http.StatusOK, reply: verifyResponse{ErrorCodes: []string{"invalid-input-response"}}, wantErr: ErrCaptchaFailed},
+		{name: "missing token", token: "", wantErr: ErrCaptchaFailed},
+		{name: "provider error", token: "ok", status: http.StatusInternalServerError, wantAny: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := r.ParseForm(); err != nil {
+					t.Fatalf("ParseForm() error = %v", err)
+				}
+				if got := r.PostForm.Get("secret"); got != "secret" {
+					t.Errorf("secret = %q, want %q", got, "secret")
+				}
+				if got := r.PostForm.Get("remoteip"); got != "203.0.113.7" {
+					t.Errorf("remoteip = %q, want %q", got, "203.0.113.7")
+				}
+				w.WriteHeader(tt.status)
+				_ = json.NewEncoder(w).Encode(tt.reply)
+			}))
+			defer server.Close()
+
+			c := NewCaptcha(ProviderTurnstile, "secret")
+			c.endpoint = server.URL
+
+			err := c.Verify(context.Background(), tt.token, "203.0.113.7")
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Verify() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAny:
+				if err == nil || errors.Is(err, ErrCaptchaFailed) {
+					t.Errorf("Verify() error = %v, want a provider error", err)
+				}
+			case err != nil:
+				t.Errorf("Verify() error = %v, want nil", err)
+			}
+		})
+	}
+}
+
+func TestCaptcha_UnknownProvider(t *testing.T) {
+	err := NewCaptcha("recaptcha", "secret").Verify(context.Background(), "token", "")
+	if err == nil || errors.Is(err, ErrCaptchaFailed) {
+		t.Errorf("Verify() error = %v, want a configuration error", err)
+	}
+}
+
+func TestDomainList_Blocked(t *testing.T) {
+	list := NewDomainList(true, []string{"@Spam.Example", "junk.test."})
+
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"user@mailinator.com", true},
+		{"user@eu.mailinator.com", true},
+		{"user@spam.example", true},
+		{"user@junk.test", true},
+		{"user@example.com", false},
+		{"user@notmailinator.com", false},
+		{"not-an-email", false},
+	}
+
+	for _, tt := range tests {
+		if got := list.Blocked(tt.email); got != tt.want {
+			t.Errorf("Blocked(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+
+	if NewDomainList(false, nil).Blocked("user@mailinator.com") {
+		t.Error("Blocked() = true with the built-in list disabled, want false")
+	}
+}
+
+// fakeCounter counts in memory
+type fakeCounter struct {
+	counts map[string]int64
+	ttls   map[string]time.Duration
+}
+
+func (f *fakeCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	f.counts[key]++
+	f.ttls[key] = ttl
+	return f.counts[key], nil
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	counter := &fakeCounter{counts: map[string]int64{}, ttls: map[string]time.Duration{}}
+	now := time.Date(2026, 3, 1, 10, 15, 0, 0, time.UTC)
+
+	limiter := NewLimiter(counter, "ratelimit:signup", 2, time.Hour)
+	limiter.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	for i := 1; i <= 2; i++ {
+		if allowed, _, err := limiter.Allow(ctx, "203.0.113.7"); err != nil || !allowed {
+			t.Fatalf("Allow() attempt %d = %v, %v, want allowed", i, allowed, err)
+		}
+	}
+
+	allowed, retryAfter, err := limiter.Allow(ctx, "203.0.113.7")
+	if err != nil || allowed {
+		t.Fatalf("Allow() = %v, %v, want limited", allowed, err)
+	}
+	if retryAfter != 45*time.Minute {
+		t.Errorf("retryAfter = %v, want %v", retryAfter, 45*time.Minute)
+	}
+
+	// Other clients have their own budget
+	if allowed, _, _ := limiter.Allow(ctx, "198.51.100.1"); !allowed {
+		t.Error("Allow() for another IP = false, want true")
+	}
+
+	// The next window starts over
+	now = now.Add(time.Hour)
+	if allowed, _, _ := limiter.Allow(ctx, "203.0.113.7"); !allowed {
+		t.Error("Allow() in the next window = false, want true")
+	}
+
+	key := "ratelimit:signup:203.0.113.7:" + "1772359200"
+	if counter.ttls[key] != time.Hour {
+		t.Errorf("ttl for %s = %v, want %v", key, counter.ttls[key], time.Hour)
+	}
+}
@@ -0,0 +1,82 @@
+package abuse
+
+import "strings"
+
+// defaultDisposableDomains are widely used throwaway inbox providers
+var defaultDisposableDomains = []string{
+	"10minutemail.com",
+	"discard.email",
+	"dispostable.com",
+	"emailondeck.com",
+	"fakeinbox.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"guerrillamail.net",
+	"maildrop.cc",
+	"mailinator.com",
+	"mailnesia.com",
+	"mintemail.com",
+	"mohmal.com",
+	"sharklasers.com",
+	"temp-mail.org",
+	"tempmail.com",
+	"tempmailo.com",
+	"throwawaymail.com",
+	"trashmail.com",
+	"yopmail.com",
+}
+
+// DomainList matches email addresses against blocked domains, including
+// their subdomains
+type DomainList struct {
+	domains map[string]bool
+}
+
+// NewDomainList blocks the given domains, plus the built-in list of
+// disposable email providers when disposable is set
+func NewDomainList(disposable bool, domains []string) *DomainList {
+	l := &DomainList{domains: make(map[string]bool)}
+	if disposable {
+		l.add(defaultDisposableDomains)
+	}
+	l.add(domains)
+	return l
+}
+
+// add blocks each of domains
+func (l *DomainList) add(domains []string) {
+	for _, domain := range domains {
+		if domain = normalizeDomain(domain); domain != "" {
+			l.domains[domain] = true
+		}
+	}
+}
+
+// Blocked reports whether email belongs to a blocked domain
+func (l *DomainList) Blocked(email string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	if !ok {
+		return false
+	}
+
+	domain = normalizeDomain(domain)
+	for domain != "" {
+		if l.domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			break
+		}
+		domain = parent
+	}
+	return false
+}
+
+// normalizeDomain lowercases a domain and drops a leading "@" or
+// trailing dot
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimPrefix(domain, "@")
+	return strings.TrimSuffix(domain, ".")
+}
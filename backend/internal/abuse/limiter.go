@@ -0,0 +1,74 @@
+package abuse
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Counter counts hits in a window; *cache.Cache implements it
+type Counter interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+}
+
+// Limiter allows a fixed number of attempts per key in each window
+type Limiter struct {
+	counter Counter
+	prefix  string
+	limit   int
+	window  time.Duration
+	now     func() time.Time
+
+	failClosed bool
+}
+
+// NewLimiter allows limit attempts per window for each key under prefix
+func NewLimiter(counter Counter, prefix string, limit int, window time.Duration) *Limiter {
+	return &Limiter{
+		counter: counter,
+		prefix:  prefix,
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+// WithFailClosed sets whether attempts are refused, rather than allowed,
+// while the counter can't be reached, and returns the limiter
+func (l *Limiter) WithFailClosed(failClosed bool) *Limiter {
+	l.failClosed = failClosed
+	return l
+}
+
+// FailsClosed reports whether attempts are refused when Allow errors
+func (l *Limiter) FailsClosed() bool {
+	return l.failClosed
+}
+
+// Allow records an attempt for key and reports whether it is within the
+// limit. When it isn't, retryAfter is the time left in the window.
+func (l *Limiter) Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error) {
+	// Windows are aligned so every replica counts into the same bucket
+	start := l.now().Truncate(l.window)
+	bucket := fmt.Sprintf("%s:%s:%d", l.prefix, key, start.Unix())
+
+	count, err := l.counter.Incr(ctx, bucket, l.window)
+	if err != nil {
+		return false, 0, err
+	}
+	if count > int64(l.limit) {
+		return false, start.Add(l.window).Sub(l.now()), nil
+	}
+	return true, 0, nil
+}
+
+// Protection bundles the checks applied to sign-up and password reset
+// requests. Nil fields are skipped.
+type Protection struct {
+	// Captcha is required on registration and forgotten password requests
+	Captcha *Captcha
+	// Domains rejects registrations from disposable addresses
+	Domains *DomainList
+	// Signups limits registrations per client IP
+	Signups *Limiter
+}
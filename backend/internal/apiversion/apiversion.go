@@ -0,0 +1,131 @@
+// Package apiversion lets one set of handlers serve several API versions.
+// Each version is mounted as its own route group that records the version
+// in the request context; handlers whose output changed between versions
+// render through Render, and old versions or endpoints announce their
+// retirement with Deprecation and Sunset headers.
+package apiversion
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version is a major API version
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+
+	// Latest is the newest version clients can use
+	Latest = V2
+)
+
+// Versions lists every served version, oldest first
+var Versions = []Version{V1, V2}
+
+// HeaderName carries the version that served a response
+const HeaderName = "API-Version"
+
+type contextKey struct{}
+
+// String returns the version as used in paths, e.g. "v2"
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// Prefix returns the path the version is mounted at, e.g. "/api/v2"
+func (v Version) Prefix() string {
+	return "/api/" + v.String()
+}
+
+// Middleware records v in the request context and on the response
+func Middleware(v Version) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(HeaderName, strconv.Itoa(int(v)))
+			next.ServeHTTP(w, r.WithContext(WithVersion(r.Context(), v)))
+		})
+	}
+}
+
+// WithVersion returns a context carrying v
+func WithVersion(ctx context.Context, v Version) context.Context {
+	return context.WithValue(ctx, contextKey{}, v)
+}
+
+// FromContext returns the version serving the request, V1 outside a
+// versioned route group
+func FromContext(ctx context.Context) Version {
+	if v, ok := ctx.Value(contextKey{}).(Version); ok {
+		return v
+	}
+	return V1
+}
+
+// Serializer is implemented by responses whose JSON shape differs between
+// versions
+type Serializer interface {
+	// ForVersion returns the value to encode for v
+	ForVersion(v Version) interface{}
+}
+
+// Render returns data as the request's version expects it. Values that
+// aren't a Serializer look the same in every version.
+func Render(r *http.Request, data interface{}) interface{} {
+	if s, ok := data.(Serializer); ok {
+		return s.ForVersion(FromContext(r.Context()))
+	}
+	return data
+}
+
+// Deprecation describes the retirement of a version or endpoint
+type Deprecation struct {
+	// Since is when it was deprecated
+	Since time.Time
+
+	// Sunset is when it stops working; zero if not yet decided
+	Sunset time.Time
+
+	// Successor maps a request path to its replacement, e.g. the same path
+	// under a newer version; nil for none
+	Successor func(path string) string
+}
+
+// Deprecate adds Deprecation (RFC 9745), Sunset (RFC 8594) and successor
+// Link headers to every response
+func Deprecate(d Deprecation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if !d.Since.IsZero() {
+				h.Set("Deprecation", fmt.Sprintf("@%d", d.Since.Unix()))
+			}
+			if !d.Sunset.IsZero() {
+				h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if d.Successor != nil {
+				if path := d.Successor(r.URL.Path); path != "" {
+					h.Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", path))
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Upgrade returns a Successor that moves a path from one version's prefix
+// to another's
+func Upgrade(from, to Version) func(path string) string {
+	return func(path string) string {
+		rest, ok := strings.CutPrefix(path, from.Prefix())
+		if !ok {
+			return ""
+		}
+		return to.Prefix() + rest
+	}
+}
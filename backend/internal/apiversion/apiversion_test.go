@@ -0,0 +1,114 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// shape renders differently in v1 and v2
+type shape struct{}
+
+func (shape) ForVersion(v Version) interface{} {
+	return v.String()
+}
+
+func TestMiddleware(t *testing.T) {
+	var got Version
+	handler := Middleware(V2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v2/me", nil))
+
+	if got != V2 {
+		t.Errorf("FromContext() = %v, want %v", got, V2)
+	}
+	if h := rec.Header().Get(HeaderName); h != "2" {
+		t.Errorf("%s header = %q, want %q", HeaderName, h, "2")
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		version Version
+		data    interface{}
+		want    interface{}
+	}{
+		{"serializer in v1", V1, shape{}, "v1"},
+		{"serializer in v2", V2, shape{}, "v2"},
+		{"plain value", V2, 42, 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r = r.WithContext(WithVersion(r.Context(), tt.version))
+
+			if got := Render(r, tt.data); got != tt.want {
+				t.Errorf("Render() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Outside a versioned group the oldest shape is kept
+	if got := Render(httptest.NewRequest(http.MethodGet, "/", nil), shape{}); got != "v1" {
+		t.Errorf("Render() without a version = %v, want v1", got)
+	}
+}
+
+func TestDeprecate(t *testing.T) {
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name            string
+		deprecation     Deprecation
+		path            string
+		wantDeprecation string
+		wantSunset      string
+		wantLink        string
+	}{
+		{
+			name:            "full policy",
+			deprecation:     Deprecation{Since: since, Sunset: sunset, Successor: Upgrade(V1, V2)},
+			path:            "/api/v1/submissions/123",
+			wantDeprecation: "@1767225600",
+			wantSunset:      "Wed, 01 Jul 2026 00:00:00 GMT",
+			wantLink:        `</api/v2/submissions/123>; rel="successor-version"`,
+		},
+		{
+			name:        "sunset only",
+			deprecation: Deprecation{Sunset: sunset},
+			path:        "/api/v1/me",
+			wantSunset:  "Wed, 01 Jul 2026 00:00:00 GMT",
+		},
+		{
+			name:            "path outside the old version",
+			deprecation:     Deprecation{Since: since, Successor: Upgrade(V1, V2)},
+			path:            "/health",
+			wantDeprecation: "@1767225600",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Deprecate(tt.deprecation)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := rec.Header().Get("Deprecation"); got != tt.wantDeprecation {
+				t.Errorf("Deprecation = %q, want %q", got, tt.wantDeprecation)
+			}
+			if got := rec.Header().Get("Sunset"); got != tt.wantSunset {
+				t.Errorf("Sunset = %q, want %q", got, tt.wantSunset)
+			}
+			if got := rec.Header().Get("Link"); got != tt.wantLink {
+				t.Errorf("Link = %q, want %q", got, tt.wantLink)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// APIKeyHeader carries the API key of integrations that can't hold a JWT,
+// such as the browser extension
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyIDKey is the context key for the ID of the API key a request was
+// authenticated with
+const APIKeyIDKey ContextKey = "api_key_id"
+
+// APIKeyAuthenticator resolves API keys; *models.APIKeyStore implements it
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// APIKeyMiddleware authenticates requests by the key in the X-API-Key
+// header, setting the same user context as Middleware, limited to the
+// key's scopes if it has any. Keys of suspended and banned accounts are
+// refused; standings may be nil to skip that check. Like Middleware, it
+// refuses users outside the organization of the subdomain.
+func APIKeyMiddleware(keys APIKeyAuthenticator, standings StandingChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			plaintext := r.Header.Get(APIKeyHeader)
+			if plaintext == "" {
+				response.Unauthorized(w, "Missing API key")
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), plaintext)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					response.Unauthorized(w, "Invalid or revoked API key")
+					return
+				}
+				slog.Error("Failed to authenticate API key", "error", err)
+				response.InternalServerError(w, "Failed to authenticate API key")
+				return
+			}
+			if standings != nil && !checkStanding(w, r, standings, key.UserID, false) {
+				return
+			}
+			if !checkHostOrg(w, r, key.UserID) {
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, key.UserID)
+			ctx = context.WithValue(ctx, APIKeyIDKey, key.ID)
+			ctx = withScopes(ctx, key.Scopes)
+			errreport.SetUser(ctx, key.UserID.String())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetAPIKeyIDFromContext extracts the API key ID from the request context
+func GetAPIKeyIDFromContext(ctx context.Context) (uuid.UUID, error) {
+	id, ok := ctx.Value(APIKeyIDKey).(uuid.UUID)
+	if !ok {
+		return uuid.Nil, http.ErrNoCookie
+	}
+	return id, nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// failingKeys fails every lookup
+type failingKeys struct{}
+
+func (failingKeys) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	return nil, errors.New("database down")
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	keys := memstore.NewAPIKeyStore()
+
+	plaintext, key, err := keys.Create(ctx, userID, "extension", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedKey, _ := keys.Create(ctx, userID, "old", nil)
+	keys.Revoke(ctx, userID, revokedKey.ID)
+
+	tests := []struct {
+		name       string
+		header     string
+		keys       APIKeyAuthenticator
+		standings  StandingChecker
+		wantStatus int
+	}{
+		{name: "valid key", header: plaintext, keys: keys, wantStatus: http.StatusOK},
+		{name: "missing header", keys: keys, wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", header: "ca_nope", keys: keys, wantStatus: http.StatusUnauthorized},
+		{name: "revoked key", header: revoked, keys: keys, wantStatus: http.StatusUnauthorized},
+		{name: "lookup fails closed", header: plaintext, keys: failingKeys{}, wantStatus: http.StatusInternalServerError},
+		{name: "account in good standing", header: plaintext, keys: keys, standings: &fakeRevocations{}, wantStatus: http.StatusOK},
+		{
+			name: "suspended account", header: plaintext, keys: keys,
+			standings:  &fakeRevocations{standing: &Standing{Status: models.StatusSuspended}},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID, gotKeyID uuid.UUID
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = GetUserIDFromContext(r.Context())
+				gotKeyID, _ = GetAPIKeyIDFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(APIKeyHeader, tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			APIKeyMiddleware(tt.keys, tt.standings)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("APIKeyMiddleware() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && (gotUserID != userID || gotKeyID != key.ID) {
+				t.Errorf("APIKeyMiddleware() user ID = %s, key ID = %s", gotUserID, gotKeyID)
+			}
+		})
+	}
+
+	// A scoped key limits the request, and RequireScope enforces it
+	scoped, _, _ := keys.Create(ctx, userID, "zapier", []string{ScopeHooks})
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set(APIKeyHeader, scoped)
+	rec := httptest.NewRecorder()
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	APIKeyMiddleware(keys, nil)(RequireScope(ScopeAnalyze)(next)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("scoped key status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// ImpersonationTokenExpiry is how long an admin may act as a user with one
+// impersonation token
+const ImpersonationTokenExpiry = 15 * time.Minute
+
+// ImpersonatorKey is the context key for the admin acting as the user
+const ImpersonatorKey ContextKey = "impersonator"
+
+// Actor is the admin acting as a user, carried in the act claim of an
+// impersonation token
+type Actor struct {
+	UserID uuid.UUID `json:"sub"`
+	Email  string    `json:"email"`
+}
+
+type impersonationKey struct{}
+
+// Impersonation records whether a request was made by an admin acting as
+// a user. Middleware fills it in once it has validated the token.
+type Impersonation struct {
+	mu     sync.Mutex
+	userID uuid.UUID
+	actor  *Actor
+}
+
+// TrackImpersonation returns a context in which impersonation tokens are
+// accepted, and the Impersonation that records their use. Without it they
+// are refused, so no impersonated request goes unrecorded.
+func TrackImpersonation(ctx context.Context) (context.Context, *Impersonation) {
+	tracked := &Impersonation{}
+	return context.WithValue(ctx, impersonationKey{}, tracked), tracked
+}
+
+// Actor returns the user acted as and the admin acting, and false if the
+// request wasn't impersonated
+func (i *Impersonation) Actor() (uuid.UUID, Actor, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.actor == nil {
+		return uuid.Nil, Actor{}, false
+	}
+	return i.userID, *i.actor, true
+}
+
+// impersonate records an impersonated request in ctx's Impersonation,
+// reporting false when impersonation isn't tracked there
+func impersonate(ctx context.Context, userID uuid.UUID, actor Actor) bool {
+	tracked, ok := ctx.Value(impersonationKey{}).(*Impersonation)
+	if !ok {
+		return false
+	}
+	tracked.mu.Lock()
+	tracked.userID, tracked.actor = userID, &actor
+	tracked.mu.Unlock()
+	return true
+}
+
+// GetImpersonatorFromContext returns the admin acting as the user, and
+// false when the user is acting for themselves
+func GetImpersonatorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(ImpersonatorKey).(Actor)
+	return actor, ok
+}
+
+// ForbidImpersonation refuses impersonated requests, for account changes
+// only the user may make
+func ForbidImpersonation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetImpersonatorFromContext(r.Context()); ok {
+			response.Forbidden(w, "Not allowed while impersonating a user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -1,26 +1,56 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
 )
 
+// Audience is the aud claim of tokens for this API. Tokens issued before
+// it was set have none and are still accepted.
+const Audience = "content-analyzer-api"
+
+// ScopedTokenExpiry is how long a scoped token is valid
+const ScopedTokenExpiry = time.Hour
+
 // Claims represents the JWT claims
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
-	Email  string    `json:"email"`
+	Email  string    `json:"email,omitempty"`
+	// Scope limits a token issued to an integration to these
+	// space-separated scopes; a user's own session has none
+	Scope string `json:"scope,omitempty"`
+	// ClientID is the API key or OAuth client a scoped token was issued to
+	ClientID string `json:"client_id,omitempty"`
+	// SessionID is the remember-me session a token was issued from, so
+	// revoking the session revokes the token
+	SessionID string `json:"sid,omitempty"`
+	// Actor is the admin who was issued an impersonation token for the user
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// Scopes returns the scopes the token is limited to, or nil if it isn't
+func (c *Claims) Scopes() []string {
+	if c.Scope == "" {
+		return nil
+	}
+	return parseScope(c.Scope)
+}
+
 // TokenPair represents access and refresh tokens
 type TokenPair struct {
-	AccessToken  string    `json:"access_token"`
-	RefreshToken string    `json:"refresh_token,omitempty"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	TokenType    string    `json:"token_type"`
+	AccessToken  string         `json:"access_token"`
+	RefreshToken string         `json:"refresh_token,omitempty"`
+	ExpiresAt    timestamp.Time `json:"expires_at"`
+	TokenType    string         `json:"token_type"`
 }
 
 // JWTManager handles JWT operations
@@ -49,24 +79,109 @@ func (m *JWTManager) GenerateTokenPair(userID uuid.UUID, email string) (*TokenPa
 
 	return &TokenPair{
 		AccessToken: accessToken,
-		ExpiresAt:   expiresAt,
+		ExpiresAt:   timestamp.New(expiresAt),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// GenerateDeviceTokenPair is GenerateTokenPair for a token issued from a
+// remember-me session
+func (m *JWTManager) GenerateDeviceTokenPair(userID uuid.UUID, email string, sessionID uuid.UUID) (*TokenPair, error) {
+	accessToken, expiresAt, err := m.signToken(Claims{
+		UserID:    userID,
+		Email:     email,
+		SessionID: sessionID.String(),
+	}, m.accessTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresAt:   timestamp.New(expiresAt),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// GenerateScopedToken issues a token to an integration, such as an API key
+// or OAuth client, that may act as the user only within scopes. It can't
+// be refreshed; the integration asks for a new one.
+func (m *JWTManager) GenerateScopedToken(userID uuid.UUID, clientID string, scopes []string) (*TokenPair, error) {
+	if len(scopes) == 0 {
+		return nil, errors.New("a scoped token needs at least one scope")
+	}
+
+	accessToken, expiresAt, err := m.signToken(Claims{
+		UserID:   userID,
+		Scope:    strings.Join(scopes, " "),
+		ClientID: clientID,
+	}, ScopedTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate scoped token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresAt:   timestamp.New(expiresAt),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// GenerateAppealToken issues a suspended user a token that only the
+// routes behind SuspendedMiddleware accept, for appealing. It can't be
+// refreshed.
+func (m *JWTManager) GenerateAppealToken(userID uuid.UUID, email string) (*TokenPair, error) {
+	accessToken, expiresAt, err := m.signToken(Claims{
+		UserID: userID,
+		Email:  email,
+		Scope:  ScopeAppeal,
+	}, ScopedTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate appeal token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresAt:   timestamp.New(expiresAt),
+		TokenType:   "Bearer",
+	}, nil
+}
+
+// GenerateImpersonationToken issues a short-lived token with which actor,
+// an admin, may act as the user. The act claim marks it, so every request
+// made with it is attributed to both.
+func (m *JWTManager) GenerateImpersonationToken(userID uuid.UUID, email string, actor Actor) (*TokenPair, error) {
+	accessToken, expiresAt, err := m.signToken(Claims{
+		UserID: userID,
+		Email:  email,
+		Actor:  &actor,
+	}, ImpersonationTokenExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate impersonation token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken: accessToken,
+		ExpiresAt:   timestamp.New(expiresAt),
 		TokenType:   "Bearer",
 	}, nil
 }
 
 // generateToken creates a new JWT token
 func (m *JWTManager) generateToken(userID uuid.UUID, email string, expiry time.Duration) (string, time.Time, error) {
+	return m.signToken(Claims{UserID: userID, Email: email}, expiry)
+}
+
+// signToken fills in the registered claims and signs the token
+func (m *JWTManager) signToken(claims Claims, expiry time.Duration) (string, time.Time, error) {
 	expiresAt := time.Now().Add(expiry)
 
-	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expiresAt),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "content-analyzer",
-		},
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		Issuer:    "content-analyzer",
+		Audience:  jwt.ClaimStrings{Audience},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -96,6 +211,9 @@ func (m *JWTManager) ValidateToken(tokenString string) (*Claims, error) {
 	if !ok || !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
+	if len(claims.Audience) > 0 && !slices.Contains(claims.Audience, Audience) {
+		return nil, fmt.Errorf("token is for another audience")
+	}
 
 	return claims, nil
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -138,3 +139,62 @@ func TestJWTManager_ExtractUserID(t *testing.T) {
 		t.Errorf("ExtractUserID() = %v, want %v", userID, expectedUserID)
 	}
 }
+
+func TestJWTManager_GenerateScopedToken(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key-at-least-32-characters-long")
+	userID := uuid.New()
+
+	if _, err := jwtManager.GenerateScopedToken(userID, "zapier", nil); err == nil {
+		t.Error("GenerateScopedToken() without scopes should fail")
+	}
+
+	tokenPair, err := jwtManager.GenerateScopedToken(userID, "zapier", []string{ScopeSubmissionsRead, ScopeAnalyze})
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+	if time.Until(tokenPair.ExpiresAt.Time) > ScopedTokenExpiry {
+		t.Errorf("GenerateScopedToken() ExpiresAt = %v, want within %v", tokenPair.ExpiresAt, ScopedTokenExpiry)
+	}
+
+	claims, err := jwtManager.ValidateToken(tokenPair.AccessToken)
+	if err != nil {
+		t.Fatalf("ValidateToken() error = %v", err)
+	}
+	if claims.UserID != userID || claims.ClientID != "zapier" || claims.Email != "" {
+		t.Errorf("ValidateToken() claims = %+v", claims)
+	}
+	if got := claims.Scopes(); len(got) != 2 || got[0] != ScopeSubmissionsRead || got[1] != ScopeAnalyze {
+		t.Errorf("Scopes() = %v", got)
+	}
+	if len(claims.Audience) != 1 || claims.Audience[0] != Audience {
+		t.Errorf("ValidateToken() audience = %v, want %s", claims.Audience, Audience)
+	}
+}
+
+func TestJWTManager_ValidateToken_Audience(t *testing.T) {
+	secret := "test-secret-key-at-least-32-characters-long"
+	jwtManager := NewJWTManager(secret)
+
+	sign := func(audience jwt.ClaimStrings) string {
+		claims := Claims{
+			UserID: uuid.New(),
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				Audience:  audience,
+			},
+		}
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		if err != nil {
+			t.Fatalf("SignedString() error = %v", err)
+		}
+		return token
+	}
+
+	// Tokens issued before the audience claim still validate
+	if _, err := jwtManager.ValidateToken(sign(nil)); err != nil {
+		t.Errorf("ValidateToken() without audience error = %v", err)
+	}
+	if _, err := jwtManager.ValidateToken(sign(jwt.ClaimStrings{"another-api"})); err == nil {
+		t.Error("ValidateToken() should fail for another audience")
+	}
+}
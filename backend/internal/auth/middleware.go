@@ -2,10 +2,15 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/google/uuid"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/models"
 	"github.com/sfumato00/content-analyzer/internal/response"
 )
 
@@ -19,8 +24,32 @@ const (
 	UserEmailKey ContextKey = "user_email"
 )
 
-// Middleware creates a JWT authentication middleware
-func Middleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
+// Middleware creates a JWT authentication middleware. revocations may be
+// nil to skip the revoked-session and account status checks. Scoped
+// tokens are refused; routes open to them use ScopedMiddleware. Suspended
+// and banned accounts are refused with a StandingResponse, and on an
+// organization's subdomain so are users outside it.
+func Middleware(jwtManager *JWTManager, revocations RevocationChecker) func(http.Handler) http.Handler {
+	return authenticate(jwtManager, revocations, false, false)
+}
+
+// ScopedMiddleware is Middleware that also accepts scoped tokens. Every
+// route behind it must check their scopes with RequireScope.
+func ScopedMiddleware(jwtManager *JWTManager, revocations RevocationChecker) func(http.Handler) http.Handler {
+	return authenticate(jwtManager, revocations, true, false)
+}
+
+// SuspendedMiddleware is Middleware that also lets suspended accounts
+// through, for the routes they use to appeal, and accepts the appeal
+// tokens they are given at sign-in. Banned accounts are still refused.
+func SuspendedMiddleware(jwtManager *JWTManager, revocations RevocationChecker) func(http.Handler) http.Handler {
+	return authenticate(jwtManager, revocations, false, true)
+}
+
+// authenticate validates the bearer token, accepting scoped tokens only
+// when allowScoped is set and suspended accounts only when allowSuspended
+// is
+func authenticate(jwtManager *JWTManager, revocations RevocationChecker, allowScoped, allowSuspended bool) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Extract token from Authorization header
@@ -45,10 +74,46 @@ func Middleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
 				response.Unauthorized(w, "Invalid or expired token")
 				return
 			}
+			scopes := claims.Scopes()
+			if scopes != nil && !allowScoped && !(allowSuspended && appealOnly(scopes)) {
+				response.Forbidden(w, "Scoped tokens can't be used here")
+				return
+			}
+			if claims.Actor != nil && !impersonate(r.Context(), claims.UserID, *claims.Actor) {
+				response.Forbidden(w, "Impersonation tokens can't be used here")
+				return
+			}
+
+			// Fail open if the check itself errors: Redis trouble shouldn't
+			// log everyone out. While it is known to be down, that isn't
+			// logged on every request.
+			if revocations != nil {
+				revoked, err := revocations.IsRevoked(r.Context(), claims)
+				if err != nil {
+					if !errors.Is(err, cache.ErrUnavailable) {
+						slog.Error("Failed to check session revocation", "error", err)
+					}
+				} else if revoked {
+					response.Unauthorized(w, "Session has been revoked")
+					return
+				}
+
+				if !checkStanding(w, r, revocations, claims.UserID, allowSuspended) {
+					return
+				}
+			}
+			if !checkHostOrg(w, r, claims.UserID) {
+				return
+			}
 
 			// Add user info to context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, UserEmailKey, claims.Email)
+			ctx = withScopes(ctx, scopes)
+			if claims.Actor != nil {
+				ctx = context.WithValue(ctx, ImpersonatorKey, *claims.Actor)
+			}
+			errreport.SetUser(ctx, claims.UserID.String())
 
 			// Call next handler with updated context
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -56,6 +121,27 @@ func Middleware(jwtManager *JWTManager) func(http.Handler) http.Handler {
 	}
 }
 
+// checkStanding refuses suspended and banned accounts, letting suspended
+// ones through when allowSuspended is set. Statuses are read from the
+// database when Redis can't answer, so it fails open only when neither
+// can. It reports whether the request may go on, having written the
+// response if not.
+func checkStanding(w http.ResponseWriter, r *http.Request, standings StandingChecker, userID uuid.UUID, allowSuspended bool) bool {
+	standing, err := standings.Standing(r.Context(), userID)
+	if err != nil {
+		if !errors.Is(err, cache.ErrUnavailable) {
+			slog.Error("Failed to check account status", "error", err)
+		}
+		return true
+	}
+	if standing == nil || (allowSuspended && standing.Status == models.StatusSuspended) {
+		return true
+	}
+
+	RefuseStanding(w, standing)
+	return false
+}
+
 // GetUserIDFromContext extracts the user ID from the request context
 func GetUserIDFromContext(ctx context.Context) (uuid.UUID, error) {
 	userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
@@ -73,3 +159,32 @@ func GetUserEmailFromContext(ctx context.Context) (string, error) {
 	}
 	return email, nil
 }
+
+// RequireAdmin only lets through users whose ID is in admins. It must run
+// after Middleware. An empty list locks everyone out, and an admin
+// impersonating a user has only that user's access. Emails aren't
+// trusted for this, since accounts can be created for addresses their
+// holders don't control.
+func RequireAdmin(admins []uuid.UUID) func(http.Handler) http.Handler {
+	allowed := make(map[uuid.UUID]bool, len(admins))
+	for _, id := range admins {
+		allowed[id] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := GetUserIDFromContext(r.Context())
+			if err != nil {
+				response.Unauthorized(w, "Unauthorized")
+				return
+			}
+
+			if _, impersonated := GetImpersonatorFromContext(r.Context()); impersonated || !allowed[userID] {
+				response.Forbidden(w, "Admin access required")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
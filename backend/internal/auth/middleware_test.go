@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// fakeRevocations answers revocation and account status checks with a
+// fixed result
+type fakeRevocations struct {
+	revoked  bool
+	standing *Standing
+	err      error
+}
+
+func (f *fakeRevocations) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	return f.revoked, f.err
+}
+
+func (f *fakeRevocations) Standing(ctx context.Context, userID uuid.UUID) (*Standing, error) {
+	return f.standing, f.err
+}
+
+func TestMiddleware(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key-at-least-32-characters-long")
+	userID := uuid.New()
+
+	tokenPair, err := jwtManager.GenerateTokenPair(userID, "test@example.com")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		header      string
+		revocations RevocationChecker
+		wantStatus  int
+	}{
+		{
+			name:       "valid token",
+			header:     "Bearer " + tokenPair.AccessToken,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "valid token not revoked",
+			header:      "Bearer " + tokenPair.AccessToken,
+			revocations: &fakeRevocations{},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "revoked session",
+			header:      "Bearer " + tokenPair.AccessToken,
+			revocations: &fakeRevocations{revoked: true},
+			wantStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:        "revocation check fails open",
+			header:      "Bearer " + tokenPair.AccessToken,
+			revocations: &fakeRevocations{err: errors.New("redis down")},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "suspended account",
+			header:      "Bearer " + tokenPair.AccessToken,
+			revocations: &fakeRevocations{standing: &Standing{Status: models.StatusSuspended, Reason: "Spam"}},
+			wantStatus:  http.StatusForbidden,
+		},
+		{
+			name:        "banned account",
+			header:      "Bearer " + tokenPair.AccessToken,
+			revocations: &fakeRevocations{standing: &Standing{Status: models.StatusBanned}},
+			wantStatus:  http.StatusForbidden,
+		},
+		{
+			name:       "missing header",
+			header:     "",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong scheme",
+			header:     "Basic " + tokenPair.AccessToken,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			header:     "Bearer not-a-token",
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID uuid.UUID
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUserID, _ = GetUserIDFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			Middleware(jwtManager, tt.revocations)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Middleware() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && gotUserID != userID {
+				t.Errorf("Middleware() user ID = %s, want %s", gotUserID, userID)
+			}
+		})
+	}
+}
+
+func TestSuspendedMiddleware(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key-at-least-32-characters-long")
+	tokenPair, err := jwtManager.GenerateTokenPair(uuid.New(), "test@example.com")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		standing   *Standing
+		wantStatus int
+	}{
+		{name: "good standing", wantStatus: http.StatusOK},
+		{name: "suspended", standing: &Standing{Status: models.StatusSuspended, Reason: "Spam"}, wantStatus: http.StatusOK},
+		{name: "banned", standing: &Standing{Status: models.StatusBanned, Reason: "Fraud"}, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tokenPair.AccessToken)
+
+			rec := httptest.NewRecorder()
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			SuspendedMiddleware(jwtManager, &fakeRevocations{standing: tt.standing})(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SuspendedMiddleware() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusForbidden {
+				var body StandingResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+					t.Fatal(err)
+				}
+				if body.Status != tt.standing.Status || body.Reason != tt.standing.Reason || body.Appealable {
+					t.Errorf("SuspendedMiddleware() body = %+v", body)
+				}
+			}
+		})
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	adminID := uuid.New()
+
+	tests := []struct {
+		name         string
+		admins       []uuid.UUID
+		userID       uuid.UUID
+		email        string
+		impersonated bool
+		wantStatus   int
+	}{
+		{name: "admin", admins: []uuid.UUID{adminID}, userID: adminID, wantStatus: http.StatusOK},
+		{name: "not an admin", admins: []uuid.UUID{adminID}, userID: uuid.New(), wantStatus: http.StatusForbidden},
+		{name: "another account with an admin's email", admins: []uuid.UUID{adminID}, userID: uuid.New(), email: "ops@example.com", wantStatus: http.StatusForbidden},
+		{name: "no admins configured", userID: adminID, wantStatus: http.StatusForbidden},
+		{name: "unauthenticated", admins: []uuid.UUID{adminID}, wantStatus: http.StatusUnauthorized},
+		{name: "impersonating an admin", admins: []uuid.UUID{adminID}, userID: adminID, impersonated: true, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.userID != uuid.Nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tt.userID))
+			}
+			if tt.email != "" {
+				req = req.WithContext(context.WithValue(req.Context(), UserEmailKey, tt.email))
+			}
+			if tt.impersonated {
+				req = req.WithContext(context.WithValue(req.Context(), ImpersonatorKey, Actor{UserID: uuid.New()}))
+			}
+
+			rec := httptest.NewRecorder()
+			RequireAdmin(tt.admins)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RequireAdmin() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// HostOrgKey is the context key for the organization whose subdomain a
+// request was sent to
+const HostOrgKey ContextKey = "host_org"
+
+// OrgDirectory finds organizations by subdomain and their members' roles;
+// *models.OrganizationStore implements it
+type OrgDirectory interface {
+	GetBySubdomain(ctx context.Context, subdomain string) (*models.Organization, error)
+	Role(ctx context.Context, orgID, userID uuid.UUID) (models.OrgRole, error)
+}
+
+// hostOrg is the organization a request's host resolved to, with the
+// directory its members are checked in
+type hostOrg struct {
+	id   uuid.UUID
+	orgs OrgDirectory
+}
+
+// OrgDomains serves organizations on subdomains of a base domain, such as
+// acme.analyzer.example.com
+type OrgDomains struct {
+	base string
+	orgs OrgDirectory
+}
+
+// NewOrgDomains serves organizations on subdomains of base
+func NewOrgDomains(base string, orgs OrgDirectory) *OrgDomains {
+	return &OrgDomains{base: strings.ToLower(strings.TrimSuffix(base, ".")), orgs: orgs}
+}
+
+// Subdomain returns the organization subdomain a host names: a single
+// label right under the base domain. Any port is ignored.
+func (d *OrgDomains) Subdomain(host string) (string, bool) {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	sub, ok := strings.CutSuffix(host, "."+d.base)
+	if !ok || sub == "" || strings.Contains(sub, ".") {
+		return "", false
+	}
+	return sub, true
+}
+
+// Middleware resolves the organization whose subdomain a request was sent
+// to. The authentication middlewares then let only its members through.
+// Unknown subdomains get 404, and requests to other hosts pass through.
+func (d *OrgDomains) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sub, ok := d.Subdomain(r.Host)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		org, err := d.orgs.GetBySubdomain(r.Context(), sub)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				response.NotFound(w, "Organization not found")
+				return
+			}
+			slog.Error("Failed to resolve organization subdomain", "subdomain", sub, "error", err)
+			response.InternalServerError(w, "Failed to resolve organization")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), HostOrgKey, hostOrg{id: org.ID, orgs: d.orgs})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AllowOrigin reports whether browsers on origin may call the API: it is
+// https on an organization's subdomain. Requests to an organization's
+// subdomain are only open to that organization's origin.
+func (d *OrgDomains) AllowOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	sub, ok := d.Subdomain(u.Host)
+	if !ok {
+		return false
+	}
+	if host, ok := d.Subdomain(r.Host); ok {
+		return host == sub
+	}
+	_, err = d.orgs.GetBySubdomain(r.Context(), sub)
+	return err == nil
+}
+
+// GetHostOrgFromContext returns the organization whose subdomain the
+// request was sent to, if any
+func GetHostOrgFromContext(ctx context.Context) (uuid.UUID, bool) {
+	org, ok := ctx.Value(HostOrgKey).(hostOrg)
+	return org.id, ok
+}
+
+// checkHostOrg refuses users outside the organization whose subdomain the
+// request was sent to. It reports whether the request may go on, having
+// written the response if not.
+func checkHostOrg(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	org, ok := r.Context().Value(HostOrgKey).(hostOrg)
+	if !ok {
+		return true
+	}
+
+	if _, err := org.orgs.Role(r.Context(), org.id, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Forbidden(w, "You aren't a member of this organization")
+			return false
+		}
+		slog.Error("Failed to check organization membership", "org_id", org.id, "error", err)
+		response.InternalServerError(w, "Failed to check organization membership")
+		return false
+	}
+	return true
+}
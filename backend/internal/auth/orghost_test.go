@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestOrgDomains_Subdomain(t *testing.T) {
+	domains := NewOrgDomains("analyzer.example.com", nil)
+
+	tests := []struct {
+		host string
+		want string
+		ok   bool
+	}{
+		{"acme.analyzer.example.com", "acme", true},
+		{"ACME.analyzer.example.com:8443", "acme", true},
+		{"acme.analyzer.example.com.", "acme", true},
+		{"analyzer.example.com", "", false},
+		{"a.b.analyzer.example.com", "", false},
+		{"acme.example.com", "", false},
+		{"acmeanalyzer.example.com", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := domains.Subdomain(tt.host)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Subdomain(%q) = %q, %v, want %q, %v", tt.host, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOrgDomains_Middleware(t *testing.T) {
+	ctx := context.Background()
+	orgs := memstore.NewOrganizationStore(memstore.NewUserStore())
+	memberID, outsiderID := uuid.New(), uuid.New()
+	org, _ := orgs.Create(ctx, "Acme", memberID)
+	subdomain := "acme"
+	if _, err := orgs.SetSubdomain(ctx, org.ID, &subdomain); err != nil {
+		t.Fatal(err)
+	}
+
+	jwtManager := NewJWTManager("test-secret-key-at-least-32-characters-long")
+	domains := NewOrgDomains("analyzer.example.com", orgs)
+	var gotOrg uuid.UUID
+	handler := domains.Middleware(Middleware(jwtManager, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrg, _ = GetHostOrgFromContext(r.Context())
+	})))
+
+	tests := []struct {
+		name       string
+		host       string
+		userID     uuid.UUID
+		wantStatus int
+		wantOrg    uuid.UUID
+	}{
+		{"member on the subdomain", "acme.analyzer.example.com", memberID, http.StatusOK, org.ID},
+		{"outsider on the subdomain", "acme.analyzer.example.com", outsiderID, http.StatusForbidden, uuid.Nil},
+		{"unknown subdomain", "globex.analyzer.example.com", memberID, http.StatusNotFound, uuid.Nil},
+		{"outsider on the API host", "analyzer.example.com", outsiderID, http.StatusOK, uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOrg = uuid.Nil
+			tokens, _ := jwtManager.GenerateTokenPair(tt.userID, "user@example.com")
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = tt.host
+			req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if gotOrg != tt.wantOrg {
+				t.Errorf("host org = %s, want %s", gotOrg, tt.wantOrg)
+			}
+		})
+	}
+}
+
+func TestOrgDomains_AllowOrigin(t *testing.T) {
+	ctx := context.Background()
+	orgs := memstore.NewOrganizationStore(memstore.NewUserStore())
+	for _, subdomain := range []string{"acme", "globex"} {
+		org, _ := orgs.Create(ctx, subdomain, uuid.New())
+		orgs.SetSubdomain(ctx, org.ID, &subdomain)
+	}
+	domains := NewOrgDomains("analyzer.example.com", orgs)
+
+	tests := []struct {
+		host   string
+		origin string
+		want   bool
+	}{
+		{"analyzer.example.com", "https://acme.analyzer.example.com", true},
+		{"analyzer.example.com", "https://initech.analyzer.example.com", false},
+		{"analyzer.example.com", "http://acme.analyzer.example.com", false},
+		{"analyzer.example.com", "https://evil.example.com", false},
+		{"acme.analyzer.example.com", "https://acme.analyzer.example.com", true},
+		{"acme.analyzer.example.com", "https://globex.analyzer.example.com", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tt.host
+		if got := domains.AllowOrigin(req, tt.origin); got != tt.want {
+			t.Errorf("AllowOrigin(%s, %s) = %v, want %v", tt.host, tt.origin, got, tt.want)
+		}
+	}
+}
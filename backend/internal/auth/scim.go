@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/scim"
+)
+
+// SCIMOrgKey is the context key for the organization a SCIM request
+// provisions
+const SCIMOrgKey ContextKey = "scim_org_id"
+
+// SCIMAuthenticator looks up the organization a SCIM token belongs to
+type SCIMAuthenticator interface {
+	Authenticate(ctx context.Context, token string) (uuid.UUID, error)
+}
+
+// SCIMMiddleware authenticates identity providers by the organization's
+// SCIM bearer token. Errors are written as SCIM errors.
+func SCIMMiddleware(tokens SCIMAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				scim.WriteError(w, http.StatusUnauthorized, "", "Missing bearer token")
+				return
+			}
+
+			orgID, err := tokens.Authenticate(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					scim.WriteError(w, http.StatusUnauthorized, "", "Invalid or revoked SCIM token")
+					return
+				}
+				slog.Error("Failed to authenticate SCIM token", "error", err)
+				scim.WriteError(w, http.StatusInternalServerError, "", "Failed to authenticate SCIM token")
+				return
+			}
+
+			// A token only provisions its organization, on its subdomain
+			if hostOrgID, ok := GetHostOrgFromContext(r.Context()); ok && hostOrgID != orgID {
+				scim.WriteError(w, http.StatusUnauthorized, "", "Invalid or revoked SCIM token")
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), SCIMOrgKey, orgID)))
+		})
+	}
+}
+
+// GetSCIMOrgFromContext extracts the organization a SCIM request
+// provisions from the request context
+func GetSCIMOrgFromContext(ctx context.Context) (uuid.UUID, error) {
+	orgID, ok := ctx.Value(SCIMOrgKey).(uuid.UUID)
+	if !ok {
+		return uuid.Nil, http.ErrNoCookie
+	}
+	return orgID, nil
+}
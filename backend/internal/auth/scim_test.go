@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestSCIMMiddleware(t *testing.T) {
+	ctx := context.Background()
+	orgID := uuid.New()
+	tokens := memstore.NewSCIMStore(memstore.NewUserStore())
+
+	replaced, err := tokens.CreateToken(ctx, orgID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _ := tokens.CreateToken(ctx, orgID)
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+	}{
+		{name: "valid token", header: "Bearer " + token, wantStatus: http.StatusOK},
+		{name: "missing header", wantStatus: http.StatusUnauthorized},
+		{name: "not a bearer token", header: "Basic " + token, wantStatus: http.StatusUnauthorized},
+		{name: "replaced token", header: "Bearer " + replaced, wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", header: "Bearer ca_scim_nope", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOrgID uuid.UUID
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotOrgID, _ = GetSCIMOrgFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			SCIMMiddleware(tokens)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SCIMMiddleware() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusOK && gotOrgID != orgID {
+				t.Errorf("SCIMMiddleware() org ID = %s, want %s", gotOrgID, orgID)
+			}
+		})
+	}
+}
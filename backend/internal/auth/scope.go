@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// Scopes a third-party integration can be granted. Tokens and API keys
+// without scopes, such as a user's own session, may do anything.
+const (
+	ScopeSubmissionsRead  = "submissions:read"
+	ScopeSubmissionsWrite = "submissions:write"
+	ScopeAnalyticsRead    = "analytics:read"
+	ScopeAnalyze          = "analyze"
+	ScopeHooks            = "hooks"
+)
+
+// ScopeAppeal limits the token a suspended user is given at sign-in to
+// appealing. It is never granted to integrations, so it isn't in AllScopes.
+const ScopeAppeal = "appeal"
+
+// AllScopes lists every scope
+var AllScopes = []string{ScopeSubmissionsRead, ScopeSubmissionsWrite, ScopeAnalyticsRead, ScopeAnalyze, ScopeHooks}
+
+// ScopesKey is the context key for the scopes of a scoped token or API key
+const ScopesKey ContextKey = "scopes"
+
+// ValidScope reports whether scope is a known scope
+func ValidScope(scope string) bool {
+	return slices.Contains(AllScopes, scope)
+}
+
+// withScopes records the scopes a request is limited to; nil leaves it
+// unlimited
+func withScopes(ctx context.Context, scopes []string) context.Context {
+	if scopes == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, ScopesKey, scopes)
+}
+
+// GetScopesFromContext returns the scopes the request is limited to, and
+// false when it isn't limited
+func GetScopesFromContext(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(ScopesKey).([]string)
+	return scopes, ok
+}
+
+// HasScope reports whether the request may use scope
+func HasScope(ctx context.Context, scope string) bool {
+	scopes, limited := GetScopesFromContext(ctx)
+	return !limited || slices.Contains(scopes, scope)
+}
+
+// RequireScope only lets through requests that may use scope. It must run
+// after ScopedMiddleware or APIKeyMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !HasScope(r.Context(), scope) {
+				response.Forbidden(w, "This token is missing the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScopeByMethod is RequireScope with read for GET, HEAD and
+// OPTIONS requests and write for the rest
+func RequireScopeByMethod(read, write string) func(http.Handler) http.Handler {
+	readOnly, readWrite := RequireScope(read), RequireScope(write)
+	return func(next http.Handler) http.Handler {
+		reads, writes := readOnly(next), readWrite(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				reads.ServeHTTP(w, r)
+			default:
+				writes.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// appealOnly reports whether scopes are those of an appeal token
+func appealOnly(scopes []string) bool {
+	return len(scopes) == 1 && scopes[0] == ScopeAppeal
+}
+
+// parseScope splits a space-separated scope claim
+func parseScope(scope string) []string {
+	return strings.Fields(scope)
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequireScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		scopes     []string
+		method     string
+		wantStatus int
+	}{
+		{name: "unlimited", method: http.MethodPost, wantStatus: http.StatusOK},
+		{name: "read with read scope", scopes: []string{ScopeSubmissionsRead}, method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "write with read scope", scopes: []string{ScopeSubmissionsRead}, method: http.MethodPost, wantStatus: http.StatusForbidden},
+		{name: "write with write scope", scopes: []string{ScopeSubmissionsWrite}, method: http.MethodDelete, wantStatus: http.StatusOK},
+		{name: "read with write scope", scopes: []string{ScopeSubmissionsWrite}, method: http.MethodGet, wantStatus: http.StatusForbidden},
+		{name: "no matching scope", scopes: []string{ScopeAnalyze}, method: http.MethodGet, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			req = req.WithContext(withScopes(req.Context(), tt.scopes))
+
+			rec := httptest.NewRecorder()
+			RequireScopeByMethod(ScopeSubmissionsRead, ScopeSubmissionsWrite)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("RequireScopeByMethod() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestScopedMiddleware(t *testing.T) {
+	jwtManager := NewJWTManager("test-secret-key-at-least-32-characters-long")
+	userID := uuid.New()
+
+	session, err := jwtManager.GenerateTokenPair(userID, "test@example.com")
+	if err != nil {
+		t.Fatalf("GenerateTokenPair() error = %v", err)
+	}
+	scoped, err := jwtManager.GenerateScopedToken(userID, "zapier", []string{ScopeAnalyticsRead})
+	if err != nil {
+		t.Fatalf("GenerateScopedToken() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		middleware func(*JWTManager, RevocationChecker) func(http.Handler) http.Handler
+		token      string
+		wantStatus int
+		wantScopes []string
+	}{
+		{name: "session on scoped route", middleware: ScopedMiddleware, token: session.AccessToken, wantStatus: http.StatusOK},
+		{name: "scoped token on scoped route", middleware: ScopedMiddleware, token: scoped.AccessToken, wantStatus: http.StatusOK, wantScopes: []string{ScopeAnalyticsRead}},
+		{name: "scoped token elsewhere", middleware: Middleware, token: scoped.AccessToken, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotScopes []string
+			var limited bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotScopes, limited = GetScopesFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rec := httptest.NewRecorder()
+			tt.middleware(jwtManager, nil)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if limited != (tt.wantScopes != nil) || len(gotScopes) != len(tt.wantScopes) {
+				t.Errorf("scopes = %v (limited %v), want %v", gotScopes, limited, tt.wantScopes)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+)
+
+// revocationTTL outlives every token we issue, after which the cutoff is moot
+const revocationTTL = 7 * 24 * time.Hour
+
+// RevocationChecker reports whether a token's session has been revoked,
+// and whether its account is suspended or banned
+type RevocationChecker interface {
+	IsRevoked(ctx context.Context, claims *Claims) (bool, error)
+	StandingChecker
+}
+
+// DeviceSessionRevoker ends remember-me sessions in the database;
+// *models.DeviceSessionStore implements it
+type DeviceSessionRevoker interface {
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+// Sessions revokes a user's sessions. JWTs can't be recalled, so revoking
+// records a cutoff in Redis and tokens issued before it are rejected.
+type Sessions struct {
+	cache *cache.Cache
+	// users holds account statuses, read when Redis has lost one or
+	// can't be reached
+	users AccountStatusReader
+	// devices holds remember-me sessions, which outlive the cutoff
+	devices DeviceSessionRevoker
+}
+
+// NewSessions creates a session revocation store
+func NewSessions(cache *cache.Cache) *Sessions {
+	return &Sessions{cache: cache}
+}
+
+// WithUsers reads account statuses from users whenever Redis doesn't have
+// them, and returns the store. Without it a suspension lost from Redis
+// isn't enforced.
+func (s *Sessions) WithUsers(users AccountStatusReader) *Sessions {
+	s.users = users
+	return s
+}
+
+// WithDevices ends the user's remember-me sessions whenever all their
+// sessions are revoked, and returns the store. Without it a device cookie
+// works again once the cutoff has expired.
+func (s *Sessions) WithDevices(devices DeviceSessionRevoker) *Sessions {
+	s.devices = devices
+	return s
+}
+
+// RevokeAll invalidates every token issued to the user so far, and ends
+// their remember-me sessions. Tokens issued afterwards, such as the one
+// returned to the caller, stay valid.
+func (s *Sessions) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	if s.devices != nil {
+		if err := s.devices.RevokeAll(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	cutoff := strconv.FormatInt(time.Now().Unix(), 10)
+
+	if err := s.cache.Set(ctx, revocationKey(userID), cutoff, revocationTTL); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeDevice invalidates every token issued from a remember-me session
+func (s *Sessions) RevokeDevice(ctx context.Context, sessionID uuid.UUID) error {
+	if err := s.cache.Set(ctx, deviceRevocationKey(sessionID.String()), "1", revocationTTL); err != nil {
+		return fmt.Errorf("failed to revoke device session: %w", err)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the token was issued before the user's last
+// revocation, or from a remember-me session since revoked
+func (s *Sessions) IsRevoked(ctx context.Context, claims *Claims) (bool, error) {
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := s.RevokedSince(ctx, claims.UserID, issuedAt)
+	if err != nil || revoked || claims.SessionID == "" {
+		return revoked, err
+	}
+
+	if _, err := s.cache.Get(ctx, deviceRevocationKey(claims.SessionID)); err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check device session revocation: %w", err)
+	}
+	return true, nil
+}
+
+// RevokedSince reports whether the user revoked their sessions after
+// something, such as a token, was issued to them at issuedAt. A zero
+// issuedAt counts as revoked once there has been any revocation.
+func (s *Sessions) RevokedSince(ctx context.Context, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	value, err := s.cache.Get(ctx, revocationKey(userID))
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check session revocation: %w", err)
+	}
+
+	cutoff, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid revocation cutoff %q: %w", value, err)
+	}
+
+	// IssuedAt has second precision, so a token issued in the same second
+	// as the revocation is treated as issued after it
+	return issuedAt.IsZero() || issuedAt.Unix() < cutoff, nil
+}
+
+// revocationKey is the Redis key holding a user's revocation cutoff
+func revocationKey(userID uuid.UUID) string {
+	return "auth:revoked_before:" + userID.String()
+}
+
+// deviceRevocationKey is the Redis key marking a remember-me session
+// revoked
+func deviceRevocationKey(sessionID string) string {
+	return "auth:revoked_device:" + sessionID
+}
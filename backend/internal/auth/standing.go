@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// Standing is why an account can't be used: it is suspended or banned
+type Standing struct {
+	Status models.AccountStatus `json:"status"`
+	Reason string               `json:"reason,omitempty"`
+}
+
+// standingTTL is how long an account read from the database to be in
+// good standing is remembered in Redis. Suspending it overwrites the entry
+// at once.
+const standingTTL = 5 * time.Minute
+
+// AccountStatusReader reads users' account statuses from the database
+type AccountStatusReader interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// StandingChecker reports whether a user's account is suspended or
+// banned, returning nil when it is in good standing
+type StandingChecker interface {
+	Standing(ctx context.Context, userID uuid.UUID) (*Standing, error)
+}
+
+// StandingResponse is the 403 returned for suspended and banned accounts
+type StandingResponse struct {
+	Error  string               `json:"error"`
+	Status models.AccountStatus `json:"status"`
+	Reason string               `json:"reason,omitempty"`
+	// Appealable is set for suspensions, which the user can appeal
+	Appealable bool `json:"appealable"`
+	// AppealToken is given to suspended users signing in; it can only be
+	// used to appeal
+	AppealToken *TokenPair `json:"appeal_token,omitempty"`
+}
+
+// RefuseStanding writes the 403 for a suspended or banned account
+func RefuseStanding(w http.ResponseWriter, standing *Standing) {
+	RefuseSignIn(w, standing, nil)
+}
+
+// RefuseSignIn writes the 403 for a suspended or banned account signing
+// in, with the appeal token a suspended one is given
+func RefuseSignIn(w http.ResponseWriter, standing *Standing, appealToken *TokenPair) {
+	message := "Your account has been suspended"
+	if standing.Status == models.StatusBanned {
+		message = "Your account has been banned"
+	}
+	response.JSON(w, http.StatusForbidden, StandingResponse{
+		Error:       response.Translate(w, message),
+		Status:      standing.Status,
+		Reason:      standing.Reason,
+		Appealable:  standing.Status == models.StatusSuspended,
+		AppealToken: appealToken,
+	})
+}
+
+// SetStanding records the user's account status, which the database
+// holds, for the middleware to enforce on every request. Suspensions and
+// bans are kept until the user is reinstated; should Redis lose them,
+// Standing reads them back from the database.
+func (s *Sessions) SetStanding(ctx context.Context, userID uuid.UUID, status models.AccountStatus, reason string) error {
+	if status == models.StatusActive {
+		if err := s.cache.Delete(ctx, standingKey(userID)); err != nil {
+			return fmt.Errorf("failed to reinstate account: %w", err)
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(Standing{Status: status, Reason: reason})
+	if err != nil {
+		return fmt.Errorf("failed to encode account status: %w", err)
+	}
+	if err := s.cache.Set(ctx, standingKey(userID), value, 0); err != nil {
+		return fmt.Errorf("failed to record account status: %w", err)
+	}
+	return nil
+}
+
+// Standing returns the user's suspension or ban, or nil. Redis holds
+// statuses for the middleware; when it has lost one or can't be reached,
+// the database is read instead, and a missing status is put back.
+func (s *Sessions) Standing(ctx context.Context, userID uuid.UUID) (*Standing, error) {
+	value, err := s.cache.Get(ctx, standingKey(userID))
+	if err == nil {
+		return decodeStanding(value)
+	}
+	missing := errors.Is(err, cache.ErrNotFound)
+	if s.users == nil {
+		if missing {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check account status: %w", err)
+	}
+
+	user, dbErr := s.users.GetByID(ctx, userID)
+	if dbErr != nil {
+		if errors.Is(dbErr, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check account status: %w", errors.Join(err, dbErr))
+	}
+
+	standing := &Standing{Status: user.Status}
+	if user.StatusReason != nil {
+		standing.Reason = *user.StatusReason
+	}
+	if missing {
+		s.rememberStanding(ctx, userID, standing)
+	}
+	if standing.Status == models.StatusActive {
+		return nil, nil
+	}
+	return standing, nil
+}
+
+// rememberStanding puts a status read from the database back in Redis.
+// Accounts in good standing are remembered for standingTTL, so their
+// requests don't all read the database; suspensions and bans are kept
+// like SetStanding keeps them. Failures are only logged, as the next
+// request reads the database again.
+func (s *Sessions) rememberStanding(ctx context.Context, userID uuid.UUID, standing *Standing) {
+	ttl := time.Duration(0)
+	if standing.Status == models.StatusActive {
+		ttl = standingTTL
+	}
+	value, err := json.Marshal(standing)
+	if err == nil {
+		err = s.cache.Set(ctx, standingKey(userID), value, ttl)
+	}
+	if err != nil && !errors.Is(err, cache.ErrUnavailable) {
+		slog.Warn("Failed to restore account status", "user_id", userID, "error", err)
+	}
+}
+
+// decodeStanding reads a status held in Redis, returning nil for an
+// account in good standing
+func decodeStanding(value string) (*Standing, error) {
+	var standing Standing
+	if err := json.Unmarshal([]byte(value), &standing); err != nil {
+		return nil, fmt.Errorf("invalid account status %q: %w", value, err)
+	}
+	if standing.Status == models.StatusActive {
+		return nil, nil
+	}
+	return &standing, nil
+}
+
+// standingKey is the Redis key holding a suspended or banned user's
+// account status
+func standingKey(userID uuid.UUID) string {
+	return "auth:standing:" + userID.String()
+}
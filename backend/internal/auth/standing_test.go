@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// fakeUsers holds account statuses as the database would
+type fakeUsers map[uuid.UUID]*models.User
+
+func (f fakeUsers) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	user, ok := f[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return user, nil
+}
+
+func TestSessions_StandingWithoutRedis(t *testing.T) {
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c, err := cache.NewLazy("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	reason := "Spam"
+	suspended := &models.User{ID: uuid.New(), Status: models.StatusSuspended, StatusReason: &reason}
+	active := &models.User{ID: uuid.New(), Status: models.StatusActive}
+	users := fakeUsers{suspended.ID: suspended, active.ID: active}
+	ctx := context.Background()
+
+	// Without the database, nothing can be read
+	if _, err := NewSessions(c).Standing(ctx, suspended.ID); err == nil {
+		t.Error("Standing() without Redis or the database succeeded")
+	}
+
+	sessions := NewSessions(c).WithUsers(users)
+	standing, err := sessions.Standing(ctx, suspended.ID)
+	if err != nil || standing == nil || standing.Status != models.StatusSuspended || standing.Reason != reason {
+		t.Errorf("Standing() of a suspended user = %+v, %v, want the suspension", standing, err)
+	}
+	if standing, err := sessions.Standing(ctx, active.ID); err != nil || standing != nil {
+		t.Errorf("Standing() of an active user = %+v, %v, want nil", standing, err)
+	}
+	if standing, err := sessions.Standing(ctx, uuid.New()); err != nil || standing != nil {
+		t.Errorf("Standing() of an unknown user = %+v, %v, want nil", standing, err)
+	}
+}
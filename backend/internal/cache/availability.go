@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrUnavailable is returned without contacting Redis while Monitor has
+// found it down, so callers degrade at once instead of waiting on
+// timeouts
+var ErrUnavailable = errors.New("redis is unavailable")
+
+// availabilityCheckTimeout bounds each check Monitor makes
+const availabilityCheckTimeout = 2 * time.Second
+
+// availability is whether Redis answered the last check. It is shared by
+// a cache and its tiered views.
+type availability struct {
+	up atomic.Bool
+}
+
+func newAvailability() *availability {
+	a := &availability{}
+	a.up.Store(true)
+	return a
+}
+
+// Available reports whether Redis answered the last check. It is true
+// until Monitor finds otherwise.
+func (c *Cache) Available() bool {
+	return c.availability.up.Load()
+}
+
+// Monitor checks Redis every interval until ctx is done. While it is down,
+// commands fail fast with ErrUnavailable, except PING, which is how it is
+// seen coming back.
+func (c *Cache) Monitor(ctx context.Context, interval time.Duration) {
+	c.check(ctx)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check(ctx)
+		}
+	}
+}
+
+// check pings Redis and records whether it answered
+func (c *Cache) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, availabilityCheckTimeout)
+	defer cancel()
+
+	err := c.client.Ping(ctx).Err()
+	up := err == nil
+	if c.availability.up.Swap(up) == up {
+		return
+	}
+	if up {
+		slog.Info("Redis is back; caching, rate limits and the job queue resume")
+	} else {
+		slog.Warn("Redis is down; running degraded without caching, rate limits or the job queue", "error", err)
+	}
+}
+
+// availabilityHook fails commands with ErrUnavailable while Redis is down
+type availabilityHook struct {
+	availability *availability
+}
+
+func (h availabilityHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h availabilityHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if !h.availability.up.Load() && cmd.Name() != "ping" {
+			cmd.SetErr(ErrUnavailable)
+			return ErrUnavailable
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h availabilityHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if !h.availability.up.Load() {
+			for _, cmd := range cmds {
+				cmd.SetErr(ErrUnavailable)
+			}
+			return ErrUnavailable
+		}
+		return next(ctx, cmds)
+	}
+}
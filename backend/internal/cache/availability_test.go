@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestCache_Unavailable(t *testing.T) {
+	// A port nothing listens on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	c, err := NewLazy("redis://" + addr)
+	if err != nil {
+		t.Fatalf("NewLazy() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	view := c.Tiered(10, 1)
+
+	if !c.Available() {
+		t.Fatal("Available() = false before any check")
+	}
+
+	ctx := context.Background()
+	c.check(ctx)
+	if c.Available() || view.Available() {
+		t.Fatal("Available() = true after a failed check")
+	}
+
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Get() error = %v, want ErrUnavailable", err)
+	}
+	if _, err := c.MGet(ctx, "a", "b"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("MGet() error = %v, want ErrUnavailable", err)
+	}
+}
@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// MSet sets several keys with the same TTL in one round trip. Unlike
+// Redis's MSET, each key gets the TTL; zero means no expiry.
+func (c *Cache) MSet(ctx context.Context, values map[string]interface{}, ttl time.Duration) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	err := resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for key, value := range values {
+				pipe.Set(ctx, key, value, ttl)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set %d keys: %w", len(values), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	c.invalidate(ctx, keys...)
+	return nil
+}
+
+// MGet reads several keys in one round trip. Missing keys are left out
+// of the result. It always reads Redis, even through a tiered view.
+func (c *Cache) MGet(ctx context.Context, keys ...string) (map[string]string, error) {
+	if len(keys) == 0 {
+		return map[string]string{}, nil
+	}
+
+	values, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) ([]interface{}, error) {
+		return c.client.MGet(ctx, keys...).Result()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %d keys: %w", len(keys), err)
+	}
+
+	found := make(map[string]string, len(keys))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			found[keys[i]] = s
+		}
+	}
+
+	return found, nil
+}
+
+// MDelete deletes several keys in one round trip
+func (c *Cache) MDelete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	err := resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		return c.client.Del(ctx, keys...).Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, keys...)
+	return nil
+}
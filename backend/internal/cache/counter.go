@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// incrScript adds to a counter and starts its TTL when the counter is
+// new, so a window isn't pushed back by every increment
+var incrScript = redis.NewScript(`
+local n = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) < 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return n`)
+
+// Incr adds one to the counter at key and returns the new value. A new
+// counter expires after ttl, which makes it a fixed window for rate
+// limits and quotas; zero means no expiry.
+func (c *Cache) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(ctx, key, 1, ttl)
+}
+
+// IncrBy adds n to the counter at key, as Incr does
+func (c *Cache) IncrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	// Retrying after an ambiguous failure could count twice
+	value, err := resilience.Value(ctx, resilience.RedisWrites, func(ctx context.Context) (int64, error) {
+		return incrScript.Run(ctx, c.client, []string{key}, n, ttl.Milliseconds()).Int64()
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// Decr subtracts one from the counter at key, as Incr does
+func (c *Cache) Decr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return c.IncrBy(ctx, key, -1, ttl)
+}
+
+// DecrBy subtracts n from the counter at key, as Incr does
+func (c *Cache) DecrBy(ctx context.Context, key string, n int64, ttl time.Duration) (int64, error) {
+	return c.IncrBy(ctx, key, -n, ttl)
+}
+
+// TTL returns how long until key expires. It returns ErrNotFound for a
+// missing key and a negative duration for a key without expiry.
+func (c *Cache) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (time.Duration, error) {
+		return c.client.PTTL(ctx, key).Result()
+	})
+	if err != nil {
+		return 0, err
+	}
+	// PTTL replies -2 for a missing key, which go-redis passes through
+	// as a duration of -2ns
+	if ttl == -2 {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return ttl, nil
+}
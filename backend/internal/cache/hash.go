@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// HSet sets fields of the hash at key and, if ttl is positive, resets
+// the hash's expiry
+func (c *Cache) HSet(ctx context.Context, key string, fields map[string]interface{}, ttl time.Duration) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, key, fields)
+			if ttl > 0 {
+				pipe.Expire(ctx, key, ttl)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set hash %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// HGet reads one field of the hash at key, or returns ErrNotFound
+func (c *Cache) HGet(ctx context.Context, key, field string) (string, error) {
+	value, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (string, error) {
+		return c.client.HGet(ctx, key, field).Result()
+	})
+	if err == redis.Nil {
+		return "", fmt.Errorf("%w: %s[%s]", ErrNotFound, key, field)
+	}
+	return value, err
+}
+
+// HGetAll reads every field of the hash at key; a missing hash is empty
+func (c *Cache) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (map[string]string, error) {
+		return c.client.HGetAll(ctx, key).Result()
+	})
+}
+
+// HIncrBy adds n to a numeric field of the hash at key and returns the
+// new value
+func (c *Cache) HIncrBy(ctx context.Context, key, field string, n int64) (int64, error) {
+	value, err := resilience.Value(ctx, resilience.RedisWrites, func(ctx context.Context) (int64, error) {
+		return c.client.HIncrBy(ctx, key, field, n).Result()
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s[%s]: %w", key, field, err)
+	}
+	return value, nil
+}
+
+// HDel removes fields from the hash at key
+func (c *Cache) HDel(ctx context.Context, key string, fields ...string) error {
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		return c.client.HDel(ctx, key, fields...).Err()
+	})
+}
@@ -0,0 +1,180 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidationChannel carries keys written through a tiered cache so
+// every replica drops its local copy
+const invalidationChannel = "cache:invalidate"
+
+// resubscribeDelay is how long the listener waits after losing Redis
+const resubscribeDelay = time.Second
+
+// localLayer is the in-process tier of a tiered cache
+type localLayer struct {
+	entries *lru
+	// generation changes on every invalidation, so a read that raced
+	// with one doesn't cache the value it replaced
+	generation atomic.Uint64
+	// subscribed is false while invalidations may be missed, and the
+	// local tier is bypassed
+	subscribed atomic.Bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Tiered returns a view of the cache that keeps up to size recently read
+// keys in process for ttl, so hot keys skip the Redis round trip. Writes
+// through any tiered view are published so every replica drops its copy;
+// keys read through a tiered view must only be written through one.
+// It returns c itself when size or ttl is zero.
+func (c *Cache) Tiered(size int, ttl time.Duration) *Cache {
+	if size <= 0 || ttl <= 0 {
+		return c
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	local := &localLayer{
+		entries: newLRU(size, ttl),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go local.listen(ctx, c.client)
+
+	c.mu.Lock()
+	c.views = append(c.views, local)
+	c.mu.Unlock()
+
+	return &Cache{client: c.client, availability: c.availability, metrics: c.metrics, loads: c.loads, local: local}
+}
+
+// getLocal reads key from the local tier, falling back to Redis and
+// remembering the result, including a miss
+func (c *Cache) getLocal(ctx context.Context, key string) (string, error) {
+	l := c.local
+	if l.subscribed.Load() {
+		if value, found, ok := l.entries.get(key); ok {
+			c.metrics.observeLocal(true)
+			if !found {
+				return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+			}
+			return value, nil
+		}
+	}
+
+	c.metrics.observeLocal(false)
+	generation := l.generation.Load()
+	value, err := c.getRemote(ctx, key)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	if l.subscribed.Load() && l.generation.Load() == generation {
+		l.entries.set(key, value, err == nil)
+	}
+	return value, err
+}
+
+// invalidate drops keys locally and tells other replicas to do the same.
+// A failed publish is logged; their copies then expire with the TTL.
+func (c *Cache) invalidate(ctx context.Context, keys ...string) {
+	if c.local == nil || len(keys) == 0 {
+		return
+	}
+
+	c.local.forget(keys...)
+
+	_, err := c.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Publish(ctx, invalidationChannel, key)
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("Failed to publish cache invalidation", "keys", len(keys), "error", err)
+	}
+}
+
+// forget drops keys from the local tier
+func (l *localLayer) forget(keys ...string) {
+	l.generation.Add(1)
+	for _, key := range keys {
+		l.entries.remove(key)
+	}
+}
+
+// reset drops the whole local tier after invalidations may have been missed
+func (l *localLayer) reset(subscribed bool) {
+	l.subscribed.Store(subscribed)
+	l.generation.Add(1)
+	l.entries.purge()
+}
+
+// listen applies invalidations from every replica until ctx is canceled
+func (l *localLayer) listen(ctx context.Context, client *redis.Client) {
+	defer close(l.done)
+
+	pubsub := client.Subscribe(ctx, invalidationChannel)
+	defer pubsub.Close()
+
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			l.reset(false)
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.Warn("Cache invalidation subscription lost, bypassing local cache", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(resubscribeDelay):
+			}
+			continue
+		}
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			// Subscribed or resubscribed: anything published in between
+			// was missed, so start from empty
+			l.reset(true)
+		case *redis.Message:
+			l.forget(msg.Payload)
+		}
+	}
+}
+
+// stop ends the listener and waits for it to exit
+func (l *localLayer) stop() {
+	l.cancel()
+	<-l.done
+}
+
+// stopViews ends the listeners of every tiered view of c
+func (c *Cache) stopViews() {
+	c.mu.Lock()
+	views := c.views
+	c.views = nil
+	c.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, view := range views {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			view.stop()
+		}()
+	}
+	wg.Wait()
+}
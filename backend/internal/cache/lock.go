@@ -0,0 +1,206 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lockPrefix = "lock:"
+
+	// lockRetryInterval is how often a blocking Acquire polls a held lock
+	lockRetryInterval = 250 * time.Millisecond
+)
+
+var (
+	// ErrLockHeld is returned when another holder owns the lock
+	ErrLockHeld = errors.New("lock is held by another owner")
+	// ErrLockLost is returned when the lock expired or was taken over
+	// before it was renewed or released
+	ErrLockLost = errors.New("lock was lost")
+)
+
+// Renewing and releasing only touch the key while it still holds our
+// token, so a holder whose lock expired can't extend or delete its
+// successor's lock
+var (
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Locker hands out locks that are exclusive across every replica sharing
+// the Redis instance
+type Locker struct {
+	client *redis.Client
+}
+
+// NewLocker creates a locker on the given client
+func NewLocker(client *redis.Client) *Locker {
+	return &Locker{client: client}
+}
+
+// Locker returns a locker backed by this cache
+func (c *Cache) Locker() *Locker {
+	return NewLocker(c.client)
+}
+
+// Lock is a held lock. It expires after its TTL unless renewed.
+type Lock struct {
+	client *redis.Client
+	key    string
+	token  string
+	ttl    time.Duration
+}
+
+// TryAcquire takes the lock named key for ttl, or returns ErrLockHeld
+func (l *Locker) TryAcquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	token := uuid.NewString()
+
+	ok, err := l.client.SetNX(ctx, lockPrefix+key, token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return nil, ErrLockHeld
+	}
+
+	return &Lock{client: l.client, key: lockPrefix + key, token: token, ttl: ttl}, nil
+}
+
+// Acquire waits until the lock named key is free and takes it for ttl.
+// It gives up with ctx's error when ctx is done.
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (*Lock, error) {
+	ticker := time.NewTicker(lockRetryInterval)
+	defer ticker.Stop()
+
+	for {
+		lock, err := l.TryAcquire(ctx, key, ttl)
+		if !errors.Is(err, ErrLockHeld) {
+			return lock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Claim takes the lock named key and lets it expire after ttl instead of
+// releasing it, so work done once per period runs on only one replica.
+// It reports whether this caller won the claim.
+func (l *Locker) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	_, err := l.TryAcquire(ctx, key, ttl)
+	switch {
+	case errors.Is(err, ErrLockHeld):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return true, nil
+}
+
+// WithLock runs fn while holding the lock named key, waiting for it if
+// needed. The lock is renewed every third of ttl; if a renewal finds it
+// lost, fn's context is canceled. The lock is released when fn returns.
+func (l *Locker) WithLock(ctx context.Context, key string, ttl time.Duration, fn func(ctx context.Context) error) error {
+	lock, err := l.Acquire(ctx, key, ttl)
+	if err != nil {
+		return err
+	}
+
+	fnCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		lock.keepAlive(fnCtx, cancel)
+	}()
+
+	fnErr := fn(fnCtx)
+	cancel()
+	<-renewed
+
+	// Release even if ctx is done so the next holder needn't wait out the TTL
+	releaseCtx, releaseCancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer releaseCancel()
+	if err := lock.Release(releaseCtx); err != nil && !errors.Is(err, ErrLockLost) {
+		slog.Warn("Failed to release lock", "key", key, "error", err)
+	}
+
+	return fnErr
+}
+
+// keepAlive renews the lock until ctx is done, calling lost if it can't
+func (lk *Lock) keepAlive(ctx context.Context, lost context.CancelFunc) {
+	ticker := time.NewTicker(lk.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := lk.Renew(ctx)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+			if errors.Is(err, ErrLockLost) {
+				slog.Error("Lock lost while held", "key", lk.key)
+				lost()
+				return
+			}
+			// A failed round trip leaves the lock in place until its TTL,
+			// so try again on the next tick
+			slog.Warn("Failed to renew lock", "key", lk.key, "error", err)
+		}
+	}
+}
+
+// Key returns the Redis key of the lock
+func (lk *Lock) Key() string {
+	return lk.key
+}
+
+// Renew resets the lock's TTL, or returns ErrLockLost if it is no longer
+// held by this owner
+func (lk *Lock) Renew(ctx context.Context) error {
+	n, err := renewScript.Run(ctx, lk.client, []string{lk.key}, lk.token, lk.ttl.Milliseconds()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to renew lock: %w", err)
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release frees the lock, or returns ErrLockLost if it is no longer held
+// by this owner
+func (lk *Lock) Release(ctx context.Context) error {
+	n, err := releaseScript.Run(ctx, lk.client, []string{lk.key}, lk.token).Int()
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	if n == 0 {
+		return ErrLockLost
+	}
+	return nil
+}
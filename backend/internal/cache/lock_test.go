@@ -0,0 +1,114 @@
+//go:build integration
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+)
+
+func newLocker(t *testing.T) *cache.Locker {
+	t.Helper()
+	return newCache(t).Locker()
+}
+
+func TestLocker_TryAcquire(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	lock, err := locker.TryAcquire(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+
+	if _, err := locker.TryAcquire(ctx, "job", time.Minute); !errors.Is(err, cache.ErrLockHeld) {
+		t.Errorf("second TryAcquire() error = %v, want ErrLockHeld", err)
+	}
+	if err := lock.Renew(ctx); err != nil {
+		t.Errorf("Renew() error = %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if err := lock.Release(ctx); !errors.Is(err, cache.ErrLockLost) {
+		t.Errorf("second Release() error = %v, want ErrLockLost", err)
+	}
+
+	if _, err := locker.TryAcquire(ctx, "job", time.Minute); err != nil {
+		t.Errorf("TryAcquire() after release error = %v", err)
+	}
+}
+
+func TestLocker_ExpiredLockIsNotReleasedBySuccessor(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	stale, err := locker.TryAcquire(ctx, "job", 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("TryAcquire() error = %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	if _, err := locker.TryAcquire(ctx, "job", time.Minute); err != nil {
+		t.Fatalf("TryAcquire() after expiry error = %v", err)
+	}
+
+	if err := stale.Renew(ctx); !errors.Is(err, cache.ErrLockLost) {
+		t.Errorf("stale Renew() error = %v, want ErrLockLost", err)
+	}
+	if err := stale.Release(ctx); !errors.Is(err, cache.ErrLockLost) {
+		t.Errorf("stale Release() error = %v, want ErrLockLost", err)
+	}
+	if _, err := locker.TryAcquire(ctx, "job", time.Minute); !errors.Is(err, cache.ErrLockHeld) {
+		t.Errorf("TryAcquire() error = %v, want the successor's lock to survive", err)
+	}
+}
+
+func TestLocker_WithLockSerializes(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	var running, overlaps int32
+	done := make(chan error, 3)
+	for range 3 {
+		go func() {
+			done <- locker.WithLock(ctx, "migrations", 300*time.Millisecond, func(ctx context.Context) error {
+				if atomic.AddInt32(&running, 1) > 1 {
+					atomic.AddInt32(&overlaps, 1)
+				}
+				// Outlive the TTL so the lock has to be renewed
+				time.Sleep(500 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return ctx.Err()
+			})
+		}()
+	}
+
+	for range 3 {
+		if err := <-done; err != nil {
+			t.Errorf("WithLock() error = %v", err)
+		}
+	}
+	if overlaps != 0 {
+		t.Errorf("overlapping holders = %d, want 0", overlaps)
+	}
+}
+
+func TestLocker_Claim(t *testing.T) {
+	ctx := context.Background()
+	locker := newLocker(t)
+
+	first, err := locker.Claim(ctx, "scheduler:digest:1", time.Minute)
+	if err != nil || !first {
+		t.Fatalf("Claim() = %v, %v, want true", first, err)
+	}
+	second, err := locker.Claim(ctx, "scheduler:digest:1", time.Minute)
+	if err != nil || second {
+		t.Errorf("second Claim() = %v, %v, want false", second, err)
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lru is a fixed-size, least-recently-used map whose entries expire
+// after a TTL. It remembers misses too, so absent keys that are checked
+// on every request (such as session revocations) also skip Redis.
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	items    map[string]*list.Element
+	order    *list.List // front is most recently used
+
+	// now returns the current time; tests replace it
+	now func() time.Time
+}
+
+type lruEntry struct {
+	key     string
+	value   string
+	found   bool
+	expires time.Time
+}
+
+func newLRU(capacity int, ttl time.Duration) *lru {
+	return &lru{
+		capacity: capacity,
+		ttl:      ttl,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached value and whether it exists in Redis; ok is
+// false when the key isn't cached or has expired
+func (c *lru) get(key string) (value string, found, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, exists := c.items[key]
+	if !exists {
+		return "", false, false
+	}
+
+	entry := elem.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.removeElement(elem)
+		return "", false, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, entry.found, true
+}
+
+// set caches a value, or a miss when found is false, evicting the least
+// recently used entry when full
+func (c *lru) set(key, value string, found bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if elem, exists := c.items[key]; exists {
+		*elem.Value.(*lruEntry) = lruEntry{key: key, value: value, found: found, expires: expires}
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	if c.order.Len() >= c.capacity {
+		c.removeElement(c.order.Back())
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, found: found, expires: expires})
+}
+
+// remove drops a key
+func (c *lru) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, exists := c.items[key]; exists {
+		c.removeElement(elem)
+	}
+}
+
+// purge drops every entry
+func (c *lru) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.items)
+	c.order.Init()
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// removeElement unlinks an entry; callers hold mu
+func (c *lru) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*lruEntry).key)
+}
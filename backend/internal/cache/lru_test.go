@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRU_Eviction(t *testing.T) {
+	c := newLRU(2, time.Minute)
+
+	c.set("a", "1", true)
+	c.set("b", "2", true)
+	// Reading a makes b the least recently used
+	if _, _, ok := c.get("a"); !ok {
+		t.Fatal("get(a) missed")
+	}
+	c.set("c", "3", true)
+
+	tests := []struct {
+		key    string
+		wantOK bool
+	}{
+		{"a", true},
+		{"b", false},
+		{"c", true},
+	}
+	for _, tt := range tests {
+		if _, _, ok := c.get(tt.key); ok != tt.wantOK {
+			t.Errorf("get(%q) ok = %v, want %v", tt.key, ok, tt.wantOK)
+		}
+	}
+	if got := c.len(); got != 2 {
+		t.Errorf("len() = %d, want 2", got)
+	}
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Now()
+	c := newLRU(10, time.Second)
+	c.now = func() time.Time { return now }
+
+	c.set("a", "1", true)
+	now = now.Add(999 * time.Millisecond)
+	if v, found, ok := c.get("a"); !ok || !found || v != "1" {
+		t.Errorf("get(a) = %q, %v, %v, want 1, true, true", v, found, ok)
+	}
+
+	now = now.Add(time.Millisecond)
+	if _, _, ok := c.get("a"); ok {
+		t.Error("get(a) hit after the TTL")
+	}
+	if got := c.len(); got != 0 {
+		t.Errorf("len() = %d, want the expired entry dropped", got)
+	}
+}
+
+func TestLRU_Misses(t *testing.T) {
+	c := newLRU(10, time.Minute)
+
+	c.set("absent", "", false)
+	if _, found, ok := c.get("absent"); !ok || found {
+		t.Errorf("get(absent) found, ok = %v, %v, want false, true", found, ok)
+	}
+
+	// Overwriting a cached miss with a value
+	c.set("absent", "now here", true)
+	if v, found, ok := c.get("absent"); !ok || !found || v != "now here" {
+		t.Errorf("get(absent) = %q, %v, %v, want the new value", v, found, ok)
+	}
+}
+
+func TestLRU_RemoveAndPurge(t *testing.T) {
+	c := newLRU(10, time.Minute)
+	c.set("a", "1", true)
+	c.set("b", "2", true)
+
+	c.remove("a")
+	if _, _, ok := c.get("a"); ok {
+		t.Error("get(a) hit after remove")
+	}
+
+	c.purge()
+	if got := c.len(); got != 0 {
+		t.Errorf("len() after purge = %d, want 0", got)
+	}
+}
@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+)
+
+// Cache tiers, as labeled in metrics
+const (
+	tierLocal = "local"
+	tierRedis = "redis"
+)
+
+// Request results, as labeled in metrics. Reads are a hit or a miss;
+// other commands are ok.
+const (
+	resultHit   = "hit"
+	resultMiss  = "miss"
+	resultOK    = "ok"
+	resultError = "error"
+)
+
+// trafficCapacity bounds how many keys are tracked for TopKeys
+const trafficCapacity = 1000
+
+// readCommands are the commands whose results count as hits and misses
+var readCommands = map[string]bool{"get": true, "getex": true, "hget": true, "mget": true, "hmget": true}
+
+// keylessCommands take no key as their first argument
+var keylessCommands = map[string]bool{
+	"ping": true, "hello": true, "auth": true, "select": true, "info": true, "client": true,
+	"config": true, "command": true, "script": true, "multi": true, "exec": true, "discard": true,
+}
+
+// cacheMetrics instruments a cache and its tiered views
+type cacheMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	traffic  *keyTraffic
+}
+
+// WithMetrics counts the cache's requests by tier, command and result,
+// times every Redis command, and tracks the keys with the most traffic for
+// TopKeys. It covers every user of the Redis client, including the job
+// queue and locks, and must be called before Tiered.
+func (c *Cache) WithMetrics(registry prometheus.Registerer) *Cache {
+	c.metrics = &cacheMetrics{
+		requests: metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Cache requests by tier, command and result; hits and misses are counted for reads.",
+		}, []string{"tier", "command", "result"})),
+		duration: metrics.Register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "cache_command_duration_seconds",
+			Help:    "Time spent on a Redis command or pipeline, including the round trip.",
+			Buckets: metrics.DefaultDurationBuckets,
+		}, []string{"command"})),
+		traffic: newKeyTraffic(trafficCapacity),
+	}
+	c.client.AddHook(metricsHook{metrics: c.metrics})
+	return c
+}
+
+// observeLocal counts a read of the local tier; a hit is a read served
+// without Redis, including a remembered miss
+func (m *cacheMetrics) observeLocal(hit bool) {
+	if m == nil {
+		return
+	}
+	result := resultMiss
+	if hit {
+		result = resultHit
+	}
+	m.requests.WithLabelValues(tierLocal, "get", result).Inc()
+}
+
+// observe counts a finished Redis command and its key
+func (m *cacheMetrics) observe(cmd redis.Cmder) {
+	name := cmd.Name()
+	if key := commandKey(cmd); key != "" {
+		m.traffic.add(key)
+	}
+
+	err := cmd.Err()
+	switch {
+	case err != nil && err != redis.Nil:
+		m.requests.WithLabelValues(tierRedis, name, resultError).Inc()
+	case !readCommands[name]:
+		m.requests.WithLabelValues(tierRedis, name, resultOK).Inc()
+	case err == redis.Nil:
+		m.requests.WithLabelValues(tierRedis, name, resultMiss).Inc()
+	default:
+		// Multi-key reads count each key
+		if slice, ok := cmd.(*redis.SliceCmd); ok {
+			var hits, misses float64
+			for _, value := range slice.Val() {
+				if value == nil {
+					misses++
+				} else {
+					hits++
+				}
+			}
+			m.requests.WithLabelValues(tierRedis, name, resultHit).Add(hits)
+			m.requests.WithLabelValues(tierRedis, name, resultMiss).Add(misses)
+			return
+		}
+		m.requests.WithLabelValues(tierRedis, name, resultHit).Inc()
+	}
+}
+
+// commandKey returns the first key a command touches, or "" if it has none
+func commandKey(cmd redis.Cmder) string {
+	args := cmd.Args()
+	index := 1
+	switch name := cmd.Name(); {
+	case keylessCommands[name]:
+		return ""
+	case name == "eval" || name == "evalsha" || name == "eval_ro" || name == "evalsha_ro" || name == "fcall":
+		// EVAL script numkeys key...
+		index = 3
+		if len(args) < 4 || toString(args[2]) == "0" {
+			return ""
+		}
+	}
+	if len(args) <= index {
+		return ""
+	}
+	return toString(args[index])
+}
+
+// toString formats a command argument
+func toString(arg interface{}) string {
+	switch v := arg.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	}
+	return ""
+}
+
+// metricsHook records every Redis command the client sends
+type metricsHook struct {
+	metrics *cacheMetrics
+}
+
+func (h metricsHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h metricsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.metrics.duration.WithLabelValues(cmd.Name()).Observe(time.Since(start).Seconds())
+		h.metrics.observe(cmd)
+		return err
+	}
+}
+
+func (h metricsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.metrics.duration.WithLabelValues("pipeline").Observe(time.Since(start).Seconds())
+		for _, cmd := range cmds {
+			h.metrics.observe(cmd)
+		}
+		return err
+	}
+}
+
+// KeyCount is a key's share of Redis traffic
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	// Overcount is how much Count may overstate the key's traffic: the
+	// count of the key it replaced when it started being tracked
+	Overcount uint64 `json:"overcount"`
+}
+
+// TopKeys returns up to limit keys with the most Redis commands since
+// WithMetrics, busiest first. Counts are exact while fewer keys than the
+// tracking capacity have been seen; past that, keys with little traffic
+// are replaced and a busy key's count may be overstated by its Overcount,
+// but never understated. It returns nil without metrics.
+func (c *Cache) TopKeys(limit int) []KeyCount {
+	if c.metrics == nil {
+		return nil
+	}
+	return c.metrics.traffic.top(limit)
+}
+
+// keyTraffic counts commands per key in bounded memory with the
+// space-saving algorithm: when it is full, a new key replaces the key
+// with the lowest count and inherits that count as its overcount
+type keyTraffic struct {
+	mu       sync.Mutex
+	capacity int
+	counts   map[string]*KeyCount
+}
+
+func newKeyTraffic(capacity int) *keyTraffic {
+	return &keyTraffic{capacity: capacity, counts: make(map[string]*KeyCount, capacity)}
+}
+
+// add counts a command on key
+func (t *keyTraffic) add(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if entry, ok := t.counts[key]; ok {
+		entry.Count++
+		return
+	}
+	if len(t.counts) < t.capacity {
+		t.counts[key] = &KeyCount{Key: key, Count: 1}
+		return
+	}
+
+	var min *KeyCount
+	for _, entry := range t.counts {
+		if min == nil || entry.Count < min.Count {
+			min = entry
+		}
+	}
+	delete(t.counts, min.Key)
+	t.counts[key] = &KeyCount{Key: key, Count: min.Count + 1, Overcount: min.Count}
+}
+
+// top returns up to limit keys by count, then key
+func (t *keyTraffic) top(limit int) []KeyCount {
+	t.mu.Lock()
+	keys := make([]KeyCount, 0, len(t.counts))
+	for _, entry := range t.counts {
+		keys = append(keys, *entry)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count != keys[j].Count {
+			return keys[i].Count > keys[j].Count
+		}
+		return keys[i].Key < keys[j].Key
+	})
+	if limit >= 0 && len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys
+}
@@ -0,0 +1,96 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCacheMetrics_Observe(t *testing.T) {
+	ctx := context.Background()
+	c, err := NewLazy("redis://127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewLazy() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.WithMetrics(prometheus.NewRegistry())
+	m := c.metrics
+
+	hit := redis.NewStringCmd(ctx, "get", "analysis:1")
+	miss := redis.NewStringCmd(ctx, "get", "analysis:2")
+	miss.SetErr(redis.Nil)
+	failed := redis.NewStringCmd(ctx, "get", "analysis:1")
+	failed.SetErr(errors.New("connection refused"))
+	mget := redis.NewSliceCmd(ctx, "mget", "a", "b", "c")
+	mget.SetVal([]interface{}{"1", nil, "3"})
+	// A blocking pop timing out isn't a cache miss
+	pop := redis.NewStringSliceCmd(ctx, "blpop", "queue:jobs", 5)
+	pop.SetErr(redis.Nil)
+	script := redis.NewCmd(ctx, "evalsha", "abc123", 1, "lock:migrations", "token")
+
+	for _, cmd := range []redis.Cmder{hit, miss, failed, mget, pop, script, redis.NewStatusCmd(ctx, "ping")} {
+		m.observe(cmd)
+	}
+
+	counts := []struct {
+		command, result string
+		want            float64
+	}{
+		{"get", resultHit, 1},
+		{"get", resultMiss, 1},
+		{"get", resultError, 1},
+		{"mget", resultHit, 2},
+		{"mget", resultMiss, 1},
+		{"blpop", resultOK, 1},
+		{"blpop", resultMiss, 0},
+		{"evalsha", resultOK, 1},
+	}
+	for _, tt := range counts {
+		if got := testutil.ToFloat64(m.requests.WithLabelValues(tierRedis, tt.command, tt.result)); got != tt.want {
+			t.Errorf("%s %s = %v, want %v", tt.command, tt.result, got, tt.want)
+		}
+	}
+
+	top := c.TopKeys(10)
+	want := []KeyCount{{Key: "analysis:1", Count: 2}, {Key: "a", Count: 1}, {Key: "analysis:2", Count: 1}, {Key: "lock:migrations", Count: 1}, {Key: "queue:jobs", Count: 1}}
+	if len(top) != len(want) {
+		t.Fatalf("TopKeys() = %+v, want %+v", top, want)
+	}
+	for i := range want {
+		if top[i] != want[i] {
+			t.Errorf("TopKeys()[%d] = %+v, want %+v", i, top[i], want[i])
+		}
+	}
+
+	// Views share the metrics
+	view := c.Tiered(10, 1)
+	view.metrics.observeLocal(true)
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(tierLocal, "get", resultHit)); got != 1 {
+		t.Errorf("local hits = %v, want 1", got)
+	}
+
+	if keys := (&Cache{}).TopKeys(10); keys != nil {
+		t.Errorf("TopKeys() without metrics = %+v, want nil", keys)
+	}
+}
+
+func TestKeyTraffic(t *testing.T) {
+	traffic := newKeyTraffic(2)
+	for _, key := range []string{"a", "a", "a", "b", "c"} {
+		traffic.add(key)
+	}
+
+	// c replaced b, the least busy key, and may be overstated by its count
+	want := []KeyCount{{Key: "a", Count: 3}, {Key: "c", Count: 2, Overcount: 1}}
+	top := traffic.top(10)
+	if len(top) != len(want) || top[0] != want[0] || top[1] != want[1] {
+		t.Errorf("top() = %+v, want %+v", top, want)
+	}
+	if top := traffic.top(1); len(top) != 1 || top[0].Key != "a" {
+		t.Errorf("top(1) = %+v, want a", top)
+	}
+}
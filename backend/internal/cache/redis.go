@@ -2,70 +2,154 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
 )
 
+// ErrNotFound is returned when a key doesn't exist
+var ErrNotFound = errors.New("key not found")
+
 // Cache represents the Redis cache client
 type Cache struct {
-	client *redis.Client
+	client       *redis.Client
+	availability *availability
+	metrics      *cacheMetrics
+	// loads shares concurrent Fetch misses of a key
+	loads *singleflight.Group
+
+	// local is the in-process tier of a view returned by Tiered
+	local *localLayer
+
+	mu    sync.Mutex
+	views []*localLayer
 }
 
 // New creates a new Redis client
 func New(redisURL string) (*Cache, error) {
-	// Parse Redis URL
-	opts, err := redis.ParseURL(redisURL)
+	c, err := NewLazy(redisURL)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse Redis URL: %w", err)
+		return nil, err
 	}
 
-	// Create Redis client
-	client := redis.NewClient(opts)
-
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := client.Ping(ctx).Err(); err != nil {
+	if err := c.client.Ping(ctx).Err(); err != nil {
+		c.client.Close()
 		return nil, fmt.Errorf("unable to connect to Redis: %w", err)
 	}
 
 	slog.Info("Redis connection established")
 
-	return &Cache{client: client}, nil
+	return c, nil
+}
+
+// NewLazy creates a Redis client without checking the connection, so it
+// succeeds while Redis is down; commands fail until it comes up
+func NewLazy(redisURL string) (*Cache, error) {
+	// Parse Redis URL
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse Redis URL: %w", err)
+	}
+
+	// Create Redis client
+	client := redis.NewClient(opts)
+	available := newAvailability()
+	client.AddHook(availabilityHook{availability: available})
+
+	return &Cache{client: client, availability: available, loads: &singleflight.Group{}}, nil
 }
 
 // Set sets a key-value pair with TTL
 func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
-	return c.client.Set(ctx, key, value, ttl).Err()
+	err := resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		return c.client.Set(ctx, key, value, ttl).Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, key)
+	return nil
 }
 
 // Get retrieves a value by key
 func (c *Cache) Get(ctx context.Context, key string) (string, error) {
-	val, err := c.client.Get(ctx, key).Result()
+	if c.local != nil {
+		return c.getLocal(ctx, key)
+	}
+	return c.getRemote(ctx, key)
+}
+
+// getRemote reads a value from Redis
+func (c *Cache) getRemote(ctx context.Context, key string) (string, error) {
+	val, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (string, error) {
+		return c.client.Get(ctx, key).Result()
+	})
 	if err == redis.Nil {
-		return "", fmt.Errorf("key not found: %s", key)
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
 	}
 	return val, err
 }
 
 // Delete deletes a key
 func (c *Cache) Delete(ctx context.Context, key string) error {
-	return c.client.Del(ctx, key).Err()
+	err := resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		return c.client.Del(ctx, key).Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, key)
+	return nil
+}
+
+// Take deletes a key and returns the value it held, in one step, so only
+// one caller gets it. It returns ErrNotFound for a missing key.
+func (c *Cache) Take(ctx context.Context, key string) (string, error) {
+	// Retrying after an ambiguous failure could lose the value
+	val, err := resilience.Value(ctx, resilience.RedisWrites, func(ctx context.Context) (string, error) {
+		return c.client.GetDel(ctx, key).Result()
+	})
+	if err == redis.Nil {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	c.invalidate(ctx, key)
+	return val, nil
 }
 
 // Exists checks if a key exists
 func (c *Cache) Exists(ctx context.Context, key string) (bool, error) {
-	count, err := c.client.Exists(ctx, key).Result()
+	count, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (int64, error) {
+		return c.client.Exists(ctx, key).Result()
+	})
 	if err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
+// Client returns the underlying Redis client for subsystems that need
+// commands beyond simple key/value access (e.g. the job queue)
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
 // Ping checks if Redis is reachable
 func (c *Cache) Ping(ctx context.Context) error {
 	return c.client.Ping(ctx).Err()
@@ -73,6 +157,8 @@ func (c *Cache) Ping(ctx context.Context) error {
 
 // Close closes the Redis connection
 func (c *Cache) Close() error {
+	c.stopViews()
+
 	slog.Info("Closing Redis connection")
 	return c.client.Close()
 }
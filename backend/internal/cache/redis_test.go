@@ -0,0 +1,204 @@
+//go:build integration
+
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/testutil"
+)
+
+// Run with: go test -tags integration ./internal/cache/
+
+func newCache(t *testing.T) *cache.Cache {
+	t.Helper()
+
+	c, err := cache.New(testutil.RedisURL(t))
+	if err != nil {
+		t.Fatalf("cache.New() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+func TestCache_Get_NotFound(t *testing.T) {
+	c := newCache(t)
+
+	if _, err := c.Get(context.Background(), "missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCache_MSetMGet(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t)
+
+	if err := c.MSet(ctx, map[string]interface{}{"a": "1", "b": 2}, time.Minute); err != nil {
+		t.Fatalf("MSet() error = %v", err)
+	}
+
+	got, err := c.MGet(ctx, "a", "b", "missing")
+	if err != nil {
+		t.Fatalf("MGet() error = %v", err)
+	}
+	if want := map[string]string{"a": "1", "b": "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MGet() = %v, want %v", got, want)
+	}
+
+	if ttl, err := c.TTL(ctx, "b"); err != nil || ttl <= 0 || ttl > time.Minute {
+		t.Errorf("TTL() = %v, %v, want up to a minute", ttl, err)
+	}
+
+	if err := c.MDelete(ctx, "a", "b"); err != nil {
+		t.Fatalf("MDelete() error = %v", err)
+	}
+	if _, err := c.TTL(ctx, "a"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("TTL() after delete error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCache_IncrKeepsWindow(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t)
+
+	for want := int64(1); want <= 3; want++ {
+		got, err := c.Incr(ctx, "quota", time.Minute)
+		if err != nil || got != want {
+			t.Fatalf("Incr() = %v, %v, want %d", got, err, want)
+		}
+	}
+	if got, err := c.DecrBy(ctx, "quota", 2, time.Hour); err != nil || got != 1 {
+		t.Errorf("DecrBy() = %v, %v, want 1", got, err)
+	}
+
+	// The TTL set by the first increment is kept, not extended to an hour
+	if ttl, err := c.TTL(ctx, "quota"); err != nil || ttl > time.Minute {
+		t.Errorf("TTL() = %v, %v, want at most a minute", ttl, err)
+	}
+}
+
+func TestCache_Hash(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t)
+
+	if err := c.HSet(ctx, "usage", map[string]interface{}{"plan": "pro", "calls": 1}, time.Minute); err != nil {
+		t.Fatalf("HSet() error = %v", err)
+	}
+	if n, err := c.HIncrBy(ctx, "usage", "calls", 4); err != nil || n != 5 {
+		t.Errorf("HIncrBy() = %v, %v, want 5", n, err)
+	}
+	if err := c.HDel(ctx, "usage", "plan"); err != nil {
+		t.Fatalf("HDel() error = %v", err)
+	}
+	if _, err := c.HGet(ctx, "usage", "plan"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("HGet() error = %v, want ErrNotFound", err)
+	}
+
+	got, err := c.HGetAll(ctx, "usage")
+	if want := map[string]string{"calls": "5"}; err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("HGetAll() = %v, %v, want %v", got, err, want)
+	}
+}
+
+func TestCache_SortedSet(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t)
+
+	err := c.ZAdd(ctx, "window",
+		cache.ScoredMember{Member: "a", Score: 10},
+		cache.ScoredMember{Member: "b", Score: 20},
+		cache.ScoredMember{Member: "c", Score: 30},
+	)
+	if err != nil {
+		t.Fatalf("ZAdd() error = %v", err)
+	}
+
+	if n, err := c.ZRemRangeByScore(ctx, "window", math.Inf(-1), 15); err != nil || n != 1 {
+		t.Errorf("ZRemRangeByScore() = %v, %v, want 1", n, err)
+	}
+
+	got, err := c.ZRangeByScore(ctx, "window", 0, math.Inf(1), 0)
+	want := []cache.ScoredMember{{Member: "b", Score: 20}, {Member: "c", Score: 30}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ZRangeByScore() = %v, %v, want %v", got, err, want)
+	}
+
+	if err := c.ZRem(ctx, "window", "b"); err != nil {
+		t.Fatalf("ZRem() error = %v", err)
+	}
+	if n, err := c.ZCard(ctx, "window"); err != nil || n != 1 {
+		t.Errorf("ZCard() = %v, %v, want 1", n, err)
+	}
+}
+
+func TestCache_TieredInvalidatesAcrossReplicas(t *testing.T) {
+	ctx := context.Background()
+	url := testutil.RedisURL(t)
+
+	// Two replicas, each with its own connection and local tier
+	replicas := make([]*cache.Cache, 2)
+	for i := range replicas {
+		c, err := cache.New(url)
+		if err != nil {
+			t.Fatalf("cache.New() error = %v", err)
+		}
+		t.Cleanup(func() { c.Close() })
+		replicas[i] = c.Tiered(100, time.Minute)
+	}
+	a, b := replicas[0], replicas[1]
+
+	// Wait for both listeners to subscribe, then cache a miss on b
+	testutil.Eventually(t, 5*time.Second, func() bool {
+		_, errA := a.Get(ctx, "revoked")
+		_, errB := b.Get(ctx, "revoked")
+		return errors.Is(errA, cache.ErrNotFound) && errors.Is(errB, cache.ErrNotFound)
+	})
+
+	if err := a.Set(ctx, "revoked", "1", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	testutil.Eventually(t, 5*time.Second, func() bool {
+		v, err := b.Get(ctx, "revoked")
+		return err == nil && v == "1"
+	})
+}
+
+func TestCache_FetchServesStaleWhileRefreshing(t *testing.T) {
+	ctx := context.Background()
+	c := newCache(t)
+
+	var loads atomic.Int32
+	load := func(ctx context.Context) (string, error) {
+		return fmt.Sprintf("v%d", loads.Add(1)), nil
+	}
+	freshness := cache.Freshness{TTL: 100 * time.Millisecond, Stale: time.Minute}
+
+	for range 2 {
+		if got, err := c.Fetch(ctx, "stats", freshness, load); err != nil || got != "v1" {
+			t.Fatalf("Fetch() = %q, %v, want v1 from the cache", got, err)
+		}
+	}
+
+	// Once stale, the old value is served while it is refreshed
+	time.Sleep(150 * time.Millisecond)
+	if got, err := c.Fetch(ctx, "stats", freshness, load); err != nil || got != "v1" {
+		t.Fatalf("Fetch() = %q, %v, want the stale v1", got, err)
+	}
+	testutil.Eventually(t, 5*time.Second, func() bool {
+		got, err := c.Fetch(ctx, "stats", freshness, load)
+		return err == nil && got == "v2"
+	})
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loaded %d times, want 2", n)
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// ScoredMember is a member of a sorted set with its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAdd adds members to the sorted set at key, updating the scores of
+// members already present
+func (c *Cache) ZAdd(ctx context.Context, key string, members ...ScoredMember) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	zs := make([]redis.Z, len(members))
+	for i, m := range members {
+		zs[i] = redis.Z{Score: m.Score, Member: m.Member}
+	}
+
+	return resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		if err := c.client.ZAdd(ctx, key, zs...).Err(); err != nil {
+			return fmt.Errorf("failed to add to sorted set %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+// ZRangeByScore returns members scored between min and max inclusive,
+// lowest first. A limit of zero returns them all.
+func (c *Cache) ZRangeByScore(ctx context.Context, key string, min, max float64, limit int64) ([]ScoredMember, error) {
+	zs, err := resilience.Value(ctx, resilience.Redis, func(ctx context.Context) ([]redis.Z, error) {
+		return c.client.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+			Min:   formatScore(min),
+			Max:   formatScore(max),
+			Count: limit,
+		}).Result()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sorted set %s: %w", key, err)
+	}
+
+	members := make([]ScoredMember, len(zs))
+	for i, z := range zs {
+		members[i] = ScoredMember{Member: fmt.Sprint(z.Member), Score: z.Score}
+	}
+	return members, nil
+}
+
+// ZRemRangeByScore removes members scored between min and max inclusive,
+// such as entries that fell out of a sliding window, and returns how
+// many were removed
+func (c *Cache) ZRemRangeByScore(ctx context.Context, key string, min, max float64) (int64, error) {
+	return resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (int64, error) {
+		return c.client.ZRemRangeByScore(ctx, key, formatScore(min), formatScore(max)).Result()
+	})
+}
+
+// ZRem removes members from the sorted set at key
+func (c *Cache) ZRem(ctx context.Context, key string, members ...string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, len(members))
+	for i, m := range members {
+		args[i] = m
+	}
+
+	return resilience.Redis.Do(ctx, func(ctx context.Context) error {
+		return c.client.ZRem(ctx, key, args...).Err()
+	})
+}
+
+// ZCard returns the number of members in the sorted set at key
+func (c *Cache) ZCard(ctx context.Context, key string) (int64, error) {
+	return resilience.Value(ctx, resilience.Redis, func(ctx context.Context) (int64, error) {
+		return c.client.ZCard(ctx, key).Result()
+	})
+}
+
+// formatScore renders a score bound, mapping infinities to Redis's
+// -inf and +inf
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// refreshTimeout bounds a background refresh
+const refreshTimeout = 30 * time.Second
+
+// Freshness is how long a value computed by Fetch is served
+type Freshness struct {
+	// TTL is how long the value is fresh
+	TTL time.Duration
+	// Stale is how long after that it is still served, while it is
+	// refreshed in the background
+	Stale time.Duration
+	// Jitter spreads TTL randomly by up to this fraction either way, so
+	// values cached together don't expire together
+	Jitter float64
+}
+
+// Jitter returns ttl moved randomly by up to fraction of itself either way
+func Jitter(ttl time.Duration, fraction float64) time.Duration {
+	if fraction <= 0 || ttl <= 0 {
+		return ttl
+	}
+	spread := float64(ttl) * min(fraction, 1)
+	return ttl + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// Fetch returns the value cached at key, computing it with load when there
+// is none. A fresh value is returned as is. A stale one is returned too,
+// and refreshed in the background by one caller across every replica.
+// Concurrent misses in the process share one load. When Redis fails, the
+// value is loaded without it.
+func (c *Cache) Fetch(ctx context.Context, key string, freshness Freshness, load func(ctx context.Context) (string, error)) (string, error) {
+	if cached, err := c.Get(ctx, key); err == nil {
+		if value, freshUntil, ok := unwrapEntry(cached); ok {
+			if time.Now().After(freshUntil) {
+				c.refresh(ctx, key, freshness, load)
+			}
+			return value, nil
+		}
+	}
+
+	value, err, _ := c.loads.Do(key, func() (interface{}, error) {
+		return c.store(ctx, key, freshness, load)
+	})
+	if err != nil {
+		return "", err
+	}
+	return value.(string), nil
+}
+
+// FetchJSON is Fetch for a value cached as JSON
+func FetchJSON[T any](ctx context.Context, c *Cache, key string, freshness Freshness, load func(ctx context.Context) (T, error)) (T, error) {
+	var value T
+	data, err := c.Fetch(ctx, key, freshness, func(ctx context.Context) (string, error) {
+		loaded, err := load(ctx)
+		if err != nil {
+			return "", err
+		}
+		data, err := json.Marshal(loaded)
+		return string(data), err
+	})
+	if err != nil {
+		return value, err
+	}
+
+	if err := json.Unmarshal([]byte(data), &value); err != nil {
+		return value, fmt.Errorf("failed to decode cached %s: %w", key, err)
+	}
+	return value, nil
+}
+
+// refresh reloads a stale value in the background, unless another caller
+// already is
+func (c *Cache) refresh(ctx context.Context, key string, freshness Freshness, load func(ctx context.Context) (string, error)) {
+	lock, err := c.Locker().TryAcquire(ctx, "refresh:"+key, refreshTimeout)
+	if err != nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), refreshTimeout)
+		defer cancel()
+		defer lock.Release(ctx)
+
+		if _, err := c.store(ctx, key, freshness, load); err != nil {
+			slog.Warn("Failed to refresh cached value", "key", key, "error", err)
+		}
+	}()
+}
+
+// store loads a value and caches it. A failure to cache it is logged.
+func (c *Cache) store(ctx context.Context, key string, freshness Freshness, load func(ctx context.Context) (string, error)) (string, error) {
+	value, err := load(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	ttl := Jitter(freshness.TTL, freshness.Jitter)
+	freshUntil := time.Now().Add(ttl)
+	if err := c.Set(ctx, key, wrapEntry(value, freshUntil), ttl+freshness.Stale); err != nil {
+		slog.Warn("Failed to cache value", "key", key, "error", err)
+	}
+	return value, nil
+}
+
+// wrapEntry prefixes a value with when it goes stale, in Unix milliseconds
+func wrapEntry(value string, freshUntil time.Time) string {
+	return strconv.FormatInt(freshUntil.UnixMilli(), 10) + "|" + value
+}
+
+// unwrapEntry splits a value stored by wrapEntry. It reports false for a
+// value stored another way, which is then loaded again.
+func unwrapEntry(entry string) (value string, freshUntil time.Time, ok bool) {
+	prefix, value, found := strings.Cut(entry, "|")
+	if !found {
+		return "", time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return value, time.UnixMilli(ms), true
+}
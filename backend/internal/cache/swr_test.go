@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJitter(t *testing.T) {
+	for range 100 {
+		got := Jitter(time.Minute, 0.1)
+		if got < 54*time.Second || got > 66*time.Second {
+			t.Fatalf("Jitter(1m, 0.1) = %v, want within 10%%", got)
+		}
+	}
+	if got := Jitter(time.Minute, 0); got != time.Minute {
+		t.Errorf("Jitter(1m, 0) = %v, want 1m", got)
+	}
+}
+
+func TestEntry(t *testing.T) {
+	freshUntil := time.UnixMilli(1700000000123)
+	value, got, ok := unwrapEntry(wrapEntry(`{"a":"x|y"}`, freshUntil))
+	if !ok || value != `{"a":"x|y"}` || !got.Equal(freshUntil) {
+		t.Errorf("unwrapEntry() = %q, %v, %v", value, got, ok)
+	}
+
+	for _, entry := range []string{`{"a":1}`, "soon|value"} {
+		if _, _, ok := unwrapEntry(entry); ok {
+			t.Errorf("unwrapEntry(%q) ok, want a value stored another way rejected", entry)
+		}
+	}
+}
+
+func TestCache_Fetch_Unavailable(t *testing.T) {
+	c, err := NewLazy("redis://127.0.0.1:1")
+	if err != nil {
+		t.Fatalf("NewLazy() error = %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.availability.up.Store(false)
+
+	// Without Redis, concurrent callers still share one load
+	var loads atomic.Int32
+	release := make(chan struct{})
+	load := func(ctx context.Context) (string, error) {
+		loads.Add(1)
+		<-release
+		return "computed", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = c.Fetch(context.Background(), "stats", Freshness{TTL: time.Minute}, load)
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("loaded %d times, want 1", loads.Load())
+	}
+	for _, result := range results {
+		if result != "computed" {
+			t.Errorf("Fetch() = %q, want computed", result)
+		}
+	}
+
+	failure := errors.New("database down")
+	if _, err := c.Fetch(context.Background(), "stats", Freshness{TTL: time.Minute}, func(ctx context.Context) (string, error) {
+		return "", failure
+	}); !errors.Is(err, failure) {
+		t.Errorf("Fetch() error = %v, want the load's", err)
+	}
+}
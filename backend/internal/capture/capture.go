@@ -0,0 +1,306 @@
+// Package capture records sanitized requests and responses of chosen
+// routes and users in a ring buffer, for operators debugging client
+// integrations. Capture is switched on per route by configuration and per
+// user with a feature flag, so neither needs a deploy.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// FlagKey is the feature flag that captures the requests of the users it
+// is on for
+const FlagKey = "debug.capture"
+
+const (
+	// DefaultSize is how many exchanges are kept by default
+	DefaultSize = 200
+	// DefaultMaxBody is how much of each body is kept by default, in bytes
+	DefaultMaxBody = 16 << 10
+)
+
+// Checker reports whether a feature flag is on for a user; *flags.Flags
+// implements it
+type Checker interface {
+	Enabled(ctx context.Context, key string, userID uuid.UUID) bool
+}
+
+// Exchange is a captured request and its response. Credentials and
+// personal data are redacted, and bodies are cut to the recorder's limit.
+type Exchange struct {
+	ID        uuid.UUID `json:"id"`
+	RequestID string    `json:"request_id,omitempty"`
+	// UserID is who made the request, or nil if it wasn't authenticated
+	UserID          *uuid.UUID        `json:"user_id"`
+	Method          string            `json:"method"`
+	Path            string            `json:"path"`
+	Query           string            `json:"query,omitempty"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	RequestBody     string            `json:"request_body"`
+	Status          int               `json:"status"`
+	ResponseHeaders map[string]string `json:"response_headers"`
+	ResponseBody    string            `json:"response_body"`
+	// Truncated is set when a body was longer than the recorder keeps
+	Truncated  bool           `json:"truncated"`
+	DurationMs int            `json:"duration_ms"`
+	CapturedAt timestamp.Time `json:"captured_at"`
+}
+
+// Filter narrows the exchanges listed
+type Filter struct {
+	UserID *uuid.UUID
+	// PathPrefix matches the start of the request path
+	PathPrefix string
+	Limit      int
+}
+
+// Recorder keeps the most recent exchanges in memory. Each instance keeps
+// its own.
+type Recorder struct {
+	maxBody int
+	users   Checker
+	routes  atomic.Pointer[[]string]
+
+	mu      sync.Mutex
+	entries []Exchange
+	next    int
+	full    bool
+}
+
+// New creates a recorder keeping the last size exchanges, with up to
+// maxBody bytes of each body
+func New(size, maxBody int) *Recorder {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if maxBody <= 0 {
+		maxBody = DefaultMaxBody
+	}
+	r := &Recorder{maxBody: maxBody, entries: make([]Exchange, size)}
+	r.SetRoutes(nil)
+	return r
+}
+
+// WithUsers captures every request of the users FlagKey is on for and
+// returns the recorder
+func (r *Recorder) WithUsers(users Checker) *Recorder {
+	r.users = users
+	return r
+}
+
+// SetRoutes captures every request to a path starting with one of
+// prefixes, such as /submissions, relative to the API version. It can be
+// called while requests are served.
+func (r *Recorder) SetRoutes(prefixes []string) {
+	routes := slices.Clone(prefixes)
+	r.routes.Store(&routes)
+}
+
+// add stores an exchange, replacing the oldest once the buffer is full
+func (r *Recorder) add(e Exchange) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns the captured exchanges matching filter, newest first
+func (r *Recorder) List(filter Filter) []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+	out := []Exchange{}
+	for i := 1; i <= count; i++ {
+		e := r.entries[(r.next-i+len(r.entries))%len(r.entries)]
+		if filter.UserID != nil && (e.UserID == nil || *e.UserID != *filter.UserID) {
+			continue
+		}
+		if !strings.HasPrefix(e.Path, filter.PathPrefix) {
+			continue
+		}
+		out = append(out, e)
+		if filter.Limit > 0 && len(out) == filter.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// Clear drops every captured exchange
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.entries)
+	r.next, r.full = 0, false
+}
+
+// Middleware captures the requests under prefix, an API version's path,
+// that match a route or come from a flagged user. Who made a request is
+// only known once it has been authenticated, so up to the body limit of
+// every request is held until it is answered; streamed bodies still reach
+// the client as they are written.
+func (r *Recorder) Middleware(prefix string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			start := time.Now()
+			body := &bodyCapture{ReadCloser: req.Body, limit: r.maxBody}
+			if req.Body != nil && req.Body != http.NoBody {
+				req.Body = body
+			}
+			rec := &responseCapture{ResponseWriter: w, limit: r.maxBody, status: http.StatusOK}
+
+			next.ServeHTTP(rec, req)
+
+			userID := requestUser(req.Context())
+			if !r.selected(req.Context(), strings.TrimPrefix(req.URL.Path, prefix), userID) {
+				return
+			}
+			r.add(Exchange{
+				ID:              uuid.New(),
+				RequestID:       middleware.GetReqID(req.Context()),
+				UserID:          userID,
+				Method:          req.Method,
+				Path:            req.URL.Path,
+				Query:           sanitizeQuery(req.URL.RawQuery),
+				RequestHeaders:  sanitizeHeaders(req.Header),
+				RequestBody:     sanitizeBody(body.buf.Bytes(), req.Header.Get("Content-Type")),
+				Status:          rec.status,
+				ResponseHeaders: sanitizeHeaders(rec.Header()),
+				ResponseBody:    sanitizeBody(rec.body(), rec.Header().Get("Content-Type")),
+				Truncated:       body.truncated || rec.truncated,
+				DurationMs:      int(time.Since(start).Milliseconds()),
+				CapturedAt:      timestamp.New(start),
+			})
+		})
+	}
+}
+
+// selected reports whether a request to route by userID is captured
+func (r *Recorder) selected(ctx context.Context, route string, userID *uuid.UUID) bool {
+	for _, prefix := range *r.routes.Load() {
+		if route == prefix || strings.HasPrefix(route, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return userID != nil && r.users != nil && r.users.Enabled(ctx, FlagKey, *userID)
+}
+
+// requestUser returns the user the auth middleware found, if any
+func requestUser(ctx context.Context) *uuid.UUID {
+	scope, ok := errreport.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	userID, err := uuid.Parse(scope.UserID())
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// bodyCapture keeps up to limit bytes of a request body as the handler
+// reads it
+type bodyCapture struct {
+	io.ReadCloser
+	limit     int
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (b *bodyCapture) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		keep := min(n, b.limit-b.buf.Len())
+		b.buf.Write(p[:keep])
+		if keep < n {
+			b.truncated = true
+		}
+	}
+	return n, err
+}
+
+// responseCapture keeps the status and up to limit bytes of a response
+// while passing it on
+type responseCapture struct {
+	http.ResponseWriter
+	limit       int
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+	truncated   bool
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if !c.wroteHeader {
+		c.status, c.wroteHeader = status, true
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(p []byte) (int, error) {
+	c.wroteHeader = true
+	keep := min(len(p), c.limit-c.buf.Len())
+	c.buf.Write(p[:keep])
+	if keep < len(p) {
+		c.truncated = true
+	}
+	return c.ResponseWriter.Write(p)
+}
+
+func (c *responseCapture) Flush() {
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (c *responseCapture) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := c.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, errors.New("capture: response writer doesn't support hijacking")
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// body returns the captured response body, decompressing it when the
+// route compressed it. A body cut off at the limit decompresses as far as
+// it goes.
+func (c *responseCapture) body() []byte {
+	if c.Header().Get("Content-Encoding") != "gzip" {
+		return c.buf.Bytes()
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(c.buf.Bytes()))
+	if err != nil {
+		return nil
+	}
+	plain, _ := io.ReadAll(io.LimitReader(gz, int64(c.limit)))
+	return plain
+}
@@ -0,0 +1,162 @@
+package capture
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+)
+
+// fakeFlag is on for the listed users
+type fakeFlag []uuid.UUID
+
+func (f fakeFlag) Enabled(ctx context.Context, key string, userID uuid.UUID) bool {
+	for _, id := range f {
+		if key == FlagKey && id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// serve sends a request through the recorder's middleware to a handler
+// that echoes the body, as userID when set
+func serve(t *testing.T, r *Recorder, method, target, body string, userID *uuid.UUID) *httptest.ResponseRecorder {
+	t.Helper()
+
+	handler := r.Middleware("/api/v1")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if userID != nil {
+			errreport.SetUser(req.Context(), userID.String())
+		}
+		data, _ := io.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}))
+
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer secret")
+	ctx, _ := errreport.NewContext(req.Context())
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+	return rec
+}
+
+func TestRecorder_Middleware_Routes(t *testing.T) {
+	r := New(10, 0)
+	r.SetRoutes([]string{"/submissions"})
+
+	rec := serve(t, r, http.MethodPost, "/api/v1/submissions?token=abc", `{"content":"hello","password":"pw"}`, nil)
+	serve(t, r, http.MethodPost, "/api/v1/submissions-archive", `{}`, nil)
+	serve(t, r, http.MethodGet, "/api/v1/analytics", "", nil)
+
+	if rec.Code != http.StatusCreated || rec.Body.String() != `{"content":"hello","password":"pw"}` {
+		t.Errorf("response = %d %s, want it passed through unchanged", rec.Code, rec.Body.String())
+	}
+
+	got := r.List(Filter{})
+	if len(got) != 1 {
+		t.Fatalf("captured %d exchanges, want only the listed route", len(got))
+	}
+	e := got[0]
+	if e.Method != http.MethodPost || e.Path != "/api/v1/submissions" || e.Status != http.StatusCreated || e.UserID != nil {
+		t.Errorf("exchange = %+v", e)
+	}
+	if e.Query != "token=[REDACTED]" || e.RequestHeaders["Authorization"] != Redacted {
+		t.Errorf("query = %q, headers = %v, want credentials redacted", e.Query, e.RequestHeaders)
+	}
+	want := `{"content":"hello","password":"[REDACTED]"}`
+	if e.RequestBody != want || e.ResponseBody != want {
+		t.Errorf("bodies = %s / %s, want %s", e.RequestBody, e.ResponseBody, want)
+	}
+}
+
+func TestRecorder_Middleware_FlaggedUsers(t *testing.T) {
+	flagged, other := uuid.New(), uuid.New()
+	r := New(10, 0).WithUsers(fakeFlag{flagged})
+
+	serve(t, r, http.MethodGet, "/api/v1/analytics", "", &flagged)
+	serve(t, r, http.MethodGet, "/api/v1/analytics", "", &other)
+	serve(t, r, http.MethodGet, "/api/v1/analytics", "", nil)
+
+	got := r.List(Filter{})
+	if len(got) != 1 || got[0].UserID == nil || *got[0].UserID != flagged {
+		t.Errorf("captured %+v, want only the flagged user's request", got)
+	}
+}
+
+func TestRecorder_Middleware_Truncates(t *testing.T) {
+	r := New(10, 8)
+	r.SetRoutes([]string{"/"})
+
+	rec := serve(t, r, http.MethodPost, "/api/v1/submissions", strings.Repeat("a", 20), nil)
+
+	if rec.Body.Len() != 20 {
+		t.Errorf("response body = %d bytes, want all of it", rec.Body.Len())
+	}
+	if got := r.List(Filter{}); len(got) != 1 || !got[0].Truncated {
+		t.Errorf("captured %+v, want a truncated exchange", got)
+	}
+}
+
+func TestRecorder_Middleware_DecompressesResponses(t *testing.T) {
+	r := New(10, 0)
+	r.SetRoutes([]string{"/analytics"})
+
+	handler := r.Middleware("/api/v1")(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`{"total":3}`))
+		gz.Close()
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/analytics", nil))
+
+	if got := r.List(Filter{}); len(got) != 1 || got[0].ResponseBody != `{"total":3}` {
+		t.Errorf("captured %+v, want the decompressed body", got)
+	}
+}
+
+func TestRecorder_List(t *testing.T) {
+	userID := uuid.New()
+	r := New(3, 0)
+	r.SetRoutes([]string{"/"})
+
+	for _, path := range []string{"/api/v1/a", "/api/v1/b", "/api/v1/c", "/api/v1/d"} {
+		serve(t, r, http.MethodGet, path, "", &userID)
+	}
+
+	paths := func(exchanges []Exchange) string {
+		var out []string
+		for _, e := range exchanges {
+			out = append(out, e.Path)
+		}
+		return strings.Join(out, ",")
+	}
+	if got := paths(r.List(Filter{})); got != "/api/v1/d,/api/v1/c,/api/v1/b" {
+		t.Errorf("List() = %s, want the newest 3, newest first", got)
+	}
+	if got := paths(r.List(Filter{Limit: 1})); got != "/api/v1/d" {
+		t.Errorf("List(limit 1) = %s", got)
+	}
+	if got := paths(r.List(Filter{PathPrefix: "/api/v1/c"})); got != "/api/v1/c" {
+		t.Errorf("List(path) = %s", got)
+	}
+	other := uuid.New()
+	if got := r.List(Filter{UserID: &other}); len(got) != 0 {
+		t.Errorf("List(other user) = %+v", got)
+	}
+
+	r.Clear()
+	if got := r.List(Filter{}); len(got) != 0 {
+		t.Errorf("List() after Clear() = %+v", got)
+	}
+}
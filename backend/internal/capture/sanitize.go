@@ -0,0 +1,182 @@
+package capture
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+)
+
+// Redacted replaces every secret that is captured
+const Redacted = "[REDACTED]"
+
+// secretHeaders carry credentials; other headers are kept
+var secretHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// secretField reports whether a JSON field, form field or query parameter
+// holds a credential, such as password, client_secret, refresh_token,
+// api_key or invite_code
+func secretField(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range []string{"password", "secret", "authorization", "cookie"} {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	for _, suffix := range []string{"token", "key", "code", "otp"} {
+		if name == suffix || strings.HasSuffix(name, "_"+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeHeaders copies headers, redacting the ones carrying credentials
+func sanitizeHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if secretHeaders[http.CanonicalHeaderKey(name)] || secretField(strings.ReplaceAll(name, "-", "_")) {
+			value = Redacted
+		}
+		out[name] = value
+	}
+	return out
+}
+
+// sanitizeQuery redacts the credentials in a query string or form body.
+// Names and values are decoded, so personal data is caught and the result
+// reads easily; pairs keep their order.
+func sanitizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		name, value, hasValue := strings.Cut(pair, "=")
+		name, value = unescape(name), unescape(value)
+		switch {
+		case !hasValue:
+			pairs[i] = name
+		case secretField(name):
+			pairs[i] = name + "=" + Redacted
+		default:
+			pairs[i] = name + "=" + value
+		}
+	}
+	return redactPersonalData(strings.Join(pairs, "&"))
+}
+
+// unescape decodes a query component, keeping it as it is if it isn't
+// valid
+func unescape(s string) string {
+	if decoded, err := url.QueryUnescape(s); err == nil {
+		return decoded
+	}
+	return s
+}
+
+// sanitizeBody returns a captured body fit to keep: credentials are
+// redacted, and so are emails, phone numbers and other personal data.
+// Bodies that aren't text are described rather than kept.
+func sanitizeBody(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"):
+		return redactPersonalData(sanitizeJSON(body))
+	case mediaType == "application/x-www-form-urlencoded":
+		return sanitizeQuery(string(body))
+	case mediaType == "application/x-ndjson", strings.HasPrefix(mediaType, "text/"):
+		return redactPersonalData(redactJSONFields(string(body)))
+	default:
+		return fmt.Sprintf("[%d bytes of %s omitted]", len(body), describe(mediaType))
+	}
+}
+
+func describe(mediaType string) string {
+	if mediaType == "" {
+		return "unknown content"
+	}
+	return mediaType
+}
+
+// sanitizeJSON redacts the credentials in a JSON document. A document cut
+// off at the capture limit can't be parsed, so its fields are redacted
+// by pattern instead.
+func sanitizeJSON(body []byte) string {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return redactJSONFields(string(body))
+	}
+	encoded, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return redactJSONFields(string(body))
+	}
+	return string(encoded)
+}
+
+// redactValue replaces the values of credential fields throughout a
+// decoded JSON document
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for name, value := range v {
+			if secretField(name) {
+				v[name] = Redacted
+			} else {
+				v[name] = redactValue(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+// jsonField matches a JSON field with a string, number or literal value,
+// or a string value cut off at the end of the text
+var jsonField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*:\s*("(?:[^"\\]|\\.)*(?:"|$)|[^,}\]\s]+)`)
+
+// redactJSONFields redacts the credential fields of JSON text by pattern
+func redactJSONFields(text string) string {
+	return jsonField.ReplaceAllStringFunc(text, func(field string) string {
+		m := jsonField.FindStringSubmatch(field)
+		if !secretField(m[1]) {
+			return field
+		}
+		return `"` + m[1] + `": "` + Redacted + `"`
+	})
+}
+
+// redactPersonalData masks the emails, phone numbers, SSNs and card
+// numbers in text. Profanity isn't personal, so it is left alone.
+func redactPersonalData(text string) string {
+	findings := sensitive.Detect(text)
+	personal := findings[:0]
+	for _, f := range findings {
+		if f.Type != models.FindingProfanity {
+			personal = append(personal, f)
+		}
+	}
+	return sensitive.Redact(text, personal)
+}
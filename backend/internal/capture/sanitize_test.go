@@ -0,0 +1,97 @@
+package capture
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSecretField(t *testing.T) {
+	for _, name := range []string{"password", "new_password", "client_secret", "token", "refresh_token", "api_key", "invite_code", "Authorization"} {
+		if !secretField(name) {
+			t.Errorf("secretField(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"content", "prompt_tokens", "keyphrases", "api_key_id", "email"} {
+		if secretField(name) {
+			t.Errorf("secretField(%q) = true", name)
+		}
+	}
+}
+
+func TestSanitizeHeaders(t *testing.T) {
+	got := sanitizeHeaders(http.Header{
+		"Authorization": {"Bearer abc"},
+		"X-Api-Key":     {"ca_123"},
+		"Cookie":        {"session=1"},
+		"Content-Type":  {"application/json"},
+	})
+	for _, name := range []string{"Authorization", "X-Api-Key", "Cookie"} {
+		if got[name] != Redacted {
+			t.Errorf("%s = %q, want it redacted", name, got[name])
+		}
+	}
+	if got["Content-Type"] != "application/json" {
+		t.Errorf("Content-Type = %q", got["Content-Type"])
+	}
+}
+
+func TestSanitizeBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        []string
+		notWant     []string
+	}{
+		{
+			name:        "json",
+			body:        `{"email":"jane@example.com","password":"hunter22","nested":[{"refresh_token":"r1","content":"Call 555-123-4567"}]}`,
+			contentType: "application/json; charset=utf-8",
+			want:        []string{`"password":"[REDACTED]"`, `"refresh_token":"[REDACTED]"`, "[EMAIL]", "[PHONE]"},
+			notWant:     []string{"hunter22", "r1", "jane@example.com", "555-123-4567"},
+		},
+		{
+			name:        "truncated json",
+			body:        `{"content":"hello","token":"abc.def`,
+			contentType: "application/json",
+			want:        []string{`"token": "[REDACTED]"`, `"content":"hello"`},
+			notWant:     []string{"abc.def"},
+		},
+		{
+			name:        "form",
+			body:        "grant_type=password&password=hunter22&username=jane%40example.com",
+			contentType: "application/x-www-form-urlencoded",
+			want:        []string{"grant_type=password", "password=[REDACTED]", "username=[EMAIL]"},
+			notWant:     []string{"hunter22"},
+		},
+		{
+			name:        "binary",
+			body:        "\x00\x01\x02",
+			contentType: "audio/mpeg",
+			want:        []string{"[3 bytes of audio/mpeg omitted]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sanitizeBody([]byte(tt.body), tt.contentType)
+			for _, s := range tt.want {
+				if !strings.Contains(got, s) {
+					t.Errorf("sanitizeBody() = %s, want it to contain %s", got, s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("sanitizeBody() = %s, leaked %s", got, s)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	got := sanitizeQuery("code=abc123&state=xyz&email=jane%40example.com&page=2")
+	if want := "code=[REDACTED]&state=xyz&email=[EMAIL]&page=2"; got != want {
+		t.Errorf("sanitizeQuery() = %q, want %q", got, want)
+	}
+}
@@ -1,51 +1,477 @@
 package config
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
 	"fmt"
+	"math"
+	"net/http"
+	"net/url"
 	"os"
-	"strconv"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
+	"golang.org/x/crypto/acme"
+
+	"github.com/sfumato00/content-analyzer/internal/abuse"
+	"github.com/sfumato00/content-analyzer/internal/capture"
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/logging"
+	"github.com/sfumato00/content-analyzer/internal/logins"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/outbound"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/aidetect"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/broker"
+	"github.com/sfumato00/content-analyzer/internal/services/factcheck"
+	"github.com/sfumato00/content-analyzer/internal/services/limits"
+	"github.com/sfumato00/content-analyzer/internal/services/transcription"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+	"github.com/sfumato00/content-analyzer/internal/storage"
 )
 
-// Config holds all application configuration
+// Config holds all application configuration. Fields tagged reload can be
+// changed by a Watcher while the server runs; the rest need a restart.
 type Config struct {
+	// ConfigFile is an optional KEY=value file loaded in every environment
+	// and watched for changes
+	ConfigFile string `env:"CONFIG_FILE"`
+
 	// API Keys
-	GeminiAPIKey string
+	GeminiAPIKey string `env:"GEMINI_API_KEY" secret:"true"`
+
+	// AI models
+	GeminiModel          string `env:"GEMINI_MODEL" reload:"true"`
+	GeminiEmbeddingModel string `env:"GEMINI_EMBEDDING_MODEL"`
+	// Model prices in US dollars per million tokens, used to record the
+	// cost of each analysis
+	GeminiInputPrice  float64 `env:"GEMINI_INPUT_PRICE"`
+	GeminiOutputPrice float64 `env:"GEMINI_OUTPUT_PRICE"`
+	// GeminiResponseSchema constrains model output to each module's JSON
+	// schema; turn it off for models without structured output
+	GeminiResponseSchema bool `env:"GEMINI_RESPONSE_SCHEMA"`
+	// AIRepairAttempts is how many times output that doesn't match its
+	// schema is sent back to the model to be fixed before the analysis
+	// fails
+	AIRepairAttempts int `env:"AI_REPAIR_ATTEMPTS"`
+	// AIReplayMode records model responses to AIReplayDir ("record") or
+	// answers from them without calling the API ("replay"), for
+	// deterministic tests and offline development; empty turns it off
+	AIReplayMode string `env:"AI_REPLAY_MODE"`
+	AIReplayDir  string `env:"AI_REPLAY_DIR"`
+	// AnalysisVerification scores each summary's faithfulness to its
+	// source with a second model call
+	AnalysisVerification bool `env:"ANALYSIS_VERIFICATION"`
+	// ClaimExtraction extracts and assesses each submission's factual
+	// claims with two more model calls
+	ClaimExtraction bool `env:"CLAIM_EXTRACTION"`
+	// Proofreading finds grammar, spelling and style issues in each
+	// submission with another model call
+	Proofreading bool `env:"PROOFREADING"`
+	// BiasAnalysis reports each submission's framing, loaded language and
+	// one-sidedness with another model call
+	BiasAnalysis bool `env:"BIAS_ANALYSIS"`
+	// AIDetection estimates how likely each submission is machine-generated
+	AIDetection bool `env:"AI_DETECTION"`
+	// Moderation scores each submission for harmful content with another
+	// model call and enforces organizations' moderation policies
+	Moderation bool `env:"MODERATION"`
+	// Optional OpenAI-compatible completions API, local or hosted, that
+	// scores perplexity for AI detection
+	PerplexityURL    string `env:"PERPLEXITY_URL"`
+	PerplexityAPIKey string `env:"PERPLEXITY_API_KEY" secret:"true"`
+	PerplexityModel  string `env:"PERPLEXITY_MODEL"`
+
+	// Web search for fact-checking extracted claims, with "brave" or a
+	// self-hosted "searxng" instance at FACT_CHECK_BASE_URL; without a
+	// provider claims are assessed from the model's knowledge
+	FactCheckProvider   string `env:"FACT_CHECK_PROVIDER"`
+	FactCheckAPIKey     string `env:"FACT_CHECK_API_KEY" secret:"true"`
+	FactCheckBaseURL    string `env:"FACT_CHECK_BASE_URL"`
+	FactCheckMaxResults int    `env:"FACT_CHECK_MAX_RESULTS"`
+
+	// Caps on the analyzer's provider calls in flight at once in each
+	// process, overall and per provider; zero means no cap
+	AnalyzerConcurrency   int `env:"ANALYZER_CONCURRENCY"`
+	GeminiConcurrency     int `env:"GEMINI_CONCURRENCY"`
+	FactCheckConcurrency  int `env:"FACT_CHECK_CONCURRENCY"`
+	PerplexityConcurrency int `env:"PERPLEXITY_CONCURRENCY"`
+
+	// Analysis pipeline stages to skip, and "stage=duration" bounds on how
+	// long a stage may run
+	AnalysisStagesDisabled []string `env:"ANALYSIS_STAGES_DISABLED"`
+	AnalysisStageTimeouts  []string `env:"ANALYSIS_STAGE_TIMEOUTS"`
+	// How long an analysis may run before the stages left are recorded as
+	// timed out; zero leaves analyses unbounded
+	AnalysisTimeout time.Duration `env:"ANALYSIS_TIMEOUT"`
+
+	// Submission lifecycle events published as CloudEvents to "nats" or
+	// "kafka"; no events are published without a driver
+	EventBroker      string `env:"EVENT_BROKER"`
+	EventBrokerURL   string `env:"EVENT_BROKER_URL" secret:"true"`
+	EventBrokerTopic string `env:"EVENT_BROKER_TOPIC"`
+	EventSource      string `env:"EVENT_SOURCE"`
+
+	// Speech-to-text for audio and video uploads, with "openai" (or a
+	// Whisper-compatible server at TRANSCRIPTION_BASE_URL) or "deepgram";
+	// uploads are disabled without a provider
+	TranscriptionProvider string `env:"TRANSCRIPTION_PROVIDER"`
+	TranscriptionAPIKey   string `env:"TRANSCRIPTION_API_KEY" secret:"true"`
+	TranscriptionBaseURL  string `env:"TRANSCRIPTION_BASE_URL"`
+	TranscriptionModel    string `env:"TRANSCRIPTION_MODEL"`
+	MediaUploadMaxMB      int    `env:"MEDIA_UPLOAD_MAX_MB"`
+
+	// ImportMaxMB is the largest CSV or JSONL file accepted for bulk
+	// import; 0 disables imports
+	ImportMaxMB int `env:"IMPORT_MAX_MB"`
+
+	// Object storage for uploads, with "local", "s3" or "minio"; without a
+	// driver uploads are kept in Postgres until they are processed. The
+	// S3 access keys default to the AWS ones.
+	StorageDriver          string `env:"STORAGE_DRIVER"`
+	StorageDir             string `env:"STORAGE_DIR"`
+	StorageBaseURL         string `env:"STORAGE_BASE_URL"`
+	StorageBucket          string `env:"STORAGE_BUCKET"`
+	StorageRegion          string `env:"STORAGE_REGION"`
+	StorageEndpoint        string `env:"STORAGE_ENDPOINT"`
+	StorageAccessKeyID     string `env:"STORAGE_ACCESS_KEY_ID" secret:"true"`
+	StorageSecretAccessKey string `env:"STORAGE_SECRET_ACCESS_KEY" secret:"true"`
+
+	// UploadMaxMB is the largest file accepted for direct upload to object
+	// storage. The object is read back to check it, so it is capped.
+	UploadMaxMB int `env:"UPLOAD_MAX_MB"`
 
 	// Database
-	DatabaseURL string
+	DatabaseURL string `env:"DATABASE_URL" secret:"url"`
+	// DatabaseReplicaURL is an optional read replica for lag-tolerant reads
+	DatabaseReplicaURL           string        `env:"DATABASE_REPLICA_URL" secret:"url"`
+	DatabaseReplicaCheckInterval time.Duration `env:"DATABASE_REPLICA_CHECK_INTERVAL"`
+	// SlowQueryThreshold logs statements that take longer; zero disables it
+	SlowQueryThreshold time.Duration `env:"DB_SLOW_QUERY_THRESHOLD"`
 
 	// Redis
-	RedisURL string
+	RedisURL string `env:"REDIS_URL" secret:"url"`
+	// LocalCacheSize and LocalCacheTTL bound the in-process tier in front
+	// of Redis for hot keys such as session revocations; zero disables it
+	LocalCacheSize int           `env:"LOCAL_CACHE_SIZE"`
+	LocalCacheTTL  time.Duration `env:"LOCAL_CACHE_TTL"`
+	// RedisCheckInterval is how often Redis is pinged; zero disables the
+	// check. While it is down the API runs degraded: caching is skipped,
+	// the job queue pauses and rate limits follow RateLimitFailMode.
+	RedisCheckInterval time.Duration `env:"REDIS_CHECK_INTERVAL"`
+
+	// StartupWait is how long startup retries connecting to Postgres and
+	// Redis before giving up; zero fails on the first error. With
+	// StartupLazyConnect the server starts without them and connects on
+	// first use, reporting degraded health until they are up.
+	StartupWait        time.Duration `env:"STARTUP_WAIT"`
+	StartupLazyConnect bool          `env:"STARTUP_LAZY_CONNECT"`
 
 	// Authentication
-	JWTSecret string
+	JWTSecret string `env:"JWT_SECRET" secret:"true"`
+	// AdminUserIDs may use the /api/v1/admin endpoints. Admins are named
+	// by ID rather than email, so no one can claim the role by getting an
+	// account with an admin's address.
+	AdminUserIDs []uuid.UUID `env:"ADMIN_USER_IDS"`
+	// AdminEmails are told when a spend budget runs out
+	AdminEmails []string `env:"ADMIN_EMAILS"`
+	// ImpersonationEnabled lets admins act as a user for support, through
+	// /api/v1/admin/users/{id}/impersonate
+	ImpersonationEnabled bool `env:"IMPERSONATION_ENABLED"`
+	// Remember-me sessions, kept by a device cookie. Each use extends one
+	// by RememberMeIdle, up to RememberMeMaxLifetime after sign-in; a zero
+	// RememberMeMaxLifetime turns remember-me off.
+	RememberMeIdle        time.Duration `env:"REMEMBER_ME_IDLE"`
+	RememberMeMaxLifetime time.Duration `env:"REMEMBER_ME_MAX_LIFETIME"`
+	// LoginAnomalyDetection emails users about sign-ins from a new device
+	// or country and, unless they opt out, asks for a code to finish them
+	LoginAnomalyDetection bool `env:"LOGIN_ANOMALY_DETECTION"`
+	// GeoIPCountryHeader is set by the CDN in front of the API to the
+	// client's country, such as CF-IPCountry; empty tracks devices only
+	GeoIPCountryHeader string `env:"GEOIP_COUNTRY_HEADER"`
+	// SSOEnabled lets organizations sign their members in through their
+	// own OpenID Connect identity provider
+	SSOEnabled bool `env:"SSO_ENABLED"`
+	// SCIMEnabled serves /scim/v2, through which organizations' identity
+	// providers provision and deprovision their members
+	SCIMEnabled bool `env:"SCIM_ENABLED"`
+	// GraphQLEnabled serves read-only GraphQL queries at /graphql
+	GraphQLEnabled bool `env:"GRAPHQL_ENABLED"`
+
+	// RegistrationMode is "open", or "invite" to require an invitation
+	// code from /api/v1/admin/invites to register
+	RegistrationMode string `env:"REGISTRATION_MODE"`
+
+	// Sign-up abuse protection. CAPTCHA is required on registration and
+	// forgotten password requests when a provider is set.
+	CaptchaProvider       string        `env:"CAPTCHA_PROVIDER"`
+	CaptchaSecret         string        `env:"CAPTCHA_SECRET" secret:"true"`
+	BlockDisposableEmails bool          `env:"BLOCK_DISPOSABLE_EMAILS"`
+	BlockedEmailDomains   []string      `env:"BLOCKED_EMAIL_DOMAINS"`
+	SignupRateLimit       int           `env:"SIGNUP_RATE_LIMIT"`
+	SignupRateWindow      time.Duration `env:"SIGNUP_RATE_WINDOW"`
+
+	// Synchronous quick analyses for the browser extension, limited per
+	// API key; zero disables the limit
+	QuickAnalyzeRateLimit  int           `env:"QUICK_ANALYZE_RATE_LIMIT"`
+	QuickAnalyzeRateWindow time.Duration `env:"QUICK_ANALYZE_RATE_WINDOW"`
+
+	// RateLimitFailMode is "open" to allow requests the sign-up and quick
+	// analysis limits can't count while Redis is down, or "closed" to
+	// refuse them
+	RateLimitFailMode string `env:"RATE_LIMIT_FAIL_MODE"`
+
+	// Monthly analysis quotas as "plan:limit" entries; plans without one
+	// are unlimited. Users are warned by email, and the operator by a
+	// signed webhook when a URL is set, at 80% and 100% of their quota.
+	MonthlyQuotas      []string `env:"MONTHLY_ANALYSIS_QUOTAS"`
+	QuotaWebhookURL    string   `env:"QUOTA_WEBHOOK_URL" secret:"true"`
+	QuotaWebhookSecret string   `env:"QUOTA_WEBHOOK_SECRET" secret:"true"`
+
+	// Words each plan can queue for analysis in one submission, as
+	// "plan:words" entries; plans without one are only held to the
+	// content length limit
+	SubmissionWordLimits []string `env:"SUBMISSION_WORD_LIMITS"`
+
+	// Spend budgets on model usage across the deployment, in US dollars;
+	// zero is no budget. Organizations' budgets are set by admins. While a
+	// budget is used up new analyses are held in the queue or, in reject
+	// mode, refused, and ADMIN_EMAILS are told once per period.
+	CostBudgetDailyUSD   float64 `env:"COST_BUDGET_DAILY_USD"`
+	CostBudgetMonthlyUSD float64 `env:"COST_BUDGET_MONTHLY_USD"`
+	CostBudgetMode       string  `env:"COST_BUDGET_MODE"`
+
+	// Password hashing. Existing hashes are upgraded on the next login.
+	PasswordHashAlgorithm string `env:"PASSWORD_HASH_ALGORITHM"`
+	BcryptCost            int    `env:"BCRYPT_COST"`
+	Argon2MemoryKiB       int    `env:"ARGON2_MEMORY_KIB"`
+	Argon2Iterations      int    `env:"ARGON2_ITERATIONS"`
+	Argon2Parallelism     int    `env:"ARGON2_PARALLELISM"`
 
 	// Server
-	Port           string
-	Environment    string
-	AllowedOrigins []string
+	Port           string   `env:"PORT"`
+	Environment    string   `env:"ENV"`
+	AllowedOrigins []string `env:"ALLOWED_ORIGINS" reload:"true"`
+
+	// Organizations are served on their own subdomains of OrgBaseDomain,
+	// such as acme.analyzer.example.com, when it is set
+	OrgBaseDomain string `env:"ORG_BASE_DOMAIN"`
+
+	// API v1 retirement, announced with Deprecation and Sunset headers
+	APIV1DeprecatedAt time.Time `env:"API_V1_DEPRECATED_AT"`
+	APIV1Sunset       time.Time `env:"API_V1_SUNSET"`
+
+	// TLS: either a certificate and key file, or certificates issued
+	// automatically over ACME for the listed hosts
+	TLSCert              string   `env:"TLS_CERT"`
+	TLSKey               string   `env:"TLS_KEY"`
+	TLSAutocertHosts     []string `env:"TLS_AUTOCERT_HOSTS"`
+	TLSAutocertEmail     string   `env:"TLS_AUTOCERT_EMAIL"`
+	TLSAutocertCacheDir  string   `env:"TLS_AUTOCERT_CACHE_DIR"`
+	TLSAutocertDirectory string   `env:"TLS_AUTOCERT_DIRECTORY_URL"`
+	TLSRedirectAddr      string   `env:"TLS_REDIRECT_ADDR"`
+
+	// Requests to addresses users give: REST hook targets, feeds, quick
+	// analysis pages and SSO providers. Internal addresses are refused
+	// unless allowed; a negative redirect limit follows none.
+	OutboundMaxResponseMB int    `env:"OUTBOUND_MAX_RESPONSE_MB"`
+	OutboundMaxRedirects  int    `env:"OUTBOUND_MAX_REDIRECTS"`
+	OutboundMaxPerHost    int    `env:"OUTBOUND_MAX_PER_HOST"`
+	OutboundAllowPrivate  bool   `env:"OUTBOUND_ALLOW_PRIVATE"`
+	OutboundCAFile        string `env:"OUTBOUND_CA_FILE"`
+	OutboundTLSSkipVerify bool   `env:"OUTBOUND_TLS_SKIP_VERIFY"`
+
+	// Proxies for the Gemini API, webhook deliveries, and feeds and pages
+	// fetched, used instead of HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	GeminiProxyURL  string `env:"GEMINI_PROXY_URL" secret:"true"`
+	WebhookProxyURL string `env:"WEBHOOK_PROXY_URL" secret:"true"`
+	FetchProxyURL   string `env:"FETCH_PROXY_URL" secret:"true"`
+
+	// Metrics
+	MetricsEnabled bool `env:"METRICS_ENABLED"`
+
+	// Logging
+	LogLevel       string  `env:"LOG_LEVEL" reload:"true"`
+	LogFormat      string  `env:"LOG_FORMAT"`
+	LogSampleRate  float64 `env:"LOG_SAMPLE_RATE" reload:"true"`
+	DebugEndpoints bool    `env:"DEBUG_ENDPOINTS"`
+	// AnalysisArtifacts keeps every model call of each analysis, prompts
+	// and responses included, and lets operators read them
+	AnalysisArtifacts bool `env:"ANALYSIS_ARTIFACTS"`
+	// DebugCapture keeps sanitized requests and responses of the routes in
+	// DebugCaptureRoutes, and of users with the debug.capture flag, for
+	// operators to read
+	DebugCapture        bool     `env:"DEBUG_CAPTURE"`
+	DebugCaptureRoutes  []string `env:"DEBUG_CAPTURE_ROUTES" reload:"true"`
+	DebugCaptureSize    int      `env:"DEBUG_CAPTURE_SIZE"`
+	DebugCaptureMaxBody int      `env:"DEBUG_CAPTURE_MAX_BODY"`
+	// ErrorReportingDSN is the Sentry-compatible project that panics and
+	// server errors are reported to; empty reports nothing
+	ErrorReportingDSN string `env:"ERROR_REPORTING_DSN" secret:"true"`
+
+	// Background jobs
+	WorkerConcurrency       int           `env:"WORKER_CONCURRENCY"`
+	HighPriorityBurst       int           `env:"QUEUE_HIGH_PRIORITY_BURST"`
+	QueueMaxDepth           int           `env:"QUEUE_MAX_DEPTH"`
+	QueueVisibilityTimeout  time.Duration `env:"QUEUE_VISIBILITY_TIMEOUT"`
+	TopicClusteringInterval time.Duration `env:"TOPIC_CLUSTERING_INTERVAL"`
+	WeeklyDigestInterval    time.Duration `env:"WEEKLY_DIGEST_INTERVAL"`
+	UsageRollupInterval     time.Duration `env:"USAGE_ROLLUP_INTERVAL"`
+	RetentionPurgeInterval  time.Duration `env:"RETENTION_PURGE_INTERVAL"`
+	TrashRetention          time.Duration `env:"TRASH_RETENTION"`
+	TrashPurgeInterval      time.Duration `env:"TRASH_PURGE_INTERVAL"`
+	ContentTierMonths       int           `env:"CONTENT_TIER_MONTHS"`
+	ContentTierInterval     time.Duration `env:"CONTENT_TIER_INTERVAL"`
+	FeedPollInterval        time.Duration `env:"FEED_POLL_INTERVAL"`
+
+	// Email
+	AppBaseURL         string `env:"APP_BASE_URL"`
+	MailDriver         string `env:"MAIL_DRIVER"`
+	MailFrom           string `env:"MAIL_FROM"`
+	SMTPHost           string `env:"SMTP_HOST"`
+	SMTPPort           int    `env:"SMTP_PORT"`
+	SMTPUsername       string `env:"SMTP_USERNAME"`
+	SMTPPassword       string `env:"SMTP_PASSWORD" secret:"true"`
+	SendGridAPIKey     string `env:"SENDGRID_API_KEY" secret:"true"`
+	AWSRegion          string `env:"AWS_REGION"`
+	AWSAccessKeyID     string `env:"AWS_ACCESS_KEY_ID" secret:"true"`
+	AWSSecretAccessKey string `env:"AWS_SECRET_ACCESS_KEY" secret:"true"`
+
+	// Encryption at rest of submission content and analysis text, with
+	// master keys given as "id:base64key" entries (active key first) or
+	// held in AWS KMS. Content hashes are keyed with the master key, or
+	// with a KMS HMAC key under KMS.
+	EncryptionMasterKeys   []string `env:"ENCRYPTION_MASTER_KEYS" secret:"true"`
+	EncryptionKMSKeyID     string   `env:"ENCRYPTION_KMS_KEY_ID"`
+	EncryptionKMSHMACKeyID string   `env:"ENCRYPTION_KMS_HMAC_KEY_ID"`
+
+	// CORS
+	CORSAllowAll         bool     `env:"CORS_ALLOW_ALL"`
+	CORSAllowCredentials bool     `env:"CORS_ALLOW_CREDENTIALS"`
+	CORSExposedHeaders   []string `env:"CORS_EXPOSED_HEADERS"`
+
+	// parseErrors records variables Read couldn't parse
+	parseErrors ValidationErrors
 }
 
-// Load reads configuration from environment variables
+// Load reads configuration from environment variables and validates it
 // In development, it loads from .env file
 func Load() (*Config, error) {
-	// Load .env file only in non-production environments
-	env := os.Getenv("ENV")
-	if env != "production" {
-		// Attempt to load from current directory, then parent directory
-		_ = godotenv.Load()
-		_ = godotenv.Load("../.env")
+	cfg := Read()
+
+	// Validate required configuration
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
 
+	return cfg, nil
+}
+
+// Read resolves configuration from environment variables and any
+// configuration files without validating it. Values that fail to parse are
+// reported by Validate.
+func Read() *Config {
+	// Variables already in the environment take precedence over the files
+	for _, file := range envFiles() {
+		_ = godotenv.Load(file)
+	}
+
+	return readEnv()
+}
+
+// envFiles returns the KEY=value files configuration is loaded from, in
+// precedence order. The .env files are only used outside production.
+func envFiles() []string {
+	var files []string
+	if file := os.Getenv("CONFIG_FILE"); file != "" {
+		files = append(files, file)
+	}
+	if os.Getenv("ENV") != "production" {
+		// Current directory, then parent directory
+		files = append(files, ".env", "../.env")
+	}
+	return files
+}
+
+// readEnv builds a Config from the current environment
+func readEnv() *Config {
+	env := &envReader{}
 	cfg := &Config{
-		GeminiAPIKey: os.Getenv("GEMINI_API_KEY"),
-		DatabaseURL:  os.Getenv("DATABASE_URL"),
-		RedisURL:     os.Getenv("REDIS_URL"),
-		JWTSecret:    os.Getenv("JWT_SECRET"),
-		Port:         getEnvOrDefault("PORT", "8080"),
-		Environment:  getEnvOrDefault("ENV", "development"),
+		ConfigFile:                   os.Getenv("CONFIG_FILE"),
+		GeminiAPIKey:                 os.Getenv("GEMINI_API_KEY"),
+		GeminiModel:                  getEnvOrDefault("GEMINI_MODEL", "gemini-2.0-flash"),
+		GeminiEmbeddingModel:         getEnvOrDefault("GEMINI_EMBEDDING_MODEL", "text-embedding-004"),
+		GeminiInputPrice:             env.asFloat("GEMINI_INPUT_PRICE", 0.10),
+		GeminiOutputPrice:            env.asFloat("GEMINI_OUTPUT_PRICE", 0.40),
+		GeminiResponseSchema:         env.asBool("GEMINI_RESPONSE_SCHEMA", true),
+		AIRepairAttempts:             env.asInt("AI_REPAIR_ATTEMPTS", 2),
+		AIReplayMode:                 os.Getenv("AI_REPLAY_MODE"),
+		AIReplayDir:                  getEnvOrDefault("AI_REPLAY_DIR", "data/ai-recordings"),
+		AnalysisVerification:         env.asBool("ANALYSIS_VERIFICATION", true),
+		ClaimExtraction:              env.asBool("CLAIM_EXTRACTION", false),
+		Proofreading:                 env.asBool("PROOFREADING", true),
+		BiasAnalysis:                 env.asBool("BIAS_ANALYSIS", false),
+		AIDetection:                  env.asBool("AI_DETECTION", false),
+		Moderation:                   env.asBool("MODERATION", false),
+		PerplexityURL:                os.Getenv("PERPLEXITY_URL"),
+		PerplexityAPIKey:             os.Getenv("PERPLEXITY_API_KEY"),
+		PerplexityModel:              getEnvOrDefault("PERPLEXITY_MODEL", "gpt2"),
+		DatabaseURL:                  os.Getenv("DATABASE_URL"),
+		DatabaseReplicaURL:           os.Getenv("DATABASE_REPLICA_URL"),
+		DatabaseReplicaCheckInterval: env.asDuration("DATABASE_REPLICA_CHECK_INTERVAL", 5*time.Second),
+		SlowQueryThreshold:           env.asDuration("DB_SLOW_QUERY_THRESHOLD", 200*time.Millisecond),
+		MetricsEnabled:               env.asBool("METRICS_ENABLED", true),
+		RedisURL:                     os.Getenv("REDIS_URL"),
+		LocalCacheSize:               env.asInt("LOCAL_CACHE_SIZE", 10000),
+		LocalCacheTTL:                env.asDuration("LOCAL_CACHE_TTL", 30*time.Second),
+		RedisCheckInterval:           env.asDuration("REDIS_CHECK_INTERVAL", 5*time.Second),
+		StartupWait:                  env.asDuration("STARTUP_WAIT", 30*time.Second),
+		StartupLazyConnect:           env.asBool("STARTUP_LAZY_CONNECT", false),
+		JWTSecret:                    os.Getenv("JWT_SECRET"),
+		PasswordHashAlgorithm:        getEnvOrDefault("PASSWORD_HASH_ALGORITHM", string(models.DefaultPasswordParams.Algorithm)),
+		BcryptCost:                   env.asInt("BCRYPT_COST", models.DefaultPasswordParams.BcryptCost),
+		Argon2MemoryKiB:              env.asInt("ARGON2_MEMORY_KIB", int(models.DefaultPasswordParams.Argon2Memory)),
+		Argon2Iterations:             env.asInt("ARGON2_ITERATIONS", int(models.DefaultPasswordParams.Argon2Iterations)),
+		Argon2Parallelism:            env.asInt("ARGON2_PARALLELISM", int(models.DefaultPasswordParams.Argon2Parallelism)),
+		Port:                         getEnvOrDefault("PORT", "8080"),
+		Environment:                  getEnvOrDefault("ENV", "development"),
+		WorkerConcurrency:            env.asInt("WORKER_CONCURRENCY", 4),
+		HighPriorityBurst:            env.asInt("QUEUE_HIGH_PRIORITY_BURST", 4),
+		QueueMaxDepth:                env.asInt("QUEUE_MAX_DEPTH", 10000),
+		QueueVisibilityTimeout:       env.asDuration("QUEUE_VISIBILITY_TIMEOUT", 30*time.Minute),
+		AnalyzerConcurrency:          env.asInt("ANALYZER_CONCURRENCY", 0),
+		GeminiConcurrency:            env.asInt("GEMINI_CONCURRENCY", 0),
+		FactCheckConcurrency:         env.asInt("FACT_CHECK_CONCURRENCY", 0),
+		PerplexityConcurrency:        env.asInt("PERPLEXITY_CONCURRENCY", 0),
+		AnalysisTimeout:              env.asDuration("ANALYSIS_TIMEOUT", 10*time.Minute),
+		TopicClusteringInterval:      env.asDuration("TOPIC_CLUSTERING_INTERVAL", 24*time.Hour),
+		WeeklyDigestInterval:         env.asDuration("WEEKLY_DIGEST_INTERVAL", 7*24*time.Hour),
+		UsageRollupInterval:          env.asDuration("USAGE_ROLLUP_INTERVAL", 24*time.Hour),
+		RetentionPurgeInterval:       env.asDuration("RETENTION_PURGE_INTERVAL", 24*time.Hour),
+		TrashRetention:               env.asDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval:           env.asDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		ContentTierMonths:            env.asInt("CONTENT_TIER_MONTHS", 0),
+		ContentTierInterval:          env.asDuration("CONTENT_TIER_INTERVAL", 24*time.Hour),
+		FeedPollInterval:             env.asDuration("FEED_POLL_INTERVAL", 15*time.Minute),
+		AppBaseURL:                   getEnvOrDefault("APP_BASE_URL", "http://localhost:3000"),
+		MailDriver:                   getEnvOrDefault("MAIL_DRIVER", "log"),
+		MailFrom:                     getEnvOrDefault("MAIL_FROM", "Content Analyzer <no-reply@localhost>"),
+		SMTPHost:                     os.Getenv("SMTP_HOST"),
+		SMTPPort:                     env.asInt("SMTP_PORT", 587),
+		SMTPUsername:                 os.Getenv("SMTP_USERNAME"),
+		SMTPPassword:                 os.Getenv("SMTP_PASSWORD"),
+		SendGridAPIKey:               os.Getenv("SENDGRID_API_KEY"),
+		AWSRegion:                    getEnvOrDefault("AWS_REGION", "us-east-1"),
+		AWSAccessKeyID:               os.Getenv("AWS_ACCESS_KEY_ID"),
+		AWSSecretAccessKey:           os.Getenv("AWS_SECRET_ACCESS_KEY"),
 	}
 
 	// Parse allowed origins (comma-separated)
@@ -56,35 +482,1037 @@ func Load() (*Config, error) {
 		cfg.AllowedOrigins = []string{"http://localhost:3000", "http://localhost:8080"}
 	}
 
-	// Validate required configuration
-	if err := cfg.Validate(); err != nil {
-		return nil, err
+	cfg.OrgBaseDomain = strings.ToLower(os.Getenv("ORG_BASE_DOMAIN"))
+
+	cfg.APIV1DeprecatedAt = env.asDate("API_V1_DEPRECATED_AT")
+	cfg.APIV1Sunset = env.asDate("API_V1_SUNSET")
+
+	// TLS termination
+	cfg.TLSCert = os.Getenv("TLS_CERT")
+	cfg.TLSKey = os.Getenv("TLS_KEY")
+	cfg.TLSAutocertHosts = parseCommaSeparated(strings.ToLower(os.Getenv("TLS_AUTOCERT_HOSTS")))
+	cfg.TLSAutocertEmail = os.Getenv("TLS_AUTOCERT_EMAIL")
+	cfg.TLSAutocertCacheDir = getEnvOrDefault("TLS_AUTOCERT_CACHE_DIR", "./certs")
+	cfg.TLSAutocertDirectory = getEnvOrDefault("TLS_AUTOCERT_DIRECTORY_URL", acme.LetsEncryptURL)
+	cfg.TLSRedirectAddr = os.Getenv("TLS_REDIRECT_ADDR")
+
+	// Outbound requests
+	cfg.OutboundMaxResponseMB = env.asInt("OUTBOUND_MAX_RESPONSE_MB", outbound.DefaultMaxResponseBytes>>20)
+	cfg.OutboundMaxRedirects = env.asInt("OUTBOUND_MAX_REDIRECTS", outbound.DefaultMaxRedirects)
+	cfg.OutboundMaxPerHost = env.asInt("OUTBOUND_MAX_PER_HOST", outbound.DefaultMaxPerHost)
+	cfg.OutboundAllowPrivate = env.asBool("OUTBOUND_ALLOW_PRIVATE", false)
+	cfg.OutboundCAFile = os.Getenv("OUTBOUND_CA_FILE")
+	cfg.OutboundTLSSkipVerify = env.asBool("OUTBOUND_TLS_SKIP_VERIFY", false)
+	cfg.GeminiProxyURL = os.Getenv("GEMINI_PROXY_URL")
+	cfg.WebhookProxyURL = os.Getenv("WEBHOOK_PROXY_URL")
+	cfg.FetchProxyURL = os.Getenv("FETCH_PROXY_URL")
+
+	cfg.EncryptionMasterKeys = parseCommaSeparated(os.Getenv("ENCRYPTION_MASTER_KEYS"))
+	cfg.EncryptionKMSKeyID = os.Getenv("ENCRYPTION_KMS_KEY_ID")
+	cfg.EncryptionKMSHMACKeyID = os.Getenv("ENCRYPTION_KMS_HMAC_KEY_ID")
+
+	// Logging defaults depend on the environment
+	if cfg.IsProduction() {
+		cfg.LogLevel = getEnvOrDefault("LOG_LEVEL", "info")
+		cfg.LogFormat = getEnvOrDefault("LOG_FORMAT", logging.FormatJSON)
+	} else {
+		cfg.LogLevel = getEnvOrDefault("LOG_LEVEL", "debug")
+		cfg.LogFormat = getEnvOrDefault("LOG_FORMAT", logging.FormatText)
 	}
+	cfg.LogSampleRate = env.asFloat("LOG_SAMPLE_RATE", 1)
+	cfg.DebugEndpoints = env.asBool("DEBUG_ENDPOINTS", !cfg.IsProduction())
+	cfg.AnalysisArtifacts = env.asBool("ANALYSIS_ARTIFACTS", false)
+	cfg.DebugCapture = env.asBool("DEBUG_CAPTURE", false)
+	cfg.DebugCaptureRoutes = parseCommaSeparated(os.Getenv("DEBUG_CAPTURE_ROUTES"))
+	cfg.DebugCaptureSize = env.asInt("DEBUG_CAPTURE_SIZE", capture.DefaultSize)
+	cfg.DebugCaptureMaxBody = env.asInt("DEBUG_CAPTURE_MAX_BODY", capture.DefaultMaxBody)
+	cfg.ErrorReportingDSN = os.Getenv("ERROR_REPORTING_DSN")
 
-	return cfg, nil
+	cfg.AdminUserIDs = env.asUUIDs("ADMIN_USER_IDS")
+	cfg.AdminEmails = parseCommaSeparated(os.Getenv("ADMIN_EMAILS"))
+	cfg.ImpersonationEnabled = env.asBool("IMPERSONATION_ENABLED", true)
+	cfg.RememberMeIdle = env.asDuration("REMEMBER_ME_IDLE", 30*24*time.Hour)
+	cfg.RememberMeMaxLifetime = env.asDuration("REMEMBER_ME_MAX_LIFETIME", 90*24*time.Hour)
+	cfg.LoginAnomalyDetection = env.asBool("LOGIN_ANOMALY_DETECTION", true)
+	cfg.GeoIPCountryHeader = getEnvOrDefault("GEOIP_COUNTRY_HEADER", "")
+	cfg.SSOEnabled = env.asBool("SSO_ENABLED", true)
+	cfg.SCIMEnabled = env.asBool("SCIM_ENABLED", true)
+	cfg.GraphQLEnabled = env.asBool("GRAPHQL_ENABLED", false)
+
+	cfg.RegistrationMode = strings.ToLower(getEnvOrDefault("REGISTRATION_MODE", RegistrationOpen))
+
+	// Sign-up abuse protection
+	cfg.CaptchaProvider = strings.ToLower(os.Getenv("CAPTCHA_PROVIDER"))
+	cfg.CaptchaSecret = os.Getenv("CAPTCHA_SECRET")
+	cfg.BlockDisposableEmails = env.asBool("BLOCK_DISPOSABLE_EMAILS", true)
+	cfg.BlockedEmailDomains = parseCommaSeparated(strings.ToLower(os.Getenv("BLOCKED_EMAIL_DOMAINS")))
+	cfg.SignupRateLimit = env.asInt("SIGNUP_RATE_LIMIT", 5)
+	cfg.SignupRateWindow = env.asDuration("SIGNUP_RATE_WINDOW", time.Hour)
+
+	// Quick analyses
+	cfg.QuickAnalyzeRateLimit = env.asInt("QUICK_ANALYZE_RATE_LIMIT", 10)
+	cfg.QuickAnalyzeRateWindow = env.asDuration("QUICK_ANALYZE_RATE_WINDOW", time.Minute)
+	cfg.RateLimitFailMode = strings.ToLower(getEnvOrDefault("RATE_LIMIT_FAIL_MODE", RateLimitFailOpen))
+
+	// Audio and video transcription
+	cfg.TranscriptionProvider = strings.ToLower(os.Getenv("TRANSCRIPTION_PROVIDER"))
+	cfg.TranscriptionAPIKey = os.Getenv("TRANSCRIPTION_API_KEY")
+	cfg.TranscriptionBaseURL = os.Getenv("TRANSCRIPTION_BASE_URL")
+	cfg.TranscriptionModel = os.Getenv("TRANSCRIPTION_MODEL")
+	cfg.MediaUploadMaxMB = env.asInt("MEDIA_UPLOAD_MAX_MB", 25)
+
+	// Bulk imports
+	cfg.ImportMaxMB = env.asInt("IMPORT_MAX_MB", 10)
+
+	// Object storage
+	cfg.StorageDriver = strings.ToLower(os.Getenv("STORAGE_DRIVER"))
+	cfg.StorageDir = getEnvOrDefault("STORAGE_DIR", "data/storage")
+	cfg.StorageBaseURL = getEnvOrDefault("STORAGE_BASE_URL", "http://localhost:"+cfg.Port)
+	cfg.StorageBucket = os.Getenv("STORAGE_BUCKET")
+	cfg.StorageRegion = getEnvOrDefault("STORAGE_REGION", cfg.AWSRegion)
+	cfg.StorageEndpoint = os.Getenv("STORAGE_ENDPOINT")
+	cfg.StorageAccessKeyID = getEnvOrDefault("STORAGE_ACCESS_KEY_ID", cfg.AWSAccessKeyID)
+	cfg.StorageSecretAccessKey = getEnvOrDefault("STORAGE_SECRET_ACCESS_KEY", cfg.AWSSecretAccessKey)
+	cfg.UploadMaxMB = env.asInt("UPLOAD_MAX_MB", 100)
+
+	// Fact-checking
+	cfg.FactCheckProvider = strings.ToLower(os.Getenv("FACT_CHECK_PROVIDER"))
+	cfg.FactCheckAPIKey = os.Getenv("FACT_CHECK_API_KEY")
+	cfg.FactCheckBaseURL = os.Getenv("FACT_CHECK_BASE_URL")
+	cfg.FactCheckMaxResults = env.asInt("FACT_CHECK_MAX_RESULTS", factcheck.DefaultMaxResults)
+
+	// Event broker
+	cfg.EventBroker = strings.ToLower(os.Getenv("EVENT_BROKER"))
+	cfg.EventBrokerURL = os.Getenv("EVENT_BROKER_URL")
+	cfg.EventBrokerTopic = getEnvOrDefault("EVENT_BROKER_TOPIC", broker.DefaultTopic)
+	cfg.EventSource = getEnvOrDefault("EVENT_SOURCE", broker.DefaultSource)
+
+	// Analysis pipeline
+	cfg.AnalysisStagesDisabled = parseCommaSeparated(strings.ToLower(os.Getenv("ANALYSIS_STAGES_DISABLED")))
+	cfg.AnalysisStageTimeouts = parseCommaSeparated(strings.ToLower(os.Getenv("ANALYSIS_STAGE_TIMEOUTS")))
+
+	// Usage quotas
+	cfg.MonthlyQuotas = parseCommaSeparated(strings.ToLower(os.Getenv("MONTHLY_ANALYSIS_QUOTAS")))
+	cfg.QuotaWebhookURL = os.Getenv("QUOTA_WEBHOOK_URL")
+	cfg.QuotaWebhookSecret = os.Getenv("QUOTA_WEBHOOK_SECRET")
+	cfg.SubmissionWordLimits = parseCommaSeparated(strings.ToLower(os.Getenv("SUBMISSION_WORD_LIMITS")))
+
+	// Spend budgets
+	cfg.CostBudgetDailyUSD = env.asFloat("COST_BUDGET_DAILY_USD", 0)
+	cfg.CostBudgetMonthlyUSD = env.asFloat("COST_BUDGET_MONTHLY_USD", 0)
+	cfg.CostBudgetMode = strings.ToLower(getEnvOrDefault("COST_BUDGET_MODE", spend.ModeHold))
+
+	// CORS policy
+	cfg.CORSAllowAll = env.asBool("CORS_ALLOW_ALL", false)
+	cfg.CORSAllowCredentials = env.asBool("CORS_ALLOW_CREDENTIALS", true)
+	cfg.CORSExposedHeaders = parseCommaSeparated(getEnvOrDefault("CORS_EXPOSED_HEADERS", "Link,ETag,API-Version,Deprecation,Sunset,X-Quota-Limit,X-Quota-Remaining,X-Quota-Reset,Retry-After"))
+
+	cfg.parseErrors = env.errs
+
+	return cfg
 }
 
-// Validate checks that all required configuration is present
+// Validate checks the configuration and reports every missing or invalid
+// variable at once as ValidationErrors
 func (c *Config) Validate() error {
-	if c.GeminiAPIKey == "" {
-		return fmt.Errorf("GEMINI_API_KEY environment variable is required")
+	errs := append(ValidationErrors{}, c.parseErrors...)
+
+	required := []struct {
+		key   string
+		value string
+	}{
+		{"GEMINI_API_KEY", c.GeminiAPIKey},
+		{"DATABASE_URL", c.DatabaseURL},
+		{"REDIS_URL", c.RedisURL},
+		{"JWT_SECRET", c.JWTSecret},
+	}
+	for _, r := range required {
+		// Replayed responses need no API key
+		if r.key == "GEMINI_API_KEY" && c.AIReplayMode == ai.ReplayReplay {
+			continue
+		}
+		if r.value == "" {
+			errs.add(r.key, "%s environment variable is required", r.key)
+		}
+	}
+
+	// Validate JWT secret length (should be at least 32 characters for security)
+	if c.JWTSecret != "" && len(c.JWTSecret) < 32 {
+		errs.add("JWT_SECRET", "JWT_SECRET must be at least 32 characters long")
 	}
 
-	if c.DatabaseURL == "" {
-		return fmt.Errorf("DATABASE_URL environment variable is required")
+	if h := c.OrgBaseDomain; h != "" && (strings.ContainsAny(h, ":/*") || strings.Trim(h, ".") != h || !strings.Contains(h, ".")) {
+		errs.add("ORG_BASE_DOMAIN", "ORG_BASE_DOMAIN must be a host name, such as analyzer.example.com")
 	}
 
-	if c.RedisURL == "" {
-		return fmt.Errorf("REDIS_URL environment variable is required")
+	c.validateLogging(&errs)
+	c.validateMail(&errs)
+	c.validatePasswordHashing(&errs)
+	c.validateRememberMe(&errs)
+	c.validateLoginAnomalyDetection(&errs)
+	c.validateTLS(&errs)
+	c.validateOutbound(&errs)
+	c.validateEncryption(&errs)
+	c.validateAbuseProtection(&errs)
+	c.validateQuotas(&errs)
+	c.validateSpendBudget(&errs)
+	c.validateTranscription(&errs)
+	c.validateFactCheck(&errs)
+	c.validateEventBroker(&errs)
+	c.validateStorage(&errs)
+
+	if c.TrashRetention < 0 {
+		errs.add("TRASH_RETENTION", "TRASH_RETENTION cannot be negative")
+	}
+	if c.ContentTierMonths < 0 {
+		errs.add("CONTENT_TIER_MONTHS", "CONTENT_TIER_MONTHS cannot be negative")
+	} else if c.ContentTierMonths > 0 && c.StorageDriver == "" {
+		errs.add("CONTENT_TIER_MONTHS", "CONTENT_TIER_MONTHS requires STORAGE_DRIVER")
 	}
 
-	if c.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET environment variable is required")
+	if c.PerplexityURL != "" {
+		if u, err := url.Parse(c.PerplexityURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("PERPLEXITY_URL", "PERPLEXITY_URL must be an http or https URL")
+		}
 	}
 
-	// Validate JWT secret length (should be at least 32 characters for security)
-	if len(c.JWTSecret) < 32 {
-		return fmt.Errorf("JWT_SECRET must be at least 32 characters long")
+	c.validateConcurrency(&errs)
+	c.validateStages(&errs)
+
+	if c.ImportMaxMB < 0 {
+		errs.add("IMPORT_MAX_MB", "IMPORT_MAX_MB cannot be negative")
+	}
+
+	if c.GeminiInputPrice < 0 || c.GeminiOutputPrice < 0 {
+		errs.add("GEMINI_INPUT_PRICE", "GEMINI_INPUT_PRICE and GEMINI_OUTPUT_PRICE cannot be negative")
+	}
+	if c.AIRepairAttempts < 0 {
+		errs.add("AI_REPAIR_ATTEMPTS", "AI_REPAIR_ATTEMPTS cannot be negative")
+	}
+	c.validateReplay(&errs)
+
+	if c.LocalCacheSize < 0 {
+		errs.add("LOCAL_CACHE_SIZE", "LOCAL_CACHE_SIZE cannot be negative")
+	}
+	if c.LocalCacheTTL < 0 {
+		errs.add("LOCAL_CACHE_TTL", "LOCAL_CACHE_TTL cannot be negative")
+	}
+	if c.RedisCheckInterval < 0 {
+		errs.add("REDIS_CHECK_INTERVAL", "REDIS_CHECK_INTERVAL cannot be negative")
+	}
+	if c.StartupWait < 0 {
+		errs.add("STARTUP_WAIT", "STARTUP_WAIT cannot be negative")
+	}
+
+	if !c.APIV1Sunset.IsZero() && !c.APIV1Sunset.After(c.APIV1DeprecatedAt) {
+		errs.add("API_V1_SUNSET", "API_V1_SUNSET must be after API_V1_DEPRECATED_AT")
+	}
+
+	// Allowing every origin is a development convenience only
+	if c.CORSAllowAll && c.IsProduction() {
+		errs.add("CORS_ALLOW_ALL", "CORS_ALLOW_ALL cannot be enabled in production")
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if err := ValidateOriginPattern(origin); err != nil {
+			errs.add("ALLOWED_ORIGINS", "ALLOWED_ORIGINS contains an invalid origin %q: %v", origin, err)
+		}
+	}
+
+	return errs.err()
+}
+
+// validateReplay checks the replay mode. Replayed responses are canned, so
+// they are kept out of production.
+func (c *Config) validateReplay(errs *ValidationErrors) {
+	if c.AIReplayMode == "" {
+		return
+	}
+	if !ai.ValidReplayMode(c.AIReplayMode) {
+		errs.add("AI_REPLAY_MODE", "AI_REPLAY_MODE must be one of: %s", strings.Join(ai.ReplayModes, ", "))
+		return
+	}
+	if c.AIReplayDir == "" {
+		errs.add("AI_REPLAY_DIR", "AI_REPLAY_DIR is required when AI_REPLAY_MODE is set")
+	}
+	if c.IsProduction() {
+		errs.add("AI_REPLAY_MODE", "AI_REPLAY_MODE cannot be set in production")
+	}
+}
+
+// validateTLS checks that at most one certificate source is configured and
+// that it is complete
+func (c *Config) validateTLS(errs *ValidationErrors) {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		errs.add("TLS_CERT", "TLS_CERT and TLS_KEY must be set together")
+	}
+	if c.TLSCert != "" && len(c.TLSAutocertHosts) > 0 {
+		errs.add("TLS_AUTOCERT_HOSTS", "TLS_AUTOCERT_HOSTS cannot be combined with TLS_CERT and TLS_KEY")
+	}
+
+	for _, host := range c.TLSAutocertHosts {
+		if strings.ContainsAny(host, ":/*") || strings.Trim(host, ".") != host || !strings.Contains(host, ".") {
+			errs.add("TLS_AUTOCERT_HOSTS", "TLS_AUTOCERT_HOSTS contains an invalid host name %q", host)
+		}
+	}
+	if len(c.TLSAutocertHosts) > 0 {
+		if u, err := url.Parse(c.TLSAutocertDirectory); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs.add("TLS_AUTOCERT_DIRECTORY_URL", "TLS_AUTOCERT_DIRECTORY_URL must be an https URL")
+		}
+	}
+
+	if c.TLSRedirectAddr != "" && !c.TLSEnabled() {
+		errs.add("TLS_REDIRECT_ADDR", "TLS_REDIRECT_ADDR requires TLS_CERT and TLS_KEY or TLS_AUTOCERT_HOSTS")
+	}
+}
+
+// TLSEnabled reports whether the server terminates TLS itself
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCert != "" || len(c.TLSAutocertHosts) > 0
+}
+
+// validateOutbound checks the limits on outbound requests and the proxies,
+// and that the protections aren't turned off in production
+func (c *Config) validateOutbound(errs *ValidationErrors) {
+	if c.OutboundMaxResponseMB < 0 {
+		errs.add("OUTBOUND_MAX_RESPONSE_MB", "OUTBOUND_MAX_RESPONSE_MB cannot be negative")
+	}
+	if c.OutboundMaxPerHost < 0 {
+		errs.add("OUTBOUND_MAX_PER_HOST", "OUTBOUND_MAX_PER_HOST cannot be negative")
+	}
+	if c.OutboundCAFile != "" {
+		if _, err := os.Stat(c.OutboundCAFile); err != nil {
+			errs.add("OUTBOUND_CA_FILE", "OUTBOUND_CA_FILE cannot be read: %v", err)
+		}
+	}
+	for _, proxy := range []struct{ key, value string }{
+		{"GEMINI_PROXY_URL", c.GeminiProxyURL},
+		{"WEBHOOK_PROXY_URL", c.WebhookProxyURL},
+		{"FETCH_PROXY_URL", c.FetchProxyURL},
+	} {
+		if proxy.value == "" {
+			continue
+		}
+		if u, err := url.Parse(proxy.value); err != nil || !slices.Contains([]string{"http", "https", "socks5"}, u.Scheme) || u.Host == "" {
+			errs.add(proxy.key, "%s must be an http, https or socks5 URL", proxy.key)
+		}
+	}
+	if c.IsProduction() {
+		if c.OutboundAllowPrivate {
+			errs.add("OUTBOUND_ALLOW_PRIVATE", "OUTBOUND_ALLOW_PRIVATE cannot be enabled in production")
+		}
+		if c.OutboundTLSSkipVerify {
+			errs.add("OUTBOUND_TLS_SKIP_VERIFY", "OUTBOUND_TLS_SKIP_VERIFY cannot be enabled in production")
+		}
+	}
+}
+
+// Outbound returns the client for requests to addresses users give, with
+// the package defaults for zero limits. Call it on a validated config.
+func (c *Config) Outbound() (*outbound.Client, error) {
+	cfg := outbound.Config{
+		MaxResponseBytes:   int64(c.OutboundMaxResponseMB) << 20,
+		MaxRedirects:       c.OutboundMaxRedirects,
+		MaxPerHost:         c.OutboundMaxPerHost,
+		AllowPrivate:       c.OutboundAllowPrivate,
+		InsecureSkipVerify: c.OutboundTLSSkipVerify,
+	}
+	if c.OutboundCAFile != "" {
+		pem, err := os.ReadFile(c.OutboundCAFile)
+		if err != nil {
+			return nil, err
+		}
+		// The file's certificates are trusted besides the system's
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", c.OutboundCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return outbound.New(cfg), nil
+}
+
+// proxy parses a validated proxy URL, or returns nil when none is set
+func proxy(raw string) *url.URL {
+	if raw == "" {
+		return nil
+	}
+	u, _ := url.Parse(raw)
+	return u
+}
+
+// proxiedClient returns a client with timeout going through the proxy at
+// raw, or nil when none is set
+func proxiedClient(raw string, timeout time.Duration) *http.Client {
+	u := proxy(raw)
+	if u == nil {
+		return nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(u)
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// GeminiHTTPClient returns the client for the Gemini API when it has its
+// own proxy, or nil for the default one
+func (c *Config) GeminiHTTPClient() *http.Client {
+	return proxiedClient(c.GeminiProxyURL, 60*time.Second)
+}
+
+// WebhookProxy is the proxy for REST hook deliveries, or nil
+func (c *Config) WebhookProxy() *url.URL {
+	return proxy(c.WebhookProxyURL)
+}
+
+// QuotaWebhookHTTPClient returns the client for the quota webhook when
+// webhooks have their own proxy, or nil for the default one
+func (c *Config) QuotaWebhookHTTPClient() *http.Client {
+	return proxiedClient(c.WebhookProxyURL, 10*time.Second)
+}
+
+// FetchProxy is the proxy for feeds and pages fetched, or nil
+func (c *Config) FetchProxy() *url.URL {
+	return proxy(c.FetchProxyURL)
+}
+
+// validateLogging checks the log level, format and sample rate, error
+// reporting and debug capture
+func (c *Config) validateLogging(errs *ValidationErrors) {
+	if c.LogLevel != "" {
+		if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+			errs.add("LOG_LEVEL", "invalid LOG_LEVEL: %v", err)
+		}
+	}
+
+	if c.LogFormat != "" {
+		if err := logging.ValidateFormat(c.LogFormat); err != nil {
+			errs.add("LOG_FORMAT", "invalid LOG_FORMAT: %v", err)
+		}
+	}
+
+	if c.LogSampleRate < 0 || c.LogSampleRate > 1 {
+		errs.add("LOG_SAMPLE_RATE", "LOG_SAMPLE_RATE must be between 0 and 1")
+	}
+
+	if c.ErrorReportingDSN != "" {
+		if err := errreport.ValidateDSN(c.ErrorReportingDSN); err != nil {
+			errs.add("ERROR_REPORTING_DSN", "invalid ERROR_REPORTING_DSN: %v", err)
+		}
+	}
+
+	if c.DebugCapture {
+		if c.DebugCaptureSize < 1 {
+			errs.add("DEBUG_CAPTURE_SIZE", "DEBUG_CAPTURE_SIZE must be at least 1")
+		}
+		if c.DebugCaptureMaxBody < 1 {
+			errs.add("DEBUG_CAPTURE_MAX_BODY", "DEBUG_CAPTURE_MAX_BODY must be at least 1")
+		}
+	}
+	for _, route := range c.DebugCaptureRoutes {
+		if !strings.HasPrefix(route, "/") {
+			errs.add("DEBUG_CAPTURE_ROUTES", "DEBUG_CAPTURE_ROUTES contains %q; routes must start with /", route)
+		}
+	}
+}
+
+// validatePasswordHashing checks the password hashing parameters
+func (c *Config) validatePasswordHashing(errs *ValidationErrors) {
+	if c.PasswordHashAlgorithm == "" {
+		return
+	}
+
+	if c.Argon2MemoryKiB < 0 || c.Argon2Iterations < 0 || c.Argon2Parallelism < 0 {
+		errs.add("ARGON2_PARALLELISM", "ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM must not be negative")
+		return
+	}
+	if c.Argon2Parallelism > 255 {
+		errs.add("ARGON2_PARALLELISM", "ARGON2_PARALLELISM must be at most 255")
+		return
+	}
+
+	if err := c.PasswordParams().Validate(); err != nil {
+		errs.add("PASSWORD_HASH_ALGORITHM", "invalid password hashing settings: %v", err)
+	}
+}
+
+// validateEncryption checks that at most one master key source is
+// configured and that it is usable
+func (c *Config) validateEncryption(errs *ValidationErrors) {
+	if len(c.EncryptionMasterKeys) > 0 {
+		if c.EncryptionKMSKeyID != "" {
+			errs.add("ENCRYPTION_KMS_KEY_ID", "ENCRYPTION_KMS_KEY_ID cannot be combined with ENCRYPTION_MASTER_KEYS")
+		}
+		if _, err := encryption.ParseMasterKeys(c.EncryptionMasterKeys); err != nil {
+			errs.add("ENCRYPTION_MASTER_KEYS", "invalid ENCRYPTION_MASTER_KEYS: %v", err)
+		}
+	}
+
+	if c.EncryptionKMSKeyID != "" && (c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+		errs.add("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when ENCRYPTION_KMS_KEY_ID is set")
+	}
+	if c.EncryptionKMSKeyID != "" && c.EncryptionKMSHMACKeyID == "" {
+		errs.add("ENCRYPTION_KMS_HMAC_KEY_ID", "ENCRYPTION_KMS_HMAC_KEY_ID is required when ENCRYPTION_KMS_KEY_ID is set")
+	}
+}
+
+// ErrorReporter returns the reporter for panics and server errors, which
+// discards them when no DSN is set. Call it on a validated config.
+func (c *Config) ErrorReporter() (errreport.Reporter, error) {
+	return errreport.New(c.ErrorReportingDSN, c.Environment)
+}
+
+// Encryptor returns the encryptor for content at rest, or nil when
+// encryption is disabled. Call it on a validated config.
+func (c *Config) Encryptor() (*encryption.Encryptor, error) {
+	switch {
+	case c.EncryptionKMSKeyID != "":
+		kms := encryption.NewKMS(c.AWSRegion, c.AWSAccessKeyID, c.AWSSecretAccessKey, c.EncryptionKMSKeyID).
+			WithHMACKey(c.EncryptionKMSHMACKeyID)
+		return encryption.New(kms), nil
+	case len(c.EncryptionMasterKeys) > 0:
+		keys, err := encryption.ParseMasterKeys(c.EncryptionMasterKeys)
+		if err != nil {
+			return nil, err
+		}
+		return encryption.New(keys), nil
+	}
+	return nil, nil
+}
+
+// Registration modes
+const (
+	RegistrationOpen   = "open"
+	RegistrationInvite = "invite"
+)
+
+// Rate limit fail modes
+const (
+	RateLimitFailOpen   = "open"
+	RateLimitFailClosed = "closed"
+)
+
+// InviteOnly reports whether registering needs an invitation code
+func (c *Config) InviteOnly() bool {
+	return c.RegistrationMode == RegistrationInvite
+}
+
+// validateAbuseProtection checks the registration mode, CAPTCHA provider,
+// sign-up limit and quick analysis limit
+func (c *Config) validateAbuseProtection(errs *ValidationErrors) {
+	switch c.RegistrationMode {
+	case "", RegistrationOpen, RegistrationInvite:
+	default:
+		errs.add("REGISTRATION_MODE", "REGISTRATION_MODE must be one of: open, invite")
+	}
+
+	if c.CaptchaProvider != "" {
+		if !abuse.ValidProvider(c.CaptchaProvider) {
+			errs.add("CAPTCHA_PROVIDER", "CAPTCHA_PROVIDER must be one of: hcaptcha, turnstile")
+		} else if c.CaptchaSecret == "" {
+			errs.add("CAPTCHA_SECRET", "CAPTCHA_SECRET is required when CAPTCHA_PROVIDER is set")
+		}
+	}
+
+	if c.SignupRateLimit < 0 {
+		errs.add("SIGNUP_RATE_LIMIT", "SIGNUP_RATE_LIMIT must not be negative")
+	}
+	if c.SignupRateLimit > 0 && c.SignupRateWindow <= 0 {
+		errs.add("SIGNUP_RATE_WINDOW", "SIGNUP_RATE_WINDOW must be positive")
+	}
+
+	if c.QuickAnalyzeRateLimit < 0 {
+		errs.add("QUICK_ANALYZE_RATE_LIMIT", "QUICK_ANALYZE_RATE_LIMIT must not be negative")
+	}
+	if c.QuickAnalyzeRateLimit > 0 && c.QuickAnalyzeRateWindow <= 0 {
+		errs.add("QUICK_ANALYZE_RATE_WINDOW", "QUICK_ANALYZE_RATE_WINDOW must be positive")
+	}
+
+	switch c.RateLimitFailMode {
+	case "", RateLimitFailOpen, RateLimitFailClosed:
+	default:
+		errs.add("RATE_LIMIT_FAIL_MODE", "RATE_LIMIT_FAIL_MODE must be one of: open, closed")
+	}
+}
+
+// AbuseProtection returns the sign-up checks to apply, counting attempts
+// in counter. Call it on a validated config.
+func (c *Config) AbuseProtection(counter abuse.Counter) abuse.Protection {
+	var protection abuse.Protection
+	if c.CaptchaProvider != "" {
+		protection.Captcha = abuse.NewCaptcha(c.CaptchaProvider, c.CaptchaSecret)
+	}
+	if c.BlockDisposableEmails || len(c.BlockedEmailDomains) > 0 {
+		protection.Domains = abuse.NewDomainList(c.BlockDisposableEmails, c.BlockedEmailDomains)
+	}
+	if c.SignupRateLimit > 0 {
+		protection.Signups = abuse.NewLimiter(counter, "ratelimit:signup", c.SignupRateLimit, c.SignupRateWindow).
+			WithFailClosed(c.RateLimitFailMode == RateLimitFailClosed)
+	}
+	return protection
+}
+
+// QuickAnalyzeLimiter returns the per-key limit on quick analyses, or nil
+// when it is disabled. Call it on a validated config.
+func (c *Config) QuickAnalyzeLimiter(counter abuse.Counter) *abuse.Limiter {
+	if c.QuickAnalyzeRateLimit == 0 {
+		return nil
+	}
+	return abuse.NewLimiter(counter, "ratelimit:quick", c.QuickAnalyzeRateLimit, c.QuickAnalyzeRateWindow).
+		WithFailClosed(c.RateLimitFailMode == RateLimitFailClosed)
+}
+
+// validateQuotas checks the quota and word limit entries and the webhook
+// that is told about quota warnings
+func (c *Config) validateQuotas(errs *ValidationErrors) {
+	if _, err := quota.ParseLimits(c.MonthlyQuotas); err != nil {
+		errs.add("MONTHLY_ANALYSIS_QUOTAS", "invalid MONTHLY_ANALYSIS_QUOTAS: %v", err)
+	}
+	if _, err := quota.ParseLimits(c.SubmissionWordLimits); err != nil {
+		errs.add("SUBMISSION_WORD_LIMITS", "invalid SUBMISSION_WORD_LIMITS: %v", err)
+	}
+
+	if c.QuotaWebhookURL != "" {
+		if u, err := url.Parse(c.QuotaWebhookURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("QUOTA_WEBHOOK_URL", "QUOTA_WEBHOOK_URL must be an http or https URL")
+		}
+		if c.QuotaWebhookSecret == "" {
+			errs.add("QUOTA_WEBHOOK_SECRET", "QUOTA_WEBHOOK_SECRET is required when QUOTA_WEBHOOK_URL is set")
+		}
+	}
+}
+
+// QuotaLimits returns the monthly analysis quota of each plan. Call it on
+// a validated config.
+func (c *Config) QuotaLimits() quota.Limits {
+	limits, _ := quota.ParseLimits(c.MonthlyQuotas)
+	return limits
+}
+
+// WordLimits returns the words each plan can queue for analysis in one
+// submission. Call it on a validated config.
+func (c *Config) WordLimits() map[models.Plan]int {
+	limits, _ := quota.ParseLimits(c.SubmissionWordLimits)
+	return limits
+}
+
+// validateSpendBudget checks the deployment-wide spend budgets and what
+// happens to analyses over them
+func (c *Config) validateSpendBudget(errs *ValidationErrors) {
+	if c.CostBudgetDailyUSD < 0 || c.CostBudgetMonthlyUSD < 0 {
+		errs.add("COST_BUDGET_DAILY_USD", "COST_BUDGET_DAILY_USD and COST_BUDGET_MONTHLY_USD cannot be negative")
+	}
+	if c.CostBudgetMode != "" && c.CostBudgetMode != spend.ModeHold && c.CostBudgetMode != spend.ModeReject {
+		errs.add("COST_BUDGET_MODE", "COST_BUDGET_MODE must be one of: %s, %s", spend.ModeHold, spend.ModeReject)
+	}
+}
+
+// SpendLimits returns the deployment-wide spend budgets in millionths of a
+// US dollar
+func (c *Config) SpendLimits() spend.Limits {
+	return spend.Limits{
+		DailyMicros:   int64(math.Round(c.CostBudgetDailyUSD * 1e6)),
+		MonthlyMicros: int64(math.Round(c.CostBudgetMonthlyUSD * 1e6)),
+	}
+}
+
+// validateTranscription checks the speech-to-text provider and the upload
+// size limit
+func (c *Config) validateTranscription(errs *ValidationErrors) {
+	if c.TranscriptionProvider == "" {
+		return
+	}
+
+	if !transcription.ValidProvider(c.TranscriptionProvider) {
+		errs.add("TRANSCRIPTION_PROVIDER", "TRANSCRIPTION_PROVIDER must be one of: %s", strings.Join(transcription.Providers, ", "))
+	}
+	// A self-hosted Whisper server may not need a key
+	if c.TranscriptionAPIKey == "" && (c.TranscriptionProvider == transcription.ProviderDeepgram || c.TranscriptionBaseURL == "") {
+		errs.add("TRANSCRIPTION_API_KEY", "TRANSCRIPTION_API_KEY is required when TRANSCRIPTION_PROVIDER is set")
+	}
+	if c.TranscriptionBaseURL != "" {
+		if u, err := url.Parse(c.TranscriptionBaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("TRANSCRIPTION_BASE_URL", "TRANSCRIPTION_BASE_URL must be an http or https URL")
+		}
+	}
+	if c.MediaUploadMaxMB < 1 {
+		errs.add("MEDIA_UPLOAD_MAX_MB", "MEDIA_UPLOAD_MAX_MB must be at least 1")
+	}
+}
+
+// Transcription returns the speech-to-text provider, or nil when uploads
+// are disabled. Call it on a validated config.
+func (c *Config) Transcription() transcription.Provider {
+	if c.TranscriptionProvider == "" {
+		return nil
+	}
+	provider, _ := transcription.NewProvider(transcription.Config{
+		Provider: c.TranscriptionProvider,
+		APIKey:   c.TranscriptionAPIKey,
+		BaseURL:  c.TranscriptionBaseURL,
+		Model:    c.TranscriptionModel,
+	})
+	return provider
+}
+
+// maxUploadMB matches the largest body the local storage driver accepts
+const maxUploadMB = 512
+
+// validateStorage checks the object storage driver, its settings and the
+// direct upload limit
+func (c *Config) validateStorage(errs *ValidationErrors) {
+	if c.StorageDriver == "" {
+		return
+	}
+	if c.UploadMaxMB < 1 || c.UploadMaxMB > maxUploadMB {
+		errs.add("UPLOAD_MAX_MB", "UPLOAD_MAX_MB must be between 1 and %d", maxUploadMB)
+	}
+
+	switch c.StorageDriver {
+	case storage.DriverLocal:
+		if c.StorageDir == "" {
+			errs.add("STORAGE_DIR", "STORAGE_DIR is required when STORAGE_DRIVER=local")
+		}
+		if u, err := url.Parse(c.StorageBaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("STORAGE_BASE_URL", "STORAGE_BASE_URL must be an http or https URL")
+		}
+		return
+	case storage.DriverS3, storage.DriverMinIO:
+	default:
+		errs.add("STORAGE_DRIVER", "STORAGE_DRIVER must be one of: %s", strings.Join(storage.Drivers, ", "))
+		return
+	}
+
+	if c.StorageBucket == "" {
+		errs.add("STORAGE_BUCKET", "STORAGE_BUCKET is required when STORAGE_DRIVER=%s", c.StorageDriver)
+	}
+	if c.StorageAccessKeyID == "" || c.StorageSecretAccessKey == "" {
+		errs.add("STORAGE_ACCESS_KEY_ID", "STORAGE_ACCESS_KEY_ID and STORAGE_SECRET_ACCESS_KEY (or the AWS keys) are required when STORAGE_DRIVER=%s", c.StorageDriver)
+	}
+	if c.StorageDriver == storage.DriverMinIO && c.StorageEndpoint == "" {
+		errs.add("STORAGE_ENDPOINT", "STORAGE_ENDPOINT is required when STORAGE_DRIVER=minio")
+	}
+	if c.StorageEndpoint != "" {
+		if u, err := url.Parse(c.StorageEndpoint); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("STORAGE_ENDPOINT", "STORAGE_ENDPOINT must be an http or https URL")
+		}
+	}
+}
+
+// Storage returns the object storage for uploads, or nil when uploads are
+// kept in Postgres. Call it on a validated config.
+func (c *Config) Storage() (storage.Storage, error) {
+	if c.StorageDriver == "" {
+		return nil, nil
+	}
+	// Local presigned URLs are signed with a key derived from the JWT
+	// secret rather than the secret itself, so neither can stand in for
+	// the other
+	mac := hmac.New(sha256.New, []byte(c.JWTSecret))
+	mac.Write([]byte("storage"))
+
+	return storage.New(storage.Config{
+		Driver:          c.StorageDriver,
+		Dir:             c.StorageDir,
+		BaseURL:         c.StorageBaseURL,
+		Secret:          hex.EncodeToString(mac.Sum(nil)),
+		Bucket:          c.StorageBucket,
+		Region:          c.StorageRegion,
+		Endpoint:        c.StorageEndpoint,
+		AccessKeyID:     c.StorageAccessKeyID,
+		SecretAccessKey: c.StorageSecretAccessKey,
+	})
+}
+
+// validateFactCheck checks the search provider used to fact-check claims
+func (c *Config) validateFactCheck(errs *ValidationErrors) {
+	if c.FactCheckProvider == "" {
+		return
+	}
+
+	if !factcheck.ValidProvider(c.FactCheckProvider) {
+		errs.add("FACT_CHECK_PROVIDER", "FACT_CHECK_PROVIDER must be one of: %s", strings.Join(factcheck.Providers, ", "))
+	}
+	if c.FactCheckProvider == factcheck.ProviderBrave && c.FactCheckAPIKey == "" {
+		errs.add("FACT_CHECK_API_KEY", "FACT_CHECK_API_KEY is required for the brave provider")
+	}
+	if c.FactCheckProvider == factcheck.ProviderSearXNG && c.FactCheckBaseURL == "" {
+		errs.add("FACT_CHECK_BASE_URL", "FACT_CHECK_BASE_URL is required for the searxng provider")
+	}
+	if c.FactCheckBaseURL != "" {
+		if u, err := url.Parse(c.FactCheckBaseURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			errs.add("FACT_CHECK_BASE_URL", "FACT_CHECK_BASE_URL must be an http or https URL")
+		}
+	}
+	if c.FactCheckMaxResults < 1 || c.FactCheckMaxResults > 10 {
+		errs.add("FACT_CHECK_MAX_RESULTS", "FACT_CHECK_MAX_RESULTS must be between 1 and 10")
+	}
+}
+
+// FactCheck returns the search provider for fact-checking claims, or nil
+// when none is configured. Call it on a validated config.
+func (c *Config) FactCheck() factcheck.Searcher {
+	if c.FactCheckProvider == "" {
+		return nil
+	}
+	searcher, _ := factcheck.NewSearcher(factcheck.Config{
+		Provider:   c.FactCheckProvider,
+		APIKey:     c.FactCheckAPIKey,
+		BaseURL:    c.FactCheckBaseURL,
+		MaxResults: c.FactCheckMaxResults,
+	})
+	return searcher
+}
+
+// validateEventBroker checks the broker lifecycle events are published to
+func (c *Config) validateEventBroker(errs *ValidationErrors) {
+	if c.EventBroker == "" {
+		return
+	}
+
+	if !broker.ValidDriver(c.EventBroker) {
+		errs.add("EVENT_BROKER", "EVENT_BROKER must be one of: %s", strings.Join(broker.Drivers, ", "))
+		return
+	}
+	if c.EventBrokerURL == "" {
+		errs.add("EVENT_BROKER_URL", "EVENT_BROKER_URL is required when EVENT_BROKER is set")
+		return
+	}
+	if _, err := broker.NewPublisher(c.eventBrokerConfig()); err != nil {
+		errs.add("EVENT_BROKER_URL", "%v", err)
+	}
+}
+
+func (c *Config) eventBrokerConfig() broker.Config {
+	return broker.Config{
+		Driver: c.EventBroker,
+		URL:    c.EventBrokerURL,
+		Topic:  c.EventBrokerTopic,
+	}
+}
+
+// EventPublisher returns the publisher of lifecycle events, or nil when no
+// broker is configured. Call it on a validated config.
+func (c *Config) EventPublisher() broker.Publisher {
+	if c.EventBroker == "" {
+		return nil
+	}
+	publisher, _ := broker.NewPublisher(c.eventBrokerConfig())
+	return publisher
+}
+
+// validateConcurrency checks the analyzer's concurrency caps and the
+// queue depth that triggers backpressure
+func (c *Config) validateConcurrency(errs *ValidationErrors) {
+	caps := []struct {
+		name  string
+		value int
+	}{
+		{"ANALYZER_CONCURRENCY", c.AnalyzerConcurrency},
+		{"GEMINI_CONCURRENCY", c.GeminiConcurrency},
+		{"FACT_CHECK_CONCURRENCY", c.FactCheckConcurrency},
+		{"PERPLEXITY_CONCURRENCY", c.PerplexityConcurrency},
+		{"QUEUE_MAX_DEPTH", c.QueueMaxDepth},
+	}
+	for _, limit := range caps {
+		if limit.value < 0 {
+			errs.add(limit.name, "%s cannot be negative", limit.name)
+		}
+	}
+}
+
+// AnalyzerLimits returns the caps on the analyzer's provider calls
+func (c *Config) AnalyzerLimits() limits.Config {
+	return limits.Config{
+		Global: c.AnalyzerConcurrency,
+		PerProvider: map[string]int{
+			limits.ProviderGemini:     c.GeminiConcurrency,
+			limits.ProviderFactCheck:  c.FactCheckConcurrency,
+			limits.ProviderPerplexity: c.PerplexityConcurrency,
+		},
+	}
+}
+
+// AnalyzerStages returns the configuration of the analysis pipeline's
+// stages by name. Call it on a validated config.
+func (c *Config) AnalyzerStages() map[string]analyzer.StageConfig {
+	stages := make(map[string]analyzer.StageConfig)
+	for _, name := range c.AnalysisStagesDisabled {
+		stage := stages[name]
+		stage.Disabled = true
+		stages[name] = stage
+	}
+	timeouts, _ := parseStageTimeouts(c.AnalysisStageTimeouts)
+	for name, timeout := range timeouts {
+		stage := stages[name]
+		stage.Timeout = timeout
+		stages[name] = stage
+	}
+	return stages
+}
+
+// parseStageTimeouts reads "stage=duration" entries
+func parseStageTimeouts(entries []string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q is not of the form stage=duration", entry)
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("timeout of stage %s must be a positive duration", name)
+		}
+		timeouts[name] = timeout
+	}
+	return timeouts, nil
+}
+
+// validateStages checks the analysis pipeline's stage configuration.
+// Stage names aren't checked, since stages can be registered by code
+// outside the analyzer.
+func (c *Config) validateStages(errs *ValidationErrors) {
+	if slices.Contains(c.AnalysisStagesDisabled, analyzer.StageAnalysis) {
+		errs.add("ANALYSIS_STAGES_DISABLED", "the %s stage cannot be disabled", analyzer.StageAnalysis)
+	}
+	if _, err := parseStageTimeouts(c.AnalysisStageTimeouts); err != nil {
+		errs.add("ANALYSIS_STAGE_TIMEOUTS", "invalid ANALYSIS_STAGE_TIMEOUTS: %v", err)
+	}
+	if c.AnalysisTimeout < 0 {
+		errs.add("ANALYSIS_TIMEOUT", "ANALYSIS_TIMEOUT cannot be negative")
+	}
+	if c.QueueVisibilityTimeout < 0 {
+		errs.add("QUEUE_VISIBILITY_TIMEOUT", "QUEUE_VISIBILITY_TIMEOUT cannot be negative")
+	}
+}
+
+// Perplexity returns the perplexity scorer for AI detection, or nil when
+// none is configured
+func (c *Config) Perplexity() aidetect.PerplexityScorer {
+	if c.PerplexityURL == "" {
+		return nil
+	}
+	return aidetect.NewPerplexityScorer(aidetect.Config{
+		BaseURL: c.PerplexityURL,
+		APIKey:  c.PerplexityAPIKey,
+		Model:   c.PerplexityModel,
+	})
+}
+
+// MediaUploadMaxBytes is the largest audio or video upload accepted
+func (c *Config) MediaUploadMaxBytes() int64 {
+	return int64(c.MediaUploadMaxMB) << 20
+}
+
+// ImportMaxBytes is the largest bulk import file accepted
+func (c *Config) ImportMaxBytes() int64 {
+	return int64(c.ImportMaxMB) << 20
+}
+
+// UploadMaxBytes is the largest direct upload accepted
+func (c *Config) UploadMaxBytes() int64 {
+	return int64(c.UploadMaxMB) << 20
+}
+
+// PasswordParams returns the parameters for new password hashes
+func (c *Config) PasswordParams() models.PasswordParams {
+	return models.PasswordParams{
+		Algorithm:         models.PasswordAlgorithm(c.PasswordHashAlgorithm),
+		BcryptCost:        c.BcryptCost,
+		Argon2Memory:      uint32(c.Argon2MemoryKiB),
+		Argon2Iterations:  uint32(c.Argon2Iterations),
+		Argon2Parallelism: uint8(c.Argon2Parallelism),
+	}
+}
+
+// validateRememberMe checks the lifetimes of remember-me sessions
+func (c *Config) validateRememberMe(errs *ValidationErrors) {
+	if c.RememberMeMaxLifetime < 0 {
+		errs.add("REMEMBER_ME_MAX_LIFETIME", "REMEMBER_ME_MAX_LIFETIME cannot be negative")
+	}
+	if c.RememberMeMaxLifetime > 0 && c.RememberMeIdle <= 0 {
+		errs.add("REMEMBER_ME_IDLE", "REMEMBER_ME_IDLE must be positive")
+	}
+}
+
+// RememberMe returns how long remember-me sessions last, and false when
+// they are turned off
+func (c *Config) RememberMe() (models.DeviceLifetime, bool) {
+	return models.DeviceLifetime{Idle: c.RememberMeIdle, Max: c.RememberMeMaxLifetime}, c.RememberMeMaxLifetime > 0
+}
+
+// validateLoginAnomalyDetection checks the country header is a header name
+func (c *Config) validateLoginAnomalyDetection(errs *ValidationErrors) {
+	if strings.ContainsAny(c.GeoIPCountryHeader, " \t:") {
+		errs.add("GEOIP_COUNTRY_HEADER", "GEOIP_COUNTRY_HEADER must be a header name, such as CF-IPCountry")
+	}
+}
+
+// LoginLocator returns how sign-ins are located, or nil when the country
+// isn't tracked
+func (c *Config) LoginLocator() logins.Locator {
+	if c.GeoIPCountryHeader == "" {
+		return nil
+	}
+	return logins.HeaderLocator(c.GeoIPCountryHeader)
+}
+
+// SSORedirectURI returns where identity providers send users back to
+// after single sign-on: the frontend's callback page, which completes the
+// sign-in with the API
+func (c *Config) SSORedirectURI() string {
+	return strings.TrimRight(c.AppBaseURL, "/") + "/sso/callback"
+}
+
+// validateMail checks the settings required by the selected mail driver
+func (c *Config) validateMail(errs *ValidationErrors) {
+	switch c.MailDriver {
+	case "", "log":
+	case "smtp":
+		if c.SMTPHost == "" {
+			errs.add("SMTP_HOST", "SMTP_HOST is required when MAIL_DRIVER=smtp")
+		}
+	case "sendgrid":
+		if c.SendGridAPIKey == "" {
+			errs.add("SENDGRID_API_KEY", "SENDGRID_API_KEY is required when MAIL_DRIVER=sendgrid")
+		}
+	case "ses":
+		if c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "" {
+			errs.add("AWS_ACCESS_KEY_ID", "AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required when MAIL_DRIVER=ses")
+		}
+	default:
+		errs.add("MAIL_DRIVER", "MAIL_DRIVER must be one of: log, smtp, ses, sendgrid")
+	}
+}
+
+// ValidateOriginPattern checks that an allowed origin is either an exact
+// origin (scheme://host[:port]) or a wildcard subdomain pattern such as
+// https://*.example.com
+func ValidateOriginPattern(origin string) error {
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok || scheme == "" || host == "" {
+		return fmt.Errorf("origin must be in the form scheme://host[:port]")
+	}
+
+	if strings.ContainsAny(host, "/?#") {
+		return fmt.Errorf("origin must not contain a path, query or fragment")
+	}
+
+	if strings.Count(origin, "*") > 1 {
+		return fmt.Errorf("only one wildcard is allowed")
+	}
+
+	if strings.Contains(host, "*") {
+		if !strings.HasPrefix(host, "*.") {
+			return fmt.Errorf("wildcard must be the leftmost label (e.g. https://*.example.com)")
+		}
+		// Require a registrable domain after the wildcard so "*.com" is rejected
+		if base, _, _ := strings.Cut(host[2:], ":"); !strings.Contains(base, ".") {
+			return fmt.Errorf("wildcard must be followed by at least two domain labels")
+		}
 	}
 
 	return nil
@@ -108,18 +1536,6 @@ func getEnvOrDefault(key, defaultVal string) string {
 	return defaultVal
 }
 
-// getEnvAsBool returns an environment variable as a boolean
-func getEnvAsBool(key string, defaultVal bool) bool {
-	if val := os.Getenv(key); val != "" {
-		b, err := strconv.ParseBool(val)
-		if err != nil {
-			return defaultVal
-		}
-		return b
-	}
-	return defaultVal
-}
-
 // parseCommaSeparated parses a comma-separated string into a slice
 func parseCommaSeparated(s string) []string {
 	var result []string
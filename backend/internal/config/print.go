@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// redacted replaces secret values in printed configuration
+const redacted = "[REDACTED]"
+
+// Setting is a resolved configuration value and the variable it came from
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// Settings returns every configuration value in declaration order, keyed by
+// environment variable, with secrets redacted. Fields tagged secret:"true"
+// are hidden entirely; secret:"url" only hides the URL's password.
+func (c *Config) Settings() []Setting {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	settings := make([]Setting, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" {
+			continue
+		}
+
+		value := formatValue(v.Field(i))
+		switch field.Tag.Get("secret") {
+		case "true":
+			if value != "" {
+				value = redacted
+			}
+		case "url":
+			value = redactURL(value)
+		}
+
+		settings = append(settings, Setting{Key: key, Value: value})
+	}
+
+	return settings
+}
+
+// Print writes the resolved configuration as KEY=value lines with secrets
+// redacted, for debugging deployments
+func (c *Config) Print(w io.Writer) error {
+	for _, s := range c.Settings() {
+		if _, err := fmt.Fprintf(w, "%s=%s\n", s.Key, s.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatValue renders a field the way it would be written in the environment
+func formatValue(v reflect.Value) string {
+	switch x := v.Interface().(type) {
+	case string:
+		return x
+	case []string:
+		return strings.Join(x, ",")
+	case []uuid.UUID:
+		ids := make([]string, len(x))
+		for i, id := range x {
+			ids[i] = id.String()
+		}
+		return strings.Join(ids, ",")
+	case time.Duration:
+		return x.String()
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(time.DateOnly)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// redactURL hides the password of a connection URL, whether in the
+// userinfo or a password query parameter, keeping the rest visible. Values
+// that don't parse are hidden entirely.
+func redactURL(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return redacted
+	}
+
+	if q := u.Query(); q.Has("password") {
+		q.Set("password", "xxxxx")
+		u.RawQuery = q.Encode()
+	}
+
+	// Redacted replaces the userinfo password with "xxxxx"
+	return u.Redacted()
+}
@@ -0,0 +1,269 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// reloadPollInterval is how often a Watcher checks its files for changes
+const reloadPollInterval = 2 * time.Second
+
+// processEnv holds the variables set before any file was loaded. Files never
+// override them, on startup or on reload.
+var processEnv = environKeys()
+
+// Watcher holds the active configuration and reloads its reloadable fields
+// on SIGHUP or when a configuration file changes
+type Watcher struct {
+	files     []string
+	protected map[string]bool
+
+	mu        sync.RWMutex
+	current   *Config
+	version   int64
+	fileKeys  map[string]bool
+	modTimes  map[string]time.Time
+	listeners []func(*Config)
+}
+
+// NewWatcher creates a watcher serving cfg as version 1
+func NewWatcher(cfg *Config) *Watcher {
+	return newWatcher(cfg, envFiles(), processEnv)
+}
+
+// newWatcher creates a watcher over the given files. Variables in protected
+// are never overwritten from a file.
+func newWatcher(cfg *Config, files []string, protected map[string]bool) *Watcher {
+	w := &Watcher{
+		files:     files,
+		protected: protected,
+		current:   cfg,
+		version:   1,
+		fileKeys:  make(map[string]bool),
+	}
+	w.modTimes = w.stat()
+
+	// Remember what the files set on startup, so removing a variable from
+	// a file unsets it on the next reload
+	if values, err := w.readFiles(); err == nil {
+		for key := range values {
+			w.fileKeys[key] = true
+		}
+	}
+
+	return w
+}
+
+// Current returns the active configuration. It must not be modified.
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Version counts applied reloads, starting at 1
+func (w *Watcher) Version() int64 {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.version
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// reload that changes a reloadable setting
+func (w *Watcher) OnReload(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.listeners = append(w.listeners, fn)
+}
+
+// Run reloads on SIGHUP and whenever a watched file changes, until ctx is
+// canceled. Failed reloads are logged and the active configuration kept.
+func (w *Watcher) Run(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			w.reloadAndLog("signal")
+		case <-ticker.C:
+			if w.filesChanged() {
+				w.reloadAndLog("file")
+			}
+		}
+	}
+}
+
+// reloadAndLog reloads and logs the outcome
+func (w *Watcher) reloadAndLog(trigger string) {
+	if err := w.Reload(); err != nil {
+		slog.Error("Failed to reload configuration", "trigger", trigger, "error", err)
+	}
+}
+
+// Reload re-reads the configuration files and environment. If the result is
+// valid, reloadable fields are applied, the version is bumped and listeners
+// are notified; other changes are logged as needing a restart. An invalid
+// configuration is rejected and the active one kept.
+func (w *Watcher) Reload() error {
+	w.mu.Lock()
+
+	if err := w.applyFiles(); err != nil {
+		w.mu.Unlock()
+		return err
+	}
+
+	next := readEnv()
+	if err := next.Validate(); err != nil {
+		w.mu.Unlock()
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	merged, changed, restart := mergeReloadable(w.current, next)
+	if len(restart) > 0 {
+		slog.Warn("Configuration changes need a restart to take effect", "settings", strings.Join(restart, ","))
+	}
+	if len(changed) == 0 {
+		w.mu.Unlock()
+		return nil
+	}
+
+	w.current = merged
+	w.version++
+	version := w.version
+	listeners := append([]func(*Config){}, w.listeners...)
+	w.mu.Unlock()
+
+	slog.Info("Configuration reloaded", "version", version, "settings", strings.Join(changed, ","))
+	for _, fn := range listeners {
+		fn(merged)
+	}
+
+	return nil
+}
+
+// readFiles returns the variables set by the watched files. Earlier files
+// win, as on startup; missing files are skipped.
+func (w *Watcher) readFiles() (map[string]string, error) {
+	values := make(map[string]string)
+	for _, file := range w.files {
+		vars, err := godotenv.Read(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		for k, v := range vars {
+			if _, ok := values[k]; !ok {
+				values[k] = v
+			}
+		}
+	}
+	return values, nil
+}
+
+// applyFiles copies the files' variables into the environment, unsetting
+// ones removed since the last load
+func (w *Watcher) applyFiles() error {
+	values, err := w.readFiles()
+	if err != nil {
+		return err
+	}
+
+	for key := range w.fileKeys {
+		if _, ok := values[key]; !ok && !w.protected[key] {
+			os.Unsetenv(key)
+		}
+	}
+
+	w.fileKeys = make(map[string]bool, len(values))
+	for key, value := range values {
+		w.fileKeys[key] = true
+		if !w.protected[key] {
+			os.Setenv(key, value)
+		}
+	}
+
+	return nil
+}
+
+// filesChanged reports whether any watched file was modified, created or
+// removed since the last check
+func (w *Watcher) filesChanged() bool {
+	modTimes := w.stat()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	changed := !reflect.DeepEqual(modTimes, w.modTimes)
+	w.modTimes = modTimes
+	return changed
+}
+
+// stat returns the modification time of each watched file that exists
+func (w *Watcher) stat() map[string]time.Time {
+	modTimes := make(map[string]time.Time, len(w.files))
+	for _, file := range w.files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// mergeReloadable returns a copy of current with next's reloadable fields,
+// the variables those changed, and the variables that differ but need a
+// restart
+func mergeReloadable(current, next *Config) (*Config, []string, []string) {
+	merged := *current
+	mv := reflect.ValueOf(&merged).Elem()
+	cv := reflect.ValueOf(current).Elem()
+	nv := reflect.ValueOf(next).Elem()
+	t := mv.Type()
+
+	var changed, restart []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		key := field.Tag.Get("env")
+		if key == "" || reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			continue
+		}
+
+		if field.Tag.Get("reload") == "true" {
+			mv.Field(i).Set(nv.Field(i))
+			changed = append(changed, key)
+		} else {
+			restart = append(restart, key)
+		}
+	}
+
+	return &merged, changed, restart
+}
+
+// environKeys returns the names of the variables currently set
+func environKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		if key, _, ok := strings.Cut(kv, "="); ok {
+			keys[key] = true
+		}
+	}
+	return keys
+}
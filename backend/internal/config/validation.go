@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FieldError is a problem with a single environment variable
+type FieldError struct {
+	Key     string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors lists every configuration problem found, so a deployment
+// can be fixed in one pass rather than one variable at a time
+type ValidationErrors []FieldError
+
+// Error returns the message of a single problem as is, or a numbered list
+func (e ValidationErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Message
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d configuration problems:", len(e))
+	for i, fe := range e {
+		fmt.Fprintf(&b, "\n  %d. %s", i+1, fe.Message)
+	}
+	return b.String()
+}
+
+// add records a problem with key
+func (e *ValidationErrors) add(key, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Key: key, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the problems as an error, or nil if there are none
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// envReader reads typed environment variables. Values that don't parse
+// fall back to the default and are recorded, so Validate can report them.
+type envReader struct {
+	errs ValidationErrors
+}
+
+// asInt returns an environment variable as an integer
+func (e *envReader) asInt(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		e.errs.add(key, "%s must be an integer, got %q", key, val)
+		return defaultVal
+	}
+	return i
+}
+
+// asBool returns an environment variable as a boolean
+func (e *envReader) asBool(key string, defaultVal bool) bool {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		e.errs.add(key, "%s must be true or false, got %q", key, val)
+		return defaultVal
+	}
+	return b
+}
+
+// asDuration returns an environment variable as a duration (e.g. "30s", "24h")
+func (e *envReader) asDuration(key string, defaultVal time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		e.errs.add(key, "%s must be a duration such as 30s or 24h, got %q", key, val)
+		return defaultVal
+	}
+	return d
+}
+
+// asFloat returns an environment variable as a float
+func (e *envReader) asFloat(key string, defaultVal float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		e.errs.add(key, "%s must be a number, got %q", key, val)
+		return defaultVal
+	}
+	return f
+}
+
+// asUUIDs returns a comma-separated environment variable as UUIDs,
+// skipping any that don't parse
+func (e *envReader) asUUIDs(key string) []uuid.UUID {
+	var ids []uuid.UUID
+	for _, val := range parseCommaSeparated(os.Getenv(key)) {
+		id, err := uuid.Parse(val)
+		if err != nil {
+			e.errs.add(key, "%s must be comma-separated user IDs, got %q", key, val)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// asDate returns an environment variable in YYYY-MM-DD form as midnight UTC,
+// or the zero time when unset
+func (e *envReader) asDate(key string) time.Time {
+	val := os.Getenv(key)
+	if val == "" {
+		return time.Time{}
+	}
+	d, err := time.Parse(time.DateOnly, val)
+	if err != nil {
+		e.errs.add(key, "%s must be a date such as 2026-01-31, got %q", key, val)
+		return time.Time{}
+	}
+	return d
+}
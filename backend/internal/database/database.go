@@ -4,26 +4,138 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// replicaPingTimeout bounds each replica health check
+const replicaPingTimeout = 2 * time.Second
+
 // Database represents the database connection
 type Database struct {
+	// Pool is the primary, used for all writes
 	Pool *pgxpool.Pool
+
+	// Replica serves read-only queries when one is configured. Use Reader
+	// rather than reading it directly.
+	Replica *pgxpool.Pool
+
+	opts           Options
+	replicaHealthy atomic.Bool
+}
+
+// Options configures the connection pools
+type Options struct {
+	// Tracer observes every statement on both pools; nil disables tracing
+	Tracer pgx.QueryTracer
+	// Lazy skips checking the connection, so New succeeds while the
+	// database is down and the pool connects on first use
+	Lazy bool
 }
 
 // New creates a new database connection pool
-func New(ctx context.Context, databaseURL string) (*Database, error) {
+func New(ctx context.Context, databaseURL string, opts Options) (*Database, error) {
+	pool, config, err := newPool(ctx, databaseURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	if !opts.Lazy {
+		if err := pool.Ping(ctx); err != nil {
+			pool.Close()
+			return nil, fmt.Errorf("unable to ping database: %w", err)
+		}
+	}
+
+	slog.Info("Database connection pool created",
+		"max_conns", config.MaxConns,
+		"min_conns", config.MinConns,
+	)
+
+	return &Database{Pool: pool, opts: opts}, nil
+}
+
+// ConnectReplica adds a read replica. An unreachable replica isn't an
+// error: reads stay on the primary until MonitorReplica sees it come up.
+func (db *Database) ConnectReplica(ctx context.Context, replicaURL string) error {
+	pool, _, err := newPool(ctx, replicaURL, db.opts)
+	if err != nil {
+		return fmt.Errorf("replica: %w", err)
+	}
+	db.Replica = pool
+
+	db.checkReplica(ctx)
+	slog.Info("Database replica configured", "healthy", db.replicaHealthy.Load())
+
+	return nil
+}
+
+// Reader returns the pool for read-only queries: the replica while it is
+// healthy, otherwise the primary
+func (db *Database) Reader() *pgxpool.Pool {
+	if db.Replica != nil && db.replicaHealthy.Load() {
+		return db.Replica
+	}
+	return db.Pool
+}
+
+// ReplicaHealthy reports whether reads are going to the replica
+func (db *Database) ReplicaHealthy() bool {
+	return db.Replica != nil && db.replicaHealthy.Load()
+}
+
+// MonitorReplica pings the replica every interval, moving reads back to
+// the primary while it is down. It returns when ctx is cancelled.
+func (db *Database) MonitorReplica(ctx context.Context, interval time.Duration) {
+	if db.Replica == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			db.checkReplica(ctx)
+		}
+	}
+}
+
+// checkReplica pings the replica and records whether it can take reads
+func (db *Database) checkReplica(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, replicaPingTimeout)
+	defer cancel()
+
+	err := db.Replica.Ping(ctx)
+	healthy := err == nil
+
+	if was := db.replicaHealthy.Swap(healthy); was != healthy {
+		if healthy {
+			slog.Info("Database replica is back; routing reads to it")
+		} else {
+			slog.Warn("Database replica is down; routing reads to the primary", "error", err)
+		}
+	}
+}
+
+// newPool creates a connection pool with the application's pool settings
+func newPool(ctx context.Context, databaseURL string, opts Options) (*pgxpool.Pool, *pgxpool.Config, error) {
 	// Configure connection pool
 	config, err := pgxpool.ParseConfig(databaseURL)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse database URL: %w", err)
+		return nil, nil, fmt.Errorf("unable to parse database URL: %w", err)
 	}
+	config.ConnConfig.Tracer = opts.Tracer
 
 	// Connection pool settings
 	config.MaxConns = 25                      // Maximum number of connections
@@ -35,21 +147,10 @@ func New(ctx context.Context, databaseURL string) (*Database, error) {
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		return nil, fmt.Errorf("unable to create connection pool: %w", err)
-	}
-
-	// Test connection
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		return nil, fmt.Errorf("unable to ping database: %w", err)
+		return nil, nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
-	slog.Info("Database connection pool created",
-		"max_conns", config.MaxConns,
-		"min_conns", config.MinConns,
-	)
-
-	return &Database{Pool: pool}, nil
+	return pool, config, nil
 }
 
 // RunMigrations runs pending database migrations
@@ -93,6 +194,9 @@ func RunMigrations(databaseURL string, migrationsPath string) error {
 func (db *Database) Close() {
 	slog.Info("Closing database connection pool")
 	db.Pool.Close()
+	if db.Replica != nil {
+		db.Replica.Close()
+	}
 }
 
 // Ping checks if the database is reachable
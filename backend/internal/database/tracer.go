@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+)
+
+// maxLoggedSQL bounds how much of a slow statement is logged
+const maxLoggedSQL = 500
+
+var (
+	// nameComment matches an explicit "-- name: ListSubmissions" label
+	nameComment = regexp.MustCompile(`--\s*name:\s*([A-Za-z0-9_]+)`)
+
+	// verbPattern matches the statement's leading keyword
+	verbPattern = regexp.MustCompile(`^\s*([A-Za-z]+)`)
+
+	// tablePattern matches the first table a statement reads or writes
+	tablePattern = regexp.MustCompile(`(?i)\b(?:from|into|update)\s+([A-Za-z_][A-Za-z0-9_.]*)`)
+)
+
+// rowBuckets cover single-row lookups up to large listings
+var rowBuckets = []float64{0, 1, 5, 10, 50, 100, 500, 1000, 5000}
+
+// QueryTracer records per-statement duration, row counts and errors, and
+// logs statements slower than a threshold. It implements pgx.QueryTracer.
+type QueryTracer struct {
+	slowThreshold time.Duration
+
+	duration *prometheus.HistogramVec
+	rows     *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// NewQueryTracer registers the query metrics on registry. A zero
+// slowThreshold disables slow query logging.
+func NewQueryTracer(registry prometheus.Registerer, slowThreshold time.Duration) *QueryTracer {
+	return &QueryTracer{
+		slowThreshold: slowThreshold,
+		duration: metrics.Register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time spent executing a statement, including reading its rows.",
+			Buckets: metrics.DefaultDurationBuckets,
+		}, []string{"query", "status"})),
+		rows: metrics.Register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_rows",
+			Help:    "Rows returned or affected by a statement.",
+			Buckets: rowBuckets,
+		}, []string{"query"})),
+		errors: metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Statements that failed.",
+		}, []string{"query"})),
+	}
+}
+
+// queryTraceKey carries the in-flight statement between start and end
+type queryTraceKey struct{}
+
+// queryTrace is the state kept for an in-flight statement
+type queryTrace struct {
+	name  string
+	sql   string
+	start time.Time
+}
+
+// TraceQueryStart implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, queryTraceKey{}, &queryTrace{
+		name:  QueryName(data.SQL),
+		sql:   data.SQL,
+		start: time.Now(),
+	})
+}
+
+// TraceQueryEnd implements pgx.QueryTracer
+func (t *QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	trace, ok := ctx.Value(queryTraceKey{}).(*queryTrace)
+	if !ok {
+		return
+	}
+
+	elapsed := time.Since(trace.start)
+	rows := data.CommandTag.RowsAffected()
+
+	status := "ok"
+	if data.Err != nil {
+		status = "error"
+		t.errors.WithLabelValues(trace.name).Inc()
+	}
+	t.duration.WithLabelValues(trace.name, status).Observe(elapsed.Seconds())
+	t.rows.WithLabelValues(trace.name).Observe(float64(rows))
+
+	if t.slowThreshold > 0 && elapsed >= t.slowThreshold {
+		// Arguments are left out: they can hold user content
+		slog.WarnContext(ctx, "Slow query",
+			"query", trace.name,
+			"duration_ms", elapsed.Milliseconds(),
+			"rows", rows,
+			"error", data.Err,
+			"sql", compactSQL(trace.sql),
+		)
+	}
+}
+
+// QueryName labels a statement for metrics. An explicit "-- name: X"
+// comment wins; otherwise it is the verb and first table, such as
+// "select_submissions", which keeps label cardinality bounded.
+func QueryName(sql string) string {
+	if m := nameComment.FindStringSubmatch(sql); m != nil {
+		return m[1]
+	}
+
+	verb := "query"
+	if m := verbPattern.FindStringSubmatch(stripComments(sql)); m != nil {
+		verb = strings.ToLower(m[1])
+	}
+
+	if m := tablePattern.FindStringSubmatch(sql); m != nil {
+		return verb + "_" + strings.ToLower(m[1])
+	}
+	return verb
+}
+
+// stripComments drops leading "--" comment lines
+func stripComments(sql string) string {
+	lines := strings.Split(sql, "\n")
+	for i, line := range lines {
+		if trimmed := strings.TrimSpace(line); trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			return strings.Join(lines[i:], "\n")
+		}
+	}
+	return ""
+}
+
+// compactSQL collapses whitespace and truncates a statement for logging
+func compactSQL(sql string) string {
+	compact := strings.Join(strings.Fields(sql), " ")
+	if len(compact) > maxLoggedSQL {
+		return compact[:maxLoggedSQL] + "..."
+	}
+	return compact
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+)
+
+func TestQueryName(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want string
+	}{
+		{"SELECT id, email FROM users WHERE id = $1", "select_users"},
+		{"\n\t\tINSERT INTO submissions (user_id, content)\n\t\tVALUES ($1, $2)", "insert_submissions"},
+		{"UPDATE submissions s SET status = $2", "update_submissions"},
+		{"DELETE FROM keyphrases WHERE analysis_id = $1", "delete_keyphrases"},
+		{"-- name: ListSubmissions\nSELECT * FROM submissions", "ListSubmissions"},
+		{"-- moving average over a week\nSELECT avg(score) FROM analyses", "select_analyses"},
+		{"begin", "begin"},
+		{"", "query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := QueryName(tt.sql); got != tt.want {
+				t.Errorf("QueryName(%q) = %q, want %q", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+// observations returns how many values a histogram recorded
+func observations(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestQueryTracer(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	tracer := NewQueryTracer(registry, 0)
+
+	run := func(sql string, tag pgconn.CommandTag, err error) {
+		ctx := tracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: sql})
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: tag, Err: err})
+	}
+
+	run("SELECT * FROM users", pgconn.NewCommandTag("SELECT 3"), nil)
+	run("SELECT * FROM users", pgconn.NewCommandTag("SELECT 1"), nil)
+	run("UPDATE users SET email = $1", pgconn.CommandTag{}, errors.New("unique violation"))
+
+	if got := observations(t, tracer.duration.WithLabelValues("select_users", "ok")); got != 2 {
+		t.Errorf("ok select_users observations = %d, want 2", got)
+	}
+	if got := observations(t, tracer.duration.WithLabelValues("update_users", "error")); got != 1 {
+		t.Errorf("failed update_users observations = %d, want 1", got)
+	}
+	if got := testutil.ToFloat64(tracer.errors.WithLabelValues("update_users")); got != 1 {
+		t.Errorf("update_users errors = %v, want 1", got)
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler(registry).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `db_query_rows_sum{query="select_users"} 4`) {
+		t.Errorf("exposition missing row totals:\n%s", rec.Body.String())
+	}
+}
+
+func TestCompactSQL(t *testing.T) {
+	if got := compactSQL("\n\t\tSELECT *\n\t\tFROM users\n"); got != "SELECT * FROM users" {
+		t.Errorf("compactSQL() = %q, want %q", got, "SELECT * FROM users")
+	}
+
+	long := compactSQL("SELECT " + strings.Repeat("x, ", 400) + "y FROM t")
+	if len(long) != maxLoggedSQL+3 || !strings.HasSuffix(long, "...") {
+		t.Errorf("compactSQL() of a long statement has length %d, want %d", len(long), maxLoggedSQL+3)
+	}
+}
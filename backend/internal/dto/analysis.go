@@ -0,0 +1,98 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Analysis is an analysis rendered for an API version: v1 has the flat
+// sentiment and sentiment_score fields, and v2 groups them as AnalysisV2
+type Analysis struct {
+	analysis *models.Analysis
+	version  apiversion.Version
+}
+
+// NewAnalysis maps an analysis for v. A nil analysis is written as null.
+func NewAnalysis(analysis *models.Analysis, v apiversion.Version) *Analysis {
+	return &Analysis{analysis: analysis, version: v}
+}
+
+// MarshalJSON writes the analysis in its version's shape
+func (a *Analysis) MarshalJSON() ([]byte, error) {
+	if a.analysis == nil {
+		return []byte("null"), nil
+	}
+	if a.version < apiversion.V2 {
+		return json.Marshal(a.analysis)
+	}
+	return json.Marshal(NewAnalysisV2(a.analysis))
+}
+
+// AnalysisV2 is the v2 analysis, which groups the sentiment label and score
+type AnalysisV2 struct {
+	ID               uuid.UUID                  `json:"id"`
+	SubmissionID     uuid.UUID                  `json:"submission_id"`
+	Sentiment        SentimentV2                `json:"sentiment"`
+	Topics           []string                   `json:"topics"`
+	Keyphrases       []models.Keyphrase         `json:"keyphrases"`
+	Findings         []models.Finding           `json:"findings"`
+	Summary          string                     `json:"summary"`
+	Readability      *models.ReadabilityMetrics `json:"readability"`
+	Confidence       *float64                   `json:"confidence"`
+	LowConfidence    bool                       `json:"low_confidence"`
+	Instructions     *string                    `json:"instructions,omitempty"`
+	Changes          *models.RevisionChanges    `json:"changes,omitempty"`
+	Claims           []models.Claim             `json:"claims,omitempty"`
+	Bias             *models.BiasReport         `json:"bias,omitempty"`
+	AIDetection      *models.AIDetection        `json:"ai_detection,omitempty"`
+	Moderation       models.ModerationScores    `json:"moderation,omitempty"`
+	PolicyDecision   *models.PolicyDecision     `json:"policy_decision,omitempty"`
+	Compliance       []models.ComplianceFlag    `json:"compliance,omitempty"`
+	Labels           []models.Classification    `json:"labels,omitempty"`
+	Language         string                     `json:"language,omitempty"`
+	TimedOut         []string                   `json:"timed_out,omitempty"`
+	ProcessingTimeMs int                        `json:"processing_time_ms"`
+	CreatedAt        timestamp.Time             `json:"created_at"`
+	UpdatedAt        timestamp.Time             `json:"updated_at"`
+}
+
+// SentimentV2 is the overall sentiment of an analysis
+type SentimentV2 struct {
+	Label string   `json:"label"`
+	Score *float64 `json:"score"`
+}
+
+// NewAnalysisV2 maps an analysis to its v2 shape
+func NewAnalysisV2(a *models.Analysis) AnalysisV2 {
+	return AnalysisV2{
+		ID:               a.ID,
+		SubmissionID:     a.SubmissionID,
+		Sentiment:        SentimentV2{Label: a.Sentiment, Score: a.SentimentScore},
+		Topics:           a.Topics,
+		Keyphrases:       a.Keyphrases,
+		Findings:         a.Findings,
+		Summary:          a.Summary,
+		Readability:      a.Readability,
+		Confidence:       a.Confidence,
+		LowConfidence:    a.LowConfidence,
+		Instructions:     a.Instructions,
+		Changes:          a.Changes,
+		Claims:           a.Claims,
+		Bias:             a.Bias,
+		AIDetection:      a.AIDetection,
+		Moderation:       a.Moderation,
+		PolicyDecision:   a.PolicyDecision,
+		Compliance:       a.Compliance,
+		Labels:           a.Labels,
+		Language:         a.Language,
+		TimedOut:         a.TimedOut,
+		ProcessingTimeMs: a.ProcessingTimeMs,
+		CreatedAt:        a.CreatedAt,
+		UpdatedAt:        a.UpdatedAt,
+	}
+}
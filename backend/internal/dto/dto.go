@@ -0,0 +1,62 @@
+// Package dto defines the shapes the API responds with and maps models
+// onto them. Handlers render users, submissions and analyses only through
+// these types, so a storage field doesn't reach clients by accident and
+// every endpoint returns a resource the same way.
+package dto
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// IncludeAnalysis embeds a submission's latest analysis
+const IncludeAnalysis = "analysis"
+
+// Include is the set of related resources a client asked to embed with
+// ?include=
+type Include map[string]bool
+
+// ParseInclude reads a comma-separated include parameter. Names that
+// aren't allowed are rejected, so a typo isn't silently ignored.
+func ParseInclude(raw string, allowed ...string) (Include, error) {
+	include := Include{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(allowed, name) {
+			return nil, fmt.Errorf("include must be one of: %s", strings.Join(allowed, ", "))
+		}
+		include[name] = true
+	}
+	return include, nil
+}
+
+// Has reports whether name was asked for
+func (i Include) Has(name string) bool {
+	return i[name]
+}
+
+// String returns the names asked for, sorted and comma-separated, so equal
+// sets give equal strings
+func (i Include) String() string {
+	return strings.Join(slices.Sorted(maps.Keys(i)), ",")
+}
+
+// Message is a response with nothing to return but a confirmation
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Pending is returned with 202 while an analysis isn't ready, with the
+// status of the submission it waits on
+type Pending struct {
+	Status models.SubmissionStatus `json:"status"`
+	// PreviousStatus is the status of the revision a comparison waits on
+	PreviousStatus *models.SubmissionStatus `json:"previous_status,omitempty"`
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseInclude(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{raw: "", want: nil},
+		{raw: "analysis", want: []string{IncludeAnalysis}},
+		{raw: " analysis , ,analysis", want: []string{IncludeAnalysis}},
+		{raw: "analysis,owner", wantErr: true},
+		{raw: "Analysis", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseInclude(tt.raw, IncludeAnalysis)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseInclude() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseInclude() = %v, want %v", got, tt.want)
+			}
+			for _, name := range tt.want {
+				if !got.Has(name) {
+					t.Errorf("ParseInclude() = %v, want %s", got, name)
+				}
+			}
+			if s := got.String(); s != strings.Join(tt.want, ",") {
+				t.Errorf("String() = %q, want %q", s, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
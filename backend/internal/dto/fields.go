@@ -0,0 +1,127 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Fields is the set of fields a client asked for with ?fields=, named as
+// they appear in the JSON. A nil Fields selects every field.
+type Fields map[string]bool
+
+// ParseFields reads a comma-separated fields parameter naming fields of
+// resource, a response struct. Unknown names are rejected, so a typo isn't
+// silently answered with less than the client expected. An empty
+// parameter returns nil.
+func ParseFields(raw string, resource interface{}) (Fields, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	known := map[string]bool{}
+	for _, field := range jsonFields(reflect.TypeOf(resource)) {
+		known[field.name] = true
+	}
+
+	fields := Fields{}
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q in fields", name)
+		}
+		fields[name] = true
+	}
+	return fields, nil
+}
+
+// With adds name to a selection and returns it. A nil Fields already
+// selects everything and is returned as is.
+func (f Fields) With(name string) Fields {
+	if f != nil {
+		f[name] = true
+	}
+	return f
+}
+
+// String returns the selected fields, sorted and comma-separated, so equal
+// selections give equal strings. Every field, a nil Fields, is "".
+func (f Fields) String() string {
+	return strings.Join(slices.Sorted(maps.Keys(f)), ",")
+}
+
+// jsonField is a struct field as encoding/json sees it
+type jsonField struct {
+	index     int
+	name      string
+	omitEmpty bool
+}
+
+// jsonFields lists the encoded fields of a struct type, in order
+func jsonFields(t reflect.Type) []jsonField {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		fields = append(fields, jsonField{index: i, name: name, omitEmpty: strings.Contains(options, "omitempty")})
+	}
+	return fields
+}
+
+// marshalFields encodes the fields of v, a struct, that selected names,
+// in the order encoding/json would
+func marshalFields(v reflect.Value, selected Fields) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for _, field := range jsonFields(v.Type()) {
+		value := v.Field(field.index)
+		if !selected[field.name] || (field.omitEmpty && isEmpty(value)) {
+			continue
+		}
+
+		encoded, err := json.Marshal(value.Interface())
+		if err != nil {
+			return nil, err
+		}
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// isEmpty reports whether omitempty leaves v out
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Interface, reflect.Pointer:
+		return v.IsZero()
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    []string
+		wantErr bool
+	}{
+		{raw: "", want: nil},
+		{raw: "id,status", want: []string{"id", "status"}},
+		{raw: " id , ,links", want: []string{"id", "links"}},
+		{raw: "id,title", wantErr: true},
+		{raw: "fields", wantErr: true},
+		{raw: "ID", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseFields(tt.raw, Submission{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("ParseFields() = %v, want %v", got, tt.want)
+			}
+			for _, name := range tt.want {
+				if !got[name] {
+					t.Errorf("ParseFields() = %v, want %s", got, name)
+				}
+			}
+			if s := got.String(); s != strings.Join(tt.want, ",") {
+				t.Errorf("String() = %q, want %q", s, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestSubmission_WithFields(t *testing.T) {
+	id := uuid.MustParse("0b6a3f4e-8f1c-4d2a-9a57-3c2f1e0d9b8a")
+	submission := NewSubmission(&models.Submission{ID: id, Content: "text", Status: models.StatusQueued})
+
+	tests := []struct {
+		name   string
+		fields Fields
+		want   string
+	}{
+		{"in declaration order", Fields{"status": true, "id": true}, `{"id":"` + id.String() + `","status":"queued"}`},
+		{"empty omitted", Fields{"id": true, "failure_reason": true}, `{"id":"` + id.String() + `"}`},
+		{"none", Fields{}, `{}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(*submission.WithFields(tt.fields))
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("json.Marshal() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+
+	// Without a selection every field is written
+	data, err := json.Marshal(submission.WithFields(nil))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil || all["content"] != "text" || all["revision"] == nil {
+		t.Errorf("json.Marshal() = %s, want every field", data)
+	}
+}
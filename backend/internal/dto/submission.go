@@ -0,0 +1,134 @@
+package dto
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/links"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Submission is a submitted document and where its analysis has got to
+type Submission struct {
+	ID              uuid.UUID               `json:"id"`
+	UserID          uuid.UUID               `json:"user_id"`
+	Content         string                  `json:"content"`
+	RedactedContent *string                 `json:"redacted_content,omitempty"`
+	Status          models.SubmissionStatus `json:"status"`
+	Version         int                     `json:"version"`
+	CreatedAt       timestamp.Time          `json:"created_at"`
+	UpdatedAt       timestamp.Time          `json:"updated_at"`
+
+	Instructions *string    `json:"instructions,omitempty"`
+	ProfileID    *uuid.UUID `json:"profile_id,omitempty"`
+
+	PreviousID *uuid.UUID `json:"previous_id,omitempty"`
+	Revision   int        `json:"revision"`
+
+	AssigneeID     *uuid.UUID            `json:"assignee_id,omitempty"`
+	DueAt          *timestamp.Time       `json:"due_at,omitempty"`
+	WorkflowStatus models.WorkflowStatus `json:"workflow_status"`
+
+	QueuedAt     *timestamp.Time `json:"queued_at,omitempty"`
+	ProcessingAt *timestamp.Time `json:"processing_at,omitempty"`
+	CompletedAt  *timestamp.Time `json:"completed_at,omitempty"`
+	FailedAt     *timestamp.Time `json:"failed_at,omitempty"`
+	CanceledAt   *timestamp.Time `json:"canceled_at,omitempty"`
+	ArchivedAt   *timestamp.Time `json:"archived_at,omitempty"`
+
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	QuarantinedAt    *timestamp.Time `json:"quarantined_at,omitempty"`
+	QuarantineReason *string         `json:"quarantine_reason,omitempty"`
+
+	DeletedAt *timestamp.Time `json:"deleted_at,omitempty"`
+
+	Stats       *textstats.Stats `json:"stats,omitempty"`
+	DuplicateOf *uuid.UUID       `json:"duplicate_of,omitempty"`
+
+	// Analysis is the latest analysis, embedded with ?include=analysis;
+	// null while there is none
+	Analysis *Analysis `json:"analysis,omitempty"`
+
+	// Links point at the submission's related resources and actions
+	Links links.Links `json:"links,omitempty"`
+
+	// fields limits the JSON to the fields a client asked for
+	fields Fields
+}
+
+// NewSubmission maps a submission
+func NewSubmission(s *models.Submission) *Submission {
+	return &Submission{
+		ID:               s.ID,
+		UserID:           s.UserID,
+		Content:          s.Content,
+		RedactedContent:  s.RedactedContent,
+		Status:           s.Status,
+		Version:          s.Version,
+		CreatedAt:        s.CreatedAt,
+		UpdatedAt:        s.UpdatedAt,
+		Instructions:     s.Instructions,
+		ProfileID:        s.ProfileID,
+		PreviousID:       s.PreviousID,
+		Revision:         s.Revision,
+		AssigneeID:       s.AssigneeID,
+		DueAt:            s.DueAt,
+		WorkflowStatus:   s.WorkflowStatus,
+		QueuedAt:         s.QueuedAt,
+		ProcessingAt:     s.ProcessingAt,
+		CompletedAt:      s.CompletedAt,
+		FailedAt:         s.FailedAt,
+		CanceledAt:       s.CanceledAt,
+		ArchivedAt:       s.ArchivedAt,
+		FailureReason:    s.FailureReason,
+		QuarantinedAt:    s.QuarantinedAt,
+		QuarantineReason: s.QuarantineReason,
+		DeletedAt:        s.DeletedAt,
+		Stats:            s.Stats,
+		DuplicateOf:      s.DuplicateOf,
+	}
+}
+
+// NewSubmissions maps a list of submissions
+func NewSubmissions(submissions []models.Submission) []Submission {
+	out := make([]Submission, len(submissions))
+	for i := range submissions {
+		out[i] = *NewSubmission(&submissions[i])
+	}
+	return out
+}
+
+// WithAnalysis embeds analysis, rendered for v, and returns the submission.
+// A nil analysis is embedded as null.
+func (s *Submission) WithAnalysis(analysis *models.Analysis, v apiversion.Version) *Submission {
+	s.Analysis = NewAnalysis(analysis, v)
+	return s
+}
+
+// WithLinks sets the submission's links and returns it
+func (s *Submission) WithLinks(l links.Links) *Submission {
+	s.Links = l
+	return s
+}
+
+// WithFields limits the submission's JSON to fields and returns it; nil
+// writes every field
+func (s *Submission) WithFields(fields Fields) *Submission {
+	s.fields = fields
+	return s
+}
+
+// MarshalJSON writes the fields selected with WithFields
+func (s Submission) MarshalJSON() ([]byte, error) {
+	if s.fields == nil {
+		type plain Submission
+		return json.Marshal(plain(s))
+	}
+	return marshalFields(reflect.ValueOf(s), s.fields)
+}
@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+func TestNewSubmission(t *testing.T) {
+	submission := &models.Submission{
+		ID:          uuid.New(),
+		Content:     "text",
+		Status:      models.StatusCompleted,
+		CreatedAt:   timestamp.New(time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)),
+		ContentHash: "secret-hash",
+	}
+
+	data, err := json.Marshal(NewSubmission(submission))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"content":"text"`, `"status":"completed"`, `"created_at":"2024-05-01T09:30:00.000Z"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("json.Marshal() = %s, want %s", data, want)
+		}
+	}
+	for _, leaked := range []string{"secret-hash", `"analysis"`} {
+		if strings.Contains(string(data), leaked) {
+			t.Errorf("json.Marshal() = %s, leaked %s", data, leaked)
+		}
+	}
+}
+
+func TestSubmission_WithAnalysis(t *testing.T) {
+	score := 0.5
+	analysis := &models.Analysis{ID: uuid.New(), Sentiment: "positive", SentimentScore: &score}
+	submission := &models.Submission{ID: uuid.New()}
+
+	tests := []struct {
+		name     string
+		analysis *models.Analysis
+		version  apiversion.Version
+		want     string
+	}{
+		{"v1", analysis, apiversion.V1, `"sentiment":"positive","sentiment_score":0.5`},
+		{"v2", analysis, apiversion.V2, `"sentiment":{"label":"positive","score":0.5}`},
+		{"none yet", nil, apiversion.V2, `"analysis":null`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(NewSubmission(submission).WithAnalysis(tt.analysis, tt.version))
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if !strings.Contains(string(data), tt.want) {
+				t.Errorf("json.Marshal() = %s, want %s", data, tt.want)
+			}
+		})
+	}
+}
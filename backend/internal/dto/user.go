@@ -0,0 +1,38 @@
+package dto
+
+import (
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// User is an account without its credentials
+type User struct {
+	ID               string         `json:"id"`
+	Email            string         `json:"email"`
+	DisplayName      *string        `json:"display_name"`
+	TimeZone         *string        `json:"time_zone"`
+	EmailVerified    bool           `json:"email_verified"`
+	Plan             string         `json:"plan"`
+	ConfirmNewLogins bool           `json:"confirm_new_logins"`
+	Status           string         `json:"status"`
+	StatusReason     *string        `json:"status_reason,omitempty"`
+	Version          int            `json:"version"`
+	CreatedAt        timestamp.Time `json:"created_at"`
+}
+
+// NewUser maps a user
+func NewUser(user *models.User) *User {
+	return &User{
+		ID:               user.ID.String(),
+		Email:            user.Email,
+		DisplayName:      user.DisplayName,
+		TimeZone:         user.TimeZone,
+		EmailVerified:    user.EmailVerifiedAt != nil,
+		Plan:             string(user.Plan),
+		ConfirmNewLogins: user.ConfirmNewLogins,
+		Status:           string(user.Status),
+		StatusReason:     user.StatusReason,
+		Version:          user.Version,
+		CreatedAt:        user.CreatedAt,
+	}
+}
@@ -0,0 +1,233 @@
+// Package encryption encrypts sensitive fields at rest with envelope
+// encryption: every value is sealed with its own AES-256-GCM data key,
+// and that key is stored alongside it wrapped by a master key held in
+// config or a KMS. Rotating the master key therefore never rewrites data.
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// prefix marks an encrypted value. Values without it are plaintext
+// written before encryption was enabled and are returned unchanged.
+// Values sealed with only their field name, before they were bound to a
+// record, carry legacyPrefix instead.
+const (
+	prefix       = "enc:v2:"
+	legacyPrefix = "enc:v1:"
+)
+
+// Plaintext stored without encryption that starts with reservedPrefix,
+// and so could be taken for ciphertext, is stored behind plainPrefix
+const (
+	reservedPrefix = "enc:"
+	plainPrefix    = "enc:plain:"
+)
+
+// dataKeySize is the AES-256 key size
+const dataKeySize = 32
+
+var (
+	// ErrMalformed is returned for a value with the prefix that can't be parsed
+	ErrMalformed = errors.New("malformed encrypted value")
+	// ErrUnknownKey is returned when a value was wrapped with a master key
+	// that isn't configured
+	ErrUnknownKey = errors.New("unknown master key")
+)
+
+// KeyWrapper protects data keys with a master key
+type KeyWrapper interface {
+	// Wrap encrypts a data key with the active master key and returns
+	// that key's ID with the result
+	Wrap(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// Unwrap decrypts a data key wrapped with the master key keyID
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KeyDeriver derives keys for purposes other than wrapping data keys,
+// such as keyed hashes of encrypted values
+type KeyDeriver interface {
+	// DeriveKey returns a key for purpose derived from the master key,
+	// and an ID that changes when the derived key does
+	DeriveKey(ctx context.Context, purpose string) (keyID string, key []byte, err error)
+}
+
+// Binding is what a value is sealed to: the field it is stored in, such
+// as "submissions.content", the ID of the record holding it, and the ID
+// of the record's owner if it has one. The same binding must be given to
+// Decrypt, so a value copied into another column, row or account won't
+// open.
+type Binding struct {
+	Field  string
+	Record string
+	Owner  string
+}
+
+// additionalData encodes the binding as GCM additional data. Each part is
+// length-prefixed, so no two bindings encode the same.
+func (b Binding) additionalData() []byte {
+	var data []byte
+	for _, part := range []string{b.Field, b.Record, b.Owner} {
+		data = binary.AppendUvarint(data, uint64(len(part)))
+		data = append(data, part...)
+	}
+	return data
+}
+
+// Encryptor seals and opens field values
+type Encryptor struct {
+	wrapper KeyWrapper
+}
+
+// New creates an encryptor whose data keys are wrapped by wrapper
+func New(wrapper KeyWrapper) *Encryptor {
+	return &Encryptor{wrapper: wrapper}
+}
+
+// DeriveKey returns a key for purpose derived from the master key, and
+// its ID. The same purpose gives the same key until the master key
+// changes.
+func (e *Encryptor) DeriveKey(ctx context.Context, purpose string) (keyID string, key []byte, err error) {
+	deriver, ok := e.wrapper.(KeyDeriver)
+	if !ok {
+		return "", nil, fmt.Errorf("%T can't derive keys", e.wrapper)
+	}
+	return deriver.DeriveKey(ctx, purpose)
+}
+
+// IsEncrypted reports whether value was produced by Encrypt
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix) || strings.HasPrefix(value, legacyPrefix)
+}
+
+// Escape returns plaintext as it should be stored without encryption.
+// Text that starts like an encrypted value is marked as plaintext, so it
+// can't be mistaken for one; anything else is unchanged. Unescape and
+// Decrypt undo it.
+func Escape(plaintext string) string {
+	if strings.HasPrefix(plaintext, reservedPrefix) {
+		return plainPrefix + plaintext
+	}
+	return plaintext
+}
+
+// Unescape returns a plaintext value stored with Escape as it was written
+func Unescape(value string) string {
+	return strings.TrimPrefix(value, plainPrefix)
+}
+
+// Encrypt seals plaintext under a fresh data key, bound to where it is
+// stored
+func (e *Encryptor) Encrypt(ctx context.Context, plaintext string, binding Binding) (string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	sealed, err := seal(dataKey, []byte(plaintext), binding.additionalData())
+	if err != nil {
+		return "", err
+	}
+
+	keyID, wrapped, err := e.wrapper.Wrap(ctx, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	if strings.Contains(keyID, ":") {
+		return "", fmt.Errorf("master key ID %q contains ':'", keyID)
+	}
+
+	return prefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed by Encrypt with the same binding. Values
+// sealed before they were bound to records only need the same field.
+// Plaintext values are returned as they were written.
+func (e *Encryptor) Decrypt(ctx context.Context, value string, binding Binding) (string, error) {
+	additionalData := binding.additionalData()
+	switch {
+	case strings.HasPrefix(value, prefix):
+		value = strings.TrimPrefix(value, prefix)
+	case strings.HasPrefix(value, legacyPrefix):
+		value = strings.TrimPrefix(value, legacyPrefix)
+		additionalData = []byte(binding.Field)
+	default:
+		return Unescape(value), nil
+	}
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", ErrMalformed
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrMalformed
+	}
+
+	dataKey, err := e.wrapper.Unwrap(ctx, parts[0], wrapped)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+
+	plaintext, err := open(dataKey, sealed, additionalData)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// seal encrypts with AES-GCM and returns the nonce followed by the
+// ciphertext
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open reverses seal
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,224 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testKey returns a base64 master key made of one repeated byte
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), dataKeySize)))
+}
+
+// testBinding binds values to a submission's content
+var testBinding = Binding{Field: "submissions.content", Record: "c0ffee00-0000-4000-8000-000000000001", Owner: "c0ffee00-0000-4000-8000-000000000002"}
+
+func newTestEncryptor(t *testing.T, entries ...string) *Encryptor {
+	t.Helper()
+
+	keys, err := ParseMasterKeys(entries)
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	return New(keys)
+}
+
+func TestEncryptor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncryptor(t, "k1:"+testKey('a'))
+
+	for _, plaintext := range []string{"", "hello", "ünïcödé 😀", strings.Repeat("x", 100_000)} {
+		sealed, err := enc.Encrypt(ctx, plaintext, testBinding)
+		if err != nil {
+			t.Fatalf("Encrypt() error = %v", err)
+		}
+		if !IsEncrypted(sealed) || !strings.HasPrefix(sealed, "enc:v2:") || (plaintext != "" && strings.Contains(sealed, plaintext)) {
+			t.Fatalf("Encrypt() = %.40q..., want an opaque enc:v2 value", sealed)
+		}
+
+		got, err := enc.Decrypt(ctx, sealed, testBinding)
+		if err != nil || got != plaintext {
+			t.Errorf("Decrypt() = %.20q, %v, want the plaintext", got, err)
+		}
+	}
+}
+
+func TestEncryptor_FreshKeyPerValue(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncryptor(t, "k1:"+testKey('a'))
+
+	a, _ := enc.Encrypt(ctx, "same", Binding{Field: "f"})
+	b, _ := enc.Encrypt(ctx, "same", Binding{Field: "f"})
+	if a == b {
+		t.Error("Encrypt() gave identical output for two calls")
+	}
+	if strings.Split(a, ":")[3] == strings.Split(b, ":")[3] {
+		t.Error("Encrypt() reused a wrapped data key")
+	}
+}
+
+func TestEncryptor_Decrypt(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncryptor(t, "k1:"+testKey('a'))
+	sealed, err := enc.Encrypt(ctx, "secret", testBinding)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	parts := strings.Split(sealed, ":")
+	legacy := legacyEncrypt(t, enc, "written before binding", testBinding.Field)
+
+	otherField, otherRecord, otherOwner, unowned := testBinding, testBinding, testBinding, testBinding
+	otherField.Field = "submissions.redacted_content"
+	otherRecord.Record = "c0ffee00-0000-4000-8000-000000000003"
+	otherOwner.Owner = "c0ffee00-0000-4000-8000-000000000003"
+	unowned.Owner = ""
+
+	tests := []struct {
+		name    string
+		value   string
+		binding Binding
+		want    string
+		wantErr error
+	}{
+		{name: "plaintext passes through", value: "written before encryption", binding: testBinding, want: "written before encryption"},
+		{name: "other field", value: sealed, binding: otherField, wantErr: errAny},
+		{name: "other record", value: sealed, binding: otherRecord, wantErr: errAny},
+		{name: "other owner", value: sealed, binding: otherOwner, wantErr: errAny},
+		{name: "no owner", value: sealed, binding: unowned, wantErr: errAny},
+		{name: "sealed with the field only", value: legacy, binding: otherRecord, want: "written before binding"},
+		{name: "sealed with another field only", value: legacy, binding: otherField, wantErr: errAny},
+		{name: "tampered ciphertext", value: sealed[:len(sealed)-2] + "AA", binding: testBinding, wantErr: errAny},
+		{name: "unknown key", value: strings.Replace(sealed, ":k1:", ":k9:", 1), binding: testBinding, wantErr: ErrUnknownKey},
+		{name: "truncated", value: strings.Join(parts[:4], ":"), binding: testBinding, wantErr: ErrMalformed},
+		{name: "bad base64", value: "enc:v2:k1:!!:!!", binding: testBinding, wantErr: ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enc.Decrypt(ctx, tt.value, tt.binding)
+			switch {
+			case tt.wantErr == errAny:
+				if err == nil {
+					t.Errorf("Decrypt() = %q, want an error", got)
+				}
+			case !errors.Is(err, tt.wantErr):
+				t.Errorf("Decrypt() error = %v, want %v", err, tt.wantErr)
+			case got != tt.want:
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// legacyEncrypt seals plaintext the way values were before they were
+// bound to records, with only the field name as additional data
+func legacyEncrypt(t *testing.T, enc *Encryptor, plaintext, field string) string {
+	t.Helper()
+
+	dataKey := bytes.Repeat([]byte{1}, dataKeySize)
+	sealed, err := seal(dataKey, []byte(plaintext), []byte(field))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyID, wrapped, err := enc.wrapper.Wrap(context.Background(), dataKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return legacyPrefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(wrapped) + ":" + base64.RawStdEncoding.EncodeToString(sealed)
+}
+
+// errAny marks cases that must fail without a specific sentinel
+var errAny = errors.New("any error")
+
+func TestEscape(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncryptor(t, "k1:"+testKey('a'))
+
+	for _, plaintext := range []string{"", "hello", "enc:v1:k1:not:ciphertext", "enc:plain:hello", "enc:"} {
+		stored := Escape(plaintext)
+		if IsEncrypted(stored) {
+			t.Errorf("Escape(%q) = %q, which passes for ciphertext", plaintext, stored)
+		}
+		if got := Unescape(stored); got != plaintext {
+			t.Errorf("Unescape(Escape(%q)) = %q", plaintext, got)
+		}
+		// Plaintext stored before encryption was enabled still reads back
+		if got, err := enc.Decrypt(ctx, stored, testBinding); err != nil || got != plaintext {
+			t.Errorf("Decrypt(Escape(%q)) = %q, %v", plaintext, got, err)
+		}
+	}
+
+	if Escape("hello") != "hello" {
+		t.Error("Escape() changed ordinary text")
+	}
+}
+
+func TestLocalKeys_Rotation(t *testing.T) {
+	ctx := context.Background()
+	old := newTestEncryptor(t, "2025:"+testKey('a'))
+	sealed, err := old.Encrypt(ctx, "before rotation", Binding{Field: "analyses.summary"})
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+
+	rotated := newTestEncryptor(t, "2026:"+testKey('b'), "2025:"+testKey('a'))
+	if got, err := rotated.Decrypt(ctx, sealed, Binding{Field: "analyses.summary"}); err != nil || got != "before rotation" {
+		t.Errorf("Decrypt() with the old key retired = %q, %v", got, err)
+	}
+
+	fresh, _ := rotated.Encrypt(ctx, "after rotation", Binding{Field: "analyses.summary"})
+	if !strings.HasPrefix(fresh, "enc:v2:2026:") {
+		t.Errorf("Encrypt() = %.20q..., want it wrapped with the new active key", fresh)
+	}
+}
+
+func TestLocalKeys_DeriveKey(t *testing.T) {
+	ctx := context.Background()
+	enc := newTestEncryptor(t, "2025:"+testKey('a'))
+
+	keyID, key, err := enc.DeriveKey(ctx, "content-hash")
+	if err != nil || keyID != "2025" || len(key) != dataKeySize {
+		t.Fatalf("DeriveKey() = %q, %d bytes, %v", keyID, len(key), err)
+	}
+	if _, again, _ := enc.DeriveKey(ctx, "content-hash"); !bytes.Equal(again, key) {
+		t.Error("DeriveKey() gave another key for the same purpose")
+	}
+	if _, other, _ := enc.DeriveKey(ctx, "other"); bytes.Equal(other, key) {
+		t.Error("DeriveKey() gave the same key for another purpose")
+	}
+
+	rotated := newTestEncryptor(t, "2026:"+testKey('b'), "2025:"+testKey('a'))
+	if keyID, other, _ := rotated.DeriveKey(ctx, "content-hash"); keyID != "2026" || bytes.Equal(other, key) {
+		t.Errorf("DeriveKey() after rotation = %q, same key %t, want a key from 2026", keyID, bytes.Equal(other, key))
+	}
+}
+
+func TestParseMasterKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		wantErr bool
+	}{
+		{name: "one key", entries: []string{"k1:" + testKey('a')}},
+		{name: "rotation", entries: []string{"k2:" + testKey('b'), "k1:" + testKey('a')}},
+		{name: "none", entries: nil, wantErr: true},
+		{name: "no id", entries: []string{testKey('a')}, wantErr: true},
+		{name: "bad id", entries: []string{"k 1:" + testKey('a')}, wantErr: true},
+		{name: "short key", entries: []string{"k1:" + base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+		{name: "not base64", entries: []string{"k1:???"}, wantErr: true},
+		{name: "duplicate", entries: []string{"k1:" + testKey('a'), "k1:" + testKey('b')}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMasterKeys(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMasterKeys() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+const (
+	// kmsKeyID is the key ID recorded in values wrapped by KMS; the KMS
+	// ciphertext names the actual key itself
+	kmsKeyID = "kms"
+
+	// kmsCacheSize bounds the unwrapped data keys kept in memory, so
+	// reading the same rows again doesn't call KMS each time
+	kmsCacheSize = 4096
+)
+
+// KMS wraps data keys with an AWS KMS key
+type KMS struct {
+	keyID string
+	// hmacKeyID is the HMAC_256 KMS key keys are derived with
+	hmacKeyID   string
+	endpoint    string
+	region      string
+	signer      *v4.Signer
+	credentials aws.Credentials
+	httpClient  *http.Client
+
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+// NewKMS creates a wrapper for the KMS key keyID (an ID, ARN or alias)
+func NewKMS(region, accessKeyID, secretAccessKey, keyID string) *KMS {
+	return &KMS{
+		keyID:    keyID,
+		endpoint: fmt.Sprintf("https://kms.%s.amazonaws.com/", region),
+		region:   region,
+		signer:   v4.NewSigner(),
+		credentials: aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+		},
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cache:      make(map[string][]byte),
+	}
+}
+
+// WithHMACKey derives keys with the HMAC_256 KMS key keyID and returns
+// the wrapper. Without one, DeriveKey fails.
+func (k *KMS) WithHMACKey(keyID string) *KMS {
+	k.hmacKeyID = keyID
+	return k
+}
+
+// DeriveKey implements KeyDeriver with KMS GenerateMac over purpose. KMS
+// never reveals a symmetric key, so keys are derived with the HMAC key
+// instead, which doesn't rotate.
+func (k *KMS) DeriveKey(ctx context.Context, purpose string) (string, []byte, error) {
+	if k.hmacKeyID == "" {
+		return "", nil, fmt.Errorf("no KMS HMAC key to derive keys with")
+	}
+
+	var resp struct {
+		Mac []byte `json:"Mac"`
+	}
+	err := k.call(ctx, "GenerateMac", map[string]interface{}{
+		"KeyId":        k.hmacKeyID,
+		"Message":      []byte(purpose),
+		"MacAlgorithm": "HMAC_SHA_256",
+	}, &resp)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(resp.Mac) != dataKeySize {
+		return "", nil, fmt.Errorf("KMS GenerateMac returned %d bytes, want %d", len(resp.Mac), dataKeySize)
+	}
+	return kmsKeyID + "/" + k.hmacKeyID, resp.Mac, nil
+}
+
+// Wrap implements KeyWrapper with KMS Encrypt
+func (k *KMS) Wrap(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	var resp struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	err := k.call(ctx, "Encrypt", map[string]interface{}{
+		"KeyId":     k.keyID,
+		"Plaintext": dataKey,
+	}, &resp)
+	if err != nil {
+		return "", nil, err
+	}
+
+	k.remember(resp.CiphertextBlob, dataKey)
+	return kmsKeyID, resp.CiphertextBlob, nil
+}
+
+// Unwrap implements KeyWrapper with KMS Decrypt
+func (k *KMS) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != kmsKeyID {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	k.mu.Lock()
+	dataKey, ok := k.cache[string(wrapped)]
+	k.mu.Unlock()
+	if ok {
+		return dataKey, nil
+	}
+
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	err := k.call(ctx, "Decrypt", map[string]interface{}{
+		"KeyId":          k.keyID,
+		"CiphertextBlob": wrapped,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	k.remember(wrapped, resp.Plaintext)
+	return resp.Plaintext, nil
+}
+
+// remember caches an unwrapped data key, starting over when full
+func (k *KMS) remember(wrapped, dataKey []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.cache) >= kmsCacheSize {
+		clear(k.cache)
+	}
+	k.cache[string(wrapped)] = dataKey
+}
+
+// call invokes a KMS JSON API action. []byte fields are sent and received
+// as base64, which is what KMS expects.
+func (k *KMS) call(ctx context.Context, action string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode KMS request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, k.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create KMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	sum := sha256.Sum256(payload)
+	if err := k.signer.SignHTTP(ctx, k.credentials, req, hex.EncodeToString(sum[:]), "kms", k.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign KMS request: %w", err)
+	}
+
+	resp, err := k.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call KMS %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("KMS %s returned %d: %s", action, resp.StatusCode, detail)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode KMS %s response: %w", action, err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeKMS "encrypts" by reversing the bytes, which is enough to check the
+// wire format
+func fakeKMS(t *testing.T, decrypts *int32) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/kms/aws4_request") {
+			t.Errorf("Authorization = %q, want a SigV4 header scoped to kms", r.Header.Get("Authorization"))
+		}
+
+		var req struct {
+			KeyId          string
+			Plaintext      []byte
+			CiphertextBlob []byte
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.KeyId != "alias/content" {
+			t.Errorf("request = %+v, %v, want KeyId alias/content", req, err)
+		}
+
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": reverse(req.Plaintext)})
+		case "TrentService.Decrypt":
+			atomic.AddInt32(decrypts, 1)
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": reverse(req.CiphertextBlob)})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func TestKMS_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	var decrypts int32
+	srv := fakeKMS(t, &decrypts)
+	defer srv.Close()
+
+	writer := NewKMS("us-east-1", "AKIDEXAMPLE", "secret", "alias/content")
+	writer.endpoint = srv.URL
+	sealed, err := New(writer).Encrypt(ctx, "confidential", testBinding)
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v2:kms:") {
+		t.Errorf("Encrypt() = %.20q..., want the kms key ID", sealed)
+	}
+
+	// Another replica has to ask KMS once, then uses its cache
+	reader := NewKMS("us-east-1", "AKIDEXAMPLE", "secret", "alias/content")
+	reader.endpoint = srv.URL
+	for range 2 {
+		got, err := New(reader).Decrypt(ctx, sealed, testBinding)
+		if err != nil || got != "confidential" {
+			t.Fatalf("Decrypt() = %q, %v, want confidential", got, err)
+		}
+	}
+	if decrypts != 1 {
+		t.Errorf("KMS Decrypt calls = %d, want 1", decrypts)
+	}
+}
+
+func TestKMS_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"__type":"AccessDeniedException"}`))
+	}))
+	defer srv.Close()
+
+	k := NewKMS("us-east-1", "AKIDEXAMPLE", "secret", "alias/content")
+	k.endpoint = srv.URL
+
+	_, err := New(k).Encrypt(context.Background(), "x", Binding{Field: "f"})
+	if err == nil || !strings.Contains(err.Error(), "AccessDeniedException") {
+		t.Errorf("Encrypt() error = %v, want the KMS error", err)
+	}
+}
+
+func TestKMS_DeriveKey(t *testing.T) {
+	mac := bytes.Repeat([]byte{7}, dataKeySize)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			KeyId        string
+			Message      []byte
+			MacAlgorithm string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Header.Get("X-Amz-Target") != "TrentService.GenerateMac" ||
+			req.KeyId != "alias/content-hmac" || string(req.Message) != "content-hash" || req.MacAlgorithm != "HMAC_SHA_256" {
+			t.Errorf("request = %s %+v, %v, want GenerateMac of content-hash with alias/content-hmac", r.Header.Get("X-Amz-Target"), req, err)
+		}
+		json.NewEncoder(w).Encode(map[string][]byte{"Mac": mac})
+	}))
+	defer srv.Close()
+
+	k := NewKMS("us-east-1", "AKIDEXAMPLE", "secret", "alias/content")
+	k.endpoint = srv.URL
+	if _, _, err := New(k).DeriveKey(context.Background(), "content-hash"); err == nil {
+		t.Error("DeriveKey() without an HMAC key succeeded")
+	}
+
+	keyID, key, err := New(k.WithHMACKey("alias/content-hmac")).DeriveKey(context.Background(), "content-hash")
+	if err != nil || keyID != "kms/alias/content-hmac" || !bytes.Equal(key, mac) {
+		t.Errorf("DeriveKey() = %q, %x, %v, want the MAC", keyID, key, err)
+	}
+}
@@ -0,0 +1,81 @@
+package encryption
+
+import (
+	"context"
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// keyIDPattern restricts master key IDs to what fits in the value format
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// LocalKeys wraps data keys with master keys supplied in config. The
+// first key wraps new data keys; the rest only unwrap values written
+// before a rotation.
+type LocalKeys struct {
+	active string
+	keys   map[string][]byte
+}
+
+// ParseMasterKeys reads master keys given as "id:base64key" entries, the
+// active key first. Each key must decode to 32 bytes.
+func ParseMasterKeys(entries []string) (*LocalKeys, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no master keys given")
+	}
+
+	l := &LocalKeys{keys: make(map[string][]byte, len(entries))}
+	for _, entry := range entries {
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok || !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("master keys must look like id:base64key, with an ID of letters, digits, '.', '_' or '-'")
+		}
+		if _, dup := l.keys[id]; dup {
+			return nil, fmt.Errorf("master key %q is given twice", id)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("master key %q must be %d bytes, base64 encoded", id, dataKeySize)
+		}
+
+		l.keys[id] = key
+		if l.active == "" {
+			l.active = id
+		}
+	}
+
+	return l, nil
+}
+
+// Wrap implements KeyWrapper
+func (l *LocalKeys) Wrap(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(l.keys[l.active], dataKey, []byte(l.active))
+	if err != nil {
+		return "", nil, err
+	}
+	return l.active, wrapped, nil
+}
+
+// Unwrap implements KeyWrapper
+func (l *LocalKeys) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := l.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(key, wrapped, []byte(keyID))
+}
+
+// DeriveKey implements KeyDeriver with HKDF-SHA256 over the active master
+// key, so rotating it changes the derived key
+func (l *LocalKeys) DeriveKey(ctx context.Context, purpose string) (string, []byte, error) {
+	key, err := hkdf.Key(sha256.New, l.keys[l.active], nil, purpose, dataKeySize)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return l.active, key, nil
+}
@@ -0,0 +1,153 @@
+// Package errreport sends panics and server errors to an error tracker,
+// with the request, user and stack trace they happened in. Events are
+// sent with sentry-go to Sentry or a tracker that speaks its protocol
+// (GlitchTip, Bugsink); without a DSN reports go nowhere.
+package errreport
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// inAppPrefix marks the frames of this module, which trackers show
+// expanded while library frames are folded away
+const inAppPrefix = "github.com/sfumato00/content-analyzer/"
+
+// Levels of an event
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// Frame is one call in a stack trace
+type Frame struct {
+	Function string
+	File     string
+	Line     int
+}
+
+// InApp reports whether the frame is in this module rather than a library
+// or the runtime
+func (f Frame) InApp() bool {
+	return strings.HasPrefix(f.Function, inAppPrefix)
+}
+
+// Request describes the HTTP request an event happened in. Query strings,
+// cookies and credentials are left out, since they can carry secrets.
+type Request struct {
+	Method  string
+	URL     string
+	Headers map[string]string
+}
+
+// Event is a panic or an error to report
+type Event struct {
+	Level string
+	// Type groups events, such as "panic" or "Internal Server Error"
+	Type    string
+	Message string
+	// Stack is innermost call first, as Go prints it
+	Stack   []Frame
+	Request *Request
+	UserID  string
+	Tags    map[string]string
+	Time    time.Time
+}
+
+// Reporter sends events to an error tracker. Report must not block the
+// caller on the network.
+type Reporter interface {
+	Report(ctx context.Context, event Event)
+	// Close sends the events still waiting, until ctx is done
+	Close(ctx context.Context) error
+}
+
+// Nop discards every event; it is the reporter when none is configured
+type Nop struct{}
+
+// Report does nothing
+func (Nop) Report(ctx context.Context, event Event) {}
+
+// Close does nothing
+func (Nop) Close(ctx context.Context) error { return nil }
+
+// New returns a reporter sending to the tracker at dsn, or Nop when dsn
+// is empty
+func New(dsn, environment string) (Reporter, error) {
+	if dsn == "" {
+		return Nop{}, nil
+	}
+	return NewSentry(dsn, environment)
+}
+
+// Stack returns the calling goroutine's stack, innermost call first,
+// leaving out skip frames above the caller of Stack
+func Stack(skip int) []Frame {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack []Frame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more {
+			break
+		}
+	}
+	return stack
+}
+
+// PanicStack returns the stack of the panic being recovered, starting at
+// the call that panicked. Call it from the deferred function that
+// recovers.
+func PanicStack() []Frame {
+	stack := Stack(1)
+	for i, frame := range stack {
+		if frame.Function == "runtime.gopanic" {
+			return stack[i+1:]
+		}
+	}
+	return stack
+}
+
+type scopeKey struct{}
+
+// Scope collects what is learned about a request as it passes through
+// middleware, such as who made it, for the events reported about it and
+// for outer middleware that only learns it afterwards
+type Scope struct {
+	mu     sync.Mutex
+	userID string
+}
+
+// NewContext returns a context carrying a new scope
+func NewContext(ctx context.Context) (context.Context, *Scope) {
+	scope := &Scope{}
+	return context.WithValue(ctx, scopeKey{}, scope), scope
+}
+
+// FromContext returns ctx's scope, and false if it has none
+func FromContext(ctx context.Context) (*Scope, bool) {
+	scope, ok := ctx.Value(scopeKey{}).(*Scope)
+	return scope, ok
+}
+
+// SetUser records the user making the request in ctx's scope, if any
+func SetUser(ctx context.Context, userID string) {
+	if scope, ok := ctx.Value(scopeKey{}).(*Scope); ok {
+		scope.mu.Lock()
+		scope.userID = userID
+		scope.mu.Unlock()
+	}
+}
+
+// UserID returns the user recorded in the scope, or ""
+func (s *Scope) UserID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.userID
+}
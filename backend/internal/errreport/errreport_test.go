@@ -0,0 +1,56 @@
+package errreport
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPanicStack(t *testing.T) {
+	var stack []Frame
+	func() {
+		defer func() {
+			recover()
+			stack = PanicStack()
+		}()
+		explode()
+	}()
+
+	if len(stack) == 0 || !strings.HasSuffix(stack[0].Function, "errreport.explode") {
+		t.Fatalf("PanicStack() starts at %v, want the call that panicked", stack)
+	}
+	if !stack[0].InApp() || stack[0].Line == 0 {
+		t.Errorf("frame = %+v, want an in-app frame with its line", stack[0])
+	}
+}
+
+func explode() {
+	panic("boom")
+}
+
+func TestScope(t *testing.T) {
+	// Without a scope, setting the user is harmless
+	SetUser(context.Background(), "ignored")
+
+	ctx, scope := NewContext(context.Background())
+	SetUser(context.WithValue(ctx, struct{}{}, "derived"), "user-1")
+	if got := scope.UserID(); got != "user-1" {
+		t.Errorf("UserID() = %q, want the user set on a derived context", got)
+	}
+	if found, ok := FromContext(ctx); !ok || found != scope {
+		t.Error("FromContext() didn't return the scope")
+	}
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("FromContext() found a scope in a context without one")
+	}
+}
+
+func TestNew_WithoutDSN(t *testing.T) {
+	reporter, err := New("", "production")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := reporter.(Nop); !ok {
+		t.Errorf("New() = %T, want Nop", reporter)
+	}
+}
@@ -0,0 +1,107 @@
+package errreport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/sfumato00/content-analyzer/internal/version"
+)
+
+// ValidateDSN checks that raw is a Sentry DSN such as
+// https://key@o1.ingest.sentry.io/42
+func ValidateDSN(raw string) error {
+	_, err := sentry.NewDsn(raw)
+	return err
+}
+
+// Sentry reports events through sentry-go, whose transport queues them
+// and sends them in the background
+type Sentry struct {
+	client *sentry.Client
+}
+
+// NewSentry creates a reporter for the project at dsn
+func NewSentry(dsn, environment string) (*Sentry, error) {
+	hostname, _ := os.Hostname()
+
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         dsn,
+		Release:     release(version.Get()),
+		Environment: environment,
+		ServerName:  hostname,
+		// Events are built from what middleware chose to keep, so the
+		// SDK adds no request data of its own
+		SendDefaultPII: false,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid DSN: %w", err)
+	}
+	return &Sentry{client: client}, nil
+}
+
+// Report queues an event to send. The transport drops it if its queue is
+// full, rather than letting events pile up while the tracker is down.
+func (s *Sentry) Report(ctx context.Context, event Event) {
+	s.client.CaptureEvent(s.event(event), nil, nil)
+}
+
+// Close sends the events still waiting, until ctx is done, and stops the
+// transport
+func (s *Sentry) Close(ctx context.Context) error {
+	defer s.client.Close()
+	if !s.client.FlushWithContext(ctx) {
+		return errors.New("timed out sending error reports")
+	}
+	return nil
+}
+
+// release names the build events come from, so the tracker can tell
+// which release introduced or fixed an error
+func release(build version.Info) string {
+	if build.Version != "dev" || build.Commit == "unknown" {
+		return "content-analyzer@" + build.Version
+	}
+	return "content-analyzer@" + build.ShortCommit()
+}
+
+// event converts an event to Sentry's shape
+func (s *Sentry) event(event Event) *sentry.Event {
+	converted := sentry.NewEvent()
+	converted.Level = sentry.LevelError
+	if event.Level != "" {
+		converted.Level = sentry.Level(event.Level)
+	}
+	if !event.Time.IsZero() {
+		converted.Timestamp = event.Time
+	} else {
+		converted.Timestamp = time.Now()
+	}
+	converted.Tags = event.Tags
+
+	exception := sentry.Exception{Type: event.Type, Value: event.Message}
+	if len(event.Stack) > 0 {
+		// Sentry lists frames outermost first
+		frames := make([]sentry.Frame, len(event.Stack))
+		for i, frame := range event.Stack {
+			f := sentry.NewFrame(runtime.Frame{Function: frame.Function, File: frame.File, Line: frame.Line})
+			f.InApp = frame.InApp()
+			frames[len(frames)-1-i] = f
+		}
+		exception.Stacktrace = &sentry.Stacktrace{Frames: frames}
+	}
+	converted.Exception = []sentry.Exception{exception}
+
+	if event.Request != nil {
+		converted.Request = &sentry.Request{Method: event.Request.Method, URL: event.Request.URL, Headers: event.Request.Headers}
+	}
+	if event.UserID != "" {
+		converted.User = sentry.User{ID: event.UserID}
+	}
+	return converted
+}
@@ -0,0 +1,115 @@
+package errreport
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+
+	"github.com/sfumato00/content-analyzer/internal/version"
+)
+
+func TestValidateDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		wantErr bool
+	}{
+		{dsn: "https://abc@o1.ingest.sentry.io/42"},
+		{dsn: "http://abc@glitchtip.internal:8000/tracker/7"},
+		{dsn: "https://o1.ingest.sentry.io/42", wantErr: true},
+		{dsn: "https://abc@o1.ingest.sentry.io/", wantErr: true},
+		{dsn: "ftp://abc@example.com/1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dsn, func(t *testing.T) {
+			if err := ValidateDSN(tt.dsn); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDSN() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSentry_Report(t *testing.T) {
+	received := make(chan []string, 1)
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("X-Sentry-Auth")
+		var lines []string
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		received <- lines
+	}))
+	defer server.Close()
+
+	dsn := strings.Replace(server.URL, "://", "://abc@", 1) + "/42"
+	reporter, err := NewSentry(dsn, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reporter.Report(context.Background(), Event{
+		Level:   LevelFatal,
+		Type:    "panic",
+		Message: "boom",
+		Stack:   []Frame{{Function: inAppPrefix + "handlers.(*SubmissionHandler).Create", Line: 42}, {Function: "net/http.HandlerFunc.ServeHTTP", Line: 7}},
+		Request: &Request{Method: http.MethodPost, URL: "https://api.example.com/api/v1/submissions"},
+		UserID:  "user-1",
+		Tags:    map[string]string{"request_id": "req-1"},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := reporter.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := <-received
+	if len(lines) != 3 || !strings.Contains(lines[1], `"type":"event"`) {
+		t.Fatalf("envelope = %q, want a header, an item header and an event", lines)
+	}
+	if !strings.Contains(auth, "sentry_key=abc") {
+		t.Errorf("X-Sentry-Auth = %q, want the public key", auth)
+	}
+
+	var event sentry.Event
+	if err := json.Unmarshal([]byte(lines[2]), &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Level != sentry.LevelFatal || event.Environment != "staging" || event.User.ID != "user-1" || event.Tags["request_id"] != "req-1" {
+		t.Errorf("event = %+v, want the level, environment, user and tags", event)
+	}
+	if len(event.Exception) != 1 || event.Exception[0].Stacktrace == nil {
+		t.Fatalf("exception = %+v, want the panic with its stack", event.Exception)
+	}
+	exception := event.Exception[0]
+	frames := exception.Stacktrace.Frames
+	if exception.Type != "panic" || exception.Value != "boom" || len(frames) != 2 || !frames[1].InApp || frames[0].InApp {
+		t.Errorf("exception = %+v, want the panic with the handler frame last", exception)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tests := []struct {
+		build version.Info
+		want  string
+	}{
+		{version.Info{Version: "v1.4.0", Commit: "0123456789abcdef"}, "content-analyzer@v1.4.0"},
+		{version.Info{Version: "dev", Commit: "0123456789abcdef"}, "content-analyzer@0123456789ab"},
+		{version.Info{Version: "dev", Commit: "unknown"}, "content-analyzer@dev"},
+	}
+
+	for _, tt := range tests {
+		if got := release(tt.build); got != tt.want {
+			t.Errorf("release(%+v) = %q, want %q", tt.build, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package flags evaluates feature flags so risky features can be rolled out
+// to a few users, then a growing percentage, and switched off again without
+// a deploy. Flags live in Postgres; each process keeps a snapshot that is
+// refreshed periodically and immediately after a local change.
+package flags
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// DefaultRefreshInterval bounds how long a change made on another instance
+// takes to apply
+const DefaultRefreshInterval = 30 * time.Second
+
+// Source lists the stored flags
+type Source interface {
+	List(ctx context.Context) ([]models.FeatureFlag, error)
+}
+
+// Checker reports whether a flag is on for a user
+type Checker interface {
+	Enabled(ctx context.Context, key string, userID uuid.UUID) bool
+}
+
+// Flags evaluates flags against a cached snapshot of a Source
+type Flags struct {
+	source   Source
+	interval time.Duration
+
+	// refreshing is held by the one caller reloading the snapshot; others
+	// keep using the previous one meanwhile
+	refreshing sync.Mutex
+
+	mu       sync.RWMutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+}
+
+// New creates an evaluator over source
+func New(source Source) *Flags {
+	return &Flags{source: source, interval: DefaultRefreshInterval}
+}
+
+// WithRefreshInterval changes how often the snapshot is reloaded and
+// returns the evaluator
+func (f *Flags) WithRefreshInterval(interval time.Duration) *Flags {
+	f.interval = interval
+	return f
+}
+
+// Enabled reports whether the flag is on for userID. Unknown flags are off,
+// and so is everything when flags have never loaded.
+func (f *Flags) Enabled(ctx context.Context, key string, userID uuid.UUID) bool {
+	flag, ok := f.snapshot(ctx)[key]
+	return ok && flag.EnabledFor(userID)
+}
+
+// EnabledKeys returns the keys of every flag that is on for userID, sorted
+func (f *Flags) EnabledKeys(ctx context.Context, userID uuid.UUID) []string {
+	keys := []string{}
+	for key, flag := range f.snapshot(ctx) {
+		if flag.EnabledFor(userID) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Invalidate makes the next check reload the flags
+func (f *Flags) Invalidate() {
+	f.mu.Lock()
+	f.loadedAt = time.Time{}
+	f.mu.Unlock()
+}
+
+// snapshot returns the cached flags, reloading them when stale. Only the
+// first load blocks; later reloads are done by one caller while the rest
+// use the previous snapshot.
+func (f *Flags) snapshot(ctx context.Context) map[string]models.FeatureFlag {
+	f.mu.RLock()
+	flags, fresh := f.flags, time.Since(f.loadedAt) < f.interval
+	f.mu.RUnlock()
+	if fresh {
+		return flags
+	}
+
+	if flags == nil {
+		f.refreshing.Lock()
+	} else if !f.refreshing.TryLock() {
+		return flags
+	}
+	defer f.refreshing.Unlock()
+
+	// Another caller may have reloaded while we waited
+	f.mu.RLock()
+	flags, fresh = f.flags, time.Since(f.loadedAt) < f.interval
+	f.mu.RUnlock()
+	if fresh {
+		return flags
+	}
+
+	list, err := f.source.List(ctx)
+	if err != nil {
+		// Keep the last good snapshot and try again after the interval
+		slog.Error("Failed to load feature flags", "error", err)
+		list = nil
+		for _, flag := range flags {
+			list = append(list, flag)
+		}
+	}
+
+	loaded := make(map[string]models.FeatureFlag, len(list))
+	for _, flag := range list {
+		loaded[flag.Key] = flag
+	}
+
+	f.mu.Lock()
+	f.flags = loaded
+	f.loadedAt = time.Now()
+	f.mu.Unlock()
+
+	return loaded
+}
+
+// Require hides the routes it wraps, answering 404, from users the flag is
+// off for. It must run after auth.Middleware; without a user only flags
+// rolled out to everyone pass.
+func Require(checker Checker, key string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, _ := auth.GetUserIDFromContext(r.Context())
+			if !checker.Enabled(r.Context(), key, userID) {
+				response.NotFound(w, "The requested resource was not found")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
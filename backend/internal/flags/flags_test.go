@@ -0,0 +1,147 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// fakeSource serves a fixed flag list and counts loads
+type fakeSource struct {
+	mu    sync.Mutex
+	flags []models.FeatureFlag
+	err   error
+	loads int
+}
+
+func (s *fakeSource) List(ctx context.Context) ([]models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return append([]models.FeatureFlag(nil), s.flags...), nil
+}
+
+func (s *fakeSource) set(flags []models.FeatureFlag, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags, s.err = flags, err
+}
+
+func TestFlags_Enabled(t *testing.T) {
+	user := uuid.New()
+	source := &fakeSource{flags: []models.FeatureFlag{
+		{Key: "on", Enabled: true, RolloutPercent: 100},
+		{Key: "off", Enabled: false, RolloutPercent: 100},
+		{Key: "beta", Enabled: true, UserIDs: []uuid.UUID{user}},
+	}}
+	flags := New(source)
+	ctx := context.Background()
+
+	tests := []struct {
+		key    string
+		userID uuid.UUID
+		want   bool
+	}{
+		{"on", user, true},
+		{"off", user, false},
+		{"beta", user, true},
+		{"beta", uuid.New(), false},
+		{"unknown", user, false},
+	}
+
+	for _, tt := range tests {
+		if got := flags.Enabled(ctx, tt.key, tt.userID); got != tt.want {
+			t.Errorf("Enabled(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	if source.loads != 1 {
+		t.Errorf("source loaded %d times, want 1", source.loads)
+	}
+
+	if got, want := flags.EnabledKeys(ctx, user), []string{"beta", "on"}; !slices.Equal(got, want) {
+		t.Errorf("EnabledKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestFlags_Invalidate(t *testing.T) {
+	source := &fakeSource{flags: []models.FeatureFlag{{Key: "f", Enabled: true, RolloutPercent: 100}}}
+	flags := New(source)
+	ctx := context.Background()
+
+	if !flags.Enabled(ctx, "f", uuid.New()) {
+		t.Fatal("Enabled() = false before the change, want true")
+	}
+
+	source.set([]models.FeatureFlag{{Key: "f", Enabled: false}}, nil)
+	if !flags.Enabled(ctx, "f", uuid.New()) {
+		t.Error("Enabled() = false within the refresh interval, want the cached true")
+	}
+
+	flags.Invalidate()
+	if flags.Enabled(ctx, "f", uuid.New()) {
+		t.Error("Enabled() = true after Invalidate, want false")
+	}
+}
+
+func TestFlags_KeepsSnapshotOnError(t *testing.T) {
+	source := &fakeSource{flags: []models.FeatureFlag{{Key: "f", Enabled: true, RolloutPercent: 100}}}
+	flags := New(source).WithRefreshInterval(time.Nanosecond)
+	ctx := context.Background()
+
+	flags.Enabled(ctx, "f", uuid.New())
+	source.set(nil, errors.New("connection refused"))
+
+	if !flags.Enabled(ctx, "f", uuid.New()) {
+		t.Error("Enabled() = false after a failed reload, want the last loaded value")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	user := uuid.New()
+	flags := New(&fakeSource{flags: []models.FeatureFlag{
+		{Key: "beta", Enabled: true, UserIDs: []uuid.UUID{user}},
+	}})
+	handler := Require(flags, "beta")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		want   int
+	}{
+		{"enabled user", user, http.StatusOK},
+		{"other user", uuid.New(), http.StatusNotFound},
+		{"anonymous", uuid.Nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.userID != uuid.Nil {
+				req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, tt.userID))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
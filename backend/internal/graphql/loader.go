@@ -0,0 +1,116 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// loaderWait is how long a loader collects keys after the first before
+// fetching them. Fields of a list's elements are resolved concurrently,
+// so a page's lookups arrive within it.
+const loaderWait = 2 * time.Millisecond
+
+// loaderMaxBatch fetches a batch early once this many keys are waiting
+const loaderMaxBatch = maxPageSize
+
+// Loader batches the lookups of one request made at about the same time
+// into one call of fetch, and remembers the results for the rest of the
+// request. It must not be shared between requests or users.
+type Loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending *batch[K, V]
+	// batches holds the batch each key was fetched, or is waiting, in
+	batches map[K]*batch[K, V]
+}
+
+// batch is one call of a loader's fetch
+type batch[K comparable, V any] struct {
+	keys   []K
+	once   sync.Once
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+// NewLoader creates a loader that fetches keys with fetch. fetch leaves
+// out keys that have no value.
+func NewLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, batches: map[K]*batch[K, V]{}}
+}
+
+// Load returns the value of key, or the zero value if it has none
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	b, ok := l.batches[key]
+	if !ok {
+		b = l.pending
+		if b == nil {
+			b = &batch[K, V]{done: make(chan struct{})}
+			l.pending = b
+			time.AfterFunc(loaderWait, func() { l.run(ctx, b) })
+		}
+		b.keys = append(b.keys, key)
+		l.batches[key] = b
+		if len(b.keys) >= loaderMaxBatch {
+			go l.run(ctx, b)
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+		return b.values[key], b.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// run fetches a batch, once
+func (l *Loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	b.once.Do(func() {
+		l.mu.Lock()
+		if l.pending == b {
+			l.pending = nil
+		}
+		keys := b.keys
+		l.mu.Unlock()
+
+		b.values, b.err = l.fetch(ctx, keys)
+		close(b.done)
+	})
+}
+
+// loaders are a request's loaders
+type loaders struct {
+	analyses *Loader[uuid.UUID, *models.Analysis]
+	labels   *Loader[uuid.UUID, []models.Classification]
+}
+
+type loadersKey struct{}
+
+// WithLoaders returns a context carrying fresh loaders for one request by
+// userID, batching the latest analysis and labels of submissions
+func (r *Resolver) WithLoaders(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, loadersKey{}, &loaders{
+		analyses: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.Analysis, error) {
+			return r.submissions.GetAnalyses(ctx, userID, ids)
+		}),
+		labels: NewLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID][]models.Classification, error) {
+			return r.submissions.GetLabels(ctx, userID, ids)
+		}),
+	})
+}
+
+// loadersFrom returns the loaders of the request ctx belongs to
+func loadersFrom(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
@@ -0,0 +1,67 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestLoader_Batches(t *testing.T) {
+	var mu sync.Mutex
+	var calls [][]int
+	loader := NewLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		calls = append(calls, keys)
+		mu.Unlock()
+		values := map[int]string{}
+		for _, key := range keys {
+			if key != 3 {
+				values[key] = string(rune('a' + key))
+			}
+		}
+		return values, nil
+	})
+
+	ctx := context.Background()
+	keys := []int{0, 1, 2, 3, 1}
+	got := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			value, err := loader.Load(ctx, key)
+			if err != nil {
+				t.Errorf("Load(%d) error = %v", key, err)
+			}
+			got[i] = value
+		}()
+	}
+	wg.Wait()
+
+	want := []string{"a", "b", "c", "", "b"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Load(%d) = %q, want %q", keys[i], got[i], want[i])
+		}
+	}
+	if len(calls) != 1 || len(calls[0]) != 4 {
+		t.Fatalf("fetch calls = %v, want one of 4 keys", calls)
+	}
+
+	// Keys already loaded are remembered
+	if value, _ := loader.Load(ctx, 2); value != "c" || len(calls) != 1 {
+		t.Errorf("Load(2) again = %q after %d calls, want c from the first", value, len(calls))
+	}
+}
+
+func TestLoader_Error(t *testing.T) {
+	fetchErr := errors.New("database down")
+	loader := NewLoader(func(ctx context.Context, keys []string) (map[string]int, error) {
+		return nil, fetchErr
+	})
+	if _, err := loader.Load(context.Background(), "a"); !errors.Is(err, fetchErr) {
+		t.Errorf("Load() error = %v, want %v", err, fetchErr)
+	}
+}
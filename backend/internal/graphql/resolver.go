@@ -0,0 +1,71 @@
+// Package graphql serves read-only GraphQL queries over the signed-in
+// user, their submissions and the submissions' analyses. The executor is
+// generated by gqlgen from schema.graphqls; resolvers read through the
+// same stores as the REST handlers.
+package graphql
+
+//go:generate go tool gqlgen generate --config gqlgen.yml
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Page sizes, as the REST submission list allows them
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// UserReader reads accounts; *models.UserStore implements it
+type UserReader interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// SubmissionReader reads users' submissions and their latest analyses;
+// *models.SubmissionStore implements it
+type SubmissionReader interface {
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error)
+	List(ctx context.Context, userID uuid.UUID, filter models.SubmissionFilter, limit, offset int) ([]models.Submission, int, error)
+	GetAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*models.Analysis, error)
+	GetLabels(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID][]models.Classification, error)
+}
+
+// SubmissionPage is a page of submissions and the total matching
+type SubmissionPage struct {
+	Total  int
+	Limit  int
+	Offset int
+	Nodes  []*dto.Submission
+}
+
+// Resolver resolves the schema's fields
+type Resolver struct {
+	users       UserReader
+	submissions SubmissionReader
+}
+
+// NewResolver creates a resolver reading from the given stores
+func NewResolver(users UserReader, submissions SubmissionReader) *Resolver {
+	return &Resolver{users: users, submissions: submissions}
+}
+
+// Schema returns the executable schema. A page of submissions costs its
+// limit times the fields selected on each, so complexity limits bound
+// the rows a query can load.
+func (r *Resolver) Schema() graphql.ExecutableSchema {
+	config := Config{Resolvers: r}
+	config.Complexity.Query.Submissions = func(childComplexity int, limit, offset *int, keyword, label *string) int {
+		n := defaultPageSize
+		if limit != nil {
+			n = *limit
+		}
+		return 1 + n*childComplexity
+	}
+	return NewExecutableSchema(config)
+}
@@ -0,0 +1,32 @@
+package graphql
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// MarshalTime writes a timestamp as the REST API does
+func MarshalTime(t timestamp.Time) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(timestamp.Format(t.Time)))
+	})
+}
+
+// UnmarshalTime reads a timestamp in any format the REST API accepts
+func UnmarshalTime(v any) (timestamp.Time, error) {
+	s, ok := v.(string)
+	if !ok {
+		return timestamp.Time{}, fmt.Errorf("time must be a string")
+	}
+	t, err := timestamp.Parse(s, time.UTC)
+	if err != nil {
+		return timestamp.Time{}, err
+	}
+	return timestamp.New(t), nil
+}
@@ -0,0 +1,176 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver
+// implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.85
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+	pgx "github.com/jackc/pgx/v5"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Claims is the resolver for the claims field.
+func (r *analysisResolver) Claims(ctx context.Context, obj *dto.AnalysisV2) (any, error) {
+	return obj.Claims, nil
+}
+
+// Bias is the resolver for the bias field.
+func (r *analysisResolver) Bias(ctx context.Context, obj *dto.AnalysisV2) (any, error) {
+	return obj.Bias, nil
+}
+
+// AiDetection is the resolver for the aiDetection field.
+func (r *analysisResolver) AiDetection(ctx context.Context, obj *dto.AnalysisV2) (any, error) {
+	return obj.AIDetection, nil
+}
+
+// Moderation is the resolver for the moderation field.
+func (r *analysisResolver) Moderation(ctx context.Context, obj *dto.AnalysisV2) (any, error) {
+	return obj.Moderation, nil
+}
+
+// PolicyDecision is the resolver for the policyDecision field.
+func (r *analysisResolver) PolicyDecision(ctx context.Context, obj *dto.AnalysisV2) (any, error) {
+	return obj.PolicyDecision, nil
+}
+
+// Type is the resolver for the type field.
+func (r *findingResolver) Type(ctx context.Context, obj *models.Finding) (string, error) {
+	return string(obj.Type), nil
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*dto.User, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	user, err := r.users.GetByID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to get user", "error", err)
+		return nil, errors.New("failed to get user")
+	}
+	return dto.NewUser(user), nil
+}
+
+// Submission is the resolver for the submission field.
+func (r *queryResolver) Submission(ctx context.Context, id uuid.UUID) (*dto.Submission, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := r.submissions.GetByID(ctx, userID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("Failed to get submission", "error", err)
+		return nil, errors.New("failed to get submission")
+	}
+	return dto.NewSubmission(submission), nil
+}
+
+// Submissions is the resolver for the submissions field.
+func (r *queryResolver) Submissions(ctx context.Context, limit *int, offset *int, keyword *string, label *string) (*SubmissionPage, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SubmissionPage{Limit: defaultPageSize}
+	if limit != nil {
+		page.Limit = *limit
+	}
+	if page.Limit < 1 || page.Limit > maxPageSize {
+		return nil, errors.New("limit must be between 1 and 100")
+	}
+	if offset != nil {
+		page.Offset = *offset
+	}
+	if page.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+	var filter models.SubmissionFilter
+	if keyword != nil {
+		filter.Keyword = *keyword
+	}
+	if label != nil {
+		filter.Label = *label
+	}
+
+	submissions, total, err := r.submissions.List(ctx, userID, filter, page.Limit, page.Offset)
+	if err != nil {
+		slog.Error("Failed to list submissions", "error", err)
+		return nil, errors.New("failed to list submissions")
+	}
+
+	page.Total = total
+	page.Nodes = make([]*dto.Submission, len(submissions))
+	for i := range submissions {
+		page.Nodes[i] = dto.NewSubmission(&submissions[i])
+	}
+	return page, nil
+}
+
+// Status is the resolver for the status field.
+func (r *submissionResolver) Status(ctx context.Context, obj *dto.Submission) (string, error) {
+	return string(obj.Status), nil
+}
+
+// WorkflowStatus is the resolver for the workflowStatus field.
+func (r *submissionResolver) WorkflowStatus(ctx context.Context, obj *dto.Submission) (string, error) {
+	return string(obj.WorkflowStatus), nil
+}
+
+// Analysis is the resolver for the analysis field.
+func (r *submissionResolver) Analysis(ctx context.Context, obj *dto.Submission) (*dto.AnalysisV2, error) {
+	analysis, err := loadersFrom(ctx).analyses.Load(ctx, obj.ID)
+	if err != nil {
+		slog.Error("Failed to get analyses", "error", err)
+		return nil, errors.New("failed to get analysis")
+	}
+	if analysis == nil {
+		return nil, nil
+	}
+	v2 := dto.NewAnalysisV2(analysis)
+	return &v2, nil
+}
+
+// Labels is the resolver for the labels field.
+func (r *submissionResolver) Labels(ctx context.Context, obj *dto.Submission) ([]models.Classification, error) {
+	labels, err := loadersFrom(ctx).labels.Load(ctx, obj.ID)
+	if err != nil {
+		slog.Error("Failed to get labels", "error", err)
+		return nil, errors.New("failed to get labels")
+	}
+	if labels == nil {
+		labels = []models.Classification{}
+	}
+	return labels, nil
+}
+
+// Analysis returns AnalysisResolver implementation.
+func (r *Resolver) Analysis() AnalysisResolver { return &analysisResolver{r} }
+
+// Finding returns FindingResolver implementation.
+func (r *Resolver) Finding() FindingResolver { return &findingResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+// Submission returns SubmissionResolver implementation.
+func (r *Resolver) Submission() SubmissionResolver { return &submissionResolver{r} }
+
+type analysisResolver struct{ *Resolver }
+type findingResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
+type submissionResolver struct{ *Resolver }
@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// AccountHandler handles credential changes for the current user
+type AccountHandler struct {
+	userStore  UserStorer
+	tokenStore EmailTokenStorer
+	jwtManager *auth.JWTManager
+	notifier   AccountNotifier
+	sessions   SessionRevoker
+	audit      AuditRecorder
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(userStore UserStorer, tokenStore EmailTokenStorer, jwtManager *auth.JWTManager, notifier AccountNotifier, sessions SessionRevoker, audit AuditRecorder) *AccountHandler {
+	return &AccountHandler{
+		userStore:  userStore,
+		tokenStore: tokenStore,
+		jwtManager: jwtManager,
+		notifier:   notifier,
+		sessions:   sessions,
+		audit:      audit,
+	}
+}
+
+// ChangePasswordRequest represents the change password request
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ChangeEmailRequest represents the change email request
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// ConfirmEmailChangeRequest represents the email change confirmation request
+type ConfirmEmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// UpdateProfileRequest represents the profile update request. An empty
+// display name clears it.
+type UpdateProfileRequest struct {
+	DisplayName string `json:"display_name"`
+	// TimeZone is an IANA time zone such as Europe/Paris; leaving it out
+	// keeps the current one, and "" clears it
+	TimeZone *string `json:"time_zone"`
+	// Version is the version being updated, for clients that can't send If-Match
+	Version *int `json:"version"`
+}
+
+// UpdateProfile changes the current user's profile. The client must send
+// the version it read, in If-Match or the body; a stale version gets 409.
+// PATCH /api/v1/me
+func (h *AccountHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	version, err := expectedVersion(r, req.Version)
+	if err != nil {
+		writePreconditionError(w, err)
+		return
+	}
+
+	var displayName *string
+	if name := strings.TrimSpace(req.DisplayName); name != "" {
+		if utf8.RuneCountInString(name) > models.MaxDisplayNameLength {
+			response.ValidationError(w, map[string]string{
+				"display_name": fmt.Sprintf("Display name must be at most %d characters", models.MaxDisplayNameLength),
+			})
+			return
+		}
+		displayName = &name
+	}
+
+	timeZone, ok := h.profileTimeZone(w, r, userID, req.TimeZone)
+	if !ok {
+		return
+	}
+
+	user, err := h.userStore.UpdateProfile(r.Context(), userID, version, displayName, timeZone)
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "User not found")
+		case errors.Is(err, models.ErrVersionConflict):
+			response.Conflict(w, "Profile was changed by another request; reload it and try again")
+		default:
+			slog.Error("Failed to update profile", "user_id", userID, "error", err)
+			response.InternalServerError(w, "Failed to update profile")
+		}
+		return
+	}
+
+	setETag(w, user.Version)
+	response.Success(w, dto.NewUser(user))
+}
+
+// profileTimeZone validates the time zone a profile update sets, or
+// returns the user's current one if it leaves it out. It reports whether
+// it could, having written the response if not.
+func (h *AccountHandler) profileTimeZone(w http.ResponseWriter, r *http.Request, userID uuid.UUID, requested *string) (*string, bool) {
+	if requested == nil {
+		user, err := h.userStore.GetByID(r.Context(), userID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				response.NotFound(w, "User not found")
+				return nil, false
+			}
+			slog.Error("Failed to get user", "user_id", userID, "error", err)
+			response.InternalServerError(w, "Failed to update profile")
+			return nil, false
+		}
+		return user.TimeZone, true
+	}
+
+	name := strings.TrimSpace(*requested)
+	if name == "" {
+		return nil, true
+	}
+	if _, err := models.LoadTimeZone(name); err != nil {
+		response.ValidationError(w, map[string]string{"time_zone": "Time zone must be an IANA name such as Europe/Paris"})
+		return nil, false
+	}
+	return &name, true
+}
+
+// ChangePassword sets a new password and signs out every other session.
+// The response carries a fresh token for the caller.
+// PUT /api/v1/me/password
+func (h *AccountHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if err := user.ComparePassword(req.CurrentPassword); err != nil {
+		response.ValidationError(w, map[string]string{"current_password": "Current password is incorrect"})
+		return
+	}
+	if err := models.ValidatePassword(req.NewPassword); err != nil {
+		response.ValidationError(w, map[string]string{"new_password": err.Error()})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		response.ValidationError(w, map[string]string{"new_password": "New password must be different from the current one"})
+		return
+	}
+
+	if err := h.userStore.UpdatePassword(r.Context(), user.ID, req.NewPassword); err != nil {
+		slog.Error("Failed to update password", "error", err)
+		response.InternalServerError(w, "Failed to change password")
+		return
+	}
+
+	h.record(r, user.ID, models.AuditPasswordChanged, nil)
+
+	// Revoke first so the token issued below is the only one left
+	if err := h.sessions.RevokeAll(r.Context(), user.ID); err != nil {
+		slog.Error("Failed to revoke sessions after password change", "user_id", user.ID, "error", err)
+		response.InternalServerError(w, "Password changed but other sessions could not be signed out")
+		return
+	}
+
+	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
+	if err != nil {
+		slog.Error("Failed to generate token", "error", err)
+		response.InternalServerError(w, "Failed to generate authentication token")
+		return
+	}
+
+	response.Success(w, AuthResponse{
+		User:  dto.NewUser(user),
+		Token: tokenPair,
+	})
+}
+
+// ChangeEmail emails a confirmation link to a new address. The account
+// keeps its current email until the link is followed.
+// PUT /api/v1/me/email
+func (h *AccountHandler) ChangeEmail(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	if err := user.ComparePassword(req.Password); err != nil {
+		response.ValidationError(w, map[string]string{"password": "Password is incorrect"})
+		return
+	}
+	if err := models.ValidateEmail(email); err != nil {
+		response.ValidationError(w, map[string]string{"email": err.Error()})
+		return
+	}
+	if email == user.Email {
+		response.ValidationError(w, map[string]string{"email": "New email must be different from the current one"})
+		return
+	}
+
+	switch _, err := h.userStore.GetByEmail(r.Context(), email); {
+	case err == nil:
+		response.BadRequest(w, "Email already exists")
+		return
+	case !errors.Is(err, pgx.ErrNoRows):
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to change email")
+		return
+	}
+
+	token, err := h.tokenStore.Create(r.Context(), user.ID, models.PurposeChangeEmail, email, verificationTokenTTL)
+	if err != nil {
+		slog.Error("Failed to create email change token", "error", err)
+		response.InternalServerError(w, "Failed to change email")
+		return
+	}
+
+	if err := h.notifier.SendEmailChange(r.Context(), email, token, verificationTokenTTL); err != nil {
+		slog.Error("Failed to send email change confirmation", "error", err)
+		response.InternalServerError(w, "Failed to send confirmation email")
+		return
+	}
+
+	h.record(r, user.ID, models.AuditEmailChangeRequested, map[string]string{"new_email": email})
+
+	response.Success(w, dto.Message{Message: "Confirmation email sent to the new address"})
+}
+
+// ConfirmEmailChange switches the account to the new address using the
+// token from the confirmation email
+// POST /api/v1/auth/confirm-email-change
+func (h *AccountHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmEmailChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	token, err := h.tokenStore.Consume(r.Context(), models.PurposeChangeEmail, req.Token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidToken) {
+			response.BadRequest(w, "Invalid or expired confirmation link")
+			return
+		}
+
+		slog.Error("Failed to consume email change token", "error", err)
+		response.InternalServerError(w, "Failed to change email")
+		return
+	}
+
+	user, err := h.userStore.GetByID(r.Context(), token.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "User not found")
+			return
+		}
+
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to change email")
+		return
+	}
+
+	if err := h.userStore.UpdateEmail(r.Context(), user.ID, token.Email); err != nil {
+		// Someone registered the address after the change was requested
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			response.BadRequest(w, "Email already exists")
+			return
+		}
+
+		slog.Error("Failed to update email", "error", err)
+		response.InternalServerError(w, "Failed to change email")
+		return
+	}
+
+	h.record(r, user.ID, models.AuditEmailChanged, map[string]string{
+		"old_email": user.Email,
+		"new_email": token.Email,
+	})
+
+	response.Success(w, dto.Message{Message: "Email changed successfully"})
+}
+
+// currentUser loads the authenticated user, writing an error response if
+// that fails
+func (h *AccountHandler) currentUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return nil, false
+	}
+
+	user, err := h.userStore.GetByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "User not found")
+			return nil, false
+		}
+
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to get user")
+		return nil, false
+	}
+
+	return user, true
+}
+
+// record writes an audit entry, logging rather than failing the request
+// if it can't be stored
+func (h *AccountHandler) record(r *http.Request, userID uuid.UUID, action models.AuditAction, metadata map[string]string) {
+	entry := &models.AuditEntry{
+		UserID:    userID,
+		Action:    action,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  metadata,
+	}
+
+	// Record even if the client has gone away mid-request
+	if err := h.audit.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+		slog.Error("Failed to record audit entry", "action", action, "user_id", userID, "error", err)
+	}
+}
+
+// clientIP returns the request's remote address without the port. The
+// RealIP middleware has already applied X-Forwarded-For.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// accountFixture is an account handler with its fakes and a seeded user
+type accountFixture struct {
+	handler  *AccountHandler
+	users    *memstore.UserStore
+	notifier *fakeNotifier
+	sessions *fakeSessions
+	audit    *memstore.AuditStore
+	user     *models.User
+}
+
+func newAccountFixture(t *testing.T) *accountFixture {
+	t.Helper()
+
+	f := &accountFixture{
+		users:    memstore.NewUserStore(),
+		notifier: newFakeNotifier(),
+		sessions: &fakeSessions{},
+		audit:    memstore.NewAuditStore(),
+	}
+	f.handler = NewAccountHandler(
+		f.users,
+		memstore.NewEmailTokenStore(),
+		auth.NewJWTManager("test-secret-key-at-least-32-characters"),
+		f.notifier,
+		f.sessions,
+		f.audit,
+	)
+	f.user = seedUser(t, f.users, "user@example.com")
+
+	return f
+}
+
+// actions returns the audit actions recorded so far
+func (f *accountFixture) actions() []models.AuditAction {
+	var actions []models.AuditAction
+	for _, e := range f.audit.Entries() {
+		actions = append(actions, e.Action)
+	}
+	return actions
+}
+
+func TestAccountHandler_UpdateProfile(t *testing.T) {
+	version := func(v int) *int { return &v }
+
+	tests := []struct {
+		name        string
+		ifMatch     string
+		body        interface{}
+		wantStatus  int
+		wantName    *string
+		wantVersion int
+	}{
+		{
+			name:        "If-Match",
+			ifMatch:     `"1"`,
+			body:        UpdateProfileRequest{DisplayName: "  Jane  "},
+			wantStatus:  http.StatusOK,
+			wantName:    ptr("Jane"),
+			wantVersion: 2,
+		},
+		{
+			name:        "version in body",
+			body:        UpdateProfileRequest{DisplayName: "Jane", Version: version(1)},
+			wantStatus:  http.StatusOK,
+			wantName:    ptr("Jane"),
+			wantVersion: 2,
+		},
+		{
+			name:        "empty name clears it",
+			ifMatch:     `W/"1"`,
+			body:        UpdateProfileRequest{},
+			wantStatus:  http.StatusOK,
+			wantVersion: 2,
+		},
+		{
+			name:       "stale version",
+			ifMatch:    `"7"`,
+			body:       UpdateProfileRequest{DisplayName: "Jane"},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "no version",
+			body:       UpdateProfileRequest{DisplayName: "Jane"},
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:       "malformed If-Match",
+			ifMatch:    "1",
+			body:       UpdateProfileRequest{DisplayName: "Jane"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "name too long",
+			ifMatch:    `"1"`,
+			body:       UpdateProfileRequest{DisplayName: strings.Repeat("a", models.MaxDisplayNameLength+1)},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAccountFixture(t)
+
+			req := newJSONRequest(t, http.MethodPatch, "/api/v1/me", tt.body)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			rec := httptest.NewRecorder()
+			f.handler.UpdateProfile(rec, withUser(req, f.user.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("UpdateProfile() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got dto.User
+			decodeBody(t, rec, &got)
+			if !reflect.DeepEqual(got.DisplayName, tt.wantName) {
+				t.Errorf("UpdateProfile() display_name = %v, want %v", deref(got.DisplayName), deref(tt.wantName))
+			}
+			if got.Version != tt.wantVersion {
+				t.Errorf("UpdateProfile() version = %d, want %d", got.Version, tt.wantVersion)
+			}
+			if etag := rec.Header().Get("ETag"); etag != fmt.Sprintf("%q", fmt.Sprint(tt.wantVersion)) {
+				t.Errorf("UpdateProfile() ETag = %s, want \"%d\"", etag, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_UpdateProfile_LostUpdate(t *testing.T) {
+	f := newAccountFixture(t)
+
+	// Two clients read version 1; only the first write wins
+	for i, want := range []int{http.StatusOK, http.StatusConflict} {
+		req := newJSONRequest(t, http.MethodPatch, "/api/v1/me", UpdateProfileRequest{DisplayName: fmt.Sprintf("client %d", i)})
+		req.Header.Set("If-Match", `"1"`)
+
+		rec := httptest.NewRecorder()
+		f.handler.UpdateProfile(rec, withUser(req, f.user.ID))
+		if rec.Code != want {
+			t.Errorf("UpdateProfile() from client %d status = %d, want %d", i, rec.Code, want)
+		}
+	}
+
+	user, _ := f.users.GetByID(context.Background(), f.user.ID)
+	if user.DisplayName == nil || *user.DisplayName != "client 0" {
+		t.Errorf("display name = %v, want the first client's", deref(user.DisplayName))
+	}
+}
+
+func TestAccountHandler_ChangePassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name:       "valid change",
+			body:       ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: "new-password-456"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong current password",
+			body:       ChangePasswordRequest{CurrentPassword: "wrong-password", NewPassword: "new-password-456"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "weak new password",
+			body:       ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: "short"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "unchanged password",
+			body:       ChangePasswordRequest{CurrentPassword: testPassword, NewPassword: testPassword},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "malformed body",
+			body:       "{",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAccountFixture(t)
+
+			rec := httptest.NewRecorder()
+			f.handler.ChangePassword(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/password", tt.body), f.user.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ChangePassword() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			user, _ := f.users.GetByID(context.Background(), f.user.ID)
+
+			if tt.wantStatus != http.StatusOK {
+				if user.ComparePassword(testPassword) != nil {
+					t.Error("ChangePassword() changed the password on a rejected request")
+				}
+				if len(f.sessions.revoked) != 0 || len(f.audit.Entries()) != 0 {
+					t.Error("ChangePassword() revoked sessions or audited a rejected request")
+				}
+				return
+			}
+
+			var resp AuthResponse
+			decodeBody(t, rec, &resp)
+			if resp.Token == nil || resp.Token.AccessToken == "" {
+				t.Error("ChangePassword() returned no replacement token")
+			}
+
+			if user.ComparePassword("new-password-456") != nil {
+				t.Error("ChangePassword() did not store the new password")
+			}
+			if len(f.sessions.revoked) != 1 || f.sessions.revoked[0] != f.user.ID {
+				t.Errorf("ChangePassword() revoked %v, want the user's sessions", f.sessions.revoked)
+			}
+
+			entries := f.audit.Entries()
+			if len(entries) != 1 || entries[0].Action != models.AuditPasswordChanged || entries[0].UserID != f.user.ID {
+				t.Errorf("ChangePassword() audit entries = %+v, want one %s", entries, models.AuditPasswordChanged)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_ChangePassword_RevokeFailure(t *testing.T) {
+	f := newAccountFixture(t)
+	f.sessions.err = errors.New("redis down")
+
+	rec := httptest.NewRecorder()
+	f.handler.ChangePassword(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/password", ChangePasswordRequest{
+		CurrentPassword: testPassword,
+		NewPassword:     "new-password-456",
+	}), f.user.ID))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("ChangePassword() status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestAccountHandler_ChangeEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name:       "valid change",
+			body:       ChangeEmailRequest{Email: " New@Example.com", Password: testPassword},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong password",
+			body:       ChangeEmailRequest{Email: "new@example.com", Password: "wrong-password"},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "invalid email",
+			body:       ChangeEmailRequest{Email: "not-an-email", Password: testPassword},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "same email",
+			body:       ChangeEmailRequest{Email: "user@example.com", Password: testPassword},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "email taken",
+			body:       ChangeEmailRequest{Email: "taken@example.com", Password: testPassword},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAccountFixture(t)
+			seedUser(t, f.users, "taken@example.com")
+
+			rec := httptest.NewRecorder()
+			f.handler.ChangeEmail(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/email", tt.body), f.user.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ChangeEmail() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				if len(f.notifier.emailChanges) != 0 {
+					t.Errorf("ChangeEmail() sent %v for a rejected request", f.notifier.emailChanges)
+				}
+				return
+			}
+
+			if f.notifier.emailChanges["new@example.com"] == "" {
+				t.Error("ChangeEmail() did not email the new address")
+			}
+
+			// The address only changes once confirmed
+			user, _ := f.users.GetByID(context.Background(), f.user.ID)
+			if user.Email != "user@example.com" {
+				t.Errorf("ChangeEmail() email = %q, want unchanged until confirmed", user.Email)
+			}
+
+			entries := f.audit.Entries()
+			if len(entries) != 1 || entries[0].Action != models.AuditEmailChangeRequested || entries[0].Metadata["new_email"] != "new@example.com" {
+				t.Errorf("ChangeEmail() audit entries = %+v, want one %s", entries, models.AuditEmailChangeRequested)
+			}
+		})
+	}
+}
+
+func TestAccountHandler_ConfirmEmailChange(t *testing.T) {
+	f := newAccountFixture(t)
+
+	rec := httptest.NewRecorder()
+	f.handler.ChangeEmail(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/email", ChangeEmailRequest{
+		Email:    "new@example.com",
+		Password: testPassword,
+	}), f.user.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ChangeEmail() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	token := f.notifier.emailChanges["new@example.com"]
+
+	rec = httptest.NewRecorder()
+	f.handler.ConfirmEmailChange(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/confirm-email-change", ConfirmEmailChangeRequest{Token: token}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ConfirmEmailChange() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	user, _ := f.users.GetByID(context.Background(), f.user.ID)
+	if user.Email != "new@example.com" || user.EmailVerifiedAt == nil {
+		t.Errorf("ConfirmEmailChange() user = %+v, want verified new@example.com", user)
+	}
+
+	want := []models.AuditAction{models.AuditEmailChangeRequested, models.AuditEmailChanged}
+	if got := f.actions(); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("audit actions = %v, want %v", got, want)
+	}
+	if entry := f.audit.Entries()[1]; entry.Metadata["old_email"] != "user@example.com" {
+		t.Errorf("email_changed metadata = %v, want the old address", entry.Metadata)
+	}
+
+	// Tokens are single use
+	rec = httptest.NewRecorder()
+	f.handler.ConfirmEmailChange(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/confirm-email-change", ConfirmEmailChangeRequest{Token: token}))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ConfirmEmailChange() reused token status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAccountHandler_ConfirmEmailChange_AddressTaken(t *testing.T) {
+	f := newAccountFixture(t)
+
+	rec := httptest.NewRecorder()
+	f.handler.ChangeEmail(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/email", ChangeEmailRequest{
+		Email:    "new@example.com",
+		Password: testPassword,
+	}), f.user.ID))
+	token := f.notifier.emailChanges["new@example.com"]
+
+	// Someone else registers the address before the link is followed
+	seedUser(t, f.users, "new@example.com")
+
+	rec = httptest.NewRecorder()
+	f.handler.ConfirmEmailChange(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/confirm-email-change", ConfirmEmailChangeRequest{Token: token}))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("ConfirmEmailChange() status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAccountHandler_RecordsClientIP(t *testing.T) {
+	f := newAccountFixture(t)
+
+	req := withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/password", ChangePasswordRequest{
+		CurrentPassword: testPassword,
+		NewPassword:     "new-password-456",
+	}), f.user.ID)
+	req.RemoteAddr = "203.0.113.7:54321"
+	req.Header.Set("User-Agent", "test-agent")
+
+	f.handler.ChangePassword(httptest.NewRecorder(), req)
+
+	entries := f.audit.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("audit entries = %d, want 1", len(entries))
+	}
+	if entries[0].IPAddress != "203.0.113.7" || entries[0].UserAgent != "test-agent" {
+		t.Errorf("audit entry = %+v, want IP 203.0.113.7 and the user agent", entries[0])
+	}
+}
+
+func TestAccountHandler_UnknownUser(t *testing.T) {
+	f := newAccountFixture(t)
+
+	rec := httptest.NewRecorder()
+	f.handler.ChangePassword(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/password", ChangePasswordRequest{}), uuid.New()))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("ChangePassword() status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestAccountHandler_UpdateProfile_TimeZone(t *testing.T) {
+	f := newAccountFixture(t)
+
+	steps := []struct {
+		name       string
+		body       UpdateProfileRequest
+		wantStatus int
+		want       *string
+	}{
+		{"sets it", UpdateProfileRequest{TimeZone: ptr("America/New_York")}, http.StatusOK, ptr("America/New_York")},
+		{"keeps it when left out", UpdateProfileRequest{DisplayName: "Jane"}, http.StatusOK, ptr("America/New_York")},
+		{"unknown zone", UpdateProfileRequest{TimeZone: ptr("Mars/Olympus_Mons")}, http.StatusUnprocessableEntity, ptr("America/New_York")},
+		{"clears it", UpdateProfileRequest{TimeZone: ptr("")}, http.StatusOK, nil},
+	}
+
+	for _, step := range steps {
+		user, _ := f.users.GetByID(context.Background(), f.user.ID)
+		req := newJSONRequest(t, http.MethodPatch, "/api/v1/me", step.body)
+		req.Header.Set("If-Match", fmt.Sprintf("%q", fmt.Sprint(user.Version)))
+
+		rec := httptest.NewRecorder()
+		f.handler.UpdateProfile(rec, withUser(req, f.user.ID))
+		if rec.Code != step.wantStatus {
+			t.Fatalf("%s: status = %d, want %d (body: %s)", step.name, rec.Code, step.wantStatus, rec.Body.String())
+		}
+
+		user, _ = f.users.GetByID(context.Background(), f.user.ID)
+		if !reflect.DeepEqual(user.TimeZone, step.want) {
+			t.Errorf("%s: time_zone = %v, want %v", step.name, deref(user.TimeZone), deref(step.want))
+		}
+	}
+}
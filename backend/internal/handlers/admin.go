@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/logging"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// LogLevelHandler reads and changes the log level at runtime
+type LogLevelHandler struct {
+	level *slog.LevelVar
+}
+
+// NewLogLevelHandler creates a new log level handler
+func NewLogLevelHandler(level *slog.LevelVar) *LogLevelHandler {
+	return &LogLevelHandler{level: level}
+}
+
+// LogLevelRequest represents the log level change request
+type LogLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// LogLevelResponse represents the current log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// Get returns the current log level
+func (h *LogLevelHandler) Get(w http.ResponseWriter, r *http.Request) {
+	response.Success(w, LogLevelResponse{Level: strings.ToLower(h.level.Level().String())})
+}
+
+// Set changes the log level
+func (h *LogLevelHandler) Set(w http.ResponseWriter, r *http.Request) {
+	var req LogLevelRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	level, err := logging.ParseLevel(req.Level)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	previous := h.level.Level()
+	h.level.Set(level)
+
+	// Logged at warn: it explains a sudden drop or flood in log volume,
+	// and is kept even by a level raised as far as warn
+	slog.Warn("Log level changed", "from", previous.String(), "to", level.String())
+
+	response.Success(w, LogLevelResponse{Level: strings.ToLower(level.String())})
+}
@@ -0,0 +1,429 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// analyticsFreshness keeps aggregates fresh for about five minutes, then
+// serves them for up to half an hour more while they are recomputed
+var analyticsFreshness = cache.Freshness{TTL: 5 * time.Minute, Stale: 30 * time.Minute, Jitter: 0.1}
+
+const (
+	// maxAnalyticsBuckets bounds the size of a single time series
+	maxAnalyticsBuckets = 366
+
+	// topicRepresentatives is the number of example submissions per cluster
+	topicRepresentatives = 3
+)
+
+// AnalyticsHandler handles analytics requests
+type AnalyticsHandler struct {
+	store      *models.AnalyticsStore
+	topicStore *models.TopicStore
+	cache      *cache.Cache
+	// users hold each user's default time zone; nil defaults to UTC
+	users UserStorer
+}
+
+// NewAnalyticsHandler creates a new analytics handler
+func NewAnalyticsHandler(store *models.AnalyticsStore, topicStore *models.TopicStore, cache *cache.Cache) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		store:      store,
+		topicStore: topicStore,
+		cache:      cache,
+	}
+}
+
+// WithUsers defaults the time zone of each user's analytics to the one
+// in their profile and returns the handler
+func (h *AnalyticsHandler) WithUsers(users UserStorer) *AnalyticsHandler {
+	h.users = users
+	return h
+}
+
+// SentimentTrendResponse represents the sentiment time series response
+type SentimentTrendResponse struct {
+	From     timestamp.Time    `json:"from"`
+	To       timestamp.Time    `json:"to"`
+	Interval models.TimeBucket `json:"interval"`
+	// TimeZone is the zone whose midnights the buckets start at
+	TimeZone string                  `json:"time_zone"`
+	Points   []models.SentimentPoint `json:"points"`
+}
+
+// ForVersion implements apiversion.Serializer. From v2 the points are a
+// list, with the range and interval as its filters.
+func (t SentimentTrendResponse) ForVersion(v apiversion.Version) interface{} {
+	if v < apiversion.V2 {
+		return t
+	}
+	return response.Complete(t.Points).
+		WithFilter("from", timestamp.Format(t.From.Time)).
+		WithFilter("to", timestamp.Format(t.To.Time)).
+		WithFilter("interval", string(t.Interval)).
+		WithFilter("tz", t.TimeZone)
+}
+
+// TopicsResponse is the v1 shape of the topic cluster list
+type TopicsResponse struct {
+	Clusters    []models.TopicCluster `json:"clusters"`
+	GeneratedAt *timestamp.Time       `json:"generated_at,omitempty"`
+}
+
+// topicList renders topic clusters, in the v1 shape for v1
+type topicList []models.TopicCluster
+
+// ForVersion implements apiversion.Serializer
+func (l topicList) ForVersion(v apiversion.Version) interface{} {
+	if v >= apiversion.V2 {
+		return response.Complete([]models.TopicCluster(l))
+	}
+
+	resp := TopicsResponse{Clusters: l}
+	if len(l) > 0 {
+		resp.GeneratedAt = &l[0].CreatedAt
+	}
+	return resp
+}
+
+// SentimentTrend returns the sentiment trend of the current user's
+// analyses, bucketed by days, weeks or months of tz, which defaults to
+// the user's time zone
+// GET /api/v1/analytics/sentiment?from=&to=&interval=day&tz=
+func (h *AnalyticsHandler) SentimentTrend(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+
+	loc, ok := h.timeZone(w, r, userID)
+	if !ok {
+		return
+	}
+
+	interval := models.BucketDay
+	if v := query.Get("interval"); v != "" {
+		interval, err = models.ParseTimeBucket(v)
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	from, to, ok := parseDateRangeIn(w, r, loc)
+	if !ok {
+		return
+	}
+
+	if to.Sub(from) > maxAnalyticsBuckets*interval.Duration() {
+		response.BadRequest(w, fmt.Sprintf("Date range too large for interval '%s'", interval))
+		return
+	}
+
+	cacheKey := fmt.Sprintf("analytics:sentiment:%s:%d:%d:%s:%s", userID, from.Unix(), to.Unix(), interval, loc)
+	resp, err := cache.FetchJSON(r.Context(), h.cache, cacheKey, analyticsFreshness, func(ctx context.Context) (SentimentTrendResponse, error) {
+		points, err := h.store.SentimentTrend(ctx, userID, from, to, interval, loc)
+		return SentimentTrendResponse{From: timestamp.New(from), To: timestamp.New(to), Interval: interval, TimeZone: loc.String(), Points: points}, err
+	})
+	if err != nil {
+		slog.Error("Failed to compute sentiment trend", "error", err)
+		response.InternalServerError(w, "Failed to compute sentiment trend")
+		return
+	}
+
+	response.Success(w, apiversion.Render(r, resp))
+}
+
+// WithSentiment reports organizations' sentiment trends from trends and
+// returns the handler
+func (h *OrgHandler) WithSentiment(trends SentimentTrendReporter) *OrgHandler {
+	h.sentiment = trends
+	return h
+}
+
+// SentimentTrend returns the sentiment trend of the analyses of all the
+// organization's current members, bucketed like the user's own trend in
+// tz, which defaults to the caller's time zone. Owners and admins only.
+// GET /api/v1/orgs/{id}/analytics/sentiment?from=&to=&interval=day&tz=
+func (h *OrgHandler) SentimentTrend(w http.ResponseWriter, r *http.Request) {
+	orgID, userID, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can see analytics")
+		return
+	}
+
+	loc, ok := requestTimeZone(w, r, h.users, userID)
+	if !ok {
+		return
+	}
+
+	interval := models.BucketDay
+	if v := r.URL.Query().Get("interval"); v != "" {
+		var err error
+		interval, err = models.ParseTimeBucket(v)
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	from, to, ok := parseDateRangeIn(w, r, loc)
+	if !ok {
+		return
+	}
+
+	if to.Sub(from) > maxAnalyticsBuckets*interval.Duration() {
+		response.BadRequest(w, fmt.Sprintf("Date range too large for interval '%s'", interval))
+		return
+	}
+
+	points, err := h.sentiment.OrgSentimentTrend(r.Context(), orgID, from, to, interval, loc)
+	if err != nil {
+		slog.Error("Failed to compute organization sentiment trend", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to compute sentiment trend")
+		return
+	}
+
+	response.Success(w, apiversion.Render(r, SentimentTrendResponse{
+		From:     timestamp.New(from),
+		To:       timestamp.New(to),
+		Interval: interval,
+		TimeZone: loc.String(),
+		Points:   points,
+	}))
+}
+
+// LabelsResponse is the distribution of taxonomy labels over a date range
+type LabelsResponse struct {
+	From   timestamp.Time      `json:"from"`
+	To     timestamp.Time      `json:"to"`
+	Labels []models.LabelCount `json:"labels"`
+}
+
+// ForVersion implements apiversion.Serializer. From v2 the counts are a
+// list, with the range as its filters.
+func (l LabelsResponse) ForVersion(v apiversion.Version) interface{} {
+	if v < apiversion.V2 {
+		return l
+	}
+	return response.Complete(l.Labels).
+		WithFilter("from", timestamp.Format(l.From.Time)).
+		WithFilter("to", timestamp.Format(l.To.Time))
+}
+
+// Labels returns how often each taxonomy label was given to the current
+// user's submissions. Plain from and to dates are midnights in tz, which
+// defaults to the user's time zone.
+// GET /api/v1/analytics/labels?from=&to=&tz=
+func (h *AnalyticsHandler) Labels(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	loc, ok := h.timeZone(w, r, userID)
+	if !ok {
+		return
+	}
+
+	from, to, ok := parseDateRangeIn(w, r, loc)
+	if !ok {
+		return
+	}
+
+	cacheKey := fmt.Sprintf("analytics:labels:%s:%d:%d", userID, from.Unix(), to.Unix())
+	resp, err := cache.FetchJSON(r.Context(), h.cache, cacheKey, analyticsFreshness, func(ctx context.Context) (LabelsResponse, error) {
+		labels, err := h.store.LabelDistribution(ctx, userID, from, to)
+		return LabelsResponse{From: timestamp.New(from), To: timestamp.New(to), Labels: labels}, err
+	})
+	if err != nil {
+		slog.Error("Failed to compute label distribution", "error", err)
+		response.InternalServerError(w, "Failed to compute label distribution")
+		return
+	}
+
+	response.Success(w, apiversion.Render(r, resp))
+}
+
+// Stats returns totals over the current user's submissions and analyses
+// GET /api/v1/me/stats
+func (h *AnalyticsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	stats, err := cache.FetchJSON(r.Context(), h.cache, statsKey(userID), analyticsFreshness, func(ctx context.Context) (*models.UserStats, error) {
+		return h.store.UserStats(ctx, userID)
+	})
+	if err != nil {
+		slog.Error("Failed to compute user stats", "error", err)
+		response.InternalServerError(w, "Failed to compute user stats")
+		return
+	}
+
+	response.Success(w, stats)
+}
+
+// statsKey is the cache key holding a user's stats
+func statsKey(userID uuid.UUID) string {
+	return "analytics:stats:" + userID.String()
+}
+
+// StatsCache drops users' cached stats when their submissions are
+// created, deleted or change status, so /me/stats doesn't trail them by
+// the freshness window
+type StatsCache struct {
+	cache *cache.Cache
+}
+
+// NewStatsCache creates a stats cache over the one Stats reads from
+func NewStatsCache(c *cache.Cache) *StatsCache {
+	return &StatsCache{cache: c}
+}
+
+// InvalidateStats drops the user's cached stats. A failure is only
+// logged: the stats go stale within analyticsFreshness anyway.
+func (s *StatsCache) InvalidateStats(ctx context.Context, userID uuid.UUID) {
+	if err := s.cache.Delete(ctx, statsKey(userID)); err != nil {
+		slog.Warn("Failed to invalidate user stats", "user_id", userID, "error", err)
+	}
+}
+
+// StatsSubscriber drops the owner's cached stats whenever one of their
+// submissions changes status, which covers analyses completing
+func StatsSubscriber(stats StatsInvalidator) events.Subscriber {
+	return func(ctx context.Context, change models.StatusChange) error {
+		stats.InvalidateStats(ctx, change.UserID)
+		return nil
+	}
+}
+
+// Topics returns the current user's topic clusters with representative submissions
+// GET /api/v1/analytics/topics
+func (h *AnalyticsHandler) Topics(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	clusters, err := h.topicStore.ListClusters(r.Context(), userID, topicRepresentatives)
+	if err != nil {
+		slog.Error("Failed to list topic clusters", "error", err)
+		response.InternalServerError(w, "Failed to list topic clusters")
+		return
+	}
+
+	response.Success(w, apiversion.Render(r, topicList(clusters)))
+}
+
+// timeZone returns the time zone the tz query parameter names, or else
+// the user's, or UTC. It reports whether it could, having written the
+// response if not.
+func (h *AnalyticsHandler) timeZone(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (*time.Location, bool) {
+	return requestTimeZone(w, r, h.users, userID)
+}
+
+// requestTimeZone is timeZone for handlers that look up users in users,
+// which may be nil
+func requestTimeZone(w http.ResponseWriter, r *http.Request, users UserStorer, userID uuid.UUID) (*time.Location, bool) {
+	if name := r.URL.Query().Get("tz"); name != "" {
+		loc, err := models.LoadTimeZone(name)
+		if err != nil {
+			response.BadRequest(w, "Invalid 'tz' parameter: use an IANA time zone such as Europe/Paris")
+			return nil, false
+		}
+		return loc, true
+	}
+
+	if users == nil {
+		return time.UTC, true
+	}
+	user, err := users.GetByID(r.Context(), userID)
+	if err != nil {
+		// Bucketing by UTC beats failing the request
+		slog.Warn("Failed to get user's time zone", "user_id", userID, "error", err)
+		return time.UTC, true
+	}
+	if user.TimeZone == nil {
+		return time.UTC, true
+	}
+	loc, err := models.LoadTimeZone(*user.TimeZone)
+	if err != nil {
+		slog.Warn("User has an unknown time zone", "user_id", userID, "time_zone", *user.TimeZone)
+		return time.UTC, true
+	}
+	return loc, true
+}
+
+// parseDateRangeIn reads the from and to query parameters, defaulting to
+// the last 30 days, and responds with an error if they are invalid. Plain
+// dates are midnights in loc.
+func parseDateRangeIn(w http.ResponseWriter, r *http.Request, loc *time.Location) (from, to time.Time, ok bool) {
+	query := r.URL.Query()
+
+	// Default to the 30 days up to the end of today in loc. Whole days keep
+	// the range, and so the cache key, the same all day.
+	from, to = defaultDateRange(time.Now(), loc)
+
+	var err error
+	if v := query.Get("to"); v != "" {
+		if to, err = parseDateParam(v, loc); err != nil {
+			response.BadRequest(w, "Invalid 'to' parameter: use RFC3339 or YYYY-MM-DD")
+			return from, to, false
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if from, err = parseDateParam(v, loc); err != nil {
+			response.BadRequest(w, "Invalid 'from' parameter: use RFC3339 or YYYY-MM-DD")
+			return from, to, false
+		}
+	}
+
+	if !from.Before(to) {
+		response.BadRequest(w, "'from' must be before 'to'")
+		return from, to, false
+	}
+	return from, to, true
+}
+
+// defaultDateRange returns the 30 days ending at the midnight after now
+// in loc
+func defaultDateRange(now time.Time, loc *time.Location) (from, to time.Time) {
+	y, m, d := now.In(loc).Date()
+	end := time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	return end.AddDate(0, 0, -30).UTC(), end.UTC()
+}
+
+// parseDateParam parses a query parameter in any format timestamp.Parse
+// accepts. A plain date is midnight in loc.
+func parseDateParam(v string, loc *time.Location) (time.Time, error) {
+	t, err := timestamp.Parse(v, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
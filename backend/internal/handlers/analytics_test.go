@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestAnalyticsHandler_TimeZone(t *testing.T) {
+	ctx := context.Background()
+	users := memstore.NewUserStore()
+	user := seedUser(t, users, "user@example.com")
+	zoned := seedUser(t, users, "zoned@example.com")
+	if _, err := users.UpdateProfile(ctx, zoned.ID, zoned.Version, nil, ptr("Asia/Tokyo")); err != nil {
+		t.Fatal(err)
+	}
+	handler := NewAnalyticsHandler(nil, nil, nil).WithUsers(users)
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		tz         string
+		want       string
+		wantStatus int
+	}{
+		{"defaults to UTC", user.ID, "", "UTC", http.StatusOK},
+		{"defaults to the profile's", zoned.ID, "", "Asia/Tokyo", http.StatusOK},
+		{"parameter wins", zoned.ID, "Europe/Paris", "Europe/Paris", http.StatusOK},
+		{"unknown zone", user.ID, "Nowhere/Special", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/sentiment?tz="+tt.tz, nil)
+			rec := httptest.NewRecorder()
+
+			loc, ok := handler.timeZone(rec, req, tt.userID)
+			if tt.wantStatus != http.StatusOK {
+				if ok || rec.Code != tt.wantStatus {
+					t.Errorf("timeZone() ok = %v, status = %d, want %d", ok, rec.Code, tt.wantStatus)
+				}
+				return
+			}
+			if !ok || loc.String() != tt.want {
+				t.Errorf("timeZone() = %v, %v, want %s", loc, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateRangeIn(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?from=2026-03-01&to=2026-03-02T00:00:00Z", nil)
+	from, to, ok := parseDateRangeIn(httptest.NewRecorder(), req, tokyo)
+	if !ok {
+		t.Fatal("parseDateRangeIn() rejected a valid range")
+	}
+	// A plain date is midnight in Tokyo, 15:00 UTC the day before
+	if want := time.Date(2026, 2, 28, 15, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+	if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("to = %v, want %v", to, want)
+	}
+}
+
+func TestDefaultDateRange(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20:00 UTC is already the next morning in Tokyo
+	morning := time.Date(2026, 3, 10, 20, 0, 0, 0, time.UTC)
+	from, to := defaultDateRange(morning, tokyo)
+	if want := time.Date(2026, 3, 11, 15, 0, 0, 0, time.UTC); !to.Equal(want) {
+		t.Errorf("to = %v, want the next midnight in Tokyo %v", to, want)
+	}
+	if want := time.Date(2026, 2, 9, 15, 0, 0, 0, time.UTC); !from.Equal(want) {
+		t.Errorf("from = %v, want %v", from, want)
+	}
+
+	// The range, and so the cache key, holds all day
+	laterFrom, laterTo := defaultDateRange(morning.Add(18*time.Hour), tokyo)
+	if !laterFrom.Equal(from) || !laterTo.Equal(to) {
+		t.Errorf("defaultDateRange() later that day = %v-%v, want %v-%v", laterFrom, laterTo, from, to)
+	}
+}
+
+// fakeStats records whose stats were invalidated
+type fakeStats struct {
+	users []uuid.UUID
+}
+
+func (f *fakeStats) InvalidateStats(_ context.Context, userID uuid.UUID) {
+	f.users = append(f.users, userID)
+}
+
+func TestStatsSubscriber(t *testing.T) {
+	stats := &fakeStats{}
+	userID := uuid.New()
+
+	change := models.StatusChange{SubmissionID: uuid.New(), UserID: userID, From: models.StatusProcessing, To: models.StatusCompleted}
+	if err := StatsSubscriber(stats)(context.Background(), change); err != nil {
+		t.Fatalf("StatsSubscriber() error = %v", err)
+	}
+	if len(stats.users) != 1 || stats.users[0] != userID {
+		t.Errorf("StatsSubscriber() invalidated %v, want %s", stats.users, userID)
+	}
+}
+
+// fakeSentimentTrend records the organization trend it was asked for
+type fakeSentimentTrend struct {
+	orgID    uuid.UUID
+	from, to time.Time
+	loc      *time.Location
+}
+
+func (f *fakeSentimentTrend) OrgSentimentTrend(_ context.Context, orgID uuid.UUID, from, to time.Time, _ models.TimeBucket, loc *time.Location) ([]models.SentimentPoint, error) {
+	f.orgID, f.from, f.to, f.loc = orgID, from, to, loc
+	return []models.SentimentPoint{{Bucket: from.In(loc), Count: 2}}, nil
+}
+
+func TestOrgHandler_SentimentTrend(t *testing.T) {
+	f := newOrgFixture(t)
+	path := "/orgs/" + f.org.ID.String() + "/analytics/sentiment"
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		query      string
+		wantStatus int
+		wantFrom   time.Time
+	}{
+		{"admin", f.admin, "?from=2026-03-01&to=2026-03-08&tz=Asia/Tokyo", http.StatusOK, time.Date(2026, 2, 28, 15, 0, 0, 0, time.UTC)},
+		{"owner in UTC", f.owner, "?from=2026-03-01&to=2026-03-08", http.StatusOK, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"member", f.member, "?from=2026-03-01&to=2026-03-08", http.StatusForbidden, time.Time{}},
+		{"outsider", uuid.New(), "?from=2026-03-01&to=2026-03-08", http.StatusNotFound, time.Time{}},
+		{"bad interval", f.admin, "?interval=hour", http.StatusBadRequest, time.Time{}},
+		{"too long", f.admin, "?from=2024-01-01&to=2026-01-01", http.StatusBadRequest, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			*f.sentiment = fakeSentimentTrend{}
+
+			rec := f.router.do(t, tt.userID, http.MethodGet, path+tt.query, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SentimentTrend() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if f.sentiment.orgID != uuid.Nil {
+					t.Error("SentimentTrend() queried the trend of a refused request")
+				}
+				return
+			}
+
+			var resp SentimentTrendResponse
+			decodeBody(t, rec, &resp)
+			if f.sentiment.orgID != f.org.ID || !f.sentiment.from.Equal(tt.wantFrom) {
+				t.Errorf("SentimentTrend() queried org %s from %v, want %s from %v", f.sentiment.orgID, f.sentiment.from, f.org.ID, tt.wantFrom)
+			}
+			if resp.TimeZone != f.sentiment.loc.String() || len(resp.Points) != 1 || resp.Points[0].Count != 2 {
+				t.Errorf("SentimentTrend() = %+v, want the store's one point in %s", resp, f.sentiment.loc)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/sfumato00/content-analyzer/internal/config"
 	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/version"
 )
 
 // APIHandler handles general API requests
@@ -19,17 +20,43 @@ func NewAPIHandler(cfg *config.Config) *APIHandler {
 	}
 }
 
+// APIInfo describes the API and the build serving it
+type APIInfo struct {
+	Name         string       `json:"name"`
+	Version      string       `json:"version"`
+	Build        version.Info `json:"build"`
+	Environment  string       `json:"environment"`
+	Registration string       `json:"registration"`
+	Endpoints    APIEndpoints `json:"endpoints"`
+}
+
+// APIEndpoints are the paths to start from
+type APIEndpoints struct {
+	Health  string `json:"health"`
+	Ready   string `json:"ready"`
+	Live    string `json:"live"`
+	APIRoot string `json:"api_root"`
+}
+
 // Index returns API information
 func (h *APIHandler) Index(w http.ResponseWriter, r *http.Request) {
-	response.Success(w, map[string]interface{}{
-		"name":        "Content Analyzer API",
-		"version":     "1.0.0",
-		"environment": h.config.Environment,
-		"endpoints": map[string]string{
-			"health":   "/health",
-			"ready":    "/ready",
-			"live":     "/live",
-			"api_root": "/api/v1",
+	registration := config.RegistrationOpen
+	if h.config.InviteOnly() {
+		registration = config.RegistrationInvite
+	}
+
+	build := version.Get()
+	response.Success(w, APIInfo{
+		Name:         "Content Analyzer API",
+		Version:      build.Version,
+		Build:        build,
+		Environment:  h.config.Environment,
+		Registration: registration,
+		Endpoints: APIEndpoints{
+			Health:  "/health",
+			Ready:   "/ready",
+			Live:    "/live",
+			APIRoot: "/api/v1",
 		},
 	})
 }
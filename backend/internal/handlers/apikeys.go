@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// maxAPIKeyNameLength matches the api_keys.name column
+const maxAPIKeyNameLength = 100
+
+// APIKeyHandler lets users issue and revoke API keys for integrations
+// such as the browser extension
+type APIKeyHandler struct {
+	store  APIKeyStorer
+	tokens *auth.JWTManager
+}
+
+// NewAPIKeyHandler creates a new API key handler. tokens signs the scoped
+// tokens exchanged for keys.
+func NewAPIKeyHandler(store APIKeyStorer, tokens *auth.JWTManager) *APIKeyHandler {
+	return &APIKeyHandler{store: store, tokens: tokens}
+}
+
+// CreateAPIKeyRequest issues an API key. Without scopes, the key may do
+// anything the user can through API key and scoped routes.
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// CreateAPIKeyResponse includes the key, which can't be read back later
+type CreateAPIKeyResponse struct {
+	Key string `json:"key"`
+	*models.APIKey
+}
+
+// List returns the user's API keys without the keys themselves
+// GET /api/v1/me/api-keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	keys, err := h.store.List(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list API keys", "error", err)
+		response.InternalServerError(w, "Failed to list API keys")
+		return
+	}
+
+	response.Success(w, response.Complete(keys))
+}
+
+// Create issues a new API key
+// POST /api/v1/me/api-keys
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLength {
+		response.ValidationError(w, map[string]string{"name": fmt.Sprintf("Must be between 1 and %d characters", maxAPIKeyNameLength)})
+		return
+	}
+
+	scopes, problem := parseScopes(req.Scopes)
+	if problem != "" {
+		response.ValidationError(w, map[string]string{"scopes": problem})
+		return
+	}
+
+	plaintext, key, err := h.store.Create(r.Context(), userID, name, scopes)
+	if err != nil {
+		if errors.Is(err, models.ErrAPIKeyLimit) {
+			response.Conflict(w, "API key limit reached; revoke a key first")
+			return
+		}
+		slog.Error("Failed to create API key", "error", err)
+		response.InternalServerError(w, "Failed to create API key")
+		return
+	}
+
+	slog.Info("API key created", "user_id", userID, "api_key_id", key.ID)
+	response.Created(w, CreateAPIKeyResponse{Key: plaintext, APIKey: key})
+}
+
+// Revoke stops one of the user's API keys from authenticating
+// DELETE /api/v1/me/api-keys/{id}
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid API key ID")
+		return
+	}
+
+	if err := h.store.Revoke(r.Context(), userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "API key not found")
+			return
+		}
+		slog.Error("Failed to revoke API key", "api_key_id", id, "error", err)
+		response.InternalServerError(w, "Failed to revoke API key")
+		return
+	}
+
+	slog.Info("API key revoked", "user_id", userID, "api_key_id", id)
+	response.NoContent(w)
+}
+
+// Token exchanges the API key the request is authenticated with for a
+// bearer token limited to the key's scopes, for integrations calling
+// routes that take tokens
+// POST /api/v1/auth/token
+func (h *APIKeyHandler) Token(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	keyID, err := auth.GetAPIKeyIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	scopes, limited := auth.GetScopesFromContext(r.Context())
+	if !limited {
+		scopes = auth.AllScopes
+	}
+
+	tokens, err := h.tokens.GenerateScopedToken(userID, keyID.String(), scopes)
+	if err != nil {
+		slog.Error("Failed to issue scoped token", "api_key_id", keyID, "error", err)
+		response.InternalServerError(w, "Failed to issue token")
+		return
+	}
+
+	response.Success(w, ScopedTokenResponse{TokenPair: tokens, Scope: strings.Join(scopes, " ")})
+}
+
+// ScopedTokenResponse is a scoped token with the scopes it was granted
+type ScopedTokenResponse struct {
+	*auth.TokenPair
+	Scope string `json:"scope"`
+}
+
+// parseScopes validates the scopes requested for a key, dropping
+// duplicates, or describes what is wrong with them. nil stays nil, for a
+// key without limits.
+func parseScopes(requested []string) ([]string, string) {
+	if requested == nil {
+		return nil, ""
+	}
+	if len(requested) == 0 {
+		return nil, "Must list at least one scope, or be left out for full access"
+	}
+
+	scopes := []string{}
+	for _, scope := range requested {
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Sprintf("Unknown scope %q; must be one of: %s", scope, strings.Join(auth.AllScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, ""
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// testTokens signs the scoped tokens exchanged for keys
+var testTokens = auth.NewJWTManager("test-secret-key-at-least-32-characters")
+
+// newAPIKeyRouter mounts the handler so chi URL parameters resolve
+func newAPIKeyRouter(handler *APIKeyHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/me/api-keys", handler.List)
+	r.Post("/me/api-keys", handler.Create)
+	r.Delete("/me/api-keys/{id}", handler.Revoke)
+	return r
+}
+
+func TestAPIKeyHandler_Create(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		setup      func(store *memstore.APIKeyStore)
+		body       interface{}
+		wantStatus int
+	}{
+		{"issues a key", nil, CreateAPIKeyRequest{Name: " Browser extension "}, http.StatusCreated},
+		{"blank name", nil, CreateAPIKeyRequest{Name: "  "}, http.StatusUnprocessableEntity},
+		{"name too long", nil, CreateAPIKeyRequest{Name: strings.Repeat("a", maxAPIKeyNameLength+1)}, http.StatusUnprocessableEntity},
+		{"malformed body", nil, "not json", http.StatusBadRequest},
+		{"scoped", nil, CreateAPIKeyRequest{Name: "Browser extension", Scopes: []string{"analyze", "analyze"}}, http.StatusCreated},
+		{"no scopes", nil, CreateAPIKeyRequest{Name: "Browser extension", Scopes: []string{}}, http.StatusUnprocessableEntity},
+		{"unknown scope", nil, CreateAPIKeyRequest{Name: "Browser extension", Scopes: []string{"admin"}}, http.StatusUnprocessableEntity},
+		{
+			"limit reached",
+			func(store *memstore.APIKeyStore) {
+				for i := range models.MaxAPIKeysPerUser {
+					store.Create(context.Background(), userID, fmt.Sprintf("key %d", i), nil)
+				}
+			},
+			CreateAPIKeyRequest{Name: "one more"},
+			http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewAPIKeyStore()
+			if tt.setup != nil {
+				tt.setup(store)
+			}
+
+			rec := httptest.NewRecorder()
+			newAPIKeyRouter(NewAPIKeyHandler(store, testTokens)).ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/me/api-keys", tt.body), userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var resp CreateAPIKeyResponse
+			decodeBody(t, rec, &resp)
+			if resp.Name != "Browser extension" || !strings.HasPrefix(resp.Key, resp.Prefix) {
+				t.Errorf("response = %+v", resp)
+			}
+			if key, err := store.Authenticate(context.Background(), resp.Key); err != nil || key.UserID != userID {
+				t.Errorf("Authenticate() = %+v, %v", key, err)
+			}
+			if req := tt.body.(CreateAPIKeyRequest); req.Scopes != nil && !slices.Equal(resp.Scopes, []string{"analyze"}) {
+				t.Errorf("scopes = %v, want [analyze] without duplicates", resp.Scopes)
+			}
+		})
+	}
+}
+
+func TestAPIKeyHandler_Revoke(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	store := memstore.NewAPIKeyStore()
+	router := newAPIKeyRouter(NewAPIKeyHandler(store, testTokens))
+
+	plaintext, key, _ := store.Create(ctx, userID, "extension", nil)
+
+	// Another user's key looks the same as a missing one
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodDelete, "/me/api-keys/"+key.ID.String(), nil), uuid.New()))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodDelete, "/me/api-keys/"+key.ID.String(), nil), userID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if _, err := store.Authenticate(ctx, plaintext); err == nil {
+		t.Error("revoked key still authenticates")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/me/api-keys", nil), userID))
+	var list response.ListResponse[models.APIKey]
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 1 || list.Data[0].RevokedAt == nil {
+		t.Errorf("List() = %+v, want the revoked key", list.Data)
+	}
+}
+
+func TestAPIKeyHandler_Token(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	store := memstore.NewAPIKeyStore()
+	handler := NewAPIKeyHandler(store, testTokens)
+
+	r := chi.NewRouter()
+	r.With(auth.APIKeyMiddleware(store, nil)).Post("/auth/token", handler.Token)
+
+	scoped, scopedKey, _ := store.Create(ctx, userID, "Zapier", []string{auth.ScopeSubmissionsRead})
+	unlimited, unlimitedKey, _ := store.Create(ctx, userID, "extension", nil)
+
+	tests := []struct {
+		name      string
+		key       string
+		keyID     uuid.UUID
+		wantScope string
+	}{
+		{"scoped key", scoped, scopedKey.ID, auth.ScopeSubmissionsRead},
+		{"unlimited key", unlimited, unlimitedKey.ID, strings.Join(auth.AllScopes, " ")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/token", nil)
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+			var resp ScopedTokenResponse
+			decodeBody(t, rec, &resp)
+			if resp.Scope != tt.wantScope {
+				t.Errorf("scope = %q, want %q", resp.Scope, tt.wantScope)
+			}
+
+			claims, err := testTokens.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			// The key stands in for the client, and admin checks fail without an email
+			if claims.UserID != userID || claims.Scope != tt.wantScope || claims.ClientID != tt.keyID.String() || claims.Email != "" {
+				t.Errorf("claims = %+v", claims)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// ArtifactsHandler shows operators exactly what an analysis sent to the
+// model and got back, for prompt engineering and support investigations
+type ArtifactsHandler struct {
+	store ArtifactStorer
+}
+
+// NewArtifactsHandler creates a new artifacts handler
+func NewArtifactsHandler(store ArtifactStorer) *ArtifactsHandler {
+	return &ArtifactsHandler{store: store}
+}
+
+// Get returns an analysis' model calls with their prompts, raw responses,
+// token counts and latencies
+// GET /api/v1/admin/analyses/{id}/artifacts
+func (h *ArtifactsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid analysis ID")
+		return
+	}
+
+	artifacts, err := h.store.GetArtifacts(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Analysis not found")
+			return
+		}
+		slog.Error("Failed to get analysis artifacts", "analysis_id", id, "error", err)
+		response.InternalServerError(w, "Failed to get analysis artifacts")
+		return
+	}
+
+	// The prompts hold the user's content, so each look is logged
+	slog.Info("Analysis artifacts viewed", "analysis_id", id, "submission_id", artifacts.SubmissionID, "by", operator(r))
+	response.Success(w, artifacts)
+}
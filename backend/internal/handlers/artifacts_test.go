@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestArtifactsHandler_Get(t *testing.T) {
+	ctx := context.Background()
+	submissions := memstore.NewSubmissionStore()
+	submission, err := submissions.Create(ctx, uuid.New(), "Great phone.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("failed to seed submission: %v", err)
+	}
+	submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing)
+	analysis := &models.Analysis{
+		SubmissionID:     submission.ID,
+		Sentiment:        "positive",
+		RawResponse:      json.RawMessage(`{"sentiment": "positive"}`),
+		PromptTokens:     12,
+		OutputTokens:     4,
+		ProcessingTimeMs: 800,
+		Calls: []models.ModelCall{
+			{Stage: "analysis", Model: "gemini-2.0-flash", Prompt: "Great phone.", Response: `{"sentiment": "positive"}`, PromptTokens: 12, OutputTokens: 4, LatencyMs: 750},
+		},
+	}
+	if err := submissions.SaveAnalysis(ctx, analysis); err != nil {
+		t.Fatalf("failed to seed analysis: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/admin/analyses/{id}/artifacts", NewArtifactsHandler(submissions).Get)
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{name: "invalid id", id: "nope", wantStatus: http.StatusBadRequest},
+		{name: "unknown analysis", id: uuid.NewString(), wantStatus: http.StatusNotFound},
+		{name: "found", id: analysis.ID.String(), wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/analyses/"+tt.id+"/artifacts", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Get() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got models.AnalysisArtifacts
+			decodeBody(t, rec, &got)
+			if got.SubmissionID != submission.ID || got.PromptTokens != 12 || got.ProcessingTimeMs != 800 || string(got.RawResponse) != `{"sentiment":"positive"}` {
+				t.Errorf("artifacts = %+v", got)
+			}
+			if len(got.Calls) != 1 || got.Calls[0] != analysis.Calls[0] {
+				t.Errorf("calls = %+v, want %+v", got.Calls, analysis.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// AssignmentHandler assigns submissions to teammates for review and tracks
+// the review's workflow status
+type AssignmentHandler struct {
+	store    AssignmentStorer
+	users    UserStorer
+	teams    TeammateChecker
+	notifier AssignmentNotifier
+}
+
+// NewAssignmentHandler creates a new assignment handler
+func NewAssignmentHandler(store AssignmentStorer, users UserStorer, teams TeammateChecker, notifier AssignmentNotifier) *AssignmentHandler {
+	return &AssignmentHandler{store: store, users: users, teams: teams, notifier: notifier}
+}
+
+// AssignRequest assigns a submission to the teammate with Email
+type AssignRequest struct {
+	Email string          `json:"email"`
+	DueAt *timestamp.Time `json:"due_at"`
+}
+
+// WorkflowRequest moves a submission's review to another status
+type WorkflowRequest struct {
+	WorkflowStatus string `json:"workflow_status"`
+}
+
+// Assign makes a teammate, someone who shares an organization with the
+// owner, or the owner themselves, the submission's reviewer. On an
+// organization's subdomain only its members are teammates. Assigning
+// again replaces the reviewer and due date. Teammates are emailed. Owners
+// only.
+// PUT /api/v1/submissions/{id}/assignee
+func (h *AssignmentHandler) Assign(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := submissionParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req AssignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		response.ValidationError(w, map[string]string{"email": "email is required"})
+		return
+	}
+
+	// Accounts outside the owner's organizations are reported like missing
+	// ones, so assignments can't be used to probe for accounts
+	assignee, err := h.users.GetByEmail(r.Context(), email)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to assign submission")
+		return
+	}
+	teammate := err == nil && assignee.ID == userID
+	if err == nil && !teammate {
+		if teammate, err = h.isTeammate(r, userID, assignee.ID); err != nil {
+			slog.Error("Failed to check organizations", "error", err)
+			response.InternalServerError(w, "Failed to assign submission")
+			return
+		}
+	}
+	if !teammate {
+		response.NotFound(w, "No teammate with that email")
+		return
+	}
+
+	submission, err := h.store.Assign(r.Context(), userID, id, assignee.ID, req.DueAt.Std())
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to assign submission", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to assign submission")
+		return
+	}
+
+	// The assignment stands even if the email can't be queued
+	if assignee.ID != userID {
+		if err := h.notifier.SendAssignment(r.Context(), assignee.Email, operator(r), req.DueAt.Std()); err != nil {
+			slog.Error("Failed to send assignment email", "submission_id", id, "assignee_id", assignee.ID, "error", err)
+		}
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// isTeammate reports whether otherID shares an organization with userID,
+// or on an organization's subdomain, whether they belong to it
+func (h *AssignmentHandler) isTeammate(r *http.Request, userID, otherID uuid.UUID) (bool, error) {
+	orgID, ok := auth.GetHostOrgFromContext(r.Context())
+	if !ok {
+		return h.teams.ShareOrganization(r.Context(), userID, otherID)
+	}
+	if _, err := h.teams.Role(r.Context(), orgID, otherID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Unassign removes the submission's reviewer and due date. The owner can
+// take a submission back and the assignee can hand it back.
+// DELETE /api/v1/submissions/{id}/assignee
+func (h *AssignmentHandler) Unassign(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := submissionParams(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := h.store.Unassign(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to unassign submission", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to unassign submission")
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// SetWorkflowStatus moves the submission's review to another status. The
+// owner and the assignee can both do so, in any order.
+// PUT /api/v1/submissions/{id}/workflow
+func (h *AssignmentHandler) SetWorkflowStatus(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := submissionParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req WorkflowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	status, err := models.ParseWorkflowStatus(req.WorkflowStatus)
+	if err != nil {
+		response.ValidationError(w, map[string]string{"workflow_status": err.Error()})
+		return
+	}
+
+	submission, err := h.store.SetWorkflowStatus(r.Context(), userID, id, status)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to set workflow status", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to set workflow status")
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// Queue returns the submissions assigned to the caller, soonest due first
+// and those without a due date last, optionally in one workflow status
+// GET /api/v1/me/queue?workflow_status=
+func (h *AssignmentHandler) Queue(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	var status models.WorkflowStatus
+	if v := r.URL.Query().Get("workflow_status"); v != "" {
+		if status, err = models.ParseWorkflowStatus(v); err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	submissions, total, err := h.store.Queue(r.Context(), userID, status, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list assigned submissions", "error", err)
+		response.InternalServerError(w, "Failed to list assigned submissions")
+		return
+	}
+
+	list := response.NewList(dto.NewSubmissions(submissions), total, limit, offset).
+		WithFilter("workflow_status", string(status))
+	response.Success(w, apiversion.Render(r, submissionList(list)))
+}
+
+// submissionParams reads the caller and the submission ID in the URL
+func submissionParams(w http.ResponseWriter, r *http.Request) (userID, id uuid.UUID, ok bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	return userID, id, true
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// assignmentFixture is an owner with a submission, a teammate in the owner's
+// organization and an outsider
+type assignmentFixture struct {
+	router     testRouter
+	notifier   *fakeNotifier
+	orgs       *memstore.OrganizationStore
+	org        *models.Organization
+	submission *models.Submission
+	owner      uuid.UUID
+	teammate   uuid.UUID
+	outsider   uuid.UUID
+}
+
+func newAssignmentFixture(t *testing.T) *assignmentFixture {
+	t.Helper()
+
+	users := memstore.NewUserStore()
+	orgs := memstore.NewOrganizationStore(users)
+	submissions := memstore.NewSubmissionStore()
+	f := &assignmentFixture{
+		router:   newTestRouter(),
+		notifier: newFakeNotifier(),
+		orgs:     orgs,
+		owner:    seedUser(t, users, "owner@example.com").ID,
+		teammate: seedUser(t, users, "teammate@example.com").ID,
+		outsider: seedUser(t, users, "outsider@example.com").ID,
+	}
+	f.org = seedOrg(t, orgs, f.owner, map[uuid.UUID]models.OrgRole{f.teammate: models.RoleMember})
+
+	var err error
+	if f.submission, err = submissions.Create(context.Background(), f.owner, "Draft for review.", nil, nil, nil, models.StatusDraft); err != nil {
+		t.Fatalf("failed to seed submission: %v", err)
+	}
+
+	handler := NewAssignmentHandler(submissions, users, orgs, f.notifier)
+	f.router.Put("/submissions/{id}/assignee", handler.Assign)
+	f.router.Delete("/submissions/{id}/assignee", handler.Unassign)
+	f.router.Put("/submissions/{id}/workflow", handler.SetWorkflowStatus)
+	f.router.Get("/me/queue", handler.Queue)
+
+	return f
+}
+
+func TestAssignmentHandler_Assign(t *testing.T) {
+	f := newAssignmentFixture(t)
+	path := "/submissions/" + f.submission.ID.String() + "/assignee"
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		path       string
+		body       string
+		wantStatus int
+		wantEmail  bool
+	}{
+		{name: "invalid id", user: f.owner, path: "/submissions/nope/assignee", body: `{"email": "teammate@example.com"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing email", user: f.owner, path: path, body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown email", user: f.owner, path: path, body: `{"email": "nobody@example.com"}`, wantStatus: http.StatusNotFound},
+		{name: "outsider", user: f.owner, path: path, body: `{"email": "outsider@example.com"}`, wantStatus: http.StatusNotFound},
+		{name: "not the owner", user: f.teammate, path: path, body: `{"email": "teammate@example.com"}`, wantStatus: http.StatusNotFound},
+		{name: "self", user: f.owner, path: path, body: `{"email": "owner@example.com"}`, wantStatus: http.StatusOK},
+		{name: "teammate", user: f.owner, path: path, body: `{"email": " Teammate@Example.com ", "due_at": "2026-11-02T17:00:00Z"}`, wantStatus: http.StatusOK, wantEmail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.router.do(t, tt.user, http.MethodPut, tt.path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Assign() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			_, emailed := f.notifier.assignments["teammate@example.com"]
+			if emailed != tt.wantEmail {
+				t.Errorf("assignment emailed = %v, want %v", emailed, tt.wantEmail)
+			}
+			if len(f.notifier.assignments) > 1 {
+				t.Errorf("assignments emailed to %v, want only the teammate", f.notifier.assignments)
+			}
+		})
+	}
+
+	var resp models.Submission
+	decodeBody(t, f.router.do(t, f.owner, http.MethodPut, path, `{"email": "teammate@example.com"}`), &resp)
+	if resp.AssigneeID == nil || *resp.AssigneeID != f.teammate || resp.DueAt != nil {
+		t.Errorf("Assign() = assignee %v due %v, want the teammate with no due date", resp.AssigneeID, resp.DueAt)
+	}
+}
+
+func TestAssignmentHandler_Assign_HostOrg(t *testing.T) {
+	f := newAssignmentFixture(t)
+	ctx := context.Background()
+	path := "/submissions/" + f.submission.ID.String() + "/assignee"
+
+	// The owner also belongs to Globex, which the teammate doesn't
+	other, err := f.orgs.Create(ctx, "Globex", f.owner)
+	if err != nil {
+		t.Fatalf("failed to seed organization: %v", err)
+	}
+	for org, subdomain := range map[uuid.UUID]string{f.org.ID: "acme", other.ID: "globex"} {
+		if _, err := f.orgs.SetSubdomain(ctx, org, &subdomain); err != nil {
+			t.Fatalf("failed to seed subdomain: %v", err)
+		}
+	}
+	router := auth.NewOrgDomains("analyzer.example.com", f.orgs).Middleware(f.router)
+
+	assign := func(host string) int {
+		req := withUser(newJSONRequest(t, http.MethodPut, path, `{"email": "teammate@example.com"}`), f.owner)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := assign("globex.analyzer.example.com"); code != http.StatusNotFound {
+		t.Errorf("Assign() on another organization's subdomain status = %d, want %d", code, http.StatusNotFound)
+	}
+	if code := assign("acme.analyzer.example.com"); code != http.StatusOK {
+		t.Errorf("Assign() on the shared organization's subdomain status = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestAssignmentHandler_Workflow(t *testing.T) {
+	f := newAssignmentFixture(t)
+	id := f.submission.ID.String()
+
+	if rec := f.router.do(t, f.owner, http.MethodPut, "/submissions/"+id+"/assignee", `{"email": "teammate@example.com"}`); rec.Code != http.StatusOK {
+		t.Fatalf("Assign() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown status", user: f.teammate, body: `{"workflow_status": "done"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "outsider", user: f.outsider, body: `{"workflow_status": "approved"}`, wantStatus: http.StatusNotFound},
+		{name: "assignee", user: f.teammate, body: `{"workflow_status": "in_review"}`, wantStatus: http.StatusOK},
+		{name: "owner", user: f.owner, body: `{"workflow_status": "changes_requested"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.router.do(t, tt.user, http.MethodPut, "/submissions/"+id+"/workflow", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SetWorkflowStatus() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// The teammate's queue has the submission and filters by status
+	var queue SubmissionListResponse
+	decodeBody(t, f.router.do(t, f.teammate, http.MethodGet, "/me/queue?workflow_status=changes_requested", nil), &queue)
+	if len(queue.Submissions) != 1 || queue.Submissions[0].WorkflowStatus != models.WorkflowChangesRequested {
+		t.Errorf("Queue() = %+v, want the submission with changes requested", queue.Submissions)
+	}
+	decodeBody(t, f.router.do(t, f.teammate, http.MethodGet, "/me/queue?workflow_status=approved", nil), &queue)
+	if len(queue.Submissions) != 0 {
+		t.Errorf("Queue(approved) returned %d submissions, want 0", len(queue.Submissions))
+	}
+	if rec := f.router.do(t, f.teammate, http.MethodGet, "/me/queue?workflow_status=done", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Queue(done) status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// The assignee can hand the submission back, emptying their queue
+	if rec := f.router.do(t, f.teammate, http.MethodDelete, "/submissions/"+id+"/assignee", nil); rec.Code != http.StatusOK {
+		t.Fatalf("Unassign() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	decodeBody(t, f.router.do(t, f.teammate, http.MethodGet, "/me/queue", nil), &queue)
+	if len(queue.Submissions) != 0 {
+		t.Errorf("Queue() after Unassign returned %d submissions, want 0", len(queue.Submissions))
+	}
+}
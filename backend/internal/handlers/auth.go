@@ -1,58 +1,146 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 
+	"github.com/sfumato00/content-analyzer/internal/abuse"
 	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
 	"github.com/sfumato00/content-analyzer/internal/models"
 	"github.com/sfumato00/content-analyzer/internal/response"
 )
 
+const (
+	// verificationTokenTTL is how long email verification links stay valid
+	verificationTokenTTL = 24 * time.Hour
+
+	// passwordResetTokenTTL is how long password reset links stay valid
+	passwordResetTokenTTL = time.Hour
+)
+
 // AuthHandler handles authentication requests
 type AuthHandler struct {
-	userStore  *models.UserStore
+	userStore  UserStorer
+	tokenStore EmailTokenStorer
 	jwtManager *auth.JWTManager
+	notifier   AccountNotifier
+	// sessions signs a user out everywhere once their password is reset
+	sessions SessionRevoker
+	abuse    abuse.Protection
+	// inviteOnly closes open registration; new accounts need an invite code
+	inviteOnly bool
+	// remember keeps devices signed in; nil when remember-me is off
+	remember *SessionHandler
+	// guard holds sign-ins from a new country or device; nil when off
+	guard *LoginGuard
+	// sso turns password sign-in off for members of organizations that
+	// enforce single sign-on; nil when SSO is off
+	sso SSOEnforcer
+	// invitations makes users who verify their email members of the
+	// organizations that invited them through SCIM; nil when SCIM is off
+	invitations InvitationAccepter
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(userStore *models.UserStore, jwtManager *auth.JWTManager) *AuthHandler {
+func NewAuthHandler(userStore UserStorer, tokenStore EmailTokenStorer, jwtManager *auth.JWTManager, notifier AccountNotifier, sessions SessionRevoker) *AuthHandler {
 	return &AuthHandler{
 		userStore:  userStore,
+		tokenStore: tokenStore,
 		jwtManager: jwtManager,
+		notifier:   notifier,
+		sessions:   sessions,
 	}
 }
 
+// WithAbuseProtection enables CAPTCHA, disposable domain and sign-up rate
+// limit checks
+func (h *AuthHandler) WithAbuseProtection(protection abuse.Protection) *AuthHandler {
+	h.abuse = protection
+	return h
+}
+
+// WithInviteOnly requires an invitation code to register
+func (h *AuthHandler) WithInviteOnly(inviteOnly bool) *AuthHandler {
+	h.inviteOnly = inviteOnly
+	return h
+}
+
+// WithRememberMe lets users signing in keep their device signed in
+func (h *AuthHandler) WithRememberMe(sessions *SessionHandler) *AuthHandler {
+	h.remember = sessions
+	return h
+}
+
+// WithLoginGuard confirms sign-ins from a new country or device
+func (h *AuthHandler) WithLoginGuard(guard *LoginGuard) *AuthHandler {
+	h.guard = guard
+	return h
+}
+
+// WithSSOEnforcement refuses password sign-in to members of
+// organizations that enforce single sign-on
+func (h *AuthHandler) WithSSOEnforcement(enforcer SSOEnforcer) *AuthHandler {
+	h.sso = enforcer
+	return h
+}
+
+// WithSCIMInvitations accepts users' SCIM invitations once they verify
+// their email address
+func (h *AuthHandler) WithSCIMInvitations(invitations InvitationAccepter) *AuthHandler {
+	h.invitations = invitations
+	return h
+}
+
 // RegisterRequest represents the registration request
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+	InviteCode   string `json:"invite_code,omitempty"`
 }
 
 // LoginRequest represents the login request
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// RememberMe keeps the device signed in with a cookie, when enabled
+	RememberMe bool `json:"remember_me"`
+}
+
+// VerifyEmailRequest represents the email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+// ForgotPasswordRequest represents the forgot password request
+type ForgotPasswordRequest struct {
+	Email        string `json:"email"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+// ResetPasswordRequest represents the password reset request
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
 }
 
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	User  *UserResponse   `json:"user"`
+	User  *dto.User       `json:"user"`
 	Token *auth.TokenPair `json:"token"`
 }
 
-// UserResponse represents the user data in responses (without sensitive fields)
-type UserResponse struct {
-	ID        string `json:"id"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"created_at"`
-}
-
 // Register handles user registration
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
@@ -61,14 +149,40 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.allowSignup(w, r) || !h.verifyCaptcha(w, r, req.CaptchaToken) {
+		return
+	}
+
 	// Normalize email
 	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 
-	// Create user
-	user, err := h.userStore.Create(r.Context(), req.Email, req.Password)
+	if h.abuse.Domains != nil && h.abuse.Domains.Blocked(req.Email) {
+		response.BadRequest(w, "Disposable email addresses are not allowed")
+		return
+	}
+
+	if h.inviteOnly && strings.TrimSpace(req.InviteCode) == "" {
+		response.Forbidden(w, "Registration requires an invitation code")
+		return
+	}
+
+	// Create user, spending an invitation in invite-only mode
+	var user *models.User
+	var err error
+	if h.inviteOnly {
+		user, err = h.userStore.CreateWithInvite(r.Context(), req.Email, req.Password, req.InviteCode)
+	} else {
+		user, err = h.userStore.Create(r.Context(), req.Email, req.Password)
+	}
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidInvite) {
+			response.Forbidden(w, "Invalid or expired invitation code")
+			return
+		}
+
 		// Check for duplicate email error
-		if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23505" {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			response.BadRequest(w, "Email already exists")
 			return
 		}
@@ -92,19 +206,65 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Send verification email (registration succeeds even if this fails)
+	h.sendVerification(r.Context(), user)
+
 	// Return user and token
 	authResp := AuthResponse{
-		User: &UserResponse{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
+		User:  dto.NewUser(user),
 		Token: tokenPair,
 	}
 
 	response.Created(w, authResp)
 }
 
+// allowSignup applies the per-IP registration limit, writing a 429 when
+// it is exceeded. If Redis is unavailable the limit fails open, or refuses
+// the request when configured to fail closed.
+func (h *AuthHandler) allowSignup(w http.ResponseWriter, r *http.Request) bool {
+	if h.abuse.Signups == nil {
+		return true
+	}
+
+	ip := clientIP(r)
+	allowed, retryAfter, err := h.abuse.Signups.Allow(r.Context(), ip)
+	if err != nil {
+		slog.Warn("Failed to check sign-up rate limit", "ip", ip, "error", err)
+		if h.abuse.Signups.FailsClosed() {
+			rateLimitUnavailable(w)
+			return false
+		}
+		return true
+	}
+	if !allowed {
+		slog.Warn("Sign-up rate limit exceeded", "ip", ip)
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		response.TooManyRequests(w, "Too many sign-up attempts, please try again later")
+		return false
+	}
+	return true
+}
+
+// verifyCaptcha checks the request's CAPTCHA token when CAPTCHA is
+// enabled, writing an error response when it doesn't pass
+func (h *AuthHandler) verifyCaptcha(w http.ResponseWriter, r *http.Request, token string) bool {
+	if h.abuse.Captcha == nil {
+		return true
+	}
+
+	err := h.abuse.Captcha.Verify(r.Context(), token, clientIP(r))
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, abuse.ErrCaptchaFailed):
+		response.BadRequest(w, "CAPTCHA verification failed")
+	default:
+		slog.Error("Failed to verify CAPTCHA", "error", err)
+		response.ServiceUnavailable(w, "CAPTCHA verification is unavailable, please try again")
+	}
+	return false
+}
+
 // Login handles user login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
@@ -135,8 +295,87 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Generate JWT token
-	tokenPair, err := h.jwtManager.GenerateTokenPair(user.ID, user.Email)
+	// Upgrade hashes made with an older algorithm or cost while we have the
+	// plain text password. Failing to do so doesn't stop the login.
+	if user.NeedsRehash() {
+		if err := h.userStore.RehashPassword(r.Context(), user.ID, user.PasswordHash, req.Password); err != nil {
+			slog.WarnContext(r.Context(), "Failed to rehash password", "user_id", user.ID, "error", err)
+		}
+	}
+
+	// Suspended and banned accounts can't sign in; suspended ones are
+	// given a token to appeal with
+	if refuseStanding(w, h.jwtManager, user) {
+		return
+	}
+
+	if h.sso != nil && h.requiresSSO(w, r, user) {
+		return
+	}
+
+	// Sign-ins from a new country or device may wait for an emailed code
+	if h.guard != nil && h.guard.hold(w, r, user, req.RememberMe) {
+		return
+	}
+
+	h.signIn(w, r, user, req.RememberMe)
+}
+
+// requiresSSO refuses the sign-in if one of the user's organizations
+// enforces single sign-on. Unlike the login guard it fails closed: the
+// organization has chosen to manage its members' access. It reports
+// whether it refused, having written the response.
+func (h *AuthHandler) requiresSSO(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	orgID, enforced, err := h.sso.Enforced(r.Context(), user.ID)
+	if err != nil {
+		slog.Error("Failed to check SSO enforcement", "user_id", user.ID, "error", err)
+		response.InternalServerError(w, "Failed to authenticate")
+		return true
+	}
+	if !enforced {
+		return false
+	}
+
+	response.Forbidden(w, "Your organization requires single sign-on; sign in at /api/v1/auth/sso/"+orgID.String())
+	return true
+}
+
+// refuseStanding refuses to sign a suspended or banned user in,
+// reporting whether it did, having written the response. A suspended user
+// is given a token that can only be used to appeal.
+func refuseStanding(w http.ResponseWriter, tokens *auth.JWTManager, user *models.User) bool {
+	if user.Status == models.StatusActive {
+		return false
+	}
+	standing := &auth.Standing{Status: user.Status}
+	if user.StatusReason != nil {
+		standing.Reason = *user.StatusReason
+	}
+
+	var appealToken *auth.TokenPair
+	if user.Status == models.StatusSuspended {
+		var err error
+		appealToken, err = tokens.GenerateAppealToken(user.ID, user.Email)
+		if err != nil {
+			slog.Error("Failed to generate appeal token", "user_id", user.ID, "error", err)
+			response.InternalServerError(w, "Failed to generate authentication token")
+			return true
+		}
+	}
+	auth.RefuseSignIn(w, standing, appealToken)
+	return true
+}
+
+// signIn issues the user a token, bound to a remember-me session if asked
+// for, and writes the login response
+func (h *AuthHandler) signIn(w http.ResponseWriter, r *http.Request, user *models.User, rememberMe bool) {
+	var tokenPair *auth.TokenPair
+	var err error
+	if rememberMe && h.remember != nil {
+		tokenPair, err = h.remember.Remember(w, r, user)
+	} else {
+		tokenPair, err = h.jwtManager.GenerateTokenPair(user.ID, user.Email)
+	}
 	if err != nil {
 		slog.Error("Failed to generate token", "error", err)
 		response.InternalServerError(w, "Failed to generate authentication token")
@@ -145,11 +384,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	// Return user and token
 	authResp := AuthResponse{
-		User: &UserResponse{
-			ID:        user.ID.String(),
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		},
+		User:  dto.NewUser(user),
 		Token: tokenPair,
 	}
 
@@ -160,11 +395,13 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // Note: Since we're using JWT, logout is primarily client-side
 // The client should remove the token from storage
 func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// For JWT, logout is handled client-side by removing the token
-	// In the future, we could implement token blacklisting using Redis
-	response.Success(w, map[string]string{
-		"message": "Logged out successfully",
-	})
+	// For JWT, logout is handled client-side by removing the token. A
+	// remembered device is forgotten, which also revokes its tokens.
+	if h.remember != nil {
+		h.remember.Forget(w, r)
+	}
+
+	response.Success(w, dto.Message{Message: "Logged out successfully"})
 }
 
 // Me returns the current authenticated user
@@ -190,11 +427,170 @@ func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Return user
-	userResp := UserResponse{
-		ID:        user.ID.String(),
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	setETag(w, user.Version)
+	response.Success(w, dto.NewUser(user))
+}
+
+// VerifyEmail confirms a user's email address using a token from their inbox
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	token, err := h.tokenStore.Consume(r.Context(), models.PurposeVerifyEmail, req.Token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidToken) {
+			response.BadRequest(w, "Invalid or expired verification link")
+			return
+		}
+
+		slog.Error("Failed to consume verification token", "error", err)
+		response.InternalServerError(w, "Failed to verify email")
+		return
+	}
+
+	if err := h.userStore.MarkEmailVerified(r.Context(), token.UserID); err != nil {
+		slog.Error("Failed to mark email verified", "error", err)
+		response.InternalServerError(w, "Failed to verify email")
+		return
+	}
+	h.acceptInvitations(r.Context(), token.UserID)
+
+	response.Success(w, dto.Message{Message: "Email verified successfully"})
+}
+
+// ResendVerification sends a new verification email to the current user
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	user, err := h.userStore.GetByID(r.Context(), userID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			response.NotFound(w, "User not found")
+			return
+		}
+
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to get user")
+		return
+	}
+
+	if user.EmailVerifiedAt != nil {
+		response.BadRequest(w, "Email is already verified")
+		return
+	}
+
+	h.sendVerification(r.Context(), user)
+
+	response.Success(w, dto.Message{Message: "Verification email sent"})
+}
+
+// ForgotPassword emails a password reset link. The response is the same
+// whether or not the account exists to avoid leaking registered emails.
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
 	}
 
-	response.Success(w, userResp)
+	if !h.verifyCaptcha(w, r, req.CaptchaToken) {
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	user, err := h.userStore.GetByEmail(r.Context(), email)
+	switch {
+	case err == nil:
+		token, err := h.tokenStore.Create(r.Context(), user.ID, models.PurposePasswordReset, user.Email, passwordResetTokenTTL)
+		if err != nil {
+			slog.Error("Failed to create password reset token", "error", err)
+			break
+		}
+		if err := h.notifier.SendPasswordReset(r.Context(), user.Email, token, passwordResetTokenTTL); err != nil {
+			slog.Error("Failed to send password reset email", "error", err)
+		}
+	case err != pgx.ErrNoRows:
+		slog.Error("Failed to get user", "error", err)
+	}
+
+	response.Success(w, dto.Message{Message: "If an account exists for that email, a reset link has been sent"})
+}
+
+// ResetPassword sets a new password using a token from a reset email
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	// Validate before consuming so a weak password doesn't burn the token
+	if err := models.ValidatePassword(req.Password); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	token, err := h.tokenStore.Consume(r.Context(), models.PurposePasswordReset, req.Token)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidToken) {
+			response.BadRequest(w, "Invalid or expired reset link")
+			return
+		}
+
+		slog.Error("Failed to consume password reset token", "error", err)
+		response.InternalServerError(w, "Failed to reset password")
+		return
+	}
+
+	if err := h.userStore.UpdatePassword(r.Context(), token.UserID, req.Password); err != nil {
+		slog.Error("Failed to update password", "error", err)
+		response.InternalServerError(w, "Failed to reset password")
+		return
+	}
+
+	// A reset usually follows a compromise, so whoever else holds a token
+	// or a remembered device is signed out
+	if err := h.sessions.RevokeAll(r.Context(), token.UserID); err != nil {
+		slog.Error("Failed to revoke sessions after password reset", "user_id", token.UserID, "error", err)
+		response.InternalServerError(w, "Password reset but other sessions could not be signed out")
+		return
+	}
+
+	// Receiving the reset email proves ownership of the address
+	if err := h.userStore.MarkEmailVerified(r.Context(), token.UserID); err != nil {
+		slog.Warn("Failed to mark email verified after reset", "error", err)
+	} else {
+		h.acceptInvitations(r.Context(), token.UserID)
+	}
+
+	response.Success(w, dto.Message{Message: "Password reset successfully"})
+}
+
+// acceptInvitations makes a user whose email was just verified a member
+// of the organizations that invited them
+func (h *AuthHandler) acceptInvitations(ctx context.Context, userID uuid.UUID) {
+	if h.invitations != nil {
+		h.invitations.AcceptInvitations(ctx, userID)
+	}
+}
+
+// sendVerification issues a verification token and emails it, logging failures
+func (h *AuthHandler) sendVerification(ctx context.Context, user *models.User) {
+	token, err := h.tokenStore.Create(ctx, user.ID, models.PurposeVerifyEmail, user.Email, verificationTokenTTL)
+	if err != nil {
+		slog.Error("Failed to create verification token", "error", err)
+		return
+	}
+
+	if err := h.notifier.SendVerification(ctx, user.Email, token, verificationTokenTTL); err != nil {
+		slog.Error("Failed to send verification email", "error", err)
+	}
 }
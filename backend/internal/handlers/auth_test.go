@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/abuse"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+const testPassword = "password123"
+
+func newTestAuthHandler() (*AuthHandler, *memstore.UserStore, *fakeNotifier) {
+	users := memstore.NewUserStore()
+	notifier := newFakeNotifier()
+	handler := NewAuthHandler(
+		users,
+		memstore.NewEmailTokenStore(),
+		auth.NewJWTManager("test-secret-key-at-least-32-characters"),
+		notifier,
+		&fakeSessions{},
+	)
+	return handler, users, notifier
+}
+
+func TestAuthHandler_Register(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name:       "valid registration",
+			body:       RegisterRequest{Email: "New@Example.com ", Password: testPassword},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "invalid email",
+			body:       RegisterRequest{Email: "not-an-email", Password: testPassword},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "short password",
+			body:       RegisterRequest{Email: "short@example.com", Password: "short"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed body",
+			body:       "{",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _, notifier := newTestAuthHandler()
+
+			rec := httptest.NewRecorder()
+			handler.Register(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/register", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Register() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var resp AuthResponse
+			decodeBody(t, rec, &resp)
+
+			if resp.User.Email != "new@example.com" {
+				t.Errorf("Register() email = %q, want normalized %q", resp.User.Email, "new@example.com")
+			}
+			if resp.User.EmailVerified {
+				t.Error("Register() new user should not be verified")
+			}
+			if resp.Token == nil || resp.Token.AccessToken == "" {
+				t.Error("Register() missing access token")
+			}
+			if notifier.verifications["new@example.com"] == "" {
+				t.Error("Register() did not send a verification email")
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Register_DuplicateEmail(t *testing.T) {
+	handler, users, _ := newTestAuthHandler()
+
+	seedUser(t, users, "taken@example.com")
+
+	rec := httptest.NewRecorder()
+	handler.Register(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/register", RegisterRequest{
+		Email:    "taken@example.com",
+		Password: testPassword,
+	}))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Register() status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	var resp map[string]string
+	decodeBody(t, rec, &resp)
+	if resp["error"] != "Email already exists" {
+		t.Errorf("Register() error = %q, want %q", resp["error"], "Email already exists")
+	}
+}
+
+// fakeCounter counts rate limit hits in memory
+type fakeCounter map[string]int64
+
+func (c fakeCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	c[key]++
+	return c[key], nil
+}
+
+func TestAuthHandler_Register_AbuseProtection(t *testing.T) {
+	handler, _, _ := newTestAuthHandler()
+	handler.WithAbuseProtection(abuse.Protection{
+		Domains: abuse.NewDomainList(true, nil),
+		Signups: abuse.NewLimiter(fakeCounter{}, "ratelimit:signup", 2, time.Hour),
+	})
+
+	register := func(email string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Register(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/register", RegisterRequest{
+			Email:    email,
+			Password: testPassword,
+		}))
+		return rec
+	}
+
+	if rec := register("bot@mailinator.com"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Register() disposable status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := register("first@example.com"); rec.Code != http.StatusCreated {
+		t.Errorf("Register() status = %d, want %d (body: %s)", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+
+	rec := register("second@example.com")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Register() over limit status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Register() over limit missing Retry-After")
+	}
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	handler, users, _ := newTestAuthHandler()
+
+	seedUser(t, users, "user@example.com")
+
+	tests := []struct {
+		name       string
+		body       LoginRequest
+		wantStatus int
+	}{
+		{
+			name:       "valid credentials",
+			body:       LoginRequest{Email: "USER@example.com", Password: testPassword},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "wrong password",
+			body:       LoginRequest{Email: "user@example.com", Password: "wrong-password"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "unknown user",
+			body:       LoginRequest{Email: "nobody@example.com", Password: testPassword},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.Login(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/login", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Login() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Login_RehashesOutdatedHash(t *testing.T) {
+	handler, users, _ := newTestAuthHandler()
+	ctx := context.Background()
+
+	setPasswordParams(t, models.PasswordParams{Algorithm: models.PasswordBcrypt, BcryptCost: 4})
+	user := seedUser(t, users, "user@example.com")
+
+	setPasswordParams(t, models.PasswordParams{Algorithm: models.PasswordArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1})
+
+	rec := httptest.NewRecorder()
+	handler.Login(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: user.Email, Password: testPassword}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Login() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	updated, _ := users.GetByID(ctx, user.ID)
+	if !strings.HasPrefix(updated.PasswordHash, "$argon2id$") {
+		t.Errorf("Login() left hash %q, want an argon2id hash", updated.PasswordHash)
+	}
+	if updated.Version != user.Version {
+		t.Errorf("Login() changed version to %d, want %d", updated.Version, user.Version)
+	}
+	if err := updated.ComparePassword(testPassword); err != nil {
+		t.Errorf("ComparePassword() after rehash error = %v", err)
+	}
+}
+
+// setPasswordParams sets the password hashing parameters for the rest of the test
+func setPasswordParams(t *testing.T, p models.PasswordParams) {
+	t.Helper()
+	if err := models.SetPasswordParams(p); err != nil {
+		t.Fatalf("SetPasswordParams() error = %v", err)
+	}
+	t.Cleanup(func() { _ = models.SetPasswordParams(models.DefaultPasswordParams) })
+}
+
+func TestAuthHandler_Me(t *testing.T) {
+	handler, users, _ := newTestAuthHandler()
+
+	user := seedUser(t, users, "me@example.com")
+
+	t.Run("existing user", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.Me(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/me", nil), user.ID))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Me() status = %d, want %d", rec.Code, http.StatusOK)
+		}
+
+		var resp dto.User
+		decodeBody(t, rec, &resp)
+		if resp.ID != user.ID.String() {
+			t.Errorf("Me() id = %q, want %q", resp.ID, user.ID)
+		}
+	})
+
+	t.Run("deleted user", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.Me(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/v1/me", nil), uuid.New()))
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("Me() status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.Me(rec, httptest.NewRequest(http.MethodGet, "/api/v1/me", nil))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("Me() status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+// fakeAccepter records whose invitations were accepted
+type fakeAccepter struct {
+	accepted []uuid.UUID
+}
+
+func (f *fakeAccepter) AcceptInvitations(ctx context.Context, userID uuid.UUID) {
+	f.accepted = append(f.accepted, userID)
+}
+
+func TestAuthHandler_VerifyEmail(t *testing.T) {
+	handler, users, notifier := newTestAuthHandler()
+	invitations := &fakeAccepter{}
+	handler.WithSCIMInvitations(invitations)
+
+	rec := httptest.NewRecorder()
+	handler.Register(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/register", RegisterRequest{
+		Email:    "verify@example.com",
+		Password: testPassword,
+	}))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Register() status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+
+	token := notifier.verifications["verify@example.com"]
+
+	rec = httptest.NewRecorder()
+	handler.VerifyEmail(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/verify-email", VerifyEmailRequest{Token: token}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("VerifyEmail() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	user, err := users.GetByEmail(context.Background(), "verify@example.com")
+	if err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	if user.EmailVerifiedAt == nil {
+		t.Error("VerifyEmail() did not mark the email verified")
+	}
+	if len(invitations.accepted) != 1 || invitations.accepted[0] != user.ID {
+		t.Errorf("accepted invitations of %v, want %s", invitations.accepted, user.ID)
+	}
+
+	// Tokens are single use
+	rec = httptest.NewRecorder()
+	handler.VerifyEmail(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/verify-email", VerifyEmailRequest{Token: token}))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("VerifyEmail() reused token status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAuthHandler_PasswordReset(t *testing.T) {
+	handler, users, notifier := newTestAuthHandler()
+
+	seedUser(t, users, "reset@example.com")
+
+	// Unknown emails get the same response and no email
+	rec := httptest.NewRecorder()
+	handler.ForgotPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/forgot-password", ForgotPasswordRequest{Email: "nobody@example.com"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ForgotPassword() unknown email status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(notifier.resets) != 0 {
+		t.Errorf("ForgotPassword() sent %d emails for an unknown address", len(notifier.resets))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ForgotPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/forgot-password", ForgotPasswordRequest{Email: "reset@example.com"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ForgotPassword() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	token := notifier.resets["reset@example.com"]
+	if token == "" {
+		t.Fatal("ForgotPassword() did not send a reset email")
+	}
+
+	// A weak password is rejected without burning the token
+	rec = httptest.NewRecorder()
+	handler.ResetPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/reset-password", ResetPasswordRequest{Token: token, Password: "short"}))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("ResetPassword() weak password status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ResetPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/reset-password", ResetPasswordRequest{Token: token, Password: "new-password-456"}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ResetPassword() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	user, err := users.GetByEmail(context.Background(), "reset@example.com")
+	if err != nil {
+		t.Fatalf("failed to load user: %v", err)
+	}
+	if err := user.ComparePassword("new-password-456"); err != nil {
+		t.Error("ResetPassword() did not update the password")
+	}
+	// Everyone else holding a token or remembered device is signed out
+	if revoked := handler.sessions.(*fakeSessions).revoked; len(revoked) != 1 || revoked[0] != user.ID {
+		t.Errorf("ResetPassword() revoked sessions of %v, want %s", revoked, user.ID)
+	}
+}
+
+func TestAuthHandler_ResetPassword_RevokeFails(t *testing.T) {
+	handler, users, notifier := newTestAuthHandler()
+	handler.sessions = &fakeSessions{err: errors.New("redis down")}
+
+	seedUser(t, users, "reset@example.com")
+	rec := httptest.NewRecorder()
+	handler.ForgotPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/forgot-password", ForgotPasswordRequest{Email: "reset@example.com"}))
+
+	// The password is changed, but the user is told the sign-out failed
+	rec = httptest.NewRecorder()
+	handler.ResetPassword(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/reset-password", ResetPasswordRequest{Token: notifier.resets["reset@example.com"], Password: "new-password-456"}))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("ResetPassword() status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
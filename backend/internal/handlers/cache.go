@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+const (
+	defaultTopKeys = 20
+	maxTopKeys     = 1000
+)
+
+// CacheHandler shows which cache keys get the most traffic
+type CacheHandler struct {
+	traffic KeyTrafficReporter
+}
+
+// NewCacheHandler creates a new cache handler
+func NewCacheHandler(traffic KeyTrafficReporter) *CacheHandler {
+	return &CacheHandler{traffic: traffic}
+}
+
+// TopKeysResponse lists the busiest cache keys
+type TopKeysResponse struct {
+	Keys []cache.KeyCount `json:"keys"`
+}
+
+// TopKeys returns the keys with the most Redis commands since startup
+// GET /admin/cache/keys?limit=20
+func (h *CacheHandler) TopKeys(w http.ResponseWriter, r *http.Request) {
+	limit := defaultTopKeys
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxTopKeys {
+			response.BadRequest(w, fmt.Sprintf("limit must be between 1 and %d", maxTopKeys))
+			return
+		}
+		limit = n
+	}
+
+	keys := h.traffic.TopKeys(limit)
+	if keys == nil {
+		keys = []cache.KeyCount{}
+	}
+	response.Success(w, TopKeysResponse{Keys: keys})
+}
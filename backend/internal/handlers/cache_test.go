@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+)
+
+// fakeTraffic reports fixed key counts
+type fakeTraffic []cache.KeyCount
+
+func (f fakeTraffic) TopKeys(limit int) []cache.KeyCount {
+	if len(f) > limit {
+		return f[:limit]
+	}
+	return f
+}
+
+func TestCacheHandler_TopKeys(t *testing.T) {
+	traffic := fakeTraffic{{Key: "auth:revoked_before:1", Count: 40}, {Key: "analysis:2", Count: 12}, {Key: "analysis:3", Count: 3}}
+
+	tests := []struct {
+		name       string
+		traffic    KeyTrafficReporter
+		query      string
+		wantStatus int
+		wantKeys   int
+	}{
+		{name: "default limit", traffic: traffic, wantStatus: http.StatusOK, wantKeys: 3},
+		{name: "limit", traffic: traffic, query: "?limit=2", wantStatus: http.StatusOK, wantKeys: 2},
+		{name: "invalid limit", traffic: traffic, query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "limit too large", traffic: traffic, query: "?limit=5000", wantStatus: http.StatusBadRequest},
+		{name: "no metrics", traffic: fakeTraffic(nil), wantStatus: http.StatusOK, wantKeys: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewCacheHandler(tt.traffic).TopKeys(rec, httptest.NewRequest(http.MethodGet, "/admin/cache/keys"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("TopKeys() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got TopKeysResponse
+			decodeBody(t, rec, &got)
+			if got.Keys == nil || len(got.Keys) != tt.wantKeys {
+				t.Errorf("TopKeys() = %+v, want %d keys", got.Keys, tt.wantKeys)
+			}
+			if tt.wantKeys > 0 && got.Keys[0].Key != "auth:revoked_before:1" {
+				t.Errorf("TopKeys() first = %+v, want the busiest key", got.Keys[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/capture"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+const (
+	defaultCaptures = 50
+	maxCaptures     = 1000
+)
+
+// CaptureHandler lets operators read the requests and responses captured
+// for debugging client integrations
+type CaptureHandler struct {
+	captures CaptureReader
+}
+
+// NewCaptureHandler creates a new capture handler
+func NewCaptureHandler(captures CaptureReader) *CaptureHandler {
+	return &CaptureHandler{captures: captures}
+}
+
+// CapturesResponse lists captured exchanges
+type CapturesResponse struct {
+	Captures []capture.Exchange `json:"captures"`
+}
+
+// List returns this instance's captured exchanges, newest first,
+// optionally only a user's or those under a path
+// GET /admin/captures?user_id=&path=&limit=50
+func (h *CaptureHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := capture.Filter{PathPrefix: query.Get("path"), Limit: defaultCaptures}
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxCaptures {
+			response.BadRequest(w, fmt.Sprintf("limit must be between 1 and %d", maxCaptures))
+			return
+		}
+		filter.Limit = n
+	}
+	if v := query.Get("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			response.BadRequest(w, "Invalid user ID")
+			return
+		}
+		filter.UserID = &userID
+	}
+
+	captures := h.captures.List(filter)
+	slog.Info("Debug captures viewed", "count", len(captures), "by", operator(r))
+	response.Success(w, CapturesResponse{Captures: captures})
+}
+
+// Clear drops this instance's captured exchanges
+// DELETE /admin/captures
+func (h *CaptureHandler) Clear(w http.ResponseWriter, r *http.Request) {
+	h.captures.Clear()
+	slog.Info("Debug captures cleared", "by", operator(r))
+	response.NoContent(w)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/capture"
+)
+
+// fakeCaptures records the filter it was listed with
+type fakeCaptures struct {
+	filter  capture.Filter
+	cleared bool
+}
+
+func (f *fakeCaptures) List(filter capture.Filter) []capture.Exchange {
+	f.filter = filter
+	return []capture.Exchange{{Method: http.MethodGet, Path: "/api/v1/submissions", Status: http.StatusOK}}
+}
+
+func (f *fakeCaptures) Clear() {
+	f.cleared = true
+}
+
+func TestCaptureHandler_List(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantFilter capture.Filter
+	}{
+		{name: "defaults", wantStatus: http.StatusOK, wantFilter: capture.Filter{Limit: defaultCaptures}},
+		{
+			name:       "filtered",
+			query:      "?user_id=" + userID.String() + "&path=/api/v1/submissions&limit=5",
+			wantStatus: http.StatusOK,
+			wantFilter: capture.Filter{UserID: &userID, PathPrefix: "/api/v1/submissions", Limit: 5},
+		},
+		{name: "invalid limit", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=5000", wantStatus: http.StatusBadRequest},
+		{name: "invalid user", query: "?user_id=nope", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			captures := &fakeCaptures{}
+			rec := httptest.NewRecorder()
+			NewCaptureHandler(captures).List(rec, withUser(httptest.NewRequest(http.MethodGet, "/admin/captures"+tt.query, nil), uuid.New()))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("List() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			got := captures.filter
+			if got.Limit != tt.wantFilter.Limit || got.PathPrefix != tt.wantFilter.PathPrefix || (got.UserID == nil) != (tt.wantFilter.UserID == nil) ||
+				(got.UserID != nil && *got.UserID != *tt.wantFilter.UserID) {
+				t.Errorf("filter = %+v, want %+v", got, tt.wantFilter)
+			}
+			var resp CapturesResponse
+			decodeBody(t, rec, &resp)
+			if len(resp.Captures) != 1 || resp.Captures[0].Path != "/api/v1/submissions" {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestCaptureHandler_Clear(t *testing.T) {
+	captures := &fakeCaptures{}
+	rec := httptest.NewRecorder()
+	NewCaptureHandler(captures).Clear(rec, withUser(httptest.NewRequest(http.MethodDelete, "/admin/captures", nil), uuid.New()))
+
+	if rec.Code != http.StatusNoContent || !captures.cleared {
+		t.Errorf("Clear() status = %d, cleared = %v", rec.Code, captures.cleared)
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// degradedRetryAfter is the Retry-After, in seconds, sent while Redis is
+// unavailable
+const degradedRetryAfter = 30
+
+// enqueueFailed writes the response for a job that couldn't be queued:
+// 503 while Redis is unavailable, which clears up by itself, and 500
+// otherwise
+func enqueueFailed(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, cache.ErrUnavailable) {
+		w.Header().Set("Retry-After", strconv.Itoa(degradedRetryAfter))
+		response.ServiceUnavailable(w, message+", please try again shortly")
+		return
+	}
+	response.InternalServerError(w, message)
+}
+
+// rateLimitUnavailable refuses a request whose rate limit can't be
+// checked, for limits that fail closed
+func rateLimitUnavailable(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(degradedRetryAfter))
+	response.ServiceUnavailable(w, "Temporarily unavailable, please try again shortly")
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// DomainRequest claims an email domain for an organization
+type DomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+// WithDomains manages the email domains organizations claim in domains,
+// verifying them over DNS, and returns the handler
+func (h *OrgHandler) WithDomains(domains OrgDomainStorer) *OrgHandler {
+	h.domains = domains
+	h.lookupTXT = net.DefaultResolver.LookupTXT
+	return h
+}
+
+// ListDomains returns the email domains the organization claims, with
+// the TXT records that verify them. Owners and admins only.
+// GET /api/v1/orgs/{id}/domains
+func (h *OrgHandler) ListDomains(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can see domains")
+		return
+	}
+
+	domains, err := h.domains.List(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to list organization domains", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to list domains")
+		return
+	}
+	response.Success(w, response.Complete(domains))
+}
+
+// AddDomain claims an email domain for the organization. It is unverified
+// until the TXT record it returns is published. Owners only.
+// POST /api/v1/orgs/{id}/domains
+func (h *OrgHandler) AddDomain(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can manage domains")
+		return
+	}
+
+	var req DomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	domain := strings.ToLower(strings.TrimSpace(req.Domain))
+	if err := models.ValidateDomain(domain); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	existing, err := h.domains.List(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to list organization domains", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to add domain")
+		return
+	}
+	if len(existing) >= models.MaxOrgDomains {
+		response.BadRequest(w, fmt.Sprintf("An organization can claim at most %d domains", models.MaxOrgDomains))
+		return
+	}
+
+	added, err := h.domains.Add(r.Context(), orgID, domain)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			response.Conflict(w, "The organization already claims this domain")
+			return
+		}
+		slog.Error("Failed to add organization domain", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to add domain")
+		return
+	}
+
+	slog.Info("Organization domain added", "org_id", orgID, "domain", domain, "by", operator(r))
+	response.Created(w, added)
+}
+
+// VerifyDomain checks the domain's TXT record and, if it holds the claim's
+// token, marks the domain verified. Owners only.
+// POST /api/v1/orgs/{id}/domains/{domain}/verify
+func (h *OrgHandler) VerifyDomain(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can manage domains")
+		return
+	}
+
+	domain := strings.ToLower(chi.URLParam(r, "domain"))
+	claim, err := h.domains.Get(r.Context(), orgID, domain)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Domain not found")
+			return
+		}
+		slog.Error("Failed to get organization domain", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to verify domain")
+		return
+	}
+
+	if !claim.Verified() && !h.hasRecord(r.Context(), claim) {
+		response.BadRequest(w, fmt.Sprintf("No TXT record %s with the value %s was found", claim.RecordName, claim.RecordValue))
+		return
+	}
+
+	verified, err := h.domains.MarkVerified(r.Context(), orgID, domain)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Domain not found")
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			response.Conflict(w, "Another organization has verified this domain")
+		default:
+			slog.Error("Failed to verify organization domain", "org_id", orgID, "error", err)
+			response.InternalServerError(w, "Failed to verify domain")
+		}
+		return
+	}
+
+	// Logged at warn: the organization's identity provider can now create
+	// accounts under the domain
+	slog.Warn("Organization domain verified", "org_id", orgID, "domain", domain, "by", operator(r))
+	response.Success(w, verified)
+}
+
+// hasRecord reports whether the claim's TXT record is published. Lookup
+// failures count as missing.
+func (h *OrgHandler) hasRecord(ctx context.Context, claim *models.OrgDomain) bool {
+	records, err := h.lookupTXT(ctx, claim.RecordName)
+	if err != nil {
+		slog.Info("Domain TXT lookup failed", "org_id", claim.OrgID, "name", claim.RecordName, "error", err)
+		return false
+	}
+	return slices.Contains(records, claim.RecordValue)
+}
+
+// RemoveDomain drops the organization's claim to a domain. Accounts
+// already provisioned under it are kept. Owners only.
+// DELETE /api/v1/orgs/{id}/domains/{domain}
+func (h *OrgHandler) RemoveDomain(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can manage domains")
+		return
+	}
+
+	domain := strings.ToLower(chi.URLParam(r, "domain"))
+	if err := h.domains.Remove(r.Context(), orgID, domain); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Domain not found")
+			return
+		}
+		slog.Error("Failed to remove organization domain", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to remove domain")
+		return
+	}
+
+	slog.Info("Organization domain removed", "org_id", orgID, "domain", domain, "by", operator(r))
+	response.NoContent(w)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+func TestOrgHandler_AddDomain(t *testing.T) {
+	f := newOrgFixture(t)
+	path := "/orgs/" + f.org.ID.String() + "/domains"
+
+	tests := []struct {
+		name       string
+		caller     uuid.UUID
+		body       string
+		wantStatus int
+	}{
+		{name: "admin", caller: f.admin, body: `{"domain": "acme.com"}`, wantStatus: http.StatusForbidden},
+		{name: "invalid", caller: f.owner, body: `{"domain": "acme"}`, wantStatus: http.StatusBadRequest},
+		{name: "owner", caller: f.owner, body: `{"domain": " Acme.com "}`, wantStatus: http.StatusCreated},
+		{name: "claimed twice", caller: f.owner, body: `{"domain": "acme.com"}`, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.router.do(t, tt.caller, http.MethodPost, path, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("AddDomain() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var domain models.OrgDomain
+			decodeBody(t, rec, &domain)
+			if domain.Domain != "acme.com" || domain.RecordName != "_content-analyzer.acme.com" || domain.Verified() {
+				t.Errorf("AddDomain() = %+v, want unverified acme.com", domain)
+			}
+		})
+	}
+
+	// Admins can see the claims, members can't
+	if rec := f.router.do(t, f.member, http.MethodGet, path, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("ListDomains() as member status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec := f.router.do(t, f.admin, http.MethodGet, path, nil)
+	var list response.ListResponse[models.OrgDomain]
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 1 || list.Data[0].Domain != "acme.com" {
+		t.Errorf("ListDomains() = %+v, want acme.com", list.Data)
+	}
+}
+
+func TestOrgHandler_VerifyDomain(t *testing.T) {
+	ctx := context.Background()
+	f := newOrgFixture(t)
+	path := "/orgs/" + f.org.ID.String() + "/domains/acme.com"
+
+	claim, err := f.domains.Add(ctx, f.org.ID, "acme.com")
+	if err != nil {
+		t.Fatalf("failed to seed domain: %v", err)
+	}
+	records := map[string][]string{}
+	f.orgHandler.lookupTXT = func(_ context.Context, name string) ([]string, error) {
+		if values, ok := records[name]; ok {
+			return values, nil
+		}
+		return nil, errors.New("no such host")
+	}
+
+	if rec := f.router.do(t, f.admin, http.MethodPost, path+"/verify", nil); rec.Code != http.StatusForbidden {
+		t.Errorf("VerifyDomain() as admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := f.router.do(t, f.owner, http.MethodPost, "/orgs/"+f.org.ID.String()+"/domains/globex.com/verify", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("VerifyDomain() unclaimed status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Without the record, or with someone else's token, it stays unverified
+	if rec := f.router.do(t, f.owner, http.MethodPost, path+"/verify", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("VerifyDomain() without a record status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	records[claim.RecordName] = []string{"v=spf1 -all", "content-analyzer-verification=someone-else"}
+	if rec := f.router.do(t, f.owner, http.MethodPost, path+"/verify", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("VerifyDomain() with a wrong token status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if ok, _ := f.domains.IsVerified(ctx, f.org.ID, "acme.com"); ok {
+		t.Fatal("domain verified without its record")
+	}
+
+	records[claim.RecordName] = append(records[claim.RecordName], claim.RecordValue)
+	rec := f.router.do(t, f.owner, http.MethodPost, path+"/verify", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("VerifyDomain() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var verified models.OrgDomain
+	decodeBody(t, rec, &verified)
+	if !verified.Verified() {
+		t.Errorf("VerifyDomain() = %+v, want verified", verified)
+	}
+
+	// Only one organization can verify a domain
+	other, err := f.orgs.Create(ctx, "Globex", f.member)
+	if err != nil {
+		t.Fatalf("failed to seed organization: %v", err)
+	}
+	if _, err := f.domains.Add(ctx, other.ID, "acme.com"); err != nil {
+		t.Fatalf("failed to seed domain: %v", err)
+	}
+	otherClaim, _ := f.domains.Get(ctx, other.ID, "acme.com")
+	records[otherClaim.RecordName] = append(records[otherClaim.RecordName], otherClaim.RecordValue)
+	rec = f.router.do(t, f.member, http.MethodPost, "/orgs/"+other.ID.String()+"/domains/acme.com/verify", nil)
+	if rec.Code != http.StatusConflict {
+		t.Errorf("VerifyDomain() by a second organization status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Removing the claim stops provisioning under it
+	if rec := f.router.do(t, f.owner, http.MethodDelete, path, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("RemoveDomain() status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if ok, _ := f.domains.IsVerified(ctx, f.org.ID, "acme.com"); ok {
+		t.Error("removed domain is still verified")
+	}
+	if rec := f.router.do(t, f.owner, http.MethodDelete, path, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("RemoveDomain() twice status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
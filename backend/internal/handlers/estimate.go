@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// EstimateHandler previews what analyzing a text would cost, so users and
+// UIs can check before submitting it. Nothing is analyzed or stored.
+type EstimateHandler struct {
+	estimator AnalysisEstimator
+	profiles  ProfileGetter
+}
+
+// NewEstimateHandler creates a new estimate handler
+func NewEstimateHandler(estimator AnalysisEstimator, profiles ProfileGetter) *EstimateHandler {
+	return &EstimateHandler{estimator: estimator, profiles: profiles}
+}
+
+// EstimateRequest is the text to estimate, with the options a submission
+// of it would use
+type EstimateRequest struct {
+	Content      string     `json:"content"`
+	ProfileID    *uuid.UUID `json:"profile_id"`
+	Instructions string     `json:"instructions"`
+}
+
+// Estimate returns the model calls, tokens, cost and latency an analysis
+// of the content would take, without running it
+// POST /api/v1/analyze/estimate
+func (h *EstimateHandler) Estimate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req EstimateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	content, errs := validateContent(req.Content)
+	if errs != nil {
+		response.ValidationError(w, errs)
+		return
+	}
+	focus, errs := validateInstructions(req.Instructions)
+	if errs != nil {
+		response.ValidationError(w, errs)
+		return
+	}
+
+	var profile *models.AnalysisProfile
+	if req.ProfileID != nil {
+		profile, err = h.profiles.GetByID(r.Context(), userID, *req.ProfileID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				response.ValidationError(w, map[string]string{"profile_id": "Unknown analysis profile"})
+				return
+			}
+			slog.Error("Failed to load analysis profile", "profile_id", *req.ProfileID, "error", err)
+			response.InternalServerError(w, "Failed to estimate analysis")
+			return
+		}
+	}
+
+	submission := &models.Submission{UserID: userID, Content: content, Instructions: focus, ProfileID: req.ProfileID}
+	estimate, err := h.estimator.Estimate(r.Context(), submission, profile)
+	if err != nil {
+		slog.Error("Failed to estimate analysis", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to estimate analysis")
+		return
+	}
+
+	response.Success(w, estimate)
+}
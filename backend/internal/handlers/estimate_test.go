@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+)
+
+// fakeEstimator records what it was asked to estimate
+type fakeEstimator struct {
+	submission *models.Submission
+	profile    *models.AnalysisProfile
+}
+
+func (e *fakeEstimator) Estimate(ctx context.Context, submission *models.Submission, profile *models.AnalysisProfile) (*analyzer.Estimate, error) {
+	e.submission, e.profile = submission, profile
+	return &analyzer.Estimate{Model: "test-model", ContentTokens: 3, PromptTokens: 120, OutputTokens: 250, CostMicros: 42, LatencyMs: 3600}, nil
+}
+
+func TestEstimateHandler_Estimate(t *testing.T) {
+	profiles := memstore.NewProfileStore()
+	userID := uuid.New()
+	profile, err := profiles.Create(context.Background(), &models.AnalysisProfile{
+		UserID:  &userID,
+		Name:    "Summary only",
+		Modules: []models.AnalysisModule{models.ModuleSummary},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	estimator := &fakeEstimator{}
+	h := NewEstimateHandler(estimator, profiles)
+
+	req := newJSONRequest(t, http.MethodPost, "/api/v1/analyze/estimate", map[string]interface{}{
+		"content":      "  Some text.  ",
+		"profile_id":   profile.ID,
+		"instructions": "Focus on tone",
+	})
+	rec := httptest.NewRecorder()
+	h.Estimate(rec, withUser(req, userID))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp analyzer.Estimate
+	decodeBody(t, rec, &resp)
+	if resp.Model != "test-model" || resp.CostMicros != 42 || resp.LatencyMs != 3600 {
+		t.Errorf("response = %+v", resp)
+	}
+	if s := estimator.submission; s.UserID != userID || s.Content != "Some text." || s.Instructions == nil || *s.Instructions != "Focus on tone" {
+		t.Errorf("estimated submission = %+v", s)
+	}
+	if estimator.profile == nil || estimator.profile.ID != profile.ID {
+		t.Errorf("estimated profile = %+v, want %s", estimator.profile, profile.ID)
+	}
+}
+
+func TestEstimateHandler_Estimate_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  interface{}
+		field string
+	}{
+		{"empty content", map[string]string{"content": " "}, "content"},
+		{"content too long", map[string]string{"content": strings.Repeat("a", MaxContentLength+1)}, "content"},
+		{"unknown profile", map[string]interface{}{"content": "Text", "profile_id": uuid.New()}, "profile_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			estimator := &fakeEstimator{}
+			h := NewEstimateHandler(estimator, memstore.NewProfileStore())
+
+			rec := httptest.NewRecorder()
+			h.Estimate(rec, withUser(newJSONRequest(t, http.MethodPost, "/api/v1/analyze/estimate", tt.body), uuid.New()))
+
+			if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), tt.field) {
+				t.Errorf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			if estimator.submission != nil {
+				t.Error("an invalid request was estimated")
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/feeds"
+)
+
+const (
+	defaultDigestSize = 20
+	maxDigestSize     = 100
+	maxFeedURLLength  = 2048
+)
+
+// FeedHandler manages monitored RSS and Atom feeds. A background job polls
+// them and submits new items for analysis.
+type FeedHandler struct {
+	store FeedStorer
+	jobs  JobEnqueuer
+}
+
+// NewFeedHandler creates a new feed handler
+func NewFeedHandler(store FeedStorer, jobs JobEnqueuer) *FeedHandler {
+	return &FeedHandler{store: store, jobs: jobs}
+}
+
+// FeedRequest registers a feed
+type FeedRequest struct {
+	URL string `json:"url"`
+}
+
+// List returns the user's feeds
+// GET /api/v1/feeds
+func (h *FeedHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	list, err := h.store.List(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list feeds", "error", err)
+		response.InternalServerError(w, "Failed to list feeds")
+		return
+	}
+
+	response.Success(w, response.Complete(list))
+}
+
+// Create registers a feed and polls it right away
+// POST /api/v1/feeds
+func (h *FeedHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req FeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	feedURL := strings.TrimSpace(req.URL)
+	if u, err := url.Parse(feedURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(feedURL) > maxFeedURLLength {
+		response.ValidationError(w, map[string]string{"url": "Must be an http or https URL"})
+		return
+	}
+
+	feed, err := h.store.Create(r.Context(), userID, feedURL)
+	if err != nil {
+		h.writeError(w, err, "Failed to add feed")
+		return
+	}
+
+	// The scheduled poll picks the feed up anyway, so a failure only
+	// delays the first items
+	if _, err := h.jobs.Enqueue(r.Context(), feeds.JobType, feeds.Payload{FeedID: &feed.ID}); err != nil {
+		slog.Warn("Failed to enqueue first poll of feed", "feed_id", feed.ID, "error", err)
+	}
+
+	response.Created(w, feed)
+}
+
+// Get returns one of the user's feeds
+// GET /api/v1/feeds/{id}
+func (h *FeedHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.feedID(w, r)
+	if !ok {
+		return
+	}
+
+	feed, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to load feed")
+		return
+	}
+
+	response.Success(w, feed)
+}
+
+// Delete stops monitoring a feed. Submissions made from its items are kept.
+// DELETE /api/v1/feeds/{id}
+func (h *FeedHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.feedID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), userID, id); err != nil {
+		h.writeError(w, err, "Failed to delete feed")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// Digest summarizes the latest submitted items of a feed with the
+// sentiment and summary of each
+// GET /api/v1/feeds/{id}/digest?limit=
+func (h *FeedHandler) Digest(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.feedID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultDigestSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDigestSize {
+			response.BadRequest(w, fmt.Sprintf("limit must be between 1 and %d", maxDigestSize))
+			return
+		}
+		limit = n
+	}
+
+	feed, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to load feed")
+		return
+	}
+
+	items, err := h.store.DigestItems(r.Context(), userID, id, limit)
+	if err != nil {
+		h.writeError(w, err, "Failed to load feed digest")
+		return
+	}
+
+	response.Success(w, models.NewFeedDigest(*feed, items))
+}
+
+// feedID reads the current user and the feed ID from the URL, writing an
+// error response and returning false if either is missing
+func (h *FeedHandler) feedID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid feed ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
+
+// writeError maps store errors to responses
+func (h *FeedHandler) writeError(w http.ResponseWriter, err error, message string) {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		response.NotFound(w, "Feed not found")
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		response.Conflict(w, "You already monitor this feed")
+	case errors.Is(err, models.ErrFeedLimit):
+		response.Conflict(w, "Feed limit reached; delete a feed first")
+	default:
+		slog.Error(message, "error", err)
+		response.InternalServerError(w, message)
+	}
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/feeds"
+)
+
+// newFeedRouter mounts the handler so chi URL parameters resolve
+func newFeedRouter(handler *FeedHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/feeds", handler.List)
+	r.Post("/feeds", handler.Create)
+	r.Get("/feeds/{id}", handler.Get)
+	r.Delete("/feeds/{id}", handler.Delete)
+	r.Get("/feeds/{id}/digest", handler.Digest)
+	return r
+}
+
+func TestFeedHandler_Create(t *testing.T) {
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		setup      func(store *memstore.FeedStore)
+		body       interface{}
+		wantStatus int
+	}{
+		{"adds a feed", nil, FeedRequest{URL: " https://example.com/feed.xml "}, http.StatusCreated},
+		{"not http", nil, FeedRequest{URL: "ftp://example.com/feed"}, http.StatusUnprocessableEntity},
+		{"no host", nil, FeedRequest{URL: "https:///feed"}, http.StatusUnprocessableEntity},
+		{"blank", nil, FeedRequest{URL: ""}, http.StatusUnprocessableEntity},
+		{"too long", nil, FeedRequest{URL: "https://example.com/" + strings.Repeat("a", maxFeedURLLength)}, http.StatusUnprocessableEntity},
+		{"malformed body", nil, "not json", http.StatusBadRequest},
+		{
+			"already monitored",
+			func(store *memstore.FeedStore) {
+				store.Create(context.Background(), userID, "https://example.com/feed.xml")
+			},
+			FeedRequest{URL: "https://example.com/feed.xml"},
+			http.StatusConflict,
+		},
+		{
+			"limit reached",
+			func(store *memstore.FeedStore) {
+				for i := range models.MaxFeedsPerUser {
+					store.Create(context.Background(), userID, fmt.Sprintf("https://example.com/%d.xml", i))
+				}
+			},
+			FeedRequest{URL: "https://example.com/feed.xml"},
+			http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewFeedStore(memstore.NewSubmissionStore())
+			if tt.setup != nil {
+				tt.setup(store)
+			}
+			jobs := &fakeQueue{}
+
+			rec := httptest.NewRecorder()
+			newFeedRouter(NewFeedHandler(store, jobs)).ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/feeds", tt.body), userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var feed models.Feed
+			decodeBody(t, rec, &feed)
+			if feed.URL != "https://example.com/feed.xml" || feed.UserID != userID {
+				t.Errorf("feed = %+v", feed)
+			}
+
+			// The new feed is polled right away
+			if len(jobs.jobs) != 1 || jobs.jobs[0].Type != feeds.JobType {
+				t.Fatalf("jobs = %+v, want one %s job", jobs.jobs, feeds.JobType)
+			}
+			var payload feeds.Payload
+			if err := jobs.jobs[0].Decode(&payload); err != nil || payload.FeedID == nil || *payload.FeedID != feed.ID {
+				t.Errorf("payload = %+v, %v", payload, err)
+			}
+		})
+	}
+}
+
+func TestFeedHandler_GetListDelete(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	store := memstore.NewFeedStore(memstore.NewSubmissionStore())
+	feed, _ := store.Create(ctx, userID, "https://example.com/feed.xml")
+	router := newFeedRouter(NewFeedHandler(store, &fakeQueue{}))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		user       uuid.UUID
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/feeds", userID, http.StatusOK},
+		{"get", http.MethodGet, "/feeds/" + feed.ID.String(), userID, http.StatusOK},
+		{"other user's feed", http.MethodGet, "/feeds/" + feed.ID.String(), uuid.New(), http.StatusNotFound},
+		{"invalid ID", http.MethodGet, "/feeds/nope", userID, http.StatusBadRequest},
+		{"other user can't delete", http.MethodDelete, "/feeds/" + feed.ID.String(), uuid.New(), http.StatusNotFound},
+		{"delete", http.MethodDelete, "/feeds/" + feed.ID.String(), userID, http.StatusNoContent},
+		{"gone", http.MethodGet, "/feeds/" + feed.ID.String(), userID, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(tt.method, tt.target, nil), tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestFeedHandler_Digest(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	store := memstore.NewFeedStore(submissions)
+	feed, _ := store.Create(ctx, userID, "https://example.com/feed.xml")
+
+	// One analyzed item, one still queued and one recorded but skipped
+	for i, analyzed := range []bool{true, false} {
+		title := fmt.Sprintf("Story %d", i)
+		item, err := store.AddItem(ctx, &models.FeedItem{FeedID: feed.ID, GUID: title, Title: &title})
+		if err != nil {
+			t.Fatal(err)
+		}
+		submission, _ := submissions.Create(ctx, userID, title, nil, nil, nil, models.StatusQueued)
+		store.AttachSubmission(ctx, item.ID, submission.ID)
+		if analyzed {
+			score := 0.6
+			submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing)
+			submissions.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Sentiment: "positive", SentimentScore: &score, Summary: "Good news."})
+		}
+	}
+	store.AddItem(ctx, &models.FeedItem{FeedID: feed.ID, GUID: "skipped"})
+
+	router := newFeedRouter(NewFeedHandler(store, &fakeQueue{}))
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		target     string
+		wantStatus int
+	}{
+		{"digest", userID, "/feeds/" + feed.ID.String() + "/digest", http.StatusOK},
+		{"other user's feed", uuid.New(), "/feeds/" + feed.ID.String() + "/digest", http.StatusNotFound},
+		{"limit too high", userID, "/feeds/" + feed.ID.String() + "/digest?limit=101", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, tt.target, nil), tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var digest models.FeedDigest
+			decodeBody(t, rec, &digest)
+			if len(digest.Items) != 2 || digest.Analyzed != 1 || digest.Pending != 1 || digest.Sentiment["positive"] != 1 {
+				t.Fatalf("digest = %+v", digest)
+			}
+			if digest.AverageSentimentScore == nil || *digest.AverageSentimentScore != 0.6 {
+				t.Errorf("AverageSentimentScore = %v, want 0.6", digest.AverageSentimentScore)
+			}
+			for _, item := range digest.Items {
+				if item.Sentiment != nil && (item.Summary == nil || *item.Summary != "Good news.") {
+					t.Errorf("analyzed item = %+v, want its summary", item)
+				}
+			}
+		})
+	}
+}
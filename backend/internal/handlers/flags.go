@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// FeatureFlagHandler lets operators manage feature flags and users see
+// which flags are on for them
+type FeatureFlagHandler struct {
+	store FeatureFlagStorer
+	flags FlagEvaluator
+}
+
+// NewFeatureFlagHandler creates a new feature flag handler
+func NewFeatureFlagHandler(store FeatureFlagStorer, flags FlagEvaluator) *FeatureFlagHandler {
+	return &FeatureFlagHandler{store: store, flags: flags}
+}
+
+// FeatureFlagRequest creates or replaces a flag
+type FeatureFlagRequest struct {
+	Description    string      `json:"description"`
+	Enabled        bool        `json:"enabled"`
+	RolloutPercent int         `json:"rollout_percent"`
+	UserIDs        []uuid.UUID `json:"user_ids"`
+}
+
+// FeatureFlagPatchRequest changes some of a flag's settings
+type FeatureFlagPatchRequest struct {
+	Description    *string      `json:"description"`
+	Enabled        *bool        `json:"enabled"`
+	RolloutPercent *int         `json:"rollout_percent"`
+	UserIDs        *[]uuid.UUID `json:"user_ids"`
+}
+
+// EnabledFlagsResponse lists the flags that are on for the current user
+type EnabledFlagsResponse struct {
+	Flags []string `json:"flags"`
+}
+
+// List returns every feature flag
+// GET /api/v1/admin/flags
+func (h *FeatureFlagHandler) List(w http.ResponseWriter, r *http.Request) {
+	flags, err := h.store.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list feature flags", "error", err)
+		response.InternalServerError(w, "Failed to list feature flags")
+		return
+	}
+	response.Success(w, response.Complete(flags))
+}
+
+// Get returns a single feature flag
+// GET /api/v1/admin/flags/{key}
+func (h *FeatureFlagHandler) Get(w http.ResponseWriter, r *http.Request) {
+	flag, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	response.Success(w, flag)
+}
+
+// Put creates or replaces a feature flag
+// PUT /api/v1/admin/flags/{key}
+func (h *FeatureFlagHandler) Put(w http.ResponseWriter, r *http.Request) {
+	var req FeatureFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	h.save(w, r, &models.FeatureFlag{
+		Key:            chi.URLParam(r, "key"),
+		Description:    strings.TrimSpace(req.Description),
+		Enabled:        req.Enabled,
+		RolloutPercent: req.RolloutPercent,
+		UserIDs:        req.UserIDs,
+	})
+}
+
+// Patch changes the given settings of an existing flag, e.g. to switch it
+// off or widen its rollout
+// PATCH /api/v1/admin/flags/{key}
+func (h *FeatureFlagHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	var req FeatureFlagPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	flag, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+
+	if req.Description != nil {
+		flag.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Enabled != nil {
+		flag.Enabled = *req.Enabled
+	}
+	if req.RolloutPercent != nil {
+		flag.RolloutPercent = *req.RolloutPercent
+	}
+	if req.UserIDs != nil {
+		flag.UserIDs = *req.UserIDs
+	}
+
+	h.save(w, r, flag)
+}
+
+// Delete removes a feature flag, turning it off for everyone
+// DELETE /api/v1/admin/flags/{key}
+func (h *FeatureFlagHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+
+	if err := h.store.Delete(r.Context(), key); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Feature flag not found")
+			return
+		}
+		slog.Error("Failed to delete feature flag", "flag", key, "error", err)
+		response.InternalServerError(w, "Failed to delete feature flag")
+		return
+	}
+
+	h.flags.Invalidate()
+	slog.Warn("Feature flag deleted", "flag", key, "by", operator(r))
+	response.NoContent(w)
+}
+
+// Enabled lists the flags that are on for the current user, so clients can
+// show or hide features
+// GET /api/v1/me/flags
+func (h *FeatureFlagHandler) Enabled(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	response.Success(w, EnabledFlagsResponse{Flags: h.flags.EnabledKeys(r.Context(), userID)})
+}
+
+// load reads the flag named in the URL, writing the error response if it
+// can't
+func (h *FeatureFlagHandler) load(w http.ResponseWriter, r *http.Request) (*models.FeatureFlag, bool) {
+	key := chi.URLParam(r, "key")
+
+	flag, err := h.store.Get(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Feature flag not found")
+			return nil, false
+		}
+		slog.Error("Failed to get feature flag", "flag", key, "error", err)
+		response.InternalServerError(w, "Failed to get feature flag")
+		return nil, false
+	}
+
+	return flag, true
+}
+
+// save validates and stores flag, then applies it to this instance at once
+func (h *FeatureFlagHandler) save(w http.ResponseWriter, r *http.Request, flag *models.FeatureFlag) {
+	if err := flag.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	if flag.UserIDs == nil {
+		flag.UserIDs = []uuid.UUID{}
+	}
+	flag.UpdatedBy = operator(r)
+
+	stored, err := h.store.Upsert(r.Context(), flag)
+	if err != nil {
+		slog.Error("Failed to save feature flag", "flag", flag.Key, "error", err)
+		response.InternalServerError(w, "Failed to save feature flag")
+		return
+	}
+
+	h.flags.Invalidate()
+
+	// Logged at warn: a flag changes what live users get without a deploy
+	slog.Warn("Feature flag updated",
+		"flag", stored.Key,
+		"enabled", stored.Enabled,
+		"rollout_percent", stored.RolloutPercent,
+		"users", len(stored.UserIDs),
+		"by", stored.UpdatedBy,
+	)
+	response.Success(w, stored)
+}
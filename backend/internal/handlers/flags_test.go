@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/flags"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// newFlagRouter mounts the flag endpoints the way the server does
+func newFlagRouter(handler *FeatureFlagHandler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/admin/flags", handler.List)
+	r.Get("/admin/flags/{key}", handler.Get)
+	r.Put("/admin/flags/{key}", handler.Put)
+	r.Patch("/admin/flags/{key}", handler.Patch)
+	r.Delete("/admin/flags/{key}", handler.Delete)
+	r.Get("/me/flags", handler.Enabled)
+	return r
+}
+
+func TestFeatureFlagHandler_Put(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name:       "valid flag",
+			key:        "analyzer.v2",
+			body:       FeatureFlagRequest{Enabled: true, RolloutPercent: 10},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid key",
+			key:        "Analyzer_V2",
+			body:       FeatureFlagRequest{Enabled: true},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "percent out of range",
+			key:        "analyzer.v2",
+			body:       FeatureFlagRequest{Enabled: true, RolloutPercent: 150},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "malformed body",
+			key:        "analyzer.v2",
+			body:       "{",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewFeatureFlagStore()
+			router := newFlagRouter(NewFeatureFlagHandler(store, flags.New(store)))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newJSONRequest(t, http.MethodPut, "/admin/flags/"+tt.key, tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Put() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got models.FeatureFlag
+			decodeBody(t, rec, &got)
+			if got.Key != tt.key || !got.Enabled || got.RolloutPercent != 10 {
+				t.Errorf("Put() = %+v, want enabled %s at 10%%", got, tt.key)
+			}
+		})
+	}
+}
+
+func TestFeatureFlagHandler_PatchAppliesImmediately(t *testing.T) {
+	user := uuid.New()
+	store := memstore.NewFeatureFlagStore()
+	featureFlags := flags.New(store)
+	router := newFlagRouter(NewFeatureFlagHandler(store, featureFlags))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, newJSONRequest(t, http.MethodPut, "/admin/flags/analyzer.v2", FeatureFlagRequest{
+		Enabled: true,
+		UserIDs: []uuid.UUID{user},
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Put() status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/me/flags", nil), user))
+	var enabled EnabledFlagsResponse
+	decodeBody(t, rec, &enabled)
+	if !slices.Equal(enabled.Flags, []string{"analyzer.v2"}) {
+		t.Fatalf("Enabled() flags = %v, want [analyzer.v2]", enabled.Flags)
+	}
+
+	// Switching the flag off must not wait for the refresh interval
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, newJSONRequest(t, http.MethodPatch, "/admin/flags/analyzer.v2", `{"enabled": false}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Patch() status = %d (body: %s)", rec.Code, rec.Body.String())
+	}
+
+	var patched models.FeatureFlag
+	decodeBody(t, rec, &patched)
+	if patched.Enabled || len(patched.UserIDs) != 1 {
+		t.Errorf("Patch() = %+v, want disabled with the user list kept", patched)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/me/flags", nil), user))
+	decodeBody(t, rec, &enabled)
+	if len(enabled.Flags) != 0 {
+		t.Errorf("Enabled() flags after disabling = %v, want none", enabled.Flags)
+	}
+}
+
+func TestFeatureFlagHandler_NotFound(t *testing.T) {
+	store := memstore.NewFeatureFlagStore()
+	router := newFlagRouter(NewFeatureFlagHandler(store, flags.New(store)))
+
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/admin/flags/missing", nil),
+		newJSONRequest(t, http.MethodPatch, "/admin/flags/missing", `{"enabled": true}`),
+		httptest.NewRequest(http.MethodDelete, "/admin/flags/missing", nil),
+	}
+
+	for _, req := range requests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s %s status = %d, want %d", req.Method, req.URL.Path, rec.Code, http.StatusNotFound)
+		}
+	}
+}
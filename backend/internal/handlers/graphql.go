@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/graphql"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+const (
+	// maxGraphQLBytes bounds the body of a GraphQL request
+	maxGraphQLBytes = 1 << 20
+	// maxGraphQLComplexity bounds the fields a query may resolve, counting
+	// each of a page's submissions
+	maxGraphQLComplexity = 5000
+)
+
+// GraphQLHandler serves read-only GraphQL queries over the signed-in user,
+// their submissions and the submissions' analyses and labels, as described
+// by internal/graphql/schema.graphqls. Fields are named in camelCase and
+// otherwise match the v2 REST resources.
+type GraphQLHandler struct {
+	resolver *graphql.Resolver
+	server   *handler.Server
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(users UserStorer, submissions SubmissionStorer) *GraphQLHandler {
+	resolver := graphql.NewResolver(users, submissions)
+
+	server := handler.New(resolver.Schema())
+	// graphql-response+json by default answers requests that can't be
+	// parsed or validated with 400
+	server.AddTransport(transport.GET{UseGrapQLResponseJsonByDefault: true})
+	server.AddTransport(transport.POST{UseGrapQLResponseJsonByDefault: true})
+	server.Use(extension.Introspection{})
+	server.Use(extension.FixedComplexityLimit(maxGraphQLComplexity))
+
+	return &GraphQLHandler{resolver: resolver, server: server}
+}
+
+// Serve executes a query sent as JSON in a POST body or, for GET, in the
+// query, operationName and variables parameters. Queries that can't be
+// parsed or validated get 400; errors resolving fields are returned with
+// the rest of the data. The analyses and labels of the submissions a query
+// selects are each loaded in one batch.
+// POST /graphql
+// GET /graphql?query=
+func (h *GraphQLHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGraphQLBytes)
+	h.server.ServeHTTP(w, r.WithContext(h.resolver.WithLoaders(r.Context(), userID)))
+}
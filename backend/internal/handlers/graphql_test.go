@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// countingSubmissionStore counts the batched analysis and label loads
+type countingSubmissionStore struct {
+	*memstore.SubmissionStore
+	analysisLoads int
+	labelLoads    int
+}
+
+func (s *countingSubmissionStore) GetAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*models.Analysis, error) {
+	s.analysisLoads++
+	return s.SubmissionStore.GetAnalyses(ctx, userID, submissionIDs)
+}
+
+func (s *countingSubmissionStore) GetLabels(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID][]models.Classification, error) {
+	s.labelLoads++
+	return s.SubmissionStore.GetLabels(ctx, userID, submissionIDs)
+}
+
+func TestGraphQLHandler_Serve(t *testing.T) {
+	ctx := context.Background()
+	users := memstore.NewUserStore()
+	store := &countingSubmissionStore{SubmissionStore: memstore.NewSubmissionStore()}
+	h := NewGraphQLHandler(users, store)
+
+	user, err := users.Create(ctx, "graphql@example.com", "password123")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	var analyzed *models.Submission
+	for i := 0; i < 3; i++ {
+		submission, _ := store.Create(ctx, user.ID, "content", nil, nil, nil, models.StatusQueued)
+		analyzed = submission
+	}
+	store.UpdateStatus(ctx, analyzed.ID, models.StatusProcessing)
+	if err := store.SaveAnalysis(ctx, &models.Analysis{
+		SubmissionID: analyzed.ID,
+		Sentiment:    "positive",
+		Labels:       []models.Classification{{Label: "news", Confidence: 0.9}},
+	}); err != nil {
+		t.Fatalf("SaveAnalysis() error = %v", err)
+	}
+	// Someone else's submission is never returned
+	other, _ := store.Create(ctx, uuid.New(), "content", nil, nil, nil, models.StatusQueued)
+
+	post := func(query string, variables map[string]interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := map[string]interface{}{"query": query, "variables": variables}
+		h.Serve(rec, withUser(newJSONRequest(t, http.MethodPost, "/graphql", body), user.ID))
+		return rec
+	}
+
+	rec := post(`query Page($limit: Int) {
+		me { email }
+		submissions(limit: $limit) {
+			total
+			nodes { id analysis { sentiment { label } } labels { label } }
+		}
+	}`, map[string]interface{}{"limit": 10})
+	var page struct {
+		Data struct {
+			Me          struct{ Email string }
+			Submissions struct {
+				Total int
+				Nodes []struct {
+					ID       string
+					Analysis *struct {
+						Sentiment struct{ Label string }
+					}
+					Labels []struct{ Label string }
+				}
+			}
+		}
+		Errors []struct{ Message string }
+	}
+	decodeBody(t, rec, &page)
+	if rec.Code != http.StatusOK || len(page.Errors) > 0 {
+		t.Fatalf("Serve() = %d %s", rec.Code, rec.Body.String())
+	}
+	if page.Data.Me.Email != user.Email {
+		t.Errorf("me.email = %q, want %q", page.Data.Me.Email, user.Email)
+	}
+	if page.Data.Submissions.Total != 3 || len(page.Data.Submissions.Nodes) != 3 {
+		t.Fatalf("submissions = %+v, want 3 of 3", page.Data.Submissions)
+	}
+	for _, node := range page.Data.Submissions.Nodes {
+		if node.ID == analyzed.ID.String() {
+			if node.Analysis == nil || node.Analysis.Sentiment.Label != "positive" {
+				t.Errorf("analyzed submission's analysis = %+v, want positive", node.Analysis)
+			}
+			if len(node.Labels) != 1 || node.Labels[0].Label != "news" {
+				t.Errorf("analyzed submission's labels = %+v, want news", node.Labels)
+			}
+		} else if node.Analysis != nil || len(node.Labels) != 0 {
+			t.Errorf("submission %s analysis = %+v, labels = %+v, want null and none", node.ID, node.Analysis, node.Labels)
+		}
+	}
+	if store.analysisLoads != 1 || store.labelLoads != 1 {
+		t.Errorf("analyses and labels were loaded in %d and %d calls, want 1 each", store.analysisLoads, store.labelLoads)
+	}
+
+	// A submission that isn't the user's is null
+	rec = post(`query($id: ID!) { submission(id: $id) { id } }`, map[string]interface{}{"id": other.ID.String()})
+	if body := rec.Body.String(); rec.Code != http.StatusOK || body != `{"data":{"submission":null}}` {
+		t.Errorf("submission(other) = %d %s, want null", rec.Code, body)
+	}
+
+	// Invalid queries are rejected as a whole
+	for _, query := range []string{`{ submissions { nodes { title } } }`, `{ submission { id } }`, `mutation { me { id } }`, `{ me `} {
+		if rec := post(query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("Serve(%q) status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	// GET carries the query in the URL
+	rec = httptest.NewRecorder()
+	target := "/graphql?query=" + url.QueryEscape(`{ me { id } }`)
+	h.Serve(rec, withUser(httptest.NewRequest(http.MethodGet, target, nil), user.ID))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET Serve() = %d %s", rec.Code, rec.Body.String())
+	}
+}
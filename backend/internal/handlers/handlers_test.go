@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeNotifier records the tokens that would have been emailed
+type fakeNotifier struct {
+	mu            sync.Mutex
+	verifications map[string]string
+	resets        map[string]string
+	emailChanges  map[string]string
+	assignments   map[string]string
+	quarantines   map[string]string
+	// newLogins holds the code of each new login email, or "" for notices
+	newLogins map[string]string
+	err       error
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{
+		verifications: make(map[string]string),
+		resets:        make(map[string]string),
+		emailChanges:  make(map[string]string),
+		assignments:   make(map[string]string),
+		quarantines:   make(map[string]string),
+		newLogins:     make(map[string]string),
+	}
+}
+
+func (n *fakeNotifier) SendVerification(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.verifications[to] = token
+	return n.err
+}
+
+func (n *fakeNotifier) SendPasswordReset(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.resets[to] = token
+	return n.err
+}
+
+func (n *fakeNotifier) SendEmailChange(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.emailChanges[to] = token
+	return n.err
+}
+
+func (n *fakeNotifier) SendAssignment(ctx context.Context, to, assignedBy string, dueAt *time.Time) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.assignments[to] = assignedBy
+	return n.err
+}
+
+func (n *fakeNotifier) SendQuarantine(ctx context.Context, to, outcome string, submissionID uuid.UUID, createdAt time.Time, reason string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.quarantines[to] = outcome
+	return n.err
+}
+
+func (n *fakeNotifier) SendNewLogin(ctx context.Context, to string, fp models.LoginFingerprint, ipAddress string, at time.Time, code string, expiresIn time.Duration) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.newLogins[to] = code
+	return n.err
+}
+
+// fakeSessions records revocations
+type fakeSessions struct {
+	mu      sync.Mutex
+	revoked []uuid.UUID
+	err     error
+}
+
+func (s *fakeSessions) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.revoked = append(s.revoked, userID)
+	return nil
+}
+
+// fakeQueue records enqueued jobs and cancellation requests
+type fakeQueue struct {
+	mu       sync.Mutex
+	jobs     []*queue.Job
+	canceled []string
+	err      error
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error) {
+	return q.EnqueuePriority(ctx, queue.PriorityDefault, jobType, payload)
+}
+
+func (q *fakeQueue) EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job := &queue.Job{ID: uuid.NewString(), Type: jobType, Payload: data, Priority: priority}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+func (q *fakeQueue) RequestCancel(ctx context.Context, key string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.canceled = append(q.canceled, key)
+	return nil
+}
+
+// newJSONRequest builds a request with a JSON body
+func newJSONRequest(t *testing.T, method, target string, body interface{}) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if s, ok := body.(string); ok {
+			buf.WriteString(s)
+		} else if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+	}
+
+	req := httptest.NewRequest(method, target, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// withUser attaches an authenticated user to the request as auth.Middleware would
+func withUser(r *http.Request, userID uuid.UUID) *http.Request {
+	ctx := context.WithValue(r.Context(), auth.UserIDKey, userID)
+	ctx = context.WithValue(ctx, auth.UserEmailKey, "user@example.com")
+	return r.WithContext(ctx)
+}
+
+// decodeBody unmarshals a recorded JSON response
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+}
+
+// testRouter routes requests to the handlers under test, with URL
+// parameters, as a signed-in user
+type testRouter struct {
+	*chi.Mux
+}
+
+func newTestRouter() testRouter {
+	return testRouter{chi.NewRouter()}
+}
+
+// do sends a request as userID
+func (r testRouter) do(t *testing.T, userID uuid.UUID, method, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, withUser(newJSONRequest(t, method, target, body), userID))
+	return rec
+}
+
+// seedUser creates an account with testPassword
+func seedUser(t *testing.T, users *memstore.UserStore, email string) *models.User {
+	t.Helper()
+	user, err := users.Create(context.Background(), email, testPassword)
+	if err != nil {
+		t.Fatalf("failed to seed user %s: %v", email, err)
+	}
+	return user
+}
+
+// seedOrg creates an organization owned by owner, with members in their
+// roles
+func seedOrg(t *testing.T, orgs *memstore.OrganizationStore, owner uuid.UUID, members map[uuid.UUID]models.OrgRole) *models.Organization {
+	t.Helper()
+	ctx := context.Background()
+
+	org, err := orgs.Create(ctx, "Acme", owner)
+	if err != nil {
+		t.Fatalf("failed to seed organization: %v", err)
+	}
+	for userID, role := range members {
+		if err := orgs.SetMember(ctx, org.ID, userID, role); err != nil {
+			t.Fatalf("failed to seed %s: %v", role, err)
+		}
+	}
+	return org
+}
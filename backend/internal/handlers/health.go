@@ -8,21 +8,53 @@ import (
 	"github.com/sfumato00/content-analyzer/internal/cache"
 	"github.com/sfumato00/content-analyzer/internal/database"
 	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/version"
 )
 
+// ConfigVersioner reports the version of the active configuration
+type ConfigVersioner interface {
+	Version() int64
+}
+
+// redisFeatures are degraded while Redis is down: responses aren't
+// cached, rate limits fail open or closed as configured, and background
+// jobs wait
+var redisFeatures = []string{"caching", "rate_limiting", "job_queue"}
+
+// HealthResponse reports the state of the application and the services it
+// depends on
+type HealthResponse struct {
+	Status        string            `json:"status"`
+	Uptime        string            `json:"uptime"`
+	Version       string            `json:"version"`
+	Build         version.Info      `json:"build"`
+	ConfigVersion int64             `json:"config_version"`
+	Components    map[string]string `json:"components"`
+	// DegradedFeatures lists what works in a limited way while Redis is down
+	DegradedFeatures []string `json:"degraded_features,omitempty"`
+}
+
+// ProbeResponse answers a readiness or liveness probe
+type ProbeResponse struct {
+	Status           string   `json:"status"`
+	DegradedFeatures []string `json:"degraded_features,omitempty"`
+}
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
 	startTime time.Time
 	db        *database.Database
 	cache     *cache.Cache
+	config    ConfigVersioner
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(db *database.Database, cache *cache.Cache) *HealthHandler {
+func NewHealthHandler(db *database.Database, cache *cache.Cache, config ConfigVersioner) *HealthHandler {
 	return &HealthHandler{
 		startTime: time.Now(),
 		db:        db,
 		cache:     cache,
+		config:    config,
 	}
 }
 
@@ -43,6 +75,16 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		components["database"] = "connected"
 	}
 
+	// Reads fall back to the primary while the replica is down, so it
+	// doesn't affect the overall status
+	if h.db.Replica != nil {
+		if h.db.ReplicaHealthy() {
+			components["database_replica"] = "connected"
+		} else {
+			components["database_replica"] = "fallback_to_primary"
+		}
+	}
+
 	// Check Redis
 	if err := h.cache.Ping(ctx); err != nil {
 		components["redis"] = "disconnected"
@@ -56,12 +98,19 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 		status = "degraded"
 	}
 
-	response.Success(w, map[string]interface{}{
-		"status":     status,
-		"uptime":     uptime.String(),
-		"version":    "1.0.0",
-		"components": components,
-	})
+	build := version.Get()
+	body := HealthResponse{
+		Status:        status,
+		Uptime:        uptime.String(),
+		Version:       build.Version,
+		Build:         build,
+		ConfigVersion: h.config.Version(),
+		Components:    components,
+	}
+	if components["redis"] != "connected" {
+		body.DegradedFeatures = redisFeatures
+	}
+	response.Success(w, body)
 }
 
 // Ready returns readiness status (useful for Kubernetes readiness probes)
@@ -76,21 +125,17 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check if Redis is ready
+	// Redis isn't required: without it the API serves requests degraded,
+	// and taking every replica out of rotation would be worse
 	if err := h.cache.Ping(ctx); err != nil {
-		w.WriteHeader(http.StatusServiceUnavailable)
-		response.Error(w, http.StatusServiceUnavailable, "redis not ready")
+		response.Success(w, ProbeResponse{Status: "degraded", DegradedFeatures: redisFeatures})
 		return
 	}
 
-	response.Success(w, map[string]interface{}{
-		"status": "ready",
-	})
+	response.Success(w, ProbeResponse{Status: "ready"})
 }
 
 // Live returns liveness status (useful for Kubernetes liveness probes)
 func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
-	response.Success(w, map[string]interface{}{
-		"status": "alive",
-	})
+	response.Success(w, ProbeResponse{Status: "alive"})
 }
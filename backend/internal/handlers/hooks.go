@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+const (
+	defaultHookPollSize = 50
+	maxHookPollSize     = 100
+)
+
+// HookHandler implements REST hooks the way Zapier and Make expect them:
+// subscribe and unsubscribe endpoints for instant triggers, and a polling
+// endpoint for samples and polling triggers. It is authenticated with an
+// API key, and hooks are removed when their key is revoked.
+type HookHandler struct {
+	store HookStorer
+}
+
+// NewHookHandler creates a new hook handler
+func NewHookHandler(store HookStorer) *HookHandler {
+	return &HookHandler{store: store}
+}
+
+// HookRequest subscribes a hook. Fields limits the analysis it is sent
+// to some of models.HookFields; leaving it out sends them all.
+type HookRequest struct {
+	TargetURL string   `json:"target_url"`
+	Event     string   `json:"event"`
+	Fields    []string `json:"fields"`
+}
+
+// List returns the user's hooks
+// GET /api/v1/hooks
+func (h *HookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	hooks, err := h.store.List(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list hooks", "error", err)
+		response.InternalServerError(w, "Failed to list hooks")
+		return
+	}
+
+	response.Success(w, response.Complete(hooks))
+}
+
+// Subscribe registers a target URL for an event
+// POST /api/v1/hooks
+func (h *HookHandler) Subscribe(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	keyID, err := auth.GetAPIKeyIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req HookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	fields := map[string]string{}
+	target := strings.TrimSpace(req.TargetURL)
+	if u, err := url.Parse(target); err != nil || u.Scheme != "https" || u.Host == "" || len(target) > maxFeedURLLength {
+		fields["target_url"] = "Must be an https URL"
+	}
+	if !slices.Contains(models.HookEvents, req.Event) {
+		fields["event"] = fmt.Sprintf("Must be one of %s", strings.Join(models.HookEvents, ", "))
+	}
+	var selected []string
+	for _, field := range req.Fields {
+		field = strings.TrimSpace(field)
+		if !slices.Contains(models.HookFields, field) {
+			fields["fields"] = fmt.Sprintf("Unknown field %q; must be among %s", field, strings.Join(models.HookFields, ", "))
+			break
+		}
+		if !slices.Contains(selected, field) {
+			selected = append(selected, field)
+		}
+	}
+	if len(fields) > 0 {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	hook, err := h.store.Create(r.Context(), models.RESTHook{
+		UserID:    userID,
+		APIKeyID:  keyID,
+		Event:     req.Event,
+		TargetURL: target,
+		Fields:    selected,
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrHookLimit) {
+			response.Conflict(w, "Hook limit reached; unsubscribe a hook first")
+			return
+		}
+		slog.Error("Failed to subscribe hook", "error", err)
+		response.InternalServerError(w, "Failed to subscribe hook")
+		return
+	}
+
+	response.Created(w, hook)
+}
+
+// Unsubscribe removes a hook
+// DELETE /api/v1/hooks/{id}
+func (h *HookHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid hook ID")
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Hook not found")
+			return
+		}
+		slog.Error("Failed to unsubscribe hook", "error", err)
+		response.InternalServerError(w, "Failed to unsubscribe hook")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// RecentAnalyses returns the latest completed analyses, newest first, as a
+// bare array in the same shape hooks receive. Polling clients deduplicate
+// on id.
+// GET /api/v1/hooks/analyses?limit=
+func (h *HookHandler) RecentAnalyses(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	limit := defaultHookPollSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxHookPollSize {
+			response.BadRequest(w, fmt.Sprintf("limit must be between 1 and %d", maxHookPollSize))
+			return
+		}
+		limit = n
+	}
+
+	analyses, err := h.store.RecentAnalyses(r.Context(), userID, limit)
+	if err != nil {
+		slog.Error("Failed to list recent analyses", "error", err)
+		response.InternalServerError(w, "Failed to list recent analyses")
+		return
+	}
+
+	response.Success(w, analyses)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// newHookRouter mounts the handler so chi URL parameters resolve
+func newHookRouter(handler *HookHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/hooks", handler.List)
+	r.Post("/hooks", handler.Subscribe)
+	r.Delete("/hooks/{id}", handler.Unsubscribe)
+	r.Get("/hooks/analyses", handler.RecentAnalyses)
+	return r
+}
+
+// withUserKey attaches a user and their API key to the request as
+// auth.APIKeyMiddleware would
+func withUserKey(r *http.Request, userID, keyID uuid.UUID) *http.Request {
+	ctx := context.WithValue(r.Context(), auth.UserIDKey, userID)
+	ctx = context.WithValue(ctx, auth.APIKeyIDKey, keyID)
+	return r.WithContext(ctx)
+}
+
+func TestHookHandler_Subscribe(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		setup      func(store *memstore.HookStore, keyID uuid.UUID)
+		body       interface{}
+		wantStatus int
+	}{
+		{"subscribes", nil, HookRequest{TargetURL: " https://hooks.zapier.com/hooks/standard/1/abc ", Event: models.HookEventAnalysisCompleted}, http.StatusCreated},
+		{"not https", nil, HookRequest{TargetURL: "http://example.com/hook", Event: models.HookEventAnalysisCompleted}, http.StatusUnprocessableEntity},
+		{"no host", nil, HookRequest{TargetURL: "https:///hook", Event: models.HookEventAnalysisCompleted}, http.StatusUnprocessableEntity},
+		{"unknown event", nil, HookRequest{TargetURL: "https://example.com/hook", Event: "submission.created"}, http.StatusUnprocessableEntity},
+		{"malformed body", nil, "not json", http.StatusBadRequest},
+		{
+			"limit reached",
+			func(store *memstore.HookStore, keyID uuid.UUID) {
+				for i := range models.MaxHooksPerUser {
+					store.Create(ctx, models.RESTHook{UserID: userID, APIKeyID: keyID, Event: models.HookEventAnalysisCompleted, TargetURL: fmt.Sprintf("https://example.com/%d", i)})
+				}
+			},
+			HookRequest{TargetURL: "https://example.com/hook", Event: models.HookEventAnalysisCompleted},
+			http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := memstore.NewAPIKeyStore()
+			_, key, _ := keys.Create(ctx, userID, "Zapier", nil)
+			store := memstore.NewHookStore(keys, memstore.NewSubmissionStore())
+			if tt.setup != nil {
+				tt.setup(store, key.ID)
+			}
+
+			rec := httptest.NewRecorder()
+			newHookRouter(NewHookHandler(store)).ServeHTTP(rec, withUserKey(newJSONRequest(t, http.MethodPost, "/hooks", tt.body), userID, key.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+
+			var hook models.RESTHook
+			decodeBody(t, rec, &hook)
+			if hook.ID == uuid.Nil || hook.APIKeyID != key.ID || hook.TargetURL != "https://hooks.zapier.com/hooks/standard/1/abc" {
+				t.Errorf("hook = %+v, want an ID, the request's key and the trimmed URL", hook)
+			}
+		})
+	}
+}
+
+func TestHookHandler_ListUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	keys := memstore.NewAPIKeyStore()
+	_, key, _ := keys.Create(ctx, userID, "Zapier", nil)
+	store := memstore.NewHookStore(keys, memstore.NewSubmissionStore())
+	hook, _ := store.Create(ctx, models.RESTHook{UserID: userID, APIKeyID: key.ID, Event: models.HookEventAnalysisCompleted, TargetURL: "https://example.com/hook"})
+	router := newHookRouter(NewHookHandler(store))
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		user       uuid.UUID
+		wantStatus int
+	}{
+		{"list", http.MethodGet, "/hooks", userID, http.StatusOK},
+		{"invalid ID", http.MethodDelete, "/hooks/nope", userID, http.StatusBadRequest},
+		{"other user can't unsubscribe", http.MethodDelete, "/hooks/" + hook.ID.String(), uuid.New(), http.StatusNotFound},
+		{"unsubscribe", http.MethodDelete, "/hooks/" + hook.ID.String(), userID, http.StatusNoContent},
+		{"gone", http.MethodDelete, "/hooks/" + hook.ID.String(), userID, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUserKey(httptest.NewRequest(tt.method, tt.target, nil), tt.user, key.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestHookHandler_RecentAnalyses(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	keys := memstore.NewAPIKeyStore()
+	_, key, _ := keys.Create(ctx, userID, "Zapier", nil)
+
+	// Two analyzed submissions and one still queued
+	for i, analyzed := range []bool{true, true, false} {
+		submission, _ := submissions.Create(ctx, userID, fmt.Sprintf("Story %d", i), nil, nil, nil, models.StatusQueued)
+		if analyzed {
+			submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing)
+			submissions.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Sentiment: "positive", Summary: "Good news.", Topics: []string{"news"}})
+		}
+	}
+	router := newHookRouter(NewHookHandler(memstore.NewHookStore(keys, submissions)))
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		target     string
+		wantStatus int
+		wantCount  int
+	}{
+		{"recent", userID, "/hooks/analyses", http.StatusOK, 2},
+		{"limited", userID, "/hooks/analyses?limit=1", http.StatusOK, 1},
+		{"other user", uuid.New(), "/hooks/analyses", http.StatusOK, 0},
+		{"limit too high", userID, "/hooks/analyses?limit=101", http.StatusBadRequest, 0},
+		{"limit not a number", userID, "/hooks/analyses?limit=all", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUserKey(httptest.NewRequest(http.MethodGet, tt.target, nil), tt.user, key.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			// Zapier expects a bare array
+			var analyses []models.HookAnalysis
+			decodeBody(t, rec, &analyses)
+			if len(analyses) != tt.wantCount {
+				t.Fatalf("got %d analyses, want %d", len(analyses), tt.wantCount)
+			}
+			for _, a := range analyses {
+				if a.ID == uuid.Nil || a.Summary != "Good news." || a.Excerpt == "" || len(a.Topics) != 1 {
+					t.Errorf("analysis = %+v, want a flattened completed analysis", a)
+				}
+			}
+		})
+	}
+}
+
+func TestHookHandler_Subscribe_Fields(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	keys := memstore.NewAPIKeyStore()
+	_, key, _ := keys.Create(ctx, userID, "Zapier", nil)
+	router := newHookRouter(NewHookHandler(memstore.NewHookStore(keys, memstore.NewSubmissionStore())))
+
+	subscribe := func(req HookRequest) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, withUserKey(newJSONRequest(t, http.MethodPost, "/hooks", req), userID, key.ID))
+		return rec
+	}
+
+	rec := subscribe(HookRequest{TargetURL: "https://example.com/hook", Event: models.HookEventAnalysisFlagged, Fields: []string{"summary", " labels", "summary"}})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var hook models.RESTHook
+	decodeBody(t, rec, &hook)
+	if hook.Event != models.HookEventAnalysisFlagged || !reflect.DeepEqual(hook.Fields, []string{"summary", "labels"}) {
+		t.Errorf("hook = %+v, want a flagged hook with the summary and labels", hook)
+	}
+
+	rec = subscribe(HookRequest{TargetURL: "https://example.com/hook", Event: models.HookEventAnalysisCompleted, Fields: []string{"content"}})
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `Unknown field \"content\"`) {
+		t.Errorf("unknown field = %d %s, want %d", rec.Code, rec.Body.String(), http.StatusUnprocessableEntity)
+	}
+}
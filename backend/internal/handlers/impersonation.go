@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// ImpersonationHandler lets admins act as a user for support, recording
+// everything they do in the user's audit log
+type ImpersonationHandler struct {
+	users  UserStorer
+	tokens *auth.JWTManager
+	audit  AuditRecorder
+}
+
+// NewImpersonationHandler creates a new impersonation handler
+func NewImpersonationHandler(users UserStorer, tokens *auth.JWTManager, audit AuditRecorder) *ImpersonationHandler {
+	return &ImpersonationHandler{users: users, tokens: tokens, audit: audit}
+}
+
+// ImpersonationResponse is a token for acting as a user
+type ImpersonationResponse struct {
+	*auth.TokenPair
+	UserID       uuid.UUID  `json:"user_id"`
+	Email        string     `json:"email"`
+	Impersonator auth.Actor `json:"impersonator"`
+}
+
+// Impersonate issues the admin a short-lived token for acting as a user
+// POST /api/v1/admin/users/{id}/impersonate
+func (h *ImpersonationHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	adminID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+	if id == adminID {
+		response.BadRequest(w, "You can't impersonate yourself")
+		return
+	}
+
+	user, err := h.users.GetByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "User not found")
+			return
+		}
+		slog.Error("Failed to get user", "user_id", id, "error", err)
+		response.InternalServerError(w, "Failed to get user")
+		return
+	}
+
+	actor := auth.Actor{UserID: adminID, Email: operator(r)}
+	tokenPair, err := h.tokens.GenerateImpersonationToken(user.ID, user.Email, actor)
+	if err != nil {
+		slog.Error("Failed to generate impersonation token", "error", err)
+		response.InternalServerError(w, "Failed to generate token")
+		return
+	}
+
+	slog.Warn("Impersonation started", "user_id", user.ID, "by", actor.Email)
+	h.record(r, user.ID, actor, models.AuditImpersonationStarted, map[string]string{
+		"expires_at": timestamp.Format(tokenPair.ExpiresAt.Time),
+	})
+
+	response.Created(w, ImpersonationResponse{
+		TokenPair:    tokenPair,
+		UserID:       user.ID,
+		Email:        user.Email,
+		Impersonator: actor,
+	})
+}
+
+// Audit records every request made with an impersonation token in the
+// impersonated user's audit log. It must wrap the routes that accept them;
+// elsewhere such tokens are refused.
+func (h *ImpersonationHandler) Audit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, tracked := auth.TrackImpersonation(r.Context())
+		// A handler that writes nothing has answered 200
+		sw := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		r = r.WithContext(ctx)
+		next.ServeHTTP(sw, r)
+
+		if userID, actor, ok := tracked.Actor(); ok {
+			h.record(r, userID, actor, models.AuditImpersonatedRequest, map[string]string{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"status": strconv.Itoa(sw.status),
+			})
+		}
+	})
+}
+
+// record writes an audit entry naming the admin as well as the user,
+// logging rather than failing the request if it can't be stored
+func (h *ImpersonationHandler) record(r *http.Request, userID uuid.UUID, actor auth.Actor, action models.AuditAction, metadata map[string]string) {
+	metadata["impersonator_id"] = actor.UserID.String()
+	metadata["impersonator_email"] = actor.Email
+	entry := &models.AuditEntry{
+		UserID:    userID,
+		Action:    action,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  metadata,
+	}
+
+	// Record even if the client has gone away mid-request
+	if err := h.audit.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+		slog.Error("Failed to record audit entry", "action", action, "user_id", userID, "error", err)
+	}
+}
+
+// statusRecorder records the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status  int
+	written bool
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if !sr.written {
+		sr.status, sr.written = status, true
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(p []byte) (int, error) {
+	sr.written = true
+	return sr.ResponseWriter.Write(p)
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestImpersonationHandler_Impersonate(t *testing.T) {
+	users := memstore.NewUserStore()
+	target, err := users.Create(context.Background(), "customer@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	adminID := uuid.New()
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{"issues a token", target.ID.String(), http.StatusCreated},
+		{"unknown user", uuid.NewString(), http.StatusNotFound},
+		{"invalid ID", "nope", http.StatusBadRequest},
+		{"themselves", adminID.String(), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := memstore.NewAuditStore()
+			handler := NewImpersonationHandler(users, testTokens, audit)
+			r := chi.NewRouter()
+			r.Post("/admin/users/{id}/impersonate", handler.Impersonate)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodPost, "/admin/users/"+tt.id+"/impersonate", nil), adminID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(audit.Entries()) != 0 {
+					t.Errorf("audit = %+v, want nothing recorded", audit.Entries())
+				}
+				return
+			}
+
+			var resp ImpersonationResponse
+			decodeBody(t, rec, &resp)
+			claims, err := testTokens.ValidateToken(resp.AccessToken)
+			if err != nil {
+				t.Fatalf("ValidateToken() error = %v", err)
+			}
+			if claims.UserID != target.ID || claims.Actor == nil || claims.Actor.UserID != adminID {
+				t.Errorf("claims = %+v, want the user acted as by the admin", claims)
+			}
+
+			entries := audit.Entries()
+			if len(entries) != 1 || entries[0].UserID != target.ID || entries[0].Action != models.AuditImpersonationStarted ||
+				entries[0].Metadata["impersonator_id"] != adminID.String() {
+				t.Errorf("audit = %+v", entries)
+			}
+		})
+	}
+}
+
+func TestImpersonationHandler_Audit(t *testing.T) {
+	userID, adminID := uuid.New(), uuid.New()
+	actor := auth.Actor{UserID: adminID, Email: "ops@example.com"}
+	impersonated, err := testTokens.GenerateImpersonationToken(userID, "customer@example.com", actor)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := testTokens.GenerateTokenPair(userID, "customer@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newRouter := func(audit *memstore.AuditStore) chi.Router {
+		r := chi.NewRouter()
+		if audit != nil {
+			r.Use(NewImpersonationHandler(memstore.NewUserStore(), testTokens, audit).Audit)
+		}
+		r.Use(auth.Middleware(testTokens, nil))
+		r.Get("/me", func(w http.ResponseWriter, r *http.Request) {})
+		r.With(auth.ForbidImpersonation).Put("/me/password", func(w http.ResponseWriter, r *http.Request) {})
+		return r
+	}
+	do := func(r chi.Router, method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	audit := memstore.NewAuditStore()
+	r := newRouter(audit)
+	if code := do(r, http.MethodGet, "/me", impersonated.AccessToken); code != http.StatusOK {
+		t.Errorf("impersonated GET status = %d, want %d", code, http.StatusOK)
+	}
+	if code := do(r, http.MethodPut, "/me/password", impersonated.AccessToken); code != http.StatusForbidden {
+		t.Errorf("impersonated password change status = %d, want %d", code, http.StatusForbidden)
+	}
+	if code := do(r, http.MethodGet, "/me", session.AccessToken); code != http.StatusOK {
+		t.Errorf("session GET status = %d, want %d", code, http.StatusOK)
+	}
+
+	// Both impersonated requests are recorded with both identities, and
+	// the user's own isn't
+	entries := audit.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("audit = %+v, want 2 entries", entries)
+	}
+	for i, want := range []struct{ path, status string }{{"/me", "200"}, {"/me/password", "403"}} {
+		entry := entries[i]
+		if entry.UserID != userID || entry.Action != models.AuditImpersonatedRequest ||
+			entry.Metadata["path"] != want.path || entry.Metadata["status"] != want.status ||
+			entry.Metadata["impersonator_email"] != actor.Email {
+			t.Errorf("entry %d = %+v", i, entry)
+		}
+	}
+
+	// With impersonation disabled nothing audits the token, so it's refused
+	if code := do(newRouter(nil), http.MethodGet, "/me", impersonated.AccessToken); code != http.StatusForbidden {
+		t.Errorf("untracked status = %d, want %d", code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/imports"
+)
+
+// WithImports accepts CSV and JSONL files of up to maxBytes for bulk
+// import and returns the handler. Without it imports return 404.
+func (h *SubmissionHandler) WithImports(store ImportStorer, maxBytes int64) *SubmissionHandler {
+	h.imports = store
+	h.maxImportBytes = maxBytes
+	return h
+}
+
+// Import accepts a CSV or JSONL file as the "file" field of a multipart
+// form. A "mapping" field names the column each submission field is read
+// from, and optional "format", "analyze", "redact", "instructions" and
+// "profile_id" fields apply to every row. The rows become submissions in
+// the background.
+// POST /api/v1/imports
+func (h *SubmissionHandler) Import(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	if h.imports == nil {
+		response.NotFound(w, "Imports aren't enabled")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxImportBytes+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.importTooLarge(w)
+			return
+		}
+		response.BadRequest(w, "Expected a multipart/form-data upload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.ValidationError(w, map[string]string{"file": "A CSV or JSONL file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > h.maxImportBytes {
+		h.importTooLarge(w)
+		return
+	}
+
+	format, fields := importFormat(r.FormValue("format"), header.Filename)
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	mapping, fields := importMapping(r.FormValue("mapping"))
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	analyze, fields := formBool(r, "analyze")
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	redact, fields := formBool(r, "redact")
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	focus, fields := validateInstructions(r.FormValue("instructions"))
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	var profileID *uuid.UUID
+	if raw := r.FormValue("profile_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			response.ValidationError(w, map[string]string{"profile_id": "Unknown analysis profile"})
+			return
+		}
+		profileID = &id
+	}
+	if !h.checkProfile(w, r, userID, profileID) {
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("Failed to read import file", "error", err)
+		response.InternalServerError(w, "Failed to read upload")
+		return
+	}
+
+	// Read the whole file now so a file that can't be imported is
+	// rejected straight away, rather than failing in the background
+	rows, err := imports.Read(format, data, mapping)
+	if err != nil {
+		var mappingErr *imports.MappingError
+		if errors.As(err, &mappingErr) {
+			response.ValidationError(w, map[string]string{"mapping": capitalize(err.Error())})
+			return
+		}
+		response.ValidationError(w, map[string]string{"file": capitalize(err.Error())})
+		return
+	}
+	if len(rows) == 0 {
+		response.ValidationError(w, map[string]string{"file": "The file has no rows"})
+		return
+	}
+
+	// Analyzed rows run in the user's priority lane
+	priority := h.priority(h.currentUser(r))
+
+	upload, err := h.imports.Create(r.Context(), &models.Import{
+		UserID:       userID,
+		Filename:     cleanFilename(header.Filename),
+		Format:       format,
+		Mapping:      mapping,
+		Analyze:      analyze,
+		Redact:       redact,
+		TotalRows:    len(rows),
+		Instructions: focus,
+		ProfileID:    profileID,
+		Priority:     string(priority),
+	}, data)
+	if err != nil {
+		slog.Error("Failed to store import", "error", err)
+		response.InternalServerError(w, "Failed to store import")
+		return
+	}
+
+	if _, err := h.jobs.EnqueuePriority(r.Context(), priority, imports.JobType, imports.Payload{ImportID: upload.ID}); err != nil {
+		slog.Error("Failed to enqueue import", "import_id", upload.ID, "error", err)
+
+		if err := h.imports.Fail(r.Context(), upload.ID, "The file couldn't be queued for import"); err != nil {
+			slog.Error("Failed to mark import failed", "import_id", upload.ID, "error", err)
+		}
+
+		enqueueFailed(w, err, "Failed to queue file for import")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, upload)
+}
+
+// GetImport reports the progress of an import and the rows that couldn't
+// be imported
+// GET /api/v1/imports/{id}
+func (h *SubmissionHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid import ID")
+		return
+	}
+
+	if h.imports == nil {
+		response.NotFound(w, "Import not found")
+		return
+	}
+
+	upload, err := h.imports.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Import not found")
+			return
+		}
+		slog.Error("Failed to load import", "id", id, "error", err)
+		response.InternalServerError(w, "Failed to load import")
+		return
+	}
+
+	response.Success(w, upload)
+}
+
+// importTooLarge writes the response for an import over the size limit
+func (h *SubmissionHandler) importTooLarge(w http.ResponseWriter) {
+	response.Error(w, http.StatusRequestEntityTooLarge, response.Sprintf(w, "Imports must be at most %d MB", h.maxImportBytes>>20))
+}
+
+// importFormat reads the format field, falling back to the file's
+// extension
+func importFormat(raw, filename string) (models.ImportFormat, map[string]string) {
+	if raw == "" {
+		switch strings.ToLower(filepath.Ext(filename)) {
+		case ".csv":
+			return models.ImportCSV, nil
+		case ".jsonl", ".ndjson":
+			return models.ImportJSONL, nil
+		}
+		return "", map[string]string{"format": "Set format to csv or jsonl, or upload a .csv or .jsonl file"}
+	}
+
+	format, err := models.ParseImportFormat(strings.ToLower(raw))
+	if err != nil {
+		return "", map[string]string{"format": capitalize(err.Error())}
+	}
+	return format, nil
+}
+
+// importMapping reads the mapping field. Without one, content is read
+// from a column named "content".
+func importMapping(raw string) (models.ImportMapping, map[string]string) {
+	mapping := models.ImportMapping{Content: "content"}
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return mapping, map[string]string{"mapping": `Mapping must be a JSON object such as {"content": "body", "instructions": "notes"}`}
+	}
+	if strings.TrimSpace(mapping.Content) == "" {
+		return mapping, map[string]string{"mapping": "Mapping must name the content column"}
+	}
+	return mapping, nil
+}
+
+// formBool reads an optional true/false form field
+func formBool(r *http.Request, name string) (bool, map[string]string) {
+	raw := r.FormValue(name)
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, map[string]string{name: fmt.Sprintf("%s must be true or false", name)}
+	}
+	return value, nil
+}
+
+// capitalize upper-cases the first letter of an error message
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
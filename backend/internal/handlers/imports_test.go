@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/imports"
+)
+
+// newImportRouter mounts the handler so chi URL parameters resolve
+func newImportRouter(handler *SubmissionHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/imports", handler.Import)
+	r.Get("/imports/{id}", handler.GetImport)
+	return r
+}
+
+// newImportRequest builds a multipart import of data as the "file" field
+func newImportRequest(t *testing.T, filename string, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	req := newUploadRequest(t, filename, "application/octet-stream", data, fields)
+	req.URL.Path = "/imports"
+	return req
+}
+
+func TestSubmissionHandler_Import(t *testing.T) {
+	csvData := []byte("id,body\n1,First review\n2,Second review\n")
+
+	tests := []struct {
+		name       string
+		filename   string
+		data       []byte
+		fields     map[string]string
+		wantStatus int
+		wantRows   int
+	}{
+		{"csv", "reviews.csv", csvData, map[string]string{"mapping": `{"content": "body"}`, "analyze": "true"}, http.StatusAccepted, 2},
+		{"jsonl with default mapping", "reviews.jsonl", []byte(`{"content": "Only review"}`), nil, http.StatusAccepted, 1},
+		{"format overrides extension", "export.txt", []byte(`{"content": "Only review"}`), map[string]string{"format": "JSONL"}, http.StatusAccepted, 1},
+		{"missing file", "", nil, nil, http.StatusUnprocessableEntity, 0},
+		{"unknown extension", "export.txt", csvData, nil, http.StatusUnprocessableEntity, 0},
+		{"unknown format", "reviews.csv", csvData, map[string]string{"format": "xlsx"}, http.StatusUnprocessableEntity, 0},
+		{"invalid mapping", "reviews.csv", csvData, map[string]string{"mapping": `{"body": "content"}`}, http.StatusUnprocessableEntity, 0},
+		{"unmapped column", "reviews.csv", csvData, nil, http.StatusUnprocessableEntity, 0},
+		{"invalid analyze", "reviews.csv", csvData, map[string]string{"mapping": `{"content": "body"}`, "analyze": "maybe"}, http.StatusUnprocessableEntity, 0},
+		{"no rows", "reviews.csv", []byte("body\n"), map[string]string{"mapping": `{"content": "body"}`}, http.StatusUnprocessableEntity, 0},
+		{"too large", "reviews.csv", bytes.Repeat([]byte("row\n"), 1<<10), nil, http.StatusRequestEntityTooLarge, 0},
+		{"bad profile", "reviews.csv", csvData, map[string]string{"mapping": `{"content": "body"}`, "profile_id": uuid.NewString()}, http.StatusUnprocessableEntity, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewImportStore()
+			jobs := &fakeQueue{}
+			handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), jobs).WithImports(store, 2<<10)
+
+			rec := httptest.NewRecorder()
+			newImportRouter(handler).ServeHTTP(rec, withUser(newImportRequest(t, tt.filename, tt.data, tt.fields), uuid.New()))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if len(jobs.jobs) != 0 {
+					t.Errorf("enqueued %d jobs, want 0", len(jobs.jobs))
+				}
+				return
+			}
+
+			var upload models.Import
+			decodeBody(t, rec, &upload)
+			if upload.Status != models.ImportPending || upload.TotalRows != tt.wantRows || upload.Filename != tt.filename {
+				t.Errorf("import = %+v, want %d pending rows", upload, tt.wantRows)
+			}
+			if upload.Analyze != (tt.fields["analyze"] == "true") {
+				t.Errorf("analyze = %v", upload.Analyze)
+			}
+			if len(jobs.jobs) != 1 || jobs.jobs[0].Type != imports.JobType {
+				t.Fatalf("jobs = %+v, want one %s job", jobs.jobs, imports.JobType)
+			}
+			if data, err := store.Data(context.Background(), upload.ID); err != nil || !bytes.Equal(data, tt.data) {
+				t.Errorf("Data() = %q, %v", data, err)
+			}
+		})
+	}
+}
+
+func TestSubmissionHandler_GetImport(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewImportStore()
+	handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), &fakeQueue{}).WithImports(store, 1<<20)
+	router := newImportRouter(handler)
+
+	userID := uuid.New()
+	upload, err := store.Create(ctx, &models.Import{UserID: userID, Filename: "reviews.csv", Format: models.ImportCSV, TotalRows: 3}, []byte("content\na\n\nb\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload.ProcessedRows, upload.ImportedRows = 2, 1
+	upload.AddRowError(3, "content is empty")
+	if err := store.Progress(ctx, upload); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		id         string
+		wantStatus int
+	}{
+		{"owner", userID, upload.ID.String(), http.StatusOK},
+		{"someone else", uuid.New(), upload.ID.String(), http.StatusNotFound},
+		{"invalid id", userID, "nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/imports/"+tt.id, nil), tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var got models.Import
+			decodeBody(t, rec, &got)
+			if got.Status != models.ImportProcessing || got.ProcessedRows != 2 || len(got.RowErrors) != 1 || got.RowErrors[0].Line != 3 {
+				t.Errorf("import = %+v, want its progress and row errors", got)
+			}
+		})
+	}
+}
+
+func TestSubmissionHandler_Import_Disabled(t *testing.T) {
+	handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), &fakeQueue{})
+
+	rec := httptest.NewRecorder()
+	newImportRouter(handler).ServeHTTP(rec, withUser(newImportRequest(t, "reviews.csv", []byte("content\nhello\n"), nil), uuid.New()))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// InviteHandler lets operators issue and revoke invitation codes for
+// invite-only registration
+type InviteHandler struct {
+	store InviteStorer
+}
+
+// NewInviteHandler creates a new invitation handler
+func NewInviteHandler(store InviteStorer) *InviteHandler {
+	return &InviteHandler{store: store}
+}
+
+// CreateInviteRequest issues an invitation. MaxUses defaults to a single
+// use and ExpiresIn to never expiring.
+type CreateInviteRequest struct {
+	Note      string `json:"note"`
+	MaxUses   int    `json:"max_uses"`
+	ExpiresIn string `json:"expires_in"`
+}
+
+// CreateInviteResponse includes the code, which can't be read back later
+type CreateInviteResponse struct {
+	Code string `json:"code"`
+	*models.InviteCode
+}
+
+// Create issues a new invitation code
+// POST /api/v1/admin/invites
+func (h *InviteHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req CreateInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	invite := &models.InviteCode{
+		Note:      strings.TrimSpace(req.Note),
+		MaxUses:   req.MaxUses,
+		CreatedBy: operator(r),
+	}
+	if invite.MaxUses == 0 {
+		invite.MaxUses = 1
+	}
+	if req.ExpiresIn != "" {
+		ttl, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || ttl <= 0 {
+			response.BadRequest(w, "expires_in must be a positive duration such as 72h")
+			return
+		}
+		expiresAt := timestamp.New(time.Now().Add(ttl))
+		invite.ExpiresAt = &expiresAt
+	}
+	if err := invite.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	code, stored, err := h.store.Create(r.Context(), invite)
+	if err != nil {
+		slog.Error("Failed to create invitation", "error", err)
+		response.InternalServerError(w, "Failed to create invitation")
+		return
+	}
+
+	slog.Info("Invitation created", "invite_id", stored.ID, "max_uses", stored.MaxUses, "by", stored.CreatedBy)
+	response.Created(w, CreateInviteResponse{Code: code, InviteCode: stored})
+}
+
+// List returns every invitation with its remaining uses
+// GET /api/v1/admin/invites
+func (h *InviteHandler) List(w http.ResponseWriter, r *http.Request) {
+	invites, err := h.store.List(r.Context())
+	if err != nil {
+		slog.Error("Failed to list invitations", "error", err)
+		response.InternalServerError(w, "Failed to list invitations")
+		return
+	}
+	response.Success(w, response.Complete(invites))
+}
+
+// Revoke stops an invitation from registering more accounts
+// DELETE /api/v1/admin/invites/{id}
+func (h *InviteHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid invitation ID")
+		return
+	}
+
+	invite, err := h.store.Revoke(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Invitation not found")
+			return
+		}
+		slog.Error("Failed to revoke invitation", "invite_id", id, "error", err)
+		response.InternalServerError(w, "Failed to revoke invitation")
+		return
+	}
+
+	slog.Info("Invitation revoked", "invite_id", id, "by", operator(r))
+	response.Success(w, invite)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// newInviteRouter mounts the invitation endpoints the way the server does
+func newInviteRouter(handler *InviteHandler) *chi.Mux {
+	r := chi.NewRouter()
+	r.Get("/admin/invites", handler.List)
+	r.Post("/admin/invites", handler.Create)
+	r.Delete("/admin/invites/{id}", handler.Revoke)
+	return r
+}
+
+func TestInviteHandler_Create(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		wantUses   int
+	}{
+		{
+			name:       "single use by default",
+			body:       CreateInviteRequest{Note: "beta tester"},
+			wantStatus: http.StatusCreated,
+			wantUses:   1,
+		},
+		{
+			name:       "multi use with expiry",
+			body:       CreateInviteRequest{MaxUses: 25, ExpiresIn: "72h"},
+			wantStatus: http.StatusCreated,
+			wantUses:   25,
+		},
+		{
+			name:       "invalid expiry",
+			body:       CreateInviteRequest{ExpiresIn: "soon"},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "negative uses",
+			body:       CreateInviteRequest{MaxUses: -1},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newInviteRouter(NewInviteHandler(memstore.NewInviteStore()))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, newJSONRequest(t, http.MethodPost, "/admin/invites", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Create() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var resp CreateInviteResponse
+			decodeBody(t, rec, &resp)
+			if resp.Code == "" {
+				t.Error("Create() response missing code")
+			}
+			if resp.MaxUses != tt.wantUses {
+				t.Errorf("Create() max_uses = %d, want %d", resp.MaxUses, tt.wantUses)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_Register_InviteOnly(t *testing.T) {
+	invites := memstore.NewInviteStore()
+	users := memstore.NewUserStore().WithInvites(invites)
+	handler := NewAuthHandler(
+		users,
+		memstore.NewEmailTokenStore(),
+		auth.NewJWTManager("test-secret-key-at-least-32-characters"),
+		newFakeNotifier(),
+		&fakeSessions{},
+	).WithInviteOnly(true)
+
+	code, invite, err := invites.Create(t.Context(), &models.InviteCode{MaxUses: 1})
+	if err != nil {
+		t.Fatalf("failed to create invitation: %v", err)
+	}
+
+	register := func(email, code string) int {
+		rec := httptest.NewRecorder()
+		handler.Register(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/register", RegisterRequest{
+			Email:      email,
+			Password:   testPassword,
+			InviteCode: code,
+		}))
+		return rec.Code
+	}
+
+	if status := register("nocode@example.com", ""); status != http.StatusForbidden {
+		t.Errorf("Register() without code status = %d, want %d", status, http.StatusForbidden)
+	}
+	if status := register("wrong@example.com", "AAAA-BBBB-CCCC-DDDD"); status != http.StatusForbidden {
+		t.Errorf("Register() with unknown code status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	// A failed registration gives the use back
+	if status := register("bad-email", code); status != http.StatusBadRequest {
+		t.Errorf("Register() with invalid email status = %d, want %d", status, http.StatusBadRequest)
+	}
+
+	if status := register("invited@example.com", code); status != http.StatusCreated {
+		t.Fatalf("Register() with code status = %d, want %d", status, http.StatusCreated)
+	}
+	if status := register("second@example.com", code); status != http.StatusForbidden {
+		t.Errorf("Register() with used code status = %d, want %d", status, http.StatusForbidden)
+	}
+
+	// Revoked codes stop working
+	router := newInviteRouter(NewInviteHandler(invites))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/invites/"+invite.ID.String(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Revoke() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var revoked models.InviteCode
+	decodeBody(t, rec, &revoked)
+	if revoked.RevokedAt == nil || revoked.Uses != 1 {
+		t.Errorf("Revoke() = uses %d, revoked_at %v, want 1 use and revoked", revoked.Uses, revoked.RevokedAt)
+	}
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// ListIssues returns the grammar, spelling and style issues proofreading
+// found in a submission, ordered by position
+// GET /api/v1/submissions/{id}/issues?category=&severity=
+func (h *SubmissionHandler) ListIssues(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	category := models.IssueCategory(strings.ToLower(r.URL.Query().Get("category")))
+	if category != "" && !models.ValidIssueCategory(category) {
+		response.BadRequest(w, "category must be grammar, spelling or style")
+		return
+	}
+	severity := models.IssueSeverity(strings.ToLower(r.URL.Query().Get("severity")))
+	if severity != "" && !models.ValidIssueSeverity(severity) {
+		response.BadRequest(w, "severity must be error, warning or suggestion")
+		return
+	}
+
+	analysis, err := h.store.GetAnalysis(r.Context(), userID, id)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to get analysis", "error", err)
+			response.InternalServerError(w, "Failed to get issues")
+			return
+		}
+
+		// No analysis yet: report why
+		submission, err := h.store.GetByID(r.Context(), userID, id)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				response.NotFound(w, "Submission not found")
+				return
+			}
+			slog.Error("Failed to get submission", "error", err)
+			response.InternalServerError(w, "Failed to get issues")
+			return
+		}
+
+		if pending(submission.Status) {
+			response.JSON(w, http.StatusAccepted, dto.Pending{Status: submission.Status})
+			return
+		}
+		response.NotFound(w, "Analysis not available")
+		return
+	}
+
+	if analysis.Issues == nil {
+		response.NotFound(w, "Submission was not proofread")
+		return
+	}
+
+	issues := make([]models.Issue, 0, len(analysis.Issues))
+	for _, issue := range analysis.Issues {
+		if (category == "" || issue.Category == category) && (severity == "" || issue.Severity == severity) {
+			issues = append(issues, issue)
+		}
+	}
+
+	response.Success(w, response.Complete(issues).
+		WithFilter("category", string(category)).
+		WithFilter("severity", string(severity)))
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+func TestSubmissionHandler_ListIssues(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewSubmissionStore()
+	router := newSubmissionRouter(NewSubmissionHandler(store, memstore.NewUserStore(), &fakeQueue{}))
+	userID := uuid.New()
+
+	proofread, _ := store.Create(ctx, userID, "Their is a tpyo here.", nil, nil, nil, models.StatusQueued)
+	unproofread, _ := store.Create(ctx, userID, "Fine text.", nil, nil, nil, models.StatusQueued)
+	queued, _ := store.Create(ctx, userID, "Not yet.", nil, nil, nil, models.StatusQueued)
+
+	completeSubmission(t, store, &models.Analysis{
+		SubmissionID: proofread.ID,
+		Sentiment:    "neutral",
+		Issues: []models.Issue{
+			{Category: models.IssueGrammar, Severity: models.SeverityError, Start: 0, End: 5, Message: "Wrong word.", Suggestion: "There"},
+			{Category: models.IssueSpelling, Severity: models.SeverityError, Start: 11, End: 15, Message: "Misspelled.", Suggestion: "typo"},
+			{Category: models.IssueStyle, Severity: models.SeveritySuggestion, Start: 16, End: 20, Message: "Vague."},
+		},
+	})
+	completeSubmission(t, store, &models.Analysis{SubmissionID: unproofread.ID, Sentiment: "neutral"})
+
+	tests := []struct {
+		name       string
+		id         uuid.UUID
+		query      string
+		user       uuid.UUID
+		wantStatus int
+		wantIssues int
+	}{
+		{name: "all issues", id: proofread.ID, user: userID, wantStatus: http.StatusOK, wantIssues: 3},
+		{name: "by category", id: proofread.ID, query: "?category=spelling", user: userID, wantStatus: http.StatusOK, wantIssues: 1},
+		{name: "by severity", id: proofread.ID, query: "?severity=ERROR", user: userID, wantStatus: http.StatusOK, wantIssues: 2},
+		{name: "no match", id: proofread.ID, query: "?category=style&severity=error", user: userID, wantStatus: http.StatusOK, wantIssues: 0},
+		{name: "unknown category", id: proofread.ID, query: "?category=tone", user: userID, wantStatus: http.StatusBadRequest},
+		{name: "unknown severity", id: proofread.ID, query: "?severity=fatal", user: userID, wantStatus: http.StatusBadRequest},
+		{name: "not proofread", id: unproofread.ID, user: userID, wantStatus: http.StatusNotFound},
+		{name: "analysis pending", id: queued.ID, user: userID, wantStatus: http.StatusAccepted},
+		{name: "another user's submission", id: proofread.ID, user: uuid.New(), wantStatus: http.StatusNotFound},
+		{name: "unknown submission", id: uuid.New(), user: userID, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+tt.id.String()+"/issues"+tt.query, nil), tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ListIssues() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got response.ListResponse[models.Issue]
+			decodeBody(t, rec, &got)
+			if len(got.Data) != tt.wantIssues {
+				t.Errorf("ListIssues() returned %d issues, want %d", len(got.Data), tt.wantIssues)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// JobsHandler lets operators inspect and manage the background job queue
+type JobsHandler struct {
+	queue             JobQueueAdmin
+	budget            ProviderBudget
+	visibilityTimeout time.Duration
+}
+
+// NewJobsHandler creates a new jobs handler
+func NewJobsHandler(jobs JobQueueAdmin) *JobsHandler {
+	return &JobsHandler{queue: jobs, visibilityTimeout: queue.DefaultVisibilityTimeout}
+}
+
+// WithVisibilityTimeout sets how long a job must have been claimed for
+// RequeueStale to put it back when no threshold is given, and returns the
+// handler. Zero keeps the default.
+func (h *JobsHandler) WithVisibilityTimeout(timeout time.Duration) *JobsHandler {
+	if timeout > 0 {
+		h.visibilityTimeout = timeout
+	}
+	return h
+}
+
+// WithBudget reports the model provider's rate limit budget with the queue
+// stats and returns the handler
+func (h *JobsHandler) WithBudget(budget ProviderBudget) *JobsHandler {
+	h.budget = budget
+	return h
+}
+
+// JobQueueResponse describes the state of the job queue
+type JobQueueResponse struct {
+	Paused              bool                     `json:"paused"`
+	Depth               int64                    `json:"depth"`
+	DepthByPriority     map[queue.Priority]int64 `json:"depth_by_priority"`
+	InFlight            int64                    `json:"in_flight"`
+	Dead                int64                    `json:"dead"`
+	Held                int64                    `json:"held"`
+	OldestJobAgeSeconds float64                  `json:"oldest_job_age_seconds"`
+	Throughput          JobThroughput            `json:"throughput"`
+	InFlightJobs        []queue.Job              `json:"in_flight_jobs"`
+	// ProviderBudget is the model provider's rate limit as this process
+	// sees it; null when not tracked
+	ProviderBudget *ai.BudgetState `json:"provider_budget"`
+}
+
+// JobThroughput counts jobs finished over a recent window
+type JobThroughput struct {
+	WindowSeconds      int     `json:"window_seconds"`
+	Completed          int64   `json:"completed"`
+	Failed             int64   `json:"failed"`
+	CompletedPerMinute float64 `json:"completed_per_minute"`
+}
+
+// RequeueStaleResponse reports how many in-flight jobs were put back
+type RequeueStaleResponse struct {
+	Requeued int `json:"requeued"`
+}
+
+// JobQueueStateResponse reports whether workers are paused
+type JobQueueStateResponse struct {
+	Paused bool `json:"paused"`
+}
+
+// Stats returns queue depth, in-flight jobs, throughput, oldest job age and
+// the model provider's rate limit budget
+func (h *JobsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.queue.Stats(r.Context())
+	if err != nil {
+		slog.Error("Failed to read queue stats", "error", err)
+		response.InternalServerError(w, "Failed to read queue stats")
+		return
+	}
+
+	jobs, err := h.queue.InFlight(r.Context())
+	if err != nil {
+		slog.Error("Failed to list in-flight jobs", "error", err)
+		response.InternalServerError(w, "Failed to read queue stats")
+		return
+	}
+
+	resp := JobQueueResponse{
+		Paused:              stats.Paused,
+		Depth:               stats.Pending,
+		DepthByPriority:     stats.PendingByPriority,
+		InFlight:            stats.InFlight,
+		Dead:                stats.Dead,
+		Held:                stats.Held,
+		OldestJobAgeSeconds: stats.OldestPendingAge.Seconds(),
+		Throughput: JobThroughput{
+			WindowSeconds:      int(queue.ThroughputWindow / time.Second),
+			Completed:          stats.Completed,
+			Failed:             stats.Failed,
+			CompletedPerMinute: float64(stats.Completed) / queue.ThroughputWindow.Minutes(),
+		},
+		InFlightJobs: jobs,
+	}
+	if h.budget != nil {
+		budget := h.budget.State()
+		resp.ProviderBudget = &budget
+	}
+
+	response.Success(w, resp)
+}
+
+// Pause stops workers from picking up new jobs
+func (h *JobsHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	if err := h.queue.Pause(r.Context()); err != nil {
+		slog.Error("Failed to pause job queue", "error", err)
+		response.InternalServerError(w, "Failed to pause job queue")
+		return
+	}
+
+	// Logged at warn: no submission is analyzed until someone resumes it
+	slog.Warn("Job queue paused", "by", operator(r))
+	response.Success(w, JobQueueStateResponse{Paused: true})
+}
+
+// Resume lets workers pick up jobs again
+func (h *JobsHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if err := h.queue.Resume(r.Context()); err != nil {
+		slog.Error("Failed to resume job queue", "error", err)
+		response.InternalServerError(w, "Failed to resume job queue")
+		return
+	}
+
+	slog.Warn("Job queue resumed", "by", operator(r))
+	response.Success(w, JobQueueStateResponse{Paused: false})
+}
+
+// Cancel removes a pending or dead job so it never runs
+func (h *JobsHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	job, err := h.queue.Cancel(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, queue.ErrJobNotFound):
+			response.NotFound(w, "Job not found")
+		case errors.Is(err, queue.ErrJobRunning):
+			response.Conflict(w, "Job is already being processed")
+		default:
+			slog.Error("Failed to cancel job", "job_id", id, "error", err)
+			response.InternalServerError(w, "Failed to cancel job")
+		}
+		return
+	}
+
+	slog.Warn("Job canceled", "job_id", job.ID, "job_type", job.Type, "by", operator(r))
+	response.Success(w, job)
+}
+
+// operator returns the email of the admin making the request
+func operator(r *http.Request) string {
+	email, _ := auth.GetUserEmailFromContext(r.Context())
+	return email
+}
+
+// RequeueStale puts back in-flight jobs claimed longer ago than
+// ?older_than=, by default the visibility timeout, such as those of a
+// worker that crashed. Each counts as a failed attempt.
+// POST /api/v1/admin/jobs/requeue-stale?older_than=
+func (h *JobsHandler) RequeueStale(w http.ResponseWriter, r *http.Request) {
+	olderThan := h.visibilityTimeout
+	if raw := r.URL.Query().Get("older_than"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d <= 0 {
+			response.BadRequest(w, "older_than must be a positive duration such as 15m")
+			return
+		}
+		olderThan = d
+	}
+
+	requeued, err := h.queue.RequeueStale(r.Context(), olderThan)
+	if err != nil {
+		slog.Error("Failed to requeue stale jobs", "error", err)
+		response.InternalServerError(w, "Failed to requeue stale jobs")
+		return
+	}
+
+	slog.Warn("Stale jobs requeued", "count", requeued, "older_than", olderThan, "by", operator(r))
+	response.Success(w, RequeueStaleResponse{Requeued: requeued})
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeJobQueue is an in-memory JobQueueAdmin
+type fakeJobQueue struct {
+	stats     queue.Stats
+	inFlight  []queue.Job
+	cancelErr error
+	canceled  []string
+	olderThan []time.Duration
+	requeued  int
+	err       error
+}
+
+func (q *fakeJobQueue) Stats(ctx context.Context) (*queue.Stats, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	stats := q.stats
+	return &stats, nil
+}
+
+func (q *fakeJobQueue) InFlight(ctx context.Context) ([]queue.Job, error) {
+	return q.inFlight, q.err
+}
+
+func (q *fakeJobQueue) Pause(ctx context.Context) error {
+	q.stats.Paused = true
+	return q.err
+}
+
+func (q *fakeJobQueue) Resume(ctx context.Context) error {
+	q.stats.Paused = false
+	return q.err
+}
+
+func (q *fakeJobQueue) Cancel(ctx context.Context, id string) (*queue.Job, error) {
+	if q.cancelErr != nil {
+		return nil, q.cancelErr
+	}
+	q.canceled = append(q.canceled, id)
+	return &queue.Job{ID: id, Type: "analyze_submission"}, nil
+}
+
+func (q *fakeJobQueue) RequeueStale(ctx context.Context, olderThan time.Duration) (int, error) {
+	q.olderThan = append(q.olderThan, olderThan)
+	return q.requeued, q.err
+}
+
+func TestJobsHandler_Stats(t *testing.T) {
+	jobs := &fakeJobQueue{
+		stats: queue.Stats{
+			Pending:          12,
+			InFlight:         1,
+			Dead:             2,
+			OldestPendingAge: 90 * time.Second,
+			Completed:        50,
+			Failed:           3,
+		},
+		inFlight: []queue.Job{{ID: "job-1", Type: "analyze_submission"}},
+	}
+	handler := NewJobsHandler(jobs)
+
+	rec := httptest.NewRecorder()
+	handler.Stats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Stats() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var got JobQueueResponse
+	decodeBody(t, rec, &got)
+
+	if got.Depth != 12 || got.InFlight != 1 || got.Dead != 2 {
+		t.Errorf("Stats() counts = %d/%d/%d, want 12/1/2", got.Depth, got.InFlight, got.Dead)
+	}
+	if got.OldestJobAgeSeconds != 90 {
+		t.Errorf("Stats() oldest_job_age_seconds = %v, want 90", got.OldestJobAgeSeconds)
+	}
+	if got.Throughput.CompletedPerMinute != 10 {
+		t.Errorf("Stats() completed_per_minute = %v, want 10", got.Throughput.CompletedPerMinute)
+	}
+	if len(got.InFlightJobs) != 1 || got.InFlightJobs[0].ID != "job-1" {
+		t.Errorf("Stats() in_flight_jobs = %+v, want job-1", got.InFlightJobs)
+	}
+	if got.ProviderBudget != nil {
+		t.Errorf("Stats() provider_budget = %+v, want null without a budget", got.ProviderBudget)
+	}
+}
+
+func TestJobsHandler_Stats_ProviderBudget(t *testing.T) {
+	handler := NewJobsHandler(&fakeJobQueue{}).WithBudget(ai.NewBudget("gemini"))
+
+	rec := httptest.NewRecorder()
+	handler.Stats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Stats() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var got JobQueueResponse
+	decodeBody(t, rec, &got)
+	if got.ProviderBudget == nil || got.ProviderBudget.Provider != "gemini" || got.ProviderBudget.DelaySeconds != 0 {
+		t.Errorf("Stats() provider_budget = %+v, want an untouched gemini budget", got.ProviderBudget)
+	}
+}
+
+func TestJobsHandler_Stats_Error(t *testing.T) {
+	handler := NewJobsHandler(&fakeJobQueue{err: errors.New("redis down")})
+
+	rec := httptest.NewRecorder()
+	handler.Stats(rec, httptest.NewRequest(http.MethodGet, "/api/v1/admin/jobs", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("Stats() status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestJobsHandler_PauseResume(t *testing.T) {
+	jobs := &fakeJobQueue{}
+	handler := NewJobsHandler(jobs)
+
+	rec := httptest.NewRecorder()
+	handler.Pause(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/jobs/pause", nil))
+	if rec.Code != http.StatusOK || !jobs.stats.Paused {
+		t.Fatalf("Pause() status = %d, paused = %v", rec.Code, jobs.stats.Paused)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Resume(rec, httptest.NewRequest(http.MethodPost, "/api/v1/admin/jobs/resume", nil))
+	if rec.Code != http.StatusOK || jobs.stats.Paused {
+		t.Fatalf("Resume() status = %d, paused = %v", rec.Code, jobs.stats.Paused)
+	}
+
+	var got JobQueueStateResponse
+	decodeBody(t, rec, &got)
+	if got.Paused {
+		t.Error("Resume() response paused = true, want false")
+	}
+}
+
+func TestJobsHandler_Cancel(t *testing.T) {
+	tests := []struct {
+		name       string
+		cancelErr  error
+		wantStatus int
+	}{
+		{name: "pending job", wantStatus: http.StatusOK},
+		{name: "unknown job", cancelErr: queue.ErrJobNotFound, wantStatus: http.StatusNotFound},
+		{name: "running job", cancelErr: queue.ErrJobRunning, wantStatus: http.StatusConflict},
+		{name: "redis error", cancelErr: errors.New("redis down"), wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeJobQueue{cancelErr: tt.cancelErr}
+			handler := NewJobsHandler(jobs)
+
+			r := chi.NewRouter()
+			r.Post("/admin/jobs/{id}/cancel", handler.Cancel)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/jobs/job-1/cancel", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Cancel() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK && (len(jobs.canceled) != 1 || jobs.canceled[0] != "job-1") {
+				t.Errorf("Cancel() canceled = %v, want [job-1]", jobs.canceled)
+			}
+		})
+	}
+}
+
+func TestJobsHandler_RequeueStale(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		err           error
+		wantStatus    int
+		wantOlderThan time.Duration
+	}{
+		{name: "visibility timeout", wantStatus: http.StatusOK, wantOlderThan: 45 * time.Minute},
+		{name: "older than", query: "?older_than=5m", wantStatus: http.StatusOK, wantOlderThan: 5 * time.Minute},
+		{name: "invalid threshold", query: "?older_than=soon", wantStatus: http.StatusBadRequest},
+		{name: "non-positive threshold", query: "?older_than=0s", wantStatus: http.StatusBadRequest},
+		{name: "redis error", err: errors.New("redis down"), wantStatus: http.StatusInternalServerError, wantOlderThan: 45 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeJobQueue{requeued: 2, err: tt.err}
+			handler := NewJobsHandler(jobs).WithVisibilityTimeout(45 * time.Minute)
+
+			rec := httptest.NewRecorder()
+			handler.RequeueStale(rec, httptest.NewRequest(http.MethodPost, "/admin/jobs/requeue-stale"+tt.query, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("RequeueStale() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantOlderThan == 0 {
+				if len(jobs.olderThan) != 0 {
+					t.Errorf("RequeueStale() requeued with %v, want the queue left alone", jobs.olderThan)
+				}
+				return
+			}
+			if len(jobs.olderThan) != 1 || jobs.olderThan[0] != tt.wantOlderThan {
+				t.Errorf("RequeueStale() older than = %v, want %v", jobs.olderThan, tt.wantOlderThan)
+			}
+			if tt.wantStatus == http.StatusOK {
+				var resp RequeueStaleResponse
+				decodeBody(t, rec, &resp)
+				if resp.Requeued != 2 {
+					t.Errorf("RequeueStale() requeued = %d, want 2", resp.Requeued)
+				}
+			}
+		})
+	}
+}
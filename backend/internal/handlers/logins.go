@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/logins"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// LoginGuard spots sign-ins from a country or device the user hasn't
+// signed in from before. It tells the user about them and, unless they
+// have turned it off, holds them until confirmed with an emailed code.
+type LoginGuard struct {
+	fingerprints LoginFingerprintStorer
+	challenges   LoginChallenger
+	notifier     LoginNotifier
+	// locator finds the request's country; nil tracks devices only
+	locator logins.Locator
+}
+
+// NewLoginGuard creates a login guard
+func NewLoginGuard(fingerprints LoginFingerprintStorer, challenges LoginChallenger, notifier LoginNotifier) *LoginGuard {
+	return &LoginGuard{
+		fingerprints: fingerprints,
+		challenges:   challenges,
+		notifier:     notifier,
+	}
+}
+
+// WithLocator tracks the country sign-ins come from as well as the device
+func (g *LoginGuard) WithLocator(locator logins.Locator) *LoginGuard {
+	g.locator = locator
+	return g
+}
+
+// LoginChallengeResponse is returned instead of a token when a sign-in
+// needs confirming
+type LoginChallengeResponse struct {
+	ConfirmationRequired bool   `json:"confirmation_required"`
+	LoginToken           string `json:"login_token"`
+	// ExpiresIn is how long the emailed code stays valid, in seconds
+	ExpiresIn int `json:"expires_in"`
+}
+
+// ConfirmLoginRequest represents the sign-in confirmation request
+type ConfirmLoginRequest struct {
+	LoginToken string `json:"login_token"`
+	Code       string `json:"code"`
+}
+
+// hold checks a sign-in that has passed its password check. Familiar
+// sign-ins are let through; new ones are let through with a notice, or
+// held with a 202 response and an emailed code. It reports whether it
+// held the sign-in, having written the response.
+func (g *LoginGuard) hold(w http.ResponseWriter, r *http.Request, user *models.User, rememberMe bool) bool {
+	fp := logins.Fingerprint(r, g.locator)
+
+	// Like the rate limits, the check fails open: an outage shouldn't lock
+	// everyone out
+	familiar, err := g.fingerprints.IsFamiliar(r.Context(), user.ID, fp)
+	if err != nil {
+		slog.Error("Failed to check login fingerprint", "user_id", user.ID, "error", err)
+		return false
+	}
+	if familiar || !user.ConfirmNewLogins {
+		g.record(r, user, fp)
+		if !familiar {
+			if err := g.notifier.SendNewLogin(r.Context(), user.Email, fp, clientIP(r), time.Now(), "", 0); err != nil {
+				slog.Error("Failed to send new login email", "user_id", user.ID, "error", err)
+			}
+		}
+		return false
+	}
+
+	loginToken, code, err := g.challenges.Start(r.Context(), logins.Pending{UserID: user.ID, Fingerprint: fp, RememberMe: rememberMe})
+	if err != nil {
+		slog.Error("Failed to start login challenge", "user_id", user.ID, "error", err)
+		response.InternalServerError(w, "Failed to authenticate")
+		return true
+	}
+	if err := g.notifier.SendNewLogin(r.Context(), user.Email, fp, clientIP(r), time.Now(), code, logins.ChallengeTTL); err != nil {
+		slog.Error("Failed to send new login email", "user_id", user.ID, "error", err)
+		response.InternalServerError(w, "Failed to send confirmation code")
+		return true
+	}
+
+	slog.Info("Login held for confirmation", "user_id", user.ID, "country", fp.Country, "device", fp.Device)
+	response.JSON(w, http.StatusAccepted, LoginChallengeResponse{
+		ConfirmationRequired: true,
+		LoginToken:           loginToken,
+		ExpiresIn:            int(logins.ChallengeTTL.Seconds()),
+	})
+	return true
+}
+
+// record remembers a sign-in's fingerprint. Failing to only means the
+// next sign-in from it counts as new again, so errors are logged.
+func (g *LoginGuard) record(r *http.Request, user *models.User, fp models.LoginFingerprint) {
+	if err := g.fingerprints.Record(r.Context(), user.ID, fp); err != nil {
+		slog.Error("Failed to record login fingerprint", "user_id", user.ID, "error", err)
+	}
+}
+
+// ConfirmLogin completes a sign-in held by the login guard with the code
+// emailed to the user, and returns a token as Login would have
+// POST /api/v1/auth/confirm-login
+func (h *AuthHandler) ConfirmLogin(w http.ResponseWriter, r *http.Request) {
+	var req ConfirmLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.LoginToken == "" || strings.TrimSpace(req.Code) == "" {
+		response.ValidationError(w, map[string]string{
+			"code": "Login token and code are required",
+		})
+		return
+	}
+
+	pending, err := h.guard.challenges.Confirm(r.Context(), req.LoginToken, strings.TrimSpace(req.Code))
+	if err != nil {
+		switch {
+		case errors.Is(err, logins.ErrInvalidCode):
+			response.Unauthorized(w, "Invalid confirmation code")
+		case errors.Is(err, logins.ErrNoChallenge):
+			response.Unauthorized(w, "Sign-in has expired; sign in again")
+		default:
+			slog.Error("Failed to confirm login", "error", err)
+			response.InternalServerError(w, "Failed to confirm sign-in")
+		}
+		return
+	}
+
+	user, err := h.userStore.GetByID(r.Context(), pending.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.Unauthorized(w, "Sign-in has expired; sign in again")
+			return
+		}
+		slog.Error("Failed to get user", "user_id", pending.UserID, "error", err)
+		response.InternalServerError(w, "Failed to confirm sign-in")
+		return
+	}
+
+	h.guard.record(r, user, pending.Fingerprint)
+	h.signIn(w, r, user, pending.RememberMe)
+}
+
+// LoginConfirmationRequest turns confirming new sign-ins on or off
+type LoginConfirmationRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// SetLoginConfirmation chooses whether sign-ins from a new country or
+// device need an emailed code, or only send a notice
+// PUT /api/v1/me/login-confirmation
+func (h *AccountHandler) SetLoginConfirmation(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req LoginConfirmationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.Enabled == nil {
+		response.ValidationError(w, map[string]string{
+			"enabled": "Enabled is required",
+		})
+		return
+	}
+
+	if err := h.userStore.SetConfirmNewLogins(r.Context(), userID, *req.Enabled); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "User not found")
+			return
+		}
+		slog.Error("Failed to set login confirmation", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to update login confirmation")
+		return
+	}
+
+	action := models.AuditLoginConfirmationDisabled
+	if *req.Enabled {
+		action = models.AuditLoginConfirmationEnabled
+	}
+	h.record(r, userID, action, nil)
+
+	user, ok := h.currentUser(w, r)
+	if !ok {
+		return
+	}
+	response.Success(w, dto.NewUser(user))
+}
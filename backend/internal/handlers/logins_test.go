@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/logins"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+const (
+	firefoxOnWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0"
+	safariOnIOS      = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1"
+)
+
+// fakeChallenger holds sign-ins in memory, each confirmed by its code
+type fakeChallenger struct {
+	mu      sync.Mutex
+	pending map[string]logins.Pending
+	codes   map[string]string
+}
+
+func newFakeChallenger() *fakeChallenger {
+	return &fakeChallenger{
+		pending: make(map[string]logins.Pending),
+		codes:   make(map[string]string),
+	}
+}
+
+func (c *fakeChallenger) Start(ctx context.Context, pending logins.Pending) (string, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	loginToken := uuid.NewString()
+	c.pending[loginToken] = pending
+	c.codes[loginToken] = "123456"
+	return loginToken, "123456", nil
+}
+
+func (c *fakeChallenger) Confirm(ctx context.Context, loginToken, code string) (*logins.Pending, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pending, ok := c.pending[loginToken]
+	if !ok {
+		return nil, logins.ErrNoChallenge
+	}
+	if code != c.codes[loginToken] {
+		return nil, logins.ErrInvalidCode
+	}
+	delete(c.pending, loginToken)
+	return &pending, nil
+}
+
+// failingFingerprints fails every lookup
+type failingFingerprints struct{}
+
+func (failingFingerprints) IsFamiliar(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) (bool, error) {
+	return false, errors.New("database down")
+}
+
+func (failingFingerprints) Record(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) error {
+	return errors.New("database down")
+}
+
+// loginGuardFixture is an auth handler guarding sign-ins, with a user who
+// has signed in once from Firefox on Windows in Germany
+type loginGuardFixture struct {
+	handler  *AuthHandler
+	users    *memstore.UserStore
+	notifier *fakeNotifier
+	user     *models.User
+}
+
+func newLoginGuardFixture(t *testing.T, fingerprints LoginFingerprintStorer) *loginGuardFixture {
+	t.Helper()
+
+	handler, users, notifier := newTestAuthHandler()
+	handler.WithLoginGuard(NewLoginGuard(fingerprints, newFakeChallenger(), notifier).WithLocator(logins.HeaderLocator("CF-IPCountry")))
+
+	f := &loginGuardFixture{handler: handler, users: users, notifier: notifier, user: seedUser(t, users, "user@example.com")}
+	if rec := f.login(t, firefoxOnWindows, "DE"); rec.Code != http.StatusOK {
+		t.Fatalf("first Login() status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	return f
+}
+
+// login signs the user in with a user agent from a country
+func (f *loginGuardFixture) login(t *testing.T, userAgent, country string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req := newJSONRequest(t, http.MethodPost, "/api/v1/auth/login", LoginRequest{Email: f.user.Email, Password: testPassword})
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("CF-IPCountry", country)
+
+	rec := httptest.NewRecorder()
+	f.handler.Login(rec, req)
+	return rec
+}
+
+func TestAuthHandler_Login_NewFingerprint(t *testing.T) {
+	tests := []struct {
+		name       string
+		userAgent  string
+		country    string
+		optOut     bool
+		wantStatus int
+		// wantEmail is whether the user is told about the sign-in
+		wantEmail bool
+	}{
+		{name: "familiar", userAgent: firefoxOnWindows, country: "DE", wantStatus: http.StatusOK},
+		{name: "unknown country", userAgent: firefoxOnWindows, country: "", wantStatus: http.StatusOK},
+		{name: "new device", userAgent: safariOnIOS, country: "DE", wantStatus: http.StatusAccepted, wantEmail: true},
+		{name: "new country", userAgent: firefoxOnWindows, country: "BR", wantStatus: http.StatusAccepted, wantEmail: true},
+		{name: "opted out", userAgent: safariOnIOS, country: "BR", optOut: true, wantStatus: http.StatusOK, wantEmail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newLoginGuardFixture(t, memstore.NewLoginFingerprintStore())
+			if tt.optOut {
+				f.users.SetConfirmNewLogins(context.Background(), f.user.ID, false)
+			}
+
+			rec := f.login(t, tt.userAgent, tt.country)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Login() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			code, emailed := f.notifier.newLogins[f.user.Email]
+			if emailed != tt.wantEmail {
+				t.Errorf("new login emailed = %v, want %v", emailed, tt.wantEmail)
+			}
+			if tt.wantStatus == http.StatusAccepted {
+				var resp LoginChallengeResponse
+				decodeBody(t, rec, &resp)
+				if !resp.ConfirmationRequired || resp.LoginToken == "" || code == "" {
+					t.Errorf("response = %+v, emailed code = %q", resp, code)
+				}
+			} else if code != "" {
+				t.Errorf("emailed code %q for a sign-in that wasn't held", code)
+			}
+		})
+	}
+}
+
+func TestAuthHandler_ConfirmLogin(t *testing.T) {
+	f := newLoginGuardFixture(t, memstore.NewLoginFingerprintStore())
+
+	rec := f.login(t, safariOnIOS, "DE")
+	var challenge LoginChallengeResponse
+	decodeBody(t, rec, &challenge)
+	code := f.notifier.newLogins[f.user.Email]
+
+	confirm := func(loginToken, code string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		f.handler.ConfirmLogin(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/confirm-login", ConfirmLoginRequest{LoginToken: loginToken, Code: code}))
+		return rec
+	}
+
+	if rec := confirm(challenge.LoginToken, "000000"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong code status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := confirm("", code); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing token status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	rec = confirm(challenge.LoginToken, code)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ConfirmLogin() status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp AuthResponse
+	decodeBody(t, rec, &resp)
+	if resp.Token == nil || resp.User.ID != f.user.ID.String() {
+		t.Errorf("ConfirmLogin() = %+v", resp)
+	}
+
+	// Confirmed once, the sign-in can't be replayed, and the device is
+	// now familiar
+	if rec := confirm(challenge.LoginToken, code); rec.Code != http.StatusUnauthorized {
+		t.Errorf("replayed status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := f.login(t, safariOnIOS, "DE"); rec.Code != http.StatusOK {
+		t.Errorf("Login() from confirmed device status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestAuthHandler_Login_FingerprintsUnavailable(t *testing.T) {
+	// Sign-ins go ahead while fingerprints can't be checked
+	f := newLoginGuardFixture(t, failingFingerprints{})
+
+	if rec := f.login(t, safariOnIOS, "BR"); rec.Code != http.StatusOK {
+		t.Errorf("Login() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestAccountHandler_SetLoginConfirmation(t *testing.T) {
+	f := newAccountFixture(t)
+
+	tests := []struct {
+		name       string
+		body       interface{}
+		wantStatus int
+		want       bool
+	}{
+		{"turn off", map[string]bool{"enabled": false}, http.StatusOK, false},
+		{"turn on", map[string]bool{"enabled": true}, http.StatusOK, true},
+		{"missing", map[string]string{}, http.StatusUnprocessableEntity, true},
+		{"malformed body", "not json", http.StatusBadRequest, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.handler.SetLoginConfirmation(rec, withUser(newJSONRequest(t, http.MethodPut, "/api/v1/me/login-confirmation", tt.body), f.user.ID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if user, _ := f.users.GetByID(context.Background(), f.user.ID); user.ConfirmNewLogins != tt.want {
+				t.Errorf("ConfirmNewLogins = %v, want %v", user.ConfirmNewLogins, tt.want)
+			}
+		})
+	}
+
+	want := []models.AuditAction{models.AuditLoginConfirmationDisabled, models.AuditLoginConfirmationEnabled}
+	if got := f.actions(); !slices.Equal(got, want) {
+		t.Errorf("audit actions = %v, want %v", got, want)
+	}
+}
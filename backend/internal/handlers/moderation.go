@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// maxOverrideReasonLength bounds the reason recorded with an override, in
+// characters
+const maxOverrideReasonLength = 500
+
+// ModerationHandler lets operators override moderation policy decisions
+type ModerationHandler struct {
+	store PolicyOverrider
+}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler(store PolicyOverrider) *ModerationHandler {
+	return &ModerationHandler{store: store}
+}
+
+// PolicyOverrideRequest overrides a submission's policy decision, or
+// clears the override when Action is null
+type PolicyOverrideRequest struct {
+	Action *string `json:"action"`
+	Reason string  `json:"reason"`
+}
+
+// PolicyOverrideResponse is a submission's override and the decision now
+// in force, which is nil until the submission has been analyzed
+type PolicyOverrideResponse struct {
+	SubmissionID   uuid.UUID              `json:"submission_id"`
+	Override       *models.PolicyOverride `json:"override"`
+	PolicyDecision *models.PolicyDecision `json:"policy_decision"`
+}
+
+// SetOverride replaces whatever the owner's organizations' moderation
+// policy decided for a submission, for its latest analysis and any later
+// ones, until the override is cleared
+// PUT /api/v1/admin/submissions/{id}/policy-override
+func (h *ModerationHandler) SetOverride(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	var req PolicyOverrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	var override *models.PolicyOverride
+	if req.Action != nil {
+		action, err := models.ParsePolicyAction(*req.Action)
+		if err != nil {
+			response.ValidationError(w, map[string]string{"action": err.Error()})
+			return
+		}
+		reason := strings.TrimSpace(req.Reason)
+		if utf8.RuneCountInString(reason) > maxOverrideReasonLength {
+			response.ValidationError(w, map[string]string{"reason": "reason must be at most 500 characters"})
+			return
+		}
+		override = &models.PolicyOverride{Action: action, Reason: reason, By: operator(r), At: timestamp.Now()}
+	}
+
+	decision, err := h.store.SetOverride(r.Context(), id, override)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to set policy override", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to set policy override")
+		return
+	}
+
+	action := "cleared"
+	if override != nil {
+		action = string(override.Action)
+	}
+	slog.Warn("Policy override changed", "submission_id", id, "override", action, "by", operator(r))
+	response.Success(w, PolicyOverrideResponse{SubmissionID: id, Override: override, PolicyDecision: decision})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestModerationHandler_SetOverride(t *testing.T) {
+	ctx := context.Background()
+	users := memstore.NewUserStore()
+	submissions := memstore.NewSubmissionStore()
+	store := memstore.NewModerationStore(memstore.NewOrganizationStore(users), submissions)
+
+	blocked, err := submissions.Create(ctx, uuid.New(), "Offensive text.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("failed to seed submission: %v", err)
+	}
+	threshold := 0.8
+	completeSubmission(t, submissions, &models.Analysis{
+		SubmissionID: blocked.ID,
+		PolicyDecision: models.EvaluatePolicy(
+			&models.ModerationPolicy{Thresholds: []models.ModerationThreshold{{Category: models.ModerationToxicity, BlockAbove: &threshold}}},
+			models.ModerationScores{models.ModerationToxicity: 0.9},
+		),
+	})
+	pending, err := submissions.Create(ctx, uuid.New(), "Not analyzed yet.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("failed to seed submission: %v", err)
+	}
+
+	r := chi.NewRouter()
+	r.Put("/admin/submissions/{id}/policy-override", NewModerationHandler(store).SetOverride)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantAction models.PolicyAction
+	}{
+		{name: "invalid id", id: "nope", body: `{"action": "allow"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown submission", id: uuid.NewString(), body: `{"action": "allow"}`, wantStatus: http.StatusNotFound},
+		{name: "unknown action", id: blocked.ID.String(), body: `{"action": "ignore"}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "allow", id: blocked.ID.String(), body: `{"action": "allow", "reason": "Satire"}`, wantStatus: http.StatusOK, wantAction: models.PolicyAllow},
+		{name: "clear", id: blocked.ID.String(), body: `{"action": null}`, wantStatus: http.StatusOK, wantAction: models.PolicyBlock},
+		{name: "not analyzed", id: pending.ID.String(), body: `{"action": "block"}`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPut, "/admin/submissions/"+tt.id+"/policy-override", tt.body), uuid.New()))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SetOverride() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp PolicyOverrideResponse
+			decodeBody(t, rec, &resp)
+			if tt.wantAction == "" {
+				if resp.PolicyDecision != nil {
+					t.Errorf("PolicyDecision = %+v, want nil before analysis", resp.PolicyDecision)
+				}
+				return
+			}
+			if resp.PolicyDecision == nil || resp.PolicyDecision.Action != tt.wantAction || resp.PolicyDecision.PolicyAction != models.PolicyBlock {
+				t.Errorf("PolicyDecision = %+v, want %s in force over the policy's block", resp.PolicyDecision, tt.wantAction)
+			}
+		})
+	}
+
+	// The override is kept for the next analysis
+	if override, _ := store.Override(ctx, pending.ID); override == nil || override.Action != models.PolicyBlock || override.By != "user@example.com" {
+		t.Errorf("Override() = %+v, want block by the operator", override)
+	}
+}
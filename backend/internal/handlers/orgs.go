@@ -0,0 +1,769 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+)
+
+// maxUsageRange bounds the date range of a usage report
+const maxUsageRange = 366 * 24 * time.Hour
+
+// OrgHandler manages organizations, their members, usage reports,
+// sentiment trends, moderation policies, glossaries, taxonomies, email
+// domains, single sign-on and SCIM tokens
+type OrgHandler struct {
+	orgs       OrganizationStorer
+	users      UserStorer
+	usage      UsageReporter
+	sentiment  SentimentTrendReporter
+	policies   ModerationPolicyStorer
+	glossaries GlossaryStorer
+	taxonomies TaxonomyStorer
+	sso        SSOConfigStorer
+	scim       SCIMTokenStorer
+	domains    OrgDomainStorer
+	// lookupTXT reads a name's TXT records to verify domains
+	lookupTXT func(ctx context.Context, name string) ([]string, error)
+}
+
+// NewOrgHandler creates a new organization handler
+func NewOrgHandler(orgs OrganizationStorer, users UserStorer, usage UsageReporter, policies ModerationPolicyStorer) *OrgHandler {
+	return &OrgHandler{orgs: orgs, users: users, usage: usage, policies: policies}
+}
+
+// WithGlossaries serves organizations' glossaries from glossaries and
+// returns the handler
+func (h *OrgHandler) WithGlossaries(glossaries GlossaryStorer) *OrgHandler {
+	h.glossaries = glossaries
+	return h
+}
+
+// WithTaxonomies serves organizations' taxonomies from taxonomies and
+// returns the handler
+func (h *OrgHandler) WithTaxonomies(taxonomies TaxonomyStorer) *OrgHandler {
+	h.taxonomies = taxonomies
+	return h
+}
+
+// WithSSO manages organizations' single sign-on setups in configs and
+// returns the handler
+func (h *OrgHandler) WithSSO(configs SSOConfigStorer) *OrgHandler {
+	h.sso = configs
+	return h
+}
+
+// WithSCIM issues organizations' SCIM tokens from tokens and returns the
+// handler
+func (h *OrgHandler) WithSCIM(tokens SCIMTokenStorer) *OrgHandler {
+	h.scim = tokens
+	return h
+}
+
+// CreateOrgRequest represents the organization creation request
+type CreateOrgRequest struct {
+	Name string `json:"name"`
+}
+
+// ModerationPolicyRequest replaces an organization's moderation thresholds
+type ModerationPolicyRequest struct {
+	Thresholds []models.ModerationThreshold `json:"thresholds"`
+}
+
+// GlossaryRequest replaces an organization's glossary entries
+type GlossaryRequest struct {
+	Entries []models.GlossaryEntry `json:"entries"`
+}
+
+// TaxonomyRequest replaces an organization's taxonomy labels
+type TaxonomyRequest struct {
+	Labels []models.TaxonomyLabel `json:"labels"`
+}
+
+// SetMemberRequest adds a user to an organization or changes their role
+type SetMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// OrgResponse is an organization with the caller's role and its members
+type OrgResponse struct {
+	*models.Organization
+	Role    models.OrgRole     `json:"role"`
+	Members []models.OrgMember `json:"members"`
+}
+
+// UsageSummary is usage with its cost in dollars
+type UsageSummary struct {
+	models.Usage
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// newUsageSummary converts a cost in micro-dollars to dollars
+func newUsageSummary(u models.Usage) UsageSummary {
+	return UsageSummary{Usage: u, CostUSD: float64(u.CostMicros) / 1e6}
+}
+
+// MemberUsageResponse is one member's usage
+type MemberUsageResponse struct {
+	UserID uuid.UUID      `json:"user_id"`
+	Email  string         `json:"email"`
+	Role   models.OrgRole `json:"role"`
+	UsageSummary
+}
+
+// OrgUsageResponse reports usage per member between two dates; To is
+// exclusive
+type OrgUsageResponse struct {
+	OrgID   uuid.UUID             `json:"org_id"`
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Members []MemberUsageResponse `json:"members"`
+	Total   UsageSummary          `json:"total"`
+}
+
+// Create creates an organization owned by the current user
+// POST /api/v1/orgs
+func (h *OrgHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req CreateOrgRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if err := models.ValidateOrgName(name); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	org, err := h.orgs.Create(r.Context(), name, userID)
+	if err != nil {
+		slog.Error("Failed to create organization", "error", err)
+		response.InternalServerError(w, "Failed to create organization")
+		return
+	}
+
+	response.Created(w, org)
+}
+
+// List returns the organizations the current user belongs to
+// GET /api/v1/orgs
+func (h *OrgHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	orgs, err := h.orgs.ListForUser(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list organizations", "error", err)
+		response.InternalServerError(w, "Failed to list organizations")
+		return
+	}
+	// On an organization's subdomain, only that organization is visible
+	if hostOrgID, ok := auth.GetHostOrgFromContext(r.Context()); ok {
+		orgs = slices.DeleteFunc(orgs, func(org models.Organization) bool { return org.ID != hostOrgID })
+	}
+
+	response.Success(w, response.Complete(orgs))
+}
+
+// Get returns an organization and its members
+// GET /api/v1/orgs/{id}
+func (h *OrgHandler) Get(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	org, err := h.orgs.Get(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to get organization", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get organization")
+		return
+	}
+
+	members, err := h.orgs.Members(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to list members", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get organization")
+		return
+	}
+
+	response.Success(w, OrgResponse{Organization: org, Role: role, Members: members})
+}
+
+// SetMember adds a registered user to the organization or changes their
+// role, and returns the updated member list. Admins manage members; only
+// owners grant or change admin and owner roles.
+// POST /api/v1/orgs/{id}/members
+func (h *OrgHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	orgID, _, callerRole, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	role := models.RoleMember
+	if req.Role != "" {
+		var err error
+		if role, err = models.ParseOrgRole(req.Role); err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+	}
+
+	user, err := h.users.GetByEmail(r.Context(), strings.ToLower(strings.TrimSpace(req.Email)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "No account with that email")
+			return
+		}
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to save member")
+		return
+	}
+
+	current, ok := h.memberRole(w, r, orgID, user.ID)
+	if !ok {
+		return
+	}
+	if !canAssign(callerRole, current, role) {
+		response.Forbidden(w, "Only owners can grant or change admin and owner roles")
+		return
+	}
+
+	if err := h.orgs.SetMember(r.Context(), orgID, user.ID, role); err != nil {
+		if errors.Is(err, models.ErrLastOwner) {
+			response.Conflict(w, err.Error())
+			return
+		}
+		slog.Error("Failed to save member", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to save member")
+		return
+	}
+
+	slog.Info("Organization member saved", "org_id", orgID, "user_id", user.ID, "role", role, "by", operator(r))
+
+	members, err := h.orgs.Members(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to list members", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to list members")
+		return
+	}
+	response.Success(w, response.Complete(members))
+}
+
+// RemoveMember takes a user out of the organization. Members may remove
+// themselves; removing others follows the SetMember rules.
+// DELETE /api/v1/orgs/{id}/members/{userID}
+func (h *OrgHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	orgID, callerID, callerRole, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+
+	if userID != callerID {
+		current, ok := h.memberRole(w, r, orgID, userID)
+		if !ok {
+			return
+		}
+		if current == "" {
+			response.NotFound(w, "Member not found")
+			return
+		}
+		if !canAssign(callerRole, current, models.RoleMember) {
+			response.Forbidden(w, "Only owners can remove admins and owners")
+			return
+		}
+	}
+
+	if err := h.orgs.RemoveMember(r.Context(), orgID, userID); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Member not found")
+		case errors.Is(err, models.ErrLastOwner):
+			response.Conflict(w, err.Error())
+		default:
+			slog.Error("Failed to remove member", "org_id", orgID, "error", err)
+			response.InternalServerError(w, "Failed to remove member")
+		}
+		return
+	}
+
+	slog.Info("Organization member removed", "org_id", orgID, "user_id", userID, "by", operator(r))
+	response.NoContent(w)
+}
+
+// SetRetention replaces the organization's retention policy. Submissions
+// of its members older than retention_days are purged by a nightly job
+// unless legal_hold is set. Owners only.
+// PUT /api/v1/orgs/{id}/retention
+func (h *OrgHandler) SetRetention(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can change the retention policy")
+		return
+	}
+
+	var policy models.RetentionPolicy
+	if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	org, err := h.orgs.SetRetention(r.Context(), orgID, policy)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Organization not found")
+			return
+		}
+		slog.Error("Failed to set retention policy", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set retention policy")
+		return
+	}
+
+	// Logged at warn: shortening retention destroys data on the next run
+	retention := "forever"
+	if policy.RetentionDays != nil {
+		retention = fmt.Sprintf("%d days", *policy.RetentionDays)
+	}
+	slog.Warn("Retention policy changed", "org_id", orgID, "retention", retention, "legal_hold", policy.LegalHold, "by", operator(r))
+	response.Success(w, org)
+}
+
+// SubdomainRequest sets or, with null, removes an organization's subdomain
+type SubdomainRequest struct {
+	Subdomain *string `json:"subdomain"`
+}
+
+// SetSubdomain serves the organization on a subdomain of ORG_BASE_DOMAIN,
+// or stops serving it on one. Owners only.
+// PUT /api/v1/orgs/{id}/subdomain
+func (h *OrgHandler) SetSubdomain(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can change the subdomain")
+		return
+	}
+
+	var req SubdomainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.Subdomain != nil {
+		subdomain := strings.ToLower(strings.TrimSpace(*req.Subdomain))
+		if err := models.ValidateSubdomain(subdomain); err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		req.Subdomain = &subdomain
+	}
+
+	org, err := h.orgs.SetSubdomain(r.Context(), orgID, req.Subdomain)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Organization not found")
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			response.Conflict(w, "Subdomain is already taken")
+		default:
+			slog.Error("Failed to set subdomain", "org_id", orgID, "error", err)
+			response.InternalServerError(w, "Failed to set subdomain")
+		}
+		return
+	}
+
+	subdomain := "none"
+	if org.Subdomain != nil {
+		subdomain = *org.Subdomain
+	}
+	slog.Info("Organization subdomain changed", "org_id", orgID, "subdomain", subdomain, "by", operator(r))
+	response.Success(w, org)
+}
+
+// GetModerationPolicy returns the organization's moderation thresholds, so
+// members can see what their submissions are held to
+// GET /api/v1/orgs/{id}/moderation-policy
+func (h *OrgHandler) GetModerationPolicy(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	policy, err := h.policies.Policy(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to get moderation policy", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get moderation policy")
+		return
+	}
+
+	response.Success(w, policy)
+}
+
+// SetModerationPolicy replaces the organization's moderation thresholds.
+// Members' submissions analyzed from then on are warned about or blocked
+// when a category scores above its threshold; an empty list removes the
+// policy. Owners and admins only.
+// PUT /api/v1/orgs/{id}/moderation-policy
+func (h *OrgHandler) SetModerationPolicy(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can change the moderation policy")
+		return
+	}
+
+	var req ModerationPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateThresholds(req.Thresholds); len(errs) > 0 {
+		response.ValidationError(w, errs)
+		return
+	}
+
+	policy, err := h.policies.SetPolicy(r.Context(), orgID, req.Thresholds)
+	if err != nil {
+		slog.Error("Failed to set moderation policy", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set moderation policy")
+		return
+	}
+
+	slog.Warn("Moderation policy changed", "org_id", orgID, "thresholds", len(policy.Thresholds), "by", operator(r))
+	response.Success(w, policy)
+}
+
+// GetGlossary returns the organization's glossary, so members can see the
+// terms their analyses are given and the phrases they must not use
+// GET /api/v1/orgs/{id}/glossary
+func (h *OrgHandler) GetGlossary(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	glossary, err := h.glossaries.Glossary(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to get glossary", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get glossary")
+		return
+	}
+
+	response.Success(w, glossary)
+}
+
+// SetGlossary replaces the organization's glossary. Members' submissions
+// analyzed from then on are analyzed with its terms and brand names, and
+// uses of its banned phrases are flagged; an empty list removes the
+// glossary. Owners and admins only.
+// PUT /api/v1/orgs/{id}/glossary
+func (h *OrgHandler) SetGlossary(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can change the glossary")
+		return
+	}
+
+	var req GlossaryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	errs := sanitizeGlossary(req.Entries)
+	for field, msg := range models.ValidateGlossary(req.Entries) {
+		if _, ok := errs[field]; !ok {
+			errs[field] = msg
+		}
+	}
+	if len(errs) > 0 {
+		response.ValidationError(w, errs)
+		return
+	}
+
+	glossary, err := h.glossaries.SetGlossary(r.Context(), orgID, req.Entries)
+	if err != nil {
+		slog.Error("Failed to set glossary", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set glossary")
+		return
+	}
+
+	slog.Warn("Glossary changed", "org_id", orgID, "entries", len(glossary.Entries), "by", operator(r))
+	response.Success(w, glossary)
+}
+
+// sanitizeGlossary cleans each entry's text in place, as it is given to
+// the model, and returns the fields it had to reject
+func sanitizeGlossary(entries []models.GlossaryEntry) map[string]string {
+	errs := make(map[string]string)
+	for i := range entries {
+		field := fmt.Sprintf("entries[%d]", i)
+		sanitizeField(errs, field+".phrase", &entries[i].Phrase)
+		sanitizeField(errs, field+".description", &entries[i].Description)
+		sanitizeField(errs, field+".replacement", &entries[i].Replacement)
+	}
+	return errs
+}
+
+// sanitizeField cleans text an organization gives the model in place, as
+// instructions are, or records why it was rejected in errs. Text that is
+// too long is left for the caller's validation to report with its own
+// limit.
+func sanitizeField(errs map[string]string, field string, value *string) {
+	cleaned, err := instructions.Sanitize(*value)
+	switch {
+	case errors.Is(err, instructions.ErrTooLong):
+	case err != nil:
+		errs[field] = "may only name or describe something, not instruct the analysis"
+	default:
+		*value = cleaned
+	}
+}
+
+// GetTaxonomy returns the organization's taxonomy, so members can see the
+// labels their submissions are classified into
+// GET /api/v1/orgs/{id}/taxonomy
+func (h *OrgHandler) GetTaxonomy(w http.ResponseWriter, r *http.Request) {
+	orgID, _, _, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+
+	taxonomy, err := h.taxonomies.Taxonomy(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to get taxonomy", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get taxonomy")
+		return
+	}
+
+	response.Success(w, taxonomy)
+}
+
+// SetTaxonomy replaces the organization's taxonomy. Members' submissions
+// analyzed from then on are classified into its labels; an empty list
+// removes the taxonomy. Owners and admins only.
+// PUT /api/v1/orgs/{id}/taxonomy
+func (h *OrgHandler) SetTaxonomy(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can change the taxonomy")
+		return
+	}
+
+	var req TaxonomyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	errs := make(map[string]string)
+	for i := range req.Labels {
+		field := fmt.Sprintf("labels[%d]", i)
+		sanitizeField(errs, field+".name", &req.Labels[i].Name)
+		sanitizeField(errs, field+".description", &req.Labels[i].Description)
+	}
+	for field, msg := range models.ValidateTaxonomy(req.Labels) {
+		if _, ok := errs[field]; !ok {
+			errs[field] = msg
+		}
+	}
+	if len(errs) > 0 {
+		response.ValidationError(w, errs)
+		return
+	}
+
+	taxonomy, err := h.taxonomies.SetTaxonomy(r.Context(), orgID, req.Labels)
+	if err != nil {
+		slog.Error("Failed to set taxonomy", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set taxonomy")
+		return
+	}
+
+	slog.Warn("Taxonomy changed", "org_id", orgID, "labels", len(taxonomy.Labels), "by", operator(r))
+	response.Success(w, taxonomy)
+}
+
+// Usage reports each member's submissions, token consumption and cost
+// between two dates, from the daily usage rollups. Owners and admins only.
+// GET /api/v1/orgs/{id}/usage?from=&to=
+func (h *OrgHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can see usage")
+		return
+	}
+
+	// Default to the last 30 days, including today
+	y, m, d := time.Now().UTC().Date()
+	to := time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -30)
+
+	query := r.URL.Query()
+	var err error
+	if v := query.Get("to"); v != "" {
+		if to, err = parseDateParam(v, time.UTC); err != nil {
+			response.BadRequest(w, "Invalid 'to' parameter: use RFC3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		if from, err = parseDateParam(v, time.UTC); err != nil {
+			response.BadRequest(w, "Invalid 'from' parameter: use RFC3339 or YYYY-MM-DD")
+			return
+		}
+	}
+	from, to = from.Truncate(24*time.Hour), to.Truncate(24*time.Hour)
+
+	if !from.Before(to) {
+		response.BadRequest(w, "'from' must be before 'to'")
+		return
+	}
+	if to.Sub(from) > maxUsageRange {
+		response.BadRequest(w, "Date range must be at most 366 days")
+		return
+	}
+
+	members, err := h.usage.OrgUsage(r.Context(), orgID, from, to)
+	if err != nil {
+		slog.Error("Failed to read organization usage", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to read usage")
+		return
+	}
+
+	resp := OrgUsageResponse{
+		OrgID:   orgID,
+		From:    from.Format(time.DateOnly),
+		To:      to.Format(time.DateOnly),
+		Members: make([]MemberUsageResponse, 0, len(members)),
+	}
+	var total models.Usage
+	for _, m := range members {
+		total.Add(m.Usage)
+		resp.Members = append(resp.Members, MemberUsageResponse{
+			UserID:       m.UserID,
+			Email:        m.Email,
+			Role:         m.Role,
+			UsageSummary: newUsageSummary(m.Usage),
+		})
+	}
+	resp.Total = newUsageSummary(total)
+
+	response.Success(w, resp)
+}
+
+// membership resolves the organization in the URL and the caller's role in
+// it. Organizations the caller doesn't belong to, and on an organization's
+// subdomain any other organization, are reported as not found.
+func (h *OrgHandler) membership(w http.ResponseWriter, r *http.Request) (orgID, userID uuid.UUID, role models.OrgRole, ok bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	orgID, err = uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid organization ID")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	if hostOrgID, ok := auth.GetHostOrgFromContext(r.Context()); ok && hostOrgID != orgID {
+		response.NotFound(w, "Organization not found")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	role, err = h.orgs.Role(r.Context(), orgID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Organization not found")
+			return uuid.Nil, uuid.Nil, "", false
+		}
+		slog.Error("Failed to get organization role", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get organization")
+		return uuid.Nil, uuid.Nil, "", false
+	}
+
+	return orgID, userID, role, true
+}
+
+// memberRole returns userID's current role, or "" if they aren't a member
+func (h *OrgHandler) memberRole(w http.ResponseWriter, r *http.Request, orgID, userID uuid.UUID) (models.OrgRole, bool) {
+	role, err := h.orgs.Role(r.Context(), orgID, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to get organization role", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to update members")
+		return "", false
+	}
+	return role, true
+}
+
+// canAssign reports whether a caller with role caller may move a member
+// from current ("" for non-members) to next
+func canAssign(caller, current, next models.OrgRole) bool {
+	switch caller {
+	case models.RoleOwner:
+		return true
+	case models.RoleAdmin:
+		return next == models.RoleMember && (current == "" || current == models.RoleMember)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// errNoPrecondition means the client didn't say which version it read
+var errNoPrecondition = errors.New("missing version precondition")
+
+// setETag exposes a row version as a strong entity tag so clients can send
+// it back in If-Match
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", versionETag(version))
+}
+
+// versionETag returns the entity tag of a row version
+func versionETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// variantETag returns the entity tag of one representation of a row
+// version: the version, then a digest of what else shaped the body, such
+// as the API version and ?include= or ?fields=. Each representation gets
+// its own strong tag, and expectedVersion reads the version back out of
+// it for If-Match.
+func variantETag(version int, variant ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(variant, ";")))
+	return `"` + strconv.Itoa(version) + "-" + hex.EncodeToString(sum[:4]) + `"`
+}
+
+// analysisETag returns the entity tag of an analysis as API version v
+// renders it, which changes when a new analysis replaces it or it is
+// updated
+func analysisETag(analysis *models.Analysis, v apiversion.Version) string {
+	return `"` + analysis.ID.String() + "-" + strconv.FormatInt(analysis.UpdatedAt.UnixMilli(), 10) + "-" + v.String() + `"`
+}
+
+// notModified sets the ETag and Last-Modified of a representation and
+// reports whether the client's cached copy is still current, in which case
+// it has responded 304. As in RFC 9110, If-Modified-Since is only
+// consulted without If-None-Match; it has whole-second precision, so
+// clients that poll should prefer If-None-Match.
+func notModified(w http.ResponseWriter, r *http.Request, etag string, modified time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		if !etagListContains(ifNoneMatch, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || modified.IsZero() || modified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListContains reports whether a comma-separated list of entity tags
+// matches etag, using the weak comparison If-None-Match calls for
+func etagListContains(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// expectedVersion returns the version an update is based on, taken from
+// the If-Match header or, failing that, the version field of the body
+func expectedVersion(r *http.Request, bodyVersion *int) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" {
+		if bodyVersion == nil {
+			return 0, errNoPrecondition
+		}
+		return *bodyVersion, nil
+	}
+
+	// Weak tags are accepted since versions are exact either way, as are
+	// the tags of particular representations
+	tag := strings.TrimPrefix(ifMatch, "W/")
+	unquoted, err := strconv.Unquote(tag)
+	if err != nil {
+		return 0, errors.New("If-Match must be a quoted version, such as \"3\"")
+	}
+	unquoted, _, _ = strings.Cut(unquoted, "-")
+	version, err := strconv.Atoi(unquoted)
+	if err != nil || version < 1 {
+		return 0, errors.New("If-Match must be a quoted version, such as \"3\"")
+	}
+	return version, nil
+}
+
+// writePreconditionError responds to a missing or malformed version
+func writePreconditionError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errNoPrecondition) {
+		response.PreconditionRequired(w, "Send the version you are updating in If-Match or the request body")
+		return
+	}
+	response.BadRequest(w, err.Error())
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 9, 30, 0, 500_000_000, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no validators", nil, false},
+		{"matching tag", map[string]string{"If-None-Match": `"3"`}, true},
+		{"weak tag in a list", map[string]string{"If-None-Match": `"2", W/"3"`}, true},
+		{"any tag", map[string]string{"If-None-Match": "*"}, true},
+		{"stale tag", map[string]string{"If-None-Match": `"2"`}, false},
+		{"same second", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 09:30:00 GMT"}, true},
+		{"later", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 10:00:00 GMT"}, true},
+		{"earlier", map[string]string{"If-Modified-Since": "Wed, 01 May 2024 09:29:59 GMT"}, false},
+		{"malformed date", map[string]string{"If-Modified-Since": "yesterday"}, false},
+		{"tag wins over date", map[string]string{"If-None-Match": `"2"`, "If-Modified-Since": "Wed, 01 May 2024 10:00:00 GMT"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for name, value := range tt.headers {
+				r.Header.Set(name, value)
+			}
+			rec := httptest.NewRecorder()
+
+			if got := notModified(rec, r, `"3"`, modified); got != tt.want {
+				t.Errorf("notModified() = %v, want %v", got, tt.want)
+			}
+			if tt.want && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusNotModified)
+			}
+			if rec.Header().Get("ETag") != `"3"` || rec.Header().Get("Last-Modified") != "Wed, 01 May 2024 09:30:00 GMT" {
+				t.Errorf("headers = %v, want the ETag and Last-Modified", rec.Header())
+			}
+		})
+	}
+}
+
+func TestVariantETag(t *testing.T) {
+	plain := variantETag(3, "v2", "", "")
+	if !strings.HasPrefix(plain, `"3-`) {
+		t.Errorf("variantETag() = %s, want the version first", plain)
+	}
+	if variantETag(3, "v2", "", "") != plain {
+		t.Error("variantETag() isn't stable")
+	}
+	for _, other := range []string{variantETag(4, "v2", "", ""), variantETag(3, "v1", "", ""), variantETag(3, "v2", "analysis", ""), variantETag(3, "v2", "", "id")} {
+		if other == plain {
+			t.Errorf("variantETag() = %s for two representations", other)
+		}
+	}
+
+	// The tag can be sent back in If-Match
+	r := httptest.NewRequest(http.MethodPatch, "/", nil)
+	r.Header.Set("If-Match", plain)
+	if version, err := expectedVersion(r, nil); err != nil || version != 3 {
+		t.Errorf("expectedVersion(%s) = %d, %v, want 3", plain, version, err)
+	}
+}
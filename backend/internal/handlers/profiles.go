@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+)
+
+// ProfileHandler manages analysis profiles. Everyone can use the built-in
+// profiles; users define and edit their own.
+type ProfileHandler struct {
+	store ProfileStorer
+}
+
+// NewProfileHandler creates a new analysis profile handler
+func NewProfileHandler(store ProfileStorer) *ProfileHandler {
+	return &ProfileHandler{store: store}
+}
+
+// ProfileRequest creates or replaces a profile. Prompt follows the same
+// rules as submission instructions; leaving out Modules runs all of them.
+type ProfileRequest struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description"`
+	Prompt      string                  `json:"prompt"`
+	Modules     []models.AnalysisModule `json:"modules"`
+}
+
+// profile validates the request as a profile owned by userID
+func (req *ProfileRequest) profile(userID uuid.UUID) (*models.AnalysisProfile, error) {
+	prompt, err := instructions.Sanitize(req.Prompt)
+	switch {
+	case errors.Is(err, instructions.ErrTooLong):
+		return nil, fmt.Errorf("prompt must be at most %d characters", instructions.MaxLength)
+	case err != nil:
+		return nil, errors.New("prompt may only describe what the analysis should focus on")
+	}
+
+	profile := &models.AnalysisProfile{
+		UserID:      &userID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Prompt:      prompt,
+		Modules:     req.Modules,
+	}
+	if profile.Modules == nil {
+		profile.Modules = models.AllModules
+	}
+	return profile, profile.Validate()
+}
+
+// List returns the built-in profiles and the user's own
+// GET /api/v1/profiles
+func (h *ProfileHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	profiles, err := h.store.List(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list analysis profiles", "error", err)
+		response.InternalServerError(w, "Failed to list analysis profiles")
+		return
+	}
+
+	response.Success(w, response.Complete(profiles))
+}
+
+// Get returns a built-in profile or one of the user's own
+// GET /api/v1/profiles/{id}
+func (h *ProfileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	profile, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	response.Success(w, profile)
+}
+
+// Create defines a new profile for the current user
+// POST /api/v1/profiles
+func (h *ProfileHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	profile, err := req.profile(userID)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	stored, err := h.store.Create(r.Context(), profile)
+	if err != nil {
+		h.writeError(w, err, "Failed to create analysis profile")
+		return
+	}
+
+	response.Created(w, stored)
+}
+
+// Update replaces one of the user's profiles. Built-in profiles are read-only.
+// PUT /api/v1/profiles/{id}
+func (h *ProfileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if existing.BuiltIn {
+		response.Forbidden(w, "Built-in profiles can't be changed")
+		return
+	}
+
+	var req ProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	profile, err := req.profile(*existing.UserID)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	profile.ID = existing.ID
+
+	stored, err := h.store.Update(r.Context(), profile)
+	if err != nil {
+		h.writeError(w, err, "Failed to update analysis profile")
+		return
+	}
+
+	response.Success(w, stored)
+}
+
+// Delete removes one of the user's profiles. Submissions that used it are
+// analyzed with every module from then on.
+// DELETE /api/v1/profiles/{id}
+func (h *ProfileHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	existing, ok := h.load(w, r)
+	if !ok {
+		return
+	}
+	if existing.BuiltIn {
+		response.Forbidden(w, "Built-in profiles can't be deleted")
+		return
+	}
+
+	if err := h.store.Delete(r.Context(), *existing.UserID, existing.ID); err != nil {
+		h.writeError(w, err, "Failed to delete analysis profile")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// load reads the profile in the URL, writing a response and returning
+// false if the user can't see it
+func (h *ProfileHandler) load(w http.ResponseWriter, r *http.Request) (*models.AnalysisProfile, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid profile ID")
+		return nil, false
+	}
+
+	profile, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to load analysis profile")
+		return nil, false
+	}
+	return profile, true
+}
+
+// writeError maps store errors to responses
+func (h *ProfileHandler) writeError(w http.ResponseWriter, err error, message string) {
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		response.NotFound(w, "Analysis profile not found")
+	case errors.As(err, &pgErr) && pgErr.Code == "23505":
+		response.Conflict(w, "A profile with this name already exists")
+	case errors.Is(err, models.ErrProfileLimit):
+		response.Conflict(w, "Profile limit reached; delete a profile first")
+	default:
+		slog.Error(message, "error", err)
+		response.InternalServerError(w, message)
+	}
+}
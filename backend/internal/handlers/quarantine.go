@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// defaultQuarantineReason is recorded when an operator quarantines a
+// submission without saying why
+const defaultQuarantineReason = "Quarantined by an operator"
+
+// QuarantineHandler lets operators quarantine submissions, inspect what
+// has been quarantined, and release or destroy it. Owners are emailed
+// about each decision, and each is recorded in their audit log.
+type QuarantineHandler struct {
+	store    QuarantineStorer
+	users    UserStorer
+	notifier QuarantineNotifier
+	audit    AuditRecorder
+}
+
+// NewQuarantineHandler creates a new quarantine handler
+func NewQuarantineHandler(store QuarantineStorer, users UserStorer, notifier QuarantineNotifier, audit AuditRecorder) *QuarantineHandler {
+	return &QuarantineHandler{store: store, users: users, notifier: notifier, audit: audit}
+}
+
+// QuarantineRequest quarantines a submission
+type QuarantineRequest struct {
+	Reason string `json:"reason"`
+}
+
+// QuarantinedSubmission is a quarantined submission with its latest
+// analysis, which is nil if it hasn't been analyzed
+type QuarantinedSubmission struct {
+	Submission *dto.Submission `json:"submission"`
+	Analysis   *dto.Analysis   `json:"analysis"`
+}
+
+// List returns a page of quarantined submissions, oldest quarantine first
+// GET /api/v1/admin/quarantine
+func (h *QuarantineHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	submissions, total, err := h.store.ListQuarantined(r.Context(), limit, offset)
+	if err != nil {
+		slog.Error("Failed to list quarantined submissions", "error", err)
+		response.InternalServerError(w, "Failed to list quarantined submissions")
+		return
+	}
+
+	response.Success(w, response.NewList(dto.NewSubmissions(submissions), total, limit, offset))
+}
+
+// Get returns a quarantined submission with its content and analysis
+// GET /api/v1/admin/quarantine/{id}
+func (h *QuarantineHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := h.store.GetQuarantined(r.Context(), id)
+	if err != nil {
+		h.failed(w, err, id, "Failed to get quarantined submission")
+		return
+	}
+
+	analysis, err := h.store.GetAnalysis(r.Context(), submission.UserID, id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to get analysis", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to get quarantined submission")
+		return
+	}
+
+	response.Success(w, QuarantinedSubmission{
+		Submission: dto.NewSubmission(submission),
+		Analysis:   dto.NewAnalysis(analysis, apiversion.FromContext(r.Context())),
+	})
+}
+
+// Quarantine hides a submission from its owner's listings and from
+// integrations until it is released
+// POST /api/v1/admin/submissions/{id}/quarantine
+func (h *QuarantineHandler) Quarantine(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	var req QuarantineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	if utf8.RuneCountInString(reason) > maxOverrideReasonLength {
+		response.ValidationError(w, map[string]string{"reason": "reason must be at most 500 characters"})
+		return
+	}
+	if reason == "" {
+		reason = defaultQuarantineReason
+	}
+
+	submission, err := h.store.Quarantine(r.Context(), id, reason)
+	if err != nil {
+		h.failed(w, err, id, "Failed to quarantine submission")
+		return
+	}
+
+	h.decided(r, submission, models.AuditSubmissionQuarantined, notifications.QuarantineQuarantined, reason)
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// Release lifts a submission's quarantine
+// POST /api/v1/admin/quarantine/{id}/release
+func (h *QuarantineHandler) Release(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := h.store.Release(r.Context(), id)
+	if err != nil {
+		h.failed(w, err, id, "Failed to release submission")
+		return
+	}
+
+	h.decided(r, submission, models.AuditSubmissionReleased, notifications.QuarantineReleased, "")
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// Destroy permanently deletes a quarantined submission and its analyses.
+// Its attachments are deleted by the next upload purge.
+// DELETE /api/v1/admin/quarantine/{id}
+func (h *QuarantineHandler) Destroy(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := h.store.Destroy(r.Context(), id)
+	if err != nil {
+		h.failed(w, err, id, "Failed to destroy submission")
+		return
+	}
+
+	var reason string
+	if submission.QuarantineReason != nil {
+		reason = *submission.QuarantineReason
+	}
+	h.decided(r, submission, models.AuditSubmissionDestroyed, notifications.QuarantineDestroyed, reason)
+	response.NoContent(w)
+}
+
+// submissionID reads the submission ID in the URL
+func (h *QuarantineHandler) submissionID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// failed responds to a store error, which is pgx.ErrNoRows when there is
+// no such submission in the expected state
+func (h *QuarantineHandler) failed(w http.ResponseWriter, err error, id uuid.UUID, message string) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		response.NotFound(w, "Quarantined submission not found")
+		return
+	}
+	slog.Error(message, "submission_id", id, "error", err)
+	response.InternalServerError(w, message)
+}
+
+// decided records an operator's decision in the owner's audit log and
+// emails the owner. Neither fails the request once the decision is made.
+func (h *QuarantineHandler) decided(r *http.Request, submission *models.Submission, action models.AuditAction, outcome, reason string) {
+	// Record and notify even if the client has gone away mid-request
+	ctx := context.WithoutCancel(r.Context())
+	slog.Warn("Quarantine decision", "submission_id", submission.ID, "outcome", outcome, "by", operator(r))
+
+	metadata := map[string]string{"submission_id": submission.ID.String(), "by": operator(r)}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	entry := &models.AuditEntry{
+		UserID:    submission.UserID,
+		Action:    action,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  metadata,
+	}
+	if err := h.audit.Record(ctx, entry); err != nil {
+		slog.Error("Failed to record audit entry", "action", action, "user_id", submission.UserID, "error", err)
+	}
+
+	owner, err := h.users.GetByID(ctx, submission.UserID)
+	if err != nil {
+		slog.Error("Failed to get submission owner", "submission_id", submission.ID, "error", err)
+		return
+	}
+	if err := h.notifier.SendQuarantine(ctx, owner.Email, outcome, submission.ID, submission.CreatedAt.Time, reason); err != nil {
+		slog.Error("Failed to send quarantine email", "submission_id", submission.ID, "error", err)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// quarantineFixture is an owner with a submission the moderation policy
+// blocked, the operator reviewing it, and the handler's dependencies
+type quarantineFixture struct {
+	router      testRouter
+	operator    uuid.UUID
+	submissions *memstore.SubmissionStore
+	notifier    *fakeNotifier
+	audit       *memstore.AuditStore
+	owner       *models.User
+	blocked     *models.Submission
+}
+
+func newQuarantineFixture(t *testing.T) *quarantineFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	users := memstore.NewUserStore()
+	f := &quarantineFixture{
+		router:      newTestRouter(),
+		operator:    uuid.New(),
+		submissions: memstore.NewSubmissionStore(),
+		notifier:    newFakeNotifier(),
+		audit:       memstore.NewAuditStore(),
+		owner:       seedUser(t, users, "owner@example.com"),
+	}
+
+	var err error
+	if f.blocked, err = f.submissions.Create(ctx, f.owner.ID, "Offensive text.", nil, nil, nil, models.StatusQueued); err != nil {
+		t.Fatal(err)
+	}
+	threshold := 0.8
+	completeSubmission(t, f.submissions, &models.Analysis{
+		SubmissionID: f.blocked.ID,
+		PolicyDecision: models.EvaluatePolicy(
+			&models.ModerationPolicy{Thresholds: []models.ModerationThreshold{{Category: models.ModerationToxicity, BlockAbove: &threshold}}},
+			models.ModerationScores{models.ModerationToxicity: 0.9},
+		),
+	})
+
+	handler := NewQuarantineHandler(f.submissions, users, f.notifier, f.audit)
+	f.router.Get("/admin/quarantine", handler.List)
+	f.router.Get("/admin/quarantine/{id}", handler.Get)
+	f.router.Post("/admin/quarantine/{id}/release", handler.Release)
+	f.router.Delete("/admin/quarantine/{id}", handler.Destroy)
+	f.router.Post("/admin/submissions/{id}/quarantine", handler.Quarantine)
+	return f
+}
+
+// lastAudit returns the action of the latest audit entry, or ""
+func (f *quarantineFixture) lastAudit() models.AuditAction {
+	entries := f.audit.Entries()
+	if len(entries) == 0 {
+		return ""
+	}
+	return entries[len(entries)-1].Action
+}
+
+func TestQuarantineHandler_Inspect(t *testing.T) {
+	f := newQuarantineFixture(t)
+
+	rec := f.router.do(t, f.operator, http.MethodGet, "/admin/quarantine", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var list response.ListResponse[models.Submission]
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 1 || list.Data[0].ID != f.blocked.ID || list.Data[0].QuarantineReason == nil {
+		t.Fatalf("quarantined = %+v, want the blocked submission with a reason", list.Data)
+	}
+
+	rec = f.router.do(t, f.operator, http.MethodGet, "/admin/quarantine/"+f.blocked.ID.String(), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("get status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var detail struct {
+		Submission *dto.Submission  `json:"submission"`
+		Analysis   *models.Analysis `json:"analysis"`
+	}
+	decodeBody(t, rec, &detail)
+	if detail.Submission.Content != "Offensive text." || detail.Analysis == nil || detail.Analysis.PolicyDecision.Action != models.PolicyBlock {
+		t.Errorf("detail = %+v, want the content and blocking analysis", detail)
+	}
+
+	if rec := f.router.do(t, f.operator, http.MethodGet, "/admin/quarantine/"+uuid.NewString(), nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown submission status = %d, want 404", rec.Code)
+	}
+}
+
+func TestQuarantineHandler_Release(t *testing.T) {
+	f := newQuarantineFixture(t)
+	ctx := context.Background()
+
+	rec := f.router.do(t, f.operator, http.MethodPost, "/admin/quarantine/"+f.blocked.ID.String()+"/release", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := f.notifier.quarantines[f.owner.Email]; got != notifications.QuarantineReleased {
+		t.Errorf("emailed %q, want %q", got, notifications.QuarantineReleased)
+	}
+	if got := f.lastAudit(); got != models.AuditSubmissionReleased {
+		t.Errorf("audited %q, want %q", got, models.AuditSubmissionReleased)
+	}
+
+	submissions, total, _ := f.submissions.List(ctx, f.owner.ID, models.SubmissionFilter{}, 10, 0)
+	if total != 1 || submissions[0].QuarantinedAt != nil {
+		t.Errorf("owner's submissions = %+v, want the released submission", submissions)
+	}
+
+	// Only quarantined submissions can be released
+	if rec := f.router.do(t, f.operator, http.MethodPost, "/admin/quarantine/"+f.blocked.ID.String()+"/release", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("second release status = %d, want 404", rec.Code)
+	}
+}
+
+func TestQuarantineHandler_Destroy(t *testing.T) {
+	f := newQuarantineFixture(t)
+	ctx := context.Background()
+
+	rec := f.router.do(t, f.operator, http.MethodDelete, "/admin/quarantine/"+f.blocked.ID.String(), nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := f.notifier.quarantines[f.owner.Email]; got != notifications.QuarantineDestroyed {
+		t.Errorf("emailed %q, want %q", got, notifications.QuarantineDestroyed)
+	}
+	if got := f.lastAudit(); got != models.AuditSubmissionDestroyed {
+		t.Errorf("audited %q, want %q", got, models.AuditSubmissionDestroyed)
+	}
+	if _, err := f.submissions.GetByID(ctx, f.owner.ID, f.blocked.ID); err == nil {
+		t.Error("destroyed submission still exists")
+	}
+}
+
+func TestQuarantineHandler_Quarantine(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantReason string
+	}{
+		{"with a reason", `{"reason": "Reported by three users"}`, http.StatusOK, "Reported by three users"},
+		{"without a reason", `{}`, http.StatusOK, defaultQuarantineReason},
+		{"reason too long", `{"reason": "` + strings.Repeat("a", 501) + `"}`, http.StatusUnprocessableEntity, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newQuarantineFixture(t)
+			submission, _ := f.submissions.Create(ctx, f.owner.ID, "Fine text.", nil, nil, nil, models.StatusQueued)
+
+			rec := f.router.do(t, f.operator, http.MethodPost, "/admin/submissions/"+submission.ID.String()+"/quarantine", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var quarantined models.Submission
+			decodeBody(t, rec, &quarantined)
+			if quarantined.QuarantinedAt == nil || *quarantined.QuarantineReason != tt.wantReason {
+				t.Errorf("submission = %+v, want it quarantined for %q", quarantined, tt.wantReason)
+			}
+			if got := f.notifier.quarantines[f.owner.Email]; got != notifications.QuarantineQuarantined {
+				t.Errorf("emailed %q, want %q", got, notifications.QuarantineQuarantined)
+			}
+			if got := f.lastAudit(); got != models.AuditSubmissionQuarantined {
+				t.Errorf("audited %q, want %q", got, models.AuditSubmissionQuarantined)
+			}
+		})
+	}
+
+	t.Run("unknown submission", func(t *testing.T) {
+		f := newQuarantineFixture(t)
+		if rec := f.router.do(t, f.operator, http.MethodPost, "/admin/submissions/"+uuid.NewString()+"/quarantine", `{}`); rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+	})
+}
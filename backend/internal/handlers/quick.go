@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sfumato00/content-analyzer/internal/abuse"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/outbound"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/feeds"
+)
+
+const (
+	// QuickMaxContentLength is the most text a quick analysis reads, in
+	// characters. Typed text over it is rejected; fetched pages are cut.
+	QuickMaxContentLength = 5000
+
+	// maxQuickPageSize is the largest page fetched for a quick analysis,
+	// in bytes
+	maxQuickPageSize = 2 << 20
+)
+
+// QuickAnalyzeHandler serves synchronous, unsaved analyses of a snippet
+// of text or a web page for the browser extension and bookmarklet. It is
+// authenticated by API key and rate limited per key.
+type QuickAnalyzeHandler struct {
+	analyzer   QuickAnalyzer
+	limiter    *abuse.Limiter
+	httpClient *http.Client
+}
+
+// NewQuickAnalyzeHandler creates a new quick analysis handler. Pages are
+// fetched with a default outbound client, which refuses internal
+// addresses, until WithHTTPClient replaces it.
+func NewQuickAnalyzeHandler(analyzer QuickAnalyzer) *QuickAnalyzeHandler {
+	return &QuickAnalyzeHandler{
+		analyzer:   analyzer,
+		httpClient: outbound.New(outbound.Config{}).HTTPClient(10 * time.Second),
+	}
+}
+
+// WithHTTPClient fetches pages with client and returns the handler
+func (h *QuickAnalyzeHandler) WithHTTPClient(client *http.Client) *QuickAnalyzeHandler {
+	h.httpClient = client
+	return h
+}
+
+// WithRateLimit limits the analyses each API key can run and returns the
+// handler
+func (h *QuickAnalyzeHandler) WithRateLimit(limiter *abuse.Limiter) *QuickAnalyzeHandler {
+	h.limiter = limiter
+	return h
+}
+
+// QuickAnalyzeRequest holds either the text to analyze or the URL of a
+// page to fetch it from
+type QuickAnalyzeRequest struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// QuickAnalysisResponse is a condensed analysis
+type QuickAnalysisResponse struct {
+	Sentiment      string   `json:"sentiment"`
+	SentimentScore *float64 `json:"sentiment_score"`
+	Summary        string   `json:"summary"`
+	Topics         []string `json:"topics"`
+	Keyphrases     []string `json:"keyphrases"`
+	// Truncated is set when a fetched page was cut to QuickMaxContentLength
+	Truncated bool `json:"truncated"`
+}
+
+// Analyze runs a quick analysis
+// POST /api/v1/analyze/quick
+func (h *QuickAnalyzeHandler) Analyze(w http.ResponseWriter, r *http.Request) {
+	if !h.allow(w, r) {
+		return
+	}
+
+	var req QuickAnalyzeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	text := strings.TrimSpace(req.Text)
+	pageURL := strings.TrimSpace(req.URL)
+	if (text == "") == (pageURL == "") {
+		response.ValidationError(w, map[string]string{"text": "Provide either text or url"})
+		return
+	}
+
+	truncated := false
+	if pageURL != "" {
+		if u, err := url.Parse(pageURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(pageURL) > maxFeedURLLength {
+			response.ValidationError(w, map[string]string{"url": "Must be an http or https URL"})
+			return
+		}
+
+		page, err := h.fetch(r, pageURL)
+		if err != nil {
+			slog.Info("Failed to fetch page for quick analysis", "error", err)
+			response.Error(w, http.StatusUnprocessableEntity, "Could not read the page: "+err.Error())
+			return
+		}
+		if page == "" {
+			response.Error(w, http.StatusUnprocessableEntity, "The page has no text to analyze")
+			return
+		}
+		if utf8.RuneCountInString(page) > QuickMaxContentLength {
+			page = string([]rune(page)[:QuickMaxContentLength])
+			truncated = true
+		}
+		text = page
+	} else if utf8.RuneCountInString(text) > QuickMaxContentLength {
+		response.ValidationError(w, map[string]string{
+			"text": fmt.Sprintf("Text must be at most %d characters for a quick analysis", QuickMaxContentLength),
+		})
+		return
+	}
+
+	analysis, err := h.analyzer.Quick(r.Context(), text)
+	if err != nil {
+		slog.Error("Quick analysis failed", "error", err)
+		response.Error(w, http.StatusBadGateway, "Analysis failed, please try again")
+		return
+	}
+
+	phrases := make([]string, 0, len(analysis.Keyphrases))
+	for _, k := range analysis.Keyphrases {
+		phrases = append(phrases, k.Phrase)
+	}
+
+	response.Success(w, QuickAnalysisResponse{
+		Sentiment:      analysis.Sentiment,
+		SentimentScore: analysis.SentimentScore,
+		Summary:        analysis.Summary,
+		Topics:         analysis.Topics,
+		Keyphrases:     phrases,
+		Truncated:      truncated,
+	})
+}
+
+// allow applies the per-key rate limit, writing a 429 when it is
+// exceeded. If Redis is unavailable the limit fails open, or refuses the
+// request when configured to fail closed.
+func (h *QuickAnalyzeHandler) allow(w http.ResponseWriter, r *http.Request) bool {
+	if h.limiter == nil {
+		return true
+	}
+
+	keyID, err := auth.GetAPIKeyIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return false
+	}
+
+	allowed, retryAfter, err := h.limiter.Allow(r.Context(), keyID.String())
+	if err != nil {
+		slog.Warn("Failed to check quick analysis rate limit", "api_key_id", keyID, "error", err)
+		if h.limiter.FailsClosed() {
+			rateLimitUnavailable(w)
+			return false
+		}
+		return true
+	}
+	if !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+		response.TooManyRequests(w, "Too many quick analyses, please try again later")
+		return false
+	}
+	return true
+}
+
+// fetch downloads a page and returns its text
+func (h *QuickAnalyzeHandler) fetch(r *http.Request, pageURL string) (string, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, pageURL, nil)
+	if err != nil {
+		return "", errors.New("invalid URL")
+	}
+	req.Header.Set("Accept", "text/html, text/plain;q=0.9")
+	req.Header.Set("User-Agent", "content-analyzer quick analysis")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return "", errors.New("request failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("page returned %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "text/plain" && mediaType != "application/xhtml+xml" {
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxQuickPageSize+1))
+	if err != nil {
+		return "", errors.New("failed to read page")
+	}
+	if len(body) > maxQuickPageSize {
+		return "", fmt.Errorf("page is larger than %d MB", maxQuickPageSize>>20)
+	}
+
+	if mediaType == "text/plain" {
+		return strings.TrimSpace(string(body)), nil
+	}
+	return feeds.PlainText(string(body)), nil
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/abuse"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/outbound"
+)
+
+// fakeQuickAnalyzer records the content it was asked to analyze
+type fakeQuickAnalyzer struct {
+	content string
+}
+
+func (a *fakeQuickAnalyzer) Quick(ctx context.Context, content string) (*models.Analysis, error) {
+	a.content = content
+	score := 0.5
+	return &models.Analysis{
+		Sentiment:      "positive",
+		SentimentScore: &score,
+		Summary:        "A short summary.",
+		Topics:         []string{"phones"},
+		Keyphrases:     []models.Keyphrase{{Phrase: "battery life", Score: 1}},
+	}, nil
+}
+
+// withAPIKey attaches an API key user to the request as auth.APIKeyMiddleware would
+func withAPIKey(r *http.Request, keyID uuid.UUID) *http.Request {
+	ctx := context.WithValue(r.Context(), auth.UserIDKey, uuid.New())
+	ctx = context.WithValue(ctx, auth.APIKeyIDKey, keyID)
+	return r.WithContext(ctx)
+}
+
+func TestQuickAnalyzeHandler_Analyze(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body><p>The battery life is great.</p><script>ignored()</script></body></html>"))
+		case "/long":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte(strings.Repeat("a", QuickMaxContentLength+10)))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("png"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer page.Close()
+
+	tests := []struct {
+		name          string
+		body          interface{}
+		wantStatus    int
+		wantContent   string
+		wantTruncated bool
+	}{
+		{name: "text", body: QuickAnalyzeRequest{Text: "  The battery life is great.  "}, wantStatus: http.StatusOK, wantContent: "The battery life is great."},
+		{name: "page", body: QuickAnalyzeRequest{URL: page.URL + "/article"}, wantStatus: http.StatusOK, wantContent: "The battery life is great."},
+		{name: "long page is cut", body: QuickAnalyzeRequest{URL: page.URL + "/long"}, wantStatus: http.StatusOK, wantContent: strings.Repeat("a", QuickMaxContentLength), wantTruncated: true},
+		{name: "text too long", body: QuickAnalyzeRequest{Text: strings.Repeat("a", QuickMaxContentLength+1)}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "neither", body: QuickAnalyzeRequest{}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "both", body: QuickAnalyzeRequest{Text: "hi", URL: page.URL}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "not http", body: QuickAnalyzeRequest{URL: "file:///etc/passwd"}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unsupported page", body: QuickAnalyzeRequest{URL: page.URL + "/image"}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "missing page", body: QuickAnalyzeRequest{URL: page.URL + "/missing"}, wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed body", body: "not json", wantStatus: http.StatusBadRequest},
+	}
+
+	// The test page is on a loopback address
+	client := outbound.New(outbound.Config{AllowPrivate: true}).HTTPClient(10 * time.Second)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := &fakeQuickAnalyzer{}
+			handler := NewQuickAnalyzeHandler(analyzer).WithHTTPClient(client)
+
+			rec := httptest.NewRecorder()
+			handler.Analyze(rec, withAPIKey(newJSONRequest(t, http.MethodPost, "/api/v1/analyze/quick", tt.body), uuid.New()))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			if analyzer.content != tt.wantContent {
+				t.Errorf("analyzed %q, want %q", analyzer.content, tt.wantContent)
+			}
+
+			var resp QuickAnalysisResponse
+			decodeBody(t, rec, &resp)
+			if resp.Sentiment != "positive" || len(resp.Keyphrases) != 1 || resp.Keyphrases[0] != "battery life" {
+				t.Errorf("response = %+v", resp)
+			}
+			if resp.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v, want %v", resp.Truncated, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestQuickAnalyzeHandler_DefaultClientRefusesInternalAddresses(t *testing.T) {
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("Internal metadata."))
+	}))
+	defer page.Close()
+
+	analyzer := &fakeQuickAnalyzer{}
+	rec := httptest.NewRecorder()
+	NewQuickAnalyzeHandler(analyzer).Analyze(rec, withAPIKey(newJSONRequest(t, http.MethodPost, "/api/v1/analyze/quick", QuickAnalyzeRequest{URL: page.URL}), uuid.New()))
+
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusUnprocessableEntity, rec.Body.String())
+	}
+	if analyzer.content != "" {
+		t.Errorf("analyzed %q from a loopback address", analyzer.content)
+	}
+}
+
+func TestQuickAnalyzeHandler_RateLimit(t *testing.T) {
+	handler := NewQuickAnalyzeHandler(&fakeQuickAnalyzer{}).
+		WithRateLimit(abuse.NewLimiter(fakeCounter{}, "ratelimit:quick", 2, time.Minute))
+
+	analyze := func(keyID uuid.UUID) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Analyze(rec, withAPIKey(newJSONRequest(t, http.MethodPost, "/api/v1/analyze/quick", QuickAnalyzeRequest{Text: "Hello"}), keyID))
+		return rec
+	}
+
+	keyID := uuid.New()
+	for i := 0; i < 2; i++ {
+		if rec := analyze(keyID); rec.Code != http.StatusOK {
+			t.Fatalf("analysis %d status = %d, want %d", i+1, rec.Code, http.StatusOK)
+		}
+	}
+
+	rec := analyze(keyID)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("status over the limit = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Retry-After header not set")
+	}
+
+	if rec := analyze(uuid.New()); rec.Code != http.StatusOK {
+		t.Errorf("other key status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+// downCounter fails like a counter whose Redis is down
+type downCounter struct{}
+
+func (downCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return 0, cache.ErrUnavailable
+}
+
+func TestQuickAnalyzeHandler_RateLimitUnavailable(t *testing.T) {
+	tests := []struct {
+		failClosed bool
+		wantStatus int
+	}{
+		{failClosed: false, wantStatus: http.StatusOK},
+		{failClosed: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		handler := NewQuickAnalyzeHandler(&fakeQuickAnalyzer{}).
+			WithRateLimit(abuse.NewLimiter(downCounter{}, "ratelimit:quick", 2, time.Minute).WithFailClosed(tt.failClosed))
+
+		rec := httptest.NewRecorder()
+		handler.Analyze(rec, withAPIKey(newJSONRequest(t, http.MethodPost, "/api/v1/analyze/quick", QuickAnalyzeRequest{Text: "Hello"}), uuid.New()))
+		if rec.Code != tt.wantStatus {
+			t.Errorf("fail closed %v: status = %d, want %d", tt.failClosed, rec.Code, tt.wantStatus)
+		}
+	}
+}
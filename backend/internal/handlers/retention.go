@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// RetentionHandler lets operators exempt submissions from retention purges
+type RetentionHandler struct {
+	store LegalHoldSetter
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(store LegalHoldSetter) *RetentionHandler {
+	return &RetentionHandler{store: store}
+}
+
+// LegalHoldRequest places a submission on legal hold or releases it
+type LegalHoldRequest struct {
+	LegalHold *bool `json:"legal_hold"`
+}
+
+// SetLegalHold keeps a submission from being purged, whatever its owner's
+// organizations' retention policies say, until the hold is released
+// PUT /api/v1/admin/submissions/{id}/legal-hold
+func (h *RetentionHandler) SetLegalHold(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	var req LegalHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if req.LegalHold == nil {
+		response.ValidationError(w, map[string]string{"legal_hold": "legal_hold is required"})
+		return
+	}
+
+	if err := h.store.SetLegalHold(r.Context(), id, *req.LegalHold); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to set legal hold", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to set legal hold")
+		return
+	}
+
+	slog.Warn("Legal hold changed", "submission_id", id, "legal_hold", *req.LegalHold, "by", operator(r))
+	response.NoContent(w)
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestRetentionHandler_SetLegalHold(t *testing.T) {
+	submissions := memstore.NewSubmissionStore()
+	submission, err := submissions.Create(context.Background(), uuid.New(), "Keep this.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("failed to seed submission: %v", err)
+	}
+
+	store := memstore.NewRetentionStore(submissions)
+	r := chi.NewRouter()
+	r.Put("/admin/submissions/{id}/legal-hold", NewRetentionHandler(store).SetLegalHold)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+		wantHeld   bool
+	}{
+		{name: "invalid id", id: "nope", body: `{"legal_hold": true}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown submission", id: uuid.NewString(), body: `{"legal_hold": true}`, wantStatus: http.StatusNotFound},
+		{name: "missing flag", id: submission.ID.String(), body: `{}`, wantStatus: http.StatusUnprocessableEntity},
+		{name: "hold", id: submission.ID.String(), body: `{"legal_hold": true}`, wantStatus: http.StatusNoContent, wantHeld: true},
+		{name: "release", id: submission.ID.String(), body: `{"legal_hold": false}`, wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, newJSONRequest(t, http.MethodPut, "/admin/submissions/"+tt.id+"/legal-hold", tt.body))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SetLegalHold() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if got := store.Held(submission.ID); got != tt.wantHeld {
+				t.Errorf("Held() = %v, want %v", got, tt.wantHeld)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/revisions"
+)
+
+// CreateVersionRequest uploads a revised version of a document
+type CreateVersionRequest struct {
+	Content string `json:"content"`
+	// Redact stores a copy with personal data and profanity masked for display
+	Redact bool `json:"redact"`
+}
+
+// CreateVersion stores a revised version of a submitted document as a new
+// submission and queues it for analysis. Only the latest version can be
+// revised; drafts are edited in place instead.
+// POST /api/v1/submissions/{id}/versions
+func (h *SubmissionHandler) CreateVersion(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	var req CreateVersionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	content, fields := validateContent(req.Content)
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	previous, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to create version")
+		return
+	}
+	if previous.Status == models.StatusDraft {
+		response.Conflict(w, "Drafts are edited in place; update the draft instead")
+		return
+	}
+	if content == previous.Content {
+		response.ValidationError(w, map[string]string{"content": "Content is unchanged from the previous version"})
+		return
+	}
+	user := h.currentUser(r)
+	if !h.checkSize(w, user, content) {
+		return
+	}
+
+	submission, err := h.store.CreateRevision(r.Context(), userID, id, content, redactedCopy(content, req.Redact))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Submission not found")
+		case errors.Is(err, models.ErrNotLatestRevision):
+			response.Conflict(w, "Only the latest version can be revised")
+		default:
+			slog.Error("Failed to create revision", "submission_id", id, "error", err)
+			response.InternalServerError(w, "Failed to create version")
+		}
+		return
+	}
+	h.invalidateStats(r, userID)
+
+	if !h.enqueue(w, r, user, submission.ID) {
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Created(w, h.present(r, submission))
+}
+
+// ListVersions returns every version of the document a submission belongs
+// to, oldest first
+// GET /api/v1/submissions/{id}/versions
+func (h *SubmissionHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	versions, err := h.store.Revisions(r.Context(), userID, id)
+	if err != nil {
+		slog.Error("Failed to list revisions", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to list versions")
+		return
+	}
+	if len(versions) == 0 {
+		response.NotFound(w, "Submission not found")
+		return
+	}
+
+	response.Success(w, response.Complete(h.presentAll(r, versions)))
+}
+
+// GetDiff compares a version's analysis with that of the version it
+// revised. While either analysis is still pending it responds 202 with
+// both statuses.
+// GET /api/v1/submissions/{id}/diff
+func (h *SubmissionHandler) GetDiff(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to get diff")
+		return
+	}
+	if submission.PreviousID == nil {
+		response.NotFound(w, "Submission has no previous version")
+		return
+	}
+
+	previous, err := h.store.GetByID(r.Context(), userID, *submission.PreviousID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Previous version not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to get diff")
+		return
+	}
+
+	revisedAnalysis, revisedErr := h.store.GetAnalysis(r.Context(), userID, submission.ID)
+	previousAnalysis, previousErr := h.store.GetAnalysis(r.Context(), userID, previous.ID)
+	for _, err := range []error{revisedErr, previousErr} {
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to get analysis", "error", err)
+			response.InternalServerError(w, "Failed to get diff")
+			return
+		}
+	}
+
+	if revisedErr == nil && previousErr == nil {
+		response.Success(w, revisions.Compare(previousAnalysis, revisedAnalysis))
+		return
+	}
+
+	if pending(submission.Status) || pending(previous.Status) {
+		response.JSON(w, http.StatusAccepted, dto.Pending{Status: submission.Status, PreviousStatus: &previous.Status})
+		return
+	}
+	response.NotFound(w, "Analysis not available")
+}
+
+// pending reports whether a submission's analysis may still arrive
+func pending(status models.SubmissionStatus) bool {
+	switch status {
+	case models.StatusDraft, models.StatusQueued, models.StatusProcessing:
+		return true
+	}
+	return false
+}
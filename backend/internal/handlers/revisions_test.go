@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/revisions"
+)
+
+// completeSubmission runs a queued submission through to a stored analysis
+func completeSubmission(t *testing.T, store *memstore.SubmissionStore, analysis *models.Analysis) {
+	t.Helper()
+
+	ctx := context.Background()
+	store.UpdateStatus(ctx, analysis.SubmissionID, models.StatusProcessing)
+	if err := store.SaveAnalysis(ctx, analysis); err != nil {
+		t.Fatalf("failed to seed analysis: %v", err)
+	}
+}
+
+func TestSubmissionHandler_CreateVersion(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	tests := []struct {
+		name       string
+		setup      func(store *memstore.SubmissionStore) uuid.UUID
+		body       interface{}
+		wantStatus int
+	}{
+		{
+			name: "revises the latest version",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				original, _ := store.Create(ctx, userID, "Sales rose.", nil, ptr("focus on numbers"), nil, models.StatusQueued)
+				return original.ID
+			},
+			body:       CreateVersionRequest{Content: "Sales fell."},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "unchanged content",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				original, _ := store.Create(ctx, userID, "Sales rose.", nil, nil, nil, models.StatusQueued)
+				return original.ID
+			},
+			body:       CreateVersionRequest{Content: " Sales rose. "},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "already revised",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				original, _ := store.Create(ctx, userID, "Sales rose.", nil, nil, nil, models.StatusQueued)
+				store.CreateRevision(ctx, userID, original.ID, "Sales fell.", nil)
+				return original.ID
+			},
+			body:       CreateVersionRequest{Content: "Sales were flat."},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "draft",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				draft, _ := store.Create(ctx, userID, "Sales rose.", nil, nil, nil, models.StatusDraft)
+				return draft.ID
+			},
+			body:       CreateVersionRequest{Content: "Sales fell."},
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name: "another user's submission",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				other, _ := store.Create(ctx, uuid.New(), "Sales rose.", nil, nil, nil, models.StatusQueued)
+				return other.ID
+			},
+			body:       CreateVersionRequest{Content: "Sales fell."},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "empty content",
+			setup: func(store *memstore.SubmissionStore) uuid.UUID {
+				original, _ := store.Create(ctx, userID, "Sales rose.", nil, nil, nil, models.StatusQueued)
+				return original.ID
+			},
+			body:       CreateVersionRequest{Content: "  "},
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := memstore.NewSubmissionStore()
+			id := tt.setup(store)
+			jobs := &fakeQueue{}
+			router := newSubmissionRouter(NewSubmissionHandler(store, memstore.NewUserStore(), jobs))
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/submissions/"+id.String()+"/versions", tt.body), userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("CreateVersion() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusCreated {
+				if len(jobs.jobs) != 0 {
+					t.Errorf("enqueued %d jobs, want none", len(jobs.jobs))
+				}
+				return
+			}
+
+			var got models.Submission
+			decodeBody(t, rec, &got)
+			if got.PreviousID == nil || *got.PreviousID != id || got.Revision != 2 || got.Status != models.StatusQueued {
+				t.Errorf("CreateVersion() = %+v, want a queued second revision", got)
+			}
+			if deref(got.Instructions) != "focus on numbers" {
+				t.Errorf("Instructions = %v, want the previous version's", deref(got.Instructions))
+			}
+			if len(jobs.jobs) != 1 {
+				t.Errorf("enqueued %d jobs, want 1", len(jobs.jobs))
+			}
+		})
+	}
+}
+
+func TestSubmissionHandler_ListVersions(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewSubmissionStore()
+	router := newSubmissionRouter(NewSubmissionHandler(store, memstore.NewUserStore(), &fakeQueue{}))
+	userID := uuid.New()
+
+	first, _ := store.Create(ctx, userID, "v1", nil, nil, nil, models.StatusQueued)
+	second, _ := store.CreateRevision(ctx, userID, first.ID, "v2", nil)
+	third, _ := store.CreateRevision(ctx, userID, second.ID, "v3", nil)
+
+	// Any version lists the whole line
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+second.ID.String()+"/versions", nil), userID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ListVersions() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var list response.ListResponse[models.Submission]
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 3 || list.Data[0].ID != first.ID || list.Data[2].ID != third.ID || list.Data[2].Revision != 3 {
+		t.Errorf("ListVersions() = %+v, want the three versions oldest first", list.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+second.ID.String()+"/versions", nil), uuid.New()))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSubmissionHandler_GetDiff(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewSubmissionStore()
+	router := newSubmissionRouter(NewSubmissionHandler(store, memstore.NewUserStore(), &fakeQueue{}))
+	userID := uuid.New()
+
+	original, _ := store.Create(ctx, userID, "Sales rose.", nil, nil, nil, models.StatusQueued)
+	revised, _ := store.CreateRevision(ctx, userID, original.ID, "Sales fell.", nil)
+
+	getDiff := func(id uuid.UUID) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+id.String()+"/diff", nil), userID))
+		return rec
+	}
+
+	if rec := getDiff(original.ID); rec.Code != http.StatusNotFound {
+		t.Errorf("original status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := getDiff(revised.ID); rec.Code != http.StatusAccepted {
+		t.Errorf("pending status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	completeSubmission(t, store, &models.Analysis{SubmissionID: original.ID, Sentiment: "positive", Topics: []string{"sales"}})
+	completeSubmission(t, store, &models.Analysis{
+		SubmissionID: revised.ID,
+		Sentiment:    "negative",
+		Topics:       []string{"sales"},
+		Changes:      &models.RevisionChanges{Tone: "Gloomier.", ClaimsAdded: []string{"Sales fell."}, ClaimsRemoved: []string{"Sales rose."}},
+	})
+
+	rec := getDiff(revised.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetDiff() status = %d, want %d (body: %s)", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	var got revisions.Diff
+	decodeBody(t, rec, &got)
+	if got.PreviousID != original.ID || got.Tone.From != "positive" || got.Tone.To != "negative" || len(got.ClaimsAdded) != 1 {
+		t.Errorf("GetDiff() = %+v", got)
+	}
+}
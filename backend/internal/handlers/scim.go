@@ -0,0 +1,1091 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/scim"
+)
+
+// scimBasePath is where the SCIM server is mounted
+const scimBasePath = "/scim/v2"
+
+// maxSCIMGroupName bounds a SCIM group's display name
+const maxSCIMGroupName = 255
+
+// SCIMHandler is a SCIM 2.0 server through which an organization's
+// identity provider provisions and deprovisions its members. SCIM users
+// are accounts, active ones are members, and SCIM groups give them their
+// role through the organization's SSO role mappings. Users are only
+// provisioned under the organization's verified domains; those without
+// an account are invited, and join once they verify their email.
+type SCIMHandler struct {
+	store    SCIMStorer
+	users    UserStorer
+	orgs     OrganizationStorer
+	sessions SessionRevoker
+	// roles maps groups to roles; nil makes every member a member
+	roles SSOConfigGetter
+	// domains are the email domains users can be provisioned under; nil
+	// provisions no one
+	domains DomainVerifier
+}
+
+// NewSCIMHandler creates a new SCIM handler. sessions signs deprovisioned
+// users out.
+func NewSCIMHandler(store SCIMStorer, users UserStorer, orgs OrganizationStorer, sessions SessionRevoker) *SCIMHandler {
+	return &SCIMHandler{store: store, users: users, orgs: orgs, sessions: sessions}
+}
+
+// WithRoleMappings gives members the role their groups map to in the
+// organization's SSO setup
+func (h *SCIMHandler) WithRoleMappings(configs SSOConfigGetter) *SCIMHandler {
+	h.roles = configs
+	return h
+}
+
+// WithDomains provisions users under the domains organizations verified
+// in domains
+func (h *SCIMHandler) WithDomains(domains DomainVerifier) *SCIMHandler {
+	h.domains = domains
+	return h
+}
+
+// ServiceProviderConfig describes what the server supports
+// GET /scim/v2/ServiceProviderConfig
+func (h *SCIMHandler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	scim.WriteJSON(w, http.StatusOK, scim.ServiceProviderConfig())
+}
+
+// ListUsers returns a page of the organization's SCIM users, filtered by
+// userName or externalId
+// GET /scim/v2/Users
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	filter, ok := scimFilter(w, r, "username", "externalid")
+	if !ok {
+		return
+	}
+	startIndex, count := scim.ParsePage(r)
+
+	users, total, err := h.store.ListUsers(r.Context(), orgID, filter, startIndex-1, count)
+	if err != nil {
+		slog.Error("Failed to list SCIM users", "org_id", orgID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to list users")
+		return
+	}
+
+	resources := make([]scim.User, len(users))
+	for i := range users {
+		resources[i] = scimUserResource(&users[i])
+	}
+	scim.WriteJSON(w, http.StatusOK, scim.NewListResponse(resources, len(resources), total, startIndex))
+}
+
+// GetUser returns one of the organization's SCIM users
+// GET /scim/v2/Users/{id}
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	user, ok := h.user(w, r, orgID)
+	if !ok {
+		return
+	}
+	scim.WriteJSON(w, http.StatusOK, scimUserResource(user))
+}
+
+// CreateUser provisions a user under one of the organization's verified
+// domains: an existing member's account is taken over, and a new email
+// address is invited. An account outside the organization is never taken
+// over.
+// POST /scim/v2/Users
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+	ctx := r.Context()
+
+	var req scim.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email()))
+	if err := models.ValidateEmail(email); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "userName must be an email address")
+		return
+	}
+	displayName, ok := scimDisplayName(w, scimUserDisplayName(&req))
+	if !ok {
+		return
+	}
+	active := req.Active == nil || *req.Active
+
+	verified, err := verifiedDomain(ctx, h.domains, orgID, email)
+	if err != nil {
+		slog.Error("Failed to check organization domain", "org_id", orgID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+	if !verified {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "userName must be under one of the organization's verified domains")
+		return
+	}
+
+	account, err := h.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if _, err := h.store.GetUser(ctx, orgID, account.ID); err == nil {
+			scim.WriteError(w, http.StatusConflict, scim.ErrTypeUniqueness, "User already exists")
+			return
+		}
+		if _, err := h.orgs.Role(ctx, orgID, account.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				scim.WriteError(w, http.StatusConflict, scim.ErrTypeUniqueness, "An account with this email already exists outside the organization")
+				return
+			}
+			slog.Error("Failed to get organization role", "org_id", orgID, "error", err)
+			scim.WriteError(w, http.StatusInternalServerError, "", "Failed to create user")
+			return
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		h.invite(w, r, orgID, email, displayName, req.ExternalID, active)
+		return
+	default:
+		slog.Error("Failed to get user", "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+
+	if displayName != nil && !sameDisplayName(account.DisplayName, *displayName) {
+		if !h.setDisplayName(w, r, account.ID, *displayName) {
+			return
+		}
+	}
+	if _, err := h.store.SetUser(ctx, orgID, account.ID, req.ExternalID, active); err != nil {
+		slog.Error("Failed to save SCIM user", "org_id", orgID, "user_id", account.ID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+
+	user, ok := h.setActive(w, r, orgID, account.ID, active)
+	if !ok {
+		return
+	}
+
+	slog.Info("SCIM user provisioned", "org_id", orgID, "user_id", account.ID, "active", active)
+	w.Header().Set("Location", scimBasePath+"/Users/"+account.ID.String())
+	scim.WriteJSON(w, http.StatusCreated, scimUserResource(user))
+}
+
+// invite records a pending user for an email address without an account.
+// They become a member once they sign up and verify it.
+func (h *SCIMHandler) invite(w http.ResponseWriter, r *http.Request, orgID uuid.UUID, email string, displayName *string, externalID string, active bool) {
+	user, err := h.store.Invite(r.Context(), orgID, email, displayName, externalID, active)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			scim.WriteError(w, http.StatusConflict, scim.ErrTypeUniqueness, "User already exists")
+			return
+		}
+		slog.Error("Failed to invite SCIM user", "org_id", orgID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to create user")
+		return
+	}
+
+	slog.Info("SCIM user invited", "org_id", orgID, "user_id", user.UserID, "active", active)
+	w.Header().Set("Location", scimBasePath+"/Users/"+user.UserID.String())
+	scim.WriteJSON(w, http.StatusCreated, scimUserResource(user))
+}
+
+// AcceptInvitations makes a user who verified their email a member of the
+// organizations that invited them, if the invitation is active. Failures
+// are logged: the email is verified either way.
+func (h *SCIMHandler) AcceptInvitations(ctx context.Context, userID uuid.UUID) {
+	users, err := h.store.AcceptInvitations(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to accept SCIM invitations", "user_id", userID, "error", err)
+		return
+	}
+	for i := range users {
+		if !users[i].Active {
+			continue
+		}
+		if err := h.assignRole(ctx, &users[i]); err != nil {
+			slog.Error("Failed to activate SCIM user", "org_id", users[i].OrgID, "user_id", userID, "error", err)
+			continue
+		}
+		slog.Info("SCIM invitation accepted", "org_id", users[i].OrgID, "user_id", userID)
+	}
+}
+
+// scimUserChanges are the attributes a PUT or PATCH sets; nil leaves an
+// attribute as it is
+type scimUserChanges struct {
+	userName    *string
+	displayName *string
+	externalID  *string
+	active      *bool
+}
+
+// ReplaceUser replaces a user's display name, external ID and whether
+// they are active. userName, their email, can't be changed.
+// PUT /scim/v2/Users/{id}
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	current, ok := h.user(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req scim.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	displayName := scimUserDisplayName(&req)
+	active := req.Active == nil || *req.Active
+	h.update(w, r, current, scimUserChanges{
+		userName:    &req.UserName,
+		displayName: &displayName,
+		externalID:  &req.ExternalID,
+		active:      &active,
+	})
+}
+
+// PatchUser changes some of a user's attributes: active, displayName,
+// name.formatted and externalId. Others are ignored.
+// PATCH /scim/v2/Users/{id}
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	current, ok := h.user(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	var changes scimUserChanges
+	for _, operation := range req.Operations {
+		op, err := operation.Operation()
+		if err != nil {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, err.Error())
+			return
+		}
+
+		// Without a path, the value holds the attributes to set
+		values := map[string]json.RawMessage{operation.Path: operation.Value}
+		if operation.Path == "" {
+			values = nil
+			if err := json.Unmarshal(operation.Value, &values); err != nil {
+				scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "Operations without a path need an object value")
+				return
+			}
+		}
+		if op == scim.OpRemove {
+			for path := range values {
+				values[path] = nil
+			}
+		}
+
+		for path, value := range values {
+			if err := changes.set(path, value); err != nil {
+				scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, err.Error())
+				return
+			}
+		}
+	}
+
+	h.update(w, r, current, changes)
+}
+
+// set records a PATCH of the attribute at path; a nil value removes it
+func (c *scimUserChanges) set(path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "active":
+		if value == nil {
+			return errors.New("active can't be removed")
+		}
+		active, err := scim.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("active: %w", err)
+		}
+		c.active = &active
+	case "displayname", "name.formatted":
+		name, err := scim.ParseString(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		c.displayName = &name
+	case "name":
+		var name scim.Name
+		if value != nil {
+			if err := json.Unmarshal(value, &name); err != nil {
+				return fmt.Errorf("name: %w", err)
+			}
+		}
+		display := name.Display()
+		c.displayName = &display
+	case "externalid":
+		id, err := scim.ParseString(value)
+		if err != nil {
+			return fmt.Errorf("externalId: %w", err)
+		}
+		c.externalID = &id
+	case "username":
+		name, err := scim.ParseString(value)
+		if err != nil {
+			return fmt.Errorf("userName: %w", err)
+		}
+		c.userName = &name
+	}
+	return nil
+}
+
+// update applies changes to a SCIM user and writes the updated user
+func (h *SCIMHandler) update(w http.ResponseWriter, r *http.Request, current *models.SCIMUser, changes scimUserChanges) {
+	ctx := r.Context()
+
+	if changes.userName != nil && !strings.EqualFold(strings.TrimSpace(*changes.userName), current.Email) {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeMutability, "userName can't be changed")
+		return
+	}
+
+	if changes.displayName != nil {
+		displayName, ok := scimDisplayName(w, *changes.displayName)
+		if !ok {
+			return
+		}
+		name := ""
+		if displayName != nil {
+			name = *displayName
+		}
+		if !sameDisplayName(current.DisplayName, name) && !h.rename(w, r, current, displayName) {
+			return
+		}
+	}
+
+	externalID, active := current.ExternalID, current.Active
+	if changes.externalID != nil {
+		externalID = *changes.externalID
+	}
+	if changes.active != nil {
+		active = *changes.active
+	}
+	if _, err := h.store.SetUser(ctx, current.OrgID, current.UserID, externalID, active); err != nil {
+		slog.Error("Failed to save SCIM user", "org_id", current.OrgID, "user_id", current.UserID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to update user")
+		return
+	}
+
+	user, ok := h.setActive(w, r, current.OrgID, current.UserID, active)
+	if !ok {
+		return
+	}
+	if active != current.Active {
+		slog.Info("SCIM user updated", "org_id", current.OrgID, "user_id", current.UserID, "active", active)
+	}
+	scim.WriteJSON(w, http.StatusOK, scimUserResource(user))
+}
+
+// DeleteUser deprovisions a user and stops managing them. Their account
+// is kept, as it may hold their own work.
+// DELETE /scim/v2/Users/{id}
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	user, ok := h.user(w, r, orgID)
+	if !ok {
+		return
+	}
+	if user.Active && !user.Pending {
+		if _, ok := h.setActive(w, r, orgID, user.UserID, false); !ok {
+			return
+		}
+	}
+
+	if err := h.store.DeleteUser(r.Context(), orgID, user.UserID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			scim.WriteError(w, http.StatusNotFound, "", "User not found")
+			return
+		}
+		slog.Error("Failed to delete SCIM user", "org_id", orgID, "user_id", user.UserID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to delete user")
+		return
+	}
+
+	slog.Info("SCIM user deleted", "org_id", orgID, "user_id", user.UserID)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// user resolves the SCIM user in the URL, writing an error if there is
+// no such user
+func (h *SCIMHandler) user(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) (*models.SCIMUser, bool) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		scim.WriteError(w, http.StatusNotFound, "", "User not found")
+		return nil, false
+	}
+
+	user, err := h.store.GetUser(r.Context(), orgID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			scim.WriteError(w, http.StatusNotFound, "", "User not found")
+			return nil, false
+		}
+		slog.Error("Failed to get SCIM user", "org_id", orgID, "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to get user")
+		return nil, false
+	}
+	return user, true
+}
+
+// rename changes a SCIM user's display name, or the one a pending user's
+// account will get; nil clears it
+func (h *SCIMHandler) rename(w http.ResponseWriter, r *http.Request, user *models.SCIMUser, displayName *string) bool {
+	if !user.Pending {
+		name := ""
+		if displayName != nil {
+			name = *displayName
+		}
+		return h.setDisplayName(w, r, user.UserID, name)
+	}
+
+	if err := h.store.RenameInvitation(r.Context(), user.OrgID, user.UserID, displayName); err != nil {
+		slog.Error("Failed to rename SCIM invitation", "org_id", user.OrgID, "user_id", user.UserID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to update user")
+		return false
+	}
+	return true
+}
+
+// setDisplayName changes the user's display name; "" clears it
+func (h *SCIMHandler) setDisplayName(w http.ResponseWriter, r *http.Request, userID uuid.UUID, name string) bool {
+	account, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user", "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to update user")
+		return false
+	}
+
+	var displayName *string
+	if name != "" {
+		displayName = &name
+	}
+	if _, err := h.users.UpdateProfile(r.Context(), userID, account.Version, displayName, account.TimeZone); err != nil {
+		if errors.Is(err, models.ErrVersionConflict) {
+			scim.WriteError(w, http.StatusConflict, "", "The user was changed at the same time; try again")
+			return false
+		}
+		slog.Error("Failed to update profile", "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to update user")
+		return false
+	}
+	return true
+}
+
+// setActive makes the user a member with the role their groups map to,
+// or deprovisions them: they lose their membership and are signed out.
+// Pending users are left alone until they accept their invitation. It
+// returns the user as saved.
+func (h *SCIMHandler) setActive(w http.ResponseWriter, r *http.Request, orgID, userID uuid.UUID, active bool) (*models.SCIMUser, bool) {
+	ctx := r.Context()
+
+	if user, err := h.store.GetUser(ctx, orgID, userID); err == nil && user.Pending {
+		return user, true
+	}
+
+	if active {
+		user, err := h.store.GetUser(ctx, orgID, userID)
+		if err == nil {
+			err = h.assignRole(ctx, user)
+		}
+		if err != nil {
+			slog.Error("Failed to activate SCIM user", "org_id", orgID, "user_id", userID, "error", err)
+			scim.WriteError(w, http.StatusInternalServerError, "", "Failed to activate user")
+			return nil, false
+		}
+		return user, true
+	}
+
+	if err := h.orgs.RemoveMember(ctx, orgID, userID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, models.ErrLastOwner) {
+			scim.WriteError(w, http.StatusConflict, "", "The organization's last owner can't be deprovisioned")
+			return nil, false
+		}
+		slog.Error("Failed to remove member", "org_id", orgID, "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to deactivate user")
+		return nil, false
+	}
+	if err := h.sessions.RevokeAll(ctx, userID); err != nil {
+		slog.Error("Failed to revoke sessions", "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to deactivate user")
+		return nil, false
+	}
+
+	user, err := h.store.GetUser(ctx, orgID, userID)
+	if err != nil {
+		slog.Error("Failed to get SCIM user", "org_id", orgID, "user_id", userID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to deactivate user")
+		return nil, false
+	}
+	slog.Warn("SCIM user deprovisioned", "org_id", orgID, "user_id", userID)
+	return user, true
+}
+
+// assignRole gives an active user the role their groups map to. Owners
+// keep theirs, as the identity provider can't grant or take it.
+func (h *SCIMHandler) assignRole(ctx context.Context, user *models.SCIMUser) error {
+	role := models.RoleMember
+	if h.roles != nil {
+		config, err := h.roles.Get(ctx, user.OrgID)
+		switch {
+		case err == nil:
+			groups := make([]string, len(user.Groups))
+			for i, g := range user.Groups {
+				groups[i] = g.DisplayName
+			}
+			role = config.RoleFor(groups)
+		case !errors.Is(err, pgx.ErrNoRows):
+			return err
+		}
+	}
+
+	current, err := h.orgs.Role(ctx, user.OrgID, user.UserID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if current == models.RoleOwner || current == role {
+		return nil
+	}
+	return h.orgs.SetMember(ctx, user.OrgID, user.UserID, role)
+}
+
+// syncRoles reassigns the roles of active users after their groups
+// changed. Failing to only leaves old roles in place until the next
+// change, so errors are logged.
+func (h *SCIMHandler) syncRoles(ctx context.Context, orgID uuid.UUID, userIDs map[uuid.UUID]bool) {
+	for userID := range userIDs {
+		user, err := h.store.GetUser(ctx, orgID, userID)
+		if err != nil {
+			if !errors.Is(err, pgx.ErrNoRows) {
+				slog.Error("Failed to get SCIM user", "org_id", orgID, "user_id", userID, "error", err)
+			}
+			continue
+		}
+		if !user.Active {
+			continue
+		}
+		if err := h.assignRole(ctx, user); err != nil {
+			slog.Error("Failed to sync SCIM role", "org_id", orgID, "user_id", userID, "error", err)
+		}
+	}
+}
+
+// ListGroups returns a page of the organization's SCIM groups, filtered
+// by displayName or externalId
+// GET /scim/v2/Groups
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	filter, ok := scimFilter(w, r, "displayname", "externalid")
+	if !ok {
+		return
+	}
+	startIndex, count := scim.ParsePage(r)
+
+	groups, total, err := h.store.ListGroups(r.Context(), orgID, filter, startIndex-1, count)
+	if err != nil {
+		slog.Error("Failed to list SCIM groups", "org_id", orgID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to list groups")
+		return
+	}
+
+	resources := make([]scim.Group, len(groups))
+	for i := range groups {
+		resources[i] = scimGroupResource(&groups[i])
+	}
+	scim.WriteJSON(w, http.StatusOK, scim.NewListResponse(resources, len(resources), total, startIndex))
+}
+
+// GetGroup returns one of the organization's SCIM groups
+// GET /scim/v2/Groups/{id}
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	group, ok := h.group(w, r, orgID)
+	if !ok {
+		return
+	}
+	scim.WriteJSON(w, http.StatusOK, scimGroupResource(group))
+}
+
+// CreateGroup creates a group of the organization's SCIM users
+// POST /scim/v2/Groups
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	var req scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	group := &models.SCIMGroup{OrgID: orgID, ExternalID: req.ExternalID}
+	if !setSCIMGroupName(w, group, req.DisplayName) || !setSCIMGroupMembers(w, group, req.Members) {
+		return
+	}
+
+	h.saveGroup(w, r, group, nil, http.StatusCreated)
+}
+
+// ReplaceGroup replaces a group's name, external ID and members
+// PUT /scim/v2/Groups/{id}
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	current, ok := h.group(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req scim.Group
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	group := &models.SCIMGroup{ID: current.ID, OrgID: orgID, ExternalID: req.ExternalID}
+	if !setSCIMGroupName(w, group, req.DisplayName) || !setSCIMGroupMembers(w, group, req.Members) {
+		return
+	}
+
+	h.saveGroup(w, r, group, current, http.StatusOK)
+}
+
+// PatchGroup changes a group's name or external ID, or adds, removes or
+// replaces its members
+// PATCH /scim/v2/Groups/{id}
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	current, ok := h.group(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	var req scim.PatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, "Invalid request body")
+		return
+	}
+
+	group := *current
+	for _, operation := range req.Operations {
+		op, err := operation.Operation()
+		if err != nil {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidSyntax, err.Error())
+			return
+		}
+		if !patchSCIMGroup(w, &group, op, operation) {
+			return
+		}
+	}
+
+	h.saveGroup(w, r, &group, current, http.StatusOK)
+}
+
+// patchSCIMGroup applies one PATCH operation to group
+func patchSCIMGroup(w http.ResponseWriter, group *models.SCIMGroup, op string, operation scim.PatchOperation) bool {
+	// Without a path, the value holds the attributes to set
+	if operation.Path == "" {
+		var req scim.Group
+		if err := json.Unmarshal(operation.Value, &req); err != nil || op == scim.OpRemove {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "Operations without a path need an object value")
+			return false
+		}
+		if req.DisplayName != "" && !setSCIMGroupName(w, group, req.DisplayName) {
+			return false
+		}
+		if req.ExternalID != "" {
+			group.ExternalID = req.ExternalID
+		}
+		if req.Members != nil {
+			return patchSCIMGroupMembers(w, group, op, req.Members)
+		}
+		return true
+	}
+
+	if id, ok := scim.MemberPath(operation.Path); ok && op == scim.OpRemove {
+		return patchSCIMGroupMembers(w, group, op, []scim.Member{{Value: id}})
+	}
+
+	switch strings.ToLower(operation.Path) {
+	case "members":
+		var members []scim.Member
+		if len(operation.Value) > 0 && string(operation.Value) != "null" {
+			var err error
+			if members, err = scim.ParseMembers(operation.Value); err != nil {
+				scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, err.Error())
+				return false
+			}
+		}
+		// Removing members without naming any removes them all
+		if op == scim.OpRemove && members == nil {
+			group.Members = []models.SCIMGroupMember{}
+			return true
+		}
+		return patchSCIMGroupMembers(w, group, op, members)
+	case "displayname":
+		name, err := scim.ParseString(operation.Value)
+		if err != nil || op == scim.OpRemove {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "displayName must be a string")
+			return false
+		}
+		return setSCIMGroupName(w, group, name)
+	case "externalid":
+		id, err := scim.ParseString(operation.Value)
+		if err != nil {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, "externalId must be a string")
+			return false
+		}
+		if op == scim.OpRemove {
+			id = ""
+		}
+		group.ExternalID = id
+		return true
+	}
+
+	scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidPath, fmt.Sprintf("Unsupported path %q", operation.Path))
+	return false
+}
+
+// patchSCIMGroupMembers adds, removes or replaces members of group
+func patchSCIMGroupMembers(w http.ResponseWriter, group *models.SCIMGroup, op string, members []scim.Member) bool {
+	changed := &models.SCIMGroup{}
+	if !setSCIMGroupMembers(w, changed, members) {
+		return false
+	}
+
+	switch op {
+	case scim.OpReplace:
+		group.Members = changed.Members
+	case scim.OpAdd:
+		for _, m := range changed.Members {
+			if !hasSCIMMember(group.Members, m.UserID) {
+				group.Members = append(group.Members, m)
+			}
+		}
+	case scim.OpRemove:
+		kept := []models.SCIMGroupMember{}
+		for _, m := range group.Members {
+			if !hasSCIMMember(changed.Members, m.UserID) {
+				kept = append(kept, m)
+			}
+		}
+		group.Members = kept
+	}
+	return true
+}
+
+// hasSCIMMember reports whether userID is among members
+func hasSCIMMember(members []models.SCIMGroupMember, userID uuid.UUID) bool {
+	for _, m := range members {
+		if m.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// saveGroup saves a created or changed group, reassigns the roles of the
+// users who joined or left it, and writes it with status
+func (h *SCIMHandler) saveGroup(w http.ResponseWriter, r *http.Request, group, previous *models.SCIMGroup, status int) {
+	saved, err := h.store.SaveGroup(r.Context(), group)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == "23505":
+			scim.WriteError(w, http.StatusConflict, scim.ErrTypeUniqueness, "A group with this displayName already exists")
+		case errors.Is(err, pgx.ErrNoRows):
+			scim.WriteError(w, http.StatusNotFound, "", "Group not found")
+		default:
+			slog.Error("Failed to save SCIM group", "org_id", group.OrgID, "error", err)
+			scim.WriteError(w, http.StatusInternalServerError, "", "Failed to save group")
+		}
+		return
+	}
+
+	affected := make(map[uuid.UUID]bool)
+	for _, m := range saved.Members {
+		affected[m.UserID] = true
+	}
+	if previous != nil {
+		for _, m := range previous.Members {
+			affected[m.UserID] = true
+		}
+	}
+	// A renamed group may map to another role
+	h.syncRoles(r.Context(), group.OrgID, affected)
+
+	if status == http.StatusCreated {
+		w.Header().Set("Location", scimBasePath+"/Groups/"+saved.ID.String())
+	}
+	scim.WriteJSON(w, status, scimGroupResource(saved))
+}
+
+// DeleteGroup deletes a group, reassigning its members' roles
+// DELETE /scim/v2/Groups/{id}
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	orgID, _ := auth.GetSCIMOrgFromContext(r.Context())
+
+	group, ok := h.group(w, r, orgID)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteGroup(r.Context(), orgID, group.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			scim.WriteError(w, http.StatusNotFound, "", "Group not found")
+			return
+		}
+		slog.Error("Failed to delete SCIM group", "org_id", orgID, "group_id", group.ID, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to delete group")
+		return
+	}
+
+	affected := make(map[uuid.UUID]bool)
+	for _, m := range group.Members {
+		affected[m.UserID] = true
+	}
+	h.syncRoles(r.Context(), orgID, affected)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// group resolves the SCIM group in the URL, writing an error if there is
+// no such group
+func (h *SCIMHandler) group(w http.ResponseWriter, r *http.Request, orgID uuid.UUID) (*models.SCIMGroup, bool) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		scim.WriteError(w, http.StatusNotFound, "", "Group not found")
+		return nil, false
+	}
+
+	group, err := h.store.GetGroup(r.Context(), orgID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			scim.WriteError(w, http.StatusNotFound, "", "Group not found")
+			return nil, false
+		}
+		slog.Error("Failed to get SCIM group", "org_id", orgID, "group_id", id, "error", err)
+		scim.WriteError(w, http.StatusInternalServerError, "", "Failed to get group")
+		return nil, false
+	}
+	return group, true
+}
+
+// setSCIMGroupName validates and sets a group's display name
+func setSCIMGroupName(w http.ResponseWriter, group *models.SCIMGroup, name string) bool {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxSCIMGroupName {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, fmt.Sprintf("displayName must be 1 to %d characters", maxSCIMGroupName))
+		return false
+	}
+	group.DisplayName = name
+	return true
+}
+
+// setSCIMGroupMembers sets a group's members from their SCIM IDs
+func setSCIMGroupMembers(w http.ResponseWriter, group *models.SCIMGroup, members []scim.Member) bool {
+	group.Members = make([]models.SCIMGroupMember, 0, len(members))
+	for _, m := range members {
+		userID, err := uuid.Parse(m.Value)
+		if err != nil {
+			scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, fmt.Sprintf("Unknown member %q", m.Value))
+			return false
+		}
+		if !hasSCIMMember(group.Members, userID) {
+			group.Members = append(group.Members, models.SCIMGroupMember{UserID: userID})
+		}
+	}
+	return true
+}
+
+// scimFilter reads a list request's filter, which may only use the given
+// attributes. The first names users' userName or groups' displayName.
+func scimFilter(w http.ResponseWriter, r *http.Request, name, externalID string) (models.SCIMFilter, bool) {
+	attribute, value, err := scim.ParseFilter(r.URL.Query().Get("filter"))
+	if err != nil {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidFilter, err.Error())
+		return models.SCIMFilter{}, false
+	}
+
+	switch attribute {
+	case "":
+		return models.SCIMFilter{}, true
+	case name:
+		return models.SCIMFilter{Name: value}, true
+	case externalID:
+		return models.SCIMFilter{ExternalID: value}, true
+	}
+	scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidFilter, fmt.Sprintf("Can't filter by %s", attribute))
+	return models.SCIMFilter{}, false
+}
+
+// scimUserDisplayName returns the display name a SCIM user resource sets
+func scimUserDisplayName(user *scim.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Name.Display()
+}
+
+// scimDisplayName validates a display name; "" clears it and returns nil
+func scimDisplayName(w http.ResponseWriter, name string) (*string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, true
+	}
+	if utf8.RuneCountInString(name) > models.MaxDisplayNameLength {
+		scim.WriteError(w, http.StatusBadRequest, scim.ErrTypeInvalidValue, fmt.Sprintf("displayName must be at most %d characters", models.MaxDisplayNameLength))
+		return nil, false
+	}
+	return &name, true
+}
+
+// sameDisplayName reports whether current is already name
+func sameDisplayName(current *string, name string) bool {
+	if current == nil {
+		return name == ""
+	}
+	return *current == name
+}
+
+// scimUserResource converts a SCIM user to its resource
+func scimUserResource(user *models.SCIMUser) scim.User {
+	active := user.Active
+	resource := scim.User{
+		Schemas:    []string{scim.SchemaUser},
+		ID:         user.UserID.String(),
+		ExternalID: user.ExternalID,
+		UserName:   user.Email,
+		Emails:     []scim.Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:     &active,
+		Groups:     make([]scim.Member, len(user.Groups)),
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      user.CreatedAt,
+			LastModified: user.UpdatedAt,
+			Location:     scimBasePath + "/Users/" + user.UserID.String(),
+		},
+	}
+	if user.DisplayName != nil {
+		resource.DisplayName = *user.DisplayName
+		resource.Name = &scim.Name{Formatted: *user.DisplayName}
+	}
+	for i, g := range user.Groups {
+		resource.Groups[i] = scim.Member{Value: g.ID.String(), Display: g.DisplayName}
+	}
+	return resource
+}
+
+// scimGroupResource converts a SCIM group to its resource
+func scimGroupResource(group *models.SCIMGroup) scim.Group {
+	resource := scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          group.ID.String(),
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Members:     make([]scim.Member, len(group.Members)),
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt,
+			LastModified: group.UpdatedAt,
+			Location:     scimBasePath + "/Groups/" + group.ID.String(),
+		},
+	}
+	for i, m := range group.Members {
+		resource.Members[i] = scim.Member{Value: m.UserID.String(), Display: m.Email}
+	}
+	return resource
+}
+
+// SCIMTokenResponse holds a newly issued SCIM token, shown only once
+type SCIMTokenResponse struct {
+	Token string `json:"token"`
+}
+
+// CreateSCIMToken issues the token the organization's identity provider
+// authenticates to the SCIM server with, replacing any earlier one.
+// Owners only.
+// POST /api/v1/orgs/{id}/scim-token
+func (h *OrgHandler) CreateSCIMToken(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can manage SCIM tokens")
+		return
+	}
+
+	token, err := h.scim.CreateToken(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to create SCIM token", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to create SCIM token")
+		return
+	}
+
+	// Logged at warn: the token can add and remove the organization's members
+	slog.Warn("SCIM token issued", "org_id", orgID, "by", operator(r))
+	response.Created(w, SCIMTokenResponse{Token: token})
+}
+
+// RevokeSCIMToken revokes the organization's SCIM token, stopping
+// provisioning. Provisioned members are kept. Owners only.
+// DELETE /api/v1/orgs/{id}/scim-token
+func (h *OrgHandler) RevokeSCIMToken(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can manage SCIM tokens")
+		return
+	}
+
+	if err := h.scim.RevokeToken(r.Context(), orgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "The organization has no SCIM token")
+			return
+		}
+		slog.Error("Failed to revoke SCIM token", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to revoke SCIM token")
+		return
+	}
+
+	slog.Warn("SCIM token revoked", "org_id", orgID, "by", operator(r))
+	response.NoContent(w)
+}
@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/scim"
+)
+
+// scimFixture is an organization fixture with single sign-on set up and
+// a SCIM token
+type scimFixture struct {
+	*orgFixture
+	scimRouter  *chi.Mux
+	scimHandler *SCIMHandler
+	sessions    *fakeSessions
+	token       string
+}
+
+func newSCIMFixture(t *testing.T) *scimFixture {
+	t.Helper()
+
+	f := &scimFixture{orgFixture: newOrgFixture(t), sessions: &fakeSessions{}}
+	f.enableSSO(t)
+
+	var err error
+	if f.token, err = f.scim.CreateToken(context.Background(), f.org.ID); err != nil {
+		t.Fatalf("failed to seed SCIM token: %v", err)
+	}
+
+	handler := NewSCIMHandler(f.scim, f.users, f.orgs, f.sessions).WithRoleMappings(f.sso).WithDomains(f.domains)
+	f.scimHandler = handler
+	r := chi.NewRouter()
+	r.Use(auth.SCIMMiddleware(f.scim))
+	r.Get("/scim/v2/Users", handler.ListUsers)
+	r.Post("/scim/v2/Users", handler.CreateUser)
+	r.Get("/scim/v2/Users/{id}", handler.GetUser)
+	r.Put("/scim/v2/Users/{id}", handler.ReplaceUser)
+	r.Patch("/scim/v2/Users/{id}", handler.PatchUser)
+	r.Delete("/scim/v2/Users/{id}", handler.DeleteUser)
+	r.Post("/scim/v2/Groups", handler.CreateGroup)
+	r.Patch("/scim/v2/Groups/{id}", handler.PatchGroup)
+	r.Delete("/scim/v2/Groups/{id}", handler.DeleteGroup)
+	f.scimRouter = r
+
+	return f
+}
+
+// call sends a SCIM request with the organization's token
+func (f *scimFixture) call(t *testing.T, method, target string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	req := newJSONRequest(t, method, target, body)
+	req.Header.Set("Authorization", "Bearer "+f.token)
+	rec := httptest.NewRecorder()
+	f.scimRouter.ServeHTTP(rec, req)
+	return rec
+}
+
+// provision creates a SCIM user and returns it. A new email address is
+// invited, so an account is signed up for it and accepts the invitation.
+func (f *scimFixture) provision(t *testing.T, email string) scim.User {
+	t.Helper()
+	ctx := context.Background()
+	_, err := f.users.GetByEmail(ctx, email)
+	invited := err != nil
+
+	rec := f.call(t, http.MethodPost, "/scim/v2/Users", map[string]interface{}{
+		"schemas":  []string{scim.SchemaUser},
+		"userName": email,
+		"name":     map[string]string{"givenName": "Ada", "familyName": "Lovelace"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CreateUser(%s) status = %d, body %s", email, rec.Code, rec.Body.String())
+	}
+	var user scim.User
+	decodeBody(t, rec, &user)
+	if !invited {
+		return user
+	}
+
+	account, err := f.users.Create(ctx, email, testPassword)
+	if err != nil {
+		t.Fatalf("failed to sign up %s: %v", email, err)
+	}
+	if account.ID.String() != user.ID {
+		t.Fatalf("account ID = %s, want the invitation's %s", account.ID, user.ID)
+	}
+	f.scimHandler.AcceptInvitations(ctx, account.ID)
+	decodeBody(t, f.call(t, http.MethodGet, "/scim/v2/Users/"+user.ID, nil), &user)
+	return user
+}
+
+func TestSCIMHandler_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t)
+
+	// A new email address is invited: no account, no membership
+	rec := f.call(t, http.MethodPost, "/scim/v2/Users", map[string]interface{}{
+		"schemas":  []string{scim.SchemaUser},
+		"userName": "Ada@Example.com",
+		"name":     map[string]string{"givenName": "Ada", "familyName": "Lovelace"},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CreateUser() status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var invited scim.User
+	decodeBody(t, rec, &invited)
+	if invited.UserName != "ada@example.com" || invited.DisplayName != "Ada Lovelace" || invited.Active == nil || !*invited.Active {
+		t.Errorf("CreateUser() = %+v", invited)
+	}
+	if _, err := f.users.GetByEmail(ctx, "ada@example.com"); err == nil {
+		t.Error("CreateUser() created an account")
+	}
+	if rec := f.call(t, http.MethodGet, "/scim/v2/Users/"+invited.ID, nil); rec.Code != http.StatusOK {
+		t.Errorf("GetUser() of an invited user status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := f.call(t, http.MethodPost, "/scim/v2/Users", map[string]string{"userName": "ada@example.com"}); rec.Code != http.StatusConflict {
+		t.Errorf("CreateUser() of an invited user status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Signing up isn't enough; verifying the address accepts the invitation
+	account := seedUser(t, f.users, "ada@example.com")
+	if account.ID.String() != invited.ID {
+		t.Fatalf("account ID = %s, want the invitation's %s", account.ID, invited.ID)
+	}
+	if _, err := f.orgs.Role(ctx, f.org.ID, account.ID); err == nil {
+		t.Error("invited user is a member before verifying their email")
+	}
+	f.scimHandler.AcceptInvitations(ctx, account.ID)
+	if role, err := f.orgs.Role(ctx, f.org.ID, account.ID); err != nil || role != models.RoleMember {
+		t.Errorf("accepted user's role = %s, %v, want member", role, err)
+	}
+	account, _ = f.users.GetByID(ctx, account.ID)
+	if account.DisplayName == nil || *account.DisplayName != "Ada Lovelace" || account.EmailVerifiedAt != nil {
+		t.Errorf("accepted account = %+v, want the invitation's name", account)
+	}
+
+}
+
+func TestSCIMHandler_CreateUser_Existing(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, f *scimFixture)
+		userName   string
+		wantStatus int
+		// wantID is the account a created user must be, if any
+		wantID func(f *scimFixture) uuid.UUID
+	}{
+		{name: "outside the verified domains", userName: "ada@example.org", wantStatus: http.StatusBadRequest},
+		{name: "under a subdomain", userName: "ada@mail.example.com", wantStatus: http.StatusBadRequest},
+		{name: "invalid userName", userName: "not an email", wantStatus: http.StatusBadRequest},
+		{
+			name: "already invited",
+			setup: func(t *testing.T, f *scimFixture) {
+				f.call(t, http.MethodPost, "/scim/v2/Users", map[string]string{"userName": "ada@example.com"})
+			},
+			userName:   "ada@example.com",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "member is taken over with their account",
+			userName:   "member@example.com",
+			wantStatus: http.StatusCreated,
+			wantID:     func(f *scimFixture) uuid.UUID { return f.member },
+		},
+		{
+			name:       "account outside the organization",
+			setup:      func(t *testing.T, f *scimFixture) { seedUser(t, f.users, "outsider@example.com") },
+			userName:   "outsider@example.com",
+			wantStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSCIMFixture(t)
+			if tt.setup != nil {
+				tt.setup(t, f)
+			}
+
+			rec := f.call(t, http.MethodPost, "/scim/v2/Users", map[string]string{"userName": tt.userName})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("CreateUser(%s) status = %d, want %d: %s", tt.userName, rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantID != nil {
+				var user scim.User
+				decodeBody(t, rec, &user)
+				if want := tt.wantID(f); user.ID != want.String() {
+					t.Errorf("CreateUser(%s) = %s, want account %s", tt.userName, user.ID, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSCIMHandler_PendingUser(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t)
+
+	rec := f.call(t, http.MethodPost, "/scim/v2/Users", map[string]string{"userName": "ada@example.com", "externalId": "00u1"})
+	var invited scim.User
+	decodeBody(t, rec, &invited)
+	target := "/scim/v2/Users/" + invited.ID
+
+	// Invitations can be changed and deactivated before they are accepted
+	rec = f.call(t, http.MethodPatch, target, map[string]interface{}{
+		"schemas":    []string{scim.SchemaPatchOp},
+		"Operations": []map[string]interface{}{{"op": "replace", "value": map[string]interface{}{"active": false, "displayName": "Countess"}}},
+	})
+	var patched scim.User
+	decodeBody(t, rec, &patched)
+	if rec.Code != http.StatusOK || *patched.Active || patched.DisplayName != "Countess" || patched.ExternalID != "00u1" {
+		t.Errorf("PatchUser() of an invited user = %d %+v", rec.Code, patched)
+	}
+	if len(f.sessions.revoked) != 0 {
+		t.Errorf("revoked sessions = %v, want none", f.sessions.revoked)
+	}
+
+	// An inactive invitation is accepted without a membership
+	account := seedUser(t, f.users, "ada@example.com")
+	f.scimHandler.AcceptInvitations(ctx, account.ID)
+	if _, err := f.orgs.Role(ctx, f.org.ID, account.ID); err == nil {
+		t.Error("user accepting an inactive invitation became a member")
+	}
+	if user, err := f.scim.GetUser(ctx, f.org.ID, account.ID); err != nil || user.Pending || user.Active {
+		t.Errorf("accepted user = %+v, %v, want an inactive SCIM user", user, err)
+	}
+
+	// Withdrawn invitations don't reserve an ID
+	decodeBody(t, f.call(t, http.MethodPost, "/scim/v2/Users", map[string]string{"userName": "grace@example.com"}), &invited)
+	if rec := f.call(t, http.MethodDelete, "/scim/v2/Users/"+invited.ID, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DeleteUser() of an invited user status = %d", rec.Code)
+	}
+	if rec := f.call(t, http.MethodGet, "/scim/v2/Users/"+invited.ID, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GetUser() of a withdrawn invitation status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	grace := seedUser(t, f.users, "grace@example.com")
+	if grace.ID.String() == invited.ID {
+		t.Error("a withdrawn invitation's ID was given to the account")
+	}
+}
+
+func TestSCIMHandler_Authentication(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      func(f *scimFixture) string
+		wantStatus int
+	}{
+		{name: "no token", token: func(*scimFixture) string { return "" }, wantStatus: http.StatusUnauthorized},
+		{name: "unknown token", token: func(*scimFixture) string { return "ca_scim_unknown" }, wantStatus: http.StatusUnauthorized},
+		{name: "organization's token", token: func(f *scimFixture) string { return f.token }, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSCIMFixture(t)
+
+			req := httptest.NewRequest(http.MethodGet, "/scim/v2/Users", nil)
+			if token := tt.token(f); token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			f.scimRouter.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Errorf("ListUsers() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSCIMHandler_ListUsers(t *testing.T) {
+	f := newSCIMFixture(t)
+	f.provision(t, "ada@example.com")
+	f.provision(t, "grace@example.com")
+
+	tests := []struct {
+		name       string
+		filter     string
+		wantStatus int
+		wantUsers  []string
+	}{
+		{name: "everyone", wantStatus: http.StatusOK, wantUsers: []string{"ada@example.com", "grace@example.com"}},
+		{name: "by userName", filter: `userName eq "grace@example.com"`, wantStatus: http.StatusOK, wantUsers: []string{"grace@example.com"}},
+		{name: "nobody matches", filter: `userName eq "nobody@example.com"`, wantStatus: http.StatusOK},
+		{name: "unsupported attribute", filter: `title eq "x"`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := f.call(t, http.MethodGet, "/scim/v2/Users?filter="+url.QueryEscape(tt.filter), nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("ListUsers() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page struct {
+				TotalResults int         `json:"totalResults"`
+				Resources    []scim.User `json:"Resources"`
+			}
+			decodeBody(t, rec, &page)
+			var got []string
+			for _, user := range page.Resources {
+				got = append(got, user.UserName)
+			}
+			slices.Sort(got)
+			if page.TotalResults != len(tt.wantUsers) || !slices.Equal(got, tt.wantUsers) {
+				t.Errorf("ListUsers() = %d %v, want %v", page.TotalResults, got, tt.wantUsers)
+			}
+		})
+	}
+}
+
+func TestSCIMHandler_PatchUser(t *testing.T) {
+	ctx := context.Background()
+	patch := func(op map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"schemas": []string{scim.SchemaPatchOp}, "Operations": []map[string]interface{}{op}}
+	}
+	deactivate := patch(map[string]interface{}{"op": "replace", "path": "active", "value": false})
+
+	tests := []struct {
+		name string
+		// inactive deactivates the user first
+		inactive    bool
+		body        map[string]interface{}
+		wantStatus  int
+		wantActive  bool
+		wantName    string
+		wantRevoked bool
+	}{
+		{
+			// Azure AD deactivates users with the string "False"
+			name:        "Azure AD deactivates",
+			body:        patch(map[string]interface{}{"op": "Replace", "path": "active", "value": "False"}),
+			wantStatus:  http.StatusOK,
+			wantName:    "Ada Lovelace",
+			wantRevoked: true,
+		},
+		{
+			// Okta sends operations without a path
+			name:       "Okta reactivates",
+			inactive:   true,
+			body:       patch(map[string]interface{}{"op": "replace", "value": map[string]interface{}{"active": true, "displayName": "Countess"}}),
+			wantStatus: http.StatusOK,
+			wantActive: true,
+			wantName:   "Countess",
+		},
+		{
+			name:       "userName can't change",
+			body:       patch(map[string]interface{}{"op": "replace", "path": "userName", "value": "other@example.com"}),
+			wantStatus: http.StatusBadRequest,
+			wantActive: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSCIMFixture(t)
+			user := f.provision(t, "ada@example.com")
+			userID := uuid.MustParse(user.ID)
+			target := "/scim/v2/Users/" + user.ID
+			if tt.inactive {
+				if rec := f.call(t, http.MethodPatch, target, deactivate); rec.Code != http.StatusOK {
+					t.Fatalf("failed to deactivate user: %d %s", rec.Code, rec.Body.String())
+				}
+				f.sessions.revoked = nil
+			}
+
+			rec := f.call(t, http.MethodPatch, target, tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("PatchUser() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus == http.StatusOK {
+				var patched scim.User
+				decodeBody(t, rec, &patched)
+				if patched.Active == nil || *patched.Active != tt.wantActive || patched.DisplayName != tt.wantName {
+					t.Errorf("PatchUser() = %+v, want active %v named %q", patched, tt.wantActive, tt.wantName)
+				}
+			}
+
+			// Members are exactly the active users
+			if _, err := f.orgs.Role(ctx, f.org.ID, userID); (err == nil) != tt.wantActive {
+				t.Errorf("membership = %v, want active %v", err == nil, tt.wantActive)
+			}
+			if revoked := len(f.sessions.revoked) == 1 && f.sessions.revoked[0] == userID; revoked != tt.wantRevoked {
+				t.Errorf("revoked sessions = %v, want revoked %v", f.sessions.revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestSCIMHandler_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		email      string
+		wantStatus int
+	}{
+		// The account is kept; only the membership ends
+		{name: "provisioned user", email: "ada@example.com", wantStatus: http.StatusNoContent},
+		{name: "last owner", email: "owner@example.com", wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSCIMFixture(t)
+			user := f.provision(t, tt.email)
+			userID := uuid.MustParse(user.ID)
+
+			rec := f.call(t, http.MethodDelete, "/scim/v2/Users/"+user.ID, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("DeleteUser() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+
+			deleted := tt.wantStatus == http.StatusNoContent
+			if _, err := f.orgs.Role(ctx, f.org.ID, userID); (err != nil) != deleted {
+				t.Errorf("still a member = %v, want %v", err == nil, !deleted)
+			}
+			if _, err := f.users.GetByID(ctx, userID); err != nil {
+				t.Errorf("account is gone: %v", err)
+			}
+			if rec := f.call(t, http.MethodGet, "/scim/v2/Users/"+user.ID, nil); (rec.Code == http.StatusNotFound) != deleted {
+				t.Errorf("GetUser() after DeleteUser() status = %d", rec.Code)
+			}
+		})
+	}
+}
+
+func TestSCIMHandler_Groups(t *testing.T) {
+	ctx := context.Background()
+	f := newSCIMFixture(t)
+	ada := f.provision(t, "ada@example.com")
+	grace := f.provision(t, "grace@example.com")
+
+	rec := f.call(t, http.MethodPost, "/scim/v2/Groups", map[string]interface{}{
+		"schemas":     []string{scim.SchemaGroup},
+		"displayName": "admins",
+		"members":     []map[string]string{{"value": ada.ID}},
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CreateGroup() status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var group scim.Group
+	decodeBody(t, rec, &group)
+	if len(group.Members) != 1 || group.Members[0].Value != ada.ID {
+		t.Errorf("CreateGroup() members = %+v", group.Members)
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, uuid.MustParse(ada.ID)); role != models.RoleAdmin {
+		t.Errorf("role of admins group member = %s, want admin", role)
+	}
+
+	if rec := f.call(t, http.MethodPost, "/scim/v2/Groups", map[string]string{"displayName": "admins"}); rec.Code != http.StatusConflict {
+		t.Errorf("CreateGroup() with a taken name status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	// Swap Ada for Grace
+	rec = f.call(t, http.MethodPatch, "/scim/v2/Groups/"+group.ID, map[string]interface{}{
+		"schemas": []string{scim.SchemaPatchOp},
+		"Operations": []map[string]interface{}{
+			{"op": "add", "path": "members", "value": []map[string]string{{"value": grace.ID}}},
+			{"op": "remove", "path": `members[value eq "` + ada.ID + `"]`},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("PatchGroup() status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, uuid.MustParse(ada.ID)); role != models.RoleMember {
+		t.Errorf("role of removed member = %s, want member", role)
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, uuid.MustParse(grace.ID)); role != models.RoleAdmin {
+		t.Errorf("role of added member = %s, want admin", role)
+	}
+
+	rec = f.call(t, http.MethodPatch, "/scim/v2/Groups/"+group.ID, map[string]interface{}{
+		"schemas":    []string{scim.SchemaPatchOp},
+		"Operations": []map[string]interface{}{{"op": "replace", "path": "owners", "value": "x"}},
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("PatchGroup() of unsupported path status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	if rec := f.call(t, http.MethodDelete, "/scim/v2/Groups/"+group.ID, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DeleteGroup() status = %d", rec.Code)
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, uuid.MustParse(grace.ID)); role != models.RoleMember {
+		t.Errorf("role after group deleted = %s, want member", role)
+	}
+}
+
+func TestOrgHandler_SCIMToken(t *testing.T) {
+	ctx := context.Background()
+	f := newOrgFixture(t)
+	target := "/orgs/" + f.org.ID.String() + "/scim-token"
+
+	if rec := f.router.do(t, f.admin, http.MethodPost, target, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("CreateSCIMToken() by admin status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec := f.router.do(t, f.owner, http.MethodPost, target, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("CreateSCIMToken() status = %d, body %s", rec.Code, rec.Body.String())
+	}
+	var resp SCIMTokenResponse
+	decodeBody(t, rec, &resp)
+	if orgID, err := f.scim.Authenticate(ctx, resp.Token); err != nil || orgID != f.org.ID {
+		t.Errorf("Authenticate(issued token) = %s, %v", orgID, err)
+	}
+
+	if rec := f.router.do(t, f.owner, http.MethodDelete, target, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("RevokeSCIMToken() status = %d", rec.Code)
+	}
+	if _, err := f.scim.Authenticate(ctx, resp.Token); err == nil {
+		t.Error("revoked token still authenticates")
+	}
+	if rec := f.router.do(t, f.owner, http.MethodDelete, target, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("RevokeSCIMToken() without a token status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
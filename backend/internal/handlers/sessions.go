@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// DeviceCookie is the remember-me cookie
+const DeviceCookie = "ca_device"
+
+// deviceCookiePath sends the cookie only to the API, which exchanges it
+// for access tokens
+const deviceCookiePath = "/api/"
+
+// SessionHandler keeps browsers signed in with remember-me sessions, and
+// lets users see and end them
+type SessionHandler struct {
+	devices  DeviceSessionStorer
+	users    UserStorer
+	tokens   *auth.JWTManager
+	revoker  DeviceRevoker
+	lifetime models.DeviceLifetime
+	// insecure lets the cookie travel over plain HTTP, for development
+	insecure bool
+}
+
+// NewSessionHandler creates a new session handler. revoker invalidates
+// the access tokens of ended sessions.
+func NewSessionHandler(devices DeviceSessionStorer, users UserStorer, tokens *auth.JWTManager, revoker DeviceRevoker, lifetime models.DeviceLifetime) *SessionHandler {
+	return &SessionHandler{
+		devices:  devices,
+		users:    users,
+		tokens:   tokens,
+		revoker:  revoker,
+		lifetime: lifetime,
+	}
+}
+
+// WithInsecureCookie sends the cookie over plain HTTP too
+func (h *SessionHandler) WithInsecureCookie() *SessionHandler {
+	h.insecure = true
+	return h
+}
+
+// Remember starts a remember-me session for the user signing in, sets its
+// cookie and returns an access token bound to it
+func (h *SessionHandler) Remember(w http.ResponseWriter, r *http.Request, user *models.User) (*auth.TokenPair, error) {
+	token, session, err := h.devices.Create(r.Context(), user.ID, r.UserAgent(), clientIP(r), h.lifetime)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenPair, err := h.tokens.GenerateDeviceTokenPair(user.ID, user.Email, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	h.setCookie(w, token, session.ExpiresAt.Time)
+	return tokenPair, nil
+}
+
+// Refresh exchanges the remember-me cookie for a new access token,
+// extending the session
+// POST /api/v1/auth/refresh
+func (h *SessionHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(DeviceCookie)
+	if err != nil {
+		response.Unauthorized(w, "Not remembered on this device")
+		return
+	}
+
+	session, err := h.devices.Resume(r.Context(), cookie.Value, clientIP(r), h.lifetime.Idle)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.clearCookie(w)
+			response.Unauthorized(w, "Session has expired")
+			return
+		}
+		slog.Error("Failed to resume device session", "error", err)
+		response.InternalServerError(w, "Failed to resume session")
+		return
+	}
+
+	// Signing out everywhere, as a password change does, ends remembered
+	// sessions in the database. Sessions whose revocation didn't reach
+	// it are still caught here while the cutoff lasts. Like the auth
+	// middleware, this fails open.
+	revoked, err := h.revoker.RevokedSince(r.Context(), session.UserID, session.CreatedAt.Time)
+	if err != nil && !errors.Is(err, cache.ErrUnavailable) {
+		slog.Error("Failed to check session revocation", "error", err)
+	}
+	if revoked {
+		if err := h.devices.Revoke(r.Context(), session.UserID, session.ID); err != nil {
+			slog.Error("Failed to revoke device session", "device_session_id", session.ID, "error", err)
+		}
+		h.clearCookie(w)
+		response.Unauthorized(w, "Session has been revoked")
+		return
+	}
+
+	user, err := h.users.GetByID(r.Context(), session.UserID)
+	if err != nil {
+		slog.Error("Failed to get user", "user_id", session.UserID, "error", err)
+		response.InternalServerError(w, "Failed to resume session")
+		return
+	}
+
+	tokenPair, err := h.tokens.GenerateDeviceTokenPair(user.ID, user.Email, session.ID)
+	if err != nil {
+		slog.Error("Failed to generate token", "error", err)
+		response.InternalServerError(w, "Failed to generate authentication token")
+		return
+	}
+
+	h.setCookie(w, cookie.Value, session.ExpiresAt.Time)
+	response.Success(w, AuthResponse{
+		User:  dto.NewUser(user),
+		Token: tokenPair,
+	})
+}
+
+// Forget ends the session of the request's remember-me cookie, if any,
+// and clears the cookie. It is part of signing out, so failures are only
+// logged.
+func (h *SessionHandler) Forget(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(DeviceCookie)
+	if err != nil {
+		return
+	}
+	h.clearCookie(w)
+
+	session, err := h.devices.RevokeByToken(r.Context(), cookie.Value)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to revoke device session", "error", err)
+		}
+		return
+	}
+	if err := h.revoker.RevokeDevice(r.Context(), session.ID); err != nil {
+		slog.Error("Failed to revoke device session tokens", "device_session_id", session.ID, "error", err)
+	}
+}
+
+// List returns the user's remembered devices
+// GET /api/v1/me/sessions
+func (h *SessionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	sessions, err := h.devices.List(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list device sessions", "error", err)
+		response.InternalServerError(w, "Failed to list sessions")
+		return
+	}
+
+	response.Success(w, response.Complete(sessions))
+}
+
+// Revoke signs a remembered device out, along with the access tokens it
+// was issued
+// DELETE /api/v1/me/sessions/{id}
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid session ID")
+		return
+	}
+
+	if err := h.devices.Revoke(r.Context(), userID, id); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Session not found")
+			return
+		}
+		slog.Error("Failed to revoke device session", "device_session_id", id, "error", err)
+		response.InternalServerError(w, "Failed to revoke session")
+		return
+	}
+	if err := h.revoker.RevokeDevice(r.Context(), id); err != nil {
+		slog.Error("Failed to revoke device session tokens", "device_session_id", id, "error", err)
+		response.InternalServerError(w, "Failed to revoke session")
+		return
+	}
+
+	slog.Info("Device session revoked", "user_id", userID, "device_session_id", id)
+	response.NoContent(w)
+}
+
+// setCookie stores the session's token in the browser until the session
+// expires. It has no Domain, so it stays on the host that set it and
+// organizations' subdomains don't share it.
+func (h *SessionHandler) setCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     DeviceCookie,
+		Value:    token,
+		Path:     deviceCookiePath,
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearCookie removes the remember-me cookie
+func (h *SessionHandler) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     DeviceCookie,
+		Path:     deviceCookiePath,
+		MaxAge:   -1,
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
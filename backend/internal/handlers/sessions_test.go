@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// fakeDeviceRevoker records revoked device sessions
+type fakeDeviceRevoker struct {
+	mu      sync.Mutex
+	revoked []uuid.UUID
+	// signedOut reports every user as having revoked their sessions
+	signedOut bool
+}
+
+func (f *fakeDeviceRevoker) RevokeDevice(ctx context.Context, sessionID uuid.UUID) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.revoked = append(f.revoked, sessionID)
+	return nil
+}
+
+func (f *fakeDeviceRevoker) RevokedSince(ctx context.Context, userID uuid.UUID, issuedAt time.Time) (bool, error) {
+	return f.signedOut, nil
+}
+
+// sessionFixture is an auth handler that remembers devices, with a user
+// to sign in as
+type sessionFixture struct {
+	router  testRouter
+	devices *memstore.DeviceSessionStore
+	revoker *fakeDeviceRevoker
+	user    *models.User
+}
+
+func newSessionFixture(t *testing.T) *sessionFixture {
+	t.Helper()
+
+	users := memstore.NewUserStore()
+	f := &sessionFixture{
+		router:  newTestRouter(),
+		devices: memstore.NewDeviceSessionStore(),
+		revoker: &fakeDeviceRevoker{},
+		user:    seedUser(t, users, "user@example.com"),
+	}
+	sessions := NewSessionHandler(f.devices, users, testTokens, f.revoker, models.DeviceLifetime{Idle: time.Hour, Max: 24 * time.Hour})
+	handler := NewAuthHandler(users, memstore.NewEmailTokenStore(), testTokens, newFakeNotifier(), &fakeSessions{}).WithRememberMe(sessions)
+
+	f.router.Post("/auth/login", handler.Login)
+	f.router.Post("/auth/logout", handler.Logout)
+	f.router.Post("/auth/refresh", sessions.Refresh)
+	f.router.Get("/me/sessions", sessions.List)
+	f.router.Delete("/me/sessions/{id}", sessions.Revoke)
+	return f
+}
+
+// login signs in, returning the response and its device cookie if any
+func (f *sessionFixture) login(t *testing.T, rememberMe bool) (AuthResponse, *http.Cookie) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, newJSONRequest(t, http.MethodPost, "/auth/login", LoginRequest{Email: f.user.Email, Password: testPassword, RememberMe: rememberMe}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login status = %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp AuthResponse
+	decodeBody(t, rec, &resp)
+	return resp, deviceCookie(rec)
+}
+
+// refresh exchanges a device cookie
+func (f *sessionFixture) refresh(cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+	return rec
+}
+
+// deviceCookie returns the device cookie a response set
+func deviceCookie(rec *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == DeviceCookie {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestSessionHandler_RememberMe(t *testing.T) {
+	f := newSessionFixture(t)
+
+	if _, cookie := f.login(t, false); cookie != nil {
+		t.Errorf("login without remember_me set cookie %v", cookie)
+	}
+
+	resp, cookie := f.login(t, true)
+	if cookie == nil || !cookie.HttpOnly || !cookie.Secure || cookie.MaxAge <= 0 {
+		t.Fatalf("cookie = %+v, want a secure, HTTP-only device cookie", cookie)
+	}
+	// Host-only, so organizations' subdomains don't share it
+	if cookie.Domain != "" {
+		t.Errorf("cookie domain = %q, want none", cookie.Domain)
+	}
+	claims, err := testTokens.ValidateToken(resp.Token.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID := claims.SessionID
+
+	rec := f.refresh(cookie)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh status = %d: %s", rec.Code, rec.Body.String())
+	}
+	decodeBody(t, rec, &resp)
+	if claims, err = testTokens.ValidateToken(resp.Token.AccessToken); err != nil || claims.SessionID != sessionID {
+		t.Errorf("refreshed claims = %+v, %v, want session %s", claims, err, sessionID)
+	}
+	if deviceCookie(rec) == nil {
+		t.Error("refresh didn't extend the cookie")
+	}
+
+	if rec := f.refresh(nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh without cookie status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionHandler_Revoke(t *testing.T) {
+	f := newSessionFixture(t)
+	_, cookie := f.login(t, true)
+
+	rec := f.router.do(t, f.user.ID, http.MethodGet, "/me/sessions", nil)
+	var list response.ListResponse[models.DeviceSession]
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 1 {
+		t.Fatalf("List() = %+v, want the remembered device", list.Data)
+	}
+	id := list.Data[0].ID
+
+	// Another user's session looks the same as a missing one
+	rec = f.router.do(t, uuid.New(), http.MethodDelete, "/me/sessions/"+id.String(), nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("other user status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = f.router.do(t, f.user.ID, http.MethodDelete, "/me/sessions/"+id.String(), nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if len(f.revoker.revoked) != 1 || f.revoker.revoked[0] != id {
+		t.Errorf("revoked tokens of %v, want %s", f.revoker.revoked, id)
+	}
+
+	rec = f.refresh(cookie)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after revoke status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if cleared := deviceCookie(rec); cleared == nil || cleared.MaxAge >= 0 {
+		t.Errorf("cookie = %+v, want it cleared", cleared)
+	}
+}
+
+func TestSessionHandler_SignedOutEverywhere(t *testing.T) {
+	f := newSessionFixture(t)
+	_, cookie := f.login(t, true)
+
+	f.revoker.signedOut = true
+	if rec := f.refresh(cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if sessions, _ := f.devices.List(context.Background(), f.user.ID); len(sessions) != 0 {
+		t.Errorf("sessions = %+v, want the session ended", sessions)
+	}
+}
+
+func TestSessionHandler_SignedOutEverywhere_AfterCutoffExpires(t *testing.T) {
+	f := newSessionFixture(t)
+	_, cookie := f.login(t, true)
+
+	// Nothing listens on the port, so no cutoff is left in Redis, as after
+	// it expires; revoking still ends the device sessions first
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	c, err := cache.NewLazy("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	if err := auth.NewSessions(c).WithDevices(f.devices).RevokeAll(context.Background(), f.user.ID); err == nil {
+		t.Fatal("RevokeAll() without Redis succeeded")
+	}
+
+	if rec := f.refresh(cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh without the cutoff status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestSessionHandler_Logout(t *testing.T) {
+	f := newSessionFixture(t)
+	_, cookie := f.login(t, true)
+
+	req := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.AddCookie(cookie)
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("logout status = %d", rec.Code)
+	}
+	if len(f.revoker.revoked) != 1 {
+		t.Errorf("revoked = %v, want the device's tokens revoked", f.revoker.revoked)
+	}
+	if rec := f.refresh(cookie); rec.Code != http.StatusUnauthorized {
+		t.Errorf("refresh after logout status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
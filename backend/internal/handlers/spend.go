@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+)
+
+// HeldJobs releases jobs set aside by the queue; *queue.Queue implements
+// it
+type HeldJobs interface {
+	Unhold(ctx context.Context) (int, error)
+}
+
+// SpendHandler lets operators set organizations' spend budgets
+type SpendHandler struct {
+	orgs SpendBudgetSetter
+	held HeldJobs
+}
+
+// NewSpendHandler creates a new spend handler
+func NewSpendHandler(orgs SpendBudgetSetter) *SpendHandler {
+	return &SpendHandler{orgs: orgs}
+}
+
+// WithHeldJobs releases held jobs whenever a budget changes, so analyses
+// held by a budget that was raised or removed run without waiting for the
+// next scheduled release
+func (h *SpendHandler) WithHeldJobs(held HeldJobs) *SpendHandler {
+	h.held = held
+	return h
+}
+
+// SetBudget replaces an organization's daily and monthly spend budgets; a
+// null budget leaves that period uncapped
+// PUT /api/v1/admin/orgs/{id}/budget
+func (h *SpendHandler) SetBudget(w http.ResponseWriter, r *http.Request) {
+	orgID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid organization ID")
+		return
+	}
+
+	var budget models.SpendBudget
+	if err := json.NewDecoder(r.Body).Decode(&budget); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	if err := budget.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	org, err := h.orgs.SetSpendBudget(r.Context(), orgID, budget)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Organization not found")
+			return
+		}
+		slog.Error("Failed to set spend budget", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set spend budget")
+		return
+	}
+
+	slog.Warn("Spend budget changed", "org_id", orgID, "daily", budgetUSD(budget.DailyBudgetMicros), "monthly", budgetUSD(budget.MonthlyBudgetMicros), "by", operator(r))
+
+	if h.held != nil {
+		if _, err := h.held.Unhold(r.Context()); err != nil {
+			slog.Warn("Failed to release held jobs", "error", err)
+		}
+	}
+
+	response.Success(w, org)
+}
+
+// budgetUSD formats a budget for the log
+func budgetUSD(micros *int64) string {
+	if micros == nil {
+		return "uncapped"
+	}
+	return spend.FormatUSD(*micros)
+}
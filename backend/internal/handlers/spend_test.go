@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// countingHeldJobs counts how often held jobs are released
+type countingHeldJobs struct {
+	released int
+}
+
+func (h *countingHeldJobs) Unhold(ctx context.Context) (int, error) {
+	h.released++
+	return 0, nil
+}
+
+func TestSpendHandler_SetBudget(t *testing.T) {
+	orgs := memstore.NewOrganizationStore(memstore.NewUserStore())
+	org, err := orgs.Create(context.Background(), "Acme", uuid.New())
+	if err != nil {
+		t.Fatalf("failed to seed organization: %v", err)
+	}
+
+	held := &countingHeldJobs{}
+	r := chi.NewRouter()
+	r.Put("/admin/orgs/{id}/budget", NewSpendHandler(orgs).WithHeldJobs(held).SetBudget)
+
+	tests := []struct {
+		name        string
+		id          string
+		body        string
+		wantStatus  int
+		wantDaily   *int64
+		wantMonthly *int64
+	}{
+		{name: "invalid id", id: "nope", body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown organization", id: uuid.NewString(), body: `{"daily_budget_micros": 5000000}`, wantStatus: http.StatusNotFound},
+		{name: "not positive", id: org.ID.String(), body: `{"daily_budget_micros": 0}`, wantStatus: http.StatusBadRequest},
+		{name: "both", id: org.ID.String(), body: `{"daily_budget_micros": 5000000, "monthly_budget_micros": 100000000}`, wantStatus: http.StatusOK, wantDaily: int64Ptr(5_000_000), wantMonthly: int64Ptr(100_000_000)},
+		{name: "monthly only", id: org.ID.String(), body: `{"daily_budget_micros": null, "monthly_budget_micros": 100000000}`, wantStatus: http.StatusOK, wantMonthly: int64Ptr(100_000_000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPut, "/admin/orgs/"+tt.id+"/budget", tt.body), uuid.New()))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SetBudget() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var resp models.Organization
+			decodeBody(t, rec, &resp)
+			if !equalInt64Ptr(resp.DailyBudgetMicros, tt.wantDaily) || !equalInt64Ptr(resp.MonthlyBudgetMicros, tt.wantMonthly) {
+				t.Errorf("budget = %+v, want daily %v and monthly %v", resp.SpendBudget, tt.wantDaily, tt.wantMonthly)
+			}
+		})
+	}
+
+	if held.released != 2 {
+		t.Errorf("held jobs released %d times, want once per change", held.released)
+	}
+}
+
+func int64Ptr(n int64) *int64 { return &n }
+
+func equalInt64Ptr(a, b *int64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
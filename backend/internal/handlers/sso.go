@@ -0,0 +1,506 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/sso"
+)
+
+// SSOCookie binds a sign-in in flight to the browser that started it
+const SSOCookie = "ca_sso"
+
+// defaultGroupsClaim is the ID token claim read for groups when an
+// organization doesn't name another
+const defaultGroupsClaim = "groups"
+
+// SSOHandler signs users in through their organization's identity
+// provider. Users it hasn't seen are given an account and a membership
+// just in time, if their address is under one of the organization's
+// verified domains.
+type SSOHandler struct {
+	configs  SSOIdentityStorer
+	provider SSOProvider
+	states   SSOStateStorer
+	users    UserStorer
+	orgs     OrganizationStorer
+	tokens   *auth.JWTManager
+	audit    AuditRecorder
+	// domains allows accounts to be provisioned; without it, only
+	// existing members can sign in
+	domains DomainVerifier
+	// redirectURI is where the identity provider sends users back to: the
+	// frontend, which posts the code it is given to Callback
+	redirectURI string
+	// insecure lets the cookie travel over plain HTTP, for development
+	insecure bool
+}
+
+// NewSSOHandler creates a new SSO handler
+func NewSSOHandler(configs SSOIdentityStorer, provider SSOProvider, states SSOStateStorer, users UserStorer, orgs OrganizationStorer, tokens *auth.JWTManager, audit AuditRecorder, redirectURI string) *SSOHandler {
+	return &SSOHandler{
+		configs:     configs,
+		provider:    provider,
+		states:      states,
+		users:       users,
+		orgs:        orgs,
+		tokens:      tokens,
+		audit:       audit,
+		redirectURI: redirectURI,
+	}
+}
+
+// WithDomains provisions accounts for addresses under organizations'
+// verified domains and returns the handler
+func (h *SSOHandler) WithDomains(domains DomainVerifier) *SSOHandler {
+	h.domains = domains
+	return h
+}
+
+// WithInsecureCookie sends the sign-in cookie over plain HTTP too
+func (h *SSOHandler) WithInsecureCookie() *SSOHandler {
+	h.insecure = true
+	return h
+}
+
+// SSOCallbackRequest completes a sign-in with what the identity provider
+// sent back
+type SSOCallbackRequest struct {
+	Code  string `json:"code"`
+	State string `json:"state"`
+}
+
+// Start sends the user to their organization's identity provider
+// GET /api/v1/auth/sso/{org}
+func (h *SSOHandler) Start(w http.ResponseWriter, r *http.Request) {
+	orgID, err := uuid.Parse(chi.URLParam(r, "org"))
+	if err != nil {
+		response.BadRequest(w, "Invalid organization ID")
+		return
+	}
+
+	config, err := h.configs.Get(r.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Single sign-on is not set up for this organization")
+			return
+		}
+		slog.Error("Failed to get SSO config", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to start single sign-on")
+		return
+	}
+
+	state, nonce, binding, err := h.states.Begin(r.Context(), orgID)
+	if err != nil {
+		slog.Error("Failed to begin SSO sign-in", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to start single sign-on")
+		return
+	}
+
+	target, err := h.provider.AuthCodeURL(r.Context(), config, h.redirectURI, state, nonce)
+	if err != nil {
+		slog.Error("Failed to reach identity provider", "org_id", orgID, "issuer", config.Issuer, "error", err)
+		response.Error(w, http.StatusBadGateway, "Failed to reach the identity provider")
+		return
+	}
+
+	h.setCookie(w, binding)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// Callback exchanges the code the identity provider sent back for the
+// user's identity, and signs them in as Login would
+// POST /api/v1/auth/sso/callback
+func (h *SSOHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	var req SSOCallbackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	if req.Code == "" || req.State == "" {
+		response.ValidationError(w, map[string]string{
+			"code": "Code and state are required",
+		})
+		return
+	}
+
+	// Only the browser that started the sign-in can finish it
+	cookie, err := r.Cookie(SSOCookie)
+	if err != nil {
+		response.Unauthorized(w, "Sign-in has expired; sign in again")
+		return
+	}
+	h.clearCookie(w)
+
+	orgID, nonce, err := h.states.Finish(r.Context(), req.State, cookie.Value)
+	if err != nil {
+		if errors.Is(err, sso.ErrInvalidState) {
+			response.Unauthorized(w, "Sign-in has expired; sign in again")
+			return
+		}
+		slog.Error("Failed to finish SSO sign-in", "error", err)
+		response.InternalServerError(w, "Failed to complete single sign-on")
+		return
+	}
+
+	config, err := h.configs.Get(r.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Single sign-on is not set up for this organization")
+			return
+		}
+		slog.Error("Failed to get SSO config", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to complete single sign-on")
+		return
+	}
+
+	identity, err := h.provider.Exchange(r.Context(), config, h.redirectURI, req.Code, nonce)
+	if err != nil {
+		slog.Warn("SSO sign-in rejected", "org_id", orgID, "error", err)
+		response.Unauthorized(w, "The identity provider didn't confirm the sign-in")
+		return
+	}
+
+	user, provisioned, ok := h.resolve(w, r, config, identity)
+	if !ok || refuseStanding(w, h.tokens, user) {
+		return
+	}
+	h.syncRole(r.Context(), config, user.ID, identity.Groups)
+
+	tokenPair, err := h.tokens.GenerateTokenPair(user.ID, user.Email)
+	if err != nil {
+		slog.Error("Failed to generate token", "error", err)
+		response.InternalServerError(w, "Failed to generate authentication token")
+		return
+	}
+
+	h.record(r, user.ID, orgID, provisioned)
+	response.Success(w, AuthResponse{
+		User:  dto.NewUser(user),
+		Token: tokenPair,
+	})
+}
+
+// resolve finds the account an identity signs in to. Identities seen
+// before are linked already; new ones are linked to the account with
+// their verified email if it belongs to the organization, or given a new
+// account if the email is under one of the organization's verified
+// domains. An account outside the organization is never taken over. It
+// reports whether it created the account.
+func (h *SSOHandler) resolve(w http.ResponseWriter, r *http.Request, config *models.SSOConfig, identity *sso.Identity) (*models.User, bool, bool) {
+	ctx := r.Context()
+
+	userID, err := h.configs.FindIdentity(ctx, config.OrgID, identity.Subject)
+	if err == nil {
+		user, err := h.users.GetByID(ctx, userID)
+		if err != nil {
+			slog.Error("Failed to get user", "user_id", userID, "error", err)
+			response.InternalServerError(w, "Failed to complete single sign-on")
+			return nil, false, false
+		}
+		return user, false, true
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to find SSO identity", "org_id", config.OrgID, "error", err)
+		response.InternalServerError(w, "Failed to complete single sign-on")
+		return nil, false, false
+	}
+
+	email := strings.ToLower(strings.TrimSpace(identity.Email))
+	if email == "" || !identity.EmailVerified {
+		response.Forbidden(w, "The identity provider didn't share a verified email address")
+		return nil, false, false
+	}
+
+	provisioned := false
+	user, err := h.users.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		if _, err := h.orgs.Role(ctx, config.OrgID, user.ID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				response.Conflict(w, "An account with this email already exists; ask an organization owner to add it first")
+				return nil, false, false
+			}
+			slog.Error("Failed to get organization role", "org_id", config.OrgID, "error", err)
+			response.InternalServerError(w, "Failed to complete single sign-on")
+			return nil, false, false
+		}
+	case errors.Is(err, pgx.ErrNoRows):
+		verified, err := verifiedDomain(ctx, h.domains, config.OrgID, email)
+		if err != nil {
+			slog.Error("Failed to check organization domain", "org_id", config.OrgID, "error", err)
+			response.InternalServerError(w, "Failed to complete single sign-on")
+			return nil, false, false
+		}
+		if !verified {
+			response.Forbidden(w, "Accounts are only created for addresses under the organization's verified domains; ask an organization owner to add you")
+			return nil, false, false
+		}
+		if user, err = provisionUser(ctx, h.users, email); err != nil {
+			slog.Error("Failed to provision SSO user", "org_id", config.OrgID, "error", err)
+			response.InternalServerError(w, "Failed to complete single sign-on")
+			return nil, false, false
+		}
+		provisioned = true
+	default:
+		slog.Error("Failed to get user", "error", err)
+		response.InternalServerError(w, "Failed to complete single sign-on")
+		return nil, false, false
+	}
+
+	if err := h.configs.LinkIdentity(ctx, config.OrgID, identity.Subject, user.ID); err != nil {
+		slog.Error("Failed to link SSO identity", "org_id", config.OrgID, "user_id", user.ID, "error", err)
+		response.InternalServerError(w, "Failed to complete single sign-on")
+		return nil, false, false
+	}
+
+	slog.Info("SSO identity linked", "org_id", config.OrgID, "user_id", user.ID, "provisioned", provisioned)
+	return user, provisioned, true
+}
+
+// verifiedDomain reports whether email is under one of the
+// organization's verified domains. Without domains, none are.
+func verifiedDomain(ctx context.Context, domains DomainVerifier, orgID uuid.UUID, email string) (bool, error) {
+	if domains == nil {
+		return false, nil
+	}
+	domain := models.EmailDomain(email)
+	if domain == "" {
+		return false, nil
+	}
+	return domains.IsVerified(ctx, orgID, domain)
+}
+
+// provisionUser creates an account for a user an identity provider
+// vouches for, under one of its organization's verified domains. Its
+// password is random: the user signs in through the identity provider,
+// or resets it. The email stays unverified until the user confirms it
+// themselves; the identity provider's word isn't taken for it.
+func provisionUser(ctx context.Context, users UserStorer, email string) (*models.User, error) {
+	password, err := models.NewDeviceToken()
+	if err != nil {
+		return nil, err
+	}
+	return users.Create(ctx, email, password)
+}
+
+// syncRole gives the user the role their groups map to. Owners keep
+// theirs, as the identity provider can't grant or take it. Failing to
+// only leaves the old role in place, so errors are logged.
+func (h *SSOHandler) syncRole(ctx context.Context, config *models.SSOConfig, userID uuid.UUID, groups []string) {
+	current, err := h.orgs.Role(ctx, config.OrgID, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to get organization role", "org_id", config.OrgID, "error", err)
+		return
+	}
+
+	role := config.RoleFor(groups)
+	if current == models.RoleOwner || current == role {
+		return
+	}
+
+	if err := h.orgs.SetMember(ctx, config.OrgID, userID, role); err != nil {
+		slog.Error("Failed to sync SSO role", "org_id", config.OrgID, "user_id", userID, "role", role, "error", err)
+		return
+	}
+	slog.Info("SSO role synced", "org_id", config.OrgID, "user_id", userID, "from", current, "to", role)
+}
+
+// record adds the sign-in to the user's audit log
+func (h *SSOHandler) record(r *http.Request, userID, orgID uuid.UUID, provisioned bool) {
+	entry := &models.AuditEntry{
+		UserID:    userID,
+		Action:    models.AuditSSOLogin,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata: map[string]string{
+			"org_id":      orgID.String(),
+			"provisioned": strconv.FormatBool(provisioned),
+		},
+	}
+
+	// Record even if the client has gone away mid-request
+	if err := h.audit.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+		slog.Error("Failed to record audit entry", "action", entry.Action, "user_id", userID, "error", err)
+	}
+}
+
+// SSOConfigRequest replaces an organization's single sign-on setup
+type SSOConfigRequest struct {
+	Protocol models.SSOProtocol `json:"protocol"`
+	Issuer   string             `json:"issuer"`
+	ClientID string             `json:"client_id"`
+	// ClientSecret may be left out to keep the current one
+	ClientSecret string                  `json:"client_secret"`
+	GroupsClaim  string                  `json:"groups_claim"`
+	RoleMappings []models.SSORoleMapping `json:"role_mappings"`
+	DefaultRole  models.OrgRole          `json:"default_role"`
+	Enforced     bool                    `json:"enforced"`
+}
+
+// GetSSO returns the organization's single sign-on setup, without its
+// client secret. Owners and admins only.
+// GET /api/v1/orgs/{id}/sso
+func (h *OrgHandler) GetSSO(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if !role.CanManage() {
+		response.Forbidden(w, "Only organization owners and admins can see single sign-on settings")
+		return
+	}
+
+	config, err := h.sso.Get(r.Context(), orgID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Single sign-on is not set up for this organization")
+			return
+		}
+		slog.Error("Failed to get SSO config", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to get single sign-on settings")
+		return
+	}
+
+	response.Success(w, config)
+}
+
+// SetSSO sets up single sign-on for the organization or replaces its
+// setup. Owners only.
+// PUT /api/v1/orgs/{id}/sso
+func (h *OrgHandler) SetSSO(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can change single sign-on settings")
+		return
+	}
+
+	var req SSOConfigRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	config := &models.SSOConfig{
+		OrgID:        orgID,
+		Protocol:     req.Protocol,
+		Issuer:       strings.TrimRight(strings.TrimSpace(req.Issuer), "/"),
+		ClientID:     strings.TrimSpace(req.ClientID),
+		ClientSecret: req.ClientSecret,
+		GroupsClaim:  req.GroupsClaim,
+		RoleMappings: req.RoleMappings,
+		DefaultRole:  req.DefaultRole,
+		Enforced:     req.Enforced,
+	}
+	if config.Protocol == "" {
+		config.Protocol = models.SSOOIDC
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = defaultGroupsClaim
+	}
+	if config.DefaultRole == "" {
+		config.DefaultRole = models.RoleMember
+	}
+	if config.RoleMappings == nil {
+		config.RoleMappings = []models.SSORoleMapping{}
+	}
+
+	if config.ClientSecret == "" {
+		current, err := h.sso.Get(r.Context(), orgID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to get SSO config", "org_id", orgID, "error", err)
+			response.InternalServerError(w, "Failed to set single sign-on settings")
+			return
+		}
+		if current != nil {
+			config.ClientSecret = current.ClientSecret
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	config, err := h.sso.Set(r.Context(), config)
+	if err != nil {
+		slog.Error("Failed to set SSO config", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to set single sign-on settings")
+		return
+	}
+
+	// Logged at warn: enforcing it stops members signing in with passwords
+	slog.Warn("SSO settings changed", "org_id", orgID, "issuer", config.Issuer, "enforced", config.Enforced, "by", operator(r))
+	response.Success(w, config)
+}
+
+// DeleteSSO turns single sign-on off for the organization, unlinking its
+// members' identities. They sign in with passwords again. Owners only.
+// DELETE /api/v1/orgs/{id}/sso
+func (h *OrgHandler) DeleteSSO(w http.ResponseWriter, r *http.Request) {
+	orgID, _, role, ok := h.membership(w, r)
+	if !ok {
+		return
+	}
+	if role != models.RoleOwner {
+		response.Forbidden(w, "Only organization owners can change single sign-on settings")
+		return
+	}
+
+	if err := h.sso.Delete(r.Context(), orgID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Single sign-on is not set up for this organization")
+			return
+		}
+		slog.Error("Failed to delete SSO config", "org_id", orgID, "error", err)
+		response.InternalServerError(w, "Failed to delete single sign-on settings")
+		return
+	}
+
+	slog.Warn("SSO turned off", "org_id", orgID, "by", operator(r))
+	response.NoContent(w)
+}
+
+// setCookie gives the browser the secret its sign-in is bound to, for as
+// long as the sign-in can be finished. It is Lax rather than Strict so
+// it survives the identity provider's redirect back to the frontend.
+func (h *SSOHandler) setCookie(w http.ResponseWriter, binding string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SSOCookie,
+		Value:    binding,
+		Path:     deviceCookiePath,
+		MaxAge:   int(sso.StateTTL.Seconds()),
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearCookie removes the sign-in cookie
+func (h *SSOHandler) clearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     SSOCookie,
+		Path:     deviceCookiePath,
+		MaxAge:   -1,
+		Secure:   !h.insecure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/sso"
+)
+
+// fakeProvider signs in whoever its codes are issued to
+type fakeProvider struct {
+	mu         sync.Mutex
+	identities map[string]*sso.Identity
+}
+
+func (p *fakeProvider) AuthCodeURL(ctx context.Context, config *models.SSOConfig, redirectURI, state, nonce string) (string, error) {
+	return config.Issuer + "/authorize?state=" + state, nil
+}
+
+func (p *fakeProvider) Exchange(ctx context.Context, config *models.SSOConfig, redirectURI, code, nonce string) (*sso.Identity, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	identity, ok := p.identities[code]
+	if !ok {
+		return nil, sso.ErrInvalidToken
+	}
+	return identity, nil
+}
+
+// fakeStates remembers sign-ins in memory, each finished once
+type fakeStates struct {
+	mu     sync.Mutex
+	states map[string]fakeState
+}
+
+// fakeState is a sign-in in flight and the browser it is bound to
+type fakeState struct {
+	orgID   uuid.UUID
+	binding string
+}
+
+func (s *fakeStates) Begin(ctx context.Context, orgID uuid.UUID) (string, string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, binding := uuid.NewString(), uuid.NewString()
+	s.states[state] = fakeState{orgID: orgID, binding: binding}
+	return state, "nonce", binding, nil
+}
+
+func (s *fakeStates) Finish(ctx context.Context, state, binding string) (uuid.UUID, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.states[state]
+	delete(s.states, state)
+	if !ok || pending.binding != binding {
+		return uuid.Nil, "", sso.ErrInvalidState
+	}
+	return pending.orgID, "nonce", nil
+}
+
+// ssoFixture is an organization fixture with single sign-on set up,
+// mapping the IdP's "admins" group to admin
+type ssoFixture struct {
+	*orgFixture
+	handler  *SSOHandler
+	provider *fakeProvider
+	states   *fakeStates
+	audit    *memstore.AuditStore
+}
+
+func newSSOFixture(t *testing.T) *ssoFixture {
+	t.Helper()
+
+	f := &ssoFixture{
+		orgFixture: newOrgFixture(t),
+		provider:   &fakeProvider{identities: make(map[string]*sso.Identity)},
+		states:     &fakeStates{states: make(map[string]fakeState)},
+		audit:      memstore.NewAuditStore(),
+	}
+	f.enableSSO(t)
+
+	f.handler = NewSSOHandler(f.sso, f.provider, f.states, f.users, f.orgs,
+		auth.NewJWTManager("test-secret-key-at-least-32-characters"), f.audit, "http://localhost:3000/sso/callback").
+		WithDomains(f.domains)
+	return f
+}
+
+// signIn completes a sign-in as identity and returns the response
+func (f *ssoFixture) signIn(t *testing.T, identity *sso.Identity) *httptest.ResponseRecorder {
+	t.Helper()
+
+	state, _, binding, _ := f.states.Begin(context.Background(), f.org.ID)
+	code := uuid.NewString()
+	f.provider.identities[code] = identity
+
+	rec := httptest.NewRecorder()
+	f.handler.Callback(rec, newCallbackRequest(t, SSOCallbackRequest{Code: code, State: state}, binding))
+	return rec
+}
+
+// newCallbackRequest posts body to the callback from a browser holding
+// the sign-in cookie binding, or no cookie if it is empty
+func newCallbackRequest(t *testing.T, body SSOCallbackRequest, binding string) *http.Request {
+	t.Helper()
+
+	req := newJSONRequest(t, http.MethodPost, "/auth/sso/callback", body)
+	if binding != "" {
+		req.AddCookie(&http.Cookie{Name: SSOCookie, Value: binding})
+	}
+	return req
+}
+
+func TestSSOHandler_Start(t *testing.T) {
+	f := newSSOFixture(t)
+
+	r := chi.NewRouter()
+	r.Get("/auth/sso/{org}", f.handler.Start)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/sso/"+f.org.ID.String(), nil))
+	if rec.Code != http.StatusFound {
+		t.Fatalf("Start() status = %d, want %d", rec.Code, http.StatusFound)
+	}
+	if location := rec.Header().Get("Location"); !strings.HasPrefix(location, "https://idp.example.com/authorize?state=") {
+		t.Errorf("Start() redirected to %q", location)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != SSOCookie || cookies[0].Value == "" ||
+		!cookies[0].HttpOnly || !cookies[0].Secure || cookies[0].SameSite != http.SameSiteLaxMode {
+		t.Errorf("Start() set cookies %+v, want an HttpOnly, Secure, SameSite %s cookie", cookies, SSOCookie)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/auth/sso/"+uuid.NewString(), nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Start() for organization without SSO status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSSOHandler_Callback(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t)
+
+	// A new user under a verified domain is provisioned and given their
+	// group's role, but their email isn't verified on the IdP's word
+	rec := f.signIn(t, &sso.Identity{Subject: "sub-1", Email: "New@Example.com", EmailVerified: true, Groups: []string{"admins"}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Callback() status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var resp AuthResponse
+	decodeBody(t, rec, &resp)
+	if resp.Token == nil || resp.User.Email != "new@example.com" || resp.User.EmailVerified {
+		t.Fatalf("Callback() = %+v, want a token for unverified new@example.com", resp.User)
+	}
+	userID := uuid.MustParse(resp.User.ID)
+	if role, _ := f.orgs.Role(ctx, f.org.ID, userID); role != models.RoleAdmin {
+		t.Errorf("provisioned role = %q, want %q", role, models.RoleAdmin)
+	}
+	entries := f.audit.Entries()
+	if len(entries) != 1 || entries[0].Action != models.AuditSSOLogin || entries[0].Metadata["provisioned"] != "true" {
+		t.Errorf("audit entries = %+v, want one provisioning sso_login", entries)
+	}
+
+	// Signing in again finds the same account, and follows group changes
+	rec = f.signIn(t, &sso.Identity{Subject: "sub-1", Email: "new@example.com", EmailVerified: true})
+	var again AuthResponse
+	decodeBody(t, rec, &again)
+	if again.User == nil || again.User.ID != resp.User.ID {
+		t.Fatalf("second sign-in = %+v, want user %s", again.User, resp.User.ID)
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, userID); role != models.RoleMember {
+		t.Errorf("role after leaving group = %q, want %q", role, models.RoleMember)
+	}
+
+	// Owners keep their role whatever their groups
+	if rec := f.signIn(t, &sso.Identity{Subject: "sub-owner", Email: "owner@example.com", EmailVerified: true}); rec.Code != http.StatusOK {
+		t.Fatalf("owner sign-in status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if role, _ := f.orgs.Role(ctx, f.org.ID, f.owner); role != models.RoleOwner {
+		t.Errorf("owner role = %q, want %q", role, models.RoleOwner)
+	}
+
+	tests := []struct {
+		name       string
+		identity   *sso.Identity
+		wantStatus int
+	}{
+		{
+			name:       "existing member is linked",
+			identity:   &sso.Identity{Subject: "sub-member", Email: "member@example.com", EmailVerified: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unverified email",
+			identity:   &sso.Identity{Subject: "sub-2", Email: "other@example.com"},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "new user outside the verified domains",
+			identity:   &sso.Identity{Subject: "sub-5", Email: "new@example.org", EmailVerified: true},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "new user under a subdomain",
+			identity:   &sso.Identity{Subject: "sub-6", Email: "new@mail.example.com", EmailVerified: true},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "missing email",
+			identity:   &sso.Identity{Subject: "sub-3", EmailVerified: true},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := f.signIn(t, tt.identity); rec.Code != tt.wantStatus {
+				t.Errorf("Callback() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// An account outside the organization isn't taken over
+	seedUser(t, f.users, "outsider@example.com")
+	if rec := f.signIn(t, &sso.Identity{Subject: "sub-4", Email: "outsider@example.com", EmailVerified: true}); rec.Code != http.StatusConflict {
+		t.Errorf("outsider sign-in status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if _, err := f.sso.FindIdentity(ctx, f.org.ID, "sub-4"); err == nil {
+		t.Error("outsider's identity was linked")
+	}
+}
+
+func TestSSOHandler_CallbackRejected(t *testing.T) {
+	f := newSSOFixture(t)
+
+	state, _, binding, _ := f.states.Begin(context.Background(), f.org.ID)
+	stolen, _, _, _ := f.states.Begin(context.Background(), f.org.ID)
+	f.provider.identities["good"] = &sso.Identity{Subject: "sub-1", Email: "new@example.com", EmailVerified: true}
+
+	tests := []struct {
+		name       string
+		body       SSOCallbackRequest
+		binding    string
+		wantStatus int
+	}{
+		{name: "missing code", body: SSOCallbackRequest{State: state}, binding: binding, wantStatus: http.StatusUnprocessableEntity},
+		{name: "unknown state", body: SSOCallbackRequest{Code: "good", State: "forged"}, binding: binding, wantStatus: http.StatusUnauthorized},
+		// An attacker's sign-in finished in a victim's browser
+		{name: "no cookie", body: SSOCallbackRequest{Code: "good", State: stolen}, wantStatus: http.StatusUnauthorized},
+		{name: "another browser's cookie", body: SSOCallbackRequest{Code: "good", State: stolen}, binding: binding, wantStatus: http.StatusUnauthorized},
+		{name: "code the provider rejects", body: SSOCallbackRequest{Code: "bad", State: state}, binding: binding, wantStatus: http.StatusUnauthorized},
+		// The state was used up by the rejected attempt
+		{name: "reused state", body: SSOCallbackRequest{Code: "good", State: state}, binding: binding, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			f.handler.Callback(rec, newCallbackRequest(t, tt.body, tt.binding))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Callback() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestOrgHandler_SetSSO(t *testing.T) {
+	f := newOrgFixture(t)
+	target := "/orgs/" + f.org.ID.String() + "/sso"
+	valid := SSOConfigRequest{
+		Issuer:       "https://idp.example.com/",
+		ClientID:     "content-analyzer",
+		ClientSecret: "secret",
+		RoleMappings: []models.SSORoleMapping{{Group: "admins", Role: models.RoleAdmin}},
+		Enforced:     true,
+	}
+
+	tests := []struct {
+		name       string
+		caller     func(f *orgFixture) uuid.UUID
+		body       func(req SSOConfigRequest) SSOConfigRequest
+		wantStatus int
+	}{
+		{
+			name:       "admin can't set it up",
+			caller:     func(f *orgFixture) uuid.UUID { return f.admin },
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:   "issuer must be https",
+			caller: func(f *orgFixture) uuid.UUID { return f.owner },
+			body: func(req SSOConfigRequest) SSOConfigRequest {
+				req.Issuer = "http://idp.example.com"
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "groups can't grant owner",
+			caller: func(f *orgFixture) uuid.UUID { return f.owner },
+			body: func(req SSOConfigRequest) SSOConfigRequest {
+				req.RoleMappings = []models.SSORoleMapping{{Group: "admins", Role: models.RoleOwner}}
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "client secret is required at first",
+			caller: func(f *orgFixture) uuid.UUID { return f.owner },
+			body: func(req SSOConfigRequest) SSOConfigRequest {
+				req.ClientSecret = ""
+				return req
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "owner sets it up",
+			caller:     func(f *orgFixture) uuid.UUID { return f.owner },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "client secret is kept when left out",
+			caller: func(f *orgFixture) uuid.UUID { return f.owner },
+			body: func(req SSOConfigRequest) SSOConfigRequest {
+				req.ClientSecret = ""
+				return req
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := valid
+			if tt.body != nil {
+				body = tt.body(body)
+			}
+			rec := f.router.do(t, tt.caller(f), http.MethodPut, target, body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("SetSSO() status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// Admins can see the setup, but never the secret
+	rec := f.router.do(t, f.admin, http.MethodGet, target, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GetSSO() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if strings.Contains(rec.Body.String(), "secret\"") {
+		t.Errorf("GetSSO() leaked the client secret: %s", rec.Body.String())
+	}
+	var config models.SSOConfig
+	decodeBody(t, rec, &config)
+	if config.Issuer != "https://idp.example.com" || config.GroupsClaim != "groups" || config.DefaultRole != models.RoleMember || !config.Enforced {
+		t.Errorf("GetSSO() = %+v, want the defaults filled in", config)
+	}
+	if stored, _ := f.sso.Get(context.Background(), f.org.ID); stored.ClientSecret != "secret" {
+		t.Errorf("stored client secret = %q, want it kept", stored.ClientSecret)
+	}
+	if rec := f.router.do(t, f.member, http.MethodGet, target, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("GetSSO() by member status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// Turning it off
+	if rec := f.router.do(t, f.owner, http.MethodDelete, target, nil); rec.Code != http.StatusNoContent {
+		t.Fatalf("DeleteSSO() status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if rec := f.router.do(t, f.admin, http.MethodGet, target, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("GetSSO() after delete status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// failingEnforcer fails every lookup
+type failingEnforcer struct{}
+
+func (failingEnforcer) Enforced(ctx context.Context, userID uuid.UUID) (uuid.UUID, bool, error) {
+	return uuid.Nil, false, errors.New("database down")
+}
+
+func TestAuthHandler_LoginSSOEnforced(t *testing.T) {
+	ctx := context.Background()
+	f := newSSOFixture(t)
+
+	config, _ := f.sso.Get(ctx, f.org.ID)
+	config.Enforced = true
+	if _, err := f.sso.Set(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	seedUser(t, f.users, "outsider@example.com")
+
+	tests := []struct {
+		name       string
+		email      string
+		enforcer   SSOEnforcer
+		wantStatus int
+	}{
+		{name: "member must use SSO", email: "member@example.com", enforcer: f.sso, wantStatus: http.StatusForbidden},
+		{name: "owner keeps password sign-in", email: "owner@example.com", enforcer: f.sso, wantStatus: http.StatusOK},
+		{name: "outsider is unaffected", email: "outsider@example.com", enforcer: f.sso, wantStatus: http.StatusOK},
+		{name: "lookup fails closed", email: "outsider@example.com", enforcer: failingEnforcer{}, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(f.users, memstore.NewEmailTokenStore(), auth.NewJWTManager("test-secret-key-at-least-32-characters"), newFakeNotifier(), &fakeSessions{}).
+				WithSSOEnforcement(tt.enforcer)
+
+			rec := httptest.NewRecorder()
+			handler.Login(rec, newJSONRequest(t, http.MethodPost, "/auth/login", LoginRequest{Email: tt.email, Password: testPassword}))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("Login() status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+
+	// A wrong password is still just a wrong password
+	handler := NewAuthHandler(f.users, memstore.NewEmailTokenStore(), auth.NewJWTManager("test-secret-key-at-least-32-characters"), newFakeNotifier(), &fakeSessions{}).
+		WithSSOEnforcement(f.sso)
+	rec := httptest.NewRecorder()
+	handler.Login(rec, newJSONRequest(t, http.MethodPost, "/auth/login", LoginRequest{Email: "member@example.com", Password: "wrong"}))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Login() with wrong password status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,526 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/capture"
+	"github.com/sfumato00/content-analyzer/internal/flags"
+	"github.com/sfumato00/content-analyzer/internal/logins"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/sso"
+)
+
+// The interfaces below describe what handlers need from their dependencies.
+// Production code passes the Postgres-backed stores from the models package;
+// tests pass the in-memory fakes from models/memstore.
+
+// UserStorer persists user accounts
+type UserStorer interface {
+	Create(ctx context.Context, email, password string) (*models.User, error)
+	CreateWithInvite(ctx context.Context, email, password, code string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+	MarkEmailVerified(ctx context.Context, id uuid.UUID) error
+	UpdatePassword(ctx context.Context, id uuid.UUID, password string) error
+	RehashPassword(ctx context.Context, id uuid.UUID, currentHash, password string) error
+	UpdateEmail(ctx context.Context, id uuid.UUID, email string) error
+	UpdateProfile(ctx context.Context, id uuid.UUID, version int, displayName, timeZone *string) (*models.User, error)
+	SetConfirmNewLogins(ctx context.Context, id uuid.UUID, confirm bool) error
+	SetStatus(ctx context.Context, id uuid.UUID, status models.AccountStatus, reason string) (*models.User, error)
+}
+
+// EmailTokenStorer issues and redeems one-time email tokens
+type EmailTokenStorer interface {
+	Create(ctx context.Context, userID uuid.UUID, purpose models.EmailTokenPurpose, email string, ttl time.Duration) (string, error)
+	Consume(ctx context.Context, purpose models.EmailTokenPurpose, token string) (*models.EmailToken, error)
+}
+
+// SubmissionStorer persists submissions and reads their analyses
+type SubmissionStorer interface {
+	Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error)
+	List(ctx context.Context, userID uuid.UUID, filter models.SubmissionFilter, limit, offset int) ([]models.Submission, int, error)
+	FindDuplicate(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, content string) (*models.Submission, error)
+	CopyDuplicate(ctx context.Context, userID uuid.UUID, original *models.Submission, content string, redacted *string) (*models.Submission, error)
+	LinkDuplicate(ctx context.Context, userID uuid.UUID, submission *models.Submission) error
+	UpdateContent(ctx context.Context, userID, id uuid.UUID, version int, content string, redacted *string) (*models.Submission, error)
+	CreateRevision(ctx context.Context, userID, previousID uuid.UUID, content string, redacted *string) (*models.Submission, error)
+	Revisions(ctx context.Context, userID, id uuid.UUID) ([]models.Submission, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error
+	GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error)
+	GetAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*models.Analysis, error)
+	GetLabels(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID][]models.Classification, error)
+}
+
+// TrashStorer moves users' submissions to and from their trash
+type TrashStorer interface {
+	Trash(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error)
+	Restore(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error)
+	ListTrash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Submission, int, error)
+	EmptyTrash(ctx context.Context, userID uuid.UUID) (int, error)
+}
+
+// AssignmentStorer assigns submissions for review and lists assignees'
+// queues
+type AssignmentStorer interface {
+	Assign(ctx context.Context, ownerID, id, assigneeID uuid.UUID, dueAt *time.Time) (*models.Submission, error)
+	Unassign(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error)
+	SetWorkflowStatus(ctx context.Context, userID, id uuid.UUID, status models.WorkflowStatus) (*models.Submission, error)
+	Queue(ctx context.Context, assigneeID uuid.UUID, status models.WorkflowStatus, limit, offset int) ([]models.Submission, int, error)
+}
+
+// TeammateChecker reports whether two users share an organization, or a
+// user's role in a given one
+type TeammateChecker interface {
+	ShareOrganization(ctx context.Context, userID, otherID uuid.UUID) (bool, error)
+	Role(ctx context.Context, orgID, userID uuid.UUID) (models.OrgRole, error)
+}
+
+// AssignmentNotifier emails users assigned a submission
+type AssignmentNotifier interface {
+	SendAssignment(ctx context.Context, to, assignedBy string, dueAt *time.Time) error
+}
+
+// AccountNotifier sends account-related emails
+type AccountNotifier interface {
+	SendVerification(ctx context.Context, to, token string, expiresIn time.Duration) error
+	SendPasswordReset(ctx context.Context, to, token string, expiresIn time.Duration) error
+	SendEmailChange(ctx context.Context, to, token string, expiresIn time.Duration) error
+}
+
+// AuditRecorder appends entries to the audit log
+type AuditRecorder interface {
+	Record(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// SessionRevoker invalidates a user's outstanding tokens
+type SessionRevoker interface {
+	RevokeAll(ctx context.Context, userID uuid.UUID) error
+}
+
+// DeviceSessionStorer manages remember-me sessions
+type DeviceSessionStorer interface {
+	Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string, lifetime models.DeviceLifetime) (string, *models.DeviceSession, error)
+	Resume(ctx context.Context, token, ipAddress string, idle time.Duration) (*models.DeviceSession, error)
+	List(ctx context.Context, userID uuid.UUID) ([]models.DeviceSession, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+	RevokeByToken(ctx context.Context, token string) (*models.DeviceSession, error)
+}
+
+// DeviceRevoker invalidates the access tokens of remember-me sessions
+type DeviceRevoker interface {
+	RevokeDevice(ctx context.Context, sessionID uuid.UUID) error
+	RevokedSince(ctx context.Context, userID uuid.UUID, issuedAt time.Time) (bool, error)
+}
+
+// LoginFingerprintStorer remembers where users have signed in from
+type LoginFingerprintStorer interface {
+	IsFamiliar(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) (bool, error)
+	Record(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) error
+}
+
+// LoginChallenger holds sign-ins until they are confirmed with a code
+type LoginChallenger interface {
+	Start(ctx context.Context, pending logins.Pending) (loginToken, code string, err error)
+	Confirm(ctx context.Context, loginToken, code string) (*logins.Pending, error)
+}
+
+// LoginNotifier emails users about sign-ins from a new country or device
+type LoginNotifier interface {
+	SendNewLogin(ctx context.Context, to string, fp models.LoginFingerprint, ipAddress string, at time.Time, code string, expiresIn time.Duration) error
+}
+
+// AppealStorer persists users' appeals against suspensions
+type AppealStorer interface {
+	Create(ctx context.Context, userID uuid.UUID, message string) (*models.Appeal, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Appeal, error)
+	List(ctx context.Context, decision models.AppealDecision, limit, offset int) ([]models.Appeal, int, error)
+	Decide(ctx context.Context, id uuid.UUID, decision models.AppealDecision, response, reviewer string) (*models.Appeal, error)
+}
+
+// StandingSetter enforces account statuses on every request
+type StandingSetter interface {
+	SetStanding(ctx context.Context, userID uuid.UUID, status models.AccountStatus, reason string) error
+}
+
+// SSOConfigStorer persists organizations' single sign-on setups
+type SSOConfigStorer interface {
+	Get(ctx context.Context, orgID uuid.UUID) (*models.SSOConfig, error)
+	Set(ctx context.Context, config *models.SSOConfig) (*models.SSOConfig, error)
+	Delete(ctx context.Context, orgID uuid.UUID) error
+}
+
+// SSOIdentityStorer reads single sign-on setups and links identity
+// provider users to accounts
+type SSOIdentityStorer interface {
+	Get(ctx context.Context, orgID uuid.UUID) (*models.SSOConfig, error)
+	FindIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, orgID uuid.UUID, subject string, userID uuid.UUID) error
+}
+
+// SSOEnforcer reports whether a user has to sign in through their
+// organization's identity provider
+type SSOEnforcer interface {
+	Enforced(ctx context.Context, userID uuid.UUID) (uuid.UUID, bool, error)
+}
+
+// SSOProvider signs users in at an identity provider
+type SSOProvider interface {
+	AuthCodeURL(ctx context.Context, config *models.SSOConfig, redirectURI, state, nonce string) (string, error)
+	Exchange(ctx context.Context, config *models.SSOConfig, redirectURI, code, nonce string) (*sso.Identity, error)
+}
+
+// SSOStateStorer remembers sign-ins sent to an identity provider
+type SSOStateStorer interface {
+	Begin(ctx context.Context, orgID uuid.UUID) (state, nonce, binding string, err error)
+	Finish(ctx context.Context, state, binding string) (orgID uuid.UUID, nonce string, err error)
+}
+
+// SSOConfigGetter reads organizations' single sign-on setups
+type SSOConfigGetter interface {
+	Get(ctx context.Context, orgID uuid.UUID) (*models.SSOConfig, error)
+}
+
+// SCIMStorer persists the users and groups identity providers provision
+type SCIMStorer interface {
+	ListUsers(ctx context.Context, orgID uuid.UUID, filter models.SCIMFilter, offset, limit int) ([]models.SCIMUser, int, error)
+	GetUser(ctx context.Context, orgID, userID uuid.UUID) (*models.SCIMUser, error)
+	SetUser(ctx context.Context, orgID, userID uuid.UUID, externalID string, active bool) (*models.SCIMUser, error)
+	DeleteUser(ctx context.Context, orgID, userID uuid.UUID) error
+	ListGroups(ctx context.Context, orgID uuid.UUID, filter models.SCIMFilter, offset, limit int) ([]models.SCIMGroup, int, error)
+	GetGroup(ctx context.Context, orgID, id uuid.UUID) (*models.SCIMGroup, error)
+	SaveGroup(ctx context.Context, group *models.SCIMGroup) (*models.SCIMGroup, error)
+	DeleteGroup(ctx context.Context, orgID, id uuid.UUID) error
+	Invite(ctx context.Context, orgID uuid.UUID, email string, displayName *string, externalID string, active bool) (*models.SCIMUser, error)
+	RenameInvitation(ctx context.Context, orgID, userID uuid.UUID, displayName *string) error
+	AcceptInvitations(ctx context.Context, userID uuid.UUID) ([]models.SCIMUser, error)
+}
+
+// InvitationAccepter makes a user who has proven their email address a
+// member of the organizations that invited them
+type InvitationAccepter interface {
+	AcceptInvitations(ctx context.Context, userID uuid.UUID)
+}
+
+// OrgDomainStorer persists the email domains organizations claim
+type OrgDomainStorer interface {
+	List(ctx context.Context, orgID uuid.UUID) ([]models.OrgDomain, error)
+	Get(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error)
+	Add(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error)
+	MarkVerified(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error)
+	Remove(ctx context.Context, orgID uuid.UUID, domain string) error
+}
+
+// DomainVerifier reports whether an organization has verified an email
+// domain
+type DomainVerifier interface {
+	IsVerified(ctx context.Context, orgID uuid.UUID, domain string) (bool, error)
+}
+
+// SCIMTokenStorer issues organizations' SCIM tokens
+type SCIMTokenStorer interface {
+	CreateToken(ctx context.Context, orgID uuid.UUID) (string, error)
+	RevokeToken(ctx context.Context, orgID uuid.UUID) error
+}
+
+// JobEnqueuer schedules background jobs
+type JobEnqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// JobCanceler asks running jobs to stop
+type JobCanceler interface {
+	RequestCancel(ctx context.Context, key string) error
+}
+
+// PriorityEnqueuer schedules background jobs in a priority lane
+type PriorityEnqueuer interface {
+	EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// SubmissionJobs schedules and cancels submission analyses
+type SubmissionJobs interface {
+	PriorityEnqueuer
+	JobCanceler
+}
+
+// JobQueueAdmin inspects and manages the job queue
+type JobQueueAdmin interface {
+	Stats(ctx context.Context) (*queue.Stats, error)
+	InFlight(ctx context.Context) ([]queue.Job, error)
+	Pause(ctx context.Context) error
+	Resume(ctx context.Context) error
+	Cancel(ctx context.Context, id string) (*queue.Job, error)
+	RequeueStale(ctx context.Context, olderThan time.Duration) (int, error)
+}
+
+// ProviderBudget reports how much of the model provider's rate limit is
+// left
+type ProviderBudget interface {
+	State() ai.BudgetState
+}
+
+// CaptureReader reads and clears the requests captured for debugging
+type CaptureReader interface {
+	List(filter capture.Filter) []capture.Exchange
+	Clear()
+}
+
+// KeyTrafficReporter reports the cache keys with the most traffic
+type KeyTrafficReporter interface {
+	TopKeys(limit int) []cache.KeyCount
+}
+
+// FeatureFlagStorer persists feature flags
+type FeatureFlagStorer interface {
+	List(ctx context.Context) ([]models.FeatureFlag, error)
+	Get(ctx context.Context, key string) (*models.FeatureFlag, error)
+	Upsert(ctx context.Context, flag *models.FeatureFlag) (*models.FeatureFlag, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// InviteStorer persists invitation codes
+type InviteStorer interface {
+	Create(ctx context.Context, invite *models.InviteCode) (string, *models.InviteCode, error)
+	List(ctx context.Context) ([]models.InviteCode, error)
+	Revoke(ctx context.Context, id uuid.UUID) (*models.InviteCode, error)
+}
+
+// OrganizationStorer persists organizations and their members
+type OrganizationStorer interface {
+	Create(ctx context.Context, name string, ownerID uuid.UUID) (*models.Organization, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+	Role(ctx context.Context, orgID, userID uuid.UUID) (models.OrgRole, error)
+	Members(ctx context.Context, orgID uuid.UUID) ([]models.OrgMember, error)
+	SetMember(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	SetRetention(ctx context.Context, id uuid.UUID, policy models.RetentionPolicy) (*models.Organization, error)
+	SetSubdomain(ctx context.Context, id uuid.UUID, subdomain *string) (*models.Organization, error)
+}
+
+// ProfileStorer persists analysis profiles
+type ProfileStorer interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.AnalysisProfile, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.AnalysisProfile, error)
+	Create(ctx context.Context, profile *models.AnalysisProfile) (*models.AnalysisProfile, error)
+	Update(ctx context.Context, profile *models.AnalysisProfile) (*models.AnalysisProfile, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// ProfileGetter looks up the analysis profiles a user can use
+type ProfileGetter interface {
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.AnalysisProfile, error)
+}
+
+// ThreadStorer persists conversation threads
+type ThreadStorer interface {
+	ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]models.Thread, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Thread, error)
+	Create(ctx context.Context, userID, submissionID uuid.UUID, message string) (*models.Thread, error)
+	AddMessage(ctx context.Context, userID, threadID uuid.UUID, message string) (*models.Thread, error)
+	MarkFailed(ctx context.Context, threadID uuid.UUID) error
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// TranscriptionStorer persists audio and video uploads and their transcripts
+type TranscriptionStorer interface {
+	Create(ctx context.Context, t *models.Transcription, media []byte) (*models.Transcription, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Transcription, error)
+	GetForSubmission(ctx context.Context, userID, submissionID uuid.UUID) (*models.Transcription, error)
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+// ImportStorer persists bulk imports and reads their progress
+type ImportStorer interface {
+	Create(ctx context.Context, i *models.Import, data []byte) (*models.Import, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Import, error)
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+// FeedStorer persists monitored feeds and reads their digests
+type FeedStorer interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.Feed, error)
+	GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Feed, error)
+	Create(ctx context.Context, userID uuid.UUID, url string) (*models.Feed, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	DigestItems(ctx context.Context, userID, feedID uuid.UUID, limit int) ([]models.FeedDigestItem, error)
+}
+
+// APIKeyStorer manages a user's API keys
+type APIKeyStorer interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error)
+	Create(ctx context.Context, userID uuid.UUID, name string, scopes []string) (string, *models.APIKey, error)
+	Revoke(ctx context.Context, userID, id uuid.UUID) error
+}
+
+// HookStorer persists REST hooks and reads the analyses they poll
+type HookStorer interface {
+	List(ctx context.Context, userID uuid.UUID) ([]models.RESTHook, error)
+	Create(ctx context.Context, hook models.RESTHook) (*models.RESTHook, error)
+	Delete(ctx context.Context, userID, id uuid.UUID) error
+	RecentAnalyses(ctx context.Context, userID uuid.UUID, limit int) ([]models.HookAnalysis, error)
+}
+
+// UploadStorer persists direct uploads and attaches them to submissions
+type UploadStorer interface {
+	Create(ctx context.Context, upload *models.Upload, token string, expiresAt time.Time) (*models.Upload, error)
+	GetPending(ctx context.Context, userID uuid.UUID, token string) (*models.Upload, error)
+	Attach(ctx context.Context, userID, id, submissionID uuid.UUID) (*models.Upload, error)
+	ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]models.Upload, error)
+}
+
+// QuickAnalyzer analyzes content synchronously without storing it
+type QuickAnalyzer interface {
+	Quick(ctx context.Context, content string) (*models.Analysis, error)
+}
+
+// AnalysisEstimator works out what analyzing a submission would cost
+// without running the analysis
+type AnalysisEstimator interface {
+	Estimate(ctx context.Context, submission *models.Submission, profile *models.AnalysisProfile) (*analyzer.Estimate, error)
+}
+
+// ModerationPolicyStorer persists organizations' moderation policies
+type ModerationPolicyStorer interface {
+	Policy(ctx context.Context, orgID uuid.UUID) (*models.ModerationPolicy, error)
+	SetPolicy(ctx context.Context, orgID uuid.UUID, thresholds []models.ModerationThreshold) (*models.ModerationPolicy, error)
+}
+
+// GlossaryStorer persists organizations' glossaries
+type GlossaryStorer interface {
+	Glossary(ctx context.Context, orgID uuid.UUID) (*models.Glossary, error)
+	SetGlossary(ctx context.Context, orgID uuid.UUID, entries []models.GlossaryEntry) (*models.Glossary, error)
+}
+
+// TaxonomyStorer persists organizations' taxonomies
+type TaxonomyStorer interface {
+	Taxonomy(ctx context.Context, orgID uuid.UUID) (*models.Taxonomy, error)
+	SetTaxonomy(ctx context.Context, orgID uuid.UUID, labels []models.TaxonomyLabel) (*models.Taxonomy, error)
+}
+
+// PolicyOverrider overrides submissions' moderation policy decisions
+type PolicyOverrider interface {
+	SetOverride(ctx context.Context, submissionID uuid.UUID, override *models.PolicyOverride) (*models.PolicyDecision, error)
+}
+
+// SpendBudgetSetter sets organizations' spend budgets
+type SpendBudgetSetter interface {
+	SetSpendBudget(ctx context.Context, id uuid.UUID, budget models.SpendBudget) (*models.Organization, error)
+}
+
+// QuarantineStorer quarantines submissions and releases or destroys them
+// once an operator has inspected them
+type QuarantineStorer interface {
+	Quarantine(ctx context.Context, id uuid.UUID, reason string) (*models.Submission, error)
+	GetQuarantined(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	ListQuarantined(ctx context.Context, limit, offset int) ([]models.Submission, int, error)
+	Release(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	Destroy(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error)
+}
+
+// ArtifactStorer reads what analyses sent to the model and got back
+type ArtifactStorer interface {
+	GetArtifacts(ctx context.Context, analysisID uuid.UUID) (*models.AnalysisArtifacts, error)
+}
+
+// QuarantineNotifier emails owners about their quarantined submissions
+type QuarantineNotifier interface {
+	SendQuarantine(ctx context.Context, to, outcome string, submissionID uuid.UUID, createdAt time.Time, reason string) error
+}
+
+// LegalHoldSetter places submissions on legal hold
+type LegalHoldSetter interface {
+	SetLegalHold(ctx context.Context, submissionID uuid.UUID, hold bool) error
+}
+
+// UsageReporter reads the daily usage rollups
+type UsageReporter interface {
+	OrgUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]models.MemberUsage, error)
+}
+
+// SentimentTrendReporter aggregates the sentiment of organizations'
+// analyses
+type SentimentTrendReporter interface {
+	OrgSentimentTrend(ctx context.Context, orgID uuid.UUID, from, to time.Time, bucket models.TimeBucket, loc *time.Location) ([]models.SentimentPoint, error)
+}
+
+// FlagEvaluator evaluates feature flags and drops its cache after a change
+type FlagEvaluator interface {
+	EnabledKeys(ctx context.Context, userID uuid.UUID) []string
+	Invalidate()
+}
+
+// StatsInvalidator drops a user's cached stats after their submissions
+// change
+type StatsInvalidator interface {
+	InvalidateStats(ctx context.Context, userID uuid.UUID)
+}
+
+var (
+	_ UserStorer             = (*models.UserStore)(nil)
+	_ EmailTokenStorer       = (*models.EmailTokenStore)(nil)
+	_ SubmissionStorer       = (*models.SubmissionStore)(nil)
+	_ AuditRecorder          = (*models.AuditStore)(nil)
+	_ SessionRevoker         = (*auth.Sessions)(nil)
+	_ DeviceSessionStorer    = (*models.DeviceSessionStore)(nil)
+	_ DeviceRevoker          = (*auth.Sessions)(nil)
+	_ LoginFingerprintStorer = (*models.LoginFingerprintStore)(nil)
+	_ LoginChallenger        = (*logins.Challenges)(nil)
+	_ LoginNotifier          = (*notifications.Notifier)(nil)
+	_ AppealStorer           = (*models.AppealStore)(nil)
+	_ StandingSetter         = (*auth.Sessions)(nil)
+	_ SSOConfigStorer        = (*models.SSOStore)(nil)
+	_ SSOIdentityStorer      = (*models.SSOStore)(nil)
+	_ SSOEnforcer            = (*models.SSOStore)(nil)
+	_ SSOProvider            = (*sso.OIDC)(nil)
+	_ SSOStateStorer         = (*sso.States)(nil)
+	_ SSOConfigGetter        = (*models.SSOStore)(nil)
+	_ SCIMStorer             = (*models.SCIMStore)(nil)
+	_ SCIMTokenStorer        = (*models.SCIMStore)(nil)
+	_ OrgDomainStorer        = (*models.OrgDomainStore)(nil)
+	_ DomainVerifier         = (*models.OrgDomainStore)(nil)
+	_ JobEnqueuer            = (*queue.Queue)(nil)
+	_ KeyTrafficReporter     = (*cache.Cache)(nil)
+	_ CaptureReader          = (*capture.Recorder)(nil)
+	_ SubmissionJobs         = (*queue.Queue)(nil)
+	_ JobQueueAdmin          = (*queue.Queue)(nil)
+	_ ProviderBudget         = (*ai.Budget)(nil)
+	_ FeatureFlagStorer      = (*models.FeatureFlagStore)(nil)
+	_ InviteStorer           = (*models.InviteStore)(nil)
+	_ OrganizationStorer     = (*models.OrganizationStore)(nil)
+	_ AssignmentStorer       = (*models.SubmissionStore)(nil)
+	_ TeammateChecker        = (*models.OrganizationStore)(nil)
+	_ UsageReporter          = (*models.UsageStore)(nil)
+	_ SentimentTrendReporter = (*models.AnalyticsStore)(nil)
+	_ LegalHoldSetter        = (*models.RetentionStore)(nil)
+	_ ModerationPolicyStorer = (*models.ModerationStore)(nil)
+	_ PolicyOverrider        = (*models.ModerationStore)(nil)
+	_ GlossaryStorer         = (*models.GlossaryStore)(nil)
+	_ TaxonomyStorer         = (*models.TaxonomyStore)(nil)
+	_ SpendBudgetSetter      = (*models.OrganizationStore)(nil)
+	_ QuarantineStorer       = (*models.SubmissionStore)(nil)
+	_ ArtifactStorer         = (*models.SubmissionStore)(nil)
+	_ ProfileStorer          = (*models.ProfileStore)(nil)
+	_ ThreadStorer           = (*models.ThreadStore)(nil)
+	_ TranscriptionStorer    = (*models.TranscriptionStore)(nil)
+	_ ImportStorer           = (*models.ImportStore)(nil)
+	_ FeedStorer             = (*models.FeedStore)(nil)
+	_ APIKeyStorer           = (*models.APIKeyStore)(nil)
+	_ HookStorer             = (*models.HookStore)(nil)
+	_ UploadStorer           = (*models.UploadStore)(nil)
+	_ QuickAnalyzer          = (*analyzer.Analyzer)(nil)
+	_ AnalysisEstimator      = (*analyzer.Analyzer)(nil)
+	_ FlagEvaluator          = (*flags.Flags)(nil)
+	_ StatsInvalidator       = (*StatsCache)(nil)
+)
@@ -0,0 +1,976 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/links"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+)
+
+const (
+	// MaxContentLength is the largest submission accepted, in characters
+	MaxContentLength = 50000
+
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// SubmissionHandler handles submission requests
+type SubmissionHandler struct {
+	store SubmissionStorer
+	users UserStorer
+	jobs  SubmissionJobs
+	quota *quota.Tracker
+
+	profiles ProfileGetter
+
+	// Audio and video uploads, enabled with WithTranscription
+	transcriptions TranscriptionStorer
+	maxMediaBytes  int64
+
+	// Bulk imports, enabled with WithImports
+	imports        ImportStorer
+	maxImportBytes int64
+
+	// wordLimits caps the words each plan can queue for analysis at once
+	wordLimits map[models.Plan]int
+
+	// links adds links to related resources, enabled with WithLinks
+	links *links.Builder
+
+	// stats drops the owner's cached stats, enabled with WithStats
+	stats StatsInvalidator
+}
+
+// NewSubmissionHandler creates a new submission handler
+func NewSubmissionHandler(store SubmissionStorer, users UserStorer, jobs SubmissionJobs) *SubmissionHandler {
+	return &SubmissionHandler{
+		store: store,
+		users: users,
+		jobs:  jobs,
+	}
+}
+
+// WithQuota counts queued analyses against monthly quotas, reporting
+// usage in X-Quota-* headers
+func (h *SubmissionHandler) WithQuota(tracker *quota.Tracker) *SubmissionHandler {
+	h.quota = tracker
+	return h
+}
+
+// WithProfiles lets submissions select an analysis profile with
+// profile_id and returns the handler
+func (h *SubmissionHandler) WithProfiles(profiles ProfileGetter) *SubmissionHandler {
+	h.profiles = profiles
+	return h
+}
+
+// WithWordLimits caps the words each plan can queue for analysis at once
+// and returns the handler. Plans without a limit are only held to
+// MaxContentLength.
+func (h *SubmissionHandler) WithWordLimits(limits map[models.Plan]int) *SubmissionHandler {
+	h.wordLimits = limits
+	return h
+}
+
+// WithLinks adds links to related resources and actions to the
+// submissions the handler returns, and returns the handler
+func (h *SubmissionHandler) WithLinks(builder *links.Builder) *SubmissionHandler {
+	h.links = builder
+	return h
+}
+
+// WithStats drops the owner's cached stats when a submission is created
+// and returns the handler
+func (h *SubmissionHandler) WithStats(stats StatsInvalidator) *SubmissionHandler {
+	h.stats = stats
+	return h
+}
+
+// invalidateStats drops the user's cached stats, if enabled
+func (h *SubmissionHandler) invalidateStats(r *http.Request, userID uuid.UUID) {
+	if h.stats != nil {
+		h.stats.InvalidateStats(r.Context(), userID)
+	}
+}
+
+// CreateSubmissionRequest represents the submission request
+type CreateSubmissionRequest struct {
+	Content string `json:"content"`
+	// Redact stores a copy with personal data and profanity masked for display
+	Redact bool `json:"redact"`
+	// Draft holds the submission back from analysis until it is submitted
+	Draft bool `json:"draft"`
+	// Instructions steer the analysis, such as "focus on legal risk"
+	Instructions string `json:"instructions"`
+	// ProfileID selects a built-in or the user's own analysis profile
+	ProfileID *uuid.UUID `json:"profile_id"`
+	// AllowDuplicate stores the content even if it was already submitted
+	AllowDuplicate bool `json:"allow_duplicate"`
+}
+
+// DuplicateResponse is returned instead of a new submission when the same
+// content was already submitted
+type DuplicateResponse struct {
+	DuplicateOf uuid.UUID       `json:"duplicate_of"`
+	Submission  *dto.Submission `json:"submission"`
+}
+
+// UpdateSubmissionRequest represents the draft update request
+type UpdateSubmissionRequest struct {
+	Content string `json:"content"`
+	Redact  bool   `json:"redact"`
+	// Version is the version being updated, for clients that can't send If-Match
+	Version *int `json:"version"`
+}
+
+// SubmissionListResponse is the v1 shape of a page of submissions
+type SubmissionListResponse struct {
+	Submissions []dto.Submission `json:"submissions"`
+	Total       int              `json:"total"`
+	Limit       int              `json:"limit"`
+	Offset      int              `json:"offset"`
+}
+
+// submissionList renders a page of submissions, in the v1 shape for v1
+type submissionList response.ListResponse[dto.Submission]
+
+// ForVersion implements apiversion.Serializer
+func (l submissionList) ForVersion(v apiversion.Version) interface{} {
+	if v < apiversion.V2 {
+		return SubmissionListResponse{
+			Submissions: l.Data,
+			Total:       l.Pagination.Total,
+			Limit:       l.Pagination.Limit,
+			Offset:      l.Pagination.Offset,
+		}
+	}
+	return response.ListResponse[dto.Submission](l)
+}
+
+// Create stores content and queues it for analysis, unless it is a draft
+// POST /api/v1/submissions
+func (h *SubmissionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req CreateSubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	content, fields := validateContent(req.Content)
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	focus, fields := validateInstructions(req.Instructions)
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	if !h.checkProfile(w, r, userID, req.ProfileID) {
+		return
+	}
+	redacted := redactedCopy(content, req.Redact)
+	if !req.AllowDuplicate && h.returnDuplicate(w, r, userID, content, redacted, req.Draft) {
+		return
+	}
+
+	// Drafts are held to the plan's limit when they are submitted
+	var user *models.User
+	status := models.StatusQueued
+	if req.Draft {
+		status = models.StatusDraft
+	} else {
+		user = h.currentUser(r)
+		if !h.checkSize(w, user, content) {
+			return
+		}
+	}
+
+	submission, err := h.store.Create(r.Context(), userID, content, redacted, focus, req.ProfileID, status)
+	if err != nil {
+		slog.Error("Failed to create submission", "error", err)
+		response.InternalServerError(w, "Failed to create submission")
+		return
+	}
+	h.invalidateStats(r, userID)
+
+	if status == models.StatusQueued && !h.enqueue(w, r, user, submission.ID) {
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Created(w, h.present(r, submission))
+}
+
+// returnDuplicate looks for content the user or a teammate already
+// submitted. On an organization's subdomain only its members count as
+// teammates. The user's own earlier submission is written as it is; a
+// teammate's stays theirs, so unless a draft was asked for the user gets
+// their own completed copy that reuses its analysis. It returns true when
+// it wrote either. A failed lookup doesn't hold up the submission.
+func (h *SubmissionHandler) returnDuplicate(w http.ResponseWriter, r *http.Request, userID uuid.UUID, content string, redacted *string, draft bool) bool {
+	var orgID *uuid.UUID
+	if hostOrgID, ok := auth.GetHostOrgFromContext(r.Context()); ok {
+		orgID = &hostOrgID
+	}
+	existing, err := h.store.FindDuplicate(r.Context(), userID, orgID, content)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to look for duplicate submissions", "error", err)
+		}
+		return false
+	}
+
+	if existing.UserID == userID {
+		setETag(w, existing.Version)
+		response.Success(w, DuplicateResponse{DuplicateOf: existing.ID, Submission: h.present(r, existing)})
+		return true
+	}
+	if draft {
+		return false
+	}
+
+	submission, err := h.store.CopyDuplicate(r.Context(), userID, existing, content, redacted)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error("Failed to copy duplicate submission", "duplicate_of", existing.ID, "error", err)
+		}
+		return false
+	}
+	h.invalidateStats(r, userID)
+
+	setETag(w, submission.Version)
+	response.Created(w, h.present(r, submission))
+	return true
+}
+
+// Update replaces the content of a draft. The client must send the version
+// it read, in If-Match or the body; a stale version gets 409.
+// PATCH /api/v1/submissions/{id}
+func (h *SubmissionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	var req UpdateSubmissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	version, err := expectedVersion(r, req.Version)
+	if err != nil {
+		writePreconditionError(w, err)
+		return
+	}
+
+	content, fields := validateContent(req.Content)
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	submission, err := h.store.UpdateContent(r.Context(), userID, id, version, content, redactedCopy(content, req.Redact))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Submission not found")
+		case errors.Is(err, models.ErrVersionConflict):
+			response.Conflict(w, "Submission was changed by another request; reload it and try again")
+		case errors.Is(err, models.ErrNotDraft):
+			response.Conflict(w, "Only drafts can be edited")
+		default:
+			slog.Error("Failed to update submission", "submission_id", id, "error", err)
+			response.InternalServerError(w, "Failed to update submission")
+		}
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, h.present(r, submission))
+}
+
+// validateContent trims submitted content and returns validation errors
+// by field, or nil if it is acceptable
+func validateContent(raw string) (string, map[string]string) {
+	content := strings.TrimSpace(raw)
+	if content == "" {
+		return "", map[string]string{"content": "Content is required"}
+	}
+	if utf8.RuneCountInString(content) > MaxContentLength {
+		return "", map[string]string{
+			"content": fmt.Sprintf("Content must be at most %d characters", MaxContentLength),
+		}
+	}
+	return content, nil
+}
+
+// checkSize holds content queued for analysis to the word limit of the
+// user's plan. It writes a 422 and returns false when content is over it.
+// Without a user, as when the lookup failed, content isn't limited.
+func (h *SubmissionHandler) checkSize(w http.ResponseWriter, user *models.User, content string) bool {
+	if user == nil {
+		return true
+	}
+	limit, ok := h.wordLimits[user.Plan]
+	if !ok {
+		return true
+	}
+	if words := textstats.CountWords(content); words > limit {
+		response.ValidationError(w, map[string]string{
+			"content": fmt.Sprintf("Content is %d words; the %s plan analyzes up to %d words at once", words, user.Plan, limit),
+		})
+		return false
+	}
+	return true
+}
+
+// profile loads an analysis profile the user can use. Without a profile
+// store every profile is unknown.
+func (h *SubmissionHandler) profile(r *http.Request, userID, id uuid.UUID) (*models.AnalysisProfile, error) {
+	if h.profiles == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return h.profiles.GetByID(r.Context(), userID, id)
+}
+
+// checkProfile verifies that an optional profile_id names a profile the
+// user can use. It writes the error response and returns false otherwise.
+func (h *SubmissionHandler) checkProfile(w http.ResponseWriter, r *http.Request, userID uuid.UUID, profileID *uuid.UUID) bool {
+	if profileID == nil {
+		return true
+	}
+	if _, err := h.profile(r, userID, *profileID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.ValidationError(w, map[string]string{"profile_id": "Unknown analysis profile"})
+			return false
+		}
+		slog.Error("Failed to load analysis profile", "profile_id", *profileID, "error", err)
+		response.InternalServerError(w, "Failed to create submission")
+		return false
+	}
+	return true
+}
+
+// validateInstructions sanitizes optional analysis instructions. It
+// returns nil when none were given.
+func validateInstructions(raw string) (*string, map[string]string) {
+	cleaned, err := instructions.Sanitize(raw)
+	switch {
+	case errors.Is(err, instructions.ErrTooLong):
+		return nil, map[string]string{
+			"instructions": fmt.Sprintf("Instructions must be at most %d characters", instructions.MaxLength),
+		}
+	case err != nil:
+		return nil, map[string]string{"instructions": "Instructions may only describe what the analysis should focus on"}
+	case cleaned == "":
+		return nil, nil
+	}
+	return &cleaned, nil
+}
+
+// redactedCopy masks regex matches straight away so the copy is safe to
+// show before the analyzer has verified them. It returns nil unless
+// redaction was requested.
+func redactedCopy(content string, redact bool) *string {
+	if !redact {
+		return nil
+	}
+	masked := sensitive.Redact(content, sensitive.Detect(content))
+	return &masked
+}
+
+// Submit queues a draft for analysis
+// POST /api/v1/submissions/{id}/submit
+func (h *SubmissionHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	user := h.currentUser(r)
+	submission, ok := h.transition(w, r, models.StatusQueued, func(current *models.Submission) bool {
+		// Anything but a draft is refused by the transition itself
+		return current.Status != models.StatusDraft || h.checkSize(w, user, current.Content)
+	})
+	if !ok {
+		return
+	}
+
+	if !h.enqueue(w, r, user, submission.ID) {
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, h.present(r, submission))
+}
+
+// Cancel stops the analysis of a queued or processing submission. A
+// worker already running it stops on its next check, and any result it
+// still produces is discarded. An analysis canceled before a worker
+// picked it up is taken off the user's quota.
+// POST /api/v1/submissions/{id}/cancel
+func (h *SubmissionHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	submission, ok := h.transition(w, r, models.StatusCanceled, nil)
+	if !ok {
+		return
+	}
+
+	// The status change alone keeps the result from being saved, so a
+	// missed signal only costs the rest of the model call
+	if err := h.jobs.RequestCancel(r.Context(), analyzer.CancelKey(submission.ID)); err != nil {
+		slog.Error("Failed to signal analysis cancellation", "submission_id", submission.ID, "error", err)
+	}
+	if h.quota != nil && submission.ProcessingAt == nil {
+		if err := h.quota.Refund(r.Context(), submission.ID); err != nil {
+			slog.Warn("Failed to refund canceled analysis", "submission_id", submission.ID, "error", err)
+		}
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, h.present(r, submission))
+}
+
+// Archive moves a finished submission out of the active set
+// POST /api/v1/submissions/{id}/archive
+func (h *SubmissionHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	submission, ok := h.transition(w, r, models.StatusArchived, nil)
+	if !ok {
+		return
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, h.present(r, submission))
+}
+
+// ListEvents returns the status changes of a submission, oldest first
+// GET /api/v1/submissions/{id}/events
+func (h *SubmissionHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to list events")
+		return
+	}
+
+	response.Success(w, response.Complete(submission.History()))
+}
+
+// transition moves the current user's submission to status and returns
+// it reloaded. check, if set, vets the submission first, writing the
+// response when it refuses. It writes the error response and returns
+// false on failure.
+func (h *SubmissionHandler) transition(w http.ResponseWriter, r *http.Request, status models.SubmissionStatus, check func(*models.Submission) bool) (*models.Submission, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return nil, false
+	}
+
+	// Check ownership before touching the status
+	current, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return nil, false
+		}
+
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to update submission")
+		return nil, false
+	}
+	if check != nil && !check(current) {
+		return nil, false
+	}
+
+	if err := h.store.UpdateStatus(r.Context(), id, status); err != nil {
+		var transitionErr *models.TransitionError
+		if errors.As(err, &transitionErr) {
+			response.Conflict(w, fmt.Sprintf("Submission is %s and cannot be %s", transitionErr.From, status))
+			return nil, false
+		}
+
+		slog.Error("Failed to update submission status", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to update submission")
+		return nil, false
+	}
+
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to update submission")
+		return nil, false
+	}
+
+	return submission, true
+}
+
+// enqueue schedules the analysis of a queued submission and counts it
+// against the user's quota. It writes the error response and returns false
+// on failure.
+func (h *SubmissionHandler) enqueue(w http.ResponseWriter, r *http.Request, user *models.User, id uuid.UUID) bool {
+	priority := h.priority(user)
+	if _, err := h.jobs.EnqueuePriority(r.Context(), priority, analyzer.JobType, analyzer.Payload{SubmissionID: id}); err != nil {
+		slog.Error("Failed to enqueue analysis", "submission_id", id, "error", err)
+
+		// Don't leave the submission queued forever
+		if err := h.store.UpdateStatus(r.Context(), id, models.StatusFailed); err != nil {
+			slog.Error("Failed to mark submission failed", "submission_id", id, "error", err)
+		}
+
+		enqueueFailed(w, err, "Failed to queue submission for analysis")
+		return false
+	}
+
+	h.recordQuota(w, r, user, id)
+	return true
+}
+
+// currentUser looks up the authenticated user for plan-dependent
+// behaviour. It returns nil when the lookup fails, which callers treat as
+// the free tier rather than failing the request.
+func (h *SubmissionHandler) currentUser(r *http.Request) *models.User {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		return nil
+	}
+
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		slog.Warn("Failed to look up plan, using defaults", "user_id", userID, "error", err)
+		return nil
+	}
+	return user
+}
+
+// priority returns the queue lane for the user's analyses: paid plans
+// skip ahead of the free tier
+func (h *SubmissionHandler) priority(user *models.User) queue.Priority {
+	if user != nil && user.Plan.Paid() {
+		return queue.PriorityHigh
+	}
+	return queue.PriorityDefault
+}
+
+// recordQuota counts an analysis against the user's monthly quota under
+// chargeID and reports the usage in response headers. Quotas are soft, so
+// a counter failure only drops the headers.
+func (h *SubmissionHandler) recordQuota(w http.ResponseWriter, r *http.Request, user *models.User, chargeID uuid.UUID) {
+	if h.quota == nil || user == nil {
+		return
+	}
+
+	usage, ok, err := h.quota.Record(r.Context(), user, chargeID)
+	if err != nil {
+		slog.Warn("Failed to count analysis against quota", "user_id", user.ID, "error", err)
+		return
+	}
+	if ok {
+		usage.SetHeaders(w.Header())
+	}
+}
+
+// List returns the current user's submissions, newest first, optionally
+// only those with a keyphrase containing keyword, classified with a
+// taxonomy label or, with duplicates=true, those sharing their content
+// with another. With include=analysis each comes with its latest analysis,
+// and fields= keeps only the named fields of each.
+// GET /api/v1/submissions?limit=&offset=&cursor=&keyword=&label=&duplicates=&include=&fields=
+func (h *SubmissionHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	include, selection, err := submissionOptions(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	duplicates, fields := formBool(r, "duplicates")
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	filter := models.SubmissionFilter{
+		Keyword:    strings.TrimSpace(r.URL.Query().Get("keyword")),
+		Label:      strings.TrimSpace(r.URL.Query().Get("label")),
+		Duplicates: duplicates,
+	}
+
+	submissions, total, err := h.store.List(r.Context(), userID, filter, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list submissions", "error", err)
+		response.InternalServerError(w, "Failed to list submissions")
+		return
+	}
+
+	page := h.presentAll(r, submissions)
+	if include.Has(dto.IncludeAnalysis) {
+		if err := h.withAnalyses(r, userID, page); err != nil {
+			slog.Error("Failed to get analyses", "error", err)
+			response.InternalServerError(w, "Failed to list submissions")
+			return
+		}
+	}
+
+	for i := range page {
+		page[i].WithFields(selection)
+	}
+
+	list := response.NewList(page, total, limit, offset).
+		WithFilter("keyword", filter.Keyword).
+		WithFilter("label", filter.Label)
+	if duplicates {
+		list = list.WithFilter("duplicates", "true")
+	}
+	response.Success(w, apiversion.Render(r, submissionList(list)))
+}
+
+// Get returns a single submission, with include=analysis along with its
+// latest analysis, and with fields= only the named fields. It responds
+// 304 when the client's copy is current, by If-None-Match or
+// If-Modified-Since.
+// GET /api/v1/submissions/{id}?include=&fields=
+func (h *SubmissionHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+	include, selection, err := submissionOptions(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to get submission")
+		return
+	}
+	if err := h.store.LinkDuplicate(r.Context(), userID, submission); err != nil {
+		slog.Error("Failed to find duplicates", "error", err)
+		response.InternalServerError(w, "Failed to get submission")
+		return
+	}
+
+	// Every write, including to the analysis, moves the version on. The
+	// body also depends on the API version, include and fields, and on
+	// which earlier copy it duplicates, which changes without a write.
+	var duplicateOf string
+	if submission.DuplicateOf != nil {
+		duplicateOf = submission.DuplicateOf.String()
+	}
+	etag := variantETag(submission.Version, apiversion.FromContext(r.Context()).String(), include.String(), selection.String(), duplicateOf)
+	if notModified(w, r, etag, submission.UpdatedAt.Time) {
+		return
+	}
+
+	body := []dto.Submission{*h.present(r, submission)}
+	if include.Has(dto.IncludeAnalysis) {
+		if err := h.withAnalyses(r, userID, body); err != nil {
+			slog.Error("Failed to get analysis", "error", err)
+			response.InternalServerError(w, "Failed to get submission")
+			return
+		}
+	}
+
+	response.Success(w, body[0].WithFields(selection))
+}
+
+// submissionOptions reads the related resources a client asked to embed
+// with ?include= and the fields to keep with ?fields=. Embedded resources
+// are kept whatever fields names.
+func submissionOptions(r *http.Request) (dto.Include, dto.Fields, error) {
+	include, err := dto.ParseInclude(r.URL.Query().Get("include"), dto.IncludeAnalysis)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := dto.ParseFields(r.URL.Query().Get("fields"), dto.Submission{})
+	if err != nil {
+		return nil, nil, err
+	}
+	if include.Has(dto.IncludeAnalysis) {
+		fields = fields.With("analysis")
+	}
+	return include, fields, nil
+}
+
+// present maps a submission of the current user, with its links
+func (h *SubmissionHandler) present(r *http.Request, submission *models.Submission) *dto.Submission {
+	return dto.NewSubmission(submission).WithLinks(h.links.Submission(r, submission.ID, submission.Status))
+}
+
+// presentAll maps submissions of the current user, with their links
+func (h *SubmissionHandler) presentAll(r *http.Request, submissions []models.Submission) []dto.Submission {
+	out := dto.NewSubmissions(submissions)
+	for i := range out {
+		out[i].WithLinks(h.links.Submission(r, out[i].ID, out[i].Status))
+	}
+	return out
+}
+
+// withAnalyses embeds the latest analysis of each submission, or null for
+// those without one yet
+func (h *SubmissionHandler) withAnalyses(r *http.Request, userID uuid.UUID, submissions []dto.Submission) error {
+	ids := make([]uuid.UUID, len(submissions))
+	for i := range submissions {
+		ids[i] = submissions[i].ID
+	}
+	analyses, err := h.store.GetAnalyses(r.Context(), userID, ids)
+	if err != nil {
+		return err
+	}
+
+	version := apiversion.FromContext(r.Context())
+	for i := range submissions {
+		submissions[i].WithAnalysis(analyses[submissions[i].ID], version)
+	}
+	return nil
+}
+
+// GetAnalysis returns the analysis of a submission, or 304 when the
+// client's copy is current. While the submission is a draft or the
+// analysis is still running it responds 202 with the submission status.
+// GET /api/v1/submissions/{id}/analysis
+func (h *SubmissionHandler) GetAnalysis(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	analysis, err := h.store.GetAnalysis(r.Context(), userID, id)
+	if err == nil {
+		version := apiversion.FromContext(r.Context())
+		if !notModified(w, r, analysisETag(analysis, version), analysis.UpdatedAt.Time) {
+			response.Success(w, dto.NewAnalysis(analysis, version))
+		}
+		return
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		slog.Error("Failed to get analysis", "error", err)
+		response.InternalServerError(w, "Failed to get analysis")
+		return
+	}
+
+	// No analysis yet: report why
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to get analysis")
+		return
+	}
+
+	switch submission.Status {
+	case models.StatusDraft, models.StatusQueued, models.StatusProcessing:
+		response.JSON(w, http.StatusAccepted, dto.Pending{Status: submission.Status})
+	default:
+		response.NotFound(w, "Analysis not available")
+	}
+}
+
+// AnalysisRetryResponse lists the stages of an analysis queued to run
+// again
+type AnalysisRetryResponse struct {
+	Stages []string `json:"stages"`
+}
+
+// RetryAnalysis queues the stages of a submission's analysis that timed
+// out to run again; their results are merged into the analysis
+// POST /api/v1/submissions/{id}/analysis/retry
+func (h *SubmissionHandler) RetryAnalysis(w http.ResponseWriter, r *http.Request) {
+	h.rerun(w, r, func(analysis *models.Analysis) []string { return analysis.TimedOut }, "No stage of the analysis timed out")
+}
+
+// RerunModule queues one stage of a submission's analysis to run again;
+// its result is merged into the analysis
+// POST /api/v1/submissions/{id}/analysis/modules/{module}/rerun
+func (h *SubmissionHandler) RerunModule(w http.ResponseWriter, r *http.Request) {
+	module := chi.URLParam(r, "module")
+	if !analyzer.Rerunnable(module) {
+		response.BadRequest(w, fmt.Sprintf("Module %q can't be run again on its own", module))
+		return
+	}
+	h.rerun(w, r, func(*models.Analysis) []string { return []string{module} }, "")
+}
+
+// rerun queues the stages pick chooses of a completed submission's
+// analysis to run again. It responds 409 with none when pick chooses no
+// stage.
+func (h *SubmissionHandler) rerun(w http.ResponseWriter, r *http.Request, pick func(*models.Analysis) []string, none string) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+
+	submission, err := h.store.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to rerun analysis")
+		return
+	}
+	if submission.Status != models.StatusCompleted {
+		response.Conflict(w, fmt.Sprintf("Submission is %s and has no analysis to rerun", submission.Status))
+		return
+	}
+
+	analysis, err := h.store.GetAnalysis(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Analysis not found")
+			return
+		}
+		slog.Error("Failed to get analysis", "error", err)
+		response.InternalServerError(w, "Failed to rerun analysis")
+		return
+	}
+	stages := pick(analysis)
+	if len(stages) == 0 {
+		response.Conflict(w, none)
+		return
+	}
+
+	payload := analyzer.ModulesPayload{SubmissionID: id, Stages: stages}
+	if _, err := h.jobs.EnqueuePriority(r.Context(), h.priority(h.currentUser(r)), analyzer.ModulesJobType, payload); err != nil {
+		slog.Error("Failed to enqueue analysis rerun", "submission_id", id, "error", err)
+		enqueueFailed(w, err, "Failed to queue analysis rerun")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, AnalysisRetryResponse{Stages: stages})
+}
+
+// parsePagination reads the limit query parameter and the offset, given
+// directly or as a cursor
+func parsePagination(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageSize, 0
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPageSize {
+			return 0, 0, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+		offset = n
+	}
+
+	// A cursor from a previous page's next_cursor replaces the offset
+	if v := query.Get("cursor"); v != "" {
+		if query.Has("offset") {
+			return 0, 0, fmt.Errorf("use either cursor or offset, not both")
+		}
+		n, err := response.DecodeCursor(v)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cursor is invalid; use the next_cursor of a previous page")
+		}
+		offset = n
+	}
+
+	return limit, offset, nil
+}
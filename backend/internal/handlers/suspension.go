@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// SuspensionHandler lets operators suspend, ban and reinstate accounts
+// and review appeals, and lets suspended users appeal. The database holds
+// each account's status; standings enforces it on every request.
+type SuspensionHandler struct {
+	users     UserStorer
+	appeals   AppealStorer
+	standings StandingSetter
+	audit     AuditRecorder
+}
+
+// NewSuspensionHandler creates a new suspension handler
+func NewSuspensionHandler(users UserStorer, appeals AppealStorer, standings StandingSetter, audit AuditRecorder) *SuspensionHandler {
+	return &SuspensionHandler{users: users, appeals: appeals, standings: standings, audit: audit}
+}
+
+// AccountStatusRequest changes an account's status
+type AccountStatusRequest struct {
+	Status string `json:"status"`
+	// Reason is shown to the user; required to suspend or ban
+	Reason string `json:"reason"`
+}
+
+// AppealRequest appeals the user's suspension
+type AppealRequest struct {
+	Message string `json:"message"`
+}
+
+// AppealDecisionRequest grants or denies an appeal
+type AppealDecisionRequest struct {
+	Decision string `json:"decision"`
+	// Response explains the decision to the user
+	Response string `json:"response"`
+}
+
+// SetStatus suspends, bans or reinstates an account. Setting the status
+// an account already has records it again, which also re-applies it if
+// enforcement lost track of it.
+// PUT /api/v1/admin/users/{id}/status
+func (h *SuspensionHandler) SetStatus(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+	if operatorID, _ := auth.GetUserIDFromContext(r.Context()); operatorID == userID {
+		response.Forbidden(w, "You can't change your own account status")
+		return
+	}
+
+	var req AccountStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	status, err := models.ParseAccountStatus(req.Status)
+	if err != nil {
+		response.ValidationError(w, map[string]string{"status": err.Error()})
+		return
+	}
+	reason := strings.TrimSpace(req.Reason)
+	switch {
+	case status != models.StatusActive && reason == "":
+		response.ValidationError(w, map[string]string{"reason": "reason is required to suspend or ban an account"})
+		return
+	case utf8.RuneCountInString(reason) > models.MaxStatusReasonLength:
+		response.ValidationError(w, map[string]string{"reason": fmt.Sprintf("reason must be at most %d characters", models.MaxStatusReasonLength)})
+		return
+	}
+
+	user, err := h.users.SetStatus(r.Context(), userID, status, reason)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "User not found")
+			return
+		}
+		slog.Error("Failed to set account status", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to set account status")
+		return
+	}
+	if !h.enforce(w, r, user) {
+		return
+	}
+
+	// Logged at warn: suspensions and bans lock the user out
+	slog.Warn("Account status changed", "user_id", userID, "status", status, "by", operator(r))
+	metadata := map[string]string{"status": string(status), "by": operator(r)}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	h.record(r, userID, models.AuditAccountStatusChanged, metadata)
+
+	response.Success(w, dto.NewUser(user))
+}
+
+// ListAppeals returns a page of appeals, oldest first. decision filters
+// them, and defaults to pending.
+// GET /api/v1/admin/appeals
+func (h *SuspensionHandler) ListAppeals(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	decision := models.AppealDecision(r.URL.Query().Get("decision"))
+	switch decision {
+	case "":
+		decision = models.AppealPending
+	case models.AppealPending, models.AppealGranted, models.AppealDenied:
+	default:
+		response.BadRequest(w, "decision must be one of: pending, granted, denied")
+		return
+	}
+
+	appeals, total, err := h.appeals.List(r.Context(), decision, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list appeals", "error", err)
+		response.InternalServerError(w, "Failed to list appeals")
+		return
+	}
+
+	response.Success(w, response.NewList(appeals, total, limit, offset))
+}
+
+// DecideAppeal grants or denies a pending appeal. Granting it reinstates
+// the user, unless they have been banned since.
+// POST /api/v1/admin/appeals/{id}/decision
+func (h *SuspensionHandler) DecideAppeal(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid appeal ID")
+		return
+	}
+
+	var req AppealDecisionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	decision := models.AppealDecision(req.Decision)
+	if decision != models.AppealGranted && decision != models.AppealDenied {
+		response.ValidationError(w, map[string]string{"decision": "decision must be one of: granted, denied"})
+		return
+	}
+	reply := strings.TrimSpace(req.Response)
+	if utf8.RuneCountInString(reply) > models.MaxAppealLength {
+		response.ValidationError(w, map[string]string{"response": fmt.Sprintf("response must be at most %d characters", models.MaxAppealLength)})
+		return
+	}
+
+	appeal, err := h.appeals.Decide(r.Context(), id, decision, reply, operator(r))
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Appeal not found")
+		case errors.Is(err, models.ErrAppealDecided):
+			response.Conflict(w, "The appeal has already been decided")
+		default:
+			slog.Error("Failed to decide appeal", "appeal_id", id, "error", err)
+			response.InternalServerError(w, "Failed to decide appeal")
+		}
+		return
+	}
+
+	if decision == models.AppealGranted {
+		// Enforce whatever the account's status now is, which stays banned
+		// if it was banned after the appeal
+		user, err := h.users.GetByID(r.Context(), appeal.UserID)
+		if err != nil {
+			slog.Error("Failed to get user", "user_id", appeal.UserID, "error", err)
+			response.InternalServerError(w, "The appeal was granted, but the account couldn't be reinstated; set its status to active")
+			return
+		}
+		if !h.enforce(w, r, user) {
+			return
+		}
+	}
+
+	slog.Warn("Appeal decided", "appeal_id", id, "user_id", appeal.UserID, "decision", decision, "by", operator(r))
+	h.record(r, appeal.UserID, models.AuditAppealDecided, map[string]string{
+		"appeal_id": id.String(),
+		"decision":  string(decision),
+		"by":        operator(r),
+	})
+
+	response.Success(w, appeal)
+}
+
+// ListMyAppeals returns the user's appeals and their decisions, newest
+// first
+// GET /api/v1/account/appeals
+func (h *SuspensionHandler) ListMyAppeals(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	appeals, err := h.appeals.ListForUser(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to list appeals", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to list appeals")
+		return
+	}
+
+	response.Success(w, response.Complete(appeals))
+}
+
+// Appeal submits the suspended user's appeal for operators to review.
+// Only one appeal can be pending at a time.
+// POST /api/v1/account/appeals
+func (h *SuspensionHandler) Appeal(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	var req AppealRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	message := strings.TrimSpace(req.Message)
+	if message == "" || utf8.RuneCountInString(message) > models.MaxAppealLength {
+		response.ValidationError(w, map[string]string{"message": fmt.Sprintf("message must be 1 to %d characters", models.MaxAppealLength)})
+		return
+	}
+
+	user, err := h.users.GetByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to submit appeal")
+		return
+	}
+	if user.Status != models.StatusSuspended {
+		response.Conflict(w, "Only a suspended account can be appealed")
+		return
+	}
+
+	appeal, err := h.appeals.Create(r.Context(), userID, message)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			response.Conflict(w, "You already have an appeal waiting for review")
+			return
+		}
+		slog.Error("Failed to create appeal", "user_id", userID, "error", err)
+		response.InternalServerError(w, "Failed to submit appeal")
+		return
+	}
+
+	slog.Info("Appeal submitted", "appeal_id", appeal.ID, "user_id", userID)
+	h.record(r, userID, models.AuditAppealSubmitted, map[string]string{"appeal_id": appeal.ID.String()})
+
+	response.Created(w, appeal)
+}
+
+// enforce applies the user's status to every request they make,
+// reporting whether it could, having written the response if not
+func (h *SuspensionHandler) enforce(w http.ResponseWriter, r *http.Request, user *models.User) bool {
+	var reason string
+	if user.StatusReason != nil {
+		reason = *user.StatusReason
+	}
+	if err := h.standings.SetStanding(context.WithoutCancel(r.Context()), user.ID, user.Status, reason); err != nil {
+		slog.Error("Failed to enforce account status", "user_id", user.ID, "status", user.Status, "error", err)
+		response.InternalServerError(w, "The account status was saved but couldn't be enforced; try again")
+		return false
+	}
+	return true
+}
+
+// record adds an entry to the user's audit log. Failing to doesn't undo
+// the change.
+func (h *SuspensionHandler) record(r *http.Request, userID uuid.UUID, action models.AuditAction, metadata map[string]string) {
+	entry := &models.AuditEntry{
+		UserID:    userID,
+		Action:    action,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+		Metadata:  metadata,
+	}
+	if err := h.audit.Record(context.WithoutCancel(r.Context()), entry); err != nil {
+		slog.Error("Failed to record audit entry", "action", action, "user_id", userID, "error", err)
+	}
+}
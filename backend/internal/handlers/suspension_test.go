@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// fakeStandings records the account statuses enforced
+type fakeStandings struct {
+	mu       sync.Mutex
+	statuses map[uuid.UUID]models.AccountStatus
+	err      error
+}
+
+func (s *fakeStandings) SetStanding(ctx context.Context, userID uuid.UUID, status models.AccountStatus, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	if s.statuses == nil {
+		s.statuses = map[uuid.UUID]models.AccountStatus{}
+	}
+	s.statuses[userID] = status
+	return nil
+}
+
+func (s *fakeStandings) status(userID uuid.UUID) models.AccountStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statuses[userID]
+}
+
+// suspensionFixture is a user in good standing and an operator who can
+// suspend them
+type suspensionFixture struct {
+	router    testRouter
+	users     *memstore.UserStore
+	standings *fakeStandings
+	audit     *memstore.AuditStore
+	admin     uuid.UUID
+	user      *models.User
+}
+
+func newSuspensionFixture(t *testing.T) *suspensionFixture {
+	t.Helper()
+
+	users := memstore.NewUserStore()
+	f := &suspensionFixture{
+		router:    newTestRouter(),
+		users:     users,
+		standings: &fakeStandings{},
+		audit:     memstore.NewAuditStore(),
+		admin:     uuid.New(),
+		user:      seedUser(t, users, "user@example.com"),
+	}
+
+	handler := NewSuspensionHandler(users, memstore.NewAppealStore(users), f.standings, f.audit)
+	f.router.Put("/admin/users/{id}/status", handler.SetStatus)
+	f.router.Get("/admin/appeals", handler.ListAppeals)
+	f.router.Post("/admin/appeals/{id}/decision", handler.DecideAppeal)
+	f.router.Get("/account/appeals", handler.ListMyAppeals)
+	f.router.Post("/account/appeals", handler.Appeal)
+	return f
+}
+
+func TestSuspensionHandler_SetStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         func(f *suspensionFixture) string
+		body       AccountStatusRequest
+		wantStatus int
+	}{
+		{"suspends", userIDOf, AccountStatusRequest{Status: "suspended", Reason: "Spam"}, http.StatusOK},
+		{"bans", userIDOf, AccountStatusRequest{Status: "banned", Reason: "Fraud"}, http.StatusOK},
+		{"reinstates", userIDOf, AccountStatusRequest{Status: "active"}, http.StatusOK},
+		{"no reason", userIDOf, AccountStatusRequest{Status: "suspended", Reason: "  "}, http.StatusUnprocessableEntity},
+		{"reason too long", userIDOf, AccountStatusRequest{Status: "banned", Reason: strings.Repeat("x", models.MaxStatusReasonLength+1)}, http.StatusUnprocessableEntity},
+		{"unknown status", userIDOf, AccountStatusRequest{Status: "frozen", Reason: "Spam"}, http.StatusUnprocessableEntity},
+		{"unknown user", func(*suspensionFixture) string { return uuid.NewString() }, AccountStatusRequest{Status: "suspended", Reason: "Spam"}, http.StatusNotFound},
+		{"invalid ID", func(*suspensionFixture) string { return "nope" }, AccountStatusRequest{Status: "suspended", Reason: "Spam"}, http.StatusBadRequest},
+		{"themselves", func(f *suspensionFixture) string { return f.admin.String() }, AccountStatusRequest{Status: "banned", Reason: "Oops"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSuspensionFixture(t)
+			rec := f.router.do(t, f.admin, http.MethodPut, "/admin/users/"+tt.id(f)+"/status", tt.body)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				if len(f.audit.Entries()) != 0 {
+					t.Errorf("audit = %+v, want nothing recorded", f.audit.Entries())
+				}
+				return
+			}
+
+			var resp dto.User
+			decodeBody(t, rec, &resp)
+			if resp.Status != tt.body.Status {
+				t.Errorf("status = %q, want %q", resp.Status, tt.body.Status)
+			}
+			if got := f.standings.status(f.user.ID); string(got) != tt.body.Status {
+				t.Errorf("enforced status = %q, want %q", got, tt.body.Status)
+			}
+			entries := f.audit.Entries()
+			if len(entries) != 1 || entries[0].Action != models.AuditAccountStatusChanged || entries[0].Metadata["status"] != tt.body.Status {
+				t.Errorf("audit = %+v", entries)
+			}
+		})
+	}
+}
+
+func userIDOf(f *suspensionFixture) string {
+	return f.user.ID.String()
+}
+
+func TestSuspensionHandler_SetStatus_EnforcementFails(t *testing.T) {
+	f := newSuspensionFixture(t)
+	f.standings.err = errors.New("redis down")
+
+	rec := f.router.do(t, f.admin, http.MethodPut, "/admin/users/"+f.user.ID.String()+"/status", AccountStatusRequest{Status: "suspended", Reason: "Spam"})
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestSuspensionHandler_Appeal(t *testing.T) {
+	f := newSuspensionFixture(t)
+
+	appeal := AppealRequest{Message: "It was a misunderstanding"}
+	if rec := f.router.do(t, f.user.ID, http.MethodPost, "/account/appeals", appeal); rec.Code != http.StatusConflict {
+		t.Fatalf("appeal while active status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	if rec := f.router.do(t, f.admin, http.MethodPut, "/admin/users/"+f.user.ID.String()+"/status", AccountStatusRequest{Status: "suspended", Reason: "Spam"}); rec.Code != http.StatusOK {
+		t.Fatalf("suspend status = %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := f.router.do(t, f.user.ID, http.MethodPost, "/account/appeals", AppealRequest{Message: " "}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("empty appeal status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+
+	rec := f.router.do(t, f.user.ID, http.MethodPost, "/account/appeals", appeal)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("appeal status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+	}
+	var created models.Appeal
+	decodeBody(t, rec, &created)
+	if created.Decision != models.AppealPending || created.StatusReason != "Spam" {
+		t.Errorf("appeal = %+v, want a pending appeal against the suspension", created)
+	}
+
+	if rec := f.router.do(t, f.user.ID, http.MethodPost, "/account/appeals", appeal); rec.Code != http.StatusConflict {
+		t.Errorf("second appeal status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	var mine response.ListResponse[models.Appeal]
+	decodeBody(t, f.router.do(t, f.user.ID, http.MethodGet, "/account/appeals", nil), &mine)
+	if len(mine.Data) != 1 || mine.Data[0].ID != created.ID || mine.Pagination.Total != 1 {
+		t.Errorf("appeals = %+v, want the pending appeal", mine)
+	}
+}
+
+func TestSuspensionHandler_DecideAppeal(t *testing.T) {
+	tests := []struct {
+		name       string
+		decision   string
+		wantStatus models.AccountStatus
+	}{
+		{"granted", "granted", models.StatusActive},
+		{"denied", "denied", models.StatusSuspended},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newSuspensionFixture(t)
+			f.router.do(t, f.admin, http.MethodPut, "/admin/users/"+f.user.ID.String()+"/status", AccountStatusRequest{Status: "suspended", Reason: "Spam"})
+
+			var appeal models.Appeal
+			decodeBody(t, f.router.do(t, f.user.ID, http.MethodPost, "/account/appeals", AppealRequest{Message: "Please"}), &appeal)
+
+			var page response.ListResponse[models.Appeal]
+			decodeBody(t, f.router.do(t, f.admin, http.MethodGet, "/admin/appeals", nil), &page)
+			if page.Pagination.Total != 1 || len(page.Data) != 1 || page.Data[0].ID != appeal.ID {
+				t.Fatalf("pending appeals = %+v, want the appeal", page)
+			}
+
+			target := "/admin/appeals/" + appeal.ID.String() + "/decision"
+			body := AppealDecisionRequest{Decision: tt.decision, Response: "Reviewed"}
+			rec := f.router.do(t, f.admin, http.MethodPost, target, body)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+			}
+
+			user, err := f.users.GetByID(context.Background(), f.user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Status != tt.wantStatus || f.standings.status(f.user.ID) != tt.wantStatus {
+				t.Errorf("status = %q, enforced %q, want %q", user.Status, f.standings.status(f.user.ID), tt.wantStatus)
+			}
+
+			if rec := f.router.do(t, f.admin, http.MethodPost, target, body); rec.Code != http.StatusConflict {
+				t.Errorf("second decision status = %d, want %d", rec.Code, http.StatusConflict)
+			}
+		})
+	}
+}
+
+func TestSuspensionHandler_DecideAppeal_Invalid(t *testing.T) {
+	f := newSuspensionFixture(t)
+
+	if rec := f.router.do(t, f.admin, http.MethodPost, "/admin/appeals/"+uuid.NewString()+"/decision", AppealDecisionRequest{Decision: "granted"}); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown appeal status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if rec := f.router.do(t, f.admin, http.MethodPost, "/admin/appeals/"+uuid.NewString()+"/decision", AppealDecisionRequest{Decision: "pending"}); rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("pending decision status = %d, want %d", rec.Code, http.StatusUnprocessableEntity)
+	}
+	if rec := f.router.do(t, f.admin, http.MethodGet, "/admin/appeals?decision=maybe", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("unknown decision filter status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestAuthHandler_Login_SuspendedOrBanned(t *testing.T) {
+	handler, users, _ := newTestAuthHandler()
+	ctx := context.Background()
+
+	user := seedUser(t, users, "user@example.com")
+
+	login := LoginRequest{Email: user.Email, Password: testPassword}
+	if _, err := users.SetStatus(ctx, user.ID, models.StatusSuspended, "Spam"); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.Login(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/login", login))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("suspended login status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var suspended auth.StandingResponse
+	decodeBody(t, rec, &suspended)
+	if suspended.Status != models.StatusSuspended || !suspended.Appealable || suspended.AppealToken == nil {
+		t.Fatalf("response = %+v, want the suspension with an appeal token", suspended)
+	}
+
+	// The appeal token reaches the appeal routes and nothing else
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range []struct {
+		name       string
+		middleware func(*auth.JWTManager, auth.RevocationChecker) func(http.Handler) http.Handler
+		wantStatus int
+	}{
+		{name: "appeal routes", middleware: auth.SuspendedMiddleware, wantStatus: http.StatusOK},
+		{name: "other routes", middleware: auth.Middleware, wantStatus: http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+suspended.AppealToken.AccessToken)
+		rec := httptest.NewRecorder()
+		tt.middleware(handler.jwtManager, nil)(next).ServeHTTP(rec, req)
+		if rec.Code != tt.wantStatus {
+			t.Errorf("appeal token on %s: status = %d, want %d", tt.name, rec.Code, tt.wantStatus)
+		}
+	}
+
+	if _, err := users.SetStatus(ctx, user.ID, models.StatusBanned, "Fraud"); err != nil {
+		t.Fatal(err)
+	}
+	rec = httptest.NewRecorder()
+	handler.Login(rec, newJSONRequest(t, http.MethodPost, "/api/v1/auth/login", login))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("banned login status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	var resp auth.StandingResponse
+	decodeBody(t, rec, &resp)
+	if resp.Status != models.StatusBanned || resp.Reason != "Fraud" || resp.Appealable {
+		t.Errorf("response = %+v, want the unappealable ban", resp)
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/threads"
+)
+
+// ThreadHandler manages follow-up conversations about a submission's
+// analysis. Replies are written by a background job, so posting a message
+// returns 202 and the thread reports pending until the reply arrives.
+type ThreadHandler struct {
+	threads     ThreadStorer
+	submissions SubmissionStorer
+	jobs        JobEnqueuer
+}
+
+// NewThreadHandler creates a new thread handler
+func NewThreadHandler(threads ThreadStorer, submissions SubmissionStorer, jobs JobEnqueuer) *ThreadHandler {
+	return &ThreadHandler{
+		threads:     threads,
+		submissions: submissions,
+		jobs:        jobs,
+	}
+}
+
+// ThreadMessageRequest posts a message to a thread
+type ThreadMessageRequest struct {
+	Message string `json:"message"`
+}
+
+// message returns the trimmed message, writing a validation error and
+// returning false if it is blank or too long
+func (req *ThreadMessageRequest) message(w http.ResponseWriter) (string, bool) {
+	message := strings.TrimSpace(req.Message)
+	if message == "" || utf8.RuneCountInString(message) > models.MaxThreadMessageLength {
+		response.ValidationError(w, map[string]string{
+			"message": fmt.Sprintf("Message must be 1-%d characters", models.MaxThreadMessageLength),
+		})
+		return "", false
+	}
+	return message, true
+}
+
+// List returns the user's threads on a submission, most recently active
+// first
+// GET /api/v1/submissions/{id}/threads
+func (h *ThreadHandler) List(w http.ResponseWriter, r *http.Request) {
+	submission, ok := h.submission(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.threads.ListForSubmission(r.Context(), submission.UserID, submission.ID)
+	if err != nil {
+		slog.Error("Failed to list threads", "submission_id", submission.ID, "error", err)
+		response.InternalServerError(w, "Failed to list threads")
+		return
+	}
+
+	response.Success(w, response.Complete(list))
+}
+
+// Create starts a thread on a completed submission with the user's first
+// message and schedules the reply
+// POST /api/v1/submissions/{id}/threads
+func (h *ThreadHandler) Create(w http.ResponseWriter, r *http.Request) {
+	submission, ok := h.submission(w, r)
+	if !ok {
+		return
+	}
+	if submission.Status != models.StatusCompleted {
+		response.Conflict(w, "Only analyzed submissions can be discussed")
+		return
+	}
+
+	var req ThreadMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	message, ok := req.message(w)
+	if !ok {
+		return
+	}
+
+	thread, err := h.threads.Create(r.Context(), submission.UserID, submission.ID, message)
+	if err != nil {
+		slog.Error("Failed to create thread", "submission_id", submission.ID, "error", err)
+		response.InternalServerError(w, "Failed to create thread")
+		return
+	}
+
+	h.reply(w, r, thread)
+}
+
+// Get returns a thread with its messages
+// GET /api/v1/threads/{id}
+func (h *ThreadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.threadID(w, r)
+	if !ok {
+		return
+	}
+
+	thread, err := h.threads.GetByID(r.Context(), userID, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to load thread")
+		return
+	}
+
+	response.Success(w, thread)
+}
+
+// AddMessage continues a thread with a follow-up message and schedules the
+// reply. A thread takes one message at a time.
+// POST /api/v1/threads/{id}/messages
+func (h *ThreadHandler) AddMessage(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.threadID(w, r)
+	if !ok {
+		return
+	}
+
+	var req ThreadMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	message, ok := req.message(w)
+	if !ok {
+		return
+	}
+
+	thread, err := h.threads.AddMessage(r.Context(), userID, id, message)
+	if err != nil {
+		h.writeError(w, err, "Failed to add message")
+		return
+	}
+
+	h.reply(w, r, thread)
+}
+
+// Delete removes a thread and its messages
+// DELETE /api/v1/threads/{id}
+func (h *ThreadHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.threadID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.threads.Delete(r.Context(), userID, id); err != nil {
+		h.writeError(w, err, "Failed to delete thread")
+		return
+	}
+
+	response.NoContent(w)
+}
+
+// reply schedules the answer to the thread's last message and writes the
+// thread
+func (h *ThreadHandler) reply(w http.ResponseWriter, r *http.Request, thread *models.Thread) {
+	if _, err := h.jobs.Enqueue(r.Context(), threads.JobType, threads.Payload{ThreadID: thread.ID}); err != nil {
+		slog.Error("Failed to enqueue thread reply", "thread_id", thread.ID, "error", err)
+
+		// Let the user post again rather than wait forever
+		if err := h.threads.MarkFailed(r.Context(), thread.ID); err != nil {
+			slog.Error("Failed to mark thread message failed", "thread_id", thread.ID, "error", err)
+		}
+
+		enqueueFailed(w, err, "Failed to queue reply")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, thread)
+}
+
+// submission reads the submission in the URL, writing a response and
+// returning false if the user can't see it
+func (h *ThreadHandler) submission(w http.ResponseWriter, r *http.Request) (*models.Submission, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return nil, false
+	}
+
+	submission, err := h.submissions.GetByID(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return nil, false
+		}
+		slog.Error("Failed to load submission", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to load submission")
+		return nil, false
+	}
+	return submission, true
+}
+
+// threadID reads the user and the thread ID in the URL, writing a response
+// and returning false if either is missing
+func (h *ThreadHandler) threadID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid thread ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
+
+// writeError maps store errors to responses
+func (h *ThreadHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		response.NotFound(w, "Thread not found")
+	case errors.Is(err, models.ErrThreadBusy):
+		response.Conflict(w, "Wait for the reply to the previous message")
+	case errors.Is(err, models.ErrThreadFull):
+		response.Conflict(w, fmt.Sprintf("Threads are limited to %d messages; start a new one", models.MaxThreadMessages))
+	default:
+		slog.Error(message, "error", err)
+		response.InternalServerError(w, message)
+	}
+}
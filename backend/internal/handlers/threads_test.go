@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/threads"
+)
+
+// newThreadRouter mounts the handler so chi URL parameters resolve
+func newThreadRouter(handler *ThreadHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Get("/submissions/{id}/threads", handler.List)
+	r.Post("/submissions/{id}/threads", handler.Create)
+	r.Get("/threads/{id}", handler.Get)
+	r.Post("/threads/{id}/messages", handler.AddMessage)
+	r.Delete("/threads/{id}", handler.Delete)
+	return r
+}
+
+// analyzedSubmission stores a completed submission for userID
+func analyzedSubmission(t *testing.T, store *memstore.SubmissionStore, userID uuid.UUID) *models.Submission {
+	t.Helper()
+	ctx := context.Background()
+
+	submission, err := store.Create(ctx, userID, "Quarterly results were strong.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := store.UpdateStatus(ctx, submission.ID, models.StatusProcessing); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := store.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Sentiment: "positive"}); err != nil {
+		t.Fatalf("SaveAnalysis() error = %v", err)
+	}
+	return submission
+}
+
+func TestThreadHandler_Create(t *testing.T) {
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	submission := analyzedSubmission(t, submissions, userID)
+	draft, _ := submissions.Create(context.Background(), userID, "Draft", nil, nil, nil, models.StatusDraft)
+
+	tests := []struct {
+		name         string
+		user         uuid.UUID
+		submissionID uuid.UUID
+		body         interface{}
+		wantStatus   int
+	}{
+		{"starts a thread", userID, submission.ID, ThreadMessageRequest{Message: "  List the risks  "}, http.StatusAccepted},
+		{"blank message", userID, submission.ID, ThreadMessageRequest{Message: " "}, http.StatusUnprocessableEntity},
+		{"message too long", userID, submission.ID, ThreadMessageRequest{Message: strings.Repeat("a", models.MaxThreadMessageLength+1)}, http.StatusUnprocessableEntity},
+		{"not analyzed yet", userID, draft.ID, ThreadMessageRequest{Message: "Hi"}, http.StatusConflict},
+		{"other user's submission", uuid.New(), submission.ID, ThreadMessageRequest{Message: "Hi"}, http.StatusNotFound},
+		{"malformed body", userID, submission.ID, "not json", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := &fakeQueue{}
+			router := newThreadRouter(NewThreadHandler(memstore.NewThreadStore(), submissions, jobs))
+
+			rec := httptest.NewRecorder()
+			req := newJSONRequest(t, http.MethodPost, "/submissions/"+tt.submissionID.String()+"/threads", tt.body)
+			router.ServeHTTP(rec, withUser(req, tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Create() status = %d, want %d (body: %s)", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if len(jobs.jobs) != 0 {
+					t.Errorf("Create() enqueued %d jobs for a rejected request", len(jobs.jobs))
+				}
+				return
+			}
+
+			var got models.Thread
+			decodeBody(t, rec, &got)
+			if got.Title != "List the risks" || !got.Pending || len(got.Messages) != 1 {
+				t.Errorf("Create() = %+v, want a pending thread titled by the message", got)
+			}
+
+			if len(jobs.jobs) != 1 || jobs.jobs[0].Type != threads.JobType {
+				t.Fatalf("Create() enqueued %v, want one %s job", jobs.jobs, threads.JobType)
+			}
+			var payload threads.Payload
+			if err := jobs.jobs[0].Decode(&payload); err != nil || payload.ThreadID != got.ID {
+				t.Errorf("job payload = %+v, want thread %s", payload, got.ID)
+			}
+		})
+	}
+}
+
+func TestThreadHandler_Create_EnqueueFailure(t *testing.T) {
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	submission := analyzedSubmission(t, submissions, userID)
+	store := memstore.NewThreadStore()
+	router := newThreadRouter(NewThreadHandler(store, submissions, &fakeQueue{err: errors.New("redis down")}))
+
+	rec := httptest.NewRecorder()
+	req := newJSONRequest(t, http.MethodPost, "/submissions/"+submission.ID.String()+"/threads", ThreadMessageRequest{Message: "Hi"})
+	router.ServeHTTP(rec, withUser(req, userID))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Create() status = %d, want 500", rec.Code)
+	}
+
+	list, _ := store.ListForSubmission(context.Background(), userID, submission.ID)
+	if len(list) != 1 || list[0].Pending {
+		t.Errorf("threads = %+v, want the message left unanswered so the user can retry", list)
+	}
+}
+
+func TestThreadHandler_AddMessage(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	submission := analyzedSubmission(t, submissions, userID)
+	store := memstore.NewThreadStore()
+	jobs := &fakeQueue{}
+	router := newThreadRouter(NewThreadHandler(store, submissions, jobs))
+
+	thread, err := store.Create(ctx, userID, submission.ID, "Make the summary shorter")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	post := func(user uuid.UUID, message string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := newJSONRequest(t, http.MethodPost, "/threads/"+thread.ID.String()+"/messages", ThreadMessageRequest{Message: message})
+		router.ServeHTTP(rec, withUser(req, user))
+		return rec
+	}
+
+	if rec := post(userID, "And list the risks"); rec.Code != http.StatusConflict {
+		t.Errorf("AddMessage() while awaiting a reply status = %d, want 409", rec.Code)
+	}
+
+	if err := store.AddReply(ctx, thread.ID, &models.ThreadMessage{Content: "Strong quarter."}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := post(uuid.New(), "And list the risks"); rec.Code != http.StatusNotFound {
+		t.Errorf("AddMessage() by another user status = %d, want 404", rec.Code)
+	}
+
+	rec := post(userID, "And list the risks")
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("AddMessage() status = %d, want 202 (body: %s)", rec.Code, rec.Body.String())
+	}
+
+	var got models.Thread
+	decodeBody(t, rec, &got)
+	if len(got.Messages) != 3 || got.Messages[1].Role != models.MessageRoleAssistant || !got.Pending {
+		t.Errorf("AddMessage() = %+v, want three messages awaiting a reply", got)
+	}
+	if len(jobs.jobs) != 1 {
+		t.Errorf("AddMessage() enqueued %d jobs, want 1", len(jobs.jobs))
+	}
+}
+
+func TestThreadHandler_GetListDelete(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	submissions := memstore.NewSubmissionStore()
+	submission := analyzedSubmission(t, submissions, userID)
+	store := memstore.NewThreadStore()
+	router := newThreadRouter(NewThreadHandler(store, submissions, &fakeQueue{}))
+
+	thread, err := store.Create(ctx, userID, submission.ID, "List the risks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+submission.ID.String()+"/threads", nil), userID))
+	var list struct {
+		Data []models.Thread `json:"data"`
+	}
+	decodeBody(t, rec, &list)
+	if len(list.Data) != 1 || list.Data[0].MessageCount != 1 || list.Data[0].Messages != nil {
+		t.Errorf("List() = %+v, want the thread without its messages", list.Data)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/threads/"+thread.ID.String(), nil), uuid.New()))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Get() by another user status = %d, want 404", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodDelete, "/threads/"+thread.ID.String(), nil), userID))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Delete() status = %d, want 204", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/threads/"+thread.ID.String(), nil), userID))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Get() after Delete() status = %d, want 404", rec.Code)
+	}
+}
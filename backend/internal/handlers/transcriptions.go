@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/transcription"
+)
+
+const (
+	// multipartMemory is how much of an upload is held in memory; the
+	// rest spills to a temporary file
+	multipartMemory = 8 << 20
+
+	// multipartOverhead allows for the form fields and part headers
+	// around the file
+	multipartOverhead = 64 << 10
+
+	maxFilenameLength = 255
+)
+
+// WithTranscription accepts audio and video uploads of up to maxBytes for
+// transcription and returns the handler. Without it uploads return 404.
+func (h *SubmissionHandler) WithTranscription(store TranscriptionStorer, maxBytes int64) *SubmissionHandler {
+	h.transcriptions = store
+	h.maxMediaBytes = maxBytes
+	return h
+}
+
+// Upload accepts an audio or video recording as the "file" field of a
+// multipart form, with optional "instructions" and "profile_id" fields.
+// The recording is transcribed in the background and the transcript
+// becomes a new submission queued for analysis.
+// POST /api/v1/submissions/transcriptions
+func (h *SubmissionHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	if h.transcriptions == nil {
+		response.NotFound(w, "Audio and video uploads aren't enabled")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxMediaBytes+multipartOverhead)
+	if err := r.ParseMultipartForm(multipartMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			h.uploadTooLarge(w)
+			return
+		}
+		response.BadRequest(w, "Expected a multipart/form-data upload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.ValidationError(w, map[string]string{"file": "An audio or video file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > h.maxMediaBytes {
+		h.uploadTooLarge(w)
+		return
+	}
+
+	media, err := io.ReadAll(file)
+	if err != nil {
+		slog.Error("Failed to read upload", "error", err)
+		response.InternalServerError(w, "Failed to read upload")
+		return
+	}
+	contentType := mediaType(media, header.Header.Get("Content-Type"))
+	if contentType == "" {
+		response.Error(w, http.StatusUnsupportedMediaType, "Upload an audio or video file")
+		return
+	}
+
+	focus, fields := validateInstructions(r.FormValue("instructions"))
+	if fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+	var profileID *uuid.UUID
+	if raw := r.FormValue("profile_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			response.ValidationError(w, map[string]string{"profile_id": "Unknown analysis profile"})
+			return
+		}
+		profileID = &id
+	}
+	if !h.checkProfile(w, r, userID, profileID) {
+		return
+	}
+
+	// The analysis that follows the transcript is counted now, and runs in
+	// the user's priority lane
+	user := h.currentUser(r)
+	priority := h.priority(user)
+
+	upload, err := h.transcriptions.Create(r.Context(), &models.Transcription{
+		UserID:       userID,
+		Filename:     cleanFilename(header.Filename),
+		ContentType:  contentType,
+		Instructions: focus,
+		ProfileID:    profileID,
+		Priority:     string(priority),
+	}, media)
+	if err != nil {
+		slog.Error("Failed to store upload", "error", err)
+		response.InternalServerError(w, "Failed to store upload")
+		return
+	}
+
+	if _, err := h.jobs.EnqueuePriority(r.Context(), priority, transcription.JobType, transcription.Payload{TranscriptionID: upload.ID}); err != nil {
+		slog.Error("Failed to enqueue transcription", "transcription_id", upload.ID, "error", err)
+
+		if err := h.transcriptions.Fail(r.Context(), upload.ID, "The upload couldn't be queued for transcription"); err != nil {
+			slog.Error("Failed to mark transcription failed", "transcription_id", upload.ID, "error", err)
+		}
+
+		enqueueFailed(w, err, "Failed to queue upload for transcription")
+		return
+	}
+
+	h.recordQuota(w, r, user, upload.ID)
+	response.JSON(w, http.StatusAccepted, upload)
+}
+
+// GetTranscription reports the progress of an upload and, once it is
+// transcribed, the submission created from it and the timed transcript
+// GET /api/v1/submissions/transcriptions/{id}
+func (h *SubmissionHandler) GetTranscription(w http.ResponseWriter, r *http.Request) {
+	h.writeTranscription(w, r, "Invalid transcription ID", func(userID, id uuid.UUID) (*models.Transcription, error) {
+		return h.transcriptions.GetByID(r.Context(), userID, id)
+	})
+}
+
+// GetTranscript returns the timed transcript of a submission created from
+// an upload
+// GET /api/v1/submissions/{id}/transcript
+func (h *SubmissionHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	h.writeTranscription(w, r, "Invalid submission ID", func(userID, id uuid.UUID) (*models.Transcription, error) {
+		return h.transcriptions.GetForSubmission(r.Context(), userID, id)
+	})
+}
+
+// writeTranscription looks up a transcription by the ID in the URL and
+// writes it
+func (h *SubmissionHandler) writeTranscription(w http.ResponseWriter, r *http.Request, invalidID string, get func(userID, id uuid.UUID) (*models.Transcription, error)) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, invalidID)
+		return
+	}
+
+	if h.transcriptions == nil {
+		response.NotFound(w, "Transcript not found")
+		return
+	}
+
+	upload, err := get(userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Transcript not found")
+			return
+		}
+		slog.Error("Failed to load transcription", "id", id, "error", err)
+		response.InternalServerError(w, "Failed to load transcript")
+		return
+	}
+
+	response.Success(w, upload)
+}
+
+// uploadTooLarge writes the response for an upload over the size limit
+func (h *SubmissionHandler) uploadTooLarge(w http.ResponseWriter) {
+	response.Error(w, http.StatusRequestEntityTooLarge, response.Sprintf(w, "Uploads must be at most %d MB", h.maxMediaBytes>>20))
+}
+
+// mediaType sniffs the type of an upload, falling back to the declared
+// type only when sniffing is inconclusive. It returns "" for anything
+// other than audio or video.
+func mediaType(media []byte, declared string) string {
+	sniffed := http.DetectContentType(media)
+	if transcription.IsMediaType(sniffed) {
+		return sniffed
+	}
+	if sniffed == "application/octet-stream" {
+		if parsed, _, err := mime.ParseMediaType(declared); err == nil && transcription.IsMediaType(parsed) {
+			return parsed
+		}
+	}
+	return ""
+}
+
+// cleanFilename keeps the base name of an uploaded file for display
+func cleanFilename(name string) string {
+	name = strings.TrimSpace(filepath.Base(strings.ReplaceAll(name, `\`, "/")))
+	if name == "" || name == "." || name == "/" {
+		return "upload"
+	}
+	if utf8.RuneCountInString(name) > maxFilenameLength {
+		runes := []rune(name)
+		name = string(runes[:maxFilenameLength])
+	}
+	return name
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/transcription"
+)
+
+// oggHeader is enough of an Ogg stream for content sniffing
+var oggHeader = []byte("OggS\x00\x02\x00\x00\x00\x00\x00\x00\x00\x00")
+
+// newTranscriptionRouter mounts the handler so chi URL parameters resolve
+func newTranscriptionRouter(handler *SubmissionHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/submissions/transcriptions", handler.Upload)
+	r.Get("/submissions/transcriptions/{id}", handler.GetTranscription)
+	r.Get("/submissions/{id}/transcript", handler.GetTranscript)
+	return r
+}
+
+// newUploadRequest builds a multipart upload of data as the "file" field
+func newUploadRequest(t *testing.T, filename, contentType string, data []byte, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data != nil {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(data)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/submissions/transcriptions", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestSubmissionHandler_Upload(t *testing.T) {
+	tests := []struct {
+		name        string
+		filename    string
+		contentType string
+		data        []byte
+		fields      map[string]string
+		wantStatus  int
+	}{
+		{"sniffed recording", "call.ogg", "application/octet-stream", oggHeader, nil, http.StatusAccepted},
+		{"declared type for unknown bytes", "memo.m4a", "audio/mp4", []byte{0x00, 0x01, 0x02, 0x03}, nil, http.StatusAccepted},
+		{"with instructions", "call.ogg", "audio/ogg", oggHeader, map[string]string{"instructions": "Focus on action items"}, http.StatusAccepted},
+		{"text file", "notes.txt", "audio/mpeg", []byte("just some notes"), nil, http.StatusUnsupportedMediaType},
+		{"missing file", "", "", nil, nil, http.StatusUnprocessableEntity},
+		{"too large", "long.ogg", "audio/ogg", append(oggHeader, make([]byte, 2<<10)...), nil, http.StatusRequestEntityTooLarge},
+		{"bad profile", "call.ogg", "audio/ogg", oggHeader, map[string]string{"profile_id": "nope"}, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			store := memstore.NewTranscriptionStore()
+			jobs := &fakeQueue{}
+			handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), jobs).WithTranscription(store, 1<<10)
+
+			rec := httptest.NewRecorder()
+			newTranscriptionRouter(handler).ServeHTTP(rec, withUser(newUploadRequest(t, tt.filename, tt.contentType, tt.data, tt.fields), userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusAccepted {
+				if len(jobs.jobs) != 0 {
+					t.Errorf("enqueued %d jobs, want 0", len(jobs.jobs))
+				}
+				return
+			}
+
+			var upload models.Transcription
+			decodeBody(t, rec, &upload)
+			if upload.Status != models.TranscriptionPending || upload.Filename != tt.filename || upload.SizeBytes != int64(len(tt.data)) {
+				t.Errorf("upload = %+v", upload)
+			}
+			if len(jobs.jobs) != 1 || jobs.jobs[0].Type != transcription.JobType {
+				t.Fatalf("jobs = %+v, want one %s job", jobs.jobs, transcription.JobType)
+			}
+			if media, err := store.Media(context.Background(), upload.ID); err != nil || !bytes.Equal(media, tt.data) {
+				t.Errorf("Media() = %v, %v", media, err)
+			}
+		})
+	}
+}
+
+func TestSubmissionHandler_Upload_Disabled(t *testing.T) {
+	handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), &fakeQueue{})
+
+	rec := httptest.NewRecorder()
+	newTranscriptionRouter(handler).ServeHTTP(rec, withUser(newUploadRequest(t, "call.ogg", "audio/ogg", oggHeader, nil), uuid.New()))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// recordingTranscriptions remembers the last upload stored
+type recordingTranscriptions struct {
+	*memstore.TranscriptionStore
+	created *models.Transcription
+}
+
+func (s *recordingTranscriptions) Create(ctx context.Context, t *models.Transcription, media []byte) (*models.Transcription, error) {
+	created, err := s.TranscriptionStore.Create(ctx, t, media)
+	s.created = created
+	return created, err
+}
+
+func TestSubmissionHandler_Upload_EnqueueFailure(t *testing.T) {
+	userID := uuid.New()
+	store := &recordingTranscriptions{TranscriptionStore: memstore.NewTranscriptionStore()}
+	handler := NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), &fakeQueue{err: errors.New("redis down")}).WithTranscription(store, 1<<20)
+
+	rec := httptest.NewRecorder()
+	newTranscriptionRouter(handler).ServeHTTP(rec, withUser(newUploadRequest(t, "call.ogg", "audio/ogg", oggHeader, nil), userID))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+	if store.created == nil {
+		t.Fatal("upload wasn't stored")
+	}
+	failed, err := store.GetByID(context.Background(), userID, store.created.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.Status != models.TranscriptionFailed {
+		t.Errorf("status = %s, want %s", failed.Status, models.TranscriptionFailed)
+	}
+}
+
+func TestSubmissionHandler_GetTranscript(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	submissionID := uuid.New()
+	store := memstore.NewTranscriptionStore()
+
+	upload, err := store.Create(ctx, &models.Transcription{UserID: userID, Filename: "call.ogg", ContentType: "audio/ogg"}, oggHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider := transcription.ProviderOpenAI
+	upload.SubmissionID = &submissionID
+	upload.Provider = &provider
+	upload.Segments = []models.TranscriptSegment{{Start: 0, End: 1.5, Text: "Hello there."}}
+	if err := store.Complete(ctx, upload); err != nil {
+		t.Fatal(err)
+	}
+
+	router := newTranscriptionRouter(NewSubmissionHandler(memstore.NewSubmissionStore(), memstore.NewUserStore(), &fakeQueue{}).WithTranscription(store, 1<<20))
+
+	tests := []struct {
+		name       string
+		user       uuid.UUID
+		target     string
+		wantStatus int
+	}{
+		{"by upload", userID, "/submissions/transcriptions/" + upload.ID.String(), http.StatusOK},
+		{"by submission", userID, "/submissions/" + submissionID.String() + "/transcript", http.StatusOK},
+		{"other user's upload", uuid.New(), "/submissions/transcriptions/" + upload.ID.String(), http.StatusNotFound},
+		{"submission without transcript", userID, "/submissions/" + uuid.NewString() + "/transcript", http.StatusNotFound},
+		{"invalid ID", userID, "/submissions/transcriptions/nope", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, tt.target, nil), tt.user))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var got models.Transcription
+			decodeBody(t, rec, &got)
+			if got.Status != models.TranscriptionCompleted || len(got.Segments) != 1 || got.Segments[0].End != 1.5 {
+				t.Errorf("transcription = %+v", got)
+			}
+		})
+	}
+}
+
+func TestMediaType(t *testing.T) {
+	tests := []struct {
+		name     string
+		media    []byte
+		declared string
+		want     string
+	}{
+		{"sniffed", oggHeader, "text/plain", "application/ogg"},
+		{"declared when inconclusive", []byte{0x00, 0x01}, "video/mp4", "video/mp4"},
+		{"declared with parameters", []byte{0x00, 0x01}, "audio/webm; codecs=opus", "audio/webm"},
+		{"declared type can't override text", []byte("hello"), "audio/mpeg", ""},
+		{"unknown declared type", []byte{0x00, 0x01}, "application/pdf", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mediaType(tt.media, tt.declared); got != tt.want {
+				t.Errorf("mediaType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "call.ogg", "call.ogg"},
+		{"unix path", "/home/me/call.ogg", "call.ogg"},
+		{"windows path", `C:\Users\me\call.ogg`, "call.ogg"},
+		{"empty", "", "upload"},
+		{"long", strings.Repeat("a", 300), strings.Repeat("a", maxFilenameLength)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanFilename(tt.in); got != tt.want {
+				t.Errorf("cleanFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
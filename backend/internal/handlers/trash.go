@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// TrashHandler handles deleting submissions and the trash they wait in
+// until they are restored or purged
+type TrashHandler struct {
+	store TrashStorer
+	// stats drops the owner's cached stats, enabled with WithStats
+	stats StatsInvalidator
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(store TrashStorer) *TrashHandler {
+	return &TrashHandler{store: store}
+}
+
+// WithStats drops the owner's cached stats when a submission is deleted or
+// restored and returns the handler
+func (h *TrashHandler) WithStats(stats StatsInvalidator) *TrashHandler {
+	h.stats = stats
+	return h
+}
+
+// EmptyTrashResponse says how many submissions emptying the trash deleted
+type EmptyTrashResponse struct {
+	Purged int `json:"purged"`
+}
+
+// Delete moves a submission to the trash. Queued and processing
+// submissions have to be canceled first.
+// DELETE /api/v1/submissions/{id}
+func (h *TrashHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	if _, err := h.store.Trash(r.Context(), userID, id); err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			response.NotFound(w, "Submission not found")
+		case errors.Is(err, models.ErrAnalysisInProgress):
+			response.Conflict(w, "Cancel the submission's analysis before deleting it")
+		default:
+			slog.Error("Failed to delete submission", "submission_id", id, "error", err)
+			response.InternalServerError(w, "Failed to delete submission")
+		}
+		return
+	}
+	if h.stats != nil {
+		h.stats.InvalidateStats(r.Context(), userID)
+	}
+
+	response.NoContent(w)
+}
+
+// List returns a page of the submissions in the trash, most recently
+// deleted first
+// GET /api/v1/trash
+func (h *TrashHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	limit, offset, err := parsePagination(r)
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+
+	submissions, total, err := h.store.ListTrash(r.Context(), userID, limit, offset)
+	if err != nil {
+		slog.Error("Failed to list trash", "error", err)
+		response.InternalServerError(w, "Failed to list trash")
+		return
+	}
+
+	list := response.NewList(dto.NewSubmissions(submissions), total, limit, offset)
+	response.Success(w, apiversion.Render(r, submissionList(list)))
+}
+
+// Restore takes a submission out of the trash
+// POST /api/v1/trash/{id}/restore
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	userID, id, ok := h.submissionID(w, r)
+	if !ok {
+		return
+	}
+
+	submission, err := h.store.Restore(r.Context(), userID, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found in trash")
+			return
+		}
+		slog.Error("Failed to restore submission", "submission_id", id, "error", err)
+		response.InternalServerError(w, "Failed to restore submission")
+		return
+	}
+	if h.stats != nil {
+		h.stats.InvalidateStats(r.Context(), userID)
+	}
+
+	setETag(w, submission.Version)
+	response.Success(w, dto.NewSubmission(submission))
+}
+
+// Empty permanently deletes every submission in the trash, except those
+// on legal hold
+// DELETE /api/v1/trash
+func (h *TrashHandler) Empty(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+
+	purged, err := h.store.EmptyTrash(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to empty trash", "error", err)
+		response.InternalServerError(w, "Failed to empty trash")
+		return
+	}
+
+	response.Success(w, EmptyTrashResponse{Purged: purged})
+}
+
+// submissionID reads the signed-in user and the submission ID in the path.
+// It writes the error response and returns false if either is missing.
+func (h *TrashHandler) submissionID(w http.ResponseWriter, r *http.Request) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
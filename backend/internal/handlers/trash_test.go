@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+func TestTrashHandler(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewSubmissionStore()
+	stats := &fakeStats{}
+	trash := NewTrashHandler(store).WithStats(stats)
+	submissions := NewSubmissionHandler(store, memstore.NewUserStore(), &fakeQueue{})
+
+	router := chi.NewRouter()
+	router.Get("/submissions", submissions.List)
+	router.Get("/submissions/{id}", submissions.Get)
+	router.Delete("/submissions/{id}", trash.Delete)
+	router.Get("/trash", trash.List)
+	router.Delete("/trash", trash.Empty)
+	router.Post("/trash/{id}/restore", trash.Restore)
+
+	userID := uuid.New()
+	do := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, withUser(httptest.NewRequest(method, target, nil), userID))
+		return rec
+	}
+
+	draft, _ := store.Create(ctx, userID, "draft", nil, nil, nil, models.StatusDraft)
+	queued, _ := store.Create(ctx, userID, "queued", nil, nil, nil, models.StatusQueued)
+	path := "/submissions/" + draft.ID.String()
+
+	if rec := do(http.MethodDelete, "/submissions/"+queued.ID.String()); rec.Code != http.StatusConflict {
+		t.Errorf("Delete(queued) status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := do(http.MethodDelete, path); rec.Code != http.StatusNoContent {
+		t.Fatalf("Delete() = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodDelete, path); rec.Code != http.StatusNotFound {
+		t.Errorf("Delete() twice status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// The deleted submission is hidden outside the trash
+	if rec := do(http.MethodGet, path); rec.Code != http.StatusNotFound {
+		t.Errorf("Get(deleted) status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	var list SubmissionListResponse
+	decodeBody(t, do(http.MethodGet, "/submissions"), &list)
+	if list.Total != 1 || list.Submissions[0].ID != queued.ID {
+		t.Errorf("List() = %+v, want only the queued submission", list.Submissions)
+	}
+
+	rec := do(http.MethodGet, "/trash")
+	var trashed SubmissionListResponse
+	decodeBody(t, rec, &trashed)
+	if rec.Code != http.StatusOK || trashed.Total != 1 || trashed.Submissions[0].ID != draft.ID || trashed.Submissions[0].DeletedAt == nil {
+		t.Fatalf("List(trash) = %d %s, want the deleted draft", rec.Code, rec.Body.String())
+	}
+
+	if rec := do(http.MethodPost, "/trash/"+draft.ID.String()+"/restore"); rec.Code != http.StatusOK {
+		t.Fatalf("Restore() = %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := do(http.MethodGet, path); rec.Code != http.StatusOK {
+		t.Errorf("Get(restored) status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec := do(http.MethodPost, "/trash/"+draft.ID.String()+"/restore"); rec.Code != http.StatusNotFound {
+		t.Errorf("Restore() outside the trash status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	// Deleting and restoring change the user's stats, failed attempts don't
+	if len(stats.users) != 2 || stats.users[0] != userID || stats.users[1] != userID {
+		t.Errorf("stats invalidated for %v, want %s twice", stats.users, userID)
+	}
+
+	// Emptying the trash deletes only what is in it
+	do(http.MethodDelete, path)
+	rec = do(http.MethodDelete, "/trash")
+	var emptied EmptyTrashResponse
+	decodeBody(t, rec, &emptied)
+	if rec.Code != http.StatusOK || emptied.Purged != 1 {
+		t.Errorf("Empty() = %d %s, want 1 purged", rec.Code, rec.Body.String())
+	}
+	if _, err := store.Get(ctx, draft.ID); err == nil {
+		t.Error("Empty() kept the deleted submission")
+	}
+	if _, err := store.Get(ctx, queued.ID); err != nil {
+		t.Errorf("Empty() deleted a submission outside the trash: %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/transcription"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+const (
+	// uploadExpiry is how long a presigned upload URL and its token stay
+	// valid
+	uploadExpiry = time.Hour
+
+	// downloadExpiry is how long the download URLs of attachments stay
+	// valid
+	downloadExpiry = 15 * time.Minute
+)
+
+// documentTypes are the non-media content types accepted for direct
+// uploads; audio and video are accepted too
+var documentTypes = []string{"text/plain", "text/markdown", "text/csv", "application/json", "application/pdf"}
+
+// UploadHandler lets clients put large files straight into object storage
+// with a presigned URL, keeping their bytes off the API, and then attach
+// them to a submission. Without object storage its routes return 404.
+type UploadHandler struct {
+	store       UploadStorer
+	submissions SubmissionStorer
+	objects     storage.Storage
+	maxBytes    int64
+}
+
+// NewUploadHandler creates a new upload handler accepting files of up to
+// maxBytes. objects may be nil when no storage is configured.
+func NewUploadHandler(store UploadStorer, submissions SubmissionStorer, objects storage.Storage, maxBytes int64) *UploadHandler {
+	return &UploadHandler{store: store, submissions: submissions, objects: objects, maxBytes: maxBytes}
+}
+
+// PresignRequest declares a file about to be uploaded
+type PresignRequest struct {
+	Filename       string `json:"filename"`
+	ContentType    string `json:"content_type"`
+	SizeBytes      int64  `json:"size_bytes"`
+	ChecksumSHA256 string `json:"checksum_sha256"`
+}
+
+// PresignResponse is where and how to upload the file, and the token that
+// completes the upload
+type PresignResponse struct {
+	Upload      *models.Upload `json:"upload"`
+	UploadURL   string         `json:"upload_url"`
+	Method      string         `json:"method"`
+	UploadToken string         `json:"upload_token"`
+}
+
+// CompleteUploadRequest redeems an upload token
+type CompleteUploadRequest struct {
+	UploadToken  string    `json:"upload_token"`
+	SubmissionID uuid.UUID `json:"submission_id"`
+}
+
+// enabled responds 404 and returns false when no storage is configured
+func (h *UploadHandler) enabled(w http.ResponseWriter) bool {
+	if h.objects == nil {
+		response.NotFound(w, "Direct uploads aren't enabled")
+		return false
+	}
+	return true
+}
+
+// Presign issues a presigned PUT URL for a file of the declared size,
+// type and SHA-256 checksum, and a token to complete the upload with.
+// Both expire after an hour.
+// POST /api/v1/uploads/presign
+func (h *UploadHandler) Presign(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	if !h.enabled(w) {
+		return
+	}
+
+	var req PresignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+
+	fields := map[string]string{}
+	contentType, _, err := mime.ParseMediaType(req.ContentType)
+	if err != nil || !(slices.Contains(documentTypes, contentType) || transcription.IsMediaType(contentType)) {
+		fields["content_type"] = fmt.Sprintf("Must be audio, video or one of %s", strings.Join(documentTypes, ", "))
+	}
+	if req.SizeBytes < 1 || req.SizeBytes > h.maxBytes {
+		fields["size_bytes"] = fmt.Sprintf("Must be between 1 and %d bytes", h.maxBytes)
+	}
+	checksum := strings.ToLower(strings.TrimSpace(req.ChecksumSHA256))
+	if decoded, err := hex.DecodeString(checksum); err != nil || len(decoded) != sha256.Size {
+		fields["checksum_sha256"] = "Must be the hex SHA-256 of the file"
+	}
+	if len(fields) > 0 {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	token, err := models.NewUploadToken()
+	if err != nil {
+		slog.Error("Failed to generate upload token", "error", err)
+		response.InternalServerError(w, "Failed to create upload")
+		return
+	}
+
+	upload, err := h.store.Create(r.Context(), &models.Upload{
+		UserID:         userID,
+		Filename:       cleanFilename(req.Filename),
+		ContentType:    contentType,
+		SizeBytes:      req.SizeBytes,
+		ChecksumSHA256: checksum,
+	}, token, time.Now().Add(uploadExpiry))
+	if err != nil {
+		slog.Error("Failed to create upload", "error", err)
+		response.InternalServerError(w, "Failed to create upload")
+		return
+	}
+
+	uploadURL, err := h.objects.PresignURL(r.Context(), http.MethodPut, upload.Key, uploadExpiry)
+	if err != nil {
+		slog.Error("Failed to presign upload", "upload_id", upload.ID, "error", err)
+		response.InternalServerError(w, "Failed to create upload")
+		return
+	}
+
+	response.Created(w, PresignResponse{
+		Upload:      upload,
+		UploadURL:   uploadURL,
+		Method:      http.MethodPut,
+		UploadToken: token,
+	})
+}
+
+// Complete checks an uploaded object against the size, type and checksum
+// declared when it was presigned, and attaches it to one of the user's
+// submissions. An upload that doesn't match stays pending, so the file can
+// be uploaded again before the token expires.
+// POST /api/v1/uploads/complete
+func (h *UploadHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	if !h.enabled(w) {
+		return
+	}
+
+	var req CompleteUploadRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, "Invalid request body")
+		return
+	}
+	fields := map[string]string{}
+	if req.UploadToken == "" {
+		fields["upload_token"] = "An upload token is required"
+	}
+	if req.SubmissionID == uuid.Nil {
+		fields["submission_id"] = "A submission ID is required"
+	}
+	if len(fields) > 0 {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	upload, err := h.store.GetPending(r.Context(), userID, req.UploadToken)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Upload not found, already completed or expired")
+			return
+		}
+		slog.Error("Failed to get upload", "error", err)
+		response.InternalServerError(w, "Failed to complete upload")
+		return
+	}
+
+	if _, err := h.submissions.GetByID(r.Context(), userID, req.SubmissionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to complete upload")
+		return
+	}
+
+	data, err := h.objects.Get(r.Context(), upload.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			response.Conflict(w, "The file hasn't been uploaded yet")
+			return
+		}
+		slog.Error("Failed to read upload", "upload_id", upload.ID, "error", err)
+		response.InternalServerError(w, "Failed to complete upload")
+		return
+	}
+	if fields := checkUpload(upload, data); fields != nil {
+		response.ValidationError(w, fields)
+		return
+	}
+
+	attached, err := h.store.Attach(r.Context(), userID, upload.ID, req.SubmissionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Upload not found, already completed or expired")
+			return
+		}
+		slog.Error("Failed to attach upload", "upload_id", upload.ID, "error", err)
+		response.InternalServerError(w, "Failed to complete upload")
+		return
+	}
+
+	response.Success(w, attached)
+}
+
+// ListAttachments returns the files attached to a submission, with
+// download URLs valid for 15 minutes
+// GET /api/v1/submissions/{id}/attachments
+func (h *UploadHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.GetUserIDFromContext(r.Context())
+	if err != nil {
+		response.Unauthorized(w, "Unauthorized")
+		return
+	}
+	if !h.enabled(w) {
+		return
+	}
+
+	submissionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		response.BadRequest(w, "Invalid submission ID")
+		return
+	}
+	if _, err := h.submissions.GetByID(r.Context(), userID, submissionID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			response.NotFound(w, "Submission not found")
+			return
+		}
+		slog.Error("Failed to get submission", "error", err)
+		response.InternalServerError(w, "Failed to list attachments")
+		return
+	}
+
+	attachments, err := h.store.ListForSubmission(r.Context(), userID, submissionID)
+	if err != nil {
+		slog.Error("Failed to list attachments", "error", err)
+		response.InternalServerError(w, "Failed to list attachments")
+		return
+	}
+	for i := range attachments {
+		attachments[i].DownloadURL, err = h.objects.PresignURL(r.Context(), http.MethodGet, attachments[i].Key, downloadExpiry)
+		if err != nil {
+			slog.Error("Failed to presign download", "upload_id", attachments[i].ID, "error", err)
+			response.InternalServerError(w, "Failed to list attachments")
+			return
+		}
+	}
+
+	response.Success(w, response.Complete(attachments))
+}
+
+// checkUpload compares an uploaded object with what was declared for it,
+// returning validation errors for any mismatch
+func checkUpload(upload *models.Upload, data []byte) map[string]string {
+	if int64(len(data)) != upload.SizeBytes {
+		return map[string]string{"size_bytes": fmt.Sprintf("The file is %d bytes, not the %d declared", len(data), upload.SizeBytes)}
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != upload.ChecksumSHA256 {
+		return map[string]string{"checksum_sha256": "The file doesn't match the declared checksum"}
+	}
+	if !matchesContentType(data, upload.ContentType) {
+		return map[string]string{"content_type": fmt.Sprintf("The file isn't %s", upload.ContentType)}
+	}
+	return nil
+}
+
+// matchesContentType reports whether the sniffed type of data agrees with
+// the declared type. Text formats all sniff as plain text, so they are
+// only checked for being valid UTF-8 and not markup.
+func matchesContentType(data []byte, declared string) bool {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	switch {
+	case declared == "application/pdf":
+		return sniffed == "application/pdf"
+	case transcription.IsMediaType(declared):
+		return mediaType(data, declared) != ""
+	default:
+		return sniffed == "text/plain" && utf8.Valid(data)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+// newUploadRouter mounts the handler so chi URL parameters resolve
+func newUploadRouter(handler *UploadHandler) chi.Router {
+	r := chi.NewRouter()
+	r.Post("/uploads/presign", handler.Presign)
+	r.Post("/uploads/complete", handler.Complete)
+	r.Get("/submissions/{id}/attachments", handler.ListAttachments)
+	return r
+}
+
+// checksum returns the hex SHA-256 of data
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// putPresigned uploads data to a presigned URL of the local driver
+func putPresigned(t *testing.T, objects *storage.Local, presigned string, data []byte) {
+	t.Helper()
+	u, err := url.Parse(presigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	objects.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, u.RequestURI(), bytes.NewReader(data)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("upload status = %d, want 200: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUploadHandler_Presign(t *testing.T) {
+	userID := uuid.New()
+	valid := PresignRequest{Filename: "report.pdf", ContentType: "application/pdf", SizeBytes: 1024, ChecksumSHA256: checksum([]byte("report"))}
+
+	tests := []struct {
+		name       string
+		modify     func(req *PresignRequest)
+		wantStatus int
+	}{
+		{"presigns", func(req *PresignRequest) {}, http.StatusCreated},
+		{"audio", func(req *PresignRequest) { req.ContentType = "audio/mpeg" }, http.StatusCreated},
+		{"unsupported type", func(req *PresignRequest) { req.ContentType = "text/html" }, http.StatusUnprocessableEntity},
+		{"empty", func(req *PresignRequest) { req.SizeBytes = 0 }, http.StatusUnprocessableEntity},
+		{"too large", func(req *PresignRequest) { req.SizeBytes = 1<<20 + 1 }, http.StatusUnprocessableEntity},
+		{"bad checksum", func(req *PresignRequest) { req.ChecksumSHA256 = "abc" }, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := storage.NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+			handler := NewUploadHandler(memstore.NewUploadStore(), memstore.NewSubmissionStore(), objects, 1<<20)
+			req := valid
+			tt.modify(&req)
+
+			rec := httptest.NewRecorder()
+			newUploadRouter(handler).ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/uploads/presign", req), userID))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusCreated {
+				return
+			}
+
+			var resp PresignResponse
+			decodeBody(t, rec, &resp)
+			if resp.Method != http.MethodPut || resp.UploadToken == "" || resp.Upload.Status != models.UploadPending {
+				t.Errorf("response = %+v, want a pending upload with a token", resp)
+			}
+			if u, err := url.Parse(resp.UploadURL); err != nil || u.Query().Get("signature") == "" {
+				t.Errorf("upload URL = %s, want a presigned URL", resp.UploadURL)
+			}
+		})
+	}
+
+	t.Run("without storage", func(t *testing.T) {
+		handler := NewUploadHandler(memstore.NewUploadStore(), memstore.NewSubmissionStore(), nil, 1<<20)
+		rec := httptest.NewRecorder()
+		newUploadRouter(handler).ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/uploads/presign", valid), userID))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", rec.Code)
+		}
+	})
+}
+
+func TestUploadHandler_Complete(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	notes := []byte("Meeting notes\n\nThe launch is on track.\n")
+	pdf := []byte("%PDF-1.7\n1 0 obj\n<<>>\nendobj\n")
+
+	tests := []struct {
+		name       string
+		declared   PresignRequest
+		uploaded   []byte
+		otherUser  bool
+		wantStatus int
+	}{
+		{"attaches", PresignRequest{ContentType: "text/plain", SizeBytes: int64(len(notes)), ChecksumSHA256: checksum(notes)}, notes, false, http.StatusOK},
+		{"pdf", PresignRequest{ContentType: "application/pdf", SizeBytes: int64(len(pdf)), ChecksumSHA256: checksum(pdf)}, pdf, false, http.StatusOK},
+		{"not uploaded", PresignRequest{ContentType: "text/plain", SizeBytes: int64(len(notes)), ChecksumSHA256: checksum(notes)}, nil, false, http.StatusConflict},
+		{"size mismatch", PresignRequest{ContentType: "text/plain", SizeBytes: int64(len(notes)) + 1, ChecksumSHA256: checksum(notes)}, notes, false, http.StatusUnprocessableEntity},
+		{"checksum mismatch", PresignRequest{ContentType: "text/plain", SizeBytes: int64(len(notes)), ChecksumSHA256: checksum(pdf)}, notes, false, http.StatusUnprocessableEntity},
+		{"type mismatch", PresignRequest{ContentType: "application/pdf", SizeBytes: int64(len(notes)), ChecksumSHA256: checksum(notes)}, notes, false, http.StatusUnprocessableEntity},
+		{"another user's submission", PresignRequest{ContentType: "text/plain", SizeBytes: int64(len(notes)), ChecksumSHA256: checksum(notes)}, notes, true, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objects := storage.NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+			submissions := memstore.NewSubmissionStore()
+			router := newUploadRouter(NewUploadHandler(memstore.NewUploadStore(), submissions, objects, 1<<20))
+
+			owner := userID
+			if tt.otherUser {
+				owner = uuid.New()
+			}
+			submission, _ := submissions.Create(ctx, owner, "Launch update", nil, nil, nil, models.StatusQueued)
+
+			tt.declared.Filename = "upload"
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/uploads/presign", tt.declared), userID))
+			if rec.Code != http.StatusCreated {
+				t.Fatalf("presign status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var presigned PresignResponse
+			decodeBody(t, rec, &presigned)
+			if tt.uploaded != nil {
+				putPresigned(t, objects, presigned.UploadURL, tt.uploaded)
+			}
+
+			complete := CompleteUploadRequest{UploadToken: presigned.UploadToken, SubmissionID: submission.ID}
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/uploads/complete", complete), userID))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var attached models.Upload
+			decodeBody(t, rec, &attached)
+			if attached.Status != models.UploadAttached || attached.SubmissionID == nil || *attached.SubmissionID != submission.ID {
+				t.Errorf("upload = %+v, want it attached to submission %s", attached, submission.ID)
+			}
+
+			// Tokens are redeemed once
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(newJSONRequest(t, http.MethodPost, "/uploads/complete", complete), userID))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("second completion status = %d, want 404", rec.Code)
+			}
+
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/submissions/"+submission.ID.String()+"/attachments", nil), userID))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("list status = %d: %s", rec.Code, rec.Body.String())
+			}
+			var list response.ListResponse[models.Upload]
+			decodeBody(t, rec, &list)
+			if len(list.Data) != 1 || list.Data[0].DownloadURL == "" {
+				t.Fatalf("attachments = %+v, want the upload with a download URL", list.Data)
+			}
+
+			u, _ := url.Parse(list.Data[0].DownloadURL)
+			rec = httptest.NewRecorder()
+			objects.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+			if !bytes.Equal(rec.Body.Bytes(), tt.uploaded) {
+				t.Errorf("downloaded %q, want the uploaded file", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Package links builds the links API resources carry to related
+// resources and actions, so clients follow them instead of putting paths
+// together themselves. Links point at the API version serving the request
+// and are only added for routes that are mounted, so a route that is
+// switched off or not yet deployed is never advertised.
+package links
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Link points at a related resource or an action
+type Link struct {
+	Href string `json:"href"`
+	// Method is set for links that aren't fetched with GET
+	Method string `json:"method,omitempty"`
+}
+
+// Links maps a relation, such as "self", to its link
+type Links map[string]Link
+
+// Builder builds links for the routes mounted on a router
+type Builder struct {
+	routes chi.Routes
+}
+
+// NewBuilder creates a builder for routes. The routes are looked up when
+// links are built, so they may be mounted after the builder is created.
+func NewBuilder(routes chi.Routes) *Builder {
+	return &Builder{routes: routes}
+}
+
+// Submission returns the links of a submission: itself, its analysis,
+// its status history and, while it can still be canceled, the cancel
+// action. A nil builder returns no links.
+func (b *Builder) Submission(r *http.Request, id uuid.UUID, status models.SubmissionStatus) Links {
+	if b == nil {
+		return nil
+	}
+
+	self := apiversion.FromContext(r.Context()).Prefix() + "/submissions/" + id.String()
+	links := Links{}
+	b.add(links, "self", http.MethodGet, self)
+	b.add(links, "analysis", http.MethodGet, self+"/analysis")
+	b.add(links, "events", http.MethodGet, self+"/events")
+	if status.CanTransitionTo(models.StatusCanceled) {
+		b.add(links, "cancel", http.MethodPost, self+"/cancel")
+	}
+	return links
+}
+
+// add sets rel to path if a route serves method on it
+func (b *Builder) add(links Links, rel, method, path string) {
+	if !b.routes.Match(chi.NewRouteContext(), method, path) {
+		return
+	}
+	link := Link{Href: path}
+	if method != http.MethodGet {
+		link.Method = method
+	}
+	links[rel] = link
+}
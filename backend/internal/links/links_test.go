@@ -0,0 +1,64 @@
+package links
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestBuilder_Submission(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+	router := chi.NewRouter()
+	builder := NewBuilder(router)
+
+	// Routes mounted after the builder was created still count
+	for _, version := range apiversion.Versions {
+		router.Route(version.Prefix(), func(r chi.Router) {
+			r.Route("/submissions", func(r chi.Router) {
+				r.Get("/{id}", noop)
+				r.Get("/{id}/analysis", noop)
+				r.Post("/{id}/cancel", noop)
+			})
+		})
+	}
+
+	id := uuid.New()
+	self := "/api/v2/submissions/" + id.String()
+	r := httptest.NewRequest(http.MethodGet, self, nil)
+	r = r.WithContext(apiversion.WithVersion(r.Context(), apiversion.V2))
+
+	got := builder.Submission(r, id, models.StatusQueued)
+	want := Links{
+		"self":     {Href: self},
+		"analysis": {Href: self + "/analysis"},
+		"cancel":   {Href: self + "/cancel", Method: http.MethodPost},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Submission() = %v, want %v", got, want)
+	}
+	for rel, link := range want {
+		if got[rel] != link {
+			t.Errorf("Submission()[%q] = %v, want %v", rel, got[rel], link)
+		}
+	}
+
+	// Without an events route there is no events link
+	if _, ok := got["events"]; ok {
+		t.Errorf("Submission() has an events link for a route that isn't mounted")
+	}
+
+	if got := builder.Submission(r, id, models.StatusCompleted); got["cancel"] != (Link{}) {
+		t.Errorf("Submission() of a completed submission has cancel link %v", got["cancel"])
+	}
+
+	var none *Builder
+	if got := none.Submission(r, id, models.StatusQueued); got != nil {
+		t.Errorf("nil Builder.Submission() = %v, want nil", got)
+	}
+}
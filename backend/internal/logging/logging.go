@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the process-wide minimum log level. It is shared by the
+// application logger and the request logger and can be changed at runtime.
+var Level = new(slog.LevelVar)
+
+// Log output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ParseLevel converts a level name (debug, info, warn, error) to a slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("log level must be one of: debug, info, warn, error")
+	}
+}
+
+// ValidateFormat checks that a log format is supported
+func ValidateFormat(format string) error {
+	switch format {
+	case FormatJSON, FormatText:
+		return nil
+	default:
+		return fmt.Errorf("log format must be one of: json, text")
+	}
+}
+
+// NewHandler creates a JSON or text handler that honours Level
+func NewHandler(w io.Writer, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: Level}
+
+	if format == FormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// Sampler is a slog.Handler that keeps only a fraction of records below
+// warning level. Warnings and errors are always passed through, so failed
+// requests are never dropped.
+type Sampler struct {
+	handler slog.Handler
+	// rate holds the float64 bits of the sampling rate, shared with the
+	// samplers derived by WithAttrs and WithGroup
+	rate *atomic.Uint64
+}
+
+// NewSampler wraps a handler, keeping roughly rate (0 to 1) of the
+// debug and info records that pass Level
+func NewSampler(handler slog.Handler, rate float64) *Sampler {
+	s := &Sampler{handler: handler, rate: new(atomic.Uint64)}
+	s.SetRate(rate)
+	return s
+}
+
+// SetRate changes the sampling rate of the sampler and every sampler
+// derived from it
+func (s *Sampler) SetRate(rate float64) {
+	s.rate.Store(math.Float64bits(rate))
+}
+
+// Rate returns the current sampling rate
+func (s *Sampler) Rate() float64 {
+	return math.Float64frombits(s.rate.Load())
+}
+
+// Enabled reports whether the level passes Level and the wrapped handler
+func (s *Sampler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= Level.Level() && s.handler.Enabled(ctx, level)
+}
+
+// Handle drops a share of low-severity records and forwards the rest
+func (s *Sampler) Handle(ctx context.Context, r slog.Record) error {
+	if rate := s.Rate(); r.Level < slog.LevelWarn && rate < 1 && rand.Float64() >= rate {
+		return nil
+	}
+	return s.handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a sampler wrapping the handler with extra attributes
+func (s *Sampler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Sampler{handler: s.handler.WithAttrs(attrs), rate: s.rate}
+}
+
+// WithGroup returns a sampler wrapping the handler within a group
+func (s *Sampler) WithGroup(name string) slog.Handler {
+	return &Sampler{handler: s.handler.WithGroup(name), rate: s.rate}
+}
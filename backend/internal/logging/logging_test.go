@@ -0,0 +1,166 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    slog.Level
+		wantErr bool
+	}{
+		{name: "debug", want: slog.LevelDebug},
+		{name: "INFO", want: slog.LevelInfo},
+		{name: "warn", want: slog.LevelWarn},
+		{name: "warning", want: slog.LevelWarn},
+		{name: " error ", want: slog.LevelError},
+		{name: "verbose", wantErr: true},
+		{name: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatText} {
+		if err := ValidateFormat(format); err != nil {
+			t.Errorf("ValidateFormat(%q) error = %v", format, err)
+		}
+	}
+
+	if err := ValidateFormat("xml"); err == nil {
+		t.Error("ValidateFormat(\"xml\") expected error, got nil")
+	}
+}
+
+func TestNewHandler_HonoursLevel(t *testing.T) {
+	defer Level.Set(Level.Level())
+
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, FormatJSON))
+
+	Level.Set(slog.LevelWarn)
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("info record logged at warn level: %s", buf.String())
+	}
+
+	Level.Set(slog.LevelDebug)
+	logger.Debug("visible")
+	if !strings.Contains(buf.String(), `"msg":"visible"`) {
+		t.Errorf("debug record not logged after lowering level: %s", buf.String())
+	}
+}
+
+func TestSampler(t *testing.T) {
+	defer Level.Set(Level.Level())
+	Level.Set(slog.LevelDebug)
+
+	tests := []struct {
+		name      string
+		rate      float64
+		level     slog.Level
+		wantCount int
+	}{
+		{name: "rate 1 keeps info", rate: 1, level: slog.LevelInfo, wantCount: 100},
+		{name: "rate 0 drops info", rate: 0, level: slog.LevelInfo, wantCount: 0},
+		{name: "rate 0 keeps warnings", rate: 0, level: slog.LevelWarn, wantCount: 100},
+		{name: "rate 0 keeps errors", rate: 0, level: slog.LevelError, wantCount: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewSampler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), tt.rate))
+
+			for i := 0; i < 100; i++ {
+				logger.Log(context.Background(), tt.level, "request")
+			}
+
+			if got := strings.Count(buf.String(), "msg=request"); got != tt.wantCount {
+				t.Errorf("logged %d records, want %d", got, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestSampler_PartialRate(t *testing.T) {
+	defer Level.Set(Level.Level())
+	Level.Set(slog.LevelDebug)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewSampler(slog.NewTextHandler(&buf, nil), 0.5))
+
+	for i := 0; i < 2000; i++ {
+		logger.Info("request")
+	}
+
+	// Loose bounds keep the test deterministic in practice
+	got := strings.Count(buf.String(), "msg=request")
+	if got < 800 || got > 1200 {
+		t.Errorf("logged %d of 2000 records at rate 0.5, want roughly 1000", got)
+	}
+}
+
+func TestSampler_HonoursLevel(t *testing.T) {
+	defer Level.Set(Level.Level())
+	Level.Set(slog.LevelError)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewSampler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), 1))
+
+	logger.Warn("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("warn record logged at error level: %s", buf.String())
+	}
+}
+
+func TestSampler_WithAttrs(t *testing.T) {
+	defer Level.Set(Level.Level())
+	Level.Set(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	logger := slog.New(NewSampler(slog.NewTextHandler(&buf, nil), 0)).With("service", "api")
+
+	logger.Info("dropped")
+	logger.Error("kept")
+
+	if strings.Contains(buf.String(), "dropped") {
+		t.Errorf("sampling lost after With: %s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "service=api") {
+		t.Errorf("attributes lost after With: %s", buf.String())
+	}
+}
+
+func TestSampler_SetRate(t *testing.T) {
+	defer Level.Set(Level.Level())
+	Level.Set(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	sampler := NewSampler(slog.NewTextHandler(&buf, nil), 0)
+	logger := slog.New(sampler).With("service", "api")
+
+	logger.Info("dropped")
+	sampler.SetRate(1)
+	logger.Info("kept")
+
+	if strings.Contains(buf.String(), "dropped") || !strings.Contains(buf.String(), "kept") {
+		t.Errorf("SetRate() not applied to derived logger: %s", buf.String())
+	}
+}
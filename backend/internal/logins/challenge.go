@@ -0,0 +1,144 @@
+package logins
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+const (
+	// ChallengeTTL is how long a sign-in waits for its code
+	ChallengeTTL = 15 * time.Minute
+
+	// maxAttempts is how many codes may be tried before the sign-in has
+	// to start over, which keeps guessing a six digit code hopeless
+	maxAttempts = 5
+
+	challengeKeyPrefix = "auth:login_challenge:"
+)
+
+var (
+	// ErrNoChallenge is returned for unknown, expired and exhausted
+	// challenges; the user has to sign in again
+	ErrNoChallenge = errors.New("no pending sign-in")
+
+	// ErrInvalidCode is returned when the code doesn't match
+	ErrInvalidCode = errors.New("invalid confirmation code")
+)
+
+// Store keeps pending sign-ins; *cache.Cache implements it
+type Store interface {
+	HSet(ctx context.Context, key string, fields map[string]interface{}, ttl time.Duration) error
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HIncrBy(ctx context.Context, key, field string, n int64) (int64, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Pending is a sign-in waiting for its emailed code
+type Pending struct {
+	UserID      uuid.UUID
+	Fingerprint models.LoginFingerprint
+	// RememberMe carries the login request's choice through to the token
+	RememberMe bool
+}
+
+// Challenges holds sign-ins from unfamiliar fingerprints until the user
+// confirms them with a code sent to their email address
+type Challenges struct {
+	store Store
+}
+
+// NewChallenges creates a challenge store
+func NewChallenges(store Store) *Challenges {
+	return &Challenges{store: store}
+}
+
+// Start holds a sign-in and returns the token identifying it, for the
+// client, and the code to email to the user
+func (c *Challenges) Start(ctx context.Context, pending Pending) (loginToken, code string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("failed to generate login token: %w", err)
+	}
+	loginToken = base64.RawURLEncoding.EncodeToString(raw)
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate confirmation code: %w", err)
+	}
+	code = fmt.Sprintf("%06d", n.Int64())
+
+	err = c.store.HSet(ctx, challengeKeyPrefix+loginToken, map[string]interface{}{
+		"user_id":     pending.UserID.String(),
+		"country":     pending.Fingerprint.Country,
+		"device":      pending.Fingerprint.Device,
+		"remember_me": strconv.FormatBool(pending.RememberMe),
+		"code":        code,
+		"attempts":    0,
+	}, ChallengeTTL)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start login challenge: %w", err)
+	}
+
+	return loginToken, code, nil
+}
+
+// Confirm completes a held sign-in when code matches, and returns it. A
+// sign-in can be confirmed once, and is dropped after too many wrong codes.
+func (c *Challenges) Confirm(ctx context.Context, loginToken, code string) (*Pending, error) {
+	key := challengeKeyPrefix + loginToken
+
+	fields, err := c.store.HGetAll(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login challenge: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, ErrNoChallenge
+	}
+
+	// Count the attempt before comparing, so concurrent guesses can't
+	// share one
+	attempts, err := c.store.HIncrBy(ctx, key, "attempts", 1)
+	if err != nil {
+		return nil, err
+	}
+	if attempts > maxAttempts {
+		c.drop(ctx, key)
+		return nil, ErrNoChallenge
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code), []byte(fields["code"])) != 1 {
+		return nil, ErrInvalidCode
+	}
+	c.drop(ctx, key)
+
+	userID, err := uuid.Parse(fields["user_id"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid login challenge: %w", err)
+	}
+	rememberMe, _ := strconv.ParseBool(fields["remember_me"])
+
+	return &Pending{
+		UserID: userID,
+		Fingerprint: models.LoginFingerprint{
+			Country: fields["country"],
+			Device:  fields["device"],
+		},
+		RememberMe: rememberMe,
+	}, nil
+}
+
+// drop removes a challenge. It expires anyway, so failures are ignored.
+func (c *Challenges) drop(ctx context.Context, key string) {
+	_ = c.store.Delete(ctx, key)
+}
@@ -0,0 +1,125 @@
+package logins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// memStore is an in-memory Store without expiry
+type memStore struct {
+	mu     sync.Mutex
+	hashes map[string]map[string]string
+}
+
+func newMemStore() *memStore {
+	return &memStore{hashes: make(map[string]map[string]string)}
+}
+
+func (s *memStore) HSet(ctx context.Context, key string, fields map[string]interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hashes[key] == nil {
+		s.hashes[key] = make(map[string]string)
+	}
+	for field, value := range fields {
+		s.hashes[key][field] = fmt.Sprint(value)
+	}
+	return nil
+}
+
+func (s *memStore) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fields := make(map[string]string)
+	for field, value := range s.hashes[key] {
+		fields[field] = value
+	}
+	return fields, nil
+}
+
+func (s *memStore) HIncrBy(ctx context.Context, key, field string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hashes[key] == nil {
+		s.hashes[key] = make(map[string]string)
+	}
+	value, _ := strconv.ParseInt(s.hashes[key][field], 10, 64)
+	value += n
+	s.hashes[key][field] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (s *memStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.hashes, key)
+	return nil
+}
+
+func TestChallenges(t *testing.T) {
+	ctx := context.Background()
+	challenges := NewChallenges(newMemStore())
+	pending := Pending{
+		UserID:      uuid.New(),
+		Fingerprint: models.LoginFingerprint{Country: "DE", Device: "Firefox on Windows"},
+		RememberMe:  true,
+	}
+
+	loginToken, code, err := challenges.Start(ctx, pending)
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(code) != 6 {
+		t.Errorf("code = %q, want six digits", code)
+	}
+
+	if _, err := challenges.Confirm(ctx, "unknown", code); !errors.Is(err, ErrNoChallenge) {
+		t.Errorf("Confirm(unknown token) error = %v, want %v", err, ErrNoChallenge)
+	}
+	if _, err := challenges.Confirm(ctx, loginToken, "not-it"); !errors.Is(err, ErrInvalidCode) {
+		t.Errorf("Confirm(wrong code) error = %v, want %v", err, ErrInvalidCode)
+	}
+
+	got, err := challenges.Confirm(ctx, loginToken, code)
+	if err != nil {
+		t.Fatalf("Confirm() error = %v", err)
+	}
+	if *got != pending {
+		t.Errorf("Confirm() = %+v, want %+v", *got, pending)
+	}
+
+	// A code works once
+	if _, err := challenges.Confirm(ctx, loginToken, code); !errors.Is(err, ErrNoChallenge) {
+		t.Errorf("Confirm() again error = %v, want %v", err, ErrNoChallenge)
+	}
+}
+
+func TestChallenges_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	challenges := NewChallenges(newMemStore())
+
+	loginToken, code, _ := challenges.Start(ctx, Pending{UserID: uuid.New()})
+	for range maxAttempts {
+		if _, err := challenges.Confirm(ctx, loginToken, "wrong"); !errors.Is(err, ErrInvalidCode) {
+			t.Fatalf("Confirm(wrong code) error = %v, want %v", err, ErrInvalidCode)
+		}
+	}
+
+	// Out of attempts, even the right code is refused
+	if _, err := challenges.Confirm(ctx, loginToken, code); !errors.Is(err, ErrNoChallenge) {
+		t.Errorf("Confirm() error = %v, want %v", err, ErrNoChallenge)
+	}
+}
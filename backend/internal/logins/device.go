@@ -0,0 +1,109 @@
+// Package logins spots sign-ins from a new country or device and confirms
+// them with a code sent by email.
+package logins
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// maxDeviceLength fits the login_fingerprints.device column
+const maxDeviceLength = 100
+
+// browsers and systems are matched in order, so tokens that other user
+// agents mimic come last: Edge and Opera claim to be Chrome, and Chrome
+// claims to be Safari
+var (
+	browsers = []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	systems = []struct{ token, name string }{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "ChromeOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+// Device names the browser and OS of a user agent, such as "Firefox on
+// Windows". It is coarse on purpose: browser updates don't make a device
+// new. Unrecognised agents, such as scripts, are named by their product.
+func Device(userAgent string) string {
+	browser := match(userAgent, browsers)
+	system := match(userAgent, systems)
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return "Browser on " + system
+	}
+
+	// "curl/8.4.0" is "curl"
+	product, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	product, _, _ = strings.Cut(product, " ")
+	if product == "" {
+		return "Unknown device"
+	}
+	if len(product) > maxDeviceLength {
+		product = product[:maxDeviceLength]
+	}
+	return product
+}
+
+// match returns the name of the first pattern found in userAgent
+func match(userAgent string, patterns []struct{ token, name string }) string {
+	for _, p := range patterns {
+		if strings.Contains(userAgent, p.token) {
+			return p.name
+		}
+	}
+	return ""
+}
+
+// Locator finds the country a request came from
+type Locator interface {
+	// Country returns an ISO 3166 country code, or "" when unknown
+	Country(r *http.Request) string
+}
+
+// HeaderLocator reads the country from a header set by a CDN or load
+// balancer in front of the API, such as Cloudflare's CF-IPCountry. Only
+// use it when clients can't set the header themselves.
+type HeaderLocator string
+
+// Country returns the header's country code, or "" when it is missing,
+// malformed or unknown
+func (h HeaderLocator) Country(r *http.Request) string {
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(string(h))))
+	if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+		return ""
+	}
+	// Cloudflare sends XX when it doesn't know
+	if country == "XX" {
+		return ""
+	}
+	return country
+}
+
+// Fingerprint describes where and on what a request's sign-in happens.
+// With no locator, the country is unknown.
+func Fingerprint(r *http.Request, locator Locator) models.LoginFingerprint {
+	fp := models.LoginFingerprint{Device: Device(r.UserAgent())}
+	if locator != nil {
+		fp.Country = locator.Country(r)
+	}
+	return fp
+}
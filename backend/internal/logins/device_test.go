@@ -0,0 +1,69 @@
+package logins
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestDevice(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0", "Firefox on Windows"},
+		{"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36", "Chrome on macOS"},
+		{"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0", "Edge on Windows"},
+		{"Mozilla/5.0 (iPhone; CPU iPhone OS 17_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Mobile/15E148 Safari/604.1", "Safari on iOS"},
+		{"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Mobile Safari/537.36", "Chrome on Android"},
+		{"Mozilla/5.0 (X11; Linux x86_64)", "Browser on Linux"},
+		{"curl/8.4.0", "curl"},
+		{"python-requests/2.32.3", "python-requests"},
+		{"", "Unknown device"},
+	}
+
+	for _, tt := range tests {
+		if got := Device(tt.userAgent); got != tt.want {
+			t.Errorf("Device(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
+
+func TestHeaderLocator_Country(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"DE", "DE"},
+		{" nz ", "NZ"},
+		{"XX", ""},
+		{"T1", ""},
+		{"USA", ""},
+		{"", ""},
+	}
+
+	locator := HeaderLocator("CF-IPCountry")
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		req.Header.Set("CF-IPCountry", tt.header)
+		if got := locator.Country(req); got != tt.want {
+			t.Errorf("Country(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("User-Agent", "curl/8.4.0")
+	req.Header.Set("CF-IPCountry", "FR")
+
+	if got := Fingerprint(req, HeaderLocator("CF-IPCountry")); got != (models.LoginFingerprint{Country: "FR", Device: "curl"}) {
+		t.Errorf("Fingerprint() = %+v", got)
+	}
+	// Without a locator the country isn't tracked
+	if got := Fingerprint(req, nil); got.Country != "" {
+		t.Errorf("Fingerprint() country = %q, want none", got.Country)
+	}
+}
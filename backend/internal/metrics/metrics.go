@@ -0,0 +1,51 @@
+// Package metrics holds the Prometheus registry the service's instruments
+// are registered with and serves it for scraping. Instruments are those of
+// github.com/prometheus/client_golang.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultDurationBuckets suit latencies in seconds, from 1ms to 10s
+var DefaultDurationBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the registry served by Handler, with the Go runtime and
+// process metrics alongside the service's own
+var Default = newDefault()
+
+func newDefault() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return registry
+}
+
+// Register adds c to registry and returns it. When a collector with the
+// same name and labels is already registered, that one is returned
+// instead, so components built more than once, such as a budget per
+// provider, share their instruments. It panics on any other conflict.
+func Register[C prometheus.Collector](registry prometheus.Registerer, c C) C {
+	if err := registry.Register(c); err != nil {
+		var registered prometheus.AlreadyRegisteredError
+		if errors.As(err, &registered) {
+			if existing, ok := registered.ExistingCollector.(C); ok {
+				return existing
+			}
+		}
+		panic(err)
+	}
+	return c
+}
+
+// Handler serves registry for Prometheus to scrape
+func Handler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
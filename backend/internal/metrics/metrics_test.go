@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRegister_Twice(t *testing.T) {
+	r := prometheus.NewRegistry()
+	newCounter := func() *prometheus.CounterVec {
+		return prometheus.NewCounterVec(prometheus.CounterOpts{Name: "jobs_total", Help: "Jobs."}, []string{"type"})
+	}
+
+	first := Register(r, newCounter())
+	second := Register(r, newCounter())
+	if first != second {
+		t.Error("registering the same counter twice returned a new counter")
+	}
+
+	second.WithLabelValues("analyze").Inc()
+	if got := testutil.ToFloat64(first.WithLabelValues("analyze")); got != 1 {
+		t.Errorf("first counter = %v, want the increment through the second", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a histogram under a counter's name did not panic")
+		}
+	}()
+	Register(r, prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "jobs_total", Help: "Jobs."}, []string{"type"}))
+}
+
+func TestHandler(t *testing.T) {
+	r := prometheus.NewRegistry()
+	Register(r, prometheus.NewCounter(prometheus.CounterOpts{Name: "up", Help: "Up."})).Inc()
+
+	rec := httptest.NewRecorder()
+	Handler(r).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q, want the text exposition format", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "up 1\n") {
+		t.Errorf("body = %q, want the counter value", rec.Body.String())
+	}
+}
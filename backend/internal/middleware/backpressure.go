@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// Saturation reports whether the system is too busy to accept more work,
+// and how long clients should wait before trying again
+type Saturation interface {
+	Saturated(ctx context.Context) (bool, time.Duration, error)
+}
+
+// Backpressure turns requests away with 503 and a Retry-After header
+// while s is saturated. It fails open if saturation can't be checked.
+func Backpressure(s Saturation) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			saturated, retryAfter, err := s.Saturated(r.Context())
+			if err != nil {
+				slog.Warn("Failed to check for backpressure", "error", err)
+			}
+			if saturated {
+				w.Header().Set("Retry-After", strconv.Itoa(int((retryAfter+time.Second-1)/time.Second)))
+				response.ServiceUnavailable(w, "Too much work is queued, please try again later")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeSaturation reports a fixed state
+type fakeSaturation struct {
+	saturated  bool
+	retryAfter time.Duration
+	err        error
+}
+
+func (s fakeSaturation) Saturated(ctx context.Context) (bool, time.Duration, error) {
+	return s.saturated, s.retryAfter, s.err
+}
+
+func TestBackpressure(t *testing.T) {
+	tests := []struct {
+		name           string
+		saturation     fakeSaturation
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{"accepts work", fakeSaturation{}, http.StatusCreated, ""},
+		{"saturated", fakeSaturation{saturated: true, retryAfter: 30 * time.Second}, http.StatusServiceUnavailable, "30"},
+		{"rounds retry up", fakeSaturation{saturated: true, retryAfter: 1500 * time.Millisecond}, http.StatusServiceUnavailable, "2"},
+		{"fails open", fakeSaturation{err: errors.New("redis down")}, http.StatusCreated, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Backpressure(tt.saturation)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/submissions", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
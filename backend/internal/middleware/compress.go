@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Compress gzips responses for clients that accept it. Bodies are held
+// back until minSize bytes are written, and ones that end up smaller are
+// sent as they are, since compressing a small JSON document costs more
+// than it saves. Only text-like content types are compressed. Event
+// streams, and responses flushed before reaching minSize, are passed
+// through so each write reaches the client as soon as it is flushed.
+func Compress(level, minSize int) func(http.Handler) http.Handler {
+	pool := &sync.Pool{New: func() any {
+		gz, err := gzip.NewWriterLevel(nil, level)
+		if err != nil {
+			gz = gzip.NewWriter(nil)
+		}
+		return gz
+	}}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead || !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Add("Vary", "Accept-Encoding")
+			cw := &compressWriter{ResponseWriter: w, pool: pool, minSize: minSize, status: http.StatusOK}
+			defer cw.close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if weight, err := strconv.ParseFloat(q, 64); err == nil && weight == 0 {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressible reports whether a content type is worth compressing
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		return true
+	case strings.HasSuffix(mediaType, "+json"), strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/x-ndjson", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a response until it can tell
+// whether to compress it
+type compressWriter struct {
+	http.ResponseWriter
+	pool    *sync.Pool
+	minSize int
+	status  int
+
+	buf []byte
+	// decided is set once the headers are sent; gz is set when the body
+	// is being compressed
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.decided {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if status >= 100 && status < 200 {
+		cw.ResponseWriter.WriteHeader(status)
+		return
+	}
+	cw.status = status
+	// Bodiless and partial responses are never compressed
+	if status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		cw.passThrough()
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		header := cw.Header()
+		if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+			cw.passThrough()
+		} else if contentType := header.Get("Content-Type"); contentType != "" && !compressible(contentType) {
+			cw.passThrough()
+		}
+	}
+	if cw.decided {
+		if cw.gz != nil {
+			return cw.gz.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush sends what has been written so far. A response flushed before it
+// is big enough to compress is taken to be streaming and isn't compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		cw.passThrough()
+	}
+	if cw.gz != nil {
+		cw.gz.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the headers for the buffered body, compressing it if it is
+// text-like, and then the body itself
+func (cw *compressWriter) decide() error {
+	header := cw.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if !compressible(header.Get("Content-Type")) {
+		return cw.passThrough()
+	}
+
+	cw.decided = true
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", "gzip")
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	cw.gz = cw.pool.Get().(*gzip.Writer)
+	cw.gz.Reset(cw.ResponseWriter)
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.gz.Write(buf)
+	return err
+}
+
+// passThrough sends the headers and anything buffered uncompressed
+func (cw *compressWriter) passThrough() error {
+	cw.decided = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+	if len(cw.buf) == 0 {
+		return nil
+	}
+	buf := cw.buf
+	cw.buf = nil
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// close finishes the response once the handler returns
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		cw.passThrough()
+		return
+	}
+	if cw.gz != nil {
+		cw.gz.Close()
+		cw.gz.Reset(nil)
+		cw.pool.Put(cw.gz)
+		cw.gz = nil
+	}
+}
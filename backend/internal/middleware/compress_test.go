@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"summary": "A long analysis."}`, 100)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		flush          bool
+		wantGzip       bool
+	}{
+		{name: "large json", acceptEncoding: "gzip, deflate", contentType: "application/json", body: large, wantGzip: true},
+		{name: "sniffed text", acceptEncoding: "gzip", body: large, wantGzip: true},
+		{name: "tiny json", acceptEncoding: "gzip", contentType: "application/json", body: `{"ok": true}`},
+		{name: "not accepted", acceptEncoding: "br", contentType: "application/json", body: large},
+		{name: "refused", acceptEncoding: "gzip;q=0, identity", contentType: "application/json", body: large},
+		{name: "binary", acceptEncoding: "gzip", contentType: "application/octet-stream", body: large},
+		{name: "event stream", acceptEncoding: "gzip", contentType: "text/event-stream", body: large},
+		{name: "flushed early", acceptEncoding: "gzip", contentType: "application/x-ndjson", body: large, flush: true},
+		{name: "not modified", acceptEncoding: "gzip", status: http.StatusNotModified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := Compress(5, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.contentType != "" {
+					w.Header().Set("Content-Type", tt.contentType)
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				if tt.flush {
+					io.WriteString(w, "{}\n")
+					w.(http.Flusher).Flush()
+				}
+				io.WriteString(w, tt.body)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/submissions", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			wantBody := tt.body
+			if tt.flush {
+				wantBody = "{}\n" + tt.body
+			}
+			body := rec.Body.String()
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Fatalf("compressed = %v, want %v", got, tt.wantGzip)
+			}
+			if tt.wantGzip {
+				gz, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, err := io.ReadAll(gz)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body = string(data)
+			}
+			if body != wantBody {
+				t.Errorf("body = %.40q..., want %.40q...", body, wantBody)
+			}
+			if tt.status != 0 && rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-chi/cors"
+)
+
+// CORSOptions configures the CORS middleware
+type CORSOptions struct {
+	// AllowedOrigins holds exact origins and wildcard subdomain patterns
+	// (e.g. https://*.example.com)
+	AllowedOrigins []string
+
+	// Origins, when set, is used instead of AllowedOrigins so the list can
+	// be replaced while serving
+	Origins *OriginList
+
+	// AllowAll reflects any request origin (development only)
+	AllowAll bool
+
+	// AllowOrigin, when set, allows further origins depending on the
+	// request, such as organizations' own domains
+	AllowOrigin func(r *http.Request, origin string) bool
+
+	// AllowCredentials controls the Access-Control-Allow-Credentials header
+	AllowCredentials bool
+
+	// ExposedHeaders lists response headers readable by the browser
+	ExposedHeaders []string
+}
+
+// CORS creates a CORS middleware from the given options
+func CORS(opts CORSOptions) func(http.Handler) http.Handler {
+	matcher := opts.Origins
+	if matcher == nil {
+		matcher = NewOriginList(opts.AllowedOrigins)
+	}
+
+	return cors.Handler(cors.Options{
+		// Using AllowOriginFunc makes the library echo the request origin
+		// instead of "*", which browsers require when credentials are allowed
+		AllowOriginFunc: func(r *http.Request, origin string) bool {
+			return opts.AllowAll || matcher.Match(origin) || (opts.AllowOrigin != nil && opts.AllowOrigin(r, origin))
+		},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-Modified-Since", "If-None-Match", "X-API-Key", "X-CSRF-Token"},
+		ExposedHeaders:   opts.ExposedHeaders,
+		AllowCredentials: opts.AllowCredentials,
+		MaxAge:           300,
+	})
+}
+
+// OriginList is an OriginMatcher that can be replaced at runtime
+type OriginList struct {
+	matcher atomic.Pointer[OriginMatcher]
+}
+
+// NewOriginList creates a list matching the given origin patterns
+func NewOriginList(patterns []string) *OriginList {
+	l := &OriginList{}
+	l.Set(patterns)
+	return l
+}
+
+// Set replaces the origin patterns
+func (l *OriginList) Set(patterns []string) {
+	l.matcher.Store(NewOriginMatcher(patterns))
+}
+
+// Match reports whether the origin is allowed by the current patterns
+func (l *OriginList) Match(origin string) bool {
+	return l.matcher.Load().Match(origin)
+}
+
+// OriginMatcher matches request origins against exact and wildcard patterns
+type OriginMatcher struct {
+	exact     map[string]struct{}
+	wildcards []wildcardOrigin
+}
+
+// wildcardOrigin is a parsed https://*.example.com style pattern
+type wildcardOrigin struct {
+	scheme string
+	suffix string // ".example.com" (including any port)
+}
+
+// NewOriginMatcher builds a matcher from a list of origin patterns.
+// Patterns are compared case-insensitively.
+func NewOriginMatcher(patterns []string) *OriginMatcher {
+	m := &OriginMatcher{exact: make(map[string]struct{})}
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+
+		scheme, host, ok := strings.Cut(pattern, "://")
+		if ok && strings.HasPrefix(host, "*.") {
+			m.wildcards = append(m.wildcards, wildcardOrigin{
+				scheme: scheme,
+				suffix: host[1:],
+			})
+			continue
+		}
+
+		m.exact[pattern] = struct{}{}
+	}
+
+	return m
+}
+
+// Match reports whether the origin is allowed
+func (m *OriginMatcher) Match(origin string) bool {
+	origin = strings.ToLower(origin)
+
+	if _, ok := m.exact[origin]; ok {
+		return true
+	}
+
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok {
+		return false
+	}
+
+	for _, w := range m.wildcards {
+		if scheme != w.scheme || !strings.HasSuffix(host, w.suffix) {
+			continue
+		}
+
+		// The wildcard covers one or more non-empty subdomain labels
+		sub := strings.TrimSuffix(host, w.suffix)
+		if isSubdomain(sub) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSubdomain checks that s is a dot-separated list of valid DNS labels
+func isSubdomain(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, label := range strings.Split(s, ".") {
+		if label == "" || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for i := 0; i < len(label); i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOriginMatcher_Match(t *testing.T) {
+	matcher := NewOriginMatcher([]string{
+		"http://localhost:3000",
+		"https://*.example.com",
+		"https://*.staging.example.org:8443",
+	})
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{
+			name:   "exact match",
+			origin: "http://localhost:3000",
+			want:   true,
+		},
+		{
+			name:   "exact match is case-insensitive",
+			origin: "HTTP://LOCALHOST:3000",
+			want:   true,
+		},
+		{
+			name:   "exact origin with different port",
+			origin: "http://localhost:4000",
+			want:   false,
+		},
+		{
+			name:   "single subdomain",
+			origin: "https://app.example.com",
+			want:   true,
+		},
+		{
+			name:   "nested subdomain",
+			origin: "https://eu.app.example.com",
+			want:   true,
+		},
+		{
+			name:   "bare domain is not a subdomain",
+			origin: "https://example.com",
+			want:   false,
+		},
+		{
+			name:   "empty label",
+			origin: "https://.example.com",
+			want:   false,
+		},
+		{
+			name:   "scheme mismatch",
+			origin: "http://app.example.com",
+			want:   false,
+		},
+		{
+			name:   "lookalike domain",
+			origin: "https://app.evilexample.com",
+			want:   false,
+		},
+		{
+			name:   "suffix trick",
+			origin: "https://app.example.com.evil.io",
+			want:   false,
+		},
+		{
+			name:   "wildcard with port",
+			origin: "https://pr-42.staging.example.org:8443",
+			want:   true,
+		},
+		{
+			name:   "wildcard with missing port",
+			origin: "https://pr-42.staging.example.org",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matcher.Match(tt.origin); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	tests := []struct {
+		name            string
+		opts            CORSOptions
+		origin          string
+		wantAllowOrigin string
+		wantCredentials string
+	}{
+		{
+			name: "allowed wildcard origin is echoed",
+			opts: CORSOptions{
+				AllowedOrigins:   []string{"https://*.example.com"},
+				AllowCredentials: true,
+			},
+			origin:          "https://app.example.com",
+			wantAllowOrigin: "https://app.example.com",
+			wantCredentials: "true",
+		},
+		{
+			name: "disallowed origin",
+			opts: CORSOptions{
+				AllowedOrigins: []string{"https://*.example.com"},
+			},
+			origin:          "https://evil.io",
+			wantAllowOrigin: "",
+		},
+		{
+			name: "allow all without credentials",
+			opts: CORSOptions{
+				AllowAll: true,
+			},
+			origin:          "https://anything.test",
+			wantAllowOrigin: "https://anything.test",
+			wantCredentials: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Origin", tt.origin)
+			rec := httptest.NewRecorder()
+
+			CORS(tt.opts)(next).ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantAllowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantAllowOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCredentials)
+			}
+		})
+	}
+}
+
+func TestCORS_OriginListUpdates(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	origins := NewOriginList([]string{"https://app.example.com"})
+	handler := CORS(CORSOptions{Origins: origins})(next)
+
+	allowed := func(origin string) bool {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+
+	if !allowed("https://app.example.com") || allowed("https://new.example.com") {
+		t.Fatal("CORS() did not apply the initial origin list")
+	}
+
+	origins.Set([]string{"https://new.example.com"})
+
+	if allowed("https://app.example.com") || !allowed("https://new.example.com") {
+		t.Error("CORS() did not apply the replaced origin list")
+	}
+}
+
+func TestCORS_AllowOrigin(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := CORS(CORSOptions{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowOrigin: func(r *http.Request, origin string) bool {
+			return origin == "https://"+r.Host
+		},
+	})(next)
+
+	allowed := func(host, origin string) bool {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		req.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+
+	if !allowed("acme.example.com", "https://app.example.com") {
+		t.Error("CORS() refused an allowed origin")
+	}
+	if !allowed("acme.example.com", "https://acme.example.com") {
+		t.Error("CORS() refused an origin AllowOrigin allows")
+	}
+	if allowed("acme.example.com", "https://globex.example.com") {
+		t.Error("CORS() allowed an origin neither allows")
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// Locale picks the language of error messages from the request's
+// Accept-Language header. It is announced in Content-Language, where the
+// response package reads it back, so it must run before anything that
+// writes errors.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", response.MatchLocale(r.Header.Get("Accept-Language")).String())
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r)
+	})
+}
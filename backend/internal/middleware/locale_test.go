@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+func TestLocale(t *testing.T) {
+	handler := Locale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.Unauthorized(w, "Missing authorization header")
+	}))
+
+	tests := []struct {
+		acceptLanguage string
+		wantLanguage   string
+		wantBody       string
+	}{
+		{"", "en", `{"error":"Missing authorization header"}`},
+		{"es-ES,es;q=0.9,en;q=0.8", "es", `{"error":"Falta la cabecera de autorización"}`},
+		{"fr", "fr", `{"error":"En-tête d'autorisation manquant"}`},
+		{"ja", "en", `{"error":"Missing authorization header"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptLanguage, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Language"); got != tt.wantLanguage {
+				t.Errorf("Content-Language = %q, want %q", got, tt.wantLanguage)
+			}
+			if got := rec.Header().Get("Vary"); got != "Accept-Language" {
+				t.Errorf("Vary = %q, want Accept-Language", got)
+			}
+			if got := rec.Body.String(); got != tt.wantBody+"\n" {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+		})
+	}
+}
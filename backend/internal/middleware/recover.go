@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// reportedHeaders are the request headers sent with error reports; others,
+// such as Authorization and Cookie, can carry credentials
+var reportedHeaders = []string{"Accept", "API-Version", "Content-Length", "Content-Type", "Origin", "User-Agent"}
+
+// responsePackage prefixes the functions of the response package
+const responsePackage = "github.com/sfumato00/content-analyzer/internal/response."
+
+// maxReportedBody bounds how much of a 5xx response is kept to find its
+// error message
+const maxReportedBody = 1024
+
+// Recover turns panics into 500 responses and reports them, and reports
+// the 5xx responses handlers send, with the request, the user and where
+// it happened. 503s are left out, since the API sends them on purpose
+// while shedding load. It replaces chi's Recoverer, so it must run after
+// middleware.RequestID for reports to carry the request ID.
+func Recover(reporter errreport.Reporter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, scope := errreport.NewContext(r.Context())
+			r = r.WithContext(ctx)
+			sw := &statusWriter{ResponseWriter: w}
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					if rec == http.ErrAbortHandler {
+						// Aborting a response isn't an error
+						panic(rec)
+					}
+					stack := errreport.PanicStack()
+					slog.Error("Handler panicked", "panic", rec, "method", r.Method, "path", r.URL.Path, "request_id", middleware.GetReqID(ctx))
+					reporter.Report(ctx, requestEvent(r, scope, errreport.Event{
+						Level:   errreport.LevelFatal,
+						Type:    "panic",
+						Message: fmt.Sprint(rec),
+						Stack:   stack,
+					}))
+					if sw.status == 0 {
+						response.InternalServerError(w, "Internal server error")
+					}
+					return
+				}
+
+				if sw.status >= 500 && sw.status != http.StatusServiceUnavailable {
+					reporter.Report(ctx, requestEvent(r, scope, errreport.Event{
+						Level:   errreport.LevelError,
+						Type:    http.StatusText(sw.status),
+						Message: sw.message(),
+						Stack:   sw.stack,
+					}))
+				}
+			}()
+
+			next.ServeHTTP(sw, r)
+		})
+	}
+}
+
+// requestEvent fills in what an event knows about the request
+func requestEvent(r *http.Request, scope *errreport.Scope, event errreport.Event) errreport.Event {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	headers := make(map[string]string)
+	for _, name := range reportedHeaders {
+		if value := r.Header.Get(name); value != "" {
+			headers[name] = value
+		}
+	}
+
+	event.Request = &errreport.Request{
+		Method:  r.Method,
+		URL:     scheme + "://" + r.Host + r.URL.Path,
+		Headers: headers,
+	}
+	event.UserID = scope.UserID()
+	event.Tags = map[string]string{"route": routePattern(r)}
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		event.Tags["request_id"] = id
+	}
+	return event
+}
+
+// routePattern returns the route a request matched, such as
+// /api/v1/submissions/{id}, so reports of one route group together
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return r.URL.Path
+}
+
+// statusWriter records the status of a response and, for 5xx responses,
+// where it was written and the start of its body
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	stack  []errreport.Frame
+	body   []byte
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if sw.status == 0 && status >= 200 {
+		sw.status = status
+		if status >= 500 {
+			sw.stack = errreport.Stack(1)
+			// Start at the handler rather than the response helper it called
+			for len(sw.stack) > 1 && strings.HasPrefix(sw.stack[0].Function, responsePackage) {
+				sw.stack = sw.stack[1:]
+			}
+		}
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	if sw.status >= 500 && len(sw.body) < maxReportedBody {
+		sw.body = append(sw.body, p[:min(len(p), maxReportedBody-len(sw.body))]...)
+	}
+	return sw.ResponseWriter.Write(p)
+}
+
+func (sw *statusWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// message returns the error a 5xx response gave, or its status text
+func (sw *statusWriter) message() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(sw.body, &body) == nil && body.Error != "" {
+		return body.Error
+	}
+	return http.StatusText(sw.status)
+}
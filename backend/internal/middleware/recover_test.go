@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/response"
+)
+
+// recordingReporter records the events it is sent
+type recordingReporter struct {
+	events []errreport.Event
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event errreport.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingReporter) Close(ctx context.Context) error { return nil }
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantStatus  int
+		wantType    string
+		wantMessage string
+	}{
+		{
+			name:       "success",
+			handler:    func(w http.ResponseWriter, r *http.Request) { response.Success(w, nil) },
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "panic",
+			handler:     func(w http.ResponseWriter, r *http.Request) { panic("nil map") },
+			wantStatus:  http.StatusInternalServerError,
+			wantType:    "panic",
+			wantMessage: "nil map",
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				response.InternalServerError(w, "Failed to create submission")
+			},
+			wantStatus:  http.StatusInternalServerError,
+			wantType:    "Internal Server Error",
+			wantMessage: "Failed to create submission",
+		},
+		{
+			name:       "shedding load",
+			handler:    func(w http.ResponseWriter, r *http.Request) { response.ServiceUnavailable(w, "Too busy") },
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reporter := &recordingReporter{}
+			r := chi.NewRouter()
+			r.Use(middleware.RequestID)
+			r.Use(Recover(reporter))
+			r.With(func(next http.Handler) http.Handler {
+				// Stands in for the auth middleware
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					errreport.SetUser(r.Context(), "user-1")
+					next.ServeHTTP(w, r)
+				})
+			}).Post("/api/v1/submissions/{id}/submit", tt.handler)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/submissions/42/submit", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantType == "" {
+				if len(reporter.events) != 0 {
+					t.Errorf("reported %+v, want nothing", reporter.events)
+				}
+				return
+			}
+			if len(reporter.events) != 1 {
+				t.Fatalf("reported %d events, want 1", len(reporter.events))
+			}
+
+			event := reporter.events[0]
+			if event.Type != tt.wantType || event.Message != tt.wantMessage {
+				t.Errorf("event = %s %q, want %s %q", event.Type, event.Message, tt.wantType, tt.wantMessage)
+			}
+			if event.UserID != "user-1" || event.Tags["request_id"] == "" || event.Tags["route"] != "/api/v1/submissions/{id}/submit" {
+				t.Errorf("event user %q and tags %v, want the user, request ID and route", event.UserID, event.Tags)
+			}
+			if _, ok := event.Request.Headers["Authorization"]; ok {
+				t.Error("event carries the Authorization header")
+			}
+			if len(event.Stack) == 0 || !strings.Contains(event.Stack[0].Function, "middleware.TestRecover") {
+				t.Errorf("stack starts at %v, want the handler", event.Stack)
+			}
+		})
+	}
+}
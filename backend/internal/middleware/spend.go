@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+)
+
+// SpendLimit reports the spend budget a user's analyses are over, if any;
+// *spend.Guard implements it
+type SpendLimit interface {
+	Check(ctx context.Context, userID uuid.UUID) (*spend.Exceeded, error)
+}
+
+// SpendBudget refuses requests from users over a spend budget with 402,
+// naming the budget and when it resets. It fails open if the budget
+// can't be checked.
+func SpendBudget(limit SpendLimit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, err := auth.GetUserIDFromContext(r.Context())
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			exceeded, err := limit.Check(r.Context(), userID)
+			if err != nil {
+				slog.Warn("Failed to check spend budget", "user_id", userID, "error", err)
+			}
+			if exceeded != nil {
+				if wait := time.Until(exceeded.ResetsAt.Time); wait > 0 {
+					w.Header().Set("Retry-After", strconv.Itoa(int((wait+time.Second-1)/time.Second)))
+				}
+				response.Error(w, http.StatusPaymentRequired, exceeded.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
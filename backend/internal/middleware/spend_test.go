@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// fakeSpendLimit reports a fixed budget
+type fakeSpendLimit struct {
+	exceeded *spend.Exceeded
+	err      error
+}
+
+func (l fakeSpendLimit) Check(ctx context.Context, userID uuid.UUID) (*spend.Exceeded, error) {
+	return l.exceeded, l.err
+}
+
+func TestSpendBudget(t *testing.T) {
+	exceeded := &spend.Exceeded{Scope: spend.ScopeGlobal, Period: spend.PeriodDaily, LimitMicros: 25_000_000, ResetsAt: timestamp.New(time.Now().Add(time.Hour))}
+
+	tests := []struct {
+		name       string
+		limit      fakeSpendLimit
+		wantStatus int
+	}{
+		{"under budget", fakeSpendLimit{}, http.StatusCreated},
+		{"over budget", fakeSpendLimit{exceeded: exceeded}, http.StatusPaymentRequired},
+		{"fails open", fakeSpendLimit{err: errors.New("database down")}, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := SpendBudget(tt.limit)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/submissions", nil)
+			req = req.WithContext(context.WithValue(req.Context(), auth.UserIDKey, uuid.New()))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.limit.exceeded != nil {
+				if !strings.Contains(rec.Body.String(), "the daily spend budget ($25.00) is used up") {
+					t.Errorf("body = %s, want the budget named", rec.Body.String())
+				}
+				if got := rec.Header().Get("Retry-After"); got != "3600" {
+					t.Errorf("Retry-After = %q, want 3600", got)
+				}
+			}
+		})
+	}
+}
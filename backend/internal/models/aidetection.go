@@ -0,0 +1,89 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// AIDetectionLabel summarizes an AI detection score
+type AIDetectionLabel string
+
+const (
+	LikelyHuman     AIDetectionLabel = "likely_human"
+	LikelyAI        AIDetectionLabel = "likely_ai"
+	UncertainOrigin AIDetectionLabel = "uncertain"
+)
+
+// DetectionConfidence is how far an AI detection score can be trusted
+type DetectionConfidence string
+
+const (
+	DetectionLow    DetectionConfidence = "low"
+	DetectionMedium DetectionConfidence = "medium"
+	DetectionHigh   DetectionConfidence = "high"
+)
+
+// AIDetection estimates how likely content is to be machine-generated
+type AIDetection struct {
+	// Score is the estimated probability, from 0 to 1, that the content
+	// was machine-generated
+	Score      float64             `json:"score"`
+	Label      AIDetectionLabel    `json:"label"`
+	Confidence DetectionConfidence `json:"confidence"`
+	// Uncertainty explains what limits the estimate
+	Uncertainty string `json:"uncertainty"`
+	// Explanation is the model's reasoning about the text
+	Explanation string             `json:"explanation"`
+	Signals     AIDetectionSignals `json:"signals"`
+}
+
+// AIDetectionSignals are the measurements behind an AI detection score.
+// A signal is nil when it couldn't be measured.
+type AIDetectionSignals struct {
+	Words int `json:"words"`
+	// Burstiness is the coefficient of variation of sentence lengths;
+	// people vary their sentences more than models do
+	Burstiness *float64 `json:"burstiness"`
+	// Perplexity is how predictable the text is to a reference language
+	// model; generated text tends to be more predictable
+	Perplexity *float64 `json:"perplexity"`
+	// ModelProbability is the analysis model's own judgment
+	ModelProbability *float64 `json:"model_probability"`
+}
+
+// sealAIDetection encodes an analysis' AI detection for the ai_detection
+// column, encrypting it when enabled
+func (s *SubmissionStore) sealAIDetection(ctx context.Context, analysis record, detection *AIDetection) (*string, error) {
+	if detection == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(detection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode AI detection: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisAIDetection))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt AI detection: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openAIDetection decodes a value read from the ai_detection column
+func (s *SubmissionStore) openAIDetection(ctx context.Context, analysis record, value *string) (*AIDetection, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisAIDetection))
+	if err != nil {
+		return nil, err
+	}
+
+	var detection AIDetection
+	if err := json.Unmarshal([]byte(opened), &detection); err != nil {
+		return nil, err
+	}
+	return &detection, nil
+}
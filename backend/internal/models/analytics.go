@@ -0,0 +1,383 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// TimeBucket is the granularity of an analytics time series
+type TimeBucket string
+
+const (
+	BucketDay   TimeBucket = "day"
+	BucketWeek  TimeBucket = "week"
+	BucketMonth TimeBucket = "month"
+)
+
+// ParseTimeBucket validates a bucket name coming from user input
+func ParseTimeBucket(s string) (TimeBucket, error) {
+	switch b := TimeBucket(s); b {
+	case BucketDay, BucketWeek, BucketMonth:
+		return b, nil
+	default:
+		return "", fmt.Errorf("interval must be one of: day, week, month")
+	}
+}
+
+// Duration returns the approximate length of a bucket, used to bound series size
+func (b TimeBucket) Duration() time.Duration {
+	switch b {
+	case BucketWeek:
+		return 7 * 24 * time.Hour
+	case BucketMonth:
+		return 30 * 24 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// SentimentPoint is a single bucket in a sentiment time series
+type SentimentPoint struct {
+	// Bucket keeps the offset of the zone it starts in, unlike other
+	// times, so clients can tell which local day it is
+	Bucket        time.Time `json:"bucket"`
+	Count         int       `json:"count"`
+	AverageScore  *float64  `json:"average_score"`
+	MovingAverage *float64  `json:"moving_average"`
+	Change        *float64  `json:"change"`
+	Positive      int       `json:"positive"`
+	Neutral       int       `json:"neutral"`
+	Negative      int       `json:"negative"`
+}
+
+// AnalyticsStore runs aggregate queries over submissions and analyses
+type AnalyticsStore struct {
+	db      *pgxpool.Pool
+	replica ReadRouter
+}
+
+// NewAnalyticsStore creates a new analytics store
+func NewAnalyticsStore(db *pgxpool.Pool) *AnalyticsStore {
+	return &AnalyticsStore{db: db}
+}
+
+// WithReplica routes the store's queries through router and returns the
+// store. Every analytics query is read-only.
+func (s *AnalyticsStore) WithReplica(router ReadRouter) *AnalyticsStore {
+	s.replica = router
+	return s
+}
+
+// SentimentTrend aggregates the sentiment scores of a user's analyses into a
+// gap-free time series between from (inclusive) and to (exclusive).
+// Buckets start at midnight in loc, and weeks on Monday.
+// Empty buckets are returned with a zero count and null averages.
+func (s *AnalyticsStore) SentimentTrend(ctx context.Context, userID uuid.UUID, from, to time.Time, bucket TimeBucket, loc *time.Location) ([]SentimentPoint, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]SentimentPoint, error) {
+		return s.sentimentTrend(ctx, userSubmissions, userID, from, to, bucket, loc)
+	})
+}
+
+// OrgSentimentTrend is SentimentTrend over the submissions of everyone who
+// is currently a member of an organization
+func (s *AnalyticsStore) OrgSentimentTrend(ctx context.Context, orgID uuid.UUID, from, to time.Time, bucket TimeBucket, loc *time.Location) ([]SentimentPoint, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]SentimentPoint, error) {
+		return s.sentimentTrend(ctx, orgSubmissions, orgID, from, to, bucket, loc)
+	})
+}
+
+// Conditions selecting the submissions a sentiment trend covers, by the
+// user or organization ID in $1
+const (
+	userSubmissions = `s.user_id = $1`
+	orgSubmissions  = `s.user_id IN (SELECT user_id FROM organization_members WHERE org_id = $1)`
+)
+
+// sentimentTrend runs one attempt of SentimentTrend over the submissions
+// matching scope
+func (s *AnalyticsStore) sentimentTrend(ctx context.Context, scope string, id uuid.UUID, from, to time.Time, bucket TimeBucket, loc *time.Location) ([]SentimentPoint, error) {
+	// Buckets are truncated in local time and turned back into instants,
+	// so a bucket spanning a DST change is an hour shorter or longer.
+	// Every conversion names its zone, so the session's TimeZone setting
+	// never matters: from and to are instants, and created_at holds UTC
+	// without a zone.
+	// The moving average spans the current bucket and the 6 before it
+	// (a week of daily buckets)
+	query := fmt.Sprintf(`
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($4, $2::timestamptz AT TIME ZONE $5),
+				date_trunc($4, ($3::timestamptz - interval '1 microsecond') AT TIME ZONE $5),
+				('1 ' || $4)::interval
+			) AS bucket
+		),
+		scores AS (
+			SELECT date_trunc($4, a.created_at AT TIME ZONE 'UTC' AT TIME ZONE $5) AS bucket, a.sentiment, a.sentiment_score
+			FROM analyses a
+			JOIN submissions s ON s.id = a.submission_id
+			WHERE %s
+			  AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+			  AND a.created_at >= $2::timestamptz AT TIME ZONE 'UTC'
+			  AND a.created_at < $3::timestamptz AT TIME ZONE 'UTC'
+			  AND a.sentiment_score IS NOT NULL
+		),
+		aggregated AS (
+			SELECT
+				b.bucket,
+				COUNT(sc.sentiment_score) AS count,
+				AVG(sc.sentiment_score) AS average_score,
+				COUNT(*) FILTER (WHERE sc.sentiment = 'positive') AS positive,
+				COUNT(*) FILTER (WHERE sc.sentiment = 'neutral') AS neutral,
+				COUNT(*) FILTER (WHERE sc.sentiment = 'negative') AS negative
+			FROM buckets b
+			LEFT JOIN scores sc ON sc.bucket = b.bucket
+			GROUP BY b.bucket
+		)
+		SELECT
+			bucket AT TIME ZONE $5,
+			count,
+			average_score,
+			AVG(average_score) OVER (ORDER BY bucket ROWS BETWEEN 6 PRECEDING AND CURRENT ROW) AS moving_average,
+			average_score - LAG(average_score) OVER (ORDER BY bucket) AS change,
+			positive,
+			neutral,
+			negative
+		FROM aggregated
+		ORDER BY bucket
+	`, scope)
+
+	rows, err := readPool(s.db, s.replica).Query(ctx, query, id, from, to, string(bucket), loc.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sentiment trend: %w", err)
+	}
+	defer rows.Close()
+
+	points := []SentimentPoint{}
+	for rows.Next() {
+		var p SentimentPoint
+		if err := rows.Scan(
+			&p.Bucket,
+			&p.Count,
+			&p.AverageScore,
+			&p.MovingAverage,
+			&p.Change,
+			&p.Positive,
+			&p.Neutral,
+			&p.Negative,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan sentiment point: %w", err)
+		}
+		p.Bucket = p.Bucket.In(loc)
+		points = append(points, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sentiment trend: %w", err)
+	}
+
+	return points, nil
+}
+
+// LabelCount is how many of a user's submissions a taxonomy label applies to
+type LabelCount struct {
+	Label             string  `json:"label"`
+	Count             int     `json:"count"`
+	Share             float64 `json:"share"`
+	AverageConfidence float64 `json:"average_confidence"`
+}
+
+// LabelDistribution counts the taxonomy labels of a user's submissions
+// analyzed between from (inclusive) and to (exclusive), most frequent
+// first. Share is the fraction of the classified submissions in the range
+// with the label; a submission can have several labels.
+func (s *AnalyticsStore) LabelDistribution(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]LabelCount, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]LabelCount, error) {
+		return s.labelDistribution(ctx, userID, from, to)
+	})
+}
+
+// labelDistribution runs one attempt of LabelDistribution
+func (s *AnalyticsStore) labelDistribution(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]LabelCount, error) {
+	query := `
+		WITH labels AS (
+			SELECT l.submission_id, l.label, l.confidence
+			FROM submission_labels l
+			JOIN submissions s ON s.id = l.submission_id
+			WHERE s.user_id = $1
+			  AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+			  AND l.created_at >= $2
+			  AND l.created_at < $3
+		)
+		SELECT
+			MIN(label) AS label,
+			COUNT(*) AS count,
+			COUNT(*)::float8 / (SELECT COUNT(DISTINCT submission_id) FROM labels) AS share,
+			AVG(confidence) AS average_confidence
+		FROM labels
+		GROUP BY LOWER(label)
+		ORDER BY count DESC, LOWER(label)
+	`
+
+	rows, err := readPool(s.db, s.replica).Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query label distribution: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []LabelCount{}
+	for rows.Next() {
+		var c LabelCount
+		if err := rows.Scan(&c.Label, &c.Count, &c.Share, &c.AverageConfidence); err != nil {
+			return nil, fmt.Errorf("failed to scan label count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read label distribution: %w", err)
+	}
+
+	return counts, nil
+}
+
+// UserStats totals a user's submissions and analyses
+type UserStats struct {
+	Submissions int `json:"submissions"`
+	// ByStatus counts submissions in each status that has any
+	ByStatus         map[SubmissionStatus]int `json:"by_status"`
+	Analyses         int                      `json:"analyses"`
+	Positive         int                      `json:"positive"`
+	Neutral          int                      `json:"neutral"`
+	Negative         int                      `json:"negative"`
+	AverageSentiment *float64                 `json:"average_sentiment"`
+	PromptTokens     int64                    `json:"prompt_tokens"`
+	OutputTokens     int64                    `json:"output_tokens"`
+	CostMicros       int64                    `json:"cost_micros"`
+	LastSubmittedAt  *timestamp.Time          `json:"last_submitted_at"`
+}
+
+// UserStats totals a user's submissions and their analyses, leaving out
+// quarantined submissions
+func (s *AnalyticsStore) UserStats(ctx context.Context, userID uuid.UUID) (*UserStats, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*UserStats, error) {
+		return s.userStats(ctx, userID)
+	})
+}
+
+// userStats runs one attempt of UserStats
+func (s *AnalyticsStore) userStats(ctx context.Context, userID uuid.UUID) (*UserStats, error) {
+	pool := readPool(s.db, s.replica)
+	stats := &UserStats{ByStatus: map[SubmissionStatus]int{}}
+
+	rows, err := pool.Query(ctx, `
+		SELECT status, COUNT(*), MAX(created_at)
+		FROM submissions
+		WHERE user_id = $1 AND quarantined_at IS NULL AND deleted_at IS NULL
+		GROUP BY status
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submission counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var status SubmissionStatus
+		var count int
+		var last *timestamp.Time
+		if err := rows.Scan(&status, &count, &last); err != nil {
+			return nil, fmt.Errorf("failed to scan submission count: %w", err)
+		}
+		stats.ByStatus[status] = count
+		stats.Submissions += count
+		if last != nil && (stats.LastSubmittedAt == nil || last.After(stats.LastSubmittedAt.Time)) {
+			stats.LastSubmittedAt = last
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read submission counts: %w", err)
+	}
+
+	err = pool.QueryRow(ctx, `
+		SELECT
+			COUNT(*),
+			COUNT(*) FILTER (WHERE a.sentiment = 'positive'),
+			COUNT(*) FILTER (WHERE a.sentiment = 'neutral'),
+			COUNT(*) FILTER (WHERE a.sentiment = 'negative'),
+			AVG(a.sentiment_score),
+			COALESCE(SUM(a.prompt_tokens), 0),
+			COALESCE(SUM(a.output_tokens), 0),
+			COALESCE(SUM(a.cost_micros), 0)
+		FROM analyses a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE s.user_id = $1 AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+	`, userID).Scan(
+		&stats.Analyses,
+		&stats.Positive,
+		&stats.Neutral,
+		&stats.Negative,
+		&stats.AverageSentiment,
+		&stats.PromptTokens,
+		&stats.OutputTokens,
+		&stats.CostMicros,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query analysis totals: %w", err)
+	}
+
+	return stats, nil
+}
+
+// WeeklyActivity summarizes a user's recent activity for digest emails
+type WeeklyActivity struct {
+	UserID           uuid.UUID
+	Email            string
+	Submissions      int
+	Analyses         int
+	AverageSentiment *float64
+}
+
+// WeeklyActivity returns per-user activity since the given time for
+// verified users who submitted content in that period
+func (s *AnalyticsStore) WeeklyActivity(ctx context.Context, since time.Time) ([]WeeklyActivity, error) {
+	query := `
+		SELECT
+			u.id,
+			u.email,
+			COUNT(DISTINCT s.id) AS submissions,
+			COUNT(a.id) AS analyses,
+			AVG(a.sentiment_score) AS average_sentiment
+		FROM users u
+		JOIN submissions s ON s.user_id = u.id AND s.created_at >= $1
+		LEFT JOIN analyses a ON a.submission_id = s.id
+		WHERE u.email_verified_at IS NOT NULL
+		GROUP BY u.id, u.email
+	`
+
+	rows, err := readPool(s.db, s.replica).Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weekly activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activity []WeeklyActivity
+	for rows.Next() {
+		var a WeeklyActivity
+		if err := rows.Scan(&a.UserID, &a.Email, &a.Submissions, &a.Analyses, &a.AverageSentiment); err != nil {
+			return nil, fmt.Errorf("failed to scan weekly activity: %w", err)
+		}
+		activity = append(activity, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read weekly activity: %w", err)
+	}
+
+	return activity, nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    TimeBucket
+		wantErr bool
+	}{
+		{name: "day", input: "day", want: BucketDay},
+		{name: "week", input: "week", want: BucketWeek},
+		{name: "month", input: "month", want: BucketMonth},
+		{name: "empty", input: "", wantErr: true},
+		{name: "sql injection attempt", input: "day'); DROP TABLE users; --", wantErr: true},
+		{name: "unsupported", input: "hour", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTimeBucket(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTimeBucket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTimeBucket() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeBucket_Duration(t *testing.T) {
+	if BucketDay.Duration() != 24*time.Hour {
+		t.Errorf("BucketDay.Duration() = %v, want 24h", BucketDay.Duration())
+	}
+	if BucketWeek.Duration() != 7*24*time.Hour {
+		t.Errorf("BucketWeek.Duration() = %v, want 168h", BucketWeek.Duration())
+	}
+	if BucketMonth.Duration() <= BucketWeek.Duration() {
+		t.Error("BucketMonth.Duration() should be longer than a week")
+	}
+}
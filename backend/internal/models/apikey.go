@@ -0,0 +1,183 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+const (
+	// MaxAPIKeysPerUser caps how many active keys one user can hold
+	MaxAPIKeysPerUser = 10
+
+	// apiKeyPrefix marks keys so they are easy to recognize in leaks and
+	// secret scanners
+	apiKeyPrefix = "ca_"
+
+	// apiKeyPrefixLength is how much of a key is kept in the clear to tell
+	// keys apart
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+)
+
+// ErrAPIKeyLimit is returned when a user already has MaxAPIKeysPerUser
+// active keys
+var ErrAPIKeyLimit = fmt.Errorf("a user can have at most %d API keys", MaxAPIKeysPerUser)
+
+// APIKey authenticates integrations such as the browser extension as a
+// user. The key itself is only returned when it is created.
+type APIKey struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Name   string    `json:"name"`
+	Prefix string    `json:"prefix"`
+	// Scopes limits what the key may do; null allows everything
+	Scopes     []string        `json:"scopes"`
+	LastUsedAt *timestamp.Time `json:"last_used_at"`
+	CreatedAt  timestamp.Time  `json:"created_at"`
+	RevokedAt  *timestamp.Time `json:"revoked_at"`
+}
+
+// NewAPIKey returns a random key such as "ca_3f9a..."
+func NewAPIKey() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(raw), nil
+}
+
+// APIKeyPrefix returns the start of a key that is kept in the clear
+func APIKeyPrefix(key string) string {
+	return key[:min(len(key), apiKeyPrefixLength)]
+}
+
+// APIKeyStore persists API keys
+type APIKeyStore struct {
+	db *pgxpool.Pool
+}
+
+// NewAPIKeyStore creates a new API key store
+func NewAPIKeyStore(db *pgxpool.Pool) *APIKeyStore {
+	return &APIKeyStore{db: db}
+}
+
+const apiKeyColumns = `id, user_id, name, prefix, scopes, last_used_at, created_at, revoked_at`
+
+// scanAPIKey reads a row selected with apiKeyColumns
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	var key APIKey
+	if err := row.Scan(
+		&key.ID,
+		&key.UserID,
+		&key.Name,
+		&key.Prefix,
+		&key.Scopes,
+		&key.LastUsedAt,
+		&key.CreatedAt,
+		&key.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List returns a user's keys, including revoked ones, newest first
+func (s *APIKeyStore) List(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	keys, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]APIKey, error) {
+		rows, err := s.db.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		keys := []APIKey{}
+		for rows.Next() {
+			key, err := scanAPIKey(rows)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, *key)
+		}
+		return keys, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// Create stores a new key for the user, limited to scopes unless they are
+// nil, and returns it with its plaintext key
+func (s *APIKeyStore) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string) (string, *APIKey, error) {
+	plaintext, err := NewAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Keys created at the same moment are counted without a lock and can
+	// leave the user a key or two over MaxAPIKeysPerUser. The limit is
+	// there to stop a script minting keys in a loop, not to be exact.
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		SELECT $1, $2, $3, $4, $6
+		WHERE (SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL) < $5
+		RETURNING ` + apiKeyColumns
+
+	key, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*APIKey, error) {
+		return scanAPIKey(s.db.QueryRow(ctx, query, userID, name, APIKeyPrefix(plaintext), hashToken(plaintext), MaxAPIKeysPerUser, scopes))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, ErrAPIKeyLimit
+		}
+		return "", nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return plaintext, key, nil
+}
+
+// Revoke stops one of the user's keys from authenticating
+func (s *APIKeyStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	// Revoking twice keeps the first time, so retrying is harmless
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+			WHERE id = $1 AND user_id = $2
+		`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke API key: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// Authenticate looks up an active key by its plaintext and records its
+// use. Unknown and revoked keys return pgx.ErrNoRows.
+func (s *APIKeyStore) Authenticate(ctx context.Context, plaintext string) (*APIKey, error) {
+	if !strings.HasPrefix(plaintext, apiKeyPrefix) {
+		return nil, pgx.ErrNoRows
+	}
+
+	query := `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE key_hash = $1 AND revoked_at IS NULL
+		RETURNING ` + apiKeyColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*APIKey, error) {
+		return scanAPIKey(s.db.QueryRow(ctx, query, hashToken(plaintext)))
+	})
+}
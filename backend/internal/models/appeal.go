@@ -0,0 +1,211 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// AccountStatus is whether an account may be used
+type AccountStatus string
+
+const (
+	StatusActive AccountStatus = "active"
+	// StatusSuspended accounts can sign in only to appeal
+	StatusSuspended AccountStatus = "suspended"
+	// StatusBanned accounts can't sign in, and can't appeal
+	StatusBanned AccountStatus = "banned"
+)
+
+// ParseAccountStatus validates an account status
+func ParseAccountStatus(s string) (AccountStatus, error) {
+	switch status := AccountStatus(s); status {
+	case StatusActive, StatusSuspended, StatusBanned:
+		return status, nil
+	default:
+		return "", fmt.Errorf("status must be one of: active, suspended, banned")
+	}
+}
+
+// MaxStatusReasonLength bounds the reason given for a suspension or ban,
+// in characters
+const MaxStatusReasonLength = 1000
+
+// MaxAppealLength bounds an appeal's message and its response, in
+// characters
+const MaxAppealLength = 5000
+
+// AppealDecision is where an appeal stands
+type AppealDecision string
+
+const (
+	AppealPending AppealDecision = "pending"
+	// AppealGranted appeals reinstated the account
+	AppealGranted AppealDecision = "granted"
+	AppealDenied  AppealDecision = "denied"
+)
+
+// ErrAppealDecided is returned when deciding an appeal that was already
+// decided
+var ErrAppealDecided = errors.New("appeal already decided")
+
+// Appeal is a user's request to lift their suspension
+type Appeal struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	// StatusReason is the suspension's reason when the appeal was made
+	StatusReason string         `json:"status_reason"`
+	Message      string         `json:"message"`
+	Decision     AppealDecision `json:"decision"`
+	// Response is the operator's explanation of the decision, shown to
+	// the user
+	Response   string          `json:"response"`
+	ReviewedBy *string         `json:"reviewed_by,omitempty"`
+	ReviewedAt *timestamp.Time `json:"reviewed_at"`
+	CreatedAt  timestamp.Time  `json:"created_at"`
+}
+
+// appealColumns is the column list matching scanAppeal
+const appealColumns = `id, user_id, status_reason, message, decision, response, reviewed_by, reviewed_at, created_at`
+
+// scanAppeal scans a row selected with appealColumns
+func scanAppeal(row pgx.Row) (*Appeal, error) {
+	var a Appeal
+	if err := row.Scan(&a.ID, &a.UserID, &a.StatusReason, &a.Message, &a.Decision, &a.Response, &a.ReviewedBy, &a.ReviewedAt, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// AppealStore persists users' appeals against suspensions
+type AppealStore struct {
+	db *pgxpool.Pool
+}
+
+// NewAppealStore creates a new appeal store
+func NewAppealStore(db *pgxpool.Pool) *AppealStore {
+	return &AppealStore{db: db}
+}
+
+// Create records the user's appeal against their current suspension. A
+// user with a pending appeal gets a unique violation.
+func (s *AppealStore) Create(ctx context.Context, userID uuid.UUID, message string) (*Appeal, error) {
+	query := `
+		INSERT INTO account_appeals (user_id, status_reason, message)
+		SELECT id, COALESCE(status_reason, ''), $2 FROM users WHERE id = $1
+		RETURNING ` + appealColumns
+
+	appeal, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Appeal, error) {
+		return scanAppeal(s.db.QueryRow(ctx, query, userID, message))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to create appeal: %w", err)
+	}
+	return appeal, err
+}
+
+// ListForUser returns the user's appeals, newest first
+func (s *AppealStore) ListForUser(ctx context.Context, userID uuid.UUID) ([]Appeal, error) {
+	return s.list(ctx, `
+		SELECT `+appealColumns+` FROM account_appeals
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+	`, userID)
+}
+
+// List returns a page of every user's appeals with the given decision,
+// oldest first, and the total count
+func (s *AppealStore) List(ctx context.Context, decision AppealDecision, limit, offset int) ([]Appeal, int, error) {
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `SELECT COUNT(*) FROM account_appeals WHERE decision = $1`, decision).Scan(&total)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count appeals: %w", err)
+	}
+
+	appeals, err := s.list(ctx, `
+		SELECT `+appealColumns+` FROM account_appeals
+		WHERE decision = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`, decision, limit, offset)
+	return appeals, total, err
+}
+
+// list runs a query selecting appealColumns
+func (s *AppealStore) list(ctx context.Context, query string, args ...interface{}) ([]Appeal, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Appeal, error) {
+		rows, err := s.db.Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list appeals: %w", err)
+		}
+		defer rows.Close()
+
+		appeals := []Appeal{}
+		for rows.Next() {
+			appeal, err := scanAppeal(rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan appeal: %w", err)
+			}
+			appeals = append(appeals, *appeal)
+		}
+		return appeals, rows.Err()
+	})
+}
+
+// Decide grants or denies a pending appeal. Granting it reinstates the
+// user in the same transaction. It returns pgx.ErrNoRows if there is no
+// such appeal, and ErrAppealDecided if it was already decided.
+func (s *AppealStore) Decide(ctx context.Context, id uuid.UUID, decision AppealDecision, response, reviewer string) (*Appeal, error) {
+	appeal, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Appeal, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		appeal, err := scanAppeal(tx.QueryRow(ctx, `
+			UPDATE account_appeals
+			SET decision = $2, response = $3, reviewed_by = $4, reviewed_at = NOW()
+			WHERE id = $1 AND decision = 'pending'
+			RETURNING `+appealColumns, id, decision, response, reviewer))
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM account_appeals WHERE id = $1)`, id).Scan(&exists); err != nil {
+				return nil, err
+			}
+			if exists {
+				return nil, ErrAppealDecided
+			}
+			return nil, pgx.ErrNoRows
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Only a suspension is lifted; a ban made since the appeal stands
+		if decision == AppealGranted {
+			if _, err := tx.Exec(ctx, `
+				UPDATE users
+				SET status = 'active', status_reason = NULL, status_changed_at = NOW(), updated_at = NOW()
+				WHERE id = $1 AND status = 'suspended'
+			`, appeal.UserID); err != nil {
+				return nil, err
+			}
+		}
+
+		return appeal, tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) && !errors.Is(err, ErrAppealDecided) {
+		return nil, fmt.Errorf("failed to decide appeal: %w", err)
+	}
+	return appeal, err
+}
@@ -0,0 +1,161 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// ModelCall is a model call made for an analysis, exactly as sent and
+// received
+type ModelCall struct {
+	// Stage is the pipeline stage that made the call
+	Stage             string `json:"stage"`
+	Model             string `json:"model"`
+	SystemInstruction string `json:"system_instruction"`
+	Prompt            string `json:"prompt"`
+	// Response is the model's text, before any cleanup or repair
+	Response     string `json:"response"`
+	PromptTokens int    `json:"prompt_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	LatencyMs    int64  `json:"latency_ms"`
+	// Error is why the call failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// AnalysisArtifacts is what went into and came out of an analysis, for
+// operators debugging prompts
+type AnalysisArtifacts struct {
+	AnalysisID       uuid.UUID       `json:"analysis_id"`
+	SubmissionID     uuid.UUID       `json:"submission_id"`
+	UserID           uuid.UUID       `json:"user_id"`
+	RawResponse      json.RawMessage `json:"raw_response"`
+	PromptTokens     int             `json:"prompt_tokens"`
+	OutputTokens     int             `json:"output_tokens"`
+	ProcessingTimeMs int             `json:"processing_time_ms"`
+	// Calls is empty when artifacts weren't kept for the analysis
+	Calls     []ModelCall    `json:"calls"`
+	CreatedAt timestamp.Time `json:"created_at"`
+}
+
+// GetArtifacts retrieves the artifacts of an analysis regardless of owner.
+// It is meant for operators; it returns pgx.ErrNoRows if the analysis
+// doesn't exist.
+func (s *SubmissionStore) GetArtifacts(ctx context.Context, analysisID uuid.UUID) (*AnalysisArtifacts, error) {
+	query := `
+		SELECT a.id, a.submission_id, s.user_id, a.raw_response, a.prompt_tokens, a.output_tokens,
+			a.processing_time_ms, art.calls, a.created_at
+		FROM analyses a
+		JOIN submissions s ON s.id = a.submission_id
+		LEFT JOIN analysis_artifacts art ON art.analysis_id = a.id
+		WHERE a.id = $1
+	`
+
+	var artifacts AnalysisArtifacts
+	var raw []byte
+	var calls *string
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, query, analysisID).Scan(
+			&artifacts.AnalysisID,
+			&artifacts.SubmissionID,
+			&artifacts.UserID,
+			&raw,
+			&artifacts.PromptTokens,
+			&artifacts.OutputTokens,
+			&artifacts.ProcessingTimeMs,
+			&calls,
+			&artifacts.CreatedAt,
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analysis := record{id: artifacts.AnalysisID, owner: artifacts.UserID}
+	if artifacts.RawResponse, err = s.openRaw(ctx, analysis, raw); err != nil {
+		return nil, fmt.Errorf("failed to decrypt raw response: %w", err)
+	}
+	if artifacts.Calls, err = s.openCalls(ctx, analysis, calls); err != nil {
+		return nil, fmt.Errorf("failed to decrypt model calls: %w", err)
+	}
+	return &artifacts, nil
+}
+
+// escapeRaw escapes a plaintext response that is a JSON string which
+// could pass for ciphertext, as sealField does for other columns
+func escapeRaw(raw json.RawMessage) json.RawMessage {
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil || encryption.Escape(value) == value {
+		return raw
+	}
+	escaped, err := json.Marshal(encryption.Escape(value))
+	if err != nil {
+		return raw
+	}
+	return escaped
+}
+
+// openRaw decodes a value read from the raw_response column, which holds
+// the response itself, escaped by escapeRaw, or when encrypted a JSON
+// string of its ciphertext
+func (s *SubmissionStore) openRaw(ctx context.Context, analysis record, raw []byte) (json.RawMessage, error) {
+	var sealed string
+	if err := json.Unmarshal(raw, &sealed); err != nil {
+		return raw, nil
+	}
+	if !encryption.IsEncrypted(sealed) {
+		if plain := encryption.Unescape(sealed); plain != sealed {
+			return json.Marshal(plain)
+		}
+		return raw, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, sealed, analysis.bind(fieldAnalysisRaw))
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(opened), nil
+}
+
+// sealCalls encodes and encrypts model calls for the analysis_artifacts
+// table, returning nil when there are none
+func (s *SubmissionStore) sealCalls(ctx context.Context, analysis record, calls []ModelCall) (*string, error) {
+	if len(calls) == 0 {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(calls)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode model calls: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisCalls))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt model calls: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openCalls decodes a value read from the calls column, returning an
+// empty list when there is none
+func (s *SubmissionStore) openCalls(ctx context.Context, analysis record, value *string) ([]ModelCall, error) {
+	if value == nil {
+		return []ModelCall{}, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisCalls))
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []ModelCall
+	if err := json.Unmarshal([]byte(opened), &calls); err != nil {
+		return nil, err
+	}
+	return calls, nil
+}
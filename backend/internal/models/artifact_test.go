@@ -0,0 +1,75 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+)
+
+func TestSubmissionStore_SealCalls(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+
+	analysis := record{uuid.New(), uuid.New()}
+	for _, store := range []*SubmissionStore{{}, {cipher: encryption.New(keys)}} {
+		sealed, err := store.sealCalls(ctx, analysis, nil)
+		if sealed != nil || err != nil {
+			t.Errorf("sealCalls(nil) = %v, %v, want nil", sealed, err)
+		}
+
+		calls := []ModelCall{{Stage: "analysis", Prompt: "Analyze this.", Response: `{"sentiment": "neutral"}`, PromptTokens: 10, LatencyMs: 250}}
+		sealed, err = store.sealCalls(ctx, analysis, calls)
+		if err != nil {
+			t.Fatalf("sealCalls() error = %v", err)
+		}
+		if store.cipher != nil && strings.Contains(*sealed, "Analyze this.") {
+			t.Errorf("sealCalls() stored the prompt in the clear: %s", *sealed)
+		}
+
+		opened, err := store.openCalls(ctx, analysis, sealed)
+		if err != nil {
+			t.Fatalf("openCalls() error = %v", err)
+		}
+		if len(opened) != 1 || opened[0] != calls[0] {
+			t.Errorf("openCalls() = %+v, want %+v", opened, calls)
+		}
+	}
+
+	opened, err := (&SubmissionStore{}).openCalls(ctx, analysis, nil)
+	if err != nil || opened == nil || len(opened) != 0 {
+		t.Errorf("openCalls(nil) = %#v, %v, want an empty list", opened, err)
+	}
+}
+
+func TestSubmissionStore_OpenRaw(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	store := &SubmissionStore{cipher: encryption.New(keys)}
+	analysis := record{uuid.New(), uuid.New()}
+
+	response := `{"sentiment": "positive"}`
+	sealed, err := sealField(ctx, store.cipher, response, analysis.bind(fieldAnalysisRaw))
+	if err != nil {
+		t.Fatalf("sealField() error = %v", err)
+	}
+	stored, _ := json.Marshal(sealed)
+
+	for _, raw := range [][]byte{[]byte(response), stored} {
+		got, err := store.openRaw(ctx, analysis, raw)
+		if err != nil || string(got) != response {
+			t.Errorf("openRaw(%s) = %s, %v, want %s", raw, got, err, response)
+		}
+	}
+}
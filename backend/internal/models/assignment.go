@@ -0,0 +1,138 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// WorkflowStatus is how far a submission's review has come. It is set by
+// people, unlike SubmissionStatus, which tracks the analysis.
+type WorkflowStatus string
+
+const (
+	// WorkflowOpen is waiting for review
+	WorkflowOpen WorkflowStatus = "open"
+	// WorkflowInReview is being reviewed
+	WorkflowInReview WorkflowStatus = "in_review"
+	// WorkflowChangesRequested needs another revision
+	WorkflowChangesRequested WorkflowStatus = "changes_requested"
+	// WorkflowApproved passed review
+	WorkflowApproved WorkflowStatus = "approved"
+)
+
+// ParseWorkflowStatus validates a workflow status name
+func ParseWorkflowStatus(s string) (WorkflowStatus, error) {
+	switch w := WorkflowStatus(s); w {
+	case WorkflowOpen, WorkflowInReview, WorkflowChangesRequested, WorkflowApproved:
+		return w, nil
+	default:
+		return "", fmt.Errorf("workflow_status must be one of: open, in_review, changes_requested, approved")
+	}
+}
+
+// Assign makes assigneeID the reviewer of a submission owned by ownerID,
+// due at dueAt or with no due date when nil. It returns pgx.ErrNoRows if
+// the owner has no such submission.
+func (s *SubmissionStore) Assign(ctx context.Context, ownerID, id, assigneeID uuid.UUID, dueAt *time.Time) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET assignee_id = $3, due_at = $4, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		RETURNING ` + submissionColumns
+
+	return s.updateWorkflow(ctx, "assign submission", query, id, ownerID, assigneeID, dueAt)
+}
+
+// Unassign removes a submission's reviewer and due date. The owner and the
+// assignee can both do so; it returns pgx.ErrNoRows for anyone else.
+func (s *SubmissionStore) Unassign(ctx context.Context, userID, id uuid.UUID) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET assignee_id = NULL, due_at = NULL, version = version + 1
+		WHERE id = $1 AND (user_id = $2 OR assignee_id = $2) AND deleted_at IS NULL
+		RETURNING ` + submissionColumns
+
+	return s.updateWorkflow(ctx, "unassign submission", query, id, userID)
+}
+
+// SetWorkflowStatus moves a submission's review to status. The owner and
+// the assignee can both do so; it returns pgx.ErrNoRows for anyone else.
+func (s *SubmissionStore) SetWorkflowStatus(ctx context.Context, userID, id uuid.UUID, status WorkflowStatus) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET workflow_status = $3, version = version + 1
+		WHERE id = $1 AND (user_id = $2 OR assignee_id = $2) AND deleted_at IS NULL
+		RETURNING ` + submissionColumns
+
+	return s.updateWorkflow(ctx, "set workflow status", query, id, userID, status)
+}
+
+// updateWorkflow runs a workflow update returning the submission
+func (s *SubmissionStore) updateWorkflow(ctx context.Context, action, query string, args ...any) (*Submission, error) {
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, args...))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to %s: %w", action, err)
+	}
+	return submission, err
+}
+
+// Queue returns a page of the submissions assigned to assigneeID, in
+// status or any status when it is "", soonest due first and those without
+// a due date last, and the total count
+func (s *SubmissionStore) Queue(ctx context.Context, assigneeID uuid.UUID, status WorkflowStatus, limit, offset int) ([]Submission, int, error) {
+	var submissions []Submission
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		var err error
+		submissions, total, err = s.queue(ctx, assigneeID, status, limit, offset)
+		return err
+	})
+	return submissions, total, err
+}
+
+// queue runs one attempt of Queue
+func (s *SubmissionStore) queue(ctx context.Context, assigneeID uuid.UUID, status WorkflowStatus, limit, offset int) ([]Submission, int, error) {
+	where := `WHERE assignee_id = $1 AND ($2 = '' OR workflow_status = $2) AND quarantined_at IS NULL AND deleted_at IS NULL`
+	db := readPool(s.db, s.replica)
+
+	var total int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions `+where, assigneeID, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count assigned submissions: %w", err)
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT `+submissionColumns+`
+		FROM submissions
+		`+where+`
+		ORDER BY due_at NULLS LAST, created_at, id
+		LIMIT $3 OFFSET $4
+	`, assigneeID, status, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list assigned submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []Submission{}
+	for rows.Next() {
+		submission, err := s.scan(ctx, rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan submission: %w", err)
+		}
+		submissions = append(submissions, *submission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read assigned submissions: %w", err)
+	}
+
+	return submissions, total, nil
+}
@@ -0,0 +1,16 @@
+package models
+
+import "testing"
+
+func TestParseWorkflowStatus(t *testing.T) {
+	for _, status := range []string{"open", "in_review", "changes_requested", "approved"} {
+		if _, err := ParseWorkflowStatus(status); err != nil {
+			t.Errorf("ParseWorkflowStatus(%q) error = %v", status, err)
+		}
+	}
+	for _, status := range []string{"", "Open", "closed"} {
+		if _, err := ParseWorkflowStatus(status); err == nil {
+			t.Errorf("ParseWorkflowStatus(%q) error = nil, want error", status)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// AuditAction names a recorded account event
+type AuditAction string
+
+const (
+	AuditPasswordChanged      AuditAction = "password_changed"
+	AuditEmailChangeRequested AuditAction = "email_change_requested"
+	AuditEmailChanged         AuditAction = "email_changed"
+	// AuditSubmissionPurged records a submission destroyed by a retention
+	// policy; the entry outlives the data it describes
+	AuditSubmissionPurged AuditAction = "submission_purged"
+	// Quarantine actions taken by operators on a user's submission
+	AuditSubmissionQuarantined AuditAction = "submission_quarantined"
+	AuditSubmissionReleased    AuditAction = "submission_released"
+	AuditSubmissionDestroyed   AuditAction = "submission_destroyed"
+	// Impersonation by an admin; the metadata names the admin
+	AuditImpersonationStarted AuditAction = "impersonation_started"
+	AuditImpersonatedRequest  AuditAction = "impersonated_request"
+	// Whether sign-ins from a new country or device need an emailed code
+	AuditLoginConfirmationEnabled  AuditAction = "login_confirmation_enabled"
+	AuditLoginConfirmationDisabled AuditAction = "login_confirmation_disabled"
+	// AuditSSOLogin records a sign-in through an organization's identity
+	// provider; the metadata names the organization and whether the
+	// account was created by it
+	AuditSSOLogin AuditAction = "sso_login"
+	// AuditAccountStatusChanged records an operator suspending, banning or
+	// reinstating the account; the metadata names the status, reason and
+	// operator
+	AuditAccountStatusChanged AuditAction = "account_status_changed"
+	// Appeals against a suspension, and operators' decisions on them
+	AuditAppealSubmitted AuditAction = "appeal_submitted"
+	AuditAppealDecided   AuditAction = "appeal_decided"
+)
+
+// AuditEntry is a security-relevant event on a user's account
+type AuditEntry struct {
+	ID        uuid.UUID         `json:"id"`
+	UserID    uuid.UUID         `json:"user_id"`
+	Action    AuditAction       `json:"action"`
+	IPAddress string            `json:"ip_address"`
+	UserAgent string            `json:"user_agent"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt timestamp.Time    `json:"created_at"`
+}
+
+// AuditStore records account events in the audit log
+type AuditStore struct {
+	db *pgxpool.Pool
+}
+
+// NewAuditStore creates a new audit store
+func NewAuditStore(db *pgxpool.Pool) *AuditStore {
+	return &AuditStore{db: db}
+}
+
+// Record appends an entry to the audit log, filling in its ID and timestamp
+func (s *AuditStore) Record(ctx context.Context, entry *AuditEntry) error {
+	var metadata []byte
+	if len(entry.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(entry.Metadata); err != nil {
+			return fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_log (user_id, action, ip_address, user_agent, metadata)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, query,
+			entry.UserID,
+			entry.Action,
+			entry.IPAddress,
+			entry.UserAgent,
+			metadata,
+		).Scan(&entry.ID, &entry.CreatedAt)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return nil
+}
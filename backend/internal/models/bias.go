@@ -0,0 +1,76 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// BiasExampleKind is what a cited bias example illustrates
+type BiasExampleKind string
+
+const (
+	BiasFraming        BiasExampleKind = "framing"
+	BiasLoadedLanguage BiasExampleKind = "loaded_language"
+	BiasOneSided       BiasExampleKind = "one_sided"
+)
+
+// BiasReport assesses the political or ideological framing of content,
+// its loaded language and how one-sided it is
+type BiasReport struct {
+	// Framing describes the framing in a sentence or two; empty when the
+	// content takes no discernible position
+	Framing string `json:"framing"`
+	// OneSidedness is 0 for content that gives every relevant side a fair
+	// hearing and 1 for content presenting a single side
+	OneSidedness float64 `json:"one_sidedness"`
+	// MissingPerspectives are relevant viewpoints the content leaves out
+	MissingPerspectives []string      `json:"missing_perspectives"`
+	Examples            []BiasExample `json:"examples"`
+}
+
+// BiasExample is a passage cited as evidence for the report. Start and
+// End are character (rune) offsets, like findings.
+type BiasExample struct {
+	Kind        BiasExampleKind `json:"kind"`
+	Text        string          `json:"text"`
+	Start       int             `json:"start"`
+	End         int             `json:"end"`
+	Explanation string          `json:"explanation"`
+}
+
+// sealBias encodes an analysis' bias report for the bias column,
+// encrypting it when enabled
+func (s *SubmissionStore) sealBias(ctx context.Context, analysis record, report *BiasReport) (*string, error) {
+	if report == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode bias report: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisBias))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt bias report: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openBias decodes a value read from the bias column
+func (s *SubmissionStore) openBias(ctx context.Context, analysis record, value *string) (*BiasReport, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisBias))
+	if err != nil {
+		return nil, err
+	}
+
+	var report BiasReport
+	if err := json.Unmarshal([]byte(opened), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
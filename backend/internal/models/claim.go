@@ -0,0 +1,70 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ClaimAssessment is how well the evidence supports a claim
+type ClaimAssessment string
+
+const (
+	ClaimSupported    ClaimAssessment = "supported"
+	ClaimContradicted ClaimAssessment = "contradicted"
+	ClaimDisputed     ClaimAssessment = "disputed"
+	ClaimUnverified   ClaimAssessment = "unverified"
+)
+
+// Claim is a factual claim made in the content and its fact-check
+type Claim struct {
+	Text       string          `json:"text"`
+	Assessment ClaimAssessment `json:"assessment"`
+	// Explanation says briefly why the claim was assessed as it was
+	Explanation string `json:"explanation,omitempty"`
+	// Sources are the search results the assessment relied on; empty when
+	// no search provider is configured
+	Sources []ClaimSource `json:"sources"`
+}
+
+// ClaimSource is a web page cited for a claim's assessment
+type ClaimSource struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// sealClaims encodes an analysis' claims for the claims column,
+// encrypting them when enabled
+func (s *SubmissionStore) sealClaims(ctx context.Context, analysis record, claims []Claim) (*string, error) {
+	if claims == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode claims: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisClaims))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt claims: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openClaims decodes a value read from the claims column
+func (s *SubmissionStore) openClaims(ctx context.Context, analysis record, value *string) ([]Claim, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisClaims))
+	if err != nil {
+		return nil, err
+	}
+
+	claims := []Claim{}
+	if err := json.Unmarshal([]byte(opened), &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// DeviceLifetime is how long a remember-me session lasts
+type DeviceLifetime struct {
+	// Idle is how long the session lasts unused; each use extends it
+	Idle time.Duration
+	// Max is how long it lasts however often it is used
+	Max time.Duration
+}
+
+// DeviceSession keeps a browser signed in through a remember-me cookie.
+// The cookie itself is only returned when the session is created.
+type DeviceSession struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       uuid.UUID       `json:"user_id"`
+	UserAgent    string          `json:"user_agent"`
+	IPAddress    string          `json:"ip_address"`
+	CreatedAt    timestamp.Time  `json:"created_at"`
+	LastUsedAt   timestamp.Time  `json:"last_used_at"`
+	ExpiresAt    timestamp.Time  `json:"expires_at"`
+	MaxExpiresAt timestamp.Time  `json:"max_expires_at"`
+	RevokedAt    *timestamp.Time `json:"revoked_at,omitempty"`
+}
+
+// NewDeviceToken returns a random value for a device cookie
+func NewDeviceToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate device token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DeviceSessionStore persists remember-me sessions
+type DeviceSessionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewDeviceSessionStore creates a new device session store
+func NewDeviceSessionStore(db *pgxpool.Pool) *DeviceSessionStore {
+	return &DeviceSessionStore{db: db}
+}
+
+const deviceSessionColumns = `id, user_id, user_agent, ip_address, created_at, last_used_at, expires_at, max_expires_at, revoked_at`
+
+// scanDeviceSession reads a row selected with deviceSessionColumns
+func scanDeviceSession(row pgx.Row) (*DeviceSession, error) {
+	var session DeviceSession
+	if err := row.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.ExpiresAt,
+		&session.MaxExpiresAt,
+		&session.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Create starts a session for the user's device and returns it with the
+// plaintext token for its cookie
+func (s *DeviceSessionStore) Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string, lifetime DeviceLifetime) (string, *DeviceSession, error) {
+	token, err := NewDeviceToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	query := `
+		INSERT INTO device_sessions (user_id, token_hash, user_agent, ip_address, expires_at, max_expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + deviceSessionColumns
+
+	session, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*DeviceSession, error) {
+		return scanDeviceSession(s.db.QueryRow(ctx, query,
+			userID, hashToken(token), userAgent, ipAddress,
+			now.Add(min(lifetime.Idle, lifetime.Max)), now.Add(lifetime.Max),
+		))
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create device session: %w", err)
+	}
+
+	return token, session, nil
+}
+
+// Resume looks up an active session by its cookie token and records its
+// use, extending it by idle up to its maximum lifetime. Unknown, expired
+// and revoked sessions return pgx.ErrNoRows.
+func (s *DeviceSessionStore) Resume(ctx context.Context, token, ipAddress string, idle time.Duration) (*DeviceSession, error) {
+	query := `
+		UPDATE device_sessions
+		SET last_used_at = NOW(), ip_address = $2, expires_at = LEAST($3, max_expires_at)
+		WHERE token_hash = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		RETURNING ` + deviceSessionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*DeviceSession, error) {
+		return scanDeviceSession(s.db.QueryRow(ctx, query, hashToken(token), ipAddress, time.Now().Add(idle)))
+	})
+}
+
+// List returns a user's active sessions, most recently used first
+func (s *DeviceSessionStore) List(ctx context.Context, userID uuid.UUID) ([]DeviceSession, error) {
+	sessions, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]DeviceSession, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT `+deviceSessionColumns+` FROM device_sessions
+			WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+			ORDER BY last_used_at DESC
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		sessions := []DeviceSession{}
+		for rows.Next() {
+			session, err := scanDeviceSession(rows)
+			if err != nil {
+				return nil, err
+			}
+			sessions = append(sessions, *session)
+		}
+		return sessions, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list device sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions
+func (s *DeviceSessionStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	// Revoking twice keeps the first time, so retrying is harmless
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE device_sessions SET revoked_at = COALESCE(revoked_at, NOW())
+			WHERE id = $1 AND user_id = $2
+		`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke device session: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// RevokeAll ends all of the user's sessions, as signing out everywhere
+// does. The Redis cutoff that also rejects their cookies expires long
+// before remembered sessions do.
+func (s *DeviceSessionStore) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		if _, err := s.db.Exec(ctx, `
+			UPDATE device_sessions SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+		`, userID); err != nil {
+			return fmt.Errorf("failed to revoke device sessions: %w", err)
+		}
+		return nil
+	})
+}
+
+// RevokeByToken ends the session a cookie belongs to, if any
+func (s *DeviceSessionStore) RevokeByToken(ctx context.Context, token string) (*DeviceSession, error) {
+	query := `
+		UPDATE device_sessions SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE token_hash = $1
+		RETURNING ` + deviceSessionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*DeviceSession, error) {
+		return scanDeviceSession(s.db.QueryRow(ctx, query, hashToken(token)))
+	})
+}
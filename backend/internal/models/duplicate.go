@@ -0,0 +1,400 @@
+package models
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+)
+
+// contentHashPurpose names the key content hashes are keyed with
+const contentHashPurpose = "content-analyzer/submissions.content_hash"
+
+// ContentHash identifies content for duplicate detection when encryption
+// is off. Content is hashed exactly as stored, so only identical text
+// matches.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// contentHasher hashes content for duplicate detection. With encryption
+// on, hashes are HMAC-SHA256 under a key derived from the master key, so
+// a hash stored next to encrypted text can't be used to confirm a guess
+// of the text.
+type contentHasher struct {
+	cipher *encryption.Encryptor
+
+	mu    sync.Mutex
+	keyID *string
+	key   []byte
+}
+
+// newContentHasher creates a hasher keyed from cipher's master key, or
+// one of plain SHA-256 when cipher is nil
+func newContentHasher(cipher *encryption.Encryptor) *contentHasher {
+	return &contentHasher{cipher: cipher}
+}
+
+// hash returns content's hash and the ID of the master key it was keyed
+// with, or nil for plain SHA-256
+func (h *contentHasher) hash(ctx context.Context, content string) (string, *string, error) {
+	keyID, key, err := h.derive(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	if keyID == nil {
+		return ContentHash(content), nil, nil
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(content))
+	return hex.EncodeToString(mac.Sum(nil)), keyID, nil
+}
+
+// derive returns the hash key and its ID, deriving them on first use.
+// Failures aren't remembered, so a KMS outage doesn't outlive itself.
+func (h *contentHasher) derive(ctx context.Context) (*string, []byte, error) {
+	if h == nil || h.cipher == nil {
+		return nil, nil, nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.keyID == nil {
+		keyID, key, err := h.cipher.DeriveKey(ctx, contentHashPurpose)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to derive content hash key: %w", err)
+		}
+		h.keyID, h.key = &keyID, key
+	}
+	return h.keyID, h.key, nil
+}
+
+// FindDuplicate returns the earliest submission with the same content that
+// was queued for analysis, from the user's own submissions or failing
+// that their organizations' members', or only orgID's members when it is
+// set. Drafts, failed, canceled and quarantined submissions don't count,
+// and neither do members' submissions whose analysis isn't done yet. It
+// returns pgx.ErrNoRows when there is none.
+func (s *SubmissionStore) FindDuplicate(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, content string) (*Submission, error) {
+	hash, _, err := s.hashes.hash(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT ` + submissionColumns + `
+		FROM submissions
+		WHERE content_hash = $2
+			AND status IN ('queued', 'processing', 'completed', 'archived')
+			AND quarantined_at IS NULL AND deleted_at IS NULL
+			AND (user_id = $1 OR status IN ('completed', 'archived') AND user_id IN (
+				SELECT b.user_id
+				FROM organization_members a
+				JOIN organization_members b ON b.org_id = a.org_id
+				WHERE a.user_id = $1 AND ($3::uuid IS NULL OR a.org_id = $3)
+			))
+		ORDER BY user_id = $1 DESC, created_at, id
+		LIMIT 1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, userID, hash, orgID))
+	})
+}
+
+// RehashContent recomputes the hashes of up to limit submissions with IDs
+// after after that aren't hashed the way new content is: those stored
+// before hashes were kept, or hashed without encryption or under a master
+// key since rotated. It returns the last ID it looked at and how many it
+// looked at. Submissions whose content can't be read are logged and left
+// as they are.
+func (s *SubmissionStore) RehashContent(ctx context.Context, after uuid.UUID, limit int) (uuid.UUID, int, error) {
+	keyID, _, err := s.hashes.derive(ctx)
+	if err != nil {
+		return after, 0, err
+	}
+
+	ids, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]uuid.UUID, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT id FROM submissions
+			WHERE id > $1 AND (content_hash IS NULL OR content_hash_key IS DISTINCT FROM $2)
+			ORDER BY id
+			LIMIT $3
+		`, after, keyID, limit)
+		if err != nil {
+			return nil, err
+		}
+		return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	})
+	if err != nil {
+		return after, 0, fmt.Errorf("failed to list submissions to rehash: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := s.rehash(ctx, id); err != nil {
+			if ctx.Err() != nil {
+				return after, 0, ctx.Err()
+			}
+			slog.Warn("Failed to rehash submission content", "submission_id", id, "error", err)
+		}
+		after = id
+	}
+	return after, len(ids), nil
+}
+
+// rehash recomputes one submission's content hash, unless its content
+// was replaced, and so hashed the current way, in the meantime
+func (s *SubmissionStore) rehash(ctx context.Context, id uuid.UUID) error {
+	submission, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, `SELECT `+submissionColumns+` FROM submissions WHERE id = $1`, id))
+	})
+	if err != nil {
+		return err
+	}
+
+	hash, keyID, err := s.hashes.hash(ctx, submission.Content)
+	if err != nil {
+		return err
+	}
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE submissions SET content_hash = $2, content_hash_key = $3
+			WHERE id = $1 AND (content_hash IS NULL OR content_hash_key IS DISTINCT FROM $3)
+		`, id, hash, keyID)
+		return err
+	})
+}
+
+// CopyDuplicate stores content a teammate already had analyzed as the
+// user's own completed submission, linked to original through
+// duplicate_of, with a copy of original's latest analysis. The copy leaves
+// out the teammate's instructions and model calls, and has no tokens or
+// cost since no model was called for it. It returns pgx.ErrNoRows if
+// original has no analysis.
+func (s *SubmissionStore) CopyDuplicate(ctx context.Context, userID uuid.UUID, original *Submission, content string, redacted *string) (*Submission, error) {
+	stats := textstats.Compute(content)
+	hash, hashKey, err := s.hashes.hash(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	copied := newRecord(userID)
+	content, redacted, err = s.sealContent(ctx, copied, content, redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.copyDuplicate(ctx, copied, original, content, redacted, stats, hash, hashKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.emit(ctx, StatusChange{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		To:           submission.Status,
+		At:           submission.CreatedAt,
+	})
+
+	return submission, nil
+}
+
+// copyDuplicate runs one attempt of CopyDuplicate's transaction with
+// content sealed for the copy
+func (s *SubmissionStore) copyDuplicate(ctx context.Context, copied record, original *Submission, content string, redacted *string, stats textstats.Stats, hash string, hashKey *string) (*Submission, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	submission, err := s.scan(ctx, tx.QueryRow(ctx, `
+		INSERT INTO submissions (id, user_id, content, redacted_content, status, completed_at,
+			word_count, character_count, token_estimate, content_hash, content_hash_key, duplicate_of)
+		VALUES ($1, $2, $3, $4, 'completed', NOW(), $5, $6, $7, $8, $9, $10)
+		RETURNING `+submissionColumns,
+		copied.id, copied.owner, content, redacted, stats.Words, stats.Characters, stats.Tokens, hash, hashKey, original.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create submission: %w", err)
+	}
+
+	// Encrypted fields are bound to the teammate's analysis, so they are
+	// sealed again for the copy rather than copied in SQL
+	var from analysisFields
+	from.record.owner = original.UserID
+	err = tx.QueryRow(ctx, `
+		SELECT id, summary, raw_response, claims, issues, bias, ai_detection, moderation, compliance
+		FROM analyses
+		WHERE submission_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, original.ID).Scan(&from.record.id, &from.summary, &from.raw, &from.claims, &from.issues, &from.bias, &from.aiDetection, &from.moderation, &from.compliance)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.resealAnalysis(ctx, &from, newRecord(copied.owner))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO analyses (id, submission_id, sentiment, sentiment_score, topics, summary, readability, findings, raw_response,
+			processing_time_ms, confidence, claims, issues, bias, ai_detection, moderation, policy_decision, compliance, language, timed_out)
+		SELECT $1, $2, sentiment, sentiment_score, topics, $4, readability, findings, $5,
+			0, confidence, $6, $7, $8, $9, $10, policy_decision, $11, language, timed_out
+		FROM analyses
+		WHERE id = $3
+	`, to.record.id, submission.ID, from.record.id, to.summary, to.raw, to.claims, to.issues, to.bias, to.aiDetection, to.moderation, to.compliance)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy analysis: %w", err)
+	}
+	analysisID := to.record.id
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO keyphrases (submission_id, analysis_id, phrase, score)
+		SELECT $1, $2, phrase, score FROM keyphrases WHERE submission_id = $3
+	`, submission.ID, analysisID, original.ID); err != nil {
+		return nil, fmt.Errorf("failed to copy keyphrases: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO submission_labels (submission_id, analysis_id, label, confidence)
+		SELECT $1, $2, label, confidence FROM submission_labels WHERE submission_id = $3
+	`, submission.ID, analysisID, original.ID); err != nil {
+		return nil, fmt.Errorf("failed to copy labels: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit submission: %w", err)
+	}
+
+	return submission, nil
+}
+
+// analysisFields are the encrypted fields of an analysis that a duplicate
+// copies, as stored
+type analysisFields struct {
+	record                                                             record
+	summary, claims, issues, bias, aiDetection, moderation, compliance *string
+	raw                                                                []byte
+}
+
+// resealAnalysis seals an analysis' encrypted fields again for another
+// analysis
+func (s *SubmissionStore) resealAnalysis(ctx context.Context, from *analysisFields, to record) (*analysisFields, error) {
+	resealed := analysisFields{record: to, raw: from.raw}
+	reseal := func(value *string, field string) (*string, error) {
+		value, err := resealOptionalField(ctx, s.cipher, value, from.record.bind(field), to.bind(field))
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %w", field, err)
+		}
+		return value, nil
+	}
+
+	var err error
+	if resealed.summary, err = reseal(from.summary, fieldAnalysisSummary); err != nil {
+		return nil, err
+	}
+	if resealed.claims, err = reseal(from.claims, fieldAnalysisClaims); err != nil {
+		return nil, err
+	}
+	if resealed.issues, err = reseal(from.issues, fieldAnalysisIssues); err != nil {
+		return nil, err
+	}
+	if resealed.bias, err = reseal(from.bias, fieldAnalysisBias); err != nil {
+		return nil, err
+	}
+	if resealed.aiDetection, err = reseal(from.aiDetection, fieldAnalysisAIDetection); err != nil {
+		return nil, err
+	}
+	if resealed.moderation, err = reseal(from.moderation, fieldAnalysisModeration); err != nil {
+		return nil, err
+	}
+	if resealed.compliance, err = reseal(from.compliance, fieldAnalysisCompliance); err != nil {
+		return nil, err
+	}
+
+	// An encrypted response is a JSON string of its ciphertext
+	var raw string
+	if json.Unmarshal(from.raw, &raw) == nil && encryption.IsEncrypted(raw) {
+		sealed, err := reseal(&raw, fieldAnalysisRaw)
+		if err != nil {
+			return nil, err
+		}
+		if resealed.raw, err = json.Marshal(*sealed); err != nil {
+			return nil, fmt.Errorf("failed to encode raw response: %w", err)
+		}
+	}
+	return &resealed, nil
+}
+
+// LinkDuplicate points the user's submission at their earliest submission
+// with the same content, as List does for each of a page
+func (s *SubmissionStore) LinkDuplicate(ctx context.Context, userID uuid.UUID, submission *Submission) error {
+	return resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		page := []Submission{*submission}
+		if err := linkDuplicates(ctx, readPool(s.db, s.replica), userID, page); err != nil {
+			return err
+		}
+		submission.DuplicateOf = page[0].DuplicateOf
+		return nil
+	})
+}
+
+// linkDuplicates points each of a page of the user's submissions that
+// repeats earlier content at the earliest submission with it
+func linkDuplicates(ctx context.Context, db *pgxpool.Pool, userID uuid.UUID, submissions []Submission) error {
+	var hashes []string
+	for _, submission := range submissions {
+		if submission.ContentHash != "" {
+			hashes = append(hashes, submission.ContentHash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT DISTINCT ON (content_hash) content_hash, id
+		FROM submissions
+		WHERE user_id = $1 AND content_hash = ANY($2) AND quarantined_at IS NULL AND deleted_at IS NULL
+		ORDER BY content_hash, created_at, id
+	`, userID, hashes)
+	if err != nil {
+		return fmt.Errorf("failed to find duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	earliest := make(map[string]uuid.UUID)
+	for rows.Next() {
+		var hash string
+		var id uuid.UUID
+		if err := rows.Scan(&hash, &id); err != nil {
+			return fmt.Errorf("failed to scan duplicate: %w", err)
+		}
+		earliest[hash] = id
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read duplicates: %w", err)
+	}
+
+	for i := range submissions {
+		if id, ok := earliest[submissions[i].ContentHash]; ok && id != submissions[i].ID {
+			submissions[i].DuplicateOf = &id
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// EmailTokenPurpose distinguishes what a one-time email token is for
+type EmailTokenPurpose string
+
+const (
+	PurposeVerifyEmail   EmailTokenPurpose = "verify_email"
+	PurposePasswordReset EmailTokenPurpose = "password_reset"
+	PurposeChangeEmail   EmailTokenPurpose = "change_email"
+)
+
+// ErrInvalidToken is returned for unknown, used or expired tokens
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// EmailToken is a one-time token sent by email
+type EmailToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   EmailTokenPurpose
+	Email     string
+	ExpiresAt time.Time
+}
+
+// EmailTokenStore handles one-time tokens for email verification and password resets.
+// Only a SHA-256 hash of each token is stored.
+type EmailTokenStore struct {
+	db *pgxpool.Pool
+}
+
+// NewEmailTokenStore creates a new email token store
+func NewEmailTokenStore(db *pgxpool.Pool) *EmailTokenStore {
+	return &EmailTokenStore{db: db}
+}
+
+// Create issues a new token for the user and returns its plaintext value.
+// Earlier unused tokens for the same purpose are invalidated.
+func (s *EmailTokenStore) Create(ctx context.Context, userID uuid.UUID, purpose EmailTokenPurpose, email string, ttl time.Duration) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.create(ctx, userID, purpose, email, token, ttl)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// create runs one attempt of Create's transaction
+func (s *EmailTokenStore) create(ctx context.Context, userID uuid.UUID, purpose EmailTokenPurpose, email, token string, ttl time.Duration) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE email_tokens SET used_at = NOW()
+		WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+	`, userID, purpose); err != nil {
+		return fmt.Errorf("failed to invalidate tokens: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO email_tokens (user_id, purpose, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, userID, purpose, email, hashToken(token), time.Now().Add(ttl)); err != nil {
+		return fmt.Errorf("failed to create token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit token: %w", err)
+	}
+
+	return nil
+}
+
+// Consume marks a valid token as used and returns it. It returns
+// ErrInvalidToken if the token doesn't exist, was used, or has expired.
+func (s *EmailTokenStore) Consume(ctx context.Context, purpose EmailTokenPurpose, token string) (*EmailToken, error) {
+	query := `
+		UPDATE email_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1
+		  AND purpose = $2
+		  AND used_at IS NULL
+		  AND expires_at > NOW()
+		RETURNING id, user_id, purpose, email, expires_at
+	`
+
+	var t EmailToken
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, query, hashToken(token), purpose).Scan(
+			&t.ID,
+			&t.UserID,
+			&t.Purpose,
+			&t.Email,
+			&t.ExpiresAt,
+		)
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to consume token: %w", err)
+	}
+
+	return &t, nil
+}
+
+// hashToken returns the hex SHA-256 of a token
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,142 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+)
+
+// Fields encrypted at rest. The name is bound into the ciphertext along
+// with the record and its owner, so a value can't be moved to another
+// column, row or account and still decrypt.
+const (
+	fieldSubmissionContent         = "submissions.content"
+	fieldSubmissionRedacted        = "submissions.redacted_content"
+	fieldSubmissionInstructions    = "submissions.instructions"
+	fieldAnalysisSummary           = "analyses.summary"
+	fieldAnalysisRaw               = "analyses.raw_response"
+	fieldAnalysisInstructions      = "analyses.instructions"
+	fieldAnalysisChanges           = "analyses.changes"
+	fieldAnalysisClaims            = "analyses.claims"
+	fieldAnalysisIssues            = "analyses.issues"
+	fieldAnalysisBias              = "analyses.bias"
+	fieldAnalysisAIDetection       = "analyses.ai_detection"
+	fieldAnalysisModeration        = "analyses.moderation"
+	fieldAnalysisCompliance        = "analyses.compliance"
+	fieldAnalysisCalls             = "analysis_artifacts.calls"
+	fieldThreadTitle               = "threads.title"
+	fieldThreadMessage             = "thread_messages.content"
+	fieldTranscriptSegments        = "transcriptions.segments"
+	fieldTranscriptionInstructions = "transcriptions.instructions"
+	fieldImportInstructions        = "imports.instructions"
+	fieldSSOClientSecret           = "organization_sso.client_secret"
+)
+
+// excerptSQL selects the first n characters of a content column, or all
+// of it if it is encrypted or escaped plaintext, since neither can be cut
+// in SQL; pass the result through openExcerpt
+const excerptSQL = `CASE WHEN %[1]s LIKE 'enc:%%' THEN %[1]s ELSE LEFT(%[1]s, %[2]d) END`
+
+// errNoEncryptionKey is returned when an encrypted value is read by a
+// store without a key
+var errNoEncryptionKey = errors.New("value is encrypted but no encryption key is configured")
+
+// record is a row holding encrypted fields and the user who owns it, or
+// uuid.Nil when nobody does. Values are bound to the record they are
+// stored in.
+type record struct {
+	id, owner uuid.UUID
+}
+
+// newRecord picks the ID of a row owner is about to insert. Values are
+// sealed before the INSERT and must already name their row, so rows with
+// encrypted fields can't take an ID generated by the database.
+func newRecord(owner uuid.UUID) record {
+	return record{uuid.New(), owner}
+}
+
+// bind names where the record's value of field is stored
+func (r record) bind(field string) encryption.Binding {
+	binding := encryption.Binding{Field: field, Record: r.id.String()}
+	if r.owner != uuid.Nil {
+		binding.Owner = r.owner.String()
+	}
+	return binding
+}
+
+// sealField encrypts value when enc is set. Otherwise plaintext that
+// could pass for ciphertext is escaped.
+func sealField(ctx context.Context, enc *encryption.Encryptor, value string, binding encryption.Binding) (string, error) {
+	if enc == nil {
+		return encryption.Escape(value), nil
+	}
+	return enc.Encrypt(ctx, value, binding)
+}
+
+// sealOptionalField encrypts a nullable value when enc is set
+func sealOptionalField(ctx context.Context, enc *encryption.Encryptor, value *string, binding encryption.Binding) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	sealed, err := sealField(ctx, enc, *value, binding)
+	return &sealed, err
+}
+
+// openField decrypts value if it is encrypted. Values written before
+// encryption was enabled are plaintext and pass through, unescaped.
+func openField(ctx context.Context, enc *encryption.Encryptor, value string, binding encryption.Binding) (string, error) {
+	if !encryption.IsEncrypted(value) {
+		return encryption.Unescape(value), nil
+	}
+	if enc == nil {
+		return "", errNoEncryptionKey
+	}
+	return enc.Decrypt(ctx, value, binding)
+}
+
+// resealField re-encrypts a value stored for one record to store it in
+// another, such as a copy. Plaintext passes through as it is.
+func resealField(ctx context.Context, enc *encryption.Encryptor, value string, from, to encryption.Binding) (string, error) {
+	if !encryption.IsEncrypted(value) {
+		return value, nil
+	}
+	plain, err := openField(ctx, enc, value, from)
+	if err != nil {
+		return "", err
+	}
+	return enc.Encrypt(ctx, plain, to)
+}
+
+// resealOptionalField re-encrypts a nullable value for another record
+func resealOptionalField(ctx context.Context, enc *encryption.Encryptor, value *string, from, to encryption.Binding) (*string, error) {
+	if value == nil {
+		return nil, nil
+	}
+	resealed, err := resealField(ctx, enc, *value, from, to)
+	return &resealed, err
+}
+
+// openExcerpt decrypts the content of a submission selected with
+// excerptSQL and cuts it to n characters
+func openExcerpt(ctx context.Context, enc *encryption.Encryptor, value string, submission record, n int) (string, error) {
+	value, err := openField(ctx, enc, value, submission.bind(fieldSubmissionContent))
+	if err != nil {
+		return "", err
+	}
+	if utf8.RuneCountInString(value) <= n {
+		return value, nil
+	}
+
+	runes := 0
+	for i := range value {
+		if runes == n {
+			return value[:i], nil
+		}
+		runes++
+	}
+	return value, nil
+}
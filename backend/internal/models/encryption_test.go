@@ -0,0 +1,176 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+)
+
+func TestOpenExcerpt(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	enc := encryption.New(keys)
+	submission := record{uuid.New(), uuid.New()}
+
+	sealed, err := sealField(ctx, enc, "héllo wörld", submission.bind(fieldSubmissionContent))
+	if err != nil {
+		t.Fatalf("sealField() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cipher  *encryption.Encryptor
+		value   string
+		n       int
+		want    string
+		wantErr error
+	}{
+		{name: "plaintext cut by SQL", value: "héllo", n: 5, want: "héllo"},
+		{name: "encrypted, cut by characters", cipher: enc, value: sealed, n: 4, want: "héll"},
+		{name: "encrypted, shorter than n", cipher: enc, value: sealed, n: 50, want: "héllo wörld"},
+		{name: "encrypted without a key", value: sealed, n: 4, wantErr: errNoEncryptionKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := openExcerpt(ctx, tt.cipher, tt.value, submission, tt.n)
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("openExcerpt() = %q, %v, want %q, %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExcerptSQL(t *testing.T) {
+	got := fmt.Sprintf(excerptSQL, "s.content", 280)
+	want := `CASE WHEN s.content LIKE 'enc:%' THEN s.content ELSE LEFT(s.content, 280) END`
+	if got != want {
+		t.Errorf("excerptSQL = %s, want %s", got, want)
+	}
+}
+
+func TestSealField_WithoutEncryption(t *testing.T) {
+	ctx := context.Background()
+	submission := record{uuid.New(), uuid.New()}
+
+	// Text that looks like ciphertext is stored escaped and reads back as
+	// written, rather than failing to decrypt
+	for _, plaintext := range []string{"hello", "enc:v1:k1:abc:def", "enc:v2:k1:abc:def", "enc:plain:hello"} {
+		stored, err := sealField(ctx, nil, plaintext, submission.bind(fieldSubmissionContent))
+		if err != nil {
+			t.Fatalf("sealField() error = %v", err)
+		}
+		if encryption.IsEncrypted(stored) {
+			t.Errorf("sealField(%q) = %q, which passes for ciphertext", plaintext, stored)
+		}
+		if got, err := openField(ctx, nil, stored, submission.bind(fieldSubmissionContent)); err != nil || got != plaintext {
+			t.Errorf("openField() = %q, %v, want %q", got, err, plaintext)
+		}
+		if got, err := openExcerpt(ctx, nil, stored, submission, 7); err != nil || got != string([]rune(plaintext)[:min(7, len([]rune(plaintext)))]) {
+			t.Errorf("openExcerpt() = %q, %v", got, err)
+		}
+	}
+
+	store := &SubmissionStore{}
+	for _, raw := range []string{`{"sentiment":"positive"}`, `"enc:v1:k1:abc:def"`} {
+		got, err := store.openRaw(ctx, submission, escapeRaw(json.RawMessage(raw)))
+		if err != nil || string(got) != raw {
+			t.Errorf("openRaw(escapeRaw(%s)) = %s, %v", raw, got, err)
+		}
+	}
+}
+
+func TestSealField_BoundToRecord(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	enc := encryption.New(keys)
+
+	owner := uuid.New()
+	original := record{uuid.New(), owner}
+	sealed, err := sealField(ctx, enc, "Quarterly numbers", original.bind(fieldAnalysisSummary))
+	if err != nil {
+		t.Fatalf("sealField() error = %v", err)
+	}
+
+	// Ciphertext moved to another row, account or column doesn't open
+	moved := map[string]encryption.Binding{
+		"another record": record{uuid.New(), owner}.bind(fieldAnalysisSummary),
+		"another owner":  record{original.id, uuid.New()}.bind(fieldAnalysisSummary),
+		"no owner":       record{id: original.id}.bind(fieldAnalysisSummary),
+		"another field":  original.bind(fieldAnalysisInstructions),
+	}
+	for name, binding := range moved {
+		if got, err := openField(ctx, enc, sealed, binding); err == nil {
+			t.Errorf("openField() under %s = %q, want an error", name, got)
+		}
+	}
+
+	// A copy is sealed again for its own record
+	copied := record{uuid.New(), uuid.New()}
+	resealed, err := resealField(ctx, enc, sealed, original.bind(fieldAnalysisSummary), copied.bind(fieldAnalysisSummary))
+	if err != nil {
+		t.Fatalf("resealField() error = %v", err)
+	}
+	if got, err := openField(ctx, enc, resealed, copied.bind(fieldAnalysisSummary)); err != nil || got != "Quarterly numbers" {
+		t.Errorf("openField() of the copy = %q, %v, want %q", got, err, "Quarterly numbers")
+	}
+
+	// Plaintext stays as stored
+	if got, err := resealField(ctx, nil, "enc:plain:enc:v2:x", original.bind(fieldAnalysisSummary), copied.bind(fieldAnalysisSummary)); err != nil || got != "enc:plain:enc:v2:x" {
+		t.Errorf("resealField() of plaintext = %q, %v", got, err)
+	}
+}
+
+func TestContentHasher(t *testing.T) {
+	ctx := context.Background()
+	newKeys := func(entries ...string) *encryption.Encryptor {
+		keys, err := encryption.ParseMasterKeys(entries)
+		if err != nil {
+			t.Fatalf("ParseMasterKeys() error = %v", err)
+		}
+		return encryption.New(keys)
+	}
+	k1 := "k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))
+	k2 := "k2:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("j", 32)))
+
+	// Without encryption, content is hashed with plain SHA-256
+	hash, keyID, err := newContentHasher(nil).hash(ctx, "hello")
+	if err != nil || hash != ContentHash("hello") || keyID != nil {
+		t.Errorf("hash() without encryption = %q, %v, %v, want the SHA-256 hash", hash, keyID, err)
+	}
+
+	keyed := newContentHasher(newKeys(k1))
+	hash, keyID, err = keyed.hash(ctx, "hello")
+	if err != nil || keyID == nil || *keyID != "k1" {
+		t.Fatalf("hash() with encryption = %q, %v, %v, want a hash keyed with k1", hash, keyID, err)
+	}
+	if hash == ContentHash("hello") || len(hash) != 64 {
+		t.Errorf("hash() with encryption = %q, want a keyed 64-character hash", hash)
+	}
+	if again, _, _ := keyed.hash(ctx, "hello"); again != hash {
+		t.Errorf("hash() again = %q, want %q", again, hash)
+	}
+	if other, _, _ := keyed.hash(ctx, "hello!"); other == hash {
+		t.Error("hash() of other content matched")
+	}
+
+	// Rotating the master key changes the hash key
+	rotated, keyID, _ := newContentHasher(newKeys(k2, k1)).hash(ctx, "hello")
+	if rotated == hash || keyID == nil || *keyID != "k2" {
+		t.Errorf("hash() after rotation = %q, %v, want another hash keyed with k2", rotated, keyID)
+	}
+}
@@ -0,0 +1,212 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// flagKeyPattern restricts flag keys to lowercase dotted or dashed names
+var flagKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{0,99}$`)
+
+// FeatureFlag turns a feature on for listed users and a stable percentage
+// of everyone else. A disabled flag is off for all users.
+type FeatureFlag struct {
+	Key            string         `json:"key"`
+	Description    string         `json:"description"`
+	Enabled        bool           `json:"enabled"`
+	RolloutPercent int            `json:"rollout_percent"`
+	UserIDs        []uuid.UUID    `json:"user_ids"`
+	UpdatedBy      string         `json:"updated_by"`
+	CreatedAt      timestamp.Time `json:"created_at"`
+	UpdatedAt      timestamp.Time `json:"updated_at"`
+}
+
+// ValidateFlagKey checks a feature flag key
+func ValidateFlagKey(key string) error {
+	if !flagKeyPattern.MatchString(key) {
+		return errors.New("flag key must be 1-100 lowercase letters, digits, '.', '_' or '-'")
+	}
+	return nil
+}
+
+// Validate checks the flag's key and rollout percentage
+func (f *FeatureFlag) Validate() error {
+	if err := ValidateFlagKey(f.Key); err != nil {
+		return err
+	}
+	if f.RolloutPercent < 0 || f.RolloutPercent > 100 {
+		return errors.New("rollout_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// EnabledFor reports whether the flag is on for userID. Listed users always
+// get the feature; the rest are bucketed by a hash of the flag key and user
+// ID, so a user stays in or out as the percentage grows, and different flags
+// pick different users. Anonymous requests (uuid.Nil) only see flags rolled
+// out to everyone.
+func (f *FeatureFlag) EnabledFor(userID uuid.UUID) bool {
+	if !f.Enabled {
+		return false
+	}
+	if f.RolloutPercent >= 100 {
+		return true
+	}
+	if userID == uuid.Nil {
+		return false
+	}
+	if slices.Contains(f.UserIDs, userID) {
+		return true
+	}
+	return rolloutBucket(f.Key, userID) < f.RolloutPercent
+}
+
+// rolloutBucket places a user in one of 100 buckets for a flag
+func rolloutBucket(key string, userID uuid.UUID) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	h.Write(userID[:])
+	return int(h.Sum32() % 100)
+}
+
+// FeatureFlagStore persists feature flags
+type FeatureFlagStore struct {
+	db *pgxpool.Pool
+}
+
+// NewFeatureFlagStore creates a new feature flag store
+func NewFeatureFlagStore(db *pgxpool.Pool) *FeatureFlagStore {
+	return &FeatureFlagStore{db: db}
+}
+
+const featureFlagColumns = `key, description, enabled, rollout_percent, user_ids::text[], updated_by, created_at, updated_at`
+
+// scanFeatureFlag reads a row selected with featureFlagColumns
+func scanFeatureFlag(row pgx.Row) (*FeatureFlag, error) {
+	var flag FeatureFlag
+	var userIDs []string
+	if err := row.Scan(
+		&flag.Key,
+		&flag.Description,
+		&flag.Enabled,
+		&flag.RolloutPercent,
+		&userIDs,
+		&flag.UpdatedBy,
+		&flag.CreatedAt,
+		&flag.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	flag.UserIDs = make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID in flag %s: %w", flag.Key, err)
+		}
+		flag.UserIDs = append(flag.UserIDs, parsed)
+	}
+
+	return &flag, nil
+}
+
+// List returns every feature flag ordered by key
+func (s *FeatureFlagStore) List(ctx context.Context) ([]FeatureFlag, error) {
+	flags, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]FeatureFlag, error) {
+		rows, err := s.db.Query(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags ORDER BY key`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var flags []FeatureFlag
+		for rows.Next() {
+			flag, err := scanFeatureFlag(rows)
+			if err != nil {
+				return nil, err
+			}
+			flags = append(flags, *flag)
+		}
+		return flags, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feature flags: %w", err)
+	}
+
+	return flags, nil
+}
+
+// Get returns a feature flag by key
+func (s *FeatureFlagStore) Get(ctx context.Context, key string) (*FeatureFlag, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*FeatureFlag, error) {
+		return scanFeatureFlag(s.db.QueryRow(ctx, `SELECT `+featureFlagColumns+` FROM feature_flags WHERE key = $1`, key))
+	})
+}
+
+// Upsert creates or replaces a feature flag and returns the stored row
+func (s *FeatureFlagStore) Upsert(ctx context.Context, flag *FeatureFlag) (*FeatureFlag, error) {
+	if err := flag.Validate(); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]string, 0, len(flag.UserIDs))
+	for _, id := range flag.UserIDs {
+		userIDs = append(userIDs, id.String())
+	}
+
+	query := `
+		INSERT INTO feature_flags (key, description, enabled, rollout_percent, user_ids, updated_by)
+		VALUES ($1, $2, $3, $4, $5::uuid[], $6)
+		ON CONFLICT (key) DO UPDATE SET
+			description = EXCLUDED.description,
+			enabled = EXCLUDED.enabled,
+			rollout_percent = EXCLUDED.rollout_percent,
+			user_ids = EXCLUDED.user_ids,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING ` + featureFlagColumns
+
+	stored, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*FeatureFlag, error) {
+		return scanFeatureFlag(s.db.QueryRow(ctx, query,
+			flag.Key,
+			flag.Description,
+			flag.Enabled,
+			flag.RolloutPercent,
+			userIDs,
+			flag.UpdatedBy,
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save feature flag: %w", err)
+	}
+
+	return stored, nil
+}
+
+// Delete removes a feature flag
+func (s *FeatureFlagStore) Delete(ctx context.Context, key string) error {
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, `DELETE FROM feature_flags WHERE key = $1`, key)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFeatureFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		flag    FeatureFlag
+		wantErr bool
+	}{
+		{"valid", FeatureFlag{Key: "analyzer.v2", RolloutPercent: 10}, false},
+		{"dashes and underscores", FeatureFlag{Key: "new-analyzer_beta"}, false},
+		{"empty key", FeatureFlag{Key: ""}, true},
+		{"uppercase key", FeatureFlag{Key: "NewAnalyzer"}, true},
+		{"spaces in key", FeatureFlag{Key: "new analyzer"}, true},
+		{"negative percent", FeatureFlag{Key: "analyzer.v2", RolloutPercent: -1}, true},
+		{"percent over 100", FeatureFlag{Key: "analyzer.v2", RolloutPercent: 101}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.flag.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFeatureFlag_EnabledFor(t *testing.T) {
+	listed := uuid.New()
+	other := uuid.New()
+
+	tests := []struct {
+		name   string
+		flag   FeatureFlag
+		userID uuid.UUID
+		want   bool
+	}{
+		{"disabled", FeatureFlag{Key: "f", Enabled: false, RolloutPercent: 100, UserIDs: []uuid.UUID{listed}}, listed, false},
+		{"listed user", FeatureFlag{Key: "f", Enabled: true, UserIDs: []uuid.UUID{listed}}, listed, true},
+		{"unlisted user at 0%", FeatureFlag{Key: "f", Enabled: true, UserIDs: []uuid.UUID{listed}}, other, false},
+		{"everyone at 100%", FeatureFlag{Key: "f", Enabled: true, RolloutPercent: 100}, other, true},
+		{"anonymous at 100%", FeatureFlag{Key: "f", Enabled: true, RolloutPercent: 100}, uuid.Nil, true},
+		{"anonymous at 99%", FeatureFlag{Key: "f", Enabled: true, RolloutPercent: 99}, uuid.Nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.flag.EnabledFor(tt.userID); got != tt.want {
+				t.Errorf("EnabledFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeatureFlag_EnabledFor_Rollout(t *testing.T) {
+	users := make([]uuid.UUID, 2000)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	count := func(flag FeatureFlag) map[uuid.UUID]bool {
+		on := make(map[uuid.UUID]bool)
+		for _, id := range users {
+			if flag.EnabledFor(id) {
+				on[id] = true
+			}
+		}
+		return on
+	}
+
+	ten := count(FeatureFlag{Key: "analyzer.v2", Enabled: true, RolloutPercent: 10})
+	fifty := count(FeatureFlag{Key: "analyzer.v2", Enabled: true, RolloutPercent: 50})
+
+	// Roughly the requested share, with generous bounds for randomness
+	if n := len(ten); n < 120 || n > 280 {
+		t.Errorf("10%% rollout enabled %d of %d users", n, len(users))
+	}
+	if n := len(fifty); n < 850 || n > 1150 {
+		t.Errorf("50%% rollout enabled %d of %d users", n, len(users))
+	}
+
+	// Widening a rollout keeps everyone who already had the feature
+	for id := range ten {
+		if !fifty[id] {
+			t.Fatalf("user %s lost the feature when the rollout grew", id)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// MaxFeedsPerUser caps how many feeds one user can monitor
+const MaxFeedsPerUser = 20
+
+// ErrFeedLimit is returned when a user already has MaxFeedsPerUser feeds
+var ErrFeedLimit = fmt.Errorf("a user can monitor at most %d feeds", MaxFeedsPerUser)
+
+// Feed is an RSS or Atom feed whose new items are submitted for analysis
+type Feed struct {
+	ID           uuid.UUID       `json:"id"`
+	UserID       uuid.UUID       `json:"user_id"`
+	URL          string          `json:"url"`
+	Title        *string         `json:"title"`
+	LastPolledAt *timestamp.Time `json:"last_polled_at"`
+	LastError    *string         `json:"last_error"`
+	CreatedAt    timestamp.Time  `json:"created_at"`
+	UpdatedAt    timestamp.Time  `json:"updated_at"`
+}
+
+// FeedItem is an item seen in a feed. SubmissionID is nil for items that
+// weren't submitted, or whose submission has since been deleted.
+type FeedItem struct {
+	ID           uuid.UUID       `json:"id"`
+	FeedID       uuid.UUID       `json:"feed_id"`
+	GUID         string          `json:"guid"`
+	Title        *string         `json:"title"`
+	Link         *string         `json:"link"`
+	PublishedAt  *timestamp.Time `json:"published_at"`
+	SubmissionID *uuid.UUID      `json:"submission_id"`
+	CreatedAt    timestamp.Time  `json:"created_at"`
+}
+
+// FeedDigestItem is a submitted feed item with the latest analysis of its
+// submission, if there is one yet
+type FeedDigestItem struct {
+	FeedItem
+	Status         SubmissionStatus `json:"status"`
+	Sentiment      *string          `json:"sentiment"`
+	SentimentScore *float64         `json:"sentiment_score"`
+	Summary        *string          `json:"summary"`
+}
+
+// FeedDigest summarizes the latest submitted items of a feed
+type FeedDigest struct {
+	Feed                  Feed             `json:"feed"`
+	Items                 []FeedDigestItem `json:"items"`
+	Analyzed              int              `json:"analyzed"`
+	Pending               int              `json:"pending"`
+	Sentiment             map[string]int   `json:"sentiment"`
+	AverageSentimentScore *float64         `json:"average_sentiment_score"`
+}
+
+// NewFeedDigest counts the sentiment of the analyzed items. Items still
+// queued or processing are pending; failed and canceled ones count as
+// neither.
+func NewFeedDigest(feed Feed, items []FeedDigestItem) *FeedDigest {
+	digest := &FeedDigest{
+		Feed:      feed,
+		Items:     items,
+		Sentiment: map[string]int{"positive": 0, "neutral": 0, "negative": 0},
+	}
+
+	var total float64
+	scored := 0
+	for _, item := range items {
+		switch {
+		case item.Sentiment != nil:
+			digest.Analyzed++
+			digest.Sentiment[*item.Sentiment]++
+			if item.SentimentScore != nil {
+				total += *item.SentimentScore
+				scored++
+			}
+		case item.Status == StatusQueued || item.Status == StatusProcessing:
+			digest.Pending++
+		}
+	}
+	if scored > 0 {
+		average := total / float64(scored)
+		digest.AverageSentimentScore = &average
+	}
+	return digest
+}
+
+// FeedStore persists monitored feeds and the items seen in them
+type FeedStore struct {
+	db     *pgxpool.Pool
+	cipher *encryption.Encryptor
+}
+
+// NewFeedStore creates a new feed store
+func NewFeedStore(db *pgxpool.Pool) *FeedStore {
+	return &FeedStore{db: db}
+}
+
+// WithEncryption decrypts the analysis summaries in digests and returns
+// the store
+func (s *FeedStore) WithEncryption(cipher *encryption.Encryptor) *FeedStore {
+	s.cipher = cipher
+	return s
+}
+
+const feedColumns = `id, user_id, url, title, last_polled_at, last_error, created_at, updated_at`
+
+// scanFeed reads a row selected with feedColumns
+func scanFeed(row pgx.Row) (*Feed, error) {
+	var f Feed
+	if err := row.Scan(
+		&f.ID,
+		&f.UserID,
+		&f.URL,
+		&f.Title,
+		&f.LastPolledAt,
+		&f.LastError,
+		&f.CreatedAt,
+		&f.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// queryFeeds runs a query selecting feedColumns
+func (s *FeedStore) queryFeeds(ctx context.Context, query string, args ...interface{}) ([]Feed, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Feed, error) {
+		rows, err := s.db.Query(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		feeds := []Feed{}
+		for rows.Next() {
+			feed, err := scanFeed(rows)
+			if err != nil {
+				return nil, err
+			}
+			feeds = append(feeds, *feed)
+		}
+		return feeds, rows.Err()
+	})
+}
+
+// List returns the user's feeds, oldest first
+func (s *FeedStore) List(ctx context.Context, userID uuid.UUID) ([]Feed, error) {
+	feeds, err := s.queryFeeds(ctx, `SELECT `+feedColumns+` FROM feeds WHERE user_id = $1 ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feeds: %w", err)
+	}
+	return feeds, nil
+}
+
+// ListDue returns up to limit feeds not polled since before, least
+// recently polled first
+func (s *FeedStore) ListDue(ctx context.Context, before time.Time, limit int) ([]Feed, error) {
+	query := `
+		SELECT ` + feedColumns + `
+		FROM feeds
+		WHERE last_polled_at IS NULL OR last_polled_at < $1
+		ORDER BY last_polled_at NULLS FIRST
+		LIMIT $2
+	`
+
+	feeds, err := s.queryFeeds(ctx, query, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feeds due for polling: %w", err)
+	}
+	return feeds, nil
+}
+
+// Get retrieves a feed by ID regardless of owner. It is meant for
+// background jobs; handlers should use GetByID.
+func (s *FeedStore) Get(ctx context.Context, id uuid.UUID) (*Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Feed, error) {
+		return scanFeed(s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// GetByID retrieves a feed owned by the given user
+func (s *FeedStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*Feed, error) {
+	query := `SELECT ` + feedColumns + ` FROM feeds WHERE id = $1 AND user_id = $2`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Feed, error) {
+		return scanFeed(s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// Create starts monitoring a feed for a user. It returns ErrFeedLimit once
+// the user has MaxFeedsPerUser feeds, and a unique violation if the user
+// already monitors the URL.
+func (s *FeedStore) Create(ctx context.Context, userID uuid.UUID, url string) (*Feed, error) {
+	// The count isn't locked, so feeds added in parallel may end a little
+	// past MaxFeedsPerUser. What the limit bounds is how much one user has
+	// the poller fetch, which a feed or two more doesn't change.
+	query := `
+		INSERT INTO feeds (user_id, url)
+		SELECT $1, $2
+		WHERE (SELECT COUNT(*) FROM feeds WHERE user_id = $1) < $3
+		RETURNING ` + feedColumns
+
+	feed, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Feed, error) {
+		return scanFeed(s.db.QueryRow(ctx, query, userID, url, MaxFeedsPerUser))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFeedLimit
+		}
+		return nil, fmt.Errorf("failed to create feed: %w", err)
+	}
+
+	return feed, nil
+}
+
+// Delete stops monitoring a feed owned by the given user. Submissions
+// created from its items are kept.
+func (s *FeedStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM feeds WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete feed: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// MarkPolled records a poll of a feed, with the title it reported or the
+// error that stopped it. A nil title keeps the previous one.
+func (s *FeedStore) MarkPolled(ctx context.Context, id uuid.UUID, title, pollErr *string) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE feeds
+			SET title = COALESCE($2, title), last_error = $3, last_polled_at = NOW(), updated_at = NOW()
+			WHERE id = $1
+		`, id, title, pollErr)
+		if err != nil {
+			return fmt.Errorf("failed to mark feed polled: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddItem records an item seen in a feed. It returns pgx.ErrNoRows if the
+// feed already has an item with the same GUID.
+func (s *FeedStore) AddItem(ctx context.Context, item *FeedItem) (*FeedItem, error) {
+	query := `
+		INSERT INTO feed_items (feed_id, guid, title, link, published_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (feed_id, guid) DO NOTHING
+		RETURNING id, feed_id, guid, title, link, published_at, submission_id, created_at
+	`
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*FeedItem, error) {
+		var stored FeedItem
+		err := s.db.QueryRow(ctx, query, item.FeedID, item.GUID, item.Title, item.Link, item.PublishedAt).Scan(
+			&stored.ID,
+			&stored.FeedID,
+			&stored.GUID,
+			&stored.Title,
+			&stored.Link,
+			&stored.PublishedAt,
+			&stored.SubmissionID,
+			&stored.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &stored, nil
+	})
+}
+
+// AttachSubmission links an item to the submission created from it
+func (s *FeedStore) AttachSubmission(ctx context.Context, itemID, submissionID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `UPDATE feed_items SET submission_id = $2 WHERE id = $1`, itemID, submissionID)
+		if err != nil {
+			return fmt.Errorf("failed to attach submission to feed item: %w", err)
+		}
+		return nil
+	})
+}
+
+// DigestItems returns the latest limit submitted items of a feed owned by
+// the given user, newest first, with the latest analysis of each. Items
+// whose submission was quarantined or trashed are left out.
+func (s *FeedStore) DigestItems(ctx context.Context, userID, feedID uuid.UUID, limit int) ([]FeedDigestItem, error) {
+	query := `
+		SELECT i.id, i.feed_id, i.guid, i.title, i.link, i.published_at, i.submission_id, i.created_at,
+			s.status, a.id, a.sentiment, a.sentiment_score, a.summary
+		FROM feed_items i
+		JOIN feeds f ON f.id = i.feed_id
+		JOIN submissions s ON s.id = i.submission_id
+		LEFT JOIN LATERAL (
+			SELECT id, sentiment, sentiment_score, summary
+			FROM analyses
+			WHERE submission_id = s.id
+			ORDER BY created_at DESC
+			LIMIT 1
+		) a ON TRUE
+		WHERE i.feed_id = $1 AND f.user_id = $2 AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY COALESCE(i.published_at, i.created_at) DESC
+		LIMIT $3
+	`
+
+	// The analysis of each item, to open its summary with
+	var analyses []*uuid.UUID
+	items, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]FeedDigestItem, error) {
+		analyses = nil
+		rows, err := s.db.Query(ctx, query, feedID, userID, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		items := []FeedDigestItem{}
+		for rows.Next() {
+			var item FeedDigestItem
+			var analysisID *uuid.UUID
+			if err := rows.Scan(
+				&item.ID,
+				&item.FeedID,
+				&item.GUID,
+				&item.Title,
+				&item.Link,
+				&item.PublishedAt,
+				&item.SubmissionID,
+				&item.CreatedAt,
+				&item.Status,
+				&analysisID,
+				&item.Sentiment,
+				&item.SentimentScore,
+				&item.Summary,
+			); err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			analyses = append(analyses, analysisID)
+		}
+		return items, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list feed digest: %w", err)
+	}
+
+	for i := range items {
+		summary := items[i].Summary
+		if summary == nil || analyses[i] == nil {
+			continue
+		}
+		// Profiles can skip the summary, which leaves it empty
+		plain, err := openField(ctx, s.cipher, *summary, record{*analyses[i], userID}.bind(fieldAnalysisSummary))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt summary: %w", err)
+		}
+		items[i].Summary = nil
+		if plain != "" {
+			items[i].Summary = &plain
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,31 @@
+package models
+
+import "testing"
+
+func TestNewFeedDigest(t *testing.T) {
+	positive, negative := "positive", "negative"
+	high, low := 0.8, -0.4
+
+	digest := NewFeedDigest(Feed{URL: "https://example.com/feed"}, []FeedDigestItem{
+		{Status: StatusCompleted, Sentiment: &positive, SentimentScore: &high},
+		{Status: StatusCompleted, Sentiment: &negative, SentimentScore: &low},
+		{Status: StatusArchived, Sentiment: &positive},
+		{Status: StatusQueued},
+		{Status: StatusProcessing},
+		{Status: StatusFailed},
+	})
+
+	if digest.Analyzed != 3 || digest.Pending != 2 {
+		t.Errorf("Analyzed, Pending = %d, %d, want 3, 2", digest.Analyzed, digest.Pending)
+	}
+	if digest.Sentiment["positive"] != 2 || digest.Sentiment["negative"] != 1 || digest.Sentiment["neutral"] != 0 {
+		t.Errorf("Sentiment = %v", digest.Sentiment)
+	}
+	if digest.AverageSentimentScore == nil || *digest.AverageSentimentScore < 0.199 || *digest.AverageSentimentScore > 0.201 {
+		t.Errorf("AverageSentimentScore = %v, want 0.2", digest.AverageSentimentScore)
+	}
+
+	if empty := NewFeedDigest(Feed{}, nil); empty.AverageSentimentScore != nil || empty.Analyzed != 0 {
+		t.Errorf("empty digest = %+v", empty)
+	}
+}
@@ -0,0 +1,283 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// GlossaryKind is what a glossary entry tells the analysis about a phrase
+type GlossaryKind string
+
+const (
+	// GlossaryTerm is domain vocabulary, optionally with its meaning
+	GlossaryTerm GlossaryKind = "term"
+	// GlossaryBrand is a brand or product name, spelled as listed
+	GlossaryBrand GlossaryKind = "brand"
+	// GlossaryBanned is a phrase members must not use, optionally with
+	// what to write instead
+	GlossaryBanned GlossaryKind = "banned"
+)
+
+// Glossary limits
+const (
+	MaxGlossaryEntries           = 500
+	MaxGlossaryPhraseLength      = 100
+	MaxGlossaryDescriptionLength = 300
+)
+
+// GlossaryEntry is a phrase in an organization's glossary
+type GlossaryEntry struct {
+	Kind   GlossaryKind `json:"kind"`
+	Phrase string       `json:"phrase"`
+	// Description explains a term or brand to the analysis
+	Description string `json:"description,omitempty"`
+	// Replacement is what to write instead of a banned phrase
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Glossary is an organization's custom vocabulary
+type Glossary struct {
+	OrgID   uuid.UUID       `json:"org_id"`
+	Entries []GlossaryEntry `json:"entries"`
+	// UpdatedAt is when the entries last changed, or nil if there are none
+	UpdatedAt *timestamp.Time `json:"updated_at"`
+}
+
+// Of returns the entries of one kind, in order
+func (g *Glossary) Of(kind GlossaryKind) []GlossaryEntry {
+	if g == nil {
+		return nil
+	}
+	var entries []GlossaryEntry
+	for _, e := range g.Entries {
+		if e.Kind == kind {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// ValidateGlossary checks a glossary's entries, keyed by field for a
+// validation error response. A phrase may be listed once per kind,
+// ignoring case.
+func ValidateGlossary(entries []GlossaryEntry) map[string]string {
+	errs := make(map[string]string)
+	if len(entries) > MaxGlossaryEntries {
+		errs["entries"] = fmt.Sprintf("at most %d entries are allowed", MaxGlossaryEntries)
+		return errs
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range entries {
+		field := fmt.Sprintf("entries[%d]", i)
+		switch e.Kind {
+		case GlossaryTerm, GlossaryBrand, GlossaryBanned:
+		default:
+			errs[field+".kind"] = "must be one of: term, brand, banned"
+		}
+
+		phrase := strings.TrimSpace(e.Phrase)
+		key := string(e.Kind) + ":" + strings.ToLower(phrase)
+		switch {
+		case phrase == "":
+			errs[field+".phrase"] = "phrase is required"
+		case utf8.RuneCountInString(phrase) > MaxGlossaryPhraseLength:
+			errs[field+".phrase"] = fmt.Sprintf("must be at most %d characters", MaxGlossaryPhraseLength)
+		case seen[key]:
+			errs[field+".phrase"] = "phrase is listed more than once"
+		}
+		seen[key] = true
+
+		if utf8.RuneCountInString(e.Description) > MaxGlossaryDescriptionLength {
+			errs[field+".description"] = fmt.Sprintf("must be at most %d characters", MaxGlossaryDescriptionLength)
+		}
+		switch {
+		case e.Replacement != "" && e.Kind != GlossaryBanned:
+			errs[field+".replacement"] = "only banned phrases have a replacement"
+		case utf8.RuneCountInString(e.Replacement) > MaxGlossaryPhraseLength:
+			errs[field+".replacement"] = fmt.Sprintf("must be at most %d characters", MaxGlossaryPhraseLength)
+		}
+	}
+	return errs
+}
+
+// ComplianceFlag is a use of a phrase the owner's organizations banned.
+// Start and End are character offsets into the submission content.
+type ComplianceFlag struct {
+	// Phrase is the banned phrase as the glossary lists it
+	Phrase      string `json:"phrase"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// sealCompliance encodes an analysis' compliance flags for the compliance
+// column, encrypting them when enabled
+func (s *SubmissionStore) sealCompliance(ctx context.Context, analysis record, flags []ComplianceFlag) (*string, error) {
+	if flags == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(flags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode compliance flags: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisCompliance))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt compliance flags: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openCompliance decodes a value read from the compliance column
+func (s *SubmissionStore) openCompliance(ctx context.Context, analysis record, value *string) ([]ComplianceFlag, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisCompliance))
+	if err != nil {
+		return nil, err
+	}
+
+	flags := []ComplianceFlag{}
+	if err := json.Unmarshal([]byte(opened), &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// GlossaryStore persists organizations' glossaries
+type GlossaryStore struct {
+	db *pgxpool.Pool
+}
+
+// NewGlossaryStore creates a new glossary store
+func NewGlossaryStore(db *pgxpool.Pool) *GlossaryStore {
+	return &GlossaryStore{db: db}
+}
+
+// Glossary returns an organization's glossary, which has no entries if
+// none were set
+func (s *GlossaryStore) Glossary(ctx context.Context, orgID uuid.UUID) (*Glossary, error) {
+	glossary, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Glossary, error) {
+		return s.glossary(ctx, s.db, orgID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glossary: %w", err)
+	}
+	return glossary, nil
+}
+
+// glossary reads an organization's entries in the order they were listed
+func (s *GlossaryStore) glossary(ctx context.Context, db rowsQuerier, orgID uuid.UUID) (*Glossary, error) {
+	rows, err := db.Query(ctx, `
+		SELECT kind, phrase, description, replacement, updated_at
+		FROM glossary_entries
+		WHERE org_id = $1
+		ORDER BY position
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	glossary := &Glossary{OrgID: orgID, Entries: []GlossaryEntry{}}
+	for rows.Next() {
+		var e GlossaryEntry
+		var updatedAt timestamp.Time
+		if err := rows.Scan(&e.Kind, &e.Phrase, &e.Description, &e.Replacement, &updatedAt); err != nil {
+			return nil, err
+		}
+		glossary.Entries = append(glossary.Entries, e)
+		if glossary.UpdatedAt == nil || updatedAt.After(glossary.UpdatedAt.Time) {
+			glossary.UpdatedAt = &updatedAt
+		}
+	}
+	return glossary, rows.Err()
+}
+
+// SetGlossary replaces an organization's entries and returns its glossary.
+// An empty list removes the glossary.
+func (s *GlossaryStore) SetGlossary(ctx context.Context, orgID uuid.UUID, entries []GlossaryEntry) (*Glossary, error) {
+	if errs := ValidateGlossary(entries); len(errs) > 0 {
+		return nil, errors.New("invalid glossary entries")
+	}
+
+	glossary, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Glossary, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM glossary_entries WHERE org_id = $1`, orgID); err != nil {
+			return nil, err
+		}
+		for i, e := range entries {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO glossary_entries (org_id, kind, phrase, description, replacement, position)
+				VALUES ($1, $2, $3, $4, $5, $6)
+			`, orgID, e.Kind, strings.TrimSpace(e.Phrase), e.Description, e.Replacement, i); err != nil {
+				return nil, err
+			}
+		}
+
+		glossary, err := s.glossary(ctx, tx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return glossary, tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set glossary: %w", err)
+	}
+	return glossary, nil
+}
+
+// GlossaryForUser returns the glossary in force for userID's submissions,
+// or nil if none of their organizations has one. A member of several
+// organizations gets the entries of all of them, each phrase once per
+// kind.
+func (s *GlossaryStore) GlossaryForUser(ctx context.Context, userID uuid.UUID) (*Glossary, error) {
+	glossary, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Glossary, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT DISTINCT ON (g.kind, LOWER(g.phrase)) g.kind, g.phrase, g.description, g.replacement
+			FROM glossary_entries g
+			JOIN organization_members m ON m.org_id = g.org_id
+			WHERE m.user_id = $1
+			ORDER BY g.kind, LOWER(g.phrase), g.org_id
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var entries []GlossaryEntry
+		for rows.Next() {
+			var e GlossaryEntry
+			if err := rows.Scan(&e.Kind, &e.Phrase, &e.Description, &e.Replacement); err != nil {
+				return nil, err
+			}
+			entries = append(entries, e)
+		}
+		if err := rows.Err(); err != nil || len(entries) == 0 {
+			return nil, err
+		}
+		return &Glossary{Entries: entries}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get glossary: %w", err)
+	}
+	return glossary, nil
+}
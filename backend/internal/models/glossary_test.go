@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateGlossary(t *testing.T) {
+	tests := []struct {
+		name      string
+		entries   []GlossaryEntry
+		wantField string
+	}{
+		{name: "empty", entries: nil},
+		{name: "valid", entries: []GlossaryEntry{
+			{Kind: GlossaryTerm, Phrase: "churn", Description: "customers who cancel"},
+			{Kind: GlossaryBrand, Phrase: "AcmeCloud"},
+			{Kind: GlossaryBanned, Phrase: "guaranteed", Replacement: "expected"},
+			{Kind: GlossaryBanned, Phrase: "churn"},
+		}},
+		{name: "unknown kind", entries: []GlossaryEntry{{Kind: "slang", Phrase: "lit"}}, wantField: "entries[0].kind"},
+		{name: "blank phrase", entries: []GlossaryEntry{{Kind: GlossaryTerm, Phrase: " "}}, wantField: "entries[0].phrase"},
+		{name: "long phrase", entries: []GlossaryEntry{{Kind: GlossaryTerm, Phrase: strings.Repeat("a", MaxGlossaryPhraseLength+1)}}, wantField: "entries[0].phrase"},
+		{name: "duplicate ignoring case", entries: []GlossaryEntry{
+			{Kind: GlossaryBrand, Phrase: "Acme"},
+			{Kind: GlossaryBrand, Phrase: " ACME"},
+		}, wantField: "entries[1].phrase"},
+		{name: "long description", entries: []GlossaryEntry{
+			{Kind: GlossaryTerm, Phrase: "churn", Description: strings.Repeat("a", MaxGlossaryDescriptionLength+1)},
+		}, wantField: "entries[0].description"},
+		{name: "replacement on a term", entries: []GlossaryEntry{{Kind: GlossaryTerm, Phrase: "churn", Replacement: "attrition"}}, wantField: "entries[0].replacement"},
+		{name: "too many", entries: make([]GlossaryEntry, MaxGlossaryEntries+1), wantField: "entries"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateGlossary(tt.entries)
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Errorf("ValidateGlossary() = %v, want none", errs)
+				}
+				return
+			}
+			if _, ok := errs[tt.wantField]; !ok {
+				t.Errorf("ValidateGlossary() = %v, want an error on %s", errs, tt.wantField)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+const (
+	// HookEventAnalysisCompleted fires when a submission's analysis completes
+	HookEventAnalysisCompleted = "analysis.completed"
+	// HookEventAnalysisFlagged fires when a submission's analysis completes
+	// flagged; see HookAnalysis.Flagged
+	HookEventAnalysisFlagged = "analysis.flagged"
+)
+
+// HookEvents are the events a REST hook can subscribe to
+var HookEvents = []string{HookEventAnalysisCompleted, HookEventAnalysisFlagged}
+
+// HookFields are the fields of a HookAnalysis a REST hook can choose to
+// be sent. The analysis and submission IDs are always sent.
+var HookFields = []string{
+	"sentiment", "sentiment_score", "summary", "topics", "confidence",
+	"excerpt", "completed_at", "language", "labels", "policy_action",
+}
+
+// MaxHooksPerUser caps how many REST hooks one user can subscribe
+const MaxHooksPerUser = 25
+
+// hookExcerptLength is the number of characters of content sent with each
+// analysis
+const hookExcerptLength = 280
+
+// ErrHookLimit is returned when a user already has MaxHooksPerUser hooks
+var ErrHookLimit = fmt.Errorf("a user can subscribe at most %d hooks", MaxHooksPerUser)
+
+// RESTHook is a subscription, made by an automation platform such as
+// Zapier or Make, to have events POSTed to a target URL
+type RESTHook struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"-"`
+	APIKeyID  uuid.UUID `json:"api_key_id"`
+	Event     string    `json:"event"`
+	TargetURL string    `json:"target_url"`
+	// Fields are the HookFields the hook is sent, or empty for all of them
+	Fields    []string       `json:"fields,omitempty"`
+	CreatedAt timestamp.Time `json:"created_at"`
+}
+
+// HookAnalysis is a completed analysis flattened for automation platforms,
+// which map fields rather than walk nested objects. The same shape is
+// delivered to hooks and returned by the polling endpoint, whose clients
+// deduplicate on ID.
+type HookAnalysis struct {
+	ID             uuid.UUID      `json:"id"`
+	SubmissionID   uuid.UUID      `json:"submission_id"`
+	Sentiment      string         `json:"sentiment"`
+	SentimentScore *float64       `json:"sentiment_score"`
+	Summary        string         `json:"summary"`
+	Topics         []string       `json:"topics"`
+	Confidence     *float64       `json:"confidence"`
+	Excerpt        string         `json:"excerpt"`
+	CompletedAt    timestamp.Time `json:"completed_at"`
+	Language       string         `json:"language"`
+	// The taxonomy labels that apply, most confident first
+	Labels []string `json:"labels"`
+	// What the moderation policy decided, or empty when none applies
+	PolicyAction PolicyAction `json:"policy_action"`
+}
+
+// Flagged reports whether the analysis needs a closer look: the
+// moderation policy warned about the content, or the summary has low
+// confidence. Blocked content is quarantined and never sent.
+func (a *HookAnalysis) Flagged() bool {
+	return a.PolicyAction == PolicyWarn || (a.Confidence != nil && *a.Confidence < LowConfidenceThreshold)
+}
+
+// HookStore persists REST hooks and reads the analyses they deliver
+type HookStore struct {
+	db     *pgxpool.Pool
+	cipher *encryption.Encryptor
+}
+
+// NewHookStore creates a new hook store
+func NewHookStore(db *pgxpool.Pool) *HookStore {
+	return &HookStore{db: db}
+}
+
+// WithEncryption decrypts delivered summaries and content and returns the
+// store
+func (s *HookStore) WithEncryption(cipher *encryption.Encryptor) *HookStore {
+	s.cipher = cipher
+	return s
+}
+
+const hookColumns = `h.id, h.user_id, h.api_key_id, h.event, h.target_url, h.fields, h.created_at`
+
+// activeHooks joins hooks to their API key, leaving out hooks whose key
+// has been revoked
+const activeHooks = `rest_hooks h JOIN api_keys k ON k.id = h.api_key_id AND k.revoked_at IS NULL`
+
+// scanHook reads a row selected with hookColumns
+func scanHook(row pgx.Row) (*RESTHook, error) {
+	var h RESTHook
+	if err := row.Scan(&h.ID, &h.UserID, &h.APIKeyID, &h.Event, &h.TargetURL, &h.Fields, &h.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// Create subscribes a hook
+func (s *HookStore) Create(ctx context.Context, hook RESTHook) (*RESTHook, error) {
+	// Automation platforms subscribe one hook per trigger, sometimes in
+	// parallel, and a race between them may pass MaxHooksPerUser by one;
+	// the limit only keeps a user's fan-out of deliveries bounded
+	query := `
+		INSERT INTO rest_hooks AS h (user_id, api_key_id, event, target_url, fields)
+		SELECT $1, $2, $3, $4, COALESCE($6::text[], '{}')
+		WHERE (SELECT COUNT(*) FROM rest_hooks WHERE user_id = $1) < $5
+		RETURNING ` + hookColumns
+
+	created, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*RESTHook, error) {
+		return scanHook(s.db.QueryRow(ctx, query, hook.UserID, hook.APIKeyID, hook.Event, hook.TargetURL, MaxHooksPerUser, hook.Fields))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrHookLimit
+		}
+		return nil, fmt.Errorf("failed to create hook: %w", err)
+	}
+
+	return created, nil
+}
+
+// List returns the user's hooks whose API key is still active, oldest first
+func (s *HookStore) List(ctx context.Context, userID uuid.UUID) ([]RESTHook, error) {
+	return s.list(ctx, `SELECT `+hookColumns+` FROM `+activeHooks+` WHERE h.user_id = $1 ORDER BY h.created_at`, userID)
+}
+
+// ListForEvent returns the user's active hooks subscribed to event
+func (s *HookStore) ListForEvent(ctx context.Context, userID uuid.UUID, event string) ([]RESTHook, error) {
+	return s.list(ctx, `SELECT `+hookColumns+` FROM `+activeHooks+` WHERE h.user_id = $1 AND h.event = $2 ORDER BY h.created_at`, userID, event)
+}
+
+// list runs a query selecting hookColumns
+func (s *HookStore) list(ctx context.Context, query string, args ...any) ([]RESTHook, error) {
+	hooks, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]RESTHook, error) {
+		rows, err := s.db.Query(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		hooks := []RESTHook{}
+		for rows.Next() {
+			hook, err := scanHook(rows)
+			if err != nil {
+				return nil, err
+			}
+			hooks = append(hooks, *hook)
+		}
+		return hooks, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// GetByID retrieves an active hook for delivery
+func (s *HookStore) GetByID(ctx context.Context, id uuid.UUID) (*RESTHook, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*RESTHook, error) {
+		return scanHook(s.db.QueryRow(ctx, `SELECT `+hookColumns+` FROM `+activeHooks+` WHERE h.id = $1`, id))
+	})
+}
+
+// Delete unsubscribes a hook owned by the given user
+func (s *HookStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return s.delete(ctx, `DELETE FROM rest_hooks WHERE id = $1 AND user_id = $2`, id, userID)
+}
+
+// DeleteByID unsubscribes a hook whose target reported it gone
+func (s *HookStore) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	return s.delete(ctx, `DELETE FROM rest_hooks WHERE id = $1`, id)
+}
+
+// delete runs a query deleting one hook
+func (s *HookStore) delete(ctx context.Context, query string, args ...any) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to delete hook: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+const hookAnalysisQuery = `
+	SELECT a.id, a.submission_id, s.user_id, COALESCE(a.sentiment, ''), a.sentiment_score,
+		COALESCE(a.summary, ''), COALESCE(a.topics, '[]'::jsonb), a.confidence,
+		%s, a.created_at, COALESCE(a.language, ''),
+		ARRAY(SELECT l.label FROM submission_labels l WHERE l.analysis_id = a.id ORDER BY l.confidence DESC, l.label),
+		COALESCE(a.policy_decision->>'action', '')
+	FROM analyses a
+	JOIN submissions s ON s.id = a.submission_id
+`
+
+// RecentAnalyses returns the latest limit analyses of the user's
+// completed submissions, newest first, leaving out quarantined ones
+func (s *HookStore) RecentAnalyses(ctx context.Context, userID uuid.UUID, limit int) ([]HookAnalysis, error) {
+	query := fmt.Sprintf(hookAnalysisQuery, fmt.Sprintf(excerptSQL, "s.content", hookExcerptLength)) + `
+		WHERE s.user_id = $1 AND s.status = 'completed' AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY a.created_at DESC
+		LIMIT $2
+	`
+
+	analyses, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]HookAnalysis, error) {
+		rows, err := s.db.Query(ctx, query, userID, limit)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		analyses := []HookAnalysis{}
+		for rows.Next() {
+			a, err := s.scanAnalysis(ctx, rows)
+			if err != nil {
+				return nil, err
+			}
+			analyses = append(analyses, *a)
+		}
+		return analyses, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recent analyses: %w", err)
+	}
+	return analyses, nil
+}
+
+// LatestAnalysis returns the latest analysis of a submission owned by the
+// given user. Quarantined submissions are never sent to integrations, so
+// their analyses return pgx.ErrNoRows.
+func (s *HookStore) LatestAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*HookAnalysis, error) {
+	query := fmt.Sprintf(hookAnalysisQuery, fmt.Sprintf(excerptSQL, "s.content", hookExcerptLength)) + `
+		WHERE a.submission_id = $1 AND s.user_id = $2 AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY a.created_at DESC
+		LIMIT 1
+	`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*HookAnalysis, error) {
+		return s.scanAnalysis(ctx, s.db.QueryRow(ctx, query, submissionID, userID))
+	})
+}
+
+// scanAnalysis reads a row selected with hookAnalysisQuery
+func (s *HookStore) scanAnalysis(ctx context.Context, row pgx.Row) (*HookAnalysis, error) {
+	var a HookAnalysis
+	var topics []byte
+	var userID uuid.UUID
+	if err := row.Scan(
+		&a.ID,
+		&a.SubmissionID,
+		&userID,
+		&a.Sentiment,
+		&a.SentimentScore,
+		&a.Summary,
+		&topics,
+		&a.Confidence,
+		&a.Excerpt,
+		&a.CompletedAt,
+		&a.Language,
+		&a.Labels,
+		&a.PolicyAction,
+	); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if a.Summary, err = openField(ctx, s.cipher, a.Summary, record{a.ID, userID}.bind(fieldAnalysisSummary)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt analysis %s: %w", a.ID, err)
+	}
+	if a.Excerpt, err = openExcerpt(ctx, s.cipher, a.Excerpt, record{a.SubmissionID, userID}, hookExcerptLength); err != nil {
+		return nil, fmt.Errorf("failed to decrypt excerpt of analysis %s: %w", a.ID, err)
+	}
+	if err := json.Unmarshal(topics, &a.Topics); err != nil {
+		return nil, fmt.Errorf("failed to decode topics: %w", err)
+	}
+	if a.Topics == nil {
+		a.Topics = []string{}
+	}
+	return &a, nil
+}
@@ -0,0 +1,320 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// ImportStatus is the state of a bulk import
+type ImportStatus string
+
+const (
+	ImportPending    ImportStatus = "pending"
+	ImportProcessing ImportStatus = "processing"
+	ImportCompleted  ImportStatus = "completed"
+	ImportFailed     ImportStatus = "failed"
+)
+
+// ImportFormat is the file format of a bulk import
+type ImportFormat string
+
+const (
+	ImportCSV   ImportFormat = "csv"
+	ImportJSONL ImportFormat = "jsonl"
+)
+
+// ParseImportFormat validates an import format name
+func ParseImportFormat(s string) (ImportFormat, error) {
+	switch f := ImportFormat(s); f {
+	case ImportCSV, ImportJSONL:
+		return f, nil
+	default:
+		return "", fmt.Errorf("format must be one of: csv, jsonl")
+	}
+}
+
+// MaxImportRowErrors bounds the row errors kept per import; failed_rows
+// still counts them all
+const MaxImportRowErrors = 100
+
+// ImportMapping names the CSV column or JSONL field each submission field
+// is read from
+type ImportMapping struct {
+	Content string `json:"content"`
+	// Instructions, when mapped, overrides the import's instructions for
+	// rows that have them
+	Instructions string `json:"instructions,omitempty"`
+}
+
+// ImportRowError is a row that couldn't be imported. Line is the line of
+// the file the row starts on.
+type ImportRowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Import is a CSV or JSONL file whose rows are imported as submissions in
+// the background, with its progress
+type Import struct {
+	ID            uuid.UUID        `json:"id"`
+	UserID        uuid.UUID        `json:"user_id"`
+	Filename      string           `json:"filename"`
+	Format        ImportFormat     `json:"format"`
+	Mapping       ImportMapping    `json:"mapping"`
+	Analyze       bool             `json:"analyze"`
+	Redact        bool             `json:"redact"`
+	Status        ImportStatus     `json:"status"`
+	Error         *string          `json:"error"`
+	TotalRows     int              `json:"total_rows"`
+	ProcessedRows int              `json:"processed_rows"`
+	ImportedRows  int              `json:"imported_rows"`
+	FailedRows    int              `json:"failed_rows"`
+	RowErrors     []ImportRowError `json:"row_errors"`
+	CreatedAt     timestamp.Time   `json:"created_at"`
+	UpdatedAt     timestamp.Time   `json:"updated_at"`
+
+	// Analysis settings for the submissions created from the rows
+	Instructions *string    `json:"instructions,omitempty"`
+	ProfileID    *uuid.UUID `json:"profile_id,omitempty"`
+	Priority     string     `json:"-"`
+}
+
+// AddRowError counts a failed row, keeping its error if there is room
+func (i *Import) AddRowError(line int, message string) {
+	i.FailedRows++
+	if len(i.RowErrors) < MaxImportRowErrors {
+		i.RowErrors = append(i.RowErrors, ImportRowError{Line: line, Message: message})
+	}
+}
+
+// ImportStore persists bulk imports and their progress
+type ImportStore struct {
+	db      *pgxpool.Pool
+	cipher  *encryption.Encryptor
+	objects storage.Storage
+}
+
+// NewImportStore creates a new import store
+func NewImportStore(db *pgxpool.Pool) *ImportStore {
+	return &ImportStore{db: db}
+}
+
+// WithEncryption encrypts instructions at rest and returns the store. The
+// file is deleted once imported, so it isn't encrypted.
+func (s *ImportStore) WithEncryption(cipher *encryption.Encryptor) *ImportStore {
+	s.cipher = cipher
+	return s
+}
+
+// WithStorage keeps new files in object storage rather than Postgres and
+// returns the store. Files already in Postgres are still read from there.
+func (s *ImportStore) WithStorage(objects storage.Storage) *ImportStore {
+	s.objects = objects
+	return s
+}
+
+const importColumns = `id, user_id, filename, format, mapping, auto_analyze, redact, status, error, total_rows, processed_rows, imported_rows, failed_rows, row_errors, instructions, profile_id, priority, created_at, updated_at`
+
+// scan reads a row selected with importColumns
+func (s *ImportStore) scan(ctx context.Context, row pgx.Row) (*Import, error) {
+	var i Import
+	var mapping, rowErrors []byte
+	if err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Filename,
+		&i.Format,
+		&mapping,
+		&i.Analyze,
+		&i.Redact,
+		&i.Status,
+		&i.Error,
+		&i.TotalRows,
+		&i.ProcessedRows,
+		&i.ImportedRows,
+		&i.FailedRows,
+		&rowErrors,
+		&i.Instructions,
+		&i.ProfileID,
+		&i.Priority,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(mapping, &i.Mapping); err != nil {
+		return nil, fmt.Errorf("failed to decode mapping: %w", err)
+	}
+	i.RowErrors = []ImportRowError{}
+	if err := json.Unmarshal(rowErrors, &i.RowErrors); err != nil {
+		return nil, fmt.Errorf("failed to decode row errors: %w", err)
+	}
+
+	if i.Instructions != nil {
+		plain, err := openField(ctx, s.cipher, *i.Instructions, record{i.ID, i.UserID}.bind(fieldImportInstructions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt instructions: %w", err)
+		}
+		i.Instructions = &plain
+	}
+	return &i, nil
+}
+
+// Create stores an import awaiting processing
+func (s *ImportStore) Create(ctx context.Context, i *Import, data []byte) (*Import, error) {
+	mapping, err := json.Marshal(i.Mapping)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode mapping: %w", err)
+	}
+	r := newRecord(i.UserID)
+	instructions, err := sealOptionalField(ctx, s.cipher, i.Instructions, r.bind(fieldImportInstructions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+
+	inline, dataKey := data, (*string)(nil)
+	if s.objects != nil {
+		contentType := "text/csv"
+		if i.Format == ImportJSONL {
+			contentType = "application/x-ndjson"
+		}
+		key := newObjectKey("imports")
+		if err := s.objects.Put(ctx, key, data, contentType); err != nil {
+			return nil, fmt.Errorf("failed to store import file: %w", err)
+		}
+		inline, dataKey = nil, &key
+	}
+
+	query := `
+		INSERT INTO imports (id, user_id, filename, format, mapping, data, data_key, auto_analyze, redact, instructions, profile_id, priority, total_rows)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING ` + importColumns
+
+	stored, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Import, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query,
+			r.id,
+			i.UserID,
+			i.Filename,
+			i.Format,
+			mapping,
+			inline,
+			dataKey,
+			i.Analyze,
+			i.Redact,
+			instructions,
+			i.ProfileID,
+			i.Priority,
+			i.TotalRows,
+		))
+	})
+	if err != nil {
+		deleteObject(ctx, s.objects, dataKey)
+		return nil, fmt.Errorf("failed to create import: %w", err)
+	}
+
+	return stored, nil
+}
+
+// Get retrieves an import by ID regardless of owner. It is meant for
+// background jobs; handlers should use GetByID.
+func (s *ImportStore) Get(ctx context.Context, id uuid.UUID) (*Import, error) {
+	query := `SELECT ` + importColumns + ` FROM imports WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Import, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// GetByID retrieves an import owned by the given user
+func (s *ImportStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*Import, error) {
+	query := `SELECT ` + importColumns + ` FROM imports WHERE id = $1 AND user_id = $2`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Import, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// Data returns the file of an unfinished import. It returns pgx.ErrNoRows
+// once the file has been deleted.
+func (s *ImportStore) Data(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var data []byte
+	var key *string
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `
+			SELECT data, data_key FROM imports
+			WHERE id = $1 AND (data IS NOT NULL OR data_key IS NOT NULL)
+		`, id).Scan(&data, &key)
+	})
+	if err != nil || key == nil {
+		return data, err
+	}
+	return getObject(ctx, s.objects, *key)
+}
+
+// Progress records how far an unfinished import has got and marks it
+// processing. It returns pgx.ErrNoRows once the import has finished.
+func (s *ImportStore) Progress(ctx context.Context, i *Import) error {
+	rowErrors, err := json.Marshal(i.RowErrors)
+	if err != nil {
+		return fmt.Errorf("failed to encode row errors: %w", err)
+	}
+
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE imports
+			SET status = $2, processed_rows = $3, imported_rows = $4, failed_rows = $5, row_errors = $6, updated_at = NOW()
+			WHERE id = $1 AND status IN ($7, $2)
+		`, i.ID, ImportProcessing, i.ProcessedRows, i.ImportedRows, i.FailedRows, rowErrors, ImportPending)
+		if err != nil {
+			return fmt.Errorf("failed to record import progress: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// Complete marks an unfinished import completed and deletes the file
+func (s *ImportStore) Complete(ctx context.Context, id uuid.UUID) error {
+	return s.finish(ctx, id, ImportCompleted, nil)
+}
+
+// Fail marks an unfinished import failed with a message for the user and
+// deletes the file. Rows already imported are kept.
+func (s *ImportStore) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	return s.finish(ctx, id, ImportFailed, &message)
+}
+
+// finish moves an unfinished import to status and deletes the file
+func (s *ImportStore) finish(ctx context.Context, id uuid.UUID, status ImportStatus, message *string) error {
+	var dataKey *string
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `
+			WITH old AS (SELECT data_key FROM imports WHERE id = $1 FOR UPDATE)
+			UPDATE imports
+			SET status = $2, error = $3, data = NULL, data_key = NULL, updated_at = NOW()
+			FROM old
+			WHERE id = $1 AND status IN ($4, $5)
+			RETURNING old.data_key
+		`, id, status, message, ImportPending, ImportProcessing).Scan(&dataKey)
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to mark import %s: %w", status, err)
+	}
+
+	deleteObject(ctx, s.objects, dataKey)
+	return nil
+}
@@ -0,0 +1,213 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// ErrInvalidInvite is returned for unknown, revoked, expired or used up
+// invitation codes
+var ErrInvalidInvite = errors.New("invalid or expired invitation code")
+
+// MaxInviteUses caps how many accounts one code can register
+const MaxInviteUses = 10000
+
+// inviteEncoding writes codes without padding or easily confused padding
+// characters; codes are shown in groups of four
+var inviteEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// InviteCode lets people register while open registration is closed. The
+// code itself is only returned when it is created.
+type InviteCode struct {
+	ID        uuid.UUID       `json:"id"`
+	Note      string          `json:"note"`
+	MaxUses   int             `json:"max_uses"`
+	Uses      int             `json:"uses"`
+	ExpiresAt *timestamp.Time `json:"expires_at"`
+	CreatedBy string          `json:"created_by"`
+	CreatedAt timestamp.Time  `json:"created_at"`
+	RevokedAt *timestamp.Time `json:"revoked_at"`
+}
+
+// Validate checks the code's use limit and expiry
+func (i *InviteCode) Validate() error {
+	if i.MaxUses < 1 || i.MaxUses > MaxInviteUses {
+		return fmt.Errorf("max_uses must be between 1 and %d", MaxInviteUses)
+	}
+	if i.ExpiresAt != nil && !i.ExpiresAt.After(time.Now()) {
+		return errors.New("expires_at must be in the future")
+	}
+	return nil
+}
+
+// Usable reports whether the code can still register an account at now
+func (i *InviteCode) Usable(now time.Time) bool {
+	return i.RevokedAt == nil && i.Uses < i.MaxUses && (i.ExpiresAt == nil || now.Before(i.ExpiresAt.Time))
+}
+
+// NewInviteCode returns a random code such as "K7QF-2M4X-Z9TB-WPLA"
+func NewInviteCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate invitation code: %w", err)
+	}
+
+	encoded := inviteEncoding.EncodeToString(raw)
+	groups := make([]string, 0, len(encoded)/4)
+	for len(encoded) > 0 {
+		n := min(4, len(encoded))
+		groups = append(groups, encoded[:n])
+		encoded = encoded[n:]
+	}
+	return strings.Join(groups, "-"), nil
+}
+
+// NormalizeInviteCode uppercases a code and drops the dashes and spaces
+// people add when typing it
+func NormalizeInviteCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(strings.TrimSpace(code)))
+}
+
+// hashInviteCode returns the stored hash of a code
+func hashInviteCode(code string) string {
+	return hashToken(NormalizeInviteCode(code))
+}
+
+// InviteStore persists invitation codes
+type InviteStore struct {
+	db *pgxpool.Pool
+}
+
+// NewInviteStore creates a new invitation code store
+func NewInviteStore(db *pgxpool.Pool) *InviteStore {
+	return &InviteStore{db: db}
+}
+
+const inviteColumns = `id, note, max_uses, uses, expires_at, created_by, created_at, revoked_at`
+
+// scanInvite reads a row selected with inviteColumns
+func scanInvite(row pgx.Row) (*InviteCode, error) {
+	var invite InviteCode
+	if err := row.Scan(
+		&invite.ID,
+		&invite.Note,
+		&invite.MaxUses,
+		&invite.Uses,
+		&invite.ExpiresAt,
+		&invite.CreatedBy,
+		&invite.CreatedAt,
+		&invite.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// Create stores a new invitation and returns it with its plaintext code
+func (s *InviteStore) Create(ctx context.Context, invite *InviteCode) (string, *InviteCode, error) {
+	if err := invite.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	code, err := NewInviteCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	query := `
+		INSERT INTO invite_codes (code_hash, note, max_uses, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + inviteColumns
+
+	stored, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*InviteCode, error) {
+		return scanInvite(s.db.QueryRow(ctx, query,
+			hashInviteCode(code),
+			invite.Note,
+			invite.MaxUses,
+			invite.ExpiresAt,
+			invite.CreatedBy,
+		))
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return code, stored, nil
+}
+
+// List returns every invitation, newest first
+func (s *InviteStore) List(ctx context.Context) ([]InviteCode, error) {
+	invites, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]InviteCode, error) {
+		rows, err := s.db.Query(ctx, `SELECT `+inviteColumns+` FROM invite_codes ORDER BY created_at DESC`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var invites []InviteCode
+		for rows.Next() {
+			invite, err := scanInvite(rows)
+			if err != nil {
+				return nil, err
+			}
+			invites = append(invites, *invite)
+		}
+		return invites, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	return invites, nil
+}
+
+// Revoke stops an invitation from registering more accounts
+func (s *InviteStore) Revoke(ctx context.Context, id uuid.UUID) (*InviteCode, error) {
+	query := `
+		UPDATE invite_codes SET revoked_at = COALESCE(revoked_at, NOW())
+		WHERE id = $1
+		RETURNING ` + inviteColumns
+
+	// Revoking twice keeps the first time, so retrying is harmless
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*InviteCode, error) {
+		return scanInvite(s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// consumeInvite takes one use of a code inside tx, returning
+// ErrInvalidInvite if it can't be used
+func consumeInvite(ctx context.Context, tx pgx.Tx, code string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := tx.QueryRow(ctx, `
+		UPDATE invite_codes SET uses = uses + 1
+		WHERE code_hash = $1
+		  AND revoked_at IS NULL
+		  AND uses < max_uses
+		  AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING id
+	`, hashInviteCode(code)).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, ErrInvalidInvite
+	}
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to redeem invitation: %w", err)
+	}
+	return id, nil
+}
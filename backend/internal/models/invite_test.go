@@ -0,0 +1,98 @@
+package models
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+func TestNewInviteCode(t *testing.T) {
+	code, err := NewInviteCode()
+	if err != nil {
+		t.Fatalf("NewInviteCode() error = %v", err)
+	}
+
+	if !regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`).MatchString(code) {
+		t.Errorf("NewInviteCode() = %q, want four dash-separated groups of four", code)
+	}
+
+	other, _ := NewInviteCode()
+	if other == code {
+		t.Errorf("NewInviteCode() returned %q twice", code)
+	}
+}
+
+func TestNormalizeInviteCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"K7QF-2M4X-Z9TB-WPLA", "K7QF2M4XZ9TBWPLA"},
+		{" k7qf 2m4x-z9tb-wpla ", "K7QF2M4XZ9TBWPLA"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeInviteCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeInviteCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+
+	if hashInviteCode("k7qf-2m4x-z9tb-wpla") != hashInviteCode("K7QF2M4XZ9TBWPLA") {
+		t.Error("hashInviteCode() differs for the same code typed differently")
+	}
+}
+
+func TestInviteCode_Validate(t *testing.T) {
+	past := timestamp.New(time.Now().Add(-time.Hour))
+	future := timestamp.New(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name    string
+		invite  InviteCode
+		wantErr bool
+	}{
+		{"single use", InviteCode{MaxUses: 1}, false},
+		{"multi use with expiry", InviteCode{MaxUses: 50, ExpiresAt: &future}, false},
+		{"no uses", InviteCode{MaxUses: 0}, true},
+		{"too many uses", InviteCode{MaxUses: MaxInviteUses + 1}, true},
+		{"already expired", InviteCode{MaxUses: 1, ExpiresAt: &past}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.invite.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInviteCode_Usable(t *testing.T) {
+	now := time.Now()
+	past := timestamp.New(now.Add(-time.Minute))
+	future := timestamp.New(now.Add(time.Minute))
+
+	tests := []struct {
+		name   string
+		invite InviteCode
+		want   bool
+	}{
+		{"unused", InviteCode{MaxUses: 1}, true},
+		{"uses left", InviteCode{MaxUses: 3, Uses: 2, ExpiresAt: &future}, true},
+		{"used up", InviteCode{MaxUses: 3, Uses: 3}, false},
+		{"expired", InviteCode{MaxUses: 1, ExpiresAt: &past}, false},
+		{"revoked", InviteCode{MaxUses: 1, RevokedAt: &past}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.invite.Usable(now); got != tt.want {
+				t.Errorf("Usable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
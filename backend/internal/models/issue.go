@@ -0,0 +1,92 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// IssueCategory is a kind of writing issue found by proofreading
+type IssueCategory string
+
+const (
+	IssueGrammar  IssueCategory = "grammar"
+	IssueSpelling IssueCategory = "spelling"
+	IssueStyle    IssueCategory = "style"
+)
+
+// IssueCategories lists every issue category
+var IssueCategories = []IssueCategory{IssueGrammar, IssueSpelling, IssueStyle}
+
+// IssueSeverity is how much an issue matters, from errors that should be
+// fixed to optional suggestions
+type IssueSeverity string
+
+const (
+	SeverityError      IssueSeverity = "error"
+	SeverityWarning    IssueSeverity = "warning"
+	SeveritySuggestion IssueSeverity = "suggestion"
+)
+
+// IssueSeverities lists every severity, most severe first
+var IssueSeverities = []IssueSeverity{SeverityError, SeverityWarning, SeveritySuggestion}
+
+// ValidIssueCategory reports whether c is a known category
+func ValidIssueCategory(c IssueCategory) bool {
+	return slices.Contains(IssueCategories, c)
+}
+
+// ValidIssueSeverity reports whether s is a known severity
+func ValidIssueSeverity(s IssueSeverity) bool {
+	return slices.Contains(IssueSeverities, s)
+}
+
+// Issue is a grammar, spelling or style problem in a submission's content.
+// Start and End are character (rune) offsets, like findings.
+type Issue struct {
+	Category IssueCategory `json:"category"`
+	Severity IssueSeverity `json:"severity"`
+	Start    int           `json:"start"`
+	End      int           `json:"end"`
+	Message  string        `json:"message"`
+	// Suggestion replaces the text between Start and End; empty when
+	// there is no single fix
+	Suggestion string `json:"suggestion"`
+}
+
+// sealIssues encodes an analysis' issues for the issues column,
+// encrypting them when enabled
+func (s *SubmissionStore) sealIssues(ctx context.Context, analysis record, issues []Issue) (*string, error) {
+	if issues == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(issues)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode issues: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisIssues))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt issues: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openIssues decodes a value read from the issues column
+func (s *SubmissionStore) openIssues(ctx context.Context, analysis record, value *string) ([]Issue, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisIssues))
+	if err != nil {
+		return nil, err
+	}
+
+	issues := []Issue{}
+	if err := json.Unmarshal([]byte(opened), &issues); err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// LoginFingerprint is where and on what a user signed in
+type LoginFingerprint struct {
+	// Country is an ISO 3166 country code, or empty when unknown
+	Country string `json:"country"`
+	// Device names the browser and OS, such as "Firefox on Windows"
+	Device string `json:"device"`
+}
+
+// LoginFingerprintStore remembers the countries and devices users have
+// signed in from
+type LoginFingerprintStore struct {
+	db *pgxpool.Pool
+}
+
+// NewLoginFingerprintStore creates a new login fingerprint store
+func NewLoginFingerprintStore(db *pgxpool.Pool) *LoginFingerprintStore {
+	return &LoginFingerprintStore{db: db}
+}
+
+// IsFamiliar reports whether the user has signed in from the fingerprint's
+// device and country before. An unknown country matches any, and a user's
+// first sign-in is familiar since there is nothing to compare it with.
+func (s *LoginFingerprintStore) IsFamiliar(ctx context.Context, userID uuid.UUID, fp LoginFingerprint) (bool, error) {
+	familiar, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (bool, error) {
+		var familiar bool
+		err := s.db.QueryRow(ctx, `
+			SELECT NOT EXISTS (SELECT 1 FROM login_fingerprints WHERE user_id = $1)
+				OR (EXISTS (SELECT 1 FROM login_fingerprints WHERE user_id = $1 AND device = $3)
+					AND ($2 = '' OR EXISTS (SELECT 1 FROM login_fingerprints WHERE user_id = $1 AND country = $2)))
+		`, userID, fp.Country, fp.Device).Scan(&familiar)
+		return familiar, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check login fingerprint: %w", err)
+	}
+
+	return familiar, nil
+}
+
+// Record remembers a sign-in from the fingerprint
+func (s *LoginFingerprintStore) Record(ctx context.Context, userID uuid.UUID, fp LoginFingerprint) error {
+	// An upsert, so retrying is harmless
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO login_fingerprints (user_id, country, device)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, country, device) DO UPDATE SET last_seen_at = NOW()
+		`, userID, fp.Country, fp.Device)
+		if err != nil {
+			return fmt.Errorf("failed to record login fingerprint: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,4201 @@
+// Package memstore provides in-memory implementations of the model stores
+// for unit tests that shouldn't need Postgres. They mirror the behaviour the
+// handlers rely on: validation, pgx.ErrNoRows for missing rows and unique
+// violations for duplicate emails.
+package memstore
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// UserStore is an in-memory user store
+type UserStore struct {
+	mu      sync.Mutex
+	users   map[uuid.UUID]*models.User
+	invites *InviteStore
+	// reserved returns the ID a SCIM invitation reserved for an email
+	reserved func(email string) (uuid.UUID, bool)
+}
+
+// NewUserStore creates an empty in-memory user store
+func NewUserStore() *UserStore {
+	return &UserStore{users: make(map[uuid.UUID]*models.User)}
+}
+
+// Create validates and stores a new user
+func (s *UserStore) Create(ctx context.Context, email, password string) (*models.User, error) {
+	if err := models.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if err := models.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := models.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	id := uuid.New()
+	if s.reserved != nil {
+		if reserved, ok := s.reserved(email); ok {
+			id = reserved
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			return nil, fmt.Errorf("failed to create user: %w", &pgconn.PgError{
+				Code:           "23505",
+				Message:        "duplicate key value violates unique constraint",
+				ConstraintName: "users_email_key",
+			})
+		}
+	}
+
+	now := timestamp.Now()
+	user := &models.User{
+		ID:               id,
+		Email:            email,
+		PasswordHash:     passwordHash,
+		Plan:             models.PlanFree,
+		ConfirmNewLogins: true,
+		Status:           models.StatusActive,
+		Version:          1,
+		CreatedAt:        now,
+		UpdatedAt:        now,
+	}
+	s.users[user.ID] = user
+
+	copied := *user
+	return &copied, nil
+}
+
+// WithInvites redeems CreateWithInvite codes from invites
+func (s *UserStore) WithInvites(invites *InviteStore) *UserStore {
+	s.invites = invites
+	return s
+}
+
+// CreateWithInvite takes one use of an invitation code and stores a new
+// user, giving the use back if the user can't be created
+func (s *UserStore) CreateWithInvite(ctx context.Context, email, password, code string) (*models.User, error) {
+	if err := models.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if err := models.ValidatePassword(password); err != nil {
+		return nil, err
+	}
+	if s.invites == nil {
+		return nil, models.ErrInvalidInvite
+	}
+
+	id, err := s.invites.consume(code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.Create(ctx, email, password)
+	if err != nil {
+		s.invites.release(id)
+		return nil, err
+	}
+	return user, nil
+}
+
+// GetByEmail retrieves a user by email
+func (s *UserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, u := range s.users {
+		if u.Email == email {
+			copied := *u
+			return &copied, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// GetByID retrieves a user by ID
+func (s *UserStore) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *u
+	return &copied, nil
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (s *UserStore) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok && u.EmailVerifiedAt == nil {
+		now := timestamp.Now()
+		u.EmailVerifiedAt = &now
+		u.Version++
+	}
+	return nil
+}
+
+// UpdatePassword validates, hashes and stores a new password
+func (s *UserStore) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	if err := models.ValidatePassword(password); err != nil {
+		return err
+	}
+
+	passwordHash, err := models.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	u.PasswordHash = passwordHash
+	u.UpdatedAt = timestamp.Now()
+	u.Version++
+	return nil
+}
+
+// RehashPassword replaces the password hash unless it changed since
+// currentHash was read
+func (s *UserStore) RehashPassword(ctx context.Context, id uuid.UUID, currentHash, password string) error {
+	passwordHash, err := models.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[id]; ok && u.PasswordHash == currentHash {
+		u.PasswordHash = passwordHash
+	}
+	return nil
+}
+
+// UpdateEmail changes the user's email address and marks it verified
+func (s *UserStore) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	if err := models.ValidateEmail(email); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	for _, other := range s.users {
+		if other.ID != id && other.Email == email {
+			return fmt.Errorf("failed to update email: %w", &pgconn.PgError{
+				Code:           "23505",
+				Message:        "duplicate key value violates unique constraint",
+				ConstraintName: "users_email_key",
+			})
+		}
+	}
+
+	now := timestamp.Now()
+	u.Email = email
+	u.EmailVerifiedAt = &now
+	u.UpdatedAt = now
+	u.Version++
+	return nil
+}
+
+// UpdateProfile sets the user's display name and time zone if the user is
+// still at version
+func (s *UserStore) UpdateProfile(ctx context.Context, id uuid.UUID, version int, displayName, timeZone *string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if u.Version != version {
+		return nil, models.ErrVersionConflict
+	}
+
+	u.DisplayName = displayName
+	u.TimeZone = timeZone
+	u.UpdatedAt = timestamp.Now()
+	u.Version++
+
+	copied := *u
+	return &copied, nil
+}
+
+// SetPlan changes the user's subscription plan
+func (s *UserStore) SetPlan(ctx context.Context, id uuid.UUID, plan models.Plan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	u.Plan = plan
+	u.UpdatedAt = timestamp.Now()
+	return nil
+}
+
+// SetConfirmNewLogins chooses whether sign-ins from a new country or
+// device need an emailed code
+func (s *UserStore) SetConfirmNewLogins(ctx context.Context, id uuid.UUID, confirm bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+
+	u.ConfirmNewLogins = confirm
+	u.UpdatedAt = timestamp.Now()
+	return nil
+}
+
+// SetStatus suspends, bans or reinstates the user
+func (s *UserStore) SetStatus(ctx context.Context, id uuid.UUID, status models.AccountStatus, reason string) (*models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+
+	now := timestamp.Now()
+	u.Status, u.StatusReason, u.StatusChangedAt = status, nil, &now
+	if reason != "" && status != models.StatusActive {
+		u.StatusReason = &reason
+	}
+	u.UpdatedAt = now
+
+	copied := *u
+	return &copied, nil
+}
+
+// emailToken is a stored token with its redemption state
+type emailToken struct {
+	models.EmailToken
+	used bool
+}
+
+// EmailTokenStore is an in-memory one-time token store
+type EmailTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]*emailToken
+}
+
+// NewEmailTokenStore creates an empty in-memory token store
+func NewEmailTokenStore() *EmailTokenStore {
+	return &EmailTokenStore{tokens: make(map[string]*emailToken)}
+}
+
+// Create issues a new token, invalidating earlier unused ones for the same purpose
+func (s *EmailTokenStore) Create(ctx context.Context, userID uuid.UUID, purpose models.EmailTokenPurpose, email string, ttl time.Duration) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.tokens {
+		if t.UserID == userID && t.Purpose == purpose {
+			t.used = true
+		}
+	}
+
+	token := uuid.NewString()
+	s.tokens[token] = &emailToken{EmailToken: models.EmailToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	}}
+
+	return token, nil
+}
+
+// Consume marks a valid token as used and returns it
+func (s *EmailTokenStore) Consume(ctx context.Context, purpose models.EmailTokenPurpose, token string) (*models.EmailToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.tokens[token]
+	if !ok || t.used || t.Purpose != purpose || !time.Now().Before(t.ExpiresAt) {
+		return nil, models.ErrInvalidToken
+	}
+
+	t.used = true
+	copied := t.EmailToken
+	return &copied, nil
+}
+
+// AuditStore is an in-memory audit log
+type AuditStore struct {
+	mu      sync.Mutex
+	entries []models.AuditEntry
+}
+
+// NewAuditStore creates an empty in-memory audit log
+func NewAuditStore() *AuditStore {
+	return &AuditStore{}
+}
+
+// Record appends an entry, filling in its ID and timestamp
+func (s *AuditStore) Record(ctx context.Context, entry *models.AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry.ID = uuid.New()
+	entry.CreatedAt = timestamp.Now()
+	s.entries = append(s.entries, *entry)
+	return nil
+}
+
+// Entries returns the recorded entries in order
+func (s *AuditStore) Entries() []models.AuditEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]models.AuditEntry(nil), s.entries...)
+}
+
+// FeatureFlagStore is an in-memory feature flag store
+type FeatureFlagStore struct {
+	mu    sync.Mutex
+	flags map[string]models.FeatureFlag
+}
+
+// NewFeatureFlagStore creates an empty in-memory feature flag store
+func NewFeatureFlagStore() *FeatureFlagStore {
+	return &FeatureFlagStore{flags: make(map[string]models.FeatureFlag)}
+}
+
+// List returns every flag ordered by key
+func (s *FeatureFlagStore) List(ctx context.Context) ([]models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flags := make([]models.FeatureFlag, 0, len(s.flags))
+	for _, flag := range s.flags {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// Get returns a flag by key
+func (s *FeatureFlagStore) Get(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	flag, ok := s.flags[key]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return &flag, nil
+}
+
+// Upsert validates and creates or replaces a flag
+func (s *FeatureFlagStore) Upsert(ctx context.Context, flag *models.FeatureFlag) (*models.FeatureFlag, error) {
+	if err := flag.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *flag
+	stored.UserIDs = append([]uuid.UUID{}, flag.UserIDs...)
+	stored.UpdatedAt = timestamp.Now()
+	stored.CreatedAt = stored.UpdatedAt
+	if existing, ok := s.flags[flag.Key]; ok {
+		stored.CreatedAt = existing.CreatedAt
+	}
+	s.flags[flag.Key] = stored
+
+	return &stored, nil
+}
+
+// Delete removes a flag
+func (s *FeatureFlagStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.flags[key]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(s.flags, key)
+	return nil
+}
+
+// InviteStore is an in-memory invitation code store
+type InviteStore struct {
+	mu      sync.Mutex
+	invites map[uuid.UUID]*models.InviteCode
+	codes   map[string]uuid.UUID
+}
+
+// NewInviteStore creates an empty in-memory invitation store
+func NewInviteStore() *InviteStore {
+	return &InviteStore{
+		invites: make(map[uuid.UUID]*models.InviteCode),
+		codes:   make(map[string]uuid.UUID),
+	}
+}
+
+// Create validates and stores an invitation, returning its code
+func (s *InviteStore) Create(ctx context.Context, invite *models.InviteCode) (string, *models.InviteCode, error) {
+	if err := invite.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	code, err := models.NewInviteCode()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *invite
+	stored.ID = uuid.New()
+	stored.Uses = 0
+	stored.CreatedAt = timestamp.Now()
+	stored.RevokedAt = nil
+	s.invites[stored.ID] = &stored
+	s.codes[models.NormalizeInviteCode(code)] = stored.ID
+
+	copied := stored
+	return code, &copied, nil
+}
+
+// List returns every invitation, newest first
+func (s *InviteStore) List(ctx context.Context) ([]models.InviteCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invites := make([]models.InviteCode, 0, len(s.invites))
+	for _, invite := range s.invites {
+		invites = append(invites, *invite)
+	}
+	sort.Slice(invites, func(i, j int) bool { return invites[i].CreatedAt.After(invites[j].CreatedAt.Time) })
+	return invites, nil
+}
+
+// Revoke stops an invitation from registering more accounts
+func (s *InviteStore) Revoke(ctx context.Context, id uuid.UUID) (*models.InviteCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.invites[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if invite.RevokedAt == nil {
+		now := timestamp.Now()
+		invite.RevokedAt = &now
+	}
+
+	copied := *invite
+	return &copied, nil
+}
+
+// consume takes one use of a code
+func (s *InviteStore) consume(code string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.codes[models.NormalizeInviteCode(code)]
+	if !ok || !s.invites[id].Usable(time.Now()) {
+		return uuid.Nil, models.ErrInvalidInvite
+	}
+	s.invites[id].Uses++
+	return id, nil
+}
+
+// release gives back a use taken by consume
+func (s *InviteStore) release(id uuid.UUID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.invites[id].Uses--
+}
+
+// statsOf counts content as the store does when it is written
+func statsOf(content string) *textstats.Stats {
+	stats := textstats.Compute(content)
+	return &stats
+}
+
+// SubmissionStore is an in-memory submission store
+type SubmissionStore struct {
+	mu          sync.Mutex
+	submissions map[uuid.UUID]*models.Submission
+	analyses    map[uuid.UUID]*models.Analysis
+	listener    models.StatusListener
+	orgs        *OrganizationStore
+}
+
+// NewSubmissionStore creates an empty in-memory submission store
+func NewSubmissionStore() *SubmissionStore {
+	return &SubmissionStore{
+		submissions: make(map[uuid.UUID]*models.Submission),
+		analyses:    make(map[uuid.UUID]*models.Analysis),
+	}
+}
+
+// WithListener sets the listener told about every status change and
+// returns the store
+func (s *SubmissionStore) WithListener(listener models.StatusListener) *SubmissionStore {
+	s.listener = listener
+	return s
+}
+
+// WithOrganizations lets FindDuplicate look through the submissions of
+// the user's organizations' members and returns the store
+func (s *SubmissionStore) WithOrganizations(orgs *OrganizationStore) *SubmissionStore {
+	s.orgs = orgs
+	return s
+}
+
+// Create stores new content for a user as a draft or queued
+func (s *SubmissionStore) Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error) {
+	if status != models.StatusDraft && status != models.StatusQueued {
+		return nil, fmt.Errorf("invalid initial status %q", status)
+	}
+
+	s.mu.Lock()
+	now := timestamp.Now()
+	submission := &models.Submission{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Content:         content,
+		RedactedContent: redacted,
+		Version:         1,
+		Revision:        1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Instructions:    instructions,
+		ProfileID:       profileID,
+		WorkflowStatus:  models.WorkflowOpen,
+		Stats:           statsOf(content),
+		ContentHash:     models.ContentHash(content),
+	}
+	submission.SetStatus(status, now.Time)
+	s.submissions[submission.ID] = submission
+	copied := *submission
+	s.mu.Unlock()
+
+	s.emit(ctx, models.StatusChange{SubmissionID: copied.ID, UserID: userID, To: status, At: now})
+	return &copied, nil
+}
+
+// Get retrieves a submission by ID regardless of owner
+func (s *SubmissionStore) Get(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *submission
+	return &copied, nil
+}
+
+// GetByID retrieves a submission owned by the given user
+func (s *SubmissionStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error) {
+	submission, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if submission.UserID != userID || submission.DeletedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+	return submission, nil
+}
+
+// List returns a page of a user's submissions matching filter, newest
+// first, and the total count
+func (s *SubmissionStore) List(ctx context.Context, userID uuid.UUID, filter models.SubmissionFilter, limit, offset int) ([]models.Submission, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keyword := strings.ToLower(strings.TrimSpace(filter.Keyword))
+
+	// The earliest of the user's submissions with each content, and how
+	// many share it
+	earliest := make(map[string]*models.Submission)
+	copies := make(map[string]int)
+	for _, submission := range s.submissions {
+		if submission.UserID != userID || submission.QuarantinedAt != nil || submission.DeletedAt != nil {
+			continue
+		}
+		hash := submission.ContentHash
+		if hash == "" {
+			continue
+		}
+		copies[hash]++
+		if first, ok := earliest[hash]; !ok || submission.CreatedAt.Before(first.CreatedAt.Time) ||
+			submission.CreatedAt.Equal(first.CreatedAt.Time) && submission.ID.String() < first.ID.String() {
+			earliest[hash] = submission
+		}
+	}
+
+	owned := []models.Submission{}
+	for _, submission := range s.submissions {
+		if submission.UserID != userID || submission.QuarantinedAt != nil || submission.DeletedAt != nil || !s.matchesKeyword(submission.ID, keyword) {
+			continue
+		}
+		if filter.Duplicates && copies[submission.ContentHash] < 2 {
+			continue
+		}
+		if !s.matchesLabel(submission.ID, filter.Label) {
+			continue
+		}
+		copied := *submission
+		if first, ok := earliest[copied.ContentHash]; ok && first.ID != copied.ID {
+			copied.DuplicateOf = &first.ID
+		}
+		owned = append(owned, copied)
+	}
+
+	sort.Slice(owned, func(i, j int) bool {
+		if !owned[i].CreatedAt.Equal(owned[j].CreatedAt.Time) {
+			return owned[i].CreatedAt.After(owned[j].CreatedAt.Time)
+		}
+		return owned[i].ID.String() < owned[j].ID.String()
+	})
+
+	total := len(owned)
+	if offset >= total {
+		return []models.Submission{}, total, nil
+	}
+
+	end := min(offset+limit, total)
+	return owned[offset:end], total, nil
+}
+
+// FindDuplicate returns the earliest submission with the same content that
+// was queued for analysis, from the user's own or, with WithOrganizations,
+// their organizations' members' whose analysis is done
+func (s *SubmissionStore) FindDuplicate(ctx context.Context, userID uuid.UUID, orgID *uuid.UUID, content string) (*models.Submission, error) {
+	s.mu.Lock()
+	var candidates []models.Submission
+	hash := models.ContentHash(content)
+	for _, submission := range s.submissions {
+		switch submission.Status {
+		case models.StatusQueued, models.StatusProcessing, models.StatusCompleted, models.StatusArchived:
+		default:
+			continue
+		}
+		if submission.ContentHash == hash && submission.QuarantinedAt == nil && submission.DeletedAt == nil {
+			candidates = append(candidates, *submission)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if own := candidates[i].UserID == userID; own != (candidates[j].UserID == userID) {
+			return own
+		}
+		if !candidates[i].CreatedAt.Equal(candidates[j].CreatedAt.Time) {
+			return candidates[i].CreatedAt.Before(candidates[j].CreatedAt.Time)
+		}
+		return candidates[i].ID.String() < candidates[j].ID.String()
+	})
+
+	for _, candidate := range candidates {
+		if candidate.UserID == userID {
+			return &candidate, nil
+		}
+		if s.orgs == nil {
+			break
+		}
+		if candidate.Status != models.StatusCompleted && candidate.Status != models.StatusArchived {
+			continue
+		}
+		if orgID != nil {
+			if _, err := s.orgs.Role(ctx, *orgID, candidate.UserID); err == nil {
+				return &candidate, nil
+			}
+			continue
+		}
+		if shared, _ := s.orgs.ShareOrganization(ctx, userID, candidate.UserID); shared {
+			return &candidate, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// CopyDuplicate stores content a teammate already had analyzed as the
+// user's own completed submission, with a copy of original's analysis
+func (s *SubmissionStore) CopyDuplicate(ctx context.Context, userID uuid.UUID, original *models.Submission, content string, redacted *string) (*models.Submission, error) {
+	s.mu.Lock()
+	analysis, ok := s.analyses[original.ID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, pgx.ErrNoRows
+	}
+
+	now := timestamp.Now()
+	submission := &models.Submission{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Content:         content,
+		RedactedContent: redacted,
+		Version:         1,
+		Revision:        1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		WorkflowStatus:  models.WorkflowOpen,
+		Stats:           statsOf(content),
+		ContentHash:     models.ContentHash(content),
+		DuplicateOf:     &original.ID,
+	}
+	submission.SetStatus(models.StatusCompleted, now.Time)
+	s.submissions[submission.ID] = submission
+
+	reused := *analysis
+	reused.ID, reused.SubmissionID = uuid.New(), submission.ID
+	reused.CreatedAt, reused.UpdatedAt = now, now
+	reused.Instructions, reused.Changes = nil, nil
+	reused.ProcessingTimeMs, reused.PromptTokens, reused.OutputTokens, reused.CostMicros = 0, 0, 0, 0
+	reused.Calls = nil
+	s.analyses[submission.ID] = &reused
+	copied := *submission
+	s.mu.Unlock()
+
+	s.emit(ctx, models.StatusChange{SubmissionID: copied.ID, UserID: userID, To: copied.Status, At: now})
+	return &copied, nil
+}
+
+// LinkDuplicate points the user's submission at their earliest submission
+// with the same content
+func (s *SubmissionStore) LinkDuplicate(ctx context.Context, userID uuid.UUID, submission *models.Submission) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var first *models.Submission
+	for _, candidate := range s.submissions {
+		if candidate.UserID != userID || candidate.ContentHash != submission.ContentHash || candidate.ContentHash == "" ||
+			candidate.QuarantinedAt != nil || candidate.DeletedAt != nil {
+			continue
+		}
+		if first == nil || candidate.CreatedAt.Before(first.CreatedAt.Time) ||
+			candidate.CreatedAt.Equal(first.CreatedAt.Time) && candidate.ID.String() < first.ID.String() {
+			first = candidate
+		}
+	}
+	if first != nil && first.ID != submission.ID {
+		submission.DuplicateOf = &first.ID
+	}
+	return nil
+}
+
+// Assign makes assigneeID the reviewer of a submission owned by ownerID
+func (s *SubmissionStore) Assign(ctx context.Context, ownerID, id, assigneeID uuid.UUID, dueAt *time.Time) (*models.Submission, error) {
+	return s.updateWorkflow(id, func(submission *models.Submission) bool {
+		if submission.UserID != ownerID {
+			return false
+		}
+		submission.AssigneeID = &assigneeID
+		submission.DueAt = timestamp.Ptr(dueAt)
+		return true
+	})
+}
+
+// Unassign removes a submission's reviewer and due date, for its owner or
+// assignee
+func (s *SubmissionStore) Unassign(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error) {
+	return s.updateWorkflow(id, func(submission *models.Submission) bool {
+		if !canReview(submission, userID) {
+			return false
+		}
+		submission.AssigneeID = nil
+		submission.DueAt = nil
+		return true
+	})
+}
+
+// SetWorkflowStatus moves a submission's review to status, for its owner
+// or assignee
+func (s *SubmissionStore) SetWorkflowStatus(ctx context.Context, userID, id uuid.UUID, status models.WorkflowStatus) (*models.Submission, error) {
+	return s.updateWorkflow(id, func(submission *models.Submission) bool {
+		if !canReview(submission, userID) {
+			return false
+		}
+		submission.WorkflowStatus = status
+		return true
+	})
+}
+
+// canReview reports whether userID owns or is assigned the submission
+func canReview(submission *models.Submission, userID uuid.UUID) bool {
+	return submission.UserID == userID || (submission.AssigneeID != nil && *submission.AssigneeID == userID)
+}
+
+// updateWorkflow applies update to a submission and bumps its version,
+// or returns pgx.ErrNoRows if update refuses
+func (s *SubmissionStore) updateWorkflow(id uuid.UUID, update func(*models.Submission) bool) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.DeletedAt != nil || !update(submission) {
+		return nil, pgx.ErrNoRows
+	}
+	touch(submission)
+	copied := *submission
+	return &copied, nil
+}
+
+// Queue returns a page of the submissions assigned to assigneeID, soonest
+// due first, and the total count
+func (s *SubmissionStore) Queue(ctx context.Context, assigneeID uuid.UUID, status models.WorkflowStatus, limit, offset int) ([]models.Submission, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assigned := []models.Submission{}
+	for _, submission := range s.submissions {
+		if submission.AssigneeID != nil && *submission.AssigneeID == assigneeID && (status == "" || submission.WorkflowStatus == status) && submission.QuarantinedAt == nil && submission.DeletedAt == nil {
+			assigned = append(assigned, *submission)
+		}
+	}
+
+	sort.Slice(assigned, func(i, j int) bool {
+		a, b := assigned[i], assigned[j]
+		switch {
+		case a.DueAt != nil && b.DueAt != nil && !a.DueAt.Equal(b.DueAt.Time):
+			return a.DueAt.Before(b.DueAt.Time)
+		case (a.DueAt == nil) != (b.DueAt == nil):
+			return a.DueAt != nil
+		case !a.CreatedAt.Equal(b.CreatedAt.Time):
+			return a.CreatedAt.Before(b.CreatedAt.Time)
+		}
+		return a.ID.String() < b.ID.String()
+	})
+
+	total := len(assigned)
+	if offset >= total {
+		return []models.Submission{}, total, nil
+	}
+
+	end := min(offset+limit, total)
+	return assigned[offset:end], total, nil
+}
+
+// matchesLabel reports whether a submission's latest analysis has label,
+// ignoring case. The caller must hold s.mu.
+func (s *SubmissionStore) matchesLabel(id uuid.UUID, label string) bool {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return true
+	}
+
+	analysis, ok := s.analyses[id]
+	if !ok {
+		return false
+	}
+	for _, c := range analysis.Labels {
+		if strings.EqualFold(c.Label, label) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesKeyword reports whether a submission has a keyphrase containing
+// keyword. The caller must hold s.mu.
+func (s *SubmissionStore) matchesKeyword(id uuid.UUID, keyword string) bool {
+	if keyword == "" {
+		return true
+	}
+
+	analysis, ok := s.analyses[id]
+	if !ok {
+		return false
+	}
+	for _, k := range analysis.Keyphrases {
+		if strings.Contains(k.Phrase, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateRevision stores content as a new, queued revision of a user's
+// submission, keeping its instructions and profile
+func (s *SubmissionStore) CreateRevision(ctx context.Context, userID, previousID uuid.UUID, content string, redacted *string) (*models.Submission, error) {
+	s.mu.Lock()
+	previous, ok := s.submissions[previousID]
+	if !ok || previous.UserID != userID || previous.DeletedAt != nil {
+		s.mu.Unlock()
+		return nil, pgx.ErrNoRows
+	}
+	for _, submission := range s.submissions {
+		if submission.PreviousID != nil && *submission.PreviousID == previousID {
+			s.mu.Unlock()
+			return nil, models.ErrNotLatestRevision
+		}
+	}
+
+	now := timestamp.Now()
+	submission := &models.Submission{
+		ID:              uuid.New(),
+		UserID:          userID,
+		Content:         content,
+		RedactedContent: redacted,
+		Version:         1,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		Instructions:    previous.Instructions,
+		ProfileID:       previous.ProfileID,
+		PreviousID:      &previous.ID,
+		Revision:        previous.Revision + 1,
+		AssigneeID:      previous.AssigneeID,
+		DueAt:           previous.DueAt,
+		WorkflowStatus:  models.WorkflowOpen,
+		Stats:           statsOf(content),
+		ContentHash:     models.ContentHash(content),
+	}
+	submission.SetStatus(models.StatusQueued, now.Time)
+	s.submissions[submission.ID] = submission
+	copied := *submission
+	s.mu.Unlock()
+
+	s.emit(ctx, models.StatusChange{SubmissionID: copied.ID, UserID: userID, To: models.StatusQueued, At: now})
+	return &copied, nil
+}
+
+// Revisions returns every revision of the document a user's submission
+// belongs to, oldest first, leaving out those in the trash
+func (s *SubmissionStore) Revisions(ctx context.Context, userID, id uuid.UUID) ([]models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+		return []models.Submission{}, nil
+	}
+
+	// Walk back to the first revision still stored, then forward
+	for submission.PreviousID != nil {
+		previous, ok := s.submissions[*submission.PreviousID]
+		if !ok {
+			break
+		}
+		submission = previous
+	}
+
+	revisions := []models.Submission{}
+	for {
+		if submission.DeletedAt == nil {
+			revisions = append(revisions, *submission)
+		}
+		var next *models.Submission
+		for _, candidate := range s.submissions {
+			if candidate.PreviousID != nil && *candidate.PreviousID == submission.ID {
+				next = candidate
+				break
+			}
+		}
+		if next == nil {
+			return revisions, nil
+		}
+		submission = next
+	}
+}
+
+// UpdateStatus moves a submission to a new status if the state machine
+// allows it
+func (s *SubmissionStore) UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error {
+	s.mu.Lock()
+	change, err := s.transition(id, status)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, *change)
+	return nil
+}
+
+// transition applies a status change. The caller must hold s.mu.
+func (s *SubmissionStore) transition(id uuid.UUID, status models.SubmissionStatus) (*models.StatusChange, error) {
+	submission, ok := s.submissions[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if !submission.Status.CanTransitionTo(status) {
+		return nil, &models.TransitionError{From: submission.Status, To: status}
+	}
+
+	change := &models.StatusChange{
+		SubmissionID: id,
+		UserID:       submission.UserID,
+		From:         submission.Status,
+		To:           status,
+		At:           timestamp.Now(),
+	}
+	submission.SetStatus(status, change.At.Time)
+	touch(submission)
+	return change, nil
+}
+
+// touch records a write to a submission: its version moves on and its
+// UpdatedAt is now. The caller must hold s.mu.
+func touch(submission *models.Submission) {
+	submission.Version++
+	submission.UpdatedAt = timestamp.Now()
+}
+
+// emit tells the listener about a status change
+func (s *SubmissionStore) emit(ctx context.Context, change models.StatusChange) {
+	if s.listener != nil {
+		s.listener.StatusChanged(ctx, change)
+	}
+}
+
+// UpdateContent replaces the content of a user's draft if it is still at
+// version
+func (s *SubmissionStore) UpdateContent(ctx context.Context, userID, id uuid.UUID, version int, content string, redacted *string) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+	if submission.Version != version {
+		return nil, models.ErrVersionConflict
+	}
+	if submission.Status != models.StatusDraft {
+		return nil, models.ErrNotDraft
+	}
+
+	submission.Content = content
+	submission.RedactedContent = redacted
+	submission.Stats = statsOf(content)
+	submission.ContentHash = models.ContentHash(content)
+	touch(submission)
+
+	copied := *submission
+	return &copied, nil
+}
+
+// UpdateRedactedContent replaces the masked copy of a submission
+func (s *SubmissionStore) UpdateRedactedContent(ctx context.Context, id uuid.UUID, redacted string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	submission.RedactedContent = &redacted
+	touch(submission)
+	return nil
+}
+
+// SaveAnalysis stores an analysis and moves its submission from processing
+// to completed
+func (s *SubmissionStore) SaveAnalysis(ctx context.Context, analysis *models.Analysis) error {
+	s.mu.Lock()
+	if _, ok := s.submissions[analysis.SubmissionID]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("failed to save analysis: submission %s not found", analysis.SubmissionID)
+	}
+
+	change, err := s.transition(analysis.SubmissionID, models.StatusCompleted)
+	if err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	analysis.ID = uuid.New()
+	analysis.CreatedAt, analysis.UpdatedAt = change.At, change.At
+	if reason := models.QuarantineReason(analysis.PolicyDecision); reason != "" {
+		s.quarantine(analysis.SubmissionID, reason, false)
+	}
+
+	copied := *analysis
+	s.analyses[analysis.SubmissionID] = &copied
+	s.mu.Unlock()
+
+	s.emit(ctx, *change)
+	return nil
+}
+
+// GetAnalysis retrieves the analysis of a submission owned by the given user
+func (s *SubmissionStore) GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[submissionID]
+	if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+
+	analysis, ok := s.analyses[submissionID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *analysis
+	return &copied, nil
+}
+
+// GetAnalyses retrieves the analyses of the given submissions owned by
+// the user, by submission ID
+func (s *SubmissionStore) GetAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*models.Analysis, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	analyses := map[uuid.UUID]*models.Analysis{}
+	for _, id := range submissionIDs {
+		submission, ok := s.submissions[id]
+		if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+			continue
+		}
+		if analysis, ok := s.analyses[id]; ok {
+			copied := *analysis
+			analyses[id] = &copied
+		}
+	}
+	return analyses, nil
+}
+
+// GetLabels returns the labels of the latest analysis of each of the
+// user's submissions, by submission ID
+func (s *SubmissionStore) GetLabels(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID][]models.Classification, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	labels := map[uuid.UUID][]models.Classification{}
+	for _, id := range submissionIDs {
+		submission, ok := s.submissions[id]
+		if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+			continue
+		}
+		if analysis, ok := s.analyses[id]; ok && len(analysis.Labels) > 0 {
+			labels[id] = append([]models.Classification{}, analysis.Labels...)
+		}
+	}
+	return labels, nil
+}
+
+// GetArtifacts retrieves the artifacts of an analysis regardless of owner
+func (s *SubmissionStore) GetArtifacts(ctx context.Context, analysisID uuid.UUID) (*models.AnalysisArtifacts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, analysis := range s.analyses {
+		if analysis.ID != analysisID {
+			continue
+		}
+		calls := append([]models.ModelCall{}, analysis.Calls...)
+		return &models.AnalysisArtifacts{
+			AnalysisID:       analysis.ID,
+			SubmissionID:     analysis.SubmissionID,
+			UserID:           s.submissions[analysis.SubmissionID].UserID,
+			RawResponse:      analysis.RawResponse,
+			PromptTokens:     analysis.PromptTokens,
+			OutputTokens:     analysis.OutputTokens,
+			ProcessingTimeMs: analysis.ProcessingTimeMs,
+			Calls:            calls,
+			CreatedAt:        analysis.CreatedAt,
+		}, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// quarantine quarantines a submission, keeping the time it was first
+// quarantined. The caller must hold s.mu.
+func (s *SubmissionStore) quarantine(id uuid.UUID, reason string, bump bool) *models.Submission {
+	submission := s.submissions[id]
+	if submission.QuarantinedAt == nil {
+		now := timestamp.Now()
+		submission.QuarantinedAt = &now
+	}
+	submission.QuarantineReason = &reason
+	submission.UpdatedAt = timestamp.Now()
+	if bump {
+		submission.Version++
+	}
+	return submission
+}
+
+// Quarantine hides a submission from listings until it is released
+func (s *SubmissionStore) Quarantine(ctx context.Context, id uuid.UUID, reason string) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.submissions[id]; !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *s.quarantine(id, reason, true)
+	return &copied, nil
+}
+
+// GetQuarantined retrieves a quarantined submission regardless of owner
+func (s *SubmissionStore) GetQuarantined(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.QuarantinedAt == nil {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *submission
+	return &copied, nil
+}
+
+// ListQuarantined returns a page of quarantined submissions, oldest
+// quarantine first, and the total count
+func (s *SubmissionStore) ListQuarantined(ctx context.Context, limit, offset int) ([]models.Submission, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	quarantined := []models.Submission{}
+	for _, submission := range s.submissions {
+		if submission.QuarantinedAt != nil {
+			quarantined = append(quarantined, *submission)
+		}
+	}
+	sort.Slice(quarantined, func(i, j int) bool {
+		if !quarantined[i].QuarantinedAt.Equal(quarantined[j].QuarantinedAt.Time) {
+			return quarantined[i].QuarantinedAt.Before(quarantined[j].QuarantinedAt.Time)
+		}
+		return quarantined[i].ID.String() < quarantined[j].ID.String()
+	})
+
+	total := len(quarantined)
+	if offset >= total {
+		return []models.Submission{}, total, nil
+	}
+	return quarantined[offset:min(offset+limit, total)], total, nil
+}
+
+// Release lifts a submission's quarantine
+func (s *SubmissionStore) Release(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.QuarantinedAt == nil {
+		return nil, pgx.ErrNoRows
+	}
+	submission.QuarantinedAt = nil
+	submission.QuarantineReason = nil
+	touch(submission)
+
+	copied := *submission
+	return &copied, nil
+}
+
+// Destroy deletes a quarantined submission and its analysis
+func (s *SubmissionStore) Destroy(ctx context.Context, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.QuarantinedAt == nil {
+		return nil, pgx.ErrNoRows
+	}
+	delete(s.submissions, id)
+	delete(s.analyses, id)
+	return submission, nil
+}
+
+// Trash moves a user's submission to their trash, unless it is queued or
+// processing
+func (s *SubmissionStore) Trash(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.UserID != userID || submission.DeletedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+	if submission.Status == models.StatusQueued || submission.Status == models.StatusProcessing {
+		return nil, models.ErrAnalysisInProgress
+	}
+	now := timestamp.Now()
+	submission.DeletedAt = &now
+	touch(submission)
+	copied := *submission
+	return &copied, nil
+}
+
+// Restore takes a submission out of a user's trash
+func (s *SubmissionStore) Restore(ctx context.Context, userID, id uuid.UUID) (*models.Submission, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	submission, ok := s.submissions[id]
+	if !ok || submission.UserID != userID || submission.DeletedAt == nil {
+		return nil, pgx.ErrNoRows
+	}
+	submission.DeletedAt = nil
+	touch(submission)
+	copied := *submission
+	return &copied, nil
+}
+
+// ListTrash returns a page of the submissions in a user's trash, most
+// recently deleted first, and the total count
+func (s *SubmissionStore) ListTrash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.Submission, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	trashed := []models.Submission{}
+	for _, submission := range s.submissions {
+		if submission.UserID == userID && submission.DeletedAt != nil {
+			trashed = append(trashed, *submission)
+		}
+	}
+	sort.Slice(trashed, func(i, j int) bool {
+		if !trashed[i].DeletedAt.Equal(trashed[j].DeletedAt.Time) {
+			return trashed[i].DeletedAt.After(trashed[j].DeletedAt.Time)
+		}
+		return trashed[i].ID.String() < trashed[j].ID.String()
+	})
+
+	total := len(trashed)
+	if offset >= total {
+		return []models.Submission{}, total, nil
+	}
+	end := min(offset+limit, total)
+	return trashed[offset:end], total, nil
+}
+
+// EmptyTrash deletes the submissions in a user's trash
+func (s *SubmissionStore) EmptyTrash(ctx context.Context, userID uuid.UUID) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, submission := range s.submissions {
+		if submission.UserID == userID && submission.DeletedAt != nil {
+			delete(s.submissions, id)
+			delete(s.analyses, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// PurgeTrash deletes up to limit submissions moved to the trash before
+// deletedBefore
+func (s *SubmissionStore) PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for id, submission := range s.submissions {
+		if purged == limit {
+			break
+		}
+		if submission.DeletedAt != nil && submission.DeletedAt.Before(deletedBefore) {
+			delete(s.submissions, id)
+			delete(s.analyses, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// OrganizationStore is an in-memory organization store. Member emails are
+// read from the user store.
+type OrganizationStore struct {
+	mu      sync.Mutex
+	users   *UserStore
+	orgs    map[uuid.UUID]*models.Organization
+	members map[uuid.UUID]map[uuid.UUID]*models.OrgMember
+}
+
+// NewOrganizationStore creates an empty in-memory organization store
+func NewOrganizationStore(users *UserStore) *OrganizationStore {
+	return &OrganizationStore{
+		users:   users,
+		orgs:    make(map[uuid.UUID]*models.Organization),
+		members: make(map[uuid.UUID]map[uuid.UUID]*models.OrgMember),
+	}
+}
+
+// Create stores a new organization with ownerID as its owner
+func (s *OrganizationStore) Create(ctx context.Context, name string, ownerID uuid.UUID) (*models.Organization, error) {
+	if err := models.ValidateOrgName(name); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	org := &models.Organization{ID: uuid.New(), Name: name, CreatedAt: now, UpdatedAt: now}
+	s.orgs[org.ID] = org
+	s.members[org.ID] = map[uuid.UUID]*models.OrgMember{
+		ownerID: {UserID: ownerID, Role: models.RoleOwner, CreatedAt: now},
+	}
+
+	copied := *org
+	return &copied, nil
+}
+
+// Get returns an organization by ID
+func (s *OrganizationStore) Get(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *org
+	return &copied, nil
+}
+
+// SetRetention replaces an organization's retention policy
+func (s *OrganizationStore) SetRetention(ctx context.Context, id uuid.UUID, policy models.RetentionPolicy) (*models.Organization, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	org.RetentionPolicy = policy
+	org.UpdatedAt = timestamp.Now()
+	copied := *org
+	return &copied, nil
+}
+
+// GetBySubdomain returns the organization served on a subdomain
+func (s *OrganizationStore) GetBySubdomain(ctx context.Context, subdomain string) (*models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, org := range s.orgs {
+		if org.Subdomain != nil && *org.Subdomain == subdomain {
+			copied := *org
+			return &copied, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// SetSubdomain sets or removes an organization's subdomain, failing with
+// a unique violation when another organization has it
+func (s *OrganizationStore) SetSubdomain(ctx context.Context, id uuid.UUID, subdomain *string) (*models.Organization, error) {
+	if subdomain != nil {
+		if err := models.ValidateSubdomain(*subdomain); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	if subdomain != nil {
+		for _, other := range s.orgs {
+			if other.ID != id && other.Subdomain != nil && *other.Subdomain == *subdomain {
+				return nil, &pgconn.PgError{Code: "23505"}
+			}
+		}
+		value := *subdomain
+		subdomain = &value
+	}
+	org.Subdomain = subdomain
+	org.UpdatedAt = timestamp.Now()
+	copied := *org
+	return &copied, nil
+}
+
+// SetSpendBudget replaces an organization's spend budget
+func (s *OrganizationStore) SetSpendBudget(ctx context.Context, id uuid.UUID, budget models.SpendBudget) (*models.Organization, error) {
+	if err := budget.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	org, ok := s.orgs[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	org.SpendBudget = budget
+	org.UpdatedAt = timestamp.Now()
+	copied := *org
+	return &copied, nil
+}
+
+// ListForUser returns the organizations userID belongs to, by name
+func (s *OrganizationStore) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var orgs []models.Organization
+	for id, members := range s.members {
+		if _, ok := members[userID]; ok {
+			orgs = append(orgs, *s.orgs[id])
+		}
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].Name < orgs[j].Name })
+	return orgs, nil
+}
+
+// Role returns userID's role in an organization
+func (s *OrganizationStore) Role(ctx context.Context, orgID, userID uuid.UUID) (models.OrgRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.members[orgID][userID]
+	if !ok {
+		return "", pgx.ErrNoRows
+	}
+	return m.Role, nil
+}
+
+// ShareOrganization reports whether two users belong to a common
+// organization
+func (s *OrganizationStore) ShareOrganization(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, members := range s.members {
+		_, a := members[userID]
+		_, b := members[otherID]
+		if a && b {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Members returns an organization's members, by email
+func (s *OrganizationStore) Members(ctx context.Context, orgID uuid.UUID) ([]models.OrgMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members := make([]models.OrgMember, 0, len(s.members[orgID]))
+	for _, m := range s.members[orgID] {
+		member := *m
+		if user, err := s.users.GetByID(ctx, m.UserID); err == nil {
+			member.Email = user.Email
+		}
+		members = append(members, member)
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].Email < members[j].Email })
+	return members, nil
+}
+
+// SetMember adds a member or changes their role
+func (s *OrganizationStore) SetMember(ctx context.Context, orgID, userID uuid.UUID, role models.OrgRole) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if role != models.RoleOwner && s.lastOwner(orgID, userID) {
+		return models.ErrLastOwner
+	}
+
+	if m, ok := s.members[orgID][userID]; ok {
+		m.Role = role
+		return nil
+	}
+	s.members[orgID][userID] = &models.OrgMember{UserID: userID, Role: role, CreatedAt: timestamp.Now()}
+	return nil
+}
+
+// RemoveMember takes a member out of an organization
+func (s *OrganizationStore) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.members[orgID][userID]; !ok {
+		return pgx.ErrNoRows
+	}
+	if s.lastOwner(orgID, userID) {
+		return models.ErrLastOwner
+	}
+	delete(s.members[orgID], userID)
+	return nil
+}
+
+// lastOwner reports whether userID is the organization's only owner
+func (s *OrganizationStore) lastOwner(orgID, userID uuid.UUID) bool {
+	owners := 0
+	for _, m := range s.members[orgID] {
+		if m.Role == models.RoleOwner {
+			owners++
+		}
+	}
+	m, ok := s.members[orgID][userID]
+	return ok && m.Role == models.RoleOwner && owners == 1
+}
+
+// orgDomainKey identifies an organization's claim to a domain
+type orgDomainKey struct {
+	orgID  uuid.UUID
+	domain string
+}
+
+// OrgDomainStore is an in-memory organization domain store
+type OrgDomainStore struct {
+	mu      sync.Mutex
+	domains map[orgDomainKey]*models.OrgDomain
+}
+
+// NewOrgDomainStore creates an empty in-memory organization domain store
+func NewOrgDomainStore() *OrgDomainStore {
+	return &OrgDomainStore{domains: make(map[orgDomainKey]*models.OrgDomain)}
+}
+
+// List returns the organization's domains by name
+func (s *OrgDomainStore) List(ctx context.Context, orgID uuid.UUID) ([]models.OrgDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var domains []models.OrgDomain
+	for key, d := range s.domains {
+		if key.orgID == orgID {
+			domains = append(domains, *d)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].Domain < domains[j].Domain })
+	return domains, nil
+}
+
+// Get returns one of the organization's domains, or pgx.ErrNoRows
+func (s *OrgDomainStore) Get(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.domains[orgDomainKey{orgID, domain}]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// Add claims a domain for the organization, failing with a unique
+// violation if it already has
+func (s *OrgDomainStore) Add(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error) {
+	if err := models.ValidateDomain(domain); err != nil {
+		return nil, err
+	}
+	claim, err := models.NewOrgDomain(orgID, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := orgDomainKey{orgID, domain}
+	if _, ok := s.domains[key]; ok {
+		return nil, &pgconn.PgError{Code: "23505"}
+	}
+	claim.CreatedAt = timestamp.Now()
+	s.domains[key] = claim
+	copied := *claim
+	return &copied, nil
+}
+
+// MarkVerified records that the organization verified the domain,
+// failing with a unique violation if another organization has
+func (s *OrgDomainStore) MarkVerified(ctx context.Context, orgID uuid.UUID, domain string) (*models.OrgDomain, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.domains[orgDomainKey{orgID, domain}]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	for key, other := range s.domains {
+		if key.domain == domain && key.orgID != orgID && other.Verified() {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+	}
+	if d.VerifiedAt == nil {
+		now := timestamp.Now()
+		d.VerifiedAt = &now
+	}
+	copied := *d
+	return &copied, nil
+}
+
+// Remove drops the organization's claim to a domain
+func (s *OrgDomainStore) Remove(ctx context.Context, orgID uuid.UUID, domain string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := orgDomainKey{orgID, domain}
+	if _, ok := s.domains[key]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(s.domains, key)
+	return nil
+}
+
+// IsVerified reports whether the organization has verified the domain
+func (s *OrgDomainStore) IsVerified(ctx context.Context, orgID uuid.UUID, domain string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.domains[orgDomainKey{orgID, domain}]
+	return ok && d.Verified(), nil
+}
+
+// UsageStore is an in-memory usage rollup store
+type UsageStore struct {
+	mu   sync.Mutex
+	orgs *OrganizationStore
+	days map[uuid.UUID]map[time.Time]models.Usage
+}
+
+// NewUsageStore creates an empty usage store reporting on orgs' members
+func NewUsageStore(orgs *OrganizationStore) *UsageStore {
+	return &UsageStore{orgs: orgs, days: make(map[uuid.UUID]map[time.Time]models.Usage)}
+}
+
+// Record adds usage to userID's rollup for the UTC day of at
+func (s *UsageStore) Record(userID uuid.UUID, at time.Time, usage models.Usage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := at.UTC().Truncate(24 * time.Hour)
+	if s.days[userID] == nil {
+		s.days[userID] = make(map[time.Time]models.Usage)
+	}
+	total := s.days[userID][day]
+	total.Add(usage)
+	s.days[userID][day] = total
+}
+
+// OrgUsage returns each member's usage for the days in [from, to)
+func (s *UsageStore) OrgUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]models.MemberUsage, error) {
+	members, err := s.orgs.Members(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]models.MemberUsage, 0, len(members))
+	for _, m := range members {
+		usage := models.MemberUsage{UserID: m.UserID, Email: m.Email, Role: m.Role}
+		for day, u := range s.days[m.UserID] {
+			if !day.Before(from) && day.Before(to) {
+				usage.Add(u)
+			}
+		}
+		result = append(result, usage)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CostMicros > result[j].CostMicros })
+	return result, nil
+}
+
+// RetentionStore is an in-memory record of legal holds on submissions
+type RetentionStore struct {
+	mu          sync.Mutex
+	submissions *SubmissionStore
+	held        map[uuid.UUID]bool
+}
+
+// NewRetentionStore places holds on submissions in the given store
+func NewRetentionStore(submissions *SubmissionStore) *RetentionStore {
+	return &RetentionStore{
+		submissions: submissions,
+		held:        make(map[uuid.UUID]bool),
+	}
+}
+
+// SetLegalHold places a submission on legal hold, or releases it
+func (s *RetentionStore) SetLegalHold(ctx context.Context, submissionID uuid.UUID, hold bool) error {
+	if _, err := s.submissions.Get(ctx, submissionID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held[submissionID] = hold
+	return nil
+}
+
+// Held reports whether a submission is on legal hold
+func (s *RetentionStore) Held(submissionID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.held[submissionID]
+}
+
+// ModerationStore is an in-memory store of moderation policies and policy
+// overrides. Policies apply to the members of orgs' organizations and
+// overrides to the analyses in submissions.
+type ModerationStore struct {
+	mu          sync.Mutex
+	orgs        *OrganizationStore
+	submissions *SubmissionStore
+	policies    map[uuid.UUID]*models.ModerationPolicy
+	overrides   map[uuid.UUID]*models.PolicyOverride
+}
+
+// NewModerationStore creates an empty moderation store
+func NewModerationStore(orgs *OrganizationStore, submissions *SubmissionStore) *ModerationStore {
+	return &ModerationStore{
+		orgs:        orgs,
+		submissions: submissions,
+		policies:    make(map[uuid.UUID]*models.ModerationPolicy),
+		overrides:   make(map[uuid.UUID]*models.PolicyOverride),
+	}
+}
+
+// Policy returns an organization's moderation policy
+func (s *ModerationStore) Policy(ctx context.Context, orgID uuid.UUID) (*models.ModerationPolicy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if policy, ok := s.policies[orgID]; ok {
+		copied := *policy
+		return &copied, nil
+	}
+	return &models.ModerationPolicy{OrgID: orgID, Thresholds: []models.ModerationThreshold{}}, nil
+}
+
+// SetPolicy replaces an organization's thresholds
+func (s *ModerationStore) SetPolicy(ctx context.Context, orgID uuid.UUID, thresholds []models.ModerationThreshold) (*models.ModerationPolicy, error) {
+	if errs := models.ValidateThresholds(thresholds); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid moderation thresholds")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(thresholds) == 0 {
+		delete(s.policies, orgID)
+		return &models.ModerationPolicy{OrgID: orgID, Thresholds: []models.ModerationThreshold{}}, nil
+	}
+
+	sorted := append([]models.ModerationThreshold(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Category < sorted[j].Category })
+	now := timestamp.Now()
+	s.policies[orgID] = &models.ModerationPolicy{OrgID: orgID, Thresholds: sorted, UpdatedAt: &now}
+
+	copied := *s.policies[orgID]
+	return &copied, nil
+}
+
+// PolicyForUser merges the policies of userID's organizations, keeping the
+// strictest threshold of each category
+func (s *ModerationStore) PolicyForUser(ctx context.Context, userID uuid.UUID) (*models.ModerationPolicy, error) {
+	orgs, err := s.orgs.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	strictest := func(a, b *float64) *float64 {
+		if a == nil || (b != nil && *b < *a) {
+			return b
+		}
+		return a
+	}
+
+	merged := make(map[models.ModerationCategory]models.ModerationThreshold)
+	for _, org := range orgs {
+		policy, ok := s.policies[org.ID]
+		if !ok {
+			continue
+		}
+		for _, t := range policy.Thresholds {
+			m := merged[t.Category]
+			m.Category = t.Category
+			m.WarnAbove = strictest(m.WarnAbove, t.WarnAbove)
+			m.BlockAbove = strictest(m.BlockAbove, t.BlockAbove)
+			merged[t.Category] = m
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+
+	policy := &models.ModerationPolicy{}
+	for _, t := range merged {
+		policy.Thresholds = append(policy.Thresholds, t)
+	}
+	sort.Slice(policy.Thresholds, func(i, j int) bool { return policy.Thresholds[i].Category < policy.Thresholds[j].Category })
+	return policy, nil
+}
+
+// Override returns a submission's policy override, or nil
+func (s *ModerationStore) Override(ctx context.Context, submissionID uuid.UUID) (*models.PolicyOverride, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.overrides[submissionID], nil
+}
+
+// SetOverride replaces a submission's override and applies it to its
+// analysis' decision
+func (s *ModerationStore) SetOverride(ctx context.Context, submissionID uuid.UUID, override *models.PolicyOverride) (*models.PolicyDecision, error) {
+	if _, err := s.submissions.Get(ctx, submissionID); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if override != nil {
+		s.overrides[submissionID] = override
+	} else {
+		delete(s.overrides, submissionID)
+	}
+	s.mu.Unlock()
+
+	s.submissions.mu.Lock()
+	defer s.submissions.mu.Unlock()
+	if submission, ok := s.submissions.submissions[submissionID]; ok {
+		touch(submission)
+	}
+
+	analysis, ok := s.submissions.analyses[submissionID]
+	if !ok {
+		return nil, nil
+	}
+	decision := analysis.PolicyDecision
+	if decision == nil {
+		if override == nil {
+			return nil, nil
+		}
+		decision = models.EvaluatePolicy(nil, nil)
+	}
+
+	updated := *decision
+	updated.ApplyOverride(override)
+	analysis.PolicyDecision = &updated
+	analysis.UpdatedAt = timestamp.Now()
+	copied := updated
+	return &copied, nil
+}
+
+// GlossaryStore is an in-memory store of organization glossaries, which
+// apply to the members of orgs' organizations
+type GlossaryStore struct {
+	mu         sync.Mutex
+	orgs       *OrganizationStore
+	glossaries map[uuid.UUID]*models.Glossary
+}
+
+// NewGlossaryStore creates an empty glossary store
+func NewGlossaryStore(orgs *OrganizationStore) *GlossaryStore {
+	return &GlossaryStore{orgs: orgs, glossaries: make(map[uuid.UUID]*models.Glossary)}
+}
+
+// Glossary returns an organization's glossary
+func (s *GlossaryStore) Glossary(ctx context.Context, orgID uuid.UUID) (*models.Glossary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if glossary, ok := s.glossaries[orgID]; ok {
+		copied := *glossary
+		copied.Entries = append([]models.GlossaryEntry(nil), glossary.Entries...)
+		return &copied, nil
+	}
+	return &models.Glossary{OrgID: orgID, Entries: []models.GlossaryEntry{}}, nil
+}
+
+// SetGlossary replaces an organization's entries
+func (s *GlossaryStore) SetGlossary(ctx context.Context, orgID uuid.UUID, entries []models.GlossaryEntry) (*models.Glossary, error) {
+	if errs := models.ValidateGlossary(entries); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid glossary entries")
+	}
+
+	s.mu.Lock()
+	if len(entries) == 0 {
+		delete(s.glossaries, orgID)
+	} else {
+		stored := make([]models.GlossaryEntry, len(entries))
+		for i, e := range entries {
+			e.Phrase = strings.TrimSpace(e.Phrase)
+			stored[i] = e
+		}
+		now := timestamp.Now()
+		s.glossaries[orgID] = &models.Glossary{OrgID: orgID, Entries: stored, UpdatedAt: &now}
+	}
+	s.mu.Unlock()
+
+	return s.Glossary(ctx, orgID)
+}
+
+// GlossaryForUser merges the glossaries of userID's organizations, each
+// phrase once per kind
+func (s *GlossaryStore) GlossaryForUser(ctx context.Context, userID uuid.UUID) (*models.Glossary, error) {
+	orgs, err := s.orgs.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID.String() < orgs[j].ID.String() })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var entries []models.GlossaryEntry
+	for _, org := range orgs {
+		glossary, ok := s.glossaries[org.ID]
+		if !ok {
+			continue
+		}
+		for _, e := range glossary.Entries {
+			key := string(e.Kind) + ":" + strings.ToLower(e.Phrase)
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, e)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Kind != entries[j].Kind {
+			return entries[i].Kind < entries[j].Kind
+		}
+		return strings.ToLower(entries[i].Phrase) < strings.ToLower(entries[j].Phrase)
+	})
+	return &models.Glossary{Entries: entries}, nil
+}
+
+// TaxonomyStore is an in-memory store of organization taxonomies, which
+// apply to the members of orgs' organizations
+type TaxonomyStore struct {
+	mu         sync.Mutex
+	orgs       *OrganizationStore
+	taxonomies map[uuid.UUID]*models.Taxonomy
+}
+
+// NewTaxonomyStore creates an empty taxonomy store
+func NewTaxonomyStore(orgs *OrganizationStore) *TaxonomyStore {
+	return &TaxonomyStore{orgs: orgs, taxonomies: make(map[uuid.UUID]*models.Taxonomy)}
+}
+
+// Taxonomy returns an organization's taxonomy
+func (s *TaxonomyStore) Taxonomy(ctx context.Context, orgID uuid.UUID) (*models.Taxonomy, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if taxonomy, ok := s.taxonomies[orgID]; ok {
+		copied := *taxonomy
+		copied.Labels = append([]models.TaxonomyLabel(nil), taxonomy.Labels...)
+		return &copied, nil
+	}
+	return &models.Taxonomy{OrgID: orgID, Labels: []models.TaxonomyLabel{}}, nil
+}
+
+// SetTaxonomy replaces an organization's labels
+func (s *TaxonomyStore) SetTaxonomy(ctx context.Context, orgID uuid.UUID, labels []models.TaxonomyLabel) (*models.Taxonomy, error) {
+	if errs := models.ValidateTaxonomy(labels); len(errs) > 0 {
+		return nil, fmt.Errorf("invalid taxonomy labels")
+	}
+
+	s.mu.Lock()
+	if len(labels) == 0 {
+		delete(s.taxonomies, orgID)
+	} else {
+		stored := make([]models.TaxonomyLabel, len(labels))
+		for i, l := range labels {
+			l.Name = strings.TrimSpace(l.Name)
+			stored[i] = l
+		}
+		now := timestamp.Now()
+		s.taxonomies[orgID] = &models.Taxonomy{OrgID: orgID, Labels: stored, UpdatedAt: &now}
+	}
+	s.mu.Unlock()
+
+	return s.Taxonomy(ctx, orgID)
+}
+
+// TaxonomyForUser merges the taxonomies of userID's organizations, each
+// label name once
+func (s *TaxonomyStore) TaxonomyForUser(ctx context.Context, userID uuid.UUID) (*models.Taxonomy, error) {
+	orgs, err := s.orgs.ListForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID.String() < orgs[j].ID.String() })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	var labels []models.TaxonomyLabel
+	for _, org := range orgs {
+		taxonomy, ok := s.taxonomies[org.ID]
+		if !ok {
+			continue
+		}
+		for _, l := range taxonomy.Labels {
+			if key := strings.ToLower(l.Name); !seen[key] {
+				seen[key] = true
+				labels = append(labels, l)
+			}
+		}
+	}
+	if len(labels) == 0 {
+		return nil, nil
+	}
+
+	sort.SliceStable(labels, func(i, j int) bool { return strings.ToLower(labels[i].Name) < strings.ToLower(labels[j].Name) })
+	return &models.Taxonomy{Labels: labels}, nil
+}
+
+// ProfileStore is an in-memory analysis profile store
+type ProfileStore struct {
+	mu       sync.Mutex
+	profiles map[uuid.UUID]*models.AnalysisProfile
+}
+
+// NewProfileStore creates an in-memory profile store without built-in profiles
+func NewProfileStore() *ProfileStore {
+	return &ProfileStore{profiles: make(map[uuid.UUID]*models.AnalysisProfile)}
+}
+
+// AddBuiltIn stores a built-in profile, as the migrations do
+func (s *ProfileStore) AddBuiltIn(name string, modules ...models.AnalysisModule) *models.AnalysisProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	profile := &models.AnalysisProfile{
+		ID:        uuid.New(),
+		Name:      name,
+		Modules:   modules,
+		BuiltIn:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	s.profiles[profile.ID] = profile
+
+	copied := *profile
+	return &copied
+}
+
+// visible reports whether userID can use profile
+func visible(profile *models.AnalysisProfile, userID uuid.UUID) bool {
+	return profile.UserID == nil || *profile.UserID == userID
+}
+
+// List returns the built-in profiles followed by the user's own, each by name
+func (s *ProfileStore) List(ctx context.Context, userID uuid.UUID) ([]models.AnalysisProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles := []models.AnalysisProfile{}
+	for _, profile := range s.profiles {
+		if visible(profile, userID) {
+			profiles = append(profiles, *profile)
+		}
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		if profiles[i].BuiltIn != profiles[j].BuiltIn {
+			return profiles[i].BuiltIn
+		}
+		return strings.ToLower(profiles[i].Name) < strings.ToLower(profiles[j].Name)
+	})
+	return profiles, nil
+}
+
+// Get retrieves a profile by ID regardless of owner
+func (s *ProfileStore) Get(ctx context.Context, id uuid.UUID) (*models.AnalysisProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+// GetByID retrieves a built-in profile or one owned by the given user
+func (s *ProfileStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.AnalysisProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[id]
+	if !ok || !visible(profile, userID) {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *profile
+	return &copied, nil
+}
+
+// checkName returns a unique violation if the owner already has a profile
+// named name, other than the one with id. Callers hold s.mu.
+func (s *ProfileStore) checkName(userID uuid.UUID, id uuid.UUID, name string) error {
+	for _, profile := range s.profiles {
+		if profile.ID != id && profile.UserID != nil && *profile.UserID == userID && strings.EqualFold(profile.Name, name) {
+			return &pgconn.PgError{
+				Code:           "23505",
+				Message:        "duplicate key value violates unique constraint",
+				ConstraintName: "idx_analysis_profiles_owner_name",
+			}
+		}
+	}
+	return nil
+}
+
+// Create stores a new profile owned by profile.UserID
+func (s *ProfileStore) Create(ctx context.Context, profile *models.AnalysisProfile) (*models.AnalysisProfile, error) {
+	if profile.UserID == nil {
+		return nil, fmt.Errorf("built-in profiles are created by migrations")
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owned := 0
+	for _, p := range s.profiles {
+		if p.UserID != nil && *p.UserID == *profile.UserID {
+			owned++
+		}
+	}
+	if owned >= models.MaxProfilesPerUser {
+		return nil, models.ErrProfileLimit
+	}
+	name := strings.TrimSpace(profile.Name)
+	if err := s.checkName(*profile.UserID, uuid.Nil, name); err != nil {
+		return nil, fmt.Errorf("failed to create analysis profile: %w", err)
+	}
+
+	now := timestamp.Now()
+	stored := *profile
+	stored.ID = uuid.New()
+	stored.Name = name
+	stored.BuiltIn = false
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.profiles[stored.ID] = &stored
+
+	copied := stored
+	return &copied, nil
+}
+
+// Update replaces a profile owned by profile.UserID
+func (s *ProfileStore) Update(ctx context.Context, profile *models.AnalysisProfile) (*models.AnalysisProfile, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.profiles[profile.ID]
+	if !ok || existing.UserID == nil || profile.UserID == nil || *existing.UserID != *profile.UserID {
+		return nil, pgx.ErrNoRows
+	}
+	name := strings.TrimSpace(profile.Name)
+	if err := s.checkName(*profile.UserID, profile.ID, name); err != nil {
+		return nil, err
+	}
+
+	existing.Name = name
+	existing.Description = profile.Description
+	existing.Prompt = profile.Prompt
+	existing.Modules = profile.Modules
+	existing.UpdatedAt = timestamp.Now()
+
+	copied := *existing
+	return &copied, nil
+}
+
+// Delete removes a profile owned by the given user
+func (s *ProfileStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.profiles[id]
+	if !ok || profile.UserID == nil || *profile.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	delete(s.profiles, id)
+	return nil
+}
+
+// ThreadStore is an in-memory conversation thread store
+type ThreadStore struct {
+	mu      sync.Mutex
+	threads map[uuid.UUID]*models.Thread
+}
+
+// NewThreadStore creates an empty in-memory thread store
+func NewThreadStore() *ThreadStore {
+	return &ThreadStore{threads: make(map[uuid.UUID]*models.Thread)}
+}
+
+// snapshotThread copies a thread, with its messages when messages is set.
+// Callers hold s.mu.
+func snapshotThread(thread *models.Thread, messages bool) models.Thread {
+	copied := *thread
+	copied.MessageCount = len(thread.Messages)
+	copied.Pending = models.Awaiting(thread.Messages)
+	copied.Messages = nil
+	if messages {
+		copied.Messages = append([]models.ThreadMessage{}, thread.Messages...)
+	}
+	return copied
+}
+
+// ListForSubmission returns the user's threads on a submission, most
+// recently active first
+func (s *ThreadStore) ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	threads := []models.Thread{}
+	for _, thread := range s.threads {
+		if thread.UserID == userID && thread.SubmissionID == submissionID {
+			threads = append(threads, snapshotThread(thread, false))
+		}
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		return threads[i].UpdatedAt.After(threads[j].UpdatedAt.Time)
+	})
+	return threads, nil
+}
+
+// Get retrieves a thread and its messages regardless of owner
+func (s *ThreadStore) Get(ctx context.Context, id uuid.UUID) (*models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := snapshotThread(thread, true)
+	return &copied, nil
+}
+
+// GetByID retrieves a thread owned by the given user, with its messages
+func (s *ThreadStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[id]
+	if !ok || thread.UserID != userID {
+		return nil, pgx.ErrNoRows
+	}
+	copied := snapshotThread(thread, true)
+	return &copied, nil
+}
+
+// Create starts a thread on a submission with the user's first message
+func (s *ThreadStore) Create(ctx context.Context, userID, submissionID uuid.UUID, message string) (*models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	thread := &models.Thread{
+		ID:           uuid.New(),
+		SubmissionID: submissionID,
+		UserID:       userID,
+		Title:        models.ThreadTitle(message),
+		CreatedAt:    now,
+		UpdatedAt:    now,
+		Messages: []models.ThreadMessage{
+			{ID: uuid.New(), Role: models.MessageRoleUser, Content: message, CreatedAt: now},
+		},
+	}
+	s.threads[thread.ID] = thread
+
+	copied := snapshotThread(thread, true)
+	return &copied, nil
+}
+
+// AddMessage appends a user message to a thread owned by the given user
+func (s *ThreadStore) AddMessage(ctx context.Context, userID, threadID uuid.UUID, message string) (*models.Thread, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[threadID]
+	if !ok || thread.UserID != userID {
+		return nil, pgx.ErrNoRows
+	}
+	switch {
+	case models.Awaiting(thread.Messages):
+		return nil, models.ErrThreadBusy
+	case len(thread.Messages) >= models.MaxThreadMessages:
+		return nil, models.ErrThreadFull
+	}
+
+	now := timestamp.Now()
+	thread.Messages = append(thread.Messages, models.ThreadMessage{
+		ID:        uuid.New(),
+		Role:      models.MessageRoleUser,
+		Content:   message,
+		CreatedAt: now,
+	})
+	thread.UpdatedAt = now
+
+	copied := snapshotThread(thread, true)
+	return &copied, nil
+}
+
+// AddReply appends the model's reply to a thread awaiting one
+func (s *ThreadStore) AddReply(ctx context.Context, threadID uuid.UUID, reply *models.ThreadMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[threadID]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	if !models.Awaiting(thread.Messages) {
+		return models.ErrThreadBusy
+	}
+
+	now := timestamp.Now()
+	stored := *reply
+	stored.ID = uuid.New()
+	stored.Role = models.MessageRoleAssistant
+	stored.CreatedAt = now
+	thread.Messages = append(thread.Messages, stored)
+	thread.UpdatedAt = now
+	return nil
+}
+
+// MarkFailed flags a thread's last message as unanswered when it is a
+// user message
+func (s *ThreadStore) MarkFailed(ctx context.Context, threadID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[threadID]
+	if ok && len(thread.Messages) > 0 {
+		last := &thread.Messages[len(thread.Messages)-1]
+		if last.Role == models.MessageRoleUser {
+			last.Failed = true
+		}
+	}
+	return nil
+}
+
+// Delete removes a thread owned by the given user
+func (s *ThreadStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	thread, ok := s.threads[id]
+	if !ok || thread.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	delete(s.threads, id)
+	return nil
+}
+
+// TranscriptionStore is an in-memory audio and video upload store
+type TranscriptionStore struct {
+	mu             sync.Mutex
+	transcriptions map[uuid.UUID]*models.Transcription
+	media          map[uuid.UUID][]byte
+}
+
+// NewTranscriptionStore creates an empty in-memory transcription store
+func NewTranscriptionStore() *TranscriptionStore {
+	return &TranscriptionStore{
+		transcriptions: make(map[uuid.UUID]*models.Transcription),
+		media:          make(map[uuid.UUID][]byte),
+	}
+}
+
+// Create stores an upload awaiting transcription
+func (s *TranscriptionStore) Create(ctx context.Context, t *models.Transcription, media []byte) (*models.Transcription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	stored := *t
+	stored.ID = uuid.New()
+	stored.SizeBytes = int64(len(media))
+	stored.Status = models.TranscriptionPending
+	stored.Segments = []models.TranscriptSegment{}
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.transcriptions[stored.ID] = &stored
+	s.media[stored.ID] = append([]byte(nil), media...)
+
+	copied := stored
+	return &copied, nil
+}
+
+// find returns a copy of the first transcription matching match
+func (s *TranscriptionStore) find(match func(t *models.Transcription) bool) (*models.Transcription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, t := range s.transcriptions {
+		if match(t) {
+			copied := *t
+			return &copied, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// Get retrieves a transcription by ID regardless of owner
+func (s *TranscriptionStore) Get(ctx context.Context, id uuid.UUID) (*models.Transcription, error) {
+	return s.find(func(t *models.Transcription) bool { return t.ID == id })
+}
+
+// GetByID retrieves a transcription owned by the given user
+func (s *TranscriptionStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Transcription, error) {
+	return s.find(func(t *models.Transcription) bool { return t.ID == id && t.UserID == userID })
+}
+
+// GetForSubmission retrieves the transcription a submission was created from
+func (s *TranscriptionStore) GetForSubmission(ctx context.Context, userID, submissionID uuid.UUID) (*models.Transcription, error) {
+	return s.find(func(t *models.Transcription) bool {
+		return t.SubmissionID != nil && *t.SubmissionID == submissionID && t.UserID == userID
+	})
+}
+
+// Media returns the uploaded recording of a pending transcription
+func (s *TranscriptionStore) Media(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	media, ok := s.media[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return media, nil
+}
+
+// Complete stores the transcript of a pending transcription
+func (s *TranscriptionStore) Complete(ctx context.Context, t *models.Transcription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.transcriptions[t.ID]
+	if !ok || stored.Status != models.TranscriptionPending {
+		return pgx.ErrNoRows
+	}
+	stored.Status = models.TranscriptionCompleted
+	stored.SubmissionID = t.SubmissionID
+	stored.Provider = t.Provider
+	stored.Language = t.Language
+	stored.DurationSeconds = t.DurationSeconds
+	stored.Segments = append([]models.TranscriptSegment{}, t.Segments...)
+	stored.UpdatedAt = timestamp.Now()
+	delete(s.media, t.ID)
+	return nil
+}
+
+// Fail marks a pending transcription failed
+func (s *TranscriptionStore) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.transcriptions[id]
+	if ok && stored.Status == models.TranscriptionPending {
+		stored.Status = models.TranscriptionFailed
+		stored.Error = &message
+		stored.UpdatedAt = timestamp.Now()
+		delete(s.media, id)
+	}
+	return nil
+}
+
+// ImportStore is an in-memory bulk import store
+type ImportStore struct {
+	mu      sync.Mutex
+	imports map[uuid.UUID]*models.Import
+	data    map[uuid.UUID][]byte
+}
+
+// NewImportStore creates an empty in-memory import store
+func NewImportStore() *ImportStore {
+	return &ImportStore{
+		imports: make(map[uuid.UUID]*models.Import),
+		data:    make(map[uuid.UUID][]byte),
+	}
+}
+
+// Create stores an import awaiting processing
+func (s *ImportStore) Create(ctx context.Context, i *models.Import, data []byte) (*models.Import, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	stored := *i
+	stored.ID = uuid.New()
+	stored.Status = models.ImportPending
+	stored.RowErrors = []models.ImportRowError{}
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	s.imports[stored.ID] = &stored
+	s.data[stored.ID] = append([]byte(nil), data...)
+
+	return copyImport(&stored), nil
+}
+
+// copyImport copies an import so callers can't modify the stored row
+func copyImport(i *models.Import) *models.Import {
+	copied := *i
+	copied.RowErrors = append([]models.ImportRowError{}, i.RowErrors...)
+	return &copied
+}
+
+// Get retrieves an import by ID regardless of owner
+func (s *ImportStore) Get(ctx context.Context, id uuid.UUID) (*models.Import, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.imports[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return copyImport(stored), nil
+}
+
+// GetByID retrieves an import owned by the given user
+func (s *ImportStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Import, error) {
+	i, err := s.Get(ctx, id)
+	if err != nil || i.UserID != userID {
+		return nil, pgx.ErrNoRows
+	}
+	return i, nil
+}
+
+// Data returns the file of an unfinished import
+func (s *ImportStore) Data(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.data[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return data, nil
+}
+
+// Progress records how far an unfinished import has got
+func (s *ImportStore) Progress(ctx context.Context, i *models.Import) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.imports[i.ID]
+	if !ok || (stored.Status != models.ImportPending && stored.Status != models.ImportProcessing) {
+		return pgx.ErrNoRows
+	}
+	stored.Status = models.ImportProcessing
+	stored.ProcessedRows = i.ProcessedRows
+	stored.ImportedRows = i.ImportedRows
+	stored.FailedRows = i.FailedRows
+	stored.RowErrors = append([]models.ImportRowError{}, i.RowErrors...)
+	stored.UpdatedAt = timestamp.Now()
+	return nil
+}
+
+// Complete marks an unfinished import completed
+func (s *ImportStore) Complete(ctx context.Context, id uuid.UUID) error {
+	return s.finish(id, models.ImportCompleted, nil)
+}
+
+// Fail marks an unfinished import failed
+func (s *ImportStore) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	return s.finish(id, models.ImportFailed, &message)
+}
+
+// finish moves an unfinished import to status and deletes the file
+func (s *ImportStore) finish(id uuid.UUID, status models.ImportStatus, message *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.imports[id]
+	if ok && (stored.Status == models.ImportPending || stored.Status == models.ImportProcessing) {
+		stored.Status = status
+		stored.Error = message
+		stored.UpdatedAt = timestamp.Now()
+		delete(s.data, id)
+	}
+	return nil
+}
+
+// FeedStore is an in-memory feed store. Digests read submission statuses
+// and analyses from the given submission store.
+type FeedStore struct {
+	mu          sync.Mutex
+	submissions *SubmissionStore
+	feeds       map[uuid.UUID]*models.Feed
+	items       map[uuid.UUID]*models.FeedItem
+}
+
+// NewFeedStore creates an empty in-memory feed store
+func NewFeedStore(submissions *SubmissionStore) *FeedStore {
+	return &FeedStore{
+		submissions: submissions,
+		feeds:       make(map[uuid.UUID]*models.Feed),
+		items:       make(map[uuid.UUID]*models.FeedItem),
+	}
+}
+
+// List returns the user's feeds, oldest first
+func (s *FeedStore) List(ctx context.Context, userID uuid.UUID) ([]models.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeds := []models.Feed{}
+	for _, f := range s.feeds {
+		if f.UserID == userID {
+			feeds = append(feeds, *f)
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool { return feeds[i].CreatedAt.Before(feeds[j].CreatedAt.Time) })
+	return feeds, nil
+}
+
+// ListDue returns up to limit feeds not polled since before
+func (s *FeedStore) ListDue(ctx context.Context, before time.Time, limit int) ([]models.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	feeds := []models.Feed{}
+	for _, f := range s.feeds {
+		if f.LastPolledAt == nil || f.LastPolledAt.Before(before) {
+			feeds = append(feeds, *f)
+		}
+	}
+	sort.Slice(feeds, func(i, j int) bool {
+		a, b := feeds[i].LastPolledAt, feeds[j].LastPolledAt
+		return a == nil && b != nil || a != nil && b != nil && a.Before(b.Time)
+	})
+	if len(feeds) > limit {
+		feeds = feeds[:limit]
+	}
+	return feeds, nil
+}
+
+// Get retrieves a feed by ID regardless of owner
+func (s *FeedStore) Get(ctx context.Context, id uuid.UUID) (*models.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *f
+	return &copied, nil
+}
+
+// GetByID retrieves a feed owned by the given user
+func (s *FeedStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*models.Feed, error) {
+	f, err := s.Get(ctx, id)
+	if err != nil || f.UserID != userID {
+		return nil, pgx.ErrNoRows
+	}
+	return f, nil
+}
+
+// Create starts monitoring a feed, enforcing the per-user limit and
+// unique URLs like the Postgres store
+func (s *FeedStore) Create(ctx context.Context, userID uuid.UUID, url string) (*models.Feed, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, f := range s.feeds {
+		if f.UserID != userID {
+			continue
+		}
+		if f.URL == url {
+			return nil, &pgconn.PgError{Code: "23505"}
+		}
+		count++
+	}
+	if count >= models.MaxFeedsPerUser {
+		return nil, models.ErrFeedLimit
+	}
+
+	now := timestamp.Now()
+	f := &models.Feed{ID: uuid.New(), UserID: userID, URL: url, CreatedAt: now, UpdatedAt: now}
+	s.feeds[f.ID] = f
+	copied := *f
+	return &copied, nil
+}
+
+// Delete stops monitoring a feed owned by the given user
+func (s *FeedStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok || f.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	delete(s.feeds, id)
+	for itemID, item := range s.items {
+		if item.FeedID == id {
+			delete(s.items, itemID)
+		}
+	}
+	return nil
+}
+
+// MarkPolled records a poll of a feed
+func (s *FeedStore) MarkPolled(ctx context.Context, id uuid.UUID, title, pollErr *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, ok := s.feeds[id]
+	if !ok {
+		return nil
+	}
+	now := timestamp.Now()
+	if title != nil {
+		f.Title = title
+	}
+	f.LastError = pollErr
+	f.LastPolledAt = &now
+	f.UpdatedAt = now
+	return nil
+}
+
+// AddItem records an item seen in a feed, returning pgx.ErrNoRows for a
+// GUID the feed already has
+func (s *FeedStore) AddItem(ctx context.Context, item *models.FeedItem) (*models.FeedItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.items {
+		if existing.FeedID == item.FeedID && existing.GUID == item.GUID {
+			return nil, pgx.ErrNoRows
+		}
+	}
+
+	stored := *item
+	stored.ID = uuid.New()
+	stored.SubmissionID = nil
+	stored.CreatedAt = timestamp.Now()
+	s.items[stored.ID] = &stored
+	copied := stored
+	return &copied, nil
+}
+
+// AttachSubmission links an item to the submission created from it
+func (s *FeedStore) AttachSubmission(ctx context.Context, itemID, submissionID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if item, ok := s.items[itemID]; ok {
+		item.SubmissionID = &submissionID
+	}
+	return nil
+}
+
+// Items returns every item recorded for a feed, in no particular order
+func (s *FeedStore) Items(feedID uuid.UUID) []models.FeedItem {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []models.FeedItem{}
+	for _, item := range s.items {
+		if item.FeedID == feedID {
+			items = append(items, *item)
+		}
+	}
+	return items
+}
+
+// DigestItems returns the latest submitted items of a feed owned by the
+// given user, with the analysis of each
+func (s *FeedStore) DigestItems(ctx context.Context, userID, feedID uuid.UUID, limit int) ([]models.FeedDigestItem, error) {
+	if _, err := s.GetByID(ctx, userID, feedID); err != nil {
+		return []models.FeedDigestItem{}, nil
+	}
+
+	items := []models.FeedDigestItem{}
+	for _, item := range s.Items(feedID) {
+		if item.SubmissionID == nil {
+			continue
+		}
+		submission, err := s.submissions.Get(ctx, *item.SubmissionID)
+		if err != nil {
+			continue
+		}
+		digest := models.FeedDigestItem{FeedItem: item, Status: submission.Status}
+		if analysis, err := s.submissions.GetAnalysis(ctx, userID, submission.ID); err == nil {
+			digest.Sentiment = &analysis.Sentiment
+			digest.SentimentScore = analysis.SentimentScore
+			if analysis.Summary != "" {
+				digest.Summary = &analysis.Summary
+			}
+		}
+		items = append(items, digest)
+	}
+
+	published := func(item models.FeedDigestItem) time.Time {
+		if item.PublishedAt != nil {
+			return item.PublishedAt.Time
+		}
+		return item.CreatedAt.Time
+	}
+	sort.Slice(items, func(i, j int) bool { return published(items[i]).After(published(items[j])) })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
+
+// APIKeyStore is an in-memory API key store
+type APIKeyStore struct {
+	mu   sync.Mutex
+	keys map[uuid.UUID]*models.APIKey
+	// plaintext maps each key to its ID; the Postgres store keeps hashes
+	plaintext map[string]uuid.UUID
+}
+
+// NewAPIKeyStore creates an empty in-memory API key store
+func NewAPIKeyStore() *APIKeyStore {
+	return &APIKeyStore{
+		keys:      make(map[uuid.UUID]*models.APIKey),
+		plaintext: make(map[string]uuid.UUID),
+	}
+}
+
+// List returns a user's keys, including revoked ones, newest first
+func (s *APIKeyStore) List(ctx context.Context, userID uuid.UUID) ([]models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := []models.APIKey{}
+	for _, k := range s.keys {
+		if k.UserID == userID {
+			keys = append(keys, *k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt.Time) })
+	return keys, nil
+}
+
+// Create stores a new key for the user, enforcing the per-user limit like
+// the Postgres store
+func (s *APIKeyStore) Create(ctx context.Context, userID uuid.UUID, name string, scopes []string) (string, *models.APIKey, error) {
+	plaintext, err := models.NewAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := 0
+	for _, k := range s.keys {
+		if k.UserID == userID && k.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= models.MaxAPIKeysPerUser {
+		return "", nil, models.ErrAPIKeyLimit
+	}
+
+	key := &models.APIKey{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Name:      name,
+		Prefix:    models.APIKeyPrefix(plaintext),
+		Scopes:    scopes,
+		CreatedAt: timestamp.Now(),
+	}
+	s.keys[key.ID] = key
+	s.plaintext[plaintext] = key.ID
+
+	copied := *key
+	return plaintext, &copied, nil
+}
+
+// Revoke stops one of the user's keys from authenticating
+func (s *APIKeyStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok || key.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	if key.RevokedAt == nil {
+		now := timestamp.Now()
+		key.RevokedAt = &now
+	}
+	return nil
+}
+
+// Authenticate looks up an active key by its plaintext and records its use
+func (s *APIKeyStore) Authenticate(ctx context.Context, plaintext string) (*models.APIKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.plaintext[plaintext]
+	if !ok || s.keys[id].RevokedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+	now := timestamp.Now()
+	s.keys[id].LastUsedAt = &now
+
+	copied := *s.keys[id]
+	return &copied, nil
+}
+
+// HookStore is an in-memory REST hook store. Like the Postgres store it
+// leaves out hooks whose API key has been revoked and reads analyses from
+// the submission store.
+type HookStore struct {
+	mu          sync.Mutex
+	keys        *APIKeyStore
+	submissions *SubmissionStore
+	hooks       map[uuid.UUID]*models.RESTHook
+}
+
+// NewHookStore creates an empty in-memory hook store
+func NewHookStore(keys *APIKeyStore, submissions *SubmissionStore) *HookStore {
+	return &HookStore{
+		keys:        keys,
+		submissions: submissions,
+		hooks:       make(map[uuid.UUID]*models.RESTHook),
+	}
+}
+
+// active reports whether the hook's API key is still active
+func (s *HookStore) active(h *models.RESTHook) bool {
+	s.keys.mu.Lock()
+	defer s.keys.mu.Unlock()
+
+	key, ok := s.keys.keys[h.APIKeyID]
+	return ok && key.RevokedAt == nil
+}
+
+// Create subscribes a hook, enforcing the per-user limit like the Postgres
+// store
+func (s *HookStore) Create(ctx context.Context, hook models.RESTHook) (*models.RESTHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, h := range s.hooks {
+		if h.UserID == hook.UserID {
+			count++
+		}
+	}
+	if count >= models.MaxHooksPerUser {
+		return nil, models.ErrHookLimit
+	}
+
+	hook.ID = uuid.New()
+	hook.CreatedAt = timestamp.Now()
+	s.hooks[hook.ID] = &hook
+
+	copied := hook
+	return &copied, nil
+}
+
+// List returns the user's active hooks, oldest first
+func (s *HookStore) List(ctx context.Context, userID uuid.UUID) ([]models.RESTHook, error) {
+	return s.list(func(h *models.RESTHook) bool { return h.UserID == userID }), nil
+}
+
+// ListForEvent returns the user's active hooks subscribed to event
+func (s *HookStore) ListForEvent(ctx context.Context, userID uuid.UUID, event string) ([]models.RESTHook, error) {
+	return s.list(func(h *models.RESTHook) bool { return h.UserID == userID && h.Event == event }), nil
+}
+
+// list returns the active hooks matching keep, oldest first
+func (s *HookStore) list(keep func(*models.RESTHook) bool) []models.RESTHook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hooks := []models.RESTHook{}
+	for _, h := range s.hooks {
+		if keep(h) && s.active(h) {
+			hooks = append(hooks, *h)
+		}
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt.Time) })
+	return hooks
+}
+
+// GetByID retrieves an active hook
+func (s *HookStore) GetByID(ctx context.Context, id uuid.UUID) (*models.RESTHook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.hooks[id]
+	if !ok || !s.active(h) {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *h
+	return &copied, nil
+}
+
+// Delete unsubscribes a hook owned by the given user
+func (s *HookStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h, ok := s.hooks[id]
+	if !ok || h.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	delete(s.hooks, id)
+	return nil
+}
+
+// DeleteByID unsubscribes a hook regardless of owner
+func (s *HookStore) DeleteByID(ctx context.Context, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.hooks[id]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(s.hooks, id)
+	return nil
+}
+
+// RecentAnalyses returns the analyses of the user's completed submissions,
+// newest first
+func (s *HookStore) RecentAnalyses(ctx context.Context, userID uuid.UUID, limit int) ([]models.HookAnalysis, error) {
+	s.submissions.mu.Lock()
+	defer s.submissions.mu.Unlock()
+
+	analyses := []models.HookAnalysis{}
+	for id, a := range s.submissions.analyses {
+		sub := s.submissions.submissions[id]
+		if sub == nil || sub.UserID != userID || sub.Status != models.StatusCompleted || sub.QuarantinedAt != nil {
+			continue
+		}
+		analyses = append(analyses, hookAnalysis(sub, a))
+	}
+	sort.Slice(analyses, func(i, j int) bool { return analyses[i].CompletedAt.After(analyses[j].CompletedAt.Time) })
+	if len(analyses) > limit {
+		analyses = analyses[:limit]
+	}
+	return analyses, nil
+}
+
+// LatestAnalysis returns the analysis of a submission owned by the given
+// user
+func (s *HookStore) LatestAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.HookAnalysis, error) {
+	s.submissions.mu.Lock()
+	defer s.submissions.mu.Unlock()
+
+	sub, ok := s.submissions.submissions[submissionID]
+	a, analyzed := s.submissions.analyses[submissionID]
+	if !ok || !analyzed || sub.UserID != userID || sub.QuarantinedAt != nil {
+		return nil, pgx.ErrNoRows
+	}
+	analysis := hookAnalysis(sub, a)
+	return &analysis, nil
+}
+
+// hookAnalysis flattens an analysis the way the Postgres store selects it
+func hookAnalysis(sub *models.Submission, a *models.Analysis) models.HookAnalysis {
+	excerpt := []rune(sub.Content)
+	if len(excerpt) > 280 {
+		excerpt = excerpt[:280]
+	}
+	topics := append([]string{}, a.Topics...)
+	labels := []string{}
+	for _, l := range a.Labels {
+		labels = append(labels, l.Label)
+	}
+	var action models.PolicyAction
+	if a.PolicyDecision != nil {
+		action = a.PolicyDecision.Action
+	}
+	return models.HookAnalysis{
+		ID:             a.ID,
+		SubmissionID:   a.SubmissionID,
+		Sentiment:      a.Sentiment,
+		SentimentScore: a.SentimentScore,
+		Summary:        a.Summary,
+		Topics:         topics,
+		Confidence:     a.Confidence,
+		Excerpt:        string(excerpt),
+		CompletedAt:    a.CreatedAt,
+		Language:       a.Language,
+		Labels:         labels,
+		PolicyAction:   action,
+	}
+}
+
+// UploadStore is an in-memory direct upload store
+type UploadStore struct {
+	mu      sync.Mutex
+	uploads map[uuid.UUID]*models.Upload
+	// tokens maps each token to its upload; the Postgres store keeps hashes
+	tokens map[string]uuid.UUID
+}
+
+// NewUploadStore creates an empty in-memory upload store
+func NewUploadStore() *UploadStore {
+	return &UploadStore{
+		uploads: make(map[uuid.UUID]*models.Upload),
+		tokens:  make(map[string]uuid.UUID),
+	}
+}
+
+// Create records a pending upload redeemable with token until expiresAt
+func (s *UploadStore) Create(ctx context.Context, u *models.Upload, token string, expiresAt time.Time) (*models.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *u
+	stored.ID = uuid.New()
+	stored.Key = "uploads/" + stored.ID.String()
+	stored.Status = models.UploadPending
+	stored.SubmissionID = nil
+	stored.AttachedAt = nil
+	stored.ExpiresAt = timestamp.New(expiresAt)
+	stored.CreatedAt = timestamp.Now()
+	s.uploads[stored.ID] = &stored
+	s.tokens[token] = stored.ID
+
+	copied := stored
+	return &copied, nil
+}
+
+// pending reports whether an upload can still be completed
+func pending(u *models.Upload) bool {
+	return u.Status == models.UploadPending && time.Now().Before(u.ExpiresAt.Time)
+}
+
+// GetPending returns the user's pending upload for token
+func (s *UploadStore) GetPending(ctx context.Context, userID uuid.UUID, token string) (*models.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.uploads[s.tokens[token]]
+	if !ok || u.UserID != userID || !pending(u) {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *u
+	return &copied, nil
+}
+
+// Attach completes a pending upload by attaching it to a submission
+func (s *UploadStore) Attach(ctx context.Context, userID, id, submissionID uuid.UUID) (*models.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.uploads[id]
+	if !ok || u.UserID != userID || !pending(u) {
+		return nil, pgx.ErrNoRows
+	}
+	now := timestamp.Now()
+	u.Status = models.UploadAttached
+	u.SubmissionID = &submissionID
+	u.AttachedAt = &now
+
+	copied := *u
+	return &copied, nil
+}
+
+// ListForSubmission returns the uploads attached to a user's submission,
+// oldest first
+func (s *UploadStore) ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]models.Upload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	uploads := []models.Upload{}
+	for _, u := range s.uploads {
+		if u.UserID == userID && u.Status == models.UploadAttached && *u.SubmissionID == submissionID {
+			uploads = append(uploads, *u)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].AttachedAt.Before(uploads[j].AttachedAt.Time) })
+	return uploads, nil
+}
+
+// Purge deletes up to limit expired pending uploads. Submissions aren't
+// tracked here, so attachments are kept.
+func (s *UploadStore) Purge(ctx context.Context, limit int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	purged := 0
+	for token, id := range s.tokens {
+		u := s.uploads[id]
+		if purged == limit || u.Status != models.UploadPending || time.Now().Before(u.ExpiresAt.Time) {
+			continue
+		}
+		delete(s.uploads, id)
+		delete(s.tokens, token)
+		purged++
+	}
+	return purged, nil
+}
+
+// DeviceSessionStore is an in-memory remember-me session store
+type DeviceSessionStore struct {
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*models.DeviceSession
+	// tokens maps each cookie token to its session; the Postgres store
+	// keeps hashes
+	tokens map[string]uuid.UUID
+}
+
+// NewDeviceSessionStore creates an empty in-memory device session store
+func NewDeviceSessionStore() *DeviceSessionStore {
+	return &DeviceSessionStore{
+		sessions: make(map[uuid.UUID]*models.DeviceSession),
+		tokens:   make(map[string]uuid.UUID),
+	}
+}
+
+// Create starts a session and returns it with its cookie token
+func (s *DeviceSessionStore) Create(ctx context.Context, userID uuid.UUID, userAgent, ipAddress string, lifetime models.DeviceLifetime) (string, *models.DeviceSession, error) {
+	token, err := models.NewDeviceToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	session := &models.DeviceSession{
+		ID:           uuid.New(),
+		UserID:       userID,
+		UserAgent:    userAgent,
+		IPAddress:    ipAddress,
+		CreatedAt:    now,
+		LastUsedAt:   now,
+		ExpiresAt:    timestamp.New(now.Add(min(lifetime.Idle, lifetime.Max))),
+		MaxExpiresAt: timestamp.New(now.Add(lifetime.Max)),
+	}
+	s.sessions[session.ID] = session
+	s.tokens[token] = session.ID
+
+	copied := *session
+	return token, &copied, nil
+}
+
+// Resume extends an active session like the Postgres store
+func (s *DeviceSessionStore) Resume(ctx context.Context, token, ipAddress string, idle time.Duration) (*models.DeviceSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.tokens[token]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	session := s.sessions[id]
+	now := timestamp.Now()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now.Time) {
+		return nil, pgx.ErrNoRows
+	}
+	session.LastUsedAt = now
+	session.IPAddress = ipAddress
+	session.ExpiresAt = timestamp.New(now.Add(idle))
+	if session.ExpiresAt.After(session.MaxExpiresAt.Time) {
+		session.ExpiresAt = session.MaxExpiresAt
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+// List returns a user's active sessions, most recently used first
+func (s *DeviceSessionStore) List(ctx context.Context, userID uuid.UUID) ([]models.DeviceSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	sessions := []models.DeviceSession{}
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, *session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt.Time) })
+	return sessions, nil
+}
+
+// Revoke ends one of the user's sessions
+func (s *DeviceSessionStore) Revoke(ctx context.Context, userID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok || session.UserID != userID {
+		return pgx.ErrNoRows
+	}
+	if session.RevokedAt == nil {
+		now := timestamp.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+// RevokeAll ends all of the user's sessions
+func (s *DeviceSessionStore) RevokeAll(ctx context.Context, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	for _, session := range s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			revokedAt := now
+			session.RevokedAt = &revokedAt
+		}
+	}
+	return nil
+}
+
+// RevokeByToken ends the session a cookie belongs to
+func (s *DeviceSessionStore) RevokeByToken(ctx context.Context, token string) (*models.DeviceSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, ok := s.tokens[token]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	session := s.sessions[id]
+	if session.RevokedAt == nil {
+		now := timestamp.Now()
+		session.RevokedAt = &now
+	}
+
+	copied := *session
+	return &copied, nil
+}
+
+// LoginFingerprintStore is an in-memory models.LoginFingerprintStore
+type LoginFingerprintStore struct {
+	mu   sync.Mutex
+	seen map[uuid.UUID]map[models.LoginFingerprint]bool
+}
+
+// NewLoginFingerprintStore creates an empty in-memory login fingerprint store
+func NewLoginFingerprintStore() *LoginFingerprintStore {
+	return &LoginFingerprintStore{seen: make(map[uuid.UUID]map[models.LoginFingerprint]bool)}
+}
+
+// IsFamiliar reports whether the user has signed in from the device and
+// country before, with the same rules as the Postgres store
+func (s *LoginFingerprintStore) IsFamiliar(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := s.seen[userID]
+	if len(seen) == 0 {
+		return true, nil
+	}
+
+	var device, country bool
+	for known := range seen {
+		device = device || known.Device == fp.Device
+		country = country || known.Country == fp.Country
+	}
+	return device && (fp.Country == "" || country), nil
+}
+
+// Record remembers a sign-in from the fingerprint
+func (s *LoginFingerprintStore) Record(ctx context.Context, userID uuid.UUID, fp models.LoginFingerprint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.seen[userID] == nil {
+		s.seen[userID] = make(map[models.LoginFingerprint]bool)
+	}
+	s.seen[userID][fp] = true
+	return nil
+}
+
+// ssoIdentity keys a linked identity provider subject
+type ssoIdentity struct {
+	orgID   uuid.UUID
+	subject string
+}
+
+// SSOStore is an in-memory models.SSOStore
+type SSOStore struct {
+	mu         sync.Mutex
+	orgs       *OrganizationStore
+	configs    map[uuid.UUID]*models.SSOConfig
+	identities map[ssoIdentity]uuid.UUID
+}
+
+// NewSSOStore creates an empty in-memory SSO store; enforcement is checked
+// against the members of orgs
+func NewSSOStore(orgs *OrganizationStore) *SSOStore {
+	return &SSOStore{
+		orgs:       orgs,
+		configs:    make(map[uuid.UUID]*models.SSOConfig),
+		identities: make(map[ssoIdentity]uuid.UUID),
+	}
+}
+
+// Get returns an organization's SSO setup, or pgx.ErrNoRows
+func (s *SSOStore) Get(ctx context.Context, orgID uuid.UUID) (*models.SSOConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.configs[orgID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// Set creates or replaces an organization's SSO setup
+func (s *SSOStore) Set(ctx context.Context, config *models.SSOConfig) (*models.SSOConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	saved := *config
+	saved.CreatedAt, saved.UpdatedAt = now, now
+	if existing, ok := s.configs[config.OrgID]; ok {
+		saved.CreatedAt = existing.CreatedAt
+	}
+	s.configs[config.OrgID] = &saved
+
+	copied := saved
+	return &copied, nil
+}
+
+// Delete removes an organization's SSO setup and its linked identities
+func (s *SSOStore) Delete(ctx context.Context, orgID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.configs[orgID]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(s.configs, orgID)
+	for id := range s.identities {
+		if id.orgID == orgID {
+			delete(s.identities, id)
+		}
+	}
+	return nil
+}
+
+// FindIdentity returns the user a subject is linked to, or pgx.ErrNoRows
+func (s *SSOStore) FindIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userID, ok := s.identities[ssoIdentity{orgID, subject}]
+	if !ok {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	return userID, nil
+}
+
+// LinkIdentity links a subject to a user, keeping an existing link
+func (s *SSOStore) LinkIdentity(ctx context.Context, orgID uuid.UUID, subject string, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := ssoIdentity{orgID, subject}
+	if _, ok := s.identities[key]; !ok {
+		s.identities[key] = userID
+	}
+	return nil
+}
+
+// Enforced returns an organization of the user's that requires single
+// sign-on, and false if none does. Owners are exempt.
+func (s *SSOStore) Enforced(ctx context.Context, userID uuid.UUID) (uuid.UUID, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for orgID, c := range s.configs {
+		if !c.Enforced {
+			continue
+		}
+		if role, err := s.orgs.Role(ctx, orgID, userID); err == nil && role != models.RoleOwner {
+			return orgID, true, nil
+		}
+	}
+	return uuid.Nil, false, nil
+}
+
+// scimUser keys a SCIM user
+type scimUser struct {
+	orgID  uuid.UUID
+	userID uuid.UUID
+}
+
+// SCIMStore is an in-memory models.SCIMStore
+type SCIMStore struct {
+	mu          sync.Mutex
+	users       *UserStore
+	tokens      map[string]uuid.UUID
+	scim        map[scimUser]*models.SCIMUser
+	invitations map[scimUser]*models.SCIMUser
+	groups      map[uuid.UUID]*models.SCIMGroup
+}
+
+// NewSCIMStore creates an empty in-memory SCIM store; emails and display
+// names are read from users, whose new accounts get the IDs invitations
+// reserved
+func NewSCIMStore(users *UserStore) *SCIMStore {
+	s := &SCIMStore{
+		users:       users,
+		tokens:      make(map[string]uuid.UUID),
+		scim:        make(map[scimUser]*models.SCIMUser),
+		invitations: make(map[scimUser]*models.SCIMUser),
+		groups:      make(map[uuid.UUID]*models.SCIMGroup),
+	}
+	users.reserved = s.reservedID
+	return s
+}
+
+// reservedID returns the ID an invitation reserved for email, if no
+// account has it yet
+func (s *SCIMStore) reservedID(email string) (uuid.UUID, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, inv := range s.invitations {
+		if inv.Email != strings.ToLower(email) {
+			continue
+		}
+		if _, err := s.users.GetByID(context.Background(), key.userID); err != nil {
+			return key.userID, true
+		}
+	}
+	return uuid.Nil, false
+}
+
+// CreateToken issues the organization a SCIM token, replacing any it had
+func (s *SCIMStore) CreateToken(ctx context.Context, orgID uuid.UUID) (string, error) {
+	token, err := models.NewSCIMToken()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for t, id := range s.tokens {
+		if id == orgID {
+			delete(s.tokens, t)
+		}
+	}
+	s.tokens[token] = orgID
+	return token, nil
+}
+
+// RevokeToken deletes the organization's SCIM token, or returns
+// pgx.ErrNoRows
+func (s *SCIMStore) RevokeToken(ctx context.Context, orgID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for t, id := range s.tokens {
+		if id == orgID {
+			delete(s.tokens, t)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+// Authenticate returns the organization a token belongs to, or
+// pgx.ErrNoRows
+func (s *SCIMStore) Authenticate(ctx context.Context, token string) (uuid.UUID, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orgID, ok := s.tokens[token]
+	if !ok {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+	return orgID, nil
+}
+
+// ListUsers returns a page of the organization's SCIM users in the order
+// they were provisioned
+func (s *SCIMStore) ListUsers(ctx context.Context, orgID uuid.UUID, filter models.SCIMFilter, offset, limit int) ([]models.SCIMUser, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	users := []models.SCIMUser{}
+	for key := range s.scim {
+		if key.orgID != orgID {
+			continue
+		}
+		user := s.user(ctx, key)
+		if filter.Name != "" && !strings.EqualFold(user.Email, filter.Name) {
+			continue
+		}
+		if filter.ExternalID != "" && user.ExternalID != filter.ExternalID {
+			continue
+		}
+		users = append(users, *user)
+	}
+	for key, inv := range s.invitations {
+		if key.orgID != orgID {
+			continue
+		}
+		if filter.Name != "" && !strings.EqualFold(inv.Email, filter.Name) {
+			continue
+		}
+		if filter.ExternalID != "" && inv.ExternalID != filter.ExternalID {
+			continue
+		}
+		users = append(users, *inv)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt.Time) })
+
+	total := len(users)
+	users = users[min(offset, total):min(offset+limit, total)]
+	return users, total, nil
+}
+
+// GetUser returns one of the organization's SCIM users, or pgx.ErrNoRows
+func (s *SCIMStore) GetUser(ctx context.Context, orgID, userID uuid.UUID) (*models.SCIMUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scimUser{orgID, userID}
+	if _, ok := s.scim[key]; !ok {
+		if inv, ok := s.invitations[key]; ok {
+			user := *inv
+			return &user, nil
+		}
+		return nil, pgx.ErrNoRows
+	}
+	return s.user(ctx, key), nil
+}
+
+// user returns a copy of a SCIM user with their account's details and
+// groups filled in
+func (s *SCIMStore) user(ctx context.Context, key scimUser) *models.SCIMUser {
+	user := *s.scim[key]
+	if account, err := s.users.GetByID(ctx, key.userID); err == nil {
+		user.Email = account.Email
+		user.DisplayName = account.DisplayName
+	}
+
+	user.Groups = []models.SCIMGroupRef{}
+	for _, g := range s.groups {
+		if g.OrgID != key.orgID {
+			continue
+		}
+		for _, m := range g.Members {
+			if m.UserID == key.userID {
+				user.Groups = append(user.Groups, models.SCIMGroupRef{ID: g.ID, DisplayName: g.DisplayName})
+			}
+		}
+	}
+	sort.Slice(user.Groups, func(i, j int) bool { return user.Groups[i].DisplayName < user.Groups[j].DisplayName })
+	return &user
+}
+
+// SetUser marks the user as managed by the organization's identity
+// provider, or updates them
+func (s *SCIMStore) SetUser(ctx context.Context, orgID, userID uuid.UUID, externalID string, active bool) (*models.SCIMUser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := timestamp.Now()
+	key := scimUser{orgID, userID}
+	if inv, ok := s.invitations[key]; ok {
+		inv.ExternalID = externalID
+		inv.Active = active
+		inv.UpdatedAt = now
+		user := *inv
+		return &user, nil
+	}
+	user, ok := s.scim[key]
+	if !ok {
+		user = &models.SCIMUser{OrgID: orgID, UserID: userID, CreatedAt: now}
+		s.scim[key] = user
+	}
+	user.ExternalID = externalID
+	user.Active = active
+	user.UpdatedAt = now
+	return s.user(ctx, key), nil
+}
+
+// DeleteUser stops the organization managing the user and takes them out
+// of its groups, or returns pgx.ErrNoRows
+func (s *SCIMStore) DeleteUser(ctx context.Context, orgID, userID uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := scimUser{orgID, userID}
+	if _, ok := s.invitations[key]; ok {
+		delete(s.invitations, key)
+		return nil
+	}
+	if _, ok := s.scim[key]; !ok {
+		return pgx.ErrNoRows
+	}
+	delete(s.scim, key)
+	for _, g := range s.groups {
+		if g.OrgID == orgID {
+			g.Members = slices.DeleteFunc(g.Members, func(m models.SCIMGroupMember) bool { return m.UserID == userID })
+		}
+	}
+	return nil
+}
+
+// Invite records a pending user for an email address without an account,
+// reusing the ID another invitation reserved for it. Inviting an address
+// twice fails with a unique violation.
+func (s *SCIMStore) Invite(ctx context.Context, orgID uuid.UUID, email string, displayName *string, externalID string, active bool) (*models.SCIMUser, error) {
+	id, ok := s.reservedID(email)
+	if !ok {
+		id = uuid.New()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email = strings.ToLower(email)
+	for key, inv := range s.invitations {
+		if key.orgID == orgID && inv.Email == email {
+			return nil, fmt.Errorf("failed to create SCIM invitation: %w", &pgconn.PgError{Code: "23505"})
+		}
+	}
+
+	now := timestamp.Now()
+	inv := &models.SCIMUser{
+		OrgID:       orgID,
+		UserID:      id,
+		Email:       email,
+		DisplayName: displayName,
+		ExternalID:  externalID,
+		Active:      active,
+		Pending:     true,
+		Groups:      []models.SCIMGroupRef{},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	s.invitations[scimUser{orgID, id}] = inv
+	user := *inv
+	return &user, nil
+}
+
+// RenameInvitation sets a pending user's display name, or returns
+// pgx.ErrNoRows
+func (s *SCIMStore) RenameInvitation(ctx context.Context, orgID, userID uuid.UUID, displayName *string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inv, ok := s.invitations[scimUser{orgID, userID}]
+	if !ok {
+		return pgx.ErrNoRows
+	}
+	inv.DisplayName = displayName
+	inv.UpdatedAt = timestamp.Now()
+	return nil
+}
+
+// AcceptInvitations turns the invitations for the user's email address
+// into SCIM users, naming the account after one if it has no name
+func (s *SCIMStore) AcceptInvitations(ctx context.Context, userID uuid.UUID) ([]models.SCIMUser, error) {
+	account, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	var accepted []*models.SCIMUser
+	for key, inv := range s.invitations {
+		if inv.Email == strings.ToLower(account.Email) {
+			accepted = append(accepted, inv)
+			delete(s.invitations, key)
+		}
+	}
+	s.mu.Unlock()
+
+	users := []models.SCIMUser{}
+	for _, inv := range accepted {
+		if inv.DisplayName != nil && account.DisplayName == nil {
+			if account, err = s.users.UpdateProfile(ctx, userID, account.Version, inv.DisplayName, account.TimeZone); err != nil {
+				return nil, err
+			}
+		}
+		user, err := s.SetUser(ctx, inv.OrgID, userID, inv.ExternalID, inv.Active)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+// ListGroups returns a page of the organization's SCIM groups by name
+func (s *SCIMStore) ListGroups(ctx context.Context, orgID uuid.UUID, filter models.SCIMFilter, offset, limit int) ([]models.SCIMGroup, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := []models.SCIMGroup{}
+	for _, g := range s.groups {
+		if g.OrgID != orgID {
+			continue
+		}
+		if filter.Name != "" && g.DisplayName != filter.Name {
+			continue
+		}
+		if filter.ExternalID != "" && g.ExternalID != filter.ExternalID {
+			continue
+		}
+		groups = append(groups, *s.group(g))
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].DisplayName < groups[j].DisplayName })
+
+	total := len(groups)
+	groups = groups[min(offset, total):min(offset+limit, total)]
+	return groups, total, nil
+}
+
+// GetGroup returns one of the organization's SCIM groups, or
+// pgx.ErrNoRows
+func (s *SCIMStore) GetGroup(ctx context.Context, orgID, id uuid.UUID) (*models.SCIMGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.groups[id]
+	if !ok || g.OrgID != orgID {
+		return nil, pgx.ErrNoRows
+	}
+	return s.group(g), nil
+}
+
+// group returns a copy of a group
+func (s *SCIMStore) group(g *models.SCIMGroup) *models.SCIMGroup {
+	copied := *g
+	copied.Members = slices.Clone(g.Members)
+	return &copied
+}
+
+// SaveGroup creates or replaces a group, leaving out members that aren't
+// the organization's SCIM users
+func (s *SCIMStore) SaveGroup(ctx context.Context, group *models.SCIMGroup) (*models.SCIMGroup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.groups {
+		if g.OrgID == group.OrgID && g.ID != group.ID && g.DisplayName == group.DisplayName {
+			return nil, fmt.Errorf("failed to save SCIM group: %w", &pgconn.PgError{
+				Code:           "23505",
+				Message:        "duplicate key value violates unique constraint",
+				ConstraintName: "scim_groups_org_id_display_name_key",
+			})
+		}
+	}
+
+	now := timestamp.Now()
+	saved := *group
+	saved.UpdatedAt = now
+	if saved.ID == uuid.Nil {
+		saved.ID = uuid.New()
+		saved.CreatedAt = now
+	} else {
+		existing, ok := s.groups[saved.ID]
+		if !ok || existing.OrgID != group.OrgID {
+			return nil, pgx.ErrNoRows
+		}
+		saved.CreatedAt = existing.CreatedAt
+	}
+
+	saved.Members = []models.SCIMGroupMember{}
+	for _, m := range group.Members {
+		if _, ok := s.scim[scimUser{group.OrgID, m.UserID}]; !ok {
+			continue
+		}
+		if account, err := s.users.GetByID(ctx, m.UserID); err == nil {
+			m.Email = account.Email
+		}
+		saved.Members = append(saved.Members, m)
+	}
+	s.groups[saved.ID] = &saved
+	return s.group(&saved), nil
+}
+
+// DeleteGroup deletes one of the organization's SCIM groups, or returns
+// pgx.ErrNoRows
+func (s *SCIMStore) DeleteGroup(ctx context.Context, orgID, id uuid.UUID) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.groups[id]
+	if !ok || g.OrgID != orgID {
+		return pgx.ErrNoRows
+	}
+	delete(s.groups, id)
+	return nil
+}
+
+// AppealStore is an in-memory models.AppealStore
+type AppealStore struct {
+	mu      sync.Mutex
+	users   *UserStore
+	appeals []*models.Appeal
+}
+
+// NewAppealStore creates an empty in-memory appeal store; granting an
+// appeal reinstates its user in users
+func NewAppealStore(users *UserStore) *AppealStore {
+	return &AppealStore{users: users}
+}
+
+// Create records the user's appeal against their current suspension
+func (s *AppealStore) Create(ctx context.Context, userID uuid.UUID, message string) (*models.Appeal, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.appeals {
+		if a.UserID == userID && a.Decision == models.AppealPending {
+			return nil, fmt.Errorf("failed to create appeal: %w", &pgconn.PgError{
+				Code:           "23505",
+				Message:        "duplicate key value violates unique constraint",
+				ConstraintName: "idx_account_appeals_pending",
+			})
+		}
+	}
+
+	appeal := &models.Appeal{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Message:   message,
+		Decision:  models.AppealPending,
+		CreatedAt: timestamp.Now(),
+	}
+	if user.StatusReason != nil {
+		appeal.StatusReason = *user.StatusReason
+	}
+	s.appeals = append(s.appeals, appeal)
+
+	copied := *appeal
+	return &copied, nil
+}
+
+// ListForUser returns the user's appeals, newest first
+func (s *AppealStore) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Appeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	appeals := []models.Appeal{}
+	for i := len(s.appeals) - 1; i >= 0; i-- {
+		if s.appeals[i].UserID == userID {
+			appeals = append(appeals, *s.appeals[i])
+		}
+	}
+	return appeals, nil
+}
+
+// List returns a page of every user's appeals with the given decision,
+// oldest first, and the total count
+func (s *AppealStore) List(ctx context.Context, decision models.AppealDecision, limit, offset int) ([]models.Appeal, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matching := []models.Appeal{}
+	for _, a := range s.appeals {
+		if a.Decision == decision {
+			matching = append(matching, *a)
+		}
+	}
+
+	total := len(matching)
+	if offset >= total {
+		return []models.Appeal{}, total, nil
+	}
+	return matching[offset:min(offset+limit, total)], total, nil
+}
+
+// Decide grants or denies a pending appeal, reinstating a suspended user
+// whose appeal is granted
+func (s *AppealStore) Decide(ctx context.Context, id uuid.UUID, decision models.AppealDecision, response, reviewer string) (*models.Appeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, a := range s.appeals {
+		if a.ID != id {
+			continue
+		}
+		if a.Decision != models.AppealPending {
+			return nil, models.ErrAppealDecided
+		}
+
+		now := timestamp.Now()
+		a.Decision, a.Response, a.ReviewedBy, a.ReviewedAt = decision, response, &reviewer, &now
+		if decision == models.AppealGranted {
+			if user, err := s.users.GetByID(ctx, a.UserID); err == nil && user.Status == models.StatusSuspended {
+				if _, err := s.users.SetStatus(ctx, a.UserID, models.StatusActive, ""); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		copied := *a
+		return &copied, nil
+	}
+	return nil, pgx.ErrNoRows
+}
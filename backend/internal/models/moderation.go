@@ -0,0 +1,465 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// ModerationCategory is a kind of harmful content scored by moderation
+type ModerationCategory string
+
+const (
+	ModerationToxicity   ModerationCategory = "toxicity"
+	ModerationHarassment ModerationCategory = "harassment"
+	ModerationHate       ModerationCategory = "hate"
+	ModerationSexual     ModerationCategory = "sexual"
+	ModerationViolence   ModerationCategory = "violence"
+	ModerationSelfHarm   ModerationCategory = "self_harm"
+)
+
+// ModerationCategories lists every category in the order they're reported
+var ModerationCategories = []ModerationCategory{
+	ModerationToxicity,
+	ModerationHarassment,
+	ModerationHate,
+	ModerationSexual,
+	ModerationViolence,
+	ModerationSelfHarm,
+}
+
+// ValidModerationCategory reports whether c is a known category
+func ValidModerationCategory(c ModerationCategory) bool {
+	return slices.Contains(ModerationCategories, c)
+}
+
+// ModerationScores are how strongly content falls in each category, from
+// 0 to 1
+type ModerationScores map[ModerationCategory]float64
+
+// PolicyAction is what a moderation policy does with content
+type PolicyAction string
+
+const (
+	// PolicyAllow lets the content through
+	PolicyAllow PolicyAction = "allow"
+	// PolicyWarn lets the content through with a warning
+	PolicyWarn PolicyAction = "warn"
+	// PolicyBlock stops the content
+	PolicyBlock PolicyAction = "block"
+)
+
+// ParsePolicyAction validates an action name
+func ParsePolicyAction(s string) (PolicyAction, error) {
+	switch a := PolicyAction(s); a {
+	case PolicyAllow, PolicyWarn, PolicyBlock:
+		return a, nil
+	default:
+		return "", fmt.Errorf("action must be one of: allow, warn, block")
+	}
+}
+
+// severity orders actions from allow to block
+func (a PolicyAction) severity() int {
+	switch a {
+	case PolicyWarn:
+		return 1
+	case PolicyBlock:
+		return 2
+	default:
+		return 0
+	}
+}
+
+// ModerationThreshold sets the scores above which content in a category is
+// warned about or blocked. Either may be nil to skip that action.
+type ModerationThreshold struct {
+	Category   ModerationCategory `json:"category"`
+	WarnAbove  *float64           `json:"warn_above"`
+	BlockAbove *float64           `json:"block_above"`
+}
+
+// ModerationPolicy is an organization's moderation thresholds
+type ModerationPolicy struct {
+	OrgID      uuid.UUID             `json:"org_id"`
+	Thresholds []ModerationThreshold `json:"thresholds"`
+	// UpdatedAt is when the thresholds last changed, or nil if none are set
+	UpdatedAt *timestamp.Time `json:"updated_at"`
+}
+
+// ValidateThresholds checks a policy's thresholds, keyed by field for a
+// validation error response
+func ValidateThresholds(thresholds []ModerationThreshold) map[string]string {
+	errs := make(map[string]string)
+	seen := make(map[ModerationCategory]bool)
+	for i, t := range thresholds {
+		field := fmt.Sprintf("thresholds[%d]", i)
+		switch {
+		case !ValidModerationCategory(t.Category):
+			errs[field+".category"] = "unknown category"
+		case seen[t.Category]:
+			errs[field+".category"] = "category is listed more than once"
+		}
+		seen[t.Category] = true
+
+		if t.WarnAbove == nil && t.BlockAbove == nil {
+			errs[field] = "set warn_above, block_above or both"
+		}
+		if t.WarnAbove != nil && (*t.WarnAbove < 0 || *t.WarnAbove > 1) {
+			errs[field+".warn_above"] = "must be between 0 and 1"
+		}
+		if t.BlockAbove != nil && (*t.BlockAbove < 0 || *t.BlockAbove > 1) {
+			errs[field+".block_above"] = "must be between 0 and 1"
+		}
+		if t.WarnAbove != nil && t.BlockAbove != nil && *t.WarnAbove > *t.BlockAbove {
+			errs[field+".warn_above"] = "must not be above block_above"
+		}
+	}
+	return errs
+}
+
+// PolicyViolation is a category whose score crossed a threshold
+type PolicyViolation struct {
+	Category  ModerationCategory `json:"category"`
+	Score     float64            `json:"score"`
+	Threshold float64            `json:"threshold"`
+	Action    PolicyAction       `json:"action"`
+}
+
+// PolicyOverride is an operator's decision for one submission, replacing
+// whatever the policy decided
+type PolicyOverride struct {
+	Action PolicyAction   `json:"action"`
+	Reason string         `json:"reason,omitempty"`
+	By     string         `json:"by,omitempty"`
+	At     timestamp.Time `json:"at"`
+}
+
+// PolicyDecision is what the moderation policy made of an analysis
+type PolicyDecision struct {
+	// Action is the decision in force: the override's if there is one,
+	// otherwise the policy's
+	Action PolicyAction `json:"action"`
+	// PolicyAction is the strictest action of the violations, or allow
+	PolicyAction PolicyAction      `json:"policy_action"`
+	Violations   []PolicyViolation `json:"violations"`
+	Override     *PolicyOverride   `json:"override,omitempty"`
+}
+
+// EvaluatePolicy decides what policy does with content given its scores.
+// A score strictly above a threshold violates it; a category over both
+// thresholds is reported once, as blocked. A nil policy allows everything.
+func EvaluatePolicy(policy *ModerationPolicy, scores ModerationScores) *PolicyDecision {
+	decision := &PolicyDecision{Action: PolicyAllow, PolicyAction: PolicyAllow, Violations: []PolicyViolation{}}
+	if policy == nil {
+		return decision
+	}
+
+	for _, t := range policy.Thresholds {
+		score, ok := scores[t.Category]
+		if !ok {
+			continue
+		}
+
+		violation := PolicyViolation{Category: t.Category, Score: score}
+		switch {
+		case t.BlockAbove != nil && score > *t.BlockAbove:
+			violation.Threshold, violation.Action = *t.BlockAbove, PolicyBlock
+		case t.WarnAbove != nil && score > *t.WarnAbove:
+			violation.Threshold, violation.Action = *t.WarnAbove, PolicyWarn
+		default:
+			continue
+		}
+
+		decision.Violations = append(decision.Violations, violation)
+		if violation.Action.severity() > decision.PolicyAction.severity() {
+			decision.PolicyAction = violation.Action
+		}
+	}
+
+	decision.Action = decision.PolicyAction
+	return decision
+}
+
+// ApplyOverride puts override in force, or restores the policy's action
+// when it is nil
+func (d *PolicyDecision) ApplyOverride(override *PolicyOverride) {
+	d.Override = override
+	if override != nil {
+		d.Action = override.Action
+	} else {
+		d.Action = d.PolicyAction
+	}
+}
+
+// sealModeration encodes an analysis' scores for the moderation column,
+// encrypted when the store has a key
+func (s *SubmissionStore) sealModeration(ctx context.Context, analysis record, scores ModerationScores) (*string, error) {
+	if scores == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(scores)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode moderation scores: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisModeration))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt moderation scores: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openModeration decodes a value read from the moderation column
+func (s *SubmissionStore) openModeration(ctx context.Context, analysis record, value *string) (ModerationScores, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisModeration))
+	if err != nil {
+		return nil, err
+	}
+
+	var scores ModerationScores
+	if err := json.Unmarshal([]byte(opened), &scores); err != nil {
+		return nil, err
+	}
+	return scores, nil
+}
+
+// ModerationStore persists organizations' moderation policies and
+// operators' overrides of submissions' policy decisions
+type ModerationStore struct {
+	db *pgxpool.Pool
+}
+
+// NewModerationStore creates a new moderation store
+func NewModerationStore(db *pgxpool.Pool) *ModerationStore {
+	return &ModerationStore{db: db}
+}
+
+// Policy returns an organization's moderation policy, which has no
+// thresholds if none were set
+func (s *ModerationStore) Policy(ctx context.Context, orgID uuid.UUID) (*ModerationPolicy, error) {
+	policy, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*ModerationPolicy, error) {
+		return s.policy(ctx, s.db, orgID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// rowsQuerier is satisfied by both the pool and a transaction
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// policy reads an organization's thresholds, by category
+func (s *ModerationStore) policy(ctx context.Context, db rowsQuerier, orgID uuid.UUID) (*ModerationPolicy, error) {
+	rows, err := db.Query(ctx, `
+		SELECT category, warn_above, block_above, updated_at
+		FROM moderation_thresholds
+		WHERE org_id = $1
+		ORDER BY category
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policy := &ModerationPolicy{OrgID: orgID, Thresholds: []ModerationThreshold{}}
+	for rows.Next() {
+		var t ModerationThreshold
+		var updatedAt timestamp.Time
+		if err := rows.Scan(&t.Category, &t.WarnAbove, &t.BlockAbove, &updatedAt); err != nil {
+			return nil, err
+		}
+		policy.Thresholds = append(policy.Thresholds, t)
+		if policy.UpdatedAt == nil || updatedAt.After(policy.UpdatedAt.Time) {
+			policy.UpdatedAt = &updatedAt
+		}
+	}
+	return policy, rows.Err()
+}
+
+// SetPolicy replaces an organization's thresholds and returns its policy.
+// An empty list removes the policy.
+func (s *ModerationStore) SetPolicy(ctx context.Context, orgID uuid.UUID, thresholds []ModerationThreshold) (*ModerationPolicy, error) {
+	if errs := ValidateThresholds(thresholds); len(errs) > 0 {
+		return nil, errors.New("invalid moderation thresholds")
+	}
+
+	policy, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*ModerationPolicy, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM moderation_thresholds WHERE org_id = $1`, orgID); err != nil {
+			return nil, err
+		}
+		for _, t := range thresholds {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO moderation_thresholds (org_id, category, warn_above, block_above)
+				VALUES ($1, $2, $3, $4)
+			`, orgID, t.Category, t.WarnAbove, t.BlockAbove); err != nil {
+				return nil, err
+			}
+		}
+
+		policy, err := s.policy(ctx, tx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return policy, tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set moderation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// PolicyForUser returns the policy in force for userID's submissions, or
+// nil if none of their organizations has one. A member of several
+// organizations gets the strictest threshold of each category among them.
+func (s *ModerationStore) PolicyForUser(ctx context.Context, userID uuid.UUID) (*ModerationPolicy, error) {
+	policy, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*ModerationPolicy, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT t.category, MIN(t.warn_above), MIN(t.block_above)
+			FROM moderation_thresholds t
+			JOIN organization_members m ON m.org_id = t.org_id
+			WHERE m.user_id = $1
+			GROUP BY t.category
+			ORDER BY t.category
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var thresholds []ModerationThreshold
+		for rows.Next() {
+			var t ModerationThreshold
+			if err := rows.Scan(&t.Category, &t.WarnAbove, &t.BlockAbove); err != nil {
+				return nil, err
+			}
+			thresholds = append(thresholds, t)
+		}
+		if err := rows.Err(); err != nil || len(thresholds) == 0 {
+			return nil, err
+		}
+		return &ModerationPolicy{Thresholds: thresholds}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation policy: %w", err)
+	}
+	return policy, nil
+}
+
+// Override returns the operator's override of a submission's policy
+// decision, or nil if there is none
+func (s *ModerationStore) Override(ctx context.Context, submissionID uuid.UUID) (*PolicyOverride, error) {
+	override, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*PolicyOverride, error) {
+		var encoded []byte
+		err := s.db.QueryRow(ctx, `SELECT policy_override FROM submissions WHERE id = $1`, submissionID).Scan(&encoded)
+		if err != nil || encoded == nil {
+			return nil, err
+		}
+
+		var override PolicyOverride
+		if err := json.Unmarshal(encoded, &override); err != nil {
+			return nil, err
+		}
+		return &override, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get policy override: %w", err)
+	}
+	return override, nil
+}
+
+// SetOverride replaces a submission's override, or clears it when nil, and
+// applies it to the latest analysis' decision. It returns the updated
+// decision, nil if the submission hasn't been decided on yet, or
+// pgx.ErrNoRows if it doesn't exist. Later analyses keep the override.
+func (s *ModerationStore) SetOverride(ctx context.Context, submissionID uuid.UUID, override *PolicyOverride) (*PolicyDecision, error) {
+	var encoded []byte
+	if override != nil {
+		var err error
+		if encoded, err = json.Marshal(override); err != nil {
+			return nil, fmt.Errorf("failed to encode policy override: %w", err)
+		}
+	}
+
+	decision, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*PolicyDecision, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		// The version moves on since the submission's analysis reads differently
+		tag, err := tx.Exec(ctx, `UPDATE submissions SET policy_override = $2, version = version + 1 WHERE id = $1`, submissionID, encoded)
+		if err != nil {
+			return nil, err
+		}
+		if tag.RowsAffected() == 0 {
+			return nil, pgx.ErrNoRows
+		}
+
+		var analysisID uuid.UUID
+		var stored []byte
+		err = tx.QueryRow(ctx, `
+			SELECT id, policy_decision
+			FROM analyses
+			WHERE submission_id = $1
+			ORDER BY created_at DESC
+			LIMIT 1
+			FOR UPDATE
+		`, submissionID).Scan(&analysisID, &stored)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		// Not analyzed yet, or analyzed with no policy or override in force
+		var decision *PolicyDecision
+		switch {
+		case stored != nil:
+			decision = &PolicyDecision{}
+			if err := json.Unmarshal(stored, decision); err != nil {
+				return nil, err
+			}
+		case analysisID != uuid.Nil && override != nil:
+			decision = EvaluatePolicy(nil, nil)
+		}
+
+		if decision != nil {
+			decision.ApplyOverride(override)
+			updated, err := json.Marshal(decision)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := tx.Exec(ctx, `UPDATE analyses SET policy_decision = $2 WHERE id = $1`, analysisID, updated); err != nil {
+				return nil, err
+			}
+		}
+
+		return decision, tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set policy override: %w", err)
+	}
+	return decision, err
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+func ptrFloat(v float64) *float64 { return &v }
+
+func TestValidateThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds []ModerationThreshold
+		wantField  string
+	}{
+		{name: "empty", thresholds: nil},
+		{name: "valid", thresholds: []ModerationThreshold{
+			{Category: ModerationToxicity, WarnAbove: ptrFloat(0.5), BlockAbove: ptrFloat(0.8)},
+			{Category: ModerationHate, BlockAbove: ptrFloat(0.6)},
+		}},
+		{name: "unknown category", thresholds: []ModerationThreshold{{Category: "spam", WarnAbove: ptrFloat(0.5)}}, wantField: "thresholds[0].category"},
+		{name: "duplicate", thresholds: []ModerationThreshold{
+			{Category: ModerationHate, WarnAbove: ptrFloat(0.5)},
+			{Category: ModerationHate, BlockAbove: ptrFloat(0.9)},
+		}, wantField: "thresholds[1].category"},
+		{name: "no threshold", thresholds: []ModerationThreshold{{Category: ModerationSexual}}, wantField: "thresholds[0]"},
+		{name: "out of range", thresholds: []ModerationThreshold{{Category: ModerationViolence, BlockAbove: ptrFloat(1.5)}}, wantField: "thresholds[0].block_above"},
+		{name: "warn above block", thresholds: []ModerationThreshold{
+			{Category: ModerationToxicity, WarnAbove: ptrFloat(0.9), BlockAbove: ptrFloat(0.8)},
+		}, wantField: "thresholds[0].warn_above"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateThresholds(tt.thresholds)
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Errorf("ValidateThresholds() = %v, want none", errs)
+				}
+				return
+			}
+			if _, ok := errs[tt.wantField]; !ok {
+				t.Errorf("ValidateThresholds() = %v, want an error on %s", errs, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	policy := &ModerationPolicy{Thresholds: []ModerationThreshold{
+		{Category: ModerationToxicity, WarnAbove: ptrFloat(0.5), BlockAbove: ptrFloat(0.8)},
+		{Category: ModerationHarassment, WarnAbove: ptrFloat(0.4)},
+		{Category: ModerationSelfHarm, BlockAbove: ptrFloat(0.3)},
+	}}
+
+	tests := []struct {
+		name       string
+		scores     ModerationScores
+		want       PolicyAction
+		violations int
+	}{
+		{name: "clean", scores: ModerationScores{ModerationToxicity: 0.1, ModerationHarassment: 0.1}, want: PolicyAllow},
+		{name: "at threshold", scores: ModerationScores{ModerationToxicity: 0.5}, want: PolicyAllow},
+		{name: "warn", scores: ModerationScores{ModerationToxicity: 0.6, ModerationHarassment: 0.5}, want: PolicyWarn, violations: 2},
+		{name: "block wins", scores: ModerationScores{ModerationToxicity: 0.9, ModerationHarassment: 0.5}, want: PolicyBlock, violations: 2},
+		{name: "block only category", scores: ModerationScores{ModerationSelfHarm: 0.35}, want: PolicyBlock, violations: 1},
+		{name: "unscored", scores: nil, want: PolicyAllow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EvaluatePolicy(policy, tt.scores)
+			if got.Action != tt.want || got.PolicyAction != tt.want || len(got.Violations) != tt.violations {
+				t.Errorf("EvaluatePolicy() = %+v, want %s with %d violations", got, tt.want, tt.violations)
+			}
+		})
+	}
+
+	// A category over both thresholds is reported once, at the block threshold
+	got := EvaluatePolicy(policy, ModerationScores{ModerationToxicity: 0.9})
+	if len(got.Violations) != 1 || got.Violations[0].Threshold != 0.8 || got.Violations[0].Action != PolicyBlock {
+		t.Errorf("Violations = %+v, want one block at 0.8", got.Violations)
+	}
+}
+
+func TestPolicyDecision_ApplyOverride(t *testing.T) {
+	decision := EvaluatePolicy(&ModerationPolicy{Thresholds: []ModerationThreshold{
+		{Category: ModerationHate, BlockAbove: ptrFloat(0.5)},
+	}}, ModerationScores{ModerationHate: 0.7})
+
+	decision.ApplyOverride(&PolicyOverride{Action: PolicyAllow, Reason: "Quoted in a news report", At: timestamp.Now()})
+	if decision.Action != PolicyAllow || decision.PolicyAction != PolicyBlock || decision.Override == nil {
+		t.Errorf("ApplyOverride() = %+v, want allow over a block", decision)
+	}
+
+	decision.ApplyOverride(nil)
+	if decision.Action != PolicyBlock || decision.Override != nil {
+		t.Errorf("ApplyOverride(nil) = %+v, want the policy's block restored", decision)
+	}
+}
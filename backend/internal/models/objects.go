@@ -0,0 +1,40 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+// errNoObjectStorage is returned when an upload or tiered content kept in
+// object storage is read by a store without one
+var errNoObjectStorage = errors.New("data is in object storage but no storage is configured")
+
+// newObjectKey returns a fresh key under prefix for an upload
+func newObjectKey(prefix string) string {
+	return prefix + "/" + uuid.NewString()
+}
+
+// getObject reads an upload kept under key
+func getObject(ctx context.Context, objects storage.Storage, key string) ([]byte, error) {
+	if objects == nil {
+		return nil, errNoObjectStorage
+	}
+	return objects.Get(ctx, key)
+}
+
+// deleteObject removes an upload that is no longer needed, if it was kept
+// in object storage. Failures are logged rather than returned, since the
+// row no longer points at the object and the worst case is an orphan.
+func deleteObject(ctx context.Context, objects storage.Storage, key *string) {
+	if key == nil || objects == nil {
+		return
+	}
+	if err := objects.Delete(ctx, *key); err != nil {
+		slog.Warn("Failed to delete upload from object storage", "key", *key, "error", err)
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+func TestObjects(t *testing.T) {
+	ctx := context.Background()
+
+	key := newObjectKey("imports")
+	if !strings.HasPrefix(key, "imports/") || !storage.ValidKey(key) || key == newObjectKey("imports") {
+		t.Fatalf("newObjectKey() = %s, want a fresh valid key under imports/", key)
+	}
+
+	if _, err := getObject(ctx, nil, key); !errors.Is(err, errNoObjectStorage) {
+		t.Errorf("getObject() without storage error = %v, want errNoObjectStorage", err)
+	}
+
+	objects := storage.NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	if err := objects.Put(ctx, key, []byte("content\nhello"), "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := getObject(ctx, objects, key); err != nil || string(data) != "content\nhello" {
+		t.Fatalf("getObject() = %q, %v, want the stored file", data, err)
+	}
+
+	// Uploads kept in Postgres have no key, and nothing is deleted
+	deleteObject(ctx, objects, nil)
+	if _, err := objects.Get(ctx, key); err != nil {
+		t.Errorf("deleteObject(nil) removed the object: %v", err)
+	}
+
+	deleteObject(ctx, objects, &key)
+	if _, err := objects.Get(ctx, key); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("Get() after deleteObject() error = %v, want storage.ErrNotFound", err)
+	}
+}
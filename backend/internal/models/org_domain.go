@@ -0,0 +1,236 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// MaxOrgDomains bounds the email domains an organization can claim
+const MaxOrgDomains = 20
+
+// Domains are verified with a TXT record named domainRecordLabel under
+// them, holding domainRecordPrefix followed by the claim's token
+const (
+	domainRecordLabel  = "_content-analyzer"
+	domainRecordPrefix = "content-analyzer-verification="
+)
+
+// OrgDomain is an email domain an organization claims. Once verified, its
+// identity provider may provision accounts for addresses under it.
+type OrgDomain struct {
+	OrgID  uuid.UUID `json:"org_id"`
+	Domain string    `json:"domain"`
+	// RecordName and RecordValue are the TXT record that verifies the
+	// claim
+	RecordName  string          `json:"record_name"`
+	RecordValue string          `json:"record_value"`
+	VerifiedAt  *timestamp.Time `json:"verified_at"`
+	CreatedAt   timestamp.Time  `json:"created_at"`
+}
+
+// Verified reports whether the organization has proven it controls the
+// domain
+func (d *OrgDomain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// NewOrgDomain returns an unverified claim to domain with a fresh token
+func NewOrgDomain(orgID uuid.UUID, domain string) (*OrgDomain, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate domain token: %w", err)
+	}
+	return newOrgDomain(orgID, domain, hex.EncodeToString(raw)), nil
+}
+
+// newOrgDomain fills in the TXT record for a claim's token
+func newOrgDomain(orgID uuid.UUID, domain, token string) *OrgDomain {
+	return &OrgDomain{
+		OrgID:       orgID,
+		Domain:      domain,
+		RecordName:  domainRecordLabel + "." + domain,
+		RecordValue: domainRecordPrefix + token,
+	}
+}
+
+// Token returns the claim's token, from its record value
+func (d *OrgDomain) Token() string {
+	return strings.TrimPrefix(d.RecordValue, domainRecordPrefix)
+}
+
+// ValidateDomain checks an email domain: a lowercase DNS name of at least
+// two labels
+func ValidateDomain(domain string) error {
+	if len(domain) > 253 || !strings.Contains(domain, ".") {
+		return errors.New("domain must be a DNS name such as example.com")
+	}
+	for _, label := range strings.Split(domain, ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return errors.New("domain must be a DNS name such as example.com")
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+				return errors.New("domain may only contain lowercase letters, digits, hyphens and dots")
+			}
+		}
+	}
+	return nil
+}
+
+// EmailDomain returns the lowercased domain of an email address, or ""
+// if it has none
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// OrgDomainStore persists the email domains organizations claim
+type OrgDomainStore struct {
+	db *pgxpool.Pool
+}
+
+// NewOrgDomainStore creates a new organization domain store
+func NewOrgDomainStore(db *pgxpool.Pool) *OrgDomainStore {
+	return &OrgDomainStore{db: db}
+}
+
+// orgDomainColumns is the column list matching scanOrgDomain
+const orgDomainColumns = `org_id, domain, token, verified_at, created_at`
+
+// scanOrgDomain reads a row selected with orgDomainColumns
+func scanOrgDomain(row pgx.Row) (*OrgDomain, error) {
+	var (
+		orgID      uuid.UUID
+		domain     string
+		token      string
+		verifiedAt *timestamp.Time
+		createdAt  timestamp.Time
+	)
+	if err := row.Scan(&orgID, &domain, &token, &verifiedAt, &createdAt); err != nil {
+		return nil, err
+	}
+	d := newOrgDomain(orgID, domain, token)
+	d.VerifiedAt = verifiedAt
+	d.CreatedAt = createdAt
+	return d, nil
+}
+
+// List returns the organization's domains by name
+func (s *OrgDomainStore) List(ctx context.Context, orgID uuid.UUID) ([]OrgDomain, error) {
+	domains, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]OrgDomain, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT `+orgDomainColumns+`
+			FROM organization_domains
+			WHERE org_id = $1
+			ORDER BY domain
+		`, orgID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var domains []OrgDomain
+		for rows.Next() {
+			d, err := scanOrgDomain(rows)
+			if err != nil {
+				return nil, err
+			}
+			domains = append(domains, *d)
+		}
+		return domains, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization domains: %w", err)
+	}
+	return domains, nil
+}
+
+// Get returns one of the organization's domains, or pgx.ErrNoRows
+func (s *OrgDomainStore) Get(ctx context.Context, orgID uuid.UUID, domain string) (*OrgDomain, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*OrgDomain, error) {
+		return scanOrgDomain(s.db.QueryRow(ctx, `
+			SELECT `+orgDomainColumns+` FROM organization_domains WHERE org_id = $1 AND domain = $2
+		`, orgID, domain))
+	})
+}
+
+// Add claims a domain for the organization, unverified. Claiming it twice
+// fails with a unique violation.
+func (s *OrgDomainStore) Add(ctx context.Context, orgID uuid.UUID, domain string) (*OrgDomain, error) {
+	if err := ValidateDomain(domain); err != nil {
+		return nil, err
+	}
+	claim, err := NewOrgDomain(orgID, domain)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*OrgDomain, error) {
+		return scanOrgDomain(s.db.QueryRow(ctx, `
+			INSERT INTO organization_domains (org_id, domain, token) VALUES ($1, $2, $3)
+			RETURNING `+orgDomainColumns, orgID, domain, claim.Token()))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add organization domain: %w", err)
+	}
+	return added, nil
+}
+
+// MarkVerified records that the organization proved it controls the
+// domain. It returns pgx.ErrNoRows if the organization hasn't claimed it,
+// and a unique violation if another organization verified it first.
+func (s *OrgDomainStore) MarkVerified(ctx context.Context, orgID uuid.UUID, domain string) (*OrgDomain, error) {
+	verified, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*OrgDomain, error) {
+		return scanOrgDomain(s.db.QueryRow(ctx, `
+			UPDATE organization_domains SET verified_at = COALESCE(verified_at, NOW())
+			WHERE org_id = $1 AND domain = $2
+			RETURNING `+orgDomainColumns, orgID, domain))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to verify organization domain: %w", err)
+	}
+	return verified, err
+}
+
+// Remove drops the organization's claim to a domain, returning
+// pgx.ErrNoRows if it had none. Accounts already provisioned are kept.
+func (s *OrgDomainStore) Remove(ctx context.Context, orgID uuid.UUID, domain string) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM organization_domains WHERE org_id = $1 AND domain = $2`, orgID, domain)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// IsVerified reports whether the organization has verified the domain
+func (s *OrgDomainStore) IsVerified(ctx context.Context, orgID uuid.UUID, domain string) (bool, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (bool, error) {
+		var verified bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM organization_domains
+				WHERE org_id = $1 AND domain = $2 AND verified_at IS NOT NULL
+			)
+		`, orgID, domain).Scan(&verified)
+		return verified, err
+	})
+}
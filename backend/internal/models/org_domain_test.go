@@ -0,0 +1,66 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		domain  string
+		wantErr bool
+	}{
+		{"example.com", false},
+		{"mail.example.co.uk", false},
+		{"xn--bcher-kva.example", false},
+		{"localhost", true},
+		{"Example.com", true},
+		{"-example.com", true},
+		{"example..com", true},
+		{"example.com.", true},
+		{"*.example.com", true},
+		{"user@example.com", true},
+		{strings.Repeat("a", 64) + ".com", true},
+	}
+	for _, tt := range tests {
+		if err := ValidateDomain(tt.domain); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateDomain(%q) error = %v, wantErr %v", tt.domain, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEmailDomain(t *testing.T) {
+	tests := map[string]string{
+		"ada@Example.com":        "example.com",
+		"a@b@mail.example.com":   "mail.example.com",
+		"no-at-sign.example.com": "",
+	}
+	for email, want := range tests {
+		if got := EmailDomain(email); got != want {
+			t.Errorf("EmailDomain(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
+
+func TestNewOrgDomain(t *testing.T) {
+	a, err := NewOrgDomain(uuid.New(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := NewOrgDomain(uuid.New(), "example.com")
+
+	if a.RecordName != "_content-analyzer.example.com" {
+		t.Errorf("RecordName = %q", a.RecordName)
+	}
+	if !strings.HasPrefix(a.RecordValue, "content-analyzer-verification=") || a.Token() == "" {
+		t.Errorf("RecordValue = %q, want a verification token", a.RecordValue)
+	}
+	if a.Token() == b.Token() {
+		t.Error("two claims got the same token")
+	}
+	if a.Verified() {
+		t.Error("a new claim is verified")
+	}
+}
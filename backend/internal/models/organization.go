@@ -0,0 +1,462 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// MaxOrgNameLength is the longest organization name accepted, in characters
+const MaxOrgNameLength = 100
+
+// MaxRetentionDays is the longest retention period accepted, ten years
+const MaxRetentionDays = 3650
+
+// OrgRole is a member's role in an organization
+type OrgRole string
+
+const (
+	// RoleOwner can do everything, including managing admins
+	RoleOwner OrgRole = "owner"
+	// RoleAdmin manages members and sees usage
+	RoleAdmin OrgRole = "admin"
+	// RoleMember belongs to the organization
+	RoleMember OrgRole = "member"
+)
+
+// reservedSubdomains can't be taken by organizations, since they name the
+// service's own hosts
+var reservedSubdomains = []string{"admin", "api", "app", "auth", "docs", "mail", "status", "www"}
+
+// ErrLastOwner is returned when a change would leave an organization
+// without an owner
+var ErrLastOwner = errors.New("an organization must keep at least one owner")
+
+// ParseOrgRole validates a role name
+func ParseOrgRole(s string) (OrgRole, error) {
+	switch r := OrgRole(s); r {
+	case RoleOwner, RoleAdmin, RoleMember:
+		return r, nil
+	default:
+		return "", fmt.Errorf("role must be one of: owner, admin, member")
+	}
+}
+
+// CanManage reports whether the role may manage members and see usage
+func (r OrgRole) CanManage() bool {
+	return r == RoleOwner || r == RoleAdmin
+}
+
+// Organization groups users
+type Organization struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Subdomain is the subdomain of ORG_BASE_DOMAIN the organization is
+	// served on, if any
+	Subdomain *string `json:"subdomain"`
+	RetentionPolicy
+	SpendBudget
+	CreatedAt timestamp.Time `json:"created_at"`
+	UpdatedAt timestamp.Time `json:"updated_at"`
+}
+
+// RetentionPolicy controls how long members' submissions are kept
+type RetentionPolicy struct {
+	// RetentionDays is how long submissions are kept; nil keeps them
+	// forever
+	RetentionDays *int `json:"retention_days"`
+	// LegalHold suspends purging for every member while it is set
+	LegalHold bool `json:"legal_hold"`
+}
+
+// Validate checks the retention period
+func (p RetentionPolicy) Validate() error {
+	if p.RetentionDays != nil && (*p.RetentionDays < 1 || *p.RetentionDays > MaxRetentionDays) {
+		return fmt.Errorf("retention_days must be between 1 and %d", MaxRetentionDays)
+	}
+	return nil
+}
+
+// SpendBudget caps what an organization's members may spend on the model
+// each UTC day and calendar month, in millionths of a US dollar
+type SpendBudget struct {
+	// DailyBudgetMicros is nil when daily spend is uncapped
+	DailyBudgetMicros *int64 `json:"daily_budget_micros"`
+	// MonthlyBudgetMicros is nil when monthly spend is uncapped
+	MonthlyBudgetMicros *int64 `json:"monthly_budget_micros"`
+}
+
+// Validate checks that the caps are positive
+func (b SpendBudget) Validate() error {
+	if b.DailyBudgetMicros != nil && *b.DailyBudgetMicros < 1 {
+		return fmt.Errorf("daily_budget_micros must be positive")
+	}
+	if b.MonthlyBudgetMicros != nil && *b.MonthlyBudgetMicros < 1 {
+		return fmt.Errorf("monthly_budget_micros must be positive")
+	}
+	return nil
+}
+
+// orgColumns is the column list matching scanOrg
+const orgColumns = `o.id, o.name, o.subdomain, o.retention_days, o.legal_hold, o.daily_budget_micros, o.monthly_budget_micros, o.created_at, o.updated_at`
+
+// scanOrg scans a row selected with orgColumns
+func scanOrg(row pgx.Row) (*Organization, error) {
+	var org Organization
+	if err := row.Scan(&org.ID, &org.Name, &org.Subdomain, &org.RetentionDays, &org.LegalHold, &org.DailyBudgetMicros, &org.MonthlyBudgetMicros, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// OrgMember is a user's membership in an organization
+type OrgMember struct {
+	UserID    uuid.UUID      `json:"user_id"`
+	Email     string         `json:"email"`
+	Role      OrgRole        `json:"role"`
+	CreatedAt timestamp.Time `json:"created_at"`
+}
+
+// ValidateOrgName checks an organization name
+func ValidateOrgName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("name is required")
+	}
+	if utf8.RuneCountInString(name) > MaxOrgNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxOrgNameLength)
+	}
+	return nil
+}
+
+// ValidateSubdomain checks an organization subdomain: a lowercase DNS
+// label of 3 to 63 letters, digits and hyphens that isn't reserved
+func ValidateSubdomain(subdomain string) error {
+	if len(subdomain) < 3 || len(subdomain) > 63 {
+		return errors.New("subdomain must be between 3 and 63 characters")
+	}
+	for _, c := range subdomain {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return errors.New("subdomain may only contain lowercase letters, digits and hyphens")
+		}
+	}
+	if subdomain[0] == '-' || subdomain[len(subdomain)-1] == '-' {
+		return errors.New("subdomain cannot start or end with a hyphen")
+	}
+	if slices.Contains(reservedSubdomains, subdomain) {
+		return fmt.Errorf("subdomain %q is reserved", subdomain)
+	}
+	return nil
+}
+
+// OrganizationStore persists organizations and their members
+type OrganizationStore struct {
+	db *pgxpool.Pool
+}
+
+// NewOrganizationStore creates a new organization store
+func NewOrganizationStore(db *pgxpool.Pool) *OrganizationStore {
+	return &OrganizationStore{db: db}
+}
+
+// Create stores a new organization with ownerID as its owner
+func (s *OrganizationStore) Create(ctx context.Context, name string, ownerID uuid.UUID) (*Organization, error) {
+	if err := ValidateOrgName(name); err != nil {
+		return nil, err
+	}
+
+	org, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Organization, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		org, err := scanOrg(tx.QueryRow(ctx, `
+			INSERT INTO organizations AS o (name) VALUES ($1)
+			RETURNING `+orgColumns, name))
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+		`, org.ID, ownerID, RoleOwner); err != nil {
+			return nil, err
+		}
+
+		return org, tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return org, nil
+}
+
+// Get returns an organization by ID
+func (s *OrganizationStore) Get(ctx context.Context, id uuid.UUID) (*Organization, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Organization, error) {
+		return scanOrg(s.db.QueryRow(ctx, `SELECT `+orgColumns+` FROM organizations o WHERE o.id = $1`, id))
+	})
+}
+
+// GetBySubdomain returns the organization served on a subdomain, or
+// pgx.ErrNoRows if none is
+func (s *OrganizationStore) GetBySubdomain(ctx context.Context, subdomain string) (*Organization, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Organization, error) {
+		return scanOrg(s.db.QueryRow(ctx, `SELECT `+orgColumns+` FROM organizations o WHERE o.subdomain = $1`, subdomain))
+	})
+}
+
+// ListForUser returns the organizations userID belongs to, by name
+func (s *OrganizationStore) ListForUser(ctx context.Context, userID uuid.UUID) ([]Organization, error) {
+	orgs, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Organization, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT `+orgColumns+`
+			FROM organizations o
+			JOIN organization_members m ON m.org_id = o.id
+			WHERE m.user_id = $1
+			ORDER BY o.name, o.id
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var orgs []Organization
+		for rows.Next() {
+			org, err := scanOrg(rows)
+			if err != nil {
+				return nil, err
+			}
+			orgs = append(orgs, *org)
+		}
+		return orgs, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// SetRetention replaces an organization's retention policy and returns
+// the updated organization, or pgx.ErrNoRows if it doesn't exist
+func (s *OrganizationStore) SetRetention(ctx context.Context, id uuid.UUID, policy RetentionPolicy) (*Organization, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	org, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Organization, error) {
+		return scanOrg(s.db.QueryRow(ctx, `
+			UPDATE organizations o SET retention_days = $2, legal_hold = $3
+			WHERE o.id = $1
+			RETURNING `+orgColumns, id, policy.RetentionDays, policy.LegalHold))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set retention policy: %w", err)
+	}
+	return org, err
+}
+
+// SetSubdomain sets or, when nil, removes an organization's subdomain and
+// returns the updated organization, or pgx.ErrNoRows if it doesn't exist.
+// A subdomain another organization has fails with a unique violation.
+func (s *OrganizationStore) SetSubdomain(ctx context.Context, id uuid.UUID, subdomain *string) (*Organization, error) {
+	if subdomain != nil {
+		if err := ValidateSubdomain(*subdomain); err != nil {
+			return nil, err
+		}
+	}
+
+	org, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Organization, error) {
+		return scanOrg(s.db.QueryRow(ctx, `
+			UPDATE organizations o SET subdomain = $2
+			WHERE o.id = $1
+			RETURNING `+orgColumns, id, subdomain))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set subdomain: %w", err)
+	}
+	return org, err
+}
+
+// SetSpendBudget replaces an organization's spend budget and returns the
+// updated organization, or pgx.ErrNoRows if it doesn't exist
+func (s *OrganizationStore) SetSpendBudget(ctx context.Context, id uuid.UUID, budget SpendBudget) (*Organization, error) {
+	if err := budget.Validate(); err != nil {
+		return nil, err
+	}
+
+	org, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Organization, error) {
+		return scanOrg(s.db.QueryRow(ctx, `
+			UPDATE organizations o SET daily_budget_micros = $2, monthly_budget_micros = $3
+			WHERE o.id = $1
+			RETURNING `+orgColumns, id, budget.DailyBudgetMicros, budget.MonthlyBudgetMicros))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set spend budget: %w", err)
+	}
+	return org, err
+}
+
+// Role returns userID's role in an organization, or pgx.ErrNoRows if they
+// aren't a member
+func (s *OrganizationStore) Role(ctx context.Context, orgID, userID uuid.UUID) (OrgRole, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (OrgRole, error) {
+		var role OrgRole
+		err := s.db.QueryRow(ctx, `
+			SELECT role FROM organization_members WHERE org_id = $1 AND user_id = $2
+		`, orgID, userID).Scan(&role)
+		return role, err
+	})
+}
+
+// ShareOrganization reports whether two users belong to a common
+// organization
+func (s *OrganizationStore) ShareOrganization(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	shared, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (bool, error) {
+		var shared bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1
+				FROM organization_members a
+				JOIN organization_members b ON b.org_id = a.org_id
+				WHERE a.user_id = $1 AND b.user_id = $2
+			)
+		`, userID, otherID).Scan(&shared)
+		return shared, err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check organizations: %w", err)
+	}
+	return shared, nil
+}
+
+// Members returns an organization's members, by email
+func (s *OrganizationStore) Members(ctx context.Context, orgID uuid.UUID) ([]OrgMember, error) {
+	members, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]OrgMember, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT m.user_id, u.email, m.role, m.created_at
+			FROM organization_members m
+			JOIN users u ON u.id = m.user_id
+			WHERE m.org_id = $1
+			ORDER BY u.email
+		`, orgID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var members []OrgMember
+		for rows.Next() {
+			var m OrgMember
+			if err := rows.Scan(&m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+				return nil, err
+			}
+			members = append(members, m)
+		}
+		return members, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list members: %w", err)
+	}
+
+	return members, nil
+}
+
+// SetMember adds userID to an organization or changes their role. It
+// returns ErrLastOwner rather than demote the only owner.
+func (s *OrganizationStore) SetMember(ctx context.Context, orgID, userID uuid.UUID, role OrgRole) error {
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if role != RoleOwner {
+			if err := keepAnOwner(ctx, tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, user_id) DO UPDATE SET role = EXCLUDED.role
+		`, orgID, userID, role); err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, ErrLastOwner) {
+		return fmt.Errorf("failed to save member: %w", err)
+	}
+	return err
+}
+
+// RemoveMember takes userID out of an organization. It returns
+// pgx.ErrNoRows if they weren't a member and ErrLastOwner rather than
+// remove the only owner.
+func (s *OrganizationStore) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if err := keepAnOwner(ctx, tx, orgID, userID); err != nil {
+			return err
+		}
+
+		tag, err := tx.Exec(ctx, `
+			DELETE FROM organization_members WHERE org_id = $1 AND user_id = $2
+		`, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, ErrLastOwner) && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return err
+}
+
+// keepAnOwner returns ErrLastOwner if userID is the organization's only
+// owner. The owner rows are locked so concurrent changes can't both pass.
+func keepAnOwner(ctx context.Context, tx pgx.Tx, orgID, userID uuid.UUID) error {
+	rows, err := tx.Query(ctx, `
+		SELECT user_id FROM organization_members
+		WHERE org_id = $1 AND role = $2
+		FOR UPDATE
+	`, orgID, RoleOwner)
+	if err != nil {
+		return err
+	}
+	owners, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+
+	if len(owners) == 1 && owners[0] == userID {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOrgRole(t *testing.T) {
+	for _, role := range []string{"owner", "admin", "member"} {
+		if _, err := ParseOrgRole(role); err != nil {
+			t.Errorf("ParseOrgRole(%q) error = %v", role, err)
+		}
+	}
+	for _, role := range []string{"", "Owner", "viewer"} {
+		if _, err := ParseOrgRole(role); err == nil {
+			t.Errorf("ParseOrgRole(%q) error = nil, want error", role)
+		}
+	}
+}
+
+func TestValidateOrgName(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", "Acme Corp", false},
+		{"blank", "   ", true},
+		{"longest", strings.Repeat("é", MaxOrgNameLength), false},
+		{"too long", strings.Repeat("a", MaxOrgNameLength+1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateOrgName(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateOrgName() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	days := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		policy  RetentionPolicy
+		wantErr bool
+	}{
+		{"keep forever", RetentionPolicy{}, false},
+		{"legal hold only", RetentionPolicy{LegalHold: true}, false},
+		{"one day", RetentionPolicy{RetentionDays: days(1)}, false},
+		{"longest", RetentionPolicy{RetentionDays: days(MaxRetentionDays)}, false},
+		{"zero", RetentionPolicy{RetentionDays: days(0)}, true},
+		{"too long", RetentionPolicy{RetentionDays: days(MaxRetentionDays + 1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSpendBudget_Validate(t *testing.T) {
+	micros := func(n int64) *int64 { return &n }
+
+	tests := []struct {
+		name    string
+		budget  SpendBudget
+		wantErr bool
+	}{
+		{"uncapped", SpendBudget{}, false},
+		{"both", SpendBudget{DailyBudgetMicros: micros(5_000_000), MonthlyBudgetMicros: micros(100_000_000)}, false},
+		{"zero daily", SpendBudget{DailyBudgetMicros: micros(0)}, true},
+		{"negative monthly", SpendBudget{MonthlyBudgetMicros: micros(-1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.budget.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateSubdomain(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"acme", false},
+		{"acme-corp2", false},
+		{"ab", true},
+		{strings.Repeat("a", 64), true},
+		{"Acme", true},
+		{"acme.corp", true},
+		{"-acme", true},
+		{"acme-", true},
+		{"www", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSubdomain(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSubdomain(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
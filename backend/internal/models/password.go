@@ -0,0 +1,208 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordAlgorithm names a password hashing algorithm
+type PasswordAlgorithm string
+
+const (
+	PasswordBcrypt   PasswordAlgorithm = "bcrypt"
+	PasswordArgon2id PasswordAlgorithm = "argon2id"
+)
+
+// PasswordParams configures how new password hashes are made. Hashes made
+// with other parameters still verify and are replaced on the next login.
+type PasswordParams struct {
+	Algorithm  PasswordAlgorithm
+	BcryptCost int
+
+	// Argon2Memory is in KiB
+	Argon2Memory      uint32
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+}
+
+// DefaultPasswordParams are used until SetPasswordParams is called
+var DefaultPasswordParams = PasswordParams{
+	Algorithm:         PasswordBcrypt,
+	BcryptCost:        12,
+	Argon2Memory:      64 * 1024,
+	Argon2Iterations:  3,
+	Argon2Parallelism: 2,
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// ErrPasswordMismatch is returned when a password doesn't match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+var (
+	passwordMu     sync.RWMutex
+	passwordParams = DefaultPasswordParams
+)
+
+// Validate checks that the parameters can produce a hash
+func (p PasswordParams) Validate() error {
+	switch p.Algorithm {
+	case PasswordBcrypt:
+		if p.BcryptCost < bcrypt.MinCost || p.BcryptCost > bcrypt.MaxCost {
+			return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	case PasswordArgon2id:
+		if p.Argon2Memory < 8*uint32(p.Argon2Parallelism) {
+			return fmt.Errorf("argon2id memory must be at least 8 KiB per thread")
+		}
+		if p.Argon2Iterations < 1 {
+			return fmt.Errorf("argon2id iterations must be at least 1")
+		}
+		if p.Argon2Parallelism < 1 {
+			return fmt.Errorf("argon2id parallelism must be at least 1")
+		}
+	default:
+		return fmt.Errorf("unknown password algorithm %q", p.Algorithm)
+	}
+	return nil
+}
+
+// SetPasswordParams changes how new password hashes are made
+func SetPasswordParams(p PasswordParams) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	passwordMu.Lock()
+	defer passwordMu.Unlock()
+	passwordParams = p
+	return nil
+}
+
+// currentPasswordParams returns the parameters for new hashes
+func currentPasswordParams() PasswordParams {
+	passwordMu.RLock()
+	defer passwordMu.RUnlock()
+	return passwordParams
+}
+
+// HashPassword hashes a password with the configured algorithm
+func HashPassword(password string) (string, error) {
+	p := currentPasswordParams()
+
+	if p.Algorithm == PasswordArgon2id {
+		return hashArgon2id(password, p)
+	}
+
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), p.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedBytes), nil
+}
+
+// CheckPassword compares a plain text password with a bcrypt or argon2id
+// hash. It returns ErrPasswordMismatch if they don't match.
+func CheckPassword(hash, password string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return err
+		}
+
+		got := argon2.IDKey([]byte(password), salt, params.Argon2Iterations, params.Argon2Memory, params.Argon2Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(got, key) != 1 {
+			return ErrPasswordMismatch
+		}
+		return nil
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// PasswordNeedsRehash reports whether a hash was made with a different
+// algorithm or parameters than the configured ones
+func PasswordNeedsRehash(hash string) bool {
+	p := currentPasswordParams()
+
+	if p.Algorithm == PasswordArgon2id {
+		stored, _, key, err := decodeArgon2id(hash)
+		if err != nil {
+			return true
+		}
+		return stored.Argon2Memory != p.Argon2Memory ||
+			stored.Argon2Iterations != p.Argon2Iterations ||
+			stored.Argon2Parallelism != p.Argon2Parallelism ||
+			len(key) != argon2KeyLength
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != p.BcryptCost
+}
+
+// hashArgon2id hashes a password into the PHC string format:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func hashArgon2id(password string, p PasswordParams) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, p.Argon2Iterations, p.Argon2Memory, p.Argon2Parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Argon2Memory,
+		p.Argon2Iterations,
+		p.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// decodeArgon2id parses a hash made by hashArgon2id
+func decodeArgon2id(hash string) (PasswordParams, []byte, []byte, error) {
+	p := PasswordParams{Algorithm: PasswordArgon2id}
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2id version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Argon2Memory, &p.Argon2Iterations, &p.Argon2Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("invalid argon2id key")
+	}
+
+	return p, salt, key, nil
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fastBcrypt and fastArgon2id keep hashing cheap in tests
+var (
+	fastBcrypt   = PasswordParams{Algorithm: PasswordBcrypt, BcryptCost: 4}
+	fastArgon2id = PasswordParams{Algorithm: PasswordArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}
+)
+
+// usePasswordParams sets the password parameters for the rest of the test
+func usePasswordParams(t *testing.T, p PasswordParams) {
+	t.Helper()
+	if err := SetPasswordParams(p); err != nil {
+		t.Fatalf("SetPasswordParams() error = %v", err)
+	}
+	t.Cleanup(func() { _ = SetPasswordParams(DefaultPasswordParams) })
+}
+
+func TestCheckPassword(t *testing.T) {
+	tests := []struct {
+		name       string
+		params     PasswordParams
+		wantPrefix string
+	}{
+		{name: "bcrypt", params: fastBcrypt, wantPrefix: "$2a$04$"},
+		{name: "argon2id", params: fastArgon2id, wantPrefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasswordParams(t, tt.params)
+
+			hash, err := HashPassword("mySecurePassword123")
+			if err != nil {
+				t.Fatalf("HashPassword() error = %v", err)
+			}
+
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Errorf("HashPassword() = %q, want prefix %q", hash, tt.wantPrefix)
+			}
+
+			if err := CheckPassword(hash, "mySecurePassword123"); err != nil {
+				t.Errorf("CheckPassword() with correct password error = %v", err)
+			}
+
+			if err := CheckPassword(hash, "wrongPassword"); !errors.Is(err, ErrPasswordMismatch) {
+				t.Errorf("CheckPassword() with wrong password error = %v, want %v", err, ErrPasswordMismatch)
+			}
+		})
+	}
+}
+
+func TestCheckPassword_OtherAlgorithm(t *testing.T) {
+	// Hashes keep verifying after the configured algorithm changes
+	usePasswordParams(t, fastArgon2id)
+	argonHash, _ := HashPassword("password123")
+
+	usePasswordParams(t, fastBcrypt)
+	if err := CheckPassword(argonHash, "password123"); err != nil {
+		t.Errorf("CheckPassword() with argon2id hash error = %v", err)
+	}
+}
+
+// TestCheckPassword_ReferenceHash verifies the PHC string the reference
+// implementation prints for argon2id -t 2 -m 16 -p 1 with password
+// "password" and salt "somesalt"
+func TestCheckPassword_ReferenceHash(t *testing.T) {
+	const hash = "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+
+	if err := CheckPassword(hash, "password"); err != nil {
+		t.Errorf("CheckPassword() with the reference hash error = %v", err)
+	}
+	if err := CheckPassword(hash, "Password"); !errors.Is(err, ErrPasswordMismatch) {
+		t.Errorf("CheckPassword() with the wrong password error = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
+
+func TestCheckPassword_MalformedHash(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		if err := CheckPassword(hash, "password123"); err == nil {
+			t.Errorf("CheckPassword(%q) should return error", hash)
+		}
+	}
+}
+
+func TestPasswordNeedsRehash(t *testing.T) {
+	usePasswordParams(t, fastBcrypt)
+	bcrypt4, _ := HashPassword("password123")
+
+	usePasswordParams(t, PasswordParams{Algorithm: PasswordBcrypt, BcryptCost: 5})
+	bcrypt5, _ := HashPassword("password123")
+
+	usePasswordParams(t, fastArgon2id)
+	argon, _ := HashPassword("password123")
+
+	tests := []struct {
+		name   string
+		params PasswordParams
+		hash   string
+		want   bool
+	}{
+		{name: "bcrypt at configured cost", params: fastBcrypt, hash: bcrypt4, want: false},
+		{name: "bcrypt at old cost", params: fastBcrypt, hash: bcrypt5, want: true},
+		{name: "bcrypt when argon2id is configured", params: fastArgon2id, hash: bcrypt4, want: true},
+		{name: "argon2id at configured parameters", params: fastArgon2id, hash: argon, want: false},
+		{
+			name:   "argon2id with less memory",
+			params: PasswordParams{Algorithm: PasswordArgon2id, Argon2Memory: 128, Argon2Iterations: 1, Argon2Parallelism: 1},
+			hash:   argon,
+			want:   true,
+		},
+		{name: "argon2id when bcrypt is configured", params: fastBcrypt, hash: argon, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePasswordParams(t, tt.params)
+
+			if got := PasswordNeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("PasswordNeedsRehash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordParams_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  PasswordParams
+		wantErr bool
+	}{
+		{name: "default", params: DefaultPasswordParams},
+		{name: "fast argon2id", params: fastArgon2id},
+		{name: "bcrypt cost too low", params: PasswordParams{Algorithm: PasswordBcrypt, BcryptCost: 3}, wantErr: true},
+		{name: "argon2id memory below 8 KiB per thread", params: PasswordParams{Algorithm: PasswordArgon2id, Argon2Memory: 15, Argon2Iterations: 1, Argon2Parallelism: 2}, wantErr: true},
+		{name: "argon2id without parallelism", params: PasswordParams{Algorithm: PasswordArgon2id, Argon2Memory: 64, Argon2Iterations: 1}, wantErr: true},
+		{name: "unknown algorithm", params: PasswordParams{Algorithm: "scrypt"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
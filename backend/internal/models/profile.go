@@ -0,0 +1,284 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// AnalysisModule is an optional part of an analysis that a profile can
+// turn off. Sentiment is always analyzed.
+type AnalysisModule string
+
+const (
+	ModuleTopics       AnalysisModule = "topics"
+	ModuleKeyphrases   AnalysisModule = "keyphrases"
+	ModuleSummary      AnalysisModule = "summary"
+	ModuleFindings     AnalysisModule = "findings"
+	ModuleReadability  AnalysisModule = "readability"
+	ModuleVerification AnalysisModule = "verification"
+	ModuleClaims       AnalysisModule = "claims"
+	ModuleProofreading AnalysisModule = "proofreading"
+	ModuleBias         AnalysisModule = "bias"
+	ModuleAIDetection  AnalysisModule = "ai_detection"
+	ModuleModeration   AnalysisModule = "moderation"
+	ModuleCompliance   AnalysisModule = "compliance"
+)
+
+// AllModules lists every analysis module, in the order they are reported
+var AllModules = []AnalysisModule{
+	ModuleTopics,
+	ModuleKeyphrases,
+	ModuleSummary,
+	ModuleFindings,
+	ModuleReadability,
+	ModuleVerification,
+	ModuleClaims,
+	ModuleProofreading,
+	ModuleBias,
+	ModuleAIDetection,
+	ModuleModeration,
+	ModuleCompliance,
+}
+
+const (
+	// MaxProfilesPerUser caps how many profiles one user can define
+	MaxProfilesPerUser = 50
+	// MaxProfileNameLength is the longest profile name, in characters
+	MaxProfileNameLength = 100
+	// MaxProfileDescriptionLength is the longest description, in characters
+	MaxProfileDescriptionLength = 500
+)
+
+// ErrProfileLimit is returned when a user already has MaxProfilesPerUser
+// profiles
+var ErrProfileLimit = fmt.Errorf("a user can have at most %d analysis profiles", MaxProfilesPerUser)
+
+// AnalysisProfile is a reusable analysis setup: a prompt template added to
+// the analysis prompt and the modules to run. Built-in profiles have no
+// owner, are shared by every user and can't be changed through the API.
+type AnalysisProfile struct {
+	ID          uuid.UUID        `json:"id"`
+	UserID      *uuid.UUID       `json:"user_id"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Prompt      string           `json:"prompt"`
+	Modules     []AnalysisModule `json:"modules"`
+	BuiltIn     bool             `json:"built_in"`
+	CreatedAt   timestamp.Time   `json:"created_at"`
+	UpdatedAt   timestamp.Time   `json:"updated_at"`
+}
+
+// Validate checks the profile's name, description and modules. The prompt is sanitized
+// by the caller.
+func (p *AnalysisProfile) Validate() error {
+	if name := strings.TrimSpace(p.Name); name == "" || utf8.RuneCountInString(name) > MaxProfileNameLength {
+		return fmt.Errorf("name must be 1-%d characters", MaxProfileNameLength)
+	}
+	if utf8.RuneCountInString(p.Description) > MaxProfileDescriptionLength {
+		return fmt.Errorf("description must be at most %d characters", MaxProfileDescriptionLength)
+	}
+	for i, module := range p.Modules {
+		if !slices.Contains(AllModules, module) {
+			return fmt.Errorf("unknown module %q", module)
+		}
+		if slices.Contains(p.Modules[:i], module) {
+			return fmt.Errorf("module %q is listed twice", module)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether the profile runs module. A nil profile runs
+// every module.
+func (p *AnalysisProfile) Enabled(module AnalysisModule) bool {
+	return p == nil || slices.Contains(p.Modules, module)
+}
+
+// ProfileStore persists analysis profiles
+type ProfileStore struct {
+	db *pgxpool.Pool
+}
+
+// NewProfileStore creates a new analysis profile store
+func NewProfileStore(db *pgxpool.Pool) *ProfileStore {
+	return &ProfileStore{db: db}
+}
+
+const profileColumns = `id, user_id, name, description, prompt, modules, created_at, updated_at`
+
+// scanProfile reads a row selected with profileColumns
+func scanProfile(row pgx.Row) (*AnalysisProfile, error) {
+	var p AnalysisProfile
+	var modules []string
+	if err := row.Scan(
+		&p.ID,
+		&p.UserID,
+		&p.Name,
+		&p.Description,
+		&p.Prompt,
+		&modules,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	p.BuiltIn = p.UserID == nil
+	p.Modules = make([]AnalysisModule, 0, len(modules))
+	for _, m := range modules {
+		p.Modules = append(p.Modules, AnalysisModule(m))
+	}
+	return &p, nil
+}
+
+// moduleNames converts modules for a TEXT[] column
+func moduleNames(modules []AnalysisModule) []string {
+	names := make([]string, 0, len(modules))
+	for _, m := range modules {
+		names = append(names, string(m))
+	}
+	return names
+}
+
+// List returns the built-in profiles followed by the user's own, each by name
+func (s *ProfileStore) List(ctx context.Context, userID uuid.UUID) ([]AnalysisProfile, error) {
+	query := `
+		SELECT ` + profileColumns + `
+		FROM analysis_profiles
+		WHERE user_id IS NULL OR user_id = $1
+		ORDER BY user_id NULLS FIRST, LOWER(name)
+	`
+
+	profiles, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]AnalysisProfile, error) {
+		rows, err := s.db.Query(ctx, query, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		profiles := []AnalysisProfile{}
+		for rows.Next() {
+			profile, err := scanProfile(rows)
+			if err != nil {
+				return nil, err
+			}
+			profiles = append(profiles, *profile)
+		}
+		return profiles, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analysis profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// Get retrieves a profile by ID regardless of owner. It is meant for
+// background jobs; handlers should use GetByID.
+func (s *ProfileStore) Get(ctx context.Context, id uuid.UUID) (*AnalysisProfile, error) {
+	query := `SELECT ` + profileColumns + ` FROM analysis_profiles WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*AnalysisProfile, error) {
+		return scanProfile(s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// GetByID retrieves a built-in profile or one owned by the given user
+func (s *ProfileStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*AnalysisProfile, error) {
+	query := `SELECT ` + profileColumns + ` FROM analysis_profiles WHERE id = $1 AND (user_id IS NULL OR user_id = $2)`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*AnalysisProfile, error) {
+		return scanProfile(s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// Create stores a new profile owned by profile.UserID. It returns
+// ErrProfileLimit once the user has MaxProfilesPerUser profiles, and a
+// unique violation if the user already has a profile with the same name.
+func (s *ProfileStore) Create(ctx context.Context, profile *AnalysisProfile) (*AnalysisProfile, error) {
+	if profile.UserID == nil {
+		return nil, errors.New("built-in profiles are created by migrations")
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Two profiles saved at once can both see room under
+	// MaxProfilesPerUser. Profiles are created by hand, so that race is
+	// left alone rather than locking the user's rows.
+	query := `
+		INSERT INTO analysis_profiles (user_id, name, description, prompt, modules)
+		SELECT $1, $2, $3, $4, $5
+		WHERE (SELECT COUNT(*) FROM analysis_profiles WHERE user_id = $1) < $6
+		RETURNING ` + profileColumns
+
+	stored, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*AnalysisProfile, error) {
+		return scanProfile(s.db.QueryRow(ctx, query,
+			profile.UserID,
+			strings.TrimSpace(profile.Name),
+			profile.Description,
+			profile.Prompt,
+			moduleNames(profile.Modules),
+			MaxProfilesPerUser,
+		))
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrProfileLimit
+		}
+		return nil, fmt.Errorf("failed to create analysis profile: %w", err)
+	}
+
+	return stored, nil
+}
+
+// Update replaces a profile owned by profile.UserID. Built-in profiles and
+// other users' profiles return pgx.ErrNoRows.
+func (s *ProfileStore) Update(ctx context.Context, profile *AnalysisProfile) (*AnalysisProfile, error) {
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE analysis_profiles
+		SET name = $3, description = $4, prompt = $5, modules = $6, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + profileColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*AnalysisProfile, error) {
+		return scanProfile(s.db.QueryRow(ctx, query,
+			profile.ID,
+			profile.UserID,
+			strings.TrimSpace(profile.Name),
+			profile.Description,
+			profile.Prompt,
+			moduleNames(profile.Modules),
+		))
+	})
+}
+
+// Delete removes a profile owned by the given user. Submissions that used
+// it are analyzed with every module from then on.
+func (s *ProfileStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM analysis_profiles WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete analysis profile: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAnalysisProfile_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile AnalysisProfile
+		wantErr bool
+	}{
+		{"all modules", AnalysisProfile{Name: "Review", Modules: AllModules}, false},
+		{"sentiment only", AnalysisProfile{Name: "Mood", Modules: []AnalysisModule{}}, false},
+		{"blank name", AnalysisProfile{Name: "  ", Modules: AllModules}, true},
+		{"long name", AnalysisProfile{Name: strings.Repeat("a", MaxProfileNameLength+1)}, true},
+		{"long description", AnalysisProfile{Name: "Review", Description: strings.Repeat("a", MaxProfileDescriptionLength+1)}, true},
+		{"unknown module", AnalysisProfile{Name: "Review", Modules: []AnalysisModule{"emotions"}}, true},
+		{"duplicate module", AnalysisProfile{Name: "Review", Modules: []AnalysisModule{ModuleTopics, ModuleTopics}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.profile.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnalysisProfile_Enabled(t *testing.T) {
+	var none *AnalysisProfile
+	if !none.Enabled(ModuleReadability) {
+		t.Error("Enabled() = false for no profile, want every module")
+	}
+
+	profile := &AnalysisProfile{Modules: []AnalysisModule{ModuleSummary}}
+	if !profile.Enabled(ModuleSummary) {
+		t.Error("Enabled(summary) = false, want true")
+	}
+	if profile.Enabled(ModuleTopics) {
+		t.Error("Enabled(topics) = true, want false")
+	}
+}
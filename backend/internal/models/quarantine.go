@@ -0,0 +1,123 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// quarantineQuery quarantines submission $1 with reason $2. A submission
+// quarantined again keeps the time it was first quarantined.
+const quarantineQuery = `
+	UPDATE submissions
+	SET quarantined_at = COALESCE(quarantined_at, NOW()), quarantine_reason = $2
+	WHERE id = $1`
+
+// QuarantineReason explains why a moderation decision quarantines its
+// submission, or returns "" if it doesn't: only blocked content is
+// quarantined
+func QuarantineReason(decision *PolicyDecision) string {
+	if decision == nil || decision.Action != PolicyBlock {
+		return ""
+	}
+	if decision.Override != nil && decision.Override.Action == PolicyBlock {
+		return "Blocked by an operator"
+	}
+
+	categories := make([]string, 0, len(decision.Violations))
+	for _, v := range decision.Violations {
+		if v.Action == PolicyBlock {
+			categories = append(categories, fmt.Sprintf("%s %.2f above %.2f", v.Category, v.Score, v.Threshold))
+		}
+	}
+	return "Blocked by the moderation policy: " + strings.Join(categories, ", ")
+}
+
+// Quarantine hides a submission from listings and integrations until it is
+// released. It returns pgx.ErrNoRows if the submission doesn't exist.
+func (s *SubmissionStore) Quarantine(ctx context.Context, id uuid.UUID, reason string) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET quarantined_at = COALESCE(quarantined_at, NOW()), quarantine_reason = $2, version = version + 1
+		WHERE id = $1
+		RETURNING ` + submissionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, reason))
+	})
+}
+
+// GetQuarantined retrieves a quarantined submission regardless of owner.
+// It returns pgx.ErrNoRows if the submission isn't quarantined.
+func (s *SubmissionStore) GetQuarantined(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	query := `SELECT ` + submissionColumns + ` FROM submissions WHERE id = $1 AND quarantined_at IS NOT NULL`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// ListQuarantined returns a page of quarantined submissions of every user,
+// oldest quarantine first, and the total count
+func (s *SubmissionStore) ListQuarantined(ctx context.Context, limit, offset int) ([]Submission, int, error) {
+	var submissions []Submission
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions WHERE quarantined_at IS NOT NULL`).Scan(&total); err != nil {
+			return fmt.Errorf("failed to count quarantined submissions: %w", err)
+		}
+
+		rows, err := s.db.Query(ctx, `
+			SELECT `+submissionColumns+`
+			FROM submissions
+			WHERE quarantined_at IS NOT NULL
+			ORDER BY quarantined_at, id
+			LIMIT $1 OFFSET $2
+		`, limit, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list quarantined submissions: %w", err)
+		}
+		defer rows.Close()
+
+		submissions = []Submission{}
+		for rows.Next() {
+			submission, err := s.scan(ctx, rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan submission: %w", err)
+			}
+			submissions = append(submissions, *submission)
+		}
+		return rows.Err()
+	})
+	return submissions, total, err
+}
+
+// Release lifts a submission's quarantine. It returns pgx.ErrNoRows if the
+// submission isn't quarantined. Analyzing it again quarantines it again
+// if the policy still blocks it, unless an override allows it.
+func (s *SubmissionStore) Release(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET quarantined_at = NULL, quarantine_reason = NULL, version = version + 1
+		WHERE id = $1 AND quarantined_at IS NOT NULL
+		RETURNING ` + submissionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// Destroy permanently deletes a quarantined submission with its analyses,
+// and returns it as it was. It returns pgx.ErrNoRows if the submission
+// isn't quarantined.
+func (s *SubmissionStore) Destroy(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	query := `DELETE FROM submissions WHERE id = $1 AND quarantined_at IS NOT NULL RETURNING ` + submissionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestQuarantineReason(t *testing.T) {
+	toxicity := ModerationThreshold{Category: ModerationToxicity, WarnAbove: ptrFloat(0.5), BlockAbove: ptrFloat(0.8)}
+	policy := &ModerationPolicy{Thresholds: []ModerationThreshold{toxicity}}
+
+	blocked := EvaluatePolicy(policy, ModerationScores{ModerationToxicity: 0.93})
+	overridden := EvaluatePolicy(policy, ModerationScores{ModerationToxicity: 0.93})
+	overridden.ApplyOverride(&PolicyOverride{Action: PolicyAllow})
+	operatorBlocked := EvaluatePolicy(nil, nil)
+	operatorBlocked.ApplyOverride(&PolicyOverride{Action: PolicyBlock})
+
+	tests := []struct {
+		name     string
+		decision *PolicyDecision
+		want     string
+	}{
+		{"no decision", nil, ""},
+		{"allowed", EvaluatePolicy(policy, ModerationScores{ModerationToxicity: 0.1}), ""},
+		{"warned", EvaluatePolicy(policy, ModerationScores{ModerationToxicity: 0.6}), ""},
+		{"blocked", blocked, "Blocked by the moderation policy: toxicity 0.93 above 0.80"},
+		{"allowed by an operator", overridden, ""},
+		{"blocked by an operator", operatorBlocked, "Blocked by an operator"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := QuarantineReason(tt.decision); got != tt.want {
+				t.Errorf("QuarantineReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
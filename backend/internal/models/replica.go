@@ -0,0 +1,22 @@
+package models
+
+import "github.com/jackc/pgx/v5/pgxpool"
+
+// ReadRouter picks the pool for read-only queries. *database.Database
+// implements it, returning the replica while it is healthy and the primary
+// otherwise.
+//
+// Only queries that tolerate replication lag are routed: listings and
+// aggregates. Reads that follow a write in the same flow, such as reloading
+// a submission after a status change, stay on the primary.
+type ReadRouter interface {
+	Reader() *pgxpool.Pool
+}
+
+// readPool returns the pool for a lag-tolerant read
+func readPool(primary *pgxpool.Pool, router ReadRouter) *pgxpool.Pool {
+	if router == nil {
+		return primary
+	}
+	return router.Reader()
+}
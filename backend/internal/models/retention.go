@@ -0,0 +1,102 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// RetentionStore destroys submissions that have outlived the retention
+// policies of their owners' organizations
+type RetentionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewRetentionStore creates a new retention store
+func NewRetentionStore(db *pgxpool.Pool) *RetentionStore {
+	return &RetentionStore{db: db}
+}
+
+// purgeQuery deletes up to $1 expired submissions and records each in the
+// audit log in the same statement, so nothing is destroyed unrecorded. A
+// submission expires after the shortest retention period among its
+// owner's organizations. It is kept while it, or any of those
+// organizations, is on legal hold. Analyses, keyphrases and topic
+// assignments go with it by cascade.
+const purgeQuery = `
+	WITH expired AS (
+		SELECT s.id, s.user_id, s.created_at, p.org_id, p.retention_days
+		FROM submissions s
+		JOIN LATERAL (
+			SELECT o.id AS org_id, o.retention_days
+			FROM organization_members m
+			JOIN organizations o ON o.id = m.org_id
+			WHERE m.user_id = s.user_id AND o.retention_days IS NOT NULL
+			ORDER BY o.retention_days, o.id
+			LIMIT 1
+		) p ON TRUE
+		WHERE NOT s.legal_hold
+			AND s.created_at < NOW() - make_interval(days => p.retention_days)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM organization_members m
+				JOIN organizations o ON o.id = m.org_id
+				WHERE m.user_id = s.user_id AND o.legal_hold
+			)
+		ORDER BY s.created_at
+		LIMIT $1
+		FOR UPDATE OF s SKIP LOCKED
+	), purged AS (
+		DELETE FROM submissions s
+		USING expired e
+		WHERE s.id = e.id
+		RETURNING s.id
+	)
+	INSERT INTO audit_log (user_id, action, metadata)
+	SELECT e.user_id, $2, jsonb_build_object(
+		'submission_id', e.id,
+		'org_id', e.org_id,
+		'retention_days', e.retention_days,
+		'created_at', e.created_at
+	)
+	FROM expired e
+	JOIN purged p ON p.id = e.id
+`
+
+// Purge destroys up to limit expired submissions and returns how many it
+// destroyed. Call it until it returns fewer than limit to catch up.
+func (s *RetentionStore) Purge(ctx context.Context, limit int) (int, error) {
+	purged, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (int64, error) {
+		tag, err := s.db.Exec(ctx, purgeQuery, limit, AuditSubmissionPurged)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge expired submissions: %w", err)
+	}
+	return int(purged), nil
+}
+
+// SetLegalHold places a submission on legal hold, or releases it. It
+// returns pgx.ErrNoRows if the submission doesn't exist.
+func (s *RetentionStore) SetLegalHold(ctx context.Context, submissionID uuid.UUID, hold bool) error {
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `UPDATE submissions SET legal_hold = $2 WHERE id = $1`, submissionID, hold)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to set legal hold: %w", err)
+	}
+	return err
+}
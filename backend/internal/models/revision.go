@@ -0,0 +1,167 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+)
+
+// ErrNotLatestRevision is returned when revising a submission that
+// already has a newer revision. A document's revisions form a single line.
+var ErrNotLatestRevision = errors.New("only the latest revision can be revised")
+
+// RevisionChanges is the model's comparison of a revision with the one
+// it replaces
+type RevisionChanges struct {
+	// Tone describes how the tone shifted, in a sentence
+	Tone          string   `json:"tone"`
+	ClaimsAdded   []string `json:"claims_added"`
+	ClaimsRemoved []string `json:"claims_removed"`
+}
+
+// CreateRevision stores content as a new revision of a user's submission
+// and queues it for analysis. The revision keeps the previous one's
+// instructions, profile, assignee and due date, and its review starts
+// open again. It returns pgx.ErrNoRows if the submission
+// doesn't exist and ErrNotLatestRevision if it was already revised.
+func (s *SubmissionStore) CreateRevision(ctx context.Context, userID, previousID uuid.UUID, content string, redacted *string) (*Submission, error) {
+	stats := textstats.Compute(content)
+	hash, hashKey, err := s.hashes.hash(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	revision := newRecord(userID)
+	content, redacted, err = s.sealContent(ctx, revision, content, redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	// Instructions are bound to the previous revision, so they are sealed
+	// again for this one rather than copied in SQL
+	instructions, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*string, error) {
+		var instructions *string
+		err := s.db.QueryRow(ctx, `SELECT instructions FROM submissions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+			previousID, userID).Scan(&instructions)
+		return instructions, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create revision: %w", err)
+	}
+	previous := record{previousID, userID}
+	instructions, err = resealOptionalField(ctx, s.cipher, instructions,
+		previous.bind(fieldSubmissionInstructions), revision.bind(fieldSubmissionInstructions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+
+	query := `
+		INSERT INTO submissions (id, user_id, content, redacted_content, instructions, profile_id, previous_id, revision, assignee_id, due_at, status, queued_at,
+			word_count, character_count, token_estimate, content_hash, content_hash_key)
+		SELECT $3, user_id, $4, $5, $6, profile_id, id, revision + 1, assignee_id, due_at, $7, NOW(), $8, $9, $10, $11, $12
+		FROM submissions
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+		RETURNING ` + submissionColumns
+
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, previousID, userID, revision.id, content, redacted, instructions, StatusQueued,
+			stats.Words, stats.Characters, stats.Tokens, hash, hashKey))
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrNotLatestRevision
+		}
+		return nil, fmt.Errorf("failed to create revision: %w", err)
+	}
+
+	s.emit(ctx, StatusChange{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		To:           submission.Status,
+		At:           submission.CreatedAt,
+	})
+
+	return submission, nil
+}
+
+// Revisions returns every revision of the document a user's submission
+// belongs to, oldest first. Deleting a revision starts the line again
+// from the next one; revisions in the trash are left out.
+func (s *SubmissionStore) Revisions(ctx context.Context, userID, id uuid.UUID) ([]Submission, error) {
+	query := `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, previous_id FROM submissions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			UNION ALL
+			SELECT s.id, s.previous_id FROM submissions s JOIN ancestors a ON s.id = a.previous_id
+		), lineage AS (
+			SELECT id FROM ancestors WHERE previous_id IS NULL
+			UNION ALL
+			SELECT s.id FROM submissions s JOIN lineage l ON s.previous_id = l.id
+		)
+		SELECT ` + submissionColumns + `
+		FROM submissions
+		WHERE id IN (SELECT id FROM lineage) AND deleted_at IS NULL
+		ORDER BY revision, created_at
+	`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Submission, error) {
+		rows, err := s.db.Query(ctx, query, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list revisions: %w", err)
+		}
+		defer rows.Close()
+
+		revisions := []Submission{}
+		for rows.Next() {
+			submission, err := s.scan(ctx, rows)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan revision: %w", err)
+			}
+			revisions = append(revisions, *submission)
+		}
+		return revisions, rows.Err()
+	})
+}
+
+// sealChanges encodes an analysis' changes for the changes column,
+// encrypting them when enabled
+func (s *SubmissionStore) sealChanges(ctx context.Context, analysis record, changes *RevisionChanges) (*string, error) {
+	if changes == nil {
+		return nil, nil
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode changes: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(encoded), analysis.bind(fieldAnalysisChanges))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt changes: %w", err)
+	}
+	return &sealed, nil
+}
+
+// openChanges decodes a value read from the changes column
+func (s *SubmissionStore) openChanges(ctx context.Context, analysis record, value *string) (*RevisionChanges, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	opened, err := openField(ctx, s.cipher, *value, analysis.bind(fieldAnalysisChanges))
+	if err != nil {
+		return nil, err
+	}
+
+	var changes RevisionChanges
+	if err := json.Unmarshal([]byte(opened), &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
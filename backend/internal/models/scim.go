@@ -0,0 +1,654 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// scimTokenPrefix marks SCIM tokens so they are easy to recognize in leaks
+// and secret scanners
+const scimTokenPrefix = "ca_scim_"
+
+// SCIMUser is an account an organization's identity provider manages
+// through SCIM. Inactive users have been deprovisioned: they are no
+// longer members, but the identity provider can still see them. Pending
+// users are invitations: they have no account yet, or haven't verified
+// its email, and become members once they do.
+type SCIMUser struct {
+	OrgID       uuid.UUID      `json:"org_id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Email       string         `json:"email"`
+	DisplayName *string        `json:"display_name"`
+	ExternalID  string         `json:"external_id"`
+	Active      bool           `json:"active"`
+	Pending     bool           `json:"pending"`
+	Groups      []SCIMGroupRef `json:"groups"`
+	CreatedAt   timestamp.Time `json:"created_at"`
+	UpdatedAt   timestamp.Time `json:"updated_at"`
+}
+
+// SCIMGroupRef names a group a SCIM user is in
+type SCIMGroupRef struct {
+	ID          uuid.UUID `json:"id"`
+	DisplayName string    `json:"display_name"`
+}
+
+// SCIMGroup is a group pushed by an organization's identity provider.
+// Its name is matched against the organization's SSO role mappings to
+// give its members their role.
+type SCIMGroup struct {
+	ID          uuid.UUID         `json:"id"`
+	OrgID       uuid.UUID         `json:"org_id"`
+	DisplayName string            `json:"display_name"`
+	ExternalID  string            `json:"external_id"`
+	Members     []SCIMGroupMember `json:"members"`
+	CreatedAt   timestamp.Time    `json:"created_at"`
+	UpdatedAt   timestamp.Time    `json:"updated_at"`
+}
+
+// SCIMGroupMember is a user in a SCIM group
+type SCIMGroupMember struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+}
+
+// SCIMFilter narrows a list of SCIM users or groups; empty fields match
+// everything
+type SCIMFilter struct {
+	// Name matches users' email addresses, ignoring case, or groups'
+	// display names
+	Name       string
+	ExternalID string
+}
+
+// NewSCIMToken returns a random token such as "ca_scim_3f9a..."
+func NewSCIMToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate SCIM token: %w", err)
+	}
+	return scimTokenPrefix + hex.EncodeToString(raw), nil
+}
+
+// SCIMStore persists organizations' SCIM tokens and the users and groups
+// their identity providers provision
+type SCIMStore struct {
+	db *pgxpool.Pool
+}
+
+// NewSCIMStore creates a new SCIM store
+func NewSCIMStore(db *pgxpool.Pool) *SCIMStore {
+	return &SCIMStore{db: db}
+}
+
+// CreateToken issues the organization a SCIM token, replacing any it had,
+// and returns it. Only its hash is kept.
+func (s *SCIMStore) CreateToken(ctx context.Context, orgID uuid.UUID) (string, error) {
+	token, err := NewSCIMToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO scim_tokens (org_id, token_hash) VALUES ($1, $2)
+			ON CONFLICT (org_id) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = NOW(), last_used_at = NULL
+		`, orgID, hashToken(token))
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create SCIM token: %w", err)
+	}
+
+	return token, nil
+}
+
+// RevokeToken deletes the organization's SCIM token, returning
+// pgx.ErrNoRows if it had none
+func (s *SCIMStore) RevokeToken(ctx context.Context, orgID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM scim_tokens WHERE org_id = $1`, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to revoke SCIM token: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// Authenticate returns the organization a SCIM token belongs to and
+// records its use. Unknown tokens return pgx.ErrNoRows.
+func (s *SCIMStore) Authenticate(ctx context.Context, token string) (uuid.UUID, error) {
+	if !strings.HasPrefix(token, scimTokenPrefix) {
+		return uuid.Nil, pgx.ErrNoRows
+	}
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (uuid.UUID, error) {
+		var orgID uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			UPDATE scim_tokens SET last_used_at = NOW()
+			WHERE token_hash = $1
+			RETURNING org_id
+		`, hashToken(token)).Scan(&orgID)
+		return orgID, err
+	})
+}
+
+// scimUsers selects the organization's SCIM users and pending
+// invitations as su
+const scimUsers = `(
+	SELECT su.org_id, su.user_id, u.email, u.display_name, su.external_id, su.active, FALSE AS pending, su.created_at, su.updated_at
+	FROM scim_users su JOIN users u ON u.id = su.user_id
+	UNION ALL
+	SELECT org_id, user_id, email, display_name, external_id, active, TRUE, created_at, updated_at
+	FROM scim_invitations
+) su`
+
+const scimUserColumns = `su.org_id, su.user_id, su.email, su.display_name, su.external_id, su.active, su.pending, su.created_at, su.updated_at`
+
+// scanSCIMUser reads a row selected with scimUserColumns
+func scanSCIMUser(row pgx.Row) (*SCIMUser, error) {
+	var user SCIMUser
+	if err := row.Scan(
+		&user.OrgID,
+		&user.UserID,
+		&user.Email,
+		&user.DisplayName,
+		&user.ExternalID,
+		&user.Active,
+		&user.Pending,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	user.Groups = []SCIMGroupRef{}
+	return &user, nil
+}
+
+// ListUsers returns a page of the organization's SCIM users, pending ones
+// included, in the order they were provisioned, with the total matching
+// the filter
+func (s *SCIMStore) ListUsers(ctx context.Context, orgID uuid.UUID, filter SCIMFilter, offset, limit int) ([]SCIMUser, int, error) {
+	where := `
+		FROM ` + scimUsers + `
+		WHERE su.org_id = $1
+			AND ($2 = '' OR LOWER(su.email) = LOWER($2))
+			AND ($3 = '' OR su.external_id = $3)`
+
+	var users []SCIMUser
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		if err := s.db.QueryRow(ctx, `SELECT COUNT(*) `+where, orgID, filter.Name, filter.ExternalID).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := s.db.Query(ctx, `
+			SELECT `+scimUserColumns+where+`
+			ORDER BY su.created_at, su.user_id
+			LIMIT $4 OFFSET $5
+		`, orgID, filter.Name, filter.ExternalID, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		users = []SCIMUser{}
+		for rows.Next() {
+			user, err := scanSCIMUser(rows)
+			if err != nil {
+				return err
+			}
+			users = append(users, *user)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return s.loadGroups(ctx, orgID, users)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM users: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// GetUser returns one of the organization's SCIM users, pending or not,
+// or pgx.ErrNoRows
+func (s *SCIMStore) GetUser(ctx context.Context, orgID, userID uuid.UUID) (*SCIMUser, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*SCIMUser, error) {
+		user, err := scanSCIMUser(s.db.QueryRow(ctx, `
+			SELECT `+scimUserColumns+`
+			FROM `+scimUsers+`
+			WHERE su.org_id = $1 AND su.user_id = $2
+			ORDER BY su.pending
+			LIMIT 1
+		`, orgID, userID))
+		if err != nil {
+			return nil, err
+		}
+		users := []SCIMUser{*user}
+		if err := s.loadGroups(ctx, orgID, users); err != nil {
+			return nil, err
+		}
+		return &users[0], nil
+	})
+}
+
+// loadGroups fills in the groups of users
+func (s *SCIMStore) loadGroups(ctx context.Context, orgID uuid.UUID, users []SCIMUser) error {
+	if len(users) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(users))
+	index := make(map[uuid.UUID]int, len(users))
+	for i, user := range users {
+		ids[i] = user.UserID
+		index[user.UserID] = i
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT m.user_id, g.id, g.display_name
+		FROM scim_group_members m JOIN scim_groups g ON g.id = m.group_id
+		WHERE g.org_id = $1 AND m.user_id = ANY($2)
+		ORDER BY g.display_name
+	`, orgID, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID uuid.UUID
+		var ref SCIMGroupRef
+		if err := rows.Scan(&userID, &ref.ID, &ref.DisplayName); err != nil {
+			return err
+		}
+		users[index[userID]].Groups = append(users[index[userID]].Groups, ref)
+	}
+	return rows.Err()
+}
+
+// SetUser marks the user as managed by the organization's identity
+// provider, or updates their external ID and whether they are active.
+// A pending user's invitation is updated instead.
+func (s *SCIMStore) SetUser(ctx context.Context, orgID, userID uuid.UUID, externalID string, active bool) (*SCIMUser, error) {
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE scim_invitations SET external_id = $3, active = $4, updated_at = NOW()
+			WHERE org_id = $1 AND user_id = $2
+		`, orgID, userID, externalID, active)
+		if err != nil || tag.RowsAffected() > 0 {
+			return err
+		}
+
+		_, err = s.db.Exec(ctx, `
+			INSERT INTO scim_users (org_id, user_id, external_id, active) VALUES ($1, $2, $3, $4)
+			ON CONFLICT (org_id, user_id) DO UPDATE SET
+				external_id = EXCLUDED.external_id,
+				active = EXCLUDED.active,
+				updated_at = NOW()
+		`, orgID, userID, externalID, active)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save SCIM user: %w", err)
+	}
+
+	return s.GetUser(ctx, orgID, userID)
+}
+
+// DeleteUser stops the organization's identity provider managing the
+// user and takes them out of its groups, or withdraws a pending user's
+// invitation. The account itself is kept.
+func (s *SCIMStore) DeleteUser(ctx context.Context, orgID, userID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		invited, err := tx.Exec(ctx, `DELETE FROM scim_invitations WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete SCIM invitation: %w", err)
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM scim_users WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete SCIM user: %w", err)
+		}
+		if tag.RowsAffected()+invited.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM scim_group_members
+			WHERE user_id = $2 AND group_id IN (SELECT id FROM scim_groups WHERE org_id = $1)
+		`, orgID, userID); err != nil {
+			return fmt.Errorf("failed to delete SCIM group memberships: %w", err)
+		}
+
+		return tx.Commit(ctx)
+	})
+}
+
+// reservedUserID is the user ID an invitation reserved for the email
+// address in param, if no account has it yet, or a new one
+func reservedUserID(param string) string {
+	return `COALESCE((
+		SELECT si.user_id FROM scim_invitations si
+		WHERE si.email = LOWER(` + param + `) AND NOT EXISTS (SELECT 1 FROM users WHERE id = si.user_id)
+		LIMIT 1
+	), gen_random_uuid())`
+}
+
+// Invite records a pending user for an email address that has no
+// account. Their ID is the one other invitations reserved for the
+// address, or a new one, and their account is given it when they sign
+// up. Inviting an address twice fails with a unique violation.
+func (s *SCIMStore) Invite(ctx context.Context, orgID uuid.UUID, email string, displayName *string, externalID string, active bool) (*SCIMUser, error) {
+	user, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*SCIMUser, error) {
+		return scanSCIMUser(s.db.QueryRow(ctx, `
+			INSERT INTO scim_invitations (org_id, user_id, email, display_name, external_id, active)
+			VALUES ($1, `+reservedUserID("$2")+`, LOWER($2), $3, $4, $5)
+			RETURNING org_id, user_id, email, display_name, external_id, active, TRUE, created_at, updated_at
+		`, orgID, email, displayName, externalID, active))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SCIM invitation: %w", err)
+	}
+	return user, nil
+}
+
+// RenameInvitation sets the display name a pending user's account gets;
+// nil clears it. It returns pgx.ErrNoRows if there is no such invitation.
+func (s *SCIMStore) RenameInvitation(ctx context.Context, orgID, userID uuid.UUID, displayName *string) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE scim_invitations SET display_name = $3, updated_at = NOW()
+			WHERE org_id = $1 AND user_id = $2
+		`, orgID, userID, displayName)
+		if err != nil {
+			return fmt.Errorf("failed to rename SCIM invitation: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// AcceptInvitations turns the invitations for the user's email address
+// into SCIM users of the organizations that sent them, once the user has
+// proven the address is theirs. The account takes an invitation's display
+// name if it has none. It returns the users, for the caller to make
+// members.
+func (s *SCIMStore) AcceptInvitations(ctx context.Context, userID uuid.UUID) ([]SCIMUser, error) {
+	orgIDs, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) ([]uuid.UUID, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		rows, err := tx.Query(ctx, `
+			DELETE FROM scim_invitations si USING users u
+			WHERE u.id = $1 AND si.email = LOWER(u.email)
+			RETURNING si.org_id, si.external_id, si.active, si.display_name
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		type invitation struct {
+			orgID       uuid.UUID
+			externalID  string
+			active      bool
+			displayName *string
+		}
+		var invitations []invitation
+		for rows.Next() {
+			var inv invitation
+			if err := rows.Scan(&inv.orgID, &inv.externalID, &inv.active, &inv.displayName); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			invitations = append(invitations, inv)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		orgIDs := make([]uuid.UUID, 0, len(invitations))
+		for _, inv := range invitations {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO scim_users (org_id, user_id, external_id, active) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (org_id, user_id) DO NOTHING
+			`, inv.orgID, userID, inv.externalID, inv.active); err != nil {
+				return nil, err
+			}
+			if inv.displayName != nil {
+				if _, err := tx.Exec(ctx, `
+					UPDATE users SET display_name = $2, version = version + 1
+					WHERE id = $1 AND display_name IS NULL
+				`, userID, inv.displayName); err != nil {
+					return nil, err
+				}
+			}
+			orgIDs = append(orgIDs, inv.orgID)
+		}
+
+		return orgIDs, tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept SCIM invitations: %w", err)
+	}
+
+	users := make([]SCIMUser, 0, len(orgIDs))
+	for _, orgID := range orgIDs {
+		user, err := s.GetUser(ctx, orgID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get SCIM user: %w", err)
+		}
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+const scimGroupColumns = `id, org_id, display_name, external_id, created_at, updated_at`
+
+// scanSCIMGroup reads a row selected with scimGroupColumns
+func scanSCIMGroup(row pgx.Row) (*SCIMGroup, error) {
+	var group SCIMGroup
+	if err := row.Scan(
+		&group.ID,
+		&group.OrgID,
+		&group.DisplayName,
+		&group.ExternalID,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	group.Members = []SCIMGroupMember{}
+	return &group, nil
+}
+
+// ListGroups returns a page of the organization's SCIM groups by name,
+// with the total matching the filter
+func (s *SCIMStore) ListGroups(ctx context.Context, orgID uuid.UUID, filter SCIMFilter, offset, limit int) ([]SCIMGroup, int, error) {
+	where := `
+		FROM scim_groups
+		WHERE org_id = $1
+			AND ($2 = '' OR display_name = $2)
+			AND ($3 = '' OR external_id = $3)`
+
+	var groups []SCIMGroup
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		if err := s.db.QueryRow(ctx, `SELECT COUNT(*) `+where, orgID, filter.Name, filter.ExternalID).Scan(&total); err != nil {
+			return err
+		}
+
+		rows, err := s.db.Query(ctx, `
+			SELECT `+scimGroupColumns+where+`
+			ORDER BY display_name
+			LIMIT $4 OFFSET $5
+		`, orgID, filter.Name, filter.ExternalID, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		groups = []SCIMGroup{}
+		for rows.Next() {
+			group, err := scanSCIMGroup(rows)
+			if err != nil {
+				return err
+			}
+			groups = append(groups, *group)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		return s.loadMembers(ctx, groups)
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list SCIM groups: %w", err)
+	}
+
+	return groups, total, nil
+}
+
+// GetGroup returns one of the organization's SCIM groups, or
+// pgx.ErrNoRows
+func (s *SCIMStore) GetGroup(ctx context.Context, orgID, id uuid.UUID) (*SCIMGroup, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*SCIMGroup, error) {
+		group, err := scanSCIMGroup(s.db.QueryRow(ctx, `SELECT `+scimGroupColumns+` FROM scim_groups WHERE org_id = $1 AND id = $2`, orgID, id))
+		if err != nil {
+			return nil, err
+		}
+		groups := []SCIMGroup{*group}
+		if err := s.loadMembers(ctx, groups); err != nil {
+			return nil, err
+		}
+		return &groups[0], nil
+	})
+}
+
+// loadMembers fills in the members of groups
+func (s *SCIMStore) loadMembers(ctx context.Context, groups []SCIMGroup) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(groups))
+	index := make(map[uuid.UUID]int, len(groups))
+	for i, group := range groups {
+		ids[i] = group.ID
+		index[group.ID] = i
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT m.group_id, m.user_id, u.email
+		FROM scim_group_members m JOIN users u ON u.id = m.user_id
+		WHERE m.group_id = ANY($1)
+		ORDER BY u.email
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID uuid.UUID
+		var member SCIMGroupMember
+		if err := rows.Scan(&groupID, &member.UserID, &member.Email); err != nil {
+			return err
+		}
+		groups[index[groupID]].Members = append(groups[index[groupID]].Members, member)
+	}
+	return rows.Err()
+}
+
+// SaveGroup creates a group, when group.ID is uuid.Nil, or replaces one.
+// Members that aren't the organization's SCIM users are left out. A
+// duplicate name returns the unique violation.
+func (s *SCIMStore) SaveGroup(ctx context.Context, group *SCIMGroup) (*SCIMGroup, error) {
+	members := make([]uuid.UUID, len(group.Members))
+	for i, m := range group.Members {
+		members[i] = m.UserID
+	}
+
+	id, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (uuid.UUID, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		id := group.ID
+		if id == uuid.Nil {
+			err = tx.QueryRow(ctx, `
+				INSERT INTO scim_groups (org_id, display_name, external_id) VALUES ($1, $2, $3)
+				RETURNING id
+			`, group.OrgID, group.DisplayName, group.ExternalID).Scan(&id)
+		} else {
+			err = tx.QueryRow(ctx, `
+				UPDATE scim_groups SET display_name = $3, external_id = $4, updated_at = NOW()
+				WHERE org_id = $1 AND id = $2
+				RETURNING id
+			`, group.OrgID, id, group.DisplayName, group.ExternalID).Scan(&id)
+		}
+		if err != nil {
+			return uuid.Nil, err
+		}
+
+		if _, err := tx.Exec(ctx, `DELETE FROM scim_group_members WHERE group_id = $1`, id); err != nil {
+			return uuid.Nil, err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO scim_group_members (group_id, user_id)
+			SELECT $1, user_id FROM scim_users WHERE org_id = $2 AND user_id = ANY($3)
+		`, id, group.OrgID, members); err != nil {
+			return uuid.Nil, err
+		}
+
+		return id, tx.Commit(ctx)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save SCIM group: %w", err)
+	}
+
+	return s.GetGroup(ctx, group.OrgID, id)
+}
+
+// DeleteGroup deletes one of the organization's SCIM groups, returning
+// pgx.ErrNoRows if there is no such group
+func (s *SCIMStore) DeleteGroup(ctx context.Context, orgID, id uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM scim_groups WHERE org_id = $1 AND id = $2`, orgID, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete SCIM group: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// OrgSpend is an organization's spend budget and what its members have
+// spent against it, in millionths of a US dollar
+type OrgSpend struct {
+	OrgID   uuid.UUID
+	OrgName string
+	SpendBudget
+	DailyMicros   int64
+	MonthlyMicros int64
+}
+
+// SpendStore reads model spend straight from the analyses and thread
+// replies that recorded it, so budgets see it before the usage rollups do
+type SpendStore struct {
+	db *pgxpool.Pool
+}
+
+// NewSpendStore creates a new spend store
+func NewSpendStore(db *pgxpool.Pool) *SpendStore {
+	return &SpendStore{db: db}
+}
+
+// Total returns what every user has spent since day and since month. day
+// must not be before month.
+func (s *SpendStore) Total(ctx context.Context, day, month time.Time) (daily, monthly int64, err error) {
+	query := `
+		SELECT COALESCE(SUM(cost_micros) FILTER (WHERE created_at >= $1), 0), COALESCE(SUM(cost_micros), 0)
+		FROM (
+			SELECT created_at, cost_micros FROM analyses WHERE created_at >= $2
+			UNION ALL
+			SELECT created_at, cost_micros FROM thread_messages WHERE created_at >= $2 AND cost_micros > 0
+		) spend
+	`
+
+	err = resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, query, day, month).Scan(&daily, &monthly)
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read total spend: %w", err)
+	}
+	return daily, monthly, nil
+}
+
+// ForUser returns what the members of each organization userID belongs to
+// have spent since day and since month, for organizations with a spend
+// budget. day must not be before month.
+func (s *SpendStore) ForUser(ctx context.Context, userID uuid.UUID, day, month time.Time) ([]OrgSpend, error) {
+	query := `
+		SELECT o.id, o.name, o.daily_budget_micros, o.monthly_budget_micros,
+			COALESCE(SUM(spend.cost_micros) FILTER (WHERE spend.created_at >= $2), 0),
+			COALESCE(SUM(spend.cost_micros), 0)
+		FROM organization_members me
+		JOIN organizations o ON o.id = me.org_id
+		JOIN organization_members m ON m.org_id = o.id
+		LEFT JOIN LATERAL (
+			SELECT a.created_at, a.cost_micros
+			FROM analyses a
+			JOIN submissions s ON s.id = a.submission_id
+			WHERE s.user_id = m.user_id AND a.created_at >= $3
+			UNION ALL
+			SELECT tm.created_at, tm.cost_micros
+			FROM thread_messages tm
+			JOIN threads t ON t.id = tm.thread_id
+			WHERE t.user_id = m.user_id AND tm.created_at >= $3 AND tm.cost_micros > 0
+		) spend ON TRUE
+		WHERE me.user_id = $1 AND (o.daily_budget_micros IS NOT NULL OR o.monthly_budget_micros IS NOT NULL)
+		GROUP BY o.id, o.name, o.daily_budget_micros, o.monthly_budget_micros
+		ORDER BY o.name, o.id
+	`
+
+	orgs, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]OrgSpend, error) {
+		rows, err := s.db.Query(ctx, query, userID, day, month)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var orgs []OrgSpend
+		for rows.Next() {
+			var o OrgSpend
+			if err := rows.Scan(&o.OrgID, &o.OrgName, &o.DailyBudgetMicros, &o.MonthlyBudgetMicros, &o.DailyMicros, &o.MonthlyMicros); err != nil {
+				return nil, err
+			}
+			orgs = append(orgs, o)
+		}
+		return orgs, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organization spend: %w", err)
+	}
+
+	return orgs, nil
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// SSOProtocol is how an organization's identity provider signs users in
+type SSOProtocol string
+
+// SSOOIDC is OpenID Connect's authorization code flow, the only protocol
+// supported so far
+const SSOOIDC SSOProtocol = "oidc"
+
+// MaxSSORoleMappings bounds an organization's group to role mappings
+const MaxSSORoleMappings = 50
+
+// SSORoleMapping gives members of an identity provider group a role
+type SSORoleMapping struct {
+	Group string  `json:"group"`
+	Role  OrgRole `json:"role"`
+}
+
+// SSOConfig is an organization's single sign-on setup. Members signing in
+// through it are given the role of the first mapping whose group they are
+// in, or DefaultRole.
+type SSOConfig struct {
+	OrgID    uuid.UUID   `json:"org_id"`
+	Protocol SSOProtocol `json:"protocol"`
+	// Issuer is the identity provider's issuer URL, where its discovery
+	// document is found
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"client_id"`
+	// ClientSecret is write-only; it is never returned
+	ClientSecret string `json:"-"`
+	// GroupsClaim names the ID token claim listing the user's groups
+	GroupsClaim  string           `json:"groups_claim"`
+	RoleMappings []SSORoleMapping `json:"role_mappings"`
+	DefaultRole  OrgRole          `json:"default_role"`
+	// Enforced turns off password sign-in for the organization's members,
+	// except owners, who keep it to fix a broken setup
+	Enforced  bool           `json:"enforced"`
+	CreatedAt timestamp.Time `json:"created_at"`
+	UpdatedAt timestamp.Time `json:"updated_at"`
+}
+
+// Validate checks the configuration. Roles from the identity provider
+// stop short of owner, which is only granted in the app.
+func (c *SSOConfig) Validate() error {
+	if c.Protocol != SSOOIDC {
+		return fmt.Errorf("protocol must be %s", SSOOIDC)
+	}
+	u, err := url.Parse(c.Issuer)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return errors.New("issuer must be an https URL")
+	}
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return errors.New("client_id and client_secret are required")
+	}
+	if c.GroupsClaim == "" || len(c.GroupsClaim) > 100 {
+		return errors.New("groups_claim must be 1 to 100 characters")
+	}
+	if len(c.RoleMappings) > MaxSSORoleMappings {
+		return fmt.Errorf("at most %d role mappings are allowed", MaxSSORoleMappings)
+	}
+	for _, m := range c.RoleMappings {
+		if strings.TrimSpace(m.Group) == "" {
+			return errors.New("role mappings need a group")
+		}
+		if m.Role != RoleAdmin && m.Role != RoleMember {
+			return errors.New("role mappings may only grant admin or member")
+		}
+	}
+	if c.DefaultRole != RoleAdmin && c.DefaultRole != RoleMember {
+		return errors.New("default_role must be admin or member")
+	}
+	return nil
+}
+
+// RoleFor returns the role for a member of groups
+func (c *SSOConfig) RoleFor(groups []string) OrgRole {
+	for _, m := range c.RoleMappings {
+		for _, g := range groups {
+			if g == m.Group {
+				return m.Role
+			}
+		}
+	}
+	return c.DefaultRole
+}
+
+// SSOStore persists organizations' single sign-on setups and the identity
+// provider users linked to them
+type SSOStore struct {
+	db     *pgxpool.Pool
+	cipher *encryption.Encryptor
+}
+
+// NewSSOStore creates a new SSO store
+func NewSSOStore(db *pgxpool.Pool) *SSOStore {
+	return &SSOStore{db: db}
+}
+
+// WithEncryption encrypts client secrets at rest and returns the store
+func (s *SSOStore) WithEncryption(cipher *encryption.Encryptor) *SSOStore {
+	s.cipher = cipher
+	return s
+}
+
+const ssoColumns = `org_id, protocol, issuer, client_id, client_secret, groups_claim, role_mappings, default_role, enforced, created_at, updated_at`
+
+// scanSSOConfig reads a row selected with ssoColumns, decrypting the
+// client secret
+func (s *SSOStore) scanSSOConfig(ctx context.Context, row pgx.Row) (*SSOConfig, error) {
+	var c SSOConfig
+	var mappings []byte
+	if err := row.Scan(&c.OrgID, &c.Protocol, &c.Issuer, &c.ClientID, &c.ClientSecret, &c.GroupsClaim, &mappings, &c.DefaultRole, &c.Enforced, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(mappings, &c.RoleMappings); err != nil {
+		return nil, fmt.Errorf("failed to decode role mappings: %w", err)
+	}
+
+	secret, err := openField(ctx, s.cipher, c.ClientSecret, record{id: c.OrgID}.bind(fieldSSOClientSecret))
+	if err != nil {
+		return nil, err
+	}
+	c.ClientSecret = secret
+
+	return &c, nil
+}
+
+// Get returns an organization's SSO setup, or pgx.ErrNoRows if it has none
+func (s *SSOStore) Get(ctx context.Context, orgID uuid.UUID) (*SSOConfig, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*SSOConfig, error) {
+		return s.scanSSOConfig(ctx, s.db.QueryRow(ctx, `SELECT `+ssoColumns+` FROM organization_sso WHERE org_id = $1`, orgID))
+	})
+}
+
+// Set creates or replaces an organization's SSO setup
+func (s *SSOStore) Set(ctx context.Context, config *SSOConfig) (*SSOConfig, error) {
+	mappings, err := json.Marshal(config.RoleMappings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode role mappings: %w", err)
+	}
+	secret, err := sealField(ctx, s.cipher, config.ClientSecret, record{id: config.OrgID}.bind(fieldSSOClientSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO organization_sso (org_id, protocol, issuer, client_id, client_secret, groups_claim, role_mappings, default_role, enforced)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (org_id) DO UPDATE SET
+			protocol = EXCLUDED.protocol,
+			issuer = EXCLUDED.issuer,
+			client_id = EXCLUDED.client_id,
+			client_secret = EXCLUDED.client_secret,
+			groups_claim = EXCLUDED.groups_claim,
+			role_mappings = EXCLUDED.role_mappings,
+			default_role = EXCLUDED.default_role,
+			enforced = EXCLUDED.enforced,
+			updated_at = NOW()
+		RETURNING ` + ssoColumns
+
+	saved, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*SSOConfig, error) {
+		return s.scanSSOConfig(ctx, s.db.QueryRow(ctx, query,
+			config.OrgID, config.Protocol, config.Issuer, config.ClientID, secret,
+			config.GroupsClaim, mappings, config.DefaultRole, config.Enforced,
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save SSO configuration: %w", err)
+	}
+
+	return saved, nil
+}
+
+// Delete removes an organization's SSO setup and the identities linked
+// through it. It returns pgx.ErrNoRows if there was none.
+func (s *SSOStore) Delete(ctx context.Context, orgID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		tag, err := tx.Exec(ctx, `DELETE FROM organization_sso WHERE org_id = $1`, orgID)
+		if err != nil {
+			return fmt.Errorf("failed to delete SSO configuration: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM sso_identities WHERE org_id = $1`, orgID); err != nil {
+			return fmt.Errorf("failed to delete SSO identities: %w", err)
+		}
+
+		return tx.Commit(ctx)
+	})
+}
+
+// FindIdentity returns the user an identity provider subject is linked
+// to, or pgx.ErrNoRows if it isn't linked yet
+func (s *SSOStore) FindIdentity(ctx context.Context, orgID uuid.UUID, subject string) (uuid.UUID, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (uuid.UUID, error) {
+		var userID uuid.UUID
+		err := s.db.QueryRow(ctx, `SELECT user_id FROM sso_identities WHERE org_id = $1 AND subject = $2`, orgID, subject).Scan(&userID)
+		return userID, err
+	})
+}
+
+// LinkIdentity links an identity provider subject to a user
+func (s *SSOStore) LinkIdentity(ctx context.Context, orgID uuid.UUID, subject string, userID uuid.UUID) error {
+	// Linking again is a no-op, so retrying is harmless
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO sso_identities (org_id, subject, user_id) VALUES ($1, $2, $3)
+			ON CONFLICT (org_id, subject) DO NOTHING
+		`, orgID, subject, userID)
+		if err != nil {
+			return fmt.Errorf("failed to link SSO identity: %w", err)
+		}
+		return nil
+	})
+}
+
+// Enforced returns an organization of the user's that requires single
+// sign-on, and false if none does. Owners are exempt.
+func (s *SSOStore) Enforced(ctx context.Context, userID uuid.UUID) (uuid.UUID, bool, error) {
+	orgID, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (uuid.UUID, error) {
+		var orgID uuid.UUID
+		err := s.db.QueryRow(ctx, `
+			SELECT c.org_id
+			FROM organization_sso c
+			JOIN organization_members m ON m.org_id = c.org_id
+			WHERE m.user_id = $1 AND m.role <> 'owner' AND c.enforced
+			ORDER BY c.created_at
+			LIMIT 1
+		`, userID).Scan(&orgID)
+		return orgID, err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, false, nil
+	}
+	if err != nil {
+		return uuid.Nil, false, fmt.Errorf("failed to check SSO enforcement: %w", err)
+	}
+	return orgID, true, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import "testing"
+
+func TestSSOConfig_Validate(t *testing.T) {
+	valid := func() *SSOConfig {
+		return &SSOConfig{
+			Protocol:     SSOOIDC,
+			Issuer:       "https://login.example.com/tenant",
+			ClientID:     "content-analyzer",
+			ClientSecret: "secret",
+			GroupsClaim:  "groups",
+			RoleMappings: []SSORoleMapping{{Group: "admins", Role: RoleAdmin}},
+			DefaultRole:  RoleMember,
+		}
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(c *SSOConfig)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(c *SSOConfig) {}},
+		{name: "saml", modify: func(c *SSOConfig) { c.Protocol = "saml" }, wantErr: true},
+		{name: "plain http issuer", modify: func(c *SSOConfig) { c.Issuer = "http://login.example.com" }, wantErr: true},
+		{name: "issuer with query", modify: func(c *SSOConfig) { c.Issuer = "https://login.example.com?tenant=1" }, wantErr: true},
+		{name: "missing secret", modify: func(c *SSOConfig) { c.ClientSecret = "" }, wantErr: true},
+		{name: "missing groups claim", modify: func(c *SSOConfig) { c.GroupsClaim = "" }, wantErr: true},
+		{name: "mapping grants owner", modify: func(c *SSOConfig) { c.RoleMappings[0].Role = RoleOwner }, wantErr: true},
+		{name: "mapping without group", modify: func(c *SSOConfig) { c.RoleMappings[0].Group = " " }, wantErr: true},
+		{name: "default owner", modify: func(c *SSOConfig) { c.DefaultRole = RoleOwner }, wantErr: true},
+		{name: "too many mappings", modify: func(c *SSOConfig) {
+			c.RoleMappings = make([]SSORoleMapping, MaxSSORoleMappings+1)
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := valid()
+			tt.modify(config)
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSSOConfig_RoleFor(t *testing.T) {
+	config := &SSOConfig{
+		RoleMappings: []SSORoleMapping{
+			{Group: "admins", Role: RoleAdmin},
+			{Group: "staff", Role: RoleMember},
+		},
+		DefaultRole: RoleMember,
+	}
+
+	tests := []struct {
+		groups []string
+		want   OrgRole
+	}{
+		{groups: nil, want: RoleMember},
+		{groups: []string{"staff", "admins"}, want: RoleAdmin},
+		{groups: []string{"other"}, want: RoleMember},
+	}
+
+	for _, tt := range tests {
+		if got := config.RoleFor(tt.groups); got != tt.want {
+			t.Errorf("RoleFor(%v) = %s, want %s", tt.groups, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// SubmissionStatus is the lifecycle state of a submission
+type SubmissionStatus string
+
+const (
+	StatusDraft      SubmissionStatus = "draft"
+	StatusQueued     SubmissionStatus = "queued"
+	StatusProcessing SubmissionStatus = "processing"
+	StatusCompleted  SubmissionStatus = "completed"
+	StatusFailed     SubmissionStatus = "failed"
+	StatusCanceled   SubmissionStatus = "canceled"
+	StatusArchived   SubmissionStatus = "archived"
+)
+
+// FailureParseError is the FailureReason of an analysis whose model output
+// couldn't be parsed, even after repair
+const FailureParseError = "parse_error"
+
+// FailureTimedOut is the FailureReason of an analysis that kept running
+// out of time before its main analysis was done
+const FailureTimedOut = "timed_out"
+
+// transitions lists the statuses each status may move to:
+//
+//	draft → queued → processing → completed/failed → archived
+//
+// A queued submission can also fail if it never reaches the worker, and
+// the user can cancel an analysis that is queued or processing.
+var transitions = map[SubmissionStatus][]SubmissionStatus{
+	StatusDraft:      {StatusQueued},
+	StatusQueued:     {StatusProcessing, StatusFailed, StatusCanceled},
+	StatusProcessing: {StatusCompleted, StatusFailed, StatusCanceled},
+	StatusCompleted:  {StatusArchived},
+	StatusFailed:     {StatusArchived},
+	StatusCanceled:   {StatusArchived},
+}
+
+// statusTimestampColumns records when a submission entered each status.
+// Drafts use created_at.
+var statusTimestampColumns = map[SubmissionStatus]string{
+	StatusQueued:     "queued_at",
+	StatusProcessing: "processing_at",
+	StatusCompleted:  "completed_at",
+	StatusFailed:     "failed_at",
+	StatusCanceled:   "canceled_at",
+	StatusArchived:   "archived_at",
+}
+
+// ErrInvalidTransition is returned when a status change isn't allowed
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// TransitionError describes a rejected status change
+type TransitionError struct {
+	From SubmissionStatus
+	To   SubmissionStatus
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot move submission from %s to %s", e.From, e.To)
+}
+
+// Is makes errors.Is(err, ErrInvalidTransition) match
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// Valid reports whether s is a known status
+func (s SubmissionStatus) Valid() bool {
+	_, ok := transitions[s]
+	return ok || s == StatusArchived
+}
+
+// CanTransitionTo reports whether a submission may move from s to next
+func (s SubmissionStatus) CanTransitionTo(next SubmissionStatus) bool {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// Terminal reports whether no further processing will happen
+func (s SubmissionStatus) Terminal() bool {
+	return s == StatusCompleted || s == StatusFailed || s == StatusCanceled || s == StatusArchived
+}
+
+// sourcesOf returns the statuses that may move to next
+func sourcesOf(next SubmissionStatus) []string {
+	var sources []string
+	for from := range transitions {
+		if from.CanTransitionTo(next) {
+			sources = append(sources, string(from))
+		}
+	}
+	return sources
+}
+
+// StatusChange is emitted whenever a submission enters a new status.
+// From is empty when the submission was just created.
+type StatusChange struct {
+	SubmissionID uuid.UUID        `json:"submission_id"`
+	UserID       uuid.UUID        `json:"user_id"`
+	From         SubmissionStatus `json:"from,omitempty"`
+	To           SubmissionStatus `json:"to"`
+	At           timestamp.Time   `json:"at"`
+}
+
+// StatusListener is told about status changes after they are committed
+type StatusListener interface {
+	StatusChanged(ctx context.Context, change StatusChange)
+}
+
+// SetStatus records that the submission entered status at the given time.
+// It doesn't check the transition; stores do that before calling it.
+func (s *Submission) SetStatus(status SubmissionStatus, at time.Time) {
+	s.Status = status
+	stamp := timestamp.New(at)
+
+	switch status {
+	case StatusQueued:
+		s.QueuedAt = &stamp
+	case StatusProcessing:
+		s.ProcessingAt = &stamp
+	case StatusCompleted:
+		s.CompletedAt = &stamp
+	case StatusFailed:
+		s.FailedAt = &stamp
+	case StatusCanceled:
+		s.CanceledAt = &stamp
+	case StatusArchived:
+		s.ArchivedAt = &stamp
+	}
+}
+
+// History returns the status changes the submission went through, oldest
+// first, from the time it entered each status. Only the latest entry into
+// a status is kept, and a submission that has left draft doesn't record
+// when it was created as one, so a draft appears only while it is one.
+func (s *Submission) History() []StatusChange {
+	entered := []struct {
+		status SubmissionStatus
+		at     *timestamp.Time
+	}{
+		{StatusQueued, s.QueuedAt},
+		{StatusProcessing, s.ProcessingAt},
+		{StatusCompleted, s.CompletedAt},
+		{StatusFailed, s.FailedAt},
+		{StatusCanceled, s.CanceledAt},
+		{StatusArchived, s.ArchivedAt},
+	}
+
+	var history []StatusChange
+	if s.Status == StatusDraft {
+		history = append(history, StatusChange{To: StatusDraft, At: s.CreatedAt})
+	}
+	for _, e := range entered {
+		if e.at != nil {
+			history = append(history, StatusChange{To: e.status, At: *e.at})
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		return history[i].At.Before(history[j].At.Time)
+	})
+
+	for i := range history {
+		history[i].SubmissionID, history[i].UserID = s.ID, s.UserID
+		if i > 0 {
+			history[i].From = history[i-1].To
+		}
+	}
+	return history
+}
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+func TestSubmissionStatus_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from SubmissionStatus
+		to   SubmissionStatus
+		want bool
+	}{
+		{StatusDraft, StatusQueued, true},
+		{StatusQueued, StatusProcessing, true},
+		{StatusQueued, StatusFailed, true},
+		{StatusProcessing, StatusCompleted, true},
+		{StatusProcessing, StatusFailed, true},
+		{StatusCompleted, StatusArchived, true},
+		{StatusFailed, StatusArchived, true},
+		{StatusQueued, StatusCanceled, true},
+		{StatusProcessing, StatusCanceled, true},
+		{StatusCanceled, StatusArchived, true},
+
+		{StatusDraft, StatusCanceled, false},
+		{StatusCompleted, StatusCanceled, false},
+		{StatusCanceled, StatusQueued, false},
+		{StatusDraft, StatusProcessing, false},
+		{StatusDraft, StatusArchived, false},
+		{StatusQueued, StatusCompleted, false},
+		{StatusProcessing, StatusQueued, false},
+		{StatusCompleted, StatusProcessing, false},
+		{StatusArchived, StatusQueued, false},
+		{StatusArchived, StatusArchived, false},
+		{SubmissionStatus("pending"), StatusQueued, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s to %s", tt.from, tt.to), func(t *testing.T) {
+			if got := tt.from.CanTransitionTo(tt.to); got != tt.want {
+				t.Errorf("%s.CanTransitionTo(%s) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmissionStatus_Valid(t *testing.T) {
+	for _, s := range []SubmissionStatus{StatusDraft, StatusQueued, StatusProcessing, StatusCompleted, StatusFailed, StatusCanceled, StatusArchived} {
+		if !s.Valid() {
+			t.Errorf("%s.Valid() = false, want true", s)
+		}
+		if _, ok := statusTimestampColumns[s]; !ok && s != StatusDraft {
+			t.Errorf("status %s has no timestamp column", s)
+		}
+	}
+
+	if SubmissionStatus("pending").Valid() {
+		t.Error(`"pending".Valid() = true, want false`)
+	}
+}
+
+func TestSourcesOf(t *testing.T) {
+	got := sourcesOf(StatusArchived)
+	sort.Strings(got)
+
+	if len(got) != 3 || got[0] != "canceled" || got[1] != "completed" || got[2] != "failed" {
+		t.Errorf("sourcesOf(archived) = %v, want [canceled completed failed]", got)
+	}
+}
+
+func TestTransitionError(t *testing.T) {
+	var err error = fmt.Errorf("wrapped: %w", &TransitionError{From: StatusDraft, To: StatusCompleted})
+
+	if !errors.Is(err, ErrInvalidTransition) {
+		t.Error("errors.Is(TransitionError, ErrInvalidTransition) = false, want true")
+	}
+
+	var te *TransitionError
+	if !errors.As(err, &te) || te.From != StatusDraft || te.To != StatusCompleted {
+		t.Errorf("errors.As() = %+v, want draft to completed", te)
+	}
+}
+
+func TestSubmission_History(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	draft := &Submission{Status: StatusDraft, CreatedAt: timestamp.New(start)}
+	if got := draft.History(); len(got) != 1 || got[0].To != StatusDraft || got[0].From != "" {
+		t.Errorf("draft History() = %+v, want one draft entry", got)
+	}
+
+	submission := &Submission{Status: StatusDraft, CreatedAt: timestamp.New(start)}
+	submission.SetStatus(StatusQueued, start.Add(time.Minute))
+	submission.SetStatus(StatusProcessing, start.Add(2*time.Minute))
+	submission.SetStatus(StatusCanceled, start.Add(3*time.Minute))
+
+	got := submission.History()
+	want := []StatusChange{
+		{To: StatusQueued, At: timestamp.New(start.Add(time.Minute))},
+		{From: StatusQueued, To: StatusProcessing, At: timestamp.New(start.Add(2 * time.Minute))},
+		{From: StatusProcessing, To: StatusCanceled, At: timestamp.New(start.Add(3 * time.Minute))},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("History() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].From != want[i].From || got[i].To != want[i].To || !got[i].At.Equal(want[i].At.Time) {
+			t.Errorf("History()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
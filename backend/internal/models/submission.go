@@ -0,0 +1,1317 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Submission represents content submitted for analysis
+type Submission struct {
+	ID      uuid.UUID `json:"id"`
+	UserID  uuid.UUID `json:"user_id"`
+	Content string    `json:"content"`
+	// RedactedContent is a masked copy for display, set when redaction was requested
+	RedactedContent *string          `json:"redacted_content,omitempty"`
+	Status          SubmissionStatus `json:"status"`
+	Version         int              `json:"version"`
+	CreatedAt       timestamp.Time   `json:"created_at"`
+	// UpdatedAt is when the row last changed in any way
+	UpdatedAt timestamp.Time `json:"updated_at"`
+
+	// Instructions steer the analysis, such as "focus on legal risk", and
+	// ProfileID selects its prompt template and modules
+	Instructions *string    `json:"instructions,omitempty"`
+	ProfileID    *uuid.UUID `json:"profile_id,omitempty"`
+
+	// Revised versions of a document point at the revision they replace;
+	// Revision counts from 1 for the original
+	PreviousID *uuid.UUID `json:"previous_id,omitempty"`
+	Revision   int        `json:"revision"`
+
+	// The teammate reviewing the submission, when it is due, and how far
+	// the review has come
+	AssigneeID     *uuid.UUID      `json:"assignee_id,omitempty"`
+	DueAt          *timestamp.Time `json:"due_at,omitempty"`
+	WorkflowStatus WorkflowStatus  `json:"workflow_status"`
+
+	// When the submission entered each status, if it has
+	QueuedAt     *timestamp.Time `json:"queued_at,omitempty"`
+	ProcessingAt *timestamp.Time `json:"processing_at,omitempty"`
+	CompletedAt  *timestamp.Time `json:"completed_at,omitempty"`
+	FailedAt     *timestamp.Time `json:"failed_at,omitempty"`
+	CanceledAt   *timestamp.Time `json:"canceled_at,omitempty"`
+	ArchivedAt   *timestamp.Time `json:"archived_at,omitempty"`
+
+	// FailureReason says why a failed analysis failed, when it is known
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	// When and why the submission was quarantined, while it is; quarantined
+	// submissions are left out of listings until an operator releases them
+	QuarantinedAt    *timestamp.Time `json:"quarantined_at,omitempty"`
+	QuarantineReason *string         `json:"quarantine_reason,omitempty"`
+
+	// DeletedAt is when the submission was moved to the trash; trashed
+	// submissions are hidden from their owner until restored, and purged
+	// once they have been in the trash longer than its retention
+	DeletedAt *timestamp.Time `json:"deleted_at,omitempty"`
+
+	// Stats is the size of the content, counted when it was written; nil
+	// for submissions stored before the counts were kept
+	Stats *textstats.Stats `json:"stats,omitempty"`
+
+	// ContentHash identifies identical content; empty for submissions
+	// stored before it was kept
+	ContentHash string `json:"-"`
+	// contentKey is the object holding the content once it was moved to
+	// object storage; scan reads the content back from it
+	contentKey *string
+	// DuplicateOf is the owner's earliest submission with the same
+	// content, when this is a later copy, or the teammate's submission
+	// whose analysis this one reuses
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"`
+}
+
+// Analysis represents the AI analysis of a submission
+type Analysis struct {
+	ID               uuid.UUID           `json:"id"`
+	SubmissionID     uuid.UUID           `json:"submission_id"`
+	Sentiment        string              `json:"sentiment"`
+	SentimentScore   *float64            `json:"sentiment_score"`
+	Topics           []string            `json:"topics"`
+	Keyphrases       []Keyphrase         `json:"keyphrases"`
+	Findings         []Finding           `json:"findings"`
+	Summary          string              `json:"summary"`
+	Readability      *ReadabilityMetrics `json:"readability"`
+	RawResponse      json.RawMessage     `json:"-"`
+	ProcessingTimeMs int                 `json:"processing_time_ms"`
+	CreatedAt        timestamp.Time      `json:"created_at"`
+	// UpdatedAt moves on when an operator overrides the policy decision
+	UpdatedAt timestamp.Time `json:"updated_at"`
+
+	// How faithful the summary is to the source, from 0 to 1, or nil when
+	// it wasn't verified; low confidence analyses are worth re-running
+	Confidence    *float64 `json:"confidence"`
+	LowConfidence bool     `json:"low_confidence"`
+
+	// The submission's instructions when the analysis ran, kept so the
+	// result can be reproduced
+	Instructions *string `json:"instructions,omitempty"`
+
+	// How the tone and claims changed from the previous revision, or nil
+	// for an original or when the comparison failed
+	Changes *RevisionChanges `json:"changes,omitempty"`
+
+	// The factual claims made in the content with their fact-checks, or
+	// nil when claims weren't extracted
+	Claims []Claim `json:"claims,omitempty"`
+
+	// Framing, loaded language and one-sidedness, or nil when bias
+	// analysis didn't run
+	Bias *BiasReport `json:"bias,omitempty"`
+
+	// How likely the content is machine-generated, or nil when detection
+	// didn't run
+	AIDetection *AIDetection `json:"ai_detection,omitempty"`
+
+	// How strongly the content falls in each moderation category, or nil
+	// when moderation didn't run
+	Moderation ModerationScores `json:"moderation,omitempty"`
+
+	// What the owner's organizations' moderation policy, or an operator's
+	// override, made of the content; nil when neither applies
+	PolicyDecision *PolicyDecision `json:"policy_decision,omitempty"`
+
+	// Uses of phrases the owner's organizations banned, ordered by
+	// position, or nil when none are banned or the check didn't run
+	Compliance []ComplianceFlag `json:"compliance,omitempty"`
+
+	// The labels of the owner's organizations' taxonomy that apply, most
+	// confident first, or nil when no taxonomy applies
+	Labels []Classification `json:"labels,omitempty"`
+
+	// The ISO 639-1 code of the content's language, or empty when it
+	// couldn't be detected
+	Language string `json:"language,omitempty"`
+
+	// The pipeline stages that ran out of time, leaving their part of the
+	// analysis empty until they are retried
+	TimedOut []string `json:"timed_out,omitempty"`
+
+	// Proofreading issues ordered by position, or nil when the content
+	// wasn't proofread; served by their own endpoint
+	Issues []Issue `json:"-"`
+
+	// Model usage, reported through the usage rollups
+	PromptTokens int   `json:"-"`
+	OutputTokens int   `json:"-"`
+	CostMicros   int64 `json:"-"`
+
+	// The model calls made for the analysis when artifacts are kept;
+	// served to operators by their own endpoint
+	Calls []ModelCall `json:"-"`
+}
+
+// LowConfidenceThreshold is the confidence below which an analysis is
+// flagged as low confidence
+const LowConfidenceThreshold = 0.6
+
+// SetConfidence records the verification score and flags the analysis
+// when it falls below LowConfidenceThreshold
+func (a *Analysis) SetConfidence(score *float64) {
+	a.Confidence = score
+	a.LowConfidence = score != nil && *score < LowConfidenceThreshold
+}
+
+// ReadabilityMetrics are deterministic writing-quality scores computed
+// from the text itself rather than by the model
+type ReadabilityMetrics struct {
+	Words                 int     `json:"words"`
+	Sentences             int     `json:"sentences"`
+	Syllables             int     `json:"syllables"`
+	Polysyllables         int     `json:"polysyllables"`
+	FleschReadingEase     float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade    float64 `json:"flesch_kincaid_grade"`
+	SMOGGrade             float64 `json:"smog_grade"`
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	PassiveVoiceRatio     float64 `json:"passive_voice_ratio"`
+	LexicalDiversity      float64 `json:"lexical_diversity"`
+}
+
+// FindingType is a kind of sensitive content
+type FindingType string
+
+const (
+	FindingEmail      FindingType = "email"
+	FindingPhone      FindingType = "phone"
+	FindingSSN        FindingType = "ssn"
+	FindingCreditCard FindingType = "credit_card"
+	FindingProfanity  FindingType = "profanity"
+)
+
+// Finding is a span of sensitive content. Start and End are character
+// offsets into the submission content; the matched text itself is not stored.
+type Finding struct {
+	Type  FindingType `json:"type"`
+	Start int         `json:"start"`
+	End   int         `json:"end"`
+	// Verified is set once the model has confirmed the finding
+	Verified bool `json:"verified"`
+}
+
+// Keyphrase is a key phrase of a submission with its relevance from 0 to 1
+type Keyphrase struct {
+	Phrase string  `json:"phrase"`
+	Score  float64 `json:"score"`
+}
+
+// SubmissionFilter narrows a submission listing
+type SubmissionFilter struct {
+	// Keyword matches submissions with a keyphrase containing it, case-insensitively
+	Keyword string
+	// Duplicates keeps only submissions sharing their content with another
+	Duplicates bool
+	// Label keeps only submissions classified with a taxonomy label,
+	// case-insensitively
+	Label string
+}
+
+// submissionColumns is the column list matching scanSubmission
+const submissionColumns = `id, user_id, content, redacted_content, instructions, profile_id, previous_id, revision, status, version, created_at,
+	assignee_id, due_at, workflow_status, queued_at, processing_at, completed_at, failed_at, canceled_at, archived_at,
+	quarantined_at, quarantine_reason, word_count, character_count, token_estimate, content_hash, failure_reason, updated_at, deleted_at, content_key,
+	duplicate_of`
+
+// scanSubmission scans a row selected with submissionColumns
+func scanSubmission(row pgx.Row) (*Submission, error) {
+	var s Submission
+	var words, characters, tokens *int
+	var hash *string
+	err := row.Scan(
+		&s.ID,
+		&s.UserID,
+		&s.Content,
+		&s.RedactedContent,
+		&s.Instructions,
+		&s.ProfileID,
+		&s.PreviousID,
+		&s.Revision,
+		&s.Status,
+		&s.Version,
+		&s.CreatedAt,
+		&s.AssigneeID,
+		&s.DueAt,
+		&s.WorkflowStatus,
+		&s.QueuedAt,
+		&s.ProcessingAt,
+		&s.CompletedAt,
+		&s.FailedAt,
+		&s.CanceledAt,
+		&s.ArchivedAt,
+		&s.QuarantinedAt,
+		&s.QuarantineReason,
+		&words,
+		&characters,
+		&tokens,
+		&hash,
+		&s.FailureReason,
+		&s.UpdatedAt,
+		&s.DeletedAt,
+		&s.contentKey,
+		&s.DuplicateOf,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if words != nil && characters != nil && tokens != nil {
+		s.Stats = &textstats.Stats{Words: *words, Characters: *characters, Tokens: *tokens}
+	}
+	if hash != nil {
+		s.ContentHash = *hash
+	}
+	return &s, nil
+}
+
+// SubmissionStore handles database operations for submissions and their analyses
+type SubmissionStore struct {
+	db       *pgxpool.Pool
+	replica  ReadRouter
+	listener StatusListener
+	cipher   *encryption.Encryptor
+	hashes   *contentHasher
+	objects  storage.Storage
+}
+
+// NewSubmissionStore creates a new submission store
+func NewSubmissionStore(db *pgxpool.Pool) *SubmissionStore {
+	return &SubmissionStore{db: db}
+}
+
+// WithListener sets the listener told about every status change and
+// returns the store
+func (s *SubmissionStore) WithListener(listener StatusListener) *SubmissionStore {
+	s.listener = listener
+	return s
+}
+
+// WithReplica sends List through router and returns the store
+func (s *SubmissionStore) WithReplica(router ReadRouter) *SubmissionStore {
+	s.replica = router
+	return s
+}
+
+// WithEncryption encrypts content and analysis text written from now on,
+// and keys content hashes with the master key, and returns the store.
+// Rows written earlier stay readable.
+func (s *SubmissionStore) WithEncryption(cipher *encryption.Encryptor) *SubmissionStore {
+	s.cipher = cipher
+	s.hashes = newContentHasher(cipher)
+	return s
+}
+
+// WithStorage lets old submissions' content be moved to object storage
+// with TierContent and returns the store. Content already there is read
+// back from it transparently.
+func (s *SubmissionStore) WithStorage(objects storage.Storage) *SubmissionStore {
+	s.objects = objects
+	return s
+}
+
+// scan reads a submission row and decrypts its content, loading it from
+// object storage first if it was tiered
+func (s *SubmissionStore) scan(ctx context.Context, row pgx.Row) (*Submission, error) {
+	submission, err := scanSubmission(row)
+	if err != nil {
+		return nil, err
+	}
+
+	if submission.contentKey != nil {
+		if err := s.loadContent(ctx, submission); err != nil {
+			return nil, fmt.Errorf("failed to load content of submission %s: %w", submission.ID, err)
+		}
+	}
+
+	r := record{submission.ID, submission.UserID}
+	if submission.Content, err = openField(ctx, s.cipher, submission.Content, r.bind(fieldSubmissionContent)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt submission %s: %w", submission.ID, err)
+	}
+	if submission.RedactedContent != nil {
+		redacted, err := openField(ctx, s.cipher, *submission.RedactedContent, r.bind(fieldSubmissionRedacted))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt submission %s: %w", submission.ID, err)
+		}
+		submission.RedactedContent = &redacted
+	}
+	if submission.Instructions != nil {
+		instructions, err := openField(ctx, s.cipher, *submission.Instructions, r.bind(fieldSubmissionInstructions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt submission %s: %w", submission.ID, err)
+		}
+		submission.Instructions = &instructions
+	}
+
+	return submission, nil
+}
+
+// sealContent encrypts a submission's content and masked copy for storage
+// in the given submission
+func (s *SubmissionStore) sealContent(ctx context.Context, submission record, content string, redacted *string) (string, *string, error) {
+	sealed, err := sealField(ctx, s.cipher, content, submission.bind(fieldSubmissionContent))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt content: %w", err)
+	}
+	sealedRedacted, err := sealOptionalField(ctx, s.cipher, redacted, submission.bind(fieldSubmissionRedacted))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encrypt redacted content: %w", err)
+	}
+	return sealed, sealedRedacted, nil
+}
+
+// Create stores new content for a user. status must be StatusDraft, to
+// hold it for later, or StatusQueued. redacted is the masked copy for
+// display, or nil when redaction wasn't requested. instructions are the
+// sanitized analysis instructions and profileID the analysis profile, or nil.
+func (s *SubmissionStore) Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status SubmissionStatus) (*Submission, error) {
+	if status != StatusDraft && status != StatusQueued {
+		return nil, fmt.Errorf("invalid initial status %q", status)
+	}
+
+	stats := textstats.Compute(content)
+	hash, hashKey, err := s.hashes.hash(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	r := newRecord(userID)
+	content, redacted, err = s.sealContent(ctx, r, content, redacted)
+	if err != nil {
+		return nil, err
+	}
+	instructions, err = sealOptionalField(ctx, s.cipher, instructions, r.bind(fieldSubmissionInstructions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+
+	query := `
+		INSERT INTO submissions (id, user_id, content, redacted_content, instructions, profile_id, status, queued_at,
+			word_count, character_count, token_estimate, content_hash, content_hash_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, CASE WHEN $7 = 'queued' THEN NOW() END, $8, $9, $10, $11, $12)
+		RETURNING ` + submissionColumns
+
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, r.id, userID, content, redacted, instructions, profileID, status,
+			stats.Words, stats.Characters, stats.Tokens, hash, hashKey))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create submission: %w", err)
+	}
+
+	s.emit(ctx, StatusChange{
+		SubmissionID: submission.ID,
+		UserID:       submission.UserID,
+		To:           submission.Status,
+		At:           submission.CreatedAt,
+	})
+
+	return submission, nil
+}
+
+// Get retrieves a submission by ID regardless of owner. It is meant for
+// background jobs; handlers should use GetByID.
+func (s *SubmissionStore) Get(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	query := `SELECT ` + submissionColumns + ` FROM submissions WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// GetByID retrieves a submission owned by the given user
+func (s *SubmissionStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*Submission, error) {
+	query := `SELECT ` + submissionColumns + ` FROM submissions WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// List returns a page of a user's submissions matching filter, newest
+// first, and the total count
+func (s *SubmissionStore) List(ctx context.Context, userID uuid.UUID, filter SubmissionFilter, limit, offset int) ([]Submission, int, error) {
+	var submissions []Submission
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		var err error
+		submissions, total, err = s.list(ctx, userID, filter, limit, offset)
+		return err
+	})
+	return submissions, total, err
+}
+
+// list runs one attempt of List
+func (s *SubmissionStore) list(ctx context.Context, userID uuid.UUID, filter SubmissionFilter, limit, offset int) ([]Submission, int, error) {
+	where := `WHERE user_id = $1 AND quarantined_at IS NULL AND deleted_at IS NULL`
+	args := []interface{}{userID}
+
+	if keyword := strings.TrimSpace(filter.Keyword); keyword != "" {
+		args = append(args, "%"+escapeLike(strings.ToLower(keyword))+"%")
+		where += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM keyphrases k
+			WHERE k.submission_id = submissions.id AND k.phrase LIKE $%d
+		)`, len(args))
+	}
+	if label := strings.TrimSpace(filter.Label); label != "" {
+		args = append(args, strings.ToLower(label))
+		where += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM submission_labels l
+			WHERE l.submission_id = submissions.id AND LOWER(l.label) = $%d
+		)`, len(args))
+	}
+	if filter.Duplicates {
+		where += ` AND content_hash IS NOT NULL AND EXISTS (
+			SELECT 1 FROM submissions d
+			WHERE d.content_hash = submissions.content_hash AND d.user_id = submissions.user_id
+				AND d.id <> submissions.id AND d.quarantined_at IS NULL AND d.deleted_at IS NULL
+		)`
+	}
+
+	db := readPool(s.db, s.replica)
+
+	var total int
+	if err := db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count submissions: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT `+submissionColumns+`
+		FROM submissions
+		%s
+		ORDER BY created_at DESC, id
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+
+	rows, err := db.Query(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list submissions: %w", err)
+	}
+	defer rows.Close()
+
+	submissions := []Submission{}
+	for rows.Next() {
+		submission, err := s.scan(ctx, rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan submission: %w", err)
+		}
+		submissions = append(submissions, *submission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to read submissions: %w", err)
+	}
+
+	if err := linkDuplicates(ctx, db, userID, submissions); err != nil {
+		return nil, 0, err
+	}
+
+	return submissions, total, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// UpdateStatus moves a submission to a new status and records when it
+// happened. It returns a *TransitionError if the state machine doesn't
+// allow the change and pgx.ErrNoRows if the submission doesn't exist.
+func (s *SubmissionStore) UpdateStatus(ctx context.Context, id uuid.UUID, status SubmissionStatus) error {
+	change, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*StatusChange, error) {
+		return transition(ctx, s.db, id, status)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, *change)
+	return nil
+}
+
+// Fail moves a submission to failed, recording reason as its
+// FailureReason. Like UpdateStatus it returns a *TransitionError if the
+// submission can't fail from its current status.
+func (s *SubmissionStore) Fail(ctx context.Context, id uuid.UUID, reason string) error {
+	change, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*StatusChange, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		change, err := transition(ctx, tx, id, StatusFailed)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(ctx, `UPDATE submissions SET failure_reason = $2 WHERE id = $1`, id, reason); err != nil {
+			return nil, fmt.Errorf("failed to record failure reason: %w", err)
+		}
+		return change, tx.Commit(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, *change)
+	return nil
+}
+
+// rowQuerier is satisfied by both the pool and transactions
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// transition applies a status change if the current status allows it
+func transition(ctx context.Context, db rowQuerier, id uuid.UUID, to SubmissionStatus) (*StatusChange, error) {
+	column, ok := statusTimestampColumns[to]
+	if !ok {
+		return nil, &TransitionError{To: to}
+	}
+
+	// The subquery locks the row and captures the status being replaced
+	query := fmt.Sprintf(`
+		UPDATE submissions s
+		SET status = $2, %[1]s = NOW(), version = s.version + 1
+		FROM (SELECT id, status FROM submissions WHERE id = $1 FOR UPDATE) prev
+		WHERE s.id = prev.id AND prev.status = ANY($3)
+		RETURNING s.user_id, prev.status, s.%[1]s
+	`, column)
+
+	change := StatusChange{SubmissionID: id, To: to}
+	err := db.QueryRow(ctx, query, id, to, sourcesOf(to)).Scan(&change.UserID, &change.From, &change.At)
+	if err == nil {
+		return &change, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to update submission status: %w", err)
+	}
+
+	// Either the submission is gone or its status doesn't allow the change
+	var current SubmissionStatus
+	if err := db.QueryRow(ctx, `SELECT status FROM submissions WHERE id = $1`, id).Scan(&current); err != nil {
+		return nil, err
+	}
+	return nil, &TransitionError{From: current, To: to}
+}
+
+// emit tells the listener about a committed status change
+func (s *SubmissionStore) emit(ctx context.Context, change StatusChange) {
+	if s.listener != nil {
+		s.listener.StatusChanged(ctx, change)
+	}
+}
+
+// UpdateContent replaces the content of a user's draft. The update only
+// applies if the submission is still at version; otherwise it returns
+// ErrVersionConflict. Submissions past the draft stage return ErrNotDraft.
+func (s *SubmissionStore) UpdateContent(ctx context.Context, userID, id uuid.UUID, version int, content string, redacted *string) (*Submission, error) {
+	stats := textstats.Compute(content)
+	hash, hashKey, err := s.hashes.hash(ctx, content)
+	if err != nil {
+		return nil, err
+	}
+	content, redacted, err = s.sealContent(ctx, record{id, userID}, content, redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		UPDATE submissions
+		SET content = $4, redacted_content = $5, version = version + 1,
+			word_count = $7, character_count = $8, token_estimate = $9, content_hash = $10, content_hash_key = $11
+		WHERE id = $1 AND user_id = $2 AND version = $3 AND status = $6 AND deleted_at IS NULL
+		RETURNING ` + submissionColumns
+
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID, version, content, redacted, StatusDraft,
+			stats.Words, stats.Characters, stats.Tokens, hash, hashKey))
+	})
+	if err == nil {
+		return submission, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to update submission: %w", err)
+	}
+
+	// Work out which precondition failed
+	current, err := s.GetByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if current.Version != version {
+		return nil, ErrVersionConflict
+	}
+	return nil, ErrNotDraft
+}
+
+// UpdateRedactedContent replaces the masked copy of a submission
+func (s *SubmissionStore) UpdateRedactedContent(ctx context.Context, id uuid.UUID, redacted string) error {
+	owner, err := s.owner(ctx, id)
+	if err != nil {
+		return err
+	}
+	redacted, err = sealField(ctx, s.cipher, redacted, record{id, owner}.bind(fieldSubmissionRedacted))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt redacted content: %w", err)
+	}
+
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, `UPDATE submissions SET redacted_content = $2, version = version + 1 WHERE id = $1`, id, redacted)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update redacted content: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// owner returns the user a submission belongs to, whom the values
+// encrypted for it are bound to. It returns pgx.ErrNoRows if the
+// submission doesn't exist.
+func (s *SubmissionStore) owner(ctx context.Context, submissionID uuid.UUID) (uuid.UUID, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (uuid.UUID, error) {
+		var userID uuid.UUID
+		err := s.db.QueryRow(ctx, `SELECT user_id FROM submissions WHERE id = $1`, submissionID).Scan(&userID)
+		return userID, err
+	})
+}
+
+// SaveAnalysis stores an analysis and moves its submission from processing
+// to completed
+func (s *SubmissionStore) SaveAnalysis(ctx context.Context, analysis *Analysis) error {
+	// As with newRecord, the fields are sealed bound to this ID; the owner
+	// is looked up only when there is a key to seal with
+	analysis.ID = uuid.New()
+	sealed, err := s.sealAnalysis(ctx, analysis)
+	if err != nil {
+		return err
+	}
+
+	// A serialization failure rolls back the whole transaction, so it is
+	// safe to run again from the start
+	change, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*StatusChange, error) {
+		return s.saveAnalysis(ctx, analysis, sealed)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.emit(ctx, *change)
+	return nil
+}
+
+// sealedAnalysis holds an analysis' columns encoded, and encrypted where
+// the store encrypts them, ready to be written
+type sealedAnalysis struct {
+	summary      string
+	instructions *string
+	// The modules' results, with the model calls kept as artifacts
+	changes, claims, issues, bias, aiDetection, moderation, compliance, calls *string
+	topics, findings, readability, decision, raw                              []byte
+}
+
+// sealAnalysis encodes and encrypts an analysis for writing
+func (s *SubmissionStore) sealAnalysis(ctx context.Context, analysis *Analysis) (*sealedAnalysis, error) {
+	var sealed sealedAnalysis
+	var err error
+
+	r := record{id: analysis.ID}
+	if s.cipher != nil {
+		if r.owner, err = s.owner(ctx, analysis.SubmissionID); err != nil {
+			return nil, fmt.Errorf("failed to find owner of submission %s: %w", analysis.SubmissionID, err)
+		}
+	}
+
+	if sealed.topics, err = json.Marshal(analysis.Topics); err != nil {
+		return nil, fmt.Errorf("failed to encode topics: %w", err)
+	}
+	if sealed.findings, err = json.Marshal(analysis.Findings); err != nil {
+		return nil, fmt.Errorf("failed to encode findings: %w", err)
+	}
+	if analysis.Readability != nil {
+		if sealed.readability, err = json.Marshal(analysis.Readability); err != nil {
+			return nil, fmt.Errorf("failed to encode readability: %w", err)
+		}
+	}
+
+	// raw_response is JSONB, so store NULL rather than an empty document
+	if len(analysis.RawResponse) > 0 {
+		sealed.raw = analysis.RawResponse
+	}
+
+	if sealed.summary, err = sealField(ctx, s.cipher, analysis.Summary, r.bind(fieldAnalysisSummary)); err != nil {
+		return nil, fmt.Errorf("failed to encrypt summary: %w", err)
+	}
+	if sealed.instructions, err = sealOptionalField(ctx, s.cipher, analysis.Instructions, r.bind(fieldAnalysisInstructions)); err != nil {
+		return nil, fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+	// An encrypted response is stored as a JSON string to stay valid JSONB.
+	// A plaintext one that is itself such a string is escaped.
+	if sealed.raw != nil && s.cipher == nil {
+		sealed.raw = escapeRaw(sealed.raw)
+	}
+	if sealed.raw != nil && s.cipher != nil {
+		raw, err := sealField(ctx, s.cipher, string(sealed.raw), r.bind(fieldAnalysisRaw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt raw response: %w", err)
+		}
+		if sealed.raw, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("failed to encode raw response: %w", err)
+		}
+	}
+
+	if sealed.changes, err = s.sealChanges(ctx, r, analysis.Changes); err != nil {
+		return nil, err
+	}
+	if sealed.claims, err = s.sealClaims(ctx, r, analysis.Claims); err != nil {
+		return nil, err
+	}
+	if sealed.issues, err = s.sealIssues(ctx, r, analysis.Issues); err != nil {
+		return nil, err
+	}
+	if sealed.bias, err = s.sealBias(ctx, r, analysis.Bias); err != nil {
+		return nil, err
+	}
+	if sealed.aiDetection, err = s.sealAIDetection(ctx, r, analysis.AIDetection); err != nil {
+		return nil, err
+	}
+	if sealed.moderation, err = s.sealModeration(ctx, r, analysis.Moderation); err != nil {
+		return nil, err
+	}
+	if sealed.compliance, err = s.sealCompliance(ctx, r, analysis.Compliance); err != nil {
+		return nil, err
+	}
+	if sealed.calls, err = s.sealCalls(ctx, r, analysis.Calls); err != nil {
+		return nil, err
+	}
+
+	if analysis.PolicyDecision != nil {
+		if sealed.decision, err = json.Marshal(analysis.PolicyDecision); err != nil {
+			return nil, fmt.Errorf("failed to encode policy decision: %w", err)
+		}
+	}
+	return &sealed, nil
+}
+
+// timedOut returns the stages an analysis ran out of time in for the
+// timed_out column, which is never NULL
+func timedOut(stages []string) []string {
+	if stages == nil {
+		return []string{}
+	}
+	return stages
+}
+
+// saveAnalysis runs one attempt of SaveAnalysis' transaction
+func (s *SubmissionStore) saveAnalysis(ctx context.Context, analysis *Analysis, sealed *sealedAnalysis) (*StatusChange, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		INSERT INTO analyses (id, submission_id, sentiment, sentiment_score, topics, summary, readability, findings, raw_response, processing_time_ms, prompt_tokens, output_tokens, cost_micros, confidence, instructions, changes, claims, issues, bias, ai_detection, moderation, policy_decision, compliance, language, timed_out)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, NULLIF($24, ''), $25)
+		RETURNING created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		analysis.ID,
+		analysis.SubmissionID,
+		analysis.Sentiment,
+		analysis.SentimentScore,
+		sealed.topics,
+		sealed.summary,
+		sealed.readability,
+		sealed.findings,
+		sealed.raw,
+		analysis.ProcessingTimeMs,
+		analysis.PromptTokens,
+		analysis.OutputTokens,
+		analysis.CostMicros,
+		analysis.Confidence,
+		sealed.instructions,
+		sealed.changes,
+		sealed.claims,
+		sealed.issues,
+		sealed.bias,
+		sealed.aiDetection,
+		sealed.moderation,
+		sealed.decision,
+		sealed.compliance,
+		analysis.Language,
+		timedOut(analysis.TimedOut),
+	).Scan(&analysis.CreatedAt, &analysis.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save analysis: %w", err)
+	}
+
+	if err := saveRankings(ctx, tx, analysis); err != nil {
+		return nil, err
+	}
+
+	if sealed.calls != nil {
+		if _, err := tx.Exec(ctx, `INSERT INTO analysis_artifacts (analysis_id, calls) VALUES ($1, $2)`, analysis.ID, *sealed.calls); err != nil {
+			return nil, fmt.Errorf("failed to save model calls: %w", err)
+		}
+	}
+
+	// Content the moderation policy blocks is quarantined until an operator
+	// releases it
+	if reason := QuarantineReason(analysis.PolicyDecision); reason != "" {
+		if _, err := tx.Exec(ctx, quarantineQuery, analysis.SubmissionID, reason); err != nil {
+			return nil, fmt.Errorf("failed to quarantine submission: %w", err)
+		}
+	}
+
+	change, err := transition(ctx, tx, analysis.SubmissionID, StatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit analysis: %w", err)
+	}
+
+	return change, nil
+}
+
+// saveRankings replaces the submission's keyphrases and labels with the
+// analysis'. Only the latest analysis' are kept, for filtering and
+// analytics.
+func saveRankings(ctx context.Context, tx pgx.Tx, analysis *Analysis) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM keyphrases WHERE submission_id = $1`, analysis.SubmissionID); err != nil {
+		return fmt.Errorf("failed to clear keyphrases: %w", err)
+	}
+	for _, k := range analysis.Keyphrases {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO keyphrases (submission_id, analysis_id, phrase, score) VALUES ($1, $2, $3, $4)`,
+			analysis.SubmissionID, analysis.ID, k.Phrase, k.Score,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save keyphrase: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM submission_labels WHERE submission_id = $1`, analysis.SubmissionID); err != nil {
+		return fmt.Errorf("failed to clear labels: %w", err)
+	}
+	for _, c := range analysis.Labels {
+		_, err := tx.Exec(ctx,
+			`INSERT INTO submission_labels (submission_id, analysis_id, label, confidence) VALUES ($1, $2, $3, $4)`,
+			analysis.SubmissionID, analysis.ID, c.Label, c.Confidence,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save label: %w", err)
+		}
+	}
+	return nil
+}
+
+// UpdateAnalysis writes the results of stages run again into a stored
+// analysis, such as those that timed out. The analysis' tokens, cost and
+// processing time are those of the new run, and are added to the stored
+// ones; its model calls aren't kept. It returns pgx.ErrNoRows if the
+// analysis no longer exists.
+func (s *SubmissionStore) UpdateAnalysis(ctx context.Context, analysis *Analysis) error {
+	sealed, err := s.sealAnalysis(ctx, analysis)
+	if err != nil {
+		return err
+	}
+
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.updateAnalysis(ctx, analysis, sealed)
+	})
+}
+
+// updateAnalysis runs one attempt of UpdateAnalysis' transaction
+func (s *SubmissionStore) updateAnalysis(ctx context.Context, analysis *Analysis, sealed *sealedAnalysis) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE analyses SET
+			sentiment = $2, sentiment_score = $3, topics = $4, summary = $5, readability = $6, findings = $7,
+			raw_response = COALESCE($8, raw_response),
+			processing_time_ms = COALESCE(processing_time_ms, 0) + $9,
+			prompt_tokens = prompt_tokens + $10, output_tokens = output_tokens + $11, cost_micros = cost_micros + $12,
+			confidence = $13, changes = $14, claims = $15, issues = $16, bias = $17, ai_detection = $18,
+			moderation = $19, policy_decision = $20, compliance = $21, language = NULLIF($22, ''), timed_out = $23
+		WHERE id = $1
+		RETURNING updated_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		analysis.ID,
+		analysis.Sentiment,
+		analysis.SentimentScore,
+		sealed.topics,
+		sealed.summary,
+		sealed.readability,
+		sealed.findings,
+		sealed.raw,
+		analysis.ProcessingTimeMs,
+		analysis.PromptTokens,
+		analysis.OutputTokens,
+		analysis.CostMicros,
+		analysis.Confidence,
+		sealed.changes,
+		sealed.claims,
+		sealed.issues,
+		sealed.bias,
+		sealed.aiDetection,
+		sealed.moderation,
+		sealed.decision,
+		sealed.compliance,
+		analysis.Language,
+		timedOut(analysis.TimedOut),
+	).Scan(&analysis.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update analysis: %w", err)
+	}
+
+	if err := saveRankings(ctx, tx, analysis); err != nil {
+		return err
+	}
+
+	// Moderation run again may now block the content
+	if reason := QuarantineReason(analysis.PolicyDecision); reason != "" {
+		if _, err := tx.Exec(ctx, quarantineQuery, analysis.SubmissionID, reason); err != nil {
+			return fmt.Errorf("failed to quarantine submission: %w", err)
+		}
+	}
+
+	// The submission's version covers its embedded analysis
+	if _, err := tx.Exec(ctx, `UPDATE submissions SET version = version + 1 WHERE id = $1`, analysis.SubmissionID); err != nil {
+		return fmt.Errorf("failed to update submission version: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit analysis: %w", err)
+	}
+	return nil
+}
+
+// GetAnalysis retrieves the latest analysis of a submission owned by the given user
+func (s *SubmissionStore) GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*Analysis, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Analysis, error) {
+		return s.getAnalysis(ctx, userID, submissionID)
+	})
+}
+
+// analysisColumns is the column list matching scanAnalysis, selected from
+// analyses a
+const analysisColumns = `
+	a.id,
+	a.submission_id,
+	COALESCE(a.sentiment, ''),
+	a.sentiment_score,
+	COALESCE(a.topics, '[]'::jsonb),
+	COALESCE(a.summary, ''),
+	a.readability,
+	COALESCE(a.findings, '[]'::jsonb),
+	COALESCE(a.processing_time_ms, 0),
+	a.confidence,
+	a.instructions,
+	a.changes,
+	a.claims,
+	a.issues,
+	a.bias,
+	a.ai_detection,
+	a.moderation,
+	a.policy_decision,
+	a.compliance,
+	COALESCE(a.language, ''),
+	a.timed_out,
+	a.created_at,
+	a.updated_at`
+
+// getAnalysis runs one attempt of GetAnalysis
+func (s *SubmissionStore) getAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*Analysis, error) {
+	query := `
+		SELECT ` + analysisColumns + `
+		FROM analyses a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE a.submission_id = $1 AND s.user_id = $2 AND s.deleted_at IS NULL
+		ORDER BY a.created_at DESC
+		LIMIT 1
+	`
+
+	a, err := s.scanAnalysis(ctx, userID, s.db.QueryRow(ctx, query, submissionID, userID))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachRankings(ctx, []*Analysis{a}); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// GetAnalyses retrieves the latest analysis of each of the given
+// submissions owned by the user, by submission ID, in a fixed number of
+// queries. Submissions without an analysis are left out.
+func (s *SubmissionStore) GetAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*Analysis, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (map[uuid.UUID]*Analysis, error) {
+		return s.getAnalyses(ctx, userID, submissionIDs)
+	})
+}
+
+// getAnalyses runs one attempt of GetAnalyses
+func (s *SubmissionStore) getAnalyses(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID]*Analysis, error) {
+	query := `
+		SELECT DISTINCT ON (a.submission_id) ` + analysisColumns + `
+		FROM analyses a
+		JOIN submissions s ON s.id = a.submission_id
+		WHERE a.submission_id = ANY($1) AND s.user_id = $2 AND s.deleted_at IS NULL
+		ORDER BY a.submission_id, a.created_at DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, submissionIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get analyses: %w", err)
+	}
+	defer rows.Close()
+
+	var analyses []*Analysis
+	for rows.Next() {
+		a, err := s.scanAnalysis(ctx, userID, rows)
+		if err != nil {
+			return nil, err
+		}
+		analyses = append(analyses, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read analyses: %w", err)
+	}
+	rows.Close()
+
+	if err := s.attachRankings(ctx, analyses); err != nil {
+		return nil, err
+	}
+	bySubmission := make(map[uuid.UUID]*Analysis, len(analyses))
+	for _, a := range analyses {
+		bySubmission[a.SubmissionID] = a
+	}
+	return bySubmission, nil
+}
+
+// GetLabels returns the labels of the latest analysis of each of the
+// given submissions owned by the user, most confident first, by
+// submission ID, in one query. Submissions without labels are left out.
+func (s *SubmissionStore) GetLabels(ctx context.Context, userID uuid.UUID, submissionIDs []uuid.UUID) (map[uuid.UUID][]Classification, error) {
+	query := `
+		SELECT l.submission_id, l.label, l.confidence
+		FROM submission_labels l
+		JOIN submissions s ON s.id = l.submission_id
+		WHERE l.submission_id = ANY($1) AND s.user_id = $2 AND s.deleted_at IS NULL
+		ORDER BY l.confidence DESC, l.label
+	`
+
+	labels, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (map[uuid.UUID][]Classification, error) {
+		rows, err := s.db.Query(ctx, query, submissionIDs, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		labels := map[uuid.UUID][]Classification{}
+		for rows.Next() {
+			var submissionID uuid.UUID
+			var c Classification
+			if err := rows.Scan(&submissionID, &c.Label, &c.Confidence); err != nil {
+				return nil, err
+			}
+			labels[submissionID] = append(labels[submissionID], c)
+		}
+		return labels, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	return labels, nil
+}
+
+// scanAnalysis reads a row selected with analysisColumns, of a submission
+// owned by userID, and decrypts and decodes its fields. Keyphrases and
+// labels are attached separately.
+func (s *SubmissionStore) scanAnalysis(ctx context.Context, userID uuid.UUID, row pgx.Row) (*Analysis, error) {
+	var a Analysis
+	var topics, readability, findings, decision []byte
+	var confidence *float64
+	var changes, claims, issues, bias, aiDetection, moderation, compliance *string
+	err := row.Scan(
+		&a.ID,
+		&a.SubmissionID,
+		&a.Sentiment,
+		&a.SentimentScore,
+		&topics,
+		&a.Summary,
+		&readability,
+		&findings,
+		&a.ProcessingTimeMs,
+		&confidence,
+		&a.Instructions,
+		&changes,
+		&claims,
+		&issues,
+		&bias,
+		&aiDetection,
+		&moderation,
+		&decision,
+		&compliance,
+		&a.Language,
+		&a.TimedOut,
+		&a.CreatedAt,
+		&a.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	a.SetConfidence(confidence)
+
+	r := record{a.ID, userID}
+	if a.Summary, err = openField(ctx, s.cipher, a.Summary, r.bind(fieldAnalysisSummary)); err != nil {
+		return nil, fmt.Errorf("failed to decrypt analysis %s: %w", a.ID, err)
+	}
+	if a.Instructions != nil {
+		instructions, err := openField(ctx, s.cipher, *a.Instructions, r.bind(fieldAnalysisInstructions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt analysis %s: %w", a.ID, err)
+		}
+		a.Instructions = &instructions
+	}
+
+	if a.Changes, err = s.openChanges(ctx, r, changes); err != nil {
+		return nil, fmt.Errorf("failed to decode changes of analysis %s: %w", a.ID, err)
+	}
+	if a.Claims, err = s.openClaims(ctx, r, claims); err != nil {
+		return nil, fmt.Errorf("failed to decode claims of analysis %s: %w", a.ID, err)
+	}
+	if a.Issues, err = s.openIssues(ctx, r, issues); err != nil {
+		return nil, fmt.Errorf("failed to decode issues of analysis %s: %w", a.ID, err)
+	}
+	if a.Bias, err = s.openBias(ctx, r, bias); err != nil {
+		return nil, fmt.Errorf("failed to decode bias report of analysis %s: %w", a.ID, err)
+	}
+	if a.AIDetection, err = s.openAIDetection(ctx, r, aiDetection); err != nil {
+		return nil, fmt.Errorf("failed to decode AI detection of analysis %s: %w", a.ID, err)
+	}
+	if a.Moderation, err = s.openModeration(ctx, r, moderation); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation scores of analysis %s: %w", a.ID, err)
+	}
+	if a.Compliance, err = s.openCompliance(ctx, r, compliance); err != nil {
+		return nil, fmt.Errorf("failed to decode compliance flags of analysis %s: %w", a.ID, err)
+	}
+	if decision != nil {
+		a.PolicyDecision = &PolicyDecision{}
+		if err := json.Unmarshal(decision, a.PolicyDecision); err != nil {
+			return nil, fmt.Errorf("failed to decode policy decision of analysis %s: %w", a.ID, err)
+		}
+	}
+
+	if err := json.Unmarshal(topics, &a.Topics); err != nil {
+		return nil, fmt.Errorf("failed to decode topics: %w", err)
+	}
+
+	if err := json.Unmarshal(findings, &a.Findings); err != nil {
+		return nil, fmt.Errorf("failed to decode findings: %w", err)
+	}
+	if a.Findings == nil {
+		a.Findings = []Finding{}
+	}
+
+	// Analyses stored before readability metrics existed have none
+	if readability != nil {
+		a.Readability = &ReadabilityMetrics{}
+		if err := json.Unmarshal(readability, a.Readability); err != nil {
+			return nil, fmt.Errorf("failed to decode readability: %w", err)
+		}
+	}
+
+	return &a, nil
+}
+
+// attachRankings sets the keyphrases and labels of analyses
+func (s *SubmissionStore) attachRankings(ctx context.Context, analyses []*Analysis) error {
+	if len(analyses) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(analyses))
+	for i, a := range analyses {
+		ids[i] = a.ID
+	}
+
+	keyphrases, err := s.listKeyphrases(ctx, ids)
+	if err != nil {
+		return err
+	}
+	labels, err := s.listLabels(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, a := range analyses {
+		a.Keyphrases, a.Labels = keyphrases[a.ID], labels[a.ID]
+		if a.Keyphrases == nil {
+			a.Keyphrases = []Keyphrase{}
+		}
+	}
+	return nil
+}
+
+// listKeyphrases returns the keyphrases of analyses by analysis ID, most
+// relevant first
+func (s *SubmissionStore) listKeyphrases(ctx context.Context, analysisIDs []uuid.UUID) (map[uuid.UUID][]Keyphrase, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT analysis_id, phrase, score
+		FROM keyphrases
+		WHERE analysis_id = ANY($1)
+		ORDER BY score DESC, phrase
+	`, analysisIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list keyphrases: %w", err)
+	}
+	defer rows.Close()
+
+	keyphrases := map[uuid.UUID][]Keyphrase{}
+	for rows.Next() {
+		var analysisID uuid.UUID
+		var k Keyphrase
+		if err := rows.Scan(&analysisID, &k.Phrase, &k.Score); err != nil {
+			return nil, fmt.Errorf("failed to scan keyphrase: %w", err)
+		}
+		keyphrases[analysisID] = append(keyphrases[analysisID], k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read keyphrases: %w", err)
+	}
+
+	return keyphrases, nil
+}
@@ -0,0 +1,229 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Taxonomy limits
+const (
+	MaxTaxonomyLabels         = 50
+	MaxLabelNameLength        = 50
+	MaxLabelDescriptionLength = 300
+)
+
+// TaxonomyLabel is a category an organization classifies submissions into
+type TaxonomyLabel struct {
+	Name string `json:"name"`
+	// Description tells the classifier what belongs under the label
+	Description string `json:"description,omitempty"`
+}
+
+// Taxonomy is an organization's set of classification labels
+type Taxonomy struct {
+	OrgID  uuid.UUID       `json:"org_id"`
+	Labels []TaxonomyLabel `json:"labels"`
+	// UpdatedAt is when the labels last changed, or nil if there are none
+	UpdatedAt *timestamp.Time `json:"updated_at"`
+}
+
+// Classification is a taxonomy label that applies to a submission, with
+// how confident the classifier is from 0 to 1
+type Classification struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ValidateTaxonomy checks a taxonomy's labels, keyed by field for a
+// validation error response. Names are unique ignoring case.
+func ValidateTaxonomy(labels []TaxonomyLabel) map[string]string {
+	errs := make(map[string]string)
+	if len(labels) > MaxTaxonomyLabels {
+		errs["labels"] = fmt.Sprintf("at most %d labels are allowed", MaxTaxonomyLabels)
+		return errs
+	}
+
+	seen := make(map[string]bool)
+	for i, l := range labels {
+		field := fmt.Sprintf("labels[%d]", i)
+		name := strings.TrimSpace(l.Name)
+		switch {
+		case name == "":
+			errs[field+".name"] = "name is required"
+		case utf8.RuneCountInString(name) > MaxLabelNameLength:
+			errs[field+".name"] = fmt.Sprintf("must be at most %d characters", MaxLabelNameLength)
+		case seen[strings.ToLower(name)]:
+			errs[field+".name"] = "name is listed more than once"
+		}
+		seen[strings.ToLower(name)] = true
+
+		if utf8.RuneCountInString(l.Description) > MaxLabelDescriptionLength {
+			errs[field+".description"] = fmt.Sprintf("must be at most %d characters", MaxLabelDescriptionLength)
+		}
+	}
+	return errs
+}
+
+// listLabels returns the labels of analyses by analysis ID, most
+// confident first
+func (s *SubmissionStore) listLabels(ctx context.Context, analysisIDs []uuid.UUID) (map[uuid.UUID][]Classification, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT analysis_id, label, confidence
+		FROM submission_labels
+		WHERE analysis_id = ANY($1)
+		ORDER BY confidence DESC, label
+	`, analysisIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list labels: %w", err)
+	}
+	defer rows.Close()
+
+	labels := map[uuid.UUID][]Classification{}
+	for rows.Next() {
+		var analysisID uuid.UUID
+		var c Classification
+		if err := rows.Scan(&analysisID, &c.Label, &c.Confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		labels[analysisID] = append(labels[analysisID], c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read labels: %w", err)
+	}
+
+	return labels, nil
+}
+
+// TaxonomyStore persists organizations' taxonomies
+type TaxonomyStore struct {
+	db *pgxpool.Pool
+}
+
+// NewTaxonomyStore creates a new taxonomy store
+func NewTaxonomyStore(db *pgxpool.Pool) *TaxonomyStore {
+	return &TaxonomyStore{db: db}
+}
+
+// Taxonomy returns an organization's taxonomy, which has no labels if none
+// were set
+func (s *TaxonomyStore) Taxonomy(ctx context.Context, orgID uuid.UUID) (*Taxonomy, error) {
+	taxonomy, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Taxonomy, error) {
+		return s.taxonomy(ctx, s.db, orgID)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get taxonomy: %w", err)
+	}
+	return taxonomy, nil
+}
+
+// taxonomy reads an organization's labels in the order they were listed
+func (s *TaxonomyStore) taxonomy(ctx context.Context, db rowsQuerier, orgID uuid.UUID) (*Taxonomy, error) {
+	rows, err := db.Query(ctx, `
+		SELECT name, description, updated_at
+		FROM taxonomy_labels
+		WHERE org_id = $1
+		ORDER BY position
+	`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	taxonomy := &Taxonomy{OrgID: orgID, Labels: []TaxonomyLabel{}}
+	for rows.Next() {
+		var l TaxonomyLabel
+		var updatedAt timestamp.Time
+		if err := rows.Scan(&l.Name, &l.Description, &updatedAt); err != nil {
+			return nil, err
+		}
+		taxonomy.Labels = append(taxonomy.Labels, l)
+		if taxonomy.UpdatedAt == nil || updatedAt.After(taxonomy.UpdatedAt.Time) {
+			taxonomy.UpdatedAt = &updatedAt
+		}
+	}
+	return taxonomy, rows.Err()
+}
+
+// SetTaxonomy replaces an organization's labels and returns its taxonomy.
+// An empty list removes the taxonomy.
+func (s *TaxonomyStore) SetTaxonomy(ctx context.Context, orgID uuid.UUID, labels []TaxonomyLabel) (*Taxonomy, error) {
+	if errs := ValidateTaxonomy(labels); len(errs) > 0 {
+		return nil, errors.New("invalid taxonomy labels")
+	}
+
+	taxonomy, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Taxonomy, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM taxonomy_labels WHERE org_id = $1`, orgID); err != nil {
+			return nil, err
+		}
+		for i, l := range labels {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO taxonomy_labels (org_id, name, description, position)
+				VALUES ($1, $2, $3, $4)
+			`, orgID, strings.TrimSpace(l.Name), l.Description, i); err != nil {
+				return nil, err
+			}
+		}
+
+		taxonomy, err := s.taxonomy(ctx, tx, orgID)
+		if err != nil {
+			return nil, err
+		}
+		return taxonomy, tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set taxonomy: %w", err)
+	}
+	return taxonomy, nil
+}
+
+// TaxonomyForUser returns the labels userID's submissions are classified
+// into, or nil if none of their organizations has a taxonomy. A member of
+// several organizations gets the labels of all of them, each name once.
+func (s *TaxonomyStore) TaxonomyForUser(ctx context.Context, userID uuid.UUID) (*Taxonomy, error) {
+	taxonomy, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Taxonomy, error) {
+		rows, err := s.db.Query(ctx, `
+			SELECT DISTINCT ON (LOWER(t.name)) t.name, t.description
+			FROM taxonomy_labels t
+			JOIN organization_members m ON m.org_id = t.org_id
+			WHERE m.user_id = $1
+			ORDER BY LOWER(t.name), t.org_id
+		`, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var labels []TaxonomyLabel
+		for rows.Next() {
+			var l TaxonomyLabel
+			if err := rows.Scan(&l.Name, &l.Description); err != nil {
+				return nil, err
+			}
+			labels = append(labels, l)
+		}
+		if err := rows.Err(); err != nil || len(labels) == 0 {
+			return nil, err
+		}
+		return &Taxonomy{Labels: labels}, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get taxonomy: %w", err)
+	}
+	return taxonomy, nil
+}
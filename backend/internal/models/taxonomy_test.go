@@ -0,0 +1,42 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTaxonomy(t *testing.T) {
+	tests := []struct {
+		name      string
+		labels    []TaxonomyLabel
+		wantField string
+	}{
+		{name: "empty", labels: nil},
+		{name: "valid", labels: []TaxonomyLabel{
+			{Name: "Bug report", Description: "Something doesn't work"},
+			{Name: "Feature request"},
+		}},
+		{name: "blank name", labels: []TaxonomyLabel{{Name: " "}}, wantField: "labels[0].name"},
+		{name: "long name", labels: []TaxonomyLabel{{Name: strings.Repeat("a", MaxLabelNameLength+1)}}, wantField: "labels[0].name"},
+		{name: "duplicate ignoring case", labels: []TaxonomyLabel{{Name: "Complaint"}, {Name: "complaint "}}, wantField: "labels[1].name"},
+		{name: "long description", labels: []TaxonomyLabel{
+			{Name: "Bug", Description: strings.Repeat("a", MaxLabelDescriptionLength+1)},
+		}, wantField: "labels[0].description"},
+		{name: "too many", labels: make([]TaxonomyLabel, MaxTaxonomyLabels+1), wantField: "labels"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateTaxonomy(tt.labels)
+			if tt.wantField == "" {
+				if len(errs) > 0 {
+					t.Errorf("ValidateTaxonomy() = %v, want none", errs)
+				}
+				return
+			}
+			if _, ok := errs[tt.wantField]; !ok {
+				t.Errorf("ValidateTaxonomy() = %v, want an error on %s", errs, tt.wantField)
+			}
+		})
+	}
+}
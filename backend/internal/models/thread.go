@@ -0,0 +1,411 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// MessageRole identifies who wrote a thread message
+type MessageRole string
+
+const (
+	MessageRoleUser      MessageRole = "user"
+	MessageRoleAssistant MessageRole = "assistant"
+)
+
+const (
+	// MaxThreadMessageLength is the longest message a user can post, in
+	// characters
+	MaxThreadMessageLength = 2000
+	// MaxThreadMessages caps the messages in one thread, replies included
+	MaxThreadMessages = 100
+	// maxThreadTitleLength is how much of the first message titles a thread
+	maxThreadTitleLength = 80
+)
+
+var (
+	// ErrThreadBusy is returned when a message is posted before the
+	// previous one was answered
+	ErrThreadBusy = errors.New("the previous message hasn't been answered yet")
+	// ErrThreadFull is returned once a thread has MaxThreadMessages messages
+	ErrThreadFull = fmt.Errorf("a thread can have at most %d messages", MaxThreadMessages)
+)
+
+// Thread is a conversation about a submission's analysis in which the
+// user asks follow-up questions and the model replies
+type Thread struct {
+	ID           uuid.UUID      `json:"id"`
+	SubmissionID uuid.UUID      `json:"submission_id"`
+	UserID       uuid.UUID      `json:"user_id"`
+	Title        string         `json:"title"`
+	MessageCount int            `json:"message_count"`
+	Pending      bool           `json:"pending"`
+	CreatedAt    timestamp.Time `json:"created_at"`
+	UpdatedAt    timestamp.Time `json:"updated_at"`
+
+	// The conversation, oldest first; only loaded for a single thread
+	Messages []ThreadMessage `json:"messages,omitempty"`
+}
+
+// ThreadMessage is one message of a thread. A failed user message is one
+// the model never answered; the user can post it again.
+type ThreadMessage struct {
+	ID        uuid.UUID      `json:"id"`
+	Role      MessageRole    `json:"role"`
+	Content   string         `json:"content"`
+	Failed    bool           `json:"failed"`
+	CreatedAt timestamp.Time `json:"created_at"`
+
+	// Model usage of a reply
+	PromptTokens int   `json:"-"`
+	OutputTokens int   `json:"-"`
+	CostMicros   int64 `json:"-"`
+}
+
+// Awaiting reports whether the last message is a user message still
+// waiting for its reply
+func Awaiting(messages []ThreadMessage) bool {
+	if len(messages) == 0 {
+		return false
+	}
+	last := messages[len(messages)-1]
+	return last.Role == MessageRoleUser && !last.Failed
+}
+
+// ThreadTitle derives a thread's title from its first message
+func ThreadTitle(message string) string {
+	if utf8.RuneCountInString(message) <= maxThreadTitleLength {
+		return message
+	}
+	runes := 0
+	for i := range message {
+		if runes == maxThreadTitleLength-1 {
+			return message[:i] + "…"
+		}
+		runes++
+	}
+	return message
+}
+
+// ThreadStore persists conversation threads
+type ThreadStore struct {
+	db     *pgxpool.Pool
+	cipher *encryption.Encryptor
+}
+
+// NewThreadStore creates a new thread store
+func NewThreadStore(db *pgxpool.Pool) *ThreadStore {
+	return &ThreadStore{db: db}
+}
+
+// WithEncryption encrypts thread titles and messages at rest and returns
+// the store
+func (s *ThreadStore) WithEncryption(cipher *encryption.Encryptor) *ThreadStore {
+	s.cipher = cipher
+	return s
+}
+
+// threadColumns selects a thread aliased as t along with its message
+// count and whether its last message awaits a reply
+const threadColumns = `t.id, t.submission_id, t.user_id, t.title, t.created_at, t.updated_at,
+	(SELECT COUNT(*) FROM thread_messages m WHERE m.thread_id = t.id),
+	COALESCE((
+		SELECT m.role = 'user' AND NOT m.failed FROM thread_messages m
+		WHERE m.thread_id = t.id ORDER BY m.position DESC LIMIT 1
+	), FALSE)`
+
+// scanThread reads a row selected with threadColumns
+func (s *ThreadStore) scanThread(ctx context.Context, row pgx.Row) (*Thread, error) {
+	var t Thread
+	if err := row.Scan(
+		&t.ID,
+		&t.SubmissionID,
+		&t.UserID,
+		&t.Title,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+		&t.MessageCount,
+		&t.Pending,
+	); err != nil {
+		return nil, err
+	}
+
+	title, err := openField(ctx, s.cipher, t.Title, record{t.ID, t.UserID}.bind(fieldThreadTitle))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt thread title: %w", err)
+	}
+	t.Title = title
+	return &t, nil
+}
+
+// ListForSubmission returns the user's threads on a submission, most
+// recently active first, without their messages
+func (s *ThreadStore) ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]Thread, error) {
+	query := `
+		SELECT ` + threadColumns + `
+		FROM threads t
+		WHERE t.submission_id = $1 AND t.user_id = $2
+		ORDER BY t.updated_at DESC
+	`
+
+	threads, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Thread, error) {
+		rows, err := s.db.Query(ctx, query, submissionID, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		threads := []Thread{}
+		for rows.Next() {
+			thread, err := s.scanThread(ctx, rows)
+			if err != nil {
+				return nil, err
+			}
+			threads = append(threads, *thread)
+		}
+		return threads, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list threads: %w", err)
+	}
+
+	return threads, nil
+}
+
+// Get retrieves a thread and its messages regardless of owner. It is
+// meant for background jobs; handlers should use GetByID.
+func (s *ThreadStore) Get(ctx context.Context, id uuid.UUID) (*Thread, error) {
+	return s.get(ctx, `SELECT `+threadColumns+` FROM threads t WHERE t.id = $1`, id)
+}
+
+// GetByID retrieves a thread owned by the given user, with its messages
+func (s *ThreadStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*Thread, error) {
+	return s.get(ctx, `SELECT `+threadColumns+` FROM threads t WHERE t.id = $1 AND t.user_id = $2`, id, userID)
+}
+
+// get reads one thread and its messages
+func (s *ThreadStore) get(ctx context.Context, query string, args ...any) (*Thread, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Thread, error) {
+		thread, err := s.scanThread(ctx, s.db.QueryRow(ctx, query, args...))
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := s.db.Query(ctx, `
+			SELECT id, role, content, failed, prompt_tokens, output_tokens, cost_micros, created_at
+			FROM thread_messages
+			WHERE thread_id = $1
+			ORDER BY position
+		`, thread.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		thread.Messages = []ThreadMessage{}
+		for rows.Next() {
+			var m ThreadMessage
+			if err := rows.Scan(&m.ID, &m.Role, &m.Content, &m.Failed, &m.PromptTokens, &m.OutputTokens, &m.CostMicros, &m.CreatedAt); err != nil {
+				return nil, err
+			}
+			if m.Content, err = openField(ctx, s.cipher, m.Content, record{m.ID, thread.UserID}.bind(fieldThreadMessage)); err != nil {
+				return nil, fmt.Errorf("failed to decrypt thread message: %w", err)
+			}
+			thread.Messages = append(thread.Messages, m)
+		}
+		return thread, rows.Err()
+	})
+}
+
+// Create starts a thread on a submission with the user's first message
+func (s *ThreadStore) Create(ctx context.Context, userID, submissionID uuid.UUID, message string) (*Thread, error) {
+	thread, first := newRecord(userID), newRecord(userID)
+	title, err := sealField(ctx, s.cipher, ThreadTitle(message), thread.bind(fieldThreadTitle))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt thread title: %w", err)
+	}
+	content, err := sealField(ctx, s.cipher, message, first.bind(fieldThreadMessage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt thread message: %w", err)
+	}
+
+	err = resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO threads (id, submission_id, user_id, title) VALUES ($1, $2, $3, $4)`,
+			thread.id, submissionID, userID, title,
+		); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO thread_messages (id, thread_id, position, role, content) VALUES ($1, $2, 1, $3, $4)`,
+			first.id, thread.id, MessageRoleUser, content,
+		); err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create thread: %w", err)
+	}
+
+	return s.GetByID(ctx, userID, thread.id)
+}
+
+// AddMessage appends a user message to a thread owned by the given user.
+// It returns ErrThreadBusy while the previous message awaits its reply,
+// ErrThreadFull once the thread has MaxThreadMessages messages and
+// pgx.ErrNoRows if the thread doesn't exist.
+func (s *ThreadStore) AddMessage(ctx context.Context, userID, threadID uuid.UUID, message string) (*Thread, error) {
+	r := newRecord(userID)
+	content, err := sealField(ctx, s.cipher, message, r.bind(fieldThreadMessage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt thread message: %w", err)
+	}
+
+	err = s.append(ctx, threadID, &userID, func(count int, awaiting bool) error {
+		switch {
+		case awaiting:
+			return ErrThreadBusy
+		case count >= MaxThreadMessages:
+			return ErrThreadFull
+		}
+		return nil
+	}, &ThreadMessage{ID: r.id, Role: MessageRoleUser, Content: content})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetByID(ctx, userID, threadID)
+}
+
+// AddReply appends the model's reply to a thread. It returns ErrThreadBusy
+// if the last message isn't awaiting a reply, such as when a retried job
+// already answered it.
+func (s *ThreadStore) AddReply(ctx context.Context, threadID uuid.UUID, reply *ThreadMessage) error {
+	// The reply is bound to the thread's owner
+	owner, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (uuid.UUID, error) {
+		var owner uuid.UUID
+		err := s.db.QueryRow(ctx, `SELECT user_id FROM threads WHERE id = $1`, threadID).Scan(&owner)
+		return owner, err
+	})
+	if err != nil {
+		return err
+	}
+	r := newRecord(owner)
+	content, err := sealField(ctx, s.cipher, reply.Content, r.bind(fieldThreadMessage))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt thread message: %w", err)
+	}
+
+	stored := *reply
+	stored.ID = r.id
+	stored.Role = MessageRoleAssistant
+	stored.Content = content
+
+	return s.append(ctx, threadID, nil, func(_ int, awaiting bool) error {
+		if !awaiting {
+			return ErrThreadBusy
+		}
+		return nil
+	}, &stored)
+}
+
+// append locks a thread, lets check reject the message based on the
+// thread's message count and whether it awaits a reply, then stores it.
+// A non-nil userID restricts it to that user's threads.
+func (s *ThreadStore) append(ctx context.Context, threadID uuid.UUID, userID *uuid.UUID, check func(count int, awaiting bool) error, message *ThreadMessage) error {
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		var locked uuid.UUID
+		if err := tx.QueryRow(ctx, `
+			SELECT id FROM threads WHERE id = $1 AND ($2::uuid IS NULL OR user_id = $2) FOR UPDATE
+		`, threadID, userID).Scan(&locked); err != nil {
+			return err
+		}
+
+		var count int
+		var awaiting bool
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*), COALESCE((
+				SELECT role = 'user' AND NOT failed FROM thread_messages
+				WHERE thread_id = $1 ORDER BY position DESC LIMIT 1
+			), FALSE)
+			FROM thread_messages WHERE thread_id = $1
+		`, threadID).Scan(&count, &awaiting); err != nil {
+			return err
+		}
+		if err := check(count, awaiting); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO thread_messages (id, thread_id, position, role, content, prompt_tokens, output_tokens, cost_micros)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		`, message.ID, threadID, count+1, message.Role, message.Content, message.PromptTokens, message.OutputTokens, message.CostMicros); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, `UPDATE threads SET updated_at = NOW() WHERE id = $1`, threadID); err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil && !errors.Is(err, ErrThreadBusy) && !errors.Is(err, ErrThreadFull) && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to add thread message: %w", err)
+	}
+	return err
+}
+
+// MarkFailed flags a thread's last message as unanswered when it is a
+// user message, so the user can post again
+func (s *ThreadStore) MarkFailed(ctx context.Context, threadID uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE thread_messages SET failed = TRUE
+			WHERE thread_id = $1 AND role = 'user'
+			AND position = (SELECT MAX(position) FROM thread_messages WHERE thread_id = $1)
+		`, threadID)
+		if err != nil {
+			return fmt.Errorf("failed to mark thread message failed: %w", err)
+		}
+		return nil
+	})
+}
+
+// Delete removes a thread owned by the given user along with its messages
+func (s *ThreadStore) Delete(ctx context.Context, userID, id uuid.UUID) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM threads WHERE id = $1 AND user_id = $2`, id, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete thread: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return pgx.ErrNoRows
+		}
+		return nil
+	})
+}
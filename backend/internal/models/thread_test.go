@@ -0,0 +1,37 @@
+package models
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestThreadTitle(t *testing.T) {
+	if got := ThreadTitle("List the risks"); got != "List the risks" {
+		t.Errorf("ThreadTitle() = %q, want the message", got)
+	}
+
+	got := ThreadTitle(strings.Repeat("ü", 200))
+	if utf8.RuneCountInString(got) != maxThreadTitleLength || !strings.HasSuffix(got, "…") {
+		t.Errorf("ThreadTitle() = %q, want %d characters ending in an ellipsis", got, maxThreadTitleLength)
+	}
+}
+
+func TestAwaiting(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []ThreadMessage
+		want     bool
+	}{
+		{"empty", nil, false},
+		{"user message", []ThreadMessage{{Role: MessageRoleUser}}, true},
+		{"answered", []ThreadMessage{{Role: MessageRoleUser}, {Role: MessageRoleAssistant}}, false},
+		{"failed", []ThreadMessage{{Role: MessageRoleUser, Failed: true}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Awaiting(tt.messages); got != tt.want {
+			t.Errorf("Awaiting() %s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+// tieredContent is the object a tiered submission's content is moved to.
+// The values stay sealed as they were in Postgres, so encrypted content
+// stays encrypted in object storage.
+type tieredContent struct {
+	Content         string  `json:"content"`
+	RedactedContent *string `json:"redacted_content,omitempty"`
+}
+
+// loadContent reads a tiered submission's content back from object
+// storage, still sealed
+func (s *SubmissionStore) loadContent(ctx context.Context, submission *Submission) error {
+	content, err := readTieredContent(ctx, s.objects, *submission.contentKey)
+	if err != nil {
+		return err
+	}
+	submission.Content, submission.RedactedContent = content.Content, content.RedactedContent
+	return nil
+}
+
+// readTieredContent reads the object a submission's content was tiered to
+func readTieredContent(ctx context.Context, objects storage.Storage, key string) (*tieredContent, error) {
+	data, err := getObject(ctx, objects, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var content tieredContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	return &content, nil
+}
+
+// tierCandidatesQuery selects up to $6 finished submissions created before
+// $1 whose content is still in Postgres, oldest first
+const tierCandidatesQuery = `
+	SELECT id, content, redacted_content FROM submissions
+	WHERE content_key IS NULL AND created_at < $1 AND status IN ($2, $3, $4, $5)
+	ORDER BY created_at
+	LIMIT $6`
+
+// tierQuery points submission $1 at object $2 and empties its content,
+// unless the content is no longer $3, and records the object as the
+// submission's
+const tierQuery = `
+	WITH tiered AS (
+		UPDATE submissions
+		SET content = '', redacted_content = NULL, content_key = $2
+		WHERE id = $1 AND content_key IS NULL AND content = $3
+		RETURNING id
+	)
+	UPDATE content_objects SET submission_id = tiered.id
+	FROM tiered
+	WHERE object_key = $2`
+
+// TierContent moves the content of up to limit completed, failed, canceled
+// or archived submissions created before createdBefore to object storage,
+// and returns how many it moved. Their metadata and analyses stay in
+// Postgres. Call it until it returns fewer than limit to catch up.
+func (s *SubmissionStore) TierContent(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	if s.objects == nil {
+		return 0, errors.New("content tiering needs object storage")
+	}
+
+	type candidate struct {
+		id       uuid.UUID
+		content  string
+		redacted *string
+	}
+	candidates, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]candidate, error) {
+		rows, err := s.db.Query(ctx, tierCandidatesQuery, createdBefore, StatusCompleted, StatusFailed, StatusCanceled, StatusArchived, limit)
+		if err != nil {
+			return nil, err
+		}
+		return pgx.CollectRows(rows, func(row pgx.CollectableRow) (candidate, error) {
+			var c candidate
+			err := row.Scan(&c.id, &c.content, &c.redacted)
+			return c, err
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list submissions to tier: %w", err)
+	}
+
+	tiered := 0
+	for _, c := range candidates {
+		moved, err := s.tier(ctx, c.id, c.content, c.redacted)
+		if err != nil {
+			return tiered, fmt.Errorf("failed to tier submission %s: %w", c.id, err)
+		}
+		if moved {
+			tiered++
+		}
+	}
+	return tiered, nil
+}
+
+// tier moves one submission's sealed content to a new object. The object
+// is recorded before it is written, so one left behind by a failure is
+// removed by PurgeContentObjects.
+func (s *SubmissionStore) tier(ctx context.Context, id uuid.UUID, content string, redacted *string) (bool, error) {
+	data, err := json.Marshal(tieredContent{Content: content, RedactedContent: redacted})
+	if err != nil {
+		return false, err
+	}
+
+	key := newObjectKey("submissions")
+	err = resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `INSERT INTO content_objects (object_key) VALUES ($1) ON CONFLICT DO NOTHING`, key)
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record object: %w", err)
+	}
+	if err := s.objects.Put(ctx, key, data, "application/json"); err != nil {
+		return false, fmt.Errorf("failed to store content: %w", err)
+	}
+
+	moved, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (int64, error) {
+		tag, err := s.db.Exec(ctx, tierQuery, id, key, content)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return false, err
+	}
+	return moved > 0, nil
+}
+
+// purgeContentObjectsQuery forgets up to $1 objects that no submission
+// points at, leaving an hour for tiering in progress to claim its object
+const purgeContentObjectsQuery = `
+	DELETE FROM content_objects
+	WHERE object_key IN (
+		SELECT object_key FROM content_objects
+		WHERE submission_id IS NULL AND created_at < NOW() - INTERVAL '1 hour'
+		LIMIT $1
+	)
+	RETURNING object_key`
+
+// PurgeContentObjects deletes up to limit objects of tiered content whose
+// submission was deleted, or that an interrupted TierContent left behind,
+// and returns how many it deleted
+func (s *SubmissionStore) PurgeContentObjects(ctx context.Context, limit int) (int, error) {
+	keys, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) ([]string, error) {
+		rows, err := s.db.Query(ctx, purgeContentObjectsQuery, limit)
+		if err != nil {
+			return nil, err
+		}
+		return pgx.CollectRows(rows, pgx.RowTo[string])
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge content objects: %w", err)
+	}
+
+	for _, key := range keys {
+		deleteObject(ctx, s.objects, &key)
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+func TestSubmissionStore_LoadContent(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	objects := storage.NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	store := NewSubmissionStore(nil).WithEncryption(encryption.New(keys)).WithStorage(objects)
+
+	// Tiered content is stored sealed, as it was in Postgres
+	masked := "Call me at [PHONE]"
+	content, redacted, err := store.sealContent(ctx, record{uuid.New(), uuid.New()}, "Call me at 555-0100", &masked)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(tieredContent{Content: content, RedactedContent: redacted})
+	key := newObjectKey("submissions")
+	if err := objects.Put(ctx, key, data, "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	submission := &Submission{contentKey: &key}
+	if err := store.loadContent(ctx, submission); err != nil {
+		t.Fatalf("loadContent() error = %v", err)
+	}
+	if submission.Content != content || submission.RedactedContent == nil || *submission.RedactedContent != *redacted {
+		t.Errorf("loadContent() = %q, %v, want the sealed content", submission.Content, submission.RedactedContent)
+	}
+	if strings.Contains(string(data), "555-0100") {
+		t.Errorf("tiered object %s holds plaintext", data)
+	}
+
+	missing := newObjectKey("submissions")
+	if err := store.loadContent(ctx, &Submission{contentKey: &missing}); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("loadContent() of a missing object error = %v, want storage.ErrNotFound", err)
+	}
+	if err := NewSubmissionStore(nil).loadContent(ctx, &Submission{contentKey: &key}); !errors.Is(err, errNoObjectStorage) {
+		t.Errorf("loadContent() without storage error = %v, want errNoObjectStorage", err)
+	}
+}
@@ -0,0 +1,383 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// SubmissionText is the minimal submission data needed for embedding
+type SubmissionText struct {
+	ID      uuid.UUID
+	UserID  uuid.UUID
+	Content string
+}
+
+// SubmissionEmbedding is a stored embedding vector for a submission
+type SubmissionEmbedding struct {
+	SubmissionID uuid.UUID
+	Vector       []float32
+	Excerpt      string
+}
+
+// TopicCluster is a group of semantically related submissions
+type TopicCluster struct {
+	ID              uuid.UUID             `json:"id"`
+	Label           string                `json:"label"`
+	Size            int                   `json:"size"`
+	CreatedAt       timestamp.Time        `json:"created_at"`
+	Representatives []TopicRepresentative `json:"representatives"`
+
+	// Centroid and Members are only populated when writing clusters
+	Centroid []float32            `json:"-"`
+	Members  []TopicClusterMember `json:"-"`
+}
+
+// TopicClusterMember links a submission to its cluster
+type TopicClusterMember struct {
+	SubmissionID uuid.UUID
+	Distance     float64
+}
+
+// TopicRepresentative is a submission close to its cluster's centroid
+type TopicRepresentative struct {
+	SubmissionID uuid.UUID `json:"submission_id"`
+	Excerpt      string    `json:"excerpt"`
+	Distance     float64   `json:"distance"`
+}
+
+// TopicStore handles embeddings and topic clusters
+type TopicStore struct {
+	db      *pgxpool.Pool
+	replica ReadRouter
+	cipher  *encryption.Encryptor
+	objects storage.Storage
+}
+
+// NewTopicStore creates a new topic store
+func NewTopicStore(db *pgxpool.Pool) *TopicStore {
+	return &TopicStore{db: db}
+}
+
+// WithReplica sends ListClusters through router and returns the store
+func (s *TopicStore) WithReplica(router ReadRouter) *TopicStore {
+	s.replica = router
+	return s
+}
+
+// WithEncryption decrypts submission content encrypted by the submission
+// store and returns the store
+func (s *TopicStore) WithEncryption(cipher *encryption.Encryptor) *TopicStore {
+	s.cipher = cipher
+	return s
+}
+
+// WithStorage reads excerpts of submissions whose content was tiered to
+// object storage from there and returns the store
+func (s *TopicStore) WithStorage(objects storage.Storage) *TopicStore {
+	s.objects = objects
+	return s
+}
+
+// excerpt opens a value selected with excerptSQL from a submission and
+// cuts it to n characters. Tiered submissions have no content left in
+// Postgres, so theirs is read back from object storage first.
+func (s *TopicStore) excerpt(ctx context.Context, submission record, value string, contentKey *string, n int) (string, error) {
+	if contentKey != nil {
+		content, err := readTieredContent(ctx, s.objects, *contentKey)
+		if err != nil {
+			return "", err
+		}
+		value = content.Content
+	}
+	return openExcerpt(ctx, s.cipher, value, submission, n)
+}
+
+// SubmissionsMissingEmbeddings returns submissions that have not been
+// embedded yet, leaving out those whose content was tiered to object storage
+// and quarantined or trashed ones, whose content mustn't reach the
+// embedding provider
+func (s *TopicStore) SubmissionsMissingEmbeddings(ctx context.Context, limit int) ([]SubmissionText, error) {
+	query := `
+		SELECT s.id, s.user_id, s.content
+		FROM submissions s
+		LEFT JOIN submission_embeddings e ON e.submission_id = s.id
+		WHERE e.submission_id IS NULL AND s.content_key IS NULL
+		  AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY s.created_at
+		LIMIT $1
+	`
+
+	rows, err := s.db.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query submissions: %w", err)
+	}
+
+	texts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SubmissionText, error) {
+		var t SubmissionText
+		if err := row.Scan(&t.ID, &t.UserID, &t.Content); err != nil {
+			return t, err
+		}
+		content, err := openField(ctx, s.cipher, t.Content, record{t.ID, t.UserID}.bind(fieldSubmissionContent))
+		t.Content = content
+		return t, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read submissions: %w", err)
+	}
+
+	return texts, nil
+}
+
+// SaveEmbedding stores (or replaces) the embedding of a submission
+func (s *TopicStore) SaveEmbedding(ctx context.Context, submissionID uuid.UUID, model string, vector []float32) error {
+	query := `
+		INSERT INTO submission_embeddings (submission_id, model, embedding)
+		VALUES ($1, $2, $3::vector)
+		ON CONFLICT (submission_id) DO UPDATE
+		SET model = EXCLUDED.model, embedding = EXCLUDED.embedding, created_at = NOW()
+	`
+
+	if _, err := s.db.Exec(ctx, query, submissionID, model, formatVector(vector)); err != nil {
+		return fmt.Errorf("failed to save embedding: %w", err)
+	}
+
+	return nil
+}
+
+// UsersWithEmbeddings returns users that have at least minCount embedded submissions
+func (s *TopicStore) UsersWithEmbeddings(ctx context.Context, minCount int) ([]uuid.UUID, error) {
+	query := `
+		SELECT s.user_id
+		FROM submission_embeddings e
+		JOIN submissions s ON s.id = e.submission_id
+		WHERE s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		GROUP BY s.user_id
+		HAVING COUNT(*) >= $1
+	`
+
+	rows, err := s.db.Query(ctx, query, minCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+
+	users, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+
+	return users, nil
+}
+
+// EmbeddingsForUser returns all embeddings of a user's submissions, with a
+// short excerpt of each submission for labeling. Quarantined and trashed
+// submissions are left out.
+func (s *TopicStore) EmbeddingsForUser(ctx context.Context, userID uuid.UUID) ([]SubmissionEmbedding, error) {
+	query := `
+		SELECT e.submission_id, e.embedding::text, ` + fmt.Sprintf(excerptSQL, "s.content", 500) + `, s.content_key
+		FROM submission_embeddings e
+		JOIN submissions s ON s.id = e.submission_id
+		WHERE s.user_id = $1 AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		ORDER BY s.created_at
+	`
+
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embeddings: %w", err)
+	}
+
+	embeddings, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SubmissionEmbedding, error) {
+		var e SubmissionEmbedding
+		var vector string
+		var contentKey *string
+		if err := row.Scan(&e.SubmissionID, &vector, &e.Excerpt, &contentKey); err != nil {
+			return e, err
+		}
+		excerpt, err := s.excerpt(ctx, record{e.SubmissionID, userID}, e.Excerpt, contentKey, 500)
+		if err != nil {
+			return e, err
+		}
+		e.Excerpt = excerpt
+		v, err := parseVector(vector)
+		e.Vector = v
+		return e, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings: %w", err)
+	}
+
+	return embeddings, nil
+}
+
+// ReplaceClusters atomically swaps a user's clusters for a new set
+func (s *TopicStore) ReplaceClusters(ctx context.Context, userID uuid.UUID, clusters []TopicCluster) error {
+	return resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.replaceClusters(ctx, userID, clusters)
+	})
+}
+
+// replaceClusters runs one attempt of ReplaceClusters' transaction
+func (s *TopicStore) replaceClusters(ctx context.Context, userID uuid.UUID, clusters []TopicCluster) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM topic_clusters WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete clusters: %w", err)
+	}
+
+	for _, c := range clusters {
+		var clusterID uuid.UUID
+		err := tx.QueryRow(ctx, `
+			INSERT INTO topic_clusters (user_id, label, size, centroid)
+			VALUES ($1, $2, $3, $4::vector)
+			RETURNING id
+		`, userID, c.Label, len(c.Members), formatVector(c.Centroid)).Scan(&clusterID)
+		if err != nil {
+			return fmt.Errorf("failed to insert cluster: %w", err)
+		}
+
+		for _, m := range c.Members {
+			if _, err := tx.Exec(ctx, `
+				INSERT INTO topic_cluster_members (cluster_id, submission_id, distance)
+				VALUES ($1, $2, $3)
+			`, clusterID, m.SubmissionID, m.Distance); err != nil {
+				return fmt.Errorf("failed to insert cluster member: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit clusters: %w", err)
+	}
+
+	return nil
+}
+
+// ListClusters returns a user's clusters, largest first, each with up to
+// perCluster representative submissions closest to the centroid. Submissions
+// quarantined or trashed since the clusters were computed aren't shown.
+func (s *TopicStore) ListClusters(ctx context.Context, userID uuid.UUID, perCluster int) ([]TopicCluster, error) {
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]TopicCluster, error) {
+		return s.listClusters(ctx, userID, perCluster)
+	})
+}
+
+// listClusters runs one attempt of ListClusters
+func (s *TopicStore) listClusters(ctx context.Context, userID uuid.UUID, perCluster int) ([]TopicCluster, error) {
+	query := `
+		WITH ranked AS (
+			SELECT
+				m.cluster_id,
+				m.submission_id,
+				m.distance,
+				` + fmt.Sprintf(excerptSQL, "s.content", 280) + ` AS excerpt,
+				s.content_key,
+				ROW_NUMBER() OVER (PARTITION BY m.cluster_id ORDER BY m.distance) AS rank
+			FROM topic_cluster_members m
+			JOIN submissions s ON s.id = m.submission_id
+			JOIN topic_clusters c ON c.id = m.cluster_id
+			WHERE c.user_id = $1 AND s.quarantined_at IS NULL AND s.deleted_at IS NULL
+		)
+		SELECT c.id, c.label, c.size, c.created_at, r.submission_id, r.excerpt, r.content_key, r.distance
+		FROM topic_clusters c
+		LEFT JOIN ranked r ON r.cluster_id = c.id AND r.rank <= $2
+		WHERE c.user_id = $1
+		ORDER BY c.size DESC, c.id, r.rank
+	`
+
+	rows, err := readPool(s.db, s.replica).Query(ctx, query, userID, perCluster)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clusters: %w", err)
+	}
+	defer rows.Close()
+
+	clusters := []TopicCluster{}
+	for rows.Next() {
+		var c TopicCluster
+		var submissionID *uuid.UUID
+		var excerpt, contentKey *string
+		var distance *float64
+
+		if err := rows.Scan(&c.ID, &c.Label, &c.Size, &c.CreatedAt, &submissionID, &excerpt, &contentKey, &distance); err != nil {
+			return nil, fmt.Errorf("failed to scan cluster: %w", err)
+		}
+
+		if n := len(clusters); n == 0 || clusters[n-1].ID != c.ID {
+			c.Representatives = []TopicRepresentative{}
+			clusters = append(clusters, c)
+		}
+
+		if submissionID != nil {
+			text, err := s.excerpt(ctx, record{*submissionID, userID}, *excerpt, contentKey, 280)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read excerpt of submission %s: %w", *submissionID, err)
+			}
+			*excerpt = text
+
+			last := &clusters[len(clusters)-1]
+			last.Representatives = append(last.Representatives, TopicRepresentative{
+				SubmissionID: *submissionID,
+				Excerpt:      *excerpt,
+				Distance:     *distance,
+			})
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read clusters: %w", err)
+	}
+
+	return clusters, nil
+}
+
+// formatVector encodes a vector in pgvector's text format: [1,2,3]
+func formatVector(v []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, x := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(x), 'f', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// parseVector decodes pgvector's text format
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '[' || s[len(s)-1] != ']' {
+		return nil, fmt.Errorf("invalid vector literal")
+	}
+
+	body := s[1 : len(s)-1]
+	if body == "" {
+		return []float32{}, nil
+	}
+
+	parts := strings.Split(body, ",")
+	v := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", p, err)
+		}
+		v[i] = float32(f)
+	}
+
+	return v, nil
+}
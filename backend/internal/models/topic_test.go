@@ -0,0 +1,83 @@
+package models
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+)
+
+func TestVectorRoundTrip(t *testing.T) {
+	original := []float32{0.5, -1.25, 3, 0.000123}
+
+	encoded := formatVector(original)
+	if encoded != "[0.5,-1.25,3,0.000123]" {
+		t.Errorf("formatVector() = %q", encoded)
+	}
+
+	decoded, err := parseVector(encoded)
+	if err != nil {
+		t.Fatalf("parseVector() error = %v", err)
+	}
+
+	if len(decoded) != len(original) {
+		t.Fatalf("parseVector() returned %d values, want %d", len(decoded), len(original))
+	}
+	for i := range original {
+		if decoded[i] != original[i] {
+			t.Errorf("parseVector()[%d] = %v, want %v", i, decoded[i], original[i])
+		}
+	}
+}
+
+func TestParseVector_Invalid(t *testing.T) {
+	for _, input := range []string{"", "1,2,3", "[1,abc]", "[1,2"} {
+		if _, err := parseVector(input); err == nil {
+			t.Errorf("parseVector(%q) expected error", input)
+		}
+	}
+}
+
+func TestTopicStore_ExcerptOfTieredSubmission(t *testing.T) {
+	ctx := context.Background()
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatalf("ParseMasterKeys() error = %v", err)
+	}
+	cipher := encryption.New(keys)
+	objects := storage.NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	store := NewTopicStore(nil).WithEncryption(cipher).WithStorage(objects)
+
+	submission := record{uuid.New(), uuid.New()}
+	content, _, err := NewSubmissionStore(nil).WithEncryption(cipher).sealContent(ctx, submission, "Tiered submissions keep their excerpts", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(tieredContent{Content: content})
+	key := newObjectKey("submissions")
+	if err := objects.Put(ctx, key, data, "application/json"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tiered row has no content left in Postgres
+	got, err := store.excerpt(ctx, submission, "", &key, 6)
+	if err != nil || got != "Tiered" {
+		t.Errorf("excerpt() of a tiered submission = %q, %v, want %q", got, err, "Tiered")
+	}
+
+	got, err = store.excerpt(ctx, submission, "In Postgres", nil, 2)
+	if err != nil || got != "In" {
+		t.Errorf("excerpt() = %q, %v, want %q", got, err, "In")
+	}
+
+	if _, err := NewTopicStore(nil).excerpt(ctx, submission, "", &key, 6); !errors.Is(err, errNoObjectStorage) {
+		t.Errorf("excerpt() without storage error = %v, want errNoObjectStorage", err)
+	}
+}
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// TranscriptionStatus is the state of an audio or video upload
+type TranscriptionStatus string
+
+const (
+	TranscriptionPending   TranscriptionStatus = "pending"
+	TranscriptionCompleted TranscriptionStatus = "completed"
+	TranscriptionFailed    TranscriptionStatus = "failed"
+)
+
+// TranscriptSegment is a stretch of speech with its offsets into the
+// recording, in seconds
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcription is an uploaded recording and, once transcribed, its
+// transcript. The transcript text becomes the content of a new submission.
+type Transcription struct {
+	ID              uuid.UUID           `json:"id"`
+	UserID          uuid.UUID           `json:"user_id"`
+	Filename        string              `json:"filename"`
+	ContentType     string              `json:"content_type"`
+	SizeBytes       int64               `json:"size_bytes"`
+	Status          TranscriptionStatus `json:"status"`
+	Error           *string             `json:"error"`
+	SubmissionID    *uuid.UUID          `json:"submission_id"`
+	Provider        *string             `json:"provider"`
+	Language        *string             `json:"language"`
+	DurationSeconds *float64            `json:"duration_seconds"`
+	Segments        []TranscriptSegment `json:"segments"`
+	CreatedAt       timestamp.Time      `json:"created_at"`
+	UpdatedAt       timestamp.Time      `json:"updated_at"`
+
+	// Analysis settings for the submission created from the transcript
+	Instructions *string    `json:"instructions,omitempty"`
+	ProfileID    *uuid.UUID `json:"profile_id,omitempty"`
+	Priority     string     `json:"-"`
+}
+
+// TranscriptionStore persists audio and video uploads and their transcripts
+type TranscriptionStore struct {
+	db      *pgxpool.Pool
+	cipher  *encryption.Encryptor
+	objects storage.Storage
+}
+
+// NewTranscriptionStore creates a new transcription store
+func NewTranscriptionStore(db *pgxpool.Pool) *TranscriptionStore {
+	return &TranscriptionStore{db: db}
+}
+
+// WithEncryption encrypts transcripts and instructions at rest and returns
+// the store. Media is deleted once transcribed, so it isn't encrypted.
+func (s *TranscriptionStore) WithEncryption(cipher *encryption.Encryptor) *TranscriptionStore {
+	s.cipher = cipher
+	return s
+}
+
+// WithStorage keeps new uploads in object storage rather than Postgres
+// and returns the store. Uploads already in Postgres are still read from
+// there.
+func (s *TranscriptionStore) WithStorage(objects storage.Storage) *TranscriptionStore {
+	s.objects = objects
+	return s
+}
+
+const transcriptionColumns = `id, user_id, filename, content_type, size_bytes, status, error, submission_id, provider, language, duration_seconds, segments, instructions, profile_id, priority, created_at, updated_at`
+
+// scan reads a row selected with transcriptionColumns
+func (s *TranscriptionStore) scan(ctx context.Context, row pgx.Row) (*Transcription, error) {
+	var t Transcription
+	var segments *string
+	if err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Filename,
+		&t.ContentType,
+		&t.SizeBytes,
+		&t.Status,
+		&t.Error,
+		&t.SubmissionID,
+		&t.Provider,
+		&t.Language,
+		&t.DurationSeconds,
+		&segments,
+		&t.Instructions,
+		&t.ProfileID,
+		&t.Priority,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	r := record{t.ID, t.UserID}
+	t.Segments = []TranscriptSegment{}
+	if segments != nil {
+		plain, err := openField(ctx, s.cipher, *segments, r.bind(fieldTranscriptSegments))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt transcript: %w", err)
+		}
+		if err := json.Unmarshal([]byte(plain), &t.Segments); err != nil {
+			return nil, fmt.Errorf("failed to decode transcript: %w", err)
+		}
+	}
+
+	if t.Instructions != nil {
+		plain, err := openField(ctx, s.cipher, *t.Instructions, r.bind(fieldTranscriptionInstructions))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt instructions: %w", err)
+		}
+		t.Instructions = &plain
+	}
+	return &t, nil
+}
+
+// Create stores an upload awaiting transcription
+func (s *TranscriptionStore) Create(ctx context.Context, t *Transcription, media []byte) (*Transcription, error) {
+	r := newRecord(t.UserID)
+	instructions, err := sealOptionalField(ctx, s.cipher, t.Instructions, r.bind(fieldTranscriptionInstructions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt instructions: %w", err)
+	}
+
+	inline, mediaKey := media, (*string)(nil)
+	if s.objects != nil {
+		key := newObjectKey("transcriptions")
+		if err := s.objects.Put(ctx, key, media, t.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to store media: %w", err)
+		}
+		inline, mediaKey = nil, &key
+	}
+
+	query := `
+		INSERT INTO transcriptions (id, user_id, filename, content_type, size_bytes, media, media_key, instructions, profile_id, priority)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING ` + transcriptionColumns
+
+	stored, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Transcription, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query,
+			r.id,
+			t.UserID,
+			t.Filename,
+			t.ContentType,
+			int64(len(media)),
+			inline,
+			mediaKey,
+			instructions,
+			t.ProfileID,
+			t.Priority,
+		))
+	})
+	if err != nil {
+		deleteObject(ctx, s.objects, mediaKey)
+		return nil, fmt.Errorf("failed to create transcription: %w", err)
+	}
+
+	return stored, nil
+}
+
+// Get retrieves a transcription by ID regardless of owner. It is meant for
+// background jobs; handlers should use GetByID.
+func (s *TranscriptionStore) Get(ctx context.Context, id uuid.UUID) (*Transcription, error) {
+	query := `SELECT ` + transcriptionColumns + ` FROM transcriptions WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Transcription, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// GetByID retrieves a transcription owned by the given user
+func (s *TranscriptionStore) GetByID(ctx context.Context, userID, id uuid.UUID) (*Transcription, error) {
+	query := `SELECT ` + transcriptionColumns + ` FROM transcriptions WHERE id = $1 AND user_id = $2`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Transcription, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// GetForSubmission retrieves the transcription a submission was created
+// from, if the user owns it
+func (s *TranscriptionStore) GetForSubmission(ctx context.Context, userID, submissionID uuid.UUID) (*Transcription, error) {
+	query := `SELECT ` + transcriptionColumns + ` FROM transcriptions WHERE submission_id = $1 AND user_id = $2`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Transcription, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, submissionID, userID))
+	})
+}
+
+// Media returns the uploaded recording of a pending transcription. It
+// returns pgx.ErrNoRows once the media has been deleted.
+func (s *TranscriptionStore) Media(ctx context.Context, id uuid.UUID) ([]byte, error) {
+	var media []byte
+	var key *string
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `
+			SELECT media, media_key FROM transcriptions
+			WHERE id = $1 AND (media IS NOT NULL OR media_key IS NOT NULL)
+		`, id).Scan(&media, &key)
+	})
+	if err != nil || key == nil {
+		return media, err
+	}
+	return getObject(ctx, s.objects, *key)
+}
+
+// Complete stores the transcript of a pending transcription and the
+// submission created from it, and deletes the media
+func (s *TranscriptionStore) Complete(ctx context.Context, t *Transcription) error {
+	segments, err := json.Marshal(t.Segments)
+	if err != nil {
+		return fmt.Errorf("failed to encode transcript: %w", err)
+	}
+	sealed, err := sealField(ctx, s.cipher, string(segments), record{t.ID, t.UserID}.bind(fieldTranscriptSegments))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt transcript: %w", err)
+	}
+
+	var mediaKey *string
+	err = resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `
+			WITH old AS (SELECT media_key FROM transcriptions WHERE id = $1 FOR UPDATE)
+			UPDATE transcriptions
+			SET status = $2, submission_id = $3, provider = $4, language = $5, duration_seconds = $6, segments = $7,
+				media = NULL, media_key = NULL, updated_at = NOW()
+			FROM old
+			WHERE id = $1 AND status = $8
+			RETURNING old.media_key
+		`, t.ID, TranscriptionCompleted, t.SubmissionID, t.Provider, t.Language, t.DurationSeconds, sealed, TranscriptionPending).Scan(&mediaKey)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete transcription: %w", err)
+	}
+
+	deleteObject(ctx, s.objects, mediaKey)
+	return nil
+}
+
+// Fail marks a pending transcription failed with a message for the user
+// and deletes the media
+func (s *TranscriptionStore) Fail(ctx context.Context, id uuid.UUID, message string) error {
+	var mediaKey *string
+	err := resilience.Writes.Do(ctx, func(ctx context.Context) error {
+		return s.db.QueryRow(ctx, `
+			WITH old AS (SELECT media_key FROM transcriptions WHERE id = $1 FOR UPDATE)
+			UPDATE transcriptions
+			SET status = $2, error = $3, media = NULL, media_key = NULL, updated_at = NOW()
+			FROM old
+			WHERE id = $1 AND status = $4
+			RETURNING old.media_key
+		`, id, TranscriptionFailed, message, TranscriptionPending).Scan(&mediaKey)
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to mark transcription failed: %w", err)
+	}
+
+	deleteObject(ctx, s.objects, mediaKey)
+	return nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// ErrAnalysisInProgress is returned when deleting a submission that is
+// queued or being analyzed; it has to be canceled first
+var ErrAnalysisInProgress = errors.New("submission is being analyzed")
+
+// Trash moves a user's submission to their trash, hiding it until it is
+// restored or purged. It returns pgx.ErrNoRows if the user has no such
+// submission outside the trash, and ErrAnalysisInProgress while it is
+// queued or processing.
+func (s *SubmissionStore) Trash(ctx context.Context, userID, id uuid.UUID) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET deleted_at = NOW(), version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL AND status NOT IN ($3, $4)
+		RETURNING ` + submissionColumns
+
+	submission, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID, StatusQueued, StatusProcessing))
+	})
+	if err == nil {
+		return submission, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to delete submission: %w", err)
+	}
+
+	// Work out which precondition failed
+	if _, err := s.GetByID(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return nil, ErrAnalysisInProgress
+}
+
+// Restore takes a submission out of a user's trash. It returns
+// pgx.ErrNoRows if the user has no such submission in the trash.
+func (s *SubmissionStore) Restore(ctx context.Context, userID, id uuid.UUID) (*Submission, error) {
+	query := `
+		UPDATE submissions
+		SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		RETURNING ` + submissionColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Submission, error) {
+		return s.scan(ctx, s.db.QueryRow(ctx, query, id, userID))
+	})
+}
+
+// ListTrash returns a page of the submissions in a user's trash, most
+// recently deleted first, and the total count
+func (s *SubmissionStore) ListTrash(ctx context.Context, userID uuid.UUID, limit, offset int) ([]Submission, int, error) {
+	var submissions []Submission
+	var total int
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM submissions WHERE user_id = $1 AND deleted_at IS NOT NULL`, userID).Scan(&total)
+		if err != nil {
+			return fmt.Errorf("failed to count deleted submissions: %w", err)
+		}
+
+		rows, err := s.db.Query(ctx, `
+			SELECT `+submissionColumns+`
+			FROM submissions
+			WHERE user_id = $1 AND deleted_at IS NOT NULL
+			ORDER BY deleted_at DESC, id
+			LIMIT $2 OFFSET $3
+		`, userID, limit, offset)
+		if err != nil {
+			return fmt.Errorf("failed to list deleted submissions: %w", err)
+		}
+		defer rows.Close()
+
+		submissions = []Submission{}
+		for rows.Next() {
+			submission, err := s.scan(ctx, rows)
+			if err != nil {
+				return fmt.Errorf("failed to scan submission: %w", err)
+			}
+			submissions = append(submissions, *submission)
+		}
+		return rows.Err()
+	})
+	return submissions, total, err
+}
+
+// EmptyTrash permanently deletes the submissions in a user's trash with
+// their analyses, and returns how many it deleted. Submissions on legal
+// hold stay in the trash.
+func (s *SubmissionStore) EmptyTrash(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `DELETE FROM submissions WHERE user_id = $1 AND deleted_at IS NOT NULL AND NOT legal_hold`
+
+	purged, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (int64, error) {
+		tag, err := s.db.Exec(ctx, query, userID)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to empty trash: %w", err)
+	}
+	return int(purged), nil
+}
+
+// purgeTrashQuery deletes up to $2 submissions moved to the trash before
+// $1, oldest first. Submissions on legal hold are kept.
+const purgeTrashQuery = `
+	DELETE FROM submissions
+	WHERE id IN (
+		SELECT id FROM submissions
+		WHERE deleted_at < $1 AND NOT legal_hold
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	)`
+
+// PurgeTrash permanently deletes up to limit submissions that were moved
+// to the trash before deletedBefore, and returns how many it deleted.
+// Call it until it returns fewer than limit to catch up.
+func (s *SubmissionStore) PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	purged, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (int64, error) {
+		tag, err := s.db.Exec(ctx, purgeTrashQuery, deletedBefore, limit)
+		return tag.RowsAffected(), err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return int(purged), nil
+}
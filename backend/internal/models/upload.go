@@ -0,0 +1,221 @@
+package models
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/storage"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// UploadStatus is the state of a direct upload
+type UploadStatus string
+
+const (
+	UploadPending  UploadStatus = "pending"
+	UploadAttached UploadStatus = "attached"
+)
+
+// uploadTokenPrefix marks upload tokens so they aren't mistaken for API
+// keys
+const uploadTokenPrefix = "up_"
+
+// Upload is a file a client puts straight into object storage with a
+// presigned URL, then redeems with its token to attach to a submission.
+// The size, type and checksum are declared when the URL is issued and
+// checked against the object when the upload is completed.
+type Upload struct {
+	ID             uuid.UUID       `json:"id"`
+	UserID         uuid.UUID       `json:"-"`
+	Key            string          `json:"-"`
+	Filename       string          `json:"filename"`
+	ContentType    string          `json:"content_type"`
+	SizeBytes      int64           `json:"size_bytes"`
+	ChecksumSHA256 string          `json:"checksum_sha256"`
+	Status         UploadStatus    `json:"status"`
+	SubmissionID   *uuid.UUID      `json:"submission_id"`
+	ExpiresAt      timestamp.Time  `json:"expires_at"`
+	AttachedAt     *timestamp.Time `json:"attached_at"`
+	CreatedAt      timestamp.Time  `json:"created_at"`
+
+	// DownloadURL is a presigned URL for the object, set on attachments
+	// listed for a submission
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// NewUploadToken returns a random token such as "up_3f9a..."
+func NewUploadToken() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate upload token: %w", err)
+	}
+	return uploadTokenPrefix + hex.EncodeToString(raw), nil
+}
+
+// UploadStore persists direct uploads
+type UploadStore struct {
+	db      *pgxpool.Pool
+	objects storage.Storage
+}
+
+// NewUploadStore creates a new upload store
+func NewUploadStore(db *pgxpool.Pool) *UploadStore {
+	return &UploadStore{db: db}
+}
+
+// WithStorage deletes the objects of purged uploads and returns the store
+func (s *UploadStore) WithStorage(objects storage.Storage) *UploadStore {
+	s.objects = objects
+	return s
+}
+
+const uploadColumns = `id, user_id, object_key, filename, content_type, size_bytes, checksum_sha256, status, submission_id, expires_at, attached_at, created_at`
+
+// scanUpload reads a row selected with uploadColumns
+func scanUpload(row pgx.Row) (*Upload, error) {
+	var u Upload
+	if err := row.Scan(
+		&u.ID,
+		&u.UserID,
+		&u.Key,
+		&u.Filename,
+		&u.ContentType,
+		&u.SizeBytes,
+		&u.ChecksumSHA256,
+		&u.Status,
+		&u.SubmissionID,
+		&u.ExpiresAt,
+		&u.AttachedAt,
+		&u.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// Create records a pending upload redeemable with token until expiresAt.
+// The object key is chosen here; presign the upload URL for the returned
+// upload's Key.
+func (s *UploadStore) Create(ctx context.Context, u *Upload, token string, expiresAt time.Time) (*Upload, error) {
+	query := `
+		INSERT INTO uploads (user_id, token_hash, object_key, filename, content_type, size_bytes, checksum_sha256, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING ` + uploadColumns
+
+	created, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Upload, error) {
+		return scanUpload(s.db.QueryRow(ctx, query,
+			u.UserID,
+			hashToken(token),
+			newObjectKey("uploads"),
+			u.Filename,
+			u.ContentType,
+			u.SizeBytes,
+			u.ChecksumSHA256,
+			expiresAt,
+		))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	return created, nil
+}
+
+// GetPending returns the user's pending upload for token. It returns
+// pgx.ErrNoRows if there is none, or it has expired.
+func (s *UploadStore) GetPending(ctx context.Context, userID uuid.UUID, token string) (*Upload, error) {
+	query := `
+		SELECT ` + uploadColumns + ` FROM uploads
+		WHERE token_hash = $1 AND user_id = $2 AND status = 'pending' AND expires_at > NOW()`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*Upload, error) {
+		return scanUpload(s.db.QueryRow(ctx, query, hashToken(token), userID))
+	})
+}
+
+// Attach completes a pending upload by attaching it to a submission. It
+// returns pgx.ErrNoRows if the upload is no longer pending, so a token is
+// only redeemed once.
+func (s *UploadStore) Attach(ctx context.Context, userID, id, submissionID uuid.UUID) (*Upload, error) {
+	query := `
+		UPDATE uploads
+		SET status = 'attached', submission_id = $3, attached_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND status = 'pending' AND expires_at > NOW()
+		RETURNING ` + uploadColumns
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*Upload, error) {
+		return scanUpload(s.db.QueryRow(ctx, query, id, userID, submissionID))
+	})
+}
+
+// ListForSubmission returns the uploads attached to one of the user's
+// submissions, oldest first
+func (s *UploadStore) ListForSubmission(ctx context.Context, userID, submissionID uuid.UUID) ([]Upload, error) {
+	query := `
+		SELECT ` + uploadColumns + ` FROM uploads
+		WHERE submission_id = $1 AND user_id = $2 AND status = 'attached'
+		ORDER BY attached_at, id`
+
+	uploads, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]Upload, error) {
+		rows, err := s.db.Query(ctx, query, submissionID, userID)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		uploads := []Upload{}
+		for rows.Next() {
+			upload, err := scanUpload(rows)
+			if err != nil {
+				return nil, err
+			}
+			uploads = append(uploads, *upload)
+		}
+		return uploads, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	return uploads, nil
+}
+
+// purgeUploadsQuery deletes up to $1 uploads that were never completed in
+// time, or whose submission has since been deleted
+const purgeUploadsQuery = `
+	DELETE FROM uploads
+	WHERE id IN (
+		SELECT id FROM uploads
+		WHERE (status = 'pending' AND expires_at <= NOW())
+			OR (status = 'attached' AND submission_id IS NULL)
+		LIMIT $1
+	)
+	RETURNING object_key`
+
+// Purge deletes up to limit abandoned uploads and their objects, and
+// returns how many were deleted
+func (s *UploadStore) Purge(ctx context.Context, limit int) (int, error) {
+	keys, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) ([]string, error) {
+		rows, err := s.db.Query(ctx, purgeUploadsQuery, limit)
+		if err != nil {
+			return nil, err
+		}
+		return pgx.CollectRows(rows, pgx.RowTo[string])
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge uploads: %w", err)
+	}
+
+	for _, key := range keys {
+		deleteObject(ctx, s.objects, &key)
+	}
+	return len(keys), nil
+}
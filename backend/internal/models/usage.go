@@ -0,0 +1,160 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+)
+
+// Usage is submission and model usage over a period. Cost is in millionths
+// of a US dollar.
+type Usage struct {
+	Submissions  int   `json:"submissions"`
+	Analyses     int   `json:"analyses"`
+	PromptTokens int64 `json:"prompt_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+	CostMicros   int64 `json:"cost_micros"`
+}
+
+// Add accumulates other into u
+func (u *Usage) Add(other Usage) {
+	u.Submissions += other.Submissions
+	u.Analyses += other.Analyses
+	u.PromptTokens += other.PromptTokens
+	u.OutputTokens += other.OutputTokens
+	u.CostMicros += other.CostMicros
+}
+
+// MemberUsage is one organization member's usage
+type MemberUsage struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+	Role   OrgRole   `json:"role"`
+	Usage
+}
+
+// UsageStore builds and reads the daily usage rollups
+type UsageStore struct {
+	db      *pgxpool.Pool
+	replica ReadRouter
+}
+
+// NewUsageStore creates a new usage store
+func NewUsageStore(db *pgxpool.Pool) *UsageStore {
+	return &UsageStore{db: db}
+}
+
+// WithReplica routes usage reports through router and returns the store
+func (s *UsageStore) WithReplica(router ReadRouter) *UsageStore {
+	s.replica = router
+	return s
+}
+
+// Rollup rebuilds the daily rollups for the UTC days from from up to, but
+// not including, to. Days are replaced wholesale, so running it again is
+// harmless.
+func (s *UsageStore) Rollup(ctx context.Context, from, to time.Time) (int, error) {
+	from, to = truncateDay(from), truncateDay(to)
+
+	return resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (int, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback(ctx)
+
+		if _, err := tx.Exec(ctx, `DELETE FROM usage_rollups WHERE day >= $1::date AND day < $2::date`, from, to); err != nil {
+			return 0, fmt.Errorf("failed to clear usage rollups: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO usage_rollups (day, user_id, submissions, analyses, prompt_tokens, output_tokens, cost_micros)
+			SELECT day, user_id, SUM(submissions), SUM(analyses), SUM(prompt_tokens), SUM(output_tokens), SUM(cost_micros)
+			FROM (
+				SELECT s.created_at::date AS day, s.user_id,
+					COUNT(*) AS submissions, 0 AS analyses, 0 AS prompt_tokens, 0 AS output_tokens, 0 AS cost_micros
+				FROM submissions s
+				WHERE s.created_at >= $1 AND s.created_at < $2
+				GROUP BY 1, 2
+				UNION ALL
+				SELECT a.created_at::date, s.user_id,
+					0, COUNT(*), SUM(a.prompt_tokens), SUM(a.output_tokens), SUM(a.cost_micros)
+				FROM analyses a
+				JOIN submissions s ON s.id = a.submission_id
+				WHERE a.created_at >= $1 AND a.created_at < $2
+				GROUP BY 1, 2
+			) daily
+			GROUP BY day, user_id
+		`, from, to)
+		if err != nil {
+			return 0, fmt.Errorf("failed to roll up usage: %w", err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return 0, fmt.Errorf("failed to commit usage rollups: %w", err)
+		}
+		return int(tag.RowsAffected()), nil
+	})
+}
+
+// OrgUsage returns each member's usage for the UTC days from from up to,
+// but not including, to. Members without usage are included with zeros.
+func (s *UsageStore) OrgUsage(ctx context.Context, orgID uuid.UUID, from, to time.Time) ([]MemberUsage, error) {
+	query := `
+		SELECT m.user_id, u.email, m.role,
+			COALESCE(SUM(r.submissions), 0),
+			COALESCE(SUM(r.analyses), 0),
+			COALESCE(SUM(r.prompt_tokens), 0),
+			COALESCE(SUM(r.output_tokens), 0),
+			COALESCE(SUM(r.cost_micros), 0)
+		FROM organization_members m
+		JOIN users u ON u.id = m.user_id
+		LEFT JOIN usage_rollups r ON r.user_id = m.user_id AND r.day >= $2::date AND r.day < $3::date
+		WHERE m.org_id = $1
+		GROUP BY m.user_id, u.email, m.role
+		ORDER BY 8 DESC, u.email
+	`
+
+	members, err := resilience.Value(ctx, resilience.Reads, func(ctx context.Context) ([]MemberUsage, error) {
+		rows, err := readPool(s.db, s.replica).Query(ctx, query, orgID, truncateDay(from), truncateDay(to))
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		var members []MemberUsage
+		for rows.Next() {
+			var m MemberUsage
+			if err := rows.Scan(
+				&m.UserID,
+				&m.Email,
+				&m.Role,
+				&m.Submissions,
+				&m.Analyses,
+				&m.PromptTokens,
+				&m.OutputTokens,
+				&m.CostMicros,
+			); err != nil {
+				return nil, err
+			}
+			members = append(members, m)
+		}
+		return members, rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organization usage: %w", err)
+	}
+
+	return members, nil
+}
+
+// truncateDay returns the start of t's UTC day
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -2,13 +2,18 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sfumato00/content-analyzer/internal/resilience"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
 )
 
 // User represents a user in the system
@@ -16,8 +21,87 @@ type User struct {
 	ID           uuid.UUID `json:"id"`
 	Email        string    `json:"email"`
 	PasswordHash string    `json:"-"` // Never expose in JSON
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	DisplayName  *string   `json:"display_name"`
+	// TimeZone is the IANA time zone analytics are bucketed in by default
+	TimeZone         *string         `json:"time_zone"`
+	EmailVerifiedAt  *timestamp.Time `json:"email_verified_at"`
+	Plan             Plan            `json:"plan"`
+	ConfirmNewLogins bool            `json:"confirm_new_logins"`
+	Status           AccountStatus   `json:"status"`
+	// StatusReason explains a suspension or ban to the user
+	StatusReason    *string         `json:"status_reason"`
+	StatusChangedAt *timestamp.Time `json:"status_changed_at"`
+	Version         int             `json:"version"`
+	CreatedAt       timestamp.Time  `json:"created_at"`
+	UpdatedAt       timestamp.Time  `json:"updated_at"`
+}
+
+// Plan is the subscription tier of a user
+type Plan string
+
+const (
+	PlanFree       Plan = "free"
+	PlanPro        Plan = "pro"
+	PlanEnterprise Plan = "enterprise"
+)
+
+// ParsePlan validates a plan name
+func ParsePlan(s string) (Plan, error) {
+	switch p := Plan(s); p {
+	case PlanFree, PlanPro, PlanEnterprise:
+		return p, nil
+	default:
+		return "", fmt.Errorf("plan must be one of: free, pro, enterprise")
+	}
+}
+
+// Paid reports whether the plan is a paid tier
+func (p Plan) Paid() bool {
+	return p == PlanPro || p == PlanEnterprise
+}
+
+// MaxDisplayNameLength is the longest display name accepted, in characters
+const MaxDisplayNameLength = 100
+
+// LoadTimeZone loads an IANA time zone such as Europe/Paris, refusing
+// the server's own Local zone
+func LoadTimeZone(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// userColumns is the column list matching scanUser
+const userColumns = `id, email, password_hash, display_name, time_zone, email_verified_at, plan, confirm_new_logins, status, status_reason, status_changed_at, version, created_at, updated_at`
+
+// scanUser scans a row selected with userColumns
+func scanUser(row pgx.Row) (*User, error) {
+	var user User
+	err := row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.DisplayName,
+		&user.TimeZone,
+		&user.EmailVerifiedAt,
+		&user.Plan,
+		&user.ConfirmNewLogins,
+		&user.Status,
+		&user.StatusReason,
+		&user.StatusChangedAt,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
 // UserStore handles database operations for users
@@ -30,105 +114,291 @@ func NewUserStore(db *pgxpool.Pool) *UserStore {
 	return &UserStore{db: db}
 }
 
-// Create creates a new user in the database
+// Create creates a new user in the database. An address an organization
+// invited through SCIM gets the user ID the invitation reserved.
 func (s *UserStore) Create(ctx context.Context, email, password string) (*User, error) {
+	passwordHash, err := newUserPasswordHash(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// Insert user
+	query := `
+		INSERT INTO users (id, email, password_hash)
+		VALUES (` + reservedUserID("$1") + `, $1, $2)
+		RETURNING ` + userColumns
+
+	user, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*User, error) {
+		return scanUser(s.db.QueryRow(ctx, query, email, passwordHash))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// CreateWithInvite creates a user and takes one use of the invitation code
+// in the same transaction, so a failed registration doesn't spend the code.
+// It returns ErrInvalidInvite if the code can't be used.
+func (s *UserStore) CreateWithInvite(ctx context.Context, email, password, code string) (*User, error) {
+	passwordHash, err := newUserPasswordHash(email, password)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*User, error) {
+		tx, err := s.db.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+
+		inviteID, err := consumeInvite(ctx, tx, code)
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := scanUser(tx.QueryRow(ctx, `
+			INSERT INTO users (id, email, password_hash, invite_code_id)
+			VALUES (`+reservedUserID("$1")+`, $1, $2, $3)
+			RETURNING `+userColumns, email, passwordHash, inviteID))
+		if err != nil {
+			return nil, err
+		}
+
+		return user, tx.Commit(ctx)
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidInvite) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user, nil
+}
+
+// newUserPasswordHash validates a new account's email and password and
+// hashes the password
+func newUserPasswordHash(email, password string) (string, error) {
 	// Validate email
 	if err := ValidateEmail(email); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Validate password
 	if err := ValidatePassword(password); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// Hash password
 	passwordHash, err := HashPassword(password)
 	if err != nil {
-		return nil, fmt.Errorf("failed to hash password: %w", err)
+		return "", fmt.Errorf("failed to hash password: %w", err)
 	}
+	return passwordHash, nil
+}
 
-	// Insert user
-	var user User
+// GetByEmail retrieves a user by email
+func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE email = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*User, error) {
+		return scanUser(s.db.QueryRow(ctx, query, email))
+	})
+}
+
+// GetByID retrieves a user by ID
+func (s *UserStore) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE id = $1`
+
+	return resilience.Value(ctx, resilience.Reads, func(ctx context.Context) (*User, error) {
+		return scanUser(s.db.QueryRow(ctx, query, id))
+	})
+}
+
+// MarkEmailVerified records that the user confirmed their email address
+func (s *UserStore) MarkEmailVerified(ctx context.Context, id uuid.UUID) error {
 	query := `
-		INSERT INTO users (email, password_hash)
-		VALUES ($1, $2)
-		RETURNING id, email, password_hash, created_at, updated_at
+		UPDATE users
+		SET email_verified_at = NOW(), version = version + 1
+		WHERE id = $1 AND email_verified_at IS NULL
 	`
 
-	err = s.db.QueryRow(ctx, query, email, passwordHash).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	// Repeating the update is harmless, so retry it like a read
+	err := resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, query, id)
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create user: %w", err)
+		return fmt.Errorf("failed to mark email verified: %w", err)
 	}
 
-	return &user, nil
+	return nil
 }
 
-// GetByEmail retrieves a user by email
-func (s *UserStore) GetByEmail(ctx context.Context, email string) (*User, error) {
-	var user User
-	query := `
-		SELECT id, email, password_hash, created_at, updated_at
-		FROM users
-		WHERE email = $1
-	`
+// UpdatePassword validates, hashes and stores a new password
+func (s *UserStore) UpdatePassword(ctx context.Context, id uuid.UUID, password string) error {
+	if err := ValidatePassword(password); err != nil {
+		return err
+	}
 
-	err := s.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	passwordHash, err := HashPassword(password)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
-	return &user, nil
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, `UPDATE users SET password_hash = $2, version = version + 1 WHERE id = $1`, id, passwordHash)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
 }
 
-// GetByID retrieves a user by ID
-func (s *UserStore) GetByID(ctx context.Context, id uuid.UUID) (*User, error) {
-	var user User
+// UpdateEmail changes the user's email address and marks it verified. It
+// is only called once the new address has been confirmed.
+func (s *UserStore) UpdateEmail(ctx context.Context, id uuid.UUID, email string) error {
+	if err := ValidateEmail(email); err != nil {
+		return err
+	}
+
 	query := `
-		SELECT id, email, password_hash, created_at, updated_at
-		FROM users
+		UPDATE users
+		SET email = $2, email_verified_at = NOW(), updated_at = NOW(), version = version + 1
 		WHERE id = $1
 	`
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.PasswordHash,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, query, id, email)
+	})
 	if err != nil {
+		return fmt.Errorf("failed to update email: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// RehashPassword replaces the user's password hash with one made with the
+// current parameters, unless the hash changed since currentHash was read.
+// The password itself is unchanged, so the version isn't bumped.
+func (s *UserStore) RehashPassword(ctx context.Context, id uuid.UUID, currentHash, password string) error {
+	passwordHash, err := HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	err = resilience.Reads.Do(ctx, func(ctx context.Context) error {
+		_, err := s.db.Exec(ctx, `UPDATE users SET password_hash = $3 WHERE id = $1 AND password_hash = $2`, id, currentHash, passwordHash)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rehash password: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateProfile sets the user's display name and time zone, clearing
+// those that are nil. The update only applies if the user is still at
+// version; otherwise it returns ErrVersionConflict.
+func (s *UserStore) UpdateProfile(ctx context.Context, id uuid.UUID, version int, displayName, timeZone *string) (*User, error) {
+	query := `
+		UPDATE users
+		SET display_name = $3, time_zone = $4, version = version + 1
+		WHERE id = $1 AND version = $2
+		RETURNING ` + userColumns
+
+	user, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*User, error) {
+		return scanUser(s.db.QueryRow(ctx, query, id, version, displayName, timeZone))
+	})
+	if err == nil {
+		return user, nil
+	}
+	if err != pgx.ErrNoRows {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	// Either the user is gone or someone else updated it first
+	if _, err := s.GetByID(ctx, id); err != nil {
 		return nil, err
 	}
+	return nil, ErrVersionConflict
+}
+
+// SetPlan changes the user's subscription plan
+func (s *UserStore) SetPlan(ctx context.Context, id uuid.UUID, plan Plan) error {
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, `UPDATE users SET plan = $2, updated_at = NOW() WHERE id = $1`, id, plan)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set plan: %w", err)
+	}
 
-	return &user, nil
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetConfirmNewLogins chooses whether sign-ins from a new country or
+// device need an emailed code
+func (s *UserStore) SetConfirmNewLogins(ctx context.Context, id uuid.UUID, confirm bool) error {
+	tag, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (pgconn.CommandTag, error) {
+		return s.db.Exec(ctx, `UPDATE users SET confirm_new_logins = $2, updated_at = NOW() WHERE id = $1`, id, confirm)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set login confirmation: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return nil
+}
+
+// SetStatus suspends, bans or reinstates the user. reason is shown to
+// them, and is cleared when they are reinstated.
+func (s *UserStore) SetStatus(ctx context.Context, id uuid.UUID, status AccountStatus, reason string) (*User, error) {
+	query := `
+		UPDATE users
+		SET status = $2, status_reason = NULLIF($3, ''), status_changed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING ` + userColumns
+
+	if status == StatusActive {
+		reason = ""
+	}
+	user, err := resilience.Value(ctx, resilience.Writes, func(ctx context.Context) (*User, error) {
+		return scanUser(s.db.QueryRow(ctx, query, id, status, reason))
+	})
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to set account status: %w", err)
+	}
+	return user, err
 }
 
 // ComparePassword compares a plain text password with the hashed password
 func (u *User) ComparePassword(password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	return CheckPassword(u.PasswordHash, password)
 }
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string) (string, error) {
-	// Use bcrypt cost of 12 for security (as specified in the plan)
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), 12)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedBytes), nil
+// NeedsRehash reports whether the password hash uses outdated parameters
+func (u *User) NeedsRehash() bool {
+	return PasswordNeedsRehash(u.PasswordHash)
 }
 
 // ValidateEmail validates an email address
@@ -152,3 +152,30 @@ func TestUser_ComparePassword(t *testing.T) {
 		t.Error("ComparePassword() with wrong password should return error")
 	}
 }
+
+func TestLoadTimeZone(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"Europe/Paris", false},
+		{"America/New_York", false},
+		{"UTC", false},
+		{"", true},
+		{"Local", true},
+		{"Mars/Olympus_Mons", true},
+		{"../../etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := LoadTimeZone(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadTimeZone(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if err == nil && loc.String() != tt.name {
+				t.Errorf("LoadTimeZone(%q) = %v", tt.name, loc)
+			}
+		})
+	}
+}
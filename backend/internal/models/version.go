@@ -0,0 +1,13 @@
+package models
+
+import "errors"
+
+// ErrVersionConflict is returned when an update is based on a version of
+// the row that has since changed. Every write increments a row's version,
+// so clients send back the version they read and the store only applies
+// the update if it still matches.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNotDraft is returned when editing a submission that has already been
+// queued for analysis
+var ErrNotDraft = errors.New("only drafts can be edited")
@@ -0,0 +1,66 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// Message is a rendered email ready to be delivered
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Mailer delivers email messages
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// MailerConfig selects and configures a mail driver
+type MailerConfig struct {
+	Driver string // log, smtp, ses, sendgrid
+	From   string
+
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+
+	SendGridAPIKey string
+
+	AWSRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+}
+
+// NewMailer creates the mailer for the configured driver
+func NewMailer(cfg MailerConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case "", "log":
+		return &LogMailer{}, nil
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	case "sendgrid":
+		return NewSendGridMailer(cfg.SendGridAPIKey, cfg.From), nil
+	case "ses":
+		return NewSESMailer(cfg.AWSRegion, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver: %s", cfg.Driver)
+	}
+}
+
+// LogMailer writes emails to the log instead of sending them (development)
+type LogMailer struct{}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	slog.Info("Email (log driver)",
+		"to", msg.To,
+		"subject", msg.Subject,
+		"text", msg.Text,
+	)
+	return nil
+}
@@ -0,0 +1,194 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+var testMessage = Message{
+	To:      "user@example.com",
+	Subject: "Héllo there",
+	Text:    "Plain body with a long line that goes past seventy six characters so it must be soft wrapped",
+	HTML:    "<p>HTML body</p>",
+}
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		driver  string
+		want    string
+		wantErr bool
+	}{
+		{driver: "", want: "*notifications.LogMailer"},
+		{driver: "log", want: "*notifications.LogMailer"},
+		{driver: "smtp", want: "*notifications.SMTPMailer"},
+		{driver: "sendgrid", want: "*notifications.SendGridMailer"},
+		{driver: "ses", want: "*notifications.SESMailer"},
+		{driver: "carrier-pigeon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			m, err := NewMailer(MailerConfig{Driver: tt.driver, AWSRegion: "us-east-1"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMailer() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got := fmt.Sprintf("%T", m); got != tt.want {
+				t.Errorf("NewMailer() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildMIMEMessage(t *testing.T) {
+	raw, err := buildMIMEMessage("noreply@example.com", testMessage)
+	if err != nil {
+		t.Fatalf("buildMIMEMessage() error = %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		t.Fatalf("failed to decode subject: %v", err)
+	}
+	if subject != testMessage.Subject {
+		t.Errorf("Subject = %q, want %q", subject, testMessage.Subject)
+	}
+	if got := msg.Header.Get("To"); got != testMessage.To {
+		t.Errorf("To = %q, want %q", got, testMessage.To)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, want multipart/alternative", msg.Header.Get("Content-Type"))
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	want := map[string]string{
+		"text/plain": testMessage.Text,
+		"text/html":  testMessage.HTML,
+	}
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read part: %v", err)
+		}
+
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		if err != nil {
+			t.Fatalf("failed to decode %s part: %v", contentType, err)
+		}
+
+		if string(body) != want[contentType] {
+			t.Errorf("%s part = %q, want %q", contentType, body, want[contentType])
+		}
+		delete(want, contentType)
+	}
+
+	if len(want) != 0 {
+		t.Errorf("missing parts: %v", want)
+	}
+}
+
+func TestSendGridMailer_Send(t *testing.T) {
+	var got sendGridRequest
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	m := NewSendGridMailer("sg-key", "noreply@example.com")
+	m.endpoint = server.URL
+
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if auth != "Bearer sg-key" {
+		t.Errorf("Authorization = %q, want %q", auth, "Bearer sg-key")
+	}
+	if got.From.Email != "noreply@example.com" {
+		t.Errorf("from = %q, want %q", got.From.Email, "noreply@example.com")
+	}
+	if len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != testMessage.To {
+		t.Errorf("personalizations = %+v, want a single recipient %q", got.Personalizations, testMessage.To)
+	}
+	if len(got.Content) != 2 || got.Content[0].Type != "text/plain" {
+		t.Errorf("content = %+v, want text/plain followed by text/html", got.Content)
+	}
+}
+
+func TestSendGridMailer_Send_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":[{"message":"bad key"}]}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	m := NewSendGridMailer("bad", "noreply@example.com")
+	m.endpoint = server.URL
+
+	err := m.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Send() error = %v, want a 401 error", err)
+	}
+}
+
+func TestSESMailer_Send(t *testing.T) {
+	var got sesRequest
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("failed to decode request: %v", err)
+		}
+		w.Write([]byte(`{"MessageId":"abc"}`))
+	}))
+	defer server.Close()
+
+	m := NewSESMailer("eu-west-1", "AKIDEXAMPLE", "secret", "noreply@example.com")
+	m.endpoint = server.URL
+
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	if !strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") ||
+		!strings.Contains(authorization, "/eu-west-1/ses/aws4_request") {
+		t.Errorf("Authorization = %q, want a SigV4 header scoped to eu-west-1/ses", authorization)
+	}
+	if got.FromEmailAddress != "noreply@example.com" {
+		t.Errorf("FromEmailAddress = %q, want %q", got.FromEmailAddress, "noreply@example.com")
+	}
+	if got.Content.Simple.Subject.Data != testMessage.Subject {
+		t.Errorf("Subject = %q, want %q", got.Content.Simple.Subject.Data, testMessage.Subject)
+	}
+	if got.Content.Simple.Body.HTML == nil || got.Content.Simple.Body.HTML.Data != testMessage.HTML {
+		t.Errorf("Html body = %+v, want %q", got.Content.Simple.Body.HTML, testMessage.HTML)
+	}
+}
@@ -0,0 +1,228 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// EmailJobType delivers a single rendered email
+	EmailJobType = "notifications.email"
+
+	// WeeklyDigestJobType sends the weekly activity digest to all users
+	WeeklyDigestJobType = "notifications.weekly_digest"
+)
+
+// Notifier renders emails and hands them to the job queue for delivery
+type Notifier struct {
+	queue    *queue.Queue
+	renderer *Renderer
+	baseURL  string
+}
+
+// NewNotifier creates a new notifier. baseURL is the frontend URL used to
+// build links in emails.
+func NewNotifier(q *queue.Queue, renderer *Renderer, baseURL string) *Notifier {
+	return &Notifier{
+		queue:    q,
+		renderer: renderer,
+		baseURL:  baseURL,
+	}
+}
+
+// SendVerification sends an email address confirmation link
+func (n *Notifier) SendVerification(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	return n.enqueue(ctx, TemplateVerification, to, map[string]interface{}{
+		"Link":      n.link("/verify-email", token),
+		"ExpiresIn": humanDuration(expiresIn),
+	})
+}
+
+// SendPasswordReset sends a password reset link
+func (n *Notifier) SendPasswordReset(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	return n.enqueue(ctx, TemplatePasswordReset, to, map[string]interface{}{
+		"Link":      n.link("/reset-password", token),
+		"ExpiresIn": humanDuration(expiresIn),
+	})
+}
+
+// SendEmailChange sends a confirmation link to a requested new email address
+func (n *Notifier) SendEmailChange(ctx context.Context, to, token string, expiresIn time.Duration) error {
+	return n.enqueue(ctx, TemplateEmailChange, to, map[string]interface{}{
+		"Link":      n.link("/confirm-email-change", token),
+		"ExpiresIn": humanDuration(expiresIn),
+	})
+}
+
+// SendQuotaWarning tells a user how much of their monthly quota is used
+func (n *Notifier) SendQuotaWarning(ctx context.Context, to string, used, limit int, resetsOn time.Time) error {
+	percent := 100
+	if limit > 0 {
+		percent = used * 100 / limit
+	}
+
+	return n.enqueue(ctx, TemplateQuotaWarning, to, map[string]interface{}{
+		"Used":     used,
+		"Limit":    limit,
+		"Percent":  percent,
+		"ResetsOn": resetsOn.Format("January 2, 2006"),
+	})
+}
+
+// SendAssignment tells a user a teammate assigned them a submission to
+// review, and when it is due if it has a due date. The content itself is
+// left out of the email.
+func (n *Notifier) SendAssignment(ctx context.Context, to, assignedBy string, dueAt *time.Time) error {
+	var dueOn string
+	if dueAt != nil {
+		dueOn = dueAt.UTC().Format("January 2, 2006")
+	}
+
+	return n.enqueue(ctx, TemplateAssignment, to, map[string]interface{}{
+		"AssignedBy": assignedBy,
+		"DueOn":      dueOn,
+		"QueueLink":  n.baseURL + "/queue",
+	})
+}
+
+// Quarantine outcomes the owner of a submission is told about
+const (
+	QuarantineQuarantined = "quarantined"
+	QuarantineReleased    = "released"
+	QuarantineDestroyed   = "destroyed"
+)
+
+// SendQuarantine tells the owner of a submission created at createdAt that
+// it was quarantined, released or destroyed, and why. The content itself
+// is left out of the email.
+func (n *Notifier) SendQuarantine(ctx context.Context, to, outcome string, submissionID uuid.UUID, createdAt time.Time, reason string) error {
+	return n.enqueue(ctx, TemplateQuarantine, to, map[string]interface{}{
+		"Outcome":   outcome,
+		"Reason":    reason,
+		"CreatedOn": createdAt.UTC().Format("January 2, 2006"),
+		"Link":      n.baseURL + "/submissions/" + submissionID.String(),
+	})
+}
+
+// SendSpendBudget tells an operator that a spend budget, named like "the
+// daily spend budget", is used up, and whether new analyses are rejected
+// or held until it resets
+func (n *Notifier) SendSpendBudget(ctx context.Context, to, budget, spent, limit string, resetsAt time.Time, rejected bool) error {
+	return n.enqueue(ctx, TemplateSpendBudget, to, map[string]interface{}{
+		"Budget":   budget,
+		"Spent":    spent,
+		"Limit":    limit,
+		"ResetsOn": resetsAt.UTC().Format("January 2, 2006 15:04 MST"),
+		"Rejected": rejected,
+	})
+}
+
+// SendNewLogin tells a user about a sign-in from a new country or device.
+// When the sign-in waits for confirmation, code is the one to enter;
+// otherwise it is empty and the email is only a notice.
+func (n *Notifier) SendNewLogin(ctx context.Context, to string, fp models.LoginFingerprint, ipAddress string, at time.Time, code string, expiresIn time.Duration) error {
+	return n.enqueue(ctx, TemplateNewLogin, to, map[string]interface{}{
+		"Device":       fp.Device,
+		"Country":      fp.Country,
+		"IPAddress":    ipAddress,
+		"SignedInAt":   at.UTC().Format("January 2, 2006 15:04 MST"),
+		"Code":         code,
+		"ExpiresIn":    humanDuration(expiresIn),
+		"SettingsLink": n.baseURL + "/settings/security",
+	})
+}
+
+// SendWeeklyDigest sends a summary of the user's activity
+func (n *Notifier) SendWeeklyDigest(ctx context.Context, digest models.WeeklyActivity, weekOf time.Time) error {
+	return n.enqueue(ctx, TemplateWeeklyDigest, digest.Email, map[string]interface{}{
+		"WeekOf":           weekOf.Format("January 2, 2006"),
+		"Submissions":      digest.Submissions,
+		"Analyses":         digest.Analyses,
+		"AverageSentiment": digest.AverageSentiment,
+		"DashboardLink":    n.baseURL + "/dashboard",
+	})
+}
+
+// enqueue renders a template and schedules its delivery
+func (n *Notifier) enqueue(ctx context.Context, template, to string, data interface{}) error {
+	msg, err := n.renderer.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+
+	if _, err := n.queue.Enqueue(ctx, EmailJobType, msg); err != nil {
+		return fmt.Errorf("failed to enqueue %s email: %w", template, err)
+	}
+
+	return nil
+}
+
+// link builds a frontend URL carrying a one-time token
+func (n *Notifier) link(path, token string) string {
+	return n.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// humanDuration formats token lifetimes for email copy
+func humanDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		hours := int(d / time.Hour)
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return fmt.Sprintf("%d minutes", int(d/time.Minute))
+}
+
+// NewDeliveryHandler returns the queue handler that sends queued emails
+func NewDeliveryHandler(mailer Mailer) queue.Handler {
+	return func(ctx context.Context, job *queue.Job) error {
+		var msg Message
+		if err := job.Decode(&msg); err != nil {
+			return fmt.Errorf("invalid email payload: %w", err)
+		}
+
+		return mailer.Send(ctx, msg)
+	}
+}
+
+// DigestJob sends weekly digests to users with recent activity
+type DigestJob struct {
+	store    *models.AnalyticsStore
+	notifier *Notifier
+}
+
+// NewDigestJob creates a new weekly digest job
+func NewDigestJob(store *models.AnalyticsStore, notifier *Notifier) *DigestJob {
+	return &DigestJob{
+		store:    store,
+		notifier: notifier,
+	}
+}
+
+// Handle implements queue.Handler for the weekly digest job
+func (j *DigestJob) Handle(ctx context.Context, job *queue.Job) error {
+	since := time.Now().UTC().AddDate(0, 0, -7)
+
+	activity, err := j.store.WeeklyActivity(ctx, since)
+	if err != nil {
+		return err
+	}
+
+	for _, a := range activity {
+		if err := j.notifier.SendWeeklyDigest(ctx, a, since); err != nil {
+			slog.Error("Failed to send weekly digest", "user_id", a.UserID, "error", err)
+		}
+	}
+
+	slog.Info("Weekly digests queued", "count", len(activity))
+	return nil
+}
@@ -0,0 +1,92 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// sendGridEndpoint is the SendGrid v3 mail send API
+const sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridMailer sends email through the SendGrid API
+type SendGridMailer struct {
+	apiKey     string
+	from       string
+	endpoint   string
+	httpClient *http.Client
+}
+
+// NewSendGridMailer creates a new SendGrid mailer
+func NewSendGridMailer(apiKey, from string) *SendGridMailer {
+	return &SendGridMailer{
+		apiKey:     apiKey,
+		from:       from,
+		endpoint:   sendGridEndpoint,
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridRequest struct {
+	Personalizations []struct {
+		To []sendGridAddress `json:"to"`
+	} `json:"personalizations"`
+	From    sendGridAddress   `json:"from"`
+	Subject string            `json:"subject"`
+	Content []sendGridContent `json:"content"`
+}
+
+// Send delivers the message
+func (m *SendGridMailer) Send(ctx context.Context, msg Message) error {
+	body := sendGridRequest{
+		From:    sendGridAddress{Email: m.from},
+		Subject: msg.Subject,
+	}
+	body.Personalizations = append(body.Personalizations, struct {
+		To []sendGridAddress `json:"to"`
+	}{To: []sendGridAddress{{Email: msg.To}}})
+
+	// SendGrid requires text/plain to come before text/html
+	body.Content = append(body.Content, sendGridContent{Type: "text/plain", Value: msg.Text})
+	if msg.HTML != "" {
+		body.Content = append(body.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode SendGrid request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create SendGrid request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email via SendGrid: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("SendGrid returned %d: %s", resp.StatusCode, detail)
+	}
+
+	return nil
+}
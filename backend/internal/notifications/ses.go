@@ -0,0 +1,102 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// SESMailer sends email through the Amazon SES v2 API
+type SESMailer struct {
+	endpoint    string
+	from        string
+	region      string
+	signer      *v4.Signer
+	credentials aws.Credentials
+	httpClient  *http.Client
+}
+
+// NewSESMailer creates a new SES mailer
+func NewSESMailer(region, accessKeyID, secretAccessKey, from string) *SESMailer {
+	return &SESMailer{
+		endpoint: fmt.Sprintf("https://email.%s.amazonaws.com/v2/email/outbound-emails", region),
+		from:     from,
+		region:   region,
+		signer:   v4.NewSigner(),
+		credentials: aws.Credentials{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+		},
+		httpClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text *sesContent `json:"Text,omitempty"`
+				HTML *sesContent `json:"Html,omitempty"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Send delivers the message
+func (m *SESMailer) Send(ctx context.Context, msg Message) error {
+	var body sesRequest
+	body.FromEmailAddress = m.from
+	body.Destination.ToAddresses = []string{msg.To}
+	body.Content.Simple.Subject = sesContent{Data: msg.Subject, Charset: "UTF-8"}
+	body.Content.Simple.Body.Text = &sesContent{Data: msg.Text, Charset: "UTF-8"}
+	if msg.HTML != "" {
+		body.Content.Simple.Body.HTML = &sesContent{Data: msg.HTML, Charset: "UTF-8"}
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode SES request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create SES request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sum := sha256.Sum256(payload)
+	if err := m.signer.SignHTTP(ctx, m.credentials, req, hex.EncodeToString(sum[:]), "ses", m.region, time.Now()); err != nil {
+		return fmt.Errorf("failed to sign SES request: %w", err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send email via SES: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("SES returned %d: %s", resp.StatusCode, detail)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strconv"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP relay (STARTTLS when offered)
+type SMTPMailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+// NewSMTPMailer creates a new SMTP mailer
+func NewSMTPMailer(host string, port int, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{
+		addr: net.JoinHostPort(host, strconv.Itoa(port)),
+		host: host,
+		from: from,
+	}
+
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return m
+}
+
+// Send delivers the message. net/smtp has no context support, so
+// cancellation is only honoured before the connection is made.
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	body, err := buildMIMEMessage(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, body); err != nil {
+		return fmt.Errorf("failed to send email via SMTP: %w", err)
+	}
+
+	return nil
+}
+
+// buildMIMEMessage encodes a multipart/alternative message with text and
+// HTML parts
+func buildMIMEMessage(from string, msg Message) ([]byte, error) {
+	boundaryBytes := make([]byte, 12)
+	if _, err := rand.Read(boundaryBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate MIME boundary: %w", err)
+	}
+	boundary := hex.EncodeToString(boundaryBytes)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", boundary)
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain", msg.Text},
+		{"text/html", msg.HTML},
+	}
+
+	for _, part := range parts {
+		if part.body == "" {
+			continue
+		}
+
+		fmt.Fprintf(&buf, "--%s\r\n", boundary)
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+		qp := quotedprintable.NewWriter(&buf)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, fmt.Errorf("failed to encode email body: %w", err)
+		}
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encode email body: %w", err)
+		}
+		buf.WriteString("\r\n")
+	}
+
+	fmt.Fprintf(&buf, "--%s--\r\n", boundary)
+	return buf.Bytes(), nil
+}
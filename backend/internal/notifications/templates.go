@@ -0,0 +1,95 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+//go:embed templates/*
+var templateFS embed.FS
+
+// Template names
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateEmailChange   = "email_change"
+	TemplateQuotaWarning  = "quota_warning"
+	TemplateWeeklyDigest  = "weekly_digest"
+	TemplateAssignment    = "assignment"
+	TemplateQuarantine    = "quarantine"
+	TemplateSpendBudget   = "spend_budget"
+	TemplateNewLogin      = "new_login"
+)
+
+// templateFuncs are available to both text and HTML templates
+var templateFuncs = map[string]interface{}{
+	"deref": func(f *float64) float64 {
+		if f == nil {
+			return 0
+		}
+		return *f
+	},
+}
+
+// Renderer renders email templates embedded in the binary.
+// Each template has a .txt file (defining "subject" and "content") and an
+// optional .html file (defining "content"), wrapped by the matching layout.
+type Renderer struct {
+	text map[string]*texttemplate.Template
+	html map[string]*htmltemplate.Template
+}
+
+// NewRenderer parses all embedded templates
+func NewRenderer() (*Renderer, error) {
+	r := &Renderer{
+		text: make(map[string]*texttemplate.Template),
+		html: make(map[string]*htmltemplate.Template),
+	}
+
+	for _, name := range []string{TemplateVerification, TemplatePasswordReset, TemplateEmailChange, TemplateQuotaWarning, TemplateWeeklyDigest, TemplateAssignment, TemplateQuarantine, TemplateSpendBudget, TemplateNewLogin} {
+		text, err := texttemplate.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/layout.txt", "templates/"+name+".txt")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s text template: %w", name, err)
+		}
+		r.text[name] = text
+
+		html, err := htmltemplate.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s HTML template: %w", name, err)
+		}
+		r.html[name] = html
+	}
+
+	return r, nil
+}
+
+// Render produces a message for the given template and recipient
+func (r *Renderer) Render(name, to string, data interface{}) (Message, error) {
+	text, ok := r.text[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template: %s", name)
+	}
+
+	var subject, body, html bytes.Buffer
+
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s subject: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s text body: %w", name, err)
+	}
+	if err := r.html[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("failed to render %s HTML body: %w", name, err)
+	}
+
+	return Message{
+		To:      to,
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    body.String(),
+		HTML:    html.String(),
+	}, nil
+}
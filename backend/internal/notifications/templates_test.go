@@ -0,0 +1,283 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	score := 0.42
+
+	tests := []struct {
+		name        string
+		template    string
+		data        map[string]interface{}
+		wantSubject string
+		wantBody    string
+	}{
+		{
+			name:     "verification",
+			template: TemplateVerification,
+			data: map[string]interface{}{
+				"Link":      "https://app.example.com/verify-email?token=abc",
+				"ExpiresIn": "24 hours",
+			},
+			wantSubject: "Verify",
+			wantBody:    "https://app.example.com/verify-email?token=abc",
+		},
+		{
+			name:     "password reset",
+			template: TemplatePasswordReset,
+			data: map[string]interface{}{
+				"Link":      "https://app.example.com/reset-password?token=xyz",
+				"ExpiresIn": "1 hour",
+			},
+			wantSubject: "password",
+			wantBody:    "1 hour",
+		},
+		{
+			name:     "email change",
+			template: TemplateEmailChange,
+			data: map[string]interface{}{
+				"Link":      "https://app.example.com/confirm-email-change?token=def",
+				"ExpiresIn": "24 hours",
+			},
+			wantSubject: "new email",
+			wantBody:    "https://app.example.com/confirm-email-change?token=def",
+		},
+		{
+			name:     "quota warning",
+			template: TemplateQuotaWarning,
+			data: map[string]interface{}{
+				"Used":     80,
+				"Limit":    100,
+				"Percent":  80,
+				"ResetsOn": "November 1, 2026",
+			},
+			wantSubject: "80%",
+			wantBody:    "November 1, 2026",
+		},
+		{
+			name:     "weekly digest",
+			template: TemplateWeeklyDigest,
+			data: map[string]interface{}{
+				"WeekOf":           "October 9, 2026",
+				"Submissions":      12,
+				"Analyses":         11,
+				"AverageSentiment": &score,
+				"DashboardLink":    "https://app.example.com/dashboard",
+			},
+			wantSubject: "weekly",
+			wantBody:    "12",
+		},
+		{
+			name:     "assignment",
+			template: TemplateAssignment,
+			data: map[string]interface{}{
+				"AssignedBy": "editor@example.com",
+				"DueOn":      "October 20, 2026",
+				"QueueLink":  "https://app.example.com/queue",
+			},
+			wantSubject: "editor@example.com assigned you",
+			wantBody:    "October 20, 2026",
+		},
+		{
+			name:     "assignment without due date",
+			template: TemplateAssignment,
+			data: map[string]interface{}{
+				"AssignedBy": "editor@example.com",
+				"DueOn":      "",
+				"QueueLink":  "https://app.example.com/queue",
+			},
+			wantSubject: "review",
+			wantBody:    "https://app.example.com/queue",
+		},
+		{
+			name:     "quarantined",
+			template: TemplateQuarantine,
+			data: map[string]interface{}{
+				"Outcome":   QuarantineQuarantined,
+				"Reason":    "Blocked by the moderation policy: toxicity 0.93 above 0.80",
+				"CreatedOn": "October 14, 2026",
+				"Link":      "https://app.example.com/submissions/1",
+			},
+			wantSubject: "was quarantined",
+			wantBody:    "toxicity 0.93 above 0.80",
+		},
+		{
+			name:     "released from quarantine",
+			template: TemplateQuarantine,
+			data: map[string]interface{}{
+				"Outcome":   QuarantineReleased,
+				"Reason":    "",
+				"CreatedOn": "October 14, 2026",
+				"Link":      "https://app.example.com/submissions/1",
+			},
+			wantSubject: "released",
+			wantBody:    "https://app.example.com/submissions/1",
+		},
+		{
+			name:     "destroyed in quarantine",
+			template: TemplateQuarantine,
+			data: map[string]interface{}{
+				"Outcome":   QuarantineDestroyed,
+				"Reason":    "Confirmed abuse",
+				"CreatedOn": "October 14, 2026",
+				"Link":      "",
+			},
+			wantSubject: "deleted",
+			wantBody:    "Confirmed abuse",
+		},
+		{
+			name:     "spend budget held",
+			template: TemplateSpendBudget,
+			data: map[string]interface{}{
+				"Budget":   "the monthly spend budget of Acme",
+				"Spent":    "$512.40",
+				"Limit":    "$500.00",
+				"ResetsOn": "November 1, 2026 00:00 UTC",
+				"Rejected": false,
+			},
+			wantSubject: "the monthly spend budget of Acme",
+			wantBody:    "held in the queue",
+		},
+		{
+			name:     "spend budget rejected",
+			template: TemplateSpendBudget,
+			data: map[string]interface{}{
+				"Budget":   "the daily spend budget",
+				"Spent":    "$20.01",
+				"Limit":    "$20.00",
+				"ResetsOn": "October 17, 2026 00:00 UTC",
+				"Rejected": true,
+			},
+			wantSubject: "Spend budget used up",
+			wantBody:    "New analyses are rejected",
+		},
+		{
+			name:     "new login confirmation",
+			template: TemplateNewLogin,
+			data: map[string]interface{}{
+				"Device":       "Firefox on Windows",
+				"Country":      "DE",
+				"IPAddress":    "203.0.113.7",
+				"SignedInAt":   "October 16, 2026 09:30 UTC",
+				"Code":         "042917",
+				"ExpiresIn":    "15 minutes",
+				"SettingsLink": "https://app.example.com/settings/security",
+			},
+			wantSubject: "Confirm your sign-in",
+			wantBody:    "042917",
+		},
+		{
+			name:     "new login notice",
+			template: TemplateNewLogin,
+			data: map[string]interface{}{
+				"Device":       "curl",
+				"Country":      "",
+				"IPAddress":    "203.0.113.7",
+				"SignedInAt":   "October 16, 2026 09:30 UTC",
+				"Code":         "",
+				"ExpiresIn":    "15 minutes",
+				"SettingsLink": "https://app.example.com/settings/security",
+			},
+			wantSubject: "New sign-in",
+			wantBody:    "Device: curl",
+		},
+		{
+			name:     "weekly digest without sentiment",
+			template: TemplateWeeklyDigest,
+			data: map[string]interface{}{
+				"WeekOf":           "October 9, 2026",
+				"Submissions":      1,
+				"Analyses":         0,
+				"AverageSentiment": (*float64)(nil),
+				"DashboardLink":    "https://app.example.com/dashboard",
+			},
+			wantSubject: "weekly",
+			wantBody:    "https://app.example.com/dashboard",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := renderer.Render(tt.template, "user@example.com", tt.data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if msg.To != "user@example.com" {
+				t.Errorf("Render() To = %q, want %q", msg.To, "user@example.com")
+			}
+			if !strings.Contains(strings.ToLower(msg.Subject), strings.ToLower(tt.wantSubject)) {
+				t.Errorf("Render() Subject = %q, want it to contain %q", msg.Subject, tt.wantSubject)
+			}
+			if strings.Contains(msg.Subject, "\n") {
+				t.Errorf("Render() Subject = %q, want a single line", msg.Subject)
+			}
+			if !strings.Contains(msg.Text, tt.wantBody) {
+				t.Errorf("Render() Text missing %q:\n%s", tt.wantBody, msg.Text)
+			}
+			if !strings.Contains(msg.HTML, "<html") {
+				t.Errorf("Render() HTML is not wrapped in the layout:\n%s", msg.HTML)
+			}
+			if strings.Contains(msg.Text, "<no value>") || strings.Contains(msg.HTML, "<no value>") {
+				t.Error("Render() output contains <no value>, a template key is missing")
+			}
+		})
+	}
+}
+
+func TestRenderer_Render_UnknownTemplate(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	if _, err := renderer.Render("does_not_exist", "user@example.com", nil); err == nil {
+		t.Error("Render() expected error for unknown template, got nil")
+	}
+}
+
+func TestRenderer_Render_EscapesHTML(t *testing.T) {
+	renderer, err := NewRenderer()
+	if err != nil {
+		t.Fatalf("NewRenderer() error = %v", err)
+	}
+
+	msg, err := renderer.Render(TemplateVerification, "user@example.com", map[string]interface{}{
+		"Link":      `https://app.example.com/verify-email?token="><script>`,
+		"ExpiresIn": "24 hours",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Errorf("Render() HTML contains unescaped input:\n%s", msg.HTML)
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{time.Hour, "1 hour"},
+		{24 * time.Hour, "24 hours"},
+		{30 * time.Minute, "30 minutes"},
+		{90 * time.Minute, "90 minutes"},
+	}
+
+	for _, tt := range tests {
+		if got := humanDuration(tt.d); got != tt.want {
+			t.Errorf("humanDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,377 @@
+// Package outbound builds the HTTP clients that reach addresses users
+// give us: REST hook targets, feeds, pages fetched for quick analysis and
+// SSO providers. They share one connection pool, refuse private and
+// internal addresses, and bound redirects, response sizes and how many
+// requests a single host gets at once.
+package outbound
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"sync"
+	"syscall"
+	"time"
+)
+
+var (
+	// ErrBlockedAddress is returned for a request to a loopback, private,
+	// link-local or otherwise internal address
+	ErrBlockedAddress = errors.New("destination address is not allowed")
+
+	// ErrResponseTooLarge is returned when reading a response body past
+	// Config.MaxResponseBytes
+	ErrResponseTooLarge = errors.New("response is too large")
+
+	// ErrTooManyRedirects is returned when a request is redirected more
+	// than Config.MaxRedirects times
+	ErrTooManyRedirects = errors.New("too many redirects")
+)
+
+// Defaults for a zero Config
+const (
+	DefaultMaxResponseBytes = 10 << 20
+	DefaultMaxRedirects     = 5
+	DefaultMaxPerHost       = 8
+)
+
+// Config configures a Client
+type Config struct {
+	// MaxResponseBytes bounds a response body
+	MaxResponseBytes int64
+	// MaxRedirects is how many redirects a request follows; a negative
+	// value follows none
+	MaxRedirects int
+	// MaxPerHost bounds the requests in flight to one host; further
+	// requests wait for a slot
+	MaxPerHost int
+	// AllowPrivate lets requests reach internal addresses, for
+	// development against local services
+	AllowPrivate bool
+	// RootCAs verifies servers' certificates; nil uses the system's
+	RootCAs *x509.CertPool
+	// InsecureSkipVerify accepts any server certificate
+	InsecureSkipVerify bool
+	// Proxy picks the proxy for a request, as http.Transport.Proxy does;
+	// nil follows HTTP_PROXY, HTTPS_PROXY and NO_PROXY
+	Proxy func(*http.Request) (*url.URL, error)
+}
+
+// blockedPrefixes are internal or reserved ranges netip.Addr has no
+// predicate for
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// Blocked reports whether addr is internal: loopback, private, link-local,
+// unspecified, multicast or otherwise reserved
+func Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Client hands out HTTP clients sharing one hardened transport
+type Client struct {
+	config    Config
+	hosts     *hostLimiter
+	transport http.RoundTripper
+}
+
+// New creates a client, filling in defaults for zero settings
+func New(cfg Config) *Client {
+	if cfg.MaxResponseBytes <= 0 {
+		cfg.MaxResponseBytes = DefaultMaxResponseBytes
+	}
+	if cfg.MaxRedirects == 0 {
+		cfg.MaxRedirects = DefaultMaxRedirects
+	}
+	if cfg.MaxPerHost <= 0 {
+		cfg.MaxPerHost = DefaultMaxPerHost
+	}
+	if cfg.Proxy == nil {
+		cfg.Proxy = http.ProxyFromEnvironment
+	}
+
+	c := &Client{config: cfg, hosts: newHostLimiter(cfg.MaxPerHost)}
+	c.transport = c.newTransport()
+	return c
+}
+
+// Via returns a client that sends every request through proxyURL. It
+// shares c's limits on each host, but not its connections. A nil URL
+// returns c.
+func (c *Client) Via(proxyURL *url.URL) *Client {
+	if proxyURL == nil {
+		return c
+	}
+	via := &Client{config: c.config, hosts: c.hosts}
+	via.config.Proxy = http.ProxyURL(proxyURL)
+	via.transport = via.newTransport()
+	return via
+}
+
+// newTransport builds the client's transport
+func (c *Client) newTransport() http.RoundTripper {
+	cfg := c.config
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	dial := dialer.DialContext
+
+	// Proxies are set by operators and are often internal, so the
+	// addresses of those requests went through are dialed unchecked
+	var proxies sync.Map
+	proxy := func(req *http.Request) (*url.URL, error) {
+		proxyURL, err := cfg.Proxy(req)
+		if proxyURL != nil {
+			proxies.Store(proxyAddr(proxyURL), true)
+		}
+		return proxyURL, err
+	}
+
+	if !cfg.AllowPrivate {
+		// Addresses are checked as they're dialed, after DNS resolution,
+		// so a name can't resolve to a public address when checked and a
+		// private one when connected to
+		guarded := &net.Dialer{Timeout: dialer.Timeout, KeepAlive: dialer.KeepAlive}
+		guarded.Control = func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+			}
+			if Blocked(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, addrPort.Addr())
+			}
+			return nil
+		}
+		dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			if _, ok := proxies.Load(address); ok {
+				return dialer.DialContext(ctx, network, address)
+			}
+			return guarded.DialContext(ctx, network, address)
+		}
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   cfg.MaxPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig: &tls.Config{
+			MinVersion:         tls.VersionTLS12,
+			RootCAs:            cfg.RootCAs,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		},
+	}
+
+	limited := &limitedTransport{
+		next:     transport,
+		maxBytes: cfg.MaxResponseBytes,
+		hosts:    c.hosts,
+	}
+	if !cfg.AllowPrivate {
+		limited.proxy = cfg.Proxy
+	}
+	return limited
+}
+
+// proxyAddr is the address the transport dials for a proxy
+func proxyAddr(proxyURL *url.URL) string {
+	port := proxyURL.Port()
+	if port == "" {
+		switch proxyURL.Scheme {
+		case "https":
+			port = "443"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "80"
+		}
+	}
+	return net.JoinHostPort(proxyURL.Hostname(), port)
+}
+
+// HTTPClient returns an HTTP client with the given overall timeout that
+// shares the client's connections and limits
+func (c *Client) HTTPClient(timeout time.Duration) *http.Client {
+	maxRedirects := c.config.MaxRedirects
+	return &http.Client{
+		Transport: c.transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if maxRedirects < 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) > maxRedirects {
+				return ErrTooManyRedirects
+			}
+			return nil
+		},
+	}
+}
+
+// limitedTransport bounds response sizes and the requests in flight to
+// each host
+type limitedTransport struct {
+	next     http.RoundTripper
+	maxBytes int64
+	hosts    *hostLimiter
+	// proxy, when set, picks the requests whose destination is checked
+	// before they're sent, since a proxy connects to it for us
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+// RoundTrip implements http.RoundTripper. A host's slot is held until the
+// response body is closed.
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.proxy != nil {
+		if err := checkProxied(req, t.proxy); err != nil {
+			return nil, err
+		}
+	}
+
+	release, err := t.hosts.acquire(req.Context(), req.URL.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	if resp.ContentLength > t.maxBytes {
+		resp.Body.Close()
+		release()
+		return nil, fmt.Errorf("%w: %d bytes", ErrResponseTooLarge, resp.ContentLength)
+	}
+	resp.Body = &limitedBody{body: resp.Body, remaining: t.maxBytes, release: release}
+	return resp, nil
+}
+
+// checkProxied refuses a request going through a proxy when its host
+// resolves to an internal address. The proxy resolves it again, so unlike
+// direct requests this can't rule out a name changing in between.
+func checkProxied(req *http.Request, proxy func(*http.Request) (*url.URL, error)) error {
+	proxyURL, err := proxy(req)
+	if err != nil || proxyURL == nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(req.Context(), "ip", req.URL.Hostname())
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if Blocked(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedAddress, addr)
+		}
+	}
+	return nil
+}
+
+// limitedBody fails reads past a size and releases its host's slot when
+// closed
+type limitedBody struct {
+	body      io.ReadCloser
+	remaining int64
+	release   func()
+	once      sync.Once
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining <= 0 {
+		// Tell a body that ends exactly at the limit from a longer one
+		var probe [1]byte
+		if n, err := b.body.Read(probe[:]); n == 0 {
+			return 0, err
+		}
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	n, err := b.body.Read(p)
+	b.remaining -= int64(n)
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	err := b.body.Close()
+	b.once.Do(b.release)
+	return err
+}
+
+// hostLimiter hands out a fixed number of slots per host
+type hostLimiter struct {
+	max int
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlots
+}
+
+// hostSlots are one host's slots and how many requests hold or wait for
+// one, so idle hosts can be forgotten
+type hostSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+func newHostLimiter(max int) *hostLimiter {
+	return &hostLimiter{max: max, hosts: make(map[string]*hostSlots)}
+}
+
+// acquire waits for a slot for host and returns the func that releases it
+func (l *hostLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	l.mu.Lock()
+	slots, ok := l.hosts[host]
+	if !ok {
+		slots = &hostSlots{sem: make(chan struct{}, l.max)}
+		l.hosts[host] = slots
+	}
+	slots.refs++
+	l.mu.Unlock()
+
+	select {
+	case slots.sem <- struct{}{}:
+		return func() {
+			<-slots.sem
+			l.forget(host, slots)
+		}, nil
+	case <-ctx.Done():
+		l.forget(host, slots)
+		return nil, ctx.Err()
+	}
+}
+
+// forget drops a reference to host's slots, and the slots once unused
+func (l *hostLimiter) forget(host string, slots *hostSlots) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.refs--
+	if slots.refs == 0 {
+		delete(l.hosts, host)
+	}
+}
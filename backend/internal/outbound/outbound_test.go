@@ -0,0 +1,175 @@
+package outbound
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:4700::1111", false},
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"::1", true},
+		{"fd00::1", true},
+		{"fe80::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+	}
+	for _, tt := range tests {
+		if got := Blocked(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("Blocked(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestClient_BlocksPrivateAddresses(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer target.Close()
+
+	_, err := New(Config{}).HTTPClient(time.Second).Get(target.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Get() of a loopback address error = %v, want ErrBlockedAddress", err)
+	}
+
+	resp, err := New(Config{AllowPrivate: true}).HTTPClient(time.Second).Get(target.URL)
+	if err != nil {
+		t.Fatalf("Get() with AllowPrivate error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestClient_MaxResponseBytes(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Flushing first leaves the length out, so the body is cut as it's read
+		w.(http.Flusher).Flush()
+		io.WriteString(w, strings.Repeat("x", 100))
+	}))
+	defer target.Close()
+
+	client := New(Config{AllowPrivate: true, MaxResponseBytes: 10}).HTTPClient(time.Second)
+	resp, err := client.Get(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.ReadAll(resp.Body); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("ReadAll() error = %v, want ErrResponseTooLarge", err)
+	}
+
+	client = New(Config{AllowPrivate: true, MaxResponseBytes: 100}).HTTPClient(time.Second)
+	resp, err = client.Get(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if body, err := io.ReadAll(resp.Body); err != nil || len(body) != 100 {
+		t.Errorf("ReadAll() = %d bytes, %v, want the whole body", len(body), err)
+	}
+}
+
+func TestClient_MaxRedirects(t *testing.T) {
+	var target *httptest.Server
+	target = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+r.URL.Path+"x", http.StatusFound)
+	}))
+	defer target.Close()
+
+	_, err := New(Config{AllowPrivate: true, MaxRedirects: 2}).HTTPClient(time.Second).Get(target.URL + "/")
+	if !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("Get() error = %v, want ErrTooManyRedirects", err)
+	}
+
+	resp, err := New(Config{AllowPrivate: true, MaxRedirects: -1}).HTTPClient(time.Second).Get(target.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Errorf("status = %d, want the redirect itself", resp.StatusCode)
+	}
+}
+
+func TestClient_MaxPerHost(t *testing.T) {
+	var inFlight, most atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+	}))
+	defer target.Close()
+
+	client := New(Config{AllowPrivate: true, MaxPerHost: 2}).HTTPClient(5 * time.Second)
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := client.Get(target.URL)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	if got := most.Load(); got > 2 {
+		t.Errorf("%d requests were in flight at once, want at most 2", got)
+	}
+}
+
+func TestClient_Via(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	// The proxy itself is internal, but requests through it are only
+	// refused for internal destinations
+	client := New(Config{}).Via(proxyURL).HTTPClient(time.Second)
+	resp, err := client.Get("http://93.184.216.34/feed")
+	if err != nil {
+		t.Fatalf("Get() through the proxy error = %v", err)
+	}
+	resp.Body.Close()
+	if len(proxied) != 1 || proxied[0] != "http://93.184.216.34/feed" {
+		t.Errorf("proxied %v, want the request", proxied)
+	}
+
+	if _, err := client.Get("http://10.0.0.1/"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("Get() of an internal address through the proxy error = %v, want ErrBlockedAddress", err)
+	}
+	if len(proxied) != 1 {
+		t.Errorf("proxied %v, want the internal request refused", proxied)
+	}
+}
@@ -0,0 +1,126 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/pkg/webhooksig"
+)
+
+// WebhookJobType delivers one quota warning to the operator's webhook
+const WebhookJobType = "quota.webhook"
+
+// WebhookEvent names quota warnings in webhook deliveries
+const WebhookEvent = "quota.warning"
+
+// Mailer sends quota warning emails; *notifications.Notifier implements it
+type Mailer interface {
+	SendQuotaWarning(ctx context.Context, to string, used, limit int, resetsOn time.Time) error
+}
+
+// emailNotifier emails the user who crossed the threshold
+type emailNotifier struct {
+	mailer Mailer
+}
+
+// NewEmailNotifier warns users by email
+func NewEmailNotifier(mailer Mailer) Notifier {
+	return emailNotifier{mailer: mailer}
+}
+
+// NotifyQuota implements Notifier
+func (n emailNotifier) NotifyQuota(ctx context.Context, warning Warning) error {
+	return n.mailer.SendQuotaWarning(ctx, warning.Email, warning.Used, warning.Limit, warning.ResetsAt.Time)
+}
+
+// Enqueuer schedules background jobs; *queue.Queue implements it
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// webhookNotifier queues warnings for delivery by a Webhook
+type webhookNotifier struct {
+	queue Enqueuer
+}
+
+// NewWebhookNotifier queues warnings as WebhookJobType jobs, so a slow or
+// failing endpoint is retried by the worker instead of holding up requests
+func NewWebhookNotifier(q Enqueuer) Notifier {
+	return webhookNotifier{queue: q}
+}
+
+// NotifyQuota implements Notifier
+func (n webhookNotifier) NotifyQuota(ctx context.Context, warning Warning) error {
+	if _, err := n.queue.Enqueue(ctx, WebhookJobType, warning); err != nil {
+		return fmt.Errorf("failed to enqueue quota webhook: %w", err)
+	}
+	return nil
+}
+
+// webhookBody is the JSON posted for each warning
+type webhookBody struct {
+	Event string `json:"event"`
+	Warning
+}
+
+// Webhook posts quota warnings to an endpoint, signed with pkg/webhooksig
+type Webhook struct {
+	url        string
+	secret     []byte
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewWebhook posts warnings to url, signed with secret
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		url:        url,
+		secret:     []byte(secret),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// WithHTTPClient posts warnings with client and returns the webhook
+func (w *Webhook) WithHTTPClient(client *http.Client) *Webhook {
+	w.httpClient = client
+	return w
+}
+
+// Handle implements queue.Handler for WebhookJobType
+func (w *Webhook) Handle(ctx context.Context, job *queue.Job) error {
+	var warning Warning
+	if err := job.Decode(&warning); err != nil {
+		return fmt.Errorf("invalid quota webhook payload: %w", err)
+	}
+
+	body, err := json.Marshal(webhookBody{Event: WebhookEvent, Warning: warning})
+	if err != nil {
+		return fmt.Errorf("failed to encode quota webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create quota webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhooksig.HeaderName, webhooksig.Sign(w.now(), body, w.secret))
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver quota webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("quota webhook returned %d: %s", resp.StatusCode, reply)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package quota counts analyses against monthly per-plan quotas and warns
+// users as they approach them. Quotas are soft for now: usage is reported
+// in response headers and notifications, but nothing is rejected.
+package quota
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Response headers describing the caller's quota
+const (
+	HeaderLimit     = "X-Quota-Limit"
+	HeaderRemaining = "X-Quota-Remaining"
+	HeaderReset     = "X-Quota-Reset"
+)
+
+// Thresholds are the percentages of a quota at which users are warned
+var Thresholds = []int{80, 100}
+
+// counterGrace keeps a month's counter around a little after the month
+// ends so late reads don't see it vanish mid-request
+const counterGrace = 24 * time.Hour
+
+// Counter counts analyses in a window and remembers which were counted,
+// so they can be refunded; *cache.Cache implements it
+type Counter interface {
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Decr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Take(ctx context.Context, key string) (string, error)
+}
+
+// Limits maps plans to their monthly analysis quota. Plans without an
+// entry are unlimited.
+type Limits map[models.Plan]int
+
+// ParseLimits reads "plan:limit" entries such as "free:100"
+func ParseLimits(entries []string) (Limits, error) {
+	limits := make(Limits, len(entries))
+	for _, entry := range entries {
+		name, value, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q is not in plan:limit form", entry)
+		}
+
+		plan, err := models.ParsePlan(strings.TrimSpace(name))
+		if err != nil {
+			return nil, err
+		}
+		if _, dup := limits[plan]; dup {
+			return nil, fmt.Errorf("plan %s is listed twice", plan)
+		}
+
+		limit, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || limit <= 0 {
+			return nil, fmt.Errorf("limit for %s must be a positive integer", plan)
+		}
+		limits[plan] = limit
+	}
+	return limits, nil
+}
+
+// Usage is how much of a user's quota is used this month
+type Usage struct {
+	Used     int
+	Limit    int
+	ResetsAt time.Time
+}
+
+// Remaining is the number of analyses left this month
+func (u Usage) Remaining() int {
+	return max(u.Limit-u.Used, 0)
+}
+
+// SetHeaders describes the usage in X-Quota-* response headers. The
+// reset time is in Unix seconds.
+func (u Usage) SetHeaders(h http.Header) {
+	h.Set(HeaderLimit, strconv.Itoa(u.Limit))
+	h.Set(HeaderRemaining, strconv.Itoa(u.Remaining()))
+	h.Set(HeaderReset, strconv.FormatInt(u.ResetsAt.Unix(), 10))
+}
+
+// Warning tells a user they have used Percent of their quota
+type Warning struct {
+	UserID   uuid.UUID      `json:"user_id"`
+	Email    string         `json:"email"`
+	Plan     models.Plan    `json:"plan"`
+	Percent  int            `json:"percent"`
+	Used     int            `json:"used"`
+	Limit    int            `json:"limit"`
+	ResetsAt timestamp.Time `json:"resets_at"`
+}
+
+// Notifier delivers quota warnings
+type Notifier interface {
+	NotifyQuota(ctx context.Context, warning Warning) error
+}
+
+// Tracker counts analyses per user and calendar month (UTC)
+type Tracker struct {
+	counter   Counter
+	limits    Limits
+	notifiers []Notifier
+	now       func() time.Time
+}
+
+// NewTracker counts analyses in counter against limits
+func NewTracker(counter Counter, limits Limits) *Tracker {
+	return &Tracker{
+		counter: counter,
+		limits:  limits,
+		now:     time.Now,
+	}
+}
+
+// WithNotifier adds a notifier that is told when a user crosses one of
+// the Thresholds
+func (t *Tracker) WithNotifier(n Notifier) *Tracker {
+	t.notifiers = append(t.notifiers, n)
+	return t
+}
+
+// Record counts an analysis for user and returns their usage. ok is false
+// when the user's plan has no quota. chargeID names the charge for Refund:
+// the submission analyzed, or the upload it will be made from. Failed
+// notifications are logged, not returned, so they never fail the analysis.
+func (t *Tracker) Record(ctx context.Context, user *models.User, chargeID uuid.UUID) (usage Usage, ok bool, err error) {
+	limit := t.limits[user.Plan]
+	if limit <= 0 {
+		return Usage{}, false, nil
+	}
+
+	now := t.now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	resetsAt := month.AddDate(0, 1, 0)
+	key := fmt.Sprintf("quota:%s:%s", user.ID, month.Format("2006-01"))
+
+	ttl := resetsAt.Sub(now) + counterGrace
+	used, err := t.counter.Incr(ctx, key, ttl)
+	if err != nil {
+		return Usage{}, false, err
+	}
+	// The charge names the month's counter, and is forgotten with it
+	if err := t.counter.Set(ctx, chargeKey(chargeID), key, ttl); err != nil {
+		slog.Warn("Failed to record quota charge; it can't be refunded", "charge_id", chargeID, "error", err)
+	}
+
+	usage = Usage{Used: int(used), Limit: limit, ResetsAt: resetsAt}
+	if percent := crossed(usage.Used, limit); percent > 0 {
+		t.notify(ctx, Warning{
+			UserID:   user.ID,
+			Email:    user.Email,
+			Plan:     user.Plan,
+			Percent:  percent,
+			Used:     usage.Used,
+			Limit:    limit,
+			ResetsAt: timestamp.New(resetsAt),
+		})
+	}
+	return usage, true, nil
+}
+
+// Refund gives back the analysis counted under chargeID, when it is
+// canceled or fails for good. An analysis is refunded at most once, to
+// the month it was counted in, and one that wasn't counted, such as a
+// feed's, or whose month is over, is left alone.
+func (t *Tracker) Refund(ctx context.Context, chargeID uuid.UUID) error {
+	key, err := t.counter.Take(ctx, chargeKey(chargeID))
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+	if _, err := t.counter.Decr(ctx, key, counterGrace); err != nil {
+		return fmt.Errorf("failed to refund quota: %w", err)
+	}
+	return nil
+}
+
+// Transfer moves the charge recorded under from to to, for an upload
+// whose analysis goes on as a submission
+func (t *Tracker) Transfer(ctx context.Context, from, to uuid.UUID) error {
+	key, err := t.counter.Take(ctx, chargeKey(from))
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to move quota charge: %w", err)
+	}
+	// The charge lives at most as long as the month's counter
+	if err := t.counter.Set(ctx, chargeKey(to), key, t.chargeTTL()); err != nil {
+		return fmt.Errorf("failed to move quota charge: %w", err)
+	}
+	return nil
+}
+
+// chargeTTL is how long a charge made now is kept: until its month's
+// counter is gone
+func (t *Tracker) chargeTTL() time.Duration {
+	now := t.now().UTC()
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC).Sub(now) + counterGrace
+}
+
+// chargeKey is the Redis key naming the counter an analysis was counted in
+func chargeKey(id uuid.UUID) string {
+	return "quota:charge:" + id.String()
+}
+
+// notify hands a warning to every notifier
+func (t *Tracker) notify(ctx context.Context, warning Warning) {
+	for _, n := range t.notifiers {
+		if err := n.NotifyQuota(ctx, warning); err != nil {
+			slog.Error("Failed to send quota warning", "user_id", warning.UserID, "percent", warning.Percent, "error", err)
+		}
+	}
+}
+
+// crossed returns the highest threshold that used has just reached, or 0.
+// The counter moves one at a time, so exactly one request sees each
+// threshold and the warning goes out once per month.
+func crossed(used, limit int) int {
+	for i := len(Thresholds) - 1; i >= 0; i-- {
+		if used == thresholdCount(limit, Thresholds[i]) {
+			return Thresholds[i]
+		}
+	}
+	return 0
+}
+
+// thresholdCount is the first count at or above percent of limit
+func thresholdCount(limit, percent int) int {
+	return (limit*percent + 99) / 100
+}
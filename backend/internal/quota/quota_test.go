@@ -0,0 +1,277 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/pkg/webhooksig"
+)
+
+// fakeCounter counts in memory and remembers the last TTL
+type fakeCounter struct {
+	counts map[string]int64
+	values map[string]string
+	ttl    time.Duration
+}
+
+func (c *fakeCounter) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[key]++
+	c.ttl = ttl
+	return c.counts[key], nil
+}
+
+func (c *fakeCounter) Decr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[key]--
+	return c.counts[key], nil
+}
+
+func (c *fakeCounter) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	if c.values == nil {
+		c.values = make(map[string]string)
+	}
+	c.values[key] = value.(string)
+	return nil
+}
+
+func (c *fakeCounter) Take(ctx context.Context, key string) (string, error) {
+	value, ok := c.values[key]
+	if !ok {
+		return "", cache.ErrNotFound
+	}
+	delete(c.values, key)
+	return value, nil
+}
+
+// recordingNotifier keeps the warnings it is given
+type recordingNotifier struct {
+	warnings []Warning
+}
+
+func (n *recordingNotifier) NotifyQuota(ctx context.Context, warning Warning) error {
+	n.warnings = append(n.warnings, warning)
+	return nil
+}
+
+func TestParseLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    Limits
+		wantErr bool
+	}{
+		{name: "none", entries: nil, want: Limits{}},
+		{name: "plans", entries: []string{"free:100", " pro : 2000"}, want: Limits{models.PlanFree: 100, models.PlanPro: 2000}},
+		{name: "missing limit", entries: []string{"free"}, wantErr: true},
+		{name: "unknown plan", entries: []string{"gold:5"}, wantErr: true},
+		{name: "zero", entries: []string{"free:0"}, wantErr: true},
+		{name: "not a number", entries: []string{"free:lots"}, wantErr: true},
+		{name: "duplicate", entries: []string{"free:1", "free:2"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLimits(tt.entries)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLimits() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseLimits() = %v, want %v", got, tt.want)
+			}
+			for plan, limit := range tt.want {
+				if got[plan] != limit {
+					t.Errorf("ParseLimits()[%s] = %d, want %d", plan, got[plan], limit)
+				}
+			}
+		})
+	}
+}
+
+func TestTracker_Record(t *testing.T) {
+	counter := &fakeCounter{}
+	notifier := &recordingNotifier{}
+	tracker := NewTracker(counter, Limits{models.PlanFree: 5}).WithNotifier(notifier)
+	tracker.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Plan: models.PlanFree}
+	resetsAt := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	var usage Usage
+	for i := 0; i < 6; i++ {
+		var ok bool
+		var err error
+		usage, ok, err = tracker.Record(context.Background(), user, uuid.New())
+		if err != nil || !ok {
+			t.Fatalf("Record() = %v, %v, want a counted analysis", ok, err)
+		}
+	}
+
+	if usage.Used != 6 || usage.Remaining() != 0 || !usage.ResetsAt.Equal(resetsAt) {
+		t.Errorf("Record() = %+v, want 6 used, none remaining, reset at %s", usage, resetsAt)
+	}
+	if want := 15*24*time.Hour + 12*time.Hour + counterGrace; counter.ttl != want {
+		t.Errorf("counter TTL = %s, want %s", counter.ttl, want)
+	}
+
+	// 80% of 5 is reached on the 4th analysis and 100% on the 5th; the
+	// 6th is over quota but not warned about again
+	if len(notifier.warnings) != 2 {
+		t.Fatalf("warnings = %+v, want 2", notifier.warnings)
+	}
+	for i, want := range []struct{ percent, used int }{{80, 4}, {100, 5}} {
+		got := notifier.warnings[i]
+		if got.Percent != want.percent || got.Used != want.used || got.Email != user.Email || got.Limit != 5 {
+			t.Errorf("warning %d = %+v, want %d%% at %d used", i, got, want.percent, want.used)
+		}
+	}
+}
+
+func TestTracker_Refund(t *testing.T) {
+	ctx := context.Background()
+	counter := &fakeCounter{}
+	tracker := NewTracker(counter, Limits{models.PlanFree: 5})
+	tracker.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+
+	user := &models.User{ID: uuid.New(), Plan: models.PlanFree}
+	canceled, upload, submission := uuid.New(), uuid.New(), uuid.New()
+	for _, id := range []uuid.UUID{canceled, upload, uuid.New()} {
+		if _, _, err := tracker.Record(ctx, user, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	key := "quota:" + user.ID.String() + ":2026-10"
+
+	// A charge is refunded once; unknown charges, such as feeds', are
+	// left alone
+	for _, id := range []uuid.UUID{canceled, canceled, uuid.New()} {
+		if err := tracker.Refund(ctx, id); err != nil {
+			t.Fatalf("Refund() error = %v", err)
+		}
+	}
+	if counter.counts[key] != 2 {
+		t.Errorf("count after refunds = %d, want 2", counter.counts[key])
+	}
+
+	// An upload's charge follows it to the submission made from it
+	if err := tracker.Transfer(ctx, upload, submission); err != nil {
+		t.Fatalf("Transfer() error = %v", err)
+	}
+	if err := tracker.Refund(ctx, upload); err != nil || counter.counts[key] != 2 {
+		t.Errorf("Refund() of a transferred upload = %v, count %d, want nothing refunded", err, counter.counts[key])
+	}
+	if err := tracker.Refund(ctx, submission); err != nil || counter.counts[key] != 1 {
+		t.Errorf("Refund() of the submission = %v, count %d, want 1", err, counter.counts[key])
+	}
+}
+
+func TestTracker_Record_Unlimited(t *testing.T) {
+	counter := &fakeCounter{}
+	tracker := NewTracker(counter, Limits{models.PlanFree: 5})
+
+	_, ok, err := tracker.Record(context.Background(), &models.User{ID: uuid.New(), Plan: models.PlanPro}, uuid.New())
+	if err != nil || ok {
+		t.Errorf("Record() = %v, %v, want no quota for an unlisted plan", ok, err)
+	}
+	if len(counter.counts) != 0 {
+		t.Errorf("Record() counted %v, want nothing counted", counter.counts)
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	tests := []struct {
+		used  int
+		limit int
+		want  int
+	}{
+		{used: 7, limit: 10, want: 0},
+		{used: 8, limit: 10, want: 80},
+		{used: 9, limit: 10, want: 0},
+		{used: 10, limit: 10, want: 100},
+		{used: 11, limit: 10, want: 0},
+		// 80% of 3 rounds up to the 3rd analysis, which is also 100%
+		{used: 3, limit: 3, want: 100},
+		{used: 1, limit: 1, want: 100},
+	}
+
+	for _, tt := range tests {
+		if got := crossed(tt.used, tt.limit); got != tt.want {
+			t.Errorf("crossed(%d, %d) = %d, want %d", tt.used, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestUsage_SetHeaders(t *testing.T) {
+	header := http.Header{}
+	Usage{Used: 12, Limit: 10, ResetsAt: time.Unix(1793491200, 0)}.SetHeaders(header)
+
+	for name, want := range map[string]string{
+		HeaderLimit:     "10",
+		HeaderRemaining: "0",
+		HeaderReset:     "1793491200",
+	} {
+		if got := header.Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestWebhook_Handle(t *testing.T) {
+	secret := "whsec_test"
+	var got webhookBody
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+		}
+		if err := webhooksig.Verify(r.Header.Get(webhooksig.HeaderName), body, []byte(secret), 0); err != nil {
+			t.Errorf("signature did not verify: %v", err)
+		}
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("failed to decode body: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	warning := Warning{UserID: uuid.New(), Email: "user@example.com", Percent: 80, Used: 8, Limit: 10}
+	payload, err := json.Marshal(warning)
+	if err != nil {
+		t.Fatalf("failed to encode warning: %v", err)
+	}
+
+	if err := NewWebhook(srv.URL, secret).Handle(context.Background(), &queue.Job{Type: WebhookJobType, Payload: payload}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if got.Event != WebhookEvent || got.UserID != warning.UserID || got.Percent != 80 {
+		t.Errorf("delivered %+v, want %s for %+v", got, WebhookEvent, warning)
+	}
+}
+
+func TestWebhook_Handle_Failure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	job := &queue.Job{Type: WebhookJobType, Payload: json.RawMessage(`{}`)}
+	if err := NewWebhook(srv.URL, "secret").Handle(context.Background(), job); err == nil {
+		t.Error("Handle() error = nil, want an error so the job is retried")
+	}
+}
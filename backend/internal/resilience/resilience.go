@@ -0,0 +1,231 @@
+// Package resilience retries operations that fail for transient reasons,
+// such as a Postgres serialization failure or a dropped connection, so a
+// blip doesn't reach users as a 500.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/redis/go-redis/v9"
+)
+
+// Policy describes how an operation is retried
+type Policy struct {
+	// MaxAttempts includes the first try
+	MaxAttempts int
+	// BaseDelay is the backoff cap before the second attempt; it doubles
+	// on every retry up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Retryable decides whether an error is worth another attempt
+	Retryable func(error) bool
+}
+
+var (
+	// Reads retries idempotent operations, including after a connection
+	// drops mid-statement
+	Reads = Policy{MaxAttempts: 3, BaseDelay: 25 * time.Millisecond, MaxDelay: 500 * time.Millisecond, Retryable: IsTransient}
+
+	// Writes only retries when the statement certainly wasn't applied: it
+	// was rolled back by a serialization failure or deadlock, or never
+	// reached the server. Use it for inserts and other non-idempotent writes.
+	Writes = Policy{MaxAttempts: 3, BaseDelay: 25 * time.Millisecond, MaxDelay: 500 * time.Millisecond, Retryable: IsSafeToRetry}
+
+	// Redis retries cache commands. The client already retries network
+	// errors a few times, so this adds one attempt that outlasts a short
+	// failover.
+	Redis = Policy{MaxAttempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 500 * time.Millisecond, Retryable: IsTransient}
+
+	// RedisWrites retries non-idempotent Redis commands, such as INCRBY,
+	// only when the server refused the connection or rejected the command
+	// unexecuted
+	RedisWrites = Policy{MaxAttempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 500 * time.Millisecond, Retryable: isUnexecutedRedis}
+)
+
+// startup backs off between attempts to reach a dependency that may
+// still be starting; Wait retries until its timeout rather than a number
+// of attempts
+var startup = Policy{BaseDelay: 500 * time.Millisecond, MaxDelay: 5 * time.Second}
+
+// sleep waits between attempts and now tells the time; tests replace them
+var (
+	sleep = defaultSleep
+	now   = time.Now
+)
+
+// defaultSleep waits for d or until ctx is done
+func defaultSleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Do runs fn until it succeeds, returns an error the policy won't retry,
+// or runs out of attempts. The last error is returned.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(ctx)
+		if err == nil || attempt >= p.MaxAttempts || !p.Retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt)
+		slog.DebugContext(ctx, "Retrying after transient error", "attempt", attempt, "delay", delay, "error", err)
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// Wait runs fn until it succeeds or timeout has passed, backing off
+// between attempts, and returns the last error. It is for connecting to a
+// dependency at startup, which may itself still be starting; a zero
+// timeout tries once.
+func Wait(ctx context.Context, dependency string, timeout time.Duration, fn func(ctx context.Context) error) error {
+	deadline := now().Add(timeout)
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || isContextError(err) {
+			return err
+		}
+		remaining := deadline.Sub(now())
+		if remaining <= 0 {
+			return err
+		}
+
+		delay := min(startup.backoff(attempt), remaining)
+		slog.WarnContext(ctx, "Waiting for dependency", "dependency", dependency, "attempt", attempt, "retry_in", delay, "error", err)
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// Value is Do for operations that return a result
+func Value[T any](ctx context.Context, p Policy, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := p.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// backoff returns a random delay up to the exponential cap for the given
+// attempt ("full jitter"), which spreads out clients retrying together
+func (p Policy) backoff(attempt int) time.Duration {
+	ceiling := p.BaseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxDelay {
+		ceiling = p.MaxDelay
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// Postgres error codes that are safe to retry because the transaction was
+// rolled back or the connection was refused
+var retryablePgCodes = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected
+	"57P03": true, // cannot_connect_now
+	"53300": true, // too_many_connections
+}
+
+// IsSafeToRetry reports whether err means the operation certainly had no
+// effect and may be repeated even if it isn't idempotent
+func IsSafeToRetry(err error) bool {
+	if err == nil || isContextError(err) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return retryablePgCodes[pgErr.Code]
+	}
+
+	// Connecting failed, or pgx knows nothing was sent
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) || pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsTransient reports whether err is likely to go away on retry. It
+// includes connections dropped mid-operation, so the operation may have
+// been applied; only use it for idempotent operations.
+func IsTransient(err error) bool {
+	if err == nil || isContextError(err) {
+		return false
+	}
+	if IsSafeToRetry(err) {
+		return true
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions; 57P01 and 57P02 are shutdowns
+		return strings.HasPrefix(pgErr.Code, "08") || pgErr.Code == "57P01" || pgErr.Code == "57P02"
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return isTransientRedis(err)
+}
+
+// isTransientRedis matches Redis replies sent while a server is loading,
+// failing over or read-only
+func isTransientRedis(err error) bool {
+	var redisErr redis.Error
+	if !errors.As(err, &redisErr) || errors.Is(err, redis.Nil) {
+		return false
+	}
+
+	msg := redisErr.Error()
+	for _, prefix := range []string{"LOADING ", "READONLY ", "MASTERDOWN ", "TRYAGAIN ", "CLUSTERDOWN "} {
+		if strings.HasPrefix(msg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isUnexecutedRedis reports whether a Redis command certainly wasn't run
+func isUnexecutedRedis(err error) bool {
+	return IsSafeToRetry(err) || isTransientRedis(err)
+}
+
+// isContextError reports cancellations, which retrying can't fix
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
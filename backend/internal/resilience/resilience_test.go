@@ -0,0 +1,243 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisReply is an error reply from a Redis server
+type redisReply string
+
+func (e redisReply) Error() string { return string(e) }
+func (e redisReply) RedisError()   {}
+
+func TestClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		err           error
+		wantSafe      bool
+		wantTransient bool
+	}{
+		{name: "serialization failure", err: &pgconn.PgError{Code: "40001"}, wantSafe: true, wantTransient: true},
+		{name: "deadlock", err: fmt.Errorf("failed to save: %w", &pgconn.PgError{Code: "40P01"}), wantSafe: true, wantTransient: true},
+		{name: "connection refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), wantSafe: true, wantTransient: true},
+		{name: "connection reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), wantSafe: false, wantTransient: true},
+		{name: "unexpected EOF", err: io.ErrUnexpectedEOF, wantSafe: false, wantTransient: true},
+		{name: "admin shutdown", err: &pgconn.PgError{Code: "57P01"}, wantSafe: false, wantTransient: true},
+		{name: "connection exception", err: &pgconn.PgError{Code: "08006"}, wantSafe: false, wantTransient: true},
+		{name: "redis loading", err: redisReply("LOADING Redis is loading the dataset in memory"), wantTransient: true},
+		{name: "redis readonly", err: fmt.Errorf("set: %w", redisReply("READONLY You can't write against a read only replica.")), wantTransient: true},
+
+		{name: "unique violation", err: &pgconn.PgError{Code: "23505"}},
+		{name: "no rows", err: pgx.ErrNoRows},
+		{name: "redis nil", err: redis.Nil},
+		{name: "redis wrong type", err: redisReply("WRONGTYPE Operation against a key holding the wrong kind of value")},
+		{name: "canceled", err: context.Canceled},
+		{name: "deadline", err: fmt.Errorf("query: %w", context.DeadlineExceeded)},
+		{name: "other", err: errors.New("boom")},
+		{name: "nil", err: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSafeToRetry(tt.err); got != tt.wantSafe {
+				t.Errorf("IsSafeToRetry(%v) = %v, want %v", tt.err, got, tt.wantSafe)
+			}
+			if got := IsTransient(tt.err); got != tt.wantTransient {
+				t.Errorf("IsTransient(%v) = %v, want %v", tt.err, got, tt.wantTransient)
+			}
+		})
+	}
+}
+
+func TestIsUnexecutedRedis(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"connection refused", fmt.Errorf("dial: %w", syscall.ECONNREFUSED), true},
+		{"redis loading", redisReply("LOADING Redis is loading the dataset in memory"), true},
+		{"redis readonly", redisReply("READONLY You can't write against a read only replica."), true},
+		// The command may have run before the connection dropped
+		{"connection reset", fmt.Errorf("read: %w", syscall.ECONNRESET), false},
+		{"unexpected EOF", io.ErrUnexpectedEOF, false},
+		{"redis wrong type", redisReply("WRONGTYPE Operation against a key holding the wrong kind of value"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnexecutedRedis(tt.err); got != tt.want {
+				t.Errorf("isUnexecutedRedis(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	var slept []time.Duration
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = defaultSleep })
+
+	transient := &pgconn.PgError{Code: "40001"}
+	permanent := &pgconn.PgError{Code: "23505"}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "succeeds first time", errs: []error{nil}, wantCalls: 1},
+		{name: "recovers", errs: []error{transient, transient, nil}, wantCalls: 3},
+		{name: "gives up", errs: []error{transient, transient, transient, nil}, wantCalls: 3, wantErr: transient},
+		{name: "permanent error", errs: []error{permanent, nil}, wantCalls: 1, wantErr: permanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slept = nil
+			calls := 0
+
+			err := Writes.Do(context.Background(), func(ctx context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Do() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Do() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+			if len(slept) != tt.wantCalls-1 {
+				t.Errorf("Do() slept %d times, want %d", len(slept), tt.wantCalls-1)
+			}
+		})
+	}
+}
+
+func TestPolicy_Do_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := Reads.Do(ctx, func(ctx context.Context) error {
+		calls++
+		return syscall.ECONNRESET
+	})
+
+	if calls != 1 {
+		t.Errorf("Do() called fn %d times after cancellation, want 1", calls)
+	}
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Errorf("Do() error = %v, want the operation's error", err)
+	}
+}
+
+func TestWait(t *testing.T) {
+	start := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	clock := start
+	now = func() time.Time { return clock }
+	sleep = func(ctx context.Context, d time.Duration) error {
+		clock = clock.Add(d)
+		return nil
+	}
+	t.Cleanup(func() {
+		sleep = defaultSleep
+		now = time.Now
+	})
+
+	refused := fmt.Errorf("dial: %w", syscall.ECONNREFUSED)
+
+	tests := []struct {
+		name      string
+		timeout   time.Duration
+		failures  int
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "up at once", timeout: time.Minute, failures: 0, wantCalls: 1},
+		{name: "comes up", timeout: time.Minute, failures: 3, wantCalls: 4},
+		{name: "no waiting", timeout: 0, failures: 3, wantCalls: 1, wantErr: refused},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock = start
+			calls := 0
+
+			err := Wait(context.Background(), "postgres", tt.timeout, func(ctx context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return refused
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("Wait() error = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Wait() called fn %d times, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+
+	t.Run("gives up at the timeout", func(t *testing.T) {
+		clock = start
+		err := Wait(context.Background(), "redis", 20*time.Second, func(ctx context.Context) error {
+			return refused
+		})
+
+		if !errors.Is(err, refused) {
+			t.Errorf("Wait() error = %v, want %v", err, refused)
+		}
+		if waited := clock.Sub(start); waited != 20*time.Second {
+			t.Errorf("Wait() waited %v, want the 20s timeout", waited)
+		}
+	})
+}
+
+func TestValue(t *testing.T) {
+	sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	t.Cleanup(func() { sleep = defaultSleep })
+
+	calls := 0
+	got, err := Value(context.Background(), Reads, func(ctx context.Context) (string, error) {
+		calls++
+		if calls == 1 {
+			return "", io.EOF
+		}
+		return "ok", nil
+	})
+
+	if err != nil || got != "ok" {
+		t.Errorf("Value() = %q, %v, want %q, nil", got, err, "ok")
+	}
+}
+
+func TestPolicy_Backoff(t *testing.T) {
+	p := Policy{BaseDelay: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond}
+
+	for attempt, ceiling := range map[int]time.Duration{1: 10 * time.Millisecond, 2: 20 * time.Millisecond, 3: 40 * time.Millisecond, 4: 50 * time.Millisecond, 40: 50 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := p.backoff(attempt); d < 0 || d > ceiling {
+				t.Fatalf("backoff(%d) = %v, want between 0 and %v", attempt, d, ceiling)
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package response
+
+import "golang.org/x/text/language"
+
+// catalog translates error messages, keyed by their English text, which
+// is what handlers pass. Messages missing from a locale are sent in
+// English, so only the common ones need to be here. Formats used with
+// Sprintf keep their verbs in the same order.
+var catalog = map[language.Tag]map[string]string{
+	language.Spanish: {
+		// Defaults
+		"Unauthorized":                         "No autorizado",
+		"Forbidden":                            "Prohibido",
+		"Not found":                            "No encontrado",
+		"Conflict":                             "Conflicto",
+		"Precondition required":                "Se requiere una condición previa",
+		"Too many requests":                    "Demasiadas solicitudes",
+		"Internal server error":                "Error interno del servidor",
+		"Service unavailable":                  "Servicio no disponible",
+		"Validation failed":                    "La validación falló",
+		"Method not allowed":                   "Método no permitido",
+		"Invalid request body":                 "Cuerpo de la solicitud no válido",
+		"The requested resource was not found": "No se encontró el recurso solicitado",
+
+		// Authentication
+		"Missing authorization header":            "Falta la cabecera de autorización",
+		"Invalid authorization header format":     "Formato de cabecera de autorización no válido",
+		"Invalid or expired token":                "Token no válido o caducado",
+		"Session has been revoked":                "La sesión ha sido revocada",
+		"Missing API key":                         "Falta la clave de API",
+		"Invalid or revoked API key":              "Clave de API no válida o revocada",
+		"Admin access required":                   "Se requiere acceso de administrador",
+		"Scoped tokens can't be used here":        "Los tokens con ámbito no se pueden usar aquí",
+		"Impersonation tokens can't be used here": "Los tokens de suplantación no se pueden usar aquí",
+		"Not allowed while impersonating a user":  "No permitido mientras se suplanta a un usuario",
+		"Invalid email or password":               "Correo electrónico o contraseña no válidos",
+		"Email already exists":                    "El correo electrónico ya existe",
+		"Sign-in has expired; sign in again":      "El inicio de sesión ha caducado; vuelve a iniciar sesión",
+		"Your account has been suspended":         "Tu cuenta ha sido suspendida",
+		"Your account has been banned":            "Tu cuenta ha sido bloqueada",
+
+		// Resources
+		"Submission not found":    "Envío no encontrado",
+		"Invalid submission ID":   "ID de envío no válido",
+		"User not found":          "Usuario no encontrado",
+		"Invalid user ID":         "ID de usuario no válido",
+		"Organization not found":  "Organización no encontrada",
+		"Invalid organization ID": "ID de organización no válido",
+		"Member not found":        "Miembro no encontrado",
+		"Analysis not available":  "Análisis no disponible",
+		"Thread not found":        "Conversación no encontrada",
+		"Import not found":        "Importación no encontrada",
+		"Transcript not found":    "Transcripción no encontrada",
+		"Feature flag not found":  "Indicador de función no encontrado",
+		"Invalid 'from' parameter: use RFC3339 or YYYY-MM-DD": "Parámetro 'from' no válido: usa RFC3339 o AAAA-MM-DD",
+		"Invalid 'to' parameter: use RFC3339 or YYYY-MM-DD":   "Parámetro 'to' no válido: usa RFC3339 o AAAA-MM-DD",
+		"'from' must be before 'to'":                          "'from' debe ser anterior a 'to'",
+
+		// Limits
+		"Too much work is queued, please try again later":   "Hay demasiado trabajo en cola; inténtalo de nuevo más tarde",
+		"Too many sign-up attempts, please try again later": "Demasiados intentos de registro; inténtalo de nuevo más tarde",
+		"Too many quick analyses, please try again later":   "Demasiados análisis rápidos; inténtalo de nuevo más tarde",
+		"Analysis failed, please try again":                 "El análisis falló; inténtalo de nuevo",
+		"Upload an audio or video file":                     "Sube un archivo de audio o vídeo",
+		"Expected a multipart/form-data upload":             "Se esperaba una carga multipart/form-data",
+		"Imports must be at most %d MB":                     "Las importaciones deben ocupar como máximo %d MB",
+		"Uploads must be at most %d MB":                     "Las cargas deben ocupar como máximo %d MB",
+	},
+	language.French: {
+		// Defaults
+		"Unauthorized":                         "Non autorisé",
+		"Forbidden":                            "Interdit",
+		"Not found":                            "Introuvable",
+		"Conflict":                             "Conflit",
+		"Precondition required":                "Condition préalable requise",
+		"Too many requests":                    "Trop de requêtes",
+		"Internal server error":                "Erreur interne du serveur",
+		"Service unavailable":                  "Service indisponible",
+		"Validation failed":                    "Échec de la validation",
+		"Method not allowed":                   "Méthode non autorisée",
+		"Invalid request body":                 "Corps de requête invalide",
+		"The requested resource was not found": "La ressource demandée est introuvable",
+
+		// Authentication
+		"Missing authorization header":            "En-tête d'autorisation manquant",
+		"Invalid authorization header format":     "Format d'en-tête d'autorisation invalide",
+		"Invalid or expired token":                "Jeton invalide ou expiré",
+		"Session has been revoked":                "La session a été révoquée",
+		"Missing API key":                         "Clé d'API manquante",
+		"Invalid or revoked API key":              "Clé d'API invalide ou révoquée",
+		"Admin access required":                   "Accès administrateur requis",
+		"Scoped tokens can't be used here":        "Les jetons à portée limitée ne peuvent pas être utilisés ici",
+		"Impersonation tokens can't be used here": "Les jetons d'usurpation d'identité ne peuvent pas être utilisés ici",
+		"Not allowed while impersonating a user":  "Non autorisé en agissant au nom d'un utilisateur",
+		"Invalid email or password":               "Adresse e-mail ou mot de passe incorrect",
+		"Email already exists":                    "Cette adresse e-mail existe déjà",
+		"Sign-in has expired; sign in again":      "La connexion a expiré ; reconnectez-vous",
+		"Your account has been suspended":         "Votre compte a été suspendu",
+		"Your account has been banned":            "Votre compte a été banni",
+
+		// Resources
+		"Submission not found":    "Soumission introuvable",
+		"Invalid submission ID":   "Identifiant de soumission invalide",
+		"User not found":          "Utilisateur introuvable",
+		"Invalid user ID":         "Identifiant d'utilisateur invalide",
+		"Organization not found":  "Organisation introuvable",
+		"Invalid organization ID": "Identifiant d'organisation invalide",
+		"Member not found":        "Membre introuvable",
+		"Analysis not available":  "Analyse indisponible",
+		"Thread not found":        "Conversation introuvable",
+		"Import not found":        "Importation introuvable",
+		"Transcript not found":    "Transcription introuvable",
+		"Feature flag not found":  "Indicateur de fonctionnalité introuvable",
+		"Invalid 'from' parameter: use RFC3339 or YYYY-MM-DD": "Paramètre 'from' invalide : utilisez RFC3339 ou AAAA-MM-JJ",
+		"Invalid 'to' parameter: use RFC3339 or YYYY-MM-DD":   "Paramètre 'to' invalide : utilisez RFC3339 ou AAAA-MM-JJ",
+		"'from' must be before 'to'":                          "'from' doit précéder 'to'",
+
+		// Limits
+		"Too much work is queued, please try again later":   "Trop de travail en attente, veuillez réessayer plus tard",
+		"Too many sign-up attempts, please try again later": "Trop de tentatives d'inscription, veuillez réessayer plus tard",
+		"Too many quick analyses, please try again later":   "Trop d'analyses rapides, veuillez réessayer plus tard",
+		"Analysis failed, please try again":                 "L'analyse a échoué, veuillez réessayer",
+		"Upload an audio or video file":                     "Envoyez un fichier audio ou vidéo",
+		"Expected a multipart/form-data upload":             "Un envoi multipart/form-data est attendu",
+		"Imports must be at most %d MB":                     "Les importations ne doivent pas dépasser %d Mo",
+		"Uploads must be at most %d MB":                     "Les envois ne doivent pas dépasser %d Mo",
+	},
+}
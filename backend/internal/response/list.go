@@ -0,0 +1,94 @@
+package response
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// cursorPrefix versions the cursor format so it can change later
+const cursorPrefix = "o1:"
+
+// ErrInvalidCursor is returned for cursors this server didn't issue
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListResponse is the envelope of every collection endpoint
+type ListResponse[T any] struct {
+	Data       []T               `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+	Filters    map[string]string `json:"filters"`
+}
+
+// Pagination describes where a page sits in the full collection.
+// NextCursor is null on the last page.
+type Pagination struct {
+	Total      int     `json:"total"`
+	Limit      int     `json:"limit"`
+	Offset     int     `json:"offset"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// NewList wraps one page of a collection of total items that started at
+// offset and was at most limit long
+func NewList[T any](items []T, total, limit, offset int) ListResponse[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	list := ListResponse[T]{
+		Data: items,
+		Pagination: Pagination{
+			Total:  total,
+			Limit:  limit,
+			Offset: offset,
+		},
+		Filters: map[string]string{},
+	}
+
+	if next := offset + len(items); len(items) > 0 && next < total {
+		cursor := EncodeCursor(next)
+		list.Pagination.NextCursor = &cursor
+	}
+
+	return list
+}
+
+// Complete wraps a collection that is always returned in full
+func Complete[T any](items []T) ListResponse[T] {
+	return NewList(items, len(items), len(items), 0)
+}
+
+// WithFilter records a filter that was applied, skipping empty values, and
+// returns the list
+func (l ListResponse[T]) WithFilter(name, value string) ListResponse[T] {
+	if value != "" {
+		l.Filters[name] = value
+	}
+	return l
+}
+
+// EncodeCursor returns an opaque cursor for the page starting at offset
+func EncodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+// DecodeCursor returns the offset a cursor from EncodeCursor points at
+func DecodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	rest, ok := strings.CutPrefix(string(raw), cursorPrefix)
+	if !ok {
+		return 0, ErrInvalidCursor
+	}
+
+	offset, err := strconv.Atoi(rest)
+	if err != nil || offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+
+	return offset, nil
+}
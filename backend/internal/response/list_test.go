@@ -0,0 +1,64 @@
+package response
+
+import (
+	"testing"
+)
+
+func TestNewList(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      []int
+		total      int
+		limit      int
+		offset     int
+		wantCursor int // offset the next cursor points at, -1 for none
+	}{
+		{"first page", []int{1, 2}, 5, 2, 0, 2},
+		{"middle page", []int{3, 4}, 5, 2, 2, 4},
+		{"last page", []int{5}, 5, 2, 4, -1},
+		{"past the end", nil, 5, 2, 10, -1},
+		{"empty collection", nil, 0, 20, 0, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list := NewList(tt.items, tt.total, tt.limit, tt.offset)
+
+			if list.Data == nil {
+				t.Error("NewList() data = nil, want an empty slice")
+			}
+			if list.Pagination.Total != tt.total || list.Pagination.Offset != tt.offset {
+				t.Errorf("NewList() pagination = %+v", list.Pagination)
+			}
+
+			if tt.wantCursor < 0 {
+				if list.Pagination.NextCursor != nil {
+					t.Errorf("NewList() next_cursor = %q, want none", *list.Pagination.NextCursor)
+				}
+				return
+			}
+			if list.Pagination.NextCursor == nil {
+				t.Fatal("NewList() next_cursor = nil, want a cursor")
+			}
+			if got, err := DecodeCursor(*list.Pagination.NextCursor); err != nil || got != tt.wantCursor {
+				t.Errorf("DecodeCursor(next_cursor) = %d, %v, want %d", got, err, tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestListResponse_WithFilter(t *testing.T) {
+	list := Complete([]string{"a"}).WithFilter("keyword", "billing").WithFilter("status", "")
+
+	if len(list.Filters) != 1 || list.Filters["keyword"] != "billing" {
+		t.Errorf("Filters = %v, want only keyword=billing", list.Filters)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, cursor := range []string{"", "not base64!", "MTA", EncodeCursor(3) + "x"} {
+		if _, err := DecodeCursor(cursor); err == nil {
+			t.Errorf("DecodeCursor(%q) error = nil, want ErrInvalidCursor", cursor)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package response
+
+import (
+	"net/http"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// locales are the languages error messages are written in. English, the
+// first, is what every message is written in to begin with, and the
+// fallback.
+var locales = []language.Tag{language.English, language.Spanish, language.French}
+
+var matcher = language.NewMatcher(locales)
+
+// MatchLocale picks the locale that best fits an Accept-Language header,
+// falling back to English
+func MatchLocale(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return language.English
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return language.English
+	}
+	return locales[index]
+}
+
+// Locale is the locale the response is written in: the Content-Language
+// the Locale middleware negotiated, or English
+func Locale(w http.ResponseWriter) language.Tag {
+	header := w.Header().Get("Content-Language")
+	for _, tag := range locales {
+		if tag.String() == header {
+			return tag
+		}
+	}
+	return language.English
+}
+
+// Translate returns message in the response's locale, or unchanged if the
+// catalog has no translation for it
+func Translate(w http.ResponseWriter, msg string) string {
+	if translated, ok := catalog[Locale(w)][msg]; ok {
+		return translated
+	}
+	return msg
+}
+
+// Sprintf formats a message in the response's locale: the format is
+// translated, and numbers are written the locale's way (5,000 in English,
+// 5.000 in Spanish)
+func Sprintf(w http.ResponseWriter, format string, args ...interface{}) string {
+	locale := Locale(w)
+	if translated, ok := catalog[locale][format]; ok {
+		format = translated
+	}
+	return message.NewPrinter(locale).Sprintf(format, args...)
+}
@@ -0,0 +1,118 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+func TestMatchLocale(t *testing.T) {
+	tests := []struct {
+		header string
+		want   language.Tag
+	}{
+		{"", language.English},
+		{"es", language.Spanish},
+		{"es-MX,es;q=0.9", language.Spanish},
+		{"fr-CA", language.French},
+		{"de-DE,fr;q=0.5", language.French},
+		{"de-DE", language.English},
+		{"en-GB,es;q=0.8", language.English},
+		{"!!invalid", language.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := MatchLocale(tt.header); got != tt.want {
+				t.Errorf("MatchLocale(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestError_Translates(t *testing.T) {
+	tests := []struct {
+		locale string
+		send   func(w http.ResponseWriter)
+		want   string
+	}{
+		{"", func(w http.ResponseWriter) { NotFound(w, "") }, "Not found"},
+		{"es", func(w http.ResponseWriter) { NotFound(w, "") }, "No encontrado"},
+		{"fr", func(w http.ResponseWriter) { BadRequest(w, "Invalid request body") }, "Corps de requête invalide"},
+		{"es", func(w http.ResponseWriter) { BadRequest(w, "Something only in English") }, "Something only in English"},
+		{"de", func(w http.ResponseWriter) { NotFound(w, "") }, "Not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale+" "+tt.want, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Language", tt.locale)
+			tt.send(rec)
+
+			var body map[string]string
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body["error"] != tt.want {
+				t.Errorf("error = %q, want %q", body["error"], tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationError_Translates(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Language", "fr")
+	ValidationError(rec, map[string]string{"from": "'from' must be before 'to'"})
+
+	var body struct {
+		Error  string            `json:"error"`
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error != "Échec de la validation" || body.Fields["from"] != "'from' doit précéder 'to'" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestSprintf(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"en", "Imports must be at most 5,000 MB"},
+		{"es", "Las importaciones deben ocupar como máximo 5.000 MB"},
+		{"fr", "Les importations ne doivent pas dépasser 5 000 Mo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Language", tt.locale)
+			if got := Sprintf(rec, "Imports must be at most %d MB", 5000); got != tt.want {
+				t.Errorf("Sprintf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalog_Complete(t *testing.T) {
+	for _, locale := range locales[1:] {
+		for _, other := range locales[1:] {
+			for key, translated := range catalog[other] {
+				if _, ok := catalog[locale][key]; !ok {
+					t.Errorf("%v has no translation of %q", locale, key)
+				}
+				if strings.Count(key, "%") != strings.Count(translated, "%") {
+					t.Errorf("%v translation of %q has different verbs: %q", other, key, translated)
+				}
+			}
+		}
+	}
+}
@@ -33,10 +33,11 @@ func NoContent(w http.ResponseWriter) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// Error sends an error response
+// Error sends an error response, with the message translated into the
+// response's locale
 func Error(w http.ResponseWriter, statusCode int, message string) {
 	JSON(w, statusCode, map[string]interface{}{
-		"error": message,
+		"error": Translate(w, message),
 	})
 }
 
@@ -69,6 +70,30 @@ func NotFound(w http.ResponseWriter, message string) {
 	Error(w, http.StatusNotFound, message)
 }
 
+// Conflict sends a 409 Conflict response
+func Conflict(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = "Conflict"
+	}
+	Error(w, http.StatusConflict, message)
+}
+
+// PreconditionRequired sends a 428 Precondition Required response
+func PreconditionRequired(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = "Precondition required"
+	}
+	Error(w, http.StatusPreconditionRequired, message)
+}
+
+// TooManyRequests sends a 429 Too Many Requests response
+func TooManyRequests(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = "Too many requests"
+	}
+	Error(w, http.StatusTooManyRequests, message)
+}
+
 // InternalServerError sends a 500 Internal Server Error response
 func InternalServerError(w http.ResponseWriter, message string) {
 	if message == "" {
@@ -77,10 +102,23 @@ func InternalServerError(w http.ResponseWriter, message string) {
 	Error(w, http.StatusInternalServerError, message)
 }
 
-// ValidationError sends a 422 Unprocessable Entity response
+// ServiceUnavailable sends a 503 Service Unavailable response
+func ServiceUnavailable(w http.ResponseWriter, message string) {
+	if message == "" {
+		message = "Service unavailable"
+	}
+	Error(w, http.StatusServiceUnavailable, message)
+}
+
+// ValidationError sends a 422 Unprocessable Entity response, with the
+// messages translated into the response's locale
 func ValidationError(w http.ResponseWriter, errors map[string]string) {
+	fields := make(map[string]string, len(errors))
+	for field, message := range errors {
+		fields[field] = Translate(w, message)
+	}
 	JSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
-		"error":  "Validation failed",
-		"fields": errors,
+		"error":  Translate(w, "Validation failed"),
+		"fields": fields,
 	})
 }
@@ -0,0 +1,313 @@
+// Package scim implements the parts of the SCIM 2.0 protocol (RFC 7643
+// and RFC 7644) identity providers such as Okta and Azure AD use to
+// provision users and groups: resources, list responses, errors, simple
+// filters and PATCH operations.
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// Schema URNs
+const (
+	SchemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SchemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SchemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SchemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SchemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	SchemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+)
+
+// ContentType is the media type of SCIM requests and responses
+const ContentType = "application/scim+json"
+
+// MaxPageSize bounds the resources returned in one list response
+const MaxPageSize = 100
+
+// scimType values of errors
+const (
+	ErrTypeInvalidFilter = "invalidFilter"
+	ErrTypeInvalidSyntax = "invalidSyntax"
+	ErrTypeInvalidValue  = "invalidValue"
+	ErrTypeInvalidPath   = "invalidPath"
+	ErrTypeMutability    = "mutability"
+	ErrTypeUniqueness    = "uniqueness"
+	ErrTypeNoTarget      = "noTarget"
+)
+
+// Meta describes a resource
+type Meta struct {
+	ResourceType string         `json:"resourceType"`
+	Created      timestamp.Time `json:"created"`
+	LastModified timestamp.Time `json:"lastModified"`
+	Location     string         `json:"location,omitempty"`
+}
+
+// Name is a user's name. Only Formatted is kept, as the display name.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// Display returns the name to show for the user
+func (n *Name) Display() string {
+	if n == nil {
+		return ""
+	}
+	if n.Formatted != "" {
+		return n.Formatted
+	}
+	return strings.TrimSpace(n.GivenName + " " + n.FamilyName)
+}
+
+// Email is one of a user's email addresses
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// Member references a user in a group, or a group a user is in
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+// User is the SCIM user resource
+type User struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	// Active is a pointer so a request that leaves it out can be told
+	// from one that turns it off
+	Active *bool    `json:"active,omitempty"`
+	Groups []Member `json:"groups,omitempty"`
+	Meta   *Meta    `json:"meta,omitempty"`
+}
+
+// Email returns the user's email: the primary address, the first one,
+// or userName
+func (u *User) Email() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return u.UserName
+}
+
+// Group is the SCIM group resource
+type Group struct {
+	Schemas     []string `json:"schemas"`
+	ID          string   `json:"id,omitempty"`
+	ExternalID  string   `json:"externalId,omitempty"`
+	DisplayName string   `json:"displayName"`
+	Members     []Member `json:"members"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+// ListResponse is a page of resources
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// NewListResponse returns a page of resources starting at the 1-based
+// startIndex
+func NewListResponse(resources interface{}, count, total, startIndex int) ListResponse {
+	return ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: count,
+		Resources:    resources,
+	}
+}
+
+// Error is a SCIM error response
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// PatchRequest modifies a resource
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// PatchOperation is one change of a PATCH request. Op is add, remove or
+// replace; identity providers vary in its case.
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// Patch operations
+const (
+	OpAdd     = "add"
+	OpRemove  = "remove"
+	OpReplace = "replace"
+)
+
+// Operation returns the operation in lower case, or an error if it isn't
+// one of add, remove and replace
+func (o PatchOperation) Operation() (string, error) {
+	op := strings.ToLower(o.Op)
+	switch op {
+	case OpAdd, OpRemove, OpReplace:
+		return op, nil
+	}
+	return "", fmt.Errorf("unsupported patch operation %q", o.Op)
+}
+
+// WriteJSON writes a SCIM response
+func WriteJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// WriteError writes a SCIM error; scimType may be empty
+func WriteError(w http.ResponseWriter, status int, scimType, detail string) {
+	WriteJSON(w, status, Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// ErrInvalidFilter is returned for filters other than `attribute eq
+// "value"`
+var ErrInvalidFilter = errors.New(`only filters of the form attribute eq "value" are supported`)
+
+// ParseFilter parses the one kind of filter identity providers send to
+// look resources up, `attribute eq "value"`. The attribute is returned in
+// lower case. An empty filter returns empty strings.
+func ParseFilter(filter string) (attribute, value string, err error) {
+	filter = strings.TrimSpace(filter)
+	if filter == "" {
+		return "", "", nil
+	}
+
+	fields := strings.SplitN(filter, " ", 3)
+	if len(fields) != 3 || !strings.EqualFold(fields[1], "eq") {
+		return "", "", ErrInvalidFilter
+	}
+	value, err = strconv.Unquote(strings.TrimSpace(fields[2]))
+	if err != nil {
+		return "", "", ErrInvalidFilter
+	}
+	return strings.ToLower(fields[0]), value, nil
+}
+
+// ParsePage reads startIndex and count from a list request. startIndex
+// is 1-based and count is capped at MaxPageSize; invalid values fall back
+// to the defaults, as RFC 7644 asks.
+func ParsePage(r *http.Request) (startIndex, count int) {
+	startIndex, err := strconv.Atoi(r.URL.Query().Get("startIndex"))
+	if err != nil || startIndex < 1 {
+		startIndex = 1
+	}
+	count, err = strconv.Atoi(r.URL.Query().Get("count"))
+	if err != nil || count > MaxPageSize {
+		count = MaxPageSize
+	}
+	return startIndex, max(count, 0)
+}
+
+// ParseBool reads a boolean PATCH value. Azure AD sends them as the
+// strings "True" and "False".
+func ParseBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, fmt.Errorf("%s is not a boolean", raw)
+}
+
+// ParseString reads a string PATCH value; null reads as ""
+func ParseString(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("%s is not a string", raw)
+	}
+	return s, nil
+}
+
+// ParseMembers reads the members of a PATCH value, given as a list or a
+// single member
+func ParseMembers(raw json.RawMessage) ([]Member, error) {
+	var members []Member
+	if err := json.Unmarshal(raw, &members); err == nil {
+		return members, nil
+	}
+	var member Member
+	if err := json.Unmarshal(raw, &member); err != nil || member.Value == "" {
+		return nil, fmt.Errorf("%s is not a list of members", raw)
+	}
+	return []Member{member}, nil
+}
+
+// MemberPath reads a PATCH path of the form members[value eq "id"], as
+// sent to remove one member, and returns the member's ID
+func MemberPath(path string) (string, bool) {
+	if !strings.HasPrefix(strings.ToLower(path), "members[") || !strings.HasSuffix(path, "]") {
+		return "", false
+	}
+	attribute, value, err := ParseFilter(path[len("members[") : len(path)-1])
+	if err != nil || attribute != "value" {
+		return "", false
+	}
+	return value, true
+}
+
+// ServiceProviderConfig describes what the server supports
+func ServiceProviderConfig() map[string]interface{} {
+	supported := func(ok bool) map[string]bool { return map[string]bool{"supported": ok} }
+	return map[string]interface{}{
+		"schemas":        []string{SchemaServiceProviderConfig},
+		"patch":          supported(true),
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": MaxPageSize},
+		"changePassword": supported(false),
+		"sort":           supported(false),
+		"etag":           supported(false),
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "The organization's SCIM token",
+		}},
+	}
+}
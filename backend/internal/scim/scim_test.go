@@ -0,0 +1,139 @@
+package scim
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		filter        string
+		wantAttribute string
+		wantValue     string
+		wantErr       bool
+	}{
+		{filter: ""},
+		{filter: `userName eq "ada@example.com"`, wantAttribute: "username", wantValue: "ada@example.com"},
+		{filter: `displayName EQ "Sales Team"`, wantAttribute: "displayname", wantValue: "Sales Team"},
+		{filter: `userName sw "ada"`, wantErr: true},
+		{filter: `userName eq ada`, wantErr: true},
+		{filter: `userName eq "a" and active eq true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			attribute, value, err := ParseFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if attribute != tt.wantAttribute || value != tt.wantValue {
+				t.Errorf("ParseFilter() = %q, %q, want %q, %q", attribute, value, tt.wantAttribute, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantStart int
+		wantCount int
+	}{
+		{query: "", wantStart: 1, wantCount: MaxPageSize},
+		{query: "?startIndex=11&count=10", wantStart: 11, wantCount: 10},
+		{query: "?startIndex=0&count=-1", wantStart: 1, wantCount: 0},
+		{query: "?count=1000", wantStart: 1, wantCount: MaxPageSize},
+	}
+
+	for _, tt := range tests {
+		start, count := ParsePage(httptest.NewRequest("GET", "/Users"+tt.query, nil))
+		if start != tt.wantStart || count != tt.wantCount {
+			t.Errorf("ParsePage(%q) = %d, %d, want %d, %d", tt.query, start, count, tt.wantStart, tt.wantCount)
+		}
+	}
+}
+
+func TestParseBool(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    bool
+		wantErr bool
+	}{
+		{raw: `false`, want: false},
+		{raw: `true`, want: true},
+		{raw: `"False"`, want: false},
+		{raw: `"True"`, want: true},
+		{raw: `"nope"`, wantErr: true},
+		{raw: `1`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseBool(json.RawMessage(tt.raw))
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseBool(%s) = %v, %v, want %v, error %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseMembers(t *testing.T) {
+	members, err := ParseMembers(json.RawMessage(`[{"value": "a"}, {"value": "b", "display": "B"}]`))
+	if err != nil || len(members) != 2 || members[1].Value != "b" {
+		t.Errorf("ParseMembers(list) = %+v, %v", members, err)
+	}
+
+	members, err = ParseMembers(json.RawMessage(`{"value": "a"}`))
+	if err != nil || len(members) != 1 || members[0].Value != "a" {
+		t.Errorf("ParseMembers(single) = %+v, %v", members, err)
+	}
+
+	if _, err := ParseMembers(json.RawMessage(`"a"`)); err == nil {
+		t.Error("ParseMembers(string) succeeded, want an error")
+	}
+}
+
+func TestMemberPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		wantID string
+		wantOK bool
+	}{
+		{path: `members[value eq "2819c223"]`, wantID: "2819c223", wantOK: true},
+		{path: `members`, wantOK: false},
+		{path: `members[display eq "Ada"]`, wantOK: false},
+		{path: `emails[type eq "work"]`, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		id, ok := MemberPath(tt.path)
+		if id != tt.wantID || ok != tt.wantOK {
+			t.Errorf("MemberPath(%q) = %q, %v, want %q, %v", tt.path, id, ok, tt.wantID, tt.wantOK)
+		}
+	}
+}
+
+func TestUser_Email(t *testing.T) {
+	user := User{UserName: "ada", Emails: []Email{{Value: "work@example.com"}, {Value: "home@example.com", Primary: true}}}
+	if got := user.Email(); got != "home@example.com" {
+		t.Errorf("Email() = %q, want the primary address", got)
+	}
+	if got := (&User{UserName: "ada@example.com"}).Email(); got != "ada@example.com" {
+		t.Errorf("Email() without emails = %q, want userName", got)
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, 409, ErrTypeUniqueness, "User already exists")
+
+	if rec.Code != 409 || rec.Header().Get("Content-Type") != ContentType {
+		t.Fatalf("WriteError() = %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	var body Error
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "409" || body.ScimType != ErrTypeUniqueness || body.Schemas[0] != SchemaError {
+		t.Errorf("WriteError() body = %+v", body)
+	}
+}
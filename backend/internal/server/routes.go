@@ -0,0 +1,158 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	custommw "github.com/sfumato00/content-analyzer/internal/middleware"
+)
+
+const (
+	// defaultRequestTimeout bounds how long a handler may run
+	defaultRequestTimeout = 30 * time.Second
+
+	// compressionLevel is the gzip level of compressed responses
+	compressionLevel = 5
+
+	// compressMinBytes is the smallest response worth compressing, about
+	// a packet; smaller ones cost more to compress than they save
+	compressMinBytes = 1024
+
+	// minUploadRate is the slowest client, in bytes per second, that an
+	// Upload group gives time to send its largest body
+	minUploadRate = 32 << 10
+)
+
+// RouteGroup builds a group of routes with the middleware that depends on
+// what the routes serve: a request timeout and response compression, both
+// on by default. Routes that stream or move large files opt out, since a
+// timeout cuts them off and compression holds back their writes.
+//
+//	NewRouteGroup().Streaming().Group(r, func(r chi.Router) {
+//		r.Get("/events", h.Events)
+//	})
+type RouteGroup struct {
+	timeout    time.Duration
+	compress   bool
+	streaming  bool
+	upload     bool
+	middleware []func(http.Handler) http.Handler
+}
+
+// NewRouteGroup starts a group with the default timeout and compression
+func NewRouteGroup() *RouteGroup {
+	return &RouteGroup{timeout: defaultRequestTimeout, compress: true}
+}
+
+// WithTimeout replaces the request timeout; zero removes it
+func (g *RouteGroup) WithTimeout(d time.Duration) *RouteGroup {
+	g.timeout = d
+	return g
+}
+
+// WithoutCompression sends responses uncompressed
+func (g *RouteGroup) WithoutCompression() *RouteGroup {
+	g.compress = false
+	return g
+}
+
+// Streaming removes the timeout and compression and lifts the server's
+// write timeout, for responses that are written over a long time, such as
+// event streams and large downloads
+func (g *RouteGroup) Streaming() *RouteGroup {
+	g.timeout, g.compress, g.streaming = 0, false, true
+	return g
+}
+
+// Upload gives requests with bodies of up to maxBytes, such as file
+// uploads, time to arrive: the request timeout grows with maxBytes at
+// minUploadRate, and the server's read and write timeouts are pushed back
+// to match
+func (g *RouteGroup) Upload(maxBytes int64) *RouteGroup {
+	g.timeout = defaultRequestTimeout + time.Duration(maxBytes/minUploadRate)*time.Second
+	g.upload = true
+	return g
+}
+
+// Use adds middleware that runs after the group's own
+func (g *RouteGroup) Use(middlewares ...func(http.Handler) http.Handler) *RouteGroup {
+	g.middleware = append(g.middleware, middlewares...)
+	return g
+}
+
+// Middlewares returns the group's middleware in the order it runs.
+// Streaming and Upload come first so the connection's deadlines are moved
+// before anything is read or written, the timeout next so it covers the
+// whole handler, and compression last so it wraps only the handler's own
+// writes.
+func (g *RouteGroup) Middlewares() chi.Middlewares {
+	var mws chi.Middlewares
+	if g.streaming {
+		mws = append(mws, liftWriteDeadline)
+	}
+	if g.upload {
+		mws = append(mws, extendDeadlines(g.timeout))
+	}
+	if g.timeout > 0 {
+		mws = append(mws, middleware.Timeout(g.timeout))
+	}
+	if g.compress {
+		mws = append(mws, custommw.Compress(compressionLevel, compressMinBytes))
+	}
+	return append(mws, g.middleware...)
+}
+
+// Route mounts the group's routes on r under pattern
+func (g *RouteGroup) Route(r chi.Router, pattern string, fn func(r chi.Router)) {
+	r.Route(pattern, func(r chi.Router) {
+		r.Use(g.Middlewares()...)
+		fn(r)
+	})
+}
+
+// Group adds the group's routes to r without a prefix
+func (g *RouteGroup) Group(r chi.Router, fn func(r chi.Router)) {
+	r.Group(func(r chi.Router) {
+		r.Use(g.Middlewares()...)
+		fn(r)
+	})
+}
+
+// Handler wraps a single handler in the group's middleware
+func (g *RouteGroup) Handler(h http.Handler) http.Handler {
+	return chi.Chain(g.Middlewares()...).Handler(h)
+}
+
+// liftWriteDeadline clears the server's write timeout for the request,
+// which would otherwise end long responses partway through
+func liftWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			slog.Debug("Failed to lift write deadline", "path", r.URL.Path, "error", err)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// extendDeadlines pushes the server's read and write timeouts for the
+// request back to d from now, which would otherwise end slow uploads
+// partway through the body
+func extendDeadlines(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline := time.Now().Add(d)
+			rc := http.NewResponseController(w)
+			if err := rc.SetReadDeadline(deadline); err != nil {
+				slog.Debug("Failed to extend read deadline", "path", r.URL.Path, "error", err)
+			}
+			if err := rc.SetWriteDeadline(deadline); err != nil {
+				slog.Debug("Failed to extend write deadline", "path", r.URL.Path, "error", err)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestRouteGroup(t *testing.T) {
+	large := strings.Repeat("analysis ", 500)
+
+	var deadline bool
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		_, deadline = r.Context().Deadline()
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, large)
+	}
+
+	r := chi.NewRouter()
+	NewRouteGroup().Route(r, "/default", func(r chi.Router) {
+		r.Get("/", handler)
+	})
+	NewRouteGroup().Streaming().Route(r, "/stream", func(r chi.Router) {
+		r.Get("/", handler)
+	})
+	NewRouteGroup().WithoutCompression().WithTimeout(0).Group(r, func(r chi.Router) {
+		r.Get("/plain", handler)
+	})
+
+	tests := []struct {
+		path         string
+		wantGzip     bool
+		wantDeadline bool
+	}{
+		{"/default/", true, true},
+		{"/stream/", false, false},
+		{"/plain", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding") == "gzip"; got != tt.wantGzip {
+				t.Errorf("compressed = %v, want %v", got, tt.wantGzip)
+			}
+			if deadline != tt.wantDeadline {
+				t.Errorf("deadline = %v, want %v", deadline, tt.wantDeadline)
+			}
+		})
+	}
+}
+
+func TestRouteGroup_Timeout(t *testing.T) {
+	handler := NewRouteGroup().WithTimeout(10 * time.Millisecond).Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		if r.Context().Err() != context.DeadlineExceeded {
+			t.Errorf("context error = %v, want the deadline", r.Context().Err())
+		}
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if rec.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusGatewayTimeout)
+	}
+}
+
+func TestRouteGroup_Upload(t *testing.T) {
+	upload := func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, strconv.Itoa(len(body)))
+	}
+
+	r := chi.NewRouter()
+	NewRouteGroup().Group(r, func(r chi.Router) {
+		r.Post("/default", upload)
+	})
+	NewRouteGroup().Upload(1<<20).Group(r, func(r chi.Router) {
+		r.Post("/upload", upload)
+	})
+
+	ts := httptest.NewUnstartedServer(r)
+	ts.Config.ReadTimeout = 50 * time.Millisecond
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	// slowPost sends its body in two halves with a pause longer than the
+	// server's read timeout between them
+	slowPost := func(path string) (string, error) {
+		body, w := io.Pipe()
+		go func() {
+			w.Write([]byte(strings.Repeat("a", 512)))
+			time.Sleep(150 * time.Millisecond)
+			w.Write([]byte(strings.Repeat("b", 512)))
+			w.Close()
+		}()
+
+		resp, err := http.Post(ts.URL+path, "application/octet-stream", body)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		got, err := io.ReadAll(resp.Body)
+		return string(got), err
+	}
+
+	if got, err := slowPost("/upload"); err != nil || got != "1024" {
+		t.Errorf("slow upload = %q, %v, want the whole body read", got, err)
+	}
+	if got, err := slowPost("/default"); err == nil && got == "1024" {
+		t.Errorf("slow upload outside an Upload group = %q, want it cut off by the read timeout", got)
+	}
+}
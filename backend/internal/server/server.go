@@ -12,34 +12,85 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
 	"github.com/go-chi/httplog/v2"
 
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
 	"github.com/sfumato00/content-analyzer/internal/auth"
 	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/capture"
 	"github.com/sfumato00/content-analyzer/internal/config"
 	"github.com/sfumato00/content-analyzer/internal/database"
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+	"github.com/sfumato00/content-analyzer/internal/flags"
 	"github.com/sfumato00/content-analyzer/internal/handlers"
+	"github.com/sfumato00/content-analyzer/internal/links"
+	"github.com/sfumato00/content-analyzer/internal/logging"
+	"github.com/sfumato00/content-analyzer/internal/logins"
+	"github.com/sfumato00/content-analyzer/internal/metrics"
 	custommw "github.com/sfumato00/content-analyzer/internal/middleware"
 	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/outbound"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/services/limits"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+	"github.com/sfumato00/content-analyzer/internal/sso"
+	"github.com/sfumato00/content-analyzer/internal/storage"
 )
 
 // Server represents the HTTP server
 type Server struct {
 	config     *config.Config
+	watcher    *config.Watcher
 	router     *chi.Mux
 	httpServer *http.Server
-	db         *database.Database
-	cache      *cache.Cache
+	// redirectServer sends plain HTTP to HTTPS; nil unless TLS_REDIRECT_ADDR is set
+	redirectServer *http.Server
+	db             *database.Database
+	cache          *cache.Cache
+	notifier       *notifications.Notifier
+	// encryptor encrypts submission content at rest; nil when disabled
+	encryptor *encryption.Encryptor
+	// objects keeps uploads in object storage; nil keeps them in Postgres
+	objects storage.Storage
+	// outbound makes requests to addresses users give; nil leaves them to
+	// unrestricted clients
+	outbound *outbound.Client
+	// budget is the model provider's rate limit, shared with the worker;
+	// nil tracks the server's own calls only
+	budget *ai.Budget
+	// spend refuses analyses over a spend budget in reject mode, shared
+	// with the worker; nil refuses none
+	spend *spend.Guard
+	// reporter is told about panics and server errors
+	reporter errreport.Reporter
 }
 
-// New creates a new server instance
-func New(cfg *config.Config, db *database.Database, cache *cache.Cache) *Server {
+// New creates a new server instance. Settings the watcher reloads are
+// applied while serving; the rest are read once from its current config.
+func New(watcher *config.Watcher, db *database.Database, cache *cache.Cache, notifier *notifications.Notifier, encryptor *encryption.Encryptor, objects storage.Storage, outboundClient *outbound.Client, budget *ai.Budget, spendGuard *spend.Guard, reporter errreport.Reporter) *Server {
+	if reporter == nil {
+		reporter = errreport.Nop{}
+	}
+	cfg := watcher.Current()
 	s := &Server{
-		config: cfg,
-		router: chi.NewRouter(),
-		db:     db,
-		cache:  cache,
+		config:    cfg,
+		watcher:   watcher,
+		router:    chi.NewRouter(),
+		db:        db,
+		cache:     cache,
+		notifier:  notifier,
+		encryptor: encryptor,
+		objects:   objects,
+		outbound:  outboundClient,
+		budget:    budget,
+		spend:     spendGuard,
+		reporter:  reporter,
 	}
 
 	s.setupMiddleware()
@@ -52,6 +103,7 @@ func New(cfg *config.Config, db *database.Database, cache *cache.Cache) *Server
 		WriteTimeout: 15 * time.Second,
 		IdleTimeout:  60 * time.Second,
 	}
+	s.setupTLS()
 
 	return s
 }
@@ -59,9 +111,11 @@ func New(cfg *config.Config, db *database.Database, cache *cache.Cache) *Server
 // setupMiddleware configures all middleware
 func (s *Server) setupMiddleware() {
 	// Logger middleware
+	// The level is left open here; logging.Level and sampling are applied
+	// by the wrapping handler so both can be tuned without a restart
 	logger := httplog.NewLogger("content-analyzer", httplog.Options{
-		JSON:             s.config.IsProduction(),
-		LogLevel:         slog.LevelInfo,
+		JSON:             s.config.LogFormat == logging.FormatJSON,
+		LogLevel:         slog.LevelDebug,
 		Concise:          true,
 		RequestHeaders:   true,
 		MessageFieldName: "message",
@@ -70,10 +124,10 @@ func (s *Server) setupMiddleware() {
 		},
 	})
 
-	s.router.Use(httplog.RequestLogger(logger))
+	sampler := logging.NewSampler(logger.Logger.Handler(), s.config.LogSampleRate)
+	logger.Logger = slog.New(sampler)
 
-	// Recoverer - recover from panics
-	s.router.Use(middleware.Recoverer)
+	s.router.Use(httplog.RequestLogger(logger))
 
 	// Request ID
 	s.router.Use(middleware.RequestID)
@@ -81,93 +135,421 @@ func (s *Server) setupMiddleware() {
 	// Real IP
 	s.router.Use(middleware.RealIP)
 
-	// Timeout
-	s.router.Use(middleware.Timeout(30 * time.Second))
+	// Error messages are written in the client's language
+	s.router.Use(custommw.Locale)
 
-	// Compress responses
-	s.router.Use(middleware.Compress(5))
+	// Recover from panics, reporting them and 5xx responses with the
+	// request ID set above
+	s.router.Use(custommw.Recover(s.reporter))
+
+	// Request timeouts and compression depend on what a route serves, so
+	// they are applied per route group; see RouteGroup
 
 	// Security headers
 	s.router.Use(custommw.SecurityHeaders)
 
-	// CORS
-	s.router.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   s.config.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	}))
+	// CORS, also open to organizations' own subdomains when they have them
+	origins := custommw.NewOriginList(s.config.AllowedOrigins)
+	corsOptions := custommw.CORSOptions{
+		Origins:          origins,
+		AllowAll:         s.config.CORSAllowAll,
+		AllowCredentials: s.config.CORSAllowCredentials,
+		ExposedHeaders:   s.config.CORSExposedHeaders,
+	}
+	var orgDomains *auth.OrgDomains
+	if s.config.OrgBaseDomain != "" {
+		orgDomains = auth.NewOrgDomains(s.config.OrgBaseDomain, models.NewOrganizationStore(s.db.Pool))
+		corsOptions.AllowOrigin = orgDomains.AllowOrigin
+	}
+	s.router.Use(custommw.CORS(corsOptions))
 
 	// Heartbeat endpoint (doesn't log)
 	s.router.Use(middleware.Heartbeat("/ping"))
+
+	// Requests to an organization's subdomain are scoped to it
+	if orgDomains != nil {
+		s.router.Use(orgDomains.Middleware)
+	}
+
+	s.watcher.OnReload(func(cfg *config.Config) {
+		sampler.SetRate(cfg.LogSampleRate)
+		origins.Set(cfg.AllowedOrigins)
+	})
+}
+
+// apiHandlers are shared by every API version; routes whose behaviour
+// differs between versions check apiversion.FromContext
+type apiHandlers struct {
+	jwtManager *auth.JWTManager
+	sessions   *auth.Sessions
+	auth       *handlers.AuthHandler
+	account    *handlers.AccountHandler
+	submission *handlers.SubmissionHandler
+	assignees  *handlers.AssignmentHandler
+	trash      *handlers.TrashHandler
+	analytics  *handlers.AnalyticsHandler
+	jobs       *handlers.JobsHandler
+	flags      *handlers.FeatureFlagHandler
+	invites    *handlers.InviteHandler
+	orgs       *handlers.OrgHandler
+	retention  *handlers.RetentionHandler
+	moderation *handlers.ModerationHandler
+	quarantine *handlers.QuarantineHandler
+	artifacts  *handlers.ArtifactsHandler
+	profiles   *handlers.ProfileHandler
+	threads    *handlers.ThreadHandler
+	feeds      *handlers.FeedHandler
+	apiKeys    *handlers.APIKeyHandler
+	hooks      *handlers.HookHandler
+	spend      *handlers.SpendHandler
+	uploads    *handlers.UploadHandler
+	quick      *handlers.QuickAnalyzeHandler
+	estimate   *handlers.EstimateHandler
+	suspension *handlers.SuspensionHandler
+	// devices is nil when remember-me is off
+	devices *handlers.SessionHandler
+	// impersonation is nil when IMPERSONATION_ENABLED is off
+	impersonation *handlers.ImpersonationHandler
+	// loginGuard is nil when LOGIN_ANOMALY_DETECTION is off
+	loginGuard *handlers.LoginGuard
+	// sso is nil when SSO_ENABLED is off
+	sso *handlers.SSOHandler
+	// scim is nil when SCIM_ENABLED is off
+	scim *handlers.SCIMHandler
+	// graphql is nil when GRAPHQL_ENABLED is off
+	graphql *handlers.GraphQLHandler
+	// keys authenticates integrations that send an API key instead of a JWT
+	keys auth.APIKeyAuthenticator
+	// backpressure turns away new analyses while the job queue is saturated
+	backpressure func(http.Handler) http.Handler
+	// spendBudget turns away new analyses while the user is over a spend
+	// budget, in reject mode
+	spendBudget func(http.Handler) http.Handler
 }
 
 // setupRoutes configures all routes
 func (s *Server) setupRoutes() {
 	// Create stores
 	userStore := models.NewUserStore(s.db.Pool)
+	analyticsStore := models.NewAnalyticsStore(s.db.Pool).WithReplica(s.db)
+	topicStore := models.NewTopicStore(s.db.Pool).WithReplica(s.db).WithEncryption(s.encryptor).WithStorage(s.objects)
+	emailTokenStore := models.NewEmailTokenStore(s.db.Pool)
+	auditStore := models.NewAuditStore(s.db.Pool)
+	flagStore := models.NewFeatureFlagStore(s.db.Pool)
+	inviteStore := models.NewInviteStore(s.db.Pool)
+	orgStore := models.NewOrganizationStore(s.db.Pool)
+	domainStore := models.NewOrgDomainStore(s.db.Pool)
+	usageStore := models.NewUsageStore(s.db.Pool).WithReplica(s.db)
+	profileStore := models.NewProfileStore(s.db.Pool)
+	apiKeyStore := models.NewAPIKeyStore(s.db.Pool)
+	moderationStore := models.NewModerationStore(s.db.Pool)
+
+	// Feature flags; wrap risky routes in flags.Require(featureFlags, key)
+	featureFlags := flags.New(flagStore)
+
+	// Debug capture of the configured routes and of flagged users'
+	// requests; nil unless DEBUG_CAPTURE is on
+	var captures *capture.Recorder
+	if s.config.DebugCapture {
+		captures = capture.New(s.config.DebugCaptureSize, s.config.DebugCaptureMaxBody).WithUsers(featureFlags)
+		captures.SetRoutes(s.config.DebugCaptureRoutes)
+		s.watcher.OnReload(func(cfg *config.Config) {
+			captures.SetRoutes(cfg.DebugCaptureRoutes)
+		})
+	}
 
-	// Create JWT manager
+	// Create job queue; submission status changes are published onto it
+	jobQueue := queue.New(s.cache.Client())
+	submissionStore := models.NewSubmissionStore(s.db.Pool).
+		WithReplica(s.db).
+		WithEncryption(s.encryptor).
+		WithStorage(s.objects).
+		WithListener(events.NewPublisher(jobQueue))
+
+	// Create JWT manager and session revocation. Revocation is checked on
+	// every authenticated request, so it is cached in process as well.
+	// Account statuses Redis loses are read back from the database, and
+	// revoking every session ends remembered devices there too.
 	jwtManager := auth.NewJWTManager(s.config.JWTSecret)
+	deviceStore := models.NewDeviceSessionStore(s.db.Pool)
+	sessions := auth.NewSessions(s.cache.Tiered(s.config.LocalCacheSize, s.config.LocalCacheTTL)).
+		WithUsers(userStore).
+		WithDevices(deviceStore)
+
+	// Remember-me sessions keep browsers signed in with a device cookie,
+	// which only travels over HTTPS outside development
+	var devices *handlers.SessionHandler
+	if lifetime, ok := s.config.RememberMe(); ok {
+		devices = handlers.NewSessionHandler(deviceStore, userStore, jwtManager, sessions, lifetime)
+		if s.config.IsDevelopment() {
+			devices.WithInsecureCookie()
+		}
+	}
+
+	// Sign-ins from a new device or country are emailed to the user and,
+	// unless they opted out, wait for an emailed code
+	var loginGuard *handlers.LoginGuard
+	if s.config.LoginAnomalyDetection {
+		loginGuard = handlers.NewLoginGuard(models.NewLoginFingerprintStore(s.db.Pool), logins.NewChallenges(s.cache), s.notifier).
+			WithLocator(s.config.LoginLocator())
+	}
+
+	// Organizations may sign their members in through their own identity
+	// provider, and stop them signing in with passwords
+	var ssoStore *models.SSOStore
+	if s.config.SSOEnabled {
+		ssoStore = models.NewSSOStore(s.db.Pool).WithEncryption(s.encryptor)
+	}
+
+	// Analyses are counted against monthly quotas; warnings are emailed to
+	// the user and, when configured, posted to the operator's webhook
+	quotaTracker := quota.NewTracker(s.cache, s.config.QuotaLimits()).
+		WithNotifier(quota.NewEmailNotifier(s.notifier))
+	if s.config.QuotaWebhookURL != "" {
+		quotaTracker.WithNotifier(quota.NewWebhookNotifier(jobQueue))
+	}
+
+	// Quick analyses run synchronously in the request rather than on the
+	// worker, so the server needs its own model client
+	aiClient := ai.NewClient(ai.Options{
+		APIKey:             s.config.GeminiAPIKey,
+		Model:              s.config.GeminiModel,
+		HTTPClient:         s.config.GeminiHTTPClient(),
+		Budget:             s.budget,
+		SkipResponseSchema: !s.config.GeminiResponseSchema,
+		ReplayMode:         s.config.AIReplayMode,
+		ReplayDir:          s.config.AIReplayDir,
+	})
+	s.watcher.OnReload(func(cfg *config.Config) {
+		aiClient.SetModel(cfg.GeminiModel)
+	})
+	quickAnalyzer := analyzer.NewAnalyzer(nil, aiClient).
+		WithPricing(ai.Pricing{InputPerMillion: s.config.GeminiInputPrice, OutputPerMillion: s.config.GeminiOutputPrice}).
+		WithLimits(limits.New(s.config.AnalyzerLimits())).
+		WithRepairs(s.config.AIRepairAttempts)
+
+	// Estimates walk the stages the worker's analyzer would run, so it is
+	// configured the same way; it never calls the model
+	estimator := analyzer.NewAnalyzer(nil, aiClient).
+		WithPricing(ai.Pricing{InputPerMillion: s.config.GeminiInputPrice, OutputPerMillion: s.config.GeminiOutputPrice}).
+		WithVerification(s.config.AnalysisVerification).
+		WithClaims(s.config.ClaimExtraction).
+		WithProofreading(s.config.Proofreading).
+		WithBias(s.config.BiasAnalysis).
+		WithAIDetection(s.config.AIDetection).
+		WithModeration(s.config.Moderation).
+		WithGlossaries(models.NewGlossaryStore(s.db.Pool)).
+		WithTaxonomies(models.NewTaxonomyStore(s.db.Pool)).
+		WithStageConfig(s.config.AnalyzerStages())
+
+	// Requests that queue analyses are refused with 503 while too many
+	// jobs are already waiting
+	backpressure := func(next http.Handler) http.Handler { return next }
+	if s.config.QueueMaxDepth > 0 {
+		backpressure = custommw.Backpressure(queue.NewBackpressure(jobQueue, s.config.QueueMaxDepth))
+	}
+
+	// In reject mode, requests that queue analyses are refused with 402
+	// while the user is over a spend budget; otherwise the worker holds
+	// the analyses until the budget allows them
+	spendBudget := func(next http.Handler) http.Handler { return next }
+	if s.spend != nil && s.spend.Rejects() {
+		spendBudget = custommw.SpendBudget(s.spend)
+	}
+
+	// Audio and video uploads are only accepted with a speech-to-text
+	// provider configured
+	stats := handlers.NewStatsCache(s.cache)
+	submissionHandler := handlers.NewSubmissionHandler(submissionStore, userStore, jobQueue).WithQuota(quotaTracker).WithProfiles(profileStore).
+		WithWordLimits(s.config.WordLimits()).WithLinks(links.NewBuilder(s.router)).WithStats(stats)
+	if s.config.TranscriptionProvider != "" {
+		submissionHandler.WithTranscription(models.NewTranscriptionStore(s.db.Pool).WithEncryption(s.encryptor).WithStorage(s.objects), s.config.MediaUploadMaxBytes())
+	}
+	if s.config.ImportMaxMB > 0 {
+		submissionHandler.WithImports(models.NewImportStore(s.db.Pool).WithEncryption(s.encryptor).WithStorage(s.objects), s.config.ImportMaxBytes())
+	}
 
 	// Create handlers
-	healthHandler := handlers.NewHealthHandler(s.db, s.cache)
+	healthHandler := handlers.NewHealthHandler(s.db, s.cache, s.watcher)
 	apiHandler := handlers.NewAPIHandler(s.config)
-	authHandler := handlers.NewAuthHandler(userStore, jwtManager)
+	api := &apiHandlers{
+		jwtManager: jwtManager,
+		sessions:   sessions,
+		auth: handlers.NewAuthHandler(userStore, emailTokenStore, jwtManager, s.notifier, sessions).
+			WithAbuseProtection(s.config.AbuseProtection(s.cache)).
+			WithInviteOnly(s.config.InviteOnly()).
+			WithRememberMe(devices).
+			WithLoginGuard(loginGuard),
+		account:    handlers.NewAccountHandler(userStore, emailTokenStore, jwtManager, s.notifier, sessions, auditStore),
+		submission: submissionHandler,
+		assignees:  handlers.NewAssignmentHandler(submissionStore, userStore, orgStore, s.notifier),
+		trash:      handlers.NewTrashHandler(submissionStore).WithStats(stats),
+		analytics:  handlers.NewAnalyticsHandler(analyticsStore, topicStore, s.cache).WithUsers(userStore),
+		jobs:       handlers.NewJobsHandler(jobQueue).WithBudget(aiClient.Budget()).WithVisibilityTimeout(s.config.QueueVisibilityTimeout),
+		flags:      handlers.NewFeatureFlagHandler(flagStore, featureFlags),
+		invites:    handlers.NewInviteHandler(inviteStore),
+		orgs: handlers.NewOrgHandler(orgStore, userStore, usageStore, moderationStore).
+			WithGlossaries(models.NewGlossaryStore(s.db.Pool)).
+			WithTaxonomies(models.NewTaxonomyStore(s.db.Pool)).
+			WithDomains(domainStore).
+			WithSentiment(analyticsStore),
+		retention:  handlers.NewRetentionHandler(models.NewRetentionStore(s.db.Pool)),
+		moderation: handlers.NewModerationHandler(moderationStore),
+		quarantine: handlers.NewQuarantineHandler(submissionStore, userStore, s.notifier, auditStore),
+		artifacts:  handlers.NewArtifactsHandler(submissionStore),
+		profiles:   handlers.NewProfileHandler(profileStore),
+		threads:    handlers.NewThreadHandler(models.NewThreadStore(s.db.Pool).WithEncryption(s.encryptor), submissionStore, jobQueue),
+		feeds:      handlers.NewFeedHandler(models.NewFeedStore(s.db.Pool).WithEncryption(s.encryptor), jobQueue),
+		apiKeys:    handlers.NewAPIKeyHandler(apiKeyStore, jwtManager),
+		hooks:      handlers.NewHookHandler(models.NewHookStore(s.db.Pool).WithEncryption(s.encryptor)),
+		spend:      handlers.NewSpendHandler(orgStore).WithHeldJobs(jobQueue),
+		uploads:    handlers.NewUploadHandler(models.NewUploadStore(s.db.Pool), submissionStore, s.objects, s.config.UploadMaxBytes()),
+		quick:      handlers.NewQuickAnalyzeHandler(quickAnalyzer).WithRateLimit(s.config.QuickAnalyzeLimiter(s.cache)),
+		estimate:   handlers.NewEstimateHandler(estimator, profileStore),
+		suspension: handlers.NewSuspensionHandler(userStore, models.NewAppealStore(s.db.Pool), sessions, auditStore),
+		devices:    devices,
+		keys:       apiKeyStore,
+		loginGuard: loginGuard,
+
+		backpressure: backpressure,
+		spendBudget:  spendBudget,
+	}
+	if s.outbound != nil {
+		api.quick.WithHTTPClient(s.outbound.Via(s.config.FetchProxy()).HTTPClient(10 * time.Second))
+	}
+	if s.config.ImpersonationEnabled {
+		api.impersonation = handlers.NewImpersonationHandler(userStore, jwtManager, auditStore)
+	}
+	if ssoStore != nil {
+		api.auth.WithSSOEnforcement(ssoStore)
+		api.orgs.WithSSO(ssoStore)
+		oidc := sso.NewOIDC()
+		if s.outbound != nil {
+			oidc.WithHTTPClient(s.outbound.HTTPClient(10 * time.Second))
+		}
+		api.sso = handlers.NewSSOHandler(ssoStore, oidc, sso.NewStates(s.cache), userStore, orgStore, jwtManager, auditStore, s.config.SSORedirectURI()).
+			WithDomains(domainStore)
+		if s.config.IsDevelopment() {
+			api.sso.WithInsecureCookie()
+		}
+	}
+
+	// Organizations' identity providers provision and deprovision their
+	// members over SCIM, authenticating with a per-organization token
+	var scimStore *models.SCIMStore
+	if s.config.SCIMEnabled {
+		scimStore = models.NewSCIMStore(s.db.Pool)
+		api.orgs.WithSCIM(scimStore)
+		api.scim = handlers.NewSCIMHandler(scimStore, userStore, orgStore, sessions).WithDomains(domainStore)
+		api.auth.WithSCIMInvitations(api.scim)
+		if ssoStore != nil {
+			api.scim.WithRoleMappings(ssoStore)
+		}
+	}
+
+	// Read-only GraphQL queries over the same stores as the REST API
+	if s.config.GraphQLEnabled {
+		api.graphql = handlers.NewGraphQLHandler(userStore, submissionStore)
+	}
 
 	// Root endpoint
 	s.router.Get("/", apiHandler.Index)
 
-	// Health check endpoints
-	s.router.Get("/health", healthHandler.Health)
-	s.router.Get("/ready", healthHandler.Ready)
-	s.router.Get("/live", healthHandler.Live)
+	// Health check endpoints; their responses are too small to compress
+	NewRouteGroup().WithoutCompression().Group(s.router, func(r chi.Router) {
+		r.Get("/health", healthHandler.Health)
+		r.Get("/ready", healthHandler.Ready)
+		r.Get("/live", healthHandler.Live)
+	})
 
-	// API v1 routes
-	s.router.Route("/api/v1", func(r chi.Router) {
-		r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-			http.Error(w, "API v1", http.StatusOK)
-		})
+	// Presigned URLs of the local storage driver are served by the API;
+	// the signature in the URL is their only authentication
+	if local, ok := s.objects.(*storage.Local); ok {
+		s.router.Handle(storage.LocalPathPrefix+"*", NewRouteGroup().Streaming().Handler(local))
+	}
+
+	// Prometheus scrape endpoint; keep it off the public internet
+	if s.config.MetricsEnabled {
+		s.router.Handle("/metrics", NewRouteGroup().Handler(metrics.Handler(metrics.Default)))
+	}
 
-		// Auth routes (public)
-		r.Route("/auth", func(r chi.Router) {
-			r.Post("/register", authHandler.Register)
-			r.Post("/login", authHandler.Login)
-			r.Post("/logout", authHandler.Logout)
+	// Debug endpoints (disabled in production unless DEBUG_ENDPOINTS is set)
+	// and debug captures (DEBUG_CAPTURE). They sit outside the API versions,
+	// so reading captures is never captured itself.
+	if s.config.DebugEndpoints || captures != nil {
+		NewRouteGroup().Route(s.router, "/admin", func(r chi.Router) {
+			r.Use(auth.Middleware(jwtManager, sessions))
+
+			if s.config.DebugEndpoints {
+				// Debug logging writes request headers to the logs
+				logLevelHandler := handlers.NewLogLevelHandler(logging.Level)
+				r.With(auth.RequireAdmin(s.config.AdminUserIDs)).Get("/log-level", logLevelHandler.Get)
+				r.With(auth.RequireAdmin(s.config.AdminUserIDs)).Post("/log-level", logLevelHandler.Set)
+
+				// Keys hold user IDs and client addresses
+				r.With(auth.RequireAdmin(s.config.AdminUserIDs)).Get("/cache/keys", handlers.NewCacheHandler(s.cache).TopKeys)
+			}
+
+			// Captures hold what users sent and got back
+			if captures != nil {
+				captureHandler := handlers.NewCaptureHandler(captures)
+				r.With(auth.RequireAdmin(s.config.AdminUserIDs)).Get("/captures", captureHandler.List)
+				r.With(auth.RequireAdmin(s.config.AdminUserIDs)).Delete("/captures", captureHandler.Clear)
+			}
 		})
+	}
 
-		// Submissions routes (protected)
-		r.Route("/submissions", func(r chi.Router) {
-			// Apply JWT middleware to all routes in this group
-			r.Use(auth.Middleware(jwtManager))
-
-			r.Get("/", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "TODO: List submissions", http.StatusNotImplemented)
-			})
-			r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "TODO: Create submission", http.StatusNotImplemented)
-			})
-			r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "TODO: Get submission", http.StatusNotImplemented)
-			})
-			r.Get("/{id}/analysis", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "TODO: Get analysis", http.StatusNotImplemented)
-			})
+	// SCIM server for organizations' identity providers; the token
+	// identifies the organization
+	if scimStore != nil {
+		NewRouteGroup().Route(s.router, "/scim/v2", func(r chi.Router) {
+			r.Use(auth.SCIMMiddleware(scimStore))
+
+			r.Get("/ServiceProviderConfig", api.scim.ServiceProviderConfig)
+			r.Get("/Users", api.scim.ListUsers)
+			r.Post("/Users", api.scim.CreateUser)
+			r.Get("/Users/{id}", api.scim.GetUser)
+			r.Put("/Users/{id}", api.scim.ReplaceUser)
+			r.Patch("/Users/{id}", api.scim.PatchUser)
+			r.Delete("/Users/{id}", api.scim.DeleteUser)
+			r.Get("/Groups", api.scim.ListGroups)
+			r.Post("/Groups", api.scim.CreateGroup)
+			r.Get("/Groups/{id}", api.scim.GetGroup)
+			r.Put("/Groups/{id}", api.scim.ReplaceGroup)
+			r.Patch("/Groups/{id}", api.scim.PatchGroup)
+			r.Delete("/Groups/{id}", api.scim.DeleteGroup)
 		})
+	}
 
-		// User routes (protected)
-		r.Route("/me", func(r chi.Router) {
-			// Apply JWT middleware to all routes in this group
-			r.Use(auth.Middleware(jwtManager))
+	// GraphQL endpoint, behind the same authentication as the submissions
+	// routes; it sits outside the API versions and returns v2 resources
+	if api.graphql != nil {
+		NewRouteGroup().Route(s.router, "/graphql", func(r chi.Router) {
+			r.Use(auth.ScopedMiddleware(jwtManager, sessions))
+			r.Use(auth.RequireScope(auth.ScopeSubmissionsRead))
 
-			r.Get("/", authHandler.Me)
-			r.Get("/stats", func(w http.ResponseWriter, r *http.Request) {
-				http.Error(w, "TODO: Get user stats", http.StatusNotImplemented)
-			})
+			r.Get("/", api.graphql.Serve)
+			r.Post("/", api.graphql.Serve)
 		})
-	})
+	}
+
+	// Every API version is served from the same handlers
+	for _, version := range apiversion.Versions {
+		s.router.Route(version.Prefix(), func(r chi.Router) {
+			r.Use(apiversion.Middleware(version))
+			if captures != nil {
+				r.Use(captures.Middleware(version.Prefix()))
+			}
+			// Impersonation tokens are accepted only on audited routes
+			if api.impersonation != nil {
+				r.Use(api.impersonation.Audit)
+			}
+			if version == apiversion.V1 {
+				s.deprecateV1(r)
+			}
+			s.apiRoutes(r, version, api)
+		})
+	}
 
 	// 404 handler
 	s.router.NotFound(apiHandler.NotFound)
@@ -176,6 +558,299 @@ func (s *Server) setupRoutes() {
 	s.router.MethodNotAllowed(apiHandler.MethodNotAllowed)
 }
 
+// deprecateV1 announces the retirement of v1 once API_V1_DEPRECATED_AT or
+// API_V1_SUNSET is set, pointing clients at the v2 route
+func (s *Server) deprecateV1(r chi.Router) {
+	if s.config.APIV1DeprecatedAt.IsZero() && s.config.APIV1Sunset.IsZero() {
+		return
+	}
+
+	r.Use(apiversion.Deprecate(apiversion.Deprecation{
+		Since:     s.config.APIV1DeprecatedAt,
+		Sunset:    s.config.APIV1Sunset,
+		Successor: apiversion.Upgrade(apiversion.V1, apiversion.V2),
+	}))
+}
+
+// apiRoutes mounts the routes of one API version
+func (s *Server) apiRoutes(r chi.Router, version apiversion.Version, h *apiHandlers) {
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "API "+version.String(), http.StatusOK)
+	})
+
+	// Every group times out and compresses responses by default; streaming
+	// routes belong in a NewRouteGroup().Streaming() group of their own
+	group := NewRouteGroup()
+
+	// Auth routes (public)
+	group.Route(r, "/auth", func(r chi.Router) {
+		r.Post("/register", h.auth.Register)
+		r.Post("/login", h.auth.Login)
+		r.Post("/logout", h.auth.Logout)
+		r.Post("/verify-email", h.auth.VerifyEmail)
+		r.Post("/forgot-password", h.auth.ForgotPassword)
+		r.Post("/reset-password", h.auth.ResetPassword)
+		r.Post("/confirm-email-change", h.account.ConfirmEmailChange)
+		r.With(auth.APIKeyMiddleware(h.keys, h.sessions)).Post("/token", h.apiKeys.Token)
+		if h.devices != nil {
+			r.Post("/refresh", h.devices.Refresh)
+		}
+		if h.loginGuard != nil {
+			r.Post("/confirm-login", h.auth.ConfirmLogin)
+		}
+		if h.sso != nil {
+			r.Get("/sso/{org}", h.sso.Start)
+			r.Post("/sso/callback", h.sso.Callback)
+		}
+	})
+
+	// Submissions routes (protected; open to scoped tokens)
+	r.Route("/submissions", func(r chi.Router) {
+		// Apply JWT middleware to all routes in this group
+		r.Use(auth.ScopedMiddleware(h.jwtManager, h.sessions))
+		r.Use(auth.RequireScopeByMethod(auth.ScopeSubmissionsRead, auth.ScopeSubmissionsWrite))
+
+		// Recordings take longer to arrive than the default timeout allows
+		NewRouteGroup().Upload(s.config.MediaUploadMaxBytes()).Group(r, func(r chi.Router) {
+			r.With(h.backpressure, h.spendBudget).Post("/transcriptions", h.submission.Upload)
+		})
+
+		group.Group(r, func(r chi.Router) {
+			r.Get("/", h.submission.List)
+			r.With(h.backpressure, h.spendBudget).Post("/", h.submission.Create)
+			r.Get("/transcriptions/{id}", h.submission.GetTranscription)
+			r.Get("/{id}", h.submission.Get)
+			r.Patch("/{id}", h.submission.Update)
+			r.Delete("/{id}", h.trash.Delete)
+			r.Get("/{id}/analysis", h.submission.GetAnalysis)
+			r.With(h.backpressure, h.spendBudget).Post("/{id}/analysis/retry", h.submission.RetryAnalysis)
+			r.With(h.backpressure, h.spendBudget).Post("/{id}/analysis/modules/{module}/rerun", h.submission.RerunModule)
+			r.Get("/{id}/events", h.submission.ListEvents)
+			r.Get("/{id}/transcript", h.submission.GetTranscript)
+			r.With(h.backpressure, h.spendBudget).Post("/{id}/submit", h.submission.Submit)
+			r.Post("/{id}/cancel", h.submission.Cancel)
+			r.Post("/{id}/archive", h.submission.Archive)
+			r.Get("/{id}/versions", h.submission.ListVersions)
+			r.With(h.backpressure, h.spendBudget).Post("/{id}/versions", h.submission.CreateVersion)
+			r.Get("/{id}/diff", h.submission.GetDiff)
+			r.Get("/{id}/issues", h.submission.ListIssues)
+			r.Get("/{id}/attachments", h.uploads.ListAttachments)
+			r.Get("/{id}/threads", h.threads.List)
+			r.Post("/{id}/threads", h.threads.Create)
+			r.Put("/{id}/assignee", h.assignees.Assign)
+			r.Delete("/{id}/assignee", h.assignees.Unassign)
+			r.Put("/{id}/workflow", h.assignees.SetWorkflowStatus)
+		})
+	})
+
+	// Trash routes (protected; deleted submissions are purged in the
+	// background once they have been in the trash for TRASH_RETENTION)
+	group.Route(r, "/trash", func(r chi.Router) {
+		r.Use(auth.ScopedMiddleware(h.jwtManager, h.sessions))
+		r.Use(auth.RequireScopeByMethod(auth.ScopeSubmissionsRead, auth.ScopeSubmissionsWrite))
+
+		r.Get("/", h.trash.List)
+		r.Delete("/", h.trash.Empty)
+		r.Post("/{id}/restore", h.trash.Restore)
+	})
+
+	// Bulk import routes (protected; rows are imported in the background)
+	r.Route("/imports", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		NewRouteGroup().Upload(s.config.ImportMaxBytes()).Group(r, func(r chi.Router) {
+			r.With(h.backpressure, h.spendBudget).Post("/", h.submission.Import)
+		})
+		group.Group(r, func(r chi.Router) {
+			r.Get("/{id}", h.submission.GetImport)
+		})
+	})
+
+	// Direct upload routes (protected; files go straight to object storage
+	// and are checked when completed)
+	group.Route(r, "/uploads", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Post("/presign", h.uploads.Presign)
+		r.Post("/complete", h.uploads.Complete)
+	})
+
+	// Conversation thread routes (protected; replies arrive asynchronously)
+	group.Route(r, "/threads", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Get("/{id}", h.threads.Get)
+		r.Post("/{id}/messages", h.threads.AddMessage)
+		r.Delete("/{id}", h.threads.Delete)
+	})
+
+	// Feed routes (protected; new items are polled in the background)
+	group.Route(r, "/feeds", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Get("/", h.feeds.List)
+		r.Post("/", h.feeds.Create)
+		r.Get("/{id}", h.feeds.Get)
+		r.Delete("/{id}", h.feeds.Delete)
+		r.Get("/{id}/digest", h.feeds.Digest)
+	})
+
+	// Synchronous analysis routes, where nothing is stored
+	group.Route(r, "/analyze", func(r chi.Router) {
+		// Quick analysis for the browser extension (API key)
+		r.With(auth.APIKeyMiddleware(h.keys, h.sessions), auth.RequireScope(auth.ScopeAnalyze)).
+			Post("/quick", h.quick.Analyze)
+		// Cost estimates (JWT or scoped token; the model isn't called)
+		r.With(auth.ScopedMiddleware(h.jwtManager, h.sessions), auth.RequireScope(auth.ScopeAnalyze)).
+			Post("/estimate", h.estimate.Estimate)
+	})
+
+	// REST hook routes for Zapier and Make (API key; analyses are posted
+	// to subscribed hooks in the background)
+	group.Route(r, "/hooks", func(r chi.Router) {
+		r.Use(auth.APIKeyMiddleware(h.keys, h.sessions))
+		r.Use(auth.RequireScope(auth.ScopeHooks))
+
+		r.Get("/", h.hooks.List)
+		r.Post("/", h.hooks.Subscribe)
+		r.Delete("/{id}", h.hooks.Unsubscribe)
+		r.Get("/analyses", h.hooks.RecentAnalyses)
+	})
+
+	// Analysis profile routes (protected; built-in profiles are read-only)
+	group.Route(r, "/profiles", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Get("/", h.profiles.List)
+		r.Post("/", h.profiles.Create)
+		r.Get("/{id}", h.profiles.Get)
+		r.Put("/{id}", h.profiles.Update)
+		r.Delete("/{id}", h.profiles.Delete)
+	})
+
+	// Analytics routes (protected; open to scoped tokens)
+	group.Route(r, "/analytics", func(r chi.Router) {
+		r.Use(auth.ScopedMiddleware(h.jwtManager, h.sessions))
+		r.Use(auth.RequireScope(auth.ScopeAnalyticsRead))
+
+		r.Get("/sentiment", h.analytics.SentimentTrend)
+		r.Get("/topics", h.analytics.Topics)
+		r.Get("/labels", h.analytics.Labels)
+	})
+
+	// User routes (protected)
+	group.Route(r, "/me", func(r chi.Router) {
+		// Apply JWT middleware to all routes in this group
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Get("/", h.auth.Me)
+		r.Patch("/", h.account.UpdateProfile)
+		r.Post("/resend-verification", h.auth.ResendVerification)
+		r.With(auth.ForbidImpersonation).Put("/password", h.account.ChangePassword)
+		r.With(auth.ForbidImpersonation).Put("/email", h.account.ChangeEmail)
+		r.Get("/flags", h.flags.Enabled)
+		r.Get("/api-keys", h.apiKeys.List)
+		r.With(auth.ForbidImpersonation).Post("/api-keys", h.apiKeys.Create)
+		r.With(auth.ForbidImpersonation).Delete("/api-keys/{id}", h.apiKeys.Revoke)
+		r.Get("/queue", h.assignees.Queue)
+		r.Get("/stats", h.analytics.Stats)
+		if h.devices != nil {
+			r.Get("/sessions", h.devices.List)
+			r.With(auth.ForbidImpersonation).Delete("/sessions/{id}", h.devices.Revoke)
+		}
+		if h.loginGuard != nil {
+			r.With(auth.ForbidImpersonation).Put("/login-confirmation", h.account.SetLoginConfirmation)
+		}
+	})
+
+	// Appeals; suspended users can reach these routes and nothing else
+	group.Route(r, "/account", func(r chi.Router) {
+		r.Use(auth.SuspendedMiddleware(h.jwtManager, h.sessions))
+
+		r.Get("/appeals", h.suspension.ListMyAppeals)
+		r.With(auth.ForbidImpersonation).Post("/appeals", h.suspension.Appeal)
+	})
+
+	// Organization routes (protected; roles are checked per organization)
+	group.Route(r, "/orgs", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+
+		r.Get("/", h.orgs.List)
+		r.Post("/", h.orgs.Create)
+		r.Get("/{id}", h.orgs.Get)
+		r.Post("/{id}/members", h.orgs.SetMember)
+		r.Delete("/{id}/members/{userID}", h.orgs.RemoveMember)
+		r.Get("/{id}/usage", h.orgs.Usage)
+		r.Get("/{id}/analytics/sentiment", h.orgs.SentimentTrend)
+		r.Put("/{id}/retention", h.orgs.SetRetention)
+		r.Put("/{id}/subdomain", h.orgs.SetSubdomain)
+		r.Get("/{id}/moderation-policy", h.orgs.GetModerationPolicy)
+		r.Put("/{id}/moderation-policy", h.orgs.SetModerationPolicy)
+		r.Get("/{id}/glossary", h.orgs.GetGlossary)
+		r.Put("/{id}/glossary", h.orgs.SetGlossary)
+		r.Get("/{id}/taxonomy", h.orgs.GetTaxonomy)
+		r.Put("/{id}/taxonomy", h.orgs.SetTaxonomy)
+		r.Get("/{id}/domains", h.orgs.ListDomains)
+		r.Post("/{id}/domains", h.orgs.AddDomain)
+		r.Post("/{id}/domains/{domain}/verify", h.orgs.VerifyDomain)
+		r.Delete("/{id}/domains/{domain}", h.orgs.RemoveDomain)
+		if h.sso != nil {
+			r.Get("/{id}/sso", h.orgs.GetSSO)
+			r.Put("/{id}/sso", h.orgs.SetSSO)
+			r.Delete("/{id}/sso", h.orgs.DeleteSSO)
+		}
+		if h.scim != nil {
+			r.Post("/{id}/scim-token", h.orgs.CreateSCIMToken)
+			r.Delete("/{id}/scim-token", h.orgs.RevokeSCIMToken)
+		}
+	})
+
+	// Operator routes (ADMIN_USER_IDS only)
+	group.Route(r, "/admin", func(r chi.Router) {
+		r.Use(auth.Middleware(h.jwtManager, h.sessions))
+		r.Use(auth.RequireAdmin(s.config.AdminUserIDs))
+
+		r.Get("/jobs", h.jobs.Stats)
+		r.Post("/jobs/pause", h.jobs.Pause)
+		r.Post("/jobs/resume", h.jobs.Resume)
+		r.Post("/jobs/requeue-stale", h.jobs.RequeueStale)
+		r.Post("/jobs/{id}/cancel", h.jobs.Cancel)
+
+		r.Get("/flags", h.flags.List)
+		r.Get("/flags/{key}", h.flags.Get)
+		r.Put("/flags/{key}", h.flags.Put)
+		r.Patch("/flags/{key}", h.flags.Patch)
+		r.Delete("/flags/{key}", h.flags.Delete)
+
+		r.Get("/invites", h.invites.List)
+		r.Post("/invites", h.invites.Create)
+		r.Delete("/invites/{id}", h.invites.Revoke)
+
+		r.Put("/submissions/{id}/legal-hold", h.retention.SetLegalHold)
+		r.Put("/submissions/{id}/policy-override", h.moderation.SetOverride)
+		r.Post("/submissions/{id}/quarantine", h.quarantine.Quarantine)
+
+		r.Get("/quarantine", h.quarantine.List)
+		r.Get("/quarantine/{id}", h.quarantine.Get)
+		r.Post("/quarantine/{id}/release", h.quarantine.Release)
+		r.Delete("/quarantine/{id}", h.quarantine.Destroy)
+
+		r.Put("/orgs/{id}/budget", h.spend.SetBudget)
+
+		r.Put("/users/{id}/status", h.suspension.SetStatus)
+		r.Get("/appeals", h.suspension.ListAppeals)
+		r.Post("/appeals/{id}/decision", h.suspension.DecideAppeal)
+
+		if h.impersonation != nil {
+			r.Post("/users/{id}/impersonate", h.impersonation.Impersonate)
+		}
+
+		if s.config.AnalysisArtifacts {
+			r.Get("/analyses/{id}/artifacts", h.artifacts.Get)
+		}
+	})
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	// Print routes in development
@@ -186,16 +861,24 @@ func (s *Server) Start() error {
 	slog.Info("Starting HTTP server",
 		"port", s.config.Port,
 		"env", s.config.Environment,
+		"tls", s.config.TLSEnabled(),
 	)
 
-	// Channel to listen for errors from the server
-	serverErrors := make(chan error, 1)
+	// Channel to listen for errors from the servers
+	serverErrors := make(chan error, 2)
 
 	// Start the server in a goroutine
 	go func() {
-		serverErrors <- s.httpServer.ListenAndServe()
+		serverErrors <- s.listenAndServe()
 	}()
 
+	if s.redirectServer != nil {
+		slog.Info("Redirecting HTTP to HTTPS", "addr", s.redirectServer.Addr)
+		go func() {
+			serverErrors <- s.redirectServer.ListenAndServe()
+		}()
+	}
+
 	// Channel to listen for interrupt signal
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, os.Interrupt, syscall.SIGTERM)
@@ -212,6 +895,12 @@ func (s *Server) Start() error {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if s.redirectServer != nil {
+			if err := s.redirectServer.Shutdown(ctx); err != nil {
+				s.redirectServer.Close()
+			}
+		}
+
 		// Shutdown the server gracefully
 		if err := s.httpServer.Shutdown(ctx); err != nil {
 			// Force close if graceful shutdown fails
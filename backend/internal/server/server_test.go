@@ -1,14 +1,696 @@
-package server
+//go:build integration
+
+package server_test
 
 import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/encryption"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/testutil"
 )
 
-// Server tests require database and cache connections
-// These are integration tests that should be run with Docker running
-// TODO: Add integration tests with test database
+// Run with: go test -tags integration ./internal/server/
+// Requires Docker, or TEST_DATABASE_URL and TEST_REDIS_URL pointing at
+// disposable services.
+
+const (
+	testPassword    = "password123"
+	analysisTimeout = 15 * time.Second
+)
+
+func TestAPI_RegisterLoginSubmitAnalyze(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	ts.Register(t, "flow@example.com", testPassword)
+	token := ts.Login(t, "flow@example.com", testPassword)
+
+	var submission models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, map[string]string{
+		"content": "I love how easy this tool makes it to understand feedback.",
+	}), http.StatusCreated, &submission)
+
+	if submission.Status != models.StatusQueued {
+		t.Errorf("new submission status = %q, want %q", submission.Status, models.StatusQueued)
+	}
+
+	// The worker picks the job up and stores the fake model's analysis
+	var analysis models.Analysis
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		resp := ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID.String()+"/analysis", token, nil)
+		if resp.StatusCode == http.StatusAccepted {
+			return false
+		}
+		testutil.DecodeJSON(t, resp, http.StatusOK, &analysis)
+		return true
+	})
+
+	if analysis.Sentiment != "positive" {
+		t.Errorf("analysis sentiment = %q, want %q", analysis.Sentiment, "positive")
+	}
+	if analysis.SentimentScore == nil || *analysis.SentimentScore != 0.7 {
+		t.Errorf("analysis score = %v, want 0.7", analysis.SentimentScore)
+	}
+	if len(analysis.Topics) != 1 || analysis.Topics[0] != "testing" {
+		t.Errorf("analysis topics = %v, want [testing]", analysis.Topics)
+	}
+
+	var got models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID.String(), token, nil), http.StatusOK, &got)
+	if got.Status != models.StatusCompleted {
+		t.Errorf("submission status = %q, want %q", got.Status, models.StatusCompleted)
+	}
+
+	var list struct {
+		Submissions []models.Submission `json:"submissions"`
+		Total       int                 `json:"total"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions", token, nil), http.StatusOK, &list)
+	if list.Total != 1 || len(list.Submissions) != 1 || list.Submissions[0].ID != submission.ID {
+		t.Errorf("submission list = %+v, want the single submission", list)
+	}
+
+	var stats models.UserStats
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/me/stats", token, nil), http.StatusOK, &stats)
+	if stats.Submissions != 1 || stats.ByStatus[models.StatusCompleted] != 1 || stats.Analyses != 1 || stats.Positive != 1 {
+		t.Errorf("stats = %+v, want one completed, positive submission", stats)
+	}
+}
+
+func TestAPI_AnalysisFailure(t *testing.T) {
+	ts := testutil.NewServer(t)
+	ts.Gemini.SetStatus(http.StatusInternalServerError)
+
+	token := ts.Register(t, "failure@example.com", testPassword)
+
+	var submission models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, map[string]string{
+		"content": "This one will fail.",
+	}), http.StatusCreated, &submission)
+
+	// Once retries are exhausted the submission is marked failed
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		var got models.Submission
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID.String(), token, nil), http.StatusOK, &got)
+		return got.Status == models.StatusFailed
+	})
+
+	if calls := ts.Gemini.Calls(); calls != 3 {
+		t.Errorf("model called %d times, want 3 attempts", calls)
+	}
+
+	resp := ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID.String()+"/analysis", token, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("analysis of failed submission status = %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestAPI_SubmissionsAreScopedToUser(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	alice := ts.Register(t, "alice@example.com", testPassword)
+	bob := ts.Register(t, "bob@example.com", testPassword)
+
+	var submission models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", alice, map[string]string{
+		"content": "Alice's private notes.",
+	}), http.StatusCreated, &submission)
+
+	paths := []string{
+		"/api/v1/submissions/" + submission.ID.String(),
+		"/api/v1/submissions/" + submission.ID.String() + "/analysis",
+	}
+	for _, path := range paths {
+		if resp := ts.Do(t, http.MethodGet, path, bob, nil); resp.StatusCode != http.StatusNotFound {
+			t.Errorf("GET %s as another user status = %d, want %d", path, resp.StatusCode, http.StatusNotFound)
+		}
+	}
+
+	var list struct {
+		Total int `json:"total"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions", bob, nil), http.StatusOK, &list)
+	if list.Total != 0 {
+		t.Errorf("other user's submission total = %d, want 0", list.Total)
+	}
+}
+
+func TestAPI_RequiresAuthentication(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	paths := []string{"/api/v1/me", "/api/v1/submissions", "/api/v1/analytics/sentiment"}
+	for _, path := range paths {
+		if resp := ts.Do(t, http.MethodGet, path, "", nil); resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("GET %s without token status = %d, want %d", path, resp.StatusCode, http.StatusUnauthorized)
+		}
+	}
+}
+
+func TestAPI_EmailVerification(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	token := ts.Register(t, "verify@example.com", testPassword)
+
+	var msgText string
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		msg, ok := ts.Mailer.LastTo("verify@example.com")
+		msgText = msg.Text
+		return ok
+	})
+
+	match := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(msgText)
+	if match == nil {
+		t.Fatalf("verification email has no token link:\n%s", msgText)
+	}
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/auth/verify-email", "", map[string]string{
+		"token": match[1],
+	}), http.StatusOK, nil)
+
+	var me struct {
+		EmailVerified bool `json:"email_verified"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/me", token, nil), http.StatusOK, &me)
+	if !me.EmailVerified {
+		t.Error("email not verified after following the link")
+	}
+}
+
+func TestAPI_ChangePasswordRevokesOtherSessions(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	oldToken := ts.Register(t, "sessions@example.com", testPassword)
+
+	// Revocation has one-second resolution, matching the token's iat claim
+	time.Sleep(time.Second)
+
+	var resp struct {
+		Token struct {
+			AccessToken string `json:"access_token"`
+		} `json:"token"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPut, "/api/v1/me/password", oldToken, map[string]string{
+		"current_password": testPassword,
+		"new_password":     "a-new-password",
+	}), http.StatusOK, &resp)
+
+	if r := ts.Do(t, http.MethodGet, "/api/v1/me", oldToken, nil); r.StatusCode != http.StatusUnauthorized {
+		t.Errorf("old token status = %d, want %d", r.StatusCode, http.StatusUnauthorized)
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/me", resp.Token.AccessToken, nil), http.StatusOK, nil)
+
+	ts.Login(t, "sessions@example.com", "a-new-password")
+}
+
+func TestAPI_StatusEvents(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	var mu sync.Mutex
+	var seen []models.SubmissionStatus
+	ts.Events.Subscribe(func(ctx context.Context, change models.StatusChange) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, change.To)
+		return nil
+	})
+
+	token := ts.Register(t, "events@example.com", testPassword)
+
+	var submission models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, map[string]interface{}{
+		"content": "Drafted first, analyzed later.",
+		"draft":   true,
+	}), http.StatusCreated, &submission)
+
+	// Analysis can't be archived before it has finished
+	path := "/api/v1/submissions/" + submission.ID.String()
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, path+"/archive", token, nil), http.StatusConflict, nil)
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, path+"/submit", token, nil), http.StatusOK, nil)
+
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		var got models.Submission
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, path, token, nil), http.StatusOK, &got)
+		return got.Status == models.StatusCompleted
+	})
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, path+"/archive", token, nil), http.StatusOK, nil)
+
+	want := []models.SubmissionStatus{
+		models.StatusDraft,
+		models.StatusQueued,
+		models.StatusProcessing,
+		models.StatusCompleted,
+		models.StatusArchived,
+	}
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) == len(want)
+	})
+
+	// Events are delivered by the worker, so check the set rather than the order
+	mu.Lock()
+	defer mu.Unlock()
+	for _, status := range want {
+		if !slices.Contains(seen, status) {
+			t.Errorf("status events = %v, missing %q", seen, status)
+		}
+	}
+}
+
+func TestAPI_ProfileUpdateRequiresCurrentVersion(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	token := ts.Register(t, "versions@example.com", testPassword)
+
+	var me struct {
+		Version int `json:"version"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/me", token, nil), http.StatusOK, &me)
+
+	update := map[string]interface{}{"display_name": "First", "version": me.Version}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPatch, "/api/v1/me", token, update), http.StatusOK, nil)
+
+	// A second client still holding the old version must not overwrite it
+	update["display_name"] = "Second"
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPatch, "/api/v1/me", token, update), http.StatusConflict, nil)
+
+	var got struct {
+		DisplayName string `json:"display_name"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/me", token, nil), http.StatusOK, &got)
+	if got.DisplayName != "First" {
+		t.Errorf("display_name = %q, want %q", got.DisplayName, "First")
+	}
+}
+
+func TestAPI_AdminJobQueue(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	userToken := ts.Register(t, "jobs@example.com", testPassword)
+	adminToken := ts.Login(t, testutil.AdminEmail, testutil.AdminPassword)
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/admin/jobs", userToken, nil), http.StatusForbidden, nil)
+
+	// While paused, submissions wait in the queue
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/admin/jobs/pause", adminToken, nil), http.StatusOK, nil)
+
+	var submission struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	body := map[string]string{"content": "Queued while the workers are paused."}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", userToken, body), http.StatusCreated, &submission)
+
+	var stats struct {
+		Paused bool  `json:"paused"`
+		Depth  int64 `json:"depth"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/admin/jobs", adminToken, nil), http.StatusOK, &stats)
+	if !stats.Paused || stats.Depth == 0 {
+		t.Fatalf("stats = %+v, want paused with pending jobs", stats)
+	}
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/admin/jobs/missing/cancel", adminToken, nil), http.StatusNotFound, nil)
+
+	// Resuming lets the backlog drain
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/admin/jobs/resume", adminToken, nil), http.StatusOK, nil)
+
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID, userToken, nil), http.StatusOK, &submission)
+		return submission.Status == string(models.StatusCompleted)
+	})
+}
+
+func TestAPI_AdminLogLevel(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	userToken := ts.Register(t, "loglevel@example.com", testPassword)
+	adminToken := ts.Login(t, testutil.AdminEmail, testutil.AdminPassword)
+	debug := map[string]string{"level": "debug"}
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/admin/log-level", "", nil), http.StatusUnauthorized, nil)
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/admin/log-level", userToken, nil), http.StatusForbidden, nil)
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/admin/log-level", userToken, debug), http.StatusForbidden, nil)
+
+	var level struct {
+		Level string `json:"level"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/admin/log-level", adminToken, nil), http.StatusOK, &level)
+	if level.Level == "" {
+		t.Error("admin got no log level")
+	}
+}
+
+func TestAPI_CancelQueuedAnalysis(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	token := ts.Register(t, "cancel@example.com", testPassword)
+	adminToken := ts.Login(t, testutil.AdminEmail, testutil.AdminPassword)
+
+	// Hold the queue so the submission is still queued when it's canceled
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/admin/jobs/pause", adminToken, nil), http.StatusOK, nil)
+
+	var submission struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	body := map[string]string{"content": "Never mind, don't analyze this."}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, body), http.StatusCreated, &submission)
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions/"+submission.ID+"/cancel", token, nil), http.StatusOK, &submission)
+	if submission.Status != string(models.StatusCanceled) {
+		t.Fatalf("status = %q, want %q", submission.Status, models.StatusCanceled)
+	}
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions/"+submission.ID+"/cancel", token, nil), http.StatusConflict, nil)
+
+	// Once the worker gets to the job it skips it without calling the model
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/admin/jobs/resume", adminToken, nil), http.StatusOK, nil)
+
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		var stats struct {
+			Depth    int64 `json:"depth"`
+			InFlight int64 `json:"in_flight"`
+		}
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/admin/jobs", adminToken, nil), http.StatusOK, &stats)
+		return stats.Depth == 0 && stats.InFlight == 0
+	})
+
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+submission.ID, token, nil), http.StatusOK, &submission)
+	if submission.Status != string(models.StatusCanceled) {
+		t.Errorf("status = %q, want %q", submission.Status, models.StatusCanceled)
+	}
+	if calls := ts.Gemini.Calls(); calls != 0 {
+		t.Errorf("Gemini calls = %d, want 0", calls)
+	}
+}
+
+func TestAPI_DocumentVersions(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	token := ts.Register(t, "versions@example.com", testPassword)
+
+	var original models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, map[string]string{
+		"content": "Our launch went well and customers love it.",
+	}), http.StatusCreated, &original)
+
+	var revised models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions/"+original.ID.String()+"/versions", token, map[string]string{
+		"content": "Our launch went well, though some customers reported bugs.",
+	}), http.StatusCreated, &revised)
+	if revised.PreviousID == nil || *revised.PreviousID != original.ID || revised.Revision != 2 {
+		t.Fatalf("revision = %+v, want the second revision of %s", revised, original.ID)
+	}
+
+	// Only the latest version can be revised
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions/"+original.ID.String()+"/versions", token, map[string]string{
+		"content": "A competing revision.",
+	}), http.StatusConflict, nil)
+
+	var versions struct {
+		Data []models.Submission `json:"data"`
+	}
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+revised.ID.String()+"/versions", token, nil), http.StatusOK, &versions)
+	if len(versions.Data) != 2 || versions.Data[0].ID != original.ID || versions.Data[1].ID != revised.ID {
+		t.Errorf("versions = %+v, want the original then the revision", versions.Data)
+	}
+
+	// The diff is available once both analyses are stored
+	var diff struct {
+		PreviousID string `json:"previous_id"`
+		Tone       struct {
+			From string `json:"from"`
+			To   string `json:"to"`
+		} `json:"tone"`
+		ClaimsCompared bool `json:"claims_compared"`
+	}
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		resp := ts.Do(t, http.MethodGet, "/api/v1/submissions/"+revised.ID.String()+"/diff", token, nil)
+		if resp.StatusCode == http.StatusAccepted {
+			return false
+		}
+		testutil.DecodeJSON(t, resp, http.StatusOK, &diff)
+		return true
+	})
+
+	if diff.PreviousID != original.ID.String() || diff.Tone.From != "positive" || diff.Tone.To != "positive" || !diff.ClaimsCompared {
+		t.Errorf("diff = %+v", diff)
+	}
+}
+
+func TestAPI_TopicsAndDigestsSkipHiddenSubmissions(t *testing.T) {
+	ts := testutil.NewServer(t)
+	ctx := context.Background()
+
+	token := ts.Register(t, "hidden@example.com", testPassword)
+
+	var live, quarantined, trashed models.Submission
+	for i, s := range []*models.Submission{&live, &quarantined, &trashed} {
+		body := map[string]string{"content": fmt.Sprintf("Submission %d about testing.", i)}
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, body), http.StatusCreated, s)
+	}
+	userID := live.UserID
+
+	exec := func(sql string, args ...interface{}) {
+		t.Helper()
+		if _, err := ts.DB.Pool.Exec(ctx, sql, args...); err != nil {
+			t.Fatalf("exec %q: %v", sql, err)
+		}
+	}
+	exec(`UPDATE submissions SET quarantined_at = NOW(), quarantine_reason = 'test' WHERE id = $1`, quarantined.ID)
+	exec(`UPDATE submissions SET deleted_at = NOW() WHERE id = $1`, trashed.ID)
+
+	topics := models.NewTopicStore(ts.DB.Pool)
+	pending, err := topics.SubmissionsMissingEmbeddings(ctx, 10)
+	if err != nil {
+		t.Fatalf("SubmissionsMissingEmbeddings() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != live.ID {
+		t.Errorf("submissions to embed = %+v, want only %s", pending, live.ID)
+	}
+
+	var members []models.TopicClusterMember
+	for _, s := range []models.Submission{live, quarantined, trashed} {
+		exec(`INSERT INTO submission_embeddings (submission_id, model, embedding) VALUES ($1, 'test', '[1,0]')`, s.ID)
+		members = append(members, models.TopicClusterMember{SubmissionID: s.ID})
+	}
+
+	embeddings, err := topics.EmbeddingsForUser(ctx, userID)
+	if err != nil {
+		t.Fatalf("EmbeddingsForUser() error = %v", err)
+	}
+	if len(embeddings) != 1 || embeddings[0].SubmissionID != live.ID {
+		t.Errorf("embeddings = %+v, want only %s", embeddings, live.ID)
+	}
+
+	cluster := models.TopicCluster{Label: "testing", Centroid: []float32{1, 0}, Members: members}
+	if err := topics.ReplaceClusters(ctx, userID, []models.TopicCluster{cluster}); err != nil {
+		t.Fatalf("ReplaceClusters() error = %v", err)
+	}
+	clusters, err := topics.ListClusters(ctx, userID, 5)
+	if err != nil {
+		t.Fatalf("ListClusters() error = %v", err)
+	}
+	if len(clusters) != 1 || len(clusters[0].Representatives) != 1 || clusters[0].Representatives[0].SubmissionID != live.ID {
+		t.Errorf("clusters = %+v, want only %s as a representative", clusters, live.ID)
+	}
+
+	feeds := models.NewFeedStore(ts.DB.Pool)
+	feed, err := feeds.Create(ctx, userID, "https://example.com/feed.xml")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, s := range []models.Submission{live, quarantined, trashed} {
+		item, err := feeds.AddItem(ctx, &models.FeedItem{FeedID: feed.ID, GUID: s.ID.String()})
+		if err != nil {
+			t.Fatalf("AddItem() error = %v", err)
+		}
+		if err := feeds.AttachSubmission(ctx, item.ID, s.ID); err != nil {
+			t.Fatalf("AttachSubmission() error = %v", err)
+		}
+	}
+
+	items, err := feeds.DigestItems(ctx, userID, feed.ID, 10)
+	if err != nil {
+		t.Fatalf("DigestItems() error = %v", err)
+	}
+	if len(items) != 1 || items[0].SubmissionID == nil || *items[0].SubmissionID != live.ID {
+		t.Errorf("digest items = %+v, want only %s", items, live.ID)
+	}
+}
+
+func TestAPI_OrgSentimentTrendCountsMembers(t *testing.T) {
+	ts := testutil.NewServer(t)
+	ctx := context.Background()
+
+	ownerToken := ts.Register(t, "trend-owner@example.com", testPassword)
+	memberToken := ts.Register(t, "trend-member@example.com", testPassword)
+	outsiderToken := ts.Register(t, "trend-outsider@example.com", testPassword)
+
+	var org models.Organization
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/orgs", ownerToken, map[string]string{"name": "Acme"}), http.StatusCreated, &org)
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/orgs/"+org.ID.String()+"/members", ownerToken, map[string]string{
+		"email": "trend-member@example.com", "role": "member",
+	}), http.StatusOK, nil)
+
+	for i, token := range []string{ownerToken, memberToken, outsiderToken} {
+		var s models.Submission
+		body := map[string]string{"content": fmt.Sprintf("I love submission %d.", i)}
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, body), http.StatusCreated, &s)
+		testutil.Eventually(t, analysisTimeout, func() bool {
+			return ts.Do(t, http.MethodGet, "/api/v1/submissions/"+s.ID.String()+"/analysis", token, nil).StatusCode == http.StatusOK
+		})
+	}
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	points, err := models.NewAnalyticsStore(ts.DB.Pool).OrgSentimentTrend(ctx, org.ID, now.Add(-time.Hour), now.Add(time.Hour), models.BucketDay, tokyo)
+	if err != nil {
+		t.Fatalf("OrgSentimentTrend() error = %v", err)
+	}
+	count := 0
+	for _, p := range points {
+		count += p.Count
+		if p.Bucket.In(tokyo).Hour() != 0 {
+			t.Errorf("bucket %v doesn't start at midnight in Tokyo", p.Bucket)
+		}
+	}
+	if count != 2 {
+		t.Errorf("trend counted %d analyses, want the owner's and the member's", count)
+	}
+}
+
+func TestAPI_TeammateDuplicateReusesAnalysis(t *testing.T) {
+	ts := testutil.NewServer(t)
+
+	ownerToken := ts.Register(t, "author@example.com", testPassword)
+	teammateToken := ts.Register(t, "reader@example.com", testPassword)
+
+	var org models.Organization
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/orgs", ownerToken, map[string]string{"name": "Acme"}), http.StatusCreated, &org)
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/orgs/"+org.ID.String()+"/members", ownerToken, map[string]string{
+		"email": "reader@example.com", "role": "member",
+	}), http.StatusOK, nil)
+
+	content := map[string]string{"content": "The new dashboard makes testing feedback easy."}
+	var original models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", ownerToken, content), http.StatusCreated, &original)
+	testutil.Eventually(t, analysisTimeout, func() bool {
+		return ts.Do(t, http.MethodGet, "/api/v1/submissions/"+original.ID.String()+"/analysis", ownerToken, nil).StatusCode == http.StatusOK
+	})
+	calls := ts.Gemini.Calls()
+
+	var copied models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", teammateToken, content), http.StatusCreated, &copied)
+	if copied.ID == original.ID || copied.Status != models.StatusCompleted || copied.DuplicateOf == nil || *copied.DuplicateOf != original.ID {
+		t.Fatalf("teammate's submission = %+v, want a completed copy of %s", copied, original.ID)
+	}
+
+	var analysis models.Analysis
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+copied.ID.String()+"/analysis", teammateToken, nil), http.StatusOK, &analysis)
+	if analysis.SubmissionID != copied.ID || analysis.Sentiment != "positive" || len(analysis.Topics) != 1 {
+		t.Errorf("copy's analysis = %+v, want the original's", analysis)
+	}
+	if ts.Gemini.Calls() != calls {
+		t.Errorf("Gemini calls = %d, want %d", ts.Gemini.Calls(), calls)
+	}
+
+	// The original stays the owner's
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodGet, "/api/v1/submissions/"+original.ID.String(), teammateToken, nil), http.StatusNotFound, nil)
+}
+
+func TestAPI_RehashContentUnderEncryption(t *testing.T) {
+	ts := testutil.NewServer(t)
+	ctx := context.Background()
+
+	token := ts.Register(t, "rehash@example.com", testPassword)
+	var submission models.Submission
+	testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, map[string]any{
+		"content": "Hashed before encryption was enabled.", "draft": true,
+	}), http.StatusCreated, &submission)
+
+	// Encryption is turned on: the plain hash no longer matches
+	keys, err := encryption.ParseMasterKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32)))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := models.NewSubmissionStore(ts.DB.Pool).WithEncryption(encryption.New(keys))
+	var before string
+	if err := ts.DB.Pool.QueryRow(ctx, `SELECT content_hash FROM submissions WHERE id = $1`, submission.ID).Scan(&before); err != nil {
+		t.Fatal(err)
+	}
+	if before != models.ContentHash("Hashed before encryption was enabled.") {
+		t.Fatalf("content_hash = %q, want the plain SHA-256 hash", before)
+	}
+
+	_, n, err := store.RehashContent(ctx, uuid.Nil, 100)
+	if err != nil || n == 0 {
+		t.Fatalf("RehashContent() = %d, %v, want the submission rehashed", n, err)
+	}
+	var after string
+	var keyID *string
+	if err := ts.DB.Pool.QueryRow(ctx, `SELECT content_hash, content_hash_key FROM submissions WHERE id = $1`, submission.ID).Scan(&after, &keyID); err != nil {
+		t.Fatal(err)
+	}
+	if after == before || keyID == nil || *keyID != "k1" {
+		t.Errorf("content_hash = %q keyed with %v, want a hash keyed with k1", after, keyID)
+	}
+
+	// Nothing is left to rehash
+	if _, n, err := store.RehashContent(ctx, uuid.Nil, 100); err != nil || n != 0 {
+		t.Errorf("RehashContent() again = %d, %v, want nothing", n, err)
+	}
+}
+
+func TestAPI_SentimentTrendSkipsHiddenSubmissions(t *testing.T) {
+	ts := testutil.NewServer(t)
+	ctx := context.Background()
+
+	token := ts.Register(t, "trend@example.com", testPassword)
+
+	var live, quarantined, trashed models.Submission
+	for i, s := range []*models.Submission{&live, &quarantined, &trashed} {
+		body := map[string]string{"content": fmt.Sprintf("I love submission %d.", i)}
+		testutil.DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/submissions", token, body), http.StatusCreated, s)
+	}
+	for _, s := range []models.Submission{live, quarantined, trashed} {
+		testutil.Eventually(t, analysisTimeout, func() bool {
+			return ts.Do(t, http.MethodGet, "/api/v1/submissions/"+s.ID.String()+"/analysis", token, nil).StatusCode == http.StatusOK
+		})
+	}
+
+	if _, err := ts.DB.Pool.Exec(ctx, `UPDATE submissions SET quarantined_at = NOW(), quarantine_reason = 'test' WHERE id = $1`, quarantined.ID); err != nil {
+		t.Fatalf("failed to quarantine submission: %v", err)
+	}
+	if _, err := ts.DB.Pool.Exec(ctx, `UPDATE submissions SET deleted_at = NOW() WHERE id = $1`, trashed.ID); err != nil {
+		t.Fatalf("failed to trash submission: %v", err)
+	}
 
-func TestServerIntegration(t *testing.T) {
-	t.Skip("Integration tests require database - run with Docker")
-	// Integration tests will be added here
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	points, err := models.NewAnalyticsStore(ts.DB.Pool).SentimentTrend(ctx, live.UserID, from, from.Add(24*time.Hour), models.BucketDay, time.UTC)
+	if err != nil {
+		t.Fatalf("SentimentTrend() error = %v", err)
+	}
+	count := 0
+	for _, p := range points {
+		count += p.Count
+	}
+	if count != 1 {
+		t.Errorf("trend counted %d analyses, want only %s's", count, live.ID)
+	}
 }
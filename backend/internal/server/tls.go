@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// setupTLS prepares TLS termination when a certificate source is configured,
+// along with the optional listener redirecting plain HTTP to HTTPS.
+// net/http negotiates HTTP/2 automatically on TLS connections.
+func (s *Server) setupTLS() {
+	if !s.config.TLSEnabled() {
+		return
+	}
+
+	var challenges func(http.Handler) http.Handler
+	if len(s.config.TLSAutocertHosts) > 0 {
+		manager := s.certManager()
+		s.httpServer.TLSConfig = manager.TLSConfig()
+		s.httpServer.TLSConfig.MinVersion = tls.VersionTLS12
+		challenges = manager.HTTPHandler
+	} else {
+		s.httpServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	if s.config.TLSRedirectAddr == "" {
+		return
+	}
+
+	var handler http.Handler = redirectHandler(s.config.Port)
+	if challenges != nil {
+		// The CA validates http-01 challenges over plain HTTP
+		handler = challenges(handler)
+	}
+	s.redirectServer = &http.Server{
+		Addr:         s.config.TLSRedirectAddr,
+		Handler:      handler,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+		IdleTimeout:  30 * time.Second,
+	}
+}
+
+// certManager returns the manager obtaining and renewing certificates for
+// TLS_AUTOCERT_HOSTS from the configured ACME CA. Certificates are issued
+// on first use, kept in TLS_AUTOCERT_CACHE_DIR and renewed in the
+// background before they expire.
+func (s *Server) certManager() *autocert.Manager {
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(s.config.TLSAutocertHosts...),
+		Cache:      autocert.DirCache(s.config.TLSAutocertCacheDir),
+		Email:      s.config.TLSAutocertEmail,
+		Client:     &acme.Client{DirectoryURL: s.config.TLSAutocertDirectory},
+	}
+}
+
+// listenAndServe serves the router over TLS when it is configured, plain
+// HTTP otherwise
+func (s *Server) listenAndServe() error {
+	switch {
+	case s.config.TLSCert != "":
+		return s.httpServer.ListenAndServeTLS(s.config.TLSCert, s.config.TLSKey)
+	case s.httpServer.TLSConfig != nil:
+		// Certificates come from TLSConfig.GetCertificate
+		return s.httpServer.ListenAndServeTLS("", "")
+	default:
+		return s.httpServer.ListenAndServe()
+	}
+}
+
+// redirectHandler permanently redirects every request to the same URL over
+// HTTPS on the given port
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			// IPv6 literals keep their brackets
+			host = "[" + host + "]"
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/sfumato00/content-analyzer/internal/config"
+)
+
+// newAutocertServer returns a server set up to obtain certificates for
+// api.example.com, caching them in dir
+func newAutocertServer(t *testing.T, dir string) *Server {
+	t.Helper()
+
+	s := &Server{
+		config: &config.Config{
+			Port:                 "443",
+			TLSAutocertHosts:     []string{"api.example.com"},
+			TLSAutocertCacheDir:  dir,
+			TLSAutocertDirectory: acme.LetsEncryptURL,
+			TLSRedirectAddr:      ":80",
+		},
+		httpServer: &http.Server{},
+	}
+	s.setupTLS()
+	return s
+}
+
+// cacheCertificate stores a self-signed ECDSA certificate for host in dir
+// the way autocert caches the certificates it obtains
+func cacheCertificate(t *testing.T, dir, host string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("CreateCertificate() error = %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalECPrivateKey() error = %v", err)
+	}
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	if err := autocert.DirCache(dir).Put(context.Background(), host, data); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+}
+
+func TestSetupTLS_Autocert(t *testing.T) {
+	dir := t.TempDir()
+	cacheCertificate(t, dir, "api.example.com")
+	s := newAutocertServer(t, dir)
+
+	tlsConfig := s.httpServer.TLSConfig
+	if tlsConfig == nil || tlsConfig.GetCertificate == nil {
+		t.Fatal("TLSConfig has no GetCertificate, want certificates from the ACME CA")
+	}
+	if tlsConfig.MinVersion != tls.VersionTLS12 || !slices.Contains(tlsConfig.NextProtos, acme.ALPNProto) {
+		t.Errorf("TLSConfig = min version %x, protocols %v, want TLS 1.2 and the tls-alpn-01 challenge", tlsConfig.MinVersion, tlsConfig.NextProtos)
+	}
+
+	// Server names are matched case-insensitively
+	cert, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:       "API.example.com",
+		CipherSuites:     []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256},
+		SignatureSchemes: []tls.SignatureScheme{tls.ECDSAWithP256AndSHA256},
+	})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v, want the cached certificate", err)
+	}
+	if got := cert.Leaf.DNSNames[0]; got != "api.example.com" {
+		t.Errorf("certificate DNS name = %q, want %q", got, "api.example.com")
+	}
+
+	// Hosts that weren't configured never reach the CA
+	if _, err := tlsConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.example.com"}); err == nil {
+		t.Error("GetCertificate() of an unknown host error = nil, want it refused")
+	}
+}
+
+func TestSetupTLS_AutocertRedirect(t *testing.T) {
+	s := newAutocertServer(t, t.TempDir())
+	if s.redirectServer == nil {
+		t.Fatal("redirectServer = nil, want the plain HTTP listener")
+	}
+
+	tests := []struct {
+		name     string
+		host     string
+		path     string
+		wantCode int
+	}{
+		{"unknown challenge", "api.example.com", "/.well-known/acme-challenge/token", http.StatusNotFound},
+		{"challenge for unknown host", "evil.example.com", "/.well-known/acme-challenge/token", http.StatusForbidden},
+		{"other path", "api.example.com", "/health", http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			s.redirectServer.Handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		host      string
+		target    string
+		want      string
+	}{
+		{"default port", "443", "example.com", "/api/v1/submissions?page=2", "https://example.com/api/v1/submissions?page=2"},
+		{"drops http port", "443", "example.com:80", "/", "https://example.com/"},
+		{"custom port", "8443", "example.com:8080", "/health", "https://example.com:8443/health"},
+		{"ipv6", "443", "[::1]:80", "/", "https://[::1]/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+
+			redirectHandler(tt.httpsPort).ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusMovedPermanently {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusMovedPermanently)
+			}
+			if got := rec.Header().Get("Location"); got != tt.want {
+				t.Errorf("Location = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package ai
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+const (
+	// paceBelow is the fraction of the request limit left at which calls
+	// are spread over what remains of the window rather than sent at once
+	paceBelow = 0.1
+
+	// minBackoff and maxBackoff bound the wait after a 429 that didn't say
+	// how long to wait; it doubles with each 429 in a row
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// BudgetState is a snapshot of a provider's rate limit as seen from its
+// responses. Limit, Remaining and ResetAt are nil until the provider
+// reports them in headers.
+type BudgetState struct {
+	Provider       string          `json:"provider"`
+	Limit          *int            `json:"limit"`
+	Remaining      *int            `json:"remaining"`
+	ResetAt        *timestamp.Time `json:"reset_at"`
+	ThrottledUntil *timestamp.Time `json:"throttled_until"`
+	// RateLimited counts 429 responses since the process started
+	RateLimited int64 `json:"rate_limited"`
+	// DelaySeconds is how long jobs calling the provider are held back now
+	DelaySeconds float64 `json:"delay_seconds"`
+}
+
+// Budget tracks how much of a provider's rate limit is left, from the
+// limit headers and 429 responses of the calls made through a client, and
+// how long to hold back the next call. It only sees this process' calls.
+type Budget struct {
+	provider string
+	now      func() time.Time
+
+	mu             sync.Mutex
+	limit          int // -1 until reported
+	remaining      int // -1 until reported
+	resetAt        time.Time
+	throttledUntil time.Time
+	backoff        time.Duration
+	rateLimited    int64
+
+	gauges  *prometheus.GaugeVec
+	limited *prometheus.CounterVec
+}
+
+// NewBudget creates an empty budget for provider
+func NewBudget(provider string) *Budget {
+	return &Budget{provider: provider, now: time.Now, limit: -1, remaining: -1}
+}
+
+// WithMetrics exports the budget to registry and returns it
+func (b *Budget) WithMetrics(registry prometheus.Registerer) *Budget {
+	b.gauges = metrics.Register(registry, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "llm_rate_limit_budget",
+		Help: "Rate limit state reported by the LLM provider: limit, remaining, and the delay in seconds jobs are held back.",
+	}, []string{"provider", "state"}))
+	b.limited = metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "llm_rate_limited_total",
+		Help: "Responses from the LLM provider refusing a call for its rate limit.",
+	}, []string{"provider"}))
+	return b
+}
+
+// observe records the limit headers of a response, and for a 429 the
+// delay it asked for in its body or Retry-After header, if any
+func (b *Budget) observe(resp *http.Response, retryDelay time.Duration) {
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if limit, ok := headerInt(resp.Header, "X-Ratelimit-Limit-Requests"); ok {
+		b.limit = limit
+	}
+	if remaining, ok := headerInt(resp.Header, "X-Ratelimit-Remaining-Requests"); ok {
+		b.remaining = remaining
+	}
+	if reset, ok := headerDelay(resp.Header.Get("X-Ratelimit-Reset-Requests"), now); ok {
+		b.resetAt = now.Add(reset)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		b.rateLimited++
+		if b.limited != nil {
+			b.limited.WithLabelValues(b.provider).Inc()
+		}
+
+		if retryDelay <= 0 {
+			b.backoff = min(max(2*b.backoff, minBackoff), maxBackoff)
+			retryDelay = b.backoff
+		}
+		if until := now.Add(retryDelay); until.After(b.throttledUntil) {
+			b.throttledUntil = until
+		}
+	} else if resp.StatusCode < 300 {
+		b.backoff = 0
+	}
+
+	b.export(now)
+}
+
+// Delay returns how long to hold back the next call: until a 429's delay
+// has passed, or, once little of the limit is left, an even share of what
+// remains of the window
+func (b *Budget) Delay() time.Duration {
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.delay(now)
+}
+
+// delay implements Delay with the lock held
+func (b *Budget) delay(now time.Time) time.Duration {
+	if now.Before(b.throttledUntil) {
+		return b.throttledUntil.Sub(now)
+	}
+	if b.limit > 0 && b.remaining >= 0 && now.Before(b.resetAt) && float64(b.remaining) <= paceBelow*float64(b.limit) {
+		return b.resetAt.Sub(now) / time.Duration(b.remaining+1)
+	}
+	return 0
+}
+
+// State returns a snapshot of the budget
+func (b *Budget) State() BudgetState {
+	now := b.now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := BudgetState{
+		Provider:     b.provider,
+		RateLimited:  b.rateLimited,
+		DelaySeconds: b.delay(now).Seconds(),
+	}
+	if b.limit >= 0 {
+		limit := b.limit
+		state.Limit = &limit
+	}
+	if b.remaining >= 0 {
+		remaining := b.remaining
+		state.Remaining = &remaining
+	}
+	if now.Before(b.resetAt) {
+		resetAt := timestamp.New(b.resetAt)
+		state.ResetAt = &resetAt
+	}
+	if now.Before(b.throttledUntil) {
+		until := timestamp.New(b.throttledUntil)
+		state.ThrottledUntil = &until
+	}
+	return state
+}
+
+// export updates the gauges with the lock held
+func (b *Budget) export(now time.Time) {
+	if b.gauges == nil {
+		return
+	}
+	b.gauges.WithLabelValues(b.provider, "limit").Set(float64(b.limit))
+	b.gauges.WithLabelValues(b.provider, "remaining").Set(float64(b.remaining))
+	b.gauges.WithLabelValues(b.provider, "delay_seconds").Set(b.delay(now).Seconds())
+}
+
+// RateLimited reports whether err is the provider refusing a call for its
+// rate limit, and how long it asked to wait, if it said
+func RateLimited(err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	return apiErr.RetryAfter, true
+}
+
+// headerInt reads a non-negative integer header
+func headerInt(h http.Header, name string) (int, bool) {
+	n, err := strconv.Atoi(h.Get(name))
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// headerDelay reads a delay given in seconds, as a duration such as "6m0s"
+// or, for Retry-After, as an HTTP date
+func headerDelay(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+		return d, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -0,0 +1,124 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// limitResponse builds a response with the given status and limit headers
+func limitResponse(status int, limit, remaining, reset string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: http.Header{}}
+	if limit != "" {
+		resp.Header.Set("X-Ratelimit-Limit-Requests", limit)
+	}
+	if remaining != "" {
+		resp.Header.Set("X-Ratelimit-Remaining-Requests", remaining)
+	}
+	if reset != "" {
+		resp.Header.Set("X-Ratelimit-Reset-Requests", reset)
+	}
+	return resp
+}
+
+func TestBudget_Delay(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		responses []*http.Response
+		retry     time.Duration
+		want      time.Duration
+	}{
+		{"nothing reported", nil, 0, 0},
+		{"plenty left", []*http.Response{limitResponse(http.StatusOK, "100", "50", "60")}, 0, 0},
+		{"paces when low", []*http.Response{limitResponse(http.StatusOK, "100", "9", "60s")}, 0, 6 * time.Second},
+		{"paces to the reset when exhausted", []*http.Response{limitResponse(http.StatusOK, "100", "0", "30")}, 0, 30 * time.Second},
+		{"429 with a retry delay", []*http.Response{limitResponse(http.StatusTooManyRequests, "", "", "")}, 37 * time.Second, 37 * time.Second},
+		{"429 without a delay backs off", []*http.Response{limitResponse(http.StatusTooManyRequests, "", "", "")}, 0, time.Second},
+		{"backoff doubles", []*http.Response{
+			limitResponse(http.StatusTooManyRequests, "", "", ""),
+			limitResponse(http.StatusTooManyRequests, "", "", ""),
+			limitResponse(http.StatusTooManyRequests, "", "", ""),
+		}, 0, 4 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := NewBudget("gemini")
+			budget.now = func() time.Time { return now }
+			for _, resp := range tt.responses {
+				budget.observe(resp, tt.retry)
+			}
+			if got := budget.Delay(); got != tt.want {
+				t.Errorf("Delay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBudget_RecoversAfterThrottle(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	budget := NewBudget("gemini").WithMetrics(prometheus.NewRegistry())
+	budget.now = func() time.Time { return now }
+
+	budget.observe(limitResponse(http.StatusTooManyRequests, "", "", ""), 10*time.Second)
+	state := budget.State()
+	if state.RateLimited != 1 || state.ThrottledUntil == nil || state.DelaySeconds != 10 {
+		t.Fatalf("State() = %+v, want throttled for 10s", state)
+	}
+	if got := testutil.ToFloat64(budget.limited.WithLabelValues("gemini")); got != 1 {
+		t.Errorf("rate limited counter = %v, want 1", got)
+	}
+
+	now = now.Add(11 * time.Second)
+	budget.observe(limitResponse(http.StatusOK, "100", "80", "60"), 0)
+	state = budget.State()
+	if state.ThrottledUntil != nil || state.DelaySeconds != 0 || state.Remaining == nil || *state.Remaining != 80 {
+		t.Errorf("State() = %+v, want unthrottled with 80 remaining", state)
+	}
+	if got := testutil.ToFloat64(budget.gauges.WithLabelValues("gemini", "remaining")); got != 80 {
+		t.Errorf("remaining gauge = %v, want 80", got)
+	}
+
+	// A success resets the backoff
+	budget.observe(limitResponse(http.StatusTooManyRequests, "", "", ""), 0)
+	if got := budget.Delay(); got != time.Second {
+		t.Errorf("Delay() = %v, want the backoff to start over at 1s", got)
+	}
+}
+
+func TestClient_RateLimited(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"code": 429, "message": "Quota exceeded", "details": [
+			{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+			{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "37s"}
+		]}}`))
+	}))
+	defer server.Close()
+
+	budget := NewBudget("gemini")
+	client := NewClient(Options{BaseURL: server.URL, Budget: budget})
+
+	_, err := client.Generate(context.Background(), GenerateRequest{Prompt: "hello"})
+	retryAfter, ok := RateLimited(err)
+	if !ok {
+		t.Fatalf("Generate() error = %v, want a rate limit error", err)
+	}
+	if retryAfter != 37*time.Second || !strings.Contains(err.Error(), "Quota") {
+		t.Errorf("RateLimited() = %v for %v, want a 37s retry delay", retryAfter, err)
+	}
+	if delay := budget.Delay(); delay <= 30*time.Second || delay > 37*time.Second {
+		t.Errorf("Delay() = %v, want about 37s", delay)
+	}
+	if client.Budget() != budget {
+		t.Error("Budget() isn't the shared budget")
+	}
+}
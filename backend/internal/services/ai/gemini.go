@@ -0,0 +1,374 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the Gemini REST API endpoint
+	DefaultBaseURL = "https://generativelanguage.googleapis.com/v1beta"
+
+	// DefaultModel is the model used for text generation
+	DefaultModel = "gemini-2.0-flash"
+
+	// DefaultEmbeddingModel is the model used for embeddings
+	DefaultEmbeddingModel = "text-embedding-004"
+
+	// EmbeddingDimensions is the size of vectors returned by DefaultEmbeddingModel
+	EmbeddingDimensions = 768
+
+	// maxEmbedBatch is the API limit for batchEmbedContents
+	maxEmbedBatch = 100
+)
+
+// Client is a minimal client for the Gemini REST API
+type Client struct {
+	apiKey         string
+	baseURL        string
+	model          atomic.Pointer[string]
+	embeddingModel string
+	httpClient     *http.Client
+	budget         *Budget
+
+	skipResponseSchema bool
+}
+
+// Options configures a Gemini client
+type Options struct {
+	APIKey         string
+	BaseURL        string
+	Model          string
+	EmbeddingModel string
+	HTTPClient     *http.Client
+
+	// Budget tracks the rate limit across the clients sharing it. A
+	// client without one tracks its own.
+	Budget *Budget
+
+	// SkipResponseSchema leaves request schemas out of the calls, for
+	// models without structured output. Responses are still validated
+	// against them by the caller.
+	SkipResponseSchema bool
+
+	// ReplayMode records the client's responses to ReplayDir or replays
+	// them from it; see Replayer. Empty calls the API as usual.
+	ReplayMode string
+	ReplayDir  string
+}
+
+// NewClient creates a new Gemini client
+func NewClient(opts Options) *Client {
+	c := &Client{
+		apiKey:         opts.APIKey,
+		baseURL:        opts.BaseURL,
+		embeddingModel: opts.EmbeddingModel,
+		httpClient:     opts.HTTPClient,
+		budget:         opts.Budget,
+
+		skipResponseSchema: opts.SkipResponseSchema,
+	}
+
+	if c.baseURL == "" {
+		c.baseURL = DefaultBaseURL
+	}
+	c.SetModel(opts.Model)
+	if c.embeddingModel == "" {
+		c.embeddingModel = DefaultEmbeddingModel
+	}
+	if c.httpClient == nil {
+		c.httpClient = &http.Client{Timeout: 60 * time.Second}
+	}
+	if opts.ReplayMode != "" {
+		replaying := *c.httpClient
+		replaying.Transport = NewReplayer(opts.ReplayMode, opts.ReplayDir, c.httpClient.Transport)
+		c.httpClient = &replaying
+	}
+	if c.budget == nil {
+		c.budget = NewBudget("gemini")
+	}
+
+	return c
+}
+
+// Model returns the generation model name
+func (c *Client) Model() string {
+	return *c.model.Load()
+}
+
+// SetModel switches the generation model for subsequent requests. An empty
+// name selects DefaultModel.
+func (c *Client) SetModel(model string) {
+	if model == "" {
+		model = DefaultModel
+	}
+	c.model.Store(&model)
+}
+
+// Budget returns the rate limit budget the client reports to
+func (c *Client) Budget() *Budget {
+	return c.budget
+}
+
+// EmbeddingModel returns the embedding model name
+func (c *Client) EmbeddingModel() string {
+	return c.embeddingModel
+}
+
+// Roles of the turns in a conversation
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// Turn is an earlier message in a conversation
+type Turn struct {
+	Role string
+	Text string
+}
+
+// GenerateRequest describes a text generation call
+type GenerateRequest struct {
+	// Prompt is the user content sent to the model
+	Prompt string
+
+	// History holds earlier turns of a conversation, oldest first, that
+	// Prompt continues
+	History []Turn
+
+	// SystemInstruction optionally steers the model's behaviour
+	SystemInstruction string
+
+	// JSON asks the model to respond with application/json
+	JSON bool
+
+	// Schema constrains a JSON response to a shape, unless the client
+	// skips response schemas
+	Schema *Schema
+
+	// Temperature overrides the model default when set
+	Temperature *float64
+}
+
+// GenerateResponse is the result of a text generation call
+type GenerateResponse struct {
+	Text         string
+	FinishReason string
+	PromptTokens int
+	OutputTokens int
+}
+
+// APIError is returned when the Gemini API responds with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Status     string
+	Message    string
+
+	// RetryAfter is how long a 429 asked to wait before calling again, or
+	// zero if it didn't say
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("gemini API error (%d %s): %s", e.StatusCode, e.Status, e.Message)
+}
+
+type part struct {
+	Text string `json:"text"`
+}
+
+type content struct {
+	Role  string `json:"role,omitempty"`
+	Parts []part `json:"parts"`
+}
+
+type generationConfig struct {
+	ResponseMIMEType string   `json:"responseMimeType,omitempty"`
+	ResponseSchema   *Schema  `json:"responseSchema,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+}
+
+type generateContentRequest struct {
+	Contents          []content         `json:"contents"`
+	SystemInstruction *content          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *generationConfig `json:"generationConfig,omitempty"`
+}
+
+type generateContentResponse struct {
+	Candidates []struct {
+		Content      content `json:"content"`
+		FinishReason string  `json:"finishReason"`
+	} `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// Generate runs a text generation, continuing req.History when it is set
+func (c *Client) Generate(ctx context.Context, req GenerateRequest) (*GenerateResponse, error) {
+	body := generateContentRequest{
+		Contents: make([]content, 0, len(req.History)+1),
+	}
+	for _, turn := range req.History {
+		body.Contents = append(body.Contents, content{Role: turn.Role, Parts: []part{{Text: turn.Text}}})
+	}
+	body.Contents = append(body.Contents, content{Role: RoleUser, Parts: []part{{Text: req.Prompt}}})
+
+	if req.SystemInstruction != "" {
+		body.SystemInstruction = &content{Parts: []part{{Text: req.SystemInstruction}}}
+	}
+
+	if req.JSON || req.Temperature != nil {
+		body.GenerationConfig = &generationConfig{Temperature: req.Temperature}
+		if req.JSON {
+			body.GenerationConfig.ResponseMIMEType = "application/json"
+			if !c.skipResponseSchema {
+				body.GenerationConfig.ResponseSchema = req.Schema
+			}
+		}
+	}
+
+	var resp generateContentResponse
+	if err := c.post(ctx, fmt.Sprintf("models/%s:generateContent", c.Model()), body, &resp); err != nil {
+		return nil, err
+	}
+
+	if len(resp.Candidates) == 0 {
+		return nil, fmt.Errorf("gemini returned no candidates")
+	}
+
+	candidate := resp.Candidates[0]
+
+	var text strings.Builder
+	for _, p := range candidate.Content.Parts {
+		text.WriteString(p.Text)
+	}
+
+	return &GenerateResponse{
+		Text:         text.String(),
+		FinishReason: candidate.FinishReason,
+		PromptTokens: resp.UsageMetadata.PromptTokenCount,
+		OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+	}, nil
+}
+
+type embedRequest struct {
+	Model   string  `json:"model"`
+	Content content `json:"content"`
+}
+
+type batchEmbedRequest struct {
+	Requests []embedRequest `json:"requests"`
+}
+
+type batchEmbedResponse struct {
+	Embeddings []struct {
+		Values []float32 `json:"values"`
+	} `json:"embeddings"`
+}
+
+// Embed returns one embedding vector per input text, in order
+func (c *Client) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	model := "models/" + c.embeddingModel
+
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		end := min(start+maxEmbedBatch, len(texts))
+
+		body := batchEmbedRequest{Requests: make([]embedRequest, 0, end-start)}
+		for _, text := range texts[start:end] {
+			body.Requests = append(body.Requests, embedRequest{
+				Model:   model,
+				Content: content{Parts: []part{{Text: text}}},
+			})
+		}
+
+		var resp batchEmbedResponse
+		if err := c.post(ctx, model+":batchEmbedContents", body, &resp); err != nil {
+			return nil, err
+		}
+
+		if len(resp.Embeddings) != end-start {
+			return nil, fmt.Errorf("gemini returned %d embeddings for %d inputs", len(resp.Embeddings), end-start)
+		}
+
+		for _, e := range resp.Embeddings {
+			vectors = append(vectors, e.Values)
+		}
+	}
+
+	return vectors, nil
+}
+
+// post sends a JSON request to the given API path and decodes the response
+func (c *Client) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/"+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", c.apiKey)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("gemini request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read gemini response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Status: http.StatusText(resp.StatusCode)}
+
+		var errBody struct {
+			Error struct {
+				Message string `json:"message"`
+				Details []struct {
+					Type       string `json:"@type"`
+					RetryDelay string `json:"retryDelay"`
+				} `json:"details"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(respBody, &errBody) == nil && errBody.Error.Message != "" {
+			apiErr.Message = errBody.Error.Message
+		} else {
+			apiErr.Message = strings.TrimSpace(string(respBody))
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			for _, detail := range errBody.Error.Details {
+				if strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+					apiErr.RetryAfter, _ = time.ParseDuration(detail.RetryDelay)
+				}
+			}
+			if apiErr.RetryAfter <= 0 {
+				apiErr.RetryAfter, _ = headerDelay(resp.Header.Get("Retry-After"), time.Now())
+			}
+		}
+
+		c.budget.observe(resp, apiErr.RetryAfter)
+		return apiErr
+	}
+	c.budget.observe(resp, 0)
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode gemini response: %w", err)
+	}
+
+	return nil
+}
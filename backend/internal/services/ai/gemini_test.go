@@ -0,0 +1,148 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestClient_Generate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/test-model:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-goog-api-key") != "test-key" {
+			t.Errorf("missing API key header")
+		}
+
+		var body generateContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if body.GenerationConfig == nil || body.GenerationConfig.ResponseMIMEType != "application/json" {
+			t.Errorf("expected JSON response MIME type to be requested")
+		}
+
+		w.Write([]byte(`{
+			"candidates": [{"content": {"parts": [{"text": "{\"label\":"}, {"text": "\"Pricing\"}"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 12, "candidatesTokenCount": 5}
+		}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{APIKey: "test-key", BaseURL: server.URL, Model: "test-model"})
+
+	resp, err := client.Generate(context.Background(), GenerateRequest{Prompt: "hello", JSON: true})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if resp.Text != `{"label":"Pricing"}` {
+		t.Errorf("Generate() text = %q", resp.Text)
+	}
+	if resp.PromptTokens != 12 || resp.OutputTokens != 5 {
+		t.Errorf("Generate() tokens = %d/%d, want 12/5", resp.PromptTokens, resp.OutputTokens)
+	}
+	if resp.FinishReason != "STOP" {
+		t.Errorf("Generate() finish reason = %q, want STOP", resp.FinishReason)
+	}
+}
+
+func TestClient_Generate_History(t *testing.T) {
+	var got []content
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body generateContentRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		got = body.Contents
+
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "Shorter."}]}}]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{BaseURL: server.URL})
+
+	_, err := client.Generate(context.Background(), GenerateRequest{
+		History: []Turn{{Role: RoleUser, Text: "List the risks"}, {Role: RoleModel, Text: "1. Termination"}},
+		Prompt:  "Make it shorter",
+	})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	want := []string{"user:List the risks", "model:1. Termination", "user:Make it shorter"}
+	if len(got) != len(want) {
+		t.Fatalf("Generate() sent %d turns, want %d", len(got), len(want))
+	}
+	for i, c := range got {
+		if turn := c.Role + ":" + c.Parts[0].Text; turn != want[i] {
+			t.Errorf("turn %d = %q, want %q", i, turn, want[i])
+		}
+	}
+}
+
+func TestClient_Generate_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": {"code": 429, "message": "Resource has been exhausted"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{BaseURL: server.URL})
+
+	_, err := client.Generate(context.Background(), GenerateRequest{Prompt: "hello"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Generate() error = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("APIError.StatusCode = %d, want 429", apiErr.StatusCode)
+	}
+	if apiErr.Message != "Resource has been exhausted" {
+		t.Errorf("APIError.Message = %q", apiErr.Message)
+	}
+}
+
+func TestClient_Embed_Batches(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		var body batchEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+
+		embeddings := make([]string, len(body.Requests))
+		for i := range body.Requests {
+			embeddings[i] = `{"values": [0.1, 0.2]}`
+		}
+		w.Write([]byte(`{"embeddings": [` + strings.Join(embeddings, ",") + `]}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(Options{BaseURL: server.URL})
+
+	texts := make([]string, maxEmbedBatch+5)
+	for i := range texts {
+		texts[i] = "text"
+	}
+
+	vectors, err := client.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if len(vectors) != len(texts) {
+		t.Errorf("Embed() returned %d vectors, want %d", len(vectors), len(texts))
+	}
+	if calls != 2 {
+		t.Errorf("Embed() made %d API calls, want 2", calls)
+	}
+}
@@ -0,0 +1,14 @@
+package ai
+
+import "math"
+
+// Pricing is what the model charges, in US dollars per million tokens
+type Pricing struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+// CostMicros returns the cost of a call in millionths of a dollar
+func (p Pricing) CostMicros(promptTokens, outputTokens int) int64 {
+	return int64(math.Round(float64(promptTokens)*p.InputPerMillion + float64(outputTokens)*p.OutputPerMillion))
+}
@@ -0,0 +1,25 @@
+package ai
+
+import "testing"
+
+func TestPricing_CostMicros(t *testing.T) {
+	tests := []struct {
+		name    string
+		pricing Pricing
+		prompt  int
+		output  int
+		want    int64
+	}{
+		{"flash prices", Pricing{InputPerMillion: 0.10, OutputPerMillion: 0.40}, 1200, 300, 240},
+		{"rounds to the nearest micro-dollar", Pricing{InputPerMillion: 0.075, OutputPerMillion: 0.30}, 10, 1, 1},
+		{"unpriced", Pricing{}, 5000, 5000, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.pricing.CostMicros(tt.prompt, tt.output); got != tt.want {
+				t.Errorf("CostMicros(%d, %d) = %d, want %d", tt.prompt, tt.output, got, tt.want)
+			}
+		})
+	}
+}
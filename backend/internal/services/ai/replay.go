@@ -0,0 +1,182 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Replay modes
+const (
+	// ReplayRecord calls the API and saves each successful response
+	ReplayRecord = "record"
+	// ReplayReplay answers from the saved responses only, without an API
+	// key or network access
+	ReplayReplay = "replay"
+)
+
+// ReplayModes lists the supported replay modes
+var ReplayModes = []string{ReplayRecord, ReplayReplay}
+
+// ErrNoRecording is returned in replay mode for a request that was never
+// recorded
+var ErrNoRecording = errors.New("no recorded response")
+
+// ValidReplayMode reports whether mode is a supported replay mode
+func ValidReplayMode(mode string) bool {
+	return slices.Contains(ReplayModes, mode)
+}
+
+// recording is a saved API exchange. The request is kept so a recording
+// can be read and edited by hand.
+type recording struct {
+	Path       string          `json:"path"`
+	Request    json.RawMessage `json:"request"`
+	Status     int             `json:"status"`
+	Response   json.RawMessage `json:"response"`
+	RecordedAt time.Time       `json:"recorded_at"`
+}
+
+// Replayer is an http.RoundTripper that records Gemini API responses to a
+// directory, one JSON file per request, or replays them from it. Requests
+// are keyed by a hash of the API path, which names the model, and the
+// request body, which holds the prompt and generation settings, so the
+// same call always gets the same response. Only successful responses are
+// recorded.
+type Replayer struct {
+	mode string
+	dir  string
+	next http.RoundTripper
+}
+
+// NewReplayer creates a replayer in mode over the recordings in dir,
+// sending the requests it records through next, or
+// http.DefaultTransport when next is nil
+func NewReplayer(mode, dir string, next http.RoundTripper) *Replayer {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Replayer{mode: mode, dir: dir, next: next}
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, fmt.Errorf("failed to read request: %w", err)
+		}
+		req.Body.Close()
+	}
+
+	path := apiPath(req)
+	file := filepath.Join(r.dir, replayKey(path, body)+".json")
+
+	if r.mode == ReplayReplay {
+		return r.replay(req, file, path)
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(body))
+	resp, err := r.next.RoundTrip(out)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if err := r.save(file, recording{Path: path, Request: body, Status: resp.StatusCode, Response: respBody, RecordedAt: time.Now().UTC()}); err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// replay answers req from its recording
+func (r *Replayer) replay(req *http.Request, file, path string) (*http.Response, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w for %s (%s)", ErrNoRecording, path, filepath.Base(file))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read recording: %w", err)
+	}
+
+	var rec recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("invalid recording %s: %w", filepath.Base(file), err)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(rec.Response)),
+		ContentLength: int64(len(rec.Response)),
+		Request:       req,
+	}, nil
+}
+
+// save writes a recording, replacing any earlier one of the same request.
+// It is written to a temporary file first so a concurrent replay never
+// reads half of it.
+func (r *Replayer) save(file string, rec recording) error {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode recording: %w", err)
+	}
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(r.dir, ".recording-*")
+	if err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("failed to save recording: %w", err)
+	}
+	return nil
+}
+
+// apiPath is the request's path below the API version, e.g.
+// models/gemini-2.0-flash:generateContent, so recordings don't depend on
+// the base URL they were made against
+func apiPath(req *http.Request) string {
+	path := req.URL.Path
+	if i := strings.Index(path, "/models/"); i >= 0 {
+		return path[i+1:]
+	}
+	return strings.TrimPrefix(path, "/")
+}
+
+// replayKey hashes a request into the name of its recording
+func replayKey(path string, body []byte) string {
+	sum := sha256.Sum256(append([]byte(path+"\n"), body...))
+	return hex.EncodeToString(sum[:])
+}
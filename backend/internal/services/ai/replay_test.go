@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestReplayer(t *testing.T) {
+	dir := t.TempDir()
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls > 2 {
+			http.Error(w, `{"error": {"message": "overloaded"}}`, http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{
+			"candidates": [{"content": {"parts": [{"text": "recorded"}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 7, "candidatesTokenCount": 3}
+		}`))
+	}))
+
+	ctx := context.Background()
+	recorder := NewClient(Options{APIKey: "test-key", BaseURL: server.URL, Model: "test-model", ReplayMode: ReplayRecord, ReplayDir: dir})
+	if _, err := recorder.Generate(ctx, GenerateRequest{Prompt: "hello"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if _, err := recorder.Generate(ctx, GenerateRequest{Prompt: "hello again"}); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// Failures are passed through but not recorded
+	if _, err := recorder.Generate(ctx, GenerateRequest{Prompt: "too busy"}); err == nil {
+		t.Fatal("Generate() succeeded against a failing API")
+	}
+	server.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("recorded %d responses, want 2", len(entries))
+	}
+
+	// Replay needs neither the API nor its key
+	replayer := NewClient(Options{BaseURL: "http://127.0.0.1:1", Model: "test-model", ReplayMode: ReplayReplay, ReplayDir: dir})
+	resp, err := replayer.Generate(ctx, GenerateRequest{Prompt: "hello"})
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if resp.Text != "recorded" || resp.PromptTokens != 7 || resp.OutputTokens != 3 {
+		t.Errorf("Generate() = %+v, want the recorded response", resp)
+	}
+
+	for _, req := range []GenerateRequest{{Prompt: "too busy"}, {Prompt: "hello", JSON: true}} {
+		if _, err := replayer.Generate(ctx, req); !errors.Is(err, ErrNoRecording) {
+			t.Errorf("Generate(%+v) error = %v, want ErrNoRecording", req, err)
+		}
+	}
+
+	// The model is part of the key
+	replayer.SetModel("other-model")
+	if _, err := replayer.Generate(ctx, GenerateRequest{Prompt: "hello"}); !errors.Is(err, ErrNoRecording) {
+		t.Errorf("Generate() error = %v, want ErrNoRecording for another model", err)
+	}
+}
+
+func TestApiPath(t *testing.T) {
+	for _, url := range []string{
+		"https://generativelanguage.googleapis.com/v1beta/models/test-model:generateContent",
+		"http://127.0.0.1:8080/models/test-model:generateContent",
+	} {
+		req, _ := http.NewRequest(http.MethodPost, url, nil)
+		if got := apiPath(req); got != "models/test-model:generateContent" {
+			t.Errorf("apiPath(%s) = %q", url, got)
+		}
+	}
+}
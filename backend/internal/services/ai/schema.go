@@ -0,0 +1,192 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Schema types, in the OpenAPI subset Gemini's structured output accepts
+const (
+	TypeString  = "STRING"
+	TypeNumber  = "NUMBER"
+	TypeInteger = "INTEGER"
+	TypeBoolean = "BOOLEAN"
+	TypeArray   = "ARRAY"
+	TypeObject  = "OBJECT"
+)
+
+// Schema describes the JSON a generation must respond with. It is sent as
+// the response schema when the client constrains output to it, and
+// checked by Validate either way.
+type Schema struct {
+	Type       string             `json:"type"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	// Required lists the properties a response can't be used without
+	Required []string `json:"required,omitempty"`
+	Items    *Schema  `json:"items,omitempty"`
+	// Enum lists a string's allowed values, matched ignoring case
+	Enum     []string `json:"enum,omitempty"`
+	Nullable bool     `json:"nullable,omitempty"`
+}
+
+// String returns a string schema, limited to values when any are given
+func String(values ...string) *Schema {
+	return &Schema{Type: TypeString, Enum: values}
+}
+
+// Number returns a number schema
+func Number() *Schema {
+	return &Schema{Type: TypeNumber}
+}
+
+// Integer returns an integer schema
+func Integer() *Schema {
+	return &Schema{Type: TypeInteger}
+}
+
+// Boolean returns a boolean schema
+func Boolean() *Schema {
+	return &Schema{Type: TypeBoolean}
+}
+
+// Array returns a schema for an array of items
+func Array(items *Schema) *Schema {
+	return &Schema{Type: TypeArray, Items: items}
+}
+
+// Object returns a schema for an object with properties, of which the
+// required ones must be present
+func Object(properties map[string]*Schema, required ...string) *Schema {
+	return &Schema{Type: TypeObject, Properties: properties, Required: required}
+}
+
+// SchemaError is a response that doesn't match its schema. Path locates
+// the offending value, such as "issues[2].severity".
+type SchemaError struct {
+	Path    string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// Validate checks that data is JSON matching the schema, returning a
+// *SchemaError for the first mismatch. Properties the schema doesn't
+// list are allowed.
+func (s *Schema) Validate(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return &SchemaError{Message: fmt.Sprintf("invalid JSON: %v", err)}
+	}
+	return s.validate("", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if value == nil {
+		if s.Nullable {
+			return nil
+		}
+		return &SchemaError{Path: path, Message: "must not be null"}
+	}
+
+	switch s.Type {
+	case TypeString:
+		str, ok := value.(string)
+		if !ok {
+			return mismatch(path, "a string", value)
+		}
+		if len(s.Enum) > 0 && !oneOf(strings.TrimSpace(str), s.Enum) {
+			return &SchemaError{Path: path, Message: fmt.Sprintf("must be one of: %s", strings.Join(s.Enum, ", "))}
+		}
+	case TypeNumber:
+		if _, ok := value.(float64); !ok {
+			return mismatch(path, "a number", value)
+		}
+	case TypeInteger:
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return mismatch(path, "an integer", value)
+		}
+	case TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return mismatch(path, "a boolean", value)
+		}
+	case TypeArray:
+		items, ok := value.([]interface{})
+		if !ok {
+			return mismatch(path, "an array", value)
+		}
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range items {
+			if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+				return err
+			}
+		}
+	case TypeObject:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return mismatch(path, "an object", value)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return &SchemaError{Path: join(path, name), Message: "is required"}
+			}
+		}
+		// Sorted so the same response always reports the same mismatch
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if v, ok := object[name]; ok {
+				if err := s.Properties[name].validate(join(path, name), v); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// mismatch reports a value of the wrong type
+func mismatch(path, want string, value interface{}) error {
+	var got string
+	switch value.(type) {
+	case string:
+		got = "a string"
+	case float64:
+		got = "a number"
+	case bool:
+		got = "a boolean"
+	case []interface{}:
+		got = "an array"
+	default:
+		got = "an object"
+	}
+	return &SchemaError{Path: path, Message: fmt.Sprintf("must be %s, got %s", want, got)}
+}
+
+func oneOf(value string, values []string) bool {
+	for _, v := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema := Object(map[string]*Schema{
+		"sentiment": String("positive", "neutral", "negative"),
+		"score":     Number(),
+		"issues": Array(Object(map[string]*Schema{
+			"start":    Integer(),
+			"severity": String("error", "warning"),
+		}, "severity")),
+		"note": {Type: TypeString, Nullable: true},
+	}, "sentiment")
+
+	tests := []struct {
+		name     string
+		data     string
+		valid    bool
+		wantPath string
+	}{
+		{"valid", `{"sentiment": "positive", "score": 0.5, "issues": [{"start": 3, "severity": "error"}], "note": null, "extra": true}`, true, ""},
+		{"enum ignores case and space", `{"sentiment": " Negative "}`, true, ""},
+		{"not json", `{"sentiment": `, false, ""},
+		{"missing required", `{"score": 0.5}`, false, "sentiment"},
+		{"enum", `{"sentiment": "mixed"}`, false, "sentiment"},
+		{"wrong type", `{"sentiment": "neutral", "score": "high"}`, false, "score"},
+		{"nested", `{"sentiment": "neutral", "issues": [{"severity": "error"}, {"severity": "fatal"}]}`, false, "issues[1].severity"},
+		{"integer", `{"sentiment": "neutral", "issues": [{"start": 1.5, "severity": "error"}]}`, false, "issues[0].start"},
+		{"null", `{"sentiment": null}`, false, "sentiment"},
+		{"not an object", `["positive"]`, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate([]byte(tt.data))
+			if tt.valid {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Validate() error = %v, want a *SchemaError", err)
+			}
+			if schemaErr.Path != tt.wantPath {
+				t.Errorf("Path = %q, want %q (%v)", schemaErr.Path, tt.wantPath, err)
+			}
+		})
+	}
+}
+
+func TestClient_Generate_ResponseSchema(t *testing.T) {
+	schema := Object(map[string]*Schema{"label": String()}, "label")
+
+	for _, skip := range []bool{false, true} {
+		var got *generationConfig
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body generateContentRequest
+			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+				t.Fatalf("failed to decode request: %v", err)
+			}
+			got = body.GenerationConfig
+			w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{}"}]}}]}`))
+		}))
+
+		client := NewClient(Options{BaseURL: server.URL, SkipResponseSchema: skip})
+		if _, err := client.Generate(context.Background(), GenerateRequest{Prompt: "hello", JSON: true, Schema: schema}); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		server.Close()
+
+		switch {
+		case got == nil:
+			t.Fatal("no generation config sent")
+		case skip && got.ResponseSchema != nil:
+			t.Error("response schema sent by a client that skips them")
+		case !skip && (got.ResponseSchema == nil || got.ResponseSchema.Properties["label"] == nil):
+			t.Errorf("response schema = %+v, want the request's", got.ResponseSchema)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+// Package aidetect estimates how likely a text is to be machine-generated.
+//
+// Language models tend to write sentences of more uniform length (low
+// burstiness) with more predictable word choices (low perplexity) than
+// people do. Neither those signals nor a model's own judgment is reliable
+// on its own, so Estimate combines whichever are available in log-odds
+// space and pulls the result toward 0.5 when the text is short or the
+// signals disagree. The weights are hand-tuned starting points rather
+// than fitted to a labeled corpus.
+package aidetect
+
+import (
+	"math"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/readability"
+)
+
+const (
+	// minSentences is the fewest sentences burstiness is measured over
+	minSentences = 3
+
+	// burstinessPivot is the burstiness at which the signal is neutral;
+	// generated prose usually falls below it and human prose above
+	burstinessPivot = 0.45
+	// perplexityPivot is the neutral perplexity for small reference
+	// models such as GPT-2
+	perplexityPivot = 30.0
+
+	// Weights of each signal's log-odds in the combined estimate
+	burstinessWeight = 0.5
+	perplexityWeight = 1.0
+	judgmentWeight   = 1.0
+
+	// maxLogOdds bounds each signal's contribution
+	maxLogOdds = 2.5
+
+	// Texts shorter than these word counts carry little signal
+	shortText   = 50
+	mediumText  = 150
+	reliableLen = 300
+
+	// Scores at or beyond these are labeled likely AI or likely human
+	likelyAIScore    = 0.7
+	likelyHumanScore = 0.3
+)
+
+// Measure computes the local statistical signals of text. Perplexity and
+// the model's judgment are filled in by the caller when available.
+func Measure(text string) models.AIDetectionSignals {
+	lengths := readability.SentenceLengths(text)
+
+	signals := models.AIDetectionSignals{}
+	for _, n := range lengths {
+		signals.Words += n
+	}
+
+	if len(lengths) >= minSentences {
+		mean := float64(signals.Words) / float64(len(lengths))
+		var variance float64
+		for _, n := range lengths {
+			variance += (float64(n) - mean) * (float64(n) - mean)
+		}
+		variance /= float64(len(lengths))
+		burstiness := round(math.Sqrt(variance) / mean)
+		signals.Burstiness = &burstiness
+	}
+
+	return signals
+}
+
+// Estimate combines the signals into a score with its label, confidence
+// and an explanation of what limits it. explanation is the model's
+// reasoning, passed through.
+func Estimate(signals models.AIDetectionSignals, explanation string) *models.AIDetection {
+	// The statistical signals are pooled before comparing them with the
+	// model's judgment
+	var statistical, statisticalWeight float64
+	if signals.Burstiness != nil {
+		statistical += burstinessWeight * clampLogOdds((burstinessPivot-*signals.Burstiness)*5)
+		statisticalWeight += burstinessWeight
+	}
+	if signals.Perplexity != nil && *signals.Perplexity > 0 {
+		statistical += perplexityWeight * clampLogOdds((math.Log(perplexityPivot)-math.Log(*signals.Perplexity))*1.5)
+		statisticalWeight += perplexityWeight
+	}
+
+	var judgment float64
+	if signals.ModelProbability != nil {
+		p := min(max(*signals.ModelProbability, 0.02), 0.98)
+		judgment = clampLogOdds(math.Log(p / (1 - p)))
+	}
+
+	total, weight := statistical, statisticalWeight
+	if signals.ModelProbability != nil {
+		total += judgmentWeight * judgment
+		weight += judgmentWeight
+	}
+
+	var logOdds float64
+	if weight > 0 {
+		logOdds = total / weight
+	}
+
+	// The model and the statistics pointing different ways is itself a
+	// sign the estimate is weak
+	disagree := false
+	if statisticalWeight > 0 && signals.ModelProbability != nil {
+		pooled := statistical / statisticalWeight
+		disagree = pooled*judgment < 0 && math.Abs(pooled) > 0.5 && math.Abs(judgment) > 0.5
+	}
+	if disagree {
+		logOdds *= 0.6
+	}
+	logOdds *= lengthReliability(signals.Words)
+
+	score := round(1 / (1 + math.Exp(-logOdds)))
+
+	detection := &models.AIDetection{
+		Score:       score,
+		Label:       label(score),
+		Explanation: strings.TrimSpace(explanation),
+		Signals:     signals,
+	}
+	detection.Confidence, detection.Uncertainty = assessUncertainty(signals, score, disagree)
+	return detection
+}
+
+// lengthReliability scales an estimate down for short texts
+func lengthReliability(words int) float64 {
+	switch {
+	case words < shortText:
+		return 0.3
+	case words < mediumText:
+		return 0.6
+	case words < reliableLen:
+		return 0.85
+	default:
+		return 1
+	}
+}
+
+// label names the range score falls in
+func label(score float64) models.AIDetectionLabel {
+	switch {
+	case score >= likelyAIScore:
+		return models.LikelyAI
+	case score <= likelyHumanScore:
+		return models.LikelyHuman
+	default:
+		return models.UncertainOrigin
+	}
+}
+
+// assessUncertainty rates how far the estimate can be trusted and says why
+func assessUncertainty(signals models.AIDetectionSignals, score float64, disagree bool) (models.DetectionConfidence, string) {
+	var reasons []string
+	available := 0
+
+	switch {
+	case signals.Words < shortText:
+		reasons = append(reasons, "The text is too short for a reliable estimate.")
+	case signals.Words < mediumText:
+		reasons = append(reasons, "The text is short, which weakens every signal.")
+	}
+	if signals.Burstiness != nil {
+		available++
+	} else {
+		reasons = append(reasons, "There are too few sentences to measure variation in sentence length.")
+	}
+	if signals.Perplexity != nil {
+		available++
+	} else {
+		reasons = append(reasons, "Perplexity wasn't measured.")
+	}
+	if signals.ModelProbability != nil {
+		available++
+	} else {
+		reasons = append(reasons, "The model's judgment is unavailable.")
+	}
+	if disagree {
+		reasons = append(reasons, "The statistical signals and the model's judgment point in different directions.")
+	}
+
+	if len(reasons) == 0 {
+		reasons = append(reasons, "The signals agree and the text is long enough for a stable estimate.")
+	}
+	uncertainty := strings.Join(reasons, " ")
+
+	switch {
+	case signals.Words < shortText || disagree || available < 2:
+		return models.DetectionLow, uncertainty
+	case signals.Words >= mediumText && available == 3 && math.Abs(score-0.5) >= 0.3:
+		return models.DetectionHigh, uncertainty
+	default:
+		return models.DetectionMedium, uncertainty
+	}
+}
+
+// clampLogOdds bounds a signal's contribution
+func clampLogOdds(x float64) float64 {
+	return min(max(x, -maxLogOdds), maxLogOdds)
+}
+
+// round keeps two decimals so stored scores are stable
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,98 @@
+package aidetect
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func TestMeasure(t *testing.T) {
+	uniform := Measure("The cat sat on the mat. The dog lay on the rug. The bird sat on the branch.")
+	if uniform.Words != 18 || uniform.Burstiness == nil || *uniform.Burstiness > 0.1 {
+		t.Errorf("Measure(uniform) = %+v, want low burstiness", uniform)
+	}
+
+	varied := Measure("Stop. The rain had been falling for hours over the quiet town by the sea. Why? Nobody could say.")
+	if varied.Burstiness == nil || *varied.Burstiness < 0.8 {
+		t.Errorf("Measure(varied) = %+v, want high burstiness", varied)
+	}
+
+	if short := Measure("One sentence. Two sentences."); short.Burstiness != nil {
+		t.Errorf("Measure(two sentences) burstiness = %v, want nil", *short.Burstiness)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name           string
+		signals        models.AIDetectionSignals
+		wantLabel      models.AIDetectionLabel
+		wantConfidence models.DetectionConfidence
+		wantReason     string
+	}{
+		{
+			name:           "every signal says generated",
+			signals:        models.AIDetectionSignals{Words: 400, Burstiness: ptr(0.2), Perplexity: ptr(9), ModelProbability: ptr(0.9)},
+			wantLabel:      models.LikelyAI,
+			wantConfidence: models.DetectionHigh,
+			wantReason:     "The signals agree",
+		},
+		{
+			name:           "every signal says human",
+			signals:        models.AIDetectionSignals{Words: 400, Burstiness: ptr(0.9), Perplexity: ptr(80), ModelProbability: ptr(0.1)},
+			wantLabel:      models.LikelyHuman,
+			wantConfidence: models.DetectionHigh,
+		},
+		{
+			name:           "short text is pulled toward the middle",
+			signals:        models.AIDetectionSignals{Words: 30, Burstiness: ptr(0.2), Perplexity: ptr(9), ModelProbability: ptr(0.9)},
+			wantLabel:      models.UncertainOrigin,
+			wantConfidence: models.DetectionLow,
+			wantReason:     "too short",
+		},
+		{
+			name:           "statistics and model disagree",
+			signals:        models.AIDetectionSignals{Words: 400, Burstiness: ptr(0.2), Perplexity: ptr(9), ModelProbability: ptr(0.05)},
+			wantLabel:      models.UncertainOrigin,
+			wantConfidence: models.DetectionLow,
+			wantReason:     "different directions",
+		},
+		{
+			name:           "judgment only",
+			signals:        models.AIDetectionSignals{Words: 400, ModelProbability: ptr(0.95)},
+			wantLabel:      models.LikelyAI,
+			wantConfidence: models.DetectionLow,
+			wantReason:     "Perplexity wasn't measured.",
+		},
+		{
+			name:           "no signals",
+			signals:        models.AIDetectionSignals{Words: 3},
+			wantLabel:      models.UncertainOrigin,
+			wantConfidence: models.DetectionLow,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Estimate(tt.signals, " Reasoning. ")
+
+			if got.Label != tt.wantLabel || got.Confidence != tt.wantConfidence {
+				t.Errorf("Estimate() = %s (%v) with %s confidence, want %s with %s confidence", got.Label, got.Score, got.Confidence, tt.wantLabel, tt.wantConfidence)
+			}
+			if got.Score < 0 || got.Score > 1 {
+				t.Errorf("Score = %v, want it within [0, 1]", got.Score)
+			}
+			if !strings.Contains(got.Uncertainty, tt.wantReason) {
+				t.Errorf("Uncertainty = %q, want it to mention %q", got.Uncertainty, tt.wantReason)
+			}
+			if got.Explanation != "Reasoning." {
+				t.Errorf("Explanation = %q", got.Explanation)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package aidetect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxPerplexityChars bounds the text sent for scoring
+const maxPerplexityChars = 8000
+
+// PerplexityScorer measures how predictable text is to a language model
+type PerplexityScorer interface {
+	Perplexity(ctx context.Context, text string) (float64, error)
+}
+
+// Config configures a perplexity scorer
+type Config struct {
+	// BaseURL is an OpenAI-compatible API, such as a local vLLM server,
+	// that returns prompt log probabilities with echo
+	BaseURL string
+	APIKey  string
+	Model   string
+}
+
+// completionsScorer scores text through an OpenAI-compatible completions
+// endpoint by echoing the prompt with its token log probabilities
+type completionsScorer struct {
+	baseURL    string
+	apiKey     string
+	model      string
+	httpClient *http.Client
+}
+
+// NewPerplexityScorer creates a scorer for an OpenAI-compatible API
+func NewPerplexityScorer(cfg Config) PerplexityScorer {
+	return &completionsScorer{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:     cfg.APIKey,
+		model:      cfg.Model,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// completionsResponse is the part of a completions response we use
+type completionsResponse struct {
+	Choices []struct {
+		Logprobs struct {
+			TokenLogprobs []*float64 `json:"token_logprobs"`
+		} `json:"logprobs"`
+	} `json:"choices"`
+}
+
+// Perplexity implements PerplexityScorer
+func (s *completionsScorer) Perplexity(ctx context.Context, text string) (float64, error) {
+	if len(text) > maxPerplexityChars {
+		text = text[:maxPerplexityChars]
+		// Don't cut a character in half
+		text = strings.ToValidUTF8(text, "")
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      s.model,
+		"prompt":     text,
+		"max_tokens": 0,
+		"echo":       true,
+		"logprobs":   0,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/completions", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("perplexity request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read perplexity response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("perplexity provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var out completionsResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return 0, fmt.Errorf("failed to decode perplexity response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return 0, fmt.Errorf("perplexity response has no choices")
+	}
+
+	return perplexity(out.Choices[0].Logprobs.TokenLogprobs)
+}
+
+// perplexity is the exponential of the mean negative log probability. The
+// first token has no probability and is skipped.
+func perplexity(logprobs []*float64) (float64, error) {
+	var sum float64
+	n := 0
+	for _, lp := range logprobs {
+		if lp == nil {
+			continue
+		}
+		sum += *lp
+		n++
+	}
+	if n == 0 {
+		return 0, fmt.Errorf("perplexity response has no token log probabilities")
+	}
+
+	return round(math.Exp(-sum / float64(n))), nil
+}
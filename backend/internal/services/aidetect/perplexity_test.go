@@ -0,0 +1,64 @@
+package aidetect
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCompletionsScorer_Perplexity(t *testing.T) {
+	var auth string
+	var request map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&request)
+		// ln(1/4) and ln(1/16): a mean of ln(1/8), so a perplexity of 8
+		w.Write([]byte(`{"choices": [{"logprobs": {"token_logprobs": [null, -1.3862943611198906, -2.772588722239781]}}]}`))
+	}))
+	defer server.Close()
+
+	scorer := NewPerplexityScorer(Config{BaseURL: server.URL + "/v1/", APIKey: "key", Model: "gpt2"})
+	got, err := scorer.Perplexity(context.Background(), "Hello world")
+	if err != nil {
+		t.Fatalf("Perplexity() error = %v", err)
+	}
+
+	if got != 8 {
+		t.Errorf("Perplexity() = %v, want 8", got)
+	}
+	if auth != "Bearer key" || request["model"] != "gpt2" || request["echo"] != true || request["prompt"] != "Hello world" {
+		t.Errorf("request = %v with Authorization %q", request, auth)
+	}
+}
+
+func TestCompletionsScorer_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{"provider error", http.StatusServiceUnavailable, `{"error": "loading"}`},
+		{"no choices", http.StatusOK, `{"choices": []}`},
+		{"no log probabilities", http.StatusOK, `{"choices": [{"logprobs": {"token_logprobs": [null]}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			if _, err := NewPerplexityScorer(Config{BaseURL: server.URL}).Perplexity(context.Background(), "text"); err == nil {
+				t.Error("Perplexity() error = nil")
+			}
+		})
+	}
+}
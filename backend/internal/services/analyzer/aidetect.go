@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/aidetect"
+)
+
+const aiDetectionInstruction = `You judge whether text was written by a person or generated by a language model. Respond only with JSON of the form:
+{"probability": number between 0 and 1 that the text is machine-generated, "explanation": "two or three sentences on the features behind your judgment"}
+Look for generic phrasing, uniform sentence rhythm, hedged or formulaic structure, and the absence of personal detail or idiosyncratic errors. Edited, translated or non-native writing can resemble generated text, so stay near 0.5 when the evidence is weak.`
+
+// aiDetectionSchema is the shape of an AI detection response
+var aiDetectionSchema = ai.Object(map[string]*ai.Schema{
+	"probability": ai.Number(),
+	"explanation": ai.String(),
+}, "probability")
+
+// aiDetectionTemperature keeps judgments of the same text stable
+var aiDetectionTemperature = 0.0
+
+// applyAIDetection estimates how likely the content is machine-generated
+// from its sentence rhythm, its perplexity when a scorer is configured,
+// and the model's judgment. A signal that fails to arrive widens the
+// reported uncertainty rather than failing the analysis.
+func (a *Analyzer) applyAIDetection(ctx context.Context, submission *models.Submission, analysis *models.Analysis) {
+	signals := aidetect.Measure(submission.Content)
+
+	if a.perplexity != nil {
+		perplexity, err := a.scorePerplexity(ctx, submission.Content)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Warn("Perplexity scoring failed", "submission_id", submission.ID, "error", err)
+			}
+		} else {
+			signals.Perplexity = &perplexity
+		}
+	}
+
+	var explanation string
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            submission.Content,
+		SystemInstruction: aiDetectionInstruction,
+		JSON:              true,
+		Schema:            aiDetectionSchema,
+		Temperature:       &aiDetectionTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("AI detection judgment failed", "submission_id", submission.ID, "error", err)
+		}
+	} else if probability, reason, err := parseAIJudgment(resp.Text); err != nil {
+		slog.Warn("AI detection judgment failed", "submission_id", submission.ID, "error", err)
+	} else {
+		signals.ModelProbability = &probability
+		explanation = reason
+		analysis.PromptTokens += resp.PromptTokens
+		analysis.OutputTokens += resp.OutputTokens
+	}
+
+	if ctx.Err() != nil {
+		return
+	}
+	analysis.AIDetection = aidetect.Estimate(signals, explanation)
+}
+
+// parseAIJudgment reads the model's probability, clamped to [0, 1], and
+// its explanation. A response without a probability is an error.
+func parseAIJudgment(text string) (float64, string, error) {
+	var out struct {
+		Probability *float64 `json:"probability"`
+		Explanation string   `json:"explanation"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return 0, "", fmt.Errorf("failed to parse AI detection response: %w", err)
+	}
+	if out.Probability == nil {
+		return 0, "", fmt.Errorf("AI detection response has no probability")
+	}
+
+	return min(max(*out.Probability, 0), 1), strings.TrimSpace(out.Explanation), nil
+}
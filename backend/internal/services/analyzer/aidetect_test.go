@@ -0,0 +1,87 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+// fakeScorer returns a fixed perplexity or error
+type fakeScorer struct {
+	perplexity float64
+	err        error
+}
+
+func (s fakeScorer) Perplexity(ctx context.Context, text string) (float64, error) {
+	return s.perplexity, s.err
+}
+
+func TestAnalyzer_ApplyAIDetection(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(geminiText(`{"probability": 0.85, "explanation": " Generic, evenly paced prose. "}`))
+	}))
+	defer server.Close()
+
+	content := strings.Repeat("This product offers a seamless experience for every user. ", 40)
+	submission := &models.Submission{ID: uuid.New(), Content: content}
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).WithAIDetection(true).WithPerplexity(fakeScorer{perplexity: 8})
+	analysis := &models.Analysis{}
+	a.applyAIDetection(context.Background(), submission, analysis)
+
+	got := analysis.AIDetection
+	if got == nil {
+		t.Fatal("AIDetection = nil")
+	}
+	if got.Label != models.LikelyAI || got.Explanation != "Generic, evenly paced prose." {
+		t.Errorf("AIDetection = %+v", got)
+	}
+	if got.Signals.Perplexity == nil || *got.Signals.Perplexity != 8 || got.Signals.ModelProbability == nil || got.Signals.Burstiness == nil {
+		t.Errorf("Signals = %+v, want every signal", got.Signals)
+	}
+	if analysis.PromptTokens != 10 || analysis.OutputTokens != 5 {
+		t.Errorf("tokens = %d/%d, want the judgment call added", analysis.PromptTokens, analysis.OutputTokens)
+	}
+
+	// Failed signals are left out and reported as uncertainty
+	a.WithPerplexity(fakeScorer{err: errors.New("scorer down")})
+	analysis = &models.Analysis{}
+	a.applyAIDetection(context.Background(), submission, analysis)
+	if analysis.AIDetection == nil || analysis.AIDetection.Signals.Perplexity != nil || !strings.Contains(analysis.AIDetection.Uncertainty, "Perplexity wasn't measured.") {
+		t.Errorf("AIDetection = %+v, want perplexity reported missing", analysis.AIDetection)
+	}
+}
+
+func TestParseAIJudgment(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    float64
+		wantErr bool
+	}{
+		{name: "judgment", text: `{"probability": 0.3, "explanation": "Personal detail."}`, want: 0.3},
+		{name: "clamped", text: `{"probability": 4}`, want: 1},
+		{name: "no probability", text: `{"explanation": "Unsure."}`, wantErr: true},
+		{name: "not JSON", text: `Probably human.`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := parseAIJudgment(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAIJudgment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseAIJudgment() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
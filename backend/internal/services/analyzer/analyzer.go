@@ -0,0 +1,681 @@
+package analyzer
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/aidetect"
+	"github.com/sfumato00/content-analyzer/internal/services/factcheck"
+	"github.com/sfumato00/content-analyzer/internal/services/limits"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+	"github.com/sfumato00/content-analyzer/internal/spend"
+)
+
+// JobType identifies the submission analysis job in the queue
+const JobType = "analysis.submission"
+
+// maxTopics bounds how many topics are kept from a single analysis
+const maxTopics = 5
+
+const systemInstruction = `You analyze user-submitted text. Respond only with JSON of the form:
+{"sentiment": "positive" | "neutral" | "negative", "sentiment_score": number between -1 and 1, "topics": [up to 5 short topic strings], "keyphrases": [up to 10 key phrases quoted exactly from the text], "summary": "one or two sentence summary", "false_positives": [numbers of listed sensitive data candidates that are not actually personal data or profanity]}
+The text may be followed by a numbered list of sensitive data candidates found by pattern matching. Use the surrounding text to judge each one, for example an order number is not a phone number.`
+
+// analysisSchema is the shape of an analysis response. Topics, keyphrases
+// and false positives can be left out; the parser defaults them.
+var analysisSchema = ai.Object(map[string]*ai.Schema{
+	"sentiment":       ai.String("positive", "neutral", "negative"),
+	"sentiment_score": ai.Number(),
+	"topics":          ai.Array(ai.String()),
+	"keyphrases":      ai.Array(ai.String()),
+	"summary":         ai.String(),
+	"false_positives": ai.Array(ai.Integer()),
+}, "sentiment", "summary")
+
+// ErrTimedOut is the cause of an analysis running past its deadline
+var ErrTimedOut = errors.New("analysis timed out")
+
+// Payload is the job payload for an analysis
+type Payload struct {
+	SubmissionID uuid.UUID `json:"submission_id"`
+}
+
+// CancelKey identifies a submission's analysis for queue cancellation
+func CancelKey(submissionID uuid.UUID) string {
+	return "analysis:" + submissionID.String()
+}
+
+// CancelWatcher lets a running analysis notice it was canceled
+type CancelWatcher interface {
+	WatchCancel(ctx context.Context, key string) (context.Context, context.CancelFunc)
+}
+
+// ProfileSource looks up the analysis profile a submission selected
+type ProfileSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.AnalysisProfile, error)
+}
+
+// PolicySource looks up the moderation policy in force for a submission's
+// owner and any operator override of its decision
+type PolicySource interface {
+	PolicyForUser(ctx context.Context, userID uuid.UUID) (*models.ModerationPolicy, error)
+	Override(ctx context.Context, submissionID uuid.UUID) (*models.PolicyOverride, error)
+}
+
+// GlossarySource looks up the glossary of a submission owner's
+// organizations
+type GlossarySource interface {
+	GlossaryForUser(ctx context.Context, userID uuid.UUID) (*models.Glossary, error)
+}
+
+// TaxonomySource looks up the taxonomy of a submission owner's
+// organizations
+type TaxonomySource interface {
+	TaxonomyForUser(ctx context.Context, userID uuid.UUID) (*models.Taxonomy, error)
+}
+
+// SpendChecker says whether a user's analyses are over a spend budget;
+// *spend.Guard implements it
+type SpendChecker interface {
+	Check(ctx context.Context, userID uuid.UUID) (*spend.Exceeded, error)
+}
+
+// QuotaRefunder gives back the quota an analysis was counted against;
+// *quota.Tracker implements it
+type QuotaRefunder interface {
+	Refund(ctx context.Context, chargeID uuid.UUID) error
+}
+
+// Analyzer runs the LLM analysis of a submission
+type Analyzer struct {
+	store      *models.SubmissionStore
+	client     *ai.Client
+	cancels    CancelWatcher
+	pricing    ai.Pricing
+	profiles   ProfileSource
+	searcher   factcheck.Searcher
+	perplexity aidetect.PerplexityScorer
+	policies   PolicySource
+	glossaries GlossarySource
+	taxonomies TaxonomySource
+	limits     *limits.Pool
+	spend      SpendChecker
+	quota      QuotaRefunder
+	repairs    int
+	orgs       OrgSource
+	metrics    *stageMetrics
+	timeout    time.Duration
+
+	stages       []Stage
+	stageConfigs map[string]StageConfig
+
+	verification bool
+	claims       bool
+	proofreading bool
+	bias         bool
+	aiDetection  bool
+	moderation   bool
+	artifacts    bool
+}
+
+// NewAnalyzer creates a new analyzer
+func NewAnalyzer(store *models.SubmissionStore, client *ai.Client) *Analyzer {
+	a := &Analyzer{
+		store:   store,
+		client:  client,
+		repairs: DefaultRepairs,
+	}
+	a.registerBuiltins()
+	return a
+}
+
+// WithCancelWatcher stops analyses as soon as they are canceled, rather
+// than when their result is rejected, and returns the analyzer
+func (a *Analyzer) WithCancelWatcher(cancels CancelWatcher) *Analyzer {
+	a.cancels = cancels
+	return a
+}
+
+// WithPricing records the cost of each analysis at the given model prices
+// and returns the analyzer
+func (a *Analyzer) WithPricing(pricing ai.Pricing) *Analyzer {
+	a.pricing = pricing
+	return a
+}
+
+// WithVerification runs a second, lightweight model call that scores how
+// faithful each summary is to its source, and returns the analyzer
+func (a *Analyzer) WithVerification(enabled bool) *Analyzer {
+	a.verification = enabled
+	return a
+}
+
+// WithClaims runs two more model calls that extract the factual claims
+// in each submission and assess them, and returns the analyzer
+func (a *Analyzer) WithClaims(enabled bool) *Analyzer {
+	a.claims = enabled
+	return a
+}
+
+// WithProofreading runs another model call that finds grammar, spelling
+// and style issues in each submission, and returns the analyzer
+func (a *Analyzer) WithProofreading(enabled bool) *Analyzer {
+	a.proofreading = enabled
+	return a
+}
+
+// WithBias runs another model call that reports the political or
+// ideological framing, loaded language and one-sidedness of each
+// submission, and returns the analyzer
+func (a *Analyzer) WithBias(enabled bool) *Analyzer {
+	a.bias = enabled
+	return a
+}
+
+// WithAIDetection estimates how likely each submission is to be
+// machine-generated, with another model call, and returns the analyzer
+func (a *Analyzer) WithAIDetection(enabled bool) *Analyzer {
+	a.aiDetection = enabled
+	return a
+}
+
+// WithPerplexity adds the text's perplexity under a reference language
+// model to the AI detection signals and returns the analyzer
+func (a *Analyzer) WithPerplexity(scorer aidetect.PerplexityScorer) *Analyzer {
+	a.perplexity = scorer
+	return a
+}
+
+// WithModeration scores each submission for toxicity, harassment and other
+// harmful content with another model call, and returns the analyzer
+func (a *Analyzer) WithModeration(enabled bool) *Analyzer {
+	a.moderation = enabled
+	return a
+}
+
+// WithPolicies enforces the moderation policies of submission owners'
+// organizations, and operators' overrides, on each moderated analysis and
+// returns the analyzer
+func (a *Analyzer) WithPolicies(policies PolicySource) *Analyzer {
+	a.policies = policies
+	return a
+}
+
+// WithGlossaries gives the analysis the glossary of each submission
+// owner's organizations and flags the phrases it bans, and returns the
+// analyzer
+func (a *Analyzer) WithGlossaries(glossaries GlossarySource) *Analyzer {
+	a.glossaries = glossaries
+	return a
+}
+
+// WithTaxonomies classifies each submission into the taxonomy of its
+// owner's organizations, with another model call, and returns the
+// analyzer
+func (a *Analyzer) WithTaxonomies(taxonomies TaxonomySource) *Analyzer {
+	a.taxonomies = taxonomies
+	return a
+}
+
+// WithRepairs sets how many times a model response that doesn't match its
+// schema is sent back to be fixed before the analysis fails, and returns
+// the analyzer
+func (a *Analyzer) WithRepairs(n int) *Analyzer {
+	a.repairs = max(n, 0)
+	return a
+}
+
+// WithArtifacts keeps every model call of each analysis, with its exact
+// prompt, response, tokens and latency, for operators to inspect, and
+// returns the analyzer
+func (a *Analyzer) WithArtifacts(enabled bool) *Analyzer {
+	a.artifacts = enabled
+	return a
+}
+
+// WithFactCheck looks up each extracted claim with searcher so it is
+// assessed against search results with source links, and returns the
+// analyzer. Without it claims are assessed from the model's knowledge.
+func (a *Analyzer) WithFactCheck(searcher factcheck.Searcher) *Analyzer {
+	a.searcher = searcher
+	return a
+}
+
+// WithLimits runs the analyzer's provider calls within the pool's
+// concurrency caps, sharing one call among identical requests in flight
+// together, and returns the analyzer
+func (a *Analyzer) WithLimits(pool *limits.Pool) *Analyzer {
+	a.limits = pool
+	return a
+}
+
+// WithSpendGuard holds queued analyses in the job queue while their
+// owner is over a spend budget, and returns the analyzer
+func (a *Analyzer) WithSpendGuard(spend SpendChecker) *Analyzer {
+	a.spend = spend
+	return a
+}
+
+// WithQuota refunds the analyses of submissions that fail for good and
+// returns the analyzer
+func (a *Analyzer) WithQuota(quota QuotaRefunder) *Analyzer {
+	a.quota = quota
+	return a
+}
+
+// WithTimeout bounds each analysis and returns the analyzer. Once the
+// deadline passes, the stages still to run are left out and recorded as
+// timed out, to be retried on their own; an analysis that runs out of time
+// before its main analysis is done fails the attempt instead. Zero leaves
+// analyses unbounded.
+func (a *Analyzer) WithTimeout(d time.Duration) *Analyzer {
+	a.timeout = d
+	return a
+}
+
+// WithProfiles applies the analysis profile each submission selected and
+// returns the analyzer. Without it every module runs.
+func (a *Analyzer) WithProfiles(profiles ProfileSource) *Analyzer {
+	a.profiles = profiles
+	return a
+}
+
+// Handle implements queue.Handler for the analysis job
+func (a *Analyzer) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid analysis payload: %w", err)
+	}
+
+	submission, err := a.store.Get(ctx, payload.SubmissionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted before we got to it; nothing left to do
+			slog.Warn("Submission not found for analysis", "submission_id", payload.SubmissionID)
+			return nil
+		}
+		return fmt.Errorf("failed to load submission: %w", err)
+	}
+
+	switch submission.Status {
+	case models.StatusQueued:
+		if err := a.checkSpend(ctx, submission); err != nil {
+			return err
+		}
+		if err := a.store.UpdateStatus(ctx, submission.ID, models.StatusProcessing); err != nil {
+			if errors.Is(err, models.ErrInvalidTransition) {
+				// Another worker or the user moved it first
+				slog.Warn("Submission no longer queued", "submission_id", submission.ID, "error", err)
+				return nil
+			}
+			return err
+		}
+	case models.StatusProcessing:
+		// A retry of an earlier attempt
+	default:
+		// Finished, canceled, archived or pulled back to a draft: nothing to do
+		return nil
+	}
+
+	if a.cancels != nil {
+		var stop context.CancelFunc
+		ctx, stop = a.cancels.WatchCancel(ctx, CancelKey(submission.ID))
+		defer stop()
+	}
+
+	if err := a.analyze(ctx, submission); err != nil {
+		// The user canceled: the submission has already left processing,
+		// so the result was discarded and there's nothing to retry
+		if errors.Is(context.Cause(ctx), queue.ErrCanceled) || errors.Is(err, models.ErrInvalidTransition) {
+			slog.Info("Analysis canceled", "submission_id", submission.ID)
+			return nil
+		}
+
+		// The provider turning calls away says nothing about the
+		// submission: wait for it without using up an attempt
+		if retryAfter, ok := ai.RateLimited(err); ok {
+			slog.Warn("Analysis rate limited", "submission_id", submission.ID, "retry_after", retryAfter)
+			return queue.Defer(err, retryAfter)
+		}
+
+		// The model couldn't produce usable output even when asked to fix
+		// it: the submission fails without the remaining attempts
+		if errors.Is(err, ErrMalformedOutput) {
+			slog.Error("Analysis output malformed", "submission_id", submission.ID, "error", err)
+			if err := a.store.Fail(context.Background(), submission.ID, models.FailureParseError); err != nil && !errors.Is(err, models.ErrInvalidTransition) {
+				slog.Error("Failed to mark submission failed", "submission_id", submission.ID, "error", err)
+			}
+			a.refund(submission.ID)
+			return queue.Fail(err)
+		}
+
+		// Only give up on the submission once the queue stops retrying
+		if job.Attempts+1 >= job.MaxAttempts {
+			var failErr error
+			if errors.Is(err, ErrTimedOut) {
+				failErr = a.store.Fail(context.Background(), submission.ID, models.FailureTimedOut)
+			} else {
+				failErr = a.store.UpdateStatus(context.Background(), submission.ID, models.StatusFailed)
+			}
+			if failErr != nil && !errors.Is(failErr, models.ErrInvalidTransition) {
+				slog.Error("Failed to mark submission failed", "submission_id", submission.ID, "error", failErr)
+			}
+			a.refund(submission.ID)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// refund gives back the quota of a submission that failed for good.
+// Quotas are soft, so a failure is only logged.
+func (a *Analyzer) refund(id uuid.UUID) {
+	if a.quota == nil {
+		return
+	}
+	if err := a.quota.Refund(context.Background(), id); err != nil {
+		slog.Warn("Failed to refund failed analysis", "submission_id", id, "error", err)
+	}
+}
+
+// checkSpend returns a queue.HoldError while the submission's owner is over
+// a spend budget, leaving it queued. A failed check lets it run rather
+// than holding every analysis.
+func (a *Analyzer) checkSpend(ctx context.Context, submission *models.Submission) error {
+	if a.spend == nil {
+		return nil
+	}
+	exceeded, err := a.spend.Check(ctx, submission.UserID)
+	if err != nil {
+		slog.Warn("Failed to check spend budget", "submission_id", submission.ID, "error", err)
+		return nil
+	}
+	if exceeded != nil {
+		slog.Info("Analysis held over spend budget", "submission_id", submission.ID, "scope", exceeded.Scope, "period", exceeded.Period)
+		return queue.Hold(exceeded)
+	}
+	return nil
+}
+
+// analyze takes the submission through the pipeline and stores its result
+func (a *Analyzer) analyze(ctx context.Context, submission *models.Submission) error {
+	start := time.Now()
+
+	profile, err := a.profile(ctx, submission)
+	if err != nil {
+		return err
+	}
+	glossary, err := a.glossary(ctx, submission)
+	if err != nil {
+		return err
+	}
+	taxonomy, err := a.taxonomy(ctx, submission)
+	if err != nil {
+		return err
+	}
+
+	var recorder *callRecorder
+	if a.artifacts {
+		ctx, recorder = withRecorder(ctx)
+	}
+
+	run := &Run{
+		Submission: submission,
+		Profile:    profile,
+		Glossary:   glossary,
+		Taxonomy:   taxonomy,
+		Analysis:   &models.Analysis{SubmissionID: submission.ID, Instructions: submission.Instructions},
+	}
+	stagesCtx, cancel := a.withDeadline(ctx)
+	defer cancel()
+	if err := a.runStages(stagesCtx, run); err != nil {
+		return err
+	}
+	if len(run.Analysis.TimedOut) > 0 {
+		slog.Warn("Analysis saved without timed out stages", "submission_id", submission.ID, "stages", run.Analysis.TimedOut)
+	}
+
+	analysis := run.Analysis
+	if recorder != nil {
+		analysis.Calls = recorder.Calls()
+	}
+	analysis.CostMicros = a.pricing.CostMicros(analysis.PromptTokens, analysis.OutputTokens)
+	analysis.ProcessingTimeMs = int(time.Since(start).Milliseconds())
+
+	return a.store.SaveAnalysis(ctx, analysis)
+}
+
+// withDeadline bounds the stages of an analysis by the analyzer's
+// timeout, with ErrTimedOut as the cause. The result is saved outside it.
+func (a *Analyzer) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if a.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeoutCause(ctx, a.timeout, ErrTimedOut)
+}
+
+// generate calls the model, enforcing req.Schema when it is set
+func (a *Analyzer) generate(ctx context.Context, req ai.GenerateRequest) (*ai.GenerateResponse, error) {
+	if req.Schema != nil {
+		return a.generateStructured(ctx, req)
+	}
+	return a.call(ctx, req)
+}
+
+// call calls the model within the provider limits. Each analysis sharing
+// a call records its tokens and cost as if it had made it.
+func (a *Analyzer) call(ctx context.Context, req ai.GenerateRequest) (*ai.GenerateResponse, error) {
+	start := time.Now()
+	resp, err := a.callLimited(ctx, req)
+	a.record(ctx, req, resp, err, time.Since(start))
+	return resp, err
+}
+
+func (a *Analyzer) callLimited(ctx context.Context, req ai.GenerateRequest) (*ai.GenerateResponse, error) {
+	if a.limits == nil {
+		return a.client.Generate(ctx, req)
+	}
+	return limits.Shared(ctx, a.limits, limits.ProviderGemini, requestKey(a.client.Model(), req), func(ctx context.Context) (*ai.GenerateResponse, error) {
+		return a.client.Generate(ctx, req)
+	})
+}
+
+// search looks up a claim within the provider limits
+func (a *Analyzer) search(ctx context.Context, query string) ([]factcheck.Result, error) {
+	return limits.Shared(ctx, a.limits, limits.ProviderFactCheck, query, func(ctx context.Context) ([]factcheck.Result, error) {
+		return a.searcher.Search(ctx, query)
+	})
+}
+
+// scorePerplexity scores text within the provider limits
+func (a *Analyzer) scorePerplexity(ctx context.Context, text string) (float64, error) {
+	return limits.Shared(ctx, a.limits, limits.ProviderPerplexity, requestKey("", text), func(ctx context.Context) (float64, error) {
+		return a.perplexity.Perplexity(ctx, text)
+	})
+}
+
+// requestKey hashes a model and request into the key identical calls are
+// coalesced under
+func requestKey(model string, req interface{}) string {
+	data, _ := json.Marshal(req)
+	sum := sha256.Sum256(append([]byte(model+"\x00"), data...))
+	return hex.EncodeToString(sum[:])
+}
+
+// profile loads the submission's analysis profile, or returns nil to run
+// every module when it has none
+func (a *Analyzer) profile(ctx context.Context, submission *models.Submission) (*models.AnalysisProfile, error) {
+	if submission.ProfileID == nil || a.profiles == nil {
+		return nil, nil
+	}
+
+	profile, err := a.profiles.Get(ctx, *submission.ProfileID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted since the submission was created
+			slog.Warn("Analysis profile not found", "submission_id", submission.ID, "profile_id", *submission.ProfileID)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load analysis profile: %w", err)
+	}
+	return profile, nil
+}
+
+// analysisFocus combines the profile's prompt template with the
+// submission's own instructions
+func analysisFocus(profile *models.AnalysisProfile, submission *models.Submission) string {
+	var parts []string
+	if profile != nil && profile.Prompt != "" {
+		parts = append(parts, profile.Prompt)
+	}
+	if submission.Instructions != nil {
+		parts = append(parts, *submission.Instructions)
+	}
+	return strings.Join(parts, " ")
+}
+
+// applyVerification scores the analysis' summary against the source. The
+// score is advisory, so a failed pass leaves the analysis unscored rather
+// than failing it.
+func (a *Analyzer) applyVerification(ctx context.Context, submission *models.Submission, analysis *models.Analysis) {
+	result, err := a.verify(ctx, submission.Content, analysis.Summary)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Summary verification failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	analysis.SetConfidence(&result.Confidence)
+	analysis.PromptTokens += result.PromptTokens
+	analysis.OutputTokens += result.OutputTokens
+
+	if analysis.LowConfidence {
+		slog.Info("Low confidence analysis",
+			"submission_id", submission.ID,
+			"confidence", result.Confidence,
+			"unsupported_claims", len(result.UnsupportedClaims),
+		)
+	}
+}
+
+// buildPrompt appends the sensitive data candidates to the content so the
+// model can verify them in the same call
+func buildPrompt(content string, findings []models.Finding) string {
+	if len(findings) == 0 {
+		return content
+	}
+
+	var b strings.Builder
+	b.WriteString(content)
+	b.WriteString("\n\n---\nSensitive data candidates:\n")
+	for i, f := range findings {
+		fmt.Fprintf(&b, "%d. %s: %q\n", i+1, f.Type, sensitive.Excerpt(content, f))
+	}
+	return b.String()
+}
+
+// parseFalsePositives reads the candidate numbers the model rejected. A
+// response without them rejects nothing.
+func parseFalsePositives(text string) []int {
+	var out struct {
+		FalsePositives []int `json:"false_positives"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil
+	}
+	return out.FalsePositives
+}
+
+// verifyFindings removes the rejected candidates, numbered from 1, and
+// marks the remaining findings verified
+func verifyFindings(findings []models.Finding, rejected []int) []models.Finding {
+	skip := make(map[int]bool, len(rejected))
+	for _, n := range rejected {
+		skip[n] = true
+	}
+
+	verified := make([]models.Finding, 0, len(findings))
+	for i, f := range findings {
+		if skip[i+1] {
+			continue
+		}
+		f.Verified = true
+		verified = append(verified, f)
+	}
+	return verified
+}
+
+// ParseResult converts the model's JSON response into an analysis,
+// normalizing the sentiment label and clamping the score. Keyphrases holds
+// the model's suggestions unscored; they are merged with local extraction
+// by the caller.
+func ParseResult(text string) (*models.Analysis, error) {
+	var out struct {
+		Sentiment      string   `json:"sentiment"`
+		SentimentScore *float64 `json:"sentiment_score"`
+		Topics         []string `json:"topics"`
+		Keyphrases     []string `json:"keyphrases"`
+		Summary        string   `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse analysis response: %w", err)
+	}
+
+	analysis := &models.Analysis{
+		Summary:    strings.TrimSpace(out.Summary),
+		Topics:     []string{},
+		Keyphrases: []models.Keyphrase{},
+	}
+
+	if out.SentimentScore != nil {
+		score := min(max(*out.SentimentScore, -1), 1)
+		analysis.SentimentScore = &score
+	}
+
+	switch sentiment := strings.ToLower(strings.TrimSpace(out.Sentiment)); sentiment {
+	case "positive", "neutral", "negative":
+		analysis.Sentiment = sentiment
+	default:
+		// Derive the label from the score when the model strays
+		if analysis.SentimentScore == nil {
+			return nil, fmt.Errorf("analysis response has no usable sentiment")
+		}
+		switch score := *analysis.SentimentScore; {
+		case score > 0.2:
+			analysis.Sentiment = "positive"
+		case score < -0.2:
+			analysis.Sentiment = "negative"
+		default:
+			analysis.Sentiment = "neutral"
+		}
+	}
+
+	for _, topic := range out.Topics {
+		if topic = strings.TrimSpace(topic); topic != "" && len(analysis.Topics) < maxTopics {
+			analysis.Topics = append(analysis.Topics, topic)
+		}
+	}
+
+	for _, phrase := range out.Keyphrases {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			analysis.Keyphrases = append(analysis.Keyphrases, models.Keyphrase{Phrase: phrase})
+		}
+	}
+
+	return analysis, nil
+}
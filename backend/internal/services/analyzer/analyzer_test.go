@@ -0,0 +1,172 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestParseResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		wantSentiment string
+		wantScore     *float64
+		wantTopics    []string
+		wantSummary   string
+		wantErr       bool
+	}{
+		{
+			name:          "well formed",
+			text:          `{"sentiment":"positive","sentiment_score":0.8,"topics":["go","testing"],"summary":" Great post. "}`,
+			wantSentiment: "positive",
+			wantScore:     ptr(0.8),
+			wantTopics:    []string{"go", "testing"},
+			wantSummary:   "Great post.",
+		},
+		{
+			name:          "label is normalized",
+			text:          `{"sentiment":" Negative ","sentiment_score":-0.5}`,
+			wantSentiment: "negative",
+			wantScore:     ptr(-0.5),
+			wantTopics:    []string{},
+		},
+		{
+			name:          "score is clamped",
+			text:          `{"sentiment":"positive","sentiment_score":3}`,
+			wantSentiment: "positive",
+			wantScore:     ptr(1),
+			wantTopics:    []string{},
+		},
+		{
+			name:          "label derived from score",
+			text:          `{"sentiment":"mixed","sentiment_score":0.1}`,
+			wantSentiment: "neutral",
+			wantScore:     ptr(0.1),
+			wantTopics:    []string{},
+		},
+		{
+			name:          "topics are trimmed and capped",
+			text:          `{"sentiment":"neutral","topics":["a"," ","b","c","d","e","f"]}`,
+			wantSentiment: "neutral",
+			wantTopics:    []string{"a", "b", "c", "d", "e"},
+		},
+		{
+			name:    "unknown label without score",
+			text:    `{"sentiment":"mixed"}`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			text:    `The sentiment is positive`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResult(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if got.Sentiment != tt.wantSentiment {
+				t.Errorf("ParseResult() Sentiment = %q, want %q", got.Sentiment, tt.wantSentiment)
+			}
+			if !reflect.DeepEqual(got.SentimentScore, tt.wantScore) {
+				t.Errorf("ParseResult() SentimentScore = %v, want %v", deref(got.SentimentScore), deref(tt.wantScore))
+			}
+			if !reflect.DeepEqual(got.Topics, tt.wantTopics) {
+				t.Errorf("ParseResult() Topics = %v, want %v", got.Topics, tt.wantTopics)
+			}
+			if got.Summary != tt.wantSummary {
+				t.Errorf("ParseResult() Summary = %q, want %q", got.Summary, tt.wantSummary)
+			}
+		})
+	}
+}
+
+func TestParseResult_Keyphrases(t *testing.T) {
+	got, err := ParseResult(`{"sentiment":"neutral","keyphrases":[" pricing page ","","checkout"]}`)
+	if err != nil {
+		t.Fatalf("ParseResult() error = %v", err)
+	}
+
+	want := []models.Keyphrase{{Phrase: "pricing page"}, {Phrase: "checkout"}}
+	if !reflect.DeepEqual(got.Keyphrases, want) {
+		t.Errorf("ParseResult() Keyphrases = %+v, want %+v", got.Keyphrases, want)
+	}
+}
+
+func TestBuildPrompt(t *testing.T) {
+	content := "Call 555-123-4567"
+	findings := []models.Finding{{Type: models.FindingPhone, Start: 5, End: 17}}
+
+	if got := buildPrompt(content, nil); got != content {
+		t.Errorf("buildPrompt() without findings = %q, want the content unchanged", got)
+	}
+
+	want := "Call 555-123-4567\n\n---\nSensitive data candidates:\n1. phone: \"555-123-4567\"\n"
+	if got := buildPrompt(content, findings); got != want {
+		t.Errorf("buildPrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestVerifyFindings(t *testing.T) {
+	findings := []models.Finding{
+		{Type: models.FindingEmail, Start: 0, End: 10},
+		{Type: models.FindingPhone, Start: 20, End: 32},
+		{Type: models.FindingProfanity, Start: 40, End: 44},
+	}
+
+	got := verifyFindings(findings, parseFalsePositives(`{"sentiment":"neutral","false_positives":[2, 7]}`))
+	want := []models.Finding{
+		{Type: models.FindingEmail, Start: 0, End: 10, Verified: true},
+		{Type: models.FindingProfanity, Start: 40, End: 44, Verified: true},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("verifyFindings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestAnalysisFocus(t *testing.T) {
+	legal := "focus on legal risk"
+	profile := &models.AnalysisProfile{Prompt: "Focus on obligations."}
+
+	tests := []struct {
+		name       string
+		profile    *models.AnalysisProfile
+		submission *models.Submission
+		want       string
+	}{
+		{name: "neither", profile: nil, submission: &models.Submission{}, want: ""},
+		{name: "instructions only", profile: nil, submission: &models.Submission{Instructions: &legal}, want: legal},
+		{name: "profile only", profile: profile, submission: &models.Submission{}, want: "Focus on obligations."},
+		{name: "both", profile: profile, submission: &models.Submission{Instructions: &legal}, want: "Focus on obligations. focus on legal risk"},
+		{name: "profile without prompt", profile: &models.AnalysisProfile{}, submission: &models.Submission{Instructions: &legal}, want: legal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := analysisFocus(tt.profile, tt.submission); got != tt.want {
+				t.Errorf("analysisFocus() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func ptr(f float64) *float64 {
+	return &f
+}
+
+func deref(f *float64) interface{} {
+	if f == nil {
+		return nil
+	}
+	return *f
+}
@@ -0,0 +1,68 @@
+package analyzer
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+// callRecorder collects the model calls of one analysis. Modules may call
+// the model concurrently, so it is safe for concurrent use.
+type callRecorder struct {
+	mu    sync.Mutex
+	calls []models.ModelCall
+}
+
+type recorderKey struct{}
+
+type stageKey struct{}
+
+// withRecorder returns a context whose model calls are recorded
+func withRecorder(ctx context.Context) (context.Context, *callRecorder) {
+	recorder := &callRecorder{}
+	return context.WithValue(ctx, recorderKey{}, recorder), recorder
+}
+
+// withStage returns a context whose model calls are attributed to stage
+func withStage(ctx context.Context, stage string) context.Context {
+	return context.WithValue(ctx, stageKey{}, stage)
+}
+
+// Calls returns the calls recorded so far, in the order they finished
+func (r *callRecorder) Calls() []models.ModelCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.ModelCall(nil), r.calls...)
+}
+
+// record adds a model call to the context's recorder, if it has one
+func (a *Analyzer) record(ctx context.Context, req ai.GenerateRequest, resp *ai.GenerateResponse, err error, latency time.Duration) {
+	recorder, ok := ctx.Value(recorderKey{}).(*callRecorder)
+	if !ok {
+		return
+	}
+
+	stage, _ := ctx.Value(stageKey{}).(string)
+	call := models.ModelCall{
+		Stage:             stage,
+		Model:             a.client.Model(),
+		SystemInstruction: req.SystemInstruction,
+		Prompt:            req.Prompt,
+		LatencyMs:         latency.Milliseconds(),
+	}
+	if resp != nil {
+		call.Response = resp.Text
+		call.PromptTokens = resp.PromptTokens
+		call.OutputTokens = resp.OutputTokens
+	}
+	if err != nil {
+		call.Error = err.Error()
+	}
+
+	recorder.mu.Lock()
+	recorder.calls = append(recorder.calls, call)
+	recorder.mu.Unlock()
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+func TestAnalyzer_Record(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "You fix JSON") {
+			w.Write(geminiText(`{"confidence": 0.9, "unsupported_claims": []}`))
+			return
+		}
+		w.Write(geminiText(`{"confidence": "high"}`))
+	}))
+	defer server.Close()
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL, Model: "gemini-test"}))
+	stage := NewStage("check", PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		_, err := a.generate(ctx, ai.GenerateRequest{Prompt: "Check this.", SystemInstruction: "Be strict.", JSON: true, Schema: verifySchema})
+		return err
+	})
+
+	ctx, recorder := withRecorder(context.Background())
+	if _, err := a.runStage(ctx, stage, StageConfig{}, newRun()); err != nil {
+		t.Fatalf("runStage() error = %v", err)
+	}
+
+	calls := recorder.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("recorded %d calls, want the call and its repair", len(calls))
+	}
+	first := calls[0]
+	if first.Stage != "check" || first.Model != "gemini-test" || first.Prompt != "Check this." || first.SystemInstruction != "Be strict." {
+		t.Errorf("call = %+v", first)
+	}
+	if first.Response != `{"confidence": "high"}` || first.PromptTokens != 10 || first.OutputTokens != 5 {
+		t.Errorf("call = %+v, want the response as received", first)
+	}
+	if calls[1].Stage != "check" || !strings.Contains(calls[1].Prompt, "confidence: must be a number") {
+		t.Errorf("repair = %+v", calls[1])
+	}
+
+	// Without a recorder, nothing is kept
+	if _, err := a.generate(context.Background(), ai.GenerateRequest{Prompt: "Check this.", JSON: true, Schema: verifySchema}); err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+	if len(recorder.Calls()) != 2 {
+		t.Errorf("recorded a call made outside the analysis")
+	}
+}
@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const biasInstruction = `You assess bias and framing in text for journalists and researchers. Respond only with JSON of the form:
+{"framing": "one or two sentences on the political or ideological framing, or empty if the text takes no discernible position", "one_sidedness": number between 0 and 1, "missing_perspectives": [relevant viewpoints the text leaves out], "examples": [{"kind": "framing" | "loaded_language" | "one_sided", "text": "a passage quoted exactly from the text", "explanation": "why it illustrates the bias"}]}
+A one_sidedness of 0 means every relevant side gets a fair hearing; 1 means only one side is presented. Cite up to 10 short examples, and only passages that actually occur in the text. Describe the framing neutrally without judging which position is right. Text that takes no position, such as a product review or a recipe, gets an empty framing, a low one_sidedness and no examples.`
+
+// biasSchema is the shape of a bias response
+var biasSchema = ai.Object(map[string]*ai.Schema{
+	"framing":              ai.String(),
+	"one_sidedness":        ai.Number(),
+	"missing_perspectives": ai.Array(ai.String()),
+	"examples": ai.Array(ai.Object(map[string]*ai.Schema{
+		"kind":        ai.String("framing", "loaded_language", "one_sided"),
+		"text":        ai.String(),
+		"explanation": ai.String(),
+	}, "kind", "text")),
+}, "one_sidedness")
+
+const (
+	// maxBiasExamples bounds the cited examples in a bias report
+	maxBiasExamples = 10
+	// maxMissingPerspectives bounds the missing perspectives listed
+	maxMissingPerspectives = 5
+)
+
+// biasTemperature keeps bias reports of the same text stable
+var biasTemperature = 0.0
+
+// applyBias assesses the content's framing, loaded language and
+// one-sidedness. Like verification it is advisory, so a failed pass
+// leaves the analysis without a report rather than failing it.
+func (a *Analyzer) applyBias(ctx context.Context, submission *models.Submission, analysis *models.Analysis) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            submission.Content,
+		SystemInstruction: biasInstruction,
+		JSON:              true,
+		Schema:            biasSchema,
+		Temperature:       &biasTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Bias analysis failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	report, err := parseBias(submission.Content, resp.Text)
+	if err != nil {
+		slog.Warn("Bias analysis failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+
+	analysis.Bias = report
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+}
+
+// parseBias reads the bias response, clamping the one-sidedness score to
+// [0, 1] and locating each example in content. Examples that don't quote
+// the content or have an unknown kind are dropped. A response without a
+// score is an error.
+func parseBias(content, text string) (*models.BiasReport, error) {
+	var out struct {
+		Framing             string   `json:"framing"`
+		OneSidedness        *float64 `json:"one_sidedness"`
+		MissingPerspectives []string `json:"missing_perspectives"`
+		Examples            []struct {
+			Kind        string `json:"kind"`
+			Text        string `json:"text"`
+			Explanation string `json:"explanation"`
+		} `json:"examples"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse bias response: %w", err)
+	}
+	if out.OneSidedness == nil {
+		return nil, fmt.Errorf("bias response has no one_sidedness")
+	}
+
+	report := &models.BiasReport{
+		Framing:             strings.TrimSpace(out.Framing),
+		OneSidedness:        min(max(*out.OneSidedness, 0), 1),
+		MissingPerspectives: []string{},
+		Examples:            []models.BiasExample{},
+	}
+
+	for _, perspective := range out.MissingPerspectives {
+		if perspective = strings.TrimSpace(perspective); perspective != "" && len(report.MissingPerspectives) < maxMissingPerspectives {
+			report.MissingPerspectives = append(report.MissingPerspectives, perspective)
+		}
+	}
+
+	quotes := quoteFinder{content: content}
+	for _, example := range out.Examples {
+		if len(report.Examples) == maxBiasExamples {
+			break
+		}
+
+		kind := models.BiasExampleKind(strings.ToLower(strings.TrimSpace(example.Kind)))
+		switch kind {
+		case models.BiasFraming, models.BiasLoadedLanguage, models.BiasOneSided:
+		default:
+			continue
+		}
+
+		quoted := strings.TrimSpace(example.Text)
+		start, end, ok := quotes.find(quoted)
+		if !ok {
+			continue
+		}
+
+		report.Examples = append(report.Examples, models.BiasExample{
+			Kind:        kind,
+			Text:        quoted,
+			Start:       start,
+			End:         end,
+			Explanation: strings.TrimSpace(example.Explanation),
+		})
+	}
+
+	return report, nil
+}
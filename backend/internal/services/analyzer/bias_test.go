@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestParseBias(t *testing.T) {
+	content := "The reckless bill, rammed through by career politicians, will ruin families."
+
+	got, err := parseBias(content, `{
+		"framing": " Frames the bill as an elite imposition on ordinary people. ",
+		"one_sidedness": 1.4,
+		"missing_perspectives": ["Supporters of the bill", " "],
+		"examples": [
+			{"kind": "Loaded_Language", "text": "reckless", "explanation": "Pejorative adjective."},
+			{"kind": "loaded_language", "text": "rammed through", "explanation": "Implies an improper process."},
+			{"kind": "framing", "text": "ordinary taxpayers", "explanation": "Not in the text."},
+			{"kind": "tone", "text": "ruin families", "explanation": "Unknown kind."},
+			{"kind": "one_sided", "text": "will ruin families", "explanation": "Asserted without evidence."}
+		]
+	}`)
+	if err != nil {
+		t.Fatalf("parseBias() error = %v", err)
+	}
+
+	if got.Framing != "Frames the bill as an elite imposition on ordinary people." || got.OneSidedness != 1 {
+		t.Errorf("parseBias() = %+v", got)
+	}
+	if len(got.MissingPerspectives) != 1 {
+		t.Errorf("MissingPerspectives = %q, want the blank one dropped", got.MissingPerspectives)
+	}
+
+	want := []models.BiasExample{
+		{Kind: models.BiasLoadedLanguage, Text: "reckless", Start: 4, End: 12, Explanation: "Pejorative adjective."},
+		{Kind: models.BiasLoadedLanguage, Text: "rammed through", Start: 19, End: 33, Explanation: "Implies an improper process."},
+		{Kind: models.BiasOneSided, Text: "will ruin families", Start: 57, End: 75, Explanation: "Asserted without evidence."},
+	}
+	if len(got.Examples) != len(want) {
+		t.Fatalf("Examples = %+v, want %+v", got.Examples, want)
+	}
+	for i := range want {
+		if got.Examples[i] != want[i] {
+			t.Errorf("example %d = %+v, want %+v", i, got.Examples[i], want[i])
+		}
+	}
+}
+
+func TestParseBias_Neutral(t *testing.T) {
+	got, err := parseBias("Whisk the eggs.", `{"framing": "", "one_sidedness": 0}`)
+	if err != nil {
+		t.Fatalf("parseBias() error = %v", err)
+	}
+	if got.Framing != "" || got.OneSidedness != 0 || got.Examples == nil || got.MissingPerspectives == nil {
+		t.Errorf("parseBias() = %#v, want an empty report", got)
+	}
+}
+
+func TestParseBias_Invalid(t *testing.T) {
+	for _, text := range []string{`{"framing": "Partisan."}`, `Very biased.`} {
+		if _, err := parseBias("text", text); err == nil {
+			t.Errorf("parseBias(%q) error = nil", text)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/factcheck"
+)
+
+const extractClaimsInstruction = `You find the factual claims a text makes. Respond only with JSON of the form:
+{"claims": [up to 8 factual claims, each restated so it can be understood on its own]}
+Only include statements that could be checked against other sources, such as figures, dates, events and attributions. Leave out opinions, predictions and advice. Respond with an empty list if there are none.`
+
+const assessClaimsInstruction = `You fact-check claims. Each claim is numbered and followed by any search results found for it. Respond only with JSON of the form:
+{"claims": [{"claim": claim number, "assessment": "supported" | "contradicted" | "disputed" | "unverified", "explanation": "one sentence", "sources": [numbers of the search results the assessment relies on]}]}
+Use "disputed" when credible sources disagree and "unverified" when the evidence is insufficient. When a claim has no search results, rely only on well-established knowledge and prefer "unverified" when unsure.`
+
+// extractClaimsSchema and assessClaimsSchema are the shapes of the two
+// claims responses
+var (
+	extractClaimsSchema = ai.Object(map[string]*ai.Schema{
+		"claims": ai.Array(ai.String()),
+	}, "claims")
+	assessClaimsSchema = ai.Object(map[string]*ai.Schema{
+		"claims": ai.Array(ai.Object(map[string]*ai.Schema{
+			"claim":       ai.Integer(),
+			"assessment":  ai.String("supported", "contradicted", "disputed", "unverified"),
+			"explanation": ai.String(),
+			"sources":     ai.Array(ai.Integer()),
+		}, "claim", "assessment")),
+	}, "claims")
+)
+
+// maxClaims bounds how many claims are checked per analysis
+const maxClaims = 8
+
+// claimsTemperature keeps fact-checks of the same content stable
+var claimsTemperature = 0.0
+
+// applyClaims extracts the content's factual claims and assesses them.
+// Like verification it is advisory, so a failed pass leaves the analysis
+// without claims rather than failing it.
+func (a *Analyzer) applyClaims(ctx context.Context, submission *models.Submission, analysis *models.Analysis) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            submission.Content,
+		SystemInstruction: extractClaimsInstruction,
+		JSON:              true,
+		Schema:            extractClaimsSchema,
+		Temperature:       &claimsTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Claim extraction failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	texts, err := parseClaims(resp.Text)
+	if err != nil {
+		slog.Warn("Claim extraction failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+
+	if len(texts) == 0 {
+		analysis.Claims = []models.Claim{}
+		return
+	}
+
+	evidence := a.searchClaims(ctx, submission, texts)
+
+	resp, err = a.generate(ctx, ai.GenerateRequest{
+		Prompt:            buildAssessPrompt(texts, evidence),
+		SystemInstruction: assessClaimsInstruction,
+		JSON:              true,
+		Schema:            assessClaimsSchema,
+		Temperature:       &claimsTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Claim assessment failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	claims, err := parseAssessments(resp.Text, texts, evidence)
+	if err != nil {
+		slog.Warn("Claim assessment failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+
+	analysis.Claims = claims
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+}
+
+// searchClaims looks up each claim with the fact-check searcher, if one is
+// configured. A failed search leaves that claim without evidence.
+func (a *Analyzer) searchClaims(ctx context.Context, submission *models.Submission, claims []string) [][]factcheck.Result {
+	evidence := make([][]factcheck.Result, len(claims))
+	if a.searcher == nil {
+		return evidence
+	}
+
+	var wg sync.WaitGroup
+	for i, claim := range claims {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := a.search(ctx, claim)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Warn("Claim search failed", "submission_id", submission.ID, "error", err)
+				}
+				return
+			}
+			evidence[i] = results
+		}()
+	}
+	wg.Wait()
+
+	return evidence
+}
+
+// parseClaims reads the extraction response, dropping blank claims and
+// keeping at most maxClaims
+func parseClaims(text string) ([]string, error) {
+	var out struct {
+		Claims []string `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse claims response: %w", err)
+	}
+
+	claims := []string{}
+	for _, claim := range out.Claims {
+		if claim = strings.TrimSpace(claim); claim != "" && len(claims) < maxClaims {
+			claims = append(claims, claim)
+		}
+	}
+	return claims, nil
+}
+
+// buildAssessPrompt numbers the claims, each followed by its numbered
+// search results
+func buildAssessPrompt(claims []string, evidence [][]factcheck.Result) string {
+	var b strings.Builder
+	for i, claim := range claims {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "Claim %d: %s\n", i+1, claim)
+		if len(evidence[i]) == 0 {
+			b.WriteString("No search results.\n")
+			continue
+		}
+		for j, r := range evidence[i] {
+			fmt.Fprintf(&b, "  [%d] %s (%s): %s\n", j+1, r.Title, r.URL, r.Snippet)
+		}
+	}
+	return b.String()
+}
+
+// parseAssessments reads the assessment response into claims. Sources are
+// taken from the search results the model cited, never from URLs it
+// wrote itself, and claims it skipped or assessed with an unknown label
+// are unverified.
+func parseAssessments(text string, claims []string, evidence [][]factcheck.Result) ([]models.Claim, error) {
+	var out struct {
+		Claims []struct {
+			Claim       int    `json:"claim"`
+			Assessment  string `json:"assessment"`
+			Explanation string `json:"explanation"`
+			Sources     []int  `json:"sources"`
+		} `json:"claims"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse assessment response: %w", err)
+	}
+
+	result := make([]models.Claim, len(claims))
+	for i, claim := range claims {
+		result[i] = models.Claim{Text: claim, Assessment: models.ClaimUnverified, Sources: []models.ClaimSource{}}
+	}
+
+	for _, assessed := range out.Claims {
+		i := assessed.Claim - 1
+		if i < 0 || i >= len(claims) {
+			continue
+		}
+
+		switch assessment := models.ClaimAssessment(strings.ToLower(strings.TrimSpace(assessed.Assessment))); assessment {
+		case models.ClaimSupported, models.ClaimContradicted, models.ClaimDisputed, models.ClaimUnverified:
+			result[i].Assessment = assessment
+		}
+		result[i].Explanation = strings.TrimSpace(assessed.Explanation)
+
+		result[i].Sources = []models.ClaimSource{}
+		cited := make(map[int]bool, len(assessed.Sources))
+		for _, n := range assessed.Sources {
+			if n < 1 || n > len(evidence[i]) || cited[n] {
+				continue
+			}
+			cited[n] = true
+			r := evidence[i][n-1]
+			result[i].Sources = append(result[i].Sources, models.ClaimSource{Title: r.Title, URL: r.URL})
+		}
+	}
+
+	return result, nil
+}
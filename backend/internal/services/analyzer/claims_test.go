@@ -0,0 +1,158 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/factcheck"
+)
+
+// fakeSearcher returns canned results, failing for the queries in fail
+type fakeSearcher struct {
+	results map[string][]factcheck.Result
+	fail    map[string]bool
+}
+
+func (s fakeSearcher) Search(ctx context.Context, query string) ([]factcheck.Result, error) {
+	if s.fail[query] {
+		return nil, errors.New("search unavailable")
+	}
+	return s.results[query], nil
+}
+
+// geminiText wraps text in a Gemini generateContent response
+func geminiText(text string) []byte {
+	body, _ := json.Marshal(map[string]interface{}{
+		"candidates":    []interface{}{map[string]interface{}{"content": map[string]interface{}{"parts": []interface{}{map[string]string{"text": text}}}}},
+		"usageMetadata": map[string]int{"promptTokenCount": 10, "candidatesTokenCount": 5},
+	})
+	return body
+}
+
+func TestAnalyzer_ApplyClaims(t *testing.T) {
+	var assessPrompt string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "You find the factual claims") {
+			w.Write(geminiText(`{"claims": ["The Eiffel Tower was completed in 1889.", " ", "Paris is the capital of Spain."]}`))
+			return
+		}
+		assessPrompt = string(body)
+		w.Write(geminiText(`{"claims": [
+			{"claim": 1, "assessment": "Supported", "explanation": "Sources agree.", "sources": [1, 1, 7]},
+			{"claim": 2, "assessment": "contradicted", "explanation": "Madrid is.", "sources": [1]},
+			{"claim": 9, "assessment": "supported"}
+		]}`))
+	}))
+	defer server.Close()
+
+	searcher := fakeSearcher{
+		results: map[string][]factcheck.Result{
+			"The Eiffel Tower was completed in 1889.": {{Title: "Eiffel Tower", URL: "https://example.com/eiffel", Snippet: "Completed in 1889."}},
+		},
+		fail: map[string]bool{"Paris is the capital of Spain.": true},
+	}
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).WithClaims(true).WithFactCheck(searcher)
+
+	analysis := &models.Analysis{PromptTokens: 100, OutputTokens: 50}
+	a.applyClaims(context.Background(), &models.Submission{ID: uuid.New(), Content: "..."}, analysis)
+
+	if len(analysis.Claims) != 2 {
+		t.Fatalf("Claims = %+v, want 2", analysis.Claims)
+	}
+
+	first := analysis.Claims[0]
+	if first.Assessment != models.ClaimSupported || first.Explanation != "Sources agree." {
+		t.Errorf("first claim = %+v", first)
+	}
+	if len(first.Sources) != 1 || first.Sources[0].URL != "https://example.com/eiffel" {
+		t.Errorf("first claim sources = %+v, want the cited result once", first.Sources)
+	}
+
+	// The search failed, so there was nothing to cite
+	second := analysis.Claims[1]
+	if second.Assessment != models.ClaimContradicted || len(second.Sources) != 0 {
+		t.Errorf("second claim = %+v", second)
+	}
+	if !strings.Contains(assessPrompt, "No search results.") {
+		t.Error("assessment prompt doesn't say the second claim has no results")
+	}
+
+	if analysis.PromptTokens != 120 || analysis.OutputTokens != 60 {
+		t.Errorf("tokens = %d/%d, want both calls added", analysis.PromptTokens, analysis.OutputTokens)
+	}
+}
+
+func TestAnalyzer_ApplyClaims_NoClaims(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write(geminiText(`{"claims": []}`))
+	}))
+	defer server.Close()
+
+	analysis := &models.Analysis{}
+	NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).applyClaims(context.Background(), &models.Submission{ID: uuid.New(), Content: "What a lovely day."}, analysis)
+
+	if analysis.Claims == nil || len(analysis.Claims) != 0 {
+		t.Errorf("Claims = %#v, want an empty list", analysis.Claims)
+	}
+	if calls != 1 {
+		t.Errorf("made %d model calls, want no assessment call", calls)
+	}
+}
+
+func TestAnalyzer_ApplyClaims_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(geminiText(`not json`))
+	}))
+	defer server.Close()
+
+	analysis := &models.Analysis{}
+	NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).applyClaims(context.Background(), &models.Submission{ID: uuid.New(), Content: "..."}, analysis)
+
+	if analysis.Claims != nil {
+		t.Errorf("Claims = %+v, want nil after a failed pass", analysis.Claims)
+	}
+}
+
+func TestParseClaims(t *testing.T) {
+	got, err := parseClaims(`{"claims": ["a", "", " b ", "c", "d", "e", "f", "g", "h", "i"]}`)
+	if err != nil {
+		t.Fatalf("parseClaims() error = %v", err)
+	}
+	if len(got) != maxClaims || got[1] != "b" {
+		t.Errorf("parseClaims() = %q, want %d trimmed claims", got, maxClaims)
+	}
+
+	if _, err := parseClaims(`No claims here.`); err == nil {
+		t.Error("parseClaims() error = nil for a non-JSON response")
+	}
+}
+
+func TestParseAssessments(t *testing.T) {
+	claims := []string{"One.", "Two."}
+	evidence := [][]factcheck.Result{nil, {{Title: "T", URL: "https://example.com/two"}}}
+
+	got, err := parseAssessments(`{"claims": [{"claim": 2, "assessment": "probably", "sources": [1]}]}`, claims, evidence)
+	if err != nil {
+		t.Fatalf("parseAssessments() error = %v", err)
+	}
+
+	if got[0].Assessment != models.ClaimUnverified || got[0].Sources == nil {
+		t.Errorf("unassessed claim = %+v, want unverified with no sources", got[0])
+	}
+	if got[1].Assessment != models.ClaimUnverified || len(got[1].Sources) != 1 {
+		t.Errorf("unknown label = %+v, want unverified with its cited source", got[1])
+	}
+}
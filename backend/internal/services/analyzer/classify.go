@@ -0,0 +1,133 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const classifyInstruction = `You classify text into the labels of a taxonomy. Respond only with JSON of the form:
+{"labels": [{"label": "a label name exactly as listed", "confidence": number between 0 and 1}]}
+List every label that applies, most confident first, with how confident you are that it applies. A text can have several labels or none; respond with an empty list if no label fits. Use only the labels listed below.`
+
+// classifySchema is the shape of a classification response
+var classifySchema = ai.Object(map[string]*ai.Schema{
+	"labels": ai.Array(ai.Object(map[string]*ai.Schema{
+		"label":      ai.String(),
+		"confidence": ai.Number(),
+	}, "label")),
+}, "labels")
+
+// maxLabels bounds how many labels are kept per analysis
+const maxLabels = 5
+
+// classifyTemperature keeps the labels of the same text stable
+var classifyTemperature = 0.0
+
+// taxonomy loads the taxonomy of the submission owner's organizations, or
+// returns nil when they have none
+func (a *Analyzer) taxonomy(ctx context.Context, submission *models.Submission) (*models.Taxonomy, error) {
+	if a.taxonomies == nil {
+		return nil, nil
+	}
+	taxonomy, err := a.taxonomies.TaxonomyForUser(ctx, submission.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load taxonomy: %w", err)
+	}
+	return taxonomy, nil
+}
+
+// applyClassification labels the content with the taxonomy of the owner's
+// organizations. Like verification it is advisory, so a failed pass
+// leaves the analysis without labels rather than failing it.
+func (a *Analyzer) applyClassification(ctx context.Context, submission *models.Submission, analysis *models.Analysis, taxonomy *models.Taxonomy) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            submission.Content,
+		SystemInstruction: classificationPrompt(taxonomy),
+		JSON:              true,
+		Schema:            classifySchema,
+		Temperature:       &classifyTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Classification failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	labels, err := parseLabels(taxonomy, resp.Text)
+	if err != nil {
+		slog.Warn("Classification failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+
+	analysis.Labels = labels
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+}
+
+// classificationPrompt lists the taxonomy's labels after the instruction,
+// each quoted as a JSON string so they are read as data
+func classificationPrompt(taxonomy *models.Taxonomy) string {
+	var b strings.Builder
+	b.WriteString(classifyInstruction)
+	b.WriteString("\nThe labels, each quoted as a JSON string with any description of what belongs under it:")
+	for _, l := range taxonomy.Labels {
+		b.WriteString("\n- " + strconv.Quote(l.Name))
+		if l.Description != "" {
+			b.WriteString(": " + strconv.Quote(l.Description))
+		}
+	}
+	return b.String()
+}
+
+// parseLabels reads the classification response, keeping the labels that
+// are in the taxonomy under their listed name with the confidence clamped
+// to [0, 1]. Each label is kept once, and the rest are ordered by
+// confidence.
+func parseLabels(taxonomy *models.Taxonomy, text string) ([]models.Classification, error) {
+	var out struct {
+		Labels []struct {
+			Label      string  `json:"label"`
+			Confidence float64 `json:"confidence"`
+		} `json:"labels"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse classification response: %w", err)
+	}
+
+	names := make(map[string]string, len(taxonomy.Labels))
+	for _, l := range taxonomy.Labels {
+		names[strings.ToLower(l.Name)] = l.Name
+	}
+
+	labels := []models.Classification{}
+	for _, raw := range out.Labels {
+		name, ok := names[strings.ToLower(strings.TrimSpace(raw.Label))]
+		if !ok || slices.ContainsFunc(labels, func(c models.Classification) bool { return c.Label == name }) {
+			continue
+		}
+		labels = append(labels, models.Classification{Label: name, Confidence: min(max(raw.Confidence, 0), 1)})
+	}
+
+	slices.SortStableFunc(labels, func(a, b models.Classification) int {
+		switch {
+		case a.Confidence > b.Confidence:
+			return -1
+		case a.Confidence < b.Confidence:
+			return 1
+		}
+		return 0
+	})
+	if len(labels) > maxLabels {
+		labels = labels[:maxLabels]
+	}
+	return labels, nil
+}
@@ -0,0 +1,64 @@
+package analyzer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+var testTaxonomy = &models.Taxonomy{Labels: []models.TaxonomyLabel{
+	{Name: "Bug report", Description: "Something doesn't work as documented"},
+	{Name: "Feature request"},
+	{Name: "Complaint"},
+}}
+
+func TestParseLabels(t *testing.T) {
+	got, err := parseLabels(testTaxonomy, `{"labels": [
+		{"label": "complaint", "confidence": 0.6},
+		{"label": " Bug report ", "confidence": 1.3},
+		{"label": "Praise", "confidence": 0.9},
+		{"label": "Complaint", "confidence": 0.2}
+	]}`)
+	if err != nil {
+		t.Fatalf("parseLabels() error = %v", err)
+	}
+
+	want := []models.Classification{
+		{Label: "Bug report", Confidence: 1},
+		{Label: "Complaint", Confidence: 0.6},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseLabels() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseLabels_None(t *testing.T) {
+	got, err := parseLabels(testTaxonomy, `{"labels": []}`)
+	if err != nil {
+		t.Fatalf("parseLabels() error = %v", err)
+	}
+	if got == nil || len(got) != 0 {
+		t.Errorf("parseLabels() = %#v, want an empty list", got)
+	}
+}
+
+func TestParseLabels_Invalid(t *testing.T) {
+	if _, err := parseLabels(testTaxonomy, `labels: bug`); err == nil {
+		t.Error("parseLabels() error = nil, want an error")
+	}
+}
+
+func TestClassificationPrompt(t *testing.T) {
+	prompt := classificationPrompt(&models.Taxonomy{Labels: []models.TaxonomyLabel{
+		{Name: `Say "hi"`},
+		{Name: "Complaint", Description: "Unhappy\nwith us"},
+	}})
+
+	for _, want := range []string{classifyInstruction, `- "Say \"hi\""`, `- "Complaint": "Unhappy\nwith us"`} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("classificationPrompt() = %q, want it to contain %q", prompt, want)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const compareInstruction = `You compare two versions of the same document. Respond only with JSON of the form:
+{"tone": "one sentence on how the tone changed, or that it did not", "claims_added": [factual claims the revised version makes that the previous version does not], "claims_removed": [factual claims of the previous version that the revised version no longer makes]}
+State each claim briefly in your own words. A claim that was only reworded has not changed.`
+
+// compareSchema is the shape of a comparison response
+var compareSchema = ai.Object(map[string]*ai.Schema{
+	"tone":           ai.String(),
+	"claims_added":   ai.Array(ai.String()),
+	"claims_removed": ai.Array(ai.String()),
+}, "tone")
+
+// maxClaimChanges bounds how many added and removed claims are kept
+const maxClaimChanges = 10
+
+// compareTemperature keeps comparisons of the same revisions stable
+var compareTemperature = 0.0
+
+// applyComparison records how a revision changed from the previous one.
+// Like verification it is advisory, so a failed pass leaves the analysis
+// without changes rather than failing it.
+func (a *Analyzer) applyComparison(ctx context.Context, submission *models.Submission, analysis *models.Analysis) {
+	previous, err := a.store.Get(ctx, *submission.PreviousID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil {
+			slog.Warn("Failed to load previous revision", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            buildComparePrompt(previous.Content, submission.Content),
+		SystemInstruction: compareInstruction,
+		JSON:              true,
+		Schema:            compareSchema,
+		Temperature:       &compareTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Revision comparison failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	changes, err := parseComparison(resp.Text)
+	if err != nil {
+		slog.Warn("Revision comparison failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+
+	analysis.Changes = changes
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+}
+
+// buildComparePrompt puts the previous and revised versions side by side
+func buildComparePrompt(previous, revised string) string {
+	var b strings.Builder
+	b.WriteString("Previous version:\n")
+	b.WriteString(previous)
+	b.WriteString("\n\n---\nRevised version:\n")
+	b.WriteString(revised)
+	return b.String()
+}
+
+// parseComparison reads the comparison response, dropping blank claims
+// and keeping at most maxClaimChanges of each kind
+func parseComparison(text string) (*models.RevisionChanges, error) {
+	var out struct {
+		Tone          string   `json:"tone"`
+		ClaimsAdded   []string `json:"claims_added"`
+		ClaimsRemoved []string `json:"claims_removed"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse comparison response: %w", err)
+	}
+
+	return &models.RevisionChanges{
+		Tone:          strings.TrimSpace(out.Tone),
+		ClaimsAdded:   cleanClaims(out.ClaimsAdded),
+		ClaimsRemoved: cleanClaims(out.ClaimsRemoved),
+	}, nil
+}
+
+// cleanClaims trims claims and drops blank ones
+func cleanClaims(claims []string) []string {
+	cleaned := []string{}
+	for _, claim := range claims {
+		if claim = strings.TrimSpace(claim); claim != "" && len(cleaned) < maxClaimChanges {
+			cleaned = append(cleaned, claim)
+		}
+	}
+	return cleaned
+}
@@ -0,0 +1,53 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseComparison(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		wantTone    string
+		wantAdded   int
+		wantRemoved int
+		wantErr     bool
+	}{
+		{
+			name:        "changes",
+			text:        `{"tone": "More cautious.", "claims_added": ["Sales fell in May."], "claims_removed": ["Sales rose.", "Costs fell."]}`,
+			wantTone:    "More cautious.",
+			wantAdded:   1,
+			wantRemoved: 2,
+		},
+		{name: "blank claims dropped", text: `{"tone": " Unchanged. ", "claims_added": ["  ", ""]}`, wantTone: "Unchanged."},
+		{name: "capped", text: `{"claims_added": [` + strings.TrimSuffix(strings.Repeat(`"a claim",`, 15), ",") + `]}`, wantAdded: maxClaimChanges},
+		{name: "not JSON", text: `The tone is warmer.`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseComparison(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseComparison() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Tone != tt.wantTone || len(got.ClaimsAdded) != tt.wantAdded || len(got.ClaimsRemoved) != tt.wantRemoved {
+				t.Errorf("parseComparison() = %+v", got)
+			}
+		})
+	}
+}
+
+func TestBuildComparePrompt(t *testing.T) {
+	got := buildComparePrompt("Sales rose in April.", "Sales fell in May.")
+
+	previous := strings.Index(got, "Sales rose in April.")
+	revised := strings.Index(got, "Sales fell in May.")
+	if previous < 0 || revised < 0 || previous > revised {
+		t.Errorf("buildComparePrompt() = %q, want the previous version followed by the revised one", got)
+	}
+}
@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"context"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+)
+
+// Typical response sizes of each model call, in tokens. Responses are
+// short JSON documents whose size barely depends on the content's length.
+const (
+	moderationOutputTokens  = 80
+	analysisOutputTokens    = 250
+	verifyOutputTokens      = 60
+	extractOutputTokens     = 300
+	assessOutputTokens      = 400
+	proofreadOutputTokens   = 400
+	biasOutputTokens        = 250
+	aiDetectionOutputTokens = 80
+	classifyOutputTokens    = 80
+)
+
+// What the calls after the main analysis are sent besides the text: its
+// summary, and the claims extracted with the search evidence for each
+const (
+	summaryTokens          = 50
+	estimatedClaims        = 5
+	evidencePerClaimTokens = 100
+)
+
+// Latency model for a model call: a fixed overhead, plus reading the
+// prompt and writing the response at the model's usual rates
+const (
+	callOverhead = 400 * time.Millisecond
+	// inputTokensPerSecond and outputTokensPerSecond are the model's
+	// throughput reading a prompt and writing a response
+	inputTokensPerSecond  = 5000
+	outputTokensPerSecond = 80
+)
+
+// Estimate is what analyzing a text would take, worked out without
+// calling the model
+type Estimate struct {
+	Model string `json:"model"`
+	// ContentTokens is the text's length in tokens. Every model call sends
+	// the whole text; it is never split into chunks.
+	ContentTokens int             `json:"content_tokens"`
+	Calls         []EstimatedCall `json:"calls"`
+	PromptTokens  int             `json:"prompt_tokens"`
+	OutputTokens  int             `json:"output_tokens"`
+	CostMicros    int64           `json:"cost_micros"`
+	// LatencyMs is how long the calls take one after another, as the
+	// pipeline makes them
+	LatencyMs int `json:"latency_ms"`
+}
+
+// EstimatedCall is one model call an analysis would make
+type EstimatedCall struct {
+	Stage        string `json:"stage"`
+	PromptTokens int    `json:"prompt_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	LatencyMs    int    `json:"latency_ms"`
+}
+
+// Estimate works out the model calls analyzing submission with profile
+// would make, with their tokens, cost and latency, without making them.
+// It follows the same options, profile modules and stage configuration as
+// an analysis, and the owner's glossary and taxonomy. Token counts are
+// approximations; stages organizations added, and comparing a revision
+// with its previous version, aren't counted.
+func (a *Analyzer) Estimate(ctx context.Context, submission *models.Submission, profile *models.AnalysisProfile) (*Estimate, error) {
+	glossary, err := a.glossary(ctx, submission)
+	if err != nil {
+		return nil, err
+	}
+	taxonomy, err := a.taxonomy(ctx, submission)
+	if err != nil {
+		return nil, err
+	}
+
+	content := textstats.EstimateTokens(submission.Content)
+	estimate := &Estimate{ContentTokens: content, Calls: []EstimatedCall{}}
+	if a.client != nil {
+		estimate.Model = a.client.Model()
+	}
+
+	add := func(stage, instruction string, prompt, output int) {
+		prompt += textstats.EstimateTokens(instruction)
+		latency := callOverhead +
+			time.Duration(prompt)*time.Second/inputTokensPerSecond +
+			time.Duration(output)*time.Second/outputTokensPerSecond
+		estimate.Calls = append(estimate.Calls, EstimatedCall{
+			Stage:        stage,
+			PromptTokens: prompt,
+			OutputTokens: output,
+			LatencyMs:    int(latency.Milliseconds()),
+		})
+		estimate.PromptTokens += prompt
+		estimate.OutputTokens += output
+		estimate.LatencyMs += int(latency.Milliseconds())
+	}
+
+	for _, stage := range a.stages {
+		name := stage.Name()
+		if _, ok := stage.(*orgStage); ok {
+			continue
+		}
+		if a.stageConfigs[name].Disabled && name != StageAnalysis {
+			continue
+		}
+
+		// Mirrors the conditions in registerBuiltins
+		switch name {
+		case StageModeration:
+			if a.moderation {
+				add(name, moderationInstruction, content, moderationOutputTokens)
+			}
+		case StageAnalysis:
+			var findings []models.Finding
+			if profile.Enabled(models.ModuleFindings) {
+				findings = sensitive.Detect(submission.Content)
+			}
+			add(name, instructions.Merge(withGlossary(systemInstruction, glossary, analysisGlossaryUse), analysisFocus(profile, submission)),
+				textstats.EstimateTokens(buildPrompt(submission.Content, findings)), analysisOutputTokens)
+		case StageVerification:
+			if a.verification && profile.Enabled(models.ModuleVerification) {
+				add(name, verifyInstruction, content+summaryTokens, verifyOutputTokens)
+			}
+		case StageClaims:
+			if a.claims && profile.Enabled(models.ModuleClaims) {
+				add(name, extractClaimsInstruction, content, extractOutputTokens)
+				add(name, assessClaimsInstruction, extractOutputTokens+estimatedClaims*evidencePerClaimTokens, assessOutputTokens)
+			}
+		case StageProofreading:
+			if a.proofreading && profile.Enabled(models.ModuleProofreading) {
+				add(name, withGlossary(proofreadInstruction, glossary, proofreadGlossaryUse), content, proofreadOutputTokens)
+			}
+		case StageBias:
+			if a.bias && profile.Enabled(models.ModuleBias) {
+				add(name, biasInstruction, content, biasOutputTokens)
+			}
+		case StageAIDetection:
+			if a.aiDetection && profile.Enabled(models.ModuleAIDetection) {
+				add(name, aiDetectionInstruction, content, aiDetectionOutputTokens)
+			}
+		case StageClassification:
+			if taxonomy != nil {
+				add(name, classificationPrompt(taxonomy), content, classifyOutputTokens)
+			}
+		}
+	}
+
+	estimate.CostMicros = a.pricing.CostMicros(estimate.PromptTokens, estimate.OutputTokens)
+	return estimate, nil
+}
@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+type fakeTaxonomies struct {
+	taxonomy *models.Taxonomy
+}
+
+func (f fakeTaxonomies) TaxonomyForUser(ctx context.Context, userID uuid.UUID) (*models.Taxonomy, error) {
+	return f.taxonomy, nil
+}
+
+func estimateStages(e *Estimate) []string {
+	var stages []string
+	for _, c := range e.Calls {
+		stages = append(stages, c.Stage)
+	}
+	return stages
+}
+
+func TestAnalyzer_Estimate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Estimate() called the model")
+	}))
+	defer server.Close()
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL, Model: "test-model"})).
+		WithPricing(ai.Pricing{InputPerMillion: 1, OutputPerMillion: 2}).
+		WithModeration(true).
+		WithClaims(true).
+		WithBias(true)
+	submission := &models.Submission{UserID: uuid.New(), Content: strings.Repeat("word ", 800)}
+
+	estimate, err := a.Estimate(context.Background(), submission, nil)
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+
+	if estimate.Model != "test-model" || estimate.ContentTokens != 1000 {
+		t.Errorf("model = %q, content tokens = %d", estimate.Model, estimate.ContentTokens)
+	}
+	want := []string{StageModeration, StageAnalysis, StageClaims, StageClaims, StageBias}
+	if got := estimateStages(estimate); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("stages = %v, want %v", got, want)
+	}
+
+	var prompt, output, latency int
+	for _, c := range estimate.Calls {
+		prompt += c.PromptTokens
+		output += c.OutputTokens
+		latency += c.LatencyMs
+		if c.Stage != StageClaims && c.PromptTokens <= estimate.ContentTokens {
+			t.Errorf("%s prompt = %d tokens, want the content and instruction", c.Stage, c.PromptTokens)
+		}
+	}
+	if estimate.PromptTokens != prompt || estimate.OutputTokens != output || estimate.LatencyMs != latency {
+		t.Errorf("totals = %d/%d/%dms, want the calls' %d/%d/%dms", estimate.PromptTokens, estimate.OutputTokens, estimate.LatencyMs, prompt, output, latency)
+	}
+	if want := int64(prompt + 2*output); estimate.CostMicros != want {
+		t.Errorf("CostMicros = %d, want %d", estimate.CostMicros, want)
+	}
+}
+
+func TestAnalyzer_Estimate_FollowsProfileAndConfig(t *testing.T) {
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{})).
+		WithModeration(true).
+		WithClaims(true).
+		WithBias(true).
+		WithTaxonomies(fakeTaxonomies{&models.Taxonomy{Labels: []models.TaxonomyLabel{{Name: "Billing"}}}}).
+		WithStageConfig(map[string]StageConfig{StageModeration: {Disabled: true}, StageAnalysis: {Disabled: true}})
+	profile := &models.AnalysisProfile{Modules: []models.AnalysisModule{models.ModuleBias}}
+
+	estimate, err := a.Estimate(context.Background(), &models.Submission{UserID: uuid.New(), Content: "Short text."}, profile)
+	if err != nil {
+		t.Fatalf("Estimate() error = %v", err)
+	}
+
+	want := []string{StageAnalysis, StageBias, StageClassification}
+	if got := estimateStages(estimate); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("stages = %v, want %v", got, want)
+	}
+}
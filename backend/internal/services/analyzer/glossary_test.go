@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestWithGlossary(t *testing.T) {
+	glossary := &models.Glossary{Entries: []models.GlossaryEntry{
+		{Kind: models.GlossaryBanned, Phrase: "guaranteed"},
+		{Kind: models.GlossaryBrand, Phrase: "Acme\"Pro"},
+		{Kind: models.GlossaryTerm, Phrase: "churn", Description: "customers who cancel"},
+	}}
+
+	got := withGlossary("Base prompt.", glossary, "Use them.")
+	for _, want := range []string{
+		"Base prompt.\n\n",
+		`- term "churn": "customers who cancel"`,
+		`- brand "Acme\"Pro"`,
+		"Use them.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("withGlossary() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "guaranteed") {
+		t.Errorf("withGlossary() = %q, banned phrases should be left out", got)
+	}
+	if strings.Index(got, "churn") > strings.Index(got, "Acme") {
+		t.Errorf("withGlossary() = %q, want terms before brand names", got)
+	}
+
+	banned := &models.Glossary{Entries: []models.GlossaryEntry{{Kind: models.GlossaryBanned, Phrase: "guaranteed"}}}
+	for _, g := range []*models.Glossary{nil, banned} {
+		if got := withGlossary("Base prompt.", g, "Use them."); got != "Base prompt." {
+			t.Errorf("withGlossary(%v) = %q, want the prompt unchanged", g, got)
+		}
+	}
+}
+
+func TestAnalyzer_ApplyCompliance(t *testing.T) {
+	a := NewAnalyzer(nil, nil)
+	submission := &models.Submission{Content: "A guaranteed, risk free return"}
+
+	t.Run("flags banned phrases", func(t *testing.T) {
+		analysis := &models.Analysis{}
+		a.applyCompliance(submission, analysis, &models.Glossary{Entries: []models.GlossaryEntry{
+			{Kind: models.GlossaryTerm, Phrase: "return"},
+			{Kind: models.GlossaryBanned, Phrase: "risk free", Replacement: "low risk"},
+			{Kind: models.GlossaryBanned, Phrase: "guaranteed"},
+		}})
+
+		want := []models.ComplianceFlag{
+			{Phrase: "guaranteed", Start: 2, End: 12},
+			{Phrase: "risk free", Start: 14, End: 23, Replacement: "low risk"},
+		}
+		if len(analysis.Compliance) != len(want) {
+			t.Fatalf("Compliance = %+v, want %+v", analysis.Compliance, want)
+		}
+		for i := range want {
+			if analysis.Compliance[i] != want[i] {
+				t.Errorf("Compliance[%d] = %+v, want %+v", i, analysis.Compliance[i], want[i])
+			}
+		}
+	})
+
+	t.Run("clean content", func(t *testing.T) {
+		analysis := &models.Analysis{}
+		a.applyCompliance(submission, analysis, &models.Glossary{Entries: []models.GlossaryEntry{{Kind: models.GlossaryBanned, Phrase: "free money"}}})
+		if analysis.Compliance == nil || len(analysis.Compliance) != 0 {
+			t.Errorf("Compliance = %#v, want an empty list", analysis.Compliance)
+		}
+	})
+
+	t.Run("nothing banned", func(t *testing.T) {
+		analysis := &models.Analysis{}
+		a.applyCompliance(submission, analysis, nil)
+		if analysis.Compliance != nil {
+			t.Errorf("Compliance = %+v, want nil", analysis.Compliance)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const moderationInstruction = `You score text for content moderation. Respond only with JSON of the form:
+{"toxicity": number, "harassment": number, "hate": number, "sexual": number, "violence": number, "self_harm": number}
+Each number is between 0 and 1: 0 when the text contains none of that kind of content, 1 when it is severe and explicit. Score what the text itself does, not the topics it discusses: a news report on an attack or a message supporting someone who self-harms scores low unless it is itself abusive, graphic or encourages harm.`
+
+// moderationSchema is the shape of a moderation response, a score for
+// every category
+var moderationSchema = func() *ai.Schema {
+	properties := make(map[string]*ai.Schema, len(models.ModerationCategories))
+	required := make([]string, 0, len(models.ModerationCategories))
+	for _, category := range models.ModerationCategories {
+		properties[string(category)] = ai.Number()
+		required = append(required, string(category))
+	}
+	return ai.Object(properties, required...)
+}()
+
+// moderationTemperature keeps the scores of the same text stable, so
+// policy decisions don't flip between runs
+var moderationTemperature = 0.0
+
+// applyModeration scores the content for moderation when the profile asks
+// for it or a policy applies to its owner, and records the policy's
+// decision, with any override in force. Scores are advisory on their own,
+// but a policy must be enforced, so failing to score content a policy
+// applies to fails the analysis to be retried.
+func (a *Analyzer) applyModeration(ctx context.Context, submission *models.Submission, analysis *models.Analysis, wanted bool) error {
+	var policy *models.ModerationPolicy
+	var override *models.PolicyOverride
+	if a.policies != nil {
+		var err error
+		if policy, err = a.policies.PolicyForUser(ctx, submission.UserID); err != nil {
+			return err
+		}
+		if override, err = a.policies.Override(ctx, submission.ID); err != nil {
+			return err
+		}
+	}
+
+	if wanted || policy != nil {
+		scores, err := a.moderate(ctx, submission.Content, analysis)
+		switch {
+		case err == nil:
+			analysis.Moderation = scores
+		case policy != nil:
+			return fmt.Errorf("failed to moderate submission: %w", err)
+		case ctx.Err() == nil:
+			slog.Warn("Moderation failed", "submission_id", submission.ID, "error", err)
+		}
+	}
+
+	if policy == nil && override == nil {
+		return nil
+	}
+	decision := models.EvaluatePolicy(policy, analysis.Moderation)
+	decision.ApplyOverride(override)
+	analysis.PolicyDecision = decision
+
+	if decision.Action != models.PolicyAllow {
+		slog.Info("Moderation policy applied", "submission_id", submission.ID, "action", decision.Action, "violations", len(decision.Violations), "overridden", override != nil)
+	}
+	return nil
+}
+
+// moderate scores content in each moderation category and adds the call's
+// tokens to the analysis
+func (a *Analyzer) moderate(ctx context.Context, content string, analysis *models.Analysis) (models.ModerationScores, error) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            content,
+		SystemInstruction: moderationInstruction,
+		JSON:              true,
+		Schema:            moderationSchema,
+		Temperature:       &moderationTemperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := parseModeration(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+	return scores, nil
+}
+
+// parseModeration reads the moderation response, clamping each score to
+// [0, 1]. Unknown categories are dropped and missing ones score 0; a
+// response without any known category is an error.
+func parseModeration(text string) (models.ModerationScores, error) {
+	var out map[string]*float64
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse moderation response: %w", err)
+	}
+
+	scores := make(models.ModerationScores, len(models.ModerationCategories))
+	found := false
+	for _, category := range models.ModerationCategories {
+		score := out[string(category)]
+		if score == nil {
+			scores[category] = 0
+			continue
+		}
+		scores[category] = min(max(*score, 0), 1)
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("moderation response has no scores")
+	}
+
+	return scores, nil
+}
@@ -0,0 +1,130 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// fakePolicies is a PolicySource with one policy and override for everyone
+type fakePolicies struct {
+	policy   *models.ModerationPolicy
+	override *models.PolicyOverride
+}
+
+func (p fakePolicies) PolicyForUser(ctx context.Context, userID uuid.UUID) (*models.ModerationPolicy, error) {
+	return p.policy, nil
+}
+
+func (p fakePolicies) Override(ctx context.Context, submissionID uuid.UUID) (*models.PolicyOverride, error) {
+	return p.override, nil
+}
+
+func TestAnalyzer_ApplyModeration(t *testing.T) {
+	reply := `{"toxicity": 0.9, "harassment": 0.6, "hate": 0.1, "sexual": 0, "violence": 0, "self_harm": 0}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(geminiText(reply))
+	}))
+	defer server.Close()
+
+	block := 0.8
+	policy := &models.ModerationPolicy{Thresholds: []models.ModerationThreshold{{Category: models.ModerationToxicity, BlockAbove: &block}}}
+	submission := &models.Submission{ID: uuid.New(), UserID: uuid.New(), Content: "You are all idiots."}
+	client := ai.NewClient(ai.Options{BaseURL: server.URL})
+
+	t.Run("policy blocks", func(t *testing.T) {
+		a := NewAnalyzer(nil, client).WithModeration(true).WithPolicies(fakePolicies{policy: policy})
+		analysis := &models.Analysis{}
+		if err := a.applyModeration(context.Background(), submission, analysis, false); err != nil {
+			t.Fatalf("applyModeration() error = %v", err)
+		}
+		if analysis.Moderation[models.ModerationToxicity] != 0.9 {
+			t.Errorf("Moderation = %v, want scores although the profile skipped it", analysis.Moderation)
+		}
+		if analysis.PolicyDecision == nil || analysis.PolicyDecision.Action != models.PolicyBlock {
+			t.Errorf("PolicyDecision = %+v, want block", analysis.PolicyDecision)
+		}
+		if analysis.PromptTokens != 10 || analysis.OutputTokens != 5 {
+			t.Errorf("tokens = %d/%d, want the moderation call added", analysis.PromptTokens, analysis.OutputTokens)
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		override := &models.PolicyOverride{Action: models.PolicyAllow, At: timestamp.Now()}
+		a := NewAnalyzer(nil, client).WithModeration(true).WithPolicies(fakePolicies{policy: policy, override: override})
+		analysis := &models.Analysis{}
+		if err := a.applyModeration(context.Background(), submission, analysis, true); err != nil {
+			t.Fatalf("applyModeration() error = %v", err)
+		}
+		if d := analysis.PolicyDecision; d == nil || d.Action != models.PolicyAllow || d.PolicyAction != models.PolicyBlock {
+			t.Errorf("PolicyDecision = %+v, want the override in force", d)
+		}
+	})
+
+	t.Run("no policy", func(t *testing.T) {
+		a := NewAnalyzer(nil, client).WithModeration(true).WithPolicies(fakePolicies{})
+		analysis := &models.Analysis{}
+		if err := a.applyModeration(context.Background(), submission, analysis, false); err != nil {
+			t.Fatalf("applyModeration() error = %v", err)
+		}
+		if analysis.Moderation != nil || analysis.PolicyDecision != nil || analysis.PromptTokens != 0 {
+			t.Errorf("analysis = %+v, want no call when neither the profile nor a policy asks", analysis)
+		}
+	})
+
+	reply = `not json`
+
+	t.Run("failure without policy", func(t *testing.T) {
+		a := NewAnalyzer(nil, client).WithModeration(true)
+		analysis := &models.Analysis{}
+		if err := a.applyModeration(context.Background(), submission, analysis, true); err != nil {
+			t.Errorf("applyModeration() error = %v, want advisory scores to fail quietly", err)
+		}
+		if analysis.Moderation != nil || analysis.PolicyDecision != nil {
+			t.Errorf("analysis = %+v, want no scores or decision", analysis)
+		}
+	})
+
+	t.Run("failure with policy", func(t *testing.T) {
+		a := NewAnalyzer(nil, client).WithModeration(true).WithPolicies(fakePolicies{policy: policy})
+		if err := a.applyModeration(context.Background(), submission, &models.Analysis{}, true); err == nil {
+			t.Error("applyModeration() error = nil, want the analysis failed so the policy is enforced on retry")
+		}
+	})
+}
+
+func TestParseModeration(t *testing.T) {
+	got, err := parseModeration(`{"toxicity": 1.7, "hate": -0.2, "violence": 0.4, "spam": 0.9}`)
+	if err != nil {
+		t.Fatalf("parseModeration() error = %v", err)
+	}
+	want := models.ModerationScores{
+		models.ModerationToxicity:   1,
+		models.ModerationHarassment: 0,
+		models.ModerationHate:       0,
+		models.ModerationSexual:     0,
+		models.ModerationViolence:   0.4,
+		models.ModerationSelfHarm:   0,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseModeration() = %v, want %v", got, want)
+	}
+	for category, score := range want {
+		if got[category] != score {
+			t.Errorf("%s = %v, want %v", category, got[category], score)
+		}
+	}
+
+	for _, text := range []string{`{"spam": 0.9}`, `Looks fine.`} {
+		if _, err := parseModeration(text); err == nil {
+			t.Errorf("parseModeration(%q) error = nil", text)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// ModulesJobType identifies the job running some stages of a stored
+// analysis again, such as those that timed out
+const ModulesJobType = "analysis.modules"
+
+// ModulesPayload is the job payload for running stages of a stored
+// analysis again
+type ModulesPayload struct {
+	SubmissionID uuid.UUID `json:"submission_id"`
+	// Stages are the names of the stages to run
+	Stages []string `json:"stages"`
+}
+
+// HandleModules implements queue.Handler for the modules job. A submission
+// analyzed again or no longer completed is left alone.
+func (a *Analyzer) HandleModules(ctx context.Context, job *queue.Job) error {
+	var payload ModulesPayload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid modules payload: %w", err)
+	}
+
+	submission, err := a.store.Get(ctx, payload.SubmissionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.Warn("Submission not found for module retry", "submission_id", payload.SubmissionID)
+			return nil
+		}
+		return fmt.Errorf("failed to load submission: %w", err)
+	}
+	if submission.Status != models.StatusCompleted {
+		slog.Info("Submission no longer completed, skipping module retry", "submission_id", submission.ID, "status", submission.Status)
+		return nil
+	}
+
+	analysis, err := a.store.GetAnalysis(ctx, submission.UserID, submission.ID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("failed to load analysis: %w", err)
+	}
+
+	if err := a.rerun(ctx, submission, analysis, payload.Stages); err != nil {
+		if retryAfter, ok := ai.RateLimited(err); ok {
+			slog.Warn("Module retry rate limited", "submission_id", submission.ID, "retry_after", retryAfter)
+			return queue.Defer(err, retryAfter)
+		}
+		return err
+	}
+	return nil
+}
+
+// rerun runs the named stages of a stored analysis again within the
+// analyzer's timeout and writes their results into it. Stages that run
+// out of time again stay recorded as timed out.
+func (a *Analyzer) rerun(ctx context.Context, submission *models.Submission, analysis *models.Analysis, stages []string) error {
+	start := time.Now()
+
+	profile, err := a.profile(ctx, submission)
+	if err != nil {
+		return err
+	}
+	glossary, err := a.glossary(ctx, submission)
+	if err != nil {
+		return err
+	}
+	taxonomy, err := a.taxonomy(ctx, submission)
+	if err != nil {
+		return err
+	}
+
+	// The stored totals are added to, so count this run's usage alone
+	previous := analysis.TimedOut
+	analysis.TimedOut = nil
+	analysis.PromptTokens, analysis.OutputTokens = 0, 0
+
+	run := &Run{
+		Submission: submission,
+		Profile:    profile,
+		Glossary:   glossary,
+		Taxonomy:   taxonomy,
+		Analysis:   analysis,
+	}
+	stagesCtx, cancel := a.withDeadline(ctx)
+	defer cancel()
+	if err := a.runStages(stagesCtx, run, stages...); err != nil {
+		return err
+	}
+
+	for _, name := range previous {
+		if !slices.Contains(stages, name) && !slices.Contains(analysis.TimedOut, name) {
+			analysis.TimedOut = append(analysis.TimedOut, name)
+		}
+	}
+	analysis.CostMicros = a.pricing.CostMicros(analysis.PromptTokens, analysis.OutputTokens)
+	analysis.ProcessingTimeMs = int(time.Since(start).Milliseconds())
+
+	return a.store.UpdateAnalysis(ctx, analysis)
+}
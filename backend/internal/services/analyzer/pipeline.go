@@ -0,0 +1,313 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sfumato00/content-analyzer/internal/metrics"
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Phase orders the stages of the analysis pipeline. Stages run phase by
+// phase, and in the order they were registered within a phase.
+type Phase int
+
+const (
+	// PhasePreprocess prepares the content, such as finding the sensitive
+	// data the analysis is asked about
+	PhasePreprocess Phase = iota
+	// PhaseDetectLanguage identifies the language of the content
+	PhaseDetectLanguage
+	// PhaseModerate scores the content for harmful material and applies
+	// moderation policies
+	PhaseModerate
+	// PhaseAnalyze runs the main model call and the modules building on it
+	PhaseAnalyze
+	// PhasePostprocess derives results from the finished analysis
+	PhasePostprocess
+)
+
+var phaseNames = map[Phase]string{
+	PhasePreprocess:     "preprocess",
+	PhaseDetectLanguage: "detect_language",
+	PhaseModerate:       "moderate",
+	PhaseAnalyze:        "analyze",
+	PhasePostprocess:    "postprocess",
+}
+
+func (p Phase) String() string {
+	if name, ok := phaseNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("phase(%d)", int(p))
+}
+
+// Run is a submission going through the pipeline: what the stages read,
+// and the analysis they build up
+type Run struct {
+	Submission *models.Submission
+
+	// The profile, glossary and taxonomy in force for the submission, or
+	// nil when there are none
+	Profile  *models.AnalysisProfile
+	Glossary *models.Glossary
+	Taxonomy *models.Taxonomy
+
+	// OrgIDs are the organizations of the submission's owner. They are only
+	// loaded when an org-specific stage is registered.
+	OrgIDs []uuid.UUID
+
+	// Findings are the sensitive data candidates found in the content
+	Findings []models.Finding
+
+	// Analysis is the result so far. Stages add their model calls' tokens
+	// to it; its cost and processing time are set once every stage ran.
+	Analysis *models.Analysis
+}
+
+// Stage is a step of the analysis pipeline. An error fails the analysis,
+// to be retried; advisory stages log their failures and return nil
+// instead, leaving their part of the analysis empty.
+type Stage interface {
+	// Name identifies the stage in metrics, logs and configuration
+	Name() string
+	Phase() Phase
+	Run(ctx context.Context, run *Run) error
+}
+
+// NewStage makes a Stage of a function
+func NewStage(name string, phase Phase, fn func(ctx context.Context, run *Run) error) Stage {
+	return &funcStage{name: name, phase: phase, fn: fn}
+}
+
+type funcStage struct {
+	name  string
+	phase Phase
+	fn    func(ctx context.Context, run *Run) error
+}
+
+func (s *funcStage) Name() string                            { return s.name }
+func (s *funcStage) Phase() Phase                            { return s.phase }
+func (s *funcStage) Run(ctx context.Context, run *Run) error { return s.fn(ctx, run) }
+
+// ForOrgs limits stage to the submissions of members of the given
+// organizations, for stages an organization added
+func ForOrgs(stage Stage, orgIDs ...uuid.UUID) Stage {
+	return &orgStage{Stage: stage, orgIDs: orgIDs}
+}
+
+type orgStage struct {
+	Stage
+	orgIDs []uuid.UUID
+}
+
+// applies reports whether the run's owner belongs to one of the stage's
+// organizations
+func (s *orgStage) applies(run *Run) bool {
+	for _, id := range run.OrgIDs {
+		if slices.Contains(s.orgIDs, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// StageConfig adjusts a stage by name
+type StageConfig struct {
+	// Disabled skips the stage. The analysis stage always runs.
+	Disabled bool
+	// Timeout bounds each run of the stage when set. A failing stage that
+	// runs out of time fails the analysis; an advisory one is left out and
+	// recorded as timed out.
+	Timeout time.Duration
+}
+
+// OrgSource lists the organizations a submission's owner belongs to, for
+// org-specific stages; *models.OrganizationStore implements it
+type OrgSource interface {
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error)
+}
+
+// stageMetrics records how long each stage takes and how often it fails
+type stageMetrics struct {
+	duration *prometheus.HistogramVec
+	errors   *prometheus.CounterVec
+}
+
+// Register adds a stage to the pipeline and returns the analyzer. Its
+// name must be unique. Register stages at startup, before the analyzer
+// handles jobs.
+func (a *Analyzer) Register(stage Stage) *Analyzer {
+	for _, s := range a.stages {
+		if s.Name() == stage.Name() {
+			panic(fmt.Sprintf("analyzer: stage %q is already registered", stage.Name()))
+		}
+	}
+
+	a.stages = append(a.stages, stage)
+	sort.SliceStable(a.stages, func(i, j int) bool {
+		return a.stages[i].Phase() < a.stages[j].Phase()
+	})
+	return a
+}
+
+// Stages returns the names of the registered stages in the order they
+// run
+func (a *Analyzer) Stages() []string {
+	names := make([]string, 0, len(a.stages))
+	for _, s := range a.stages {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+// WithStageConfig adjusts stages by name and returns the analyzer
+func (a *Analyzer) WithStageConfig(configs map[string]StageConfig) *Analyzer {
+	a.stageConfigs = configs
+	return a
+}
+
+// WithOrgs looks up the organizations of each submission's owner for the
+// stages limited to some with ForOrgs, and returns the analyzer. Without
+// it those stages never run.
+func (a *Analyzer) WithOrgs(orgs OrgSource) *Analyzer {
+	a.orgs = orgs
+	return a
+}
+
+// WithMetrics exports each stage's duration and failures to registry and
+// returns the analyzer
+func (a *Analyzer) WithMetrics(registry prometheus.Registerer) *Analyzer {
+	a.metrics = &stageMetrics{
+		duration: metrics.Register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "analysis_stage_duration_seconds",
+			Help:    "Time spent in each stage of the analysis pipeline.",
+			Buckets: metrics.DefaultDurationBuckets,
+		}, []string{"stage", "status"})),
+		errors: metrics.Register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "analysis_stage_errors_total",
+			Help: "Analysis pipeline stages that failed.",
+		}, []string{"stage"})),
+	}
+	return a
+}
+
+// runStages takes the run through every stage that applies to it, or
+// only the named ones when given. Once the analysis' deadline passes, the
+// stages left are skipped and recorded as timed out, provided the main
+// analysis is done; before then it fails with ErrTimedOut.
+func (a *Analyzer) runStages(ctx context.Context, run *Run, only ...string) error {
+	if err := a.loadOrgs(ctx, run); err != nil {
+		return err
+	}
+
+	// A stored analysis being completed already has its main analysis
+	analyzed := run.Analysis.ID != uuid.Nil
+	for _, stage := range a.stages {
+		// Every other stage builds on the main analysis, so it can't be
+		// disabled
+		config := a.stageConfigs[stage.Name()]
+		if config.Disabled && stage.Name() != StageAnalysis {
+			continue
+		}
+		if len(only) > 0 && !slices.Contains(only, stage.Name()) {
+			continue
+		}
+		if scoped, ok := stage.(*orgStage); ok && !scoped.applies(run) {
+			continue
+		}
+
+		if pastDeadline(ctx) {
+			if !analyzed {
+				return fmt.Errorf("stage %s: %w", stage.Name(), ErrTimedOut)
+			}
+			run.Analysis.TimedOut = append(run.Analysis.TimedOut, stage.Name())
+			continue
+		}
+
+		timedOut, err := a.runStage(ctx, stage, config, run)
+		if err != nil {
+			if !pastDeadline(ctx) {
+				return err
+			}
+			if !analyzed {
+				return fmt.Errorf("stage %s: %w", stage.Name(), ErrTimedOut)
+			}
+			timedOut = true
+		}
+		if timedOut && stage.Name() != StageAnalysis {
+			run.Analysis.TimedOut = append(run.Analysis.TimedOut, stage.Name())
+		}
+		if stage.Name() == StageAnalysis {
+			analyzed = true
+		}
+	}
+	return nil
+}
+
+// pastDeadline reports whether the analysis' own deadline passed, as
+// opposed to it being canceled
+func pastDeadline(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrTimedOut)
+}
+
+// runStage runs one stage within its timeout, recording its outcome. It
+// reports whether the stage ran out of time, whether it failed or gave up
+// quietly.
+func (a *Analyzer) runStage(ctx context.Context, stage Stage, config StageConfig, run *Run) (bool, error) {
+	ctx = withStage(ctx, stage.Name())
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := stage.Run(ctx, run)
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if err == nil && timedOut {
+		// An advisory stage gave up quietly when it ran out of time
+		slog.Warn("Analysis stage timed out", "submission_id", run.Submission.ID, "stage", stage.Name(), "timeout", config.Timeout)
+	}
+
+	if a.metrics != nil {
+		status := "ok"
+		if err != nil {
+			status = "error"
+			a.metrics.errors.WithLabelValues(stage.Name()).Inc()
+		}
+		a.metrics.duration.WithLabelValues(stage.Name(), status).Observe(time.Since(start).Seconds())
+	}
+	if err != nil {
+		slog.Warn("Analysis stage failed", "submission_id", run.Submission.ID, "stage", stage.Name(), "phase", stage.Phase(), "error", err)
+	}
+	return timedOut, err
+}
+
+// loadOrgs fills in the run's organizations when an org-specific stage
+// may need them
+func (a *Analyzer) loadOrgs(ctx context.Context, run *Run) error {
+	if a.orgs == nil || !slices.ContainsFunc(a.stages, func(s Stage) bool {
+		_, ok := s.(*orgStage)
+		return ok
+	}) {
+		return nil
+	}
+
+	orgs, err := a.orgs.ListForUser(ctx, run.Submission.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to load organizations: %w", err)
+	}
+	for _, org := range orgs {
+		run.OrgIDs = append(run.OrgIDs, org.ID)
+	}
+	return nil
+}
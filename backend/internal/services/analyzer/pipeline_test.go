@@ -0,0 +1,215 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+// fakeOrgs lists the same organizations for every user
+type fakeOrgs []models.Organization
+
+func (o fakeOrgs) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Organization, error) {
+	return o, nil
+}
+
+// observations returns how many values a histogram recorded
+func observations(t *testing.T, o prometheus.Observer) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	if err := o.(prometheus.Metric).Write(&m); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+// recordStage is a stage appending its name to ran
+func recordStage(name string, phase Phase, ran *[]string) Stage {
+	return NewStage(name, phase, func(ctx context.Context, run *Run) error {
+		*ran = append(*ran, name)
+		return nil
+	})
+}
+
+func newRun() *Run {
+	submission := &models.Submission{ID: uuid.New(), UserID: uuid.New()}
+	return &Run{Submission: submission, Analysis: &models.Analysis{SubmissionID: submission.ID}}
+}
+
+func TestAnalyzer_Stages(t *testing.T) {
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{})).
+		Register(NewStage("acme_redaction", PhasePreprocess, func(ctx context.Context, run *Run) error { return nil })).
+		Register(NewStage("acme_score", PhasePostprocess, func(ctx context.Context, run *Run) error { return nil }))
+
+	want := []string{
+		StageFindings, "acme_redaction", StageLanguage, StageModeration,
+		StageAnalysis, StageReadability, StageVerification, StageClaims, StageProofreading, StageBias, StageAIDetection, StageClassification,
+		StageCompliance, StageComparison, "acme_score",
+	}
+	if got := a.Stages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stages() = %v, want %v", got, want)
+	}
+}
+
+func TestAnalyzer_Register_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() of a duplicate name didn't panic")
+		}
+	}()
+	NewAnalyzer(nil, ai.NewClient(ai.Options{})).Register(NewStage(StageBias, PhaseAnalyze, nil))
+}
+
+func TestAnalyzer_RunStages(t *testing.T) {
+	member, other := uuid.New(), uuid.New()
+
+	var ran []string
+	a := (&Analyzer{}).
+		Register(recordStage("postprocess", PhasePostprocess, &ran)).
+		Register(recordStage(StageAnalysis, PhaseAnalyze, &ran)).
+		Register(recordStage("disabled", PhaseAnalyze, &ran)).
+		Register(ForOrgs(recordStage("member", PhasePreprocess, &ran), member)).
+		Register(ForOrgs(recordStage("other", PhasePreprocess, &ran), other)).
+		WithOrgs(fakeOrgs{{ID: member}}).
+		WithStageConfig(map[string]StageConfig{
+			StageAnalysis: {Disabled: true},
+			"disabled":    {Disabled: true},
+		})
+
+	run := newRun()
+	if err := a.runStages(context.Background(), run); err != nil {
+		t.Fatalf("runStages() error = %v", err)
+	}
+
+	want := []string{"member", StageAnalysis, "postprocess"}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if !reflect.DeepEqual(run.OrgIDs, []uuid.UUID{member}) {
+		t.Errorf("OrgIDs = %v, want %v", run.OrgIDs, []uuid.UUID{member})
+	}
+}
+
+func TestAnalyzer_RunStages_Failure(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	var ran []string
+	a := (&Analyzer{}).
+		Register(NewStage("slow", PhasePreprocess, func(ctx context.Context, run *Run) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})).
+		Register(recordStage(StageAnalysis, PhaseAnalyze, &ran)).
+		WithStageConfig(map[string]StageConfig{"slow": {Timeout: 10 * time.Millisecond}}).
+		WithMetrics(registry)
+
+	err := a.runStages(context.Background(), newRun())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("runStages() error = %v, want the stage's deadline", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %v after a failed stage", ran)
+	}
+	if got := testutil.ToFloat64(a.metrics.errors.WithLabelValues("slow")); got != 1 {
+		t.Errorf("errors{slow} = %v, want 1", got)
+	}
+	if got := observations(t, a.metrics.duration.WithLabelValues("slow", "error")); got != 1 {
+		t.Errorf("duration{slow,error} count = %d, want 1", got)
+	}
+}
+
+func TestAnalyzer_RunStages_Metrics(t *testing.T) {
+	var ran []string
+	a := (&Analyzer{}).
+		Register(recordStage(StageAnalysis, PhaseAnalyze, &ran)).
+		WithMetrics(prometheus.NewRegistry())
+
+	for i := 0; i < 2; i++ {
+		if err := a.runStages(context.Background(), newRun()); err != nil {
+			t.Fatalf("runStages() error = %v", err)
+		}
+	}
+
+	if got := observations(t, a.metrics.duration.WithLabelValues(StageAnalysis, "ok")); got != 2 {
+		t.Errorf("duration{analysis,ok} count = %d, want 2", got)
+	}
+	if got := testutil.ToFloat64(a.metrics.errors.WithLabelValues(StageAnalysis)); got != 0 {
+		t.Errorf("errors{analysis} = %v, want 0", got)
+	}
+}
+
+func TestAnalyzer_RunStages_Deadline(t *testing.T) {
+	var ran []string
+	// quiet is an advisory stage, giving up when it runs out of time
+	quiet := NewStage("quiet", PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		<-ctx.Done()
+		return nil
+	})
+	a := (&Analyzer{}).
+		Register(recordStage("early", PhasePreprocess, &ran)).
+		Register(recordStage(StageAnalysis, PhaseAnalyze, &ran)).
+		Register(quiet).
+		Register(recordStage("late", PhasePostprocess, &ran)).
+		WithTimeout(20 * time.Millisecond)
+
+	ctx, cancel := a.withDeadline(context.Background())
+	defer cancel()
+	run := newRun()
+	if err := a.runStages(ctx, run); err != nil {
+		t.Fatalf("runStages() error = %v, want the partial analysis", err)
+	}
+	if want := []string{"early", StageAnalysis}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %v, want %v", ran, want)
+	}
+	if want := []string{"quiet", "late"}; !reflect.DeepEqual(run.Analysis.TimedOut, want) {
+		t.Errorf("TimedOut = %v, want %v", run.Analysis.TimedOut, want)
+	}
+
+	// Only the stages asked for run on a stored analysis
+	ran = nil
+	run.Analysis.ID, run.Analysis.TimedOut = uuid.New(), nil
+	if err := a.runStages(context.Background(), run, "late"); err != nil {
+		t.Fatalf("runStages(late) error = %v", err)
+	}
+	if want := []string{"late"}; !reflect.DeepEqual(ran, want) || run.Analysis.TimedOut != nil {
+		t.Errorf("runStages(late) ran %v, timed out %v, want only late", ran, run.Analysis.TimedOut)
+	}
+}
+
+func TestAnalyzer_RunStages_DeadlineBeforeAnalysis(t *testing.T) {
+	var ran []string
+	a := (&Analyzer{}).
+		Register(NewStage("slow", PhasePreprocess, func(ctx context.Context, run *Run) error {
+			<-ctx.Done()
+			return nil
+		})).
+		Register(recordStage(StageAnalysis, PhaseAnalyze, &ran)).
+		WithTimeout(10 * time.Millisecond)
+
+	ctx, cancel := a.withDeadline(context.Background())
+	defer cancel()
+	if err := a.runStages(ctx, newRun()); !errors.Is(err, ErrTimedOut) {
+		t.Fatalf("runStages() error = %v, want ErrTimedOut", err)
+	}
+	if len(ran) != 0 {
+		t.Errorf("ran %v past the deadline", ran)
+	}
+
+	// A cancellation isn't a timeout
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if pastDeadline(ctx) {
+		t.Error("pastDeadline() of a canceled context = true")
+	}
+}
@@ -0,0 +1,128 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const proofreadInstruction = `You proofread text. Respond only with JSON of the form:
+{"issues": [{"category": "grammar" | "spelling" | "style", "severity": "error" | "warning" | "suggestion", "text": "the text with the problem, quoted exactly", "message": "a short explanation", "suggestion": "replacement for the quoted text, or empty if there is no single fix"}]}
+List issues in the order they appear, at most 50. Quote only as much text as needed to locate each problem. Report style problems such as wordiness, repetition or unclear phrasing only where they hurt readability. Respond with an empty list if there are no issues.`
+
+// proofreadSchema is the shape of a proofreading response
+var proofreadSchema = ai.Object(map[string]*ai.Schema{
+	"issues": ai.Array(ai.Object(map[string]*ai.Schema{
+		"category":   ai.String("grammar", "spelling", "style"),
+		"severity":   ai.String("error", "warning", "suggestion"),
+		"text":       ai.String(),
+		"message":    ai.String(),
+		"suggestion": ai.String(),
+	}, "category", "severity", "text")),
+}, "issues")
+
+// maxIssues bounds how many issues are kept per analysis
+const maxIssues = 50
+
+// proofreadTemperature keeps proofreading of the same text stable
+var proofreadTemperature = 0.0
+
+// applyProofreading finds grammar, spelling and style issues in the
+// content, taking the organization glossary's terms and brand names as
+// spelled. Like verification it is advisory, so a failed pass leaves the
+// analysis without issues rather than failing it.
+func (a *Analyzer) applyProofreading(ctx context.Context, submission *models.Submission, analysis *models.Analysis, glossary *models.Glossary) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            submission.Content,
+		SystemInstruction: withGlossary(proofreadInstruction, glossary, proofreadGlossaryUse),
+		JSON:              true,
+		Schema:            proofreadSchema,
+		Temperature:       &proofreadTemperature,
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Warn("Proofreading failed", "submission_id", submission.ID, "error", err)
+		}
+		return
+	}
+
+	issues, err := parseIssues(submission.Content, resp.Text)
+	if err != nil {
+		slog.Warn("Proofreading failed", "submission_id", submission.ID, "error", err)
+		return
+	}
+
+	analysis.Issues = issues
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+}
+
+// parseIssues reads the proofreading response and locates each issue's
+// quoted text in content. Issues whose text can't be found, with an
+// unknown category or whose suggestion changes nothing are dropped, and
+// the rest are ordered by position.
+func parseIssues(content, text string) ([]models.Issue, error) {
+	var out struct {
+		Issues []struct {
+			Category   string `json:"category"`
+			Severity   string `json:"severity"`
+			Text       string `json:"text"`
+			Message    string `json:"message"`
+			Suggestion string `json:"suggestion"`
+		} `json:"issues"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse proofreading response: %w", err)
+	}
+
+	issues := []models.Issue{}
+	quotes := quoteFinder{content: content}
+	for _, raw := range out.Issues {
+		if len(issues) == maxIssues {
+			break
+		}
+
+		category := models.IssueCategory(strings.ToLower(strings.TrimSpace(raw.Category)))
+		quoted := strings.TrimSpace(raw.Text)
+		suggestion := strings.TrimSpace(raw.Suggestion)
+		if !models.ValidIssueCategory(category) || suggestion == quoted {
+			continue
+		}
+
+		start, end, ok := quotes.find(quoted)
+		if !ok {
+			continue
+		}
+
+		severity := models.IssueSeverity(strings.ToLower(strings.TrimSpace(raw.Severity)))
+		if !models.ValidIssueSeverity(severity) {
+			severity = models.SeverityWarning
+		}
+
+		issue := models.Issue{
+			Category:   category,
+			Severity:   severity,
+			Start:      start,
+			End:        end,
+			Message:    strings.TrimSpace(raw.Message),
+			Suggestion: suggestion,
+		}
+		if slices.ContainsFunc(issues, func(i models.Issue) bool {
+			return i.Start == issue.Start && i.End == issue.End && i.Category == issue.Category
+		}) {
+			continue
+		}
+		issues = append(issues, issue)
+	}
+
+	slices.SortStableFunc(issues, func(a, b models.Issue) int {
+		return a.Start - b.Start
+	})
+	return issues, nil
+}
@@ -0,0 +1,72 @@
+package analyzer
+
+import (
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestParseIssues(t *testing.T) {
+	content := "Café owners says the the menu is gud. The the end."
+
+	got, err := parseIssues(content, `{"issues": [
+		{"category": "grammar", "severity": "error", "text": "owners says", "message": "Subject and verb disagree.", "suggestion": "owners say"},
+		{"category": "Grammar", "severity": "error", "text": "the the", "message": "Repeated word.", "suggestion": "the"},
+		{"category": "spelling", "severity": "urgent", "text": "gud", "message": "Misspelled.", "suggestion": "good"},
+		{"category": "grammar", "severity": "error", "text": "The the", "message": "Repeated word.", "suggestion": "The"},
+		{"category": "tone", "severity": "warning", "text": "menu", "message": "Unknown category."},
+		{"category": "style", "severity": "suggestion", "text": "not in the text", "message": "Invented."},
+		{"category": "style", "severity": "suggestion", "text": "menu", "message": "No change.", "suggestion": "menu"}
+	]}`)
+	if err != nil {
+		t.Fatalf("parseIssues() error = %v", err)
+	}
+
+	want := []models.Issue{
+		// Offsets count characters, so the é before them counts once
+		{Category: models.IssueGrammar, Severity: models.SeverityError, Start: 5, End: 16, Message: "Subject and verb disagree.", Suggestion: "owners say"},
+		{Category: models.IssueGrammar, Severity: models.SeverityError, Start: 17, End: 24, Message: "Repeated word.", Suggestion: "the"},
+		{Category: models.IssueSpelling, Severity: models.SeverityWarning, Start: 33, End: 36, Message: "Misspelled.", Suggestion: "good"},
+		{Category: models.IssueGrammar, Severity: models.SeverityError, Start: 38, End: 45, Message: "Repeated word.", Suggestion: "The"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("parseIssues() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("issue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	runes := []rune(content)
+	if quoted := string(runes[got[2].Start:got[2].End]); quoted != "gud" {
+		t.Errorf("offsets of the spelling issue select %q", quoted)
+	}
+}
+
+func TestParseIssues_RepeatedPhrase(t *testing.T) {
+	content := "its fine and its cheap"
+
+	got, err := parseIssues(content, `{"issues": [
+		{"category": "grammar", "severity": "error", "text": "its", "suggestion": "it's"},
+		{"category": "grammar", "severity": "error", "text": "its", "suggestion": "it's"}
+	]}`)
+	if err != nil {
+		t.Fatalf("parseIssues() error = %v", err)
+	}
+
+	if len(got) != 2 || got[0].Start != 0 || got[1].Start != 13 {
+		t.Errorf("parseIssues() = %+v, want both occurrences in order", got)
+	}
+}
+
+func TestParseIssues_Invalid(t *testing.T) {
+	if _, err := parseIssues("text", `Looks good.`); err == nil {
+		t.Error("parseIssues() error = nil for a non-JSON response")
+	}
+
+	got, err := parseIssues("text", `{}`)
+	if err != nil || got == nil || len(got) != 0 {
+		t.Errorf("parseIssues() = %#v, %v, want an empty list", got, err)
+	}
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/keyphrases"
+)
+
+const quickInstruction = `You analyze text a user is reading. Respond only with JSON of the form:
+{"sentiment": "positive" | "neutral" | "negative", "sentiment_score": number between -1 and 1, "topics": [up to 3 short topic strings], "keyphrases": [up to 5 key phrases quoted exactly from the text], "summary": "one sentence summary"}`
+
+const (
+	// quickMaxTopics and quickMaxKeyphrases condense quick analyses
+	quickMaxTopics     = 3
+	quickMaxKeyphrases = 5
+)
+
+// Quick analyzes content synchronously without storing anything. It skips
+// sensitive data detection, profiles and verification, and returns fewer
+// topics and keyphrases than a full analysis.
+func (a *Analyzer) Quick(ctx context.Context, content string) (*models.Analysis, error) {
+	start := time.Now()
+
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            content,
+		SystemInstruction: quickInstruction,
+		JSON:              true,
+		Schema:            analysisSchema,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze content: %w", err)
+	}
+
+	analysis, err := ParseResult(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(analysis.Topics) > quickMaxTopics {
+		analysis.Topics = analysis.Topics[:quickMaxTopics]
+	}
+	suggested := make([]string, 0, len(analysis.Keyphrases))
+	for _, k := range analysis.Keyphrases {
+		suggested = append(suggested, k.Phrase)
+	}
+	local := keyphrases.Extract(content, quickMaxKeyphrases)
+	analysis.Keyphrases = keyphrases.Refine(content, local, suggested, quickMaxKeyphrases)
+
+	analysis.PromptTokens = resp.PromptTokens
+	analysis.OutputTokens = resp.OutputTokens
+	analysis.CostMicros = a.pricing.CostMicros(analysis.PromptTokens, analysis.OutputTokens)
+	analysis.ProcessingTimeMs = int(time.Since(start).Milliseconds())
+	return analysis, nil
+}
@@ -0,0 +1,88 @@
+package analyzer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/limits"
+)
+
+func TestAnalyzer_Quick(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"sentiment\": \"positive\", \"sentiment_score\": 0.7, \"topics\": [\"a\", \"b\", \"c\", \"d\"], \"keyphrases\": [\"battery life\", \"invented phrase\"], \"summary\": \"A glowing review.\"}"}]}}], "usageMetadata": {"promptTokenCount": 20, "candidatesTokenCount": 10}}`))
+	}))
+	defer server.Close()
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).
+		WithPricing(ai.Pricing{InputPerMillion: 1, OutputPerMillion: 1})
+
+	analysis, err := a.Quick(context.Background(), "The battery life of this phone is superb and the screen is bright.")
+	if err != nil {
+		t.Fatalf("Quick() error = %v", err)
+	}
+
+	if analysis.Sentiment != "positive" || analysis.Summary != "A glowing review." {
+		t.Errorf("analysis = %+v", analysis)
+	}
+	if len(analysis.Topics) != quickMaxTopics {
+		t.Errorf("got %d topics, want %d", len(analysis.Topics), quickMaxTopics)
+	}
+	if len(analysis.Keyphrases) == 0 || len(analysis.Keyphrases) > quickMaxKeyphrases || analysis.Keyphrases[0].Phrase != "battery life" {
+		t.Errorf("Keyphrases = %+v, want the model's phrase that occurs in the text first", analysis.Keyphrases)
+	}
+	for _, k := range analysis.Keyphrases {
+		if k.Phrase == "invented phrase" {
+			t.Error("Keyphrases kept a phrase that isn't in the text")
+		}
+	}
+	if analysis.PromptTokens != 20 || analysis.OutputTokens != 10 || analysis.CostMicros != 30 {
+		t.Errorf("tokens = %d/%d, cost = %d", analysis.PromptTokens, analysis.OutputTokens, analysis.CostMicros)
+	}
+}
+
+func TestAnalyzer_Quick_InvalidResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "not json"}]}}]}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).Quick(context.Background(), "Hello"); err == nil {
+		t.Error("Quick() error = nil")
+	}
+}
+
+func TestAnalyzer_Quick_CoalescesIdenticalContent(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		// Slow enough for the other analyses to join this call
+		time.Sleep(50 * time.Millisecond)
+		w.Write([]byte(`{"candidates": [{"content": {"parts": [{"text": "{\"sentiment\": \"neutral\", \"sentiment_score\": 0, \"summary\": \"A note.\"}"}]}}]}`))
+	}))
+	defer server.Close()
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).
+		WithLimits(limits.New(limits.Config{PerProvider: map[string]int{limits.ProviderGemini: 2}}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := a.Quick(context.Background(), "The same note, submitted four times."); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("model calls = %d, want identical content analyzed once", got)
+	}
+}
@@ -0,0 +1,33 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// quoteFinder locates text the model quoted in the content it was taken
+// from. Quotes are usually listed in order of appearance, so a repeated
+// phrase is looked for after the previous quote first.
+type quoteFinder struct {
+	content string
+	cursor  int
+}
+
+// find returns the character (rune) offsets of quote in the content, or
+// false when it doesn't occur there
+func (f *quoteFinder) find(quote string) (start, end int, ok bool) {
+	if quote == "" {
+		return 0, 0, false
+	}
+
+	at := strings.Index(f.content[f.cursor:], quote)
+	if at >= 0 {
+		at += f.cursor
+	} else if at = strings.Index(f.content, quote); at < 0 {
+		return 0, 0, false
+	}
+	f.cursor = at + len(quote)
+
+	start = utf8.RuneCountInString(f.content[:at])
+	return start, start + utf8.RuneCountInString(quote), true
+}
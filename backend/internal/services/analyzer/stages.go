@@ -0,0 +1,202 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+	"github.com/sfumato00/content-analyzer/internal/services/keyphrases"
+	"github.com/sfumato00/content-analyzer/internal/services/language"
+	"github.com/sfumato00/content-analyzer/internal/services/readability"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+)
+
+// Names of the built-in stages, in the order they run
+const (
+	StageFindings       = "findings"
+	StageLanguage       = "language"
+	StageModeration     = "moderation"
+	StageAnalysis       = "analysis"
+	StageReadability    = "readability"
+	StageVerification   = "verification"
+	StageClaims         = "claims"
+	StageProofreading   = "proofreading"
+	StageBias           = "bias"
+	StageAIDetection    = "ai_detection"
+	StageClassification = "classification"
+	StageCompliance     = "compliance"
+	StageComparison     = "comparison"
+)
+
+// Rerunnable reports whether a built-in stage can run again on its own
+// over a stored analysis. The findings stage only feeds the main
+// analysis, and every other stage builds on that, so neither can.
+func Rerunnable(stage string) bool {
+	switch stage {
+	case StageLanguage, StageModeration, StageReadability, StageVerification, StageClaims, StageProofreading,
+		StageBias, StageAIDetection, StageClassification, StageCompliance, StageComparison:
+		return true
+	}
+	return false
+}
+
+// registerBuiltins adds the stages every analyzer runs. Each checks the
+// analyzer's options and the submission's profile itself, since both can
+// be set after the stages are registered.
+func (a *Analyzer) registerBuiltins() {
+	a.Register(NewStage(StageFindings, PhasePreprocess, findingsStage))
+	a.Register(NewStage(StageLanguage, PhaseDetectLanguage, languageStage))
+	a.Register(NewStage(StageModeration, PhaseModerate, a.moderationStage))
+	a.Register(NewStage(StageAnalysis, PhaseAnalyze, a.analysisStage))
+	a.Register(NewStage(StageReadability, PhaseAnalyze, readabilityStage))
+	a.Register(NewStage(StageVerification, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if a.verification && run.Profile.Enabled(models.ModuleVerification) && run.Analysis.Summary != "" {
+			a.applyVerification(ctx, run.Submission, run.Analysis)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageClaims, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if a.claims && run.Profile.Enabled(models.ModuleClaims) {
+			a.applyClaims(ctx, run.Submission, run.Analysis)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageProofreading, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if a.proofreading && run.Profile.Enabled(models.ModuleProofreading) {
+			a.applyProofreading(ctx, run.Submission, run.Analysis, run.Glossary)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageBias, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if a.bias && run.Profile.Enabled(models.ModuleBias) {
+			a.applyBias(ctx, run.Submission, run.Analysis)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageAIDetection, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if a.aiDetection && run.Profile.Enabled(models.ModuleAIDetection) {
+			a.applyAIDetection(ctx, run.Submission, run.Analysis)
+		}
+		return nil
+	}))
+	// Organizations classify all their members' submissions, whatever
+	// profile they select
+	a.Register(NewStage(StageClassification, PhaseAnalyze, func(ctx context.Context, run *Run) error {
+		if run.Taxonomy != nil {
+			a.applyClassification(ctx, run.Submission, run.Analysis, run.Taxonomy)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageCompliance, PhasePostprocess, func(ctx context.Context, run *Run) error {
+		if run.Profile.Enabled(models.ModuleCompliance) {
+			a.applyCompliance(run.Submission, run.Analysis, run.Glossary)
+		}
+		return nil
+	}))
+	a.Register(NewStage(StageComparison, PhasePostprocess, func(ctx context.Context, run *Run) error {
+		if run.Submission.PreviousID != nil {
+			a.applyComparison(ctx, run.Submission, run.Analysis)
+		}
+		return nil
+	}))
+}
+
+// findingsStage finds the sensitive data candidates the analysis call is
+// asked about
+func findingsStage(ctx context.Context, run *Run) error {
+	if run.Profile.Enabled(models.ModuleFindings) {
+		run.Findings = sensitive.Detect(run.Submission.Content)
+	}
+	return nil
+}
+
+// languageStage records the content's language
+func languageStage(ctx context.Context, run *Run) error {
+	run.Analysis.Language = language.Detect(run.Submission.Content)
+	return nil
+}
+
+// moderationStage scores the content before it is analyzed. A policy
+// applies whatever modules the owner's profile selects.
+func (a *Analyzer) moderationStage(ctx context.Context, run *Run) error {
+	if !a.moderation {
+		return nil
+	}
+	return a.applyModeration(ctx, run.Submission, run.Analysis, run.Profile.Enabled(models.ModuleModeration))
+}
+
+// analysisStage makes the main model call for the sentiment, topics,
+// keyphrases and summary, and has it confirm the sensitive data findings
+func (a *Analyzer) analysisStage(ctx context.Context, run *Run) error {
+	submission, profile := run.Submission, run.Profile
+
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            buildPrompt(submission.Content, run.Findings),
+		SystemInstruction: instructions.Merge(withGlossary(systemInstruction, run.Glossary, analysisGlossaryUse), analysisFocus(profile, submission)),
+		JSON:              true,
+		Schema:            analysisSchema,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to analyze submission: %w", err)
+	}
+
+	result, err := ParseResult(resp.Text)
+	if err != nil {
+		return err
+	}
+
+	analysis := run.Analysis
+	analysis.Sentiment = result.Sentiment
+	analysis.SentimentScore = result.SentimentScore
+	analysis.Topics = result.Topics
+	analysis.Summary = result.Summary
+
+	if profile.Enabled(models.ModuleKeyphrases) {
+		// RAKE gives a reproducible baseline; the model's picks only reorder
+		// and extend it
+		suggested := make([]string, 0, len(result.Keyphrases))
+		for _, k := range result.Keyphrases {
+			suggested = append(suggested, k.Phrase)
+		}
+		local := keyphrases.Extract(submission.Content, keyphrases.MaxPhrases)
+		analysis.Keyphrases = keyphrases.Refine(submission.Content, local, suggested, keyphrases.MaxPhrases)
+	} else {
+		analysis.Keyphrases = []models.Keyphrase{}
+	}
+	if !profile.Enabled(models.ModuleTopics) {
+		analysis.Topics = []string{}
+	}
+	if !profile.Enabled(models.ModuleSummary) {
+		analysis.Summary = ""
+	}
+
+	// Drop the candidates the model rejected; the rest are now verified
+	findings := run.Findings
+	if len(findings) > 0 {
+		findings = verifyFindings(findings, parseFalsePositives(resp.Text))
+
+		if submission.RedactedContent != nil {
+			if err := a.store.UpdateRedactedContent(ctx, submission.ID, sensitive.Redact(submission.Content, findings)); err != nil {
+				return err
+			}
+		}
+	}
+
+	analysis.Findings = findings
+	analysis.RawResponse = json.RawMessage(resp.Text)
+	analysis.PromptTokens += resp.PromptTokens
+	analysis.OutputTokens += resp.OutputTokens
+	return nil
+}
+
+// readabilityStage computes the readability metrics locally
+func readabilityStage(ctx context.Context, run *Run) error {
+	if run.Profile.Enabled(models.ModuleReadability) {
+		metrics := readability.Compute(run.Submission.Content)
+		run.Analysis.Readability = &metrics
+	}
+	return nil
+}
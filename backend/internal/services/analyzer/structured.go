@@ -0,0 +1,97 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+// ErrMalformedOutput is returned when a model response still doesn't match
+// its schema after the repair attempts. Another attempt at the same
+// content is unlikely to do better, so the analysis fails outright.
+var ErrMalformedOutput = errors.New("malformed model output")
+
+// DefaultRepairs is how many times a response that doesn't match its
+// schema is sent back to the model to be fixed
+const DefaultRepairs = 2
+
+const repairInstruction = `You fix JSON that doesn't match its schema. You are given a response and what is wrong with it. Respond only with the corrected JSON, keeping every value that already fits and changing as little as possible. The JSON must match this schema:
+`
+
+// repairTemperature keeps repairs close to the response they fix
+var repairTemperature = 0.0
+
+// generateStructured runs a generation whose response must match
+// req.Schema. A response that doesn't, after stripping any code fences or
+// text around the JSON, is sent back to the model with the mismatch to be
+// repaired, up to a.repairs times. The tokens of the repairs are added to
+// the response's.
+func (a *Analyzer) generateStructured(ctx context.Context, req ai.GenerateRequest) (*ai.GenerateResponse, error) {
+	resp, err := a.call(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Text = extractJSON(resp.Text)
+	err = req.Schema.Validate([]byte(resp.Text))
+	for attempt := 0; err != nil && attempt < a.repairs; attempt++ {
+		slog.Warn("Model output doesn't match its schema, repairing", "attempt", attempt+1, "error", err)
+
+		repaired, callErr := a.call(ctx, repairRequest(req.Schema, resp.Text, err))
+		if callErr != nil {
+			return nil, callErr
+		}
+		resp.Text = extractJSON(repaired.Text)
+		resp.PromptTokens += repaired.PromptTokens
+		resp.OutputTokens += repaired.OutputTokens
+		err = req.Schema.Validate([]byte(resp.Text))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedOutput, err)
+	}
+	return resp, nil
+}
+
+// repairRequest asks the model to fix a response that failed validation
+func repairRequest(schema *ai.Schema, text string, problem error) ai.GenerateRequest {
+	encoded, _ := json.Marshal(schema)
+
+	var prompt strings.Builder
+	prompt.WriteString("Response:\n")
+	prompt.WriteString(text)
+	prompt.WriteString("\n\nProblem: ")
+	prompt.WriteString(problem.Error())
+
+	return ai.GenerateRequest{
+		Prompt:            prompt.String(),
+		SystemInstruction: repairInstruction + string(encoded),
+		JSON:              true,
+		Schema:            schema,
+		Temperature:       &repairTemperature,
+	}
+}
+
+// extractJSON strips the Markdown code fence or prose a model sometimes
+// wraps its JSON in, keeping from the first opening bracket to the last
+// matching closing one. Text without either is returned as is, to fail
+// validation.
+func extractJSON(text string) string {
+	start := strings.IndexAny(text, "{[")
+	if start < 0 {
+		return strings.TrimSpace(text)
+	}
+	closing := byte('}')
+	if text[start] == '[' {
+		closing = ']'
+	}
+	end := strings.LastIndexByte(text, closing)
+	if end < start {
+		return strings.TrimSpace(text)
+	}
+	return text[start : end+1]
+}
@@ -0,0 +1,84 @@
+package analyzer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{"a": 1}`, `{"a": 1}`},
+		{"```json\n{\"a\": 1}\n```", `{"a": 1}`},
+		{`Here is the analysis: {"a": {"b": 2}} Hope this helps!`, `{"a": {"b": 2}}`},
+		{` [1, 2] `, `[1, 2]`},
+		{` not json `, `not json`},
+		{`{"a": `, `{"a":`},
+	}
+
+	for _, tt := range tests {
+		if got := extractJSON(tt.text); got != tt.want {
+			t.Errorf("extractJSON(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestAnalyzer_Generate_Repairs(t *testing.T) {
+	var repairPrompts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "You fix JSON") {
+			w.Write(geminiText("```json\n{\"confidence\": \"high\"}\n```"))
+			return
+		}
+		repairPrompts = append(repairPrompts, string(body))
+		w.Write(geminiText(`{"confidence": 0.9, "unsupported_claims": []}`))
+	}))
+	defer server.Close()
+
+	a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL}))
+	resp, err := a.generate(context.Background(), ai.GenerateRequest{Prompt: "text", JSON: true, Schema: verifySchema})
+	if err != nil {
+		t.Fatalf("generate() error = %v", err)
+	}
+
+	if resp.Text != `{"confidence": 0.9, "unsupported_claims": []}` {
+		t.Errorf("Text = %q, want the repaired response", resp.Text)
+	}
+	if len(repairPrompts) != 1 || !strings.Contains(repairPrompts[0], "confidence: must be a number") {
+		t.Errorf("repair calls = %q, want one naming the mismatch", repairPrompts)
+	}
+	if resp.PromptTokens != 20 || resp.OutputTokens != 10 {
+		t.Errorf("tokens = %d/%d, want the repair's added", resp.PromptTokens, resp.OutputTokens)
+	}
+}
+
+func TestAnalyzer_Generate_RepairsExhausted(t *testing.T) {
+	for _, repairs := range []int{0, 2} {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Write(geminiText(`I can't help with that.`))
+		}))
+
+		a := NewAnalyzer(nil, ai.NewClient(ai.Options{BaseURL: server.URL})).WithRepairs(repairs)
+		_, err := a.generate(context.Background(), ai.GenerateRequest{Prompt: "text", JSON: true, Schema: verifySchema})
+		server.Close()
+
+		if !errors.Is(err, ErrMalformedOutput) {
+			t.Errorf("repairs %d: generate() error = %v, want ErrMalformedOutput", repairs, err)
+		}
+		if calls != repairs+1 {
+			t.Errorf("repairs %d: model calls = %d, want %d", repairs, calls, repairs+1)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package analyzer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+)
+
+const verifyInstruction = `You check summaries for faithfulness to their source. Respond only with JSON of the form:
+{"confidence": number between 0 and 1, "unsupported_claims": [statements in the summary that the source does not support]}
+A confidence of 1 means every statement in the summary is supported by the source; 0 means the summary contradicts the source or is mostly invented. Judge only the summary, not the quality of the source.`
+
+// verifySchema is the shape of a verification response
+var verifySchema = ai.Object(map[string]*ai.Schema{
+	"confidence":         ai.Number(),
+	"unsupported_claims": ai.Array(ai.String()),
+}, "confidence")
+
+// verifyTemperature keeps scores stable between runs of the same summary
+var verifyTemperature = 0.0
+
+// verification is the outcome of a verification pass
+type verification struct {
+	Confidence        float64
+	UnsupportedClaims []string
+	PromptTokens      int
+	OutputTokens      int
+}
+
+// verify asks the model how well summary is supported by content
+func (a *Analyzer) verify(ctx context.Context, content, summary string) (*verification, error) {
+	resp, err := a.generate(ctx, ai.GenerateRequest{
+		Prompt:            buildVerifyPrompt(content, summary),
+		SystemInstruction: verifyInstruction,
+		JSON:              true,
+		Schema:            verifySchema,
+		Temperature:       &verifyTemperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify summary: %w", err)
+	}
+
+	result, err := parseVerification(resp.Text)
+	if err != nil {
+		return nil, err
+	}
+	result.PromptTokens = resp.PromptTokens
+	result.OutputTokens = resp.OutputTokens
+	return result, nil
+}
+
+// buildVerifyPrompt puts the source and the summary to check side by side
+func buildVerifyPrompt(content, summary string) string {
+	var b strings.Builder
+	b.WriteString("Source:\n")
+	b.WriteString(content)
+	b.WriteString("\n\n---\nSummary:\n")
+	b.WriteString(summary)
+	return b.String()
+}
+
+// parseVerification reads the verification response, clamping the score
+// to [0, 1]. A response without a score is an error.
+func parseVerification(text string) (*verification, error) {
+	var out struct {
+		Confidence        *float64 `json:"confidence"`
+		UnsupportedClaims []string `json:"unsupported_claims"`
+	}
+	if err := json.Unmarshal([]byte(text), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse verification response: %w", err)
+	}
+	if out.Confidence == nil {
+		return nil, fmt.Errorf("verification response has no confidence")
+	}
+
+	return &verification{
+		Confidence:        min(max(*out.Confidence, 0), 1),
+		UnsupportedClaims: out.UnsupportedClaims,
+	}, nil
+}
@@ -0,0 +1,48 @@
+package analyzer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVerification(t *testing.T) {
+	tests := []struct {
+		name       string
+		text       string
+		want       float64
+		wantClaims int
+		wantErr    bool
+	}{
+		{name: "faithful", text: `{"confidence": 0.92, "unsupported_claims": []}`, want: 0.92},
+		{name: "unsupported claims", text: `{"confidence": 0.3, "unsupported_claims": ["The author is a doctor."]}`, want: 0.3, wantClaims: 1},
+		{name: "clamped high", text: `{"confidence": 7}`, want: 1},
+		{name: "clamped low", text: `{"confidence": -0.5}`, want: 0},
+		{name: "no score", text: `{"unsupported_claims": []}`, wantErr: true},
+		{name: "not JSON", text: `Looks fine to me.`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseVerification(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseVerification() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Confidence != tt.want || len(got.UnsupportedClaims) != tt.wantClaims {
+				t.Errorf("parseVerification() = %+v, want confidence %v with %d claims", got, tt.want, tt.wantClaims)
+			}
+		})
+	}
+}
+
+func TestBuildVerifyPrompt(t *testing.T) {
+	got := buildVerifyPrompt("The launch slipped to May.", "The launch was delayed.")
+
+	source := strings.Index(got, "The launch slipped to May.")
+	summary := strings.Index(got, "The launch was delayed.")
+	if source < 0 || summary < 0 || source > summary {
+		t.Errorf("buildVerifyPrompt() = %q, want the source followed by the summary", got)
+	}
+}
@@ -0,0 +1,257 @@
+// Package broker publishes submission lifecycle events to an external
+// message broker as CloudEvents, for larger deployments that consume them
+// in their own systems. Status changes reach it through the job queue
+// after they are committed, so an event is only published for a change
+// that happened, and retried until the broker accepts it.
+package broker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// JobType publishes the events of one status change
+const JobType = "broker.publish"
+
+// Supported drivers
+const (
+	DriverNATS  = "nats"
+	DriverKafka = "kafka"
+)
+
+// Drivers lists the supported driver names
+var Drivers = []string{DriverNATS, DriverKafka}
+
+// DefaultTopic is the Kafka topic events are published to, and the prefix
+// of their NATS subjects
+const DefaultTopic = "content-analyzer"
+
+// DefaultSource identifies this service as the source of its events
+const DefaultSource = "content-analyzer"
+
+// Event types
+const (
+	EventSubmissionCreated = "submission.created"
+	EventAnalysisCompleted = "analysis.completed"
+	EventModerationFlagged = "moderation.flagged"
+)
+
+// publishTimeout bounds a publication when the job's context doesn't
+const publishTimeout = 10 * time.Second
+
+// Event is a CloudEvent in structured mode: the whole event, attributes
+// and data, is the message
+type Event struct {
+	SpecVersion     string      `json:"specversion"`
+	ID              string      `json:"id"`
+	Source          string      `json:"source"`
+	Type            string      `json:"type"`
+	Subject         string      `json:"subject"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype"`
+	Data            interface{} `json:"data"`
+}
+
+// SubmissionCreated is the data of a submission.created event
+type SubmissionCreated struct {
+	SubmissionID uuid.UUID               `json:"submission_id"`
+	UserID       uuid.UUID               `json:"user_id"`
+	Status       models.SubmissionStatus `json:"status"`
+}
+
+// AnalysisCompleted is the data of an analysis.completed event. The
+// content and summary are left out; consumers read them from the API.
+type AnalysisCompleted struct {
+	SubmissionID   uuid.UUID `json:"submission_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	AnalysisID     uuid.UUID `json:"analysis_id"`
+	Sentiment      string    `json:"sentiment"`
+	SentimentScore *float64  `json:"sentiment_score"`
+	Topics         []string  `json:"topics"`
+	Language       string    `json:"language,omitempty"`
+}
+
+// ModerationFlagged is the data of a moderation.flagged event, published
+// when a moderation policy warns about or blocks a submission
+type ModerationFlagged struct {
+	SubmissionID uuid.UUID                `json:"submission_id"`
+	UserID       uuid.UUID                `json:"user_id"`
+	Action       models.PolicyAction      `json:"action"`
+	Violations   []models.PolicyViolation `json:"violations"`
+	Overridden   bool                     `json:"overridden"`
+}
+
+// Publisher sends events to a broker. Publish returns once the broker has
+// accepted the event.
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+	Close() error
+}
+
+// Config selects and configures a driver
+type Config struct {
+	Driver string
+	// URL locates the broker: nats://[user:pass@]host[:port] (tls:// to
+	// connect with TLS), or kafka://[user:pass@]host:port[,host:port]
+	// (kafka+tls:// to connect with TLS)
+	URL string
+	// Topic is the Kafka topic, or the NATS subject prefix each event's
+	// type is appended to
+	Topic string
+}
+
+// ValidDriver reports whether name is a supported driver
+func ValidDriver(name string) bool {
+	return slices.Contains(Drivers, name)
+}
+
+// NewPublisher creates the configured publisher. Check the name with
+// ValidDriver first.
+func NewPublisher(cfg Config) (Publisher, error) {
+	if cfg.Topic == "" {
+		cfg.Topic = DefaultTopic
+	}
+
+	switch cfg.Driver {
+	case DriverNATS:
+		return newNATS(cfg)
+	case DriverKafka:
+		return newKafka(cfg)
+	default:
+		return nil, fmt.Errorf("unknown event broker driver: %s", cfg.Driver)
+	}
+}
+
+// Enqueuer schedules publications
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// Subscriber queues the publication of the events of every status change
+// that has any, so a broker outage only delays its own jobs
+func Subscriber(jobs Enqueuer) events.Subscriber {
+	return func(ctx context.Context, change models.StatusChange) error {
+		if change.From != "" && change.To != models.StatusCompleted {
+			return nil
+		}
+		if _, err := jobs.Enqueue(ctx, JobType, change); err != nil {
+			return fmt.Errorf("failed to enqueue event publication: %w", err)
+		}
+		return nil
+	}
+}
+
+// AnalysisSource reads the analyses events describe;
+// *models.SubmissionStore implements it
+type AnalysisSource interface {
+	GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error)
+}
+
+// Relay publishes the events of the status changes Subscriber queued
+type Relay struct {
+	analyses  AnalysisSource
+	publisher Publisher
+	source    string
+}
+
+// NewRelay creates a relay publishing events from source
+func NewRelay(analyses AnalysisSource, publisher Publisher, source string) *Relay {
+	if source == "" {
+		source = DefaultSource
+	}
+	return &Relay{analyses: analyses, publisher: publisher, source: source}
+}
+
+// Handle implements queue.Handler for JobType. A retried job publishes
+// the same events again with the same IDs, so consumers can drop the
+// copies.
+func (r *Relay) Handle(ctx context.Context, job *queue.Job) error {
+	var change models.StatusChange
+	if err := job.Decode(&change); err != nil {
+		return fmt.Errorf("invalid event publication payload: %w", err)
+	}
+
+	evts, err := r.Events(ctx, change)
+	if err != nil {
+		return err
+	}
+	for _, event := range evts {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			return fmt.Errorf("failed to publish %s event: %w", event.Type, err)
+		}
+	}
+	return nil
+}
+
+// Events returns the events a status change publishes: submission.created
+// for a new submission, and analysis.completed, followed by
+// moderation.flagged when a policy didn't allow the content, for a
+// completed one
+func (r *Relay) Events(ctx context.Context, change models.StatusChange) ([]*Event, error) {
+	if change.From == "" {
+		return []*Event{r.event(EventSubmissionCreated, change, SubmissionCreated{
+			SubmissionID: change.SubmissionID,
+			UserID:       change.UserID,
+			Status:       change.To,
+		})}, nil
+	}
+	if change.To != models.StatusCompleted {
+		return nil, nil
+	}
+
+	analysis, err := r.analyses.GetAnalysis(ctx, change.UserID, change.SubmissionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The submission was deleted
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load analysis: %w", err)
+	}
+
+	evts := []*Event{r.event(EventAnalysisCompleted, change, AnalysisCompleted{
+		SubmissionID:   change.SubmissionID,
+		UserID:         change.UserID,
+		AnalysisID:     analysis.ID,
+		Sentiment:      analysis.Sentiment,
+		SentimentScore: analysis.SentimentScore,
+		Topics:         analysis.Topics,
+		Language:       analysis.Language,
+	})}
+	if decision := analysis.PolicyDecision; decision != nil && decision.Action != models.PolicyAllow {
+		evts = append(evts, r.event(EventModerationFlagged, change, ModerationFlagged{
+			SubmissionID: change.SubmissionID,
+			UserID:       change.UserID,
+			Action:       decision.Action,
+			Violations:   decision.Violations,
+			Overridden:   decision.Override != nil,
+		}))
+	}
+	return evts, nil
+}
+
+// event wraps data in a CloudEvent about the change's submission. Its ID
+// is derived from the change, so it is the same each time the change is
+// published.
+func (r *Relay) event(eventType string, change models.StatusChange, data interface{}) *Event {
+	at := change.At.UTC()
+	return &Event{
+		SpecVersion:     "1.0",
+		ID:              uuid.NewSHA1(change.SubmissionID, []byte(eventType+"@"+at.Format(time.RFC3339Nano))).String(),
+		Source:          r.source,
+		Type:            eventType,
+		Subject:         change.SubmissionID.String(),
+		Time:            at,
+		DataContentType: "application/json",
+		Data:            data,
+	}
+}
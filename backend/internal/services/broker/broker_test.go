@@ -0,0 +1,392 @@
+package broker
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// fakeEnqueuer records the jobs it is given
+type fakeEnqueuer struct {
+	jobs []*queue.Job
+}
+
+func (q *fakeEnqueuer) Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{Type: jobType, Payload: data}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+// fakeAnalyses returns the analysis of each submission, or pgx.ErrNoRows
+type fakeAnalyses map[uuid.UUID]*models.Analysis
+
+func (a fakeAnalyses) GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error) {
+	if analysis, ok := a[submissionID]; ok {
+		return analysis, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+// fakePublisher records the events it is given, failing when err is set
+type fakePublisher struct {
+	events []*Event
+	err    error
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, event *Event) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *fakePublisher) Close() error { return nil }
+
+func TestSubscriber(t *testing.T) {
+	q := &fakeEnqueuer{}
+	subscriber := Subscriber(q)
+
+	changes := []models.StatusChange{
+		{SubmissionID: uuid.New(), To: models.StatusQueued},
+		{SubmissionID: uuid.New(), From: models.StatusQueued, To: models.StatusProcessing},
+		{SubmissionID: uuid.New(), From: models.StatusProcessing, To: models.StatusCompleted},
+		{SubmissionID: uuid.New(), From: models.StatusProcessing, To: models.StatusFailed},
+	}
+	for _, change := range changes {
+		if err := subscriber(context.Background(), change); err != nil {
+			t.Fatalf("subscriber() error = %v", err)
+		}
+	}
+
+	if len(q.jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2 for the creation and the completion", len(q.jobs))
+	}
+	for _, job := range q.jobs {
+		if job.Type != JobType {
+			t.Errorf("job type = %q, want %q", job.Type, JobType)
+		}
+	}
+}
+
+func TestRelay_Events(t *testing.T) {
+	userID, created, completed, flagged := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	score := 0.8
+	analyses := fakeAnalyses{
+		completed: {ID: uuid.New(), SubmissionID: completed, Sentiment: "positive", SentimentScore: &score, Topics: []string{"phones"}, Language: "en"},
+		flagged: {ID: uuid.New(), SubmissionID: flagged, PolicyDecision: &models.PolicyDecision{
+			Action:       models.PolicyBlock,
+			PolicyAction: models.PolicyBlock,
+			Violations:   []models.PolicyViolation{{Category: models.ModerationToxicity, Score: 0.9, Threshold: 0.5, Action: models.PolicyBlock}},
+		}},
+	}
+	relay := NewRelay(analyses, &fakePublisher{}, "")
+	at := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		change models.StatusChange
+		want   []string
+	}{
+		{name: "created", change: models.StatusChange{SubmissionID: created, UserID: userID, To: models.StatusDraft, At: timestamp.New(at)}, want: []string{EventSubmissionCreated}},
+		{name: "processing", change: models.StatusChange{SubmissionID: created, UserID: userID, From: models.StatusQueued, To: models.StatusProcessing, At: timestamp.New(at)}},
+		{name: "completed", change: models.StatusChange{SubmissionID: completed, UserID: userID, From: models.StatusProcessing, To: models.StatusCompleted, At: timestamp.New(at)}, want: []string{EventAnalysisCompleted}},
+		{name: "flagged", change: models.StatusChange{SubmissionID: flagged, UserID: userID, From: models.StatusProcessing, To: models.StatusCompleted, At: timestamp.New(at)}, want: []string{EventAnalysisCompleted, EventModerationFlagged}},
+		{name: "deleted", change: models.StatusChange{SubmissionID: uuid.New(), UserID: userID, From: models.StatusProcessing, To: models.StatusCompleted, At: timestamp.New(at)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evts, err := relay.Events(context.Background(), tt.change)
+			if err != nil {
+				t.Fatalf("Events() error = %v", err)
+			}
+
+			var types []string
+			for _, e := range evts {
+				types = append(types, e.Type)
+				if e.SpecVersion != "1.0" || e.Source != DefaultSource || e.Subject != tt.change.SubmissionID.String() || !e.Time.Equal(at) {
+					t.Errorf("event = %+v", e)
+				}
+			}
+			if strings.Join(types, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("types = %v, want %v", types, tt.want)
+			}
+		})
+	}
+
+	// A retried change publishes the same IDs
+	change := models.StatusChange{SubmissionID: flagged, UserID: userID, From: models.StatusProcessing, To: models.StatusCompleted, At: timestamp.New(at)}
+	first, _ := relay.Events(context.Background(), change)
+	second, _ := relay.Events(context.Background(), change)
+	if first[0].ID != second[0].ID || first[0].ID == first[1].ID {
+		t.Errorf("IDs = %s, %s then %s, %s; want stable and distinct", first[0].ID, first[1].ID, second[0].ID, second[1].ID)
+	}
+
+	data, _ := json.Marshal(first[1].Data)
+	var got ModerationFlagged
+	json.Unmarshal(data, &got)
+	if got.Action != models.PolicyBlock || len(got.Violations) != 1 || got.Overridden {
+		t.Errorf("moderation.flagged data = %+v", got)
+	}
+}
+
+func TestRelay_Handle(t *testing.T) {
+	q := &fakeEnqueuer{}
+	Subscriber(q)(context.Background(), models.StatusChange{SubmissionID: uuid.New(), To: models.StatusQueued, At: timestamp.Now()})
+
+	publisher := &fakePublisher{}
+	if err := NewRelay(fakeAnalyses{}, publisher, "https://analyzer.example.com").Handle(context.Background(), q.jobs[0]); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0].Source != "https://analyzer.example.com" {
+		t.Errorf("published %+v", publisher.events)
+	}
+
+	publisher.err = errors.New("broker unavailable")
+	if err := NewRelay(fakeAnalyses{}, publisher, "").Handle(context.Background(), q.jobs[0]); err == nil {
+		t.Error("Handle() succeeded while the broker is down, want an error to retry")
+	}
+}
+
+// newKafkaCluster starts an in-process Kafka cluster with the default
+// topic in 3 partitions, and returns its URL
+func newKafkaCluster(t *testing.T, opts ...kfake.Opt) (*kfake.Cluster, string) {
+	t.Helper()
+
+	cluster, err := kfake.NewCluster(append([]kfake.Opt{kfake.NumBrokers(1), kfake.SeedTopics(3, DefaultTopic)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster, "kafka://" + strings.Join(cluster.ListenAddrs(), ",")
+}
+
+// consumeKafka reads n records of the default topic from the cluster at
+// seeds
+func consumeKafka(t *testing.T, seeds []string, n int) []*kgo.Record {
+	t.Helper()
+
+	consumer, err := kgo.NewClient(kgo.SeedBrokers(seeds...), kgo.ConsumeTopics(DefaultTopic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer consumer.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var records []*kgo.Record
+	for len(records) < n {
+		fetches := consumer.PollFetches(ctx)
+		if err := ctx.Err(); err != nil {
+			t.Fatalf("consumed %d records, want %d: %v", len(records), n, err)
+		}
+		records = append(records, fetches.Records()...)
+	}
+	return records
+}
+
+func TestKafka_Publish(t *testing.T) {
+	cluster, brokerURL := newKafkaCluster(t)
+	publisher, err := NewPublisher(Config{Driver: DriverKafka, URL: brokerURL})
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	// A submission's events share a key, and so a partition, in order
+	subject := uuid.NewString()
+	types := []string{EventSubmissionCreated, EventAnalysisCompleted, EventModerationFlagged}
+	for i, eventType := range types {
+		event := &Event{ID: strconv.Itoa(i), Type: eventType, Subject: subject}
+		if err := publisher.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	records := consumeKafka(t, cluster.ListenAddrs(), len(types))
+	for i, record := range records {
+		var event Event
+		if err := json.Unmarshal(record.Value, &event); err != nil {
+			t.Fatal(err)
+		}
+		if string(record.Key) != subject || record.Partition != records[0].Partition || event.Type != types[i] {
+			t.Errorf("record %d = key %s, partition %d, type %s, want %s on one partition", i, record.Key, record.Partition, event.Type, types[i])
+		}
+		if len(record.Headers) != 1 || string(record.Headers[0].Value) != "application/cloudevents+json" {
+			t.Errorf("record %d headers = %+v, want the CloudEvents content type", i, record.Headers)
+		}
+	}
+}
+
+func TestKafka_Publish_SASL(t *testing.T) {
+	_, brokerURL := newKafkaCluster(t, kfake.EnableSASL(), kfake.Superuser("SCRAM-SHA-256", "events", "s3cret"))
+
+	tests := []struct {
+		name    string
+		userURL string
+		wantErr bool
+	}{
+		{name: "SCRAM", userURL: "kafka://events:s3cret@" + brokerURL[len("kafka://"):] + "?mechanism=SCRAM-SHA-256"},
+		{name: "wrong password", userURL: "kafka://events:wrong@" + brokerURL[len("kafka://"):] + "?mechanism=SCRAM-SHA-256", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publisher, err := NewPublisher(Config{Driver: DriverKafka, URL: tt.userURL})
+			if err != nil {
+				t.Fatalf("NewPublisher() error = %v", err)
+			}
+			defer publisher.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err = publisher.Publish(ctx, &Event{Type: EventSubmissionCreated, Subject: uuid.NewString()})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Publish() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// natsServer is a minimal NATS server accepting one connection. It
+// records the CONNECT and each PUB's subject and payload, and answers a
+// PUB to a subject ending in ".reject" with -ERR.
+type natsServer struct {
+	listener net.Listener
+	connect  chan string
+	pubs     chan [2]string
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &natsServer{listener: listener, connect: make(chan string, 1), pubs: make(chan [2]string, 10)}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *natsServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *natsServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.Write([]byte(`INFO {"server_id":"test","max_payload":1048576}` + "\r\n"))
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "CONNECT":
+			s.connect <- strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+		case "PUB":
+			size, _ := strconv.Atoi(fields[2])
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				return
+			}
+			if strings.HasSuffix(fields[1], ".reject") {
+				conn.Write([]byte("-ERR 'Permissions Violation for Publish'\r\n"))
+				continue
+			}
+			s.pubs <- [2]string{fields[1], string(payload[:size])}
+		case "PING":
+			conn.Write([]byte("PONG\r\n"))
+		}
+	}
+}
+
+func TestNATS_Publish(t *testing.T) {
+	server := newNATSServer(t)
+
+	publisher, err := NewPublisher(Config{Driver: DriverNATS, URL: "nats://svc:secret@" + server.listener.Addr().String(), Topic: "acme"})
+	if err != nil {
+		t.Fatalf("NewPublisher() error = %v", err)
+	}
+	defer publisher.Close()
+
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), &Event{ID: strconv.Itoa(i), Type: EventAnalysisCompleted}); err != nil {
+			t.Fatalf("Publish() error = %v", err)
+		}
+	}
+
+	var connect struct {
+		User    string `json:"user"`
+		Pass    string `json:"pass"`
+		Verbose bool   `json:"verbose"`
+	}
+	json.Unmarshal([]byte(<-server.connect), &connect)
+	if connect.User != "svc" || connect.Pass != "secret" || connect.Verbose {
+		t.Errorf("CONNECT = %+v", connect)
+	}
+	if len(server.connect) != 0 {
+		t.Error("reconnected between publications")
+	}
+
+	for i := 0; i < 2; i++ {
+		pub := <-server.pubs
+		var event Event
+		json.Unmarshal([]byte(pub[1]), &event)
+		if pub[0] != "acme.analysis.completed" || event.ID != strconv.Itoa(i) {
+			t.Errorf("PUB %s %s", pub[0], pub[1])
+		}
+	}
+
+	if err := publisher.Publish(context.Background(), &Event{Type: "reject"}); err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Errorf("Publish() error = %v, want the server's -ERR", err)
+	}
+}
+
+func TestNewPublisher_InvalidURL(t *testing.T) {
+	tests := []Config{
+		{Driver: DriverNATS, URL: "http://localhost:4222"},
+		{Driver: DriverNATS, URL: "nats://"},
+		{Driver: DriverKafka, URL: "http://kafka-rest:8082"},
+		{Driver: DriverKafka, URL: "kafka://localhost"},
+		{Driver: DriverKafka, URL: "kafka://u:p@localhost:9092?mechanism=GSSAPI"},
+		{Driver: "rabbitmq", URL: "amqp://localhost"},
+	}
+	for _, cfg := range tests {
+		if _, err := NewPublisher(cfg); err == nil {
+			t.Errorf("NewPublisher(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package broker
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// kafkaPublisher produces to Kafka with the franz-go client. Each event
+// is a record of the topic keyed by its submission, so the default
+// partitioner puts a submission's events on one partition, and the
+// idempotent producer keeps them in order there through retries.
+type kafkaPublisher struct {
+	opts []kgo.Opt
+
+	mu     sync.Mutex
+	client *kgo.Client
+}
+
+func newKafka(cfg Config) (*kafkaPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "kafka" && u.Scheme != "kafka+tls") || u.Host == "" {
+		return nil, errors.New("the Kafka URL must be kafka://host:port[,host:port] or kafka+tls://...")
+	}
+
+	var seeds []string
+	for _, seed := range strings.Split(u.Host, ",") {
+		if _, _, err := net.SplitHostPort(seed); err != nil {
+			return nil, fmt.Errorf("invalid Kafka broker address %q: %w", seed, err)
+		}
+		seeds = append(seeds, seed)
+	}
+
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(seeds...),
+		kgo.DefaultProduceTopic(cfg.Topic),
+		kgo.ClientID("content-analyzer"),
+		kgo.ProduceRequestTimeout(publishTimeout),
+	}
+	if u.Scheme == "kafka+tls" {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	if u.User != nil {
+		mechanism, err := kafkaSASL(u.User, u.Query().Get("mechanism"))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	return &kafkaPublisher{opts: opts}, nil
+}
+
+// kafkaSASL authenticates as the URL's user with PLAIN, or with SCRAM
+// when the mechanism parameter names it
+func kafkaSASL(user *url.Userinfo, mechanism string) (sasl.Mechanism, error) {
+	password, _ := user.Password()
+	scramAuth := scram.Auth{User: user.Username(), Pass: password}
+
+	switch strings.ToUpper(mechanism) {
+	case "", "PLAIN":
+		return plain.Auth{User: user.Username(), Pass: password}.AsMechanism(), nil
+	case "SCRAM-SHA-256":
+		return scramAuth.AsSha256Mechanism(), nil
+	case "SCRAM-SHA-512":
+		return scramAuth.AsSha512Mechanism(), nil
+	default:
+		return nil, fmt.Errorf("unsupported Kafka SASL mechanism %q", mechanism)
+	}
+}
+
+// Publish implements Publisher, waiting until the brokers acknowledge the
+// record. The client is created on first use; it connects and reconnects
+// to the brokers on its own.
+func (p *kafkaPublisher) Publish(ctx context.Context, event *Event) error {
+	value, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	client, err := p.connect()
+	if err != nil {
+		return err
+	}
+
+	record := &kgo.Record{
+		Key:     []byte(event.Subject),
+		Value:   value,
+		Headers: []kgo.RecordHeader{{Key: "content-type", Value: []byte("application/cloudevents+json")}},
+	}
+	if err := client.ProduceSync(ctx, record).FirstErr(); err != nil {
+		return fmt.Errorf("failed to produce to Kafka: %w", err)
+	}
+	return nil
+}
+
+func (p *kafkaPublisher) connect() (*kgo.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client == nil {
+		client, err := kgo.NewClient(p.opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Kafka client: %w", err)
+		}
+		p.client = client
+	}
+	return p.client, nil
+}
+
+// Close implements Publisher, waiting for records still being produced
+func (p *kafkaPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.client != nil {
+		p.client.Close()
+		p.client = nil
+	}
+	return nil
+}
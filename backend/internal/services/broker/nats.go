@@ -0,0 +1,97 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsPublisher publishes to core NATS with the NATS Go client. Each event
+// goes to the subject <topic>.<type>, e.g.
+// content-analyzer.analysis.completed, and is followed by a flush whose
+// round trip confirms the server processed it.
+type natsPublisher struct {
+	url   string
+	topic string
+
+	mu   sync.Mutex
+	conn *nats.Conn
+}
+
+func newNATS(cfg Config) (*natsPublisher, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil || (u.Scheme != "nats" && u.Scheme != "tls") || u.Hostname() == "" {
+		return nil, errors.New("the NATS URL must be nats://host[:port] or tls://host[:port]")
+	}
+	// Credentials in the URL are sent by the client: a user with a
+	// password, or a user alone as a token
+	return &natsPublisher{url: cfg.URL, topic: cfg.Topic}, nil
+}
+
+// Publish implements Publisher. The connection is opened on first use and
+// again after any failure.
+func (p *natsPublisher) Publish(ctx context.Context, event *Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.publish(ctx, p.topic+"."+event.Type, payload); err != nil {
+		p.closeConn()
+		return err
+	}
+	return nil
+}
+
+func (p *natsPublisher) publish(ctx context.Context, subject string, payload []byte) error {
+	if p.conn == nil {
+		conn, err := nats.Connect(p.url, nats.Name("content-analyzer"), nats.Timeout(publishTimeout))
+		if err != nil {
+			return fmt.Errorf("failed to connect to NATS: %w", err)
+		}
+		p.conn = conn
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, publishTimeout)
+		defer cancel()
+	}
+
+	if err := p.conn.Publish(subject, payload); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("failed to publish to NATS: %w", err)
+	}
+	// A rejected publication comes back as an -ERR before the flush's
+	// PONG. Connections are dropped after any error, so one recorded
+	// here is this publication's.
+	if err := p.conn.LastError(); err != nil {
+		return fmt.Errorf("NATS error: %w", err)
+	}
+	return nil
+}
+
+func (p *natsPublisher) closeConn() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+// Close implements Publisher
+func (p *natsPublisher) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closeConn()
+	return nil
+}
@@ -0,0 +1,103 @@
+// Package compliance finds the phrases an organization's glossary bans in
+// text.
+package compliance
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Check returns the uses of banned phrases in text ordered by position.
+// Matching ignores case and treats any run of whitespace in a phrase as
+// one space, and a phrase only matches whole words, so "lie" isn't found
+// in "client". Where phrases overlap the longer one is reported. Offsets
+// are in characters (runes), not bytes.
+func Check(text string, banned []models.GlossaryEntry) []models.ComplianceFlag {
+	type span struct {
+		entry      models.GlossaryEntry
+		start, end int // byte offsets
+	}
+
+	sorted := append([]models.GlossaryEntry(nil), banned...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utf8.RuneCountInString(sorted[i].Phrase) > utf8.RuneCountInString(sorted[j].Phrase)
+	})
+
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, entry := range sorted {
+		pattern := phrasePattern(entry.Phrase)
+		if pattern == nil {
+			continue
+		}
+		for _, loc := range pattern.FindAllStringIndex(text, -1) {
+			if !wholeWords(text, loc[0], loc[1]) || overlaps(loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{entry: entry, start: loc[0], end: loc[1]})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	flags := make([]models.ComplianceFlag, 0, len(spans))
+	for _, s := range spans {
+		flags = append(flags, models.ComplianceFlag{
+			Phrase:      s.entry.Phrase,
+			Start:       utf8.RuneCountInString(text[:s.start]),
+			End:         utf8.RuneCountInString(text[:s.end]),
+			Replacement: s.entry.Replacement,
+		})
+	}
+	return flags
+}
+
+// phrasePattern matches phrase case-insensitively with any whitespace
+// between its words, or is nil for a blank phrase
+func phrasePattern(phrase string) *regexp.Regexp {
+	words := strings.Fields(phrase)
+	if len(words) == 0 {
+		return nil
+	}
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	return regexp.MustCompile(`(?i)` + strings.Join(words, `\s+`))
+}
+
+// wholeWords reports whether text[start:end] doesn't begin or end inside
+// a word. The check only applies where the match itself starts or ends
+// with a word character, so a phrase like "#1" still matches after a
+// letter-free boundary.
+func wholeWords(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	if isWord(first) && start > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWord(before) {
+			return false
+		}
+	}
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if isWord(last) && end < len(text) {
+		if after, _ := utf8.DecodeRuneInString(text[end:]); isWord(after) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWord(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
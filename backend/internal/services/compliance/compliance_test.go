@@ -0,0 +1,92 @@
+package compliance
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestCheck(t *testing.T) {
+	banned := func(phrases ...string) []models.GlossaryEntry {
+		entries := make([]models.GlossaryEntry, 0, len(phrases))
+		for _, p := range phrases {
+			entries = append(entries, models.GlossaryEntry{Kind: models.GlossaryBanned, Phrase: p})
+		}
+		return entries
+	}
+
+	tests := []struct {
+		name   string
+		text   string
+		banned []models.GlossaryEntry
+		want   []models.ComplianceFlag
+	}{
+		{
+			name:   "ignores case",
+			text:   "This is a GUARANTEED win",
+			banned: banned("guaranteed"),
+			want:   []models.ComplianceFlag{{Phrase: "guaranteed", Start: 10, End: 20}},
+		},
+		{
+			name:   "whole words only",
+			text:   "Our client said so",
+			banned: banned("lie"),
+			want:   []models.ComplianceFlag{},
+		},
+		{
+			name:   "any whitespace between words",
+			text:   "Results are risk\n free",
+			banned: banned("risk free"),
+			want:   []models.ComplianceFlag{{Phrase: "risk free", Start: 12, End: 22}},
+		},
+		{
+			name:   "longer phrase wins overlap",
+			text:   "A risk free offer",
+			banned: banned("free", "risk free"),
+			want:   []models.ComplianceFlag{{Phrase: "risk free", Start: 2, End: 11}},
+		},
+		{
+			name:   "ordered by position",
+			text:   "Cheap and free, cheap again",
+			banned: banned("free", "cheap"),
+			want: []models.ComplianceFlag{
+				{Phrase: "cheap", Start: 0, End: 5},
+				{Phrase: "free", Start: 10, End: 14},
+				{Phrase: "cheap", Start: 16, End: 21},
+			},
+		},
+		{
+			name:   "character offsets",
+			text:   "Café crème: best-in-class",
+			banned: banned("best-in-class"),
+			want:   []models.ComplianceFlag{{Phrase: "best-in-class", Start: 12, End: 25}},
+		},
+		{
+			name:   "punctuation edges",
+			text:   "We're #1!",
+			banned: banned("#1"),
+			want:   []models.ComplianceFlag{{Phrase: "#1", Start: 6, End: 8}},
+		},
+		{
+			name:   "replacement",
+			text:   "Click here",
+			banned: []models.GlossaryEntry{{Kind: models.GlossaryBanned, Phrase: "click here", Replacement: "read the guide"}},
+			want:   []models.ComplianceFlag{{Phrase: "click here", Start: 0, End: 10, Replacement: "read the guide"}},
+		},
+		{
+			name:   "nothing banned",
+			text:   "Anything goes",
+			banned: nil,
+			want:   []models.ComplianceFlag{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.text, tt.banned); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package contenthash recomputes submissions' content hashes in the
+// background when the way they are keyed changes: when encryption is
+// turned on or off, or its master key is rotated.
+package contenthash
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the content rehash job in the queue
+	JobType = "contenthash.rehash"
+
+	// BatchSize is how many submissions one batch rehashes. Each one may
+	// be decrypted or read back from object storage.
+	BatchSize = 100
+)
+
+// Rehasher recomputes content hashes; *models.SubmissionStore implements it
+type Rehasher interface {
+	RehashContent(ctx context.Context, after uuid.UUID, limit int) (uuid.UUID, int, error)
+}
+
+// Job rehashes the content of every submission not hashed the way new
+// content is. It is enqueued at startup, since keys only change then.
+type Job struct {
+	store Rehasher
+}
+
+// NewJob creates a new content rehash job
+func NewJob(store Rehasher) *Job {
+	return &Job{store: store}
+}
+
+// Handle implements queue.Handler for the content rehash job. It goes
+// through submissions in batches until none are left.
+func (j *Job) Handle(ctx context.Context, job *queue.Job) error {
+	var after uuid.UUID
+	total := 0
+	for {
+		last, n, err := j.store.RehashContent(ctx, after, BatchSize)
+		total += n
+		if err != nil {
+			slog.Error("Content rehash stopped", "submissions", total, "error", err)
+			return err
+		}
+		if n < BatchSize {
+			break
+		}
+		after = last
+	}
+
+	if total > 0 {
+		slog.Info("Content hashes recomputed", "submissions", total)
+	}
+	return nil
+}
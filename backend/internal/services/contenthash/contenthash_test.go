@@ -0,0 +1,57 @@
+package contenthash
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeRehasher rehashes a fixed list of submissions, sorted by ID
+type fakeRehasher struct {
+	ids      []uuid.UUID
+	rehashed []uuid.UUID
+	err      error
+}
+
+func (f *fakeRehasher) RehashContent(ctx context.Context, after uuid.UUID, limit int) (uuid.UUID, int, error) {
+	if f.err != nil {
+		return after, 0, f.err
+	}
+	n := 0
+	for _, id := range f.ids {
+		if n == limit {
+			break
+		}
+		if id.String() > after.String() {
+			f.rehashed = append(f.rehashed, id)
+			after = id
+			n++
+		}
+	}
+	return after, n, nil
+}
+
+func TestJob_Handle(t *testing.T) {
+	store := &fakeRehasher{}
+	for range 2*BatchSize + 1 {
+		store.ids = append(store.ids, uuid.New())
+	}
+	slices.SortFunc(store.ids, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+
+	if err := NewJob(store).Handle(context.Background(), &queue.Job{Type: JobType}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if !slices.Equal(store.rehashed, store.ids) {
+		t.Errorf("Handle() rehashed %d submissions, want each of %d once", len(store.rehashed), len(store.ids))
+	}
+
+	store = &fakeRehasher{err: errors.New("connection refused")}
+	if err := NewJob(store).Handle(context.Background(), &queue.Job{Type: JobType}); err == nil {
+		t.Error("Handle() error = nil, want the store's error")
+	}
+}
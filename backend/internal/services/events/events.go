@@ -0,0 +1,94 @@
+// Package events delivers submission status changes to subscribers in the
+// background. Stores publish changes onto the job queue so slow or failing
+// subscribers never hold up a request.
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// StatusChangedJobType carries a models.StatusChange to the dispatcher
+const StatusChangedJobType = "events.submission_status_changed"
+
+// Enqueuer schedules background jobs
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// Publisher queues status changes for delivery. It implements
+// models.StatusListener.
+type Publisher struct {
+	queue Enqueuer
+}
+
+// NewPublisher creates a new publisher
+func NewPublisher(q Enqueuer) *Publisher {
+	return &Publisher{queue: q}
+}
+
+// StatusChanged queues a status change. Events are best effort: a failure
+// is logged rather than undoing the change that was already committed.
+func (p *Publisher) StatusChanged(ctx context.Context, change models.StatusChange) {
+	if _, err := p.queue.Enqueue(ctx, StatusChangedJobType, change); err != nil {
+		slog.Error("Failed to publish status change",
+			"submission_id", change.SubmissionID,
+			"to", change.To,
+			"error", err,
+		)
+	}
+}
+
+// Subscriber receives status changes. Returning an error retries the
+// event for every subscriber, so subscribers should be idempotent.
+type Subscriber func(ctx context.Context, change models.StatusChange) error
+
+// Dispatcher fans status change jobs out to subscribers
+type Dispatcher struct {
+	mu          sync.RWMutex
+	subscribers []Subscriber
+}
+
+// NewDispatcher creates a dispatcher with no subscribers
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+// Subscribe adds a subscriber for every status change
+func (d *Dispatcher) Subscribe(s Subscriber) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers = append(d.subscribers, s)
+}
+
+// Handle implements queue.Handler for StatusChangedJobType
+func (d *Dispatcher) Handle(ctx context.Context, job *queue.Job) error {
+	var change models.StatusChange
+	if err := job.Decode(&change); err != nil {
+		return fmt.Errorf("invalid status change payload: %w", err)
+	}
+
+	slog.Info("Submission status changed",
+		"submission_id", change.SubmissionID,
+		"from", change.From,
+		"to", change.To,
+	)
+
+	d.mu.RLock()
+	subscribers := d.subscribers
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := s(ctx, change); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
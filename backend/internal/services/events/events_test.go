@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// recordingQueue keeps enqueued jobs so tests can hand them to the dispatcher
+type recordingQueue struct {
+	jobs []*queue.Job
+	err  error
+}
+
+func (q *recordingQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{ID: uuid.NewString(), Type: jobType, Payload: data}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+func TestPublisherAndDispatcher(t *testing.T) {
+	q := &recordingQueue{}
+	change := models.StatusChange{
+		SubmissionID: uuid.New(),
+		UserID:       uuid.New(),
+		From:         models.StatusProcessing,
+		To:           models.StatusCompleted,
+		At:           timestamp.New(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)),
+	}
+
+	NewPublisher(q).StatusChanged(context.Background(), change)
+
+	if len(q.jobs) != 1 || q.jobs[0].Type != StatusChangedJobType {
+		t.Fatalf("StatusChanged() queued %+v, want one %s job", q.jobs, StatusChangedJobType)
+	}
+
+	var got []models.StatusChange
+	d := NewDispatcher()
+	d.Subscribe(func(ctx context.Context, c models.StatusChange) error {
+		got = append(got, c)
+		return nil
+	})
+
+	if err := d.Handle(context.Background(), q.jobs[0]); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(got) != 1 || got[0] != change {
+		t.Errorf("subscriber received %+v, want [%+v]", got, change)
+	}
+}
+
+func TestPublisher_EnqueueFailure(t *testing.T) {
+	q := &recordingQueue{err: errors.New("redis down")}
+
+	// Must not panic or block; the change is already committed
+	NewPublisher(q).StatusChanged(context.Background(), models.StatusChange{To: models.StatusQueued})
+}
+
+func TestDispatcher_SubscriberError(t *testing.T) {
+	d := NewDispatcher()
+	calls := 0
+	d.Subscribe(func(ctx context.Context, c models.StatusChange) error {
+		calls++
+		return errors.New("webhook unreachable")
+	})
+	d.Subscribe(func(ctx context.Context, c models.StatusChange) error {
+		calls++
+		return nil
+	})
+
+	err := d.Handle(context.Background(), &queue.Job{Payload: json.RawMessage(`{"to":"queued"}`)})
+	if err == nil {
+		t.Error("Handle() error = nil, want the subscriber's error so the job retries")
+	}
+	if calls != 2 {
+		t.Errorf("subscribers called %d times, want 2", calls)
+	}
+}
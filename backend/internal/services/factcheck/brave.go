@@ -0,0 +1,64 @@
+package factcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultBraveBaseURL = "https://api.search.brave.com/res/v1"
+
+// brave searches with the Brave Search API
+type brave struct {
+	apiKey     string
+	baseURL    string
+	maxResults int
+	httpClient *http.Client
+}
+
+func newBrave(cfg Config, httpClient *http.Client) *brave {
+	s := &brave{apiKey: cfg.APIKey, baseURL: cfg.BaseURL, maxResults: cfg.MaxResults, httpClient: httpClient}
+	if s.baseURL == "" {
+		s.baseURL = defaultBraveBaseURL
+	}
+	return s
+}
+
+// braveResponse is the part of a web search response we use
+type braveResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+		} `json:"results"`
+	} `json:"web"`
+}
+
+// Search implements Searcher
+func (s *brave) Search(ctx context.Context, query string) ([]Result, error) {
+	params := url.Values{
+		"q":     {query},
+		"count": {strconv.Itoa(s.maxResults)},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.baseURL, "/")+"/web/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("X-Subscription-Token", s.apiKey)
+
+	var resp braveResponse
+	if err := do(s.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(resp.Web.Results))
+	for _, r := range resp.Web.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return keep(results, s.maxResults), nil
+}
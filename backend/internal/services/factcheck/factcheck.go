@@ -0,0 +1,115 @@
+// Package factcheck looks up web sources for factual claims with a
+// configurable search API, so the analyzer can assess claims against
+// evidence rather than the model's memory alone.
+package factcheck
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Supported providers
+const (
+	ProviderBrave   = "brave"
+	ProviderSearXNG = "searxng"
+)
+
+// Providers lists the supported provider names
+var Providers = []string{ProviderBrave, ProviderSearXNG}
+
+// DefaultMaxResults is how many results are kept per claim by default
+const DefaultMaxResults = 3
+
+// Result is a search result that may support or contradict a claim
+type Result struct {
+	Title   string
+	URL     string
+	Snippet string
+}
+
+// Searcher finds web pages relevant to a claim
+type Searcher interface {
+	Search(ctx context.Context, query string) ([]Result, error)
+}
+
+// Config selects and configures a provider
+type Config struct {
+	Provider string
+	APIKey   string
+	// BaseURL overrides the provider's endpoint; SearXNG is self-hosted
+	// and requires it
+	BaseURL string
+	// MaxResults bounds the results returned per search
+	MaxResults int
+}
+
+// ValidProvider reports whether name is a supported provider
+func ValidProvider(name string) bool {
+	return slices.Contains(Providers, name)
+}
+
+// NewSearcher creates the configured searcher. Check the name with
+// ValidProvider first.
+func NewSearcher(cfg Config) (Searcher, error) {
+	if cfg.MaxResults < 1 {
+		cfg.MaxResults = DefaultMaxResults
+	}
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+
+	switch cfg.Provider {
+	case ProviderBrave:
+		return newBrave(cfg, httpClient), nil
+	case ProviderSearXNG:
+		return newSearXNG(cfg, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unknown fact-check provider: %s", cfg.Provider)
+	}
+}
+
+// keep drops results without a URL and trims the rest to max
+func keep(results []Result, max int) []Result {
+	kept := make([]Result, 0, min(len(results), max))
+	for _, r := range results {
+		if len(kept) == max {
+			break
+		}
+		if r.URL == "" {
+			continue
+		}
+		r.Title = strings.TrimSpace(r.Title)
+		r.Snippet = strings.TrimSpace(r.Snippet)
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// do sends a search request and decodes the JSON response into out
+func do(httpClient *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("search request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read search response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("search provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode search response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package factcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewSearcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		wantErr  bool
+	}{
+		{"brave", ProviderBrave, false},
+		{"searxng", ProviderSearXNG, false},
+		{"unknown", "acme", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSearcher(Config{Provider: tt.provider, APIKey: "key"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewSearcher() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBrave_Search(t *testing.T) {
+	var token, query, count string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/web/search" {
+			http.NotFound(w, r)
+			return
+		}
+		token = r.Header.Get("X-Subscription-Token")
+		query = r.URL.Query().Get("q")
+		count = r.URL.Query().Get("count")
+
+		w.Write([]byte(`{"web": {"results": [
+			{"title": " Eiffel Tower ", "url": "https://example.com/eiffel", "description": "Completed in 1889."},
+			{"title": "No link", "url": "", "description": "Dropped."},
+			{"title": "Paris", "url": "https://example.com/paris", "description": "Capital of France."},
+			{"title": "Extra", "url": "https://example.com/extra", "description": "Over the limit."}
+		]}}`))
+	}))
+	defer server.Close()
+
+	searcher, _ := NewSearcher(Config{Provider: ProviderBrave, APIKey: "secret", BaseURL: server.URL, MaxResults: 2})
+	results, err := searcher.Search(context.Background(), "Eiffel Tower completed 1889")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if token != "secret" || query != "Eiffel Tower completed 1889" || count != "2" {
+		t.Errorf("request token = %q, q = %q, count = %q", token, query, count)
+	}
+	want := []Result{
+		{Title: "Eiffel Tower", URL: "https://example.com/eiffel", Snippet: "Completed in 1889."},
+		{Title: "Paris", URL: "https://example.com/paris", Snippet: "Capital of France."},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Search() = %+v, want %+v", results, want)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestSearXNG_Search(t *testing.T) {
+	var format, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			http.NotFound(w, r)
+			return
+		}
+		format = r.URL.Query().Get("format")
+		auth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"results": [{"title": "Eiffel Tower", "url": "https://example.com/eiffel", "content": "Completed in 1889."}]}`))
+	}))
+	defer server.Close()
+
+	searcher, _ := NewSearcher(Config{Provider: ProviderSearXNG, BaseURL: server.URL + "/"})
+	results, err := searcher.Search(context.Background(), "Eiffel Tower")
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if format != "json" || auth != "" {
+		t.Errorf("request format = %q, Authorization = %q", format, auth)
+	}
+	if len(results) != 1 || results[0].Snippet != "Completed in 1889." {
+		t.Errorf("Search() = %+v", results)
+	}
+}
+
+func TestSearch_ProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	searcher, _ := NewSearcher(Config{Provider: ProviderBrave, APIKey: "key", BaseURL: server.URL})
+	if _, err := searcher.Search(context.Background(), "anything"); err == nil {
+		t.Error("Search() error = nil")
+	}
+}
@@ -0,0 +1,59 @@
+package factcheck
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// searxng searches a self-hosted SearXNG instance through its JSON API.
+// The instance must have the json format enabled.
+type searxng struct {
+	apiKey     string
+	baseURL    string
+	maxResults int
+	httpClient *http.Client
+}
+
+func newSearXNG(cfg Config, httpClient *http.Client) *searxng {
+	return &searxng{apiKey: cfg.APIKey, baseURL: cfg.BaseURL, maxResults: cfg.MaxResults, httpClient: httpClient}
+}
+
+// searxngResponse is the part of a search response we use
+type searxngResponse struct {
+	Results []struct {
+		Title   string `json:"title"`
+		URL     string `json:"url"`
+		Content string `json:"content"`
+	} `json:"results"`
+}
+
+// Search implements Searcher
+func (s *searxng) Search(ctx context.Context, query string) ([]Result, error) {
+	params := url.Values{
+		"q":      {query},
+		"format": {"json"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.baseURL, "/")+"/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Instances behind an authenticating proxy take a bearer token
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	var resp searxngResponse
+	if err := do(s.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, Result{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return keep(results, s.maxResults), nil
+}
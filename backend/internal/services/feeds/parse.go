@@ -0,0 +1,224 @@
+package feeds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Document is a parsed RSS or Atom feed
+type Document struct {
+	Title string
+	Items []Item
+}
+
+// Item is an entry of a feed. Content is plain text with the markup
+// removed.
+type Item struct {
+	GUID      string
+	Title     string
+	Link      string
+	Content   string
+	Published time.Time
+}
+
+// ErrNotAFeed is returned for XML that isn't RSS or Atom
+var ErrNotAFeed = errors.New("not an RSS or Atom feed")
+
+// rssItem is an item of an RSS 0.9x, 1.0 or 2.0 feed
+type rssItem struct {
+	GUID        string `xml:"guid"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+// atomEntry is an entry of an Atom feed
+type atomEntry struct {
+	ID    string `xml:"id"`
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Summary   string `xml:"summary"`
+	Content   string `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// document covers the root elements of every supported format: <rss>,
+// RSS 1.0's <rdf:RDF>, which puts items beside the channel, and <feed>
+type document struct {
+	XMLName xml.Name
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem   `xml:"item"`
+	Title   string      `xml:"title"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// Parse reads an RSS or Atom feed. Items are returned newest first; items
+// without a date keep their place after the dated ones.
+func Parse(data []byte) (*Document, error) {
+	var raw document
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	decoder.CharsetReader = charsetReader
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to parse feed: %w", err)
+	}
+
+	doc := &Document{}
+	switch raw.XMLName.Local {
+	case "rss", "RDF":
+		doc.Title = raw.Channel.Title
+		for _, item := range append(raw.Channel.Items, raw.Items...) {
+			content := item.Content
+			if strings.TrimSpace(content) == "" {
+				content = item.Description
+			}
+			doc.Items = append(doc.Items, newItem(item.GUID, item.Title, strings.TrimSpace(item.Link), content, firstNonEmpty(item.PubDate, item.Date)))
+		}
+	case "feed":
+		doc.Title = raw.Title
+		for _, entry := range raw.Entries {
+			content := entry.Content
+			if strings.TrimSpace(content) == "" {
+				content = entry.Summary
+			}
+			doc.Items = append(doc.Items, newItem(entry.ID, entry.Title, entryLink(entry), content, firstNonEmpty(entry.Published, entry.Updated)))
+		}
+	default:
+		return nil, ErrNotAFeed
+	}
+	doc.Title = PlainText(doc.Title)
+
+	// Drop items there is nothing to identify or analyze by
+	items := doc.Items[:0]
+	for _, item := range doc.Items {
+		if item.GUID != "" {
+			items = append(items, item)
+		}
+	}
+	doc.Items = items
+
+	sort.SliceStable(doc.Items, func(i, j int) bool {
+		a, b := doc.Items[i].Published, doc.Items[j].Published
+		return !a.IsZero() && (b.IsZero() || a.After(b))
+	})
+	return doc, nil
+}
+
+// newItem cleans up an item's fields. Items without a GUID are identified
+// by their link, or failing that a hash of their title and content.
+func newItem(guid, title, link, content, published string) Item {
+	item := Item{
+		GUID:      strings.TrimSpace(guid),
+		Title:     PlainText(title),
+		Link:      link,
+		Content:   PlainText(content),
+		Published: parseDate(published),
+	}
+	if item.GUID == "" {
+		item.GUID = item.Link
+	}
+	if item.GUID == "" && (item.Title != "" || item.Content != "") {
+		sum := sha256.Sum256([]byte(item.Title + "\n" + item.Content))
+		item.GUID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return item
+}
+
+// entryLink picks an Atom entry's alternate link
+func entryLink(entry atomEntry) string {
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			return strings.TrimSpace(link.Href)
+		}
+	}
+	return ""
+}
+
+// dateFormats are the layouts feeds use in practice, RFC 822 variants
+// for RSS and RFC 3339 for Atom and Dublin Core
+var dateFormats = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseDate parses a feed date, returning the zero time if no layout
+// matches
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+var (
+	// hiddenElements are removed with their content
+	hiddenElements = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	// blockTags end a line of text
+	blockTags = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/h[1-6]|/blockquote|/tr)\b[^>]*>`)
+	tags      = regexp.MustCompile(`<[^>]*>`)
+	spaces    = regexp.MustCompile(`[ \t\r\f\v\x{00a0}]+`)
+	newlines  = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// PlainText turns the HTML of a feed field into text: markup is removed,
+// entities decoded and whitespace collapsed, keeping paragraph breaks
+func PlainText(s string) string {
+	s = hiddenElements.ReplaceAllString(s, " ")
+	s = blockTags.ReplaceAllString(s, "\n")
+	s = tags.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	s = spaces.ReplaceAllString(s, " ")
+	s = newlines.ReplaceAllString(s, "\n")
+	return strings.TrimSpace(s)
+}
+
+// charsetReader decodes feeds that declare an encoding other than UTF-8
+func charsetReader(label string, input io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported feed encoding %q", label)
+	}
+	return enc.NewDecoder().Reader(input), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package feeds
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+const rssFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>Example &amp; Co News</title>
+    <item>
+      <title>Older story</title>
+      <link>https://example.com/older</link>
+      <guid isPermaLink="false">older-1</guid>
+      <pubDate>Mon, 02 Jan 2006 15:04:05 GMT</pubDate>
+      <description>&lt;p&gt;Short &lt;b&gt;teaser&lt;/b&gt;&lt;/p&gt;</description>
+    </item>
+    <item>
+      <title>Newer story</title>
+      <link>https://example.com/newer</link>
+      <pubDate>Tue, 3 Jan 2006 09:00:00 +0000</pubDate>
+      <description>Teaser</description>
+      <content:encoded><![CDATA[<p>Full&nbsp;text.</p><script>track()</script><p>Second paragraph.</p>]]></content:encoded>
+    </item>
+    <item>
+      <description>No date, link or GUID</description>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="html">Atom &lt;i&gt;Blog&lt;/i&gt;</title>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>First post</title>
+    <link rel="self" href="https://example.com/api/1"/>
+    <link href="https://example.com/posts/1"/>
+    <updated>2024-03-01T10:00:00Z</updated>
+    <summary>A summary.</summary>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel><title>RDF Feed</title></channel>
+  <item>
+    <title>RDF item</title>
+    <link>https://example.com/rdf/1</link>
+    <description>Body</description>
+    <dc:date>2024-05-06T07:08:09Z</dc:date>
+  </item>
+</rdf:RDF>`
+
+func TestParse_RSS(t *testing.T) {
+	doc, err := Parse([]byte(rssFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if doc.Title != "Example & Co News" {
+		t.Errorf("Title = %q", doc.Title)
+	}
+	if len(doc.Items) != 3 {
+		t.Fatalf("got %d items, want 3", len(doc.Items))
+	}
+
+	newer := doc.Items[0]
+	if newer.Title != "Newer story" || newer.GUID != "https://example.com/newer" {
+		t.Errorf("first item = %+v, want the newest, identified by its link", newer)
+	}
+	if newer.Content != "Full text.\nSecond paragraph." {
+		t.Errorf("Content = %q, want content:encoded as text", newer.Content)
+	}
+	if want := time.Date(2006, 1, 3, 9, 0, 0, 0, time.UTC); !newer.Published.Equal(want) {
+		t.Errorf("Published = %v, want %v", newer.Published, want)
+	}
+
+	if older := doc.Items[1]; older.GUID != "older-1" || older.Content != "Short teaser" {
+		t.Errorf("second item = %+v", older)
+	}
+
+	undated := doc.Items[2]
+	if !undated.Published.IsZero() || len(undated.GUID) != len("sha256:")+64 {
+		t.Errorf("undated item = %+v, want it last with a content hash GUID", undated)
+	}
+}
+
+func TestParse_Atom(t *testing.T) {
+	doc, err := Parse([]byte(atomFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if doc.Title != "Atom Blog" || len(doc.Items) != 1 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	entry := doc.Items[0]
+	if entry.GUID != "urn:uuid:1" || entry.Link != "https://example.com/posts/1" || entry.Content != "A summary." {
+		t.Errorf("entry = %+v", entry)
+	}
+	if entry.Published.IsZero() {
+		t.Error("Published is zero, want the updated date")
+	}
+}
+
+func TestParse_RDF(t *testing.T) {
+	doc, err := Parse([]byte(rdfFeed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if doc.Title != "RDF Feed" || len(doc.Items) != 1 {
+		t.Fatalf("doc = %+v", doc)
+	}
+	if item := doc.Items[0]; item.Title != "RDF item" || item.GUID != "https://example.com/rdf/1" || item.Published.IsZero() {
+		t.Errorf("item = %+v", item)
+	}
+}
+
+func TestParse_Charset(t *testing.T) {
+	// "Café" in ISO-8859-1
+	feed := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title></channel></rss>"
+
+	doc, err := Parse([]byte(feed))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if doc.Title != "Café" {
+		t.Errorf("Title = %q, want %q", doc.Title, "Café")
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"html page", "<html><body>Not a feed</body></html>"},
+		{"not xml", "{\"items\": []}"},
+		{"empty", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Parse() error = nil")
+			}
+		})
+	}
+
+	if _, err := Parse([]byte("<html></html>")); !errors.Is(err, ErrNotAFeed) {
+		t.Errorf("Parse() error = %v, want ErrNotAFeed", err)
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "Hello", "Hello"},
+		{"tags", "<p>Hello <a href=\"x\">world</a></p>", "Hello world"},
+		{"entities", "Fish &amp; chips&nbsp;&hellip;", "Fish & chips …"},
+		{"paragraphs", "<p>One</p>\n\n<p>Two</p><br/>Three", "One\nTwo\nThree"},
+		{"scripts and styles", "<style>p{}</style>Text<script>alert(1)</script>", "Text"},
+		{"whitespace", "  a \t b  ", "a b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PlainText(tt.in); got != tt.want {
+				t.Errorf("PlainText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
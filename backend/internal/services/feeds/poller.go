@@ -0,0 +1,255 @@
+// Package feeds monitors RSS and Atom feeds, submitting their new items
+// for analysis.
+package feeds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+const (
+	// JobType identifies the feed polling job in the queue
+	JobType = "feeds.poll"
+
+	// MinPollInterval is the least time between two polls of a feed, so
+	// overlapping runs don't fetch it twice
+	MinPollInterval = 5 * time.Minute
+
+	// MaxItemsPerPoll bounds the analyses one poll of a feed queues. Older
+	// new items are recorded without being analyzed, so a feed's backlog
+	// isn't analyzed when it is first added.
+	MaxItemsPerPoll = 10
+
+	// MaxFeedSize is the largest feed document fetched, in bytes
+	MaxFeedSize = 5 << 20
+
+	// MaxContentLength matches the limit on typed submissions, in
+	// characters
+	MaxContentLength = 50000
+
+	// maxFeedsPerRun bounds a single run; the next run picks up the rest
+	maxFeedsPerRun = 500
+)
+
+// Payload is the job payload for a feed poll. Without a FeedID every feed
+// that is due is polled.
+type Payload struct {
+	FeedID *uuid.UUID `json:"feed_id,omitempty"`
+}
+
+// FeedSource lists feeds and records what their polls found;
+// *models.FeedStore implements it
+type FeedSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Feed, error)
+	ListDue(ctx context.Context, before time.Time, limit int) ([]models.Feed, error)
+	MarkPolled(ctx context.Context, id uuid.UUID, title, pollErr *string) error
+	AddItem(ctx context.Context, item *models.FeedItem) (*models.FeedItem, error)
+	AttachSubmission(ctx context.Context, itemID, submissionID uuid.UUID) error
+}
+
+// SubmissionCreator stores the submissions made from feed items;
+// *models.SubmissionStore implements it
+type SubmissionCreator interface {
+	Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error
+}
+
+// Enqueuer schedules the analysis of new submissions
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// Poller fetches feeds and queues their new items for analysis
+type Poller struct {
+	store       FeedSource
+	submissions SubmissionCreator
+	jobs        Enqueuer
+	httpClient  *http.Client
+}
+
+// NewPoller creates a new feed polling job
+func NewPoller(store FeedSource, submissions SubmissionCreator, jobs Enqueuer) *Poller {
+	return &Poller{
+		store:       store,
+		submissions: submissions,
+		jobs:        jobs,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// WithHTTPClient fetches feeds with client and returns the poller
+func (p *Poller) WithHTTPClient(client *http.Client) *Poller {
+	p.httpClient = client
+	return p
+}
+
+// Handle implements queue.Handler for the feed polling job. A failing
+// feed doesn't stop the others; its error is recorded on the feed.
+func (p *Poller) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid feed poll payload: %w", err)
+	}
+
+	if payload.FeedID != nil {
+		feed, err := p.store.Get(ctx, *payload.FeedID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// The feed was deleted before we got to it
+				return nil
+			}
+			return fmt.Errorf("failed to load feed: %w", err)
+		}
+		return p.poll(ctx, feed)
+	}
+
+	feeds, err := p.store.ListDue(ctx, time.Now().Add(-MinPollInterval), maxFeedsPerRun)
+	if err != nil {
+		return err
+	}
+	for i := range feeds {
+		if err := p.poll(ctx, &feeds[i]); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Feeds polled", "count", len(feeds))
+	return nil
+}
+
+// poll fetches one feed and submits its new items. Only storage errors
+// are returned; fetch and parse errors are recorded on the feed.
+func (p *Poller) poll(ctx context.Context, feed *models.Feed) error {
+	doc, err := p.fetch(ctx, feed.URL)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		slog.Warn("Failed to poll feed", "feed_id", feed.ID, "error", err)
+		message := err.Error()
+		return p.store.MarkPolled(ctx, feed.ID, nil, &message)
+	}
+
+	submitted := 0
+	for _, entry := range doc.Items {
+		item, err := p.store.AddItem(ctx, &models.FeedItem{
+			FeedID:      feed.ID,
+			GUID:        entry.GUID,
+			Title:       optional(entry.Title),
+			Link:        optional(entry.Link),
+			PublishedAt: timestamp.Ptr(optionalTime(entry.Published)),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Seen in an earlier poll
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record feed item: %w", err)
+		}
+
+		content := itemContent(entry)
+		if submitted >= MaxItemsPerPoll || content == "" {
+			continue
+		}
+		if err := p.submit(ctx, feed, item, content); err != nil {
+			return err
+		}
+		submitted++
+	}
+
+	if submitted > 0 {
+		slog.Info("Feed items submitted", "feed_id", feed.ID, "count", submitted)
+	}
+	return p.store.MarkPolled(ctx, feed.ID, optional(doc.Title), nil)
+}
+
+// submit stores a feed item as a queued submission and schedules its
+// analysis
+func (p *Poller) submit(ctx context.Context, feed *models.Feed, item *models.FeedItem, content string) error {
+	submission, err := p.submissions.Create(ctx, feed.UserID, content, nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		return fmt.Errorf("failed to create submission: %w", err)
+	}
+	if err := p.store.AttachSubmission(ctx, item.ID, submission.ID); err != nil {
+		return err
+	}
+
+	if _, err := p.jobs.Enqueue(ctx, analyzer.JobType, analyzer.Payload{SubmissionID: submission.ID}); err != nil {
+		slog.Error("Failed to enqueue analysis of feed item", "submission_id", submission.ID, "error", err)
+
+		// Don't leave the submission queued forever
+		if err := p.submissions.UpdateStatus(ctx, submission.ID, models.StatusFailed); err != nil {
+			slog.Error("Failed to mark submission failed", "submission_id", submission.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// fetch downloads and parses a feed
+func (p *Poller) fetch(ctx context.Context, url string) (*Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.1")
+	req.Header.Set("User-Agent", "content-analyzer feed monitor")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("feed request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("feed returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxFeedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed: %w", err)
+	}
+	if len(body) > MaxFeedSize {
+		return nil, fmt.Errorf("feed is larger than %d MB", MaxFeedSize>>20)
+	}
+
+	return Parse(body)
+}
+
+// itemContent is the text submitted for an item: its title and content,
+// cut to MaxContentLength
+func itemContent(item Item) string {
+	content := strings.TrimSpace(item.Title + "\n\n" + item.Content)
+	if utf8.RuneCountInString(content) <= MaxContentLength {
+		return content
+	}
+	return string([]rune(content)[:MaxContentLength])
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
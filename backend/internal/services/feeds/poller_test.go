@@ -0,0 +1,233 @@
+package feeds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeEnqueuer records the jobs it is given
+type fakeEnqueuer struct {
+	jobs []*queue.Job
+	err  error
+}
+
+func (q *fakeEnqueuer) Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{Type: jobType, Payload: data}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+// feedServer serves an RSS feed whose items can be changed between polls
+type feedServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	items  []string
+	status int
+}
+
+func newFeedServer(t *testing.T, items int) *feedServer {
+	t.Helper()
+	s := &feedServer{status: http.StatusOK}
+	for i := range items {
+		s.add(i)
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		fmt.Fprintf(w, `<rss><channel><title>Test Feed</title>%s</channel></rss>`, strings.Join(s.items, ""))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// add publishes item n, dated n hours into 2024 so higher numbers are newer
+func (s *feedServer) add(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items = append(s.items, fmt.Sprintf(
+		`<item><guid>item-%d</guid><title>Story %d</title><description>Body %d</description><pubDate>Mon, 01 Jan 2024 %02d:00:00 +0000</pubDate></item>`,
+		n, n, n, n%24,
+	))
+}
+
+func pollJob(t *testing.T, feedID *uuid.UUID) *queue.Job {
+	t.Helper()
+	if feedID == nil {
+		return &queue.Job{Type: JobType}
+	}
+	payload, err := json.Marshal(Payload{FeedID: feedID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queue.Job{Type: JobType, Payload: payload}
+}
+
+func TestPoller_Handle(t *testing.T) {
+	ctx := context.Background()
+	server := newFeedServer(t, MaxItemsPerPoll+5)
+	submissions := memstore.NewSubmissionStore()
+	store := memstore.NewFeedStore(submissions)
+	jobs := &fakeEnqueuer{}
+	poller := NewPoller(store, submissions, jobs)
+
+	feed, err := store.Create(ctx, uuid.New(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first poll analyzes the newest items and only records the rest
+	if err := poller.Handle(ctx, pollJob(t, nil)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	items := store.Items(feed.ID)
+	if len(items) != MaxItemsPerPoll+5 {
+		t.Fatalf("recorded %d items, want %d", len(items), MaxItemsPerPoll+5)
+	}
+	if len(jobs.jobs) != MaxItemsPerPoll {
+		t.Fatalf("enqueued %d analyses, want %d", len(jobs.jobs), MaxItemsPerPoll)
+	}
+	for _, item := range items {
+		var n int
+		fmt.Sscanf(item.GUID, "item-%d", &n)
+		if submitted := item.SubmissionID != nil; submitted != (n >= 5) {
+			t.Errorf("item %d submitted = %v, want only the %d newest", n, submitted, MaxItemsPerPoll)
+		}
+	}
+
+	polled, _ := store.Get(ctx, feed.ID)
+	if polled.Title == nil || *polled.Title != "Test Feed" || polled.LastPolledAt == nil || polled.LastError != nil {
+		t.Errorf("feed = %+v", polled)
+	}
+
+	var payload analyzer.Payload
+	if err := jobs.jobs[0].Decode(&payload); err != nil {
+		t.Fatal(err)
+	}
+	submission, err := submissions.GetByID(ctx, feed.UserID, payload.SubmissionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(submission.Content, "Story ") || !strings.Contains(submission.Content, "\n\nBody ") || submission.Status != models.StatusQueued {
+		t.Errorf("submission = %+v", submission)
+	}
+
+	// A scheduled run right after skips the feed, an explicit poll doesn't,
+	// and only the new item is submitted
+	server.add(99)
+	if err := poller.Handle(ctx, pollJob(t, nil)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(jobs.jobs) != MaxItemsPerPoll {
+		t.Fatalf("scheduled run enqueued %d analyses, want %d", len(jobs.jobs), MaxItemsPerPoll)
+	}
+	if err := poller.Handle(ctx, pollJob(t, &feed.ID)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(jobs.jobs) != MaxItemsPerPoll+1 {
+		t.Errorf("enqueued %d analyses, want %d", len(jobs.jobs), MaxItemsPerPoll+1)
+	}
+}
+
+func TestPoller_Handle_FetchError(t *testing.T) {
+	ctx := context.Background()
+	server := newFeedServer(t, 1)
+	server.status = http.StatusNotFound
+	submissions := memstore.NewSubmissionStore()
+	store := memstore.NewFeedStore(submissions)
+	jobs := &fakeEnqueuer{}
+
+	broken, _ := store.Create(ctx, uuid.New(), server.URL)
+	missing, _ := store.Create(ctx, uuid.New(), server.URL+"/missing")
+
+	if err := NewPoller(store, submissions, jobs).Handle(ctx, pollJob(t, nil)); err != nil {
+		t.Fatalf("Handle() error = %v, want errors recorded on the feeds", err)
+	}
+
+	for _, id := range []uuid.UUID{broken.ID, missing.ID} {
+		feed, _ := store.Get(ctx, id)
+		if feed.LastError == nil || !strings.Contains(*feed.LastError, "404") || feed.LastPolledAt == nil {
+			t.Errorf("feed = %+v, want the 404 recorded", feed)
+		}
+	}
+	if len(jobs.jobs) != 0 {
+		t.Errorf("enqueued %d analyses, want 0", len(jobs.jobs))
+	}
+}
+
+func TestPoller_Handle_DeletedFeed(t *testing.T) {
+	submissions := memstore.NewSubmissionStore()
+	id := uuid.New()
+
+	if err := NewPoller(memstore.NewFeedStore(submissions), submissions, &fakeEnqueuer{}).Handle(context.Background(), pollJob(t, &id)); err != nil {
+		t.Errorf("Handle() error = %v", err)
+	}
+}
+
+func TestPoller_Handle_EnqueueFailure(t *testing.T) {
+	ctx := context.Background()
+	server := newFeedServer(t, 1)
+	submissions := memstore.NewSubmissionStore()
+	store := memstore.NewFeedStore(submissions)
+
+	feed, _ := store.Create(ctx, uuid.New(), server.URL)
+	if err := NewPoller(store, submissions, &fakeEnqueuer{err: errors.New("redis down")}).Handle(ctx, pollJob(t, &feed.ID)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	items := store.Items(feed.ID)
+	if len(items) != 1 || items[0].SubmissionID == nil {
+		t.Fatalf("items = %+v", items)
+	}
+	submission, _ := submissions.Get(ctx, *items[0].SubmissionID)
+	if submission.Status != models.StatusFailed {
+		t.Errorf("submission status = %s, want %s", submission.Status, models.StatusFailed)
+	}
+}
+
+func TestItemContent(t *testing.T) {
+	long := strings.Repeat("é", MaxContentLength+10)
+
+	tests := []struct {
+		name string
+		item Item
+		want int
+	}{
+		{"title and content", Item{Title: "Title", Content: "Body"}, len("Title\n\nBody")},
+		{"content only", Item{Content: "Body"}, len("Body")},
+		{"nothing", Item{}, 0},
+		{"cut to the limit", Item{Content: long}, MaxContentLength * len("é")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := itemContent(tt.item); len(got) != tt.want {
+				t.Errorf("itemContent() has %d bytes, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Package hooks delivers events to REST hooks subscribed by automation
+// platforms such as Zapier and Make.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// JobType delivers one event to one hook
+const JobType = "hooks.deliver"
+
+// Payload is the job payload for a delivery. The analysis is read when
+// the job runs, so a retried delivery sends the latest one.
+type Payload struct {
+	HookID       uuid.UUID `json:"hook_id"`
+	SubmissionID uuid.UUID `json:"submission_id"`
+}
+
+// HookSource finds hooks and the analyses delivered to them;
+// *models.HookStore implements it
+type HookSource interface {
+	ListForEvent(ctx context.Context, userID uuid.UUID, event string) ([]models.RESTHook, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.RESTHook, error)
+	DeleteByID(ctx context.Context, id uuid.UUID) error
+	LatestAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.HookAnalysis, error)
+}
+
+// Enqueuer schedules deliveries
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// Subscriber queues a delivery to each of the user's hooks whenever a
+// submission completes. Whether the analysis is flagged is checked on
+// delivery, once it is read.
+func Subscriber(store HookSource, jobs Enqueuer) events.Subscriber {
+	return func(ctx context.Context, change models.StatusChange) error {
+		if change.To != models.StatusCompleted {
+			return nil
+		}
+
+		for _, event := range models.HookEvents {
+			hooks, err := store.ListForEvent(ctx, change.UserID, event)
+			if err != nil {
+				return fmt.Errorf("failed to list hooks: %w", err)
+			}
+			for _, hook := range hooks {
+				if _, err := jobs.Enqueue(ctx, JobType, Payload{HookID: hook.ID, SubmissionID: change.SubmissionID}); err != nil {
+					return fmt.Errorf("failed to enqueue hook delivery: %w", err)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// Deliverer posts analyses to hook targets
+type Deliverer struct {
+	store      HookSource
+	httpClient *http.Client
+}
+
+// NewDeliverer creates a new deliverer
+func NewDeliverer(store HookSource) *Deliverer {
+	return &Deliverer{
+		store:      store,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// WithHTTPClient posts to targets with client and returns the deliverer
+func (d *Deliverer) WithHTTPClient(client *http.Client) *Deliverer {
+	d.httpClient = client
+	return d
+}
+
+// Handle implements queue.Handler for JobType. Deliveries are unsigned,
+// since automation platforms can't verify signatures; a target that
+// answers 410 Gone is unsubscribed, and other failures are retried. An
+// analysis.flagged hook is only sent flagged analyses.
+func (d *Deliverer) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid hook delivery payload: %w", err)
+	}
+
+	hook, err := d.store.GetByID(ctx, payload.HookID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Unsubscribed, or its API key was revoked, since it was queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load hook: %w", err)
+	}
+
+	analysis, err := d.store.LatestAnalysis(ctx, hook.UserID, payload.SubmissionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The submission was deleted
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load analysis: %w", err)
+	}
+
+	if hook.Event == models.HookEventAnalysisFlagged && !analysis.Flagged() {
+		return nil
+	}
+
+	body, err := encode(analysis, hook.Fields)
+	if err != nil {
+		return fmt.Errorf("failed to encode hook delivery: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.TargetURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create hook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to deliver hook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusGone {
+		slog.Info("Hook target is gone; unsubscribing", "hook_id", hook.ID)
+		if err := d.store.DeleteByID(ctx, hook.ID); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to unsubscribe hook: %w", err)
+		}
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("hook target returned %d: %s", resp.StatusCode, reply)
+	}
+	return nil
+}
+
+// encode marshals an analysis with only the given fields and its IDs, or
+// with every field when none are given
+func encode(analysis *models.HookAnalysis, fields []string) ([]byte, error) {
+	body, err := json.Marshal(analysis)
+	if err != nil || len(fields) == 0 {
+		return body, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(body, &all); err != nil {
+		return nil, err
+	}
+	selected := map[string]json.RawMessage{"id": all["id"], "submission_id": all["submission_id"]}
+	for _, field := range fields {
+		if value, ok := all[field]; ok {
+			selected[field] = value
+		}
+	}
+	return json.Marshal(selected)
+}
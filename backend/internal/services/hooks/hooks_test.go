@@ -0,0 +1,268 @@
+package hooks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeEnqueuer records the jobs it is given
+type fakeEnqueuer struct {
+	jobs []*queue.Job
+}
+
+func (q *fakeEnqueuer) Enqueue(ctx context.Context, jobType string, payload interface{}) (*queue.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{Type: jobType, Payload: data}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+// fixture is a user with an API key, a completed submission and the
+// stores holding them
+type fixture struct {
+	keys        *memstore.APIKeyStore
+	submissions *memstore.SubmissionStore
+	store       *memstore.HookStore
+	userID      uuid.UUID
+	keyID       uuid.UUID
+	submission  uuid.UUID
+}
+
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+	ctx := context.Background()
+
+	f := &fixture{
+		keys:        memstore.NewAPIKeyStore(),
+		submissions: memstore.NewSubmissionStore(),
+		userID:      uuid.New(),
+	}
+	f.store = memstore.NewHookStore(f.keys, f.submissions)
+
+	_, key, err := f.keys.Create(ctx, f.userID, "Zapier", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.keyID = key.ID
+
+	submission, _ := f.submissions.Create(ctx, f.userID, "The launch went well.", nil, nil, nil, models.StatusQueued)
+	f.submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing)
+	if err := f.submissions.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Sentiment: "positive", Summary: "A good launch."}); err != nil {
+		t.Fatal(err)
+	}
+	f.submission = submission.ID
+	return f
+}
+
+// subscribe adds a hook posting to target
+func (f *fixture) subscribe(t *testing.T, target string) *models.RESTHook {
+	t.Helper()
+	hook, err := f.store.Create(context.Background(), models.RESTHook{
+		UserID:    f.userID,
+		APIKeyID:  f.keyID,
+		Event:     models.HookEventAnalysisCompleted,
+		TargetURL: target,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hook
+}
+
+// deliveryJob builds the job the subscriber queues for hook
+func (f *fixture) deliveryJob(t *testing.T, hook *models.RESTHook) *queue.Job {
+	t.Helper()
+	data, err := json.Marshal(Payload{HookID: hook.ID, SubmissionID: f.submission})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queue.Job{Type: JobType, Payload: data}
+}
+
+func TestSubscriber(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	first := f.subscribe(t, "https://example.com/1")
+	second, err := f.store.Create(ctx, models.RESTHook{UserID: f.userID, APIKeyID: f.keyID, Event: models.HookEventAnalysisFlagged, TargetURL: "https://example.com/2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A hook of another user isn't delivered to
+	_, otherKey, _ := f.keys.Create(ctx, uuid.New(), "Make", nil)
+	f.store.Create(ctx, models.RESTHook{UserID: otherKey.UserID, APIKeyID: otherKey.ID, Event: models.HookEventAnalysisCompleted, TargetURL: "https://example.com/3"})
+
+	q := &fakeEnqueuer{}
+	subscriber := Subscriber(f.store, q)
+
+	if err := subscriber(ctx, models.StatusChange{SubmissionID: f.submission, UserID: f.userID, To: models.StatusProcessing}); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.jobs) != 0 {
+		t.Fatalf("queued %d deliveries for a submission still processing, want none", len(q.jobs))
+	}
+
+	if err := subscriber(ctx, models.StatusChange{SubmissionID: f.submission, UserID: f.userID, From: models.StatusProcessing, To: models.StatusCompleted}); err != nil {
+		t.Fatal(err)
+	}
+	if len(q.jobs) != 2 {
+		t.Fatalf("queued %d deliveries, want one per hook", len(q.jobs))
+	}
+	delivered := map[uuid.UUID]bool{}
+	for _, job := range q.jobs {
+		var payload Payload
+		if err := job.Decode(&payload); err != nil {
+			t.Fatal(err)
+		}
+		if job.Type != JobType || payload.SubmissionID != f.submission {
+			t.Errorf("job = %s %+v, want a delivery of submission %s", job.Type, payload, f.submission)
+		}
+		delivered[payload.HookID] = true
+	}
+	if !delivered[first.ID] || !delivered[second.ID] {
+		t.Errorf("delivered to %v, want hooks %s and %s", delivered, first.ID, second.ID)
+	}
+}
+
+func TestDeliverer_Handle(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		revoke     bool
+		wantErr    bool
+		wantPosted bool
+		wantHook   bool
+	}{
+		{"delivered", http.StatusOK, false, false, true, true},
+		{"target failed", http.StatusInternalServerError, false, true, true, true},
+		{"target gone", http.StatusGone, false, false, true, false},
+		{"key revoked", http.StatusOK, true, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newFixture(t)
+
+			var posted *models.HookAnalysis
+			target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				posted = &models.HookAnalysis{}
+				if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("got %s with Content-Type %q, want a JSON POST", r.Method, r.Header.Get("Content-Type"))
+				}
+				if err := json.NewDecoder(r.Body).Decode(posted); err != nil {
+					t.Errorf("failed to decode delivery: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer target.Close()
+
+			hook := f.subscribe(t, target.URL)
+			if tt.revoke {
+				f.keys.Revoke(ctx, f.userID, f.keyID)
+			}
+
+			err := NewDeliverer(f.store).Handle(ctx, f.deliveryJob(t, hook))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if (posted != nil) != tt.wantPosted {
+				t.Fatalf("posted = %v, want %v", posted != nil, tt.wantPosted)
+			}
+			if posted != nil && (posted.SubmissionID != f.submission || posted.Summary != "A good launch.") {
+				t.Errorf("posted %+v, want the submission's analysis", posted)
+			}
+
+			hooks, _ := f.store.List(ctx, f.userID)
+			if (len(hooks) == 1) != tt.wantHook {
+				t.Errorf("hooks after delivery = %d, want subscribed %v", len(hooks), tt.wantHook)
+			}
+		})
+	}
+}
+
+func TestDeliverer_Filters(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+
+	var posted []map[string]any
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode delivery: %v", err)
+		}
+		posted = append(posted, body)
+	}))
+	defer target.Close()
+
+	subscribe := func(event string, fields ...string) *models.RESTHook {
+		hook, err := f.store.Create(ctx, models.RESTHook{UserID: f.userID, APIKeyID: f.keyID, Event: event, TargetURL: target.URL, Fields: fields})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return hook
+	}
+	deliverer := NewDeliverer(f.store)
+
+	// Only the chosen fields are sent, with the IDs
+	if err := deliverer.Handle(ctx, f.deliveryJob(t, subscribe(models.HookEventAnalysisCompleted, "summary"))); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 1 || len(posted[0]) != 3 || posted[0]["summary"] != "A good launch." || posted[0]["submission_id"] != f.submission.String() {
+		t.Fatalf("posted %v, want the IDs and the summary", posted)
+	}
+
+	// The fixture's analysis isn't flagged
+	flagged := subscribe(models.HookEventAnalysisFlagged)
+	if err := deliverer.Handle(ctx, f.deliveryJob(t, flagged)); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 1 {
+		t.Fatalf("posted %v to a flagged hook, want nothing", posted[1:])
+	}
+
+	low := 0.2
+	submission, _ := f.submissions.Create(ctx, f.userID, "Dubious.", nil, nil, nil, models.StatusQueued)
+	f.submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing)
+	if err := f.submissions.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Confidence: &low}); err != nil {
+		t.Fatal(err)
+	}
+	f.submission = submission.ID
+	if err := deliverer.Handle(ctx, f.deliveryJob(t, flagged)); err != nil {
+		t.Fatal(err)
+	}
+	if len(posted) != 2 || posted[1]["confidence"] != low {
+		t.Errorf("posted %v, want the low confidence analysis", posted)
+	}
+}
+
+func TestHookAnalysis_Flagged(t *testing.T) {
+	low, high := 0.2, 0.9
+	tests := []struct {
+		name     string
+		analysis models.HookAnalysis
+		want     bool
+	}{
+		{"plain", models.HookAnalysis{Confidence: &high, PolicyAction: models.PolicyAllow}, false},
+		{"unverified", models.HookAnalysis{}, false},
+		{"policy warning", models.HookAnalysis{PolicyAction: models.PolicyWarn}, true},
+		{"low confidence", models.HookAnalysis{Confidence: &low}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.analysis.Flagged(); got != tt.want {
+			t.Errorf("%s: Flagged() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/services/sensitive"
+)
+
+// JobType identifies the import job in the queue
+const JobType = "imports.rows"
+
+// Payload is the job payload for an import
+type Payload struct {
+	ImportID uuid.UUID `json:"import_id"`
+}
+
+// ImportSource reads imports and records their progress;
+// *models.ImportStore implements it
+type ImportSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Import, error)
+	Data(ctx context.Context, id uuid.UUID) ([]byte, error)
+	Progress(ctx context.Context, i *models.Import) error
+	Complete(ctx context.Context, id uuid.UUID) error
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+// SubmissionCreator stores the submissions made from rows;
+// *models.SubmissionStore implements it
+type SubmissionCreator interface {
+	Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error
+}
+
+// Enqueuer schedules the analysis of imported rows
+type Enqueuer interface {
+	EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// UserGetter looks up the owner of an import for their quota;
+// *models.UserStore implements it
+type UserGetter interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// QuotaRecorder counts analyses against monthly quotas;
+// *quota.Tracker implements it
+type QuotaRecorder interface {
+	Record(ctx context.Context, user *models.User, chargeID uuid.UUID) (quota.Usage, bool, error)
+}
+
+// Importer imports the rows of uploaded files as submissions
+type Importer struct {
+	store       ImportSource
+	submissions SubmissionCreator
+	jobs        Enqueuer
+
+	users UserGetter
+	quota QuotaRecorder
+}
+
+// NewImporter creates a new import job
+func NewImporter(store ImportSource, submissions SubmissionCreator, jobs Enqueuer) *Importer {
+	return &Importer{
+		store:       store,
+		submissions: submissions,
+		jobs:        jobs,
+	}
+}
+
+// WithQuota counts the analysis of every row queued for analysis against
+// its owner's monthly quota and returns the importer
+func (im *Importer) WithQuota(users UserGetter, tracker QuotaRecorder) *Importer {
+	im.users = users
+	im.quota = tracker
+	return im
+}
+
+// Handle implements queue.Handler for the import job. Progress is
+// recorded after every row, so a retried job picks up where the last
+// attempt stopped.
+func (im *Importer) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid import payload: %w", err)
+	}
+
+	upload, err := im.store.Get(ctx, payload.ImportID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The user was deleted before we got to it
+			return nil
+		}
+		return fmt.Errorf("failed to load import: %w", err)
+	}
+	if upload.Status != models.ImportPending && upload.Status != models.ImportProcessing {
+		return nil
+	}
+
+	if err := im.run(ctx, upload); err != nil {
+		// Only give up on the import once the queue stops retrying
+		if job.Attempts+1 >= job.MaxAttempts {
+			im.fail(upload.ID, "The import stopped before every row was processed")
+		}
+		return err
+	}
+	return nil
+}
+
+// run imports the rows an earlier attempt hasn't processed
+func (im *Importer) run(ctx context.Context, upload *models.Import) error {
+	data, err := im.store.Data(ctx, upload.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load import file: %w", err)
+	}
+
+	rows, err := Read(upload.Format, data, upload.Mapping)
+	if err != nil {
+		// The file was checked on upload, so this only happens if the
+		// limits changed since
+		im.fail(upload.ID, "The file couldn't be read: "+err.Error())
+		return nil
+	}
+
+	owner := im.owner(ctx, upload)
+	for _, row := range rows[min(upload.ProcessedRows, len(rows)):] {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if row.Err != nil {
+			upload.AddRowError(row.Line, row.Err.Error())
+		} else {
+			if err := im.submit(ctx, upload, row, owner); err != nil {
+				return err
+			}
+			upload.ImportedRows++
+		}
+		upload.ProcessedRows++
+
+		if err := im.store.Progress(ctx, upload); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				// Finished by another delivery of the job
+				return nil
+			}
+			return err
+		}
+	}
+
+	if err := im.store.Complete(ctx, upload.ID); err != nil {
+		return err
+	}
+
+	slog.Info("Import completed", "import_id", upload.ID, "imported", upload.ImportedRows, "failed", upload.FailedRows)
+	return nil
+}
+
+// owner looks up the user whose quota the import's analyses count
+// against. It returns nil when they aren't counted or the lookup fails.
+func (im *Importer) owner(ctx context.Context, upload *models.Import) *models.User {
+	if im.quota == nil || !upload.Analyze {
+		return nil
+	}
+	user, err := im.users.GetByID(ctx, upload.UserID)
+	if err != nil {
+		slog.Warn("Failed to look up import owner, not counting quota", "import_id", upload.ID, "error", err)
+		return nil
+	}
+	return user
+}
+
+// submit stores a row as a submission, a draft unless the import asked
+// for analysis, in which case the analysis is scheduled
+func (im *Importer) submit(ctx context.Context, upload *models.Import, row Row, owner *models.User) error {
+	focus := row.Instructions
+	if focus == nil {
+		focus = upload.Instructions
+	}
+	var redacted *string
+	if upload.Redact {
+		masked := sensitive.Redact(row.Content, sensitive.Detect(row.Content))
+		redacted = &masked
+	}
+	status := models.StatusDraft
+	if upload.Analyze {
+		status = models.StatusQueued
+	}
+
+	submission, err := im.submissions.Create(ctx, upload.UserID, row.Content, redacted, focus, upload.ProfileID, status)
+	if err != nil {
+		return fmt.Errorf("failed to create submission: %w", err)
+	}
+	if !upload.Analyze {
+		return nil
+	}
+
+	priority := queue.Priority(upload.Priority)
+	if _, err := im.jobs.EnqueuePriority(ctx, priority, analyzer.JobType, analyzer.Payload{SubmissionID: submission.ID}); err != nil {
+		slog.Error("Failed to enqueue analysis of imported row", "submission_id", submission.ID, "error", err)
+
+		// The row is imported; don't leave the submission queued forever
+		if err := im.submissions.UpdateStatus(ctx, submission.ID, models.StatusFailed); err != nil {
+			slog.Error("Failed to mark submission failed", "submission_id", submission.ID, "error", err)
+		}
+		return nil
+	}
+
+	// Quotas are soft, so a counter failure doesn't stop the import
+	if owner != nil {
+		if _, _, err := im.quota.Record(ctx, owner, submission.ID); err != nil {
+			slog.Warn("Failed to count analysis against quota", "user_id", owner.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// fail records an import failure the user can see
+func (im *Importer) fail(id uuid.UUID, message string) {
+	if err := im.store.Fail(context.Background(), id, message); err != nil {
+		slog.Error("Failed to mark import failed", "import_id", id, "error", err)
+	}
+}
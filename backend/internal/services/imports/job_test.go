@@ -0,0 +1,254 @@
+package imports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/quota"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeEnqueuer records the jobs it is given
+type fakeEnqueuer struct {
+	jobs []*queue.Job
+	err  error
+}
+
+func (q *fakeEnqueuer) EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{Type: jobType, Payload: data, Priority: priority}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+// fakeQuota counts the analyses recorded per user
+type fakeQuota map[uuid.UUID]int
+
+func (q fakeQuota) Record(ctx context.Context, user *models.User, chargeID uuid.UUID) (quota.Usage, bool, error) {
+	q[user.ID]++
+	return quota.Usage{Used: q[user.ID], Limit: 100}, true, nil
+}
+
+// failingSubmissions can't store submissions
+type failingSubmissions struct{}
+
+func (failingSubmissions) Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error) {
+	return nil, errors.New("database down")
+}
+
+func (failingSubmissions) UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error {
+	return nil
+}
+
+func importJob(t *testing.T, id uuid.UUID, attempts int) *queue.Job {
+	t.Helper()
+	payload, err := json.Marshal(Payload{ImportID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queue.Job{Type: JobType, Payload: payload, Attempts: attempts, MaxAttempts: 3}
+}
+
+const reviewsCSV = "body,notes\n" +
+	"Great product. Contact me at jane@example.com,\n" +
+	",\n" +
+	"Shipping was slow,Focus on logistics\n"
+
+// newImport stores a pending CSV import of reviewsCSV for userID
+func newImport(t *testing.T, store *memstore.ImportStore, userID uuid.UUID, analyze bool) *models.Import {
+	t.Helper()
+	focus := "Focus on complaints"
+	upload, err := store.Create(context.Background(), &models.Import{
+		UserID:       userID,
+		Filename:     "reviews.csv",
+		Format:       models.ImportCSV,
+		Mapping:      models.ImportMapping{Content: "body", Instructions: "notes"},
+		Analyze:      analyze,
+		Redact:       true,
+		TotalRows:    3,
+		Instructions: &focus,
+		Priority:     string(queue.PriorityHigh),
+	}, []byte(reviewsCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return upload
+}
+
+func TestImporter_Handle(t *testing.T) {
+	ctx := context.Background()
+	users := memstore.NewUserStore()
+	owner, err := users.Create(ctx, "owner@example.com", "password123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memstore.NewImportStore()
+	submissions := memstore.NewSubmissionStore()
+	jobs := &fakeEnqueuer{}
+	counted := fakeQuota{}
+	upload := newImport(t, store, owner.ID, true)
+
+	importer := NewImporter(store, submissions, jobs).WithQuota(users, counted)
+	if err := importer.Handle(ctx, importJob(t, upload.ID, 0)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	done, err := store.Get(ctx, upload.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.ImportCompleted || done.ProcessedRows != 3 || done.ImportedRows != 2 || done.FailedRows != 1 {
+		t.Fatalf("import = %+v, want 2 of 3 rows imported", done)
+	}
+	if len(done.RowErrors) != 1 || done.RowErrors[0].Line != 3 || done.RowErrors[0].Message != "content is empty" {
+		t.Errorf("row errors = %+v, want the empty row on line 3", done.RowErrors)
+	}
+	if _, err := store.Data(ctx, upload.ID); err == nil {
+		t.Error("file was kept after the import")
+	}
+
+	imported, _, err := submissions.List(ctx, owner.ID, models.SubmissionFilter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 2 {
+		t.Fatalf("imported %d submissions, want 2", len(imported))
+	}
+	for _, submission := range imported {
+		if submission.Status != models.StatusQueued || submission.Instructions == nil {
+			t.Errorf("submission = %+v, want it queued with instructions", submission)
+			continue
+		}
+		switch submission.Content {
+		case "Shipping was slow":
+			if *submission.Instructions != "Focus on logistics" {
+				t.Errorf("instructions = %q, want the row's", *submission.Instructions)
+			}
+		default:
+			if *submission.Instructions != "Focus on complaints" {
+				t.Errorf("instructions = %q, want the import's", *submission.Instructions)
+			}
+			if submission.RedactedContent == nil || strings.Contains(*submission.RedactedContent, "jane@example.com") {
+				t.Errorf("redacted content = %v, want the email masked", submission.RedactedContent)
+			}
+		}
+	}
+
+	if len(jobs.jobs) != 2 || jobs.jobs[0].Type != analyzer.JobType || jobs.jobs[0].Priority != queue.PriorityHigh {
+		t.Fatalf("jobs = %+v, want two high priority analyses", jobs.jobs)
+	}
+	if counted[owner.ID] != 2 {
+		t.Errorf("counted %d analyses against the quota, want 2", counted[owner.ID])
+	}
+
+	// Redelivery of a finished job is a no-op
+	if err := importer.Handle(ctx, importJob(t, upload.ID, 1)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(jobs.jobs) != 2 {
+		t.Errorf("enqueued %d analyses, want 2", len(jobs.jobs))
+	}
+}
+
+func TestImporter_Handle_Drafts(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewImportStore()
+	submissions := memstore.NewSubmissionStore()
+	jobs := &fakeEnqueuer{}
+	userID := uuid.New()
+	upload := newImport(t, store, userID, false)
+
+	if err := NewImporter(store, submissions, jobs).Handle(ctx, importJob(t, upload.ID, 0)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	imported, _, err := submissions.List(ctx, userID, models.SubmissionFilter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 2 || imported[0].Status != models.StatusDraft || imported[1].Status != models.StatusDraft {
+		t.Errorf("submissions = %+v, want 2 drafts", imported)
+	}
+	if len(jobs.jobs) != 0 {
+		t.Errorf("enqueued %d analyses, want 0", len(jobs.jobs))
+	}
+}
+
+func TestImporter_Handle_Resume(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewImportStore()
+	submissions := memstore.NewSubmissionStore()
+	userID := uuid.New()
+	upload := newImport(t, store, userID, false)
+
+	// An earlier attempt got through the first two rows
+	upload.ProcessedRows, upload.ImportedRows = 2, 1
+	upload.AddRowError(3, "content is empty")
+	if err := store.Progress(ctx, upload); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewImporter(store, submissions, &fakeEnqueuer{}).Handle(ctx, importJob(t, upload.ID, 1)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	imported, _, err := submissions.List(ctx, userID, models.SubmissionFilter{}, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported) != 1 || imported[0].Content != "Shipping was slow" {
+		t.Errorf("submissions = %+v, want only the last row", imported)
+	}
+	done, err := store.Get(ctx, upload.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.ImportCompleted || done.ImportedRows != 2 || done.FailedRows != 1 {
+		t.Errorf("import = %+v, want the earlier progress carried over", done)
+	}
+}
+
+func TestImporter_Handle_StorageError(t *testing.T) {
+	tests := []struct {
+		name      string
+		attempts  int
+		wantState models.ImportStatus
+	}{
+		{"retried", 0, models.ImportPending},
+		{"last attempt", 2, models.ImportFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memstore.NewImportStore()
+			upload := newImport(t, store, uuid.New(), true)
+
+			if err := NewImporter(store, failingSubmissions{}, &fakeEnqueuer{}).Handle(ctx, importJob(t, upload.ID, tt.attempts)); err == nil {
+				t.Fatal("Handle() error = nil, want the storage error")
+			}
+
+			got, err := store.Get(ctx, upload.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantState {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantState)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package imports reads CSV and JSONL files and imports their rows as
+// submissions in the background.
+package imports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/instructions"
+)
+
+const (
+	// MaxRows bounds the rows of one import
+	MaxRows = 10000
+
+	// MaxContentLength matches the limit on typed submissions, in
+	// characters
+	MaxContentLength = 50000
+)
+
+var (
+	// ErrTooManyRows is returned for files with more than MaxRows rows
+	ErrTooManyRows = fmt.Errorf("files can have at most %d rows", MaxRows)
+	// ErrNotUTF8 is returned for files that aren't UTF-8 text
+	ErrNotUTF8 = errors.New("files must be UTF-8 text")
+)
+
+// MappingError is returned when the mapping names a column the file
+// doesn't have
+type MappingError struct {
+	Field  string
+	Column string
+}
+
+func (e *MappingError) Error() string {
+	return fmt.Sprintf("%s column %q isn't in the file", e.Field, e.Column)
+}
+
+// Row is a row of an import file read through the mapping. Rows that
+// can't be imported carry the reason in Err.
+type Row struct {
+	// Line is the line of the file the row starts on
+	Line         int
+	Content      string
+	Instructions *string
+	Err          error
+}
+
+// Read reads every row of an import file through mapping. Problems with a
+// single row are reported in its Err; the error is for files that can't
+// be read at all.
+func Read(format models.ImportFormat, data []byte, mapping models.ImportMapping) ([]Row, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !utf8.Valid(data) {
+		return nil, ErrNotUTF8
+	}
+
+	switch format {
+	case models.ImportCSV:
+		return readCSV(data, mapping)
+	case models.ImportJSONL:
+		return readJSONL(data, mapping)
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+// readCSV reads a CSV file whose first record names the columns. Column
+// names are matched case-insensitively.
+func readCSV(data []byte, mapping models.ImportMapping) ([]Row, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header row: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+
+	contentIndex, ok := columns[strings.ToLower(strings.TrimSpace(mapping.Content))]
+	if !ok {
+		return nil, &MappingError{Field: "content", Column: mapping.Content}
+	}
+	instructionsIndex := -1
+	if mapping.Instructions != "" {
+		if instructionsIndex, ok = columns[strings.ToLower(strings.TrimSpace(mapping.Instructions))]; !ok {
+			return nil, &MappingError{Field: "instructions", Column: mapping.Instructions}
+		}
+	}
+
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return record[i]
+	}
+
+	var rows []Row
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		line, _ := r.FieldPos(0)
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: errors.New("the row isn't valid CSV")})
+			continue
+		}
+		rows = append(rows, newRow(line, field(record, contentIndex), field(record, instructionsIndex)))
+	}
+}
+
+// readJSONL reads a file with a JSON object on each line. Blank lines are
+// skipped.
+func readJSONL(data []byte, mapping models.ImportMapping) ([]Row, error) {
+	var rows []Row
+	for i, raw := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(raw)) == 0 {
+			continue
+		}
+		if len(rows) == MaxRows {
+			return nil, ErrTooManyRows
+		}
+
+		line := i + 1
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err != nil {
+			rows = append(rows, Row{Line: line, Err: errors.New("the line isn't a JSON object")})
+			continue
+		}
+
+		content, err := stringField(object, mapping.Content)
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+		focus, err := stringField(object, mapping.Instructions)
+		if err != nil {
+			rows = append(rows, Row{Line: line, Err: err})
+			continue
+		}
+		rows = append(rows, newRow(line, content, focus))
+	}
+	return rows, nil
+}
+
+// stringField reads a string field of a JSONL object. Missing and null
+// fields are "".
+func stringField(object map[string]json.RawMessage, name string) (string, error) {
+	raw, ok := object[name]
+	if name == "" || !ok || string(raw) == "null" {
+		return "", nil
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("%q must be a string", name)
+	}
+	return value, nil
+}
+
+// newRow validates a row's content and instructions the way typed
+// submissions are validated
+func newRow(line int, content, focus string) Row {
+	row := Row{Line: line, Content: strings.TrimSpace(content)}
+
+	switch length := utf8.RuneCountInString(row.Content); {
+	case length == 0:
+		row.Err = errors.New("content is empty")
+		return row
+	case length > MaxContentLength:
+		row.Err = fmt.Errorf("content is longer than %d characters", MaxContentLength)
+		return row
+	}
+
+	cleaned, err := instructions.Sanitize(focus)
+	switch {
+	case errors.Is(err, instructions.ErrTooLong):
+		row.Err = err
+	case err != nil:
+		row.Err = errors.New("instructions may only describe what the analysis should focus on")
+	case cleaned != "":
+		row.Instructions = &cleaned
+	}
+	return row
+}
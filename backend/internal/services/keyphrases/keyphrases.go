@@ -0,0 +1,203 @@
+// Package keyphrases extracts key phrases from text with RAKE (Rapid
+// Automatic Keyword Extraction) and optionally refines them with phrases
+// suggested by the model.
+package keyphrases
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+const (
+	// MaxPhrases bounds how many keyphrases are kept per analysis
+	MaxPhrases = 10
+
+	// maxPhraseWords drops run-on candidates that RAKE tends to produce
+	maxPhraseWords = 4
+
+	// suggestedScore is the relevance given to model suggestions that RAKE missed
+	suggestedScore = 0.5
+)
+
+// stopwords delimit candidate phrases
+var stopwords = toSet(`a about above after again against all also am an and any are aren't as at
+be because been before being below between both but by can can't cannot could couldn't
+did didn't do does doesn't doing don't down during each few for from further get got
+had hadn't has hasn't have haven't having he her here hers herself him himself his how
+i if in into is isn't it it's its itself just let's like me more most much must my myself
+no nor not now of off on once only or other our ours ourselves out over own really
+same she should shouldn't so some such than that that's the their theirs them themselves
+then there there's these they this those through to too under until up us very
+was wasn't we were weren't what when where which while who whom why will with won't
+would wouldn't you your yours yourself yourselves`)
+
+func toSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// Extract returns up to limit keyphrases ranked by RAKE score. Scores are
+// normalized so the strongest phrase has relevance 1.
+func Extract(text string, limit int) []models.Keyphrase {
+	candidates := candidatePhrases(text)
+	if len(candidates) == 0 {
+		return []models.Keyphrase{}
+	}
+
+	// Word score is degree / frequency, favouring words that appear in
+	// longer phrases
+	frequency := make(map[string]int)
+	degree := make(map[string]int)
+	for _, phrase := range candidates {
+		for _, w := range phrase {
+			frequency[w]++
+			degree[w] += len(phrase)
+		}
+	}
+
+	scores := make(map[string]float64)
+	for _, phrase := range candidates {
+		key := strings.Join(phrase, " ")
+		if _, seen := scores[key]; seen {
+			continue
+		}
+
+		var score float64
+		for _, w := range phrase {
+			score += float64(degree[w]) / float64(frequency[w])
+		}
+		scores[key] = score
+	}
+
+	return rank(scores, limit)
+}
+
+// Refine merges phrases suggested by the model into locally extracted ones.
+// Suggestions that RAKE also found are promoted; suggestions that RAKE
+// missed are kept only if they actually occur in the text, which guards
+// against invented phrases.
+func Refine(text string, local []models.Keyphrase, suggested []string, limit int) []models.Keyphrase {
+	if len(suggested) == 0 {
+		return local
+	}
+
+	scores := make(map[string]float64, len(local))
+	for _, k := range local {
+		scores[k.Phrase] = k.Score
+	}
+
+	lowered := strings.ToLower(text)
+	for _, s := range suggested {
+		phrase := strings.Join(strings.Fields(strings.ToLower(s)), " ")
+		if phrase == "" {
+			continue
+		}
+
+		if score, ok := scores[phrase]; ok {
+			scores[phrase] = (score + 1) / 2
+		} else if strings.Contains(lowered, phrase) {
+			scores[phrase] = suggestedScore
+		}
+	}
+
+	return rank(scores, limit)
+}
+
+// candidatePhrases splits text into runs of content words separated by
+// punctuation and stopwords
+func candidatePhrases(text string) [][]string {
+	var phrases [][]string
+	var current []string
+
+	flush := func() {
+		if len(current) > 0 && len(current) <= maxPhraseWords {
+			phrases = append(phrases, current)
+		}
+		current = nil
+	}
+
+	for _, token := range tokenize(text) {
+		if token == "" || stopwords[token] || !isContentWord(token) {
+			flush()
+			continue
+		}
+		current = append(current, token)
+	}
+	flush()
+
+	return phrases
+}
+
+// tokenize lowercases text and splits it into words, emitting an empty
+// token at each phrase delimiter
+func tokenize(text string) []string {
+	var tokens []string
+	var word strings.Builder
+
+	emit := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, strings.Trim(word.String(), "'"))
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' || r == '-':
+			word.WriteRune(r)
+		case r == '’':
+			word.WriteRune('\'')
+		case unicode.IsSpace(r):
+			emit()
+		default:
+			emit()
+			tokens = append(tokens, "")
+		}
+	}
+	emit()
+
+	return tokens
+}
+
+// isContentWord rejects single characters and bare numbers
+func isContentWord(word string) bool {
+	if len([]rune(word)) < 2 {
+		return false
+	}
+	return strings.IndexFunc(word, unicode.IsLetter) >= 0
+}
+
+// rank sorts phrases by score, normalizes relevance to the top score and
+// applies the limit
+func rank(scores map[string]float64, limit int) []models.Keyphrase {
+	phrases := make([]models.Keyphrase, 0, len(scores))
+	var top float64
+	for phrase, score := range scores {
+		phrases = append(phrases, models.Keyphrase{Phrase: phrase, Score: score})
+		top = max(top, score)
+	}
+
+	sort.Slice(phrases, func(i, j int) bool {
+		if phrases[i].Score != phrases[j].Score {
+			return phrases[i].Score > phrases[j].Score
+		}
+		return phrases[i].Phrase < phrases[j].Phrase
+	})
+
+	if limit > 0 && len(phrases) > limit {
+		phrases = phrases[:limit]
+	}
+
+	for i := range phrases {
+		phrases[i].Score = math.Round(phrases[i].Score/top*100) / 100
+	}
+
+	return phrases
+}
@@ -0,0 +1,93 @@
+package keyphrases
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// rakeText is the abstract used in the original RAKE paper
+const rakeText = "Compatibility of systems of linear constraints over the set of natural numbers. " +
+	"Criteria of compatibility of a system of linear Diophantine equations are considered."
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []models.Keyphrase
+	}{
+		{
+			name:  "rake abstract",
+			text:  rakeText,
+			limit: 3,
+			want: []models.Keyphrase{
+				{Phrase: "linear diophantine equations", Score: 1},
+				{Phrase: "linear constraints", Score: 0.53},
+				{Phrase: "natural numbers", Score: 0.47},
+			},
+		},
+		{
+			name:  "repeated phrase counted once",
+			text:  "The checkout page is slow. Checkout page errors!",
+			limit: 10,
+			want: []models.Keyphrase{
+				{Phrase: "checkout page errors", Score: 1},
+				{Phrase: "checkout page", Score: 0.63},
+				{Phrase: "slow", Score: 0.13},
+			},
+		},
+		{
+			name:  "numbers and stopwords only",
+			text:  "It is 42. And 7 of them.",
+			limit: 10,
+			want:  []models.Keyphrase{},
+		},
+		{
+			name:  "empty",
+			text:  "",
+			limit: 10,
+			want:  []models.Keyphrase{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.text, tt.limit); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtract_DropsLongRuns(t *testing.T) {
+	got := Extract("quarterly revenue growth forecast model accuracy", 10)
+	if len(got) != 0 {
+		t.Errorf("Extract() = %+v, want no phrases longer than %d words", got, maxPhraseWords)
+	}
+}
+
+func TestRefine(t *testing.T) {
+	local := Extract(rakeText, 3)
+
+	got := Refine(rakeText, local, []string{"Natural  Numbers", "set", "quantum gravity", " "}, 4)
+	want := []models.Keyphrase{
+		{Phrase: "linear diophantine equations", Score: 1},
+		{Phrase: "natural numbers", Score: 0.74},
+		{Phrase: "linear constraints", Score: 0.53},
+		{Phrase: "set", Score: 0.5},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Refine() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRefine_NoSuggestions(t *testing.T) {
+	local := Extract(rakeText, 3)
+
+	if got := Refine(rakeText, local, nil, 3); !reflect.DeepEqual(got, local) {
+		t.Errorf("Refine() = %+v, want local phrases unchanged %+v", got, local)
+	}
+}
@@ -0,0 +1,133 @@
+// Package language identifies the language text is written in, locally
+// and without a model call. Scripts used by a single language identify it
+// directly; Latin-script languages are told apart by their most common
+// words.
+package language
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// minWords is the least text the common words are counted in
+	minWords = 5
+	// minHits is how many common words the winning language needs
+	minHits = 2
+	// margin is how many times more common words the winning language
+	// needs than the runner-up
+	margin = 1.5
+)
+
+// scripts identifies languages written in a script of their own. Han is
+// checked after kana, since Japanese mixes the two.
+var scripts = []struct {
+	code  string
+	table *unicode.RangeTable
+}{
+	{"ja", unicode.Hiragana},
+	{"ja", unicode.Katakana},
+	{"ko", unicode.Hangul},
+	{"zh", unicode.Han},
+	{"ru", unicode.Cyrillic},
+	{"ar", unicode.Arabic},
+	{"he", unicode.Hebrew},
+	{"el", unicode.Greek},
+	{"hi", unicode.Devanagari},
+	{"th", unicode.Thai},
+}
+
+// commonWords lists frequent words of each Latin-script language. A word
+// several languages share counts for each of them.
+var commonWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "that", "it", "with", "for", "this", "you", "not", "have", "be", "on", "they", "but"},
+	"es": {"el", "la", "los", "las", "y", "es", "del", "que", "en", "por", "con", "para", "una", "un", "pero", "como", "muy", "su", "lo", "está"},
+	"fr": {"le", "la", "les", "et", "est", "des", "du", "que", "une", "un", "pour", "dans", "avec", "pas", "sur", "qui", "mais", "ce", "il", "sont"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "sich", "auf", "für", "auch", "dem", "den", "von", "sie", "wir", "sind"},
+	"it": {"il", "lo", "gli", "e", "è", "di", "che", "per", "con", "non", "una", "un", "sono", "della", "del", "nel", "anche", "questo", "ma", "come"},
+	"pt": {"o", "os", "as", "e", "é", "do", "da", "que", "em", "com", "para", "não", "uma", "um", "mas", "como", "dos", "das", "mais", "são"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "dat", "op", "met", "voor", "zijn", "ook", "maar", "aan", "er", "wat", "bij", "hij", "ze"},
+}
+
+var commonWordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(commonWords))
+	for code, words := range commonWords {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[w] = true
+		}
+		sets[code] = set
+	}
+	return sets
+}()
+
+// Detect returns the ISO 639-1 code of the language text is most likely
+// written in, or "" when the text is too short or no language stands out
+func Detect(text string) string {
+	if code := detectScript(text); code != "" {
+		return code
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < minWords {
+		return ""
+	}
+
+	hits := make(map[string]int, len(commonWordSets))
+	for _, w := range words {
+		for code, set := range commonWordSets {
+			if set[w] {
+				hits[code]++
+			}
+		}
+	}
+
+	best, bestHits, runnerUp := "", 0, 0
+	for code, n := range hits {
+		switch {
+		case n > bestHits:
+			best, runnerUp, bestHits = code, bestHits, n
+		case n > runnerUp:
+			runnerUp = n
+		}
+	}
+	if bestHits < minHits || float64(bestHits) < margin*float64(runnerUp) {
+		return ""
+	}
+	return best
+}
+
+// detectScript returns the language of a script most of the text's letters
+// are written in, or "" when they are mostly Latin or mixed
+func detectScript(text string) string {
+	letters := 0
+	counts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		for _, s := range scripts {
+			if unicode.Is(s.table, r) {
+				counts[s.code]++
+				break
+			}
+		}
+	}
+	if letters == 0 {
+		return ""
+	}
+
+	// Japanese text is mostly Han with some kana
+	if counts["ja"] > 0 && counts["ja"]+counts["zh"] > letters/2 {
+		return "ja"
+	}
+	for _, s := range scripts {
+		if counts[s.code] > letters/2 {
+			return s.code
+		}
+	}
+	return ""
+}
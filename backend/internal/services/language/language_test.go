@@ -0,0 +1,34 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"english", "The battery life of this phone is superb, and the screen is bright enough to read in the sun.", "en"},
+		{"spanish", "La batería del teléfono es excelente y la pantalla es muy brillante para leer con el sol.", "es"},
+		{"french", "La batterie de ce téléphone est excellente et l'écran est assez lumineux pour lire dans le soleil.", "fr"},
+		{"german", "Die Akkulaufzeit ist hervorragend, und der Bildschirm ist hell genug, um auch in der Sonne zu lesen.", "de"},
+		{"italian", "La batteria di questo telefono è ottima e lo schermo è abbastanza luminoso per leggere anche al sole.", "it"},
+		{"portuguese", "A bateria do telefone é excelente e a tela é brilhante o suficiente para ler com o sol, mas não é leve.", "pt"},
+		{"dutch", "De batterij van deze telefoon is uitstekend en het scherm is helder genoeg om ook in de zon te lezen.", "nl"},
+		{"japanese", "このスマートフォンの電池の持ちは素晴らしいです。", "ja"},
+		{"chinese", "这部手机的电池续航非常出色，屏幕也很明亮。", "zh"},
+		{"korean", "이 휴대폰의 배터리 수명은 훌륭합니다.", "ko"},
+		{"russian", "Батарея этого телефона работает отлично, а экран очень яркий.", "ru"},
+		{"too short", "Hello there", ""},
+		{"no common words", "Lorem ipsum dolor sit amet consectetur adipiscing", ""},
+		{"empty", "  12345 !! ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package limits bounds how many calls the analyzer makes to its
+// providers at once, overall and per provider, and coalesces identical
+// calls that are in flight together into one.
+package limits
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Providers the analyzer calls
+const (
+	ProviderGemini     = "gemini"
+	ProviderFactCheck  = "fact_check"
+	ProviderPerplexity = "perplexity"
+)
+
+// Config caps concurrent calls. Zero, or a provider left out, means no
+// cap.
+type Config struct {
+	Global      int
+	PerProvider map[string]int
+}
+
+// semaphore holds one slot per call in progress; a nil semaphore never
+// blocks
+type semaphore chan struct{}
+
+// newSemaphore returns a semaphore of size slots, or nil when size isn't
+// positive
+func newSemaphore(size int) semaphore {
+	if size < 1 {
+		return nil
+	}
+	return make(semaphore, size)
+}
+
+// acquire takes a slot, waiting until one is free or ctx is done
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// release frees a slot taken with acquire
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// Pool runs provider calls within the configured caps. A nil *Pool runs
+// every call straight away.
+type Pool struct {
+	global    semaphore
+	providers map[string]semaphore
+	calls     singleflight.Group
+}
+
+// New creates a pool with the caps in cfg
+func New(cfg Config) *Pool {
+	p := &Pool{
+		global:    newSemaphore(cfg.Global),
+		providers: make(map[string]semaphore, len(cfg.PerProvider)),
+	}
+	for provider, size := range cfg.PerProvider {
+		p.providers[provider] = newSemaphore(size)
+	}
+	return p
+}
+
+// Do runs fn once a slot is free both overall and for provider. It returns
+// the cause of ctx if ctx is done first.
+func (p *Pool) Do(ctx context.Context, provider string, fn func(ctx context.Context) error) error {
+	if p == nil {
+		return fn(ctx)
+	}
+
+	if err := p.global.acquire(ctx); err != nil {
+		return err
+	}
+	defer p.global.release()
+
+	slots := p.providers[provider]
+	if err := slots.acquire(ctx); err != nil {
+		return err
+	}
+	defer slots.release()
+
+	return fn(ctx)
+}
+
+// Shared runs fn through the pool like Do, unless a call to provider with
+// the same key is already in flight, in which case it waits for that
+// call's result instead. The key must identify the request completely,
+// such as a hash of its content, since callers share the result.
+func Shared[T any](ctx context.Context, p *Pool, provider, key string, fn func(ctx context.Context) (T, error)) (T, error) {
+	run := func(ctx context.Context) (T, error) {
+		var result T
+		err := p.Do(ctx, provider, func(ctx context.Context) error {
+			var err error
+			result, err = fn(ctx)
+			return err
+		})
+		return result, err
+	}
+	if p == nil {
+		return run(ctx)
+	}
+
+	calls := p.calls.DoChan(provider+":"+key, func() (interface{}, error) {
+		return run(ctx)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, context.Cause(ctx)
+	case res := <-calls:
+		// The call we joined was given up by whoever started it, which
+		// says nothing about ours: make our own
+		if res.Err != nil && ctx.Err() == nil && (errors.Is(res.Err, context.Canceled) || errors.Is(res.Err, context.DeadlineExceeded)) {
+			return run(ctx)
+		}
+		if res.Shared {
+			slog.Debug("Coalesced provider call", "provider", provider)
+		}
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		result, _ := res.Val.(T)
+		return result, nil
+	}
+}
@@ -0,0 +1,174 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// peak runs calls concurrent calls through the pool, each holding its slot
+// briefly, and returns the most that ran at once
+func peak(t *testing.T, p *Pool, providers []string, calls int) int64 {
+	t.Helper()
+
+	var running, most atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(provider string) {
+			defer wg.Done()
+			err := p.Do(context.Background(), provider, func(ctx context.Context) error {
+				n := running.Add(1)
+				for {
+					m := most.Load()
+					if n <= m || most.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+				running.Add(-1)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(providers[i%len(providers)])
+	}
+	wg.Wait()
+	return most.Load()
+}
+
+func TestPool_Do(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		providers []string
+		wantPeak  int64
+	}{
+		{"global cap", Config{Global: 3}, []string{ProviderGemini, ProviderFactCheck}, 3},
+		{"provider cap", Config{PerProvider: map[string]int{ProviderGemini: 2}}, []string{ProviderGemini}, 2},
+		{"provider cap under global cap", Config{Global: 5, PerProvider: map[string]int{ProviderGemini: 2}}, []string{ProviderGemini}, 2},
+		{"uncapped provider", Config{PerProvider: map[string]int{ProviderGemini: 2}}, []string{ProviderPerplexity}, 12},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := peak(t, New(tt.cfg), tt.providers, 12); got != tt.wantPeak {
+				t.Errorf("peak concurrency = %d, want %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestPool_DoCanceledWhileWaiting(t *testing.T) {
+	p := New(Config{PerProvider: map[string]int{ProviderGemini: 1}})
+
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	go p.Do(context.Background(), ProviderGemini, func(ctx context.Context) error {
+		close(started)
+		<-hold
+		return nil
+	})
+	<-started
+	defer close(hold)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := p.Do(ctx, ProviderGemini, func(ctx context.Context) error {
+		t.Error("ran without a free slot")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context's deadline", err)
+	}
+}
+
+func TestShared(t *testing.T) {
+	p := New(Config{})
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	slow := func(ctx context.Context) (string, error) {
+		calls.Add(1)
+		<-release
+		return "result", nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var err error
+			if results[i], err = Shared(context.Background(), p, ProviderGemini, "same", slow); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	// Let every caller join the first call before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Errorf("calls = %d, want identical calls coalesced into 1", got)
+	}
+	for _, r := range results {
+		if r != "result" {
+			t.Errorf("result = %q, want the shared result", r)
+		}
+	}
+
+	// Different keys are separate calls
+	release = make(chan struct{})
+	close(release)
+	Shared(context.Background(), p, ProviderGemini, "other", slow)
+	if got := calls.Load(); got != 2 {
+		t.Errorf("calls = %d, want a new call for another key", got)
+	}
+}
+
+func TestShared_StarterCanceled(t *testing.T) {
+	p := New(Config{})
+
+	started := make(chan struct{})
+	starterCtx, cancelStarter := context.WithCancel(context.Background())
+	go Shared(starterCtx, p, ProviderGemini, "key", func(ctx context.Context) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	<-started
+
+	done := make(chan struct{})
+	var got string
+	var err error
+	go func() {
+		defer close(done)
+		got, err = Shared(context.Background(), p, ProviderGemini, "key", func(ctx context.Context) (string, error) {
+			return "own", nil
+		})
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancelStarter()
+	<-done
+
+	if err != nil || got != "own" {
+		t.Errorf("Shared = %q, %v, want its own call's result", got, err)
+	}
+}
+
+func TestNilPool(t *testing.T) {
+	var p *Pool
+	got, err := Shared(context.Background(), p, ProviderGemini, "key", func(ctx context.Context) (int, error) {
+		return 42, nil
+	})
+	if err != nil || got != 42 {
+		t.Errorf("Shared = %d, %v, want the call run straight away", got, err)
+	}
+}
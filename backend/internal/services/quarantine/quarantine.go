@@ -0,0 +1,62 @@
+// Package quarantine tells users when the moderation policy quarantines
+// one of their submissions.
+package quarantine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+)
+
+// SubmissionSource finds quarantined submissions; *models.SubmissionStore
+// implements it
+type SubmissionSource interface {
+	GetQuarantined(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+}
+
+// UserSource finds the owners to email; *models.UserStore implements it
+type UserSource interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
+}
+
+// Notifier emails owners; *notifications.Notifier implements it
+type Notifier interface {
+	SendQuarantine(ctx context.Context, to, outcome string, submissionID uuid.UUID, createdAt time.Time, reason string) error
+}
+
+// Subscriber emails the owner of a submission whose analysis completed
+// with a decision that quarantined it
+func Subscriber(submissions SubmissionSource, users UserSource, notifier Notifier) events.Subscriber {
+	return func(ctx context.Context, change models.StatusChange) error {
+		if change.To != models.StatusCompleted {
+			return nil
+		}
+
+		submission, err := submissions.GetQuarantined(ctx, change.SubmissionID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get submission: %w", err)
+		}
+
+		user, err := users.GetByID(ctx, submission.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to get owner: %w", err)
+		}
+
+		var reason string
+		if submission.QuarantineReason != nil {
+			reason = *submission.QuarantineReason
+		}
+		return notifier.SendQuarantine(ctx, user.Email, notifications.QuarantineQuarantined, submission.ID, submission.CreatedAt.Time, reason)
+	}
+}
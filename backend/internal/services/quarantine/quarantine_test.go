@@ -0,0 +1,70 @@
+package quarantine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+)
+
+// fakeNotifier records the outcomes emailed to each address
+type fakeNotifier struct {
+	sent map[string]string
+}
+
+func (n *fakeNotifier) SendQuarantine(ctx context.Context, to, outcome string, submissionID uuid.UUID, createdAt time.Time, reason string) error {
+	n.sent[to] = reason
+	return nil
+}
+
+func TestSubscriber(t *testing.T) {
+	ctx := context.Background()
+	reason := "Blocked by the moderation policy: toxicity 0.93 above 0.80"
+
+	tests := []struct {
+		name       string
+		to         models.SubmissionStatus
+		quarantine bool
+		wantEmail  bool
+	}{
+		{"quarantined on completion", models.StatusCompleted, true, true},
+		{"completed and allowed", models.StatusCompleted, false, false},
+		{"not completed", models.StatusProcessing, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := memstore.NewUserStore()
+			submissions := memstore.NewSubmissionStore()
+			notifier := &fakeNotifier{sent: map[string]string{}}
+
+			user, err := users.Create(ctx, "owner@example.com", "password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+			submission, _ := submissions.Create(ctx, user.ID, "Some content", nil, nil, nil, models.StatusQueued)
+			if tt.quarantine {
+				if _, err := submissions.Quarantine(ctx, submission.ID, reason); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			change := models.StatusChange{SubmissionID: submission.ID, UserID: user.ID, To: tt.to}
+			if err := Subscriber(submissions, users, notifier)(ctx, change); err != nil {
+				t.Fatal(err)
+			}
+
+			got, emailed := notifier.sent[user.Email]
+			if emailed != tt.wantEmail {
+				t.Fatalf("emailed = %v, want %v", emailed, tt.wantEmail)
+			}
+			if emailed && got != reason {
+				t.Errorf("reason = %q, want %q", got, reason)
+			}
+		})
+	}
+}
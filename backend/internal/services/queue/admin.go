@@ -0,0 +1,232 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// pausedKey is set while workers should stop picking up jobs
+	pausedKey = "queue:paused"
+
+	// statsKeyPrefix namespaces per-minute counters of finished jobs
+	statsKeyPrefix = "queue:stats:"
+
+	// ThroughputWindow is how far back Stats counts finished jobs
+	ThroughputWindow = 5 * time.Minute
+)
+
+var (
+	// ErrJobNotFound is returned when no pending or dead job has the ID
+	ErrJobNotFound = errors.New("job not found")
+
+	// ErrJobRunning is returned when canceling a job a worker already holds
+	ErrJobRunning = errors.New("job is being processed")
+)
+
+// Stats is a snapshot of the queue
+type Stats struct {
+	Paused  bool
+	Pending int64
+	// PendingByPriority splits Pending by lane
+	PendingByPriority map[Priority]int64
+	InFlight          int64
+	Dead              int64
+	// Held counts jobs set aside until Unhold releases them
+	Held int64
+	// OldestPendingAge is zero when nothing is pending
+	OldestPendingAge time.Duration
+	// Completed and Failed count jobs finished within ThroughputWindow.
+	// Failed includes attempts that will be retried.
+	Completed int64
+	Failed    int64
+}
+
+// Stats reports queue depth, in-flight jobs and recent throughput
+func (q *Queue) Stats(ctx context.Context) (*Stats, error) {
+	minutes := statsMinutes(time.Now())
+
+	pipe := q.client.Pipeline()
+	pending := make(map[Priority]*redis.IntCmd, len(Priorities))
+	oldest := make(map[Priority]*redis.StringCmd, len(Priorities))
+	heldJobs := make(map[Priority]*redis.IntCmd, len(Priorities))
+	for _, p := range Priorities {
+		pending[p] = pipe.LLen(ctx, p.key())
+		oldest[p] = pipe.LIndex(ctx, p.key(), -1)
+		heldJobs[p] = pipe.LLen(ctx, p.heldKey())
+	}
+	inFlight := pipe.LLen(ctx, processingKey)
+	dead := pipe.LLen(ctx, deadKey)
+	paused := pipe.Exists(ctx, pausedKey)
+	completed := pipe.MGet(ctx, statsKeys("completed", minutes)...)
+	failed := pipe.MGet(ctx, statsKeys("failed", minutes)...)
+
+	// LINDEX on an empty list returns redis.Nil, which Exec reports too
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read queue stats: %w", err)
+	}
+
+	stats := &Stats{
+		Paused:            paused.Val() > 0,
+		PendingByPriority: make(map[Priority]int64, len(Priorities)),
+		InFlight:          inFlight.Val(),
+		Dead:              dead.Val(),
+		Completed:         sumCounters(completed.Val()),
+		Failed:            sumCounters(failed.Val()),
+	}
+
+	for _, p := range Priorities {
+		stats.PendingByPriority[p] = pending[p].Val()
+		stats.Pending += pending[p].Val()
+		stats.Held += heldJobs[p].Val()
+
+		raw, err := oldest[p].Result()
+		if err != nil {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err == nil {
+			if age := time.Since(job.EnqueuedAt.Time); age > stats.OldestPendingAge {
+				stats.OldestPendingAge = age
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+// InFlight returns the jobs workers are currently handling
+func (q *Queue) InFlight(ctx context.Context) ([]Job, error) {
+	raws, err := q.client.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list in-flight jobs: %w", err)
+	}
+
+	jobs := make([]Job, 0, len(raws))
+	for _, raw := range raws {
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, nil
+}
+
+// Pause stops workers from picking up new jobs. Jobs already running
+// finish; a job dequeued while pausing is put back.
+func (q *Queue) Pause(ctx context.Context) error {
+	return q.client.Set(ctx, pausedKey, time.Now().UTC().Format(time.RFC3339), 0).Err()
+}
+
+// Resume lets workers pick up jobs again
+func (q *Queue) Resume(ctx context.Context) error {
+	return q.client.Del(ctx, pausedKey).Err()
+}
+
+// Paused reports whether workers are paused
+func (q *Queue) Paused(ctx context.Context) (bool, error) {
+	n, err := q.client.Exists(ctx, pausedKey).Result()
+	return n > 0, err
+}
+
+// Cancel removes a pending, held or dead job so it never runs. It returns
+// ErrJobRunning if a worker already holds the job and ErrJobNotFound if no
+// job has the ID.
+func (q *Queue) Cancel(ctx context.Context, id string) (*Job, error) {
+	for _, key := range []string{highPendingKey, pendingKey, PriorityHigh.heldKey(), PriorityDefault.heldKey(), deadKey} {
+		job, raw, err := q.find(ctx, key, id)
+		if err != nil {
+			return nil, err
+		}
+		if job == nil {
+			continue
+		}
+
+		removed, err := q.client.LRem(ctx, key, 1, raw).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to cancel job: %w", err)
+		}
+		if removed == 0 {
+			// A worker picked it up between the lookup and the removal
+			return nil, ErrJobRunning
+		}
+		return job, nil
+	}
+
+	if job, _, err := q.find(ctx, processingKey, id); err != nil {
+		return nil, err
+	} else if job != nil {
+		return nil, ErrJobRunning
+	}
+
+	return nil, ErrJobNotFound
+}
+
+// find looks up a job by ID in a list, returning it and its raw entry
+func (q *Queue) find(ctx context.Context, key, id string) (*Job, string, error) {
+	raws, err := q.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to search jobs: %w", err)
+	}
+
+	for _, raw := range raws {
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err == nil && job.ID == id {
+			return &job, raw, nil
+		}
+	}
+
+	return nil, "", nil
+}
+
+// recordOutcome counts a finished job towards throughput
+func (q *Queue) recordOutcome(ctx context.Context, outcome string) error {
+	key := statsKeyPrefix + outcome + ":" + strconv.FormatInt(time.Now().Unix()/60, 10)
+
+	pipe := q.client.Pipeline()
+	pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 2*ThroughputWindow)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// statsMinutes returns the minute buckets covered by ThroughputWindow
+func statsMinutes(now time.Time) []int64 {
+	current := now.Unix() / 60
+	n := int64(ThroughputWindow / time.Minute)
+
+	minutes := make([]int64, 0, n)
+	for i := int64(0); i < n; i++ {
+		minutes = append(minutes, current-i)
+	}
+	return minutes
+}
+
+// statsKeys returns the counter keys of an outcome for the given minutes
+func statsKeys(outcome string, minutes []int64) []string {
+	keys := make([]string, len(minutes))
+	for i, m := range minutes {
+		keys[i] = statsKeyPrefix + outcome + ":" + strconv.FormatInt(m, 10)
+	}
+	return keys
+}
+
+// sumCounters adds up MGET results, skipping missing keys
+func sumCounters(values []interface{}) int64 {
+	var total int64
+	for _, v := range values {
+		if s, ok := v.(string); ok {
+			n, _ := strconv.ParseInt(s, 10, 64)
+			total += n
+		}
+	}
+	return total
+}
@@ -0,0 +1,37 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BackpressureRetryAfter is how long clients turned away while the queue
+// is saturated are asked to wait
+const BackpressureRetryAfter = 30 * time.Second
+
+// Backpressure reports the queue saturated once maxDepth jobs are pending,
+// so new work can be refused before it piles up behind them
+type Backpressure struct {
+	queue    *Queue
+	maxDepth int64
+}
+
+// NewBackpressure creates a saturation check for queue at maxDepth
+// pending jobs
+func NewBackpressure(queue *Queue, maxDepth int) *Backpressure {
+	return &Backpressure{queue: queue, maxDepth: int64(maxDepth)}
+}
+
+// Saturated reports whether the queue is too deep to take more work, and
+// how long to wait before trying again
+func (b *Backpressure) Saturated(ctx context.Context) (bool, time.Duration, error) {
+	depth, err := b.queue.Depth(ctx)
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to read queue depth: %w", err)
+	}
+	if depth < b.maxDepth {
+		return false, 0, nil
+	}
+	return true, BackpressureRetryAfter, nil
+}
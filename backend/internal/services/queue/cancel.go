@@ -0,0 +1,59 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// cancelKeyPrefix namespaces cancellation flags
+	cancelKeyPrefix = "queue:cancel:"
+
+	// cancelFlagTTL outlives any job that could still be watching
+	cancelFlagTTL = time.Hour
+)
+
+// cancelPollInterval is how often a watched job checks for a cancellation
+var cancelPollInterval = time.Second
+
+// ErrCanceled is the cause of a context canceled by RequestCancel
+var ErrCanceled = errors.New("job canceled")
+
+// RequestCancel flags the work identified by key as canceled. Workers
+// watching the key with WatchCancel stop on their next check.
+func (q *Queue) RequestCancel(ctx context.Context, key string) error {
+	return q.client.Set(ctx, cancelKeyPrefix+key, time.Now().UTC().Format(time.RFC3339), cancelFlagTTL).Err()
+}
+
+// CancelRequested reports whether RequestCancel was called for key
+func (q *Queue) CancelRequested(ctx context.Context, key string) (bool, error) {
+	n, err := q.client.Exists(ctx, cancelKeyPrefix+key).Result()
+	return n > 0, err
+}
+
+// WatchCancel returns a context that is canceled with cause ErrCanceled
+// once RequestCancel is called for key. Call stop when the work is done.
+func (q *Queue) WatchCancel(ctx context.Context, key string) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+
+	go func() {
+		ticker := time.NewTicker(cancelPollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// A failed check is retried on the next tick
+				if requested, err := q.CancelRequested(ctx, key); err == nil && requested {
+					cancel(ErrCanceled)
+					return
+				}
+			}
+		}
+	}()
+
+	return ctx, func() { cancel(nil) }
+}
@@ -0,0 +1,32 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FailError is returned by a handler to give up on its job without using
+// its remaining attempts, for failures another attempt won't fix. The job
+// goes to the dead list.
+type FailError struct {
+	Err error
+}
+
+// Fail wraps err so its job is given up on at once
+func Fail(err error) error {
+	return &FailError{Err: err}
+}
+
+func (e *FailError) Error() string {
+	return fmt.Sprintf("failed permanently: %v", e.Err)
+}
+
+func (e *FailError) Unwrap() error {
+	return e.Err
+}
+
+// permanent reports whether err gives up on its job
+func permanent(err error) bool {
+	var failErr *FailError
+	return errors.As(err, &failErr)
+}
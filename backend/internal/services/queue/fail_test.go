@@ -0,0 +1,22 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("malformed output")
+	err := fmt.Errorf("analysis failed: %w", Fail(cause))
+
+	if !permanent(err) {
+		t.Error("permanent() = false for a wrapped FailError")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Fail() doesn't wrap its cause")
+	}
+	if permanent(cause) || permanent(Hold(cause)) || permanent(Defer(cause, 0)) {
+		t.Error("permanent() = true for an error that can be retried")
+	}
+}
@@ -0,0 +1,86 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// UnholdJobType puts held jobs back in their lanes. It is scheduled so
+// held jobs are tried again once whatever held them may have cleared.
+const UnholdJobType = "queue.unhold"
+
+// HoldError is returned by a handler to set its job aside, without using
+// up an attempt, until Unhold puts it back in its lane. It is for jobs
+// that can't run for a while, such as analyses over a spend budget.
+type HoldError struct {
+	Err error
+}
+
+// Hold wraps err so the job is set aside until held jobs are released
+func Hold(err error) error {
+	return &HoldError{Err: err}
+}
+
+func (e *HoldError) Error() string {
+	return fmt.Sprintf("held: %v", e.Err)
+}
+
+func (e *HoldError) Unwrap() error {
+	return e.Err
+}
+
+// held reports whether err holds its job
+func held(err error) bool {
+	var holdErr *HoldError
+	return errors.As(err, &holdErr)
+}
+
+// heldKey returns the list a lane's held jobs wait in
+func (p Priority) heldKey() string {
+	return p.key() + ":held"
+}
+
+// hold moves a job from the processing list to its lane's held list
+func (q *Queue) hold(ctx context.Context, raw string, job *Job) error {
+	pipe := q.client.TxPipeline()
+	pipe.LRem(ctx, processingKey, 1, raw)
+	pipe.HDel(ctx, claimsKey, raw)
+	pipe.LPush(ctx, job.Priority.heldKey(), raw)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Unhold puts every held job back at the back of its lane and returns how
+// many it released. Jobs still unable to run are held again when a worker
+// picks them up.
+func (q *Queue) Unhold(ctx context.Context) (int, error) {
+	released := 0
+	for _, p := range Priorities {
+		for {
+			err := q.client.LMove(ctx, p.heldKey(), p.key(), "RIGHT", "LEFT").Err()
+			if errors.Is(err, redis.Nil) {
+				break
+			}
+			if err != nil {
+				return released, fmt.Errorf("failed to release held jobs: %w", err)
+			}
+			released++
+		}
+	}
+	return released, nil
+}
+
+// UnholdHandler returns a Handler for UnholdJobType
+func (q *Queue) UnholdHandler() Handler {
+	return func(ctx context.Context, job *Job) error {
+		released, err := q.Unhold(ctx)
+		if released > 0 {
+			slog.Info("Released held jobs", "count", released)
+		}
+		return err
+	}
+}
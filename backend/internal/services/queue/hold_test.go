@@ -0,0 +1,31 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestHeld(t *testing.T) {
+	cause := errors.New("over budget")
+	err := fmt.Errorf("analysis held: %w", Hold(cause))
+
+	if !held(err) {
+		t.Error("held() = false for a wrapped HoldError")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Hold() doesn't wrap its cause")
+	}
+	if held(cause) || held(Defer(cause, 0)) {
+		t.Error("held() = true for an error that doesn't hold its job")
+	}
+}
+
+func TestPriority_HeldKey(t *testing.T) {
+	if PriorityHigh.heldKey() == PriorityDefault.heldKey() {
+		t.Error("lanes share a held list")
+	}
+	if Priority("").heldKey() != PriorityDefault.heldKey() {
+		t.Error("jobs without a priority aren't held with the default lane")
+	}
+}
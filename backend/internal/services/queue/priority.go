@@ -0,0 +1,122 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Priority selects the lane a job waits in
+type Priority string
+
+const (
+	// PriorityDefault is the lane every job uses unless told otherwise
+	PriorityDefault Priority = "default"
+
+	// PriorityHigh is consumed ahead of the default lane
+	PriorityHigh Priority = "high"
+
+	// highPendingKey holds high priority jobs waiting to be picked up
+	highPendingKey = "queue:pending:high"
+
+	// DefaultHighPriorityBurst is how many high priority jobs a worker takes
+	// in a row before it gives a waiting default job a turn
+	DefaultHighPriorityBurst = 4
+)
+
+// Priorities lists the lanes, highest first
+var Priorities = []Priority{PriorityHigh, PriorityDefault}
+
+// key returns the pending list of the lane. Jobs enqueued before lanes
+// existed have no priority and belong to the default lane.
+func (p Priority) key() string {
+	if p == PriorityHigh {
+		return highPendingKey
+	}
+	return pendingKey
+}
+
+// normalize maps unknown or missing priorities to the default lane
+func (p Priority) normalize() Priority {
+	if p == PriorityHigh {
+		return PriorityHigh
+	}
+	return PriorityDefault
+}
+
+// laneSelector decides which lane a worker tries first. High priority jobs
+// are preferred, but after burst of them in a row the default lane goes
+// first so it can't be starved.
+type laneSelector struct {
+	burst  int
+	streak int
+}
+
+// order returns the lanes to try, in order
+func (s *laneSelector) order() []Priority {
+	if s.streak >= s.burst {
+		return []Priority{PriorityDefault, PriorityHigh}
+	}
+	return []Priority{PriorityHigh, PriorityDefault}
+}
+
+// took records the lane a job was taken from
+func (s *laneSelector) took(p Priority) {
+	if p == PriorityHigh {
+		s.streak++
+		return
+	}
+	s.streak = 0
+}
+
+// moveFirstScript moves the oldest job of the first non-empty lane in
+// KEYS[3:] onto the processing list KEYS[1], records in the hash KEYS[2]
+// that it was claimed at ARGV[1], and returns the lane and job
+var moveFirstScript = redis.NewScript(`
+for i = 3, #KEYS do
+	local job = redis.call('LMOVE', KEYS[i], KEYS[1], 'RIGHT', 'LEFT')
+	if job then
+		redis.call('HSET', KEYS[2], job, ARGV[1])
+		return {KEYS[i], job}
+	end
+end
+return false
+`)
+
+// dequeue atomically moves the next job from the first non-empty lane onto
+// the processing list and records when it was claimed. It returns
+// (nil, "", nil) when every lane is empty.
+func (q *Queue) dequeue(ctx context.Context, lanes []Priority) (*Job, string, error) {
+	keys := make([]string, 0, len(lanes)+2)
+	keys = append(keys, processingKey, claimsKey)
+	for _, p := range lanes {
+		keys = append(keys, p.key())
+	}
+
+	res, err := moveFirstScript.Run(ctx, q.client, keys, time.Now().UnixMilli()).StringSlice()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	if len(res) != 2 {
+		return nil, "", fmt.Errorf("unexpected dequeue result: %v", res)
+	}
+	raw := res[1]
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		// Drop malformed entries so they don't block the queue forever
+		q.client.LRem(ctx, processingKey, 1, raw)
+		q.client.HDel(ctx, claimsKey, raw)
+		return nil, "", fmt.Errorf("failed to decode job: %w", err)
+	}
+	job.Priority = job.Priority.normalize()
+
+	return &job, raw, nil
+}
@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLaneSelector(t *testing.T) {
+	highFirst := []Priority{PriorityHigh, PriorityDefault}
+	defaultFirst := []Priority{PriorityDefault, PriorityHigh}
+
+	s := &laneSelector{burst: 2}
+
+	steps := []struct {
+		took Priority
+		want []Priority
+	}{
+		{took: PriorityHigh, want: highFirst},
+		{took: PriorityHigh, want: defaultFirst},
+		// The default lane was empty, so it stays first until it yields a job
+		{took: PriorityHigh, want: defaultFirst},
+		{took: PriorityDefault, want: highFirst},
+	}
+
+	if got := s.order(); !reflect.DeepEqual(got, highFirst) {
+		t.Fatalf("order() = %v, want %v", got, highFirst)
+	}
+
+	for i, step := range steps {
+		s.took(step.took)
+		if got := s.order(); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("step %d: order() = %v, want %v", i, got, step.want)
+		}
+	}
+}
+
+func TestPriority_Key(t *testing.T) {
+	tests := []struct {
+		priority Priority
+		want     string
+	}{
+		{priority: PriorityHigh, want: highPendingKey},
+		{priority: PriorityDefault, want: pendingKey},
+		// Jobs enqueued before priorities existed
+		{priority: "", want: pendingKey},
+	}
+
+	for _, tt := range tests {
+		if got := tt.priority.key(); got != tt.want {
+			t.Errorf("Priority(%q).key() = %q, want %q", tt.priority, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+const (
+	// pendingKey holds default priority jobs waiting to be picked up (LPUSH /
+	// LMOVE from the right)
+	pendingKey = "queue:pending"
+
+	// processingKey holds jobs currently being handled by a worker
+	processingKey = "queue:processing"
+
+	// claimsKey maps each entry of the processing list to the Unix
+	// millisecond it was picked up, so RequeueStale can tell when its
+	// worker stopped responding
+	claimsKey = "queue:processing:claims"
+
+	// deadKey holds jobs that exhausted their retries
+	deadKey = "queue:dead"
+
+	// defaultMaxAttempts is used when a job does not specify its own limit
+	defaultMaxAttempts = 3
+)
+
+// Job is a unit of background work
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	Priority    Priority        `json:"priority"`
+	EnqueuedAt  timestamp.Time  `json:"enqueued_at"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Decode unmarshals the job payload into v
+func (j *Job) Decode(v interface{}) error {
+	if len(j.Payload) == 0 {
+		return nil
+	}
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Queue is a Redis-backed FIFO job queue
+type Queue struct {
+	client *redis.Client
+}
+
+// New creates a new queue on top of a Redis client
+func New(client *redis.Client) *Queue {
+	return &Queue{client: client}
+}
+
+// Enqueue adds a job of the given type to the default lane
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) (*Job, error) {
+	return q.EnqueuePriority(ctx, PriorityDefault, jobType, payload)
+}
+
+// EnqueuePriority adds a job of the given type to the priority's lane
+func (q *Queue) EnqueuePriority(ctx context.Context, priority Priority, jobType string, payload interface{}) (*Job, error) {
+	job := &Job{
+		ID:          uuid.NewString(),
+		Type:        jobType,
+		MaxAttempts: defaultMaxAttempts,
+		Priority:    priority.normalize(),
+		EnqueuedAt:  timestamp.Now(),
+	}
+
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job payload: %w", err)
+		}
+		job.Payload = data
+	}
+
+	if err := q.push(ctx, job); err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
+
+// push serializes a job onto its lane
+func (q *Queue) push(ctx context.Context, job *Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	if err := q.client.LPush(ctx, job.Priority.key(), data).Err(); err != nil {
+		return fmt.Errorf("failed to enqueue job: %w", err)
+	}
+
+	return nil
+}
+
+// ack removes a finished job from the processing list
+func (q *Queue) ack(ctx context.Context, raw string) error {
+	pipe := q.client.TxPipeline()
+	pipe.LRem(ctx, processingKey, 1, raw)
+	pipe.HDel(ctx, claimsKey, raw)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// release returns an unstarted job to the front of its lane
+func (q *Queue) release(ctx context.Context, raw string, job *Job) error {
+	pipe := q.client.TxPipeline()
+	pipe.LRem(ctx, processingKey, 1, raw)
+	pipe.HDel(ctx, claimsKey, raw)
+	pipe.RPush(ctx, job.Priority.key(), raw)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// retry re-enqueues a failed job, or moves it to the dead list once it has
+// used up its attempts or failed permanently
+func (q *Queue) retry(ctx context.Context, raw string, job *Job, jobErr error) error {
+	data, target, err := failAttempt(job, jobErr)
+	if err != nil {
+		return err
+	}
+
+	pipe := q.client.TxPipeline()
+	pipe.LRem(ctx, processingKey, 1, raw)
+	pipe.HDel(ctx, claimsKey, raw)
+	pipe.LPush(ctx, target, data)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// failAttempt records a failed attempt of job and returns it encoded, with
+// the list it goes to next: its lane, or the dead list once it has used up
+// its attempts or failed permanently
+func failAttempt(job *Job, jobErr error) ([]byte, string, error) {
+	job.Attempts++
+	job.LastError = jobErr.Error()
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode job: %w", err)
+	}
+
+	if job.Attempts >= job.MaxAttempts || permanent(jobErr) {
+		return data, deadKey, nil
+	}
+	return data, job.Priority.key(), nil
+}
+
+// Depth returns the number of pending jobs across all lanes
+func (q *Queue) Depth(ctx context.Context) (int64, error) {
+	pipe := q.client.Pipeline()
+	high := pipe.LLen(ctx, highPendingKey)
+	def := pipe.LLen(ctx, pendingKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return high.Val() + def.Val(), nil
+}
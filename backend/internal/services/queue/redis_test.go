@@ -0,0 +1,86 @@
+//go:build integration
+
+package queue_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/testutil"
+)
+
+func newQueue(t *testing.T) *queue.Queue {
+	t.Helper()
+
+	opts, err := redis.ParseURL(testutil.RedisURL(t))
+	if err != nil {
+		t.Fatalf("redis.ParseURL() error = %v", err)
+	}
+	client := redis.NewClient(opts)
+	t.Cleanup(func() { client.Close() })
+
+	return queue.New(client)
+}
+
+func TestQueue_RequeueStale(t *testing.T) {
+	ctx := context.Background()
+	q := newQueue(t)
+
+	enqueued, err := q.Enqueue(ctx, "stuck", nil)
+	if err != nil {
+		t.Fatalf("Enqueue() error = %v", err)
+	}
+
+	// A worker takes the job and stops responding
+	started, unblock := make(chan struct{}), make(chan struct{})
+	worker := queue.NewWorker(q, 1)
+	worker.Register("stuck", func(ctx context.Context, job *queue.Job) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	runCtx, stop := context.WithCancel(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		worker.Run(runCtx)
+		close(stopped)
+	}()
+	<-started
+
+	// Paused, so nothing picks the job up again once it's back
+	if err := q.Pause(ctx); err != nil {
+		t.Fatalf("Pause() error = %v", err)
+	}
+
+	if n, err := q.RequeueStale(ctx, time.Hour); err != nil || n != 0 {
+		t.Errorf("RequeueStale() of a fresh claim = %d, %v, want 0", n, err)
+	}
+	if n, err := q.RequeueStale(ctx, 0); err != nil || n != 1 {
+		t.Fatalf("RequeueStale() = %d, %v, want 1", n, err)
+	}
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.InFlight != 0 || stats.Pending != 1 {
+		t.Errorf("Stats() in flight = %d, pending = %d, want 0 and 1", stats.InFlight, stats.Pending)
+	}
+
+	// The worker finishing late leaves the requeued job alone
+	close(unblock)
+	stop()
+	<-stopped
+
+	job, err := q.Cancel(ctx, enqueued.ID)
+	if err != nil {
+		t.Fatalf("Cancel() error = %v, want the requeued job pending", err)
+	}
+	if job.Attempts != 1 || job.LastError != queue.ErrWorkerLost.Error() {
+		t.Errorf("requeued job = %+v, want the lost run counted as a failed attempt", job)
+	}
+}
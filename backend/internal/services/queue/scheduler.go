@@ -0,0 +1,108 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// scheduledJob is a job enqueued on a fixed interval
+type scheduledJob struct {
+	jobType  string
+	payload  interface{}
+	interval time.Duration
+}
+
+// Locker lets one of several replicas claim a unit of work for a while
+type Locker interface {
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Scheduler periodically enqueues recurring jobs
+type Scheduler struct {
+	queue  *Queue
+	jobs   []scheduledJob
+	locker Locker
+	now    func() time.Time
+}
+
+// NewScheduler creates a new scheduler for the given queue
+func NewScheduler(queue *Queue) *Scheduler {
+	return &Scheduler{queue: queue, now: time.Now}
+}
+
+// WithLocker makes replicas sharing the locker enqueue each job once per
+// interval between them instead of once each
+func (s *Scheduler) WithLocker(locker Locker) *Scheduler {
+	s.locker = locker
+	return s
+}
+
+// Every registers a job to be enqueued once per interval.
+// Non-positive intervals disable the job.
+func (s *Scheduler) Every(interval time.Duration, jobType string, payload interface{}) {
+	if interval <= 0 {
+		slog.Warn("Scheduled job disabled", "job_type", jobType)
+		return
+	}
+
+	s.jobs = append(s.jobs, scheduledJob{
+		jobType:  jobType,
+		payload:  payload,
+		interval: interval,
+	})
+}
+
+// Run enqueues scheduled jobs until ctx is canceled
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, job := range s.jobs {
+		wg.Add(1)
+		go func(job scheduledJob) {
+			defer wg.Done()
+
+			ticker := time.NewTicker(job.interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					s.fire(ctx, job)
+				}
+			}
+		}(job)
+	}
+
+	wg.Wait()
+}
+
+// fire enqueues one run of job unless another replica already has for the
+// current period
+func (s *Scheduler) fire(ctx context.Context, job scheduledJob) {
+	if s.locker != nil {
+		claimed, err := s.locker.Claim(ctx, periodKey(job, s.now()), job.interval)
+		if err != nil {
+			// Running twice beats not running at all
+			slog.Warn("Failed to claim scheduled job, enqueueing anyway", "job_type", job.jobType, "error", err)
+		} else if !claimed {
+			slog.Debug("Scheduled job already enqueued by another replica", "job_type", job.jobType)
+			return
+		}
+	}
+
+	if _, err := s.queue.Enqueue(ctx, job.jobType, job.payload); err != nil {
+		slog.Error("Failed to enqueue scheduled job", "job_type", job.jobType, "error", err)
+	}
+}
+
+// periodKey names the interval-long window of wall-clock time containing
+// now. Every replica ticks once per interval, so each window is claimed
+// exactly once however the replicas' tickers are offset.
+func periodKey(job scheduledJob, now time.Time) string {
+	return fmt.Sprintf("scheduler:%s:%d", job.jobType, now.Truncate(job.interval).Unix())
+}
@@ -0,0 +1,53 @@
+package queue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// stubLocker grants or refuses every claim and records the keys
+type stubLocker struct {
+	claimed bool
+	keys    []string
+}
+
+func (l *stubLocker) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	l.keys = append(l.keys, key)
+	return l.claimed, nil
+}
+
+func TestPeriodKey(t *testing.T) {
+	job := scheduledJob{jobType: "digest", interval: time.Hour}
+	base := time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		a, b time.Time
+		same bool
+	}{
+		{"same hour", base.Add(5 * time.Minute), base.Add(55 * time.Minute), true},
+		{"next hour", base.Add(55 * time.Minute), base.Add(65 * time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := periodKey(job, tt.a) == periodKey(job, tt.b); got != tt.same {
+				t.Errorf("periodKey(%v) == periodKey(%v) = %v, want %v", tt.a, tt.b, got, tt.same)
+			}
+		})
+	}
+}
+
+func TestScheduler_FireSkipsClaimedPeriod(t *testing.T) {
+	locker := &stubLocker{claimed: false}
+	// A nil queue panics if the scheduler tries to enqueue
+	s := NewScheduler(nil).WithLocker(locker)
+	s.now = func() time.Time { return time.Unix(7200, 0) }
+
+	s.fire(context.Background(), scheduledJob{jobType: "digest", interval: time.Hour})
+
+	if want := "scheduler:digest:7200"; len(locker.keys) != 1 || locker.keys[0] != want {
+		t.Errorf("claimed keys = %v, want [%s]", locker.keys, want)
+	}
+}
@@ -0,0 +1,117 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// RequeueStaleJobType puts back in-flight jobs whose worker stopped
+	// responding. It is scheduled so a crashed worker's jobs don't stay in
+	// the processing list forever.
+	RequeueStaleJobType = "queue.requeue_stale"
+
+	// DefaultVisibilityTimeout is how long a job may stay claimed before
+	// RequeueStale assumes its worker is gone
+	DefaultVisibilityTimeout = 30 * time.Minute
+)
+
+// ErrWorkerLost is recorded as the last error of jobs RequeueStale put back
+var ErrWorkerLost = errors.New("worker stopped responding")
+
+// requeueScript moves entry ARGV[1] of the processing list KEYS[1] to the
+// list KEYS[3] as ARGV[2] and drops its claim from the hash KEYS[2],
+// unless a worker finished the job first
+var requeueScript = redis.NewScript(`
+if redis.call('LREM', KEYS[1], 1, ARGV[1]) == 0 then
+	return 0
+end
+redis.call('HDEL', KEYS[2], ARGV[1])
+redis.call('LPUSH', KEYS[3], ARGV[2])
+return 1
+`)
+
+// RequeueStale puts back every in-flight job claimed more than olderThan
+// ago and returns how many it put back. The lost run counts as a failed
+// attempt, so a job that keeps taking its worker down ends up dead rather
+// than looping. Jobs claimed before claims were recorded get their clock
+// started now.
+func (q *Queue) RequeueStale(ctx context.Context, olderThan time.Duration) (int, error) {
+	raws, err := q.client.LRange(ctx, processingKey, 0, -1).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list in-flight jobs: %w", err)
+	}
+	if len(raws) == 0 {
+		return 0, nil
+	}
+
+	claims, err := q.client.HMGet(ctx, claimsKey, raws...).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read job claims: %w", err)
+	}
+
+	now := time.Now()
+	requeued := 0
+	for i, raw := range raws {
+		claimed, ok := claimTime(claims[i])
+		if !ok {
+			if err := q.client.HSetNX(ctx, claimsKey, raw, now.UnixMilli()).Err(); err != nil {
+				return requeued, fmt.Errorf("failed to record job claim: %w", err)
+			}
+			continue
+		}
+		if now.Sub(claimed) < olderThan {
+			continue
+		}
+
+		var job Job
+		if err := json.Unmarshal([]byte(raw), &job); err != nil {
+			continue
+		}
+		job.Priority = job.Priority.normalize()
+
+		data, target, err := failAttempt(&job, ErrWorkerLost)
+		if err != nil {
+			return requeued, err
+		}
+		moved, err := requeueScript.Run(ctx, q.client, []string{processingKey, claimsKey, target}, raw, data).Int()
+		if err != nil {
+			return requeued, fmt.Errorf("failed to requeue job: %w", err)
+		}
+		if moved == 1 {
+			slog.Warn("Requeued stale job", "job_id", job.ID, "job_type", job.Type, "claimed_at", claimed, "dead", target == deadKey)
+			requeued++
+		}
+	}
+
+	return requeued, nil
+}
+
+// claimTime parses a claim read with HMGET; ok is false for a missing one
+func claimTime(value interface{}) (time.Time, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	ms, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(ms), true
+}
+
+// RequeueStaleHandler returns a Handler for RequeueStaleJobType that puts
+// back jobs claimed more than olderThan ago
+func (q *Queue) RequeueStaleHandler(olderThan time.Duration) Handler {
+	return func(ctx context.Context, job *Job) error {
+		_, err := q.RequeueStale(ctx, olderThan)
+		return err
+	}
+}
@@ -0,0 +1,34 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClaimTime(t *testing.T) {
+	claimed := time.UnixMilli(1760000000123)
+
+	got, ok := claimTime("1760000000123")
+	if !ok || !got.Equal(claimed) {
+		t.Errorf("claimTime() = %v, %v, want %v", got, ok, claimed)
+	}
+	for _, value := range []interface{}{nil, "", "soon"} {
+		if _, ok := claimTime(value); ok {
+			t.Errorf("claimTime(%#v) ok = true, want false", value)
+		}
+	}
+}
+
+func TestFailAttempt(t *testing.T) {
+	job := &Job{ID: "job-1", MaxAttempts: 2, Priority: PriorityHigh}
+
+	_, target, err := failAttempt(job, ErrWorkerLost)
+	if err != nil || target != highPendingKey || job.Attempts != 1 || job.LastError != ErrWorkerLost.Error() {
+		t.Errorf("failAttempt() = %s, %v with job %+v, want the job back in its lane", target, err, job)
+	}
+
+	// The last attempt a lost worker used up leaves the job dead
+	if _, target, _ := failAttempt(job, ErrWorkerLost); target != deadKey {
+		t.Errorf("failAttempt() of the last attempt = %s, want %s", target, deadKey)
+	}
+}
@@ -0,0 +1,68 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Throttle says how long to hold back jobs that call a rate limited
+// provider, such as the budget an AI client keeps
+type Throttle interface {
+	Delay() time.Duration
+}
+
+// DeferError is returned by a handler to put its job back on the queue
+// without using up an attempt, for failures that say nothing about the
+// job itself, such as the provider refusing calls for its rate limit
+type DeferError struct {
+	Err   error
+	After time.Duration
+}
+
+// Defer wraps err so the job is tried again after the given delay without
+// counting as a failed attempt
+func Defer(err error, after time.Duration) error {
+	return &DeferError{Err: err, After: after}
+}
+
+func (e *DeferError) Error() string {
+	return fmt.Sprintf("deferred for %s: %v", e.After, e.Err)
+}
+
+func (e *DeferError) Unwrap() error {
+	return e.Err
+}
+
+// deferred returns the delay asked for if err defers its job
+func deferred(err error) (time.Duration, bool) {
+	var deferErr *DeferError
+	if !errors.As(err, &deferErr) {
+		return 0, false
+	}
+	return deferErr.After, true
+}
+
+// WithThrottle holds back jobs of the given types while throttle asks for
+// a delay, and returns the worker
+func (w *Worker) WithThrottle(throttle Throttle, jobTypes ...string) *Worker {
+	for _, jobType := range jobTypes {
+		w.throttles[jobType] = throttle
+	}
+	return w
+}
+
+// throttleWait returns how long to wait before handling a job of jobType,
+// at most pollTimeout, and whether the job should go back on the queue
+// because the delay is longer than that
+func (w *Worker) throttleWait(jobType string) (time.Duration, bool) {
+	throttle, ok := w.throttles[jobType]
+	if !ok {
+		return 0, false
+	}
+	delay := throttle.Delay()
+	if delay <= 0 {
+		return 0, false
+	}
+	return min(delay, pollTimeout), delay > pollTimeout
+}
@@ -0,0 +1,55 @@
+package queue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// fixedThrottle asks for the same delay every time
+type fixedThrottle time.Duration
+
+func (t fixedThrottle) Delay() time.Duration {
+	return time.Duration(t)
+}
+
+func TestWorker_ThrottleWait(t *testing.T) {
+	tests := []struct {
+		name        string
+		jobType     string
+		delay       time.Duration
+		wantWait    time.Duration
+		wantRequeue bool
+	}{
+		{"unthrottled type", "webhook", time.Minute, 0, false},
+		{"no delay", "analyzer", 0, 0, false},
+		{"short delay", "analyzer", 2 * time.Second, 2 * time.Second, false},
+		{"long delay", "analyzer", time.Minute, pollTimeout, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewWorker(nil, 1).WithThrottle(fixedThrottle(tt.delay), "analyzer", "topics")
+			wait, requeue := w.throttleWait(tt.jobType)
+			if wait != tt.wantWait || requeue != tt.wantRequeue {
+				t.Errorf("throttleWait(%q) = %v, %v, want %v, %v", tt.jobType, wait, requeue, tt.wantWait, tt.wantRequeue)
+			}
+		})
+	}
+}
+
+func TestDeferred(t *testing.T) {
+	cause := errors.New("rate limited")
+	err := fmt.Errorf("analysis failed: %w", Defer(cause, 30*time.Second))
+
+	if after, ok := deferred(err); !ok || after != 30*time.Second {
+		t.Errorf("deferred() = %v, %v, want 30s, true", after, ok)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Defer() doesn't wrap its cause")
+	}
+	if _, ok := deferred(cause); ok {
+		t.Error("deferred() = true for a plain error")
+	}
+}
@@ -0,0 +1,247 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+)
+
+const (
+	// pollTimeout is how long a paused worker waits before checking again
+	pollTimeout = 5 * time.Second
+
+	// idlePollInterval is how long a worker waits after finding every lane
+	// empty. Lanes can't be blocked on together, so idle workers poll.
+	idlePollInterval = 250 * time.Millisecond
+)
+
+// Handler processes a single job. Returning an error schedules a retry,
+// unless it is a DeferError, HoldError or FailError.
+type Handler func(ctx context.Context, job *Job) error
+
+// Worker consumes jobs from a queue and dispatches them to handlers
+type Worker struct {
+	queue       *Queue
+	concurrency int
+	burst       int
+	handlers    map[string]Handler
+	throttles   map[string]Throttle
+	reporter    errreport.Reporter
+}
+
+// NewWorker creates a worker that runs up to concurrency jobs at once
+func NewWorker(queue *Queue, concurrency int) *Worker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Worker{
+		queue:       queue,
+		concurrency: concurrency,
+		burst:       DefaultHighPriorityBurst,
+		handlers:    make(map[string]Handler),
+		throttles:   make(map[string]Throttle),
+		reporter:    errreport.Nop{},
+	}
+}
+
+// WithHighPriorityBurst sets how many high priority jobs each worker takes
+// in a row while default jobs wait, and returns the worker
+func (w *Worker) WithHighPriorityBurst(burst int) *Worker {
+	if burst < 1 {
+		burst = 1
+	}
+	w.burst = burst
+	return w
+}
+
+// WithReporter reports handlers' panics, with the job and stack trace,
+// and returns the worker
+func (w *Worker) WithReporter(reporter errreport.Reporter) *Worker {
+	w.reporter = reporter
+	return w
+}
+
+// Register associates a handler with a job type
+func (w *Worker) Register(jobType string, handler Handler) {
+	w.handlers[jobType] = handler
+}
+
+// Run processes jobs until ctx is canceled, then waits for in-flight jobs
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("Starting job worker", "concurrency", w.concurrency, "high_priority_burst", w.burst)
+
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+
+	wg.Wait()
+	slog.Info("Job worker stopped")
+}
+
+// loop repeatedly dequeues and handles jobs
+func (w *Worker) loop(ctx context.Context) {
+	lanes := &laneSelector{burst: w.burst}
+	waiting := false
+
+	for ctx.Err() == nil {
+		// Keep polling while paused; a failed check doesn't stop work
+		if paused, err := w.queue.Paused(ctx); err == nil && paused {
+			select {
+			case <-ctx.Done():
+			case <-time.After(pollTimeout):
+			}
+			continue
+		}
+
+		job, raw, err := w.queue.dequeue(ctx, lanes.order())
+		if errors.Is(err, cache.ErrUnavailable) {
+			// Pause until Redis is back rather than fail every poll
+			if !waiting {
+				slog.Warn("Job worker paused until Redis is available")
+				waiting = true
+			}
+			sleep(ctx, pollTimeout)
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.Error("Failed to dequeue job", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+		if waiting {
+			slog.Info("Job worker resumed")
+			waiting = false
+		}
+
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(idlePollInterval):
+			}
+			continue
+		}
+
+		// The queue may have been paused since the check above
+		if paused, err := w.queue.Paused(ctx); err == nil && paused {
+			if err := w.queue.release(context.Background(), raw, job); err != nil {
+				slog.Error("Failed to release job", "job_id", job.ID, "error", err)
+			}
+			continue
+		}
+
+		lanes.took(job.Priority)
+		w.handle(ctx, job, raw)
+	}
+}
+
+// handle runs the handler for a single job and records the outcome.
+// Jobs are finished with a background context so shutdown doesn't lose
+// their bookkeeping.
+func (w *Worker) handle(ctx context.Context, job *Job, raw string) {
+	logger := slog.With("job_id", job.ID, "job_type", job.Type, "priority", job.Priority, "attempt", job.Attempts+1)
+	start := time.Now()
+
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		logger.Error("No handler registered for job type")
+		if err := w.queue.retry(context.Background(), raw, job, fmt.Errorf("no handler for job type %q", job.Type)); err != nil {
+			logger.Error("Failed to reschedule job", "error", err)
+		}
+		return
+	}
+
+	// A throttled job waits here, or goes back to the front of its lane
+	// when the wait is longer than a poll
+	if wait, requeue := w.throttleWait(job.Type); wait > 0 {
+		logger.Debug("Throttling job", "wait", wait)
+		if !sleep(ctx, wait) || requeue {
+			if err := w.queue.release(context.Background(), raw, job); err != nil {
+				logger.Error("Failed to release job", "error", err)
+			}
+			return
+		}
+	}
+
+	if err := w.runHandler(ctx, handler, job); err != nil {
+		if after, ok := deferred(err); ok {
+			logger.Info("Job deferred", "error", err, "duration", time.Since(start))
+			if err := w.queue.release(context.Background(), raw, job); err != nil {
+				logger.Error("Failed to release job", "error", err)
+			}
+			sleep(ctx, min(after, pollTimeout))
+			return
+		}
+		if held(err) {
+			logger.Info("Job held", "error", err)
+			if err := w.queue.hold(context.Background(), raw, job); err != nil {
+				logger.Error("Failed to hold job", "error", err)
+			}
+			return
+		}
+
+		logger.Warn("Job failed", "error", err, "permanent", permanent(err), "duration", time.Since(start))
+		if err := w.queue.retry(context.Background(), raw, job, err); err != nil {
+			logger.Error("Failed to reschedule job", "error", err)
+		}
+		w.record(logger, "failed")
+		return
+	}
+
+	if err := w.queue.ack(context.Background(), raw); err != nil {
+		logger.Error("Failed to acknowledge job", "error", err)
+	}
+	w.record(logger, "completed")
+
+	logger.Info("Job completed", "duration", time.Since(start))
+}
+
+// record counts a finished job towards queue throughput
+func (w *Worker) record(logger *slog.Logger, outcome string) {
+	if err := w.queue.recordOutcome(context.Background(), outcome); err != nil {
+		logger.Debug("Failed to record job outcome", "error", err)
+	}
+}
+
+// sleep waits for d, returning false if ctx is done first
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// runHandler invokes a handler, converting panics into errors and
+// reporting them
+func (w *Worker) runHandler(ctx context.Context, handler Handler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+			w.reporter.Report(ctx, errreport.Event{
+				Level:   errreport.LevelFatal,
+				Type:    "panic",
+				Message: fmt.Sprint(r),
+				Stack:   errreport.PanicStack(),
+				Tags:    map[string]string{"job_type": job.Type, "job_id": job.ID, "priority": string(job.Priority)},
+			})
+		}
+	}()
+
+	return handler(ctx, job)
+}
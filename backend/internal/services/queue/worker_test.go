@@ -0,0 +1,63 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/errreport"
+)
+
+// recordingReporter records the events it is sent
+type recordingReporter struct {
+	events []errreport.Event
+}
+
+func (r *recordingReporter) Report(ctx context.Context, event errreport.Event) {
+	r.events = append(r.events, event)
+}
+
+func (r *recordingReporter) Close(ctx context.Context) error { return nil }
+
+func TestWorker_RunHandlerReportsPanics(t *testing.T) {
+	reporter := &recordingReporter{}
+	w := NewWorker(nil, 1).WithReporter(reporter)
+	job := &Job{ID: "job-1", Type: "analyzer", Priority: PriorityHigh}
+
+	err := w.runHandler(context.Background(), func(ctx context.Context, job *Job) error {
+		var scores map[string]float64
+		scores["toxicity"] = 1
+		return nil
+	}, job)
+
+	if err == nil || !strings.Contains(err.Error(), "job panicked") {
+		t.Fatalf("runHandler() error = %v, want the panic as an error", err)
+	}
+	if len(reporter.events) != 1 {
+		t.Fatalf("reported %d events, want 1", len(reporter.events))
+	}
+	event := reporter.events[0]
+	if event.Type != "panic" || event.Tags["job_type"] != "analyzer" || event.Tags["job_id"] != "job-1" {
+		t.Errorf("event = %+v, want the panic tagged with the job", event)
+	}
+	// A runtime panic starts in the runtime; the first frame of ours is
+	// the handler
+	var first errreport.Frame
+	for _, frame := range event.Stack {
+		if frame.InApp() {
+			first = frame
+			break
+		}
+	}
+	if !strings.Contains(first.Function, "TestWorker_RunHandlerReportsPanics") {
+		t.Errorf("first in-app frame = %+v, want the handler", first)
+	}
+
+	// Handlers that return normally aren't reported
+	if err := w.runHandler(context.Background(), func(ctx context.Context, job *Job) error { return nil }, job); err != nil {
+		t.Fatal(err)
+	}
+	if len(reporter.events) != 1 {
+		t.Errorf("reported %d events, want only the panic", len(reporter.events))
+	}
+}
@@ -0,0 +1,204 @@
+package readability
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+var (
+	// sentenceEnd matches terminal punctuation followed by whitespace or the end of text
+	sentenceEnd = regexp.MustCompile(`[.!?]+(?:["')\]]*)(?:\s+|$)`)
+
+	// vowelGroup matches runs of vowels, each roughly one syllable
+	vowelGroup = regexp.MustCompile(`[aeiouy]+`)
+)
+
+// beVerbs are the auxiliaries that introduce a passive construction
+var beVerbs = map[string]bool{
+	"am": true, "is": true, "are": true, "was": true, "were": true,
+	"be": true, "been": true, "being": true,
+	"isn't": true, "aren't": true, "wasn't": true, "weren't": true,
+}
+
+// irregularParticiples are common past participles not ending in -ed
+var irregularParticiples = map[string]bool{
+	"awoken": true, "been": true, "beaten": true, "become": true, "begun": true,
+	"bent": true, "bitten": true, "blown": true, "broken": true, "brought": true,
+	"built": true, "bought": true, "caught": true, "chosen": true, "done": true,
+	"drawn": true, "driven": true, "eaten": true, "fallen": true, "felt": true,
+	"fought": true, "found": true, "forgotten": true, "forgiven": true, "frozen": true,
+	"given": true, "gone": true, "grown": true, "heard": true, "held": true,
+	"hidden": true, "hit": true, "hurt": true, "kept": true, "known": true,
+	"laid": true, "led": true, "left": true, "lent": true, "lost": true,
+	"made": true, "meant": true, "met": true, "paid": true, "put": true,
+	"read": true, "ridden": true, "run": true, "said": true, "seen": true,
+	"sent": true, "set": true, "shaken": true, "shown": true, "shut": true,
+	"sold": true, "spent": true, "spoken": true, "stolen": true, "struck": true,
+	"sung": true, "taken": true, "taught": true, "thought": true, "thrown": true,
+	"told": true, "torn": true, "understood": true, "won": true, "worn": true,
+	"written": true,
+}
+
+// Compute returns deterministic readability and writing-quality metrics for
+// English text. Scores are rounded to two decimals so repeated runs over
+// the same text always produce identical results.
+func Compute(text string) models.ReadabilityMetrics {
+	sentences := splitSentences(text)
+
+	var m models.ReadabilityMetrics
+	unique := make(map[string]bool)
+	passive := 0
+
+	for _, sentence := range sentences {
+		words := splitWords(sentence)
+		if len(words) == 0 {
+			continue
+		}
+
+		m.Sentences++
+		if isPassive(words) {
+			passive++
+		}
+
+		for _, w := range words {
+			m.Words++
+			unique[w] = true
+
+			syllables := countSyllables(w)
+			m.Syllables += syllables
+			if syllables >= 3 {
+				m.Polysyllables++
+			}
+		}
+	}
+
+	if m.Words == 0 {
+		return m
+	}
+
+	words := float64(m.Words)
+	sentenceCount := float64(m.Sentences)
+	syllables := float64(m.Syllables)
+
+	m.AverageSentenceLength = round(words / sentenceCount)
+	m.FleschReadingEase = round(206.835 - 1.015*(words/sentenceCount) - 84.6*(syllables/words))
+	m.FleschKincaidGrade = round(0.39*(words/sentenceCount) + 11.8*(syllables/words) - 15.59)
+	m.SMOGGrade = round(1.0430*math.Sqrt(float64(m.Polysyllables)*30/sentenceCount) + 3.1291)
+	m.PassiveVoiceRatio = round(float64(passive) / sentenceCount)
+	m.LexicalDiversity = round(float64(len(unique)) / words)
+
+	return m
+}
+
+// SentenceLengths returns the number of words in each sentence of text,
+// skipping sentences without words
+func SentenceLengths(text string) []int {
+	var lengths []int
+	for _, sentence := range splitSentences(text) {
+		if n := len(splitWords(sentence)); n > 0 {
+			lengths = append(lengths, n)
+		}
+	}
+	return lengths
+}
+
+// splitSentences breaks text on terminal punctuation
+func splitSentences(text string) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+
+	var sentences []string
+	start := 0
+	for _, loc := range sentenceEnd.FindAllStringIndex(text, -1) {
+		sentences = append(sentences, text[start:loc[1]])
+		start = loc[1]
+	}
+	if start < len(text) {
+		sentences = append(sentences, text[start:])
+	}
+
+	return sentences
+}
+
+// splitWords returns the lowercased words of a sentence. Apostrophes inside
+// words are kept so contractions count once.
+func splitWords(sentence string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(sentence), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\'' && r != '’'
+	})
+
+	words := fields[:0]
+	for _, f := range fields {
+		f = strings.Trim(strings.ReplaceAll(f, "’", "'"), "'")
+		if f != "" {
+			words = append(words, f)
+		}
+	}
+	return words
+}
+
+// countSyllables estimates the syllables in a lowercased word
+func countSyllables(word string) int {
+	if len(word) <= 3 {
+		return 1
+	}
+
+	// Drop silent endings: "make", "loved", "plays" but not "table"
+	trimmed := word
+	switch {
+	case strings.HasSuffix(trimmed, "es") || strings.HasSuffix(trimmed, "ed"):
+		if !strings.HasSuffix(trimmed, "ted") && !strings.HasSuffix(trimmed, "ded") {
+			trimmed = trimmed[:len(trimmed)-2]
+		}
+	case strings.HasSuffix(trimmed, "e") && !strings.HasSuffix(trimmed, "le"):
+		trimmed = trimmed[:len(trimmed)-1]
+	}
+	trimmed = strings.TrimPrefix(trimmed, "y")
+
+	count := len(vowelGroup.FindAllString(trimmed, -1))
+	if count == 0 {
+		return 1
+	}
+	return count
+}
+
+// isPassive reports whether a sentence contains a form of "to be" followed,
+// optionally after an adverb, by a past participle
+func isPassive(words []string) bool {
+	for i, w := range words {
+		if !beVerbs[w] {
+			continue
+		}
+
+		for j := i + 1; j < len(words) && j <= i+2; j++ {
+			next := words[j]
+			if isParticiple(next) {
+				return true
+			}
+			// Allow a single intervening adverb ("was quickly written")
+			if !strings.HasSuffix(next, "ly") && next != "not" {
+				break
+			}
+		}
+	}
+	return false
+}
+
+// isParticiple reports whether a word looks like a past participle
+func isParticiple(word string) bool {
+	if irregularParticiples[word] {
+		return true
+	}
+	return len(word) > 4 && strings.HasSuffix(word, "ed")
+}
+
+// round rounds to two decimal places
+func round(f float64) float64 {
+	return math.Round(f*100) / 100
+}
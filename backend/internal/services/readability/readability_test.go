@@ -0,0 +1,157 @@
+package readability
+
+import (
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestCountSyllables(t *testing.T) {
+	tests := []struct {
+		word string
+		want int
+	}{
+		{"the", 1},
+		{"make", 1},
+		{"loved", 1},
+		{"plays", 1},
+		{"table", 2},
+		{"wanted", 2},
+		{"yellow", 2},
+		{"beautiful", 3},
+		{"analysis", 4},
+		{"readability", 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := countSyllables(tt.word); got != tt.want {
+				t.Errorf("countSyllables(%q) = %d, want %d", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	got := splitSentences(`Hello there. "Quoted!" she said... Done`)
+	want := []string{"Hello there. ", `"Quoted!" `, "she said... ", "Done"}
+
+	if len(got) != len(want) {
+		t.Fatalf("splitSentences() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitSentences()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSentenceLengths(t *testing.T) {
+	got := SentenceLengths("Hello there. ... It's a fine day, isn't it?")
+	want := []int{2, 6}
+
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("SentenceLengths() = %v, want %v", got, want)
+	}
+}
+
+func TestIsPassive(t *testing.T) {
+	tests := []struct {
+		name     string
+		sentence string
+		want     bool
+	}{
+		{"regular participle", "The form was submitted yesterday", true},
+		{"irregular participle", "The report was written by the committee", true},
+		{"intervening adverb", "It was quickly taken away", true},
+		{"negated", "The bug is not fixed", true},
+		{"active voice", "We approved the budget", false},
+		{"be without participle", "The sky is blue", false},
+		{"short ed word", "It was red", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPassive(splitWords(tt.sentence)); got != tt.want {
+				t.Errorf("isPassive(%q) = %v, want %v", tt.sentence, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want models.ReadabilityMetrics
+	}{
+		{
+			name: "empty",
+			text: "   ",
+			want: models.ReadabilityMetrics{},
+		},
+		{
+			name: "simple sentences",
+			text: "The cat sat on the mat. The dog ran.",
+			want: models.ReadabilityMetrics{
+				Words:                 9,
+				Sentences:             2,
+				Syllables:             9,
+				FleschReadingEase:     117.67,
+				FleschKincaidGrade:    -2.03,
+				SMOGGrade:             3.13,
+				AverageSentenceLength: 4.5,
+				LexicalDiversity:      0.78,
+			},
+		},
+		{
+			name: "passive sentence",
+			text: "The report was written by the committee. We approved it quickly!",
+			want: models.ReadabilityMetrics{
+				Words:                 11,
+				Sentences:             2,
+				Syllables:             17,
+				Polysyllables:         1,
+				FleschReadingEase:     70.51,
+				FleschKincaidGrade:    4.79,
+				SMOGGrade:             7.17,
+				AverageSentenceLength: 5.5,
+				PassiveVoiceRatio:     0.5,
+				LexicalDiversity:      0.91,
+			},
+		},
+		{
+			name: "no terminal punctuation",
+			text: "just one fragment",
+			want: models.ReadabilityMetrics{
+				Words:                 3,
+				Sentences:             1,
+				Syllables:             4,
+				FleschReadingEase:     90.99,
+				FleschKincaidGrade:    1.31,
+				SMOGGrade:             3.13,
+				AverageSentenceLength: 3,
+				LexicalDiversity:      1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compute(tt.text); got != tt.want {
+				t.Errorf("Compute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute_Deterministic(t *testing.T) {
+	text := "Results must be reproducible. Running the same text twice is expected to give identical scores."
+
+	first := Compute(text)
+	for i := 0; i < 10; i++ {
+		if got := Compute(text); got != first {
+			t.Fatalf("Compute() run %d = %+v, want %+v", i, got, first)
+		}
+	}
+}
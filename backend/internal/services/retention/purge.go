@@ -0,0 +1,110 @@
+// Package retention enforces organizations' data retention policies by
+// purging expired submissions in the background, and empties users'
+// trash of submissions deleted longer ago than the trash retention.
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the retention purge job in the queue
+	JobType = "retention.purge"
+
+	// TrashJobType identifies the trash purge job in the queue
+	TrashJobType = "retention.trash"
+
+	// BatchSize is how many submissions one purge statement destroys, so
+	// a large backlog doesn't hold locks for long
+	BatchSize = 500
+
+	// maxBatches stops a run after 100,000 submissions, so the first purge
+	// under a newly shortened policy is spread over several runs rather
+	// than keeping a worker busy for hours
+	maxBatches = 200
+)
+
+// Purger destroys expired submissions; *models.RetentionStore implements it
+type Purger interface {
+	Purge(ctx context.Context, limit int) (int, error)
+}
+
+// PurgeJob destroys submissions past their retention period
+type PurgeJob struct {
+	store Purger
+}
+
+// NewPurgeJob creates a new retention purge job
+func NewPurgeJob(store Purger) *PurgeJob {
+	return &PurgeJob{store: store}
+}
+
+// Handle implements queue.Handler for the retention purge job. It purges
+// in batches until nothing expired is left.
+func (j *PurgeJob) Handle(ctx context.Context, job *queue.Job) error {
+	total, err := purgeAll(func(limit int) (int, error) {
+		return j.store.Purge(ctx, limit)
+	})
+	if err != nil {
+		slog.Error("Retention purge stopped", "purged", total, "error", err)
+		return err
+	}
+
+	slog.Info("Expired submissions purged", "count", total)
+	return nil
+}
+
+// TrashPurger destroys submissions that were deleted before a time;
+// *models.SubmissionStore implements it
+type TrashPurger interface {
+	PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error)
+}
+
+// TrashJob destroys submissions that have been in the trash longer than
+// the retention
+type TrashJob struct {
+	store     TrashPurger
+	retention time.Duration
+}
+
+// NewTrashJob creates a new trash purge job
+func NewTrashJob(store TrashPurger, retention time.Duration) *TrashJob {
+	return &TrashJob{store: store, retention: retention}
+}
+
+// Handle implements queue.Handler for the trash purge job. It purges in
+// batches until nothing in the trash is older than the retention.
+func (j *TrashJob) Handle(ctx context.Context, job *queue.Job) error {
+	deletedBefore := time.Now().Add(-j.retention)
+	total, err := purgeAll(func(limit int) (int, error) {
+		return j.store.PurgeTrash(ctx, deletedBefore, limit)
+	})
+	if err != nil {
+		slog.Error("Trash purge stopped", "purged", total, "error", err)
+		return err
+	}
+
+	slog.Info("Trash purged", "count", total)
+	return nil
+}
+
+// purgeAll calls purge with BatchSize until it purges less than a full
+// batch, and returns how many it purged in all
+func purgeAll(purge func(limit int) (int, error)) (int, error) {
+	total := 0
+	for range maxBatches {
+		purged, err := purge(BatchSize)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+		if purged < BatchSize {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,87 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakePurger purges from a fixed backlog and can fail after some batches
+type fakePurger struct {
+	backlog   int
+	failAfter int
+	calls     int
+}
+
+func (p *fakePurger) Purge(ctx context.Context, limit int) (int, error) {
+	p.calls++
+	if p.failAfter > 0 && p.calls > p.failAfter {
+		return 0, errors.New("connection reset")
+	}
+	n := min(limit, p.backlog)
+	p.backlog -= n
+	return n, nil
+}
+
+func TestPurgeJob_Handle(t *testing.T) {
+	tests := []struct {
+		name      string
+		backlog   int
+		failAfter int
+		wantCalls int
+		wantLeft  int
+		wantErr   bool
+	}{
+		{name: "nothing expired", backlog: 0, wantCalls: 1},
+		{name: "partial batch", backlog: BatchSize - 1, wantCalls: 1},
+		{name: "several batches", backlog: 2*BatchSize + 3, wantCalls: 3},
+		{name: "exact batches", backlog: 2 * BatchSize, wantCalls: 3},
+		{name: "failure", backlog: 3 * BatchSize, failAfter: 1, wantCalls: 2, wantLeft: 2 * BatchSize, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakePurger{backlog: tt.backlog, failAfter: tt.failAfter}
+
+			err := NewPurgeJob(store).Handle(context.Background(), &queue.Job{Type: JobType})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if store.calls != tt.wantCalls || store.backlog != tt.wantLeft {
+				t.Errorf("Handle() made %d calls leaving %d, want %d calls leaving %d", store.calls, store.backlog, tt.wantCalls, tt.wantLeft)
+			}
+		})
+	}
+}
+
+// fakeTrash purges a fixed backlog, recording the cutoff it was given
+type fakeTrash struct {
+	backlog       int
+	deletedBefore time.Time
+}
+
+func (t *fakeTrash) PurgeTrash(ctx context.Context, deletedBefore time.Time, limit int) (int, error) {
+	t.deletedBefore = deletedBefore
+	n := min(limit, t.backlog)
+	t.backlog -= n
+	return n, nil
+}
+
+func TestTrashJob_Handle(t *testing.T) {
+	store := &fakeTrash{backlog: BatchSize + 1}
+	retention := 30 * 24 * time.Hour
+
+	before := time.Now()
+	if err := NewTrashJob(store, retention).Handle(context.Background(), &queue.Job{Type: TrashJobType}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if store.backlog != 0 {
+		t.Errorf("Handle() left %d submissions, want 0", store.backlog)
+	}
+	if cutoff := before.Add(-retention); store.deletedBefore.Before(cutoff) || store.deletedBefore.After(time.Now().Add(-retention)) {
+		t.Errorf("Handle() purged submissions deleted before %v, want %v", store.deletedBefore, cutoff)
+	}
+}
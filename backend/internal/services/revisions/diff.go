@@ -0,0 +1,151 @@
+// Package revisions compares the analyses of consecutive revisions of a
+// document.
+package revisions
+
+import (
+	"math"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Diff describes how a revision differs from the one it replaced
+type Diff struct {
+	SubmissionID uuid.UUID  `json:"submission_id"`
+	PreviousID   uuid.UUID  `json:"previous_id"`
+	Tone         ToneChange `json:"tone"`
+
+	// Claims come from the model comparison run with the revision's
+	// analysis. ClaimsCompared is false when it didn't run, in which case
+	// none are listed.
+	ClaimsCompared bool     `json:"claims_compared"`
+	ClaimsAdded    []string `json:"claims_added"`
+	ClaimsRemoved  []string `json:"claims_removed"`
+
+	TopicsAdded       []string `json:"topics_added"`
+	TopicsRemoved     []string `json:"topics_removed"`
+	KeyphrasesAdded   []string `json:"keyphrases_added"`
+	KeyphrasesRemoved []string `json:"keyphrases_removed"`
+
+	// Readability is nil unless both analyses computed readability metrics
+	Readability *ReadabilityDelta `json:"readability"`
+}
+
+// ToneChange compares the sentiment of two revisions
+type ToneChange struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	FromScore *float64 `json:"from_score"`
+	ToScore   *float64 `json:"to_score"`
+	// ScoreDelta is ToScore - FromScore, or nil unless both are set
+	ScoreDelta *float64 `json:"score_delta"`
+	// Description is the model's account of the shift, when it compared them
+	Description string `json:"description,omitempty"`
+}
+
+// ReadabilityDelta is the change in each readability metric, revised
+// minus previous. A negative grade means the revision is easier to read.
+type ReadabilityDelta struct {
+	Words                 int     `json:"words"`
+	Sentences             int     `json:"sentences"`
+	FleschReadingEase     float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade    float64 `json:"flesch_kincaid_grade"`
+	SMOGGrade             float64 `json:"smog_grade"`
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	PassiveVoiceRatio     float64 `json:"passive_voice_ratio"`
+	LexicalDiversity      float64 `json:"lexical_diversity"`
+}
+
+// Compare diffs the analysis of a revision against that of the revision
+// it replaced
+func Compare(previous, revised *models.Analysis) Diff {
+	diff := Diff{
+		SubmissionID: revised.SubmissionID,
+		PreviousID:   previous.SubmissionID,
+		Tone: ToneChange{
+			From:      previous.Sentiment,
+			To:        revised.Sentiment,
+			FromScore: previous.SentimentScore,
+			ToScore:   revised.SentimentScore,
+		},
+		ClaimsAdded:   []string{},
+		ClaimsRemoved: []string{},
+	}
+
+	if previous.SentimentScore != nil && revised.SentimentScore != nil {
+		delta := round(*revised.SentimentScore - *previous.SentimentScore)
+		diff.Tone.ScoreDelta = &delta
+	}
+
+	if changes := revised.Changes; changes != nil {
+		diff.ClaimsCompared = true
+		diff.Tone.Description = changes.Tone
+		if changes.ClaimsAdded != nil {
+			diff.ClaimsAdded = changes.ClaimsAdded
+		}
+		if changes.ClaimsRemoved != nil {
+			diff.ClaimsRemoved = changes.ClaimsRemoved
+		}
+	}
+
+	diff.TopicsAdded, diff.TopicsRemoved = setDiff(previous.Topics, revised.Topics)
+	diff.KeyphrasesAdded, diff.KeyphrasesRemoved = setDiff(phrases(previous.Keyphrases), phrases(revised.Keyphrases))
+
+	if previous.Readability != nil && revised.Readability != nil {
+		diff.Readability = readabilityDelta(*previous.Readability, *revised.Readability)
+	}
+
+	return diff
+}
+
+// readabilityDelta subtracts the previous metrics from the revised ones
+func readabilityDelta(previous, revised models.ReadabilityMetrics) *ReadabilityDelta {
+	return &ReadabilityDelta{
+		Words:                 revised.Words - previous.Words,
+		Sentences:             revised.Sentences - previous.Sentences,
+		FleschReadingEase:     round(revised.FleschReadingEase - previous.FleschReadingEase),
+		FleschKincaidGrade:    round(revised.FleschKincaidGrade - previous.FleschKincaidGrade),
+		SMOGGrade:             round(revised.SMOGGrade - previous.SMOGGrade),
+		AverageSentenceLength: round(revised.AverageSentenceLength - previous.AverageSentenceLength),
+		PassiveVoiceRatio:     round(revised.PassiveVoiceRatio - previous.PassiveVoiceRatio),
+		LexicalDiversity:      round(revised.LexicalDiversity - previous.LexicalDiversity),
+	}
+}
+
+// setDiff returns the values only in revised and those only in previous,
+// ignoring case, each in its original order
+func setDiff(previous, revised []string) (added, removed []string) {
+	return missingFrom(revised, previous), missingFrom(previous, revised)
+}
+
+// missingFrom returns the values of from that other doesn't contain
+func missingFrom(from, other []string) []string {
+	seen := make(map[string]bool, len(other))
+	for _, v := range other {
+		seen[strings.ToLower(v)] = true
+	}
+
+	missing := []string{}
+	for _, v := range from {
+		if !seen[strings.ToLower(v)] {
+			missing = append(missing, v)
+		}
+	}
+	return missing
+}
+
+// phrases returns the text of each keyphrase
+func phrases(keyphrases []models.Keyphrase) []string {
+	out := make([]string, 0, len(keyphrases))
+	for _, k := range keyphrases {
+		out = append(out, k.Phrase)
+	}
+	return out
+}
+
+// round keeps two decimal places
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,78 @@
+package revisions
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func score(v float64) *float64 {
+	return &v
+}
+
+func TestCompare(t *testing.T) {
+	previous := &models.Analysis{
+		SubmissionID:   uuid.New(),
+		Sentiment:      "positive",
+		SentimentScore: score(0.6),
+		Topics:         []string{"Sales", "Hiring"},
+		Keyphrases:     []models.Keyphrase{{Phrase: "record quarter"}, {Phrase: "new office"}},
+		Readability:    &models.ReadabilityMetrics{Words: 120, Sentences: 6, FleschKincaidGrade: 9.4, PassiveVoiceRatio: 0.2},
+	}
+	revised := &models.Analysis{
+		SubmissionID:   uuid.New(),
+		Sentiment:      "neutral",
+		SentimentScore: score(0.1),
+		Topics:         []string{"sales", "Layoffs"},
+		Keyphrases:     []models.Keyphrase{{Phrase: "Record quarter"}, {Phrase: "cost cuts"}},
+		Readability:    &models.ReadabilityMetrics{Words: 100, Sentences: 7, FleschKincaidGrade: 7.1, PassiveVoiceRatio: 0.1},
+		Changes: &models.RevisionChanges{
+			Tone:          "More guarded.",
+			ClaimsAdded:   []string{"Ten staff were laid off."},
+			ClaimsRemoved: []string{"A new office opens in May."},
+		},
+	}
+
+	diff := Compare(previous, revised)
+
+	if diff.SubmissionID != revised.SubmissionID || diff.PreviousID != previous.SubmissionID {
+		t.Errorf("IDs = %s, %s", diff.SubmissionID, diff.PreviousID)
+	}
+	if diff.Tone.From != "positive" || diff.Tone.To != "neutral" || diff.Tone.ScoreDelta == nil || *diff.Tone.ScoreDelta != -0.5 {
+		t.Errorf("Tone = %+v", diff.Tone)
+	}
+	if diff.Tone.Description != "More guarded." || !diff.ClaimsCompared || len(diff.ClaimsAdded) != 1 || len(diff.ClaimsRemoved) != 1 {
+		t.Errorf("claims = %+v", diff)
+	}
+	if !reflect.DeepEqual(diff.TopicsAdded, []string{"Layoffs"}) || !reflect.DeepEqual(diff.TopicsRemoved, []string{"Hiring"}) {
+		t.Errorf("topics added %v, removed %v", diff.TopicsAdded, diff.TopicsRemoved)
+	}
+	if !reflect.DeepEqual(diff.KeyphrasesAdded, []string{"cost cuts"}) || !reflect.DeepEqual(diff.KeyphrasesRemoved, []string{"new office"}) {
+		t.Errorf("keyphrases added %v, removed %v", diff.KeyphrasesAdded, diff.KeyphrasesRemoved)
+	}
+
+	want := &ReadabilityDelta{Words: -20, Sentences: 1, FleschKincaidGrade: -2.3, PassiveVoiceRatio: -0.1}
+	if !reflect.DeepEqual(diff.Readability, want) {
+		t.Errorf("Readability = %+v, want %+v", diff.Readability, want)
+	}
+}
+
+func TestCompare_WithoutComparison(t *testing.T) {
+	previous := &models.Analysis{Sentiment: "neutral", Readability: &models.ReadabilityMetrics{Words: 10}}
+	revised := &models.Analysis{Sentiment: "negative", SentimentScore: score(-0.4)}
+
+	diff := Compare(previous, revised)
+
+	if diff.ClaimsCompared || diff.ClaimsAdded == nil || len(diff.ClaimsAdded) != 0 || diff.Tone.Description != "" {
+		t.Errorf("claims = %+v, want none listed", diff)
+	}
+	if diff.Tone.ScoreDelta != nil {
+		t.Errorf("ScoreDelta = %v, want nil without both scores", *diff.Tone.ScoreDelta)
+	}
+	if diff.Readability != nil {
+		t.Errorf("Readability = %+v, want nil without both metrics", diff.Readability)
+	}
+}
@@ -0,0 +1,172 @@
+// Package sensitive detects personal data (emails, phone numbers, SSNs and
+// credit card numbers) and profanity in text, and produces masked copies
+// for display.
+package sensitive
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// detector finds candidates of one type. valid, when set, rejects matches
+// the pattern alone can't rule out.
+type detector struct {
+	kind    models.FindingType
+	pattern *regexp.Regexp
+	valid   func(match string) bool
+}
+
+// detectors run in priority order; a later match overlapping an earlier one
+// is dropped, so a card number isn't also reported as a phone number
+var detectors = []detector{
+	{
+		kind:    models.FindingEmail,
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	{
+		kind:    models.FindingCreditCard,
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:   luhn,
+	},
+	{
+		kind:    models.FindingSSN,
+		pattern: regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		valid:   validSSN,
+	},
+	{
+		kind:    models.FindingPhone,
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`),
+	},
+	{
+		kind: models.FindingProfanity,
+		pattern: regexp.MustCompile(`(?i)\b(?:` +
+			`(?:mother)?fuck\w*|bullshit\w*|shit(?:s|ty|ting|head|heads)?|` +
+			`bitch(?:es|y)?|bastards?|assholes?|dickheads?|cunts?|` +
+			`wankers?|twats?|piss(?:ed)?|damn(?:ed|it)?` +
+			`)\b`),
+	},
+}
+
+// Detect returns the sensitive spans of text ordered by position. Offsets
+// are in characters (runes), not bytes.
+func Detect(text string) []models.Finding {
+	type span struct {
+		kind       models.FindingType
+		start, end int // byte offsets
+	}
+
+	var spans []span
+	overlaps := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, d := range detectors {
+		for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+			if d.valid != nil && !d.valid(text[loc[0]:loc[1]]) {
+				continue
+			}
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			spans = append(spans, span{kind: d.kind, start: loc[0], end: loc[1]})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	findings := make([]models.Finding, 0, len(spans))
+	for _, s := range spans {
+		findings = append(findings, models.Finding{
+			Type:  s.kind,
+			Start: utf8.RuneCountInString(text[:s.start]),
+			End:   utf8.RuneCountInString(text[:s.end]),
+		})
+	}
+
+	return findings
+}
+
+// Excerpt returns the text covered by a finding
+func Excerpt(text string, f models.Finding) string {
+	runes := []rune(text)
+	if f.Start < 0 || f.End > len(runes) || f.Start > f.End {
+		return ""
+	}
+	return string(runes[f.Start:f.End])
+}
+
+// Redact returns a copy of text with each finding masked. Personal data is
+// replaced by a label such as [EMAIL]; profanity keeps its first letter.
+func Redact(text string, findings []models.Finding) string {
+	if len(findings) == 0 {
+		return text
+	}
+
+	sorted := append([]models.Finding(nil), findings...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+
+	runes := []rune(text)
+	var b strings.Builder
+	pos := 0
+
+	for _, f := range sorted {
+		if f.Start < pos || f.End > len(runes) || f.Start > f.End {
+			continue
+		}
+
+		b.WriteString(string(runes[pos:f.Start]))
+		if f.Type == models.FindingProfanity {
+			b.WriteRune(runes[f.Start])
+			b.WriteString(strings.Repeat("*", f.End-f.Start-1))
+		} else {
+			b.WriteString("[" + strings.ToUpper(string(f.Type)) + "]")
+		}
+		pos = f.End
+	}
+	b.WriteString(string(runes[pos:]))
+
+	return b.String()
+}
+
+// luhn reports whether a card number passes the Luhn checksum
+func luhn(number string) bool {
+	sum, digits := 0, 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// validSSN rejects numbers the SSA never issues
+func validSSN(ssn string) bool {
+	area, group, serial := ssn[0:3], ssn[4:6], ssn[7:11]
+
+	return area != "000" && area != "666" && area[0] != '9' &&
+		group != "00" && serial != "0000"
+}
@@ -0,0 +1,145 @@
+package sensitive
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []models.Finding
+	}{
+		{
+			name: "email",
+			text: "Contact jane.doe+news@mail.example.co.uk today",
+			want: []models.Finding{{Type: models.FindingEmail, Start: 8, End: 40}},
+		},
+		{
+			name: "phone formats",
+			text: "Call (555) 123-4567 or +1 555.123.4567",
+			want: []models.Finding{
+				{Type: models.FindingPhone, Start: 5, End: 19},
+				{Type: models.FindingPhone, Start: 23, End: 38},
+			},
+		},
+		{
+			name: "ssn",
+			text: "SSN 123-45-6789",
+			want: []models.Finding{{Type: models.FindingSSN, Start: 4, End: 15}},
+		},
+		{
+			name: "invalid ssn ignored",
+			text: "Ref 000-12-3456 and 666-12-3456",
+			want: []models.Finding{},
+		},
+		{
+			name: "credit card passes luhn",
+			text: "Card 4111 1111 1111 1111 expires soon",
+			want: []models.Finding{{Type: models.FindingCreditCard, Start: 5, End: 24}},
+		},
+		{
+			name: "credit card failing luhn is not a card",
+			text: "Order 4111111111111112",
+			want: []models.Finding{},
+		},
+		{
+			name: "profanity with word boundaries",
+			text: "This is bullshit, unlike Scunthorpe or a classic",
+			want: []models.Finding{{Type: models.FindingProfanity, Start: 8, End: 16}},
+		},
+		{
+			name: "offsets count characters not bytes",
+			text: "Café owner: bob@example.com",
+			want: []models.Finding{{Type: models.FindingEmail, Start: 12, End: 27}},
+		},
+		{
+			name: "nothing sensitive",
+			text: "A perfectly ordinary sentence.",
+			want: []models.Finding{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Detect(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "mixed findings",
+			text: "Mail bob@example.com or call 555-123-4567, damn it",
+			want: "Mail [EMAIL] or call [PHONE], d*** it",
+		},
+		{
+			name: "multibyte text",
+			text: "Café card 4111-1111-1111-1111 ✓",
+			want: "Café card [CREDIT_CARD] ✓",
+		},
+		{
+			name: "nothing to redact",
+			text: "Nothing to see here.",
+			want: "Nothing to see here.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Redact(tt.text, Detect(tt.text)); got != tt.want {
+				t.Errorf("Redact() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedact_IgnoresInvalidOffsets(t *testing.T) {
+	findings := []models.Finding{
+		{Type: models.FindingEmail, Start: 0, End: 100},
+		{Type: models.FindingPhone, Start: 3, End: 1},
+	}
+
+	if got := Redact("short", findings); got != "short" {
+		t.Errorf("Redact() = %q, want the text unchanged", got)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	text := "Café owner: bob@example.com"
+	f := models.Finding{Type: models.FindingEmail, Start: 12, End: 27}
+
+	if got := Excerpt(text, f); got != "bob@example.com" {
+		t.Errorf("Excerpt() = %q, want %q", got, "bob@example.com")
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{"4111111111111111", true},
+		{"5500 0000 0000 0004", true},
+		{"4111111111111112", false},
+		{"1234567", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			if got := luhn(tt.number); got != tt.want {
+				t.Errorf("luhn(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
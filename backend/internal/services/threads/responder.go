@@ -0,0 +1,195 @@
+// Package threads answers follow-up questions about a submission's
+// analysis, such as "make the summary shorter" or "list the risks", in
+// conversation threads.
+//
+// Replies are generated in the background: posting a message enqueues a
+// reply job and the Responder stores the model's answer in the thread.
+package threads
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// JobType identifies the thread reply job in the queue
+const JobType = "thread.reply"
+
+const systemInstruction = `You help a user refine the automated analysis of a text they submitted. Answer their follow-up requests, such as rewriting the summary or listing risks, using only the text and analysis below. Reply in plain text and keep it brief. If a request is unrelated to the text, say that you can only discuss this submission.`
+
+// Payload is the job payload for a thread reply
+type Payload struct {
+	ThreadID uuid.UUID `json:"thread_id"`
+}
+
+// ThreadSource reads threads and stores replies; *models.ThreadStore
+// implements it
+type ThreadSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Thread, error)
+	AddReply(ctx context.Context, threadID uuid.UUID, reply *models.ThreadMessage) error
+	MarkFailed(ctx context.Context, threadID uuid.UUID) error
+}
+
+// SubmissionSource reads the submission a thread discusses;
+// *models.SubmissionStore implements it
+type SubmissionSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Submission, error)
+	GetAnalysis(ctx context.Context, userID, submissionID uuid.UUID) (*models.Analysis, error)
+}
+
+// Responder generates the model's replies in threads
+type Responder struct {
+	threads     ThreadSource
+	submissions SubmissionSource
+	client      *ai.Client
+	pricing     ai.Pricing
+}
+
+// NewResponder creates a new thread responder
+func NewResponder(threads ThreadSource, submissions SubmissionSource, client *ai.Client) *Responder {
+	return &Responder{
+		threads:     threads,
+		submissions: submissions,
+		client:      client,
+	}
+}
+
+// WithPricing records the cost of each reply at the given model prices and
+// returns the responder
+func (r *Responder) WithPricing(pricing ai.Pricing) *Responder {
+	r.pricing = pricing
+	return r
+}
+
+// Handle implements queue.Handler for the thread reply job
+func (r *Responder) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid thread reply payload: %w", err)
+	}
+
+	thread, err := r.threads.Get(ctx, payload.ThreadID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Deleted before we got to it
+			return nil
+		}
+		return fmt.Errorf("failed to load thread: %w", err)
+	}
+
+	// An earlier attempt may have answered already
+	if !models.Awaiting(thread.Messages) {
+		return nil
+	}
+
+	if err := r.reply(ctx, thread); err != nil {
+		// Only give up on the message once the queue stops retrying
+		if job.Attempts+1 >= job.MaxAttempts {
+			if err := r.threads.MarkFailed(context.Background(), thread.ID); err != nil {
+				slog.Error("Failed to mark thread message failed", "thread_id", thread.ID, "error", err)
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// reply asks the model to answer the thread's last message and stores the
+// answer
+func (r *Responder) reply(ctx context.Context, thread *models.Thread) error {
+	submission, err := r.submissions.Get(ctx, thread.SubmissionID)
+	if err != nil {
+		return fmt.Errorf("failed to load submission: %w", err)
+	}
+
+	analysis, err := r.submissions.GetAnalysis(ctx, thread.UserID, thread.SubmissionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to load analysis: %w", err)
+	}
+
+	system := buildSystemInstruction(submission.Content, analysis)
+	window := Window(thread.Messages, max(ContextBudget-EstimateTokens(system), minHistoryTokens))
+
+	history := make([]ai.Turn, 0, len(window)-1)
+	for _, m := range window[:len(window)-1] {
+		role := ai.RoleUser
+		if m.Role == models.MessageRoleAssistant {
+			role = ai.RoleModel
+		}
+		history = append(history, ai.Turn{Role: role, Text: m.Content})
+	}
+
+	resp, err := r.client.Generate(ctx, ai.GenerateRequest{
+		Prompt:            window[len(window)-1].Content,
+		History:           history,
+		SystemInstruction: system,
+	})
+	if err != nil {
+		err = fmt.Errorf("failed to generate thread reply: %w", err)
+		// Wait out the provider's rate limit without using up an attempt
+		if retryAfter, ok := ai.RateLimited(err); ok {
+			return queue.Defer(err, retryAfter)
+		}
+		return err
+	}
+
+	text := strings.TrimSpace(resp.Text)
+	if text == "" {
+		return fmt.Errorf("model returned an empty reply (finish reason %s)", resp.FinishReason)
+	}
+
+	err = r.threads.AddReply(ctx, thread.ID, &models.ThreadMessage{
+		Content:      text,
+		PromptTokens: resp.PromptTokens,
+		OutputTokens: resp.OutputTokens,
+		CostMicros:   r.pricing.CostMicros(resp.PromptTokens, resp.OutputTokens),
+	})
+	if errors.Is(err, models.ErrThreadBusy) || errors.Is(err, pgx.ErrNoRows) {
+		// Answered by a concurrent attempt, or deleted meanwhile
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store thread reply: %w", err)
+	}
+
+	slog.Info("Thread reply stored", "thread_id", thread.ID, "prompt_tokens", resp.PromptTokens, "output_tokens", resp.OutputTokens)
+	return nil
+}
+
+// buildSystemInstruction gives the model the submitted text, cut to its
+// share of the context budget, and the current analysis
+func buildSystemInstruction(content string, analysis *models.Analysis) string {
+	var b strings.Builder
+	b.WriteString(systemInstruction)
+	b.WriteString("\n\nSubmitted text, quoted as a JSON string: ")
+	b.WriteString(strconv.Quote(truncate(content, maxSourceTokens)))
+
+	if analysis != nil {
+		fmt.Fprintf(&b, "\n\nAnalysis:\nSentiment: %s\n", analysis.Sentiment)
+		if len(analysis.Topics) > 0 {
+			fmt.Fprintf(&b, "Topics: %s\n", strings.Join(analysis.Topics, ", "))
+		}
+		if len(analysis.Keyphrases) > 0 {
+			phrases := make([]string, 0, len(analysis.Keyphrases))
+			for _, k := range analysis.Keyphrases {
+				phrases = append(phrases, k.Phrase)
+			}
+			fmt.Fprintf(&b, "Keyphrases: %s\n", strings.Join(phrases, ", "))
+		}
+		if analysis.Summary != "" {
+			fmt.Fprintf(&b, "Summary: %s\n", analysis.Summary)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package threads
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// newThread stores a completed submission and starts a thread on it
+func newThread(t *testing.T, submissions *memstore.SubmissionStore, threads *memstore.ThreadStore, message string) *models.Thread {
+	t.Helper()
+	ctx := context.Background()
+	userID := uuid.New()
+
+	submission, err := submissions.Create(ctx, userID, "Our new pricing starts next month.", nil, nil, nil, models.StatusQueued)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := submissions.UpdateStatus(ctx, submission.ID, models.StatusProcessing); err != nil {
+		t.Fatalf("UpdateStatus() error = %v", err)
+	}
+	if err := submissions.SaveAnalysis(ctx, &models.Analysis{SubmissionID: submission.ID, Sentiment: "neutral", Summary: "Pricing changes."}); err != nil {
+		t.Fatalf("SaveAnalysis() error = %v", err)
+	}
+
+	thread, err := threads.Create(ctx, userID, submission.ID, message)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return thread
+}
+
+func replyJob(t *testing.T, threadID uuid.UUID, attempts int) *queue.Job {
+	t.Helper()
+	payload, err := json.Marshal(Payload{ThreadID: threadID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queue.Job{Type: JobType, Payload: payload, Attempts: attempts, MaxAttempts: 3}
+}
+
+func TestResponder_Handle(t *testing.T) {
+	var system string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SystemInstruction struct {
+				Parts []struct {
+					Text string `json:"text"`
+				} `json:"parts"`
+			} `json:"systemInstruction"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		system = body.SystemInstruction.Parts[0].Text
+
+		w.Write([]byte(`{
+			"candidates": [{"content": {"parts": [{"text": " Prices change. "}]}, "finishReason": "STOP"}],
+			"usageMetadata": {"promptTokenCount": 40, "candidatesTokenCount": 3}
+		}`))
+	}))
+	defer server.Close()
+
+	submissions := memstore.NewSubmissionStore()
+	threads := memstore.NewThreadStore()
+	thread := newThread(t, submissions, threads, "Make the summary shorter")
+
+	responder := NewResponder(threads, submissions, ai.NewClient(ai.Options{BaseURL: server.URL}))
+	if err := responder.Handle(context.Background(), replyJob(t, thread.ID, 0)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	if !strings.Contains(system, "Our new pricing starts next month.") || !strings.Contains(system, "Summary: Pricing changes.") {
+		t.Errorf("system instruction = %q, want the text and analysis", system)
+	}
+
+	got, _ := threads.Get(context.Background(), thread.ID)
+	if len(got.Messages) != 2 || got.Messages[1].Role != models.MessageRoleAssistant || got.Messages[1].Content != "Prices change." {
+		t.Fatalf("Messages = %+v, want the trimmed reply", got.Messages)
+	}
+	if got.Pending {
+		t.Error("Pending = true after the reply")
+	}
+
+	// A retried job doesn't answer twice
+	if err := responder.Handle(context.Background(), replyJob(t, thread.ID, 1)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if got, _ := threads.Get(context.Background(), thread.ID); len(got.Messages) != 2 {
+		t.Errorf("Messages = %d after a retry, want 2", len(got.Messages))
+	}
+}
+
+func TestResponder_Handle_Failure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	submissions := memstore.NewSubmissionStore()
+	threads := memstore.NewThreadStore()
+	thread := newThread(t, submissions, threads, "List the risks")
+	responder := NewResponder(threads, submissions, ai.NewClient(ai.Options{BaseURL: server.URL}))
+
+	if err := responder.Handle(context.Background(), replyJob(t, thread.ID, 0)); err == nil {
+		t.Fatal("Handle() error = nil, want the API error")
+	}
+	if got, _ := threads.Get(context.Background(), thread.ID); got.Messages[0].Failed {
+		t.Error("message marked failed before the last attempt")
+	}
+
+	if err := responder.Handle(context.Background(), replyJob(t, thread.ID, 2)); err == nil {
+		t.Fatal("Handle() error = nil, want the API error")
+	}
+	got, _ := threads.Get(context.Background(), thread.ID)
+	if !got.Messages[0].Failed || got.Pending {
+		t.Errorf("message = %+v, pending = %v; want it failed after the last attempt", got.Messages[0], got.Pending)
+	}
+}
@@ -0,0 +1,69 @@
+package threads
+
+import (
+	"unicode/utf8"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+const (
+	// ContextBudget is roughly how many tokens of context are sent with
+	// each reply: the submission, its analysis and the conversation
+	ContextBudget = 6000
+
+	// maxSourceTokens bounds the share of the budget taken by the
+	// submitted text, leaving the rest for the conversation
+	maxSourceTokens = ContextBudget / 2
+
+	// minHistoryTokens is kept for the conversation however large the
+	// context is
+	minHistoryTokens = 500
+)
+
+// EstimateTokens approximates the token count of text at four characters
+// per token, which is close enough for English prose to budget a prompt
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// truncate cuts text to at most maxTokens estimated tokens
+func truncate(text string, maxTokens int) string {
+	limit := maxTokens * 4
+	runes := 0
+	for i := range text {
+		if runes == limit {
+			return text[:i] + "…"
+		}
+		runes++
+	}
+	return text
+}
+
+// Window picks the messages sent to the model for a reply: the newest that
+// fit in budget estimated tokens, oldest first. The last message is always
+// kept, unanswered user messages are dropped and the window starts with a
+// user message so the turns alternate.
+func Window(messages []models.ThreadMessage, budget int) []models.ThreadMessage {
+	answered := make([]models.ThreadMessage, 0, len(messages))
+	for _, m := range messages {
+		if !m.Failed {
+			answered = append(answered, m)
+		}
+	}
+
+	start := len(answered)
+	used := 0
+	for start > 0 {
+		cost := EstimateTokens(answered[start-1].Content)
+		if start < len(answered) && used+cost > budget {
+			break
+		}
+		used += cost
+		start--
+	}
+
+	for start < len(answered)-1 && answered[start].Role != models.MessageRoleUser {
+		start++
+	}
+	return answered[start:]
+}
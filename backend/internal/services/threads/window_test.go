@@ -0,0 +1,102 @@
+package threads
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"a", 1},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"héllo wörld!", 3},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	user := func(content string) models.ThreadMessage {
+		return models.ThreadMessage{Role: models.MessageRoleUser, Content: content}
+	}
+	reply := func(content string) models.ThreadMessage {
+		return models.ThreadMessage{Role: models.MessageRoleAssistant, Content: content}
+	}
+	failed := func(content string) models.ThreadMessage {
+		m := user(content)
+		m.Failed = true
+		return m
+	}
+	long := strings.Repeat("x", 400) // 100 tokens
+
+	tests := []struct {
+		name     string
+		messages []models.ThreadMessage
+		budget   int
+		want     []string
+	}{
+		{
+			name:     "everything fits",
+			messages: []models.ThreadMessage{user("a"), reply("b"), user("c")},
+			budget:   100,
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name:     "oldest messages dropped first",
+			messages: []models.ThreadMessage{user(long), reply(long), user("c"), reply("d"), user("e")},
+			budget:   150,
+			want:     []string{"c", "d", "e"},
+		},
+		{
+			name:     "starts with a user message",
+			messages: []models.ThreadMessage{user("a"), reply(long), user("c")},
+			budget:   101,
+			want:     []string{"c"},
+		},
+		{
+			name:     "last message kept over budget",
+			messages: []models.ThreadMessage{user("a"), reply("b"), user(long)},
+			budget:   10,
+			want:     []string{long},
+		},
+		{
+			name:     "unanswered messages dropped",
+			messages: []models.ThreadMessage{user("a"), reply("b"), failed("c"), user("d")},
+			budget:   100,
+			want:     []string{"a", "b", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Window(tt.messages, tt.budget)
+			contents := make([]string, 0, len(got))
+			for _, m := range got {
+				contents = append(contents, m.Content)
+			}
+			if strings.Join(contents, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Window() = %v, want %v", contents, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := truncate("short", 10); got != "short" {
+		t.Errorf("truncate() = %q, want unchanged", got)
+	}
+	if got := truncate(strings.Repeat("é", 50), 10); got != strings.Repeat("é", 40)+"…" {
+		t.Errorf("truncate() = %q, want 40 characters and an ellipsis", got)
+	}
+}
@@ -0,0 +1,91 @@
+// Package tiering keeps the submissions table small by moving the content
+// of old submissions to object storage in the background. Their metadata
+// and analyses stay in Postgres, and the submission store reads tiered
+// content back on access.
+package tiering
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the content tiering job in the queue
+	JobType = "tiering.move"
+
+	// BatchSize is how many submissions one batch moves. Each one is a
+	// round trip to object storage, so batches are smaller than purges'.
+	BatchSize = 100
+
+	// maxBatches stops a run after 5,000 uploads to object storage. Turning
+	// tiering on for an old database moves its backlog over the following
+	// days rather than in one long job.
+	maxBatches = 50
+)
+
+// Tierer moves old content to object storage and deletes objects no
+// submission needs any more; *models.SubmissionStore implements it
+type Tierer interface {
+	TierContent(ctx context.Context, createdBefore time.Time, limit int) (int, error)
+	PurgeContentObjects(ctx context.Context, limit int) (int, error)
+}
+
+// Job moves the content of submissions older than a number of months to
+// object storage
+type Job struct {
+	store  Tierer
+	months int
+}
+
+// NewJob creates a new content tiering job for submissions older than
+// months
+func NewJob(store Tierer, months int) *Job {
+	return &Job{store: store, months: months}
+}
+
+// Handle implements queue.Handler for the content tiering job. It moves
+// content in batches until nothing old enough is left, then deletes the
+// objects of submissions that have since been purged.
+func (j *Job) Handle(ctx context.Context, job *queue.Job) error {
+	createdBefore := time.Now().AddDate(0, -j.months, 0)
+	tiered, err := inBatches(func(limit int) (int, error) {
+		return j.store.TierContent(ctx, createdBefore, limit)
+	})
+	if err != nil {
+		slog.Error("Content tiering stopped", "tiered", tiered, "error", err)
+		return err
+	}
+
+	purged, err := inBatches(func(limit int) (int, error) {
+		return j.store.PurgeContentObjects(ctx, limit)
+	})
+	if err != nil {
+		slog.Error("Content object purge stopped", "tiered", tiered, "purged", purged, "error", err)
+		return err
+	}
+
+	if tiered > 0 || purged > 0 {
+		slog.Info("Content tiered to object storage", "tiered", tiered, "purged", purged)
+	}
+	return nil
+}
+
+// inBatches calls run with BatchSize until it handles less than a full
+// batch, and returns how many it handled in all
+func inBatches(run func(limit int) (int, error)) (int, error) {
+	total := 0
+	for range maxBatches {
+		n, err := run(BatchSize)
+		total += n
+		if err != nil {
+			return total, err
+		}
+		if n < BatchSize {
+			break
+		}
+	}
+	return total, nil
+}
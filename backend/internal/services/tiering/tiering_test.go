@@ -0,0 +1,58 @@
+package tiering
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeTierer tiers and purges fixed backlogs, recording the cutoff it was
+// given
+type fakeTierer struct {
+	old, orphans  int
+	createdBefore time.Time
+	tierErr       error
+	purgeCalls    int
+}
+
+func (f *fakeTierer) TierContent(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	f.createdBefore = createdBefore
+	if f.tierErr != nil {
+		return 0, f.tierErr
+	}
+	n := min(limit, f.old)
+	f.old -= n
+	return n, nil
+}
+
+func (f *fakeTierer) PurgeContentObjects(ctx context.Context, limit int) (int, error) {
+	f.purgeCalls++
+	n := min(limit, f.orphans)
+	f.orphans -= n
+	return n, nil
+}
+
+func TestJob_Handle(t *testing.T) {
+	store := &fakeTierer{old: 2*BatchSize + 1, orphans: 3}
+	if err := NewJob(store, 6).Handle(context.Background(), &queue.Job{Type: JobType}); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if store.old != 0 || store.orphans != 0 {
+		t.Errorf("Handle() left %d to tier and %d to purge, want none", store.old, store.orphans)
+	}
+	if want := time.Now().AddDate(0, -6, 0); store.createdBefore.Sub(want).Abs() > time.Minute {
+		t.Errorf("Handle() tiered content created before %s, want %s", store.createdBefore, want)
+	}
+
+	// Nothing is purged after tiering fails
+	store = &fakeTierer{orphans: 3, tierErr: errors.New("bucket not found")}
+	if err := NewJob(store, 6).Handle(context.Background(), &queue.Job{Type: JobType}); err == nil {
+		t.Error("Handle() error = nil, want the tiering error")
+	}
+	if store.purgeCalls != 0 {
+		t.Errorf("Handle() purged %d times after tiering failed, want 0", store.purgeCalls)
+	}
+}
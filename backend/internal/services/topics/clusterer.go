@@ -0,0 +1,203 @@
+package topics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the topic clustering job in the queue
+	JobType = "analytics.topic_clustering"
+
+	// MinSubmissions is the smallest corpus worth clustering
+	MinSubmissions = 6
+
+	// embedBatchSize bounds how many submissions are embedded per run
+	embedBatchSize = 500
+
+	// labelSamples is how many excerpts are shown to the LLM per cluster
+	labelSamples = 5
+
+	minClusters = 2
+	maxClusters = 12
+)
+
+// Clusterer embeds submissions and groups each user's corpus into topics
+type Clusterer struct {
+	store  *models.TopicStore
+	client *ai.Client
+}
+
+// NewClusterer creates a new topic clusterer
+func NewClusterer(store *models.TopicStore, client *ai.Client) *Clusterer {
+	return &Clusterer{
+		store:  store,
+		client: client,
+	}
+}
+
+// Handle implements queue.Handler for the topic clustering job
+func (c *Clusterer) Handle(ctx context.Context, job *queue.Job) error {
+	if err := c.embedMissing(ctx); err != nil {
+		return err
+	}
+
+	users, err := c.store.UsersWithEmbeddings(ctx, MinSubmissions)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range users {
+		if err := c.clusterUser(ctx, userID); err != nil {
+			// Keep going so one user's failure doesn't block everyone else
+			slog.Error("Failed to cluster topics", "user_id", userID, "error", err)
+		}
+	}
+
+	slog.Info("Topic clustering finished", "users", len(users))
+	return nil
+}
+
+// embedMissing computes embeddings for submissions that don't have one
+func (c *Clusterer) embedMissing(ctx context.Context) error {
+	pending, err := c.store.SubmissionsMissingEmbeddings(ctx, embedBatchSize)
+	if err != nil {
+		return err
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	texts := make([]string, len(pending))
+	for i, p := range pending {
+		texts[i] = p.Content
+	}
+
+	vectors, err := c.client.Embed(ctx, texts)
+	if err != nil {
+		return fmt.Errorf("failed to embed submissions: %w", err)
+	}
+
+	for i, p := range pending {
+		if err := c.store.SaveEmbedding(ctx, p.ID, c.client.EmbeddingModel(), vectors[i]); err != nil {
+			return err
+		}
+	}
+
+	slog.Info("Embedded submissions", "count", len(pending))
+	return nil
+}
+
+// clusterUser runs k-means over a user's embeddings and stores labeled clusters
+func (c *Clusterer) clusterUser(ctx context.Context, userID uuid.UUID) error {
+	embeddings, err := c.store.EmbeddingsForUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	vectors := make([][]float32, len(embeddings))
+	for i, e := range embeddings {
+		vectors[i] = e.Vector
+	}
+
+	k := ChooseK(len(vectors), minClusters, maxClusters)
+
+	// Seed from the user ID so reruns over the same corpus are stable
+	seed := uint64(0)
+	for _, b := range userID {
+		seed = seed<<8 | uint64(b)
+	}
+
+	result := KMeans(vectors, k, seed)
+
+	clusters := make([]models.TopicCluster, len(result.Centroids))
+	for i := range clusters {
+		clusters[i].Centroid = result.Centroids[i]
+	}
+
+	for i, e := range embeddings {
+		idx := result.Assignments[i]
+		clusters[idx].Members = append(clusters[idx].Members, models.TopicClusterMember{
+			SubmissionID: e.SubmissionID,
+			Distance:     result.Distances[i],
+		})
+	}
+
+	excerpts := make(map[uuid.UUID]string, len(embeddings))
+	for _, e := range embeddings {
+		excerpts[e.SubmissionID] = e.Excerpt
+	}
+
+	// Drop empty clusters and name the rest
+	labeled := clusters[:0]
+	for i, cluster := range clusters {
+		if len(cluster.Members) == 0 {
+			continue
+		}
+		cluster.Label = c.label(ctx, cluster, excerpts, i+1)
+		labeled = append(labeled, cluster)
+	}
+
+	return c.store.ReplaceClusters(ctx, userID, labeled)
+}
+
+// label asks the LLM for a short topic name, falling back to a generic one
+func (c *Clusterer) label(ctx context.Context, cluster models.TopicCluster, excerpts map[uuid.UUID]string, n int) string {
+	fallback := fmt.Sprintf("Topic %d", n)
+
+	members := closest(cluster.Members, labelSamples)
+
+	var prompt strings.Builder
+	prompt.WriteString("The following excerpts belong to one topic cluster. ")
+	prompt.WriteString("Name the shared topic in 2 to 5 words. ")
+	prompt.WriteString(`Respond in JSON: {"label": "..."}` + "\n\n")
+	for i, m := range members {
+		fmt.Fprintf(&prompt, "Excerpt %d:\n%s\n\n", i+1, excerpts[m.SubmissionID])
+	}
+
+	resp, err := c.client.Generate(ctx, ai.GenerateRequest{
+		Prompt: prompt.String(),
+		JSON:   true,
+	})
+	if err != nil {
+		slog.Warn("Failed to label topic cluster", "error", err)
+		return fallback
+	}
+
+	var out struct {
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text), &out); err != nil || strings.TrimSpace(out.Label) == "" {
+		return fallback
+	}
+
+	label := strings.TrimSpace(out.Label)
+	if len(label) > 255 {
+		label = label[:255]
+	}
+	return label
+}
+
+// closest returns up to n members with the smallest centroid distance
+func closest(members []models.TopicClusterMember, n int) []models.TopicClusterMember {
+	sorted := append([]models.TopicClusterMember(nil), members...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Distance < sorted[j].Distance
+	})
+
+	if len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
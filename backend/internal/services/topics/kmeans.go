@@ -0,0 +1,195 @@
+package topics
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// maxIterations bounds Lloyd's algorithm when assignments keep changing
+const maxIterations = 50
+
+// KMeansResult holds the outcome of a clustering run
+type KMeansResult struct {
+	// Centroids are unit-length cluster centers
+	Centroids [][]float32
+
+	// Assignments maps each input vector to its cluster index
+	Assignments []int
+
+	// Distances is the cosine distance from each vector to its centroid
+	Distances []float64
+}
+
+// KMeans clusters vectors into k groups using cosine distance.
+// Vectors are normalized internally; k-means++ seeding with the given seed
+// makes results reproducible for the same input.
+func KMeans(vectors [][]float32, k int, seed uint64) KMeansResult {
+	n := len(vectors)
+	if n == 0 || k <= 0 {
+		return KMeansResult{}
+	}
+	if k > n {
+		k = n
+	}
+
+	points := make([][]float32, n)
+	for i, v := range vectors {
+		points[i] = normalize(v)
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	centroids := seedCentroids(points, k, rng)
+
+	assignments := make([]int, n)
+	for i := range assignments {
+		assignments[i] = -1
+	}
+
+	for iter := 0; iter < maxIterations; iter++ {
+		changed := false
+		for i, p := range points {
+			best := nearest(p, centroids)
+			if assignments[i] != best {
+				assignments[i] = best
+				changed = true
+			}
+		}
+
+		if !changed {
+			break
+		}
+
+		centroids = recompute(points, assignments, centroids)
+	}
+
+	distances := make([]float64, n)
+	for i, p := range points {
+		distances[i] = cosineDistance(p, centroids[assignments[i]])
+	}
+
+	return KMeansResult{
+		Centroids:   centroids,
+		Assignments: assignments,
+		Distances:   distances,
+	}
+}
+
+// ChooseK picks a cluster count for n documents using the sqrt(n/2) rule of
+// thumb, clamped to [minK, maxK]
+func ChooseK(n, minK, maxK int) int {
+	k := int(math.Round(math.Sqrt(float64(n) / 2)))
+	return max(minK, min(k, maxK))
+}
+
+// seedCentroids implements k-means++ initialization
+func seedCentroids(points [][]float32, k int, rng *rand.Rand) [][]float32 {
+	centroids := make([][]float32, 0, k)
+	centroids = append(centroids, clone(points[rng.IntN(len(points))]))
+
+	weights := make([]float64, len(points))
+	for len(centroids) < k {
+		var total float64
+		for i, p := range points {
+			d := cosineDistance(p, centroids[nearest(p, centroids)])
+			weights[i] = d * d
+			total += weights[i]
+		}
+
+		// All remaining points coincide with existing centroids
+		if total == 0 {
+			centroids = append(centroids, clone(points[rng.IntN(len(points))]))
+			continue
+		}
+
+		target := rng.Float64() * total
+		idx := len(points) - 1
+		for i, w := range weights {
+			target -= w
+			if target <= 0 {
+				idx = i
+				break
+			}
+		}
+		centroids = append(centroids, clone(points[idx]))
+	}
+
+	return centroids
+}
+
+// recompute averages the points of each cluster; empty clusters keep their
+// previous centroid
+func recompute(points [][]float32, assignments []int, previous [][]float32) [][]float32 {
+	dims := len(points[0])
+	sums := make([][]float64, len(previous))
+	counts := make([]int, len(previous))
+	for c := range sums {
+		sums[c] = make([]float64, dims)
+	}
+
+	for i, p := range points {
+		c := assignments[i]
+		counts[c]++
+		for d, v := range p {
+			sums[c][d] += float64(v)
+		}
+	}
+
+	centroids := make([][]float32, len(previous))
+	for c := range centroids {
+		if counts[c] == 0 {
+			centroids[c] = previous[c]
+			continue
+		}
+
+		centroid := make([]float32, dims)
+		for d := range centroid {
+			centroid[d] = float32(sums[c][d] / float64(counts[c]))
+		}
+		centroids[c] = normalize(centroid)
+	}
+
+	return centroids
+}
+
+// nearest returns the index of the closest centroid
+func nearest(p []float32, centroids [][]float32) int {
+	best, bestDist := 0, math.Inf(1)
+	for c, centroid := range centroids {
+		if d := cosineDistance(p, centroid); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+// cosineDistance assumes both vectors are unit length
+func cosineDistance(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return 1 - dot
+}
+
+// normalize returns a unit-length copy of v
+func normalize(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+
+	out := make([]float32, len(v))
+	if sum == 0 {
+		return out
+	}
+
+	norm := math.Sqrt(sum)
+	for i, x := range v {
+		out[i] = float32(float64(x) / norm)
+	}
+	return out
+}
+
+func clone(v []float32) []float32 {
+	return append([]float32(nil), v...)
+}
@@ -0,0 +1,93 @@
+package topics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestKMeans_SeparatesClusters(t *testing.T) {
+	// Two obvious groups pointing along different axes
+	vectors := [][]float32{
+		{1, 0.1, 0},
+		{0.9, 0.05, 0.1},
+		{1, 0, 0.05},
+		{0, 1, 0.1},
+		{0.1, 0.9, 0},
+		{0.05, 1, 0.05},
+	}
+
+	result := KMeans(vectors, 2, 42)
+
+	if len(result.Centroids) != 2 {
+		t.Fatalf("KMeans() returned %d centroids, want 2", len(result.Centroids))
+	}
+
+	if result.Assignments[0] != result.Assignments[1] || result.Assignments[1] != result.Assignments[2] {
+		t.Errorf("KMeans() split the first group: %v", result.Assignments)
+	}
+	if result.Assignments[3] != result.Assignments[4] || result.Assignments[4] != result.Assignments[5] {
+		t.Errorf("KMeans() split the second group: %v", result.Assignments)
+	}
+	if result.Assignments[0] == result.Assignments[3] {
+		t.Errorf("KMeans() merged both groups: %v", result.Assignments)
+	}
+
+	for i, d := range result.Distances {
+		if d < 0 || d > 0.1 {
+			t.Errorf("Distance[%d] = %v, want a small non-negative value", i, d)
+		}
+	}
+}
+
+func TestKMeans_Deterministic(t *testing.T) {
+	vectors := [][]float32{
+		{1, 0}, {0.8, 0.2}, {0.2, 0.8}, {0, 1}, {0.5, 0.5}, {0.7, 0.1},
+	}
+
+	a := KMeans(vectors, 3, 7)
+	b := KMeans(vectors, 3, 7)
+
+	for i := range a.Assignments {
+		if a.Assignments[i] != b.Assignments[i] {
+			t.Fatalf("KMeans() is not deterministic for the same seed: %v vs %v", a.Assignments, b.Assignments)
+		}
+	}
+}
+
+func TestKMeans_EdgeCases(t *testing.T) {
+	if result := KMeans(nil, 3, 1); len(result.Centroids) != 0 {
+		t.Errorf("KMeans(nil) returned %d centroids, want 0", len(result.Centroids))
+	}
+
+	// k larger than the number of points is clamped
+	result := KMeans([][]float32{{1, 0}, {0, 1}}, 5, 1)
+	if len(result.Centroids) != 2 {
+		t.Errorf("KMeans() returned %d centroids, want 2", len(result.Centroids))
+	}
+
+	// Identical points must not loop forever or produce NaN
+	result = KMeans([][]float32{{1, 1}, {1, 1}, {1, 1}}, 2, 1)
+	for i, d := range result.Distances {
+		if math.IsNaN(d) {
+			t.Errorf("Distance[%d] is NaN", i)
+		}
+	}
+}
+
+func TestChooseK(t *testing.T) {
+	tests := []struct {
+		n    int
+		want int
+	}{
+		{n: 6, want: 2},
+		{n: 50, want: 5},
+		{n: 200, want: 10},
+		{n: 10000, want: 12},
+	}
+
+	for _, tt := range tests {
+		if got := ChooseK(tt.n, 2, 12); got != tt.want {
+			t.Errorf("ChooseK(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+const (
+	defaultDeepgramBaseURL = "https://api.deepgram.com/v1"
+	defaultDeepgramModel   = "nova-2"
+)
+
+// deepgram transcribes with Deepgram's pre-recorded audio API
+type deepgram struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func newDeepgram(cfg Config, httpClient *http.Client) *deepgram {
+	p := &deepgram{apiKey: cfg.APIKey, baseURL: cfg.BaseURL, model: cfg.Model, httpClient: httpClient}
+	if p.baseURL == "" {
+		p.baseURL = defaultDeepgramBaseURL
+	}
+	if p.model == "" {
+		p.model = defaultDeepgramModel
+	}
+	return p
+}
+
+// Name implements Provider
+func (p *deepgram) Name() string {
+	return ProviderDeepgram
+}
+
+// deepgramResponse is the part of a /listen response we use
+type deepgramResponse struct {
+	Metadata struct {
+		Duration float64 `json:"duration"`
+	} `json:"metadata"`
+	Results struct {
+		Channels []struct {
+			DetectedLanguage string `json:"detected_language"`
+			Alternatives     []struct {
+				Transcript string `json:"transcript"`
+			} `json:"alternatives"`
+		} `json:"channels"`
+		Utterances []struct {
+			Start      float64 `json:"start"`
+			End        float64 `json:"end"`
+			Transcript string  `json:"transcript"`
+		} `json:"utterances"`
+	} `json:"results"`
+}
+
+// Transcribe implements Provider
+func (p *deepgram) Transcribe(ctx context.Context, media Media) (*Transcript, error) {
+	query := url.Values{
+		"model":           {p.model},
+		"smart_format":    {"true"},
+		"utterances":      {"true"},
+		"detect_language": {"true"},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.baseURL, "/")+"/listen?"+query.Encode(), bytes.NewReader(media.Data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", media.ContentType)
+	req.Header.Set("Authorization", "Token "+p.apiKey)
+
+	var resp deepgramResponse
+	if err := do(p.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+
+	transcript := &Transcript{
+		DurationSeconds: resp.Metadata.Duration,
+		Segments:        make([]models.TranscriptSegment, 0, len(resp.Results.Utterances)),
+	}
+	for _, u := range resp.Results.Utterances {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{Start: u.Start, End: u.End, Text: strings.TrimSpace(u.Transcript)})
+	}
+	if len(resp.Results.Channels) > 0 {
+		channel := resp.Results.Channels[0]
+		transcript.Language = channel.DetectedLanguage
+		if len(channel.Alternatives) > 0 {
+			transcript.Text = strings.TrimSpace(channel.Alternatives[0].Transcript)
+		}
+	}
+	if transcript.Text == "" {
+		transcript.Text = joinSegments(transcript.Segments)
+	}
+	return transcript, nil
+}
@@ -0,0 +1,207 @@
+package transcription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the transcription job in the queue
+	JobType = "transcription.media"
+
+	// MaxTranscriptLength matches the limit on typed submissions, in
+	// characters
+	MaxTranscriptLength = 50000
+)
+
+// Payload is the job payload for a transcription
+type Payload struct {
+	TranscriptionID uuid.UUID `json:"transcription_id"`
+}
+
+// TranscriptionSource reads uploads and records their outcome;
+// *models.TranscriptionStore implements it
+type TranscriptionSource interface {
+	Get(ctx context.Context, id uuid.UUID) (*models.Transcription, error)
+	Media(ctx context.Context, id uuid.UUID) ([]byte, error)
+	Complete(ctx context.Context, t *models.Transcription) error
+	Fail(ctx context.Context, id uuid.UUID, message string) error
+}
+
+// SubmissionCreator stores the submission holding a transcript;
+// *models.SubmissionStore implements it
+type SubmissionCreator interface {
+	Create(ctx context.Context, userID uuid.UUID, content string, redacted, instructions *string, profileID *uuid.UUID, status models.SubmissionStatus) (*models.Submission, error)
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.SubmissionStatus) error
+}
+
+// Enqueuer schedules the analysis of the new submission
+type Enqueuer interface {
+	EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error)
+}
+
+// QuotaCharges moves and refunds the quota charge made when a recording
+// is uploaded; *quota.Tracker implements it
+type QuotaCharges interface {
+	Transfer(ctx context.Context, from, to uuid.UUID) error
+	Refund(ctx context.Context, chargeID uuid.UUID) error
+}
+
+// Transcriber transcribes uploads and queues their transcripts for analysis
+type Transcriber struct {
+	store       TranscriptionSource
+	submissions SubmissionCreator
+	jobs        Enqueuer
+	provider    Provider
+	quota       QuotaCharges
+}
+
+// NewTranscriber creates a new transcription job
+func NewTranscriber(store TranscriptionSource, submissions SubmissionCreator, jobs Enqueuer, provider Provider) *Transcriber {
+	return &Transcriber{
+		store:       store,
+		submissions: submissions,
+		jobs:        jobs,
+		provider:    provider,
+	}
+}
+
+// WithQuota refunds the analysis counted when a recording was uploaded if
+// the recording is never analyzed, and otherwise hands the charge on to
+// its submission, and returns the transcriber
+func (t *Transcriber) WithQuota(quota QuotaCharges) *Transcriber {
+	t.quota = quota
+	return t
+}
+
+// Handle implements queue.Handler for the transcription job
+func (t *Transcriber) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid transcription payload: %w", err)
+	}
+
+	upload, err := t.store.Get(ctx, payload.TranscriptionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// The user was deleted before we got to it
+			return nil
+		}
+		return fmt.Errorf("failed to load transcription: %w", err)
+	}
+	if upload.Status != models.TranscriptionPending {
+		return nil
+	}
+
+	transcript, err := t.transcribe(ctx, upload)
+	if err != nil {
+		// Only give up on the upload once the queue stops retrying
+		if job.Attempts+1 >= job.MaxAttempts {
+			t.fail(upload.ID, "The recording couldn't be transcribed")
+		}
+		return err
+	}
+
+	switch length := utf8.RuneCountInString(transcript.Text); {
+	case length == 0:
+		t.fail(upload.ID, "No speech was found in the recording")
+		return nil
+	case length > MaxTranscriptLength:
+		t.fail(upload.ID, fmt.Sprintf("The transcript is longer than %d characters", MaxTranscriptLength))
+		return nil
+	}
+
+	return t.submit(ctx, upload, transcript)
+}
+
+// transcribe sends the upload's media to the provider
+func (t *Transcriber) transcribe(ctx context.Context, upload *models.Transcription) (*Transcript, error) {
+	media, err := t.store.Media(ctx, upload.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load media: %w", err)
+	}
+
+	transcript, err := t.provider.Transcribe(ctx, Media{
+		Filename:    upload.Filename,
+		ContentType: upload.ContentType,
+		Data:        media,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to transcribe %s: %w", upload.ID, err)
+	}
+	return transcript, nil
+}
+
+// submit stores the transcript as a queued submission and schedules its
+// analysis
+func (t *Transcriber) submit(ctx context.Context, upload *models.Transcription, transcript *Transcript) error {
+	submission, err := t.submissions.Create(ctx, upload.UserID, transcript.Text, nil, upload.Instructions, upload.ProfileID, models.StatusQueued)
+	if err != nil {
+		return fmt.Errorf("failed to create submission: %w", err)
+	}
+
+	if t.quota != nil {
+		if err := t.quota.Transfer(ctx, upload.ID, submission.ID); err != nil {
+			slog.Warn("Failed to move quota charge to transcript", "transcription_id", upload.ID, "submission_id", submission.ID, "error", err)
+		}
+	}
+
+	provider := t.provider.Name()
+	upload.SubmissionID = &submission.ID
+	upload.Provider = &provider
+	upload.Segments = transcript.Segments
+	if transcript.Language != "" {
+		upload.Language = &transcript.Language
+	}
+	if transcript.DurationSeconds > 0 {
+		upload.DurationSeconds = &transcript.DurationSeconds
+	}
+	if err := t.store.Complete(ctx, upload); err != nil {
+		return fmt.Errorf("failed to store transcript: %w", err)
+	}
+
+	priority := queue.Priority(upload.Priority)
+	if _, err := t.jobs.EnqueuePriority(ctx, priority, analyzer.JobType, analyzer.Payload{SubmissionID: submission.ID}); err != nil {
+		slog.Error("Failed to enqueue analysis of transcript", "submission_id", submission.ID, "error", err)
+
+		// The transcript is kept; don't leave the submission queued forever
+		if err := t.submissions.UpdateStatus(ctx, submission.ID, models.StatusFailed); err != nil {
+			slog.Error("Failed to mark submission failed", "submission_id", submission.ID, "error", err)
+		}
+		t.refund(submission.ID)
+		return nil
+	}
+
+	slog.Info("Recording transcribed", "transcription_id", upload.ID, "submission_id", submission.ID, "provider", provider)
+	return nil
+}
+
+// fail records a transcription failure the user can see, and refunds the
+// analysis that won't happen
+func (t *Transcriber) fail(id uuid.UUID, message string) {
+	if err := t.store.Fail(context.Background(), id, message); err != nil {
+		slog.Error("Failed to mark transcription failed", "transcription_id", id, "error", err)
+	}
+	t.refund(id)
+}
+
+// refund gives back a quota charge. Quotas are soft, so a failure is only
+// logged.
+func (t *Transcriber) refund(chargeID uuid.UUID) {
+	if t.quota == nil {
+		return
+	}
+	if err := t.quota.Refund(context.Background(), chargeID); err != nil {
+		slog.Warn("Failed to refund analysis", "charge_id", chargeID, "error", err)
+	}
+}
@@ -0,0 +1,221 @@
+package transcription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// fakeProvider returns a canned transcript or error
+type fakeProvider struct {
+	transcript *Transcript
+	err        error
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Transcribe(ctx context.Context, media Media) (*Transcript, error) {
+	return p.transcript, p.err
+}
+
+// fakeEnqueuer records the jobs it is given
+type fakeEnqueuer struct {
+	jobs []*queue.Job
+	err  error
+}
+
+func (q *fakeEnqueuer) EnqueuePriority(ctx context.Context, priority queue.Priority, jobType string, payload interface{}) (*queue.Job, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	job := &queue.Job{Type: jobType, Payload: data, Priority: priority}
+	q.jobs = append(q.jobs, job)
+	return job, nil
+}
+
+// fakeQuota records the charges it moves and refunds
+type fakeQuota struct {
+	transfers map[uuid.UUID]uuid.UUID
+	refunds   []uuid.UUID
+}
+
+func (q *fakeQuota) Transfer(ctx context.Context, from, to uuid.UUID) error {
+	if q.transfers == nil {
+		q.transfers = make(map[uuid.UUID]uuid.UUID)
+	}
+	q.transfers[from] = to
+	return nil
+}
+
+func (q *fakeQuota) Refund(ctx context.Context, chargeID uuid.UUID) error {
+	q.refunds = append(q.refunds, chargeID)
+	return nil
+}
+
+func transcriptionJob(t *testing.T, id uuid.UUID, attempts int) *queue.Job {
+	t.Helper()
+	payload, err := json.Marshal(Payload{TranscriptionID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &queue.Job{Type: JobType, Payload: payload, Attempts: attempts, MaxAttempts: 3}
+}
+
+// newUpload stores a pending upload in the high priority lane
+func newUpload(t *testing.T, store *memstore.TranscriptionStore) *models.Transcription {
+	t.Helper()
+	focus := "Focus on decisions"
+	upload, err := store.Create(context.Background(), &models.Transcription{
+		UserID:       uuid.New(),
+		Filename:     "standup.ogg",
+		ContentType:  "audio/ogg",
+		Instructions: &focus,
+		Priority:     string(queue.PriorityHigh),
+	}, []byte("OggS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return upload
+}
+
+func TestTranscriber_Handle(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewTranscriptionStore()
+	submissions := memstore.NewSubmissionStore()
+	jobs := &fakeEnqueuer{}
+	quota := &fakeQuota{}
+	upload := newUpload(t, store)
+
+	provider := &fakeProvider{transcript: &Transcript{
+		Text:            "We ship on Friday.",
+		Language:        "en",
+		DurationSeconds: 2.5,
+		Segments:        []models.TranscriptSegment{{Start: 0, End: 2.5, Text: "We ship on Friday."}},
+	}}
+	if err := NewTranscriber(store, submissions, jobs, provider).WithQuota(quota).Handle(ctx, transcriptionJob(t, upload.ID, 0)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	done, err := store.Get(ctx, upload.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != models.TranscriptionCompleted || done.SubmissionID == nil || *done.Provider != "fake" || *done.Language != "en" || len(done.Segments) != 1 {
+		t.Fatalf("transcription = %+v", done)
+	}
+	if _, err := store.Media(ctx, upload.ID); err == nil {
+		t.Error("media was kept after transcription")
+	}
+
+	submission, err := submissions.GetByID(ctx, upload.UserID, *done.SubmissionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submission.Content != "We ship on Friday." || submission.Status != models.StatusQueued {
+		t.Errorf("submission = %+v", submission)
+	}
+	if submission.Instructions == nil || *submission.Instructions != "Focus on decisions" {
+		t.Errorf("instructions = %v, want the upload's", submission.Instructions)
+	}
+	if quota.transfers[upload.ID] != submission.ID || len(quota.refunds) != 0 {
+		t.Errorf("quota = %+v, want the upload's charge moved to the submission", quota)
+	}
+
+	if len(jobs.jobs) != 1 || jobs.jobs[0].Type != analyzer.JobType || jobs.jobs[0].Priority != queue.PriorityHigh {
+		t.Fatalf("jobs = %+v, want one high priority analysis", jobs.jobs)
+	}
+
+	// Redelivery of a finished job is a no-op
+	if err := NewTranscriber(store, submissions, jobs, provider).Handle(ctx, transcriptionJob(t, upload.ID, 1)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if len(jobs.jobs) != 1 {
+		t.Errorf("enqueued %d analyses, want 1", len(jobs.jobs))
+	}
+}
+
+func TestTranscriber_Handle_Rejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  *fakeProvider
+		attempts  int
+		wantErr   bool
+		wantState models.TranscriptionStatus
+		wantError string
+	}{
+		{"no speech", &fakeProvider{transcript: &Transcript{Text: ""}}, 0, false, models.TranscriptionFailed, "No speech"},
+		{"too long", &fakeProvider{transcript: &Transcript{Text: strings.Repeat("a", MaxTranscriptLength+1)}}, 0, false, models.TranscriptionFailed, "longer than"},
+		{"provider error is retried", &fakeProvider{err: errors.New("timeout")}, 0, true, models.TranscriptionPending, ""},
+		{"provider error on last attempt", &fakeProvider{err: errors.New("timeout")}, 2, true, models.TranscriptionFailed, "couldn't be transcribed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := memstore.NewTranscriptionStore()
+			jobs := &fakeEnqueuer{}
+			quota := &fakeQuota{}
+			upload := newUpload(t, store)
+
+			err := NewTranscriber(store, memstore.NewSubmissionStore(), jobs, tt.provider).WithQuota(quota).Handle(ctx, transcriptionJob(t, upload.ID, tt.attempts))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Handle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			got, err := store.Get(ctx, upload.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantState {
+				t.Errorf("status = %s, want %s", got.Status, tt.wantState)
+			}
+			if tt.wantError != "" && (got.Error == nil || !strings.Contains(*got.Error, tt.wantError)) {
+				t.Errorf("error = %v, want it to mention %q", got.Error, tt.wantError)
+			}
+			if len(jobs.jobs) != 0 {
+				t.Errorf("enqueued %d analyses, want 0", len(jobs.jobs))
+			}
+			// Only a recording that won't be analyzed is refunded
+			if refunded := len(quota.refunds) == 1 && quota.refunds[0] == upload.ID; refunded != (tt.wantState == models.TranscriptionFailed) {
+				t.Errorf("refunds = %v for a %s upload", quota.refunds, tt.wantState)
+			}
+		})
+	}
+}
+
+func TestTranscriber_Handle_EnqueueFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewTranscriptionStore()
+	submissions := memstore.NewSubmissionStore()
+	upload := newUpload(t, store)
+
+	provider := &fakeProvider{transcript: &Transcript{Text: "Hello."}}
+	if err := NewTranscriber(store, submissions, &fakeEnqueuer{err: errors.New("redis down")}, provider).Handle(ctx, transcriptionJob(t, upload.ID, 0)); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+
+	done, err := store.Get(ctx, upload.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submission, err := submissions.GetByID(ctx, upload.UserID, *done.SubmissionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if submission.Status != models.StatusFailed {
+		t.Errorf("submission status = %s, want %s", submission.Status, models.StatusFailed)
+	}
+}
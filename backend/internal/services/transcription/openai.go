@@ -0,0 +1,133 @@
+package transcription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "whisper-1"
+)
+
+// openAI transcribes with the OpenAI audio API, or any server that
+// implements its /audio/transcriptions endpoint
+type openAI struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+func newOpenAI(cfg Config, httpClient *http.Client) *openAI {
+	p := &openAI{apiKey: cfg.APIKey, baseURL: cfg.BaseURL, model: cfg.Model, httpClient: httpClient}
+	if p.baseURL == "" {
+		p.baseURL = defaultOpenAIBaseURL
+	}
+	if p.model == "" {
+		p.model = defaultOpenAIModel
+	}
+	return p
+}
+
+// Name implements Provider
+func (p *openAI) Name() string {
+	return ProviderOpenAI
+}
+
+// openAIResponse is the verbose_json transcription response
+type openAIResponse struct {
+	Text     string  `json:"text"`
+	Language string  `json:"language"`
+	Duration float64 `json:"duration"`
+	Segments []struct {
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+		Text  string  `json:"text"`
+	} `json:"segments"`
+}
+
+// Transcribe implements Provider
+func (p *openAI) Transcribe(ctx context.Context, media Media) (*Transcript, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	fields := [][2]string{
+		{"model", p.model},
+		{"response_format", "verbose_json"},
+		{"timestamp_granularities[]", "segment"},
+	}
+	for _, field := range fields {
+		if err := form.WriteField(field[0], field[1]); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	file, err := form.CreateFormFile("file", media.Filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if _, err := file.Write(media.Data); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(p.baseURL, "/")+"/audio/transcriptions", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+
+	var resp openAIResponse
+	if err := do(p.httpClient, req, &resp); err != nil {
+		return nil, err
+	}
+
+	transcript := &Transcript{
+		Language:        resp.Language,
+		DurationSeconds: resp.Duration,
+		Segments:        make([]models.TranscriptSegment, 0, len(resp.Segments)),
+	}
+	for _, s := range resp.Segments {
+		transcript.Segments = append(transcript.Segments, models.TranscriptSegment{Start: s.Start, End: s.End, Text: strings.TrimSpace(s.Text)})
+	}
+	transcript.Text = strings.TrimSpace(resp.Text)
+	if transcript.Text == "" {
+		transcript.Text = joinSegments(transcript.Segments)
+	}
+	return transcript, nil
+}
+
+// do sends a provider request and decodes the JSON response
+func do(httpClient *http.Client, req *http.Request, out interface{}) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read transcription response: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("transcription provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to decode transcription response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+// Package transcription turns uploaded audio and video into text with a
+// speech-to-text provider, so recordings can be analyzed like typed
+// submissions.
+//
+// Uploads are transcribed in the background: the Transcriber job sends the
+// media to the configured Provider, stores the transcript with its timed
+// segments and queues a submission holding the transcript for analysis.
+package transcription
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// Supported providers
+const (
+	ProviderOpenAI   = "openai"
+	ProviderDeepgram = "deepgram"
+)
+
+// Providers lists the supported provider names
+var Providers = []string{ProviderOpenAI, ProviderDeepgram}
+
+// Media is an uploaded recording
+type Media struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Transcript is a provider's transcription of a recording
+type Transcript struct {
+	Text            string
+	Language        string
+	DurationSeconds float64
+	Segments        []models.TranscriptSegment
+}
+
+// Provider transcribes recordings
+type Provider interface {
+	// Name identifies the provider in stored transcriptions
+	Name() string
+	Transcribe(ctx context.Context, media Media) (*Transcript, error)
+}
+
+// Config selects and configures a provider
+type Config struct {
+	Provider string
+	APIKey   string
+	// BaseURL overrides the provider's endpoint, such as a self-hosted
+	// Whisper-compatible server for ProviderOpenAI
+	BaseURL string
+	Model   string
+}
+
+// ValidProvider reports whether name is a supported provider
+func ValidProvider(name string) bool {
+	return slices.Contains(Providers, name)
+}
+
+// NewProvider creates the configured provider. Check the name with
+// ValidProvider first.
+func NewProvider(cfg Config) (Provider, error) {
+	httpClient := &http.Client{Timeout: 5 * time.Minute}
+
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		return newOpenAI(cfg, httpClient), nil
+	case ProviderDeepgram:
+		return newDeepgram(cfg, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unknown transcription provider: %s", cfg.Provider)
+	}
+}
+
+// IsMediaType reports whether contentType is audio or video
+func IsMediaType(contentType string) bool {
+	return strings.HasPrefix(contentType, "audio/") || strings.HasPrefix(contentType, "video/") || contentType == "application/ogg"
+}
+
+// joinSegments builds a transcript's text from its segments
+func joinSegments(segments []models.TranscriptSegment) string {
+	texts := make([]string, 0, len(segments))
+	for _, s := range segments {
+		if text := strings.TrimSpace(s.Text); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, " ")
+}
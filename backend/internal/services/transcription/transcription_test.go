@@ -0,0 +1,165 @@
+package transcription
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		want     string
+		wantErr  bool
+	}{
+		{"openai", ProviderOpenAI, ProviderOpenAI, false},
+		{"deepgram", ProviderDeepgram, ProviderDeepgram, false},
+		{"unknown", "acme", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(Config{Provider: tt.provider, APIKey: "key"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewProvider() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && provider.Name() != tt.want {
+				t.Errorf("Name() = %q, want %q", provider.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAI_Transcribe(t *testing.T) {
+	var auth, model, filename string
+	var media []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		auth = r.Header.Get("Authorization")
+		model = r.FormValue("model")
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filename = header.Filename
+		media, _ = io.ReadAll(file)
+
+		w.Write([]byte(`{
+			"text": " Hello there. How are you? ",
+			"language": "english",
+			"duration": 3.2,
+			"segments": [
+				{"start": 0, "end": 1.1, "text": " Hello there."},
+				{"start": 1.1, "end": 3.2, "text": " How are you?"}
+			]
+		}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(Config{Provider: ProviderOpenAI, APIKey: "secret", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := provider.Transcribe(context.Background(), Media{Filename: "call.ogg", ContentType: "audio/ogg", Data: []byte("OggS")})
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+
+	if auth != "Bearer secret" || model != defaultOpenAIModel || filename != "call.ogg" || string(media) != "OggS" {
+		t.Errorf("request: auth=%q model=%q filename=%q media=%q", auth, model, filename, media)
+	}
+	if transcript.Text != "Hello there. How are you?" || transcript.Language != "english" || transcript.DurationSeconds != 3.2 {
+		t.Errorf("transcript = %+v", transcript)
+	}
+	if len(transcript.Segments) != 2 || transcript.Segments[1].Start != 1.1 || transcript.Segments[1].Text != "How are you?" {
+		t.Errorf("segments = %+v", transcript.Segments)
+	}
+}
+
+func TestDeepgram_Transcribe(t *testing.T) {
+	var auth, contentType, model string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		contentType = r.Header.Get("Content-Type")
+		model = r.URL.Query().Get("model")
+
+		w.Write([]byte(`{
+			"metadata": {"duration": 4.5},
+			"results": {
+				"channels": [{"detected_language": "de", "alternatives": [{"transcript": ""}]}],
+				"utterances": [
+					{"start": 0.2, "end": 2, "transcript": "Guten Morgen."},
+					{"start": 2.4, "end": 4.5, "transcript": "Wie geht's?"}
+				]
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewProvider(Config{Provider: ProviderDeepgram, APIKey: "secret", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transcript, err := provider.Transcribe(context.Background(), Media{Filename: "call.mp3", ContentType: "audio/mpeg", Data: []byte("ID3")})
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+
+	if auth != "Token secret" || contentType != "audio/mpeg" || model != defaultDeepgramModel {
+		t.Errorf("request: auth=%q content-type=%q model=%q", auth, contentType, model)
+	}
+	// Without a channel transcript the text is built from the utterances
+	if transcript.Text != "Guten Morgen. Wie geht's?" || transcript.Language != "de" || transcript.DurationSeconds != 4.5 {
+		t.Errorf("transcript = %+v", transcript)
+	}
+	if len(transcript.Segments) != 2 || transcript.Segments[0].Start != 0.2 {
+		t.Errorf("segments = %+v", transcript.Segments)
+	}
+}
+
+func TestTranscribe_ProviderError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error": "invalid api key"}`, http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	for _, name := range Providers {
+		t.Run(name, func(t *testing.T) {
+			provider, err := NewProvider(Config{Provider: name, APIKey: "wrong", BaseURL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := provider.Transcribe(context.Background(), Media{Filename: "a.ogg", ContentType: "audio/ogg", Data: []byte("OggS")}); err == nil {
+				t.Error("Transcribe() error = nil, want the provider's error")
+			}
+		})
+	}
+}
+
+func TestIsMediaType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"audio/mpeg", true},
+		{"video/mp4", true},
+		{"application/ogg", true},
+		{"text/plain", false},
+		{"application/octet-stream", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := IsMediaType(tt.contentType); got != tt.want {
+				t.Errorf("IsMediaType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Package uploads cleans up direct uploads: files put into object storage
+// with a presigned URL that were never completed, or whose submission has
+// since been deleted.
+package uploads
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the upload purge job in the queue
+	JobType = "uploads.purge"
+
+	// Interval is how often abandoned uploads are purged. Pending uploads
+	// expire an hour after they are issued, so an object lingers for at
+	// most two.
+	Interval = time.Hour
+
+	// BatchSize is how many uploads one purge statement deletes
+	BatchSize = 500
+
+	// maxBatches stops a run after 50,000 uploads. That many only go
+	// unclaimed when clients misbehave, and what is left waits an hour.
+	maxBatches = 100
+)
+
+// Purger deletes abandoned uploads and their objects; *models.UploadStore
+// implements it
+type Purger interface {
+	Purge(ctx context.Context, limit int) (int, error)
+}
+
+// PurgeJob deletes abandoned uploads
+type PurgeJob struct {
+	store Purger
+}
+
+// NewPurgeJob creates a new upload purge job
+func NewPurgeJob(store Purger) *PurgeJob {
+	return &PurgeJob{store: store}
+}
+
+// Handle implements queue.Handler for the upload purge job. It purges in
+// batches until nothing abandoned is left.
+func (j *PurgeJob) Handle(ctx context.Context, job *queue.Job) error {
+	total := 0
+	for range maxBatches {
+		purged, err := j.store.Purge(ctx, BatchSize)
+		total += purged
+		if err != nil {
+			slog.Error("Upload purge stopped", "purged", total, "error", err)
+			return err
+		}
+		if purged < BatchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		slog.Info("Abandoned uploads purged", "count", total)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/models/memstore"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+// failingPurger fails every purge
+type failingPurger struct{}
+
+func (failingPurger) Purge(ctx context.Context, limit int) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestPurgeJob_Handle(t *testing.T) {
+	ctx := context.Background()
+	store := memstore.NewUploadStore()
+	userID := uuid.New()
+
+	// One upload past its expiry and one still pending
+	expired, _ := store.Create(ctx, &models.Upload{UserID: userID, Filename: "old.pdf"}, "up_old", time.Now().Add(-time.Minute))
+	store.Create(ctx, &models.Upload{UserID: userID, Filename: "new.pdf"}, "up_new", time.Now().Add(time.Hour))
+
+	if err := NewPurgeJob(store).Handle(ctx, &queue.Job{Type: JobType}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Attach(ctx, userID, expired.ID, uuid.New()); err == nil {
+		t.Error("expired upload was attached after the purge, want it deleted")
+	}
+	if _, err := store.GetPending(ctx, userID, "up_new"); err != nil {
+		t.Errorf("GetPending() after the purge error = %v, want the pending upload kept", err)
+	}
+
+	if err := NewPurgeJob(failingPurger{}).Handle(ctx, &queue.Job{Type: JobType}); err == nil {
+		t.Error("Handle() error = nil, want the purge failure")
+	}
+}
@@ -0,0 +1,72 @@
+// Package usage rolls submission and model usage up into daily per-user
+// totals for the organization usage reports.
+package usage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+)
+
+const (
+	// JobType identifies the usage rollup job in the queue
+	JobType = "usage.rollup"
+
+	// DefaultDays is how many days, including today, a run rebuilds.
+	// Yesterday is included so usage recorded around midnight, or while a
+	// run failed, is still counted.
+	DefaultDays = 2
+
+	// MaxDays bounds a backfill requested in the job payload
+	MaxDays = 400
+)
+
+// Payload optionally widens a run to backfill older days
+type Payload struct {
+	Days int `json:"days,omitempty"`
+}
+
+// RollupJob rebuilds the recent days of the usage rollups
+type RollupJob struct {
+	store *models.UsageStore
+	now   func() time.Time
+}
+
+// NewRollupJob creates a new usage rollup job
+func NewRollupJob(store *models.UsageStore) *RollupJob {
+	return &RollupJob{store: store, now: time.Now}
+}
+
+// Handle implements queue.Handler for the usage rollup job
+func (j *RollupJob) Handle(ctx context.Context, job *queue.Job) error {
+	var payload Payload
+	if err := job.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid usage rollup payload: %w", err)
+	}
+
+	days := DefaultDays
+	if payload.Days > 0 {
+		days = min(payload.Days, MaxDays)
+	}
+
+	from, to := window(j.now(), days)
+	rows, err := j.store.Rollup(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Usage rolled up", "from", from.Format(time.DateOnly), "to", to.Format(time.DateOnly), "rows", rows)
+	return nil
+}
+
+// window returns the UTC days to rebuild: the last days days up to and
+// including today's
+func window(now time.Time, days int) (from, to time.Time) {
+	y, m, d := now.UTC().Date()
+	to = time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+	return to.AddDate(0, 0, -days), to
+}
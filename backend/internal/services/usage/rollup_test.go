@@ -0,0 +1,50 @@
+package usage
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		days     int
+		wantFrom string
+		wantTo   string
+	}{
+		{
+			name:     "yesterday and today",
+			now:      time.Date(2026, 3, 10, 2, 30, 0, 0, time.UTC),
+			days:     DefaultDays,
+			wantFrom: "2026-03-09",
+			wantTo:   "2026-03-11",
+		},
+		{
+			name:     "converts to UTC",
+			now:      time.Date(2026, 3, 10, 20, 0, 0, 0, time.FixedZone("EST", -5*3600)),
+			days:     1,
+			wantFrom: "2026-03-11",
+			wantTo:   "2026-03-12",
+		},
+		{
+			name:     "across a month",
+			now:      time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+			days:     3,
+			wantFrom: "2026-02-27",
+			wantTo:   "2026-03-02",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := window(tt.now, tt.days)
+			if got := from.Format(time.DateOnly); got != tt.wantFrom {
+				t.Errorf("window() from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format(time.DateOnly); got != tt.wantTo {
+				t.Errorf("window() to = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
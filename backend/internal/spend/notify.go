@@ -0,0 +1,37 @@
+package spend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Mailer sends spend budget emails; *notifications.Notifier implements it
+type Mailer interface {
+	SendSpendBudget(ctx context.Context, to, budget, spent, limit string, resetsAt time.Time, rejected bool) error
+}
+
+// emailNotifier emails every operator
+type emailNotifier struct {
+	mailer   Mailer
+	to       []string
+	rejected bool
+}
+
+// NewEmailNotifier emails the operators at to, telling them whether new
+// analyses are rejected or held in mode
+func NewEmailNotifier(mailer Mailer, to []string, mode string) Notifier {
+	return emailNotifier{mailer: mailer, to: to, rejected: mode == ModeReject}
+}
+
+// NotifyBudget implements Notifier
+func (n emailNotifier) NotifyBudget(ctx context.Context, exceeded Exceeded) error {
+	var errs []error
+	for _, to := range n.to {
+		if err := n.mailer.SendSpendBudget(ctx, to, exceeded.Name(), FormatUSD(exceeded.SpentMicros), FormatUSD(exceeded.LimitMicros), exceeded.ResetsAt.Time, n.rejected); err != nil {
+			errs = append(errs, fmt.Errorf("failed to email %s: %w", to, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,282 @@
+// Package spend enforces daily and monthly budgets on model spend, across
+// the whole deployment and per organization, so a runaway integration or
+// a busy month can't run up a surprise bill. Spend is what analyses and
+// thread replies recorded at the configured prices; calls already running
+// when a budget runs out still finish, so it can be overshot slightly.
+package spend
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// What happens to new analyses while a budget is exceeded. Analyses
+// already queued are always held.
+const (
+	// ModeHold accepts new analyses and holds them in the queue until the
+	// budget allows them
+	ModeHold = "hold"
+
+	// ModeReject refuses new analyses with an error
+	ModeReject = "reject"
+)
+
+// Budget periods, in UTC
+const (
+	PeriodDaily   = "daily"
+	PeriodMonthly = "monthly"
+)
+
+// Scopes a budget applies to
+const (
+	ScopeGlobal = "global"
+	ScopeOrg    = "organization"
+)
+
+// totalTTL is how long the deployment-wide spend is reused between checks.
+// It is summed over every call this month, which is too slow to do for
+// each analysis.
+const totalTTL = 30 * time.Second
+
+// Limits caps deployment-wide spend in millionths of a US dollar. Zero
+// leaves a period uncapped.
+type Limits struct {
+	DailyMicros   int64
+	MonthlyMicros int64
+}
+
+// Exceeded describes a budget that has been used up
+type Exceeded struct {
+	Scope       string         `json:"scope"`
+	OrgID       *uuid.UUID     `json:"org_id,omitempty"`
+	OrgName     string         `json:"org_name,omitempty"`
+	Period      string         `json:"period"`
+	SpentMicros int64          `json:"spent_micros"`
+	LimitMicros int64          `json:"limit_micros"`
+	ResetsAt    timestamp.Time `json:"resets_at"`
+}
+
+// Name names the budget, such as "the daily spend budget of Acme"
+func (e *Exceeded) Name() string {
+	name := "the " + e.Period + " spend budget"
+	if e.Scope == ScopeOrg {
+		name += " of " + e.OrgName
+	}
+	return name
+}
+
+// Error describes the budget for users turned away by it
+func (e *Exceeded) Error() string {
+	return fmt.Sprintf("%s (%s) is used up; analyses resume %s", e.Name(), FormatUSD(e.LimitMicros), e.ResetsAt.Format("January 2, 2006 15:04 MST"))
+}
+
+// key identifies the budget and period, for notifying once per period
+func (e *Exceeded) key() string {
+	scope := e.Scope
+	if e.OrgID != nil {
+		scope += ":" + e.OrgID.String()
+	}
+	return fmt.Sprintf("spend:%s:%s:%d", scope, e.Period, e.ResetsAt.Unix())
+}
+
+// FormatUSD formats millionths of a US dollar as dollars and cents
+func FormatUSD(micros int64) string {
+	return fmt.Sprintf("$%.2f", float64(micros)/1e6)
+}
+
+// Store reads recorded spend; *models.SpendStore implements it
+type Store interface {
+	Total(ctx context.Context, day, month time.Time) (daily, monthly int64, err error)
+	ForUser(ctx context.Context, userID uuid.UUID, day, month time.Time) ([]models.OrgSpend, error)
+}
+
+// Notifier tells operators a budget has been used up
+type Notifier interface {
+	NotifyBudget(ctx context.Context, exceeded Exceeded) error
+}
+
+// Locker lets one of several replicas claim a notification;
+// *cache.Locker implements it
+type Locker interface {
+	Claim(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// Guard checks users' analyses against the budgets
+type Guard struct {
+	store     Store
+	limits    Limits
+	mode      string
+	notifiers []Notifier
+	locker    Locker
+	now       func() time.Time
+
+	mu      sync.Mutex
+	total   [2]int64 // daily, monthly
+	totalAt time.Time
+	// notified maps the budgets notified this period to when it ends
+	notified map[string]time.Time
+}
+
+// New creates a guard enforcing limits deployment-wide and the budgets
+// stored on organizations, in the given mode
+func New(store Store, limits Limits, mode string) *Guard {
+	if mode == "" {
+		mode = ModeHold
+	}
+	return &Guard{
+		store:    store,
+		limits:   limits,
+		mode:     mode,
+		now:      time.Now,
+		notified: make(map[string]time.Time),
+	}
+}
+
+// WithNotifier adds a notifier that is told once per period when a
+// budget is used up
+func (g *Guard) WithNotifier(n Notifier) *Guard {
+	g.notifiers = append(g.notifiers, n)
+	return g
+}
+
+// WithLocker makes replicas sharing the locker notify once between them
+// instead of once each
+func (g *Guard) WithLocker(locker Locker) *Guard {
+	g.locker = locker
+	return g
+}
+
+// Rejects reports whether new analyses are refused while over budget
+func (g *Guard) Rejects() bool {
+	return g.mode == ModeReject
+}
+
+// Check returns the first budget userID's analyses are over, or nil. The
+// deployment-wide budget is checked first, then each of the user's
+// organizations.
+func (g *Guard) Check(ctx context.Context, userID uuid.UUID) (*Exceeded, error) {
+	now := g.now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	if g.limits.DailyMicros > 0 || g.limits.MonthlyMicros > 0 {
+		daily, monthly, err := g.totalSpend(ctx, now, day, month)
+		if err != nil {
+			return nil, err
+		}
+		if exceeded := over(ScopeGlobal, g.limits.DailyMicros, g.limits.MonthlyMicros, daily, monthly, day, month); exceeded != nil {
+			g.notify(ctx, exceeded)
+			return exceeded, nil
+		}
+	}
+
+	orgs, err := g.store.ForUser(ctx, userID, day, month)
+	if err != nil {
+		return nil, err
+	}
+	for _, org := range orgs {
+		exceeded := over(ScopeOrg, deref(org.DailyBudgetMicros), deref(org.MonthlyBudgetMicros), org.DailyMicros, org.MonthlyMicros, day, month)
+		if exceeded != nil {
+			orgID := org.OrgID
+			exceeded.OrgID = &orgID
+			exceeded.OrgName = org.OrgName
+			g.notify(ctx, exceeded)
+			return exceeded, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// totalSpend returns the deployment-wide spend, reusing a recent read
+func (g *Guard) totalSpend(ctx context.Context, now, day, month time.Time) (int64, int64, error) {
+	g.mu.Lock()
+	if now.Sub(g.totalAt) < totalTTL && !g.totalAt.Before(day) {
+		daily, monthly := g.total[0], g.total[1]
+		g.mu.Unlock()
+		return daily, monthly, nil
+	}
+	g.mu.Unlock()
+
+	daily, monthly, err := g.store.Total(ctx, day, month)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	g.mu.Lock()
+	g.total, g.totalAt = [2]int64{daily, monthly}, now
+	g.mu.Unlock()
+	return daily, monthly, nil
+}
+
+// notify tells every notifier about a budget the first time it is seen
+// used up in its period. Failures are logged, not returned.
+func (g *Guard) notify(ctx context.Context, exceeded *Exceeded) {
+	key := exceeded.key()
+	now := g.now()
+
+	g.mu.Lock()
+	_, seen := g.notified[key]
+	for k, resetsAt := range g.notified {
+		if !resetsAt.After(now) {
+			delete(g.notified, k)
+		}
+	}
+	g.notified[key] = exceeded.ResetsAt.Time
+	g.mu.Unlock()
+	if seen {
+		return
+	}
+
+	// Another replica may have notified already; if that can't be told,
+	// notify anyway
+	if g.locker != nil {
+		claimed, err := g.locker.Claim(ctx, key, exceeded.ResetsAt.Sub(now)+time.Hour)
+		if err != nil {
+			slog.Warn("Failed to claim spend budget notification", "key", key, "error", err)
+		} else if !claimed {
+			return
+		}
+	}
+
+	slog.Warn("Spend budget exceeded",
+		"scope", exceeded.Scope,
+		"org_id", exceeded.OrgID,
+		"period", exceeded.Period,
+		"spent", FormatUSD(exceeded.SpentMicros),
+		"limit", FormatUSD(exceeded.LimitMicros),
+	)
+	for _, n := range g.notifiers {
+		if err := n.NotifyBudget(ctx, *exceeded); err != nil {
+			slog.Error("Failed to send spend budget notification", "key", key, "error", err)
+		}
+	}
+}
+
+// over returns the first of the daily and monthly caps spend has reached,
+// or nil. A zero cap is no cap.
+func over(scope string, dailyLimit, monthlyLimit, daily, monthly int64, day, month time.Time) *Exceeded {
+	switch {
+	case dailyLimit > 0 && daily >= dailyLimit:
+		return &Exceeded{Scope: scope, Period: PeriodDaily, SpentMicros: daily, LimitMicros: dailyLimit, ResetsAt: timestamp.New(day.AddDate(0, 0, 1))}
+	case monthlyLimit > 0 && monthly >= monthlyLimit:
+		return &Exceeded{Scope: scope, Period: PeriodMonthly, SpentMicros: monthly, LimitMicros: monthlyLimit, ResetsAt: timestamp.New(month.AddDate(0, 1, 0))}
+	}
+	return nil
+}
+
+// deref returns *p, or zero for nil
+func deref(p *int64) int64 {
+	if p == nil {
+		return 0
+	}
+	return *p
+}
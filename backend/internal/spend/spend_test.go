@@ -0,0 +1,191 @@
+package spend
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// fakeStore returns fixed spend and counts reads of the total
+type fakeStore struct {
+	daily, monthly int64
+	orgs           []models.OrgSpend
+	totalReads     int
+}
+
+func (s *fakeStore) Total(ctx context.Context, day, month time.Time) (int64, int64, error) {
+	s.totalReads++
+	return s.daily, s.monthly, nil
+}
+
+func (s *fakeStore) ForUser(ctx context.Context, userID uuid.UUID, day, month time.Time) ([]models.OrgSpend, error) {
+	return s.orgs, nil
+}
+
+// recordingNotifier records the budgets it is told about
+type recordingNotifier struct {
+	exceeded []Exceeded
+}
+
+func (n *recordingNotifier) NotifyBudget(ctx context.Context, exceeded Exceeded) error {
+	n.exceeded = append(n.exceeded, exceeded)
+	return nil
+}
+
+// stubLocker grants or refuses every claim
+type stubLocker struct {
+	claimed bool
+}
+
+func (l stubLocker) Claim(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return l.claimed, nil
+}
+
+// recordingMailer records the spend budget emails it is asked to send
+type recordingMailer struct {
+	to       []string
+	budgets  []string
+	rejected bool
+}
+
+func (m *recordingMailer) SendSpendBudget(ctx context.Context, to, budget, spent, limit string, resetsAt time.Time, rejected bool) error {
+	m.to = append(m.to, to)
+	m.budgets = append(m.budgets, budget)
+	m.rejected = rejected
+	return nil
+}
+
+func micros(n int64) *int64 { return &n }
+
+func TestGuard_Check(t *testing.T) {
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+	acme := models.OrgSpend{OrgID: uuid.New(), OrgName: "Acme", DailyMicros: 4_000_000, MonthlyMicros: 120_000_000}
+
+	tests := []struct {
+		name         string
+		limits       Limits
+		store        *fakeStore
+		wantScope    string
+		wantPeriod   string
+		wantResetsAt time.Time
+	}{
+		{"no budgets", Limits{}, &fakeStore{daily: 1e9, monthly: 1e9}, "", "", time.Time{}},
+		{"under global budget", Limits{DailyMicros: 10_000_000}, &fakeStore{daily: 9_999_999}, "", "", time.Time{}},
+		{"global daily", Limits{DailyMicros: 10_000_000, MonthlyMicros: 500_000_000}, &fakeStore{daily: 10_000_000, monthly: 10_000_000}, ScopeGlobal, PeriodDaily, time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"global monthly", Limits{MonthlyMicros: 500_000_000}, &fakeStore{daily: 1, monthly: 600_000_000}, ScopeGlobal, PeriodMonthly, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"organization monthly", Limits{}, &fakeStore{orgs: []models.OrgSpend{
+			{OrgID: uuid.New(), OrgName: "Roomy", SpendBudget: models.SpendBudget{DailyBudgetMicros: micros(50_000_000)}, DailyMicros: 4_000_000},
+			{OrgID: acme.OrgID, OrgName: acme.OrgName, SpendBudget: models.SpendBudget{DailyBudgetMicros: micros(5_000_000), MonthlyBudgetMicros: micros(100_000_000)}, DailyMicros: acme.DailyMicros, MonthlyMicros: acme.MonthlyMicros},
+		}}, ScopeOrg, PeriodMonthly, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guard := New(tt.store, tt.limits, ModeHold)
+			guard.now = func() time.Time { return now }
+
+			exceeded, err := guard.Check(context.Background(), uuid.New())
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if tt.wantScope == "" {
+				if exceeded != nil {
+					t.Errorf("Check() = %+v, want nil", exceeded)
+				}
+				return
+			}
+			if exceeded == nil || exceeded.Scope != tt.wantScope || exceeded.Period != tt.wantPeriod || !exceeded.ResetsAt.Equal(tt.wantResetsAt) {
+				t.Fatalf("Check() = %+v, want %s %s resetting at %v", exceeded, tt.wantScope, tt.wantPeriod, tt.wantResetsAt)
+			}
+			if tt.wantScope == ScopeOrg && (exceeded.OrgID == nil || *exceeded.OrgID != acme.OrgID || !strings.Contains(exceeded.Error(), "monthly spend budget of Acme ($100.00)")) {
+				t.Errorf("Check() = %v, want Acme's monthly budget", exceeded)
+			}
+		})
+	}
+}
+
+func TestGuard_Check_ReusesTotal(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 59, 50, 0, time.UTC)
+	store := &fakeStore{daily: 1}
+	guard := New(store, Limits{DailyMicros: 10_000_000}, ModeHold)
+	guard.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if _, err := guard.Check(context.Background(), uuid.New()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if store.totalReads != 1 {
+		t.Errorf("total read %d times, want 1", store.totalReads)
+	}
+
+	// A new day starts over within the TTL
+	now = now.Add(15 * time.Second)
+	if _, err := guard.Check(context.Background(), uuid.New()); err != nil {
+		t.Fatal(err)
+	}
+	if store.totalReads != 2 {
+		t.Errorf("total read %d times after midnight, want 2", store.totalReads)
+	}
+}
+
+func TestGuard_NotifiesOncePerPeriod(t *testing.T) {
+	now := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+	store := &fakeStore{daily: 20_000_000}
+
+	t.Run("once per replica", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		guard := New(store, Limits{DailyMicros: 10_000_000}, ModeHold).WithNotifier(notifier)
+		guard.now = func() time.Time { return now }
+
+		for i := 0; i < 3; i++ {
+			guard.Check(context.Background(), uuid.New())
+		}
+		if len(notifier.exceeded) != 1 {
+			t.Fatalf("notified %d times, want 1", len(notifier.exceeded))
+		}
+
+		// The next day's budget is a new one
+		guard.now = func() time.Time { return now.Add(24 * time.Hour) }
+		guard.totalAt = time.Time{}
+		guard.Check(context.Background(), uuid.New())
+		if len(notifier.exceeded) != 2 {
+			t.Errorf("notified %d times over two days, want 2", len(notifier.exceeded))
+		}
+	})
+
+	t.Run("claimed by another replica", func(t *testing.T) {
+		notifier := &recordingNotifier{}
+		guard := New(store, Limits{DailyMicros: 10_000_000}, ModeHold).WithNotifier(notifier).WithLocker(stubLocker{claimed: false})
+		guard.now = func() time.Time { return now }
+
+		guard.Check(context.Background(), uuid.New())
+		if len(notifier.exceeded) != 0 {
+			t.Errorf("notified %d times, want none", len(notifier.exceeded))
+		}
+	})
+}
+
+func TestEmailNotifier(t *testing.T) {
+	mailer := &recordingMailer{}
+	notifier := NewEmailNotifier(mailer, []string{"ops@example.com", "cfo@example.com"}, ModeReject)
+
+	err := notifier.NotifyBudget(context.Background(), Exceeded{Scope: ScopeGlobal, Period: PeriodDaily, SpentMicros: 20_010_000, LimitMicros: 20_000_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.to) != 2 || mailer.budgets[0] != "the daily spend budget" || !mailer.rejected {
+		t.Errorf("emails = %v %v rejected=%v, want the daily budget to both operators", mailer.to, mailer.budgets, mailer.rejected)
+	}
+}
+
+func TestFormatUSD(t *testing.T) {
+	if got := FormatUSD(12_345_678); got != "$12.35" {
+		t.Errorf("FormatUSD() = %q, want $12.35", got)
+	}
+}
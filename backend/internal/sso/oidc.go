@@ -0,0 +1,200 @@
+// Package sso signs organization members in through their identity
+// provider with OpenID Connect.
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// metadataTTL is how long a provider's discovery document is cached. Its
+// signing keys are fetched again whenever a token names an unknown one,
+// which is how rotation is picked up.
+const metadataTTL = time.Hour
+
+// ErrInvalidToken is returned when the provider's ID token doesn't verify
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Identity is who the identity provider says signed in
+type Identity struct {
+	// Subject identifies the user at the provider; unlike the email
+	// address it never changes
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Groups        []string
+}
+
+// provider is a provider's cached discovery document and key set
+type provider struct {
+	*oidc.Provider
+	fetchedAt time.Time
+}
+
+// OIDC signs users in with OpenID Connect's authorization code flow.
+// Providers are discovered with go-oidc and cached by issuer.
+type OIDC struct {
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	providers map[string]*provider
+}
+
+// NewOIDC creates an OpenID Connect client
+func NewOIDC() *OIDC {
+	return &OIDC{
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		providers:  make(map[string]*provider),
+	}
+}
+
+// WithHTTPClient makes requests to providers with client and returns the
+// OIDC client
+func (o *OIDC) WithHTTPClient(client *http.Client) *OIDC {
+	o.httpClient = client
+	return o
+}
+
+// AuthCodeURL returns the provider URL to send the user to. The provider
+// sends them back to redirectURI with a code and state.
+func (o *OIDC) AuthCodeURL(ctx context.Context, config *models.SSOConfig, redirectURI, state, nonce string) (string, error) {
+	p, err := o.provider(ctx, config.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	return oauth2Config(p, config, redirectURI).AuthCodeURL(state, oidc.Nonce(nonce)), nil
+}
+
+// Exchange redeems the code the provider sent back for the user's
+// identity, verifying the ID token against the provider's keys, the
+// client ID and the nonce sent with AuthCodeURL
+func (o *OIDC) Exchange(ctx context.Context, config *models.SSOConfig, redirectURI, code, nonce string) (*Identity, error) {
+	p, err := o.provider(ctx, config.Issuer)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := oauth2Config(p, config, redirectURI).Exchange(o.clientContext(ctx), code)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem code: %w", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: token response has no ID token", ErrInvalidToken)
+	}
+
+	idToken, err := p.Verifier(&oidc.Config{ClientID: config.ClientID}).Verify(o.clientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	// The nonce ties the token to the sign-in we began, so a token
+	// replayed from another one is refused
+	if idToken.Nonce == "" || idToken.Nonce != nonce {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+	if idToken.Subject == "" {
+		return nil, fmt.Errorf("%w: no subject", ErrInvalidToken)
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return &Identity{
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(stringClaim(claims, "email"))),
+		EmailVerified: boolClaim(claims, "email_verified"),
+		Groups:        stringsClaim(claims, config.GroupsClaim),
+	}, nil
+}
+
+// oauth2Config is the code flow configuration of an organization's
+// provider
+func oauth2Config(p *provider, config *models.SSOConfig, redirectURI string) *oauth2.Config {
+	endpoint := p.Endpoint()
+	// client_secret_basic, which providers must support
+	endpoint.AuthStyle = oauth2.AuthStyleInHeader
+
+	return &oauth2.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		Endpoint:     endpoint,
+		RedirectURL:  redirectURI,
+		Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+	}
+}
+
+// provider returns an issuer's provider, discovering it when it isn't
+// cached or has gone stale. go-oidc refuses a discovery document for any
+// other issuer, whose keys could otherwise vouch for tokens from anywhere.
+func (o *OIDC) provider(ctx context.Context, issuer string) (*provider, error) {
+	o.mu.Lock()
+	p, ok := o.providers[issuer]
+	o.mu.Unlock()
+	if ok && time.Since(p.fetchedAt) < metadataTTL {
+		return p, nil
+	}
+
+	discovered, err := oidc.NewProvider(o.clientContext(ctx), issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover provider: %w", err)
+	}
+
+	p = &provider{Provider: discovered, fetchedAt: time.Now()}
+	o.mu.Lock()
+	o.providers[issuer] = p
+	o.mu.Unlock()
+	return p, nil
+}
+
+// clientContext makes go-oidc and oauth2 send their requests with the
+// client's HTTP client
+func (o *OIDC) clientContext(ctx context.Context) context.Context {
+	return context.WithValue(oidc.ClientContext(ctx, o.httpClient), oauth2.HTTPClient, o.httpClient)
+}
+
+// stringClaim reads a string claim
+func stringClaim(claims map[string]interface{}, name string) string {
+	s, _ := claims[name].(string)
+	return s
+}
+
+// boolClaim reads a boolean claim, which some providers send as a string
+func boolClaim(claims map[string]interface{}, name string) bool {
+	switch v := claims[name].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// stringsClaim reads a claim holding a list of strings, or a single one
+func stringsClaim(claims map[string]interface{}, name string) []string {
+	switch v := claims[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
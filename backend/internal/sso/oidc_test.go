@@ -0,0 +1,191 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/sfumato00/content-analyzer/internal/models"
+)
+
+// testProvider is an identity provider that issues an ID token with the
+// claims it is given for the code "good"
+type testProvider struct {
+	server *httptest.Server
+	claims jwt.MapClaims
+	// signer signs ID tokens; the provider's published key unless a test
+	// swaps in another
+	signer *rsa.PrivateKey
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{signer: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                p.server.URL,
+			"authorization_endpoint":                p.server.URL + "/authorize",
+			"token_endpoint":                        p.server.URL + "/token",
+			"jwks_uri":                              p.server.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key-1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, _ := r.BasicAuth()
+		if clientID != "client" || secret != "s3cret" || r.PostFormValue("code") != "good" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{
+			"id_token":     signWith(t, p.signer, p.claims),
+			"access_token": "unused",
+			"token_type":   "Bearer",
+		})
+	})
+
+	p.server = httptest.NewTLSServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *testProvider) config() *models.SSOConfig {
+	return &models.SSOConfig{Issuer: p.server.URL, ClientID: "client", ClientSecret: "s3cret", GroupsClaim: "groups"}
+}
+
+func (p *testProvider) client() *OIDC {
+	return NewOIDC().WithHTTPClient(p.server.Client())
+}
+
+// validClaims are an ID token's claims for the test provider
+func (p *testProvider) validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":            p.server.URL,
+		"aud":            "client",
+		"sub":            "user-123",
+		"email":          "Jane@Example.com",
+		"email_verified": true,
+		"groups":         []string{"engineering", "admins"},
+		"nonce":          "nonce-1",
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestOIDC_AuthCodeURL(t *testing.T) {
+	p := newTestProvider(t)
+
+	raw, err := p.client().AuthCodeURL(context.Background(), p.config(), "https://app.example.com/sso/callback", "state-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != "client" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" || q.Get("response_type") != "code" {
+		t.Errorf("AuthCodeURL() = %s", raw)
+	}
+}
+
+func TestOIDC_Exchange(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(claims jwt.MapClaims)
+		code    string
+		wantErr bool
+	}{
+		{name: "valid", modify: func(jwt.MapClaims) {}},
+		{name: "single group as a string", modify: func(c jwt.MapClaims) { c["groups"] = "admins" }},
+		{name: "bad code", modify: func(jwt.MapClaims) {}, code: "bad", wantErr: true},
+		{name: "wrong audience", modify: func(c jwt.MapClaims) { c["aud"] = "another-client" }, wantErr: true},
+		{name: "wrong issuer", modify: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, wantErr: true},
+		{name: "expired", modify: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, wantErr: true},
+		{name: "replayed nonce", modify: func(c jwt.MapClaims) { c["nonce"] = "nonce-0" }, wantErr: true},
+		{name: "no subject", modify: func(c jwt.MapClaims) { delete(c, "sub") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestProvider(t)
+			p.claims = p.validClaims()
+			tt.modify(p.claims)
+			code := tt.code
+			if code == "" {
+				code = "good"
+			}
+
+			identity, err := p.client().Exchange(context.Background(), p.config(), "https://app.example.com/sso/callback", code, "nonce-1")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Exchange() = %+v, want an error", identity)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Exchange() error = %v", err)
+			}
+			if identity.Subject != "user-123" || identity.Email != "jane@example.com" || !identity.EmailVerified || !slices.Contains(identity.Groups, "admins") {
+				t.Errorf("Exchange() = %+v", identity)
+			}
+		})
+	}
+}
+
+func TestOIDC_ExchangeWrongKey(t *testing.T) {
+	// Tokens signed by anyone but the provider are refused, even with
+	// the provider's key ID
+	p := newTestProvider(t)
+	p.claims = p.validClaims()
+	p.signer, _ = rsa.GenerateKey(rand.Reader, 2048)
+
+	_, err := p.client().Exchange(context.Background(), p.config(), "https://app.example.com/sso/callback", "good", "nonce-1")
+	if !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Exchange() error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestOIDC_DiscoveryIssuerMismatch(t *testing.T) {
+	p := newTestProvider(t)
+	config := p.config()
+	// The discovery document names the server's own URL, not this one
+	config.Issuer = p.server.URL + "/"
+
+	if _, err := p.client().AuthCodeURL(context.Background(), config, "https://app.example.com/sso/callback", "s", "n"); err == nil {
+		t.Error("AuthCodeURL() error = nil, want an issuer mismatch")
+	}
+}
+
+// signWith signs claims as key-1 with key
+func signWith(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
@@ -0,0 +1,120 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+)
+
+const (
+	// StateTTL is how long a user has to sign in at the provider
+	StateTTL = 10 * time.Minute
+
+	stateKeyPrefix = "auth:sso_state:"
+)
+
+// ErrInvalidState is returned for unknown, expired and reused states, and
+// for states finished by a browser other than the one that began them
+var ErrInvalidState = errors.New("invalid or expired sign-in state")
+
+// Store keeps sign-ins in flight; *cache.Cache implements it
+type Store interface {
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Take(ctx context.Context, key string) (string, error)
+}
+
+// States remembers the sign-ins sent to a provider, so the callback can
+// only complete one we started. The state guards against forged
+// callbacks and the nonce against replayed ID tokens. Each sign-in is
+// also bound to the browser that began it by a secret kept in a cookie,
+// so an attacker can't have a victim finish a sign-in they started.
+type States struct {
+	store Store
+}
+
+// NewStates creates a sign-in state store
+func NewStates(store Store) *States {
+	return &States{store: store}
+}
+
+// pending is a sign-in in flight. Only a hash of the browser's secret is
+// kept.
+type pending struct {
+	OrgID   uuid.UUID `json:"org_id"`
+	Nonce   string    `json:"nonce"`
+	Binding string    `json:"binding"`
+}
+
+// Begin starts a sign-in to an organization and returns the state and
+// nonce to send to its provider, and the secret for the browser to bring
+// back to Finish
+func (s *States) Begin(ctx context.Context, orgID uuid.UUID) (state, nonce, binding string, err error) {
+	if state, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+	if nonce, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+	if binding, err = randomString(); err != nil {
+		return "", "", "", err
+	}
+
+	data, err := json.Marshal(pending{OrgID: orgID, Nonce: nonce, Binding: hashBinding(binding)})
+	if err != nil {
+		return "", "", "", fmt.Errorf("failed to encode sign-in state: %w", err)
+	}
+	if err := s.store.Set(ctx, stateKeyPrefix+state, string(data), StateTTL); err != nil {
+		return "", "", "", fmt.Errorf("failed to save sign-in state: %w", err)
+	}
+
+	return state, nonce, binding, nil
+}
+
+// Finish completes a sign-in and returns its organization and nonce.
+// binding is the secret Begin gave the browser. A state can be finished
+// once: it is taken in one step, so concurrent callbacks can't both use
+// it, and a wrong binding uses it up too.
+func (s *States) Finish(ctx context.Context, state, binding string) (orgID uuid.UUID, nonce string, err error) {
+	data, err := s.store.Take(ctx, stateKeyPrefix+state)
+	if err != nil {
+		if errors.Is(err, cache.ErrNotFound) {
+			return uuid.Nil, "", ErrInvalidState
+		}
+		return uuid.Nil, "", fmt.Errorf("failed to get sign-in state: %w", err)
+	}
+
+	var p pending
+	if err := json.Unmarshal([]byte(data), &p); err != nil {
+		return uuid.Nil, "", ErrInvalidState
+	}
+	if subtle.ConstantTimeCompare([]byte(p.Binding), []byte(hashBinding(binding))) != 1 {
+		return uuid.Nil, "", ErrInvalidState
+	}
+	return p.OrgID, p.Nonce, nil
+}
+
+// hashBinding hashes a browser's secret for storage
+func hashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomString returns 32 random bytes, base64url encoded
+func randomString() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate sign-in state: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
@@ -0,0 +1,87 @@
+package sso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+)
+
+// memStore is an in-memory Store without expiry
+type memStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[string]string)
+	}
+	s.values[key] = fmt.Sprint(value)
+	return nil
+}
+
+func (s *memStore) Take(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	if !ok {
+		return "", cache.ErrNotFound
+	}
+	delete(s.values, key)
+	return value, nil
+}
+
+func TestStates(t *testing.T) {
+	ctx := context.Background()
+	states := NewStates(&memStore{})
+	orgID := uuid.New()
+
+	state, nonce, binding, err := states.Begin(ctx, orgID)
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	if state == nonce || state == binding {
+		t.Error("Begin() returned the same state, nonce or binding")
+	}
+
+	gotOrg, gotNonce, err := states.Finish(ctx, state, binding)
+	if err != nil || gotOrg != orgID || gotNonce != nonce {
+		t.Fatalf("Finish() = %s, %q, %v", gotOrg, gotNonce, err)
+	}
+
+	// A state finishes once
+	if _, _, err := states.Finish(ctx, state, binding); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Finish() again error = %v, want %v", err, ErrInvalidState)
+	}
+	if _, _, err := states.Finish(ctx, "forged", binding); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Finish(forged) error = %v, want %v", err, ErrInvalidState)
+	}
+}
+
+func TestStates_OtherBrowser(t *testing.T) {
+	ctx := context.Background()
+	states := NewStates(&memStore{})
+
+	state, _, binding, err := states.Begin(ctx, uuid.New())
+	if err != nil {
+		t.Fatalf("Begin() error = %v", err)
+	}
+	for _, other := range []string{"", "other-browser"} {
+		if _, _, err := states.Finish(ctx, state, other); !errors.Is(err, ErrInvalidState) {
+			t.Errorf("Finish(%q) error = %v, want %v", other, err, ErrInvalidState)
+		}
+	}
+	// The wrong browser used the state up
+	if _, _, err := states.Finish(ctx, state, binding); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("Finish() after a wrong binding error = %v, want %v", err, ErrInvalidState)
+	}
+}
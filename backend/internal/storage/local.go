@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// LocalPathPrefix is where the API serves the presigned URLs of Local
+	LocalPathPrefix = "/storage/"
+
+	// maxLocalPutBytes bounds uploads to presigned PUT URLs, which S3
+	// would otherwise cap at 5 GB
+	maxLocalPutBytes = 512 << 20
+)
+
+// Local keeps objects as files in a directory. Its presigned URLs point at
+// the API itself, which serves them with ServeHTTP. It suits development
+// and single-instance deployments; replicas don't share the directory.
+type Local struct {
+	dir     string
+	baseURL string
+	secret  []byte
+	now     func() time.Time
+}
+
+// NewLocal keeps objects in dir and presigns URLs under baseURL, signed
+// with secret
+func NewLocal(dir, baseURL, secret string) *Local {
+	return &Local{
+		dir:     dir,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  []byte(secret),
+		now:     time.Now,
+	}
+}
+
+// path returns the file holding the object under key
+func (l *Local) path(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(key)), nil
+}
+
+// Put implements Storage. The file is written under a temporary name and
+// renamed, so readers never see part of it. Content types aren't kept.
+func (l *Local) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store object: %w", err)
+	}
+	return nil
+}
+
+// Get implements Storage
+func (l *Local) Get(ctx context.Context, key string) ([]byte, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return data, nil
+}
+
+// Delete implements Storage
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	return nil
+}
+
+// PresignURL implements Storage with a URL under LocalPathPrefix carrying
+// its expiry and an HMAC of the method, key and expiry
+func (l *Local) PresignURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if err := checkPresign(method, key, expires); err != nil {
+		return "", err
+	}
+	expiry := strconv.FormatInt(l.now().Add(expires).Unix(), 10)
+	return fmt.Sprintf("%s%s%s?expires=%s&signature=%s", l.baseURL, LocalPathPrefix, key, expiry, l.sign(method, key, expiry)), nil
+}
+
+// sign returns the hex HMAC of a presigned request
+func (l *Local) sign(method, key, expiry string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(method + "\n" + key + "\n" + expiry))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP serves presigned URLs: GET downloads the object as an
+// attachment and PUT stores the request body
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, LocalPathPrefix)
+	expiry := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	valid := err == nil && ValidKey(key) && l.now().Unix() <= expiresAt &&
+		hmac.Equal([]byte(signature), []byte(l.sign(r.Method, key, expiry)))
+	if !valid {
+		http.Error(w, "Invalid or expired signature", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		data, err := l.Get(r.Context(), key)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to read object", http.StatusInternalServerError)
+			return
+		}
+		// The content type isn't kept, and serving stored bytes inline from
+		// the API's origin would let uploaded HTML run there
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(key)))
+		w.Write(data)
+	case http.MethodPut:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxLocalPutBytes))
+		if err != nil {
+			http.Error(w, "Object too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err := l.Put(r.Context(), key, data, r.Header.Get("Content-Type")); err != nil {
+			http.Error(w, "Failed to store object", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
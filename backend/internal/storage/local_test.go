@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocal_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+
+	if _, err := l.Get(ctx, "imports/missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of a missing object error = %v, want ErrNotFound", err)
+	}
+
+	if err := l.Put(ctx, "imports/a.csv", []byte("content\nhello"), "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Put(ctx, "imports/a.csv", []byte("content\nreplaced"), "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := l.Get(ctx, "imports/a.csv")
+	if err != nil || string(data) != "content\nreplaced" {
+		t.Fatalf("Get() = %q, %v, want the replaced object", data, err)
+	}
+
+	if err := l.Delete(ctx, "imports/a.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Delete(ctx, "imports/a.csv"); err != nil {
+		t.Errorf("Delete() of a missing object error = %v, want nil", err)
+	}
+	if _, err := l.Get(ctx, "imports/a.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+
+	if err := l.Put(ctx, "../outside", []byte("x"), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put() outside the directory error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestLocal_PresignURL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	l := NewLocal(t.TempDir(), "https://api.example.com/", "secret")
+	l.now = func() time.Time { return now }
+
+	if err := l.Put(ctx, "reports/r.html", []byte("<script>alert(1)</script>"), "text/html"); err != nil {
+		t.Fatal(err)
+	}
+
+	get, err := l.PresignURL(ctx, http.MethodGet, "reports/r.html", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(get, "https://api.example.com/storage/reports/r.html?expires=") {
+		t.Fatalf("PresignURL() = %s, want a URL under the base URL", get)
+	}
+	put, _ := l.PresignURL(ctx, http.MethodPut, "uploads/u.bin", time.Minute)
+
+	if _, err := l.PresignURL(ctx, http.MethodDelete, "reports/r.html", time.Minute); err == nil {
+		t.Error("PresignURL() of DELETE succeeded, want an error")
+	}
+	if _, err := l.PresignURL(ctx, http.MethodGet, "reports/r.html", 8*24*time.Hour); err == nil {
+		t.Error("PresignURL() past MaxPresignExpiry succeeded, want an error")
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		after      time.Duration
+		wantStatus int
+	}{
+		{"download", http.MethodGet, get, "", 0, http.StatusOK},
+		{"expired", http.MethodGet, get, "", 2 * time.Minute, http.StatusForbidden},
+		{"tampered key", http.MethodGet, strings.Replace(get, "r.html", "s.html", 1), "", 0, http.StatusForbidden},
+		{"wrong method", http.MethodPut, get, "x", 0, http.StatusForbidden},
+		{"unsigned", http.MethodGet, "https://api.example.com/storage/reports/r.html", "", 0, http.StatusForbidden},
+		{"upload", http.MethodPut, put, "uploaded", 0, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l.now = func() time.Time { return now.Add(tt.after) }
+			rec := httptest.NewRecorder()
+			l.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, bytes.NewBufferString(tt.body)))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+		})
+	}
+
+	// Downloads are never rendered by the browser
+	l.now = func() time.Time { return now }
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, get, nil))
+	body, _ := io.ReadAll(rec.Body)
+	if rec.Header().Get("Content-Type") != "application/octet-stream" || !strings.HasPrefix(rec.Header().Get("Content-Disposition"), "attachment") || string(body) != "<script>alert(1)</script>" {
+		t.Errorf("download headers = %v, want an octet-stream attachment", rec.Header())
+	}
+
+	if data, err := l.Get(ctx, "uploads/u.bin"); err != nil || string(data) != "uploaded" {
+		t.Errorf("Get() of the uploaded object = %q, %v, want the PUT body", data, err)
+	}
+}
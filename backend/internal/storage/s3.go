@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// defaultRegion is used when no region is configured, as MinIO expects
+const defaultRegion = "us-east-1"
+
+// S3 keeps objects in an S3 bucket, or a bucket of any server speaking
+// the S3 API such as MinIO, over plain HTTP signed with the AWS SDK's
+// Signature Version 4 signer
+type S3 struct {
+	endpoint *url.URL
+	bucket   string
+	// pathStyle addresses the bucket in the path rather than the host
+	// name, as MinIO expects
+	pathStyle   bool
+	signer      *v4.Signer
+	credentials aws.Credentials
+	region      string
+	httpClient  *http.Client
+	now         func() time.Time
+}
+
+// NewS3 creates a driver for cfg.Bucket. Without an endpoint the regional
+// AWS endpoint is used.
+func NewS3(cfg Config, pathStyle bool) (*S3, error) {
+	region := cfg.Region
+	if region == "" {
+		region = defaultRegion
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid storage endpoint: %s", endpoint)
+	}
+
+	return &S3{
+		endpoint:  u,
+		bucket:    cfg.Bucket,
+		pathStyle: pathStyle,
+		// S3 object keys are signed as they are sent, not escaped again
+		signer: v4.NewSigner(func(o *v4.SignerOptions) {
+			o.DisableURIPathEscaping = true
+		}),
+		credentials: aws.Credentials{
+			AccessKeyID:     cfg.AccessKeyID,
+			SecretAccessKey: cfg.SecretAccessKey,
+		},
+		region:     region,
+		httpClient: &http.Client{Timeout: 5 * time.Minute},
+		now:        time.Now,
+	}, nil
+}
+
+// objectURL returns the URL of the object under key
+func (s *S3) objectURL(key string) (string, error) {
+	if !ValidKey(key) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	u := *s.endpoint
+	if s.pathStyle {
+		u.Path = "/" + s.bucket + "/" + key
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	return u.String(), nil
+}
+
+// do sends a signed request for the object under key
+func (s *S3) do(ctx context.Context, method, key string, body []byte, contentType string) (*http.Response, error) {
+	target, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if err := s.signer.SignHTTP(ctx, s.credentials, req, payloadHash, "s3", s.region, s.now()); err != nil {
+		return nil, fmt.Errorf("failed to sign storage request: %w", err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("storage request failed: %w", err)
+	}
+	return resp, nil
+}
+
+// failed builds the error for an unexpected response
+func failed(action string, resp *http.Response) error {
+	reply, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("failed to %s object: storage returned %d: %s", action, resp.StatusCode, reply)
+}
+
+// Put implements Storage
+func (s *S3) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	resp, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return failed("store", resp)
+	}
+	return nil
+}
+
+// Get implements Storage
+func (s *S3) Get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object: %w", err)
+		}
+		return data, nil
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, failed("read", resp)
+	}
+}
+
+// Delete implements Storage
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// S3 answers 204 whether or not the object existed
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return failed("delete", resp)
+	}
+	return nil
+}
+
+// PresignURL implements Storage with a Signature Version 4 query-string
+// signature. Only the host is signed, so any client can use the URL.
+func (s *S3) PresignURL(ctx context.Context, method, key string, expires time.Duration) (string, error) {
+	if err := checkPresign(method, key, expires); err != nil {
+		return "", err
+	}
+	target, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create storage request: %w", err)
+	}
+	query := req.URL.Query()
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	req.URL.RawQuery = query.Encode()
+
+	// The body isn't known when the URL is signed
+	signed, _, err := s.signer.PresignHTTP(ctx, s.credentials, req, "UNSIGNED-PAYLOAD", "s3", s.region, s.now())
+	if err != nil {
+		return "", fmt.Errorf("failed to sign storage URL: %w", err)
+	}
+	return signed, nil
+}
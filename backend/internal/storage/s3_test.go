@@ -0,0 +1,136 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeBucket is an S3-compatible server holding one path-style bucket
+type fakeBucket struct {
+	mu      sync.Mutex
+	objects map[string]string
+	types   map[string]string
+}
+
+func newFakeBucket(t *testing.T) (*fakeBucket, *httptest.Server) {
+	t.Helper()
+	b := &fakeBucket{objects: map[string]string{}, types: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=id/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		key, ok := strings.CutPrefix(r.URL.Path, "/uploads/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			b.objects[key] = string(body)
+			b.types[key] = r.Header.Get("Content-Type")
+		case http.MethodGet:
+			object, ok := b.objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(w, object)
+		case http.MethodDelete:
+			delete(b.objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return b, server
+}
+
+func TestS3_PutGetDelete(t *testing.T) {
+	ctx := context.Background()
+	bucket, server := newFakeBucket(t)
+	s, err := NewS3(Config{Bucket: "uploads", Endpoint: server.URL, AccessKeyID: "id", SecretAccessKey: "secret"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "transcriptions/t1", []byte("audio"), "audio/mpeg"); err != nil {
+		t.Fatal(err)
+	}
+	if bucket.objects["transcriptions/t1"] != "audio" || bucket.types["transcriptions/t1"] != "audio/mpeg" {
+		t.Errorf("bucket = %v %v, want the object with its content type", bucket.objects, bucket.types)
+	}
+
+	data, err := s.Get(ctx, "transcriptions/t1")
+	if err != nil || string(data) != "audio" {
+		t.Fatalf("Get() = %q, %v, want the object", data, err)
+	}
+
+	if err := s.Delete(ctx, "transcriptions/t1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(ctx, "transcriptions/t1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() after Delete() error = %v, want ErrNotFound", err)
+	}
+
+	// Requests the server refuses are errors
+	s.credentials.AccessKeyID = "other"
+	if err := s.Put(ctx, "transcriptions/t2", []byte("audio"), ""); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("Put() with the wrong key error = %v, want the 403", err)
+	}
+	if err := s.Put(ctx, "../t2", []byte("audio"), ""); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Put() with an invalid key error = %v, want ErrInvalidKey", err)
+	}
+}
+
+func TestS3_PresignURL(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		pathStyle bool
+		endpoint  string
+		wantURL   string
+	}{
+		{"aws", false, "", "https://uploads.s3.eu-west-1.amazonaws.com/imports/i1"},
+		{"minio", true, "http://minio:9000", "http://minio:9000/uploads/imports/i1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewS3(Config{Bucket: "uploads", Region: "eu-west-1", Endpoint: tt.endpoint, AccessKeyID: "id", SecretAccessKey: "secret"}, tt.pathStyle)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s.now = func() time.Time { return now }
+
+			got, err := s.PresignURL(context.Background(), http.MethodPut, "imports/i1", 15*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query := u.Query()
+			u.RawQuery = ""
+			if u.String() != tt.wantURL {
+				t.Errorf("PresignURL() = %s, want it for %s", got, tt.wantURL)
+			}
+			if query.Get("X-Amz-Expires") != "900" || query.Get("X-Amz-Credential") != "id/20261016/eu-west-1/s3/aws4_request" || query.Get("X-Amz-Signature") == "" {
+				t.Errorf("PresignURL() query = %v, want a 15 minute sigv4 signature", query)
+			}
+		})
+	}
+}
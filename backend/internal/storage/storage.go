@@ -0,0 +1,134 @@
+// Package storage keeps files such as uploads in object storage: a local
+// directory, AWS S3 or a MinIO server.
+//
+// Objects are addressed by keys of slash-separated segments, such as
+// "transcriptions/<id>". Presigned URLs let clients read or write an
+// object directly, without the API proxying its bytes.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Supported drivers
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+	DriverMinIO = "minio"
+)
+
+// Drivers lists the supported driver names
+var Drivers = []string{DriverLocal, DriverS3, DriverMinIO}
+
+const (
+	// maxKeyLength is well under the 1024 bytes S3 allows
+	maxKeyLength = 512
+
+	// MaxPresignExpiry is the longest a presigned URL can be valid for,
+	// the limit S3 sets
+	MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrNotFound is returned for objects that don't exist
+	ErrNotFound = errors.New("object not found")
+
+	// ErrInvalidKey is returned for keys ValidKey rejects
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Storage stores objects by key
+type Storage interface {
+	// Put stores data under key, replacing any object already there
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get returns the object stored under key, or ErrNotFound
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete removes the object under key. Deleting a missing object isn't
+	// an error.
+	Delete(ctx context.Context, key string) error
+	// PresignURL returns a URL that allows method (GET or PUT) on the
+	// object under key, without other credentials, until expires has
+	// passed
+	PresignURL(ctx context.Context, method, key string, expires time.Duration) (string, error)
+}
+
+// Config selects and configures a driver
+type Config struct {
+	Driver string
+
+	// Dir is the directory DriverLocal keeps objects in
+	Dir string
+	// BaseURL is the public URL of the API, which serves the presigned
+	// URLs of DriverLocal
+	BaseURL string
+	// Secret signs the presigned URLs of DriverLocal
+	Secret string
+
+	// Bucket, Region and the access keys configure DriverS3 and
+	// DriverMinIO. Endpoint is the MinIO server, or overrides the
+	// regional S3 endpoint.
+	Bucket          string
+	Region          string
+	Endpoint        string
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// ValidDriver reports whether name is a supported driver
+func ValidDriver(name string) bool {
+	return slices.Contains(Drivers, name)
+}
+
+// New creates the configured driver. Check the name with ValidDriver
+// first.
+func New(cfg Config) (Storage, error) {
+	switch cfg.Driver {
+	case DriverLocal:
+		return NewLocal(cfg.Dir, cfg.BaseURL, cfg.Secret), nil
+	case DriverS3:
+		return NewS3(cfg, false)
+	case DriverMinIO:
+		return NewS3(cfg, true)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Driver)
+	}
+}
+
+// ValidKey reports whether key is a usable object key: slash-separated
+// segments of ASCII letters, digits, '.', '-' and '_', none of them empty,
+// "." or "..". Keys that pass need no escaping in paths or URLs.
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+		for _, r := range segment {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// checkPresign validates the arguments of PresignURL
+func checkPresign(method, key string, expires time.Duration) error {
+	if !ValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	if method != "GET" && method != "PUT" {
+		return fmt.Errorf("presigned URLs can't be made for %s", method)
+	}
+	if expires < time.Second || expires > MaxPresignExpiry {
+		return fmt.Errorf("presigned URLs must expire in between a second and %s", MaxPresignExpiry)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"transcriptions/6f1c7d2e-0d7a-4c55-9f63-1c1f0e1d2a3b", true},
+		{"imports/batch_1.csv", true},
+		{"report.pdf", true},
+		{"", false},
+		{"/leading", false},
+		{"trailing/", false},
+		{"double//slash", false},
+		{"../escape", false},
+		{"a/./b", false},
+		{"spaces are bad", false},
+		{"query?x=1", false},
+		{"ünicode", false},
+		{strings.Repeat("a", maxKeyLength+1), false},
+	}
+
+	for _, tt := range tests {
+		if got := ValidKey(tt.key); got != tt.want {
+			t.Errorf("ValidKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"local", Config{Driver: DriverLocal, Dir: t.TempDir(), BaseURL: "http://localhost:8080", Secret: "secret"}, false},
+		{"s3", Config{Driver: DriverS3, Bucket: "uploads", Region: "eu-west-1", AccessKeyID: "id", SecretAccessKey: "secret"}, false},
+		{"minio", Config{Driver: DriverMinIO, Bucket: "uploads", Endpoint: "http://minio:9000", AccessKeyID: "id", SecretAccessKey: "secret"}, false},
+		{"bad endpoint", Config{Driver: DriverMinIO, Bucket: "uploads", Endpoint: "minio:9000"}, true},
+		{"unknown driver", Config{Driver: "gcs"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
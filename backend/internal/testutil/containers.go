@@ -0,0 +1,143 @@
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/redis/go-redis/v9"
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	tcredis "github.com/testcontainers/testcontainers-go/modules/redis"
+)
+
+const (
+	postgresImage = "pgvector/pgvector:pg16"
+	redisImage    = "redis:7-alpine"
+)
+
+// PostgresURL returns a connection string for an empty, throwaway database.
+// If TEST_DATABASE_URL is set a uniquely named database is created on that
+// server; otherwise a Postgres container is started. Both are removed when
+// the test finishes.
+func PostgresURL(t *testing.T) string {
+	t.Helper()
+
+	if base := os.Getenv("TEST_DATABASE_URL"); base != "" {
+		return createDatabase(t, base)
+	}
+
+	requireDocker(t)
+
+	ctx := context.Background()
+	container, err := tcpostgres.Run(ctx, postgresImage,
+		tcpostgres.WithUsername("postgres"),
+		tcpostgres.WithPassword("test"),
+		tcpostgres.WithDatabase("content_analyzer_test"),
+		tcpostgres.BasicWaitStrategies(),
+	)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("failed to start postgres: %v", err)
+	}
+
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("failed to get postgres address: %v", err)
+	}
+	return dsn
+}
+
+// RedisURL returns a connection string for an empty Redis instance.
+// If TEST_REDIS_URL is set that instance is flushed before and after the
+// test, so it must be disposable; otherwise a Redis container is started.
+func RedisURL(t *testing.T) string {
+	t.Helper()
+
+	if existing := os.Getenv("TEST_REDIS_URL"); existing != "" {
+		flush := func() {
+			opts, err := redis.ParseURL(existing)
+			if err != nil {
+				t.Fatalf("invalid TEST_REDIS_URL: %v", err)
+			}
+			client := redis.NewClient(opts)
+			defer client.Close()
+			if err := client.FlushDB(context.Background()).Err(); err != nil {
+				t.Fatalf("failed to flush test redis: %v", err)
+			}
+		}
+		flush()
+		t.Cleanup(flush)
+		return existing
+	}
+
+	requireDocker(t)
+
+	ctx := context.Background()
+	container, err := tcredis.Run(ctx, redisImage)
+	testcontainers.CleanupContainer(t, container)
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+
+	redisURL, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("failed to get redis address: %v", err)
+	}
+	return redisURL
+}
+
+// requireDocker skips the test when no Docker daemon is reachable
+func requireDocker(t *testing.T) {
+	t.Helper()
+
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+}
+
+// createDatabase creates a uniquely named database on an existing server
+// and drops it when the test finishes
+func createDatabase(t *testing.T, base string) string {
+	t.Helper()
+
+	u, err := url.Parse(base)
+	if err != nil {
+		t.Fatalf("invalid TEST_DATABASE_URL: %v", err)
+	}
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatalf("failed to generate database name: %v", err)
+	}
+	name := "test_" + hex.EncodeToString(suffix)
+
+	ctx := context.Background()
+	conn, err := pgx.Connect(ctx, base)
+	if err != nil {
+		t.Fatalf("failed to connect to TEST_DATABASE_URL: %v", err)
+	}
+	defer conn.Close(ctx)
+
+	if _, err := conn.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn, err := pgx.Connect(ctx, base)
+		if err != nil {
+			t.Logf("failed to connect to drop test database %s: %v", name, err)
+			return
+		}
+		defer conn.Close(ctx)
+
+		if _, err := conn.Exec(ctx, "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Logf("failed to drop test database %s: %v", name, err)
+		}
+	})
+
+	u.Path = "/" + name
+	return u.String()
+}
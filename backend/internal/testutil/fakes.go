@@ -0,0 +1,119 @@
+package testutil
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+)
+
+// DefaultAnalysis is the model response FakeGemini returns unless told otherwise
+const DefaultAnalysis = `{"sentiment":"positive","sentiment_score":0.7,"topics":["testing"],"summary":"A test submission."}`
+
+// FakeGemini is a stand-in for the Gemini REST API that answers every
+// generateContent call with a fixed response
+type FakeGemini struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	response string
+	status   int
+	calls    int
+}
+
+// NewFakeGemini starts a fake Gemini API that is closed when the test finishes
+func NewFakeGemini(t *testing.T) *FakeGemini {
+	t.Helper()
+
+	g := &FakeGemini{response: DefaultAnalysis, status: http.StatusOK}
+	g.Server = httptest.NewServer(http.HandlerFunc(g.serve))
+	t.Cleanup(g.Close)
+	return g
+}
+
+// SetResponse changes the text returned by subsequent calls
+func (g *FakeGemini) SetResponse(text string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.response = text
+}
+
+// SetStatus makes subsequent calls fail with the given HTTP status
+func (g *FakeGemini) SetStatus(status int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.status = status
+}
+
+// Calls returns how many generation requests were received
+func (g *FakeGemini) Calls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.calls
+}
+
+func (g *FakeGemini) serve(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	g.calls++
+	text, status := g.response, g.status
+	g.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if status != http.StatusOK {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error": map[string]interface{}{"code": status, "message": "fake gemini error"},
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"candidates": []map[string]interface{}{{
+			"content": map[string]interface{}{
+				"role":  "model",
+				"parts": []map[string]string{{"text": text}},
+			},
+			"finishReason": "STOP",
+		}},
+		"usageMetadata": map[string]int{"promptTokenCount": 10, "candidatesTokenCount": 20},
+	})
+}
+
+// RecordingMailer keeps delivered messages in memory
+type RecordingMailer struct {
+	mu       sync.Mutex
+	messages []notifications.Message
+}
+
+// Send records the message
+func (m *RecordingMailer) Send(ctx context.Context, msg notifications.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns the messages delivered so far
+func (m *RecordingMailer) Messages() []notifications.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notifications.Message(nil), m.messages...)
+}
+
+// LastTo returns the most recent message sent to an address
+func (m *RecordingMailer) LastTo(to string) (notifications.Message, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i], true
+		}
+	}
+	return notifications.Message{}, false
+}
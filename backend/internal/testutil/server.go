@@ -0,0 +1,269 @@
+// Package testutil provides a fully wired API server backed by throwaway
+// Postgres and Redis instances for black-box integration tests.
+//
+// Tests using it should carry the integration build tag so the default
+// "go test ./..." stays fast and Docker-free:
+//
+//	//go:build integration
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/cache"
+	"github.com/sfumato00/content-analyzer/internal/config"
+	"github.com/sfumato00/content-analyzer/internal/database"
+	"github.com/sfumato00/content-analyzer/internal/logging"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/notifications"
+	"github.com/sfumato00/content-analyzer/internal/server"
+	"github.com/sfumato00/content-analyzer/internal/services/ai"
+	"github.com/sfumato00/content-analyzer/internal/services/analyzer"
+	"github.com/sfumato00/content-analyzer/internal/services/events"
+	"github.com/sfumato00/content-analyzer/internal/services/feeds"
+	"github.com/sfumato00/content-analyzer/internal/services/queue"
+	"github.com/sfumato00/content-analyzer/internal/services/threads"
+)
+
+// AdminEmail and AdminPassword sign in to the account that may use the
+// admin endpoints of a TestServer
+const (
+	AdminEmail    = "ops@example.com"
+	AdminPassword = "Op3rator-Passw0rd!"
+)
+
+// TestServer is a running API server with its dependencies
+type TestServer struct {
+	*httptest.Server
+
+	Config *config.Config
+	DB     *database.Database
+	Cache  *cache.Cache
+	Gemini *FakeGemini
+	Mailer *RecordingMailer
+	Events *events.Dispatcher
+}
+
+// NewServer starts Postgres and Redis, applies migrations and serves the
+// API together with a background worker that processes queued jobs.
+// Everything is torn down when the test finishes.
+func NewServer(t *testing.T) *TestServer {
+	t.Helper()
+
+	if testing.Short() {
+		t.Skip("integration test skipped in short mode")
+	}
+
+	cfg := &config.Config{
+		GeminiAPIKey:      "test-api-key",
+		DatabaseURL:       PostgresURL(t),
+		RedisURL:          RedisURL(t),
+		JWTSecret:         "integration-test-secret-at-least-32-characters",
+		Port:              "0",
+		Environment:       "test",
+		AllowedOrigins:    []string{"http://localhost:3000"},
+		LogLevel:          "warn",
+		LogFormat:         logging.FormatText,
+		LogSampleRate:     1,
+		WorkerConcurrency: 2,
+		AppBaseURL:        "http://localhost:3000",
+		MailDriver:        "log",
+		DebugEndpoints:    true,
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid test config: %v", err)
+	}
+
+	if err := database.RunMigrations(cfg.DatabaseURL, migrationsPath()); err != nil {
+		t.Fatalf("failed to run migrations: %v", err)
+	}
+
+	ctx := context.Background()
+
+	db, err := database.New(ctx, cfg.DatabaseURL, database.Options{})
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	admin, err := models.NewUserStore(db.Pool).Create(ctx, AdminEmail, AdminPassword)
+	if err != nil {
+		t.Fatalf("failed to create admin account: %v", err)
+	}
+	cfg.AdminUserIDs = []uuid.UUID{admin.ID}
+
+	redisCache, err := cache.New(cfg.RedisURL)
+	if err != nil {
+		t.Fatalf("failed to connect to redis: %v", err)
+	}
+	t.Cleanup(func() { redisCache.Close() })
+
+	renderer, err := notifications.NewRenderer()
+	if err != nil {
+		t.Fatalf("failed to load email templates: %v", err)
+	}
+
+	jobQueue := queue.New(redisCache.Client())
+	notifier := notifications.NewNotifier(jobQueue, renderer, cfg.AppBaseURL)
+
+	ts := &TestServer{
+		Config: cfg,
+		DB:     db,
+		Cache:  redisCache,
+		Gemini: NewFakeGemini(t),
+		Mailer: &RecordingMailer{},
+		Events: events.NewDispatcher(),
+	}
+
+	// Run the worker the same way main does, with fakes at the edges
+	aiClient := ai.NewClient(ai.Options{
+		APIKey:  cfg.GeminiAPIKey,
+		BaseURL: ts.Gemini.URL,
+	})
+
+	worker := queue.NewWorker(jobQueue, cfg.WorkerConcurrency)
+	submissionStore := models.NewSubmissionStore(db.Pool).WithListener(events.NewPublisher(jobQueue))
+	worker.Register(analyzer.JobType, analyzer.NewAnalyzer(submissionStore, aiClient).WithCancelWatcher(jobQueue).WithProfiles(models.NewProfileStore(db.Pool)).Handle)
+	worker.Register(threads.JobType, threads.NewResponder(models.NewThreadStore(db.Pool), submissionStore, aiClient).Handle)
+	worker.Register(feeds.JobType, feeds.NewPoller(models.NewFeedStore(db.Pool), submissionStore, jobQueue).Handle)
+	worker.Register(events.StatusChangedJobType, ts.Events.Handle)
+	worker.Register(notifications.EmailJobType, notifications.NewDeliveryHandler(ts.Mailer))
+
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker.Run(workerCtx)
+	}()
+	t.Cleanup(func() {
+		stopWorker()
+		wg.Wait()
+	})
+
+	srv := server.New(config.NewWatcher(cfg), db, redisCache, notifier, nil, nil, nil, nil, nil, nil)
+	ts.Server = httptest.NewServer(srv.Router())
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+// migrationsPath locates backend/migrations relative to this file
+func migrationsPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations")
+}
+
+// Do sends a request to the server. body is JSON encoded when not nil and
+// token, when set, is sent as a bearer token.
+func (ts *TestServer) Do(t *testing.T, method, path, token string, body interface{}) *http.Response {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("failed to encode request body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+
+	return resp
+}
+
+// DecodeJSON decodes a response body, failing the test if the status differs
+func DecodeJSON(t *testing.T, resp *http.Response, wantStatus int, v interface{}) {
+	t.Helper()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != wantStatus {
+		t.Fatalf("%s %s status = %d, want %d (body: %s)",
+			resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, wantStatus, body)
+	}
+
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			t.Fatalf("failed to decode response %q: %v", body, err)
+		}
+	}
+}
+
+// Register creates an account and returns its access token
+func (ts *TestServer) Register(t *testing.T, email, password string) string {
+	t.Helper()
+
+	var resp struct {
+		Token struct {
+			AccessToken string `json:"access_token"`
+		} `json:"token"`
+	}
+	DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/auth/register", "", map[string]string{
+		"email":    email,
+		"password": password,
+	}), http.StatusCreated, &resp)
+
+	return resp.Token.AccessToken
+}
+
+// Login authenticates and returns an access token
+func (ts *TestServer) Login(t *testing.T, email, password string) string {
+	t.Helper()
+
+	var resp struct {
+		Token struct {
+			AccessToken string `json:"access_token"`
+		} `json:"token"`
+	}
+	DecodeJSON(t, ts.Do(t, http.MethodPost, "/api/v1/auth/login", "", map[string]string{
+		"email":    email,
+		"password": password,
+	}), http.StatusOK, &resp)
+
+	return resp.Token.AccessToken
+}
+
+// Eventually polls cond until it returns true or the timeout elapses
+func Eventually(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s", timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
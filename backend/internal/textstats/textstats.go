@@ -0,0 +1,51 @@
+// Package textstats counts the words, characters and estimated model
+// tokens of submitted text. Submissions store the counts, and plans limit
+// how many words can be queued for analysis at once.
+package textstats
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Stats describes the size of a text
+type Stats struct {
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	// Tokens estimates what the text costs in a model prompt
+	Tokens int `json:"tokens"`
+}
+
+// Compute counts the words, characters and estimated tokens of text.
+// Characters are Unicode code points, as the content length limit counts
+// them.
+func Compute(text string) Stats {
+	return Stats{
+		Words:      CountWords(text),
+		Characters: utf8.RuneCountInString(text),
+		Tokens:     EstimateTokens(text),
+	}
+}
+
+// CountWords counts the whitespace-separated words of text, leaving out
+// runs of punctuation such as a dash between spaces
+func CountWords(text string) int {
+	words := 0
+	for _, field := range strings.Fields(text) {
+		if strings.IndexFunc(field, isWordRune) >= 0 {
+			words++
+		}
+	}
+	return words
+}
+
+// EstimateTokens approximates the token count of text at four characters
+// per token, which is close enough for English prose to budget a prompt
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
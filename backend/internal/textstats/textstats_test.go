@@ -0,0 +1,25 @@
+package textstats
+
+import "testing"
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want Stats
+	}{
+		{name: "empty", text: "", want: Stats{}},
+		{name: "sentence", text: "The battery lasts all day.", want: Stats{Words: 5, Characters: 26, Tokens: 7}},
+		{name: "punctuation isn't a word", text: "Fast — and quiet !", want: Stats{Words: 3, Characters: 18, Tokens: 5}},
+		{name: "contractions and numbers", text: "It's 2026,\nwe've shipped v2.", want: Stats{Words: 5, Characters: 28, Tokens: 7}},
+		{name: "multibyte characters", text: "café naïve", want: Stats{Words: 2, Characters: 10, Tokens: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Compute(tt.text); got != tt.want {
+				t.Errorf("Compute(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
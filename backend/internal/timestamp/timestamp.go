@@ -0,0 +1,127 @@
+// Package timestamp is the API's convention for times: they are written
+// in UTC as RFC 3339 with millisecond precision, and read in any of the
+// formats clients commonly send.
+package timestamp
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is how times are written: RFC 3339 in UTC, always with three
+// fractional digits, e.g. 2024-05-01T09:30:00.000Z
+const Layout = "2006-01-02T15:04:05.000Z07:00"
+
+// inputLayouts are the formats Parse accepts besides Unix seconds, in the
+// order they are tried. Those without a zone are read in the caller's
+// location.
+var inputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	time.DateOnly,
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// Format writes t the API's way
+func Format(t time.Time) string {
+	return t.UTC().Format(Layout)
+}
+
+// Parse reads a time in RFC 3339 at any precision, as a date and time
+// separated by a space or T with or without a zone, as a date, in RFC
+// 1123 (the HTTP date format), or as Unix seconds. Times without a zone,
+// and dates, which are taken as midnight, are in loc.
+func Parse(s string, loc *time.Location) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range inputLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339, YYYY-MM-DD or Unix seconds", s)
+}
+
+// Time is a time.Time that is written to JSON the API's way and read from
+// any format Parse accepts, taking times without a zone as UTC. It can be
+// scanned from and written to the database like a time.Time.
+type Time struct {
+	time.Time
+}
+
+// New wraps t
+func New(t time.Time) Time {
+	return Time{Time: t}
+}
+
+// Now returns the current time in UTC
+func Now() Time {
+	return Time{Time: time.Now().UTC()}
+}
+
+// Ptr wraps t, returning nil for nil, for optional times
+func Ptr(t *time.Time) *Time {
+	if t == nil {
+		return nil
+	}
+	return &Time{Time: *t}
+}
+
+// Std returns the time.Time t wraps, or nil for nil
+func (t *Time) Std() *time.Time {
+	if t == nil {
+		return nil
+	}
+	return &t.Time
+}
+
+// MarshalJSON writes t in Layout
+func (t Time) MarshalJSON() ([]byte, error) {
+	return json.Marshal(Format(t.Time))
+}
+
+// UnmarshalJSON reads any format Parse accepts, or null as the zero time
+func (t *Time) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*t = Time{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		// Unix seconds may be sent as a number
+		s = string(data)
+	}
+	parsed, err := Parse(s, time.UTC)
+	if err != nil {
+		return err
+	}
+	t.Time = parsed
+	return nil
+}
+
+// Scan reads a timestamp column
+func (t *Time) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Time{}
+	case time.Time:
+		t.Time = v
+	default:
+		return fmt.Errorf("timestamp: can't scan %T", src)
+	}
+	return nil
+}
+
+// Value writes t to a timestamp column
+func (t Time) Value() (driver.Value, error) {
+	return t.Time, nil
+}
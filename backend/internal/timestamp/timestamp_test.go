@@ -0,0 +1,128 @@
+package timestamp
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	zone := time.FixedZone("EST", -5*60*60)
+	tests := []struct {
+		name string
+		in   time.Time
+		want string
+	}{
+		{"utc", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC), "2024-05-01T09:30:00.000Z"},
+		{"other zone", time.Date(2024, 5, 1, 4, 30, 0, 0, zone), "2024-05-01T09:30:00.000Z"},
+		{"truncates to milliseconds", time.Date(2024, 5, 1, 9, 30, 0, 123456789, time.UTC), "2024-05-01T09:30:00.123Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(tt.in); got != tt.want {
+				t.Errorf("Format() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2024-05-01T09:30:00Z", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+		{"2024-05-01T09:30:00.123456+02:00", time.Date(2024, 5, 1, 7, 30, 0, 123456000, time.UTC)},
+		{"2024-05-01T09:30:00", time.Date(2024, 5, 1, 9, 30, 0, 0, berlin)},
+		{"2024-05-01 09:30:00Z", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+		{"2024-05-01 09:30:00", time.Date(2024, 5, 1, 9, 30, 0, 0, berlin)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, berlin)},
+		{"Wed, 01 May 2024 09:30:00 GMT", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+		{"1714555800", time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+		{" 2024-05-01 ", time.Date(2024, 5, 1, 0, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in, berlin)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, in := range []string{"", "yesterday", "2024-13-01", "01/05/2024"} {
+		if _, err := Parse(in, time.UTC); err == nil {
+			t.Errorf("Parse(%q) error = nil", in)
+		}
+	}
+}
+
+func TestTime_JSON(t *testing.T) {
+	type doc struct {
+		At       Time  `json:"at"`
+		Optional *Time `json:"optional,omitempty"`
+	}
+
+	at := time.Date(2024, 5, 1, 9, 30, 0, 500000000, time.FixedZone("", 2*60*60))
+	data, err := json.Marshal(doc{At: New(at)})
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if want := `{"at":"2024-05-01T07:30:00.500Z"}`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	for _, in := range []string{
+		`{"at":"2024-05-01T07:30:00.500Z"}`,
+		`{"at":"2024-05-01 07:30:00.5"}`,
+	} {
+		var got doc
+		if err := json.Unmarshal([]byte(in), &got); err != nil {
+			t.Fatalf("json.Unmarshal(%s) error = %v", in, err)
+		}
+		if !got.At.Equal(at) {
+			t.Errorf("json.Unmarshal(%s) = %v, want %v", in, got.At, at)
+		}
+	}
+
+	var got doc
+	if err := json.Unmarshal([]byte(`{"at":1714548600,"optional":null}`), &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !got.At.Equal(time.Unix(1714548600, 0)) || got.Optional != nil {
+		t.Errorf("json.Unmarshal() = %+v", got)
+	}
+
+	for _, in := range []string{`{"at":"soon"}`, `{"at":1714548600.5}`, `{"at":true}`} {
+		if err := json.Unmarshal([]byte(in), &got); err == nil {
+			t.Errorf("json.Unmarshal(%s) error = nil", in)
+		}
+	}
+}
+
+func TestTime_Database(t *testing.T) {
+	at := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+
+	var got Time
+	if err := got.Scan(at); err != nil || !got.Equal(at) {
+		t.Errorf("Scan() = %v, %v", got, err)
+	}
+	if err := got.Scan(nil); err != nil || !got.IsZero() {
+		t.Errorf("Scan(nil) = %v, %v", got, err)
+	}
+	if err := got.Scan("2024-05-01"); err == nil {
+		t.Error("Scan(string) error = nil")
+	}
+
+	value, err := New(at).Value()
+	if err != nil || value != at {
+		t.Errorf("Value() = %v, %v", value, err)
+	}
+}
@@ -0,0 +1,98 @@
+// Package version describes the running build. Release builds set
+// Version, Commit and BuildDate at link time:
+//
+//	go build -ldflags "-X github.com/sfumato00/content-analyzer/internal/version.Version=v1.4.0 \
+//	  -X github.com/sfumato00/content-analyzer/internal/version.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/sfumato00/content-analyzer/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// `make build` and the Dockerfile do this. Builds without them fall back to
+// the revision and commit time the Go toolchain stamps on binaries built
+// in a git checkout.
+package version
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at link time with -ldflags "-X"
+var (
+	Version   string
+	Commit    string
+	BuildDate string
+)
+
+// devVersion names builds that weren't given a version
+const devVersion = "dev"
+
+// Info describes a build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	// Modified is set when the build had uncommitted changes
+	Modified bool `json:"modified,omitempty"`
+}
+
+// String describes the build on one line, as --version prints it
+func (i Info) String() string {
+	commit := i.ShortCommit()
+	if i.Modified {
+		commit += "-dirty"
+	}
+	return fmt.Sprintf("content-analyzer %s (commit %s, built %s, %s)", i.Version, commit, i.BuildDate, i.GoVersion)
+}
+
+// ShortCommit returns the first 12 characters of the commit
+func (i Info) ShortCommit() string {
+	if len(i.Commit) > 12 {
+		return i.Commit[:12]
+	}
+	return i.Commit
+}
+
+// Get returns the running build's info
+var Get = sync.OnceValue(func() Info {
+	build, _ := debug.ReadBuildInfo()
+	return resolve(Version, Commit, BuildDate, build)
+})
+
+// resolve prefers the values set at link time, then what the toolchain
+// recorded in build
+func resolve(version, commit, buildDate string, build *debug.BuildInfo) Info {
+	info := Info{Version: version, Commit: commit, BuildDate: buildDate, GoVersion: runtime.Version()}
+
+	if build != nil {
+		if info.Version == "" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+			info.Version = build.Main.Version
+		}
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = commit == "" && setting.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = devVersion
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+	return info
+}
@@ -0,0 +1,67 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	stamped := &debug.BuildInfo{
+		Main: debug.Module{Version: "(devel)"},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef0123456789abcdef01234567"},
+			{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"},
+			{Key: "vcs.modified", Value: "true"},
+		},
+	}
+
+	tests := []struct {
+		name                string
+		version, commit, at string
+		build               *debug.BuildInfo
+		want                Info
+	}{
+		{
+			name:    "link time values",
+			version: "v1.4.0", commit: "abc123", at: "2026-10-16T08:00:00Z",
+			build: stamped,
+			want:  Info{Version: "v1.4.0", Commit: "abc123", BuildDate: "2026-10-16T08:00:00Z"},
+		},
+		{
+			name:  "stamped by the toolchain",
+			build: stamped,
+			want:  Info{Version: "dev", Commit: "0123456789abcdef0123456789abcdef01234567", BuildDate: "2026-10-01T12:00:00Z", Modified: true},
+		},
+		{
+			name:  "installed module",
+			build: &debug.BuildInfo{Main: debug.Module{Version: "v1.3.2"}},
+			want:  Info{Version: "v1.3.2", Commit: "unknown", BuildDate: "unknown"},
+		},
+		{
+			name: "no build info",
+			want: Info{Version: "dev", Commit: "unknown", BuildDate: "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.want.GoVersion = runtime.Version()
+			if got := resolve(tt.version, tt.commit, tt.at, tt.build); got != tt.want {
+				t.Errorf("resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInfo_String(t *testing.T) {
+	info := Info{Version: "v1.4.0", Commit: "0123456789abcdef", BuildDate: "2026-10-16T08:00:00Z", GoVersion: "go1.24.0", Modified: true}
+	want := "content-analyzer v1.4.0 (commit 0123456789ab-dirty, built 2026-10-16T08:00:00Z, go1.24.0)"
+	if got := info.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(Get().GoVersion, "go") {
+		t.Errorf("Get().GoVersion = %q, want the toolchain version", Get().GoVersion)
+	}
+}
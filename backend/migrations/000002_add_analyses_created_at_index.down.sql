@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_analyses_created_at;
@@ -0,0 +1,2 @@
+-- Speeds up time-range aggregation for analytics endpoints
+CREATE INDEX IF NOT EXISTS idx_analyses_created_at ON analyses(created_at);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_topic_cluster_members_submission_id;
+DROP INDEX IF EXISTS idx_topic_clusters_user_id;
+
+DROP TABLE IF EXISTS topic_cluster_members;
+DROP TABLE IF EXISTS topic_clusters;
+DROP TABLE IF EXISTS submission_embeddings;
+
+DROP EXTENSION IF EXISTS vector;
@@ -0,0 +1,31 @@
+-- Vector similarity support (requires the pgvector extension)
+CREATE EXTENSION IF NOT EXISTS vector;
+
+-- Submission embeddings used for topic clustering
+CREATE TABLE submission_embeddings (
+  submission_id UUID PRIMARY KEY REFERENCES submissions(id) ON DELETE CASCADE,
+  model VARCHAR(100) NOT NULL,
+  embedding vector NOT NULL,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Topic clusters computed per user by the clustering job
+CREATE TABLE topic_clusters (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  label VARCHAR(255) NOT NULL,
+  size INT NOT NULL,
+  centroid vector,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Cluster assignments
+CREATE TABLE topic_cluster_members (
+  cluster_id UUID NOT NULL REFERENCES topic_clusters(id) ON DELETE CASCADE,
+  submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+  distance FLOAT NOT NULL,
+  PRIMARY KEY (cluster_id, submission_id)
+);
+
+CREATE INDEX idx_topic_clusters_user_id ON topic_clusters(user_id);
+CREATE INDEX idx_topic_cluster_members_submission_id ON topic_cluster_members(submission_id);
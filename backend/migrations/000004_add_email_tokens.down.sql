@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_email_tokens_user_id;
+DROP TABLE IF EXISTS email_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
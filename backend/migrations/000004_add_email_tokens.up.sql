@@ -0,0 +1,16 @@
+-- Email verification status
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- One-time tokens for email verification and password resets
+CREATE TABLE email_tokens (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose VARCHAR(50) NOT NULL, -- verify_email, password_reset
+  email VARCHAR(255) NOT NULL,
+  token_hash VARCHAR(64) UNIQUE NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_tokens_user_id ON email_tokens(user_id);
@@ -0,0 +1,2 @@
+-- Deterministic readability metrics computed alongside the model analysis
+ALTER TABLE analyses ADD COLUMN readability JSONB;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_keyphrases_phrase;
+DROP INDEX IF EXISTS idx_keyphrases_analysis_id;
+DROP INDEX IF EXISTS idx_keyphrases_submission_id;
+DROP TABLE IF EXISTS keyphrases;
@@ -0,0 +1,13 @@
+-- Keyphrases extracted from the latest analysis of each submission
+CREATE TABLE keyphrases (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+  analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+  phrase VARCHAR(255) NOT NULL, -- lowercased
+  score DOUBLE PRECISION NOT NULL, -- relevance, 0 to 1
+  created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX idx_keyphrases_submission_id ON keyphrases(submission_id);
+CREATE INDEX idx_keyphrases_analysis_id ON keyphrases(analysis_id);
+CREATE INDEX idx_keyphrases_phrase ON keyphrases(phrase);
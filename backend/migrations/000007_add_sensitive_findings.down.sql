@@ -0,0 +1,3 @@
+ALTER TABLE analyses DROP COLUMN IF EXISTS findings;
+
+ALTER TABLE submissions DROP COLUMN IF EXISTS redacted_content;
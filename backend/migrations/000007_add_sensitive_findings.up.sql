@@ -0,0 +1,5 @@
+-- Masked copy of the content for display, set when redaction is requested
+ALTER TABLE submissions ADD COLUMN redacted_content TEXT;
+
+-- Personal data and profanity found in the content, as character offsets
+ALTER TABLE analyses ADD COLUMN findings JSONB;
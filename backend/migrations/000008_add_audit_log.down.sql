@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_audit_log_user_id_created_at;
+DROP TABLE IF EXISTS audit_log;
@@ -0,0 +1,13 @@
+ALTER TABLE submissions DROP COLUMN IF EXISTS archived_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS failed_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS processing_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS queued_at;
+
+ALTER TABLE submissions DROP CONSTRAINT IF EXISTS submissions_status_check;
+
+-- Statuses the old schema didn't know about
+UPDATE submissions SET status = 'pending' WHERE status IN ('draft', 'queued');
+UPDATE submissions SET status = 'completed' WHERE status = 'archived';
+
+ALTER TABLE submissions ALTER COLUMN status SET DEFAULT 'pending';
@@ -0,0 +1,18 @@
+-- Submissions move through draft → queued → processing → completed/failed → archived.
+-- "pending" was the old name for queued.
+UPDATE submissions SET status = 'queued' WHERE status = 'pending';
+
+ALTER TABLE submissions ALTER COLUMN status SET DEFAULT 'queued';
+ALTER TABLE submissions ADD CONSTRAINT submissions_status_check
+  CHECK (status IN ('draft', 'queued', 'processing', 'completed', 'failed', 'archived'));
+
+-- When each status was entered
+ALTER TABLE submissions ADD COLUMN queued_at TIMESTAMP;
+ALTER TABLE submissions ADD COLUMN processing_at TIMESTAMP;
+ALTER TABLE submissions ADD COLUMN completed_at TIMESTAMP;
+ALTER TABLE submissions ADD COLUMN failed_at TIMESTAMP;
+ALTER TABLE submissions ADD COLUMN archived_at TIMESTAMP;
+
+UPDATE submissions SET queued_at = created_at;
+UPDATE submissions SET completed_at = created_at WHERE status = 'completed';
+UPDATE submissions SET failed_at = created_at WHERE status = 'failed';
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
+ALTER TABLE users DROP COLUMN IF EXISTS version;
+ALTER TABLE submissions DROP COLUMN IF EXISTS version;
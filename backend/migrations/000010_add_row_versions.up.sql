@@ -0,0 +1,7 @@
+-- Row versions for optimistic concurrency: every write increments the
+-- version and updates only apply if the client's version still matches
+ALTER TABLE submissions ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+-- The first user-editable profile field
+ALTER TABLE users ADD COLUMN display_name VARCHAR(100);
@@ -0,0 +1,9 @@
+ALTER TABLE submissions DROP COLUMN IF EXISTS canceled_at;
+
+ALTER TABLE submissions DROP CONSTRAINT IF EXISTS submissions_status_check;
+
+-- The old schema had no canceled status
+UPDATE submissions SET status = 'failed', failed_at = COALESCE(failed_at, NOW()) WHERE status = 'canceled';
+
+ALTER TABLE submissions ADD CONSTRAINT submissions_status_check
+  CHECK (status IN ('draft', 'queued', 'processing', 'completed', 'failed', 'archived'));
@@ -0,0 +1,6 @@
+-- Queued or processing analyses can be canceled by the user
+ALTER TABLE submissions DROP CONSTRAINT IF EXISTS submissions_status_check;
+ALTER TABLE submissions ADD CONSTRAINT submissions_status_check
+  CHECK (status IN ('draft', 'queued', 'processing', 'completed', 'failed', 'canceled', 'archived'));
+
+ALTER TABLE submissions ADD COLUMN canceled_at TIMESTAMP;
@@ -0,0 +1,3 @@
+-- Paid plans get their analyses processed ahead of the free tier
+ALTER TABLE users ADD COLUMN plan VARCHAR(20) NOT NULL DEFAULT 'free'
+  CHECK (plan IN ('free', 'pro', 'enterprise'));
@@ -0,0 +1,11 @@
+-- Feature flags gate risky features per user or for a percentage of users
+CREATE TABLE feature_flags (
+    key VARCHAR(100) PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percent INTEGER NOT NULL DEFAULT 0 CHECK (rollout_percent BETWEEN 0 AND 100),
+    user_ids UUID[] NOT NULL DEFAULT '{}',
+    updated_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
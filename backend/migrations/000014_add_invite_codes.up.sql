@@ -0,0 +1,16 @@
+-- Invitation codes for invite-only registration. Only a SHA-256 hash of
+-- each code is stored.
+CREATE TABLE invite_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code_hash VARCHAR(64) UNIQUE NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    max_uses INTEGER NOT NULL DEFAULT 1 CHECK (max_uses > 0),
+    uses INTEGER NOT NULL DEFAULT 0 CHECK (uses >= 0),
+    expires_at TIMESTAMP,
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+-- Which invitation each account registered with
+ALTER TABLE users ADD COLUMN invite_code_id UUID REFERENCES invite_codes(id) ON DELETE SET NULL;
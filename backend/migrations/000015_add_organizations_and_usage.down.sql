@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS usage_rollups;
+
+ALTER TABLE analyses
+    DROP COLUMN IF EXISTS prompt_tokens,
+    DROP COLUMN IF EXISTS output_tokens,
+    DROP COLUMN IF EXISTS cost_micros;
+
+DROP TABLE IF EXISTS organization_members;
+DROP TABLE IF EXISTS organizations;
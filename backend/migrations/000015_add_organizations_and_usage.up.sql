@@ -0,0 +1,42 @@
+-- Organizations group users so admins can see their members' usage
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE organization_members (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'admin', 'member')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+
+CREATE TRIGGER update_organizations_updated_at BEFORE UPDATE ON organizations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Model usage of each analysis; cost is in millionths of a US dollar at
+-- the prices configured when it ran
+ALTER TABLE analyses
+    ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN output_tokens INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN cost_micros BIGINT NOT NULL DEFAULT 0;
+
+-- Daily per-user usage, rebuilt for recent days by the usage rollup job
+CREATE TABLE usage_rollups (
+    day DATE NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    submissions INTEGER NOT NULL DEFAULT 0,
+    analyses INTEGER NOT NULL DEFAULT 0,
+    prompt_tokens BIGINT NOT NULL DEFAULT 0,
+    output_tokens BIGINT NOT NULL DEFAULT 0,
+    cost_micros BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, day)
+);
+
+CREATE INDEX idx_usage_rollups_day ON usage_rollups(day);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_submissions_user_id_created_at;
+
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS legal_hold;
+
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS retention_days,
+    DROP COLUMN IF EXISTS legal_hold;
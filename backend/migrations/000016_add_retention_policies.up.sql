@@ -0,0 +1,11 @@
+-- Retention: submissions of an organization's members are purged once
+-- older than its retention period, unless a legal hold applies
+ALTER TABLE organizations
+    ADD COLUMN retention_days INTEGER CHECK (retention_days > 0),
+    ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE submissions
+    ADD COLUMN legal_hold BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Lets the purge job find members' old submissions without a full scan
+CREATE INDEX idx_submissions_user_id_created_at ON submissions(user_id, created_at);
@@ -0,0 +1,3 @@
+-- How well the summary is supported by the source text, from 0 to 1, as
+-- judged by a verification pass; NULL when it wasn't verified
+ALTER TABLE analyses ADD COLUMN confidence DOUBLE PRECISION CHECK (confidence BETWEEN 0 AND 1);
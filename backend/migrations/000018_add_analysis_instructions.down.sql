@@ -0,0 +1,2 @@
+ALTER TABLE analyses DROP COLUMN IF EXISTS instructions;
+ALTER TABLE submissions DROP COLUMN IF EXISTS instructions;
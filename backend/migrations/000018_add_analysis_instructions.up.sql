@@ -0,0 +1,5 @@
+-- Caller-supplied focus for the analysis, such as "focus on legal risk",
+-- kept on the analysis too so a result can be reproduced after the
+-- submission is re-run with different instructions
+ALTER TABLE submissions ADD COLUMN instructions TEXT;
+ALTER TABLE analyses ADD COLUMN instructions TEXT;
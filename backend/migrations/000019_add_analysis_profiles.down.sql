@@ -0,0 +1,2 @@
+ALTER TABLE submissions DROP COLUMN IF EXISTS profile_id;
+DROP TABLE IF EXISTS analysis_profiles;
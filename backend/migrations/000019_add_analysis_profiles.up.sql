@@ -0,0 +1,33 @@
+-- Analysis profiles: a prompt template and the set of analysis modules to
+-- run. Built-in profiles have no owner and are shared by every user.
+CREATE TABLE analysis_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    prompt TEXT NOT NULL DEFAULT '',
+    modules TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Names are unique per owner, and among the built-in profiles
+CREATE UNIQUE INDEX idx_analysis_profiles_owner_name
+    ON analysis_profiles (COALESCE(user_id, '00000000-0000-0000-0000-000000000000'), LOWER(name));
+
+INSERT INTO analysis_profiles (id, name, description, prompt, modules) VALUES
+    ('6f1c1a52-3c1e-4d55-9a0e-5b1d8c2f0a01', 'Marketing copy review',
+     'Tone, message and audience appeal of marketing copy',
+     'Focus on the tone, the core message, calls to action and how the copy would land with its intended audience.',
+     ARRAY['topics', 'keyphrases', 'summary', 'readability', 'verification']),
+    ('6f1c1a52-3c1e-4d55-9a0e-5b1d8c2f0a02', 'Academic tone check',
+     'Formality, hedging and clarity of academic writing',
+     'Focus on formality, hedging, unsupported claims and the clarity of the argument.',
+     ARRAY['topics', 'summary', 'readability', 'verification']),
+    ('6f1c1a52-3c1e-4d55-9a0e-5b1d8c2f0a03', 'Compliance scan',
+     'Personal data, obligations and legal or regulatory risk',
+     'Focus on obligations, commitments, personal data and anything that carries legal or regulatory risk.',
+     ARRAY['keyphrases', 'findings', 'summary', 'verification']);
+
+-- The profile a submission is analyzed with; NULL runs every module
+ALTER TABLE submissions ADD COLUMN profile_id UUID REFERENCES analysis_profiles(id) ON DELETE SET NULL;
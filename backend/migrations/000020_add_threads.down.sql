@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS thread_messages;
+DROP TABLE IF EXISTS threads;
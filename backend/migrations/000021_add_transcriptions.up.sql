@@ -0,0 +1,26 @@
+-- Audio and video uploads awaiting transcription. The media is kept only
+-- until the transcript is stored; the transcript becomes the content of a
+-- new submission and its timed segments are kept here.
+CREATE TABLE transcriptions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    media BYTEA,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'failed')),
+    error TEXT,
+    priority VARCHAR(20) NOT NULL DEFAULT 'default',
+    instructions TEXT,
+    profile_id UUID REFERENCES analysis_profiles(id) ON DELETE SET NULL,
+    submission_id UUID REFERENCES submissions(id) ON DELETE CASCADE,
+    provider VARCHAR(50),
+    language VARCHAR(20),
+    duration_seconds DOUBLE PRECISION,
+    segments TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_transcriptions_user_id ON transcriptions(user_id, created_at DESC);
+CREATE UNIQUE INDEX idx_transcriptions_submission_id ON transcriptions(submission_id);
@@ -0,0 +1,31 @@
+-- RSS and Atom feeds polled for new items to analyze
+CREATE TABLE feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    title TEXT,
+    last_polled_at TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, url)
+);
+
+CREATE INDEX idx_feeds_last_polled_at ON feeds(last_polled_at NULLS FIRST);
+
+-- Every item seen in a feed, keyed by its GUID so it is only submitted once.
+-- submission_id is NULL for items that were skipped rather than analyzed.
+CREATE TABLE feed_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    feed_id UUID NOT NULL REFERENCES feeds(id) ON DELETE CASCADE,
+    guid TEXT NOT NULL,
+    title TEXT,
+    link TEXT,
+    published_at TIMESTAMP,
+    submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (feed_id, guid)
+);
+
+CREATE INDEX idx_feed_items_feed_id ON feed_items(feed_id, created_at DESC);
+CREATE INDEX idx_feed_items_submission_id ON feed_items(submission_id);
@@ -0,0 +1,14 @@
+-- API keys for integrations such as the browser extension. Only a SHA-256
+-- hash of each key is stored; prefix is kept so users can tell them apart.
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) UNIQUE NOT NULL,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMP
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,5 @@
+ALTER TABLE analyses DROP COLUMN IF EXISTS changes;
+
+DROP INDEX IF EXISTS idx_submissions_previous_id;
+ALTER TABLE submissions DROP COLUMN IF EXISTS revision;
+ALTER TABLE submissions DROP COLUMN IF EXISTS previous_id;
@@ -0,0 +1,10 @@
+-- Revised versions of a document. Each revision points at the one it
+-- revises, and only the latest revision of a document can be revised.
+ALTER TABLE submissions ADD COLUMN previous_id UUID REFERENCES submissions(id) ON DELETE SET NULL;
+ALTER TABLE submissions ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;
+
+CREATE UNIQUE INDEX idx_submissions_previous_id ON submissions(previous_id) WHERE previous_id IS NOT NULL;
+
+-- How a revision's tone and claims changed from the previous one, as JSON
+-- (TEXT so it can be encrypted)
+ALTER TABLE analyses ADD COLUMN changes TEXT;
@@ -0,0 +1,3 @@
+-- Factual claims extracted from the content with their fact-check
+-- assessments and sources, as JSON (TEXT so it can be encrypted)
+ALTER TABLE analyses ADD COLUMN claims TEXT;
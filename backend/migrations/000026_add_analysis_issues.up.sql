@@ -0,0 +1,3 @@
+-- Grammar, spelling and style issues found by proofreading, as JSON with
+-- character offsets into the content (TEXT so it can be encrypted)
+ALTER TABLE analyses ADD COLUMN issues TEXT;
@@ -0,0 +1,3 @@
+-- Bias and framing report of the content, as JSON with cited examples
+-- (TEXT so it can be encrypted)
+ALTER TABLE analyses ADD COLUMN bias TEXT;
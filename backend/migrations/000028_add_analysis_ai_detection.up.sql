@@ -0,0 +1,3 @@
+-- Estimate of how likely the content is machine-generated, with the
+-- signals behind it, as JSON (TEXT so it can be encrypted)
+ALTER TABLE analyses ADD COLUMN ai_detection TEXT;
@@ -0,0 +1,8 @@
+ALTER TABLE analyses
+    DROP COLUMN IF EXISTS moderation,
+    DROP COLUMN IF EXISTS policy_decision;
+
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS policy_override;
+
+DROP TABLE IF EXISTS moderation_thresholds;
@@ -0,0 +1,20 @@
+-- Moderation policies: per organization, a score above which content in
+-- a category is flagged with a warning and one above which it is blocked
+CREATE TABLE moderation_thresholds (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    category VARCHAR(32) NOT NULL,
+    warn_above DOUBLE PRECISION CHECK (warn_above BETWEEN 0 AND 1),
+    block_above DOUBLE PRECISION CHECK (block_above BETWEEN 0 AND 1),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, category),
+    CHECK (warn_above IS NOT NULL OR block_above IS NOT NULL)
+);
+
+-- An operator's decision for a submission, replacing the policy's
+ALTER TABLE submissions ADD COLUMN policy_override JSONB;
+
+-- Moderation scores as JSON (TEXT so they can be encrypted), and the
+-- policy decision, which holds no content and is updated by overrides
+ALTER TABLE analyses
+    ADD COLUMN moderation TEXT,
+    ADD COLUMN policy_decision JSONB;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_submissions_assignee_id_due_at;
+
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS assignee_id,
+    DROP COLUMN IF EXISTS due_at,
+    DROP COLUMN IF EXISTS workflow_status;
@@ -0,0 +1,11 @@
+-- Review workflow: a teammate assigned to a submission, when it is due and
+-- how far its review has come
+ALTER TABLE submissions
+    ADD COLUMN assignee_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN due_at TIMESTAMPTZ,
+    ADD COLUMN workflow_status VARCHAR(20) NOT NULL DEFAULT 'open'
+        CHECK (workflow_status IN ('open', 'in_review', 'changes_requested', 'approved'));
+
+-- Serves each assignee's queue, soonest due first
+CREATE INDEX idx_submissions_assignee_id_due_at ON submissions(assignee_id, due_at)
+    WHERE assignee_id IS NOT NULL;
@@ -0,0 +1,27 @@
+-- Bulk imports of CSV and JSONL files. Each row becomes a submission; the
+-- file is kept only until every row has been processed, and progress and
+-- per-row errors are recorded here.
+CREATE TABLE imports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    filename VARCHAR(255) NOT NULL,
+    format VARCHAR(10) NOT NULL CHECK (format IN ('csv', 'jsonl')),
+    mapping JSONB NOT NULL,
+    data BYTEA,
+    auto_analyze BOOLEAN NOT NULL DEFAULT FALSE,
+    redact BOOLEAN NOT NULL DEFAULT FALSE,
+    instructions TEXT,
+    profile_id UUID REFERENCES analysis_profiles(id) ON DELETE SET NULL,
+    priority VARCHAR(20) NOT NULL DEFAULT 'default',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    error TEXT,
+    total_rows INTEGER NOT NULL,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    imported_rows INTEGER NOT NULL DEFAULT 0,
+    failed_rows INTEGER NOT NULL DEFAULT 0,
+    row_errors JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_imports_user_id ON imports(user_id, created_at DESC);
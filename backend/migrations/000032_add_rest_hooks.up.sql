@@ -0,0 +1,12 @@
+-- REST hook subscriptions in the shape Zapier and Make expect. Hooks are
+-- created with an API key and go away with it.
+CREATE TABLE rest_hooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    api_key_id UUID NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    target_url TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rest_hooks_user_id ON rest_hooks(user_id, event);
@@ -0,0 +1,2 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS data_key;
+ALTER TABLE transcriptions DROP COLUMN IF EXISTS media_key;
@@ -0,0 +1,4 @@
+-- Uploads can be kept in object storage instead of Postgres; the key of
+-- the object is recorded in place of the bytes until it is processed.
+ALTER TABLE transcriptions ADD COLUMN media_key TEXT;
+ALTER TABLE imports ADD COLUMN data_key TEXT;
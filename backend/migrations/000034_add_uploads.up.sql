@@ -0,0 +1,23 @@
+-- Files clients upload straight to object storage through a presigned
+-- URL. An upload is pending until its token is redeemed, when the object
+-- is checked against the size, type and checksum declared up front and
+-- attached to a submission. Uploads never completed, and attachments of
+-- purged submissions, are deleted along with their objects.
+CREATE TABLE uploads (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    object_key VARCHAR(512) NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    checksum_sha256 VARCHAR(64) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'attached')),
+    submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    attached_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_uploads_submission_id ON uploads(submission_id);
+CREATE INDEX idx_uploads_expires_at ON uploads(expires_at) WHERE status = 'pending';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_submissions_quarantined_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS quarantine_reason;
+ALTER TABLE submissions DROP COLUMN IF EXISTS quarantined_at;
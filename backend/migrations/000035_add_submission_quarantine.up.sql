@@ -0,0 +1,7 @@
+-- Submissions blocked by moderation, or quarantined by an operator, are
+-- hidden from listings and integrations until an operator releases or
+-- destroys them
+ALTER TABLE submissions ADD COLUMN quarantined_at TIMESTAMP;
+ALTER TABLE submissions ADD COLUMN quarantine_reason TEXT;
+
+CREATE INDEX idx_submissions_quarantined_at ON submissions(quarantined_at) WHERE quarantined_at IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE organizations
+    DROP COLUMN IF EXISTS monthly_budget_micros,
+    DROP COLUMN IF EXISTS daily_budget_micros;
@@ -0,0 +1,5 @@
+-- Daily and monthly caps on what an organization's members may spend on
+-- the model, in millionths of a US dollar; NULL leaves the period uncapped
+ALTER TABLE organizations
+    ADD COLUMN daily_budget_micros BIGINT CHECK (daily_budget_micros > 0),
+    ADD COLUMN monthly_budget_micros BIGINT CHECK (monthly_budget_micros > 0);
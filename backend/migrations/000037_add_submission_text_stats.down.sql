@@ -0,0 +1,4 @@
+ALTER TABLE submissions
+    DROP COLUMN IF EXISTS token_estimate,
+    DROP COLUMN IF EXISTS character_count,
+    DROP COLUMN IF EXISTS word_count;
@@ -0,0 +1,6 @@
+-- Size of each submission's content, counted when it is written; NULL for
+-- submissions stored before the counts were kept
+ALTER TABLE submissions
+    ADD COLUMN word_count INTEGER,
+    ADD COLUMN character_count INTEGER,
+    ADD COLUMN token_estimate INTEGER;
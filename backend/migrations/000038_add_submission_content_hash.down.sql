@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_submissions_content_hash;
+
+ALTER TABLE submissions DROP COLUMN IF EXISTS content_hash;
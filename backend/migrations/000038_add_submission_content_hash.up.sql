@@ -0,0 +1,6 @@
+-- SHA-256 of each submission's content, so duplicates can be found without
+-- decrypting it; NULL for submissions stored before hashes were kept
+ALTER TABLE submissions ADD COLUMN content_hash CHAR(64);
+
+CREATE INDEX idx_submissions_content_hash ON submissions(content_hash, user_id)
+    WHERE content_hash IS NOT NULL;
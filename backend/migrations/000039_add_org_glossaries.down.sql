@@ -0,0 +1,5 @@
+UPDATE analysis_profiles SET modules = array_remove(modules, 'compliance');
+
+ALTER TABLE analyses DROP COLUMN IF EXISTS compliance;
+
+DROP TABLE IF EXISTS glossary_entries;
@@ -0,0 +1,21 @@
+-- Organization glossaries: domain terms and brand names the analysis is
+-- told about, and phrases members must not use
+CREATE TABLE glossary_entries (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL CHECK (kind IN ('term', 'brand', 'banned')),
+    phrase VARCHAR(100) NOT NULL,
+    description VARCHAR(300) NOT NULL DEFAULT '',
+    replacement VARCHAR(100) NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_glossary_entries_phrase ON glossary_entries(org_id, kind, LOWER(phrase));
+
+-- Banned phrases found in the content as JSON (TEXT so they can be
+-- encrypted)
+ALTER TABLE analyses ADD COLUMN compliance TEXT;
+
+-- The built-in compliance profile checks for banned phrases too
+UPDATE analysis_profiles SET modules = array_append(modules, 'compliance')
+    WHERE id = '6f1c1a52-3c1e-4d55-9a0e-5b1d8c2f0a03';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS submission_labels;
+
+DROP TABLE IF EXISTS taxonomy_labels;
@@ -0,0 +1,25 @@
+-- Organization taxonomies: the labels members' submissions are
+-- classified into
+CREATE TABLE taxonomy_labels (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    description VARCHAR(300) NOT NULL DEFAULT '',
+    position INTEGER NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_taxonomy_labels_name ON taxonomy_labels(org_id, LOWER(name));
+
+-- Labels from the latest analysis of each submission, kept in the clear
+-- for filtering and analytics like keyphrases
+CREATE TABLE submission_labels (
+    submission_id UUID NOT NULL REFERENCES submissions(id) ON DELETE CASCADE,
+    analysis_id UUID NOT NULL REFERENCES analyses(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL,
+    confidence DOUBLE PRECISION NOT NULL, -- 0 to 1
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (submission_id, label)
+);
+
+CREATE INDEX idx_submission_labels_analysis_id ON submission_labels(analysis_id);
+CREATE INDEX idx_submission_labels_label ON submission_labels(LOWER(label));
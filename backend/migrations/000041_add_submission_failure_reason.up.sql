@@ -0,0 +1,3 @@
+-- Why an analysis failed, when it failed for a known reason such as model
+-- output that couldn't be parsed
+ALTER TABLE submissions ADD COLUMN failure_reason VARCHAR(32);
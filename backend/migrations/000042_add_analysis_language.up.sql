@@ -0,0 +1,3 @@
+-- The language the analysis pipeline detected in the content, as an ISO
+-- 639-1 code; NULL when it couldn't tell
+ALTER TABLE analyses ADD COLUMN language VARCHAR(8);
@@ -0,0 +1,8 @@
+-- The model calls made for an analysis, kept when ANALYSIS_ARTIFACTS is
+-- set for prompt engineering and support investigations. calls is a JSON
+-- array, encrypted with the rest of the analysis.
+CREATE TABLE analysis_artifacts (
+    analysis_id UUID PRIMARY KEY REFERENCES analyses(id) ON DELETE CASCADE,
+    calls TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
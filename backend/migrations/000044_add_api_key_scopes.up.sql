@@ -0,0 +1,3 @@
+-- The scopes an API key is limited to; NULL, as for keys created before
+-- scopes, allows everything
+ALTER TABLE api_keys ADD COLUMN scopes TEXT[];
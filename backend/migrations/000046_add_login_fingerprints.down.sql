@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS confirm_new_logins;
+DROP TABLE IF EXISTS login_fingerprints;
@@ -0,0 +1,15 @@
+-- Where and on what each user has signed in from, to spot sign-ins from a
+-- new country or device. country is an ISO 3166 code, or '' when unknown;
+-- device is a browser and OS such as "Firefox on Windows".
+CREATE TABLE login_fingerprints (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    country VARCHAR(2) NOT NULL DEFAULT '',
+    device VARCHAR(100) NOT NULL,
+    first_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, country, device)
+);
+
+-- Users can choose to only be notified of new sign-ins, rather than
+-- confirm them with an emailed code
+ALTER TABLE users ADD COLUMN confirm_new_logins BOOLEAN NOT NULL DEFAULT TRUE;
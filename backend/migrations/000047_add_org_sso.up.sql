@@ -0,0 +1,28 @@
+-- Single sign-on for an organization's members through its identity
+-- provider. client_secret is encrypted when encryption is configured.
+CREATE TABLE organization_sso (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    protocol VARCHAR(10) NOT NULL DEFAULT 'oidc',
+    issuer TEXT NOT NULL,
+    client_id TEXT NOT NULL,
+    client_secret TEXT NOT NULL,
+    groups_claim VARCHAR(100) NOT NULL DEFAULT 'groups',
+    -- [{"group": "...", "role": "admin"}], checked in order
+    role_mappings JSONB NOT NULL DEFAULT '[]',
+    default_role VARCHAR(20) NOT NULL DEFAULT 'member',
+    -- enforced turns off password sign-in for the organization's members
+    enforced BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Links an identity provider's subject to the user it signs in as
+CREATE TABLE sso_identities (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    subject TEXT NOT NULL,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, subject)
+);
+
+CREATE INDEX idx_sso_identities_user_id ON sso_identities(user_id);
@@ -0,0 +1,42 @@
+-- SCIM provisioning: each organization's identity provider authenticates
+-- with one bearer token, stored hashed
+CREATE TABLE scim_tokens (
+    org_id UUID PRIMARY KEY REFERENCES organizations(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+-- Users an organization's identity provider manages. Deactivated users
+-- keep their row, without the membership, until they are deleted.
+CREATE TABLE scim_users (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    external_id TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX idx_scim_users_user_id ON scim_users(user_id);
+
+-- Groups pushed by the identity provider; their names are matched
+-- against the organization's SSO role mappings
+CREATE TABLE scim_groups (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    display_name VARCHAR(255) NOT NULL,
+    external_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (org_id, display_name)
+);
+
+CREATE TABLE scim_group_members (
+    group_id UUID NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX idx_scim_group_members_user_id ON scim_group_members(user_id);
@@ -0,0 +1,25 @@
+-- Whether an account may be used: suspended accounts can only appeal, and
+-- banned ones can't sign in. status_reason is shown to the user.
+ALTER TABLE users
+    ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active',
+    ADD COLUMN status_reason TEXT,
+    ADD COLUMN status_changed_at TIMESTAMPTZ;
+
+-- Users' appeals against a suspension, reviewed by operators. Each user
+-- has at most one pending appeal.
+CREATE TABLE account_appeals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- the suspension's reason when the appeal was made
+    status_reason TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    -- pending, granted or denied
+    decision VARCHAR(20) NOT NULL DEFAULT 'pending',
+    response TEXT NOT NULL DEFAULT '',
+    reviewed_by VARCHAR(255),
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_account_appeals_pending ON account_appeals(user_id) WHERE decision = 'pending';
+CREATE INDEX idx_account_appeals_decision ON account_appeals(decision, created_at);
@@ -0,0 +1,3 @@
+-- The IANA time zone analytics are bucketed in by default, such as
+-- Europe/Paris; NULL buckets by UTC days
+ALTER TABLE users ADD COLUMN time_zone VARCHAR(64);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_analyses_updated_at ON analyses;
+DROP TRIGGER IF EXISTS update_submissions_updated_at ON submissions;
+ALTER TABLE analyses DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE submissions DROP COLUMN IF EXISTS updated_at;
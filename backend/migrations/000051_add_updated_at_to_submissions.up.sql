@@ -0,0 +1,22 @@
+-- When a submission or analysis row last changed, the Last-Modified of
+-- conditional GETs. Existing rows take the latest time they are known to
+-- have changed.
+ALTER TABLE submissions ADD COLUMN updated_at TIMESTAMP;
+UPDATE submissions SET updated_at = COALESCE(
+    GREATEST(created_at, queued_at, processing_at, completed_at, failed_at, canceled_at, archived_at, quarantined_at),
+    NOW()
+);
+ALTER TABLE submissions
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+ALTER TABLE analyses ADD COLUMN updated_at TIMESTAMP;
+UPDATE analyses SET updated_at = COALESCE(created_at, NOW());
+ALTER TABLE analyses
+    ALTER COLUMN updated_at SET DEFAULT NOW(),
+    ALTER COLUMN updated_at SET NOT NULL;
+
+CREATE TRIGGER update_submissions_updated_at BEFORE UPDATE ON submissions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+CREATE TRIGGER update_analyses_updated_at BEFORE UPDATE ON analyses
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_submissions_trash;
+ALTER TABLE submissions DROP COLUMN IF EXISTS deleted_at;
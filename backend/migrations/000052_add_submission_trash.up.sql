@@ -0,0 +1,5 @@
+-- Deleted submissions wait in their owner's trash until they are restored
+-- or purged
+ALTER TABLE submissions ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_submissions_trash ON submissions (user_id, deleted_at) WHERE deleted_at IS NOT NULL;
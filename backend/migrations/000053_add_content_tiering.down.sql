@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS content_objects;
+
+DROP INDEX IF EXISTS idx_submissions_untiered;
+ALTER TABLE submissions DROP COLUMN IF EXISTS content_key;
@@ -0,0 +1,17 @@
+-- The content of old submissions can be moved to object storage to keep
+-- the table small. A tiered submission's content and masked copy are
+-- emptied and content_key names the object holding them.
+ALTER TABLE submissions ADD COLUMN content_key VARCHAR(512);
+
+CREATE INDEX idx_submissions_untiered ON submissions (created_at) WHERE content_key IS NULL;
+
+-- Every object written for tiered content, so the objects of submissions
+-- that have since been deleted, or that were never tiered because the
+-- move was interrupted, can be found and removed
+CREATE TABLE content_objects (
+    object_key VARCHAR(512) PRIMARY KEY,
+    submission_id UUID REFERENCES submissions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_content_objects_orphaned ON content_objects (created_at) WHERE submission_id IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE analyses DROP COLUMN IF EXISTS timed_out;
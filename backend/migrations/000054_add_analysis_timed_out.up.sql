@@ -0,0 +1,3 @@
+-- The pipeline stages that ran out of time before an analysis was saved.
+-- Their part of the analysis is missing until they are retried.
+ALTER TABLE analyses ADD COLUMN timed_out TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1 @@
+ALTER TABLE rest_hooks DROP COLUMN IF EXISTS fields;
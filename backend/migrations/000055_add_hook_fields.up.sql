@@ -0,0 +1,3 @@
+-- The analysis fields a REST hook is sent, besides its IDs; empty sends
+-- them all
+ALTER TABLE rest_hooks ADD COLUMN fields TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+-- The subdomain of ORG_BASE_DOMAIN an organization is served on, e.g.
+-- "acme" for acme.analyzer.example.com
+ALTER TABLE organizations ADD COLUMN subdomain TEXT UNIQUE;
@@ -0,0 +1,13 @@
+-- Email domains an organization has proven it controls with a DNS TXT
+-- record. Its identity provider may only provision accounts under them.
+CREATE TABLE organization_domains (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    domain TEXT NOT NULL,
+    token VARCHAR(64) NOT NULL,
+    verified_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, domain)
+);
+
+-- Several organizations may claim a domain, but only one can verify it
+CREATE UNIQUE INDEX idx_organization_domains_verified ON organization_domains(domain) WHERE verified_at IS NOT NULL;
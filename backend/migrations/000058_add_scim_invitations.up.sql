@@ -0,0 +1,18 @@
+-- Users an organization's identity provider provisioned before they had
+-- an account. user_id is the ID their account will get, so the identity
+-- provider's SCIM ID stays the same once they sign up; invitations become
+-- scim_users rows when the account's email is verified.
+CREATE TABLE scim_invitations (
+    org_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    email VARCHAR(255) NOT NULL,
+    display_name VARCHAR(100),
+    external_id TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id),
+    UNIQUE (org_id, email)
+);
+
+CREATE INDEX idx_scim_invitations_email ON scim_invitations(email);
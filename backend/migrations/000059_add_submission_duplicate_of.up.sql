@@ -0,0 +1,3 @@
+-- A submission stored as a copy of a teammate's, whose analysis it reuses,
+-- keeps a link to the original
+ALTER TABLE submissions ADD COLUMN duplicate_of UUID REFERENCES submissions(id) ON DELETE SET NULL;
@@ -0,0 +1,5 @@
+-- The master key content_hash was keyed with when encryption is on, or NULL
+-- for a plain SHA-256 hash. Hashes not keyed with the active master key are
+-- recomputed in the background, as are the NULL hashes of submissions
+-- stored before hashes were kept.
+ALTER TABLE submissions ADD COLUMN content_hash_key TEXT;
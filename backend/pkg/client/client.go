@@ -0,0 +1,357 @@
+// Package client is a typed Go client for the content analyzer API. It
+// speaks v2, retries transient failures and, when given credentials,
+// logs in again before the access token expires.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts includes the first try
+	DefaultMaxAttempts = 3
+
+	// refreshSkew is how long before expiry a token is replaced
+	refreshSkew = time.Minute
+
+	baseDelay = 200 * time.Millisecond
+	maxDelay  = 5 * time.Second
+)
+
+// Options configures a Client
+type Options struct {
+	// BaseURL is the server root, e.g. https://analyzer.internal
+	BaseURL string
+	// HTTPClient defaults to a client with a 30 second timeout
+	HTTPClient *http.Client
+	// Token is a bearer token to use as is
+	Token string
+	// Email and Password log in on first use and whenever the token
+	// expires or is rejected
+	Email    string
+	Password string
+	// MaxAttempts caps tries per request; 0 means DefaultMaxAttempts
+	MaxAttempts int
+	UserAgent   string
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL     string
+	http        *http.Client
+	email       string
+	password    string
+	maxAttempts int
+	userAgent   string
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+
+	// sleep waits between attempts; tests replace it
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New creates a client
+func New(opts Options) (*Client, error) {
+	if opts.BaseURL == "" {
+		return nil, errors.New("client: BaseURL is required")
+	}
+	if (opts.Email == "") != (opts.Password == "") {
+		return nil, errors.New("client: Email and Password must be set together")
+	}
+
+	c := &Client{
+		baseURL:     strings.TrimRight(opts.BaseURL, "/") + "/api/v2",
+		http:        opts.HTTPClient,
+		email:       opts.Email,
+		password:    opts.Password,
+		maxAttempts: opts.MaxAttempts,
+		userAgent:   opts.UserAgent,
+		token:       opts.Token,
+		sleep:       sleepContext,
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.maxAttempts <= 0 {
+		c.maxAttempts = DefaultMaxAttempts
+	}
+	if c.userAgent == "" {
+		c.userAgent = "content-analyzer-go-client"
+	}
+
+	return c, nil
+}
+
+// APIError is a non-2xx response
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Fields holds per-field messages of a validation error
+	Fields map[string]string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("client: %d %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the API
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// request describes one API call
+type request struct {
+	method string
+	path   string
+	query  map[string]string
+	header map[string]string
+	body   interface{}
+	// anonymous calls don't send or refresh a token
+	anonymous bool
+}
+
+// do sends req, retrying transient failures, and decodes a 2xx body into
+// out when out is non-nil. It returns the final status code.
+func (c *Client) do(ctx context.Context, req request, out interface{}) (int, error) {
+	var payload []byte
+	if req.body != nil {
+		var err error
+		if payload, err = json.Marshal(req.body); err != nil {
+			return 0, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+
+	reauthed := false
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, req, payload)
+		if err != nil {
+			// A POST may have been applied before the connection dropped,
+			// so only idempotent methods are retried on network errors
+			if ctx.Err() != nil || attempt >= c.maxAttempts || !idempotent(req.method) {
+				return 0, err
+			}
+			if err := c.sleep(ctx, backoff(attempt)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		if resp.StatusCode == http.StatusUnauthorized && !req.anonymous && c.canLogin() && !reauthed {
+			// The token was revoked or expired early: log in once more
+			drain(resp)
+			reauthed = true
+			c.clearToken()
+			attempt--
+			continue
+		}
+
+		if retryable(req.method, resp.StatusCode) && attempt < c.maxAttempts {
+			delay := retryAfter(resp.Header.Get("Retry-After"))
+			if delay == 0 {
+				delay = backoff(attempt)
+			}
+			drain(resp)
+			if err := c.sleep(ctx, delay); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		defer resp.Body.Close()
+		return resp.StatusCode, decodeResponse(resp, out)
+	}
+}
+
+// send makes a single attempt
+func (c *Client) send(ctx context.Context, req request, payload []byte) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, c.baseURL+req.path, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if len(req.query) > 0 {
+		q := httpReq.URL.Query()
+		for k, v := range req.query {
+			if v != "" {
+				q.Set(k, v)
+			}
+		}
+		httpReq.URL.RawQuery = q.Encode()
+	}
+
+	httpReq.Header.Set("Accept", "application/json")
+	httpReq.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range req.header {
+		httpReq.Header.Set(k, v)
+	}
+
+	if !req.anonymous {
+		token, err := c.accessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if token != "" {
+			httpReq.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return resp, nil
+}
+
+// accessToken returns the current token, logging in when there is none or
+// it is about to expire
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fresh := c.token != "" && (c.expiresAt.IsZero() || time.Until(c.expiresAt) > refreshSkew)
+	if fresh || !c.canLogin() {
+		return c.token, nil
+	}
+
+	auth, err := c.login(ctx, c.email, c.password)
+	if err != nil {
+		return "", fmt.Errorf("failed to refresh token: %w", err)
+	}
+	c.setToken(auth.Token)
+
+	return c.token, nil
+}
+
+func (c *Client) canLogin() bool {
+	return c.email != ""
+}
+
+func (c *Client) clearToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+// setToken stores a token pair; callers hold mu
+func (c *Client) setToken(t *TokenPair) {
+	if t == nil {
+		return
+	}
+	c.token = t.AccessToken
+	c.expiresAt = t.ExpiresAt
+}
+
+// idempotent reports whether repeating a request is harmless
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether a status is worth another attempt. 429 and
+// 503 mean the request was turned away, so even a POST can be repeated.
+func retryable(method string, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent(method)
+	}
+	return false
+}
+
+// backoff picks a full-jitter delay before the next attempt
+func backoff(attempt int) time.Duration {
+	ceiling := baseDelay << (attempt - 1)
+	if ceiling <= 0 || ceiling > maxDelay {
+		ceiling = maxDelay
+	}
+	return rand.N(ceiling) + 1
+}
+
+// retryAfter parses a Retry-After header in seconds or as an HTTP-date,
+// capped at maxDelay; it returns 0 when absent or invalid
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if t, err := http.ParseTime(v); err == nil {
+		d = time.Until(t)
+	}
+
+	if d <= 0 {
+		return 0
+	}
+	return min(d, maxDelay)
+}
+
+// decodeResponse turns a non-2xx response into an APIError and decodes
+// a successful body into out
+func decodeResponse(resp *http.Response, out interface{}) error {
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var body struct {
+			Error  string            `json:"error"`
+			Fields map[string]string `json:"fields"`
+		}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body)
+		if body.Error == "" {
+			body.Error = http.StatusText(resp.StatusCode)
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: body.Error, Fields: body.Fields}
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return nil
+}
+
+// drain discards a response so its connection can be reused
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
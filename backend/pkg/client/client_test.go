@@ -0,0 +1,343 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// newTestClient points a client at handler and skips retry delays
+func newTestClient(t *testing.T, handler http.HandlerFunc, opts Options) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	opts.BaseURL = srv.URL
+	c, err := New(opts)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	c.sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"token", Options{BaseURL: "http://localhost", Token: "t"}, false},
+		{"credentials", Options{BaseURL: "http://localhost", Email: "a@example.com", Password: "p"}, false},
+		{"no base URL", Options{Token: "t"}, true},
+		{"email without password", Options{BaseURL: "http://localhost", Email: "a@example.com"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		status    int
+		wantCalls int32
+	}{
+		{"GET retries 502", http.MethodGet, http.StatusBadGateway, 3},
+		{"GET retries 503", http.MethodGet, http.StatusServiceUnavailable, 3},
+		{"POST retries 429", http.MethodPost, http.StatusTooManyRequests, 3},
+		{"POST does not retry 502", http.MethodPost, http.StatusBadGateway, 1},
+		{"GET does not retry 500", http.MethodGet, http.StatusInternalServerError, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&calls, 1)
+				writeJSON(w, tt.status, map[string]string{"error": "try later"})
+			}, Options{Token: "t"})
+
+			_, err := c.do(context.Background(), request{method: tt.method, path: "/x"}, nil)
+
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.status {
+				t.Fatalf("do() error = %v, want APIError %d", err, tt.status)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestClient_RetryThenSuccess(t *testing.T) {
+	var calls int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "busy"})
+			return
+		}
+		writeJSON(w, http.StatusOK, User{Email: "a@example.com"})
+	}, Options{Token: "t"})
+
+	user, err := c.Me(context.Background())
+	if err != nil {
+		t.Fatalf("Me() error = %v", err)
+	}
+	if user.Email != "a@example.com" {
+		t.Errorf("Me().Email = %q, want %q", user.Email, "a@example.com")
+	}
+}
+
+func TestClient_TokenRefresh(t *testing.T) {
+	var logins int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			n := atomic.AddInt32(&logins, 1)
+			// The first token is already inside the refresh window
+			expires := time.Now().Add(30 * time.Second)
+			if n > 1 {
+				expires = time.Now().Add(time.Hour)
+			}
+			writeJSON(w, http.StatusOK, AuthResponse{Token: &TokenPair{AccessToken: fmt.Sprintf("token-%d", n), ExpiresAt: expires}})
+		case "/api/v2/me":
+			writeJSON(w, http.StatusOK, User{ID: r.Header.Get("Authorization")})
+		}
+	}, Options{Email: "a@example.com", Password: "p"})
+
+	ctx := context.Background()
+	for _, want := range []string{"Bearer token-1", "Bearer token-2", "Bearer token-2"} {
+		user, err := c.Me(ctx)
+		if err != nil {
+			t.Fatalf("Me() error = %v", err)
+		}
+		if user.ID != want {
+			t.Errorf("Authorization = %q, want %q", user.ID, want)
+		}
+	}
+	if logins != 2 {
+		t.Errorf("logins = %d, want 2", logins)
+	}
+}
+
+func TestClient_ReloginOnUnauthorized(t *testing.T) {
+	var logins int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			atomic.AddInt32(&logins, 1)
+			writeJSON(w, http.StatusOK, AuthResponse{Token: &TokenPair{AccessToken: "fresh", ExpiresAt: time.Now().Add(time.Hour)}})
+		case "/api/v2/me":
+			if r.Header.Get("Authorization") != "Bearer fresh" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Token has been revoked"})
+				return
+			}
+			writeJSON(w, http.StatusOK, User{Email: "a@example.com"})
+		}
+	}, Options{Token: "revoked", Email: "a@example.com", Password: "p"})
+
+	if _, err := c.Me(context.Background()); err != nil {
+		t.Fatalf("Me() error = %v", err)
+	}
+	if logins != 1 {
+		t.Errorf("logins = %d, want 1", logins)
+	}
+}
+
+func TestClient_ConfirmLogin(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/auth/login":
+			writeJSON(w, http.StatusAccepted, map[string]interface{}{"confirmation_required": true, "login_token": "held", "expires_in": 900})
+		case "/api/v2/auth/confirm-login":
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body["login_token"] != "held" || body["code"] != "123456" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid confirmation code"})
+				return
+			}
+			writeJSON(w, http.StatusOK, AuthResponse{Token: &TokenPair{AccessToken: "confirmed", ExpiresAt: time.Now().Add(time.Hour)}})
+		case "/api/v2/me":
+			if r.Header.Get("Authorization") != "Bearer confirmed" {
+				writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+				return
+			}
+			writeJSON(w, http.StatusOK, User{Email: "a@example.com"})
+		}
+	}, Options{})
+
+	auth, err := c.Login(context.Background(), "a@example.com", "p")
+	if !errors.Is(err, ErrConfirmationRequired) || auth == nil || auth.LoginToken != "held" {
+		t.Fatalf("Login() = %+v, %v, want the held sign-in", auth, err)
+	}
+
+	if _, err := c.ConfirmLogin(context.Background(), auth.LoginToken, "123456"); err != nil {
+		t.Fatalf("ConfirmLogin() error = %v", err)
+	}
+	if _, err := c.Me(context.Background()); err != nil {
+		t.Errorf("Me() after ConfirmLogin() error = %v", err)
+	}
+}
+
+func TestClient_UnauthorizedWithoutCredentials(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+	}, Options{Token: "bad"})
+
+	_, err := c.Me(context.Background())
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized || apiErr.Message != "Invalid token" {
+		t.Errorf("Me() error = %v, want 401 Invalid token", err)
+	}
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":  "Validation failed",
+			"fields": map[string]string{"content": "Content is required"},
+		})
+	}, Options{Token: "t"})
+
+	_, err := c.CreateSubmission(context.Background(), CreateSubmissionInput{})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Fields["content"] != "Content is required" {
+		t.Errorf("CreateSubmission() error = %v, want content field error", err)
+	}
+}
+
+func TestClient_CreateSubmission_Duplicate(t *testing.T) {
+	existing := Submission{ID: uuid.New(), Status: StatusCompleted}
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var in CreateSubmissionInput
+		_ = json.NewDecoder(r.Body).Decode(&in)
+		if in.AllowDuplicate {
+			writeJSON(w, http.StatusCreated, Submission{ID: uuid.New(), Status: StatusQueued})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"duplicate_of": existing.ID, "submission": existing})
+	}, Options{Token: "t"})
+
+	got, err := c.CreateSubmission(context.Background(), CreateSubmissionInput{Content: "text"})
+	if err != nil {
+		t.Fatalf("CreateSubmission() error = %v", err)
+	}
+	if got.ID != existing.ID || !got.Duplicate {
+		t.Errorf("CreateSubmission() = %+v, want the existing submission marked duplicate", got)
+	}
+
+	got, err = c.CreateSubmission(context.Background(), CreateSubmissionInput{Content: "text", AllowDuplicate: true})
+	if err != nil {
+		t.Fatalf("CreateSubmission() error = %v", err)
+	}
+	if got.ID == existing.ID || got.Duplicate {
+		t.Errorf("CreateSubmission() = %+v, want a new submission", got)
+	}
+}
+
+func TestClient_ListSubmissions(t *testing.T) {
+	next := "bzE6MjA"
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if q.Get("limit") != "20" || q.Get("cursor") != next || q.Has("keyword") {
+			t.Errorf("query = %q, want limit and cursor only", r.URL.RawQuery)
+		}
+		writeJSON(w, http.StatusOK, List[Submission]{
+			Data:       []Submission{{ID: uuid.New(), Status: StatusCompleted}},
+			Pagination: Pagination{Total: 21, Limit: 20, Offset: 20},
+		})
+	}, Options{Token: "t"})
+
+	list, err := c.ListSubmissions(context.Background(), ListOptions{Limit: 20, Cursor: next})
+	if err != nil {
+		t.Fatalf("ListSubmissions() error = %v", err)
+	}
+	if len(list.Data) != 1 || list.Pagination.NextCursor != nil {
+		t.Errorf("ListSubmissions() = %+v, want one item on the last page", list)
+	}
+}
+
+func TestClient_GetAnalysis(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        interface{}
+		wantPending SubmissionStatus
+		wantErr     bool
+	}{
+		{"ready", http.StatusOK, Analysis{Summary: "done"}, "", false},
+		{"processing", http.StatusAccepted, map[string]string{"status": "processing"}, StatusProcessing, true},
+		{"failed", http.StatusNotFound, map[string]string{"error": "Analysis not available"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				writeJSON(w, tt.status, tt.body)
+			}, Options{Token: "t"})
+
+			analysis, err := c.GetAnalysis(context.Background(), uuid.New())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetAnalysis() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var pending *ErrAnalysisPending
+			if errors.As(err, &pending) != (tt.wantPending != "") {
+				t.Fatalf("GetAnalysis() error = %v, want pending %q", err, tt.wantPending)
+			}
+			if pending != nil && pending.Status != tt.wantPending {
+				t.Errorf("pending status = %q, want %q", pending.Status, tt.wantPending)
+			}
+			if !tt.wantErr && analysis.Summary != "done" {
+				t.Errorf("GetAnalysis().Summary = %q, want %q", analysis.Summary, "done")
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"2", 2 * time.Second},
+		{"600", maxDelay},
+		{"-1", 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		if got := retryAfter(tt.value); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/sfumato00/content-analyzer/internal/apiversion"
+	"github.com/sfumato00/content-analyzer/internal/auth"
+	"github.com/sfumato00/content-analyzer/internal/dto"
+	"github.com/sfumato00/content-analyzer/internal/handlers"
+	"github.com/sfumato00/content-analyzer/internal/models"
+	"github.com/sfumato00/content-analyzer/internal/response"
+	"github.com/sfumato00/content-analyzer/internal/services/aidetect"
+	"github.com/sfumato00/content-analyzer/internal/services/revisions"
+	"github.com/sfumato00/content-analyzer/internal/textstats"
+	"github.com/sfumato00/content-analyzer/internal/timestamp"
+)
+
+// TestContract encodes the server's response types and decodes them into
+// the client's, failing when a field is missing on either side
+func TestContract(t *testing.T) {
+	now := timestamp.New(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	score := 0.8
+	redacted := "[EMAIL]"
+	focus := "focus on legal risk"
+	profileID := uuid.New()
+	previousID := uuid.New()
+	assigneeID := uuid.New()
+	name := "Ada"
+	reason := models.FailureParseError
+	cursor := response.EncodeCursor(20)
+
+	submission := models.Submission{
+		ID: uuid.New(), UserID: uuid.New(), Content: "text", RedactedContent: &redacted,
+		Status: models.StatusCompleted, Version: 2, CreatedAt: now,
+		QueuedAt: &now, ProcessingAt: &now, CompletedAt: &now, FailedAt: &now, CanceledAt: &now, ArchivedAt: &now, DeletedAt: &now,
+		FailureReason: &reason, AssigneeID: &assigneeID, DueAt: &now, WorkflowStatus: models.WorkflowInReview,
+		Instructions: &focus, ProfileID: &profileID,
+		PreviousID: &previousID, Revision: 2,
+		Stats:       &textstats.Stats{Words: 1, Characters: 4, Tokens: 1},
+		DuplicateOf: &previousID,
+	}
+	analysis := &models.Analysis{
+		ID: uuid.New(), SubmissionID: submission.ID, Sentiment: "positive", SentimentScore: &score,
+		Topics:           []string{"go"},
+		Keyphrases:       []models.Keyphrase{{Phrase: "typed client", Score: 0.9}},
+		Findings:         []models.Finding{{Type: "email", Start: 1, End: 5, Verified: true}},
+		Summary:          "summary",
+		Readability:      &models.ReadabilityMetrics{Words: 10, Sentences: 1, Syllables: 14, Polysyllables: 1, FleschReadingEase: 60, FleschKincaidGrade: 8, SMOGGrade: 9, AverageSentenceLength: 10, PassiveVoiceRatio: 0.1, LexicalDiversity: 0.7},
+		ProcessingTimeMs: 120, CreatedAt: now,
+		Instructions: &focus,
+		Changes:      &models.RevisionChanges{Tone: "Warmer.", ClaimsAdded: []string{"a"}, ClaimsRemoved: []string{"b"}},
+		Claims: []models.Claim{{
+			Text: "a", Assessment: models.ClaimSupported, Explanation: "Sources agree.",
+			Sources: []models.ClaimSource{{Title: "Source", URL: "https://example.com"}},
+		}},
+		Bias: &models.BiasReport{
+			Framing: "Partisan.", OneSidedness: 0.8, MissingPerspectives: []string{"Opponents"},
+			Examples: []models.BiasExample{{Kind: models.BiasLoadedLanguage, Text: "reckless", Start: 4, End: 12, Explanation: "Pejorative."}},
+		},
+		AIDetection: aidetect.Estimate(models.AIDetectionSignals{Words: 400, Burstiness: &score, ModelProbability: &score}, "Formulaic."),
+		Moderation:  models.ModerationScores{models.ModerationToxicity: 0.9},
+		PolicyDecision: &models.PolicyDecision{
+			Action: models.PolicyAllow, PolicyAction: models.PolicyBlock,
+			Violations: []models.PolicyViolation{{Category: models.ModerationToxicity, Score: 0.9, Threshold: 0.8, Action: models.PolicyBlock}},
+			Override:   &models.PolicyOverride{Action: models.PolicyAllow, Reason: "Satire.", By: "ops@example.com", At: now},
+		},
+		Compliance: []models.ComplianceFlag{{Phrase: "guaranteed", Start: 2, End: 12, Replacement: "expected"}},
+		Labels:     []models.Classification{{Label: "Complaint", Confidence: 0.8}},
+		Language:   "en",
+		TimedOut:   []string{"bias"},
+	}
+	diff := revisions.Compare(&models.Analysis{SubmissionID: previousID, Sentiment: "neutral", SentimentScore: &score, Readability: analysis.Readability}, analysis)
+	issues := response.Complete([]models.Issue{{
+		Category: models.IssueSpelling, Severity: models.SeverityError, Start: 4, End: 8, Message: "Misspelled.", Suggestion: "typo",
+	}}).WithFilter("category", "spelling")
+	list := response.ListResponse[dto.Submission]{
+		Data:       []dto.Submission{*dto.NewSubmission(&submission).WithAnalysis(analysis, apiversion.V2)},
+		Pagination: response.Pagination{Total: 40, Limit: 20, Offset: 0, NextCursor: &cursor},
+		Filters:    map[string]string{"keyword": "go"},
+	}
+
+	tests := []struct {
+		name   string
+		server interface{}
+		client interface{}
+	}{
+		{"auth", handlers.AuthResponse{
+			User:  &dto.User{ID: "u1", Email: "a@example.com", DisplayName: &name, EmailVerified: true, Plan: "pro", ConfirmNewLogins: true, Version: 3, CreatedAt: now},
+			Token: &auth.TokenPair{AccessToken: "a", RefreshToken: "r", ExpiresAt: now, TokenType: "Bearer"},
+		}, &AuthResponse{}},
+		{"submission", dto.NewSubmission(&submission), &Submission{}},
+		{"analysis", dto.NewAnalysis(analysis, apiversion.V2), &Analysis{}},
+		{"submission list", list, &List[Submission]{}},
+		{"diff", diff, &Diff{}},
+		{"issue list", issues, &List[Issue]{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serverJSON, err := json.Marshal(tt.server)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			dec := json.NewDecoder(bytes.NewReader(serverJSON))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(tt.client); err != nil {
+				t.Fatalf("client type is missing a field: %v", err)
+			}
+
+			clientJSON, err := json.Marshal(tt.client)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+
+			var want, got interface{}
+			_ = json.Unmarshal(serverJSON, &want)
+			_ = json.Unmarshal(clientJSON, &got)
+			if !reflect.DeepEqual(normalizeTimes(got), normalizeTimes(want)) {
+				t.Errorf("round trip = %s, want %s", clientJSON, serverJSON)
+			}
+		})
+	}
+}
+
+// normalizeTimes rewrites the times in a decoded JSON document in the
+// server's format, since the client's time.Time fields are written at
+// their own precision
+func normalizeTimes(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = normalizeTimes(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeTimes(value)
+		}
+	case string:
+		if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+			return timestamp.Format(t)
+		}
+	}
+	return v
+}
@@ -0,0 +1,287 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CreateSubmissionInput is the body of a new submission
+type CreateSubmissionInput struct {
+	Content string `json:"content"`
+	// Redact stores a copy with personal data and profanity masked
+	Redact bool `json:"redact"`
+	// Draft holds the submission back from analysis until Submit
+	Draft bool `json:"draft"`
+	// Instructions steer the analysis, such as "focus on legal risk"
+	Instructions string `json:"instructions,omitempty"`
+	// ProfileID selects an analysis profile
+	ProfileID *uuid.UUID `json:"profile_id,omitempty"`
+	// AllowDuplicate creates the submission even if the same content was
+	// already submitted
+	AllowDuplicate bool `json:"allow_duplicate,omitempty"`
+}
+
+// UpdateSubmissionInput replaces a draft's content. Version is the
+// version being edited; a stale one fails with 412.
+type UpdateSubmissionInput struct {
+	Content string `json:"content"`
+	Redact  bool   `json:"redact"`
+	Version int    `json:"version"`
+}
+
+// CreateVersionInput is a revised version of a document
+type CreateVersionInput struct {
+	Content string `json:"content"`
+	Redact  bool   `json:"redact"`
+}
+
+// ListOptions selects a page of submissions
+type ListOptions struct {
+	// Limit defaults to the server's page size
+	Limit int
+	// Cursor is the NextCursor of the previous page
+	Cursor  string
+	Keyword string
+	// Label lists only submissions classified with a taxonomy label
+	Label string
+	// Duplicates lists only content submitted more than once
+	Duplicates bool
+	// IncludeAnalysis returns each submission with its latest analysis
+	IncludeAnalysis bool
+	// Fields, if set, returns only the named fields of each submission,
+	// such as "id" and "status"; the others are left at their zero value
+	Fields []string
+}
+
+// IssueOptions filters a submission's proofreading issues
+type IssueOptions struct {
+	Category string
+	Severity string
+}
+
+// ErrAnalysisPending is returned by GetAnalysis while the submission is
+// a draft or still being analyzed
+type ErrAnalysisPending struct {
+	Status SubmissionStatus
+}
+
+func (e *ErrAnalysisPending) Error() string {
+	return fmt.Sprintf("client: analysis pending, submission is %s", e.Status)
+}
+
+// CreateSubmission submits content for analysis, or saves it as a draft.
+// Content the user already submitted isn't stored again unless
+// in.AllowDuplicate is set: the earlier submission is returned instead,
+// with Duplicate set. Content a teammate had analyzed is stored as a new
+// completed submission reusing their analysis, with DuplicateOf set.
+func (c *Client) CreateSubmission(ctx context.Context, in CreateSubmissionInput) (*Submission, error) {
+	var raw json.RawMessage
+	status, err := c.do(ctx, request{method: http.MethodPost, path: "/submissions", body: in}, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == http.StatusOK {
+		var duplicate struct {
+			Submission *Submission `json:"submission"`
+		}
+		if err := json.Unmarshal(raw, &duplicate); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if duplicate.Submission == nil {
+			return nil, fmt.Errorf("duplicate response has no submission")
+		}
+		duplicate.Submission.Duplicate = true
+		return duplicate.Submission, nil
+	}
+
+	var s Submission
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &s, nil
+}
+
+// GetSubmission returns one submission
+func (c *Client) GetSubmission(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	var s Submission
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions/" + id.String()}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSubmissions returns a page of the user's submissions, newest first
+func (c *Client) ListSubmissions(ctx context.Context, opts ListOptions) (*List[Submission], error) {
+	query := map[string]string{"cursor": opts.Cursor, "keyword": opts.Keyword, "label": opts.Label}
+	if opts.Limit > 0 {
+		query["limit"] = strconv.Itoa(opts.Limit)
+	}
+	if opts.Duplicates {
+		query["duplicates"] = "true"
+	}
+	if opts.IncludeAnalysis {
+		query["include"] = "analysis"
+	}
+	if len(opts.Fields) > 0 {
+		query["fields"] = strings.Join(opts.Fields, ",")
+	}
+
+	var list List[Submission]
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions", query: query}, &list); err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// UpdateSubmission edits a draft
+func (c *Client) UpdateSubmission(ctx context.Context, id uuid.UUID, in UpdateSubmissionInput) (*Submission, error) {
+	var s Submission
+	if _, err := c.do(ctx, request{method: http.MethodPatch, path: "/submissions/" + id.String(), body: in}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Submit queues a draft for analysis
+func (c *Client) Submit(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	return c.transition(ctx, id, "submit")
+}
+
+// Cancel stops a queued or processing submission
+func (c *Client) Cancel(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	return c.transition(ctx, id, "cancel")
+}
+
+// Archive hides a finished submission from the default list
+func (c *Client) Archive(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	return c.transition(ctx, id, "archive")
+}
+
+// DeleteSubmission moves a submission to the trash, where it can be
+// restored until it is purged
+func (c *Client) DeleteSubmission(ctx context.Context, id uuid.UUID) error {
+	_, err := c.do(ctx, request{method: http.MethodDelete, path: "/submissions/" + id.String()}, nil)
+	return err
+}
+
+// RestoreSubmission takes a submission out of the trash
+func (c *Client) RestoreSubmission(ctx context.Context, id uuid.UUID) (*Submission, error) {
+	var s Submission
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/trash/" + id.String() + "/restore"}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (c *Client) transition(ctx context.Context, id uuid.UUID, action string) (*Submission, error) {
+	var s Submission
+	path := "/submissions/" + id.String() + "/" + action
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: path}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetAnalysis returns a submission's analysis, or *ErrAnalysisPending
+// while it isn't ready yet
+func (c *Client) GetAnalysis(ctx context.Context, id uuid.UUID) (*Analysis, error) {
+	var body struct {
+		Analysis
+		Status SubmissionStatus `json:"status"`
+	}
+	status, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions/" + id.String() + "/analysis"}, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusAccepted {
+		return nil, &ErrAnalysisPending{Status: body.Status}
+	}
+
+	return &body.Analysis, nil
+}
+
+// RetryAnalysis queues the stages of a submission's analysis that timed
+// out to run again, and returns their names. Their results are merged
+// into the analysis GetAnalysis returns.
+func (c *Client) RetryAnalysis(ctx context.Context, id uuid.UUID) ([]string, error) {
+	var body struct {
+		Stages []string `json:"stages"`
+	}
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/submissions/" + id.String() + "/analysis/retry"}, &body); err != nil {
+		return nil, err
+	}
+	return body.Stages, nil
+}
+
+// RerunModule queues one stage of a submission's analysis, such as
+// "moderation", to run again. Its result is merged into the analysis
+// GetAnalysis returns.
+func (c *Client) RerunModule(ctx context.Context, id uuid.UUID, module string) error {
+	_, err := c.do(ctx, request{method: http.MethodPost, path: "/submissions/" + id.String() + "/analysis/modules/" + url.PathEscape(module) + "/rerun"}, nil)
+	return err
+}
+
+// CreateVersion uploads a revised version of a submitted document. It is
+// stored and queued as a new submission.
+func (c *Client) CreateVersion(ctx context.Context, id uuid.UUID, in CreateVersionInput) (*Submission, error) {
+	var s Submission
+	if _, err := c.do(ctx, request{method: http.MethodPost, path: "/submissions/" + id.String() + "/versions", body: in}, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListVersions returns every version of the document a submission belongs
+// to, oldest first
+func (c *Client) ListVersions(ctx context.Context, id uuid.UUID) ([]Submission, error) {
+	var list List[Submission]
+	if _, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions/" + id.String() + "/versions"}, &list); err != nil {
+		return nil, err
+	}
+	return list.Data, nil
+}
+
+// GetDiff compares a version with the one it revised, or returns
+// *ErrAnalysisPending while either analysis isn't ready yet
+func (c *Client) GetDiff(ctx context.Context, id uuid.UUID) (*Diff, error) {
+	var body struct {
+		Diff
+		Status SubmissionStatus `json:"status"`
+	}
+	status, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions/" + id.String() + "/diff"}, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusAccepted {
+		return nil, &ErrAnalysisPending{Status: body.Status}
+	}
+
+	return &body.Diff, nil
+}
+
+// ListIssues returns the proofreading issues of a submission ordered by
+// position, or *ErrAnalysisPending while it is still being analyzed
+func (c *Client) ListIssues(ctx context.Context, id uuid.UUID, opts IssueOptions) ([]Issue, error) {
+	var body struct {
+		List[Issue]
+		Status SubmissionStatus `json:"status"`
+	}
+	query := map[string]string{"category": opts.Category, "severity": opts.Severity}
+	status, err := c.do(ctx, request{method: http.MethodGet, path: "/submissions/" + id.String() + "/issues", query: query}, &body)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusAccepted {
+		return nil, &ErrAnalysisPending{Status: body.Status}
+	}
+
+	return body.Data, nil
+}
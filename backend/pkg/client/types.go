@@ -0,0 +1,403 @@
+package client
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// These types mirror the v2 API's JSON. contract_test.go checks them
+// against the server's response types so the two can't drift apart.
+
+// TokenPair is an access token and when it expires
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	TokenType    string    `json:"token_type"`
+}
+
+// User is an account without its credentials
+type User struct {
+	ID               string  `json:"id"`
+	Email            string  `json:"email"`
+	DisplayName      *string `json:"display_name"`
+	TimeZone         *string `json:"time_zone"`
+	EmailVerified    bool    `json:"email_verified"`
+	Plan             string  `json:"plan"`
+	ConfirmNewLogins bool    `json:"confirm_new_logins"`
+	Status           string  `json:"status"`
+	StatusReason     *string `json:"status_reason,omitempty"`
+	Version          int     `json:"version"`
+	CreatedAt        string  `json:"created_at"`
+}
+
+// AuthResponse is returned by register and login. A sign-in held for
+// confirmation has no user or token, only a login token for ConfirmLogin.
+type AuthResponse struct {
+	User  *User      `json:"user"`
+	Token *TokenPair `json:"token"`
+
+	ConfirmationRequired bool   `json:"confirmation_required,omitempty"`
+	LoginToken           string `json:"login_token,omitempty"`
+}
+
+// SubmissionStatus is where a submission is in its lifecycle
+type SubmissionStatus string
+
+const (
+	StatusDraft      SubmissionStatus = "draft"
+	StatusQueued     SubmissionStatus = "queued"
+	StatusProcessing SubmissionStatus = "processing"
+	StatusCompleted  SubmissionStatus = "completed"
+	StatusFailed     SubmissionStatus = "failed"
+	StatusCanceled   SubmissionStatus = "canceled"
+	StatusArchived   SubmissionStatus = "archived"
+)
+
+// WorkflowStatus is where a submission's review is
+type WorkflowStatus string
+
+const (
+	WorkflowOpen             WorkflowStatus = "open"
+	WorkflowInReview         WorkflowStatus = "in_review"
+	WorkflowChangesRequested WorkflowStatus = "changes_requested"
+	WorkflowApproved         WorkflowStatus = "approved"
+)
+
+// Submission is content submitted for analysis
+type Submission struct {
+	ID              uuid.UUID        `json:"id"`
+	UserID          uuid.UUID        `json:"user_id"`
+	Content         string           `json:"content"`
+	RedactedContent *string          `json:"redacted_content,omitempty"`
+	Status          SubmissionStatus `json:"status"`
+	Version         int              `json:"version"`
+	CreatedAt       time.Time        `json:"created_at"`
+	UpdatedAt       time.Time        `json:"updated_at"`
+
+	QueuedAt     *time.Time `json:"queued_at,omitempty"`
+	ProcessingAt *time.Time `json:"processing_at,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	FailedAt     *time.Time `json:"failed_at,omitempty"`
+	CanceledAt   *time.Time `json:"canceled_at,omitempty"`
+	ArchivedAt   *time.Time `json:"archived_at,omitempty"`
+	// DeletedAt is set while the submission is in the trash
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+
+	// FailureReason says why a failed analysis failed, such as
+	// "parse_error" or "timed_out", when it is known
+	FailureReason *string `json:"failure_reason,omitempty"`
+
+	// AssigneeID is the teammate reviewing the submission
+	AssigneeID     *uuid.UUID     `json:"assignee_id,omitempty"`
+	DueAt          *time.Time     `json:"due_at,omitempty"`
+	WorkflowStatus WorkflowStatus `json:"workflow_status"`
+
+	Instructions *string    `json:"instructions,omitempty"`
+	ProfileID    *uuid.UUID `json:"profile_id,omitempty"`
+
+	// PreviousID is the version this one revises; Revision counts from 1
+	PreviousID *uuid.UUID `json:"previous_id,omitempty"`
+	Revision   int        `json:"revision"`
+
+	// Stats is the size of the content, when it was counted
+	Stats *TextStats `json:"stats,omitempty"`
+
+	// DuplicateOf is the user's earliest submission with the same
+	// content, set on later copies, or the teammate's submission whose
+	// analysis this one reuses
+	DuplicateOf *uuid.UUID `json:"duplicate_of,omitempty"`
+	// Analysis is the latest analysis, when it was asked for with
+	// IncludeAnalysis; nil until there is one
+	Analysis *Analysis `json:"analysis,omitempty"`
+	// Links point at related resources and actions by relation: "self",
+	// "analysis", "events" and, while it can be canceled, "cancel"
+	Links map[string]Link `json:"links,omitempty"`
+	// Duplicate is set when CreateSubmission returned an earlier
+	// submission with the same content instead of creating one
+	Duplicate bool `json:"-"`
+}
+
+// Link points at a related resource or an action
+type Link struct {
+	Href string `json:"href"`
+	// Method is set for links that aren't fetched with GET
+	Method string `json:"method,omitempty"`
+}
+
+// TextStats counts the words, characters and estimated model tokens of
+// content
+type TextStats struct {
+	Words      int `json:"words"`
+	Characters int `json:"characters"`
+	Tokens     int `json:"tokens"`
+}
+
+// Analysis is the result of analyzing a submission
+type Analysis struct {
+	ID               uuid.UUID    `json:"id"`
+	SubmissionID     uuid.UUID    `json:"submission_id"`
+	Sentiment        Sentiment    `json:"sentiment"`
+	Topics           []string     `json:"topics"`
+	Keyphrases       []Keyphrase  `json:"keyphrases"`
+	Findings         []Finding    `json:"findings"`
+	Summary          string       `json:"summary"`
+	Readability      *Readability `json:"readability"`
+	ProcessingTimeMs int          `json:"processing_time_ms"`
+	CreatedAt        time.Time    `json:"created_at"`
+	UpdatedAt        time.Time    `json:"updated_at"`
+
+	// How faithful the summary is to the text, from 0 to 1, or nil when it
+	// wasn't checked; LowConfidence suggests re-running the analysis
+	Confidence    *float64 `json:"confidence"`
+	LowConfidence bool     `json:"low_confidence"`
+
+	// The instructions the analysis ran with, if any
+	Instructions *string `json:"instructions,omitempty"`
+
+	// How a revision's tone and claims changed from the previous version
+	Changes *RevisionChanges `json:"changes,omitempty"`
+
+	// The factual claims in the content with their fact-checks, when
+	// claim extraction ran
+	Claims []Claim `json:"claims,omitempty"`
+
+	// Framing, loaded language and one-sidedness, when bias analysis ran
+	Bias *BiasReport `json:"bias,omitempty"`
+
+	// How likely the content is machine-generated, when detection ran
+	AIDetection *AIDetection `json:"ai_detection,omitempty"`
+
+	// Moderation scores from 0 to 1 by category ("toxicity",
+	// "harassment", "hate", "sexual", "violence", "self_harm"), when
+	// moderation ran
+	Moderation map[string]float64 `json:"moderation,omitempty"`
+
+	// What the moderation policy of the owner's organizations, or an
+	// operator's override, decided
+	PolicyDecision *PolicyDecision `json:"policy_decision,omitempty"`
+
+	// Uses of phrases the owner's organizations banned, when any are
+	// banned and the compliance module ran
+	Compliance []ComplianceFlag `json:"compliance,omitempty"`
+
+	// The labels of the owner's organizations' taxonomy that apply, most
+	// confident first, when a taxonomy applies
+	Labels []Label `json:"labels,omitempty"`
+
+	// Language is the ISO 639-1 code of the content's language, when it
+	// was detected
+	Language string `json:"language,omitempty"`
+
+	// The pipeline stages that ran out of time, leaving their part of the
+	// analysis empty until RetryAnalysis runs them again
+	TimedOut []string `json:"timed_out,omitempty"`
+}
+
+// Label is a taxonomy label with the classifier's confidence from 0 to 1
+type Label struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ComplianceFlag is a use of a banned phrase. Start and End are character
+// offsets into the submission content; Replacement is what the
+// organization asks members to write instead, if anything.
+type ComplianceFlag struct {
+	Phrase      string `json:"phrase"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// PolicyDecision is a moderation policy's decision on an analysis. Action,
+// the decision in force, and PolicyAction, the policy's own, are "allow",
+// "warn" or "block"; they differ when an operator overrode the policy.
+type PolicyDecision struct {
+	Action       string            `json:"action"`
+	PolicyAction string            `json:"policy_action"`
+	Violations   []PolicyViolation `json:"violations"`
+	Override     *PolicyOverride   `json:"override,omitempty"`
+}
+
+// PolicyViolation is a moderation category that scored above a threshold
+type PolicyViolation struct {
+	Category  string  `json:"category"`
+	Score     float64 `json:"score"`
+	Threshold float64 `json:"threshold"`
+	Action    string  `json:"action"`
+}
+
+// PolicyOverride is an operator's decision replacing the policy's
+type PolicyOverride struct {
+	Action string    `json:"action"`
+	Reason string    `json:"reason,omitempty"`
+	By     string    `json:"by,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// AIDetection estimates how likely content is to be machine-generated.
+// Score is a probability from 0 to 1; Label is "likely_human",
+// "uncertain" or "likely_ai"; Confidence is "low", "medium" or "high",
+// and Uncertainty says what limits it.
+type AIDetection struct {
+	Score       float64            `json:"score"`
+	Label       string             `json:"label"`
+	Confidence  string             `json:"confidence"`
+	Uncertainty string             `json:"uncertainty"`
+	Explanation string             `json:"explanation"`
+	Signals     AIDetectionSignals `json:"signals"`
+}
+
+// AIDetectionSignals are the measurements behind an AI detection score,
+// nil when not measured
+type AIDetectionSignals struct {
+	Words            int      `json:"words"`
+	Burstiness       *float64 `json:"burstiness"`
+	Perplexity       *float64 `json:"perplexity"`
+	ModelProbability *float64 `json:"model_probability"`
+}
+
+// BiasReport assesses the political or ideological framing of content.
+// OneSidedness runs from 0, every side heard, to 1, a single side.
+type BiasReport struct {
+	Framing             string        `json:"framing"`
+	OneSidedness        float64       `json:"one_sidedness"`
+	MissingPerspectives []string      `json:"missing_perspectives"`
+	Examples            []BiasExample `json:"examples"`
+}
+
+// BiasExample is a passage cited in a bias report. Kind is "framing",
+// "loaded_language" or "one_sided"; Start and End are character offsets.
+type BiasExample struct {
+	Kind        string `json:"kind"`
+	Text        string `json:"text"`
+	Start       int    `json:"start"`
+	End         int    `json:"end"`
+	Explanation string `json:"explanation"`
+}
+
+// Claim is a factual claim made in the content. Assessment is
+// "supported", "contradicted", "disputed" or "unverified".
+type Claim struct {
+	Text        string        `json:"text"`
+	Assessment  string        `json:"assessment"`
+	Explanation string        `json:"explanation,omitempty"`
+	Sources     []ClaimSource `json:"sources"`
+}
+
+// Issue is a grammar, spelling or style problem found by proofreading.
+// Start and End are character (rune) offsets into the content; Category
+// is "grammar", "spelling" or "style" and Severity "error", "warning" or
+// "suggestion".
+type Issue struct {
+	Category   string `json:"category"`
+	Severity   string `json:"severity"`
+	Start      int    `json:"start"`
+	End        int    `json:"end"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// ClaimSource is a web page cited for a claim's assessment
+type ClaimSource struct {
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// RevisionChanges is the model's comparison of a revision with the
+// version it revises
+type RevisionChanges struct {
+	Tone          string   `json:"tone"`
+	ClaimsAdded   []string `json:"claims_added"`
+	ClaimsRemoved []string `json:"claims_removed"`
+}
+
+// Diff describes how a version differs from the one it revised.
+// ClaimsCompared is false when the model comparison failed.
+type Diff struct {
+	SubmissionID      uuid.UUID         `json:"submission_id"`
+	PreviousID        uuid.UUID         `json:"previous_id"`
+	Tone              ToneChange        `json:"tone"`
+	ClaimsCompared    bool              `json:"claims_compared"`
+	ClaimsAdded       []string          `json:"claims_added"`
+	ClaimsRemoved     []string          `json:"claims_removed"`
+	TopicsAdded       []string          `json:"topics_added"`
+	TopicsRemoved     []string          `json:"topics_removed"`
+	KeyphrasesAdded   []string          `json:"keyphrases_added"`
+	KeyphrasesRemoved []string          `json:"keyphrases_removed"`
+	Readability       *ReadabilityDelta `json:"readability"`
+}
+
+// ToneChange compares the sentiment of two versions
+type ToneChange struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	FromScore   *float64 `json:"from_score"`
+	ToScore     *float64 `json:"to_score"`
+	ScoreDelta  *float64 `json:"score_delta"`
+	Description string   `json:"description,omitempty"`
+}
+
+// ReadabilityDelta is the change in each readability metric, revised
+// minus previous
+type ReadabilityDelta struct {
+	Words                 int     `json:"words"`
+	Sentences             int     `json:"sentences"`
+	FleschReadingEase     float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade    float64 `json:"flesch_kincaid_grade"`
+	SMOGGrade             float64 `json:"smog_grade"`
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	PassiveVoiceRatio     float64 `json:"passive_voice_ratio"`
+	LexicalDiversity      float64 `json:"lexical_diversity"`
+}
+
+// Sentiment is the overall sentiment of an analysis
+type Sentiment struct {
+	Label string   `json:"label"`
+	Score *float64 `json:"score"`
+}
+
+// Keyphrase is a key phrase with its relevance from 0 to 1
+type Keyphrase struct {
+	Phrase string  `json:"phrase"`
+	Score  float64 `json:"score"`
+}
+
+// Finding is a span of sensitive content, as character offsets
+type Finding struct {
+	Type     string `json:"type"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Verified bool   `json:"verified"`
+}
+
+// Readability holds writing-quality scores computed from the text
+type Readability struct {
+	Words                 int     `json:"words"`
+	Sentences             int     `json:"sentences"`
+	Syllables             int     `json:"syllables"`
+	Polysyllables         int     `json:"polysyllables"`
+	FleschReadingEase     float64 `json:"flesch_reading_ease"`
+	FleschKincaidGrade    float64 `json:"flesch_kincaid_grade"`
+	SMOGGrade             float64 `json:"smog_grade"`
+	AverageSentenceLength float64 `json:"average_sentence_length"`
+	PassiveVoiceRatio     float64 `json:"passive_voice_ratio"`
+	LexicalDiversity      float64 `json:"lexical_diversity"`
+}
+
+// List is one page of a collection
+type List[T any] struct {
+	Data       []T               `json:"data"`
+	Pagination Pagination        `json:"pagination"`
+	Filters    map[string]string `json:"filters"`
+}
+
+// Pagination locates a page; NextCursor is nil on the last page
+type Pagination struct {
+	Total      int     `json:"total"`
+	Limit      int     `json:"limit"`
+	Offset     int     `json:"offset"`
+	NextCursor *string `json:"next_cursor"`
+}
@@ -0,0 +1,24 @@
+package webhooksig_test
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/sfumato00/content-analyzer/pkg/webhooksig"
+)
+
+func ExampleVerifyRequest() {
+	secret := []byte(os.Getenv("CONTENT_ANALYZER_WEBHOOK_SECRET"))
+
+	http.HandleFunc("/hooks/content-analyzer", func(w http.ResponseWriter, r *http.Request) {
+		body, err := webhooksig.VerifyRequest(r, secret, webhooksig.DefaultTolerance)
+		if err != nil {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		log.Printf("verified delivery: %s", body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,102 @@
+package webhooksig
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := []byte(`{"event":"analysis.completed"}`)
+	signedAt := time.Unix(1767225600, 0)
+	header := Sign(signedAt, body, secret)
+
+	tests := []struct {
+		name    string
+		header  string
+		body    []byte
+		secret  []byte
+		now     time.Time
+		wantErr error
+	}{
+		{name: "valid", header: header, body: body, secret: secret, now: signedAt.Add(time.Minute)},
+		{name: "clock slightly behind", header: header, body: body, secret: secret, now: signedAt.Add(-time.Minute)},
+		{name: "rotating secrets", header: Sign(signedAt, body, []byte("old"), secret), body: body, secret: secret, now: signedAt},
+		{name: "unknown scheme ignored", header: header + ",v0=abc", body: body, secret: secret, now: signedAt},
+		{name: "replayed later", header: header, body: body, secret: secret, now: signedAt.Add(6 * time.Minute), wantErr: ErrTimestampOutOfRange},
+		{name: "from the future", header: header, body: body, secret: secret, now: signedAt.Add(-6 * time.Minute), wantErr: ErrTimestampOutOfRange},
+		{name: "tampered body", header: header, body: []byte(`{"event":"analysis.failed"}`), secret: secret, now: signedAt, wantErr: ErrSignatureMismatch},
+		{name: "wrong secret", header: header, body: body, secret: []byte("other"), now: signedAt, wantErr: ErrSignatureMismatch},
+		{name: "timestamp swapped", header: strings.Replace(header, "t=1767225600", "t=1767225601", 1), body: body, secret: secret, now: signedAt, wantErr: ErrSignatureMismatch},
+		{name: "missing", header: "", body: body, secret: secret, now: signedAt, wantErr: ErrMissingSignature},
+		{name: "no signature", header: "t=1767225600", body: body, secret: secret, now: signedAt, wantErr: ErrInvalidHeader},
+		{name: "no timestamp", header: "v1=00", body: body, secret: secret, now: signedAt, wantErr: ErrInvalidHeader},
+		{name: "bad hex", header: "t=1767225600,v1=zz", body: body, secret: secret, now: signedAt, wantErr: ErrInvalidHeader},
+		{name: "garbage", header: "sha256=abc;t", body: body, secret: secret, now: signedAt, wantErr: ErrInvalidHeader},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyAt(tt.header, tt.body, tt.secret, 0, tt.now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyAt() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSign_Format(t *testing.T) {
+	got := Sign(time.Unix(1767225600, 0), []byte("{}"), []byte("a"), []byte("b"))
+
+	parts := strings.Split(got, ",")
+	if len(parts) != 3 || parts[0] != "t=1767225600" || !strings.HasPrefix(parts[1], "v1=") || !strings.HasPrefix(parts[2], "v1=") {
+		t.Errorf("Sign() = %q, want t=...,v1=...,v1=...", got)
+	}
+	if parts[1] == parts[2] {
+		t.Error("Sign() gave the same signature for different secrets")
+	}
+}
+
+func TestVerifyRequest(t *testing.T) {
+	secret := []byte("whsec_test")
+	body := `{"event":"analysis.completed"}`
+
+	tests := []struct {
+		name    string
+		header  string
+		wantErr error
+	}{
+		{name: "valid", header: Sign(time.Now(), []byte(body), secret)},
+		{name: "missing header", header: "", wantErr: ErrMissingSignature},
+		{name: "stale", header: Sign(time.Now().Add(-time.Hour), []byte(body), secret), wantErr: ErrTimestampOutOfRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/hooks", strings.NewReader(body))
+			if tt.header != "" {
+				r.Header.Set(HeaderName, tt.header)
+			}
+
+			got, err := VerifyRequest(r, secret, 0)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyRequest() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if string(got) != body {
+				t.Errorf("VerifyRequest() body = %q, want %q", got, body)
+			}
+			// The body can still be read by the handler
+			if again, _ := io.ReadAll(r.Body); string(again) != body {
+				t.Errorf("request body after verify = %q, want %q", again, body)
+			}
+		})
+	}
+}